@@ -0,0 +1,496 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+	"golang.org/x/crypto/acme"
+)
+
+const (
+	// acmeKeyPrefix is the name prefix of the KeyStore entries
+	// containing the ACME account key and server certificate.
+	// They cannot be accessed via the key APIs while ACME is
+	// enabled.
+	acmeKeyPrefix = "kes-acme-"
+
+	acmeAccountKey     = acmeKeyPrefix + "account"
+	acmeCertificateKey = acmeKeyPrefix + "certificate"
+
+	// defaultACMERenewBefore is the default time period before
+	// the certificate expires at which it gets renewed.
+	defaultACMERenewBefore = 30 * 24 * time.Hour
+)
+
+// acmeManager obtains the server certificate from an ACME
+// certificate authority and renews it before it expires.
+//
+// The certificate and the ACME account key are stored in
+// the KeyStore such that all servers sharing the KeyStore
+// use the same certificate.
+type acmeManager struct {
+	conf  *ACMEConfig
+	store KeyStore
+	cert  atomic.Pointer[tls.Certificate]
+}
+
+// newACMEManager returns a new acmeManager that stores the
+// certificate in the given KeyStore, or nil if conf is nil.
+func newACMEManager(conf *ACMEConfig, store KeyStore) *acmeManager {
+	if conf == nil {
+		return nil
+	}
+	return &acmeManager{
+		conf:  conf.clone(),
+		store: store,
+	}
+}
+
+// GetCertificate returns the current server certificate.
+// It returns an error if no certificate has been obtained
+// yet.
+func (m *acmeManager) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if cert := m.cert.Load(); cert != nil {
+		return cert, nil
+	}
+	return nil, errors.New("kes: no ACME certificate has been obtained yet")
+}
+
+// Certificate returns the current server certificate or
+// nil if no certificate has been obtained yet.
+func (m *acmeManager) Certificate() *tls.Certificate { return m.cert.Load() }
+
+// Load reads the server certificate from the KeyStore, if
+// present.
+func (m *acmeManager) Load(ctx context.Context) error {
+	data, err := m.store.Get(ctx, acmeCertificateKey)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// The entry contains the PEM-encoded private key followed
+	// by the certificate chain.
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return fmt.Errorf("kes: invalid ACME certificate: %v", err)
+	}
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return fmt.Errorf("kes: invalid ACME certificate: %v", err)
+	}
+	m.cert.Store(&cert)
+	return nil
+}
+
+// Renew obtains a new server certificate if there is none or
+// the current one expires soon. It reports whether the current
+// certificate has been replaced.
+func (m *acmeManager) Renew(ctx context.Context) (bool, error) {
+	if !m.needsRenewal(time.Now()) {
+		return false, nil
+	}
+
+	// Another server using the same KeyStore may
+	// have renewed the certificate already.
+	old := m.cert.Load()
+	if err := m.Load(ctx); err != nil {
+		return false, err
+	}
+	if !m.needsRenewal(time.Now()) {
+		return m.cert.Load() != old, nil
+	}
+
+	cert, err := m.obtain(ctx)
+	if err != nil {
+		return false, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		return false, err
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	for _, c := range cert.Certificate {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c})...)
+	}
+	if err = m.store.Delete(ctx, acmeCertificateKey); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+		return false, err
+	}
+	if err = m.store.Create(ctx, acmeCertificateKey, data); err != nil {
+		return false, err
+	}
+	m.cert.Store(cert)
+	return true, nil
+}
+
+// needsRenewal reports whether there is no certificate for
+// all domains or whether the certificate expires within the
+// renewal period at time t.
+func (m *acmeManager) needsRenewal(t time.Time) bool {
+	cert := m.cert.Load()
+	if cert == nil || cert.Leaf == nil {
+		return true
+	}
+	for _, domain := range m.conf.Domains {
+		if cert.Leaf.VerifyHostname(domain) != nil {
+			return true
+		}
+	}
+
+	renewBefore := m.conf.RenewBefore
+	if renewBefore == 0 {
+		renewBefore = defaultACMERenewBefore
+	}
+	return !t.Add(renewBefore).Before(cert.Leaf.NotAfter)
+}
+
+// obtain requests a new certificate for all domains from the
+// ACME certificate authority.
+func (m *acmeManager) obtain(ctx context.Context) (*tls.Certificate, error) {
+	key, err := m.accountKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client := &acme.Client{
+		Key:          key,
+		DirectoryURL: m.conf.Directory,
+	}
+	if client.DirectoryURL == "" {
+		client.DirectoryURL = acme.LetsEncryptURL
+	}
+
+	account := &acme.Account{}
+	if m.conf.Email != "" {
+		account.Contact = []string{"mailto:" + m.conf.Email}
+	}
+	if _, err = client.Register(ctx, account, acme.AcceptTOS); err != nil && !errors.Is(err, acme.ErrAccountAlreadyExists) {
+		return nil, fmt.Errorf("kes: failed to register ACME account: %v", err)
+	}
+
+	order, err := client.AuthorizeOrder(ctx, acme.DomainIDs(m.conf.Domains...))
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to create ACME order: %v", err)
+	}
+	if order.Status == acme.StatusPending {
+		if err = m.authorize(ctx, client, order.AuthzURLs); err != nil {
+			return nil, err
+		}
+	}
+	if order, err = client.WaitOrder(ctx, order.URI); err != nil {
+		return nil, fmt.Errorf("kes: ACME order failed: %v", err)
+	}
+
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: m.conf.Domains[0]},
+		DNSNames: m.conf.Domains,
+	}, certKey)
+	if err != nil {
+		return nil, err
+	}
+	chain, _, err := client.CreateOrderCert(ctx, order.FinalizeURL, csr, true)
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to obtain ACME certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("kes: invalid ACME certificate: %v", err)
+	}
+	return &tls.Certificate{
+		Certificate: chain,
+		PrivateKey:  certKey,
+		Leaf:        leaf,
+	}, nil
+}
+
+// authorize solves the configured challenge for each pending
+// authorization.
+func (m *acmeManager) authorize(ctx context.Context, client *acme.Client, urls []string) error {
+	challenge := m.conf.Challenge
+	if challenge == "" {
+		challenge = ACMEHTTP01
+	}
+
+	var solver *http01Solver
+	if challenge == ACMEHTTP01 {
+		var err error
+		if solver, err = startHTTP01Solver(m.conf.HTTPAddr); err != nil {
+			return fmt.Errorf("kes: failed to listen for ACME HTTP-01 challenges: %v", err)
+		}
+		defer solver.Close()
+	}
+
+	for _, url := range urls {
+		authz, err := client.GetAuthorization(ctx, url)
+		if err != nil {
+			return fmt.Errorf("kes: failed to fetch ACME authorization: %v", err)
+		}
+		if authz.Status == acme.StatusValid {
+			continue
+		}
+
+		var chal *acme.Challenge
+		for _, c := range authz.Challenges {
+			if c.Type == string(challenge) {
+				chal = c
+				break
+			}
+		}
+		if chal == nil {
+			return fmt.Errorf("kes: ACME server offers no %s challenge for '%s'", challenge, authz.Identifier.Value)
+		}
+
+		switch challenge {
+		case ACMEHTTP01:
+			response, err := client.HTTP01ChallengeResponse(chal.Token)
+			if err != nil {
+				return err
+			}
+			solver.Set(client.HTTP01ChallengePath(chal.Token), response)
+		case ACMEDNS01:
+			value, err := client.DNS01ChallengeRecord(chal.Token)
+			if err != nil {
+				return err
+			}
+			fqdn := "_acme-challenge." + strings.TrimPrefix(authz.Identifier.Value, "*.") + "."
+			if err = m.conf.DNS.Present(ctx, fqdn, value); err != nil {
+				return fmt.Errorf("kes: failed to create DNS record '%s': %v", fqdn, err)
+			}
+			defer m.conf.DNS.CleanUp(context.WithoutCancel(ctx), fqdn, value)
+		}
+
+		if _, err = client.Accept(ctx, chal); err != nil {
+			return fmt.Errorf("kes: failed to accept ACME challenge for '%s': %v", authz.Identifier.Value, err)
+		}
+		if _, err = client.WaitAuthorization(ctx, authz.URI); err != nil {
+			return fmt.Errorf("kes: ACME authorization for '%s' failed: %v", authz.Identifier.Value, err)
+		}
+	}
+	return nil
+}
+
+// accountKey returns the ACME account key from the KeyStore.
+// It generates and stores a new key if none exists.
+func (m *acmeManager) accountKey(ctx context.Context) (crypto.Signer, error) {
+	data, err := m.store.Get(ctx, acmeAccountKey)
+	if err == nil {
+		block, _ := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("kes: invalid ACME account key")
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("kes: invalid ACME account key: %v", err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("kes: invalid ACME account key type '%T'", key)
+		}
+		return signer, nil
+	}
+	if !errors.Is(err, kes.ErrKeyNotFound) {
+		return nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.store.Create(ctx, acmeAccountKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})); err != nil {
+		if errors.Is(err, kes.ErrKeyExists) { // Created concurrently by another server
+			return m.accountKey(ctx)
+		}
+		return nil, err
+	}
+	return key, nil
+}
+
+// http01Solver serves the responses to ACME HTTP-01 challenges.
+type http01Solver struct {
+	srv *http.Server
+
+	lock      sync.RWMutex
+	responses map[string]string
+}
+
+// startHTTP01Solver starts a new http01Solver listening on addr.
+// If addr is empty, it listens on ":80".
+func startHTTP01Solver(addr string) (*http01Solver, error) {
+	if addr == "" {
+		addr = ":80"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	solver := &http01Solver{
+		responses: map[string]string{},
+	}
+	solver.srv = &http.Server{
+		Handler:           solver,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	go solver.srv.Serve(listener)
+	return solver, nil
+}
+
+// Set sets the response to challenges sent to the given path.
+func (s *http01Solver) Set(path, response string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.responses[path] = response
+}
+
+func (s *http01Solver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.lock.RLock()
+	response, ok := s.responses[r.URL.Path]
+	s.lock.RUnlock()
+
+	if !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.Write([]byte(response))
+}
+
+// Close stops the http01Solver.
+func (s *http01Solver) Close() error { return s.srv.Close() }
+
+// renewACMECertificate obtains the server certificate via ACME,
+// if enabled, and renews it before it expires until ctx is
+// canceled.
+func (s *Server) renewACMECertificate(ctx context.Context) {
+	const (
+		Delay      = 1 * time.Minute  // Delay between checks whether the certificate has to be renewed
+		RetryDelay = 15 * time.Minute // Delay after a failed attempt to not exceed CA rate limits
+		Timeout    = 5 * time.Minute  // Max. time for obtaining a certificate
+	)
+
+	var wait time.Duration
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		wait = Delay
+		state := s.state.Load()
+		if state.ACME == nil {
+			continue
+		}
+
+		renewCtx, cancel := context.WithTimeout(ctx, Timeout)
+		renewed, err := state.ACME.Renew(renewCtx)
+		cancel()
+		if err != nil {
+			state.Log.ErrorContext(ctx, err.Error())
+			wait = RetryDelay
+			continue
+		}
+		if renewed {
+			leaf := state.ACME.Certificate().Leaf
+			state.Log.InfoContext(ctx, "obtained ACME certificate", "domains", leaf.DNSNames, "expires", leaf.NotAfter)
+		}
+	}
+}
+
+// withACME returns conf if m is nil or conf contains a server
+// certificate. Otherwise, it returns a copy of conf that uses
+// the certificate obtained by m.
+func withACME(conf *tls.Config, m *acmeManager) *tls.Config {
+	if m == nil || len(conf.Certificates) > 0 || conf.GetCertificate != nil || conf.GetConfigForClient != nil {
+		return conf
+	}
+	conf = conf.Clone()
+	conf.GetCertificate = m.GetCertificate
+	return conf
+}
+
+// hideACMEKeys returns a KeyStore that hides the KeyStore
+// entries containing the ACME account key and certificate.
+func hideACMEKeys(store KeyStore) KeyStore { return &acmeKeyStore{store: store} }
+
+// acmeKeyStore is a KeyStore that hides all entries whose
+// names start with the acmeKeyPrefix.
+type acmeKeyStore struct {
+	store KeyStore
+}
+
+var _ KeyStore = (*acmeKeyStore)(nil) // compiler check
+
+func (ks *acmeKeyStore) String() string { return fmt.Sprint(ks.store) }
+
+// Unwrap returns the underlying KeyStore.
+func (ks *acmeKeyStore) Unwrap() KeyStore { return ks.store }
+
+func (ks *acmeKeyStore) Close() error { return ks.store.Close() }
+
+func (ks *acmeKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	return ks.store.Status(ctx)
+}
+
+func (ks *acmeKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	if strings.HasPrefix(name, acmeKeyPrefix) {
+		return kes.ErrKeyExists
+	}
+	return ks.store.Create(ctx, name, value)
+}
+
+func (ks *acmeKeyStore) Delete(ctx context.Context, name string) error {
+	if strings.HasPrefix(name, acmeKeyPrefix) {
+		return kes.ErrKeyNotFound
+	}
+	return ks.store.Delete(ctx, name)
+}
+
+func (ks *acmeKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if strings.HasPrefix(name, acmeKeyPrefix) {
+		return nil, kes.ErrKeyNotFound
+	}
+	return ks.store.Get(ctx, name)
+}
+
+func (ks *acmeKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	names, next, err := ks.store.List(ctx, prefix, n)
+	if err != nil {
+		return nil, "", err
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		return strings.HasPrefix(name, acmeKeyPrefix)
+	}), next, nil
+}
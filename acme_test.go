@@ -0,0 +1,180 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+)
+
+func TestACMEManagerLoad(t *testing.T) {
+	ctx := testContext(t)
+
+	now := time.Now()
+	store := &MemKeyStore{}
+	if err := store.Create(ctx, acmeCertificateKey, generateACMECertificate(t, now.Add(90*24*time.Hour), "kes.example.com")); err != nil {
+		t.Fatalf("Failed to store certificate: %v", err)
+	}
+
+	m := newACMEManager(&ACMEConfig{Domains: []string{"kes.example.com"}}, store)
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{}); err == nil {
+		t.Fatal("Getting a certificate before loading it should fail")
+	}
+	if err := m.Load(ctx); err != nil {
+		t.Fatalf("Failed to load certificate: %v", err)
+	}
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{}); err != nil {
+		t.Fatalf("Failed to get certificate: %v", err)
+	}
+	if m.needsRenewal(now) {
+		t.Fatal("Certificate should not need to be renewed")
+	}
+	if !m.needsRenewal(now.Add(61 * 24 * time.Hour)) {
+		t.Fatal("Certificate should be renewed 30 days before it expires")
+	}
+
+	m.conf.Domains = append(m.conf.Domains, "kes-2.example.com")
+	if !m.needsRenewal(now) {
+		t.Fatal("Certificate that does not cover all domains should be renewed")
+	}
+}
+
+func TestServerACMECertificate(t *testing.T) {
+	ctx := testContext(t)
+
+	store := &MemKeyStore{}
+	if err := store.Create(ctx, acmeCertificateKey, generateACMECertificate(t, time.Now().Add(90*24*time.Hour), "localhost")); err != nil {
+		t.Fatalf("Failed to store certificate: %v", err)
+	}
+	srv, url := startServer(ctx, &Config{
+		TLS: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequestClientCert,
+			NextProtos: []string{"h2", "http/1.1"},
+		},
+		ACME: &ACMEConfig{Domains: []string{"localhost"}},
+		Keys: store,
+	})
+	defer srv.Close()
+
+	conn, err := tls.Dial("tcp", strings.TrimPrefix(url, "https://"), &tls.Config{
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect to server: %v", err)
+	}
+	defer conn.Close()
+
+	if err = conn.ConnectionState().PeerCertificates[0].VerifyHostname("localhost"); err != nil {
+		t.Fatalf("Server does not use the ACME certificate: %v", err)
+	}
+}
+
+func TestACMEKeyStore(t *testing.T) {
+	ctx := testContext(t)
+
+	store := &MemKeyStore{}
+	for _, name := range []string{"my-key", acmeAccountKey, acmeCertificateKey} {
+		if err := store.Create(ctx, name, []byte(name)); err != nil {
+			t.Fatalf("Failed to create '%s': %v", name, err)
+		}
+	}
+
+	ks := hideACMEKeys(store)
+	names, _, err := ks.List(ctx, "", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.Equal(names, []string{"my-key"}) {
+		t.Fatalf("Listing keys: got '%v' - want '%v'", names, []string{"my-key"})
+	}
+	if _, err = ks.Get(ctx, acmeCertificateKey); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Getting ACME certificate: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err = ks.Delete(ctx, acmeAccountKey); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Deleting ACME account key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err = ks.Create(ctx, acmeKeyPrefix+"other", nil); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Creating reserved key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	if _, err = ks.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+}
+
+func TestVerifyConfigACME(t *testing.T) {
+	for i, test := range []struct {
+		ACME       *ACMEConfig
+		ShouldFail bool
+	}{
+		{ACME: &ACMEConfig{Domains: []string{"kes.example.com"}}},
+		{ACME: &ACMEConfig{Domains: []string{"kes.example.com"}, Challenge: ACMEDNS01, DNS: nopDNSProvider{}}},
+		{ACME: &ACMEConfig{}, ShouldFail: true},
+		{ACME: &ACMEConfig{Domains: []string{"https://kes.example.com"}}, ShouldFail: true},
+		{ACME: &ACMEConfig{Domains: []string{"kes.example.com"}, Directory: "http://acme.example.com"}, ShouldFail: true},
+		{ACME: &ACMEConfig{Domains: []string{"kes.example.com"}, Challenge: ACMEDNS01}, ShouldFail: true},
+		{ACME: &ACMEConfig{Domains: []string{"kes.example.com"}, Challenge: "tls-alpn-01"}, ShouldFail: true},
+	} {
+		conf := &Config{
+			TLS:  &tls.Config{ClientAuth: tls.RequestClientCert},
+			ACME: test.ACME,
+			Keys: &MemKeyStore{},
+		}
+		err := verifyConfig(conf)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify config: %v", i, err)
+		}
+	}
+}
+
+type nopDNSProvider struct{}
+
+func (nopDNSProvider) Present(context.Context, string, string) error { return nil }
+
+func (nopDNSProvider) CleanUp(context.Context, string, string) error { return nil }
+
+// generateACMECertificate returns a PEM-encoded private key and
+// self-signed certificate for the given domains, as stored by
+// an acmeManager.
+func generateACMECertificate(t *testing.T, notAfter time.Time, domains ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: domains[0]},
+		DNSNames:     domains,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	return append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})...)
+}
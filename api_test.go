@@ -5,18 +5,33 @@
 package kes
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdh"
 	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"runtime"
 	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"aead.dev/mem"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kms-go/kes"
+	"golang.org/x/crypto/hkdf"
 )
 
 func TestImportKey(t *testing.T) {
@@ -50,20 +65,37 @@ func TestAPI(t *testing.T) {
 	t.Run("v1/metrics", testMetrics)
 	t.Run("v1/api", testListAPIDefaults)
 	t.Run("v1/status", testStatus)
+	t.Run("v1/health", testHealth)
 	t.Run("v1/key/create", testCreateKey)
 	t.Run("v1/key/delete", testDeleteKey)
 	t.Run("v1/key/import", testImportKey)
+	t.Run("v1/key/rotate", testRotateKey)
+	t.Run("v1/key/export", testExportRestoreKey) // also tests restore
 	t.Run("v1/key/describe", testDescribeKey)
 	t.Run("v1/key/generate", testGenerateKey)
 	t.Run("v1/key/hmac", testHMAC)
-	t.Run("v1/key/encrypt", testEncryptDecryptKey) // also tests decryption
+	t.Run("v1/key/ecdh", testECDH)
+	t.Run("v1/key/sign", testSignVerify)                    // also tests verification
+	t.Run("v1/key/encrypt", testEncryptDecryptKey)          // also tests decryption
+	t.Run("v1/key/bulk/encrypt", testBulkEncryptDecryptKey) // also tests bulk decryption
 	t.Run("v1/key/list", testListKeys)
+	t.Run("v1/key/list-info", testListKeyInfos)
+	t.Run("v1/key/list?limit", testListKeyPages)
+	t.Run("v1/key/search", testSearchKeys)
 	t.Run("v1/identity/describe", testDescribeIdentity)
 	t.Run("v1/identity/list", testListIdentities)
+	t.Run("v1/identity/list-info", testListIdentityInfos)
+	t.Run("v1/identity/issue", testIssueIdentity)
 	t.Run("v1/identity/self/describe", testSelfDescribeIdentity)
 	t.Run("v1/policy/describe", testDescribePolicy)
 	t.Run("v1/policy/read", testReadPolicy)
 	t.Run("v1/policy/list", testListPolicies)
+	t.Run("v1/policy/list-info", testListPolicyInfos)
+	t.Run("v1/policy/assign", testAssignPolicy)
+	t.Run("v1/policy/test", testTestPolicy)
+	t.Run("v1/support/bundle", testSupportBundle)
+	t.Run("v1/debug/pprof", testDebugProfile)
+	t.Run("v1/admin/reload", testReload)
 }
 
 func testMetrics(t *testing.T) {
@@ -119,6 +151,245 @@ func testStatus(t *testing.T) {
 	if stat.HeapAlloc == 0 {
 		t.Fatal("Invalid status: allocated heap memory cannot be 0")
 	}
+
+	if err = client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = client.Encrypt(ctx, "my-key", []byte("Hello"), nil); err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	var status api.StatusResponse
+	getJSON(ctx, t, client, api.PathStatus, &status)
+	if status.KeyStoreType == "" {
+		t.Fatal("Invalid status: no keystore type")
+	}
+	if status.NumKeys != 1 || status.NumIdentities != 1 || status.NumPolicies != 0 {
+		t.Fatalf("Invalid status: got '%d' keys, '%d' identities and '%d' policies - want '1', '1' and '0'", status.NumKeys, status.NumIdentities, status.NumPolicies)
+	}
+	if status.CacheEntries == 0 || status.CacheHits+status.CacheMisses == 0 {
+		t.Fatalf("Invalid status: no cache statistics: %+v", status)
+	}
+}
+
+func testSupportBundle(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathSupportBundle, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to fetch support bundle: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to fetch support bundle: %s", resp.Status)
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("Invalid support bundle: %v", err)
+	}
+	var files []string
+	archive := tar.NewReader(gz)
+	for {
+		hdr, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Invalid support bundle: %v", err)
+		}
+		files = append(files, hdr.Name)
+	}
+	want := []string{"version.json", "status.json", "config.json", "metrics.txt", "error.log", "goroutines.txt"}
+	if !slices.Equal(files, want) {
+		t.Fatalf("Invalid support bundle: got '%v' - want '%v'", files, want)
+	}
+}
+
+func testHealth(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for _, path := range []string{api.PathHealthLive, api.PathHealthReady} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to probe '%s': %v", path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Probing '%s': got '%s' - want '%d'", path, resp.Status, http.StatusOK)
+		}
+	}
+}
+
+func testDebugProfile(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for _, test := range []struct {
+		Profile string
+		Status  int
+	}{
+		{Profile: "heap", Status: http.StatusOK},
+		{Profile: "goroutine", Status: http.StatusOK},
+		{Profile: "cpu?seconds=1", Status: http.StatusOK},
+		{Profile: "cpu?seconds=0", Status: http.StatusBadRequest},
+		{Profile: "cpu?seconds=3600", Status: http.StatusBadRequest},
+		{Profile: "unknown", Status: http.StatusNotFound},
+	} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathDebugProfile+test.Profile, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to fetch profile '%s': %v", test.Profile, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Fetching profile '%s': got '%s' - want '%d'", test.Profile, resp.Status, test.Status)
+		}
+	}
+}
+
+func testBulkEncryptDecryptKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	const KeyName = "my-key"
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to create key '%s': %v", KeyName, err)
+	}
+
+	send := func(path string, body, v any) int {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+path+KeyName, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	plaintexts := [][]byte{[]byte("Hello World"), {}, []byte("Hello Bulk")}
+	encReq := api.BulkEncryptKeyRequest{}
+	for _, p := range plaintexts {
+		encReq.Items = append(encReq.Items, api.EncryptKeyRequest{Plaintext: p, Context: []byte("ctx")})
+	}
+	var encResp api.BulkEncryptKeyResponse
+	if status := send(api.PathKeyBulkEncrypt, encReq, &encResp); status != http.StatusOK {
+		t.Fatalf("Failed to bulk encrypt: got status '%d'", status)
+	}
+	if len(encResp.Items) != len(plaintexts) {
+		t.Fatalf("Invalid number of results: got '%d' - want '%d'", len(encResp.Items), len(plaintexts))
+	}
+
+	decReq := api.BulkDecryptKeyRequest{}
+	for _, item := range encResp.Items {
+		if item.Error != "" {
+			t.Fatalf("Failed to encrypt item: %s", item.Error)
+		}
+		decReq.Items = append(decReq.Items, api.DecryptKeyRequest{Ciphertext: item.Ciphertext, Context: []byte("ctx")})
+	}
+	decReq.Items = append(decReq.Items, api.DecryptKeyRequest{Ciphertext: encResp.Items[0].Ciphertext, Context: []byte("wrong")})
+
+	var decResp api.BulkDecryptKeyResponse
+	if status := send(api.PathKeyBulkDecrypt, decReq, &decResp); status != http.StatusOK {
+		t.Fatalf("Failed to bulk decrypt: got status '%d'", status)
+	}
+	if len(decResp.Items) != len(decReq.Items) {
+		t.Fatalf("Invalid number of results: got '%d' - want '%d'", len(decResp.Items), len(decReq.Items))
+	}
+	for i, p := range plaintexts {
+		if item := decResp.Items[i]; item.Error != "" || !bytes.Equal(item.Plaintext, p) {
+			t.Fatalf("Item %d: got plaintext '%s' and error '%s' - want '%s'", i, item.Plaintext, item.Error, p)
+		}
+	}
+	if decResp.Items[len(plaintexts)].Error == "" {
+		t.Fatal("Decrypting with wrong context succeeded")
+	}
+
+	tooMany := api.BulkEncryptKeyRequest{Items: make([]api.EncryptKeyRequest, maxBulkItems+1)}
+	if status := send(api.PathKeyBulkEncrypt, tooMany, nil); status != http.StatusBadRequest {
+		t.Fatalf("Encrypting too many items: got status '%d' - want '%d'", status, http.StatusBadRequest)
+	}
+}
+
+func testReload(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	reload := func(url string) int {
+		client := defaultClient(url)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+api.PathAdminReload, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to reload server: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+	if code := reload(url); code != http.StatusNotImplemented {
+		t.Fatalf("Reload without reload func: got '%d' - want '%d'", code, http.StatusNotImplemented)
+	}
+
+	const Admin = "cb1d7d2fdb2c0ab1ef1ec4d4ec3d7b3f8f5ec5e3a30f15e5d3c45dfc84d38f13"
+	reloadSrv := &Server{ShutdownTimeout: -1}
+	reloadSrv.Reload = func(context.Context) error { return reloadSrv.UpdateAdmin(Admin) }
+	reloadSrv, url = startServerWith(ctx, reloadSrv, nil)
+	defer reloadSrv.Close()
+
+	if code := reload(url); code != http.StatusOK {
+		t.Fatalf("Reload failed: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := reload(url); code != http.StatusForbidden {
+		t.Fatalf("Reload after changing admin identity: got '%d' - want '%d'", code, http.StatusForbidden)
+	}
 }
 
 func testListAPIDefaults(t *testing.T) {
@@ -127,32 +398,85 @@ func testListAPIDefaults(t *testing.T) {
 		MaxBody mem.Size
 		Timeout time.Duration
 	}{
-		"/version":    {Method: http.MethodGet, MaxBody: 0, Timeout: 10 * time.Second},
-		"/v1/ready":   {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/status":  {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/metrics": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/api":     {Method: http.MethodGet, MaxBody: 0, Timeout: 10 * time.Second},
-
-		"/v1/key/create/":   {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/key/import/":   {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
-		"/v1/key/describe/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/key/list/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/key/delete/":   {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/key/generate/": {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
-		"/v1/key/encrypt/":  {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
-		"/v1/key/decrypt/":  {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
-		"/v1/key/hmac/":     {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
-
-		"/v1/policy/describe/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/policy/read/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-		"/v1/policy/list/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/version":  {Method: http.MethodGet, MaxBody: 0, Timeout: 10 * time.Second},
+		"/v1/ready": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+
+		"/v1/health/live":  {Method: http.MethodGet, MaxBody: 0, Timeout: 10 * time.Second},
+		"/v1/health/ready": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/status":       {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/metrics":      {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/api":          {Method: http.MethodGet, MaxBody: 0, Timeout: 10 * time.Second},
+
+		"/v1/key/create/":       {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/import/":       {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/export/":       {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/restore/":      {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/rotate/":       {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/key/describe/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/key/list/":         {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/key/list-info/":    {Method: http.MethodGet, MaxBody: 0, Timeout: 30 * time.Second},
+		"/v1/key/search/":       {Method: http.MethodGet, MaxBody: 0, Timeout: 30 * time.Second},
+		"/v1/key/delete/":       {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/key/undelete/":     {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/key/generate/":     {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/encrypt/":      {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/decrypt/":      {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/hmac/":         {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/ecdh/":         {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/sign/":         {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/verify/":       {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/key/bulk/encrypt/": {Method: http.MethodPut, MaxBody: 8 * mem.MB, Timeout: 30 * time.Second},
+		"/v1/key/bulk/decrypt/": {Method: http.MethodPut, MaxBody: 8 * mem.MB, Timeout: 30 * time.Second},
+
+		"/v1/ssh/sign/": {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/ssh/ca/":   {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+
+		"/v1/cert/issue/": {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/cert/ca/":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+
+		"/v1/secret/set/":    {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/secret/get/":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/secret/list/":   {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/secret/delete/": {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+
+		"/v1/policy/describe/":  {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/policy/read/":      {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/policy/list/":      {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/policy/list-info/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/policy/create/":    {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/policy/delete/":    {Method: http.MethodDelete, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/policy/assign/":    {Method: http.MethodPut, MaxBody: 1 * mem.MB, Timeout: 15 * time.Second},
+		"/v1/policy/test/":      {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 
 		"/v1/identity/describe/":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 		"/v1/identity/self/describe": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 		"/v1/identity/list/":         {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
-
-		"/v1/log/error": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
-		"/v1/log/audit": {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+		"/v1/identity/list-info/":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/identity/issue/":        {Method: http.MethodPut, MaxBody: 1 * mem.KiB, Timeout: 15 * time.Second},
+
+		"/v1/log/error":        {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+		"/v1/log/audit":        {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+		"/v1/log/audit/proof/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/log/audit/query":  {Method: http.MethodGet, MaxBody: 0, Timeout: 60 * time.Second},
+		"/v1/watch":            {Method: http.MethodGet, MaxBody: 0, Timeout: 0},
+
+		"/v1/support/bundle":  {Method: http.MethodGet, MaxBody: 0, Timeout: 30 * time.Second},
+		"/v1/debug/pprof/":    {Method: http.MethodGet, MaxBody: 0, Timeout: 6 * time.Minute},
+		"/v1/admin/reload":    {Method: http.MethodPost, MaxBody: 0, Timeout: 60 * time.Second},
+		"/v1/admin/unseal":    {Method: http.MethodPut, MaxBody: 1 * mem.KiB, Timeout: 15 * time.Second},
+		"/v1/admin/freeze":    {Method: http.MethodPut, MaxBody: 1 * mem.KiB, Timeout: 15 * time.Second},
+		"/v1/admin/log-level": {Method: http.MethodPut, MaxBody: 1 * mem.KiB, Timeout: 15 * time.Second},
+
+		"/v1/approval/list":     {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/approval/approve/": {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/approval/deny/":    {Method: http.MethodPut, MaxBody: 0, Timeout: 15 * time.Second},
+
+		"/v1/cluster/raft":    {Method: http.MethodPost, MaxBody: 256 * mem.MiB, Timeout: 60 * time.Second},
+		"/v1/cluster/list":    {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
+		"/v1/cluster/add":     {Method: http.MethodPost, MaxBody: 1 * mem.KiB, Timeout: 30 * time.Second},
+		"/v1/cluster/remove/": {Method: http.MethodDelete, MaxBody: 0, Timeout: 30 * time.Second},
+
+		"/v1/replica/key/": {Method: http.MethodGet, MaxBody: 0, Timeout: 15 * time.Second},
 	}
 
 	t.Parallel()
@@ -252,6 +576,139 @@ func testImportKey(t *testing.T) {
 	}
 }
 
+func testRotateKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyRotate+"my-key2", nil); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Rotating non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	var ciphertexts [][]byte
+	for i := 1; i <= 3; i++ {
+		if i > 1 {
+			if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyRotate+"my-key", nil); err != nil {
+				t.Fatalf("Failed to rotate key: %v", err)
+			}
+		}
+		ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+		if err != nil {
+			t.Fatalf("Failed to encrypt with key version %d: %v", i, err)
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+	}
+	for i, ciphertext := range ciphertexts {
+		plaintext, err := client.Decrypt(ctx, "my-key", ciphertext, nil)
+		if err != nil {
+			t.Fatalf("Failed to decrypt ciphertext of key version %d: %v", i+1, err)
+		}
+		if string(plaintext) != "Hello World" {
+			t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathKeyDescribe+"my-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to describe key: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info api.DescribeKeyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Failed to decode key description: %v", err)
+	}
+	if info.Version != 3 || len(info.Versions) != 3 {
+		t.Fatalf("Invalid key versions: got version '%d' with %d versions - want version '3' with 3 versions", info.Version, len(info.Versions))
+	}
+}
+
+func testExportRestoreKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for _, name := range []string{"my-key", "my-kek"} {
+		if err := client.CreateKey(ctx, name); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyRotate+"my-key", nil); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyExport+"my-key", api.ExportKeyRequest{KEK: "my-key"}); err == nil {
+		t.Fatal("Exporting key wrapped by itself succeeded")
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyExport+"my-key", api.ExportKeyRequest{KEK: "my-kek2"}); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Exporting key wrapped by non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	body, err := json.Marshal(api.ExportKeyRequest{KEK: "my-kek"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathKeyExport+"my-key", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to export key: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to export key: %s", resp.Status)
+	}
+
+	var export api.ExportKeyResponse
+	if err = json.NewDecoder(resp.Body).Decode(&export); err != nil {
+		t.Fatalf("Failed to decode exported key: %v", err)
+	}
+	if export.Name != "my-key" || export.KEK != "my-kek" || export.Version != 2 {
+		t.Fatalf("Invalid exported key: got name '%s', KEK '%s' and version '%d'", export.Name, export.KEK, export.Version)
+	}
+
+	restore := api.RestoreKeyRequest{KEK: export.KEK, Key: export.Key}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyRestore+"my-key", restore); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Restoring existing key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	if err = client.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyRestore+"my-key2", restore); !errors.Is(err, kes.ErrDecrypt) {
+		t.Fatalf("Restoring key under different name: got '%v' - want '%v'", err, kes.ErrDecrypt)
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyRestore+"my-key", restore); err != nil {
+		t.Fatalf("Failed to restore key: %v", err)
+	}
+
+	plaintext, err := client.Decrypt(ctx, "my-key", ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt with previous version of restored key: %v", err)
+	}
+	if string(plaintext) != "Hello World" {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+	}
+}
+
 func testDescribeKey(t *testing.T) {
 	t.Parallel()
 
@@ -337,6 +794,180 @@ func testGenerateKey(t *testing.T) {
 	}
 }
 
+func testECDH(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	const KeyName = "my-key"
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to create key '%s': %v", KeyName, err)
+	}
+
+	ecdhKey := func(body api.ECDHKeyRequest) (api.ECDHKeyResponse, int) {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathKeyECDH+KeyName, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var response api.ECDHKeyResponse
+		if resp.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return response, resp.StatusCode
+	}
+
+	peer, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, status := ecdhKey(api.ECDHKeyRequest{})
+	if status != http.StatusOK {
+		t.Fatalf("Failed to fetch public key: got status '%d'", status)
+	}
+	if len(resp.Key) != 0 {
+		t.Fatal("Derived key without peer public key")
+	}
+	publicKey, err := ecdh.P256().NewPublicKey(resp.PublicKey)
+	if err != nil {
+		t.Fatalf("Invalid public key: %v", err)
+	}
+
+	resp, status = ecdhKey(api.ECDHKeyRequest{PublicKey: peer.PublicKey().Bytes(), Info: []byte("my-app")})
+	if status != http.StatusOK {
+		t.Fatalf("Failed to perform key agreement: got status '%d'", status)
+	}
+	if !publicKey.Equal(mustPublicKey(t, resp.PublicKey)) {
+		t.Fatal("Public key is not stable")
+	}
+	secret, err := peer.ECDH(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte("my-app")), want); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(resp.Key, want) {
+		t.Fatalf("Derived keys do not match: got '%x' - want '%x'", resp.Key, want)
+	}
+
+	if _, status = ecdhKey(api.ECDHKeyRequest{PublicKey: []byte("invalid")}); status != http.StatusBadRequest {
+		t.Fatalf("Key agreement with invalid public key: got status '%d' - want '%d'", status, http.StatusBadRequest)
+	}
+}
+
+func mustPublicKey(t *testing.T, b []byte) *ecdh.PublicKey {
+	key, err := ecdh.P256().NewPublicKey(b)
+	if err != nil {
+		t.Fatalf("Invalid public key: %v", err)
+	}
+	return key
+}
+
+func testSignVerify(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	const KeyName = "my-key"
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, KeyName); err != nil {
+		t.Fatalf("Failed to create key '%s': %v", KeyName, err)
+	}
+
+	send := func(path string, body, v any) int {
+		b, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+path+KeyName, bytes.NewReader(b))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	message := []byte("Hello World")
+	for _, alg := range []string{"", "ECDSA-P256", "Ed25519"} {
+		var signed api.SignKeyResponse
+		if status := send(api.PathKeySign, api.SignKeyRequest{Message: message, Algorithm: alg}, &signed); status != http.StatusOK {
+			t.Fatalf("Algorithm '%s': failed to sign message: got status '%d'", alg, status)
+		}
+		if _, err := x509.ParsePKIXPublicKey(signed.PublicKey); err != nil {
+			t.Fatalf("Algorithm '%s': invalid public key: %v", alg, err)
+		}
+
+		var verified api.VerifyKeyResponse
+		if status := send(api.PathKeyVerify, api.VerifyKeyRequest{Message: message, Signature: signed.Signature, Algorithm: alg}, &verified); status != http.StatusOK {
+			t.Fatalf("Algorithm '%s': failed to verify signature: got status '%d'", alg, status)
+		}
+		if !verified.Valid {
+			t.Fatalf("Algorithm '%s': signature is not valid", alg)
+		}
+		if status := send(api.PathKeyVerify, api.VerifyKeyRequest{Message: []byte("Hello World!"), Signature: signed.Signature, Algorithm: alg}, &verified); status != http.StatusOK {
+			t.Fatalf("Algorithm '%s': failed to verify signature: got status '%d'", alg, status)
+		}
+		if verified.Valid {
+			t.Fatalf("Algorithm '%s': signature of different message is valid", alg)
+		}
+	}
+
+	if status := send(api.PathKeySign, api.SignKeyRequest{Message: message, Algorithm: "RSA"}, nil); status != http.StatusBadRequest {
+		t.Fatalf("Signing with invalid algorithm: got status '%d' - want '%d'", status, http.StatusBadRequest)
+	}
+
+	// Rotating the key rotates its signing key. Signatures of
+	// previous versions remain valid.
+	var signed, rotated api.SignKeyResponse
+	if status := send(api.PathKeySign, api.SignKeyRequest{Message: message}, &signed); status != http.StatusOK {
+		t.Fatalf("Failed to sign message: got status '%d'", status)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyRotate+KeyName, nil); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if status := send(api.PathKeySign, api.SignKeyRequest{Message: message}, &rotated); status != http.StatusOK {
+		t.Fatalf("Failed to sign message: got status '%d'", status)
+	}
+	if bytes.Equal(signed.PublicKey, rotated.PublicKey) {
+		t.Fatal("Public key has not changed after rotating the key")
+	}
+	var verified api.VerifyKeyResponse
+	if status := send(api.PathKeyVerify, api.VerifyKeyRequest{Message: message, Signature: signed.Signature}, &verified); status != http.StatusOK {
+		t.Fatalf("Failed to verify signature: got status '%d'", status)
+	}
+	if !verified.Valid {
+		t.Fatal("Signature of previous key version is not valid")
+	}
+}
+
 func testHMAC(t *testing.T) {
 	t.Parallel()
 
@@ -384,81 +1015,288 @@ func testHMAC(t *testing.T) {
 	}
 }
 
-func testEncryptDecryptKey(t *testing.T) {
+func testEncryptDecryptKey(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	plaintext := make([]byte, mem.KB)
+	associatedData := make([]byte, 80)
+
+	client := defaultClient(url)
+	for i, test := range validNameTests {
+		err := client.CreateKey(ctx, test.Name)
+		if err == nil && test.ShouldFail {
+			t.Errorf("Test %d: setup: creating key '%s' should have failed", i, test.Name)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Errorf("Test %d: setup: failed to create key '%s': %v", i, test.Name, err)
+		}
+
+		if test.ShouldFail {
+			continue
+		}
+
+		ciphertext, err := client.Encrypt(ctx, test.Name, plaintext, associatedData)
+		if err != nil {
+			t.Errorf("Test %d: failed to encrypt with key '%s': %v", i, test.Name, err)
+		}
+		ptext, err := client.Decrypt(ctx, test.Name, ciphertext, associatedData)
+		if err != nil {
+			t.Errorf("Test %d: failed to decrypt with key '%s': %v", i, test.Name, err)
+		}
+		if !bytes.Equal(ptext, plaintext) {
+			t.Errorf("Test %d: plaintext mismatch: got %v - want %v", i, ptext, plaintext)
+		}
+
+		ctext, err := client.Encrypt(ctx, test.Name, plaintext, associatedData)
+		if err != nil {
+			t.Errorf("Test %d: failed to encrypt with key '%s': %v", i, test.Name, err)
+		}
+		if bytes.Equal(ctext, ciphertext) {
+			t.Errorf("Test %d: encryption is deterministic and produces the same ciphertexts", i)
+		}
+	}
+}
+
+func testListKeys(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	var names []string
+	client := defaultClient(url)
+	for i, test := range validNameTests {
+		err := client.CreateKey(ctx, test.Name)
+		if err == nil && test.ShouldFail {
+			t.Errorf("Test %d: setup: creating key '%s' should have failed", i, test.Name)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Errorf("Test %d: setup: failed to create key '%s': %v", i, test.Name, err)
+		}
+		if !test.ShouldFail {
+			names = append(names, test.Name)
+		}
+	}
+	slices.Sort(names)
+
+	keys, _, err := client.ListKeys(ctx, "", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.Equal(names, keys) {
+		t.Fatalf("Failed to list keys: got %v - want %v", keys, names)
+	}
+}
+
+func testListKeyPages(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for i := 0; i < 250; i++ {
+		name := fmt.Sprintf("key-%03d", i)
+		if i%2 == 0 {
+			name = fmt.Sprintf("key-%03d-even", i)
+		}
+		if err := client.CreateKey(ctx, name); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err := client.CreateKey(ctx, "other-key"); err != nil {
+		t.Fatalf("Failed to create key 'other-key': %v", err)
+	}
+
+	listAll := func(query string) []string {
+		var (
+			names []string
+			path  = api.PathKeyList + "key-*?" + query
+		)
+		for {
+			var list api.ListKeysResponse
+			getJSON(ctx, t, client, path, &list)
+			if len(list.Names) > 40 {
+				t.Fatalf("Page contains '%d' names - want at most '40'", len(list.Names))
+			}
+			names = append(names, list.Names...)
+			if list.ContinueAt == "" {
+				return names
+			}
+			path = api.PathKeyList + "key-*?" + query + "&continue=" + list.ContinueAt
+		}
+	}
+
+	names := listAll("limit=40")
+	if len(names) != 250 {
+		t.Fatalf("Invalid number of keys: got '%d' - want '%d'", len(names), 250)
+	}
+	if !slices.IsSorted(names) || len(slices.Compact(slices.Clone(names))) != len(names) {
+		t.Fatalf("Listing is not sorted or contains duplicates: %v", names)
+	}
+
+	reversed := listAll("limit=40&order=desc")
+	slices.Reverse(reversed)
+	if !slices.Equal(names, reversed) {
+		t.Fatalf("Descending listing does not match: got %v - want %v", reversed, names)
+	}
+
+	even := listAll("limit=40&match=key-*-even")
+	if len(even) != 125 {
+		t.Fatalf("Invalid number of matching keys: got '%d' - want '%d'", len(even), 125)
+	}
+	for _, name := range even {
+		if !strings.HasSuffix(name, "-even") {
+			t.Fatalf("Key '%s' does not match pattern", name)
+		}
+	}
+
+	for _, query := range []string{"limit=0", "limit=-1", "limit=abc", "match=[", "order=up", "unknown=1"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathKeyList+"*?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to list keys: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Query '%s': invalid status code: got '%d' - want '%d'", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
+func testListKeyInfos(t *testing.T) {
 	t.Parallel()
 
 	ctx := testContext(t)
 	srv, url := startServer(ctx, nil)
 	defer srv.Close()
 
-	plaintext := make([]byte, mem.KB)
-	associatedData := make([]byte, 80)
-
 	client := defaultClient(url)
-	for i, test := range validNameTests {
-		err := client.CreateKey(ctx, test.Name)
-		if err == nil && test.ShouldFail {
-			t.Errorf("Test %d: setup: creating key '%s' should have failed", i, test.Name)
-		}
-		if err != nil && !test.ShouldFail {
-			t.Errorf("Test %d: setup: failed to create key '%s': %v", i, test.Name, err)
-		}
-
-		if test.ShouldFail {
-			continue
-		}
-
-		ciphertext, err := client.Encrypt(ctx, test.Name, plaintext, associatedData)
-		if err != nil {
-			t.Errorf("Test %d: failed to encrypt with key '%s': %v", i, test.Name, err)
-		}
-		ptext, err := client.Decrypt(ctx, test.Name, ciphertext, associatedData)
-		if err != nil {
-			t.Errorf("Test %d: failed to decrypt with key '%s': %v", i, test.Name, err)
-		}
-		if !bytes.Equal(ptext, plaintext) {
-			t.Errorf("Test %d: plaintext mismatch: got %v - want %v", i, ptext, plaintext)
-		}
+	tags := map[string]map[string]string{
+		"key-0": nil,
+		"key-1": {"team": "payments"},
+		"key-2": {"team": "storage", "env": "prod"},
+	}
+	for name, tags := range tags {
+		createKeyWithTags(ctx, t, client, name, tags)
+	}
 
-		ctext, err := client.Encrypt(ctx, test.Name, plaintext, associatedData)
-		if err != nil {
-			t.Errorf("Test %d: failed to encrypt with key '%s': %v", i, test.Name, err)
+	var list api.ListKeyInfosResponse
+	getJSON(ctx, t, client, api.PathKeyListInfo+"key-*", &list)
+	if len(list.Keys) != len(tags) {
+		t.Fatalf("Failed to list keys: got %d keys - want %d", len(list.Keys), len(tags))
+	}
+	for _, key := range list.Keys {
+		if key.Algorithm == "" || key.CreatedAt.IsZero() || key.CreatedBy == "" {
+			t.Fatalf("Key '%s': missing metadata: %+v", key.Name, key)
 		}
-		if bytes.Equal(ctext, ciphertext) {
-			t.Errorf("Test %d: encryption is deterministic and produces the same ciphertexts", i)
+		if !maps.Equal(key.Tags, tags[key.Name]) {
+			t.Fatalf("Key '%s': invalid tags: got '%v' - want '%v'", key.Name, key.Tags, tags[key.Name])
 		}
 	}
 }
 
-func testListKeys(t *testing.T) {
+func testSearchKeys(t *testing.T) {
 	t.Parallel()
 
 	ctx := testContext(t)
 	srv, url := startServer(ctx, nil)
 	defer srv.Close()
 
-	var names []string
 	client := defaultClient(url)
-	for i, test := range validNameTests {
-		err := client.CreateKey(ctx, test.Name)
-		if err == nil && test.ShouldFail {
-			t.Errorf("Test %d: setup: creating key '%s' should have failed", i, test.Name)
+	for i := 0; i < 250; i++ {
+		tags := map[string]string{"team": "storage"}
+		if i%2 == 0 {
+			tags["team"] = "payments"
 		}
-		if err != nil && !test.ShouldFail {
-			t.Errorf("Test %d: setup: failed to create key '%s': %v", i, test.Name, err)
+		createKeyWithTags(ctx, t, client, fmt.Sprintf("key-%03d", i), tags)
+	}
+
+	var (
+		names []string
+		path  = api.PathKeySearch + "key-*?tag=team:payments"
+	)
+	for {
+		var list api.ListKeyInfosResponse
+		getJSON(ctx, t, client, path, &list)
+		for _, key := range list.Keys {
+			if key.Tags["team"] != "payments" {
+				t.Fatalf("Key '%s' does not match search: %v", key.Name, key.Tags)
+			}
+			names = append(names, key.Name)
 		}
-		if !test.ShouldFail {
-			names = append(names, test.Name)
+		if list.ContinueAt == "" {
+			break
 		}
+		path = api.PathKeySearch + "key-*?tag=team:payments&continue=" + list.ContinueAt
+	}
+	if len(names) != 125 {
+		t.Fatalf("Invalid number of search results: got '%d' - want '%d'", len(names), 125)
+	}
+	if !slices.IsSorted(names) || len(slices.Compact(slices.Clone(names))) != len(names) {
+		t.Fatalf("Search results are not sorted or contain duplicates: %v", names)
 	}
-	slices.Sort(names)
 
-	keys, _, err := client.ListKeys(ctx, "", -1)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathKeySearch+"*?unknown=1", nil)
 	if err != nil {
-		t.Fatalf("Failed to list keys: %v", err)
+		t.Fatal(err)
 	}
-	if !slices.Equal(names, keys) {
-		t.Fatalf("Failed to list keys: got %v - want %v", keys, names)
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to search keys: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+// createKeyWithTags creates a new key with the given tags.
+func createKeyWithTags(ctx context.Context, t *testing.T, client *kes.Client, name string, tags map[string]string) {
+	body, err := json.Marshal(api.CreateKeyRequest{Tags: tags})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, client.Endpoints[0]+api.PathKeyCreate+name, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to create key '%s': %v", name, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to create key '%s': %s", name, resp.Status)
+	}
+}
+
+// getJSON sends a GET request to the API path and decodes
+// the JSON response into v.
+func getJSON(ctx context.Context, t *testing.T, client *kes.Client, path string, v any) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.Endpoints[0]+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request to '%s': %v", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to send request to '%s': %s", path, resp.Status)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("Failed to decode response from '%s': %v", path, err)
 	}
 }
 
@@ -471,7 +1309,7 @@ func testDescribeIdentity(t *testing.T) {
 
 	var identities []kes.Identity
 	for _, test := range validNameTests {
-		if validName(test.Name) {
+		if defaultNameRules.ValidName(test.Name) {
 			identities = append(identities, kes.Identity(test.Name))
 		}
 	}
@@ -528,6 +1366,75 @@ func testListIdentities(t *testing.T) {
 	}
 }
 
+func testListIdentityInfos(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	err := srv.UpdatePolicies(map[string]Policy{
+		"policy-a": {Identities: []kes.Identity{"identity-1", "identity-2"}},
+		"policy-b": {Identities: []kes.Identity{"identity-3"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+
+	client := defaultClient(url)
+	start := time.Now()
+	body, err := json.Marshal(api.AssignPolicyRequest{Identities: []string{"identity-2"}, TTL: "1h"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathPolicyAssign+"policy-b", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to assign policy: %s", resp.Status)
+	}
+
+	var list api.ListIdentityInfosResponse
+	getJSON(ctx, t, client, api.PathIdentityListInfo+"identity-*", &list)
+	if len(list.Identities) != 3 {
+		t.Fatalf("Failed to list identities: got %d identities - want %d", len(list.Identities), 3)
+	}
+	if id := list.Identities[1]; id.Identity != "identity-2" || id.Policy != "policy-b" || id.CreatedBy != defaultIdentity || id.CreatedAt.Before(start) || id.ExpiresAt.IsZero() {
+		t.Fatalf("Identity 'identity-2': invalid metadata: %+v", id)
+	}
+
+	getJSON(ctx, t, client, api.PathIdentityListInfo+"*?policy=policy-b", &list)
+	if len(list.Identities) != 2 || list.Identities[0].Identity != "identity-2" || list.Identities[1].Identity != "identity-3" {
+		t.Fatalf("Failed to filter identities by policy: got %+v", list.Identities)
+	}
+
+	getJSON(ctx, t, client, api.PathIdentityListInfo+"*?created_after="+start.UTC().Format(time.RFC3339Nano), &list)
+	if len(list.Identities) != 1 || list.Identities[0].Identity != "identity-2" {
+		t.Fatalf("Failed to filter identities by creation time: got %+v", list.Identities)
+	}
+
+	for _, query := range []string{"created_after=yesterday", "unknown=1"} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathIdentityListInfo+"*?"+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to list identities: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("Query '%s': invalid status code: got '%d' - want '%d'", query, resp.StatusCode, http.StatusBadRequest)
+		}
+	}
+}
+
 func testSelfDescribeIdentity(t *testing.T) {
 	t.Parallel()
 
@@ -557,7 +1464,7 @@ func testDescribePolicy(t *testing.T) {
 
 	policies := make(map[string]Policy)
 	for _, test := range validNameTests {
-		if validName(test.Name) {
+		if defaultNameRules.ValidName(test.Name) {
 			policies[test.Name] = Policy{}
 		}
 	}
@@ -574,7 +1481,7 @@ func testDescribePolicy(t *testing.T) {
 		if err != nil && !test.ShouldFail {
 			t.Errorf("Test %d: failed to describe policy '%s': %v", i, test.Name, err)
 		}
-		if !validName(test.Name) && errors.Is(err, kes.ErrPolicyNotFound) {
+		if !defaultNameRules.ValidName(test.Name) && errors.Is(err, kes.ErrPolicyNotFound) {
 			t.Errorf("Test %d: received %v for invalid policy name '%s'", i, err, test.Name)
 		}
 
@@ -588,6 +1495,388 @@ func testDescribePolicy(t *testing.T) {
 	}
 }
 
+func testAssignPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	err := srv.UpdatePolicies(map[string]Policy{
+		"policy-a": {Identities: []kes.Identity{"identity-1", "identity-2"}},
+		"policy-b": {Identities: []kes.Identity{"identity-3"}},
+		"policy-c": {},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+
+	client := defaultClient(url)
+	for i, test := range assignPolicyTests {
+		body, err := json.Marshal(test.Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathPolicyAssign+test.Policy, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: failed to assign policy: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d'", i, resp.StatusCode, test.Status)
+		}
+
+		for id, want := range test.Assignments {
+			info, err := client.DescribeIdentity(ctx, id)
+			if err != nil {
+				t.Fatalf("Test %d: failed to describe identity '%s': %v", i, id, err)
+			}
+			if info.Policy != want {
+				t.Fatalf("Test %d: identity '%s': got policy '%s' - want '%s'", i, id, info.Policy, want)
+			}
+		}
+	}
+
+	// Identities assigned with a TTL get revoked once they expire.
+	if expiresAt := srv.state.Load().Identities["identity-5"].ExpiresAt; expiresAt.IsZero() {
+		t.Fatal("Identity 'identity-5' has no expiry")
+	}
+	revoked := srv.revokeExpired(ctx, time.Now().Add(2*time.Hour))
+	if !slices.Equal(revoked, []kes.Identity{"identity-5"}) {
+		t.Fatalf("Invalid revoked identities: got '%v' - want '[identity-5]'", revoked)
+	}
+	if _, err := client.DescribeIdentity(ctx, "identity-5"); !errors.Is(err, kes.ErrIdentityNotFound) {
+		t.Fatalf("Revoked identity 'identity-5' still exists: %v", err)
+	}
+
+	// An identity that may assign policies cannot assign one to itself.
+	assignerKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := kes.GenerateCertificate(assignerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	assigner := kes.NewClientWithConfig(url, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{cert},
+	})
+	err = srv.UpdatePolicies(map[string]Policy{
+		"assigners": {
+			Allow:      map[string]kes.Rule{api.PathPolicyAssign + "*": {}},
+			Identities: []kes.Identity{assignerKey.Identity()},
+		},
+		"policy-c": {},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+	for i, test := range []struct {
+		Request api.AssignPolicyRequest
+		Status  int
+	}{
+		{Request: api.AssignPolicyRequest{Identities: []string{assignerKey.Identity().String()}}, Status: http.StatusForbidden},
+		{Request: api.AssignPolicyRequest{FromPolicy: "assigners"}, Status: http.StatusForbidden},
+		{Request: api.AssignPolicyRequest{Identities: []string{"identity-6"}}, Status: http.StatusOK},
+	} {
+		body, err := json.Marshal(test.Request)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathPolicyAssign+"policy-c", bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := assigner.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Self-assignment test %d: failed to assign policy: %v", i, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Self-assignment test %d: invalid status code: got '%d' - want '%d'", i, resp.StatusCode, test.Status)
+		}
+	}
+	if info, err := client.DescribeIdentity(ctx, assignerKey.Identity()); err != nil || info.Policy != "assigners" {
+		t.Fatalf("Identity has assigned a policy to itself: got '%v' - want policy 'assigners': %v", info, err)
+	}
+}
+
+var assignPolicyTests = []struct {
+	Policy      string
+	Request     api.AssignPolicyRequest
+	Status      int
+	Assignments map[kes.Identity]string
+}{
+	{ // 0
+		Policy:  "policy-c",
+		Request: api.AssignPolicyRequest{Identities: []string{"identity-4"}, FromPolicy: "policy-a"},
+		Status:  http.StatusOK,
+		Assignments: map[kes.Identity]string{
+			"identity-1": "policy-c",
+			"identity-2": "policy-c",
+			"identity-3": "policy-b",
+			"identity-4": "policy-c",
+		},
+	},
+	{ // 1
+		Policy:      "policy-b",
+		Request:     api.AssignPolicyRequest{Identities: []string{"identity-1"}},
+		Status:      http.StatusOK,
+		Assignments: map[kes.Identity]string{"identity-1": "policy-b", "identity-2": "policy-c"},
+	},
+	{ // 2
+		Policy:      "policy-d",
+		Request:     api.AssignPolicyRequest{Identities: []string{"identity-2"}},
+		Status:      http.StatusNotFound,
+		Assignments: map[kes.Identity]string{"identity-2": "policy-c"},
+	},
+	{ // 3
+		Policy:      "policy-a",
+		Request:     api.AssignPolicyRequest{Identities: []string{"identity-2", defaultIdentity}},
+		Status:      http.StatusBadRequest,
+		Assignments: map[kes.Identity]string{"identity-2": "policy-c"},
+	},
+	{ // 4
+		Policy:  "policy-a",
+		Request: api.AssignPolicyRequest{},
+		Status:  http.StatusBadRequest,
+	},
+	{ // 5
+		Policy:      "policy-a",
+		Request:     api.AssignPolicyRequest{Identities: []string{"identity-5"}, TTL: "1h"},
+		Status:      http.StatusOK,
+		Assignments: map[kes.Identity]string{"identity-5": "policy-a"},
+	},
+	{ // 6
+		Policy:  "policy-a",
+		Request: api.AssignPolicyRequest{Identities: []string{"identity-6"}, TTL: "-1h"},
+		Status:  http.StatusBadRequest,
+	},
+	{ // 7
+		Policy:  "policy-a",
+		Request: api.AssignPolicyRequest{Identities: []string{"identity-6"}, TTL: "soon"},
+		Status:  http.StatusBadRequest,
+	},
+}
+
+func testIssueIdentity(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	err := srv.UpdatePolicies(map[string]Policy{
+		"ci": {Allow: map[string]kes.Rule{api.PathKeyCreate + "ci-*": {}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+
+	client := defaultClient(url)
+	issue := func(policy, ttl string) (*api.IssueIdentityResponse, int) {
+		body, err := json.Marshal(api.IssueIdentityRequest{TTL: ttl})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathIdentityIssue+policy, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to issue identity: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var issued api.IssueIdentityResponse
+		if resp.StatusCode == http.StatusOK {
+			if err = json.NewDecoder(resp.Body).Decode(&issued); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return &issued, resp.StatusCode
+	}
+
+	for i, test := range []struct {
+		Policy string
+		TTL    string
+		Status int
+	}{
+		{Policy: "unknown", TTL: "1h", Status: http.StatusNotFound},
+		{Policy: "ci", TTL: "", Status: http.StatusBadRequest},
+		{Policy: "ci", TTL: "-1h", Status: http.StatusBadRequest},
+		{Policy: "ci", TTL: "soon", Status: http.StatusBadRequest},
+	} {
+		if _, status := issue(test.Policy, test.TTL); status != test.Status {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d'", i, status, test.Status)
+		}
+	}
+
+	issued, status := issue("ci", "1h")
+	if status != http.StatusOK {
+		t.Fatalf("Failed to issue identity: got status '%d' - want '%d'", status, http.StatusOK)
+	}
+	key, err := kes.ParseAPIKey(issued.APIKey)
+	if err != nil {
+		t.Fatalf("Failed to parse issued API key: %v", err)
+	}
+	if key.Identity().String() != issued.Identity {
+		t.Fatalf("Identity mismatch: got '%s' - want '%s'", issued.Identity, key.Identity())
+	}
+	if d := time.Until(issued.ExpiresAt); d <= 0 || d > time.Hour {
+		t.Fatalf("Invalid expiry: got '%v'", issued.ExpiresAt)
+	}
+
+	info, err := client.DescribeIdentity(ctx, kes.Identity(issued.Identity))
+	if err != nil {
+		t.Fatalf("Failed to describe issued identity: %v", err)
+	}
+	if info.Policy != "ci" {
+		t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "ci")
+	}
+
+	cert, err := kes.GenerateCertificate(key)
+	if err != nil {
+		t.Fatalf("Failed to generate client certificate: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	issuedClient := kes.NewClientWithConfig(url, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{cert},
+	})
+	if err = issuedClient.CreateKey(ctx, "ci-key"); err != nil {
+		t.Fatalf("Failed to create key with issued identity: %v", err)
+	}
+	if err = issuedClient.CreateKey(ctx, "other-key"); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Creating key: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+
+	// Issued identities get revoked once they expire.
+	revoked := srv.revokeExpired(ctx, time.Now().Add(2*time.Hour))
+	if !slices.Equal(revoked, []kes.Identity{kes.Identity(issued.Identity)}) {
+		t.Fatalf("Invalid revoked identities: got '%v' - want '[%s]'", revoked, issued.Identity)
+	}
+}
+
+func testTestPolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	err := srv.UpdatePolicies(map[string]Policy{
+		"my-policy": {
+			Allow: map[string]kes.Rule{
+				"/v1/key/create/*":        {},
+				"/v1/key/create/my-key-*": {},
+				"/v1/status":              {},
+			},
+			Deny: map[string]kes.Rule{
+				"/v1/key/create/my-key-admin":   {},
+				"/v1/key/decrypt/team-a-secret": {},
+			},
+			Keys: []KeyRule{
+				{Pattern: "team-a-*", Operations: []string{"encrypt", "decrypt"}},
+			},
+			Identities: []kes.Identity{"my-identity"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+
+	client := defaultClient(url)
+	for i, test := range testPolicyTests {
+		path := url + api.PathPolicyTest + test.Identity + "?path=" + test.Path
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: failed to test policy: %v", i, err)
+		}
+		var result api.TestPolicyResponse
+		err = json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("Test %d: failed to decode response: %v", i, err)
+		}
+		if result != test.Result {
+			t.Fatalf("Test %d: got '%+v' - want '%+v'", i, result, test.Result)
+		}
+	}
+}
+
+var testPolicyTests = []struct {
+	Identity string
+	Path     string
+	Result   api.TestPolicyResponse
+}{
+	{ // 0
+		Identity: "my-identity",
+		Path:     "/v1/key/create/my-key-1",
+		Result:   api.TestPolicyResponse{Allowed: true, Policy: "my-policy", Allow: "/v1/key/create/my-key-*"},
+	},
+	{ // 1
+		Identity: "my-identity",
+		Path:     "/v1/key/create/other-key",
+		Result:   api.TestPolicyResponse{Allowed: true, Policy: "my-policy", Allow: "/v1/key/create/*"},
+	},
+	{ // 2
+		Identity: "my-identity",
+		Path:     "/v1/key/create/my-key-admin",
+		Result:   api.TestPolicyResponse{Policy: "my-policy", Allow: "/v1/key/create/my-key-*", Deny: "/v1/key/create/my-key-admin"},
+	},
+	{ // 3
+		Identity: "my-identity",
+		Path:     "/v1/key/delete/my-key",
+		Result:   api.TestPolicyResponse{Policy: "my-policy"},
+	},
+	{ // 4
+		Identity: "unknown-identity",
+		Path:     "/v1/status",
+		Result:   api.TestPolicyResponse{},
+	},
+	{ // 5
+		Identity: defaultIdentity,
+		Path:     "/v1/key/delete/my-key",
+		Result:   api.TestPolicyResponse{Allowed: true, IsAdmin: true},
+	},
+	{ // 6
+		Identity: "my-identity",
+		Path:     "/v1/key/encrypt/team-a-key",
+		Result:   api.TestPolicyResponse{Allowed: true, Policy: "my-policy", KeyRule: "team-a-*"},
+	},
+	{ // 7
+		Identity: "my-identity",
+		Path:     "/v1/key/encrypt/team-b-key",
+		Result:   api.TestPolicyResponse{Policy: "my-policy"},
+	},
+	{ // 8
+		Identity: "my-identity",
+		Path:     "/v1/key/delete/team-a-key",
+		Result:   api.TestPolicyResponse{Policy: "my-policy"},
+	},
+	{ // 9
+		Identity: "my-identity",
+		Path:     "/v1/key/decrypt/team-a-secret",
+		Result:   api.TestPolicyResponse{Policy: "my-policy", Deny: "/v1/key/decrypt/team-a-secret", KeyRule: "team-a-*"},
+	},
+}
+
 func testReadPolicy(t *testing.T) {
 	t.Parallel()
 
@@ -608,7 +1897,7 @@ func testReadPolicy(t *testing.T) {
 
 	policies := make(map[string]Policy)
 	for _, test := range validNameTests {
-		if validName(test.Name) {
+		if defaultNameRules.ValidName(test.Name) {
 			policies[test.Name] = Policy{
 				Allow: policy.Allow,
 				Deny:  policy.Deny,
@@ -628,7 +1917,7 @@ func testReadPolicy(t *testing.T) {
 		if err != nil && !test.ShouldFail {
 			t.Errorf("Test %d: failed to read policy '%s': %v", i, test.Name, err)
 		}
-		if !validName(test.Name) && errors.Is(err, kes.ErrPolicyNotFound) {
+		if !defaultNameRules.ValidName(test.Name) && errors.Is(err, kes.ErrPolicyNotFound) {
 			t.Errorf("Test %d: received %v for invalid policy name '%s'", i, err, test.Name)
 		}
 
@@ -652,7 +1941,7 @@ func testListPolicies(t *testing.T) {
 	var names []string
 	policies := make(map[string]Policy)
 	for _, test := range validNameTests {
-		if validName(test.Name) {
+		if defaultNameRules.ValidName(test.Name) {
 			policies[test.Name] = Policy{}
 			names = append(names, test.Name)
 		}
@@ -672,6 +1961,51 @@ func testListPolicies(t *testing.T) {
 	}
 }
 
+func testListPolicyInfos(t *testing.T) {
+	t.Parallel()
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	if err := srv.UpdatePolicies(map[string]Policy{"policy-a": {}}); err != nil {
+		t.Fatalf("Failed to update server policies: %v", err)
+	}
+
+	client := defaultClient(url)
+	start := time.Now()
+	body, err := json.Marshal(api.CreatePolicyRequest{Allow: []string{"/v1/status"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathPolicyCreate+"policy-b", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to create policy: %s", resp.Status)
+	}
+
+	var list api.ListPolicyInfosResponse
+	getJSON(ctx, t, client, api.PathPolicyListInfo+"policy-*", &list)
+	if len(list.Policies) != 2 || list.Policies[0].Name != "policy-a" || list.Policies[1].Name != "policy-b" {
+		t.Fatalf("Failed to list policies: got %+v", list.Policies)
+	}
+	if p := list.Policies[1]; p.CreatedBy != defaultIdentity || p.CreatedAt.Before(start) {
+		t.Fatalf("Policy 'policy-b': invalid metadata: %+v", p)
+	}
+
+	getJSON(ctx, t, client, api.PathPolicyListInfo+"*?created_after="+start.UTC().Format(time.RFC3339Nano), &list)
+	if len(list.Policies) != 1 || list.Policies[0].Name != "policy-b" {
+		t.Fatalf("Failed to filter policies by creation time: got %+v", list.Policies)
+	}
+}
+
 var importKeyTests = []struct {
 	Key        []byte
 	Cipher     kes.KeyAlgorithm
@@ -0,0 +1,315 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+const (
+	// defaultApprovalWindow is the time period within which a
+	// pending request has to be approved if the ApprovalConfig
+	// does not specify one.
+	defaultApprovalWindow = 1 * time.Hour
+
+	// maxPendingApprovals is the max. number of requests that
+	// can wait for approval at the same time.
+	maxPendingApprovals = 1000
+)
+
+// approvalQueue holds requests for destructive operations,
+// like deleting keys, until a second identity approves or
+// denies them.
+type approvalQueue struct {
+	lock    sync.Mutex
+	pending map[string]*pendingRequest
+}
+
+// pendingRequest is a request waiting for approval.
+type pendingRequest struct {
+	ID         string
+	Method     string
+	Path       string
+	Resource   string
+	Body       []byte
+	RemoteAddr string
+	Identity   kes.Identity
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+
+	handler    api.Handler          // Executes the request once approved
+	tls        *tls.ConnectionState // Verified again, like the policy, once approved
+	oidcPolicy string               // Policy of an identity authenticated by an OIDC token
+}
+
+// Add adds the request to the queue. It returns an error if
+// too many requests are pending.
+func (q *approvalQueue) Add(r *pendingRequest, now time.Time) api.Error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.removeExpired(now)
+	if len(q.pending) >= maxPendingApprovals {
+		return api.NewError(http.StatusServiceUnavailable, "too many requests are waiting for approval")
+	}
+	if q.pending == nil {
+		q.pending = make(map[string]*pendingRequest)
+	}
+	q.pending[r.ID] = r
+	return nil
+}
+
+// Get returns the pending request with the given ID. It
+// reports whether such a request exists and has not expired.
+func (q *approvalQueue) Get(id string, now time.Time) (*pendingRequest, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.removeExpired(now)
+	r, ok := q.pending[id]
+	return r, ok
+}
+
+// Remove removes and returns the pending request with the
+// given ID. It reports whether such a request exists and
+// has not expired.
+func (q *approvalQueue) Remove(id string, now time.Time) (*pendingRequest, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.removeExpired(now)
+	r, ok := q.pending[id]
+	if ok {
+		delete(q.pending, id)
+	}
+	return r, ok
+}
+
+// List returns all pending requests that have not
+// expired, sorted by their creation time.
+func (q *approvalQueue) List(now time.Time) []*pendingRequest {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.removeExpired(now)
+	requests := make([]*pendingRequest, 0, len(q.pending))
+	for _, r := range q.pending {
+		requests = append(requests, r)
+	}
+	slices.SortFunc(requests, func(a, b *pendingRequest) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return requests
+}
+
+func (q *approvalQueue) removeExpired(now time.Time) {
+	for id, r := range q.pending {
+		if !now.Before(r.ExpiresAt) {
+			delete(q.pending, id)
+		}
+	}
+}
+
+// requireApproval returns an api.Handler that, if approvals
+// are enabled, does not call h but queues the request until
+// it gets approved by another identity. Otherwise, it calls
+// h immediately.
+func (s *Server) requireApproval(h api.Handler) api.Handler {
+	return api.HandlerFunc(func(resp *api.Response, req *api.Request) {
+		state := s.state.Load()
+		if state.Approval == nil {
+			h.ServeAPI(resp, req)
+			return
+		}
+
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				if err, ok := api.IsError(err); ok {
+					resp.Failr(err)
+					return
+				}
+				resp.Fail(http.StatusBadRequest, "invalid request body")
+				return
+			}
+		}
+
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusInternalServerError, "failed to queue request for approval")
+			return
+		}
+		window := state.Approval.Window
+		if window <= 0 {
+			window = defaultApprovalWindow
+		}
+		oidcPolicy, _ := req.Context().Value(oidcPolicyKey{}).(string)
+		now := time.Now()
+		pending := &pendingRequest{
+			ID:         hex.EncodeToString(id[:]),
+			Method:     req.Method,
+			Path:       req.URL.Path,
+			Resource:   req.Resource,
+			Body:       body,
+			RemoteAddr: req.RemoteAddr,
+			Identity:   req.Identity,
+			CreatedAt:  now.UTC(),
+			ExpiresAt:  now.Add(window).UTC(),
+			handler:    h,
+			tls:        req.TLS,
+			oidcPolicy: oidcPolicy,
+		}
+		if err := s.approvals.Add(pending, now); err != nil {
+			resp.Failr(err)
+			return
+		}
+
+		const StatusAccepted = http.StatusAccepted
+		state.Audit.Log(
+			fmt.Sprintf("request '%s' waits for approval until %s", pending.ID, pending.ExpiresAt.Format(time.RFC3339)),
+			StatusAccepted,
+			req,
+		)
+		api.ReplyWith(resp, StatusAccepted, api.ApprovalRequiredResponse{
+			Message:   fmt.Sprintf("approval required: request '%s' has to be approved by another identity until %s", pending.ID, pending.ExpiresAt.Format(time.RFC3339)),
+			ID:        pending.ID,
+			ExpiresAt: pending.ExpiresAt,
+		})
+	})
+}
+
+func (s *Server) listApprovals(resp *api.Response, req *api.Request) {
+	pending := s.approvals.List(time.Now())
+	requests := make([]api.PendingRequest, 0, len(pending))
+	for _, r := range pending {
+		requests = append(requests, api.PendingRequest{
+			ID:        r.ID,
+			Method:    r.Method,
+			Path:      r.Path,
+			Identity:  r.Identity.String(),
+			CreatedAt: r.CreatedAt,
+			ExpiresAt: r.ExpiresAt,
+		})
+	}
+	api.ReplyWith(resp, http.StatusOK, api.ListApprovalsResponse{Requests: requests})
+}
+
+func (s *Server) approveRequest(resp *api.Response, req *api.Request) {
+	if f := s.frozen.Load(); f != nil { // Keep the request pending until the server is unfrozen
+		resp.Failr(f.Err())
+		return
+	}
+	pending, ok := s.approvals.Get(req.Resource, time.Now())
+	if !ok {
+		resp.Fail(http.StatusNotFound, "approval request not found")
+		return
+	}
+	// An identity may issue further identities without approval.
+	// Hence, a request cannot be approved by any identity that
+	// acts on behalf of the same principal as the sender.
+	if state := s.state.Load(); state.principal(pending.Identity) == state.principal(req.Identity) {
+		resp.Fail(http.StatusForbidden, "request cannot be approved by the identity that sent it or by an identity acting on behalf of the same identity")
+		return
+	}
+	if _, ok = s.approvals.Remove(pending.ID, time.Now()); !ok { // Approved or denied concurrently
+		resp.Fail(http.StatusNotFound, "approval request not found")
+		return
+	}
+
+	r, err := http.NewRequestWithContext(req.Context(), pending.Method, pending.Path, bytes.NewReader(pending.Body))
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to execute approved request")
+		return
+	}
+	r.RemoteAddr = pending.RemoteAddr
+	r.TLS = pending.tls
+
+	// The identity may have been deleted, may have expired or its
+	// policy may have changed while the request has been pending.
+	// Hence, the request has to pass the current policy, too.
+	state := s.state.Load()
+	approved, aErr := reauthorize(state, r, pending)
+	if aErr != nil {
+		state.Audit.Log(
+			fmt.Sprintf("request '%s' sent by '%s' approved but no longer allowed: %s %s", pending.ID, pending.Identity, pending.Method, pending.Path),
+			aErr.Status(),
+			req,
+		)
+		resp.Failr(aErr)
+		return
+	}
+	approved.Resource = pending.Resource
+	approved.Received = time.Now()
+
+	state.Audit.Log(
+		fmt.Sprintf("request '%s' sent by '%s' approved: %s %s", pending.ID, pending.Identity, pending.Method, pending.Path),
+		http.StatusOK,
+		req,
+	)
+	pending.handler.ServeAPI(resp, approved)
+}
+
+// reauthorize returns the approved request if the identity that
+// sent it is still the admin or if the policy currently assigned
+// to the identity allows it.
+func reauthorize(s *serverState, req *http.Request, pending *pendingRequest) (*api.Request, api.Error) {
+	if pending.Identity == s.Admin {
+		return &api.Request{
+			Request:  req,
+			Identity: pending.Identity,
+		}, nil
+	}
+	if pending.oidcPolicy != "" {
+		p, ok := s.Policies[pending.oidcPolicy]
+		if !ok {
+			return nil, kes.ErrNotAllowed
+		}
+		req = req.WithContext(withOIDCPolicy(req.Context(), pending.oidcPolicy))
+		return authorize(s, req, pending.Identity, &identityEntry{
+			Name:        pending.oidcPolicy,
+			Policy:      p,
+			policyRules: s.PolicyRules[pending.oidcPolicy],
+		})
+	}
+
+	policy, ok := s.Identities[pending.Identity]
+	if !ok {
+		return nil, kes.ErrNotAllowed
+	}
+	return authorize(s, req, pending.Identity, &policy)
+}
+
+func (s *Server) denyRequest(resp *api.Response, req *api.Request) {
+	pending, ok := s.approvals.Remove(req.Resource, time.Now())
+	if !ok {
+		resp.Fail(http.StatusNotFound, "approval request not found")
+		return
+	}
+
+	verb := "denied"
+	if pending.Identity == req.Identity {
+		verb = "withdrawn"
+	}
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("request '%s' sent by '%s' %s: %s %s", pending.ID, pending.Identity, verb, pending.Method, pending.Path),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
@@ -0,0 +1,336 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestApprovalQueue(t *testing.T) {
+	var q approvalQueue
+	now := time.Now()
+	for _, id := range []string{"a", "b", "c"} {
+		if err := q.Add(&pendingRequest{ID: id, CreatedAt: now, ExpiresAt: now.Add(time.Hour)}, now); err != nil {
+			t.Fatalf("Failed to add request '%s': %v", id, err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	if n := len(q.List(now)); n != 3 {
+		t.Fatalf("Invalid number of pending requests: got '%d' - want '3'", n)
+	}
+	if _, ok := q.Remove("b", now); !ok {
+		t.Fatal("Failed to remove pending request")
+	}
+	if _, ok := q.Remove("b", now); ok {
+		t.Fatal("Removed pending request twice")
+	}
+
+	// Request 'a' expires first.
+	requests := q.List(now.Add(58 * time.Minute))
+	if len(requests) != 1 || requests[0].ID != "c" {
+		t.Fatalf("Invalid pending requests: got '%v' - want only 'c'", requests)
+	}
+	if _, ok := q.Get("a", now); ok {
+		t.Fatal("Expired request is still pending")
+	}
+}
+
+func TestServerApproval(t *testing.T) {
+	ctx := testContext(t)
+
+	approverKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, url := startServer(ctx, &Config{
+		Approval: &ApprovalConfig{},
+		Policies: map[string]Policy{
+			"approvers": {
+				Allow:      map[string]kes.Rule{"/v1/approval/*": {}},
+				Identities: []kes.Identity{approverKey.Identity()},
+			},
+		},
+	})
+	defer srv.Close()
+
+	admin := defaultClient(url)
+	cert, err := kes.GenerateCertificate(approverKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	approver := kes.NewClientWithConfig(url, &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{cert},
+	})
+
+	send := func(client *kes.Client, method, path string, v any) int {
+		req, err := http.NewRequestWithContext(ctx, method, url+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode < 300 {
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	if err = admin.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	var pending api.ApprovalRequiredResponse
+	if code := send(admin, http.MethodDelete, api.PathKeyDelete+"my-key", &pending); code != http.StatusAccepted {
+		t.Fatalf("Deleting key: got status '%d' - want '%d'", code, http.StatusAccepted)
+	}
+	if _, err = admin.DescribeKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Key has been deleted without approval: %v", err)
+	}
+
+	if code := send(admin, http.MethodPut, api.PathApprovalApprove+pending.ID, nil); code != http.StatusForbidden {
+		t.Fatalf("Approving own request: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	var list api.ListApprovalsResponse
+	if code := send(approver, http.MethodGet, api.PathApprovalList, &list); code != http.StatusOK {
+		t.Fatalf("Listing pending requests: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if len(list.Requests) != 1 {
+		t.Fatalf("Invalid number of pending requests: got '%d' - want '1'", len(list.Requests))
+	}
+	if r := list.Requests[0]; r.ID != pending.ID || r.Path != api.PathKeyDelete+"my-key" || r.Identity != defaultIdentity {
+		t.Fatalf("Invalid pending request: got '%+v'", r)
+	}
+	if code := send(approver, http.MethodPut, api.PathApprovalApprove+pending.ID, nil); code != http.StatusOK {
+		t.Fatalf("Approving request: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if _, err = admin.DescribeKey(ctx, "my-key"); err == nil {
+		t.Fatal("Key has not been deleted after approval")
+	}
+
+	// Denied requests are not executed and cannot be approved anymore.
+	if err = admin.CreateKey(ctx, "my-key-2"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if code := send(admin, http.MethodDelete, api.PathKeyDelete+"my-key-2", &pending); code != http.StatusAccepted {
+		t.Fatalf("Deleting key: got status '%d' - want '%d'", code, http.StatusAccepted)
+	}
+	if code := send(approver, http.MethodPut, api.PathApprovalDeny+pending.ID, nil); code != http.StatusOK {
+		t.Fatalf("Denying request: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := send(approver, http.MethodPut, api.PathApprovalApprove+pending.ID, nil); code != http.StatusNotFound {
+		t.Fatalf("Approving denied request: got status '%d' - want '%d'", code, http.StatusNotFound)
+	}
+	if _, err = admin.DescribeKey(ctx, "my-key-2"); err != nil {
+		t.Fatalf("Key has been deleted although the request was denied: %v", err)
+	}
+}
+
+func TestServerApprovalReauthorize(t *testing.T) {
+	ctx := testContext(t)
+
+	approverKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requesterKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvers := Policy{
+		Allow:      map[string]kes.Rule{"/v1/approval/*": {}},
+		Identities: []kes.Identity{approverKey.Identity()},
+	}
+	srv, url := startServer(ctx, &Config{
+		Approval: &ApprovalConfig{},
+		Policies: map[string]Policy{
+			"approvers": approvers,
+			"deleters": {
+				Allow:      map[string]kes.Rule{api.PathKeyDelete + "*": {}},
+				Identities: []kes.Identity{requesterKey.Identity()},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	newClient := func(key kes.APIKey) *kes.Client {
+		cert, err := kes.GenerateCertificate(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kes.NewClientWithConfig(url, &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{cert},
+		})
+	}
+	admin, approver, requester := defaultClient(url), newClient(approverKey), newClient(requesterKey)
+
+	if err = admin.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	send := func(client *kes.Client, method, path string, v any) int {
+		req, err := http.NewRequestWithContext(ctx, method, url+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode < 300 {
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode
+	}
+	var pending api.ApprovalRequiredResponse
+	if code := send(requester, http.MethodDelete, api.PathKeyDelete+"my-key", &pending); code != http.StatusAccepted {
+		t.Fatalf("Deleting key: got status '%d' - want '%d'", code, http.StatusAccepted)
+	}
+
+	// Revoke the requester's policy while the request is pending.
+	if err = srv.UpdatePolicies(map[string]Policy{"approvers": approvers}); err != nil {
+		t.Fatalf("Failed to update policies: %v", err)
+	}
+	if code := send(approver, http.MethodPut, api.PathApprovalApprove+pending.ID, nil); code != http.StatusForbidden {
+		t.Fatalf("Approving request of revoked identity: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	if _, err = admin.DescribeKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Key has been deleted although the identity is no longer allowed to: %v", err)
+	}
+}
+
+func TestServerApprovalIssuedIdentity(t *testing.T) {
+	ctx := testContext(t)
+
+	requesterKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approverKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv, url := startServer(ctx, &Config{
+		Approval: &ApprovalConfig{},
+		Policies: map[string]Policy{
+			"operators": {
+				Allow: map[string]kes.Rule{
+					"/v1/approval/*":            {},
+					api.PathKeyDelete + "*":     {},
+					api.PathIdentityIssue + "*": {},
+				},
+				Identities: []kes.Identity{requesterKey.Identity(), approverKey.Identity()},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	newClient := func(key kes.APIKey) *kes.Client {
+		cert, err := kes.GenerateCertificate(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return kes.NewClientWithConfig(url, &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{cert},
+		})
+	}
+	send := func(client *kes.Client, method, path, body string, v any) int {
+		req, err := http.NewRequestWithContext(ctx, method, url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if v != nil && resp.StatusCode < 300 {
+			if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return resp.StatusCode
+	}
+	issue := func(client *kes.Client) *kes.Client {
+		var issued api.IssueIdentityResponse
+		if code := send(client, http.MethodPut, api.PathIdentityIssue+"operators", `{"ttl":"1h"}`, &issued); code != http.StatusOK {
+			t.Fatalf("Issuing identity: got status '%d' - want '%d'", code, http.StatusOK)
+		}
+		key, err := kes.ParseAPIKey(issued.APIKey)
+		if err != nil {
+			t.Fatalf("Failed to parse issued API key: %v", err)
+		}
+		return newClient(key)
+	}
+	admin, requester, approver := defaultClient(url), newClient(requesterKey), newClient(approverKey)
+
+	if err = admin.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	var pending api.ApprovalRequiredResponse
+	if code := send(requester, http.MethodDelete, api.PathKeyDelete+"my-key", "", &pending); code != http.StatusAccepted {
+		t.Fatalf("Deleting key: got status '%d' - want '%d'", code, http.StatusAccepted)
+	}
+
+	// Neither an identity issued by the requester, nor one issued by
+	// such an identity, can approve the requester's requests.
+	issued := issue(requester)
+	if code := send(issued, http.MethodPut, api.PathApprovalApprove+pending.ID, "", nil); code != http.StatusForbidden {
+		t.Fatalf("Approving request of issuer: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	if code := send(issue(issued), http.MethodPut, api.PathApprovalApprove+pending.ID, "", nil); code != http.StatusForbidden {
+		t.Fatalf("Approving request of transitive issuer: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+
+	// The requester cannot approve requests of identities it has issued.
+	var issuedPending api.ApprovalRequiredResponse
+	if err = admin.CreateKey(ctx, "my-key-2"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if code := send(issued, http.MethodDelete, api.PathKeyDelete+"my-key-2", "", &issuedPending); code != http.StatusAccepted {
+		t.Fatalf("Deleting key: got status '%d' - want '%d'", code, http.StatusAccepted)
+	}
+	if code := send(requester, http.MethodPut, api.PathApprovalApprove+issuedPending.ID, "", nil); code != http.StatusForbidden {
+		t.Fatalf("Approving request of issued identity: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+
+	if code := send(approver, http.MethodPut, api.PathApprovalApprove+pending.ID, "", nil); code != http.StatusOK {
+		t.Fatalf("Approving request: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if _, err = admin.DescribeKey(ctx, "my-key"); err == nil {
+		t.Fatal("Key has not been deleted after approval")
+	}
+}
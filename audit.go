@@ -6,12 +6,28 @@ package kes
 
 import (
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
 	"net/netip"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/merkle"
 	"github.com/minio/kms-go/kes"
 )
 
@@ -44,6 +60,129 @@ type AuditRecord struct {
 
 	// The log message describing the event.
 	Message string
+
+	// Checkpoint is set if the record is an audit checkpoint
+	// instead of a request/response pair. All request and
+	// response fields of a checkpoint record are empty.
+	Checkpoint *AuditCheckpoint
+}
+
+// AuditCheckpoint is a signed summary of all audit records
+// logged by a KES server before the checkpoint. Downstream
+// systems can use checkpoints to detect dropped or truncated
+// audit streams.
+//
+// Hash is a rolling SHA-256 hash over all audit records. It
+// starts with 32 zero bytes and each record updates the hash
+// to: SHA-256(hash || line). The line of a record is:
+//
+//	<time> <method> <path> <ip> <identity> <status code> <response time>\n
+//
+// The time is formatted as RFC 3339 with nanoseconds in UTC
+// and the response time is in nanoseconds. Checkpoints are
+// not part of the hash.
+//
+// If the audit Merkle tree is enabled, Root is the RFC 9162
+// Merkle tree hash over all audit records before the checkpoint.
+// The leaf of each record is its line. Inclusion proofs for
+// individual records can be verified against the root.
+type AuditCheckpoint struct {
+	Time      time.Time // Point in time when the checkpoint has been created
+	Count     uint64    // Number of audit records before the checkpoint
+	Hash      []byte    // Rolling hash of all audit records before the checkpoint
+	Root      []byte    // Merkle tree root of all audit records before the checkpoint, if enabled
+	Signature []byte    // Signature of the checkpoint
+}
+
+// Verify verifies the checkpoint's signature using the public
+// key. It supports Ed25519, ECDSA and RSA (PKCS #1 v1.5) keys.
+func (c *AuditCheckpoint) Verify(key crypto.PublicKey) error {
+	msg := c.message()
+	digest := sha256.Sum256(msg)
+
+	var valid bool
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, msg, c.Signature)
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest[:], c.Signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], c.Signature) == nil
+	default:
+		return fmt.Errorf("kes: unsupported public key type '%T'", key)
+	}
+	if !valid {
+		return errors.New("kes: invalid audit checkpoint signature")
+	}
+	return nil
+}
+
+// sign signs the checkpoint using the signer.
+func (c *AuditCheckpoint) sign(signer crypto.Signer) error {
+	msg := c.message()
+
+	var (
+		sig []byte
+		err error
+	)
+	if _, ok := signer.Public().(ed25519.PublicKey); ok {
+		sig, err = signer.Sign(rand.Reader, msg, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(msg)
+		sig, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return err
+	}
+	c.Signature = sig
+	return nil
+}
+
+// message returns the message signed by the checkpoint's signature.
+func (c *AuditCheckpoint) message() []byte {
+	msg := make([]byte, 0, 128)
+	msg = append(msg, "kes audit checkpoint\n"...)
+	msg = c.Time.UTC().AppendFormat(msg, time.RFC3339Nano)
+	msg = append(msg, '\n')
+	msg = strconv.AppendUint(msg, c.Count, 10)
+	msg = append(msg, '\n')
+	msg = append(msg, hex.EncodeToString(c.Hash)...)
+	msg = append(msg, '\n')
+	msg = append(msg, hex.EncodeToString(c.Root)...)
+	return append(msg, '\n')
+}
+
+// auditLine returns the line of the audit record that is
+// hashed by audit checkpoints and the audit Merkle tree.
+func auditLine(r *AuditRecord) []byte {
+	line := make([]byte, 0, 256)
+	line = r.Time.UTC().AppendFormat(line, time.RFC3339Nano)
+	line = append(line, ' ')
+	line = append(line, r.Method...)
+	line = append(line, ' ')
+	line = append(line, r.Path...)
+	line = append(line, ' ')
+	line = append(line, r.RemoteIP.String()...)
+	line = append(line, ' ')
+	line = append(line, r.Identity.String()...)
+	line = append(line, ' ')
+	line = strconv.AppendInt(line, int64(r.StatusCode), 10)
+	line = append(line, ' ')
+	line = strconv.AppendInt(line, int64(r.ResponseTime), 10)
+	return append(line, '\n')
+}
+
+// updateAuditHash returns the rolling hash of all audit records
+// up to and including the record with the given line given the
+// hash of all previous records.
+func updateAuditHash(hash [sha256.Size]byte, line []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write(hash[:])
+	h.Write(line)
+
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
 }
 
 // An AuditHandler handles audit records produced by a Server.
@@ -92,6 +231,15 @@ func (a *AuditLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	return a.Handler.Enabled(ctx, level)
 }
 
+// Close closes the underlying handler if it implements
+// io.Closer.
+func (a *AuditLogHandler) Close() error {
+	if c, ok := a.Handler.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
 // Handle converts the AuditRecord to an slog.Record and
 // passes it to the underlying handler.
 func (a *AuditLogHandler) Handle(ctx context.Context, r AuditRecord) error {
@@ -100,6 +248,15 @@ func (a *AuditLogHandler) Handle(ctx context.Context, r AuditRecord) error {
 		Message: r.Message,
 		Level:   r.Level,
 	}
+	if r.Checkpoint != nil {
+		rec.AddAttrs(slog.Attr{Key: "checkpoint", Value: slog.GroupValue(
+			slog.Uint64("count", r.Checkpoint.Count),
+			slog.String("hash", hex.EncodeToString(r.Checkpoint.Hash)),
+			slog.String("root", hex.EncodeToString(r.Checkpoint.Root)),
+			slog.String("signature", hex.EncodeToString(r.Checkpoint.Signature)),
+		)})
+		return a.Handler.Handle(ctx, rec)
+	}
 	rec.AddAttrs(
 		slog.Attr{Key: "req", Value: slog.GroupValue(
 			slog.String("method", r.Method),
@@ -115,6 +272,43 @@ func (a *AuditLogHandler) Handle(ctx context.Context, r AuditRecord) error {
 	return a.Handler.Handle(ctx, rec)
 }
 
+// DefaultAuditCheckpointInterval is the time between two audit
+// checkpoints if AuditCheckpointConfig.Interval is not set.
+const DefaultAuditCheckpointInterval = 5 * time.Minute
+
+// emitAuditCheckpoints emits audit checkpoints, if configured,
+// until ctx is canceled. Changes of the checkpoint interval take
+// effect after the next checkpoint.
+func (s *Server) emitAuditCheckpoints(ctx context.Context) {
+	const Delay = 1 * time.Minute // Delay between checks whether checkpoints are enabled
+
+	for {
+		wait := Delay
+		if conf := s.state.Load().AuditCheckpoint; conf != nil {
+			wait = conf.Interval
+			if wait <= 0 {
+				wait = DefaultAuditCheckpointInterval
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.AuditCheckpoint == nil {
+			continue
+		}
+		if err := state.Audit.Checkpoint(ctx, state.AuditCheckpoint.Signer); err != nil {
+			state.Log.ErrorContext(ctx, err.Error())
+		}
+	}
+}
+
 // An auditLogger records information about a request/response
 // handled by the Server.
 //
@@ -122,11 +316,34 @@ func (a *AuditLogHandler) Handle(ctx context.Context, r AuditRecord) error {
 // passes it to its AuditHandler. If clients have subscribed to
 // the AuditLog API, the logger also sends the AuditRecord to these
 // clients.
+//
+// It keeps track of the number of records and their rolling hash
+// for emitting audit checkpoints.
 type auditLogger struct {
 	h     AuditHandler
 	level slog.Leveler
 
 	out *api.Multicast // clients subscribed to the AuditLog API
+
+	// targets are additional handlers, like external log sinks.
+	// In contrast to h, they are not subject to level but decide
+	// themselves whether they handle a record.
+	targets atomic.Pointer[[]AuditHandler]
+
+	mu    sync.Mutex
+	count uint64            // Number of records logged so far
+	hash  [sha256.Size]byte // Rolling hash of all records logged so far
+
+	tree   *merkle.Tree           // Merkle tree of all records logged so far, if enabled
+	leaves map[merkle.Hash]uint64 // Leaf index of each record in the Merkle tree
+
+	store *auditStore // Persists all records, if enabled
+
+	// sampling drops records of successful requests before they
+	// are passed to h, the targets or subscribed clients. Like
+	// records below level, dropped records are only hashed if the
+	// store is enabled, which persists all records.
+	sampling *atomic.Pointer[auditSampling]
 }
 
 // newAuditLogger returns a new auditLogger passing AuditRecords to h.
@@ -140,16 +357,82 @@ func newAuditLogger(h AuditHandler, level slog.Leveler) *auditLogger {
 	}
 }
 
+// setTargets replaces the logger's audit targets. It returns
+// the previous targets.
+func (a *auditLogger) setTargets(targets []AuditHandler) []AuditHandler {
+	targets = slices.Clone(targets)
+	if old := a.targets.Swap(&targets); old != nil {
+		return *old
+	}
+	return nil
+}
+
+// closeAuditTargets closes all targets that implement io.Closer
+// and are not contained in keep.
+func closeAuditTargets(log *slog.Logger, targets, keep []AuditHandler) {
+	for _, t := range targets {
+		if slices.Contains(keep, t) {
+			continue
+		}
+		if c, ok := t.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Error(fmt.Sprintf("kes: failed to close audit target: %v", err))
+			}
+		}
+	}
+}
+
+// enabledTargets returns all audit targets that handle records
+// at the given level.
+func (a *auditLogger) enabledTargets(ctx context.Context, level slog.Level) []AuditHandler {
+	targets := a.targets.Load()
+	if targets == nil || len(*targets) == 0 {
+		return nil
+	}
+
+	enabled := make([]AuditHandler, 0, len(*targets))
+	for _, t := range *targets {
+		if t.Enabled(ctx, level) {
+			enabled = append(enabled, t)
+		}
+	}
+	return enabled
+}
+
+// enableMerkleTree enables the audit Merkle tree. It must be
+// called before any record is logged.
+func (a *auditLogger) enableMerkleTree() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.tree = &merkle.Tree{}
+	a.leaves = map[merkle.Hash]uint64{}
+}
+
+// enableStore enables persisting all records, independent of
+// the logger's level, in the audit store. It must be called
+// before any record is logged.
+func (a *auditLogger) enableStore(store *auditStore) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.store = store
+}
+
 // Log emits an audit record with the current time, log message,
 // response status code and request information.
 func (a *auditLogger) Log(msg string, statusCode int, req *api.Request) {
 	const Level = slog.LevelInfo
-	if Level < a.level.Level() {
-		return
-	}
 
-	hEnabled, oEnabled := a.h.Enabled(req.Context(), Level), a.out.Num() > 0
-	if !hEnabled && !oEnabled {
+	var hEnabled, oEnabled bool
+	if Level >= a.level.Level() {
+		hEnabled, oEnabled = a.h.Enabled(req.Context(), Level), a.out.Num() > 0
+	}
+	targets := a.enabledTargets(req.Context(), Level)
+	if a.sampling != nil && a.sampling.Load().Drop(req.URL.Path, statusCode) {
+		hEnabled, oEnabled, targets = false, false, nil
+	}
+	if !hEnabled && !oEnabled && len(targets) == 0 && a.store == nil {
 		return
 	}
 
@@ -166,23 +449,155 @@ func (a *auditLogger) Log(msg string, statusCode int, req *api.Request) {
 		Level:        Level,
 		Message:      msg,
 	}
+
+	// Records are passed to the handler and clients while holding
+	// the lock such that they see the records in the same order
+	// as they have been hashed.
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	line := auditLine(&r)
+	a.count++
+	a.hash = updateAuditHash(a.hash, line)
+	if a.tree != nil {
+		leaf := merkle.LeafHash(line)
+		index := a.tree.Append(leaf)
+		if _, ok := a.leaves[leaf]; !ok {
+			a.leaves[leaf] = index
+		}
+	}
 	if hEnabled {
 		a.h.Handle(req.Context(), r)
 	}
+	for _, t := range targets {
+		t.Handle(req.Context(), r)
+	}
 
-	if !oEnabled {
+	if !oEnabled && a.store == nil {
 		return
 	}
-	json.NewEncoder(a.out).Encode(api.AuditLogEvent{
+	event := api.AuditLogEvent{
 		Time: r.Time,
 		Request: api.AuditLogRequest{
 			IP:       r.RemoteIP.String(),
+			Method:   r.Method,
 			APIPath:  r.Path,
 			Identity: r.Identity.String(),
 		},
 		Response: api.AuditLogResponse{
 			StatusCode: r.StatusCode,
 			Time:       r.ResponseTime.Milliseconds(),
+			Duration:   int64(r.ResponseTime),
 		},
-	})
+	}
+	if a.store != nil {
+		a.store.Append(&event)
+	}
+	if oEnabled {
+		json.NewEncoder(a.out).Encode(event)
+	}
+}
+
+// Checkpoint emits an audit checkpoint, signed by the signer,
+// for all records logged so far.
+func (a *auditLogger) Checkpoint(ctx context.Context, signer crypto.Signer) error {
+	const Level = slog.LevelInfo
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var hEnabled, oEnabled bool
+	if Level >= a.level.Level() {
+		hEnabled, oEnabled = a.h.Enabled(ctx, Level), a.out.Num() > 0
+	}
+	targets := a.enabledTargets(ctx, Level)
+	if !hEnabled && !oEnabled && len(targets) == 0 && a.store == nil {
+		return nil
+	}
+
+	checkpoint := &AuditCheckpoint{
+		Time:  time.Now(),
+		Count: a.count,
+		Hash:  append([]byte(nil), a.hash[:]...),
+	}
+	if a.tree != nil {
+		root, err := a.tree.Root(a.tree.Size())
+		if err != nil {
+			return err
+		}
+		checkpoint.Root = root[:]
+	}
+	if err := checkpoint.sign(signer); err != nil {
+		return fmt.Errorf("kes: failed to sign audit checkpoint: %v", err)
+	}
+
+	record := AuditRecord{
+		Time:       checkpoint.Time,
+		Level:      Level,
+		Message:    "audit checkpoint",
+		Checkpoint: checkpoint,
+	}
+	if hEnabled {
+		a.h.Handle(ctx, record)
+	}
+	for _, t := range targets {
+		t.Handle(ctx, record)
+	}
+	event := api.AuditLogEvent{
+		Time: checkpoint.Time,
+		Checkpoint: &api.AuditLogCheckpoint{
+			Count:     checkpoint.Count,
+			Hash:      checkpoint.Hash,
+			Root:      checkpoint.Root,
+			Signature: checkpoint.Signature,
+		},
+	}
+	if a.store != nil {
+		a.store.Append(&event)
+	}
+	if oEnabled {
+		json.NewEncoder(a.out).Encode(event)
+	}
+	return nil
+}
+
+// Prove returns an inclusion proof for the audit record with the
+// given leaf hash in the audit Merkle tree with the given size.
+// If size is 0, the proof is for the current tree.
+func (a *auditLogger) Prove(leaf merkle.Hash, size uint64) (api.AuditProofResponse, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.tree == nil {
+		return api.AuditProofResponse{}, api.NewError(http.StatusNotImplemented, "audit Merkle tree is not enabled")
+	}
+	if size == 0 {
+		size = a.tree.Size()
+	}
+	if size > a.tree.Size() {
+		return api.AuditProofResponse{}, api.NewError(http.StatusBadRequest, "tree size exceeds number of audit events")
+	}
+	index, ok := a.leaves[leaf]
+	if !ok || index >= size {
+		return api.AuditProofResponse{}, api.NewError(http.StatusNotFound, "audit event not found")
+	}
+
+	root, err := a.tree.Root(size)
+	if err != nil {
+		return api.AuditProofResponse{}, err
+	}
+	proof, err := a.tree.InclusionProof(index, size)
+	if err != nil {
+		return api.AuditProofResponse{}, err
+	}
+	resp := api.AuditProofResponse{
+		Index: index,
+		Size:  size,
+		Root:  root[:],
+		Proof: make([][]byte, 0, len(proof)),
+	}
+	for i := range proof {
+		resp.Proof = append(resp.Proof, proof[i][:])
+	}
+	return resp, nil
 }
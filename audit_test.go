@@ -0,0 +1,210 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/merkle"
+)
+
+func TestAuditCheckpoint(t *testing.T) {
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, signer := range []crypto.Signer{ed25519Key, ecdsaKey, rsaKey} {
+		handler := &auditRecorder{}
+		logger := newAuditLogger(handler, slog.LevelInfo)
+		for j := 0; j < 3; j++ {
+			logger.Log("audit", http.StatusOK, newAuditTestRequest())
+		}
+		if err = logger.Checkpoint(context.Background(), signer); err != nil {
+			t.Fatalf("Test %d: failed to emit checkpoint: %v", i, err)
+		}
+
+		if len(handler.Records) != 4 {
+			t.Fatalf("Test %d: invalid number of records: got '%d' - want '%d'", i, len(handler.Records), 4)
+		}
+		var hash [sha256.Size]byte
+		for _, r := range handler.Records[:3] {
+			hash = updateAuditHash(hash, auditLine(&r))
+		}
+
+		checkpoint := handler.Records[3].Checkpoint
+		if checkpoint == nil {
+			t.Fatalf("Test %d: last record is not a checkpoint", i)
+		}
+		if checkpoint.Count != 3 {
+			t.Fatalf("Test %d: invalid checkpoint count: got '%d' - want '%d'", i, checkpoint.Count, 3)
+		}
+		if !bytes.Equal(checkpoint.Hash, hash[:]) {
+			t.Fatalf("Test %d: invalid checkpoint hash: got '%x' - want '%x'", i, checkpoint.Hash, hash)
+		}
+		if err = checkpoint.Verify(signer.Public()); err != nil {
+			t.Fatalf("Test %d: failed to verify checkpoint: %v", i, err)
+		}
+
+		checkpoint.Count++
+		if err = checkpoint.Verify(signer.Public()); err == nil {
+			t.Fatalf("Test %d: verified modified checkpoint", i)
+		}
+	}
+}
+
+func TestAuditCheckpointDisabled(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &auditRecorder{}
+	logger := newAuditLogger(handler, slog.LevelError)
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	if err = logger.Checkpoint(context.Background(), signer); err != nil {
+		t.Fatalf("Failed to emit checkpoint: %v", err)
+	}
+	if len(handler.Records) != 0 {
+		t.Fatalf("Audit logger emitted records below its level: %v", handler.Records)
+	}
+}
+
+func TestAuditTargets(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, target := &auditRecorder{}, &auditRecorder{}
+	logger := newAuditLogger(handler, slog.LevelError)
+	logger.setTargets([]AuditHandler{target})
+
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	if err = logger.Checkpoint(context.Background(), signer); err != nil {
+		t.Fatalf("Failed to emit checkpoint: %v", err)
+	}
+	if len(handler.Records) != 0 {
+		t.Fatalf("Audit logger emitted records below its level: %v", handler.Records)
+	}
+	if len(target.Records) != 2 {
+		t.Fatalf("Invalid number of target records: got '%d' - want '%d'", len(target.Records), 2)
+	}
+	if target.Records[1].Checkpoint == nil || target.Records[1].Checkpoint.Count != 1 {
+		t.Fatalf("Invalid target checkpoint: %v", target.Records[1].Checkpoint)
+	}
+
+	if old := logger.setTargets(nil); len(old) != 1 || old[0] != target {
+		t.Fatalf("Invalid previous targets: %v", old)
+	}
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	if len(target.Records) != 2 {
+		t.Fatalf("Removed audit target received records: got '%d' - want '%d'", len(target.Records), 2)
+	}
+}
+
+func TestAuditProof(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := &auditRecorder{}
+	logger := newAuditLogger(handler, slog.LevelInfo)
+	if _, err = logger.Prove(merkle.Hash{}, 0); err == nil {
+		t.Fatal("Computed proof without Merkle tree")
+	}
+
+	logger.enableMerkleTree()
+	for i := 0; i < 5; i++ {
+		logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	}
+	if err = logger.Checkpoint(context.Background(), signer); err != nil {
+		t.Fatalf("Failed to emit checkpoint: %v", err)
+	}
+	checkpoint := handler.Records[5].Checkpoint
+	if err = checkpoint.Verify(signer.Public()); err != nil {
+		t.Fatalf("Failed to verify checkpoint: %v", err)
+	}
+
+	logger.Log("audit", http.StatusOK, newAuditTestRequest()) // Not part of the checkpoint
+	for i, r := range handler.Records[:5] {
+		leaf := merkle.LeafHash(auditLine(&r))
+		proof, err := logger.Prove(leaf, checkpoint.Count)
+		if err != nil {
+			t.Fatalf("Record %d: failed to compute proof: %v", i, err)
+		}
+		if proof.Index != uint64(i) || proof.Size != checkpoint.Count {
+			t.Fatalf("Record %d: invalid proof: got index '%d' and size '%d'", i, proof.Index, proof.Size)
+		}
+		if !bytes.Equal(proof.Root, checkpoint.Root) {
+			t.Fatalf("Record %d: invalid root: got '%x' - want '%x'", i, proof.Root, checkpoint.Root)
+		}
+
+		path := make([]merkle.Hash, 0, len(proof.Proof))
+		for _, h := range proof.Proof {
+			path = append(path, merkle.Hash(h))
+		}
+		if !merkle.VerifyInclusion(leaf, proof.Index, proof.Size, path, merkle.Hash(checkpoint.Root)) {
+			t.Fatalf("Record %d: failed to verify inclusion proof", i)
+		}
+	}
+
+	if _, err = logger.Prove(merkle.LeafHash([]byte("unknown")), 0); err == nil {
+		t.Fatal("Computed proof for unknown record")
+	}
+	leaf := merkle.LeafHash(auditLine(&handler.Records[6]))
+	if _, err = logger.Prove(leaf, checkpoint.Count); err == nil {
+		t.Fatal("Computed proof for record outside of tree")
+	}
+}
+
+func newAuditTestRequest() *api.Request {
+	return &api.Request{
+		Request:  httptest.NewRequest(http.MethodGet, "/v1/key/describe/my-key", nil),
+		Identity: "a",
+		Received: time.Now(),
+	}
+}
+
+// auditRecorder is an AuditHandler that records
+// all audit records.
+type auditRecorder struct {
+	mu      sync.Mutex
+	Records []AuditRecord
+}
+
+func (*auditRecorder) Enabled(context.Context, slog.Level) bool { return true }
+
+func (a *auditRecorder) Handle(_ context.Context, r AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.Records = append(a.Records, r)
+	return nil
+}
@@ -0,0 +1,312 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/headers"
+)
+
+const (
+	// DefaultAuditRetention is the default time audit events
+	// are retained by the audit store.
+	DefaultAuditRetention = 30 * 24 * time.Hour
+
+	// auditStoreExt is the file extension of audit store files.
+	// Each file is named after the day, in UTC, of its events,
+	// e.g. '2024-01-31.jsonl'.
+	auditStoreExt = ".jsonl"
+
+	// Default and max. number of events returned by the
+	// AuditQuery API.
+	defaultAuditQueryLimit = 1000
+	maxAuditQueryLimit     = 10000
+)
+
+// auditStore persists audit events as JSON lines on disk. It
+// writes one file per day and removes files that are older than
+// its retention.
+type auditStore struct {
+	dir       string
+	retention time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	day  string // Day of the current file, e.g. '2024-01-31'
+
+	failed atomic.Uint64 // Number of events that could not be written
+}
+
+// openAuditStore opens the audit store for the given config.
+// It creates the store directory if it does not exist.
+func openAuditStore(conf *AuditStoreConfig) (*auditStore, error) {
+	if conf.Dir == "" {
+		return nil, errors.New("kes: audit store directory is empty")
+	}
+	if conf.Retention != 0 && conf.Retention < 24*time.Hour {
+		return nil, fmt.Errorf("kes: invalid audit retention '%v': must be at least one day", conf.Retention)
+	}
+	if err := os.MkdirAll(conf.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("kes: failed to create audit store: %v", err)
+	}
+
+	s := &auditStore{
+		dir:       conf.Dir,
+		retention: conf.Retention,
+	}
+	if s.retention == 0 {
+		s.retention = DefaultAuditRetention
+	}
+	return s, nil
+}
+
+// Append appends the event to the file of the event's day.
+// Events that cannot be written are counted as failed.
+func (s *auditStore) Append(event *api.AuditLogEvent) {
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.failed.Add(1)
+		return
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if day := event.Time.UTC().Format(time.DateOnly); s.file == nil || day != s.day {
+		if s.file != nil {
+			s.file.Close()
+			s.file = nil
+		}
+		file, err := os.OpenFile(filepath.Join(s.dir, day+auditStoreExt), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+		if err != nil {
+			s.failed.Add(1)
+			return
+		}
+		s.file, s.day = file, day
+	}
+	if _, err = s.file.Write(line); err != nil {
+		s.failed.Add(1)
+	}
+}
+
+// Failed returns the number of events that could not be written
+// since the last call of Failed.
+func (s *auditStore) Failed() uint64 { return s.failed.Swap(0) }
+
+// Query calls fn for all stored events that match the filter,
+// in the order they have been stored, until limit events have
+// been passed to fn or fn returns an error.
+func (s *auditStore) Query(ctx context.Context, filter *logFilter, limit int, fn func(line []byte) error) error {
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+
+	for _, day := range days {
+		start, _ := time.Parse(time.DateOnly, day)
+		if !filter.Until.IsZero() && !start.Before(filter.Until) {
+			break
+		}
+		if !filter.Since.IsZero() && !start.Add(24*time.Hour).After(filter.Since) {
+			continue
+		}
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+
+		if limit, err = s.queryFile(filepath.Join(s.dir, day+auditStoreExt), filter, limit, fn); err != nil {
+			return err
+		}
+		if limit == 0 {
+			return nil
+		}
+	}
+	return nil
+}
+
+// queryFile calls fn for all events in the file that match the
+// filter. It returns the remaining limit.
+func (s *auditStore) queryFile(filename string, filter *logFilter, limit int, fn func([]byte) error) (int, error) {
+	file, err := os.Open(filename)
+	if errors.Is(err, os.ErrNotExist) { // Removed since listing the directory
+		return limit, nil
+	}
+	if err != nil {
+		return limit, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for scanner.Scan() {
+		var event api.AuditLogEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue // Skip lines that are partially written
+		}
+		if !filter.Match(&event) {
+			continue
+		}
+		if err := fn(scanner.Bytes()); err != nil {
+			return limit, err
+		}
+		if limit--; limit == 0 {
+			break
+		}
+	}
+	return limit, scanner.Err()
+}
+
+// Expire removes all files whose events are older than the
+// store's retention.
+func (s *auditStore) Expire(now time.Time) error {
+	days, err := s.days()
+	if err != nil {
+		return err
+	}
+
+	cutoff := now.Add(-s.retention)
+	for _, day := range days {
+		start, _ := time.Parse(time.DateOnly, day)
+		if start.Add(24 * time.Hour).After(cutoff) {
+			break
+		}
+		if err := os.Remove(filepath.Join(s.dir, day+auditStoreExt)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("kes: failed to remove expired audit events: %v", err)
+		}
+	}
+	return nil
+}
+
+// Close closes the store's current file.
+func (s *auditStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// days returns the days of all files in the store in
+// chronological order.
+func (s *auditStore) days() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to read audit store: %v", err)
+	}
+
+	days := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		day, ok := strings.CutSuffix(entry.Name(), auditStoreExt)
+		if !ok || entry.IsDir() {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, day); err == nil {
+			days = append(days, day)
+		}
+	}
+	slices.Sort(days) // Days sort chronologically
+	return days, nil
+}
+
+// expireAuditEvents periodically removes expired audit events
+// from the audit store, if enabled, and reports events that could
+// not be stored.
+func (s *Server) expireAuditEvents(ctx context.Context) {
+	const Delay = 1 * time.Minute
+
+	for {
+		timer := time.NewTimer(Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.Audit.store == nil {
+			continue
+		}
+		if n := state.Audit.store.Failed(); n > 0 {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to store %d audit events", n))
+		}
+		if err := state.Audit.store.Expire(time.Now()); err != nil {
+			state.Log.ErrorContext(ctx, err.Error())
+		}
+	}
+}
+
+func (s *Server) queryAudit(resp *api.Response, req *api.Request) {
+	query := req.URL.Query()
+	limit := defaultAuditQueryLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxAuditQueryLimit {
+			resp.Fail(http.StatusBadRequest, "invalid query limit")
+			return
+		}
+		limit = n
+	}
+	query.Del("limit")
+
+	filter, err := parseLogFilter(query, true)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	state := s.state.Load()
+	if state.Audit.store == nil {
+		resp.Fail(http.StatusNotImplemented, "audit store is not enabled")
+		return
+	}
+
+	// The response header is sent once the first event is
+	// found such that errors before can still be reported.
+	var wroteHeader bool
+	err = state.Audit.store.Query(req.Context(), &filter, limit, func(line []byte) error {
+		if !wroteHeader {
+			resp.Header().Set(headers.ContentType, headers.ContentTypeJSONLines)
+			resp.WriteHeader(http.StatusOK)
+			wroteHeader = true
+		}
+		if _, err := resp.Write(line); err != nil {
+			return err
+		}
+		_, err := resp.Write([]byte{'\n'})
+		return err
+	})
+	if err != nil {
+		if !wroteHeader {
+			state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusInternalServerError, "failed to query audit events")
+		}
+		return
+	}
+	if !wroteHeader {
+		resp.Header().Set(headers.ContentType, headers.ContentTypeJSONLines)
+		resp.WriteHeader(http.StatusOK)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+)
+
+func TestAuditStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := openAuditStore(&AuditStoreConfig{Dir: dir, Retention: 48 * time.Hour})
+	if err != nil {
+		t.Fatalf("Failed to open audit store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().UTC()
+	events := []api.AuditLogEvent{
+		{Time: now.Add(-72 * time.Hour), Request: api.AuditLogRequest{APIPath: "/v1/key/create/my-key"}, Response: api.AuditLogResponse{StatusCode: 200}},
+		{Time: now.Add(-24 * time.Hour), Request: api.AuditLogRequest{APIPath: "/v1/key/delete/my-key"}, Response: api.AuditLogResponse{StatusCode: 403}},
+		{Time: now.Add(-24 * time.Hour), Request: api.AuditLogRequest{APIPath: "/v1/key/delete/my-key"}, Response: api.AuditLogResponse{StatusCode: 200}},
+		{Time: now, Request: api.AuditLogRequest{APIPath: "/v1/key/describe/my-key"}, Response: api.AuditLogResponse{StatusCode: 200}},
+	}
+	for i := range events {
+		store.Append(&events[i])
+	}
+	if n := store.Failed(); n != 0 {
+		t.Fatalf("Failed to store %d audit events", n)
+	}
+
+	query := func(filter logFilter, limit int) []api.AuditLogEvent {
+		var result []api.AuditLogEvent
+		err := store.Query(context.Background(), &filter, limit, func(line []byte) error {
+			var event api.AuditLogEvent
+			if err := json.Unmarshal(line, &event); err != nil {
+				return err
+			}
+			result = append(result, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Failed to query audit store: %v", err)
+		}
+		return result
+	}
+
+	if result := query(logFilter{}, 100); len(result) != len(events) {
+		t.Fatalf("Invalid number of events: got '%d' - want '%d'", len(result), len(events))
+	}
+	if result := query(logFilter{}, 2); len(result) != 2 || result[0].Request.APIPath != events[0].Request.APIPath {
+		t.Fatalf("Invalid limited query result: %v", result)
+	}
+	result := query(logFilter{Path: "/v1/key/delete/*", StatusCode: 200, Since: now.Add(-48 * time.Hour)}, 100)
+	if len(result) != 1 || !result[0].Time.Equal(events[2].Time) {
+		t.Fatalf("Invalid filtered query result: %v", result)
+	}
+
+	if err = store.Expire(now); err != nil {
+		t.Fatalf("Failed to expire audit events: %v", err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, events[0].Time.Format(time.DateOnly)+auditStoreExt)); !os.IsNotExist(err) {
+		t.Fatalf("Expired audit events have not been removed: %v", err)
+	}
+	if result := query(logFilter{}, 100); len(result) != 3 {
+		t.Fatalf("Invalid number of events after expiry: got '%d' - want '%d'", len(result), 3)
+	}
+}
+
+func TestAuditLoggerStore(t *testing.T) {
+	_, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openAuditStore(&AuditStoreConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to open audit store: %v", err)
+	}
+	defer store.Close()
+
+	logger := newAuditLogger(&auditRecorder{}, slog.LevelError)
+	logger.enableStore(store)
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	if err = logger.Checkpoint(context.Background(), signer); err != nil {
+		t.Fatalf("Failed to emit checkpoint: %v", err)
+	}
+
+	var events []api.AuditLogEvent
+	err = store.Query(context.Background(), &logFilter{}, 100, func(line []byte) error {
+		var event api.AuditLogEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to query audit store: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Invalid number of stored events: got '%d' - want '%d'", len(events), 2)
+	}
+	if events[0].Request.APIPath != "/v1/key/describe/my-key" {
+		t.Fatalf("Invalid API path: got '%s' - want '%s'", events[0].Request.APIPath, "/v1/key/describe/my-key")
+	}
+	if events[1].Checkpoint == nil || events[1].Checkpoint.Count != 1 {
+		t.Fatalf("Invalid stored checkpoint: %v", events[1].Checkpoint)
+	}
+}
+
+func TestOpenAuditStore(t *testing.T) {
+	if _, err := openAuditStore(&AuditStoreConfig{}); err == nil {
+		t.Fatal("Opened audit store without directory")
+	}
+	if _, err := openAuditStore(&AuditStoreConfig{Dir: t.TempDir(), Retention: time.Hour}); err == nil {
+		t.Fatal("Opened audit store with retention of less than one day")
+	}
+}
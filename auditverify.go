@@ -0,0 +1,151 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// AuditLogSummary summarizes an audit log verified by
+// VerifyAuditLog.
+type AuditLogSummary struct {
+	Events      uint64 // Number of audit events
+	Checkpoints uint64 // Number of audit checkpoints
+	Unverified  uint64 // Number of audit events not covered by any checkpoint
+}
+
+// VerifyAuditLog verifies an audit log, read from r, that consists
+// of JSON audit events, one per line, as produced by the AuditLog
+// and AuditQuery APIs or by audit targets. Multiple logs, like the
+// daily files of an audit store, can be verified by concatenating
+// them in chronological order.
+//
+// It verifies the signature of each checkpoint using the public key
+// and recomputes the rolling hash over all events up to it. Hence,
+// it detects events that have been modified, removed or inserted.
+// The log must not be filtered.
+//
+// Events before the first checkpoint can only be verified if the log
+// starts with the first event of the server. Events after the last
+// checkpoint cannot be verified until the server emits the next one.
+// Both are counted as unverified.
+func VerifyAuditLog(r io.Reader, key crypto.PublicKey) (AuditLogSummary, error) {
+	switch key.(type) {
+	case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+	default:
+		return AuditLogSummary{}, fmt.Errorf("kes: unsupported public key type '%T'", key)
+	}
+
+	var (
+		summary  AuditLogSummary
+		hash     [sha256.Size]byte // Rolling hash at the last checkpoint
+		count    uint64            // Number of events at the last checkpoint
+		anchored bool              // Whether a checkpoint has been seen
+		lines    [][]byte          // Lines of all events after the last checkpoint
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var event api.AuditLogEvent
+		if err := json.Unmarshal(text, &event); err != nil {
+			return summary, fmt.Errorf("kes: line %d: invalid audit event: %v", n, err)
+		}
+		if event.Checkpoint == nil {
+			if event.Request.Method == "" { // Older KES servers don't export all fields hashed by checkpoints
+				return summary, fmt.Errorf("kes: line %d: audit event does not contain a request method", n)
+			}
+			summary.Events++
+			lines = append(lines, auditEventLine(&event))
+			continue
+		}
+
+		summary.Checkpoints++
+		checkpoint := AuditCheckpoint{
+			Time:      event.Time,
+			Count:     event.Checkpoint.Count,
+			Hash:      event.Checkpoint.Hash,
+			Root:      event.Checkpoint.Root,
+			Signature: event.Checkpoint.Signature,
+		}
+		if err := checkpoint.Verify(key); err != nil {
+			return summary, fmt.Errorf("kes: line %d: invalid audit checkpoint signature", n)
+		}
+		if len(checkpoint.Hash) != sha256.Size {
+			return summary, fmt.Errorf("kes: line %d: invalid audit checkpoint hash", n)
+		}
+
+		// A checkpoint either continues the chain of the previous
+		// checkpoint or, if the log starts with the first event of
+		// the server or the server has been restarted, starts a new
+		// chain with 32 zero bytes.
+		m := uint64(len(lines))
+		switch {
+		case anchored && count+m == checkpoint.Count:
+		case m == checkpoint.Count:
+			hash = [sha256.Size]byte{}
+		case !anchored:
+			summary.Unverified += m
+		default:
+			want := checkpoint.Count
+			if want > count {
+				want -= count
+			}
+			return summary, fmt.Errorf("kes: line %d: audit events have been removed or inserted: found %d events before the checkpoint but want %d", n, m, want)
+		}
+		if anchored || m == checkpoint.Count {
+			for _, line := range lines {
+				hash = updateAuditHash(hash, line)
+			}
+			if !bytes.Equal(hash[:], checkpoint.Hash) {
+				return summary, fmt.Errorf("kes: line %d: audit events have been modified: checkpoint hash mismatch", n)
+			}
+		}
+
+		copy(hash[:], checkpoint.Hash)
+		count = checkpoint.Count
+		anchored = true
+		lines = lines[:0]
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, err
+	}
+	summary.Unverified += uint64(len(lines))
+	return summary, nil
+}
+
+// auditEventLine returns the line of the audit record
+// described by the event. It is the inverse of converting
+// an AuditRecord to an api.AuditLogEvent.
+func auditEventLine(event *api.AuditLogEvent) []byte {
+	ip, _ := netip.ParseAddr(event.Request.IP) // Empty or invalid if the server didn't know the client IP
+	return auditLine(&AuditRecord{
+		Time:         event.Time,
+		Method:       event.Request.Method,
+		Path:         event.Request.APIPath,
+		Identity:     kes.Identity(event.Request.Identity),
+		RemoteIP:     ip,
+		StatusCode:   event.Response.StatusCode,
+		ResponseTime: time.Duration(event.Response.Duration),
+	})
+}
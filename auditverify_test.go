@@ -0,0 +1,87 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestVerifyAuditLog(t *testing.T) {
+	public, signer, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := openAuditStore(&AuditStoreConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Failed to open audit store: %v", err)
+	}
+	defer store.Close()
+
+	logger := newAuditLogger(&auditRecorder{}, slog.LevelInfo)
+	logger.enableStore(store)
+	for _, n := range []int{3, 2, 1} {
+		for i := 0; i < n; i++ {
+			logger.Log("audit", http.StatusOK, newAuditTestRequest())
+		}
+		if n > 1 {
+			if err = logger.Checkpoint(context.Background(), signer); err != nil {
+				t.Fatalf("Failed to emit checkpoint: %v", err)
+			}
+		}
+	}
+
+	var lines [][]byte // 3 events, checkpoint, 2 events, checkpoint, 1 event
+	err = store.Query(context.Background(), &logFilter{}, 100, func(line []byte) error {
+		lines = append(lines, bytes.Clone(line))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to query audit store: %v", err)
+	}
+	join := func(lines ...[]byte) *bytes.Reader { return bytes.NewReader(bytes.Join(lines, []byte{'\n'})) }
+
+	summary, err := VerifyAuditLog(join(lines...), public)
+	if err != nil {
+		t.Fatalf("Failed to verify audit log: %v", err)
+	}
+	if summary != (AuditLogSummary{Events: 6, Checkpoints: 2, Unverified: 1}) {
+		t.Fatalf("Invalid summary: got '%+v'", summary)
+	}
+
+	// A log that doesn't start with the first event of the server
+	// can only be verified after the first checkpoint.
+	summary, err = VerifyAuditLog(join(lines[1:]...), public)
+	if err != nil {
+		t.Fatalf("Failed to verify truncated audit log: %v", err)
+	}
+	if summary != (AuditLogSummary{Events: 5, Checkpoints: 2, Unverified: 3}) {
+		t.Fatalf("Invalid summary of truncated audit log: got '%+v'", summary)
+	}
+
+	modified := bytes.Replace(lines[4], []byte(`"code":200`), []byte(`"code":403`), 1)
+	if _, err = VerifyAuditLog(join(lines[0], lines[1], lines[2], lines[3], modified, lines[5], lines[6]), public); err == nil {
+		t.Fatal("Verified audit log with modified event")
+	}
+	if _, err = VerifyAuditLog(join(lines[0], lines[1], lines[2], lines[3], lines[5], lines[6]), public); err == nil {
+		t.Fatal("Verified audit log with removed event")
+	}
+	if _, err = VerifyAuditLog(join(lines[0], lines[1], lines[2], lines[3], lines[4], lines[4], lines[5], lines[6]), public); err == nil {
+		t.Fatal("Verified audit log with inserted event")
+	}
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = VerifyAuditLog(join(lines...), otherKey); err == nil {
+		t.Fatal("Verified audit log with wrong public key")
+	}
+}
@@ -11,9 +11,12 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/https"
 	"github.com/minio/kms-go/kes"
 )
 
@@ -25,8 +28,18 @@ import (
 // A request is accepted if the identity matches the admin identity
 // or the policy associated to the identity allows the request. The
 // later is the case if none of the policy's deny rules and at least
-// one of the policy's allow rules apply. Otherwise, the request is
-// rejected.
+// one of the policy's allow or key rules apply, and the request
+// satisfies the policy's conditions, if any. Otherwise, the request
+// is rejected. Accepted requests that exceed the policy's rate limit,
+// if any, are rejected with HTTP 429.
+//
+// Requests sent by a TLS proxy are authenticated as sent by the
+// client whose certificate the proxy forwards.
+//
+// If OIDC is enabled, requests with a bearer token are authenticated
+// by the token instead of the client certificate. Such a request is
+// accepted if the token is valid and the policy assigned to the
+// token's claims allows the request.
 type verifyIdentity atomic.Pointer[serverState]
 
 // Authenticate verifies that the request is either sent by the
@@ -34,11 +47,36 @@ type verifyIdentity atomic.Pointer[serverState]
 // Otherwise, it returns an error.
 func (v *verifyIdentity) Authenticate(req *http.Request) (*api.Request, api.Error) {
 	s := (*atomic.Pointer[serverState])(v).Load()
-	identity, err := identifyRequest(req.TLS)
+	if s.OIDC != nil {
+		if token, ok := bearerToken(req); ok {
+			return authenticateToken(s, req, token)
+		}
+	}
+
+	identity, err := identifyRequest(req.TLS, s.SPIFFE)
 	if err != nil {
 		s.Log.DebugContext(req.Context(), err.Error(), "req", req)
 		return nil, err
 	}
+	if s.TLSProxy != nil && s.TLSProxy.Is(identity) {
+		if pErr := s.TLSProxy.Verify(req); pErr != nil {
+			s.Log.DebugContext(req.Context(), fmt.Sprintf("access denied: TLS proxy '%s': %v", identity, pErr), "req", req)
+			if err, ok := api.IsError(pErr); ok {
+				return nil, err
+			}
+			return nil, kes.ErrNotAllowed
+		}
+		if identity, err = identifyRequest(req.TLS, s.SPIFFE); err != nil {
+			s.Log.DebugContext(req.Context(), err.Error(), "req", req)
+			return nil, err
+		}
+	}
+	if s.Revocation != nil {
+		if err := s.Revocation.Verify(req.Context(), req.TLS); err != nil {
+			s.Log.DebugContext(req.Context(), "access denied: "+err.Error(), "req", req)
+			return nil, kes.ErrNotAllowed
+		}
+	}
 	if identity == s.Admin {
 		return &api.Request{
 			Request:  req,
@@ -51,10 +89,73 @@ func (v *verifyIdentity) Authenticate(req *http.Request) (*api.Request, api.Erro
 		s.Log.DebugContext(req.Context(), "access denied: identity not found", "req", req)
 		return nil, kes.ErrNotAllowed
 	}
-	if err := policy.Verify(req); err != nil {
+	return authorize(s, req, identity, &policy)
+}
+
+// authenticateToken verifies the JWT bearer token sent by
+// a client and returns the request if the policy assigned
+// to the token allows it.
+func authenticateToken(s *serverState, req *http.Request, token string) (*api.Request, api.Error) {
+	identity, name, err := s.OIDC.Verify(req.Context(), token, time.Now())
+	if err != nil {
+		s.Log.DebugContext(req.Context(), "access denied: "+err.Error(), "req", req)
+		return nil, kes.ErrNotAllowed
+	}
+	p, ok := s.Policies[name]
+	if !ok {
+		s.Log.DebugContext(req.Context(), fmt.Sprintf("access denied: policy '%s' not found", name), "req", req)
+		return nil, kes.ErrNotAllowed
+	}
+	req = req.WithContext(withOIDCPolicy(req.Context(), name))
+	return authorize(s, req, identity, &identityEntry{
+		Name:        name,
+		Policy:      p,
+		policyRules: s.PolicyRules[name],
+	})
+}
+
+// authorize returns the request if it is allowed by the
+// policy assigned to the identity.
+func authorize(s *serverState, req *http.Request, identity kes.Identity, policy *identityEntry) (*api.Request, api.Error) {
+	if now := time.Now(); policy.expired(now) {
+		s.Audit.Log(
+			fmt.Sprintf("access denied: identity expired at %s", policy.ExpiresAt.Format(time.RFC3339)),
+			http.StatusForbidden,
+			&api.Request{Request: req, Identity: identity, Received: now},
+		)
+		return nil, kes.ErrNotAllowed
+	}
+	if err := policy.verify(req); err != nil {
 		s.Log.DebugContext(req.Context(), fmt.Sprintf("access denied: rejected by policy '%s'", policy.Name), "req", req)
 		return nil, kes.ErrNotAllowed
 	}
+	if err := policy.Conditions.verify(req, time.Now()); err != nil {
+		s.Log.DebugContext(req.Context(), fmt.Sprintf("access denied: policy '%s': %v", policy.Name, err), "req", req)
+		return nil, kes.ErrNotAllowed
+	}
+	if policy.Context != nil {
+		contexts, err := policy.Context.verify(req)
+		if err != nil {
+			s.Log.DebugContext(req.Context(), fmt.Sprintf("access denied: policy '%s': %v", policy.Name, err), "req", req)
+			return nil, kes.ErrNotAllowed
+		}
+		if contexts != nil {
+			req = req.WithContext(withContextFields(req.Context(), contexts))
+		}
+	}
+	if policy.SSH != nil && strings.HasPrefix(req.URL.Path, api.PathSSHSign) {
+		req = req.WithContext(withSSHPolicy(req.Context(), policy.SSH))
+	}
+	if policy.Cert != nil && strings.HasPrefix(req.URL.Path, api.PathCertIssue) {
+		req = req.WithContext(withCertPolicy(req.Context(), policy.Cert))
+	}
+	if policy.RateLimit != nil {
+		if delay, ok := s.RateLimiter.Allow(identity, policy.Name, policy.RateLimit, time.Now()); !ok {
+			s.Log.DebugContext(req.Context(), fmt.Sprintf("request rejected: rate limit of policy '%s' exceeded", policy.Name), "req", req)
+			s.Metrics.RateLimited(policy.Name)
+			return nil, api.NewRetryError(http.StatusTooManyRequests, "too many requests: rate limit exceeded", delay)
+		}
+	}
 
 	return &api.Request{
 		Request:  req,
@@ -70,14 +171,52 @@ func (v *verifyIdentity) Authenticate(req *http.Request) (*api.Request, api.Erro
 type insecureIdentifyOnly struct{}
 
 func (insecureIdentifyOnly) Authenticate(req *http.Request) (*api.Request, api.Error) {
-	identity, _ := identifyRequest(req.TLS)
+	identity, _ := identifyRequest(req.TLS, nil)
 	return &api.Request{
 		Request:  req,
 		Identity: identity,
 	}, nil
 }
 
-func identifyRequest(state *tls.ConnectionState) (kes.Identity, api.Error) {
+// newTLSProxy returns a new TLSProxy for the TLS proxies of
+// the given Config, or nil if no TLS proxies are configured.
+// Forwarded client certificates are verified if the server
+// verifies client certificates.
+//
+// TLS proxies are identified like any other client, i.e. by
+// their SPIFFE ID if spiffe is not nil.
+func newTLSProxy(conf *Config, spiffe *spiffeAuth) *https.TLSProxy {
+	if conf.TLSProxy == nil {
+		return nil
+	}
+
+	proxy := &https.TLSProxy{
+		CertHeader: conf.TLSProxy.CertHeader,
+		Identify: func(state *tls.ConnectionState) kes.Identity {
+			identity, _ := identifyRequest(state, spiffe)
+			return identity
+		},
+	}
+	if proxy.CertHeader == "" {
+		proxy.CertHeader = "X-Tls-Client-Cert"
+	}
+	if conf.TLS.ClientAuth == tls.VerifyClientCertIfGiven || conf.TLS.ClientAuth == tls.RequireAndVerifyClientCert {
+		proxy.VerifyOptions = &x509.VerifyOptions{
+			Roots:     conf.TLS.ClientCAs,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}
+	}
+	for _, id := range conf.TLSProxy.Identities {
+		proxy.Add(id)
+	}
+	return proxy
+}
+
+// identifyRequest returns the identity of the client certificate.
+// If spiffe is not nil and the client certificate is a verified
+// X.509 SVID of one of its trust domains, the identity is the
+// SPIFFE ID. Otherwise, it is the certificate public key hash.
+func identifyRequest(state *tls.ConnectionState, spiffe *spiffeAuth) (kes.Identity, api.Error) {
 	if state == nil {
 		return "", api.NewError(http.StatusBadRequest, "insecure connection: TLS is required")
 	}
@@ -95,12 +234,17 @@ func identifyRequest(state *tls.ConnectionState) (kes.Identity, api.Error) {
 	if cert == nil {
 		return "", api.NewError(http.StatusBadRequest, "tls: client certificate is required")
 	}
+	if spiffe != nil {
+		if identity, ok := spiffe.Identify(state.VerifiedChains); ok {
+			return identity, nil
+		}
+	}
 
 	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
 	return kes.Identity(hex.EncodeToString(h[:])), nil
 }
 
-// validName reports whether s is a valid {policy|identity|key} name.
+// nameRules define which {policy|identity|key} names are valid.
 //
 // Valid names only contain the characters:
 //   - 0-9
@@ -108,23 +252,75 @@ func identifyRequest(state *tls.ConnectionState) (kes.Identity, api.Error) {
 //   - a-z
 //   - '-'  (hyphen, must not be first/last character)
 //   - '_'  (underscore, must not be the only character)
+//   - any character of Charset
 //
-// More characters may be allowed in the future.
-func validName(s string) bool {
-	const MaxLength = 80 // Some arbitrary but reasonable limit
+// and are at most MaxLength bytes long. Names must not start
+// with '.' since key stores, like the filesystem, may treat
+// such names as hidden or temporary entries.
+type nameRules struct {
+	MaxLength int
+	Charset   string
+}
+
+// Limits for custom name rules.
+const (
+	minNameLength = 64 // An identity is a hex-encoded SHA-256 hash
+	maxNameLength = 1024
+
+	// nameCharset contains all characters that may be
+	// allowed in names in addition to the default ones.
+	// In particular, '*' is reserved for patterns and
+	// '/', '\', '?', '#' and '%' have special meaning
+	// within URL paths.
+	nameCharset = ".:@=+,~"
+)
+
+// defaultNameRules are the name rules applied when no
+// custom NameConfig is specified.
+var defaultNameRules = nameRules{
+	MaxLength: 80, // Some arbitrary but reasonable limit
+}
+
+// newNameRules returns the name rules defined by the given
+// NameConfig. It returns the default rules if conf is nil.
+func newNameRules(conf *NameConfig) (nameRules, error) {
+	if conf == nil {
+		return defaultNameRules, nil
+	}
 
-	if s == "" || s == "_" || len(s) > MaxLength {
+	rules := nameRules{
+		MaxLength: conf.MaxLength,
+		Charset:   conf.Charset,
+	}
+	if rules.MaxLength == 0 {
+		rules.MaxLength = defaultNameRules.MaxLength
+	}
+	if rules.MaxLength < minNameLength || rules.MaxLength > maxNameLength {
+		return nameRules{}, fmt.Errorf("kes: invalid max. name length '%d': must be between %d and %d", conf.MaxLength, minNameLength, maxNameLength)
+	}
+	for _, r := range rules.Charset {
+		if !strings.ContainsRune(nameCharset, r) {
+			return nameRules{}, fmt.Errorf("kes: invalid name charset: character '%c' is not allowed. Allowed characters: '%s'", r, nameCharset)
+		}
+	}
+	return rules, nil
+}
+
+// ValidName reports whether s is a valid {policy|identity|key} name.
+func (n nameRules) ValidName(s string) bool {
+	if s == "" || s == "_" || s[0] == '.' || len(s) > n.MaxLength {
 		return false
 	}
 
-	n := len(s) - 1
+	last := len(s) - 1
 	for i, r := range s {
 		switch {
 		case r >= '0' && r <= '9':
 		case r >= 'A' && r <= 'Z':
 		case r >= 'a' && r <= 'z':
-		case r == '-' && i > 0 && i < n:
+		case r == '-' && i > 0 && i < last:
 		case r == '_':
+		case n.Charset != "" && strings.ContainsRune(n.Charset, r):
 		default:
 			return false
 		}
@@ -132,37 +328,35 @@ func validName(s string) bool {
 	return true
 }
 
-// validPattern reports whether s is a valid pattern for
+// ValidIdentity reports whether s is a valid identity. Valid
+// identities are either valid names or SPIFFE IDs.
+func (n nameRules) ValidIdentity(s string) bool {
+	return n.ValidName(s) || isSPIFFEID(s)
+}
+
+// ValidPattern reports whether s is a valid pattern for
 // listing {policy|identity|key} names.
 //
-// Valid patterns only contain the characters:
-//   - 0-9
-//   - A-Z
-//   - a-z
-//   - '-'  (hyphen, must not be first/last character)
-//   - '_'  (underscore, must not be the only character)
-//   - '*'  (only as last character)
-//
-// More characters may be allowed in the future.
-func validPattern(s string) bool {
-	const MaxLength = 80 // Some arbitrary but reasonable limit
-
+// Valid patterns only contain the characters of valid
+// names and '*' as last character.
+func (n nameRules) ValidPattern(s string) bool {
 	if s == "*" { // fast path
 		return true
 	}
-	if s == "_" || len(s) > MaxLength {
+	if s == "_" || len(s) > n.MaxLength {
 		return false
 	}
 
-	n := len(s) - 1
+	last := len(s) - 1
 	for i, r := range s {
 		switch {
 		case r >= '0' && r <= '9':
 		case r >= 'A' && r <= 'Z':
 		case r >= 'a' && r <= 'z':
-		case r == '-' && i > 0 && i < n:
+		case r == '-' && i > 0 && i < last:
 		case r == '_':
-		case r == '*' && i == n:
+		case r == '*' && i == last:
+		case n.Charset != "" && strings.ContainsRune(n.Charset, r):
 		default:
 			return false
 		}
@@ -5,14 +5,24 @@
 package kes
 
 import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/netip"
+	"net/url"
 	"strings"
 	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
 )
 
 func TestValidName(t *testing.T) {
 	t.Parallel()
 	for i, test := range validNameTests {
-		if valid := validName(test.Name); valid != !test.ShouldFail {
+		if valid := defaultNameRules.ValidName(test.Name); valid != !test.ShouldFail {
 			t.Errorf("Test %d: got 'valid=%v' - want 'fail=%v' for name '%s'", i, valid, test.ShouldFail, test.Name)
 		}
 	}
@@ -21,12 +31,109 @@ func TestValidName(t *testing.T) {
 func TestValidPattern(t *testing.T) {
 	t.Parallel()
 	for i, test := range validPatternTests {
-		if valid := validPattern(test.Pattern); valid != !test.ShouldFail {
+		if valid := defaultNameRules.ValidPattern(test.Pattern); valid != !test.ShouldFail {
 			t.Errorf("Test %d: got 'valid=%v' - want 'fail=%v' for pattern '%s'", i, valid, test.ShouldFail, test.Pattern)
 		}
 	}
 }
 
+func TestNameRules(t *testing.T) {
+	t.Parallel()
+	for i, test := range nameRulesTests {
+		rules, err := newNameRules(test.Config)
+		if err != nil {
+			if !test.ShouldFail {
+				t.Errorf("Test %d: failed to create name rules: %v", i, err)
+			}
+			continue
+		}
+		if test.ShouldFail {
+			t.Errorf("Test %d: creating name rules should have failed", i)
+			continue
+		}
+		if valid := rules.ValidName(test.Name); valid != test.Valid {
+			t.Errorf("Test %d: got 'valid=%v' - want 'valid=%v' for name '%s'", i, valid, test.Valid, test.Name)
+		}
+	}
+}
+
+func TestTLSProxy(t *testing.T) {
+	ctx := testContext(t)
+
+	proxyKey, err := kes.GenerateAPIKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	proxyCert, err := kes.GenerateCertificate(proxyKey)
+	if err != nil {
+		t.Fatalf("Failed to generate certificate: %v", err)
+	}
+	clientKey, err := kes.ParseAPIKey(defaultAPIKey)
+	if err != nil {
+		t.Fatalf("Failed to parse API key: %v", err)
+	}
+	clientCert, err := kes.GenerateCertificate(clientKey)
+	if err != nil {
+		t.Fatalf("Failed to generate certificate: %v", err)
+	}
+
+	srv, endpoint := startServer(ctx, &Config{
+		Admin:    "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		TLSProxy: &TLSProxyConfig{Identities: []kes.Identity{proxyKey.Identity()}},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{api.PathKeyCreate + "*": {}},
+				Conditions: &PolicyConditions{SourceIPs: []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	proxy := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				RootCAs:      rootCAs,
+				Certificates: []tls.Certificate{proxyCert},
+			},
+		},
+	}
+	forward := func(name string, header http.Header) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+api.PathKeyCreate+name, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		req.Header = header
+		resp, err := proxy.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	certHeader := url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientCert.Certificate[0]})))
+	if code := forward("my-key", http.Header{
+		"X-Tls-Client-Cert": []string{certHeader},
+		"X-Forwarded-For":   []string{"192.0.2.1"},
+	}); code != http.StatusOK {
+		t.Fatalf("Forwarded request has been rejected: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := forward("my-key-2", http.Header{
+		"X-Tls-Client-Cert": []string{certHeader},
+	}); code != http.StatusForbidden {
+		t.Fatalf("Forwarded request from proxy IP has been accepted: got '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	if code := forward("my-key-3", http.Header{
+		"X-Forwarded-For": []string{"192.0.2.1"},
+	}); code != http.StatusBadRequest {
+		t.Fatalf("Request without client certificate has been accepted: got '%d' - want '%d'", code, http.StatusBadRequest)
+	}
+}
+
 func BenchmarkValidName(b *testing.B) {
 	const (
 		EmptyName   = ""
@@ -36,17 +143,17 @@ func BenchmarkValidName(b *testing.B) {
 
 	b.Run("empty", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validName(EmptyName)
+			defaultNameRules.ValidName(EmptyName)
 		}
 	})
 	b.Run("valid", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validName(ValidName)
+			defaultNameRules.ValidName(ValidName)
 		}
 	})
 	b.Run("invalid", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validName(InvalidName)
+			defaultNameRules.ValidName(InvalidName)
 		}
 	})
 }
@@ -60,17 +167,17 @@ func BenchmarkValidPattern(b *testing.B) {
 
 	b.Run("matchall", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validPattern(MatchAll)
+			defaultNameRules.ValidPattern(MatchAll)
 		}
 	})
 	b.Run("valid", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validPattern(ValidPattern)
+			defaultNameRules.ValidPattern(ValidPattern)
 		}
 	})
 	b.Run("invalid", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			validPattern(InvalidPattern)
+			defaultNameRules.ValidPattern(InvalidPattern)
 		}
 	})
 }
@@ -99,6 +206,35 @@ var (
 		{Name: strings.Repeat("a", 81), ShouldFail: true}, // 15
 	}
 
+	nameRulesTests = []struct {
+		Config     *NameConfig
+		Name       string
+		Valid      bool
+		ShouldFail bool
+	}{
+		{Config: nil, Name: "my-key", Valid: true},                                             // 0
+		{Config: nil, Name: "my.key", Valid: false},                                            // 1
+		{Config: &NameConfig{}, Name: strings.Repeat("a", 80), Valid: true},                    // 2
+		{Config: &NameConfig{}, Name: strings.Repeat("a", 81), Valid: false},                   // 3
+		{Config: &NameConfig{MaxLength: 255}, Name: strings.Repeat("a", 255), Valid: true},     // 4
+		{Config: &NameConfig{MaxLength: 255}, Name: strings.Repeat("a", 256), Valid: false},    // 5
+		{Config: &NameConfig{Charset: ".@"}, Name: "minio@tenant.key", Valid: true},            // 6
+		{Config: &NameConfig{Charset: ".@"}, Name: "minio:key", Valid: false},                  // 7
+		{Config: &NameConfig{Charset: "."}, Name: "..", Valid: false},                          // 8
+		{Config: &NameConfig{Charset: "."}, Name: "-.", Valid: false},                          // 9
+		{Config: &NameConfig{Charset: ":"}, Name: strings.Repeat("a", 80) + ":", Valid: false}, // 10
+		{Config: &NameConfig{Charset: "="}, Name: "a=b", Valid: true},                          // 11
+		{Config: &NameConfig{MaxLength: 32}, ShouldFail: true},                                 // 12
+		{Config: &NameConfig{MaxLength: 2048}, ShouldFail: true},                               // 13
+		{Config: &NameConfig{MaxLength: -1}, ShouldFail: true},                                 // 14
+		{Config: &NameConfig{Charset: "/"}, ShouldFail: true},                                  // 15
+		{Config: &NameConfig{Charset: "*"}, ShouldFail: true},                                  // 16
+		{Config: &NameConfig{Charset: "☰"}, ShouldFail: true},                                  // 17
+		{Config: &NameConfig{Charset: "."}, Name: ".my-key", Valid: false},                     // 18
+		{Config: &NameConfig{Charset: "."}, Name: ".my-key.123.tmp", Valid: false},             // 19
+		{Config: &NameConfig{Charset: "."}, Name: "my-key.", Valid: true},                      // 20
+	}
+
 	validPatternTests = []struct {
 		Pattern    string
 		ShouldFail bool
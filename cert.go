@@ -0,0 +1,387 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/netip"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	kcrypto "github.com/minio/kes/internal/crypto"
+)
+
+// Default validity of X.509 certificates.
+const (
+	defaultCertTTL    = 24 * time.Hour
+	defaultCertMaxTTL = 7 * 24 * time.Hour
+
+	// certCAValidity is the validity of CA certificates,
+	// starting at the creation of the key version.
+	certCAValidity = 10 * 365 * 24 * time.Hour
+)
+
+// CertPolicy restricts the X.509 certificates the identities of
+// a policy may obtain from a key acting as certificate authority.
+// Keys act as certificate authority if created with the usage
+// "cert".
+//
+// Identities whose policy allows the cert issue API but contains
+// no CertPolicy cannot obtain any certificates.
+type CertPolicy struct {
+	// Names are glob patterns, as defined by path.Match, that
+	// the common name and all DNS names of a certificate must
+	// match. For example, "*.svc.example.com" allows certificates
+	// for "minio.svc.example.com".
+	Names []string
+
+	// IPs is a list of IP ranges. Certificates may only contain
+	// IP addresses within one of them. If empty, certificates
+	// must not contain any IP addresses.
+	IPs []netip.Prefix
+
+	// MaxTTL is the max. validity of a certificate. If zero,
+	// certificates are valid for at most 7 days.
+	MaxTTL time.Duration
+
+	// Server allows server certificates. Otherwise, only client
+	// certificates are issued.
+	Server bool
+}
+
+// validate returns an error if the policy allows no names or
+// contains a malformed pattern.
+func (p *CertPolicy) validate() error {
+	if len(p.Names) == 0 {
+		return errors.New("certificate policy allows no names")
+	}
+	for _, pattern := range p.Names {
+		if pattern == "" {
+			return errors.New("certificate policy contains an empty name pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid certificate name pattern '%s': %v", pattern, err)
+		}
+	}
+	for _, prefix := range p.IPs {
+		if !prefix.IsValid() {
+			return errors.New("certificate policy contains an invalid IP range")
+		}
+	}
+	if p.MaxTTL < 0 {
+		return fmt.Errorf("invalid certificate max. TTL '%v': must not be negative", p.MaxTTL)
+	}
+	return nil
+}
+
+// allows returns an error if the policy does not allow a
+// certificate for all names and IP addresses.
+func (p *CertPolicy) allows(names []string, ips []netip.Addr) error {
+	for _, name := range names {
+		var ok bool
+		for _, pattern := range p.Names {
+			if ok, _ = path.Match(pattern, name); ok {
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("name '%s' is not allowed", name)
+		}
+	}
+	for _, ip := range ips {
+		if !slices.ContainsFunc(p.IPs, func(prefix netip.Prefix) bool { return prefix.Contains(ip) }) {
+			return fmt.Errorf("IP address '%s' is not allowed", ip)
+		}
+	}
+	return nil
+}
+
+// certPolicyKey is the request context key of the CertPolicy
+// of the identity that sent the request.
+type certPolicyKey struct{}
+
+// withCertPolicy returns a copy of ctx carrying the CertPolicy.
+func withCertPolicy(ctx context.Context, policy *CertPolicy) context.Context {
+	return context.WithValue(ctx, certPolicyKey{}, policy)
+}
+
+// issueCert issues an X.509 certificate for the public key sent
+// by the client. It is signed by the CA key derived from the key's
+// HMAC key.
+//
+// Requests of the admin are only restricted by the default max.
+// TTL. All other requests must satisfy the CertPolicy of the
+// client's policy.
+func (s *Server) issueCert(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.IssueCertRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	publicKey, err := parsePublicKeyPEM(body.PublicKey)
+	if err != nil {
+		resp.Failf(http.StatusBadRequest, "invalid public key: %v", err)
+		return
+	}
+
+	var extKeyUsage []x509.ExtKeyUsage
+	switch body.Type {
+	case "", "client":
+		body.Type = "client"
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	case "server":
+		extKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	default:
+		resp.Failf(http.StatusBadRequest, "invalid certificate type '%s': must be 'client' or 'server'", body.Type)
+		return
+	}
+	if body.CommonName == "" {
+		resp.Fail(http.StatusBadRequest, "no common name specified")
+		return
+	}
+	names := append([]string{body.CommonName}, body.DNSNames...)
+	for _, name := range names {
+		if name == "" || strings.ContainsAny(name, " \t\n/") {
+			resp.Failf(http.StatusBadRequest, "invalid certificate name '%s'", name)
+			return
+		}
+	}
+	ips := make([]netip.Addr, 0, len(body.IPAddresses))
+	for _, v := range body.IPAddresses {
+		ip, err := netip.ParseAddr(v)
+		if err != nil {
+			resp.Failf(http.StatusBadRequest, "invalid IP address '%s'", v)
+			return
+		}
+		ips = append(ips, ip)
+	}
+	if body.TTL < 0 {
+		resp.Fail(http.StatusBadRequest, "invalid certificate TTL: must not be negative")
+		return
+	}
+
+	maxTTL := defaultCertMaxTTL
+	if req.Identity != s.state.Load().Admin {
+		policy, _ := req.Context().Value(certPolicyKey{}).(*CertPolicy)
+		if policy == nil {
+			resp.Fail(http.StatusForbidden, "policy does not allow certificates")
+			return
+		}
+		if body.Type == "server" && !policy.Server {
+			resp.Fail(http.StatusForbidden, "policy does not allow server certificates")
+			return
+		}
+		if err := policy.allows(names, ips); err != nil {
+			resp.Failf(http.StatusForbidden, "policy does not allow certificate: %v", err)
+			return
+		}
+		if policy.MaxTTL > 0 {
+			maxTTL = policy.MaxTTL
+		}
+	}
+	ttl := time.Duration(body.TTL) * time.Second
+	if ttl == 0 {
+		ttl = min(defaultCertTTL, maxTTL)
+	}
+	if ttl > maxTTL {
+		resp.Failf(http.StatusForbidden, "certificate TTL '%v' exceeds max. TTL '%v'", ttl, maxTTL)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	now := time.Now()
+	if err := checkKeyConstraints(&key, kcrypto.UsageCert, now); err != nil {
+		resp.Failr(err)
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support certificates")
+		return
+	}
+	ca, caKey, err := certAuthority(req.Resource, &key)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to issue certificate")
+		return
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to issue certificate")
+		return
+	}
+	notAfter := now.Add(ttl)
+	if notAfter.After(ca.NotAfter) {
+		notAfter = ca.NotAfter
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: body.CommonName},
+		DNSNames:     body.DNSNames,
+		NotBefore:    now.Add(-1 * time.Minute).UTC(), // Tolerate some clock skew
+		NotAfter:     notAfter.UTC(),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  extKeyUsage,
+	}
+	if _, ok := publicKey.(*rsa.PublicKey); ok {
+		template.KeyUsage |= x509.KeyUsageKeyEncipherment
+	}
+	if body.Type == "server" && !slices.Contains(template.DNSNames, body.CommonName) && net.ParseIP(body.CommonName) == nil {
+		template.DNSNames = append([]string{body.CommonName}, template.DNSNames...) // TLS clients ignore the common name
+	}
+	for _, ip := range ips {
+		template.IPAddresses = append(template.IPAddresses, net.IP(ip.AsSlice()))
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, publicKey, caKey)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to issue certificate")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("X.509 %s certificate '%s' with serial %x issued by key '%s' for names %s valid until %s",
+			body.Type, body.CommonName, serial, req.Resource, strings.Join(append(names, body.IPAddresses...), ","), template.NotAfter.Format(time.RFC3339)),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.IssueCertResponse{
+		Certificate:   string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: raw})),
+		Serial:        hex.EncodeToString(serial.Bytes()),
+		NotBefore:     template.NotBefore,
+		NotAfter:      template.NotAfter,
+		CACertificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})),
+	})
+}
+
+// describeCertCA returns the CA certificate of a key, e.g. to add
+// it to the trusted root certificates of servers and clients.
+func (s *Server) describeCertCA(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if key.Usage&kcrypto.UsageCert == 0 {
+		resp.Failr(errKeyCertNotPermitted)
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support certificates")
+		return
+	}
+	ca, _, err := certAuthority(req.Resource, &key)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to create CA certificate")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, api.CertCAResponse{
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})),
+	})
+}
+
+// certAuthority returns the self-signed CA certificate and private
+// key of the key version. The key must have an HMAC key.
+//
+// The CA certificate is created on demand. Its subject, serial
+// number and validity only depend on the key version such that
+// all certificates created for the same key version identify
+// the same CA.
+func certAuthority(name string, key *kcrypto.KeyVersion) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	asymmetricKey := key.AsymmetricKey()
+	defer asymmetricKey.Destroy()
+
+	privateKey, err := asymmetricKey.CertKey()
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	h := sha256.Sum256(publicKey)
+
+	template := &x509.Certificate{
+		SerialNumber:          new(big.Int).SetBytes(h[:16]),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             key.CreatedAt.UTC(),
+		NotAfter:              key.CreatedAt.Add(certCAValidity).UTC(),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+		SubjectKeyId:          h[:20],
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ca, privateKey, nil
+}
+
+// parsePublicKeyPEM parses s as PEM-encoded PKIX public key.
+func parsePublicKeyPEM(s string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("no PEM-encoded public key found")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestIssueCert(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, &Config{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"cert": {
+				Allow: map[string]kes.Rule{
+					"/v1/key/create/*": {},
+					"/v1/cert/issue/*": {},
+					"/v1/cert/ca/*":    {},
+				},
+				Cert: &CertPolicy{
+					Names:  []string{"*.svc.example.com"},
+					IPs:    []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+					MaxTTL: 48 * time.Hour,
+				},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-ca", api.CreateKeyRequest{Usage: []string{"cert"}}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate private key: %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+
+	var ca api.CertCAResponse
+	if err = doRequest(ctx, client, http.MethodGet, api.PathCertCA+"my-ca", nil, &ca); err != nil {
+		t.Fatalf("Failed to fetch CA certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM([]byte(ca.Certificate)) {
+		t.Fatal("Failed to parse CA certificate")
+	}
+
+	for i, test := range issueCertTests {
+		test.Request.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey}))
+
+		var resp api.IssueCertResponse
+		err := doRequest(ctx, client, http.MethodPut, api.PathCertIssue+test.Key, test.Request, &resp)
+		if test.Status != 0 {
+			if e, ok := api.IsError(err); !ok || e.Status() != test.Status {
+				t.Fatalf("Test %d: got error '%v' - want status %d", i, err, test.Status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to issue certificate: %v", i, err)
+		}
+
+		block, _ := pem.Decode([]byte(resp.Certificate))
+		if block == nil {
+			t.Fatalf("Test %d: response contains no PEM-encoded certificate", i)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse certificate: %v", i, err)
+		}
+		if cert.Subject.CommonName != test.Request.CommonName {
+			t.Fatalf("Test %d: invalid common name: got '%s' - want '%s'", i, cert.Subject.CommonName, test.Request.CommonName)
+		}
+		if !cert.PublicKey.(*ecdsa.PublicKey).Equal(privateKey.Public()) {
+			t.Fatalf("Test %d: certificate has been issued for another public key", i)
+		}
+		if _, err = cert.Verify(x509.VerifyOptions{
+			Roots:     roots,
+			KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}); err != nil {
+			t.Fatalf("Test %d: invalid certificate: %v", i, err)
+		}
+	}
+}
+
+var issueCertTests = []struct {
+	Key     string
+	Request api.IssueCertRequest
+	Status  int
+}{
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com"}},                                                                     // 0
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com", DNSNames: []string{"s3.svc.example.com"}, TTL: 3600}},                // 1
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com", IPAddresses: []string{"10.1.2.3"}}},                                  // 2
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.example.com"}, Status: http.StatusForbidden},                                           // 3
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com", IPAddresses: []string{"192.168.1.1"}}, Status: http.StatusForbidden}, // 4
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com", TTL: 72 * 3600}, Status: http.StatusForbidden},                       // 5
+	{Key: "my-ca", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com", Type: "server"}, Status: http.StatusForbidden},                       // 6
+	{Key: "my-ca", Request: api.IssueCertRequest{}, Status: http.StatusBadRequest},                                                                         // 7
+	{Key: "my-key", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com"}, Status: http.StatusForbidden},                                      // 8
+	{Key: "unknown", Request: api.IssueCertRequest{CommonName: "minio.svc.example.com"}, Status: http.StatusNotFound},                                      // 9
+}
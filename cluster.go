@@ -0,0 +1,551 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cluster"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+)
+
+// clusterOp is the operation of a clusterCommand.
+type clusterOp string
+
+// Operations replicated among cluster members.
+const (
+	clusterCreateKey clusterOp = "create_key"
+	clusterDeleteKey clusterOp = "delete_key"
+	clusterAssign    clusterOp = "assign_policy"
+)
+
+// clusterCommand is a state change that is replicated among
+// all cluster members and applied by each of them.
+type clusterCommand struct {
+	Op clusterOp `json:"op"`
+
+	// Name and Value of the key to create or delete. The value
+	// is encrypted with the cluster key.
+	Name  string `json:"name,omitempty"`
+	Value []byte `json:"value,omitempty"`
+
+	// Policy assigned to the identities until ExpiresAt,
+	// unless zero. IssuedBy is the identity that issued
+	// them, if any.
+	Policy     string         `json:"policy,omitempty"`
+	Identities []kes.Identity `json:"identities,omitempty"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	IssuedBy   kes.Identity   `json:"issued_by,omitempty"`
+}
+
+// clusterSnapshot is the state of a cluster member. It is
+// sent to members that have fallen too far behind to catch
+// up by replaying the replicated commands. Its key values
+// are encrypted with the cluster key.
+type clusterSnapshot struct {
+	Keys       map[string][]byte `json:"keys"`
+	Identities []clusterIdentity `json:"identities"`
+}
+
+// clusterIdentity is a policy assignment within a clusterSnapshot.
+type clusterIdentity struct {
+	Identity  kes.Identity `json:"identity"`
+	Policy    string       `json:"policy"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	IssuedBy  kes.Identity `json:"issued_by,omitempty"`
+}
+
+// clusterKeySize is the size of the key that encrypts the
+// keys replicated among cluster members.
+const clusterKeySize = 32
+
+// errClusterUnavailable is returned when a state change cannot be
+// replicated since this server is not, or no longer, a cluster member.
+var errClusterUnavailable = api.NewError(http.StatusServiceUnavailable, "cluster is not available")
+
+// startCluster joins or bootstraps the cluster, if this server is
+// not a member yet, and starts replicating state changes.
+func (s *Server) startCluster(ctx context.Context, conf *ClusterConfig) error {
+	key, err := crypto.NewSecretKey(crypto.AES256, conf.Key)
+	if err != nil {
+		return fmt.Errorf("kes: invalid cluster key: %v", err)
+	}
+	s.clusterKey = key
+	s.clusterPeers = slices.Clone(conf.Identities)
+
+	peers := conf.Peers
+	client := kes.NewClientWithConfig(conf.Addr, conf.TLS.Clone())
+	join := conf.Join != "" && !cluster.Initialized(conf.Dir)
+	if join {
+		members, err := joinCluster(ctx, &client.HTTPClient, conf.Join, conf.Addr)
+		if err != nil {
+			return fmt.Errorf("kes: failed to join cluster: %v", err)
+		}
+		peers = members
+	}
+
+	node, err := cluster.Start(&cluster.Config{
+		Addr:         conf.Addr,
+		Dir:          conf.Dir,
+		Peers:        peers,
+		Join:         join,
+		Path:         api.PathClusterRaft,
+		Client:       &client.HTTPClient,
+		StateMachine: (*clusterStateMachine)(s),
+		ErrorLog:     s.state.Load().Log,
+	})
+	if err != nil {
+		return err
+	}
+	s.cluster.Store(node)
+	return nil
+}
+
+// joinCluster asks the member at endpoint to add addr to its
+// cluster and returns the addresses of all members.
+func joinCluster(ctx context.Context, client *http.Client, endpoint, addr string) ([]string, error) {
+	const Timeout = 30 * time.Second
+
+	body, err := json.Marshal(api.AddClusterMemberRequest{Address: addr})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(endpoint, "/")+api.PathClusterAdd, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, api.ReadError(resp)
+	}
+	var add api.AddClusterMemberResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1*int64(mem.MiB))).Decode(&add); err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(add.Members))
+	for _, m := range add.Members {
+		members = append(members, m.Address)
+	}
+	return members, nil
+}
+
+// sealClusterValue encrypts the value of the key with the
+// cluster key before it is replicated.
+func (s *Server) sealClusterValue(name string, value []byte) ([]byte, error) {
+	return s.clusterKey.Encrypt(value, []byte(name))
+}
+
+// openClusterValue decrypts the replicated value of the key.
+func (s *Server) openClusterValue(name string, sealed []byte) ([]byte, error) {
+	value, err := s.clusterKey.Decrypt(sealed, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to decrypt replicated key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// propose replicates the command and waits until this server
+// has applied it.
+func (s *Server) propose(ctx context.Context, node *cluster.Node, cmd *clusterCommand) error {
+	b, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	return node.Propose(ctx, b)
+}
+
+// clusterKeyStore is a KeyStore that replicates key creations
+// and deletions to all cluster members. Each member applies them
+// to its local KeyStore. Reads are served by the local KeyStore.
+type clusterKeyStore struct {
+	server *Server
+	local  KeyStore
+}
+
+var _ KeyStore = (*clusterKeyStore)(nil) // compiler check
+
+func (ks *clusterKeyStore) String() string {
+	if s, ok := ks.local.(fmt.Stringer); ok {
+		return "Cluster: " + s.String()
+	}
+	return fmt.Sprintf("Cluster: %T", ks.local)
+}
+
+// Unwrap returns the local KeyStore.
+func (ks *clusterKeyStore) Unwrap() KeyStore { return ks.local }
+
+func (ks *clusterKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	return ks.local.Status(ctx)
+}
+
+func (ks *clusterKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	node := ks.server.cluster.Load()
+	if node == nil {
+		return errClusterUnavailable
+	}
+	sealed, err := ks.server.sealClusterValue(name, value)
+	if err != nil {
+		return err
+	}
+	return ks.server.propose(ctx, node, &clusterCommand{
+		Op:    clusterCreateKey,
+		Name:  name,
+		Value: sealed,
+	})
+}
+
+func (ks *clusterKeyStore) Delete(ctx context.Context, name string) error {
+	node := ks.server.cluster.Load()
+	if node == nil {
+		return errClusterUnavailable
+	}
+	return ks.server.propose(ctx, node, &clusterCommand{
+		Op:   clusterDeleteKey,
+		Name: name,
+	})
+}
+
+func (ks *clusterKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	return ks.local.Get(ctx, name)
+}
+
+func (ks *clusterKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	return ks.local.List(ctx, prefix, n)
+}
+
+func (ks *clusterKeyStore) Close() error { return ks.local.Close() }
+
+// clusterStateMachine applies replicated commands to the server
+// state and local KeyStore.
+//
+// Commands are applied again when the server restarts. Hence,
+// creating a key that exists with the same value is not an error.
+type clusterStateMachine Server
+
+// keys returns the server's key cache and the local KeyStore
+// wrapped by its clusterKeyStore.
+func (m *clusterStateMachine) keys() (*keyCache, KeyStore, error) {
+	cache := (*Server)(m).state.Load().Keys
+	for store := cache.store; store != nil; {
+		if ks, ok := store.(*clusterKeyStore); ok {
+			return cache, ks.local, nil
+		}
+		u, ok := store.(interface{ Unwrap() KeyStore })
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return nil, nil, errors.New("kes: key store is not replicated")
+}
+
+func (m *clusterStateMachine) Apply(b []byte) error {
+	var cmd clusterCommand
+	if err := json.Unmarshal(b, &cmd); err != nil {
+		return fmt.Errorf("kes: invalid cluster command: %v", err)
+	}
+
+	ctx := context.Background()
+	switch cmd.Op {
+	case clusterCreateKey:
+		cache, local, err := m.keys()
+		if err != nil {
+			return err
+		}
+		value, err := (*Server)(m).openClusterValue(cmd.Name, cmd.Value)
+		if err != nil {
+			return err
+		}
+		if err = local.Create(ctx, cmd.Name, value); errors.Is(err, kes.ErrKeyExists) {
+			if current, gErr := local.Get(ctx, cmd.Name); gErr == nil && bytes.Equal(current, value) {
+				err = nil
+			}
+		}
+		cache.invalidate(cmd.Name)
+		return err
+	case clusterDeleteKey:
+		cache, local, err := m.keys()
+		if err != nil {
+			return err
+		}
+		err = local.Delete(ctx, cmd.Name)
+		cache.invalidate(cmd.Name)
+		return err
+	case clusterAssign:
+		if _, err := (*Server)(m).assignIdentities(cmd.Policy, cmd.Identities, cmd.ExpiresAt, "", cmd.IssuedBy); err != nil {
+			return err
+		}
+		return nil
+	default:
+		return fmt.Errorf("kes: invalid cluster command '%s'", cmd.Op)
+	}
+}
+
+func (m *clusterStateMachine) Snapshot() ([]byte, error) {
+	_, local, err := m.keys()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	names, _, err := local.List(ctx, "", -1)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := clusterSnapshot{
+		Keys: make(map[string][]byte, len(names)),
+	}
+	for _, name := range names {
+		value, err := local.Get(ctx, name)
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if snapshot.Keys[name], err = (*Server)(m).sealClusterValue(name, value); err != nil {
+			return nil, err
+		}
+	}
+	for id, entry := range (*Server)(m).state.Load().Identities {
+		snapshot.Identities = append(snapshot.Identities, clusterIdentity{
+			Identity:  id,
+			Policy:    entry.Name,
+			ExpiresAt: entry.ExpiresAt,
+			IssuedBy:  entry.IssuedBy,
+		})
+	}
+	return json.Marshal(snapshot)
+}
+
+// Restore replaces the local keys by the keys of the snapshot
+// and assigns the snapshot's identities to their policies.
+// Identities of policies that do not exist on this server are
+// ignored.
+func (m *clusterStateMachine) Restore(b []byte) error {
+	var snapshot clusterSnapshot
+	if err := json.Unmarshal(b, &snapshot); err != nil {
+		return fmt.Errorf("kes: invalid cluster snapshot: %v", err)
+	}
+	for name, sealed := range snapshot.Keys {
+		value, err := (*Server)(m).openClusterValue(name, sealed)
+		if err != nil {
+			return err
+		}
+		snapshot.Keys[name] = value
+	}
+	cache, local, err := m.keys()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	names, _, err := local.List(ctx, "", -1)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, ok := snapshot.Keys[name]; !ok {
+			if err := local.Delete(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+				return err
+			}
+		}
+	}
+	for name, value := range snapshot.Keys {
+		current, err := local.Get(ctx, name)
+		if err == nil && bytes.Equal(current, value) {
+			continue
+		}
+		if err == nil {
+			if err = local.Delete(ctx, name); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+		if err = local.Create(ctx, name, value); err != nil {
+			return err
+		}
+	}
+	cache.invalidateAll()
+
+	s := (*Server)(m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.state.Load()
+	identities := maps.Clone(old.Identities)
+	for _, id := range snapshot.Identities {
+		policy, ok := old.Policies[id.Policy]
+		if !ok || id.Identity == old.Admin {
+			continue
+		}
+		identities[id.Identity] = identityEntry{
+			Name:        id.Policy,
+			Policy:      policy,
+			policyRules: old.PolicyRules[id.Policy],
+			ExpiresAt:   id.ExpiresAt,
+			IssuedBy:    id.IssuedBy,
+		}
+	}
+	state := *old
+	state.Identities = identities
+	s.state.Store(&state)
+	return nil
+}
+
+func (s *Server) clusterRaft(resp *api.Response, req *api.Request) {
+	node := s.cluster.Load()
+	if node == nil {
+		resp.Fail(http.StatusNotImplemented, "clustering is not enabled")
+		return
+	}
+	if !slices.Contains(s.clusterPeers, req.Identity) {
+		s.state.Load().Log.DebugContext(req.Context(), "access denied: identity is not a cluster member", "req", req)
+		resp.Failr(kes.ErrNotAllowed)
+		return
+	}
+
+	msg, err := io.ReadAll(req.Body)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+		resp.Fail(http.StatusBadRequest, "invalid raft message")
+		return
+	}
+	if err = node.Step(req.Context(), msg); err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	resp.Reply(http.StatusOK)
+}
+
+func (s *Server) listClusterMembers(resp *api.Response, req *api.Request) {
+	node := s.cluster.Load()
+	if node == nil {
+		resp.Fail(http.StatusNotImplemented, "clustering is not enabled")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, api.ListClusterMembersResponse{
+		Members: clusterMembers(node),
+	})
+}
+
+func (s *Server) addClusterMember(resp *api.Response, req *api.Request) {
+	node := s.cluster.Load()
+	if node == nil {
+		resp.Fail(http.StatusNotImplemented, "clustering is not enabled")
+		return
+	}
+
+	var add api.AddClusterMemberRequest
+	if err := api.ReadBody(req, &add); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid add cluster member request body")
+		return
+	}
+	if addr, err := url.Parse(add.Address); err != nil || addr.Scheme != "https" || addr.Host == "" {
+		resp.Failf(http.StatusBadRequest, "invalid cluster member address '%s'", add.Address)
+		return
+	}
+
+	member, err := node.AddMember(req.Context(), add.Address)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusServiceUnavailable, "failed to add cluster member")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("cluster member '%s' added", add.Address),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.AddClusterMemberResponse{
+		ID:      strconv.FormatUint(member.ID, 16),
+		Members: clusterMembers(node),
+	})
+}
+
+func (s *Server) removeClusterMember(resp *api.Response, req *api.Request) {
+	node := s.cluster.Load()
+	if node == nil {
+		resp.Fail(http.StatusNotImplemented, "clustering is not enabled")
+		return
+	}
+
+	id, err := strconv.ParseUint(req.Resource, 16, 64)
+	if err != nil {
+		resp.Failf(http.StatusBadRequest, "invalid cluster member ID '%s'", req.Resource)
+		return
+	}
+	switch err = node.RemoveMember(req.Context(), id); {
+	case errors.Is(err, cluster.ErrMemberNotFound):
+		resp.Failf(http.StatusNotFound, "cluster member '%s' does not exist", req.Resource)
+		return
+	case errors.Is(err, cluster.ErrLastMember):
+		resp.Fail(http.StatusBadRequest, "cannot remove the last cluster member")
+		return
+	case err != nil:
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusServiceUnavailable, "failed to remove cluster member")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("cluster member '%s' removed", req.Resource),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// clusterMembers returns the members of the node's cluster.
+func clusterMembers(node *cluster.Node) []api.ClusterMember {
+	members := node.Members()
+	list := make([]api.ClusterMember, 0, len(members))
+	for _, m := range members {
+		list = append(list, api.ClusterMember{
+			ID:      strconv.FormatUint(m.ID, 16),
+			Address: m.Addr,
+			Leader:  m.Leader,
+		})
+	}
+	return list
+}
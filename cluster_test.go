@@ -0,0 +1,203 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestCluster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping cluster test in short mode")
+	}
+	const Identity = "a4d5f1a8e7c3b2d6f9e0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2"
+	policies := map[string]Policy{
+		"my-policy": {Allow: map[string]kes.Rule{"/v1/key/encrypt/*": {}}},
+	}
+
+	ctx := testContext(t)
+	lnA, lnB := newLocalListener(), newLocalListener()
+	urlA, urlB := "https://"+lnA.Addr().String(), "https://"+lnB.Addr().String()
+	clientA, clientB := defaultClient(urlA), defaultClient(urlB)
+	clusterTLS := clientA.HTTPClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+	clusterKey := make([]byte, clusterKeySize)
+	if _, err := rand.Read(clusterKey); err != nil {
+		t.Fatalf("Failed to generate cluster key: %v", err)
+	}
+
+	srvA, _ := startServerOn(ctx, &Server{ShutdownTimeout: -1}, lnA, &Config{
+		Policies: policies,
+		Cluster: &ClusterConfig{
+			Addr:  urlA,
+			Dir:   t.TempDir(),
+			Peers: []string{urlA},
+			TLS:   clusterTLS,
+
+			Identities: []kes.Identity{defaultIdentity},
+			Key:        clusterKey,
+		},
+	})
+	defer srvA.Close()
+
+	// Wait until the first member has become the leader such
+	// that it can add the second member.
+	for {
+		list, err := listClusterMembers(ctx, clientA)
+		if err != nil {
+			t.Fatalf("Failed to list cluster members: %v", err)
+		}
+		if len(list.Members) == 1 && list.Members[0].Leader {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("No cluster leader has been elected: %v", ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	srvB, _ := startServerOn(ctx, &Server{ShutdownTimeout: -1}, lnB, &Config{
+		Policies: policies,
+		Cluster: &ClusterConfig{
+			Addr: urlB,
+			Dir:  t.TempDir(),
+			Join: urlA,
+			TLS:  clusterTLS,
+
+			Identities: []kes.Identity{defaultIdentity},
+			Key:        clusterKey,
+		},
+	})
+	defer srvB.Close()
+
+	list, err := listClusterMembers(ctx, clientB)
+	if err != nil {
+		t.Fatalf("Failed to list cluster members: %v", err)
+	}
+	if len(list.Members) != 2 {
+		t.Fatalf("Invalid number of cluster members: got '%d' - want '%d'", len(list.Members), 2)
+	}
+
+	if err = clientB.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := clientA.CreateKey(ctx, "my-key"); err != kes.ErrKeyExists {
+		t.Fatalf("Creating an existing key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	ciphertext, err := clientB.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	plaintext, err := clientA.Decrypt(ctx, "my-key", ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+	if string(plaintext) != "Hello World" {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+	}
+
+	err = sendRequest(ctx, clientA, http.MethodPost, api.PathPolicyAssign+"my-policy", api.AssignPolicyRequest{
+		Identities: []string{Identity},
+	})
+	if err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	waitForCommit := func(check func() bool) {
+		for !check() {
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Change has not been replicated: %v", ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	waitForCommit(func() bool {
+		info, err := clientB.DescribeIdentity(ctx, Identity)
+		return err == nil && info.Policy == "my-policy"
+	})
+
+	if err = clientA.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	waitForCommit(func() bool {
+		_, err := clientB.DescribeKey(ctx, "my-key")
+		return err == kes.ErrKeyNotFound
+	})
+}
+
+func TestClusterNotEnabled(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	_, err := listClusterMembers(ctx, defaultClient(url))
+	if e, ok := api.IsError(err); !ok || e.Status() != http.StatusNotImplemented {
+		t.Fatalf("Listing cluster members: got '%v' - want status '%d'", err, http.StatusNotImplemented)
+	}
+}
+
+func TestClusterRaftMembers(t *testing.T) {
+	const Identity = "a4d5f1a8e7c3b2d6f9e0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2"
+
+	ctx := testContext(t)
+	ln := newLocalListener()
+	url := "https://" + ln.Addr().String()
+	client := defaultClient(url)
+
+	srv, _ := startServerOn(ctx, &Server{ShutdownTimeout: -1}, ln, &Config{
+		Cluster: &ClusterConfig{
+			Addr:  url,
+			Dir:   t.TempDir(),
+			Peers: []string{url},
+			TLS:   client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.Clone(),
+
+			Identities: []kes.Identity{Identity},
+			Key:        make([]byte, clusterKeySize),
+		},
+	})
+	defer srv.Close()
+
+	// Only member identities can send raft messages - not
+	// even the admin.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url+api.PathClusterRaft, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Sending raft message as non-member: got status '%d' - want '%d'", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func listClusterMembers(ctx context.Context, client *kes.Client) (api.ListClusterMembersResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.Endpoints[0]+api.PathClusterList, nil)
+	if err != nil {
+		return api.ListClusterMembersResponse{}, err
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return api.ListClusterMembersResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return api.ListClusterMembersResponse{}, api.ReadError(resp)
+	}
+	var list api.ListClusterMembersResponse
+	err = json.NewDecoder(resp.Body).Decode(&list)
+	return list, err
+}
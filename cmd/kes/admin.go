@@ -0,0 +1,357 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const adminCmdUsage = `Usage:
+    kes admin <command>
+
+Commands:
+    reload                   Reload the server configuration.
+    freeze                   Put the server into read-only mode.
+    unfreeze                 Leave read-only mode.
+    log-level                Change log levels and audit sampling.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func adminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, adminCmdUsage) }
+
+	subCmds := commands{
+		"reload":    reloadAdminCmd,
+		"freeze":    freezeAdminCmd,
+		"unfreeze":  unfreezeAdminCmd,
+		"log-level": logLevelAdminCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not an admin command. See 'kes admin --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const reloadAdminCmdUsage = `Usage:
+    kes admin reload [options]
+
+Reloads the server configuration file, including policies,
+identities, cache settings, the keystore and the TLS certificate,
+private key and CA certificates. In-flight requests are not
+interrupted. It has the same effect as sending SIGHUP to the
+server process.
+
+If the new configuration is invalid, the server keeps using
+its current configuration.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin reload
+`
+
+func reloadAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, reloadAdminCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin reload --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes admin reload --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPost, api.PathAdminReload, nil, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to reload server configuration: %v", err)
+	}
+	fmt.Println("Reloaded server configuration")
+}
+
+const freezeAdminCmdUsage = `Usage:
+    kes admin freeze [options]
+
+Puts the server into read-only mode. While frozen, the server keeps
+serving cryptographic operations, like encrypt, decrypt or generate,
+but rejects any request that creates, imports, rotates or deletes
+keys, modifies secrets or policies or issues identities. Scheduled
+key rotations and the deletion of keys whose recovery window has
+passed are paused as well.
+
+The server stays frozen across config reloads but not across
+restarts. Use 'kes admin unfreeze' to leave read-only mode.
+
+Options:
+        --reason <text>      Reason for freezing the server. It is
+                             included in the error sent to clients.
+        --status             Only print the freeze status of the server.
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the freeze status in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin freeze --reason "keystore migration"
+    $ kes admin freeze --status
+`
+
+func freezeAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, freezeAdminCmdUsage) }
+
+	var (
+		reasonFlag         string
+		statusFlag         bool
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.StringVar(&reasonFlag, "reason", "", "Reason for freezing the server")
+	cmd.BoolVar(&statusFlag, "status", false, "Only print the freeze status of the server")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the freeze status in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin freeze --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 0:
+		cli.Fatal("too many arguments. See 'kes admin freeze --help'")
+	case statusFlag && reasonFlag != "":
+		cli.Fatal("'--status' and '--reason' cannot be used together. See 'kes admin freeze --help'")
+	}
+
+	req := api.FreezeRequest{Reason: reasonFlag}
+	if !statusFlag {
+		frozen := true
+		req.Frozen = &frozen
+	}
+	sendFreezeRequest(insecureSkipVerify, req, jsonFlag, "failed to freeze server")
+}
+
+const unfreezeAdminCmdUsage = `Usage:
+    kes admin unfreeze [options]
+
+Takes the server out of read-only mode such that it accepts
+requests that modify keys, secrets, policies or identities
+again. See 'kes admin freeze --help'.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the freeze status in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin unfreeze
+`
+
+func unfreezeAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, unfreezeAdminCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the freeze status in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin unfreeze --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes admin unfreeze --help'")
+	}
+
+	frozen := false
+	sendFreezeRequest(insecureSkipVerify, api.FreezeRequest{Frozen: &frozen}, jsonFlag, "failed to unfreeze server")
+}
+
+// sendFreezeRequest sends the request to the server's freeze API
+// and prints the server's freeze status.
+func sendFreezeRequest(insecureSkipVerify bool, req api.FreezeRequest, jsonFlag bool, errMsg string) {
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.FreezeResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathAdminFreeze, req, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("%s: %v", errMsg, err)
+	}
+
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	if !resp.Frozen {
+		fmt.Println("Server is not frozen")
+		return
+	}
+	fmt.Printf("Server is frozen since %s by %s", resp.FrozenAt.Local().Format(time.RFC1123), resp.FrozenBy)
+	if resp.Reason != "" {
+		fmt.Printf(": %s", resp.Reason)
+	}
+	fmt.Println()
+}
+
+const logLevelAdminCmdUsage = `Usage:
+    kes admin log-level [options]
+
+Changes the error and audit log levels of the server at runtime
+and prints the current settings. Without options, it only prints
+the current settings.
+
+Audit sampling reduces the audit log volume by logging only 1 in N
+events of successful requests, optionally only for the given API
+paths. Events of failed requests are always logged. Sampled audit
+logs cannot be verified with 'kes log verify' unless the server
+persists all events in its audit store.
+
+Log levels are reset to the levels of the config file when the
+server configuration is reloaded. Audit sampling is kept until the
+server restarts.
+
+Options:
+        --error <level>      Set the error log level: DEBUG, INFO, WARN
+                             or ERROR.
+        --audit <level>      Set the audit log level. Audit events are
+                             logged at level INFO. Any greater level,
+                             like ERROR, disables the audit log.
+        --audit-sample <N>   Log 1 in N audit events of successful
+                             requests. 0 or 1 disable sampling.
+        --audit-sample-path <pattern>
+                             Only sample requests to API paths that
+                             match the pattern. May be repeated.
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the settings in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes admin log-level --error DEBUG
+    $ kes admin log-level --audit-sample 1000 --audit-sample-path '/v1/key/decrypt/*'
+    $ kes admin log-level --audit-sample 0
+`
+
+func logLevelAdminCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, logLevelAdminCmdUsage) }
+
+	var (
+		errorFlag          string
+		auditFlag          string
+		sampleFlag         uint64
+		samplePathFlag     []string
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.StringVar(&errorFlag, "error", "", "Set the error log level")
+	cmd.StringVar(&auditFlag, "audit", "", "Set the audit log level")
+	cmd.Uint64Var(&sampleFlag, "audit-sample", 0, "Log 1 in N audit events of successful requests")
+	cmd.StringArrayVar(&samplePathFlag, "audit-sample-path", nil, "Only sample requests to API paths that match the pattern")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the settings in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes admin log-level --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 0:
+		cli.Fatal("too many arguments. See 'kes admin log-level --help'")
+	case len(samplePathFlag) > 0 && !cmd.Changed("audit-sample"):
+		cli.Fatal("'--audit-sample-path' requires '--audit-sample'. See 'kes admin log-level --help'")
+	}
+
+	var req api.LogLevelRequest
+	if cmd.Changed("error") {
+		req.ErrorLevel = &errorFlag
+	}
+	if cmd.Changed("audit") {
+		req.AuditLevel = &auditFlag
+	}
+	if cmd.Changed("audit-sample") {
+		req.AuditSampling = &api.AuditSampling{Rate: sampleFlag, Paths: samplePathFlag}
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.LogLevelResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathAdminLogLevel, req, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to change log level: %v", err)
+	}
+
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("Error log level: %s\n", resp.ErrorLevel)
+	fmt.Printf("Audit log level: %s\n", resp.AuditLevel)
+	switch {
+	case resp.AuditSampling.Rate <= 1:
+		fmt.Println("Audit sampling:  disabled")
+	case len(resp.AuditSampling.Paths) == 0:
+		fmt.Printf("Audit sampling:  1 in %d successful requests\n", resp.AuditSampling.Rate)
+	default:
+		fmt.Printf("Audit sampling:  1 in %d successful requests to %s\n", resp.AuditSampling.Rate, strings.Join(resp.AuditSampling.Paths, ", "))
+	}
+}
@@ -0,0 +1,573 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const applyCmdUsage = `Usage:
+    kes apply [options] -f <file>
+
+Reconciles the server with the keys, policies and policy assignments
+described by a state file. Keys, policies and assignments that are
+missing on the server are created. Policies whose rules or identities
+differ from the state are recreated. Since a policy is recreated by
+deleting it, its identities lose access until they are reassigned,
+usually within milliseconds.
+
+Keys and policies on the server that are not part of the state are
+only deleted with --prune. Existing keys are never modified. Policies
+of the server config cannot be changed or deleted.
+
+Options:
+    -f, --file <path>        Path of the state file. Use '-' to read the
+                             state from standard input.
+        --dry-run            Print the changes without applying them.
+        --prune              Delete keys and policies that are not part
+                             of the state.
+
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the changes in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+State file:
+    version: v1
+    keys:
+      - name: minio-key
+        usage: [encrypt, decrypt]     # optional
+        algorithm: AES256             # optional
+        rotate_every: 90d             # optional
+        tags:                         # optional
+          app: minio
+    policies:
+      minio:
+        allow:
+        - /v1/key/create/minio-*
+        - /v1/key/generate/minio-*
+        - /v1/key/decrypt/minio-*
+        deny: []                      # optional
+        identities:
+        - 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+
+Examples:
+    $ kes apply --dry-run -f state.yml
+    $ kes apply --prune -f state.yml
+`
+
+func applyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, applyCmdUsage) }
+
+	var (
+		fileFlag           string
+		dryRunFlag         bool
+		pruneFlag          bool
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Path of the state file")
+	cmd.BoolVar(&dryRunFlag, "dry-run", false, "Print the changes without applying them")
+	cmd.BoolVar(&pruneFlag, "prune", false, "Delete keys and policies that are not part of the state")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the changes in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes apply --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes apply --help'")
+	}
+	if fileFlag == "" {
+		cli.Fatal("no state file specified. Set the '--file' flag")
+	}
+
+	var (
+		b   []byte
+		err error
+	)
+	if fileFlag == "-" {
+		b, err = io.ReadAll(io.LimitReader(os.Stdin, 10<<20))
+	} else {
+		b, err = os.ReadFile(fileFlag)
+	}
+	if err != nil {
+		cli.Fatalf("failed to read state file: %v", err)
+	}
+	state, err := parseApplyState(b)
+	if err != nil {
+		cli.Fatalf("invalid state file '%s': %v", fileFlag, err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	current, err := fetchApplyState(ctx, client)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatal(err)
+	}
+	changes := planApply(state, current, pruneFlag)
+
+	var failed bool
+	if !dryRunFlag {
+		for i := range changes {
+			if err = changes[i].apply(ctx, client); err != nil {
+				if errors.Is(err, context.Canceled) {
+					os.Exit(1)
+				}
+				changes[i].Error = err.Error()
+				failed = true
+			}
+		}
+	}
+	printApplyChanges(changes, dryRunFlag, jsonFlag, colorFlag.Colorize())
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// applyState is the server state described by a state file.
+type applyState struct {
+	Version  string                 `yaml:"version"`
+	Keys     []applyKey             `yaml:"keys"`
+	Policies map[string]applyPolicy `yaml:"policies"`
+	Enclaves yaml.Node              `yaml:"enclaves"`
+}
+
+// applyKey is a key within a state file.
+type applyKey struct {
+	Name        string            `yaml:"name"`
+	Usage       []string          `yaml:"usage"`
+	Algorithm   string            `yaml:"algorithm"`
+	RotateEvery string            `yaml:"rotate_every"`
+	Derived     bool              `yaml:"derived"`
+	Tags        map[string]string `yaml:"tags"`
+}
+
+// applyPolicy is a policy, and the identities assigned
+// to it, within a state file.
+type applyPolicy struct {
+	Allow      []string `yaml:"allow"`
+	Deny       []string `yaml:"deny"`
+	Identities []string `yaml:"identities"`
+}
+
+// parseApplyState parses and validates a state file. The allow
+// and deny rules and identities of its policies are sorted and
+// deduplicated such that they can be compared to the server state.
+func parseApplyState(b []byte) (*applyState, error) {
+	decoder := yaml.NewDecoder(strings.NewReader(string(b)))
+	decoder.KnownFields(true)
+
+	var state applyState
+	if err := decoder.Decode(&state); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if state.Version != "" && state.Version != "v1" {
+		return nil, fmt.Errorf("unsupported version '%s'", state.Version)
+	}
+	if !state.Enclaves.IsZero() {
+		return nil, errors.New("enclaves are not supported by KES servers. Describe keys and policies at the top level")
+	}
+
+	names := make(map[string]bool, len(state.Keys))
+	for _, key := range state.Keys {
+		if key.Name == "" {
+			return nil, errors.New("key without name")
+		}
+		if names[key.Name] {
+			return nil, fmt.Errorf("key '%s' is specified more than once", key.Name)
+		}
+		names[key.Name] = true
+
+		if _, err := crypto.ParseKeyUsage(key.Usage); err != nil {
+			return nil, fmt.Errorf("key '%s': invalid usage '%s'", key.Name, strings.Join(key.Usage, ","))
+		}
+		if key.Algorithm != "" {
+			if _, err := crypto.ParseSecretKeyType(key.Algorithm); err != nil {
+				return nil, fmt.Errorf("key '%s': invalid algorithm '%s'", key.Name, key.Algorithm)
+			}
+		}
+		if key.RotateEvery != "" {
+			if _, err := parseInterval(key.RotateEvery); err != nil {
+				return nil, fmt.Errorf("key '%s': invalid rotation interval '%s'", key.Name, key.RotateEvery)
+			}
+		}
+	}
+
+	assigned := make(map[string]string)
+	for name, policy := range state.Policies {
+		if len(policy.Allow) == 0 && len(policy.Deny) == 0 {
+			return nil, fmt.Errorf("policy '%s': no allow or deny rules specified", name)
+		}
+		for _, rule := range append(slices.Clone(policy.Allow), policy.Deny...) {
+			if !strings.HasPrefix(rule, "/") {
+				return nil, fmt.Errorf("policy '%s': invalid rule '%s': must be an API path pattern", name, rule)
+			}
+		}
+		for _, id := range policy.Identities {
+			if other, ok := assigned[id]; ok && other != name {
+				return nil, fmt.Errorf("identity '%s' is assigned to policy '%s' and '%s'", id, other, name)
+			}
+			assigned[id] = name
+		}
+		slices.Sort(policy.Allow)
+		slices.Sort(policy.Deny)
+		slices.Sort(policy.Identities)
+		policy.Allow = slices.Compact(policy.Allow)
+		policy.Deny = slices.Compact(policy.Deny)
+		policy.Identities = slices.Compact(policy.Identities)
+		state.Policies[name] = policy
+	}
+	return &state, nil
+}
+
+// fetchApplyState returns the keys and the policies, including
+// the identities assigned to them, of the server.
+func fetchApplyState(ctx context.Context, client *kes.Client) (*applyState, error) {
+	state := &applyState{Policies: map[string]applyPolicy{}}
+
+	keys := &kes.ListIter[string]{NextFunc: client.ListKeys}
+	for {
+		name, err := keys.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %v", err)
+		}
+		state.Keys = append(state.Keys, applyKey{Name: name})
+	}
+
+	policies := &kes.ListIter[string]{NextFunc: client.ListPolicies}
+	for {
+		name, err := policies.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list policies: %v", err)
+		}
+
+		policy, err := client.GetPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy '%s': %v", name, err)
+		}
+		state.Policies[name] = applyPolicy{Allow: sortedKeys(policy.Allow), Deny: sortedKeys(policy.Deny)}
+	}
+
+	identities := &kes.ListIter[kes.Identity]{NextFunc: client.ListIdentities}
+	for {
+		id, err := identities.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list identities: %v", err)
+		}
+
+		info, err := client.DescribeIdentity(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe identity '%s': %v", id, err)
+		}
+		if policy, ok := state.Policies[info.Policy]; ok && !info.IsAdmin {
+			policy.Identities = append(policy.Identities, id.String())
+			state.Policies[info.Policy] = policy
+		}
+	}
+	for name, policy := range state.Policies {
+		slices.Sort(policy.Identities)
+		state.Policies[name] = policy
+	}
+	return state, nil
+}
+
+// Operations of an applyChange.
+const (
+	applyCreate = "create"
+	applyUpdate = "update"
+	applyDelete = "delete"
+)
+
+// applyChange is a single change required to reconcile the
+// server with a state file.
+type applyChange struct {
+	Op     string `json:"op"`
+	Kind   string `json:"kind"` // key, policy or identity
+	Name   string `json:"name"`
+	Detail string `json:"detail,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	key    *applyKey
+	policy *applyPolicy
+	target string // Policy an identity gets assigned to
+}
+
+// planApply returns the changes that reconcile the current server
+// state with the desired state. Keys are created first and deleted
+// last such that policies never refer to keys being created. If
+// prune is false, keys and policies missing from the desired state
+// are kept.
+func planApply(desired, current *applyState, prune bool) []applyChange {
+	var changes []applyChange
+
+	existing := make(map[string]bool, len(current.Keys))
+	for _, key := range current.Keys {
+		existing[key.Name] = true
+	}
+	for i := range desired.Keys {
+		if key := &desired.Keys[i]; !existing[key.Name] {
+			changes = append(changes, applyChange{Op: applyCreate, Kind: "key", Name: key.Name, key: key})
+		}
+	}
+
+	// An identity that moves to another policy is removed from its
+	// current policy by the assignment. Hence, its current policy
+	// does not need to be recreated.
+	moved := make(map[string]bool)
+	for name, policy := range desired.Policies {
+		for _, id := range policy.Identities {
+			if !slices.Contains(current.Policies[name].Identities, id) {
+				moved[id] = true
+			}
+		}
+	}
+
+	var assignments []applyChange
+	for _, name := range sortedKeys(desired.Policies) {
+		policy := desired.Policies[name]
+		cur, ok := current.Policies[name]
+		if !ok {
+			changes = append(changes, applyChange{Op: applyCreate, Kind: "policy", Name: name, policy: &policy})
+			for _, id := range policy.Identities {
+				assignments = append(assignments, applyChange{Op: applyCreate, Kind: "identity", Name: id, Detail: "policy " + name, target: name})
+			}
+			continue
+		}
+
+		var details []string
+		if added, removed := diffSorted(cur.Allow, policy.Allow); added+removed > 0 {
+			details = append(details, fmt.Sprintf("allow +%d -%d", added, removed))
+		}
+		if added, removed := diffSorted(cur.Deny, policy.Deny); added+removed > 0 {
+			details = append(details, fmt.Sprintf("deny +%d -%d", added, removed))
+		}
+		var unassigned int
+		for _, id := range cur.Identities {
+			if !slices.Contains(policy.Identities, id) && !moved[id] {
+				unassigned++
+			}
+		}
+		if unassigned > 0 {
+			details = append(details, fmt.Sprintf("identities -%d", unassigned))
+		}
+		if len(details) > 0 {
+			changes = append(changes, applyChange{Op: applyUpdate, Kind: "policy", Name: name, Detail: strings.Join(details, ", "), policy: &policy})
+			for _, id := range policy.Identities {
+				op := applyCreate
+				if slices.Contains(cur.Identities, id) {
+					op = applyUpdate // Reassigned after recreating the policy
+				}
+				assignments = append(assignments, applyChange{Op: op, Kind: "identity", Name: id, Detail: "policy " + name, target: name})
+			}
+			continue
+		}
+		for _, id := range policy.Identities {
+			if !slices.Contains(cur.Identities, id) {
+				assignments = append(assignments, applyChange{Op: applyCreate, Kind: "identity", Name: id, Detail: "policy " + name, target: name})
+			}
+		}
+	}
+	changes = append(changes, assignments...)
+
+	if prune {
+		for _, name := range sortedKeys(current.Policies) {
+			if _, ok := desired.Policies[name]; !ok {
+				changes = append(changes, applyChange{Op: applyDelete, Kind: "policy", Name: name})
+			}
+		}
+		wanted := make(map[string]bool, len(desired.Keys))
+		for _, key := range desired.Keys {
+			wanted[key.Name] = true
+		}
+		for _, key := range current.Keys {
+			if !wanted[key.Name] {
+				changes = append(changes, applyChange{Op: applyDelete, Kind: "key", Name: key.Name})
+			}
+		}
+	}
+	return changes
+}
+
+// diffSorted returns how many elements of the sorted slice b
+// are not in the sorted slice a, and vice versa.
+func diffSorted(a, b []string) (added, removed int) {
+	for _, s := range b {
+		if _, ok := slices.BinarySearch(a, s); !ok {
+			added++
+		}
+	}
+	for _, s := range a {
+		if _, ok := slices.BinarySearch(b, s); !ok {
+			removed++
+		}
+	}
+	return added, removed
+}
+
+// sortedKeys returns the keys of m in sorted order.
+func sortedKeys[M ~map[string]V, V any](m M) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// apply applies the change to the server.
+func (c *applyChange) apply(ctx context.Context, client *kes.Client) error {
+	switch {
+	case c.Kind == "key" && c.Op == applyCreate:
+		var rotationInterval int64
+		if c.key.RotateEvery != "" {
+			d, err := parseInterval(c.key.RotateEvery)
+			if err != nil {
+				return err
+			}
+			rotationInterval = int64(d.Seconds())
+		}
+		return sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+c.Name, api.CreateKeyRequest{
+			Tags:             c.key.Tags,
+			Usage:            c.key.Usage,
+			RotationInterval: rotationInterval,
+			Algorithm:        c.key.Algorithm,
+			Derived:          c.key.Derived,
+		}, nil)
+	case c.Kind == "key" && c.Op == applyDelete:
+		return sendRequest(ctx, client, http.MethodDelete, api.PathKeyDelete+c.Name, nil, nil)
+
+	case c.Kind == "policy" && c.Op == applyDelete:
+		return sendRequest(ctx, client, http.MethodDelete, api.PathPolicyDelete+c.Name, nil, nil)
+	case c.Kind == "policy":
+		if c.Op == applyUpdate {
+			if err := sendRequest(ctx, client, http.MethodDelete, api.PathPolicyDelete+c.Name, nil, nil); err != nil {
+				return err
+			}
+		}
+		return sendRequest(ctx, client, http.MethodPut, api.PathPolicyCreate+c.Name, api.CreatePolicyRequest{
+			Allow: c.policy.Allow,
+			Deny:  c.policy.Deny,
+		}, nil)
+
+	case c.Kind == "identity":
+		return sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+c.target, api.AssignPolicyRequest{
+			Identities: []string{c.Name},
+		}, nil)
+	default:
+		return fmt.Errorf("unknown change '%s %s'", c.Op, c.Kind)
+	}
+}
+
+// printApplyChanges prints the changes to STDOUT, either as JSON
+// or as one, optionally colored, line per change followed by a
+// summary.
+func printApplyChanges(changes []applyChange, dryRun, jsonFlag, colorize bool) {
+	if jsonFlag {
+		if changes == nil {
+			changes = []applyChange{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(changes); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes. The server matches the state.")
+		return
+	}
+
+	var faint, createStyle, updateStyle, deleteStyle, failStyle tui.Style
+	if colorize {
+		const (
+			ColorCreate tui.Color = "#00d700"
+			ColorUpdate tui.Color = "#d7af00"
+			ColorDelete tui.Color = "#d70000"
+		)
+		faint = faint.Faint(true)
+		createStyle = createStyle.Foreground(ColorCreate).Bold(true)
+		updateStyle = updateStyle.Foreground(ColorUpdate).Bold(true)
+		deleteStyle = deleteStyle.Foreground(ColorDelete).Bold(true)
+		failStyle = failStyle.Foreground(ColorDelete)
+	}
+	var created, updated, deleted, failed int
+	for _, c := range changes {
+		var op string
+		switch c.Op {
+		case applyCreate:
+			op = createStyle.Render("+")
+			created++
+		case applyUpdate:
+			op = updateStyle.Render("~")
+			updated++
+		default:
+			op = deleteStyle.Render("-")
+			deleted++
+		}
+		line := fmt.Sprintf("%s %-8s %s", op, c.Kind, c.Name)
+		if c.Detail != "" {
+			line += " " + faint.Render("("+c.Detail+")")
+		}
+		if c.Error != "" {
+			line += " " + failStyle.Render("failed: "+c.Error)
+			failed++
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println()
+	switch {
+	case dryRun:
+		fmt.Printf("Plan: %d to create, %d to update, %d to delete.\n", created, updated, deleted)
+	case failed > 0:
+		fmt.Printf("Applied %d of %d changes. %d failed.\n", len(changes)-failed, len(changes), failed)
+	default:
+		fmt.Printf("Applied: %d created, %d updated, %d deleted.\n", created, updated, deleted)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const approvalCmdUsage = `Usage:
+    kes approval <command>
+
+Commands:
+    ls                       List requests waiting for approval.
+    approve                  Approve and execute a pending request.
+    deny                     Deny a pending request.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func approvalCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, approvalCmdUsage) }
+
+	subCmds := commands{
+		"ls":      lsApprovalCmd,
+		"approve": approveApprovalCmd,
+		"deny":    denyApprovalCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes approval --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not an approval command. See 'kes approval --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const lsApprovalCmdUsage = `Usage:
+    kes approval ls [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print pending requests in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes approval ls
+`
+
+func lsApprovalCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsApprovalCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print pending requests in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes approval ls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes approval ls --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var list api.ListApprovalsResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathApprovalList, nil, &list); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list pending requests: %v", err)
+	}
+
+	if jsonFlag {
+		if list.Requests == nil {
+			list.Requests = []api.PendingRequest{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(list.Requests); err != nil {
+			cli.Fatalf("failed to list pending requests: %v", err)
+		}
+		return
+	}
+
+	var (
+		header = tui.NewStyle().Underline(colorFlag.Colorize())
+		buf    = &strings.Builder{}
+	)
+	fmt.Fprintf(buf, "%s %s %s %s\n",
+		header.Render(fmt.Sprintf("%-32s", "ID")),
+		header.Render(fmt.Sprintf("%-19s", "Expires")),
+		header.Render(fmt.Sprintf("%-16s", "Identity")),
+		header.Render("Request"),
+	)
+	for _, r := range list.Requests {
+		identity := r.Identity
+		if len(identity) > 16 {
+			identity = identity[:13] + "..."
+		}
+		expires := r.ExpiresAt.Local().Format(time.DateTime)
+		fmt.Fprintf(buf, "%-32s %-19s %-16s %s %s\n", r.ID, expires, identity, r.Method, r.Path)
+	}
+	fmt.Print(buf)
+}
+
+const approveApprovalCmdUsage = `Usage:
+    kes approval approve [options] <id>
+
+Approves the pending request with the given ID, as listed by
+'kes approval ls', and executes it. A request cannot be approved
+by the identity that sent it, by an identity it has issued with
+'kes identity new --ttl', or by the identity that issued it.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes approval approve 3c1bd2f3a8e0c6d4b5f7e9a1c2d3e4f5
+`
+
+func approveApprovalCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, approveApprovalCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes approval approve --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no request ID specified. See 'kes approval approve --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes approval approve --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	id := cmd.Arg(0)
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathApprovalApprove+id, nil, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to approve request '%s': %v", id, err)
+	}
+	fmt.Printf("Approved request '%s'\n", id)
+}
+
+const denyApprovalCmdUsage = `Usage:
+    kes approval deny [options] <id>
+
+Denies the pending request with the given ID, as listed by
+'kes approval ls'. The identity that sent the request can deny
+it as well to withdraw it.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes approval deny 3c1bd2f3a8e0c6d4b5f7e9a1c2d3e4f5
+`
+
+func denyApprovalCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, denyApprovalCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes approval deny --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no request ID specified. See 'kes approval deny --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes approval deny --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	id := cmd.Arg(0)
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathApprovalDeny+id, nil, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to deny request '%s': %v", id, err)
+	}
+	fmt.Printf("Denied request '%s'\n", id)
+}
@@ -29,35 +29,102 @@ func complete(cmd string) bool {
 	}
 
 	completion := map[string][]string{
-		cmd:             {"server", "key", "policy", "identity", "log", "status", "metric", "update"},
-		cmd + " server": {"--config", "--addr", "--auth"},
-		cmd + " log":    {"--audit", "--error", "--json", "--insecure"},
-		cmd + " status": {"--short", "--api", "--json", "--color", "--insecure"},
-		cmd + " metric": {"--rate", "--insecure"},
-		cmd + " update": {"--downgrade", "--output", "--os", "--arch", "--minisign-key", "--insecure"},
-
-		cmd + " key":         {"create", "import", "info", "ls", "rm", "encrypt", "decrypt", "dek"},
-		cmd + " key create":  {"--insecure"},
-		cmd + " key import":  {"--insecure"},
-		cmd + " key info":    {"--insecure", "--json", "--color"},
-		cmd + " key ls":      {"--insecure", "--json", "--color"},
-		cmd + " key rm":      {"--insecure"},
-		cmd + " key encrypt": {"--insecure"},
-		cmd + " key decrypt": {"--insecure"},
-		cmd + " key dek":     {"--insecure"},
-
-		cmd + " policy":      {"info", "ls", "rm", "show"},
-		cmd + " policy info": {"--insecure", "--json", "--color"},
-		cmd + " policy ls":   {"--insecure", "--json", "--color"},
-		cmd + " policy rm":   {"--insecure"},
-		cmd + " policy show": {"--insecure", "--json"},
-
-		cmd + " identity":      {"new", "of", "info", "ls", "rm"},
-		cmd + " identity new":  {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt"},
-		cmd + " identity of":   {},
-		cmd + " identity info": {"--insecure", "--json", "--color"},
-		cmd + " identity ls":   {"--insecure", "--json", "--color"},
-		cmd + " identity rm":   {"--insecure"},
+		cmd:             {"server", "proxy", "init", "key", "policy", "identity", "approval", "ssh", "cert", "secret", "context", "log", "watch", "status", "metric", "doctor", "compat", "support-bundle", "inspect", "debug", "benchmark", "admin", "unseal", "cluster", "tpm", "backup", "apply", "update"},
+		cmd + " server": {"install", "uninstall", "--config", "--addr", "--auth", "--selftest", "--join", "--validate"},
+
+		cmd + " proxy":            {"--addr", "--cert", "--key", "--client-cert", "--client-key", "--ca", "--insecure", "--cache", "--cache-ttl", "--cache-size"},
+		cmd + " server install":   {"--config", "--addr", "--name"},
+		cmd + " server uninstall": {"--name"},
+		cmd + " init":             {"--ip", "--dns", "--cache", "--log", "--keystore", "--keystore-opt", "--yes", "--force"},
+		cmd + " log":              {"verify", "--audit", "--error", "--json", "--insecure"},
+		cmd + " log verify":       {"--key", "--json"},
+		cmd + " watch":            {"--type", "--json", "--insecure"},
+		cmd + " status":           {"--short", "--api", "--json", "--color", "--insecure", "--watch", "--rate"},
+		cmd + " metric":           {"--rate", "--json", "--insecure"},
+		cmd + " doctor":           {"--json", "--color", "--insecure"},
+		cmd + " compat":           {"minio"},
+		cmd + " compat minio":     {"--identity", "--key", "--policy", "--no-setup", "--access-key", "--secret-key", "--bucket", "--insecure", "--json", "--color"},
+
+		cmd + " support-bundle":  {"--output", "--insecure"},
+		cmd + " inspect":         {"--no-keys", "--output", "--verify"},
+		cmd + " benchmark":       {"--op", "--key", "--concurrency", "--duration", "--size", "--json", "--insecure"},
+		cmd + " admin":           {"reload", "freeze", "unfreeze", "log-level"},
+		cmd + " admin reload":    {"--insecure"},
+		cmd + " admin freeze":    {"--reason", "--status", "--insecure", "--json"},
+		cmd + " admin unfreeze":  {"--insecure", "--json"},
+		cmd + " admin log-level": {"--error", "--audit", "--audit-sample", "--audit-sample-path", "--insecure", "--json"},
+		cmd + " unseal":          {"init", "--status", "--insecure", "--json"},
+		cmd + " unseal init":     {"--shares", "--threshold", "--json"},
+		cmd + " cluster":         {"ls", "add", "rm"},
+		cmd + " cluster ls":      {"--insecure", "--json", "--color"},
+		cmd + " cluster add":     {"--insecure"},
+		cmd + " cluster rm":      {"--insecure"},
+		cmd + " tpm":             {"seal"},
+		cmd + " tpm seal":        {"--pcr", "--device", "--force"},
+		cmd + " update":          {"--downgrade", "--output", "--os", "--arch", "--channel", "--mirror", "--file", "--minisign-key", "--dry-run", "--insecure"},
+
+		cmd + " key":          {"create", "import", "export", "restore", "rotate", "info", "ls", "search", "rm", "undelete", "encrypt", "decrypt", "dek", "hmac", "sign", "verify"},
+		cmd + " key create":   {"--insecure", "--file", "--tag", "--usage", "--expires", "--rotate-every", "--algorithm", "--derived"},
+		cmd + " key import":   {"--file", "--cipher", "--tag", "--insecure"},
+		cmd + " key export":   {"--wrap-with", "--output", "--insecure"},
+		cmd + " key restore":  {"--insecure"},
+		cmd + " key rotate":   {"--insecure"},
+		cmd + " key info":     {"--insecure", "--json", "--color"},
+		cmd + " key ls":       {"--insecure", "--json", "--color", "--long", "--page-size", "--limit", "--reverse"},
+		cmd + " key search":   {"--insecure", "--json", "--color"},
+		cmd + " key rm":       {"--insecure", "--pattern", "--yes"},
+		cmd + " key undelete": {"--insecure"},
+		cmd + " key encrypt":  {"--file", "--context", "--stream", "--output", "--insecure", "--json"},
+		cmd + " key decrypt":  {"--file", "--context", "--stream", "--output", "--insecure", "--json"},
+		cmd + " key hmac":     {"--file", "--hex", "--insecure", "--json"},
+		cmd + " key sign":     {"--file", "--algorithm", "--insecure", "--json"},
+		cmd + " key verify":   {"--file", "--algorithm", "--insecure", "--json"},
+		cmd + " key dek":      {"--insecure", "--json"},
+
+		cmd + " policy":        {"assign", "create", "info", "ls", "rm", "show", "test"},
+		cmd + " policy assign": {"--insecure", "--from", "--expiry", "--json"},
+		cmd + " policy create": {"--insecure", "--allow", "--deny"},
+		cmd + " policy info":   {"--insecure", "--json", "--color"},
+		cmd + " policy ls":     {"--insecure", "--json", "--color", "--long", "--created-after", "--sort"},
+		cmd + " policy rm":     {"--insecure"},
+		cmd + " policy show":   {"--insecure", "--json"},
+		cmd + " policy test":   {"--insecure", "--json"},
+
+		cmd + " debug":         {"profile"},
+		cmd + " debug profile": {"--cpu", "--trace", "--block", "--mutex", "--heap", "--allocs", "--goroutine", "--threadcreate", "--output", "--insecure"},
+
+		cmd + " backup":         {"create", "verify", "restore"},
+		cmd + " backup create":  {"--wrap-with", "--output", "--insecure", "--json"},
+		cmd + " backup verify":  {"--insecure", "--json"},
+		cmd + " backup restore": {"--insecure", "--json"},
+		cmd + " apply":          {"--file", "--dry-run", "--prune", "--insecure", "--json", "--color"},
+
+		cmd + " identity":       {"new", "renew", "of", "info", "ls", "rm"},
+		cmd + " identity new":   {"--key", "--cert", "--force", "--ip", "--dns", "--expiry", "--encrypt", "--json", "--ttl", "--policy", "--insecure"},
+		cmd + " identity renew": {"--grace", "--insecure", "--json"},
+		cmd + " identity of":    {"--json"},
+		cmd + " identity info":  {"--insecure", "--json", "--color"},
+		cmd + " identity ls":    {"--insecure", "--json", "--color", "--long", "--policy", "--created-after", "--sort"},
+		cmd + " identity rm":    {"--insecure"},
+
+		cmd + " approval":         {"ls", "approve", "deny"},
+		cmd + " approval ls":      {"--insecure", "--json", "--color"},
+		cmd + " approval approve": {"--insecure"},
+		cmd + " approval deny":    {"--insecure"},
+		cmd + " ssh":              {"sign", "ca"},
+		cmd + " ssh sign":         {"--principal", "--ttl", "--host", "--id", "--output", "--insecure", "--json"},
+		cmd + " ssh ca":           {"--insecure", "--json"},
+		cmd + " cert":             {"issue", "ca"},
+		cmd + " cert issue":       {"--cn", "--dns", "--ip", "--ttl", "--server", "--key", "--cert", "--ca", "--public-key", "--force", "--insecure", "--json"},
+		cmd + " cert ca":          {"--insecure", "--json"},
+		cmd + " secret":           {"set", "get", "ls", "rm"},
+		cmd + " secret set":       {"--key", "--file", "--insecure"},
+		cmd + " secret get":       {"--insecure", "--json"},
+		cmd + " secret ls":        {"--insecure", "--json"},
+		cmd + " secret rm":        {"--insecure"},
+		cmd + " context":          {"add", "use", "ls", "rm"},
+		cmd + " context add":      {"--server", "--api-key", "--cert", "--key", "--from-env", "--use", "--force"},
+		cmd + " context ls":       {"--json"},
 	}
 
 	fields := strings.Fields(line)
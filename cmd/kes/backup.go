@@ -0,0 +1,714 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const backupCmdUsage = `Usage:
+    kes backup <command>
+
+Commands:
+    create                   Create a backup archive.
+    verify                   Verify a backup archive.
+    restore                  Restore a backup archive.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func backupCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, backupCmdUsage) }
+
+	subCmds := commands{
+		"create":  createBackupCmd,
+		"verify":  verifyBackupCmd,
+		"restore": restoreBackupCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes backup --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a backup command. See 'kes backup --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const createBackupCmdUsage = `Usage:
+    kes backup create [options] --wrap-with <kek>
+
+Creates a backup archive of all keys, policies and policy
+assignments of the server. Keys are exported wrapped by the
+key encryption key (KEK). The archive itself is encrypted
+with a data key generated by the KEK. Hence, the archive can
+only be verified and restored by a server that has the KEK.
+
+The archive contains a manifest with the number of items and
+a checksum for keys, policies and identities. It is checked
+when verifying or restoring the archive.
+
+Options:
+    -w, --wrap-with <kek>    Name of the key that wraps the archive.
+    -o, --output <path>      Write the archive to the given file.
+                             Defaults to: kes-backup-<time>.kesbak
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the result in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes backup create --wrap-with backup-kek
+    $ kes backup create -w backup-kek -o kes.kesbak
+`
+
+func createBackupCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, createBackupCmdUsage) }
+
+	var (
+		kekFlag            string
+		outputFlag         string
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&kekFlag, "wrap-with", "w", "", "Name of the key that wraps the archive")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the archive to the given file")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the archive manifest in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes backup create --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 0:
+		cli.Fatal("too many arguments. See 'kes backup create --help'")
+	case kekFlag == "":
+		cli.Fatal("no key encryption key specified. Set the '--wrap-with' flag")
+	}
+	if outputFlag == "" {
+		outputFlag = fmt.Sprintf("kes-backup-%s.kesbak", time.Now().UTC().Format("2006-01-02T15-04-05"))
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	archive, err := collectBackup(ctx, client, kekFlag)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to create backup: %v", err)
+	}
+	data, err := sealBackup(ctx, client, kekFlag, archive)
+	if err != nil {
+		cli.Fatalf("failed to create backup: %v", err)
+	}
+
+	file, err := os.OpenFile(outputFlag, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		cli.Fatalf("failed to create backup: %v", err)
+	}
+	if _, err = file.Write(data); err == nil {
+		err = file.Sync()
+	}
+	if cErr := file.Close(); err == nil {
+		err = cErr
+	}
+	if err != nil {
+		os.Remove(outputFlag)
+		cli.Fatalf("failed to create backup: %v", err)
+	}
+
+	if jsonFlag {
+		printBackupJSON(archive.Manifest)
+		return
+	}
+	fmt.Printf("Created backup '%s': %d keys, %d policies, %d identities\n",
+		outputFlag, archive.Manifest.Keys.Count, archive.Manifest.Policies.Count, archive.Manifest.Identities.Count)
+}
+
+const verifyBackupCmdUsage = `Usage:
+    kes backup verify [options] <file>
+
+Decrypts the backup archive and checks its content against
+the manifest without modifying the server. The server must
+have the key encryption key (KEK) that wrapped the archive.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the result in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes backup verify kes.kesbak
+`
+
+func verifyBackupCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, verifyBackupCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the result in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes backup verify --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no backup archive specified. See 'kes backup verify --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes backup verify --help'")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	archive, _, err := openBackup(ctx, newClient(insecureSkipVerify), cmd.Arg(0))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("invalid backup '%s': %v", cmd.Arg(0), err)
+	}
+
+	if jsonFlag {
+		printBackupJSON(archive.Manifest)
+		return
+	}
+	m := archive.Manifest
+	fmt.Printf("Backup '%s' is valid\n", cmd.Arg(0))
+	fmt.Printf("  Created:    %s\n", m.CreatedAt.Local().Format(time.RFC1123))
+	fmt.Printf("  Server:     %s\n", m.Server)
+	fmt.Printf("  KEK:        %s\n", m.KEK)
+	fmt.Printf("  Keys:       %d (sha256 %s)\n", m.Keys.Count, m.Keys.Checksum)
+	fmt.Printf("  Policies:   %d (sha256 %s)\n", m.Policies.Count, m.Policies.Checksum)
+	fmt.Printf("  Identities: %d (sha256 %s)\n", m.Identities.Count, m.Identities.Checksum)
+}
+
+const restoreBackupCmdUsage = `Usage:
+    kes backup restore [options] <file>
+
+Restores all keys, policies and policy assignments of the backup
+archive. The server must have the key encryption key (KEK) that
+wrapped the archive. The archive is verified before anything is
+restored.
+
+Keys and policies that exist already are not modified. Policies
+and assignments are only persisted across restarts if the server
+has a policy store. Assignments that have expired are skipped.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the result in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes backup restore kes.kesbak
+`
+
+func restoreBackupCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, restoreBackupCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the result in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes backup restore --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no backup archive specified. See 'kes backup restore --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes backup restore --help'")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	archive, content, err := openBackup(ctx, client, cmd.Arg(0))
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("invalid backup '%s': %v", cmd.Arg(0), err)
+	}
+
+	type Result struct {
+		Restored int      `json:"restored"`
+		Skipped  int      `json:"skipped"`
+		Failed   []string `json:"failed,omitempty"`
+	}
+	var policies, keys, identities Result
+	fail := func(r *Result, format string, v ...any) {
+		if errors.Is(ctx.Err(), context.Canceled) {
+			os.Exit(1)
+		}
+		r.Failed = append(r.Failed, fmt.Sprintf(format, v...))
+	}
+
+	// Policies are restored first since identities can
+	// only be assigned to existing policies.
+	for _, p := range content.Policies {
+		err := sendRequest(ctx, client, http.MethodPut, api.PathPolicyCreate+p.Name, api.CreatePolicyRequest{
+			Allow: p.Allow,
+			Deny:  p.Deny,
+		}, nil)
+		switch {
+		case isAPIError(err, kes.ErrPolicyExists):
+			policies.Skipped++
+		case err != nil:
+			fail(&policies, "policy '%s': %v", p.Name, err)
+		default:
+			policies.Restored++
+		}
+	}
+	for _, k := range content.Keys {
+		err := sendRequest(ctx, client, http.MethodPut, api.PathKeyRestore+k.Name, api.RestoreKeyRequest{
+			KEK: k.KEK,
+			Key: k.Key,
+		}, nil)
+		switch {
+		case isAPIError(err, kes.ErrKeyExists):
+			keys.Skipped++
+		case err != nil:
+			fail(&keys, "key '%s': %v", k.Name, err)
+		default:
+			keys.Restored++
+		}
+	}
+	now := time.Now()
+	for _, id := range content.Identities {
+		req := api.AssignPolicyRequest{Identities: []string{id.Identity}}
+		if !id.ExpiresAt.IsZero() {
+			if !now.Before(id.ExpiresAt) {
+				identities.Skipped++
+				continue
+			}
+			req.TTL = id.ExpiresAt.Sub(now).Round(time.Second).String()
+		}
+		if err := sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+id.Policy, req, nil); err != nil {
+			fail(&identities, "identity '%s': %v", id.Identity, err)
+			continue
+		}
+		identities.Restored++
+	}
+
+	failed := len(policies.Failed) + len(keys.Failed) + len(identities.Failed)
+	if jsonFlag {
+		printBackupJSON(struct {
+			Manifest   backupManifest `json:"manifest"`
+			Keys       Result         `json:"keys"`
+			Policies   Result         `json:"policies"`
+			Identities Result         `json:"identities"`
+		}{archive.Manifest, keys, policies, identities})
+	} else {
+		fmt.Printf("Restored backup '%s'\n", cmd.Arg(0))
+		fmt.Printf("  Keys:       %d restored, %d skipped, %d failed\n", keys.Restored, keys.Skipped, len(keys.Failed))
+		fmt.Printf("  Policies:   %d restored, %d skipped, %d failed\n", policies.Restored, policies.Skipped, len(policies.Failed))
+		fmt.Printf("  Identities: %d restored, %d skipped, %d failed\n", identities.Restored, identities.Skipped, len(identities.Failed))
+		for _, r := range [][]string{policies.Failed, keys.Failed, identities.Failed} {
+			for _, msg := range r {
+				fmt.Fprintln(os.Stderr, "  failed to restore", msg)
+			}
+		}
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// backupVersion is the version of the backup archive format.
+//
+// An archive consists of a header, followed by a newline, and
+// the AES-256-GCM encrypted, gzip-compressed backupArchive.
+// The data key is generated by the KEK and stored, encrypted,
+// in the header. The header is authenticated as associated data.
+const backupVersion = "v1"
+
+// backupContext is the context used when generating and
+// decrypting the data key of a backup archive.
+var backupContext = []byte("kes backup " + backupVersion)
+
+// backupHeader is the plaintext header of a backup archive.
+type backupHeader struct {
+	Version   string    `json:"version"`
+	KEK       string    `json:"kek"`
+	DataKey   []byte    `json:"data_key"`
+	Nonce     []byte    `json:"nonce"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupArchive is the encrypted part of a backup archive.
+// Its sections are kept as raw JSON such that they can be
+// checked against the manifest checksums before decoding.
+type backupArchive struct {
+	Manifest   backupManifest  `json:"manifest"`
+	Keys       json.RawMessage `json:"keys"`
+	Policies   json.RawMessage `json:"policies"`
+	Identities json.RawMessage `json:"identities"`
+}
+
+// backupManifest describes the content of a backup archive.
+type backupManifest struct {
+	CreatedAt  time.Time     `json:"created_at"`
+	Server     string        `json:"server"`
+	KEK        string        `json:"kek"`
+	Keys       backupSection `json:"keys"`
+	Policies   backupSection `json:"policies"`
+	Identities backupSection `json:"identities"`
+}
+
+// backupSection contains the number of items and the hex-encoded
+// SHA-256 checksum of a backupArchive section.
+type backupSection struct {
+	Count    int    `json:"count"`
+	Checksum string `json:"sha256"`
+}
+
+// backupContent is the decoded content of a backupArchive.
+type backupContent struct {
+	Keys       []api.ExportKeyResponse
+	Policies   []backupPolicy
+	Identities []backupIdentity
+}
+
+// backupPolicy is a policy within a backup archive.
+type backupPolicy struct {
+	Name  string   `json:"name"`
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// backupIdentity is a policy assignment within a backup archive.
+type backupIdentity struct {
+	Identity  string    `json:"identity"`
+	Policy    string    `json:"policy"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+// collectBackup fetches all keys, wrapped by the KEK, all policies
+// and all policy assignments from the server. The admin identity
+// and the KEK itself are not included.
+func collectBackup(ctx context.Context, client *kes.Client, kek string) (*backupArchive, error) {
+	var content backupContent
+
+	keys := &kes.ListIter[string]{NextFunc: client.ListKeys}
+	for {
+		name, err := keys.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %v", err)
+		}
+		if name == kek {
+			continue
+		}
+
+		var export api.ExportKeyResponse
+		err = sendRequest(ctx, client, http.MethodPut, api.PathKeyExport+name, api.ExportKeyRequest{KEK: kek}, &export)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export '%s': %v", name, err)
+		}
+		content.Keys = append(content.Keys, export)
+	}
+
+	policies := &kes.ListIter[string]{NextFunc: client.ListPolicies}
+	for {
+		name, err := policies.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list policies: %v", err)
+		}
+
+		policy, err := client.GetPolicy(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy '%s': %v", name, err)
+		}
+		p := backupPolicy{Name: name}
+		for pattern := range policy.Allow {
+			p.Allow = append(p.Allow, pattern)
+		}
+		for pattern := range policy.Deny {
+			p.Deny = append(p.Deny, pattern)
+		}
+		slices.Sort(p.Allow)
+		slices.Sort(p.Deny)
+		content.Policies = append(content.Policies, p)
+	}
+
+	identities := &kes.ListIter[kes.Identity]{NextFunc: client.ListIdentities}
+	for {
+		id, err := identities.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list identities: %v", err)
+		}
+
+		info, err := client.DescribeIdentity(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe identity '%s': %v", id, err)
+		}
+		if info.IsAdmin || info.Policy == "" {
+			continue
+		}
+		content.Identities = append(content.Identities, backupIdentity{
+			Identity:  id.String(),
+			Policy:    info.Policy,
+			ExpiresAt: info.ExpiresAt,
+		})
+	}
+
+	archive := &backupArchive{
+		Manifest: backupManifest{
+			CreatedAt: time.Now().UTC(),
+			Server:    strings.Join(client.Endpoints, ","),
+			KEK:       kek,
+		},
+	}
+	var err error
+	if archive.Keys, archive.Manifest.Keys, err = marshalBackupSection(content.Keys); err != nil {
+		return nil, err
+	}
+	if archive.Policies, archive.Manifest.Policies, err = marshalBackupSection(content.Policies); err != nil {
+		return nil, err
+	}
+	if archive.Identities, archive.Manifest.Identities, err = marshalBackupSection(content.Identities); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// marshalBackupSection returns the JSON encoding of items and
+// the corresponding manifest entry.
+func marshalBackupSection[T any](items []T) (json.RawMessage, backupSection, error) {
+	if items == nil {
+		items = []T{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, backupSection{}, err
+	}
+	sum := sha256.Sum256(b)
+	return b, backupSection{Count: len(items), Checksum: hex.EncodeToString(sum[:])}, nil
+}
+
+// unmarshalBackupSection decodes the section into v if it
+// matches the manifest entry.
+func unmarshalBackupSection[T any](name string, section json.RawMessage, m backupSection, v *[]T) error {
+	sum := sha256.Sum256(section)
+	if hex.EncodeToString(sum[:]) != m.Checksum {
+		return fmt.Errorf("checksum of %s does not match manifest", name)
+	}
+	if err := json.Unmarshal(section, v); err != nil {
+		return fmt.Errorf("invalid %s: %v", name, err)
+	}
+	if len(*v) != m.Count {
+		return fmt.Errorf("archive contains %d %s but manifest lists %d", len(*v), name, m.Count)
+	}
+	return nil
+}
+
+// sealBackup encrypts the archive with a data key generated by
+// the KEK and returns the encoded backup archive.
+func sealBackup(ctx context.Context, client *kes.Client, kek string, archive *backupArchive) ([]byte, error) {
+	var body bytes.Buffer
+	w := gzip.NewWriter(&body)
+	if err := json.NewEncoder(w).Encode(archive); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	dek, err := client.GenerateKey(ctx, kek, backupContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %v", err)
+	}
+	defer clear(dek.Plaintext)
+
+	aead, err := newBackupAEAD(dek.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	header := backupHeader{
+		Version:   backupVersion,
+		KEK:       kek,
+		DataKey:   dek.Ciphertext,
+		Nonce:     make([]byte, aead.NonceSize()),
+		CreatedAt: archive.Manifest.CreatedAt,
+	}
+	if _, err = io.ReadFull(rand.Reader, header.Nonce); err != nil {
+		return nil, err
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, len(headerBytes)+1+body.Len()+aead.Overhead())
+	data = append(data, headerBytes...)
+	data = append(data, '\n')
+	return aead.Seal(data, header.Nonce, body.Bytes(), headerBytes), nil
+}
+
+// openBackup reads, decrypts and verifies the backup archive
+// stored in the given file.
+func openBackup(ctx context.Context, client *kes.Client, filename string) (*backupArchive, *backupContent, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	headerBytes, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, errors.New("archive header is missing")
+	}
+	headerBytes = headerBytes[:len(headerBytes)-1]
+
+	var header backupHeader
+	if err = json.Unmarshal(headerBytes, &header); err != nil {
+		return nil, nil, fmt.Errorf("invalid archive header: %v", err)
+	}
+	if header.Version != backupVersion {
+		return nil, nil, fmt.Errorf("unsupported archive version '%s'", header.Version)
+	}
+	ciphertext, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey, err := client.Decrypt(ctx, header.KEK, header.DataKey, backupContext)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt data key with '%s': %v", header.KEK, err)
+	}
+	defer clear(dataKey)
+
+	aead, err := newBackupAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header.Nonce) != aead.NonceSize() {
+		return nil, nil, errors.New("invalid archive header: invalid nonce")
+	}
+	body, err := aead.Open(ciphertext[:0], header.Nonce, ciphertext, headerBytes)
+	if err != nil {
+		return nil, nil, errors.New("archive has been modified or is corrupted")
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, err
+	}
+	var archive backupArchive
+	if err = json.NewDecoder(gz).Decode(&archive); err != nil {
+		return nil, nil, fmt.Errorf("invalid archive content: %v", err)
+	}
+
+	var content backupContent
+	m := archive.Manifest
+	if err = unmarshalBackupSection("keys", archive.Keys, m.Keys, &content.Keys); err != nil {
+		return nil, nil, err
+	}
+	if err = unmarshalBackupSection("policies", archive.Policies, m.Policies, &content.Policies); err != nil {
+		return nil, nil, err
+	}
+	if err = unmarshalBackupSection("identities", archive.Identities, m.Identities, &content.Identities); err != nil {
+		return nil, nil, err
+	}
+	return &archive, &content, nil
+}
+
+// isAPIError reports whether err is an API error with the
+// same status code and message as target.
+func isAPIError(err error, target kes.Error) bool {
+	code := api.CodeOf(err)
+	return code != "" && code == api.ErrorCode(target.Status(), target.Error())
+}
+
+// printBackupJSON writes v as JSON to STDOUT.
+func printBackupJSON(v any) {
+	encoder := json.NewEncoder(os.Stdout)
+	if isTerm(os.Stdout) {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(v); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+// newBackupAEAD returns a new AES-256-GCM AEAD for the data key.
+func newBackupAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("invalid data key length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
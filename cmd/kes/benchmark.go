@@ -0,0 +1,300 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const benchmarkCmdUsage = `Usage:
+    kes benchmark [options]
+
+Sends generate, encrypt or decrypt requests to the KES server
+with the given concurrency and reports the throughput and the
+latency percentiles.
+
+If no key is specified, a temporary key is created for the
+benchmark and deleted afterwards.
+
+Options:
+        --op <operation>         Operation to benchmark. Possible values:
+                                 *generate*, encrypt, decrypt.
+        --key <name>             Use the existing key for the benchmark.
+    -c, --concurrency <n>        Number of concurrent requests. (default: 16)
+    -d, --duration <duration>    Duration of the benchmark. (default: 10s)
+        --size <bytes>           Size of the plaintext to encrypt. (default: 32)
+    -k, --insecure               Skip TLS certificate validation.
+        --json                   Print benchmark results in JSON format.
+
+    -h, --help                   Print command line options.
+
+Examples:
+    $ kes benchmark
+    $ kes benchmark --op decrypt --concurrency 64 --duration 1m
+    $ kes benchmark --key my-key --op encrypt --size 1024
+`
+
+// benchmarkResult is the result of a benchmark run.
+type benchmarkResult struct {
+	Operation   string        `json:"operation"`
+	Concurrency int           `json:"concurrency"`
+	Duration    time.Duration `json:"duration"`
+	Requests    int           `json:"requests"`
+	Errors      int           `json:"errors"`
+	Throughput  float64       `json:"throughput"` // requests per second
+
+	Min time.Duration `json:"latency_min"`
+	Avg time.Duration `json:"latency_avg"`
+	P50 time.Duration `json:"latency_p50"`
+	P90 time.Duration `json:"latency_p90"`
+	P99 time.Duration `json:"latency_p99"`
+	Max time.Duration `json:"latency_max"`
+}
+
+func benchmarkCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, benchmarkCmdUsage) }
+
+	var (
+		opFlag             string
+		keyFlag            string
+		concurrency        int
+		duration           time.Duration
+		size               int
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.StringVar(&opFlag, "op", "generate", "Operation to benchmark")
+	cmd.StringVar(&keyFlag, "key", "", "Use the existing key for the benchmark")
+	cmd.IntVarP(&concurrency, "concurrency", "c", 16, "Number of concurrent requests")
+	cmd.DurationVarP(&duration, "duration", "d", 10*time.Second, "Duration of the benchmark")
+	cmd.IntVar(&size, "size", 32, "Size of the plaintext to encrypt")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print benchmark results in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes benchmark --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes benchmark --help'")
+	}
+	switch opFlag {
+	case "generate", "encrypt", "decrypt":
+	default:
+		cli.Fatalf("invalid operation '%s'. See 'kes benchmark --help'", opFlag)
+	}
+	if concurrency <= 0 {
+		cli.Fatal("concurrency must be greater than 0. See 'kes benchmark --help'")
+	}
+	if duration <= 0 {
+		cli.Fatal("duration must be greater than 0. See 'kes benchmark --help'")
+	}
+	if size < 0 {
+		cli.Fatal("size must not be negative. See 'kes benchmark --help'")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	name := keyFlag
+	if name == "" {
+		var random [8]byte
+		if _, err := rand.Read(random[:]); err != nil {
+			cli.Fatal(err)
+		}
+		name = "kes-benchmark-" + hex.EncodeToString(random[:])
+		if err := client.CreateKey(ctx, name); err != nil {
+			cli.Fatalf("failed to create benchmark key: %v", err)
+		}
+	}
+
+	result, err := benchmarkKey(ctx, client, name, opFlag, size, concurrency, duration)
+	if keyFlag == "" {
+		// Use a new context since ctx may have been canceled.
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := client.DeleteKey(ctx, name); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to delete benchmark key '%s': %v\n", name, err)
+		}
+		cancel()
+	}
+	if err != nil {
+		cli.Fatalf("benchmark failed: %v", err)
+	}
+
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err = encoder.Encode(result); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%-12s %s\n", "Operation", result.Operation)
+	fmt.Fprintf(buf, "%-12s %d\n", "Concurrency", result.Concurrency)
+	fmt.Fprintf(buf, "%-12s %v\n", "Duration", result.Duration.Round(time.Millisecond))
+	fmt.Fprintf(buf, "%-12s %d (%d errors)\n", "Requests", result.Requests, result.Errors)
+	fmt.Fprintf(buf, "%-12s %.2f req/s\n", "Throughput", result.Throughput)
+	fmt.Fprintf(buf, "%-12s min %v  avg %v  p50 %v  p90 %v  p99 %v  max %v\n", "Latency",
+		result.Min.Round(time.Microsecond),
+		result.Avg.Round(time.Microsecond),
+		result.P50.Round(time.Microsecond),
+		result.P90.Round(time.Microsecond),
+		result.P99.Round(time.Microsecond),
+		result.Max.Round(time.Microsecond),
+	)
+	fmt.Print(buf)
+}
+
+// benchmarkKey runs the benchmark for the operation op against
+// the key with the given name. For encrypt operations, it encrypts
+// random plaintexts of the given size.
+func benchmarkKey(ctx context.Context, client *kes.Client, name, op string, size, concurrency int, duration time.Duration) (*benchmarkResult, error) {
+	plaintext := make([]byte, size)
+	if _, err := rand.Read(plaintext); err != nil {
+		return nil, err
+	}
+
+	var fn func(context.Context) error
+	switch op {
+	case "generate":
+		fn = func(ctx context.Context) error {
+			_, err := client.GenerateKey(ctx, name, nil)
+			return err
+		}
+	case "encrypt":
+		fn = func(ctx context.Context) error {
+			_, err := client.Encrypt(ctx, name, plaintext, nil)
+			return err
+		}
+	case "decrypt":
+		ciphertext, err := client.Encrypt(ctx, name, plaintext, nil)
+		if err != nil {
+			return nil, err
+		}
+		fn = func(ctx context.Context) error {
+			_, err := client.Decrypt(ctx, name, ciphertext, nil)
+			return err
+		}
+	default:
+		return nil, fmt.Errorf("invalid operation '%s'", op)
+	}
+
+	result, err := runBenchmark(ctx, fn, concurrency, duration)
+	if err != nil {
+		return nil, err
+	}
+	result.Operation = op
+	return result, nil
+}
+
+// runBenchmark calls op from n goroutines concurrently until the
+// duration d has passed or ctx is canceled. It measures the latency
+// of all successful calls.
+//
+// It returns an error if no call succeeds.
+func runBenchmark(ctx context.Context, op func(context.Context) error, n int, d time.Duration) (*benchmarkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		latencies []time.Duration
+		errCount  int
+		firstErr  error
+	)
+	start := time.Now()
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				local []time.Duration
+				errs  int
+				err   error
+			)
+			for ctx.Err() == nil {
+				t := time.Now()
+				if e := op(ctx); e != nil {
+					if ctx.Err() != nil { // Canceled requests don't count as error
+						break
+					}
+					errs++
+					if err == nil {
+						err = e
+					}
+					continue
+				}
+				local = append(local, time.Since(t))
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			latencies = append(latencies, local...)
+			errCount += errs
+			if firstErr == nil {
+				firstErr = err
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Requests canceled at the end of the benchmark may take
+	// some time to return. They don't count towards the duration.
+	elapsed := min(time.Since(start), d)
+
+	if len(latencies) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, errors.New("no request completed")
+	}
+
+	slices.Sort(latencies)
+	var sum time.Duration
+	for _, l := range latencies {
+		sum += l
+	}
+	percentile := func(q float64) time.Duration {
+		i := int(math.Ceil(q*float64(len(latencies)))) - 1
+		return latencies[max(i, 0)]
+	}
+	return &benchmarkResult{
+		Concurrency: n,
+		Duration:    elapsed,
+		Requests:    len(latencies) + errCount,
+		Errors:      errCount,
+		Throughput:  float64(len(latencies)) / elapsed.Seconds(),
+		Min:         latencies[0],
+		Avg:         sum / time.Duration(len(latencies)),
+		P50:         percentile(0.50),
+		P90:         percentile(0.90),
+		P99:         percentile(0.99),
+		Max:         latencies[len(latencies)-1],
+	}, nil
+}
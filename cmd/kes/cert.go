@@ -0,0 +1,317 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const certCmdUsage = `Usage:
+    kes cert <command>
+
+Commands:
+    issue                    Issue an X.509 certificate.
+    ca                       Print the certificate of a certificate authority.
+
+Options:
+    -h, --help               Print command line options.
+
+A key acts as X.509 certificate authority (CA) if created with the
+usage 'cert', e.g. 'kes key create --usage cert svc-ca'. Servers and
+clients trust the certificates issued by it once its CA certificate,
+printed by 'kes cert ca', is added to their trusted root certificates.
+`
+
+func certCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, certCmdUsage) }
+
+	subCmds := commands{
+		"issue": issueCertCmd,
+		"ca":    caCertCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a cert command. See 'kes cert --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const issueCertCmdUsage = `Usage:
+    kes cert issue [options] <key>
+
+Options:
+        --cn <name>          Common name of the certificate.
+        --dns <domain>       Add a DNS name as subject alternative name.
+                             May be specified multiple times.
+        --ip <ip>            Add an IP address as subject alternative name.
+                             May be specified multiple times.
+        --ttl <duration>     Validity of the certificate. Limited by the policy.
+                             (default: 24h)
+        --server             Issue a server instead of a client certificate.
+                             Server certificates can be used for both.
+
+        --key <file>         Path of the generated private key.
+                             (default: private.key)
+        --cert <file>        Path of the issued certificate. (default: public.crt)
+        --ca <file>          Also write the CA certificate to the file.
+        --public-key <file>  Issue the certificate for an existing PEM-encoded
+                             public key instead of generating a private key.
+    -f, --force              Overwrite an existing private key or certificate.
+
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the certificate details in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes cert issue --cn minio.svc.example.com --ttl 24h svc-ca
+    $ kes cert issue --server --cn minio.svc.example.com --dns s3.svc.example.com --ip 10.1.2.3 \
+          --key minio.key --cert minio.crt --ca ca.crt svc-ca
+`
+
+func issueCertCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, issueCertCmdUsage) }
+
+	var (
+		cnFlag             string
+		dnsFlags           []string
+		ipFlags            []net.IP
+		ttlFlag            time.Duration
+		serverFlag         bool
+		keyPath            string
+		certPath           string
+		caPath             string
+		publicKeyPath      string
+		forceFlag          bool
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&cnFlag, "cn", "", "Common name of the certificate")
+	cmd.StringArrayVar(&dnsFlags, "dns", nil, "Add <DOMAIN> as subject alternative name")
+	cmd.IPSliceVar(&ipFlags, "ip", nil, "Add <IP> as subject alternative name")
+	cmd.DurationVar(&ttlFlag, "ttl", 0, "Validity of the certificate")
+	cmd.BoolVar(&serverFlag, "server", false, "Issue a server certificate")
+	cmd.StringVar(&keyPath, "key", "private.key", "Path of the generated private key")
+	cmd.StringVar(&certPath, "cert", "public.crt", "Path of the issued certificate")
+	cmd.StringVar(&caPath, "ca", "", "Path of the CA certificate")
+	cmd.StringVar(&publicKeyPath, "public-key", "", "Path to an existing public key")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite an existing private key or certificate")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the certificate details in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert issue --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes cert issue --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes cert issue --help'")
+	}
+	if cnFlag == "" {
+		cli.Fatal("no common name specified. Set the '--cn' flag")
+	}
+	if ttlFlag < 0 {
+		cli.Fatal("'--ttl' must not be negative")
+	}
+	if publicKeyPath != "" && cmd.Changed("key") {
+		cli.Fatal("'--key' cannot be used with '--public-key'")
+	}
+	if !forceFlag {
+		if _, err := os.Stat(certPath); err == nil {
+			cli.Fatal("certificate already exists. Use --force to overwrite it")
+		}
+		if _, err := os.Stat(keyPath); err == nil && publicKeyPath == "" {
+			cli.Fatal("private key already exists. Use --force to overwrite it")
+		}
+	}
+
+	var (
+		keyPem    []byte
+		publicKey []byte
+	)
+	if publicKeyPath != "" {
+		b, err := os.ReadFile(publicKeyPath)
+		if err != nil {
+			cli.Fatalf("failed to read public key: %v", err)
+		}
+		publicKey = b
+	} else {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			cli.Fatalf("failed to generate private key: %v", err)
+		}
+		privBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		if err != nil {
+			cli.Fatalf("failed to generate private key: %v", err)
+		}
+		pubBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+		if err != nil {
+			cli.Fatalf("failed to generate private key: %v", err)
+		}
+		keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+		publicKey = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes})
+	}
+
+	certType := "client"
+	if serverFlag {
+		certType = "server"
+	}
+	ips := make([]string, 0, len(ipFlags))
+	for _, ip := range ipFlags {
+		ips = append(ips, ip.String())
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.IssueCertResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathCertIssue+cmd.Arg(0), api.IssueCertRequest{
+		PublicKey:   string(publicKey),
+		Type:        certType,
+		CommonName:  cnFlag,
+		DNSNames:    dnsFlags,
+		IPAddresses: ips,
+		TTL:         int64(ttlFlag / time.Second),
+	}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to issue certificate: %v", err)
+	}
+
+	if keyPem != nil {
+		if err := os.WriteFile(keyPath, keyPem, 0o600); err != nil {
+			cli.Fatalf("failed to write private key: %v", err)
+		}
+	}
+	if err := os.WriteFile(certPath, []byte(resp.Certificate), 0o644); err != nil {
+		cli.Fatalf("failed to write certificate: %v", err)
+	}
+	if caPath != "" {
+		if err := os.WriteFile(caPath, []byte(resp.CACertificate), 0o644); err != nil {
+			cli.Fatalf("failed to write CA certificate: %v", err)
+		}
+	}
+
+	if jsonFlag {
+		type Output struct {
+			PrivateKey    string    `json:"private_key,omitempty"`
+			Certificate   string    `json:"certificate"`
+			CACertificate string    `json:"ca_certificate,omitempty"`
+			Serial        string    `json:"serial"`
+			NotBefore     time.Time `json:"not_before"`
+			NotAfter      time.Time `json:"not_after"`
+		}
+		output := Output{
+			Certificate:   certPath,
+			CACertificate: caPath,
+			Serial:        resp.Serial,
+			NotBefore:     resp.NotBefore,
+			NotAfter:      resp.NotAfter,
+		}
+		if keyPem != nil {
+			output.PrivateKey = keyPath
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(output); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("Serial %s valid from %s to %s\n", resp.Serial, resp.NotBefore.Local().Format(time.DateTime), resp.NotAfter.Local().Format(time.DateTime))
+}
+
+const caCertCmdUsage = `Usage:
+    kes cert ca [options] <key>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the CA certificate in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes cert ca svc-ca > ca.crt
+`
+
+func caCertCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, caCertCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the CA certificate in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cert ca --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes cert ca --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes cert ca --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.CertCAResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathCertCA+cmd.Arg(0), nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch CA certificate: %v", err)
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Print(resp.Certificate)
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const clusterCmdUsage = `Usage:
+    kes cluster <command>
+
+Commands:
+    ls                       List cluster members.
+    add                      Add a new cluster member.
+    rm                       Remove a cluster member.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func clusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, clusterCmdUsage) }
+
+	subCmds := commands{
+		"ls":  lsClusterCmd,
+		"add": addClusterCmd,
+		"rm":  rmClusterCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a cluster command. See 'kes cluster --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const lsClusterCmdUsage = `Usage:
+    kes cluster ls [options]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print cluster members in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes cluster ls
+`
+
+func lsClusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsClusterCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print cluster members in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster ls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes cluster ls --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var list api.ListClusterMembersResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathClusterList, nil, &list); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list cluster members: %v", err)
+	}
+
+	if jsonFlag {
+		if list.Members == nil {
+			list.Members = []api.ClusterMember{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(list.Members); err != nil {
+			cli.Fatalf("failed to list cluster members: %v", err)
+		}
+		return
+	}
+
+	var (
+		header = tui.NewStyle().Underline(colorFlag.Colorize())
+		leader = tui.NewStyle()
+		buf    = &strings.Builder{}
+	)
+	if colorFlag.Colorize() {
+		leader = leader.Foreground(tui.Color("#2e42d1")).Bold(true)
+	}
+	fmt.Fprintf(buf, "%s %s %s\n", header.Render(fmt.Sprintf("%-16s", "ID")), header.Render(fmt.Sprintf("%-32s", "Address")), header.Render("Role"))
+	for _, m := range list.Members {
+		role := "follower"
+		if m.Leader {
+			role = leader.Render("leader")
+		}
+		fmt.Fprintf(buf, "%-16s %-32s %s\n", m.ID, m.Address, role)
+	}
+	fmt.Print(buf)
+}
+
+const addClusterCmdUsage = `Usage:
+    kes cluster add [options] <address>
+
+Adds a new member with the given address to the cluster. The new
+member has to be started with '--join' afterwards such that it
+receives the cluster state.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes cluster add https://10.1.2.4:7373
+`
+
+func addClusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, addClusterCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster add --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no member address specified. See 'kes cluster add --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes cluster add --help'")
+	}
+	addr := cmd.Arg(0)
+	if u, err := url.Parse(addr); err != nil || u.Scheme != "https" || u.Host == "" {
+		cli.Fatalf("invalid member address '%s': must be an HTTPS URL", addr)
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var add api.AddClusterMemberResponse
+	err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPost, api.PathClusterAdd, api.AddClusterMemberRequest{Address: addr}, &add)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to add cluster member: %v", err)
+	}
+	fmt.Printf("Added cluster member '%s' with ID '%s'\n", addr, add.ID)
+}
+
+const rmClusterCmdUsage = `Usage:
+    kes cluster rm [options] <id>
+
+Removes the member with the given ID, as listed by 'kes cluster ls',
+from the cluster. A removed member stops replicating changes and
+cannot rejoin the cluster with its current cluster state.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes cluster rm 8d92df049695791
+`
+
+func rmClusterCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmClusterCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes cluster rm --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no member ID specified. See 'kes cluster rm --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes cluster rm --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	id := cmd.Arg(0)
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodDelete, api.PathClusterRemove+id, nil, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to remove cluster member '%s': %v", id, err)
+	}
+	fmt.Printf("Removed cluster member '%s'\n", id)
+}
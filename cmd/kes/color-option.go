@@ -21,6 +21,10 @@ import (
 //	· always
 //	· auto  (default)
 //	· never
+//
+// The automatic mode disables colors if the global
+// --no-color option or the NO_COLOR env. variable is
+// set. An explicit "always" takes precedence.
 type colorOption struct {
 	value string
 }
@@ -29,7 +33,7 @@ var _ flag.Value = (*colorOption)(nil)
 
 func (c *colorOption) Colorize() bool {
 	v := strings.ToLower(c.value)
-	return v == "always" || ((v == "auto" || v == "") && isTerm(os.Stdout))
+	return v == "always" || ((v == "auto" || v == "") && !globalNoColor && isTerm(os.Stdout))
 }
 
 func (c *colorOption) String() string { return c.value }
@@ -0,0 +1,455 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const compatCmdUsage = `Usage:
+    kes compat <command>
+
+Commands:
+    minio                    Check SSE-KMS compatibility with a MinIO server.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func compatCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, compatCmdUsage) }
+
+	subCmds := commands{
+		"minio": compatMinIOCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes compat --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a compat command. See 'kes compat --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const compatMinIOCmdUsage = `Usage:
+    kes compat minio [options] <endpoint>
+
+Prepares the KES server for a MinIO server and checks that MinIO
+can encrypt and decrypt objects with SSE-KMS. It creates the key,
+a policy with all API paths MinIO requires and assigns it to the
+identity of the MinIO server. Then it uploads an SSE-KMS encrypted
+object to the MinIO endpoint, downloads it again and compares the
+content and encryption metadata. It exits with a non-zero status
+if any check fails.
+
+The MinIO server has to be configured to use this KES server with
+the given identity, e.g. via MINIO_KMS_KES_ENDPOINT and either
+MINIO_KMS_KES_API_KEY or MINIO_KMS_KES_CERT_FILE.
+
+Options:
+        --identity <id>      Identity of the MinIO server at the KES server.
+        --key <name>         Name of the KES key used for SSE-KMS.
+                             (default: minio-compat)
+        --policy <name>      Name of the policy assigned to the MinIO identity.
+                             (default: minio)
+        --no-setup           Do not create the key, policy and assignment.
+                             Only check the existing configuration.
+
+        --access-key <key>   MinIO access key. (default: $MINIO_ROOT_USER)
+        --secret-key <key>   MinIO secret key. (default: $MINIO_ROOT_PASSWORD)
+        --bucket <name>      Use an existing bucket instead of creating and
+                             removing a temporary one.
+
+    -k, --insecure           Skip TLS certificate validation of the KES and
+                             MinIO server.
+        --json               Print compatibility report in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes compat minio --identity 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22 https://minio.local:9000
+    $ kes compat minio --no-setup --key minio-key --identity 3ecfcdf3...fd22 --bucket data https://minio.local:9000
+`
+
+// minioAPIPath is a KES API path used by MinIO.
+type minioAPIPath struct {
+	Path     string
+	Required bool // If false, only some MinIO features depend on the path.
+}
+
+// minioAPIPaths returns the KES API paths MinIO uses with the given
+// key.
+func minioAPIPaths(key string) []minioAPIPath {
+	return []minioAPIPath{
+		{Path: api.PathKeyCreate + key, Required: true},
+		{Path: api.PathKeyGenerate + key, Required: true},
+		{Path: api.PathKeyDecrypt + key, Required: true},
+		{Path: api.PathKeyBulkDecrypt + key},
+		{Path: api.PathKeyList + "*"},
+		{Path: api.PathStatus},
+		{Path: api.PathMetrics},
+		{Path: api.PathListAPIs},
+	}
+}
+
+func compatMinIOCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, compatMinIOCmdUsage) }
+
+	var (
+		identityFlag       string
+		keyFlag            string
+		policyFlag         string
+		noSetupFlag        bool
+		accessKeyFlag      string
+		secretKeyFlag      string
+		bucketFlag         string
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&identityFlag, "identity", "", "Identity of the MinIO server")
+	cmd.StringVar(&keyFlag, "key", "minio-compat", "Name of the KES key used for SSE-KMS")
+	cmd.StringVar(&policyFlag, "policy", "minio", "Name of the policy assigned to the MinIO identity")
+	cmd.BoolVar(&noSetupFlag, "no-setup", false, "Do not create the key, policy and assignment")
+	cmd.StringVar(&accessKeyFlag, "access-key", os.Getenv("MINIO_ROOT_USER"), "MinIO access key")
+	cmd.StringVar(&secretKeyFlag, "secret-key", os.Getenv("MINIO_ROOT_PASSWORD"), "MinIO secret key")
+	cmd.StringVar(&bucketFlag, "bucket", "", "Use an existing bucket")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print compatibility report in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes compat minio --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no MinIO endpoint specified. See 'kes compat minio --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes compat minio --help'")
+	}
+	if identityFlag == "" {
+		cli.Fatal("no MinIO identity specified. Set the '--identity' flag")
+	}
+	if accessKeyFlag == "" || secretKeyFlag == "" {
+		cli.Fatal("no MinIO credentials specified. Set the '--access-key' and '--secret-key' flags")
+	}
+	endpoint, err := url.Parse(cmd.Arg(0))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		cli.Fatalf("invalid MinIO endpoint '%s'", cmd.Arg(0))
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: insecureSkipVerify},
+		},
+	}
+	s3Session, err := session.NewSession(&aws.Config{
+		Endpoint:         aws.String(endpoint.String()),
+		Region:           aws.String("us-east-1"),
+		Credentials:      credentials.NewStaticCredentials(accessKeyFlag, secretKeyFlag, ""),
+		S3ForcePathStyle: aws.Bool(true),
+		HTTPClient:       httpClient,
+		MaxRetries:       aws.Int(0),
+	})
+	if err != nil {
+		cli.Fatalf("failed to create S3 client: %v", err)
+	}
+
+	checks := runCompatMinIO(ctx, newClient(insecureSkipVerify), s3.New(s3Session), compatMinIOConfig{
+		Identity: identityFlag,
+		Key:      keyFlag,
+		Policy:   policyFlag,
+		Bucket:   bucketFlag,
+		Setup:    !noSetupFlag,
+	})
+	if errors.Is(ctx.Err(), context.Canceled) {
+		os.Exit(1)
+	}
+	printChecks(checks, jsonFlag, colorFlag.Colorize())
+	for _, check := range checks {
+		if check.Status == checkFail {
+			os.Exit(1)
+		}
+	}
+}
+
+// compatMinIOConfig configures the MinIO compatibility check.
+type compatMinIOConfig struct {
+	Identity string // Identity of the MinIO server
+	Key      string // Name of the SSE-KMS key
+	Policy   string // Name of the policy of the MinIO server
+	Bucket   string // Existing bucket, if any
+	Setup    bool   // Whether to create the key, policy and assignment
+}
+
+// runCompatMinIO runs all MinIO compatibility checks in order.
+// Checks that depend on a previous, failed check are skipped.
+func runCompatMinIO(ctx context.Context, client *kes.Client, s3Client *s3.S3, config compatMinIOConfig) []doctorCheck {
+	const (
+		CheckKey      = "KES key"
+		CheckPolicy   = "KES policy"
+		CheckIdentity = "KES identity"
+		CheckAccess   = "Policy glue"
+		CheckBucket   = "MinIO bucket"
+		CheckUpload   = "SSE-KMS upload"
+		CheckMetadata = "SSE-KMS metadata"
+		CheckDownload = "SSE-KMS download"
+		CheckCleanup  = "Cleanup"
+
+		// ObjectSize is the size of the uploaded test object.
+		// It is larger than a single DARE package of 64 KiB
+		// such that MinIO encrypts more than one package.
+		ObjectSize = 256 * 1024
+	)
+	checks := make([]doctorCheck, 0, 9)
+	pass := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkPass, Message: fmt.Sprintf(format, v...)})
+	}
+	warn := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkWarn, Message: fmt.Sprintf(format, v...)})
+	}
+	fail := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkFail, Message: fmt.Sprintf(format, v...)})
+	}
+	skip := func(names ...string) []doctorCheck {
+		for _, name := range names {
+			checks = append(checks, doctorCheck{Name: name, Status: checkSkip, Message: "skipped due to previous failure"})
+		}
+		return checks
+	}
+
+	paths := minioAPIPaths(config.Key)
+	if config.Setup {
+		err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+config.Key, nil, nil)
+		switch {
+		case err == nil:
+			pass(CheckKey, "created key '%s'", config.Key)
+		case isAPIError(err, kes.ErrKeyExists):
+			pass(CheckKey, "key '%s' already exists", config.Key)
+		default:
+			fail(CheckKey, "failed to create key '%s': %v", config.Key, err)
+			return skip(CheckPolicy, CheckIdentity, CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		}
+
+		allow := make([]string, 0, len(paths))
+		for _, p := range paths {
+			allow = append(allow, p.Path)
+		}
+		err = sendRequest(ctx, client, http.MethodPut, api.PathPolicyCreate+config.Policy, api.CreatePolicyRequest{Allow: allow}, nil)
+		switch {
+		case err == nil:
+			pass(CheckPolicy, "created policy '%s' with %d allow rules", config.Policy, len(allow))
+		case isAPIError(err, kes.ErrPolicyExists):
+			pass(CheckPolicy, "policy '%s' already exists", config.Policy)
+		default:
+			fail(CheckPolicy, "failed to create policy '%s': %v", config.Policy, err)
+			return skip(CheckIdentity, CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		}
+
+		if err = sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+config.Policy, api.AssignPolicyRequest{
+			Identities: []string{config.Identity},
+		}, nil); err != nil {
+			fail(CheckIdentity, "failed to assign policy '%s' to '%s': %v", config.Policy, config.Identity, err)
+			return skip(CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		}
+		pass(CheckIdentity, "assigned policy '%s' to '%s'", config.Policy, config.Identity)
+	} else {
+		var key api.DescribeKeyResponse
+		err := sendRequest(ctx, client, http.MethodGet, api.PathKeyDescribe+config.Key, nil, &key)
+		switch {
+		case err != nil:
+			fail(CheckKey, "failed to describe key '%s': %v", config.Key, err)
+			return skip(CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		case !key.ExpiresAt.IsZero() && time.Now().After(key.ExpiresAt):
+			fail(CheckKey, "key '%s' expired at %s", config.Key, key.ExpiresAt.Local().Format(time.RFC3339))
+			return skip(CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		case len(key.Operations) > 0 && (!slices.Contains(key.Operations, "encrypt") || !slices.Contains(key.Operations, "decrypt")):
+			fail(CheckKey, "key '%s' may only be used for %s but MinIO requires encrypt and decrypt", config.Key, strings.Join(key.Operations, ", "))
+			return skip(CheckAccess, CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		default:
+			pass(CheckKey, "key '%s' exists", config.Key)
+		}
+	}
+
+	// The policy is tested explicitly, even if it has just been created,
+	// since the MinIO identity may be the admin, be denied by another
+	// policy rule or the assignment may not have been applied.
+	var denied, deniedOptional []string
+	var conditional bool
+	for _, p := range paths {
+		var resp api.TestPolicyResponse
+		query := url.Values{"path": []string{p.Path}}
+		if err := sendRequest(ctx, client, http.MethodGet, api.PathPolicyTest+config.Identity+"?"+query.Encode(), nil, &resp); err != nil {
+			fail(CheckAccess, "failed to test policy of '%s': %v", config.Identity, err)
+			return skip(CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+		}
+		conditional = conditional || resp.Conditional
+		switch {
+		case resp.Allowed:
+		case p.Required:
+			denied = append(denied, p.Path)
+		default:
+			deniedOptional = append(deniedOptional, p.Path)
+		}
+	}
+	switch {
+	case len(denied) > 0:
+		fail(CheckAccess, "identity '%s' must be allowed to access %s", config.Identity, strings.Join(denied, ", "))
+		return skip(CheckBucket, CheckUpload, CheckMetadata, CheckDownload)
+	case len(deniedOptional) > 0:
+		warn(CheckAccess, "identity '%s' is not allowed to access %s. Some MinIO features, like 'mc admin kms', may not work", config.Identity, strings.Join(deniedOptional, ", "))
+	case conditional:
+		warn(CheckAccess, "identity '%s' may access all %d API paths if the policy conditions, like source IPs, match", config.Identity, len(paths))
+	default:
+		pass(CheckAccess, "identity '%s' may access all %d API paths", config.Identity, len(paths))
+	}
+
+	var suffix [8]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		fail(CheckBucket, "failed to generate random object name: %v", err)
+		return skip(CheckUpload, CheckMetadata, CheckDownload)
+	}
+	bucket, object := config.Bucket, "kes-compat-"+hex.EncodeToString(suffix[:])
+	if bucket == "" {
+		bucket = object
+		if _, err := s3Client.CreateBucketWithContext(ctx, &s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			fail(CheckBucket, "failed to create bucket '%s': %v", bucket, s3Error(err))
+			return skip(CheckUpload, CheckMetadata, CheckDownload)
+		}
+		pass(CheckBucket, "created temporary bucket '%s'", bucket)
+	} else {
+		if _, err := s3Client.HeadBucketWithContext(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			fail(CheckBucket, "failed to access bucket '%s': %v", bucket, s3Error(err))
+			return skip(CheckUpload, CheckMetadata, CheckDownload)
+		}
+		pass(CheckBucket, "bucket '%s' exists", bucket)
+	}
+	defer func() {
+		var err error
+		if _, err = s3Client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(object),
+		}); err == nil && config.Bucket == "" {
+			_, err = s3Client.DeleteBucketWithContext(ctx, &s3.DeleteBucketInput{Bucket: aws.String(bucket)})
+		}
+		if err != nil {
+			warn(CheckCleanup, "failed to remove '%s/%s': %v", bucket, object, s3Error(err))
+		}
+	}()
+
+	content := make([]byte, ObjectSize)
+	if _, err := rand.Read(content); err != nil {
+		fail(CheckUpload, "failed to generate object content: %v", err)
+		return skip(CheckMetadata, CheckDownload)
+	}
+	if _, err := s3Client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket:               aws.String(bucket),
+		Key:                  aws.String(object),
+		Body:                 bytes.NewReader(content),
+		ServerSideEncryption: aws.String(s3.ServerSideEncryptionAwsKms),
+		SSEKMSKeyId:          aws.String(config.Key),
+	}); err != nil {
+		fail(CheckUpload, "failed to upload '%s/%s': %v. Check the KMS configuration and logs of the MinIO server", bucket, object, s3Error(err))
+		return skip(CheckMetadata, CheckDownload)
+	}
+	pass(CheckUpload, "uploaded %d bytes to '%s/%s'", len(content), bucket, object)
+
+	head, err := s3Client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(object),
+	})
+	switch {
+	case err != nil:
+		fail(CheckMetadata, "failed to fetch metadata of '%s/%s': %v", bucket, object, s3Error(err))
+	case aws.StringValue(head.ServerSideEncryption) != s3.ServerSideEncryptionAwsKms:
+		fail(CheckMetadata, "object is encrypted with '%s' - want '%s'", aws.StringValue(head.ServerSideEncryption), s3.ServerSideEncryptionAwsKms)
+	case strings.TrimPrefix(aws.StringValue(head.SSEKMSKeyId), "arn:aws:kms:") != config.Key:
+		fail(CheckMetadata, "object is encrypted with key '%s' - want '%s'", aws.StringValue(head.SSEKMSKeyId), config.Key)
+	default:
+		pass(CheckMetadata, "object is encrypted with key '%s'", aws.StringValue(head.SSEKMSKeyId))
+	}
+
+	obj, err := s3Client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(object),
+	})
+	if err != nil {
+		fail(CheckDownload, "failed to download '%s/%s': %v", bucket, object, s3Error(err))
+		return checks
+	}
+	defer obj.Body.Close()
+
+	downloaded, err := io.ReadAll(io.LimitReader(obj.Body, 2*ObjectSize))
+	switch {
+	case err != nil:
+		fail(CheckDownload, "failed to download '%s/%s': %v", bucket, object, err)
+	case !bytes.Equal(downloaded, content):
+		fail(CheckDownload, "downloaded content does not match uploaded content")
+	default:
+		pass(CheckDownload, "downloaded content matches uploaded content")
+	}
+	return checks
+}
+
+// s3Error returns a concise error for S3 errors that
+// contains the S3 error code and message or cause.
+func s3Error(err error) error {
+	var awsErr awserr.Error
+	if errors.As(err, &awsErr) {
+		if awsErr.OrigErr() != nil {
+			return fmt.Errorf("%s: %v", awsErr.Code(), awsErr.OrigErr())
+		}
+		return fmt.Errorf("%s: %s", awsErr.Code(), awsErr.Message())
+	}
+	return err
+}
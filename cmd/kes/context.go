@@ -0,0 +1,459 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const contextCmdUsage = `Usage:
+    kes context <command>
+
+Commands:
+    add                      Add a context.
+    use                      Set the current context.
+    ls                       List contexts.
+    rm                       Remove contexts.
+
+Options:
+    -h, --help               Print command line options.
+
+A context is a named KES server endpoint and the credentials used
+to connect to it. Commands use the context selected by the global
+'--context' option or the KES_CONTEXT env. variable. Otherwise, they
+use the current context unless any of the KES_SERVER, KES_API_KEY,
+KES_CLIENT_CERT or KES_CLIENT_KEY env. variables is set.
+
+Contexts are stored in $XDG_CONFIG_HOME/kes/config.yml, or
+~/.config/kes/config.yml if XDG_CONFIG_HOME is not set. The file
+is only readable by the current user since it may contain API keys.
+
+Examples:
+    $ kes context add --server https://kes.prod.example.com:7373 --api-key "$PROD_API_KEY" prod
+    $ kes --context prod key ls
+`
+
+func contextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, contextCmdUsage) }
+
+	subCmds := commands{
+		"add": addContextCmd,
+		"use": useContextCmd,
+		"ls":  lsContextCmd,
+		"rm":  rmContextCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes context --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a context command. See 'kes context --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const addContextCmdUsage = `Usage:
+    kes context add [options] <name>
+
+Options:
+        --server <url>       KES server endpoint. Multiple endpoints are
+                             separated by commas.
+                             (default: $KES_SERVER or https://127.0.0.1:7373)
+        --api-key <key>      Authenticate with the API key.
+        --cert <file>        Authenticate with the TLS client certificate.
+        --key <file>         Private key of the TLS client certificate.
+        --from-env           Use the server and credentials of the KES_SERVER,
+                             KES_API_KEY, KES_CLIENT_CERT and KES_CLIENT_KEY
+                             env. variables.
+        --use                Set the context as current context.
+    -f, --force              Replace an existing context.
+
+    -h, --help               Print command line options.
+
+The first context added becomes the current context. Paths of
+certificates and private keys are stored as absolute paths.
+
+Examples:
+    $ kes context add --server https://kes.prod.example.com:7373 --api-key "$PROD_API_KEY" prod
+    $ kes context add --server https://kes.dev.example.com:7373 --cert client.crt --key client.key dev
+    $ kes context add --from-env --use staging
+`
+
+func addContextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, addContextCmdUsage) }
+
+	var (
+		serverFlag  string
+		apiKeyFlag  string
+		certFlag    string
+		keyFlag     string
+		fromEnvFlag bool
+		useFlag     bool
+		forceFlag   bool
+	)
+	cmd.StringVar(&serverFlag, "server", "", "KES server endpoint")
+	cmd.StringVar(&apiKeyFlag, "api-key", "", "Authenticate with the API key")
+	cmd.StringVar(&certFlag, "cert", "", "Authenticate with the TLS client certificate")
+	cmd.StringVar(&keyFlag, "key", "", "Private key of the TLS client certificate")
+	cmd.BoolVar(&fromEnvFlag, "from-env", false, "Use the server and credentials of the env. variables")
+	cmd.BoolVar(&useFlag, "use", false, "Set the context as current context")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Replace an existing context")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes context add --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no context name specified. See 'kes context add --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes context add --help'")
+	case fromEnvFlag && (apiKeyFlag != "" || certFlag != "" || keyFlag != ""):
+		cli.Fatal("'--from-env' cannot be used with '--api-key', '--cert' or '--key'")
+	case apiKeyFlag != "" && (certFlag != "" || keyFlag != ""):
+		cli.Fatal("'--api-key' cannot be used with '--cert' or '--key'")
+	}
+
+	name := cmd.Arg(0)
+	if !validContextName(name) {
+		cli.Fatalf("invalid context name '%s': must not be empty or contain whitespaces", name)
+	}
+	if fromEnvFlag {
+		apiKeyFlag, certFlag, keyFlag = os.Getenv(EnvAPIKey), os.Getenv(EnvClientCert), os.Getenv(EnvClientKey)
+	}
+	if serverFlag == "" {
+		serverFlag = "https://127.0.0.1:7373"
+		if env, ok := os.LookupEnv(EnvServer); ok {
+			serverFlag = env
+		}
+	}
+
+	c := cliContext{Server: serverFlag}
+	switch {
+	case apiKeyFlag != "":
+		if _, err := kes.ParseAPIKey(apiKeyFlag); err != nil {
+			cli.Fatalf("invalid API key: %v", err)
+		}
+		c.APIKey = apiKeyFlag
+	case certFlag != "" && keyFlag != "":
+		var err error
+		if c.Cert, err = filepath.Abs(certFlag); err != nil {
+			cli.Fatal(err)
+		}
+		if c.Key, err = filepath.Abs(keyFlag); err != nil {
+			cli.Fatal(err)
+		}
+		if _, err = loadClientCertificate(c.Cert, c.Key); err != nil {
+			cli.Fatal(err)
+		}
+	case certFlag != "" || keyFlag != "":
+		cli.Fatal("'--cert' and '--key' must be specified together")
+	default:
+		cli.Fatal("no credentials specified. Set '--api-key', '--cert' and '--key' or '--from-env'")
+	}
+
+	config, err := readCLIConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	if _, ok := config.Contexts[name]; ok && !forceFlag {
+		cli.Fatalf("context '%s' already exists. Use --force to replace it", name)
+	}
+	config.Contexts[name] = c
+	if useFlag || config.Current == "" {
+		config.Current = name
+	}
+	if err = config.write(); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+const useContextCmdUsage = `Usage:
+    kes context use <name>
+
+Options:
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes context use prod
+`
+
+func useContextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, useContextCmdUsage) }
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes context use --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no context name specified. See 'kes context use --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes context use --help'")
+	}
+
+	config, err := readCLIConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	name := cmd.Arg(0)
+	if _, ok := config.Contexts[name]; !ok {
+		cli.Fatalf("context '%s' does not exist. See 'kes context ls'", name)
+	}
+	config.Current = name
+	if err = config.write(); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+const lsContextCmdUsage = `Usage:
+    kes context ls [options]
+
+Options:
+        --json               Print contexts in JSON format.
+
+    -h, --help               Print command line options.
+
+The current context is marked with a '*'. API keys are not printed.
+
+Examples:
+    $ kes context ls
+`
+
+func lsContextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsContextCmdUsage) }
+
+	var jsonFlag bool
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print contexts in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes context ls --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes context ls --help'")
+	}
+
+	config, err := readCLIConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	names := sortedKeys(config.Contexts)
+
+	type Context struct {
+		Name    string `json:"name"`
+		Server  string `json:"server"`
+		Auth    string `json:"auth"`
+		Current bool   `json:"current,omitempty"`
+	}
+	contexts := make([]Context, 0, len(names))
+	for _, name := range names {
+		c := config.Contexts[name]
+		auth := "api key"
+		if c.APIKey == "" {
+			auth = "certificate " + c.Cert
+		}
+		contexts = append(contexts, Context{
+			Name:    name,
+			Server:  c.Server,
+			Auth:    auth,
+			Current: name == config.Current,
+		})
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(contexts); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	var width int
+	for _, c := range contexts {
+		width = max(width, len(c.Name))
+	}
+	for _, c := range contexts {
+		mark := " "
+		if c.Current {
+			mark = "*"
+		}
+		fmt.Printf("%s %-*s  %s  %s\n", mark, width, c.Name, c.Server, c.Auth)
+	}
+}
+
+const rmContextCmdUsage = `Usage:
+    kes context rm <name>...
+
+Options:
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes context rm staging
+`
+
+func rmContextCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmContextCmdUsage) }
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes context rm --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no context name specified. See 'kes context rm --help'")
+	}
+
+	config, err := readCLIConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	for _, name := range cmd.Args() {
+		if _, ok := config.Contexts[name]; !ok {
+			cli.Fatalf("context '%s' does not exist. See 'kes context ls'", name)
+		}
+		delete(config.Contexts, name)
+		if config.Current == name {
+			config.Current = ""
+		}
+	}
+	if err = config.write(); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+// cliConfig is the configuration file of the KES CLI.
+type cliConfig struct {
+	Current  string                `yaml:"current,omitempty"`
+	Contexts map[string]cliContext `yaml:"contexts"`
+}
+
+// cliContext is a KES server endpoint and the credentials
+// used to connect to it. Either the API key or the client
+// certificate and private key are set.
+type cliContext struct {
+	Server string `yaml:"server"`
+	APIKey string `yaml:"api_key,omitempty"`
+	Cert   string `yaml:"cert,omitempty"`
+	Key    string `yaml:"key,omitempty"`
+}
+
+// cliConfigPath returns the path of the KES CLI configuration file.
+func cliConfigPath() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "kes", "config.yml"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config file: %v", err)
+	}
+	return filepath.Join(home, ".config", "kes", "config.yml"), nil
+}
+
+// readCLIConfig reads the KES CLI configuration file. It returns
+// an empty configuration if the file does not exist.
+func readCLIConfig() (*cliConfig, error) {
+	filename, err := cliConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	config := &cliConfig{}
+	b, err := os.ReadFile(filename)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if err = yaml.Unmarshal(b, config); err != nil {
+		return nil, fmt.Errorf("invalid config file '%s': %v", filename, err)
+	}
+	if config.Contexts == nil {
+		config.Contexts = map[string]cliContext{}
+	}
+	return config, nil
+}
+
+// write writes the configuration to the KES CLI configuration
+// file. The file is only accessible by the current user.
+func (c *cliConfig) write() error {
+	filename, err := cliConfigPath()
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(filename), 0o700); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err = os.WriteFile(filename, b, 0o600); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
+
+// activeContext returns the name and the context selected via
+// the global --context option or the KES_CONTEXT env. variable.
+// Otherwise, it returns the current context unless credentials
+// are specified by env. variables. It returns a nil context if
+// no context is active.
+func activeContext() (string, *cliContext, error) {
+	name := globalContext
+	if name == "" {
+		for _, env := range []string{EnvServer, EnvAPIKey, EnvClientCert, EnvClientKey} {
+			if _, ok := os.LookupEnv(env); ok {
+				return "", nil, nil
+			}
+		}
+	}
+
+	config, err := readCLIConfig()
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "" {
+		name = config.Current
+	}
+	if name == "" {
+		return "", nil, nil
+	}
+	c, ok := config.Contexts[name]
+	if !ok {
+		return "", nil, fmt.Errorf("context '%s' does not exist. See 'kes context ls'", name)
+	}
+	return name, &c, nil
+}
+
+// validContextName reports whether name is a valid context name.
+func validContextName(name string) bool {
+	return name != "" && !strings.ContainsFunc(name, unicode.IsSpace)
+}
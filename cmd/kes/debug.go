@@ -0,0 +1,232 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const debugCmdUsage = `Usage:
+    kes debug <command>
+
+Commands:
+    profile                  Capture runtime profiles of the server.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func debugCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, debugCmdUsage) }
+
+	subCmds := commands{
+		"profile": profileDebugCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes debug --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a debug command. See 'kes debug --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const profileDebugCmdUsage = `Usage:
+    kes debug profile [options]
+
+Captures runtime profiles of the server and writes them to files
+in the pprof format. The profiles can be analyzed with 'go tool
+pprof', or 'go tool trace' for execution traces.
+
+The CPU, trace, block and mutex profiles are captured over the
+given time period. All other profiles are a snapshot of the server
+state. Profiles are captured one after another in the order below.
+
+Capturing profiles requires access to the '/v1/debug/pprof/*' API.
+
+Options:
+        --cpu <duration>     Capture a CPU profile.
+        --trace <duration>   Capture an execution trace.
+        --block <duration>   Capture a profile of blocking goroutines.
+        --mutex <duration>   Capture a profile of mutex contention.
+        --heap               Capture a profile of heap allocations.
+        --allocs             Capture a profile of all past allocations.
+        --goroutine          Capture a profile of all goroutines.
+        --threadcreate       Capture a profile of OS thread creation.
+
+    -o, --output <dir>       Write the profiles to the directory. Each
+                             profile is written to kes-<profile>-<time>.pprof
+                             Defaults to the current directory.
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes debug profile --cpu 30s
+    $ kes debug profile --heap --goroutine -o ./profiles
+    $ go tool pprof kes-cpu-*.pprof
+`
+
+func profileDebugCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, profileDebugCmdUsage) }
+
+	var (
+		cpuFlag            time.Duration
+		traceFlag          time.Duration
+		blockFlag          time.Duration
+		mutexFlag          time.Duration
+		heapFlag           bool
+		allocsFlag         bool
+		goroutineFlag      bool
+		threadcreateFlag   bool
+		outputFlag         string
+		insecureSkipVerify bool
+	)
+	cmd.DurationVar(&cpuFlag, "cpu", 0, "Capture a CPU profile")
+	cmd.DurationVar(&traceFlag, "trace", 0, "Capture an execution trace")
+	cmd.DurationVar(&blockFlag, "block", 0, "Capture a profile of blocking goroutines")
+	cmd.DurationVar(&mutexFlag, "mutex", 0, "Capture a profile of mutex contention")
+	cmd.BoolVar(&heapFlag, "heap", false, "Capture a profile of heap allocations")
+	cmd.BoolVar(&allocsFlag, "allocs", false, "Capture a profile of all past allocations")
+	cmd.BoolVar(&goroutineFlag, "goroutine", false, "Capture a profile of all goroutines")
+	cmd.BoolVar(&threadcreateFlag, "threadcreate", false, "Capture a profile of OS thread creation")
+	cmd.StringVarP(&outputFlag, "output", "o", ".", "Write the profiles to the directory")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes debug profile --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes debug profile --help'")
+	}
+
+	type Profile struct {
+		Name     string
+		Duration time.Duration
+	}
+	var profiles []Profile
+	for _, p := range []struct {
+		Name     string
+		Flag     string
+		Duration time.Duration
+	}{
+		{Name: "cpu", Flag: "cpu", Duration: cpuFlag},
+		{Name: "trace", Flag: "trace", Duration: traceFlag},
+		{Name: "block", Flag: "block", Duration: blockFlag},
+		{Name: "mutex", Flag: "mutex", Duration: mutexFlag},
+	} {
+		if !cmd.Changed(p.Flag) {
+			continue
+		}
+		if p.Duration < time.Second {
+			cli.Fatalf("invalid '--%s' duration '%v'. Must be at least 1s", p.Flag, p.Duration)
+		}
+		profiles = append(profiles, Profile{Name: p.Name, Duration: p.Duration})
+	}
+	for _, p := range []struct {
+		Name string
+		Set  bool
+	}{
+		{Name: "heap", Set: heapFlag},
+		{Name: "allocs", Set: allocsFlag},
+		{Name: "goroutine", Set: goroutineFlag},
+		{Name: "threadcreate", Set: threadcreateFlag},
+	} {
+		if p.Set {
+			profiles = append(profiles, Profile{Name: p.Name})
+		}
+	}
+	if len(profiles) == 0 {
+		cli.Fatal("no profile specified. See 'kes debug profile --help'")
+	}
+	if err := os.MkdirAll(outputFlag, 0o755); err != nil {
+		cli.Fatalf("failed to create output directory: %v", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	for _, p := range profiles {
+		if p.Duration > 0 {
+			fmt.Fprintf(os.Stderr, "Capturing %s profile for %v...\n", p.Name, p.Duration)
+		}
+
+		path := api.PathDebugProfile + p.Name
+		if p.Duration > 0 {
+			path += "?" + url.Values{"seconds": {strconv.FormatInt(int64(p.Duration.Round(time.Second)/time.Second), 10)}}.Encode()
+		}
+		filename := filepath.Join(outputFlag, fmt.Sprintf("kes-%s-%s.pprof", p.Name, time.Now().UTC().Format("2006-01-02T15-04-05")))
+		endpoint, err := downloadProfile(ctx, client, path, filename)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to capture %s profile: %v", p.Name, err)
+		}
+		fmt.Println("Captured", filename)
+
+		// All profiles are captured from the same server.
+		client = pinEndpoint(client, endpoint)
+	}
+}
+
+// downloadProfile fetches the profile from the API path and writes
+// it to the file. It returns the endpoint the profile has been
+// fetched from.
+func downloadProfile(ctx context.Context, client *kes.Client, path, filename string) (string, error) {
+	resp, err := sendGet(ctx, client, path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", api.ReadError(resp)
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(file, resp.Body); err != nil {
+		file.Close()
+		os.Remove(filename)
+		return "", err
+	}
+	return strings.TrimSuffix(resp.Request.URL.String(), resp.Request.URL.RequestURI()), file.Close()
+}
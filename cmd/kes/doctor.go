@@ -0,0 +1,327 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const doctorCmdUsage = `Usage:
+    kes doctor [options]
+
+Checks the client configuration and the connection to the
+KES server and prints a diagnostic report. It exits with a
+non-zero status if any check fails.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print diagnostic report in JSON format.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes doctor
+    $ kes doctor --json > report.json
+`
+
+// Diagnostic check results.
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// doctorCheck is the result of a single diagnostic check.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+func doctorCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, doctorCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print diagnostic report in JSON format")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes doctor --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes doctor --help'")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	checks := runDoctor(ctx, insecureSkipVerify)
+	printChecks(checks, jsonFlag, colorFlag.Colorize())
+	for _, check := range checks {
+		if check.Status == checkFail {
+			os.Exit(1)
+		}
+	}
+}
+
+// printChecks prints the diagnostic report to STDOUT, either
+// as JSON or as one, optionally colored, line per check.
+func printChecks(checks []doctorCheck, jsonFlag, colorize bool) {
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err := encoder.Encode(checks); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	var faint, passStyle, warnStyle, failStyle tui.Style
+	if colorize {
+		const (
+			ColorPass tui.Color = "#00d700"
+			ColorWarn tui.Color = "#d7af00"
+			ColorFail tui.Color = "#d70000"
+		)
+		faint = faint.Faint(true)
+		passStyle = passStyle.Foreground(ColorPass).Bold(true)
+		warnStyle = warnStyle.Foreground(ColorWarn).Bold(true)
+		failStyle = failStyle.Foreground(ColorFail).Bold(true)
+	}
+	for _, check := range checks {
+		var status string
+		switch check.Status {
+		case checkPass:
+			status = passStyle.Render("PASS")
+		case checkWarn:
+			status = warnStyle.Render("WARN")
+		case checkFail:
+			status = failStyle.Render("FAIL")
+		default:
+			status = faint.Render("SKIP")
+		}
+		fmt.Println(status, fmt.Sprintf("%-19s", check.Name), check.Message)
+	}
+}
+
+// runDoctor runs all diagnostic checks in order. Checks that
+// depend on a previous, failed check are skipped.
+func runDoctor(ctx context.Context, insecureSkipVerify bool) []doctorCheck {
+	const (
+		CheckEnv      = "Environment"
+		CheckCert     = "Client certificate"
+		CheckNetwork  = "Server reachable"
+		CheckTLS      = "TLS handshake"
+		CheckClock    = "Clock skew"
+		CheckIdentity = "Identity policy"
+
+		// ExpiryWarning is the time period before a certificate
+		// expires in which a warning is reported.
+		ExpiryWarning = 30 * 24 * time.Hour
+	)
+	checks := make([]doctorCheck, 0, 6)
+	pass := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkPass, Message: fmt.Sprintf(format, v...)})
+	}
+	warn := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkWarn, Message: fmt.Sprintf(format, v...)})
+	}
+	fail := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkFail, Message: fmt.Sprintf(format, v...)})
+	}
+	skip := func(names ...string) []doctorCheck {
+		for _, name := range names {
+			checks = append(checks, doctorCheck{Name: name, Status: checkSkip, Message: "skipped due to previous failure"})
+		}
+		return checks
+	}
+
+	addr, cert, err := loadClientConfig()
+	if err != nil {
+		fail(CheckEnv, "%v", err)
+		return skip(CheckCert, CheckNetwork, CheckTLS, CheckClock, CheckIdentity)
+	}
+	if name, c, _ := activeContext(); c != nil {
+		pass(CheckEnv, "server %s, using context '%s'", addr, name)
+	} else if _, ok := os.LookupEnv(EnvAPIKey); ok {
+		pass(CheckEnv, "server %s, authenticating with %s", addr, EnvAPIKey)
+	} else {
+		pass(CheckEnv, "server %s, authenticating with %s and %s", addr, EnvClientCert, EnvClientKey)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		fail(CheckCert, "failed to parse certificate: %v", err)
+		return skip(CheckNetwork, CheckTLS, CheckClock, CheckIdentity)
+	}
+	h := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	identity := hex.EncodeToString(h[:])
+	switch now := time.Now(); {
+	case now.Before(leaf.NotBefore):
+		fail(CheckCert, "identity %s not valid before %s", identity, leaf.NotBefore.Local().Format(time.RFC3339))
+	case now.After(leaf.NotAfter):
+		fail(CheckCert, "identity %s expired at %s", identity, leaf.NotAfter.Local().Format(time.RFC3339))
+	case leaf.NotAfter.Sub(now) < ExpiryWarning:
+		warn(CheckCert, "identity %s expires soon at %s", identity, leaf.NotAfter.Local().Format(time.RFC3339))
+	default:
+		pass(CheckCert, "identity %s valid until %s", identity, leaf.NotAfter.Local().Format(time.RFC3339))
+	}
+
+	// With multiple endpoints, the network and TLS checks are
+	// performed for each endpoint and the remaining checks use
+	// all reachable endpoints.
+	endpoints := splitEndpoints(addr)
+	reachable := make([]string, 0, len(endpoints))
+	for _, addr := range endpoints {
+		prefix := ""
+		if len(endpoints) > 1 {
+			prefix = addr + ": "
+		}
+
+		endpoint, err := url.Parse(addr)
+		if err != nil || endpoint.Host == "" {
+			fail(CheckNetwork, "invalid server address '%s'", addr)
+			continue
+		}
+		host := endpoint.Host
+		if endpoint.Port() == "" {
+			host = net.JoinHostPort(endpoint.Hostname(), "443")
+		}
+		dialer := &net.Dialer{Timeout: 10 * time.Second}
+		start := time.Now()
+		conn, err := dialer.DialContext(ctx, "tcp", host)
+		if err != nil {
+			fail(CheckNetwork, "%s%v", prefix, err)
+			continue
+		}
+		pass(CheckNetwork, "connected to %s in %v", conn.RemoteAddr(), time.Since(start).Round(time.Millisecond))
+		conn.Close()
+
+		tlsConn, err := (&tls.Dialer{
+			NetDialer: dialer,
+			Config: &tls.Config{
+				ServerName:         endpoint.Hostname(),
+				Certificates:       []tls.Certificate{cert},
+				InsecureSkipVerify: insecureSkipVerify,
+			},
+		}).DialContext(ctx, "tcp", host)
+		if err != nil {
+			var verifyErr *tls.CertificateVerificationError
+			if errors.As(err, &verifyErr) {
+				fail(CheckTLS, "%s%v. Use '--insecure' to skip certificate validation", prefix, err)
+			} else {
+				fail(CheckTLS, "%s%v", prefix, err)
+			}
+			continue
+		}
+		state := tlsConn.(*tls.Conn).ConnectionState()
+		tlsConn.Close()
+		reachable = append(reachable, addr)
+
+		srvCert := state.PeerCertificates[0]
+		details := fmt.Sprintf("%s%s %s, server certificate '%s'", prefix, tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite), srvCert.Subject.CommonName)
+		switch now := time.Now(); {
+		case now.After(srvCert.NotAfter):
+			fail(CheckTLS, "%s expired at %s", details, srvCert.NotAfter.Local().Format(time.RFC3339))
+		case srvCert.NotAfter.Sub(now) < ExpiryWarning:
+			warn(CheckTLS, "%s expires soon at %s", details, srvCert.NotAfter.Local().Format(time.RFC3339))
+		case insecureSkipVerify:
+			warn(CheckTLS, "%s not verified", details)
+		default:
+			pass(CheckTLS, "%s valid until %s", details, srvCert.NotAfter.Local().Format(time.RFC3339))
+		}
+	}
+	if len(reachable) == 0 {
+		return skip(CheckClock, CheckIdentity)
+	}
+
+	client := kes.NewClientWithConfig(reachable[0], &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: insecureSkipVerify,
+	})
+	client.Endpoints = reachable
+	if skew, err := clockSkew(ctx, client); err != nil {
+		fail(CheckClock, "%v", err)
+	} else {
+		const (
+			MaxWarnSkew = 30 * time.Second
+			MaxFailSkew = 5 * time.Minute
+		)
+		switch abs := max(skew, -skew); {
+		case abs > MaxFailSkew:
+			fail(CheckClock, "local clock differs from server clock by %v", skew)
+		case abs > MaxWarnSkew:
+			warn(CheckClock, "local clock differs from server clock by %v", skew)
+		default:
+			pass(CheckClock, "local clock differs from server clock by %v", skew)
+		}
+	}
+
+	info, policy, err := client.DescribeSelf(ctx)
+	switch {
+	case err != nil:
+		fail(CheckIdentity, "%v", err)
+	case info.IsAdmin:
+		pass(CheckIdentity, "identity is the admin identity")
+	case info.Policy == "":
+		warn(CheckIdentity, "identity is not assigned to any policy")
+	default:
+		pass(CheckIdentity, "policy '%s' with %d allow and %d deny rules", info.Policy, len(policy.Allow), len(policy.Deny))
+	}
+	return checks
+}
+
+// clockSkew returns the difference between the local clock and
+// the server's clock based on the server's HTTP Date header. It
+// has a precision of one second.
+func clockSkew(ctx context.Context, client *kes.Client) (time.Duration, error) {
+	start := time.Now()
+	resp, err := sendGet(ctx, client, api.PathVersion)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	rtt := time.Since(start)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, errors.New("server response contains no valid date")
+	}
+	return start.Add(rtt / 2).Truncate(time.Second).Sub(date), nil
+}
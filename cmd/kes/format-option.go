@@ -0,0 +1,72 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	flag "github.com/spf13/pflag"
+)
+
+// formatOption is a CLI flag that holds a Go template
+// used to print command output. For example:
+//
+//	--format '{{.Name}} {{.CreatedAt}}'
+//
+// Commands that print a list of items execute the template
+// once for each item.
+type formatOption struct {
+	value string
+	tmpl  *template.Template
+}
+
+var _ flag.Value = (*formatOption)(nil)
+
+// formatFuncs are the functions available within
+// format templates in addition to the text/template
+// builtin functions.
+var formatFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// IsSet reports whether a format template has been specified.
+func (f *formatOption) IsSet() bool { return f.tmpl != nil }
+
+// Print executes the format template for each value and writes
+// the output, followed by a newline, to os.Stdout.
+func (f *formatOption) Print(values ...any) error {
+	w := bufio.NewWriter(os.Stdout)
+	for _, v := range values {
+		if err := f.tmpl.Execute(w, v); err != nil {
+			return err
+		}
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+func (f *formatOption) String() string { return f.value }
+
+func (f *formatOption) Set(value string) error {
+	tmpl, err := template.New("format").Funcs(formatFuncs).Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid format template: %v", err)
+	}
+	f.value, f.tmpl = value, tmpl
+	return nil
+}
+
+func (f *formatOption) Type() string { return "template" }
@@ -9,6 +9,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/json"
@@ -17,13 +18,15 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
 	"slices"
 	"strings"
 	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kms-go/kes"
@@ -36,6 +39,7 @@ const identityCmdUsage = `Usage:
 
 Commands:
     new                      Create a new KES identity.
+    renew                    Replace an identity by a new one.
     of                       Compute a KES identity from a certificate.
     info                     Get information about a KES identity.
     ls                       List KES identities.
@@ -49,10 +53,11 @@ func identityCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, identityCmdUsage) }
 
 	subCmds := commands{
-		"new":  newIdentityCmd,
-		"of":   ofIdentityCmd,
-		"info": infoIdentityCmd,
-		"ls":   lsIdentityCmd,
+		"new":   newIdentityCmd,
+		"renew": renewIdentityCmd,
+		"of":    ofIdentityCmd,
+		"info":  infoIdentityCmd,
+		"ls":    lsIdentityCmd,
 	}
 
 	if len(args) < 2 {
@@ -80,6 +85,13 @@ func identityCmd(args []string) {
 const newIdentityCmdUsage = `Usage:
     kes identity new [options] [<subject>]
 
+With --ttl, the identity is issued by the KES server instead of
+generated locally. The server assigns the policy to the identity
+until the TTL has passed. Afterwards, the identity gets revoked.
+This is useful for short-lived credentials, like the ones of CI
+jobs. Issuing identities requires the admin identity or a policy
+that allows the identity issue API.
+
 Options:
     --key <PATH>             Optional path for the private key. 
     --cert <PATH>            Optional path for the certificate.
@@ -93,11 +105,19 @@ Options:
     --encrypt                Encrypt the private key with a password. Requires
                              the --key and --cert flags. 
     -f, --force              Overwrite an existing private key and/or certificate.
+        --json               Print API key and identity in JSON format.
+
+        --ttl <DURATION>     Issue a short-lived identity via the KES server,
+                             e.g. 1h. Requires the --policy flag.
+        --policy <NAME>      Policy assigned to the issued identity.
+    -k, --insecure           Skip TLS certificate validation. Requires the --ttl
+                             flag.
 
     -h, --help               Print command line options.
 
 Examples:
     $ kes identity new
+    $ kes identity new --ttl 1h --policy ci-pipeline
     $ kes identity new --ip "192.168.0.182" --ip "10.0.0.92" localhost
     $ kes identity new --key server.key --cert server.crt --encrypt --expiry 8760h kes-server.local
 `
@@ -114,6 +134,11 @@ func newIdentityCmd(args []string) {
 		domains   []string
 		expiry    time.Duration
 		encrypt   bool
+		jsonFlag  bool
+
+		ttlFlag            time.Duration
+		policyFlag         string
+		insecureSkipVerify bool
 	)
 	cmd.StringVar(&keyPath, "key", "", "Path to private key")
 	cmd.StringVar(&certPath, "cert", "", "Path to certificate")
@@ -122,6 +147,10 @@ func newIdentityCmd(args []string) {
 	cmd.StringSliceVar(&domains, "dns", []string{}, "Add <DOMAIN> as subject alternative name")
 	cmd.DurationVar(&expiry, "expiry", 0, "Duration until the certificate expires")
 	cmd.BoolVar(&encrypt, "encrypt", false, "Encrypt the private key with a password")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print API key and identity in JSON format")
+	cmd.DurationVar(&ttlFlag, "ttl", 0, "Issue a short-lived identity via the KES server")
+	cmd.StringVar(&policyFlag, "policy", "", "Policy assigned to the issued identity")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -154,10 +183,45 @@ func newIdentityCmd(args []string) {
 			cli.Fatalf("'--dns' requires a private key and certificate file. Set the '--cert' and '--key' flag")
 		}
 	}
+	switch {
+	case ttlFlag < 0:
+		cli.Fatal("invalid TTL: must not be negative. See 'kes identity new --help'")
+	case ttlFlag > 0 && policyFlag == "":
+		cli.Fatal("'--ttl' requires a policy. Set the '--policy' flag")
+	case ttlFlag == 0 && policyFlag != "":
+		cli.Fatal("'--policy' requires a TTL. Set the '--ttl' flag")
+	case ttlFlag == 0 && insecureSkipVerify:
+		cli.Fatal("'--insecure' requires a TTL. Set the '--ttl' flag")
+	case ttlFlag > 0 && expiry > 0:
+		cli.Fatal("'--expiry' cannot be used with '--ttl'. The certificate expires with the issued identity")
+	}
 
-	key, err := kes.GenerateAPIKey(nil)
-	if err != nil {
-		cli.Fatalf("failed to generate API key: %v", err)
+	var (
+		key    kes.APIKey
+		issued api.IssueIdentityResponse
+		err    error
+	)
+	if ttlFlag > 0 {
+		ctx, cancelCtx := newContext()
+		defer cancelCtx()
+
+		client := newClient(insecureSkipVerify)
+		err = sendRequest(ctx, client, http.MethodPut, api.PathIdentityIssue+policyFlag, api.IssueIdentityRequest{
+			TTL: ttlFlag.String(),
+		}, &issued)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to issue identity: %v", err)
+		}
+		if key, err = kes.ParseAPIKey(issued.APIKey); err != nil {
+			cli.Fatalf("failed to issue identity: invalid API key: %v", err)
+		}
+	} else {
+		if key, err = kes.GenerateAPIKey(nil); err != nil {
+			cli.Fatalf("failed to generate API key: %v", err)
+		}
 	}
 
 	if keyPath != "" && certPath != "" {
@@ -176,6 +240,9 @@ func newIdentityCmd(args []string) {
 		options = append(options, func(cert *x509.Certificate) {
 			now := time.Now()
 			cert.NotBefore, cert.NotAfter = now, now.Add(expiry)
+			if !issued.ExpiresAt.IsZero() {
+				cert.NotAfter = issued.ExpiresAt
+			}
 		})
 		cert, err := kes.GenerateCertificate(key, options...)
 		if err != nil {
@@ -234,6 +301,32 @@ func newIdentityCmd(args []string) {
 		}
 	}
 
+	if jsonFlag {
+		type Output struct {
+			APIKey      string     `json:"api_key"`
+			Identity    string     `json:"identity"`
+			PrivateKey  string     `json:"private_key,omitempty"`
+			Certificate string     `json:"certificate,omitempty"`
+			Policy      string     `json:"policy,omitempty"`
+			ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+		}
+		output := Output{
+			APIKey:      key.String(),
+			Identity:    key.Identity().String(),
+			PrivateKey:  keyPath,
+			Certificate: certPath,
+			Policy:      issued.Policy,
+		}
+		if !issued.ExpiresAt.IsZero() {
+			output.ExpiresAt = &issued.ExpiresAt
+		}
+		err := json.NewEncoder(os.Stdout).Encode(output)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
 	bold := tui.NewStyle()
 	if isTerm(os.Stdout) {
 		bold = bold.Bold(true)
@@ -249,6 +342,11 @@ func newIdentityCmd(args []string) {
 	fmt.Fprintln(&buffer, "   "+bold.Render(key.Identity().String())+"\n")
 	fmt.Fprintln(&buffer, "The identity is not a secret. It can be shared. Any peer")
 	fmt.Fprintln(&buffer, "needs this identity in order to verify your API key.")
+	if !issued.ExpiresAt.IsZero() {
+		fmt.Fprintln(&buffer)
+		fmt.Fprintf(&buffer, "The identity has been assigned to the policy '%s'.\n", issued.Policy)
+		fmt.Fprintf(&buffer, "It expires at %s.\n", issued.ExpiresAt.Local().Format(time.RFC1123))
+	}
 	if keyPath != "" && certPath != "" {
 		fmt.Fprintln(&buffer)
 		fmt.Fprintf(&buffer, "The generated TLS private key is stored at: %s\n", keyPath)
@@ -264,11 +362,287 @@ func newIdentityCmd(args []string) {
 	cli.Println(buffer.String())
 }
 
+const renewIdentityCmdUsage = `Usage:
+    kes identity renew [options] <identity>
+
+Generates a new API key and assigns the policy of the identity
+to the new identity. The old identity remains valid for the grace
+period and gets revoked afterwards. Clients can switch to the new
+API key within the grace period without any downtime.
+
+If the identity expires, the new identity expires at the same time.
+Identities assigned to a policy within the server config are not
+revoked when the server reloads its config. Replace them within
+the config instead.
+
+An identity can renew itself. Then, the grace period of the old
+identity is set using the new API key since an identity cannot
+assign a policy to itself. If the grace period cannot be set, the
+new identity is revoked again.
+
+If assigning the policy requires an approval, the new API key is
+printed anyway. The new identity gets assigned once the request
+has been approved. Until then, the old identity is not revoked.
+
+Options:
+        --grace <DURATION>   Time until the old identity gets revoked.
+                             (default: 24h)
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print API key and identity in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes identity renew 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+    $ kes identity renew --grace 1h 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+`
+
+func renewIdentityCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, renewIdentityCmdUsage) }
+
+	var (
+		graceFlag          time.Duration
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.DurationVar(&graceFlag, "grace", 24*time.Hour, "Time until the old identity gets revoked")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print API key and identity in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes identity renew --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no identity specified. See 'kes identity renew --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes identity renew --help'")
+	case graceFlag <= 0:
+		cli.Fatal("invalid grace period: must be positive. See 'kes identity renew --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	identity := cmd.Arg(0)
+
+	var info api.DescribeIdentityResponse
+	if err := sendRequest(ctx, client, http.MethodGet, api.PathIdentityDescribe+identity, nil, &info); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to renew identity '%s': %v", identity, err)
+	}
+	if info.IsAdmin {
+		cli.Fatalf("failed to renew identity '%s': the admin identity is part of the server config", identity)
+	}
+	if info.Policy == "" {
+		cli.Fatalf("failed to renew identity '%s': identity has no policy", identity)
+	}
+
+	var self string
+	if certs := client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.Certificates; len(certs) > 0 && len(certs[0].Certificate) > 0 {
+		leaf, err := x509.ParseCertificate(certs[0].Certificate[0])
+		if err != nil {
+			cli.Fatalf("failed to parse client certificate: %v", err)
+		}
+		h := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+		self = hex.EncodeToString(h[:])
+	}
+
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		cli.Fatalf("failed to generate API key: %v", err)
+	}
+
+	// Assign the policy to the new identity before revoking
+	// the old one such that clients can switch at any time.
+	now := time.Now()
+	assign := api.AssignPolicyRequest{Identities: []string{key.Identity().String()}}
+	if !info.ExpiresAt.IsZero() {
+		ttl := info.ExpiresAt.Sub(now)
+		if ttl <= 0 {
+			cli.Fatalf("failed to renew identity '%s': identity has expired", identity)
+		}
+		assign.TTL = ttl.String()
+	}
+	var pending *approvalRequiredError
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+info.Policy, assign, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		if !errors.As(err, &pending) {
+			cli.Fatalf("failed to renew identity '%s': %v", identity, err)
+		}
+
+		// The new identity is assigned once the request has been
+		// approved. Hence, the API key must be shown now. The old
+		// identity remains valid until it is revoked explicitly.
+		fmt.Fprintf(os.Stderr, "Assigning the new identity to the policy '%s' waits for approval until %s.\n", info.Policy, pending.ExpiresAt.Local().Format(time.RFC1123))
+		fmt.Fprintf(os.Stderr, "Once approved, revoke the old identity after a grace period via:\n\n    kes policy assign --expiry %s %s %s\n\n", graceFlag, info.Policy, identity)
+		printRenewedIdentity(&identityRenewal{
+			Key:        key,
+			Identity:   identity,
+			Info:       info,
+			ApprovalID: pending.ID,
+		}, jsonFlag)
+		return
+	}
+
+	// An identity cannot assign a policy to itself. Hence, the
+	// grace period of the old identity is set by the new one if
+	// an identity renews itself. Both have the same policy.
+	graceClient := client
+	if self == identity {
+		cert, err := kes.GenerateCertificate(key)
+		if err != nil {
+			revokeRenewedIdentity(ctx, client, key.Identity(), info.Policy, identity, err)
+		}
+		conf := client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.Clone()
+		conf.Certificates = []tls.Certificate{cert}
+		graceClient = kes.NewClientWithConfig(client.Endpoints[0], conf)
+		graceClient.Endpoints = client.Endpoints
+	}
+
+	revokeAt := now.Add(graceFlag)
+	if info.ExpiresAt.IsZero() || info.ExpiresAt.After(revokeAt) {
+		assign = api.AssignPolicyRequest{
+			Identities: []string{identity},
+			TTL:        graceFlag.String(),
+		}
+		if err = sendRequest(ctx, graceClient, http.MethodPut, api.PathPolicyAssign+info.Policy, assign, nil); err != nil {
+			if !errors.As(err, &pending) {
+				revokeRenewedIdentity(ctx, client, key.Identity(), info.Policy, identity, err)
+			}
+
+			// The new identity is valid already. The old identity
+			// remains valid until the grace period is approved.
+			fmt.Fprintf(os.Stderr, "Setting the grace period of the old identity waits for approval until %s.\n\n", pending.ExpiresAt.Local().Format(time.RFC1123))
+			printRenewedIdentity(&identityRenewal{
+				Key:        key,
+				Identity:   identity,
+				Info:       info,
+				Assigned:   true,
+				ApprovalID: pending.ID,
+			}, jsonFlag)
+			return
+		}
+	} else {
+		revokeAt = info.ExpiresAt
+	}
+	printRenewedIdentity(&identityRenewal{
+		Key:      key,
+		Identity: identity,
+		Info:     info,
+		Assigned: true,
+		RevokeAt: revokeAt,
+	}, jsonFlag)
+}
+
+// revokeRenewedIdentity rolls back a failed renewal of the identity
+// by letting the assignment of the renewed identity expire right away
+// and exits. It reports the renewed identity if it cannot be revoked.
+func revokeRenewedIdentity(ctx context.Context, client *kes.Client, renewed kes.Identity, policy, identity string, cause error) {
+	if ctx.Err() != nil {
+		ctx = context.Background()
+	}
+	assign := api.AssignPolicyRequest{
+		Identities: []string{renewed.String()},
+		TTL:        time.Second.String(),
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+policy, assign, nil); err != nil {
+		cli.Fatalf("failed to set grace period of identity '%s': %v. The new identity '%s' remains assigned to the policy '%s' since it could not be revoked: %v", identity, cause, renewed, policy, err)
+	}
+	cli.Fatalf("failed to set grace period of identity '%s': %v. The new identity '%s' has been revoked", identity, cause, renewed)
+}
+
+// identityRenewal describes the result of renewing an identity.
+type identityRenewal struct {
+	Key        kes.APIKey                   // The API key of the new identity
+	Identity   string                       // The old identity
+	Info       api.DescribeIdentityResponse // The old identity's description
+	Assigned   bool                         // Whether the new identity has been assigned to the policy
+	RevokeAt   time.Time                    // When the old identity gets revoked. Zero if unknown
+	ApprovalID string                       // The request waiting for approval, if any
+}
+
+// printRenewedIdentity prints the API key and identity of a
+// renewed identity.
+func printRenewedIdentity(r *identityRenewal, jsonFlag bool) {
+	if jsonFlag {
+		type Output struct {
+			APIKey     string     `json:"api_key"`
+			Identity   string     `json:"identity"`
+			Policy     string     `json:"policy"`
+			Assigned   bool       `json:"assigned"`
+			ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+			RevokedAt  *time.Time `json:"old_identity_revoked_at,omitempty"`
+			ApprovalID string     `json:"approval_id,omitempty"`
+		}
+		output := Output{
+			APIKey:     r.Key.String(),
+			Identity:   r.Key.Identity().String(),
+			Policy:     r.Info.Policy,
+			Assigned:   r.Assigned,
+			ApprovalID: r.ApprovalID,
+		}
+		if !r.Info.ExpiresAt.IsZero() {
+			output.ExpiresAt = &r.Info.ExpiresAt
+		}
+		if !r.RevokeAt.IsZero() {
+			revokeAt := r.RevokeAt.UTC()
+			output.RevokedAt = &revokeAt
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(output); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	bold := tui.NewStyle()
+	if isTerm(os.Stdout) {
+		bold = bold.Bold(true)
+	}
+	var buffer strings.Builder
+	fmt.Fprintln(&buffer, "Your new API key:")
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "   "+bold.Render(r.Key.String())+"\n")
+	fmt.Fprintln(&buffer, "This is the only time it is shown. Keep it secret and secure!")
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "Your new Identity:")
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "   "+bold.Render(r.Key.Identity().String())+"\n")
+	if r.Assigned {
+		fmt.Fprintf(&buffer, "The new identity has been assigned to the policy '%s'.\n", r.Info.Policy)
+	} else {
+		fmt.Fprintf(&buffer, "The new identity gets assigned to the policy '%s' once request '%s' has been approved.\n", r.Info.Policy, r.ApprovalID)
+	}
+	if !r.Info.ExpiresAt.IsZero() {
+		fmt.Fprintf(&buffer, "It expires at %s.\n", r.Info.ExpiresAt.Local().Format(time.RFC1123))
+	}
+	fmt.Fprintln(&buffer)
+	switch {
+	case !r.RevokeAt.IsZero():
+		fmt.Fprintf(&buffer, "The old identity '%s' gets revoked at %s.", r.Identity, r.RevokeAt.Local().Format(time.RFC1123))
+	case r.ApprovalID != "" && r.Assigned:
+		fmt.Fprintf(&buffer, "The old identity '%s' remains valid until request '%s' has been approved.", r.Identity, r.ApprovalID)
+	default:
+		fmt.Fprintf(&buffer, "The old identity '%s' remains valid until it gets revoked.", r.Identity)
+	}
+	cli.Println(buffer.String())
+}
+
 const ofIdentityCmdUsage = `Usage:
     kes identity of <api-key>
     kes identity of <certificate>
 
 Options:
+        --json               Print identity in JSON format.
+
     -h, --help               Print command line options.
 
 Examples:
@@ -280,6 +654,8 @@ func ofIdentityCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, ofIdentityCmdUsage) }
 
+	var jsonFlag bool
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print identity in JSON format")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -316,6 +692,15 @@ func ofIdentityCmd(args []string) {
 		h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
 		identity = kes.Identity(hex.EncodeToString(h[:]))
 	}
+	if jsonFlag {
+		err := json.NewEncoder(os.Stdout).Encode(struct {
+			Identity string `json:"identity"`
+		}{identity.String()})
+		if err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
 	if isTerm(os.Stdout) {
 		var buffer strings.Builder
 		fmt.Fprintln(&buffer, "Identity:")
@@ -333,6 +718,7 @@ const infoIdentityCmdUsage = `Usage:
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print identity information in JSON format.
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -352,11 +738,13 @@ func infoIdentityCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print policy information in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print policy information in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -367,11 +755,14 @@ func infoIdentityCmd(args []string) {
 		cli.Fatalf("%v. See 'kes policy ls --help'", err)
 	}
 
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes identity info --help'")
+	}
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes identity info --help'")
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	var faint, identityStyle, policyStyle, dotAllowStyle, dotDenyStyle tui.Style
@@ -395,6 +786,39 @@ func infoIdentityCmd(args []string) {
 		if err != nil {
 			cli.Fatal(err)
 		}
+		if formatFlag.IsSet() {
+			if err = formatFlag.Print(info); err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
+		if jsonFlag {
+			resp := api.SelfDescribeIdentityResponse{
+				Identity:  info.Identity.String(),
+				IsAdmin:   info.IsAdmin,
+				CreatedAt: info.CreatedAt,
+				CreatedBy: info.CreatedBy.String(),
+			}
+			if info.Policy != "" {
+				resp.Policy = &api.ReadPolicyResponse{
+					Name:      info.Policy,
+					Allow:     make(map[string]struct{}, len(policy.Allow)),
+					Deny:      make(map[string]struct{}, len(policy.Deny)),
+					CreatedAt: policy.CreatedAt,
+					CreatedBy: policy.CreatedBy.String(),
+				}
+				for path := range policy.Allow {
+					resp.Policy.Allow[path] = struct{}{}
+				}
+				for path := range policy.Deny {
+					resp.Policy.Deny[path] = struct{}{}
+				}
+			}
+			if err = json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
 		year, month, day := info.CreatedAt.Date()
 		hour, min, sec := info.CreatedAt.Clock()
 
@@ -442,6 +866,30 @@ func infoIdentityCmd(args []string) {
 		if err != nil {
 			cli.Fatal(err)
 		}
+		if formatFlag.IsSet() {
+			if err = formatFlag.Print(info); err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
+		if jsonFlag {
+			err = json.NewEncoder(os.Stdout).Encode(struct {
+				Identity string `json:"identity"`
+				api.DescribeIdentityResponse
+			}{
+				Identity: info.Identity.String(),
+				DescribeIdentityResponse: api.DescribeIdentityResponse{
+					IsAdmin:   info.IsAdmin,
+					Policy:    info.Policy,
+					CreatedAt: info.CreatedAt,
+					CreatedBy: info.CreatedBy.String(),
+				},
+			})
+			if err != nil {
+				cli.Fatal(err)
+			}
+			return
+		}
 		year, month, day := info.CreatedAt.Date()
 		hour, min, sec := info.CreatedAt.Clock()
 
@@ -473,17 +921,27 @@ const lsIdentityCmdUsage = `Usage:
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print identities in JSON format.
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
                              the output goes to a pipe.
                              Possible values: *auto*, never, always.
+    -l, --long               List identities with their policy, creation
+                             date, creator and remaining TTL.
+        --policy <name>      Only list identities assigned to the policy.
+        --created-after <t>  Only list identities created after the RFC 3339
+                             timestamp or date, e.g. '2024-01-31'.
+        --sort <field>       Sort identities by the field.
+                             Possible values: *name*, policy, created, expires.
 
     -h, --help               Print command line options.
 
 Examples:
     $ kes identity ls
     $ kes identity ls 'b804befd*'
+    $ kes identity ls --long --policy my-app --sort created
+    $ kes identity ls --created-after 2024-01-31 --json
 `
 
 func lsIdentityCmd(args []string) {
@@ -492,12 +950,22 @@ func lsIdentityCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
+		longFlag           bool
+		policyFlag         string
+		createdAfterFlag   string
+		sortFlag           string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print identities in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVarP(&longFlag, "long", "l", false, "List identities with their metadata")
+	cmd.StringVar(&policyFlag, "policy", "", "Only list identities assigned to the policy")
+	cmd.StringVar(&createdAfterFlag, "created-after", "", "Only list identities created after the time")
+	cmd.StringVar(&sortFlag, "sort", "name", "Sort identities by the field")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -505,6 +973,9 @@ func lsIdentityCmd(args []string) {
 		cli.Fatalf("%v. See 'kes identity ls --help'", err)
 	}
 
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes identity ls --help'")
+	}
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes identity ls --help'")
 	}
@@ -514,9 +985,34 @@ func lsIdentityCmd(args []string) {
 		prefix = cmd.Arg(0)
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
+	if longFlag || policyFlag != "" || createdAfterFlag != "" || cmd.Changed("sort") {
+		compare, ok := identityInfoOrder[sortFlag]
+		if !ok {
+			cli.Fatalf("invalid sort field '%s'. See 'kes identity ls --help'", sortFlag)
+		}
+		if prefix == "" {
+			prefix = "*"
+		}
+		query := url.Values{}
+		if policyFlag != "" {
+			query.Set("policy", policyFlag)
+		}
+		if createdAfterFlag != "" {
+			query.Set("created_after", createdAfterFlag)
+		}
+
+		var resp api.ListIdentityInfosResponse
+		if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathIdentityListInfo+prefix+"?"+query.Encode(), nil, &resp); err != nil {
+			cli.Fatalf("failed to list identities: %v", err)
+		}
+		slices.SortStableFunc(resp.Identities, compare)
+		printIdentityInfos(resp.Identities, longFlag, jsonFlag, formatFlag, colorFlag)
+		return
+	}
+
 	enclave := newClient(insecureSkipVerify)
 	iter := &kes.ListIter[kes.Identity]{
 		NextFunc: enclave.ListIdentities,
@@ -531,10 +1027,24 @@ func lsIdentityCmd(args []string) {
 	}
 	slices.Sort(ids)
 
+	if formatFlag.IsSet() {
+		items := make([]any, 0, len(ids))
+		for _, v := range ids {
+			items = append(items, struct{ Identity kes.Identity }{v})
+		}
+		if err := formatFlag.Print(items...); err != nil {
+			cli.Fatalf("failed to list identities: %v", err)
+		}
+		return
+	}
 	if jsonFlag {
+		if ids == nil {
+			ids = []kes.Identity{}
+		}
 		if err := json.NewEncoder(os.Stdout).Encode(ids); err != nil {
 			cli.Fatalf("failed to list identities: %v", err)
 		}
+		return
 	}
 	if len(ids) == 0 {
 		return
@@ -551,3 +1061,102 @@ func lsIdentityCmd(args []string) {
 	}
 	fmt.Print(buf)
 }
+
+// identityInfoOrder maps the fields accepted by 'kes identity ls --sort'
+// to the corresponding sort order. Identities without an expiry are
+// sorted last when sorting by expiry.
+var identityInfoOrder = map[string]func(a, b api.IdentityInfo) int{
+	"name":    func(a, b api.IdentityInfo) int { return strings.Compare(a.Identity, b.Identity) },
+	"policy":  func(a, b api.IdentityInfo) int { return strings.Compare(a.Policy, b.Policy) },
+	"created": func(a, b api.IdentityInfo) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	"expires": func(a, b api.IdentityInfo) int {
+		switch {
+		case a.ExpiresAt.IsZero() && b.ExpiresAt.IsZero():
+			return 0
+		case a.ExpiresAt.IsZero():
+			return 1
+		case b.ExpiresAt.IsZero():
+			return -1
+		}
+		return a.ExpiresAt.Compare(b.ExpiresAt)
+	},
+}
+
+// printIdentityInfos prints the identities either as table, as
+// JSON or using the format template. The table only contains the
+// identities' metadata if long is true.
+func printIdentityInfos(ids []api.IdentityInfo, long, jsonFlag bool, formatFlag formatOption, colorFlag colorOption) {
+	if formatFlag.IsSet() {
+		items := make([]any, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, id)
+		}
+		if err := formatFlag.Print(items...); err != nil {
+			cli.Fatalf("failed to print identities: %v", err)
+		}
+		return
+	}
+	if jsonFlag {
+		if ids == nil {
+			ids = []api.IdentityInfo{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(ids); err != nil {
+			cli.Fatalf("failed to print identities: %v", err)
+		}
+		return
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var (
+		style = tui.NewStyle().Underline(colorFlag.Colorize())
+		buf   = &strings.Builder{}
+	)
+	if !long {
+		fmt.Fprintln(buf, style.Render("Identity"))
+		for _, id := range ids {
+			buf.WriteString(id.Identity)
+			buf.WriteByte('\n')
+		}
+		fmt.Print(buf)
+		return
+	}
+
+	var (
+		idWidth     = len("Identity")
+		policyWidth = len("Policy")
+		byWidth     = len("Created By")
+	)
+	for _, id := range ids {
+		idWidth = max(idWidth, len(id.Identity))
+		policyWidth = max(policyWidth, len(id.Policy), len("<admin>"))
+		byWidth = max(byWidth, len(id.CreatedBy))
+	}
+	fmt.Fprintf(buf, "%s%s  %s%s  %s%s  %s%s  %s\n",
+		style.Render("Identity"), strings.Repeat(" ", idWidth-len("Identity")),
+		style.Render("Policy"), strings.Repeat(" ", policyWidth-len("Policy")),
+		style.Render("Date"), strings.Repeat(" ", len(time.DateTime)-len("Date")),
+		style.Render("Created By"), strings.Repeat(" ", byWidth-len("Created By")),
+		style.Render("TTL"),
+	)
+	now := time.Now()
+	for _, id := range ids {
+		policy := id.Policy
+		if id.IsAdmin {
+			policy = "<admin>"
+		}
+		ttl := "-"
+		if !id.ExpiresAt.IsZero() {
+			ttl = max(id.ExpiresAt.Sub(now), 0).Round(time.Second).String()
+		}
+		fmt.Fprintf(buf, "%-*s  %-*s  %s  %-*s  %s\n",
+			idWidth, id.Identity,
+			policyWidth, policy,
+			id.CreatedAt.Local().Format(time.DateTime),
+			byWidth, id.CreatedBy,
+			ttl,
+		)
+	}
+	fmt.Print(buf)
+}
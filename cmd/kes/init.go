@@ -0,0 +1,794 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/kesconf"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
+	"gopkg.in/yaml.v3"
+)
+
+const initCmdUsage = `Usage:
+    kes init [options] [<dir>]
+
+Options:
+        --ip <IP>            Generate a self-signed certificate with <IP> as
+                             subject alternative name (SAN).
+        --dns <DOMAIN>       Generate a self-signed certificate with <DOMAIN>
+                             as subject alternative name (SAN).
+        --cache <DURATION>   Expiry of cache entries - e.g. 5m.
+        --log <LEVEL>        Error log level. Either 'on', 'off', 'debug',
+                             'info', 'warn' or 'error'.
+        --keystore <NAME>    Keystore for storing keys. Either 'fs', 'vault',
+                             'aws', 'gcp' or 'azure'.
+        --keystore-opt <KEY=VALUE>
+                             Set a keystore option - e.g. 'endpoint=<URL>'
+                             or 'approle.id=<ID>'. Options within a section
+                             are prefixed with the section name.
+
+    -y, --yes                Don't ask any questions. Use the default answers.
+    -f, --force              Overwrite an existing config file, private key
+                             and/or certificate.
+
+    -h, --help               Print command line options.
+
+Init asks a few questions about the KES server setup, like its network
+address, TLS certificate, cache, logging and keystore, and writes a new
+server config file 'server-config.yml' to the directory <dir>. If <dir>
+is not specified, the current directory is used.
+
+If the config file already exists, its values are used as default
+answers. Any other config options, like policies, are preserved when
+the config file is overwritten.
+
+Questions answered by options are not asked. With --yes, or if the
+standard input is not a terminal, init asks no questions and uses the
+default answers for all questions not answered by options.
+
+If chosen, it generates a self-signed TLS private key and certificate,
+valid for one year, next to the config file. If the config file does
+not contain an admin identity, it generates a new admin API key.
+
+Examples:
+    $ kes init
+    $ kes init ~/kes
+    $ kes init --yes /etc/kes
+    $ kes init --yes --ip 10.0.0.1 --dns kes.local --log warn \
+          --keystore vault --keystore-opt endpoint=https://vault:8200 \
+          --keystore-opt approle.id=<ID> --keystore-opt approle.secret=<SECRET> /etc/kes
+`
+
+// Names of the files written by 'kes init'.
+const (
+	initConfigFile = "server-config.yml"
+	initKeyFile    = "server.key"
+	initCertFile   = "server.cert"
+)
+
+func initCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, initCmdUsage) }
+
+	var (
+		flags     initFlags
+		yesFlag   bool
+		forceFlag bool
+	)
+	cmd.IPSliceVar(&flags.IPs, "ip", nil, "Add <IP> as subject alternative name")
+	cmd.StringSliceVar(&flags.DNSNames, "dns", nil, "Add <DOMAIN> as subject alternative name")
+	cmd.DurationVar(&flags.Cache, "cache", 0, "Expiry of cache entries")
+	cmd.StringVar(&flags.Log, "log", "", "Error log level")
+	cmd.StringVar(&flags.KeyStore, "keystore", "", "Keystore for storing keys")
+	cmd.StringToStringVar(&flags.KeyStoreOpts, "keystore-opt", nil, "Set a keystore option")
+	cmd.BoolVarP(&yesFlag, "yes", "y", false, "Don't ask any questions. Use the default answers")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite an existing config file, private key and/or certificate")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes init --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes init --help'")
+	}
+	if cmd.Changed("cache") && flags.Cache <= 0 {
+		cli.Fatal("invalid cache expiry: expiry must be positive. See 'kes init --help'")
+	}
+
+	dir := "."
+	if cmd.NArg() == 1 {
+		dir = cmd.Arg(0)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	p := &prompter{
+		r:    bufio.NewReader(os.Stdin),
+		skip: yesFlag || !isTerm(os.Stdin),
+	}
+
+	configPath := filepath.Join(dir, initConfigFile)
+	existing, err := readInitConfig(configPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		cli.Fatalf("failed to read config file: %v", err)
+	}
+	if existing != nil {
+		if !forceFlag && p.skip {
+			cli.Fatalf("config file '%s' already exists. Use --force to overwrite it", configPath)
+		}
+		if !p.skip {
+			fmt.Fprintf(os.Stderr, "Using '%s' for default answers.\n", configPath)
+		}
+	}
+	config := askInitConfig(p, dir, existing, &flags)
+	if existing != nil && !forceFlag && !p.askBool("Overwrite '"+configPath+"'?", false) {
+		os.Exit(1)
+	}
+
+	var keyPem, certPem []byte
+	if config.SelfSigned {
+		if !forceFlag {
+			if _, err = os.Stat(config.PrivateKey); err == nil {
+				cli.Fatalf("private key '%s' already exists. Use --force to overwrite it", config.PrivateKey)
+			}
+			if _, err = os.Stat(config.Certificate); err == nil {
+				cli.Fatalf("certificate '%s' already exists. Use --force to overwrite it", config.Certificate)
+			}
+		}
+		keyPem, certPem, err = config.generateCertificate()
+		if err != nil {
+			cli.Fatalf("failed to generate certificate: %v", err)
+		}
+	}
+
+	var apiKey kes.APIKey
+	if config.Admin.IsUnknown() {
+		apiKey, err = kes.GenerateAPIKey(nil)
+		if err != nil {
+			cli.Fatalf("failed to generate API key: %v", err)
+		}
+		config.Admin = apiKey.Identity()
+	}
+
+	configYAML := config.marshalYAML()
+	if _, err = kesconf.ReadFrom(bytes.NewReader(configYAML)); err != nil {
+		cli.Fatalf("failed to generate config file: %v", err)
+	}
+
+	if err = os.MkdirAll(dir, 0o700); err != nil {
+		cli.Fatalf("failed to create directory: %v", err)
+	}
+	if config.SelfSigned {
+		if err = os.WriteFile(config.PrivateKey, keyPem, 0o600); err != nil {
+			cli.Fatalf("failed to create private key: %v", err)
+		}
+		if err = os.WriteFile(config.Certificate, certPem, 0o644); err != nil {
+			os.Remove(config.PrivateKey)
+			cli.Fatalf("failed to create certificate: %v", err)
+		}
+	}
+	if err = os.WriteFile(configPath, configYAML, 0o600); err != nil {
+		if config.SelfSigned {
+			os.Remove(config.PrivateKey)
+			os.Remove(config.Certificate)
+		}
+		cli.Fatalf("failed to create config file: %v", err)
+	}
+
+	bold := tui.NewStyle()
+	if isTerm(os.Stdout) {
+		bold = bold.Bold(true)
+	}
+	var buffer strings.Builder
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "Created config file:", configPath)
+	if config.SelfSigned {
+		fmt.Fprintln(&buffer, "Created private key:", config.PrivateKey)
+		fmt.Fprintln(&buffer, "Created certificate:", config.Certificate)
+	}
+	fmt.Fprintln(&buffer)
+	if apiKey != nil {
+		fmt.Fprintln(&buffer, "Your admin API key:")
+		fmt.Fprintln(&buffer)
+		fmt.Fprintln(&buffer, "   "+bold.Render(apiKey.String())+"\n")
+		fmt.Fprintln(&buffer, "This is the only time it is shown. Keep it secret and secure!")
+		fmt.Fprintln(&buffer)
+	}
+	fmt.Fprintln(&buffer, "Start the server with:")
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "   kes server --config "+configPath)
+	fmt.Print(buffer.String())
+}
+
+// initFlags contains the answers given as
+// command line options to 'kes init'.
+type initFlags struct {
+	IPs          []net.IP
+	DNSNames     []string
+	Cache        time.Duration
+	Log          string
+	KeyStore     string
+	KeyStoreOpts map[string]string
+}
+
+// initConfig contains the answers collected by 'kes init'.
+type initConfig struct {
+	Addr  string
+	Admin kes.Identity
+
+	SelfSigned  bool
+	IPs         []net.IP
+	DNSNames    []string
+	PrivateKey  string
+	Certificate string
+
+	CacheExpiry time.Duration
+
+	LogError string
+	LogAudit bool
+
+	KeyStore *initKeyStore
+	Values   map[string]string // KeyStore field values
+
+	File *yaml.Node // Existing config file, if any
+}
+
+// initKeyStore describes a keystore that can be
+// configured by 'kes init'.
+type initKeyStore struct {
+	Name   string   // Name shown when asking for the keystore
+	Path   []string // YAML path of the keystore section below 'keystore'
+	Fields []initField
+}
+
+// initField is a keystore config field.
+type initField struct {
+	Section  string // Optional YAML section containing the field - e.g. 'credentials'
+	Key      string // YAML key of the field
+	Question string
+	Default  string
+	Required bool
+	Secret   bool // Don't echo the answer
+}
+
+// path returns the YAML path of the field within the
+// server config file.
+func (f *initField) path(ks *initKeyStore) []string {
+	path := append([]string{"keystore"}, ks.Path...)
+	if f.Section != "" {
+		path = append(path, f.Section)
+	}
+	return append(path, f.Key)
+}
+
+// initKeyStores contains the keystores 'kes init' can configure.
+// More advanced keystore setups can be configured by editing the
+// generated config file.
+var initKeyStores = []*initKeyStore{
+	{
+		Name: "fs",
+		Path: []string{"fs"},
+		Fields: []initField{
+			{Key: "path", Question: "Directory for storing keys", Required: true},
+		},
+	},
+	{
+		Name: "vault",
+		Path: []string{"vault"},
+		Fields: []initField{
+			{Key: "endpoint", Question: "Vault endpoint", Required: true},
+			{Key: "engine", Question: "Vault K/V engine path", Default: "kv"},
+			{Key: "version", Question: "Vault K/V engine version", Default: "v1"},
+			{Section: "approle", Key: "id", Question: "Vault AppRole ID", Required: true},
+			{Section: "approle", Key: "secret", Question: "Vault AppRole secret ID", Required: true, Secret: true},
+		},
+	},
+	{
+		Name: "aws",
+		Path: []string{"aws", "secretsmanager"},
+		Fields: []initField{
+			{Key: "endpoint", Question: "AWS SecretsManager endpoint", Required: true},
+			{Key: "region", Question: "AWS region", Required: true},
+			{Key: "kmskey", Question: "AWS-KMS key (optional)"},
+			{Section: "credentials", Key: "accesskey", Question: "AWS access key"},
+			{Section: "credentials", Key: "secretkey", Question: "AWS secret key", Secret: true},
+		},
+	},
+	{
+		Name: "gcp",
+		Path: []string{"gcp", "secretmanager"},
+		Fields: []initField{
+			{Key: "project_id", Question: "GCP project ID", Required: true},
+		},
+	},
+	{
+		Name: "azure",
+		Path: []string{"azure", "keyvault"},
+		Fields: []initField{
+			{Key: "endpoint", Question: "Azure KeyVault endpoint", Required: true},
+			{Section: "credentials", Key: "tenant_id", Question: "Azure tenant ID", Required: true},
+			{Section: "credentials", Key: "client_id", Question: "Azure client ID", Required: true},
+			{Section: "credentials", Key: "client_secret", Question: "Azure client secret", Required: true, Secret: true},
+		},
+	},
+}
+
+// askInitConfig asks for all init config values not answered
+// by flags. Paths of generated files are relative to dir. If
+// existing is not nil, its values are used as default answers.
+func askInitConfig(p *prompter, dir string, existing *yaml.Node, flags *initFlags) *initConfig {
+	config := &initConfig{
+		File:   existing,
+		Values: map[string]string{},
+	}
+	if existing != nil {
+		config.Admin = kes.Identity(yamlValue(existing, "admin", "identity"))
+	}
+
+	config.Addr = p.ask("Server address", yamlDefault(existing, "0.0.0.0:7373", "address"), func(s string) error {
+		_, _, err := net.SplitHostPort(s)
+		return err
+	})
+
+	var (
+		keyPath    = yamlValue(existing, "tls", "key")
+		certPath   = yamlValue(existing, "tls", "cert")
+		ipList     = "127.0.0.1"
+		dnsList    = "localhost"
+		genDefault = keyPath == "" || certPath == ""
+	)
+	if cert, err := readCertificate(certPath); err == nil {
+		ips := make([]string, 0, len(cert.IPAddresses))
+		for _, ip := range cert.IPAddresses {
+			ips = append(ips, ip.String())
+		}
+		ipList, dnsList = strings.Join(ips, ","), strings.Join(cert.DNSNames, ",")
+	}
+
+	switch {
+	case len(flags.IPs) > 0 || len(flags.DNSNames) > 0:
+		config.SelfSigned = true
+		config.IPs, config.DNSNames = flags.IPs, flags.DNSNames
+	default:
+		config.SelfSigned = p.askBool("Generate a self-signed TLS certificate?", genDefault)
+	}
+	if config.SelfSigned {
+		config.PrivateKey = filepath.Join(dir, initKeyFile)
+		config.Certificate = filepath.Join(dir, initCertFile)
+	}
+	if config.SelfSigned && config.IPs == nil && config.DNSNames == nil {
+		ips := p.ask("Certificate IP addresses (comma-separated)", ipList, func(s string) error {
+			for _, ip := range splitList(s) {
+				if net.ParseIP(ip) == nil {
+					return fmt.Errorf("'%s' is not an IP address", ip)
+				}
+			}
+			return nil
+		})
+		for _, ip := range splitList(ips) {
+			config.IPs = append(config.IPs, net.ParseIP(ip))
+		}
+		config.DNSNames = splitList(p.ask("Certificate DNS names (comma-separated)", dnsList, nil))
+		if len(config.IPs) == 0 && len(config.DNSNames) == 0 {
+			cli.Fatal("certificate requires at least one IP address or DNS name")
+		}
+	}
+	if !config.SelfSigned {
+		config.PrivateKey = p.askRequired("Path to TLS private key", keyPath, false)
+		config.Certificate = p.askRequired("Path to TLS certificate", certPath, false)
+	}
+
+	config.CacheExpiry = flags.Cache
+	if config.CacheExpiry == 0 {
+		expiry := p.ask("Cache expiry", yamlDefault(existing, "5m0s", "cache", "expiry", "any"), func(s string) error {
+			d, err := time.ParseDuration(s)
+			if err == nil && d <= 0 {
+				return errors.New("cache expiry must be positive")
+			}
+			return err
+		})
+		config.CacheExpiry, _ = time.ParseDuration(expiry)
+	}
+
+	levels := []string{"on", "off", "debug", "info", "warn", "error"}
+	validLevel := func(s string) error {
+		if !slices.Contains(levels, strings.ToLower(s)) {
+			return fmt.Errorf("'%s' is not a log level", s)
+		}
+		return nil
+	}
+	if config.LogError = flags.Log; config.LogError != "" {
+		if err := validLevel(config.LogError); err != nil {
+			cli.Fatalf("invalid log level: %v. See 'kes init --help'", err)
+		}
+	} else {
+		config.LogError = p.ask("Error log level ("+strings.Join(levels, ", ")+")", yamlDefault(existing, "on", "log", "error"), validLevel)
+	}
+	config.LogError = strings.ToLower(config.LogError)
+	config.LogAudit = p.askBool("Log audit events?", strings.EqualFold(yamlValue(existing, "log", "audit"), "on"))
+
+	names := make([]string, 0, len(initKeyStores))
+	keyStore := "fs"
+	for _, ks := range initKeyStores {
+		names = append(names, ks.Name)
+		if yamlLookup(existing, append([]string{"keystore"}, ks.Path...)...) != nil {
+			keyStore = ks.Name
+		}
+	}
+	validKeyStore := func(s string) error {
+		if !slices.Contains(names, s) {
+			return fmt.Errorf("'%s' is not a keystore", s)
+		}
+		return nil
+	}
+	name := flags.KeyStore
+	if name != "" {
+		if err := validKeyStore(name); err != nil {
+			cli.Fatalf("invalid keystore: %v. See 'kes init --help'", err)
+		}
+	} else {
+		name = p.ask("Keystore ("+strings.Join(names, ", ")+")", keyStore, validKeyStore)
+	}
+	config.KeyStore = initKeyStores[slices.Index(names, name)]
+
+	for opt, value := range flags.KeyStoreOpts {
+		if !strings.Contains(opt, ".") {
+			opt = "." + opt
+		}
+		if !slices.ContainsFunc(config.KeyStore.Fields, func(f initField) bool { return f.Section+"."+f.Key == opt }) {
+			cli.Fatalf("invalid keystore option: '%s' is not a %s keystore option. See 'kes init --help'", strings.TrimPrefix(opt, "."), name)
+		}
+		config.Values[opt] = value
+	}
+	for _, field := range config.KeyStore.Fields {
+		if _, ok := config.Values[field.Section+"."+field.Key]; ok {
+			continue
+		}
+
+		def := field.Default
+		if config.KeyStore.Name == "fs" && field.Key == "path" {
+			def = filepath.Join(dir, "keys")
+		}
+		def = yamlDefault(existing, def, field.path(config.KeyStore)...)
+
+		if field.Required {
+			config.Values[field.Section+"."+field.Key] = p.askRequired(field.Question, def, field.Secret)
+		} else {
+			config.Values[field.Section+"."+field.Key] = p.askSecret(field.Question, def, field.Secret)
+		}
+	}
+	return config
+}
+
+// generateCertificate generates a new self-signed TLS private key
+// and certificate and returns them PEM-encoded.
+func (c *initConfig) generateCertificate() (keyPem, certPem []byte, err error) {
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := kes.GenerateCertificate(key,
+		func(cert *x509.Certificate) { cert.DNSNames = c.DNSNames },
+		func(cert *x509.Certificate) { cert.IPAddresses = c.IPs },
+		func(cert *x509.Certificate) { cert.ExtKeyUsage = append(cert.ExtKeyUsage, x509.ExtKeyUsageServerAuth) },
+		func(cert *x509.Certificate) {
+			now := time.Now()
+			cert.NotBefore, cert.NotAfter = now, now.Add(365*24*time.Hour)
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	privBytes, err := x509.MarshalPKCS8PrivateKey(key.Private())
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPem = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privBytes})
+	certPem = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]})
+	return keyPem, certPem, nil
+}
+
+// marshalYAML returns the init config as server config YAML file.
+// If the init config has been created from an existing config file,
+// all config options not set by 'kes init' are preserved.
+func (c *initConfig) marshalYAML() []byte {
+	root := c.File
+	if root == nil {
+		root = &yaml.Node{
+			Kind:        yaml.MappingNode,
+			HeadComment: "KES server configuration generated by 'kes init'.\nFor all options, see: https://github.com/minio/kes/blob/master/server-config.yaml",
+		}
+	}
+	yamlSet(root, yamlString("v1"), "version")
+	yamlSet(root, yamlString(c.Addr), "address")
+	yamlSet(root, yamlString(c.Admin.String()), "admin", "identity")
+	yamlSet(root, yamlString(c.PrivateKey), "tls", "key")
+	yamlSet(root, yamlString(c.Certificate), "tls", "cert")
+	yamlSet(root, yamlPlain(c.CacheExpiry.String()), "cache", "expiry", "any")
+	yamlSet(root, yamlPlain(c.LogError), "log", "error")
+	if c.LogAudit {
+		yamlSet(root, yamlPlain("on"), "log", "audit")
+	} else {
+		yamlSet(root, yamlPlain("off"), "log", "audit")
+	}
+
+	// Replace the keystore section if another keystore has been
+	// chosen. Otherwise, keep options not set by 'kes init'.
+	if yamlLookup(root, append([]string{"keystore"}, c.KeyStore.Path...)...) == nil {
+		yamlSet(root, &yaml.Node{Kind: yaml.MappingNode}, "keystore")
+	}
+	for _, field := range c.KeyStore.Fields {
+		if value := c.Values[field.Section+"."+field.Key]; value != "" {
+			yamlSet(root, yamlString(value), field.path(c.KeyStore)...)
+		} else {
+			yamlDelete(root, field.path(c.KeyStore)...)
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}); err != nil {
+		cli.Fatalf("failed to generate config file: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		cli.Fatalf("failed to generate config file: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// readInitConfig reads the YAML config file and returns its root
+// mapping node.
+func readInitConfig(filename string) (*yaml.Node, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err = yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode}, nil
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("'%s' is not a server config file", filename)
+	}
+	return doc.Content[0], nil
+}
+
+// readCertificate reads the first PEM-encoded certificate
+// from the file.
+func readCertificate(filename string) (*x509.Certificate, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type == "CERTIFICATE" {
+			return x509.ParseCertificate(block.Bytes)
+		}
+	}
+	return nil, errors.New("no PEM-encoded certificate found")
+}
+
+func yamlString(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+func yamlPlain(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: s}
+}
+
+// yamlLookup returns the node at the path within the
+// YAML mapping node m, or nil if there is no such node.
+func yamlLookup(m *yaml.Node, path ...string) *yaml.Node {
+	for _, key := range path {
+		if m == nil || m.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			if m.Content[i].Value == key {
+				next = m.Content[i+1]
+				break
+			}
+		}
+		m = next
+	}
+	return m
+}
+
+// yamlValue returns the scalar value at the path within
+// the YAML mapping node m, if any.
+func yamlValue(m *yaml.Node, path ...string) string {
+	if n := yamlLookup(m, path...); n != nil && n.Kind == yaml.ScalarNode {
+		return n.Value
+	}
+	return ""
+}
+
+// yamlDefault returns the scalar value at the path within
+// the YAML mapping node m or def if it is empty.
+func yamlDefault(m *yaml.Node, def string, path ...string) string {
+	if v := yamlValue(m, path...); v != "" {
+		return v
+	}
+	return def
+}
+
+// yamlSet sets the value at the path within the YAML mapping
+// node m. It creates any missing mapping nodes along the path.
+func yamlSet(m *yaml.Node, value *yaml.Node, path ...string) {
+	key := path[0]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value != key {
+			continue
+		}
+		if len(path) == 1 {
+			value.LineComment = m.Content[i+1].LineComment
+			m.Content[i+1] = value
+			return
+		}
+		if m.Content[i+1].Kind != yaml.MappingNode {
+			m.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		yamlSet(m.Content[i+1], value, path[1:]...)
+		return
+	}
+
+	child := value
+	if len(path) > 1 {
+		child = &yaml.Node{Kind: yaml.MappingNode}
+		yamlSet(child, value, path[1:]...)
+	}
+	m.Content = append(m.Content, yamlString(key), child)
+}
+
+// yamlDelete removes the value at the path within the
+// YAML mapping node m, if any.
+func yamlDelete(m *yaml.Node, path ...string) {
+	m = yamlLookup(m, path[:len(path)-1]...)
+	if m == nil || m.Kind != yaml.MappingNode {
+		return
+	}
+	key := path[len(path)-1]
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = slices.Delete(m.Content, i, i+2)
+			return
+		}
+	}
+}
+
+// prompter asks questions on the terminal. If skip is true,
+// it does not ask but returns the default answers.
+type prompter struct {
+	r    *bufio.Reader
+	skip bool
+}
+
+// ask asks the question and returns the answer or the default,
+// if the answer is empty. It asks again if validate, if not nil,
+// returns an error.
+func (p *prompter) ask(question, def string, validate func(string) error) string {
+	for {
+		answer := p.read(question, def, false)
+		if validate == nil {
+			return answer
+		}
+		err := validate(answer)
+		if err == nil {
+			return answer
+		}
+		if p.skip {
+			cli.Fatalf("invalid default for '%s': %v", question, err)
+		}
+		fmt.Fprintf(os.Stderr, "Invalid answer: %v\n", err)
+	}
+}
+
+// askSecret is like ask but does not echo the answer if secret is true.
+func (p *prompter) askSecret(question, def string, secret bool) string {
+	return p.read(question, def, secret)
+}
+
+// askRequired is like askSecret but asks again if the answer is empty.
+func (p *prompter) askRequired(question, def string, secret bool) string {
+	for {
+		answer := p.read(question, def, secret)
+		if answer != "" {
+			return answer
+		}
+		if p.skip {
+			cli.Fatalf("no answer for '%s'. See 'kes init --help'", question)
+		}
+		fmt.Fprintln(os.Stderr, "An answer is required.")
+	}
+}
+
+// askBool asks a Yes/No question.
+func (p *prompter) askBool(question string, def bool) bool {
+	defAnswer := "No"
+	if def {
+		defAnswer = "Yes"
+	}
+	answer := p.ask(question+" (Yes/No)", defAnswer, func(s string) error {
+		switch strings.ToLower(s) {
+		case "y", "yes", "n", "no":
+			return nil
+		default:
+			return errors.New("answer either 'Yes' or 'No'")
+		}
+	})
+	return strings.HasPrefix(strings.ToLower(answer), "y")
+}
+
+func (p *prompter) read(question, def string, secret bool) string {
+	if p.skip {
+		return def
+	}
+
+	if def != "" && !secret {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", question)
+	}
+
+	var answer string
+	if secret {
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		answer = string(b)
+	} else {
+		line, err := p.r.ReadString('\n')
+		if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+			if errors.Is(err, io.EOF) {
+				fmt.Fprintln(os.Stderr)
+				os.Exit(1)
+			}
+			cli.Fatal(err)
+		}
+		answer = line
+	}
+	if answer = strings.TrimSpace(answer); answer == "" {
+		return def
+	}
+	return answer
+}
+
+// splitList splits a comma-separated list and
+// removes all empty list elements.
+func splitList(s string) []string {
+	var list []string
+	for _, e := range strings.Split(s, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
@@ -0,0 +1,407 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kes/kesconf"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const inspectCmdUsage = `Usage:
+    kes inspect [options] <config>
+    kes inspect --verify <report>
+
+Creates a signed JSON report about a KES server for support and
+compliance submissions. The report contains the version of this
+binary, a hash of the server config file with all secrets redacted,
+the key store type and the number of keys, policies and identities.
+
+The report is created from the server config file and the key store
+directly. Hence, it works on air-gapped sites without access to a
+KES server. It is signed with the API key or client certificate of
+the KES CLI, e.g. KES_API_KEY, and contains the certificate required
+to verify the signature.
+
+Options:
+        --no-keys            Don't connect to the key store and don't
+                             report the number of keys.
+    -o, --output <file>      Write the report to the file instead of
+                             standard output.
+        --verify <file>      Verify the signature of a report and print
+                             its content. Use '-' to read the report
+                             from standard input.
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes inspect -o kes-report.json ./config.yml
+    $ kes inspect --verify kes-report.json
+`
+
+func inspectCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, inspectCmdUsage) }
+
+	var (
+		noKeysFlag bool
+		outputFlag string
+		verifyFlag string
+	)
+	cmd.BoolVar(&noKeysFlag, "no-keys", false, "Don't connect to the key store and don't report the number of keys")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the report to the file instead of standard output")
+	cmd.StringVar(&verifyFlag, "verify", "", "Verify the signature of a report and print its content")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes inspect --help'", err)
+	}
+
+	if verifyFlag != "" {
+		if cmd.NArg() > 0 {
+			cli.Fatal("too many arguments. See 'kes inspect --help'")
+		}
+		verifyInspectReport(verifyFlag)
+		return
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no config file specified. See 'kes inspect --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes inspect --help'")
+	}
+
+	// The report is signed with the CLI credentials. Load them
+	// before connecting to the key store to fail early.
+	_, cert, err := loadClientConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	report, err := inspectConfig(ctx, cmd.Arg(0), !noKeysFlag)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	signed, err := signInspectReport(cert, report)
+	if err != nil {
+		cli.Fatalf("failed to sign report: %v", err)
+	}
+	b, err := json.MarshalIndent(signed, "", "  ")
+	if err != nil {
+		cli.Fatal(err)
+	}
+	b = append(b, '\n')
+
+	if outputFlag == "" || outputFlag == "-" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err = os.WriteFile(outputFlag, b, 0o644); err != nil {
+		cli.Fatal(err)
+	}
+}
+
+// inspectReport is the content of a 'kes inspect' report.
+type inspectReport struct {
+	Time       time.Time `json:"time"`
+	Version    string    `json:"version"`
+	Commit     string    `json:"commit"`
+	Runtime    string    `json:"runtime"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	FIPS       bool      `json:"fips"`
+	ConfigHash string    `json:"config_hash"`
+	KeyStore   string    `json:"keystore"`
+	Keys       *int      `json:"keys,omitempty"` // Nil if keys haven't been counted
+	Policies   int       `json:"policies"`
+	Identities int       `json:"identities"`
+}
+
+// signedInspectReport is a signed 'kes inspect' report. The
+// signature is computed over the compact JSON encoding of the
+// report such that indenting the report does not invalidate it.
+type signedInspectReport struct {
+	Report      json.RawMessage `json:"report"`
+	Identity    kes.Identity    `json:"identity"`
+	Certificate string          `json:"certificate"` // PEM-encoded X.509 certificate
+	Signature   []byte          `json:"signature"`
+}
+
+// inspectConfig creates a report about the server config file.
+// If countKeys is true, it connects to the config's key store
+// and counts all keys.
+func inspectConfig(ctx context.Context, filename string, countKeys bool) (*inspectReport, error) {
+	configHash, err := hashRedactedConfig(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	config, err := kesconf.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %v", err)
+	}
+	if config.KeyStore == nil {
+		return nil, errors.New("config file contains no key store")
+	}
+
+	info, _ := sys.ReadBinaryInfo()
+	report := &inspectReport{
+		Time:       time.Now().UTC(),
+		Version:    info.Version,
+		Commit:     info.CommitID,
+		Runtime:    info.Runtime,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		FIPS:       config.FIPS,
+		ConfigHash: configHash,
+		Policies:   len(config.Policies),
+	}
+	for _, policy := range config.Policies {
+		report.Identities += len(policy.Identities)
+	}
+
+	if !countKeys {
+		// Without a key store connection, we can only report
+		// the config type, like "Vault" for *kesconf.VaultKeyStore.
+		typ := strings.TrimPrefix(fmt.Sprintf("%T", config.KeyStore), "*kesconf.")
+		report.KeyStore = strings.TrimSuffix(typ, "KeyStore")
+		return report, nil
+	}
+
+	store, err := config.KeyStore.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to key store: %v", err)
+	}
+	defer store.Close()
+
+	// Only report the key store type but nothing that may
+	// identify the deployment, like endpoints or paths.
+	report.KeyStore = fmt.Sprintf("%T", store)
+	if s, ok := store.(fmt.Stringer); ok {
+		report.KeyStore, _, _ = strings.Cut(s.String(), ":")
+	}
+
+	var (
+		n        int
+		iterator = &kes.ListIter[string]{NextFunc: store.List}
+	)
+	for {
+		if _, err = iterator.Next(ctx); err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list keys: %v", err)
+		}
+		n++
+	}
+	report.Keys = &n
+	return report, nil
+}
+
+// redactedConfigFields are the config file fields that contain
+// secrets, like passwords or access tokens.
+var redactedConfigFields = map[string]bool{
+	"api_key":                     true,
+	"auth_header":                 true,
+	"auth_token":                  true,
+	"client_certificate_password": true,
+	"client_secret":               true,
+	"master_key":                  true,
+	"password":                    true,
+	"pin":                         true,
+	"private_key":                 true,
+	"private_key_id":              true,
+	"sealed_key":                  true,
+	"secret":                      true,
+	"secret_key":                  true,
+	"secretkey":                   true,
+	"session_token":               true,
+	"token":                       true,
+}
+
+// hashRedactedConfig returns the hex-encoded SHA-256 hash of the
+// config file with all secrets and comments removed.
+func hashRedactedConfig(filename string) (string, error) {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(file, &node); err != nil {
+		return "", err
+	}
+	redactConfig(&node)
+
+	b, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(h[:]), nil
+}
+
+// redactConfig replaces all non-empty secret values within the
+// YAML node with '<redacted>' and removes all comments.
+func redactConfig(node *yaml.Node) {
+	node.HeadComment, node.LineComment, node.FootComment = "", "", ""
+	if node.Kind != yaml.MappingNode {
+		for _, n := range node.Content {
+			redactConfig(n)
+		}
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		redactConfig(key)
+		if value.Kind == yaml.ScalarNode && value.Value != "" && redactedConfigFields[strings.ToLower(key.Value)] {
+			value.HeadComment, value.LineComment, value.FootComment = "", "", ""
+			value.Value, value.Tag, value.Style = "<redacted>", "!!str", 0
+			continue
+		}
+		redactConfig(value)
+	}
+}
+
+// signInspectReport signs the JSON-encoded report with the
+// certificate's private key.
+func signInspectReport(cert tls.Certificate, report *inspectReport) (*signedInspectReport, error) {
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("no client certificate")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := cert.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type '%T'", cert.PrivateKey)
+	}
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+	var signature []byte
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		signature, err = signer.Sign(rand.Reader, body, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(body)
+		signature, err = signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha256.Sum256(leaf.RawSubjectPublicKeyInfo)
+	return &signedInspectReport{
+		Report:      body,
+		Identity:    kes.Identity(hex.EncodeToString(h[:])),
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf.Raw})),
+		Signature:   signature,
+	}, nil
+}
+
+// verifyInspectReport verifies the signature of the report
+// file and prints the report.
+func verifyInspectReport(filename string) {
+	var (
+		b   []byte
+		err error
+	)
+	if filename == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		cli.Fatalf("failed to read report: %v", err)
+	}
+
+	var signed signedInspectReport
+	if err = json.Unmarshal(b, &signed); err != nil {
+		cli.Fatalf("failed to read report: %v", err)
+	}
+	block, _ := pem.Decode([]byte(signed.Certificate))
+	if block == nil || block.Type != "CERTIFICATE" {
+		cli.Fatal("failed to verify report: invalid certificate")
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		cli.Fatalf("failed to verify report: invalid certificate: %v", err)
+	}
+	if h := sha256.Sum256(leaf.RawSubjectPublicKeyInfo); hex.EncodeToString(h[:]) != signed.Identity.String() {
+		cli.Fatal("failed to verify report: identity does not match certificate")
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch leaf.PublicKeyAlgorithm {
+	case x509.Ed25519:
+		algorithm = x509.PureEd25519
+	case x509.ECDSA:
+		algorithm = x509.ECDSAWithSHA256
+	case x509.RSA:
+		algorithm = x509.SHA256WithRSA
+	default:
+		cli.Fatalf("failed to verify report: unsupported public key algorithm '%v'", leaf.PublicKeyAlgorithm)
+	}
+	var report bytes.Buffer
+	if err = json.Compact(&report, signed.Report); err != nil {
+		cli.Fatalf("failed to read report: %v", err)
+	}
+	if err = leaf.CheckSignature(algorithm, report.Bytes(), signed.Signature); err != nil {
+		cli.Fatal("failed to verify report: invalid signature")
+	}
+
+	if globalJSON {
+		var buf bytes.Buffer
+		json.Indent(&buf, report.Bytes(), "", "  ")
+		buf.WriteByte('\n')
+		os.Stdout.Write(buf.Bytes())
+		return
+	}
+	var info inspectReport
+	if err = json.Unmarshal(report.Bytes(), &info); err != nil {
+		cli.Fatalf("failed to read report: %v", err)
+	}
+
+	keys := "-"
+	if info.Keys != nil {
+		keys = fmt.Sprint(*info.Keys)
+	}
+	fmt.Printf("Signature    valid\n")
+	fmt.Printf("Signed by    %s\n", signed.Identity)
+	fmt.Printf("Created      %s\n", info.Time.Local().Format(time.RFC3339))
+	fmt.Printf("Version      %s (commit=%s, %s, %s/%s)\n", info.Version, info.Commit, info.Runtime, info.OS, info.Arch)
+	fmt.Printf("FIPS         %v\n", info.FIPS)
+	fmt.Printf("Config hash  %s\n", info.ConfigHash)
+	fmt.Printf("Key store    %s\n", info.KeyStore)
+	fmt.Printf("Keys         %s\n", keys)
+	fmt.Printf("Policies     %d\n", info.Policies)
+	fmt.Printf("Identities   %d\n", info.Identities)
+}
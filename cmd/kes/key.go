@@ -5,19 +5,28 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/crypto"
 	"github.com/minio/kms-go/kes"
 	flag "github.com/spf13/pflag"
 )
@@ -28,13 +37,21 @@ const keyCmdUsage = `Usage:
 Commands:
     create                   Create a new crypto key.
     import                   Import a crypto key.
+    export                   Export a crypto key wrapped by another key.
+    restore                  Restore an exported crypto key.
+    rotate                   Rotate a crypto key.
     info                     Get information about a crypto key. 
     ls                       List crypto keys.
+    search                   Search crypto keys by metadata.
     rm                       Delete a crypto key.
+    undelete                 Recover a deleted crypto key.
 
     encrypt                  Encrypt a message.
     decrypt                  Decrypt an encrypted message.
     dek                      Generate a new data encryption key.
+    hmac                     Compute the HMAC of a message.
+    sign                     Sign a message.
+    verify                   Verify a signature.
 
 Options:
     -h, --help               Print command line options.
@@ -45,15 +62,23 @@ func keyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, keyCmdUsage) }
 
 	subCmds := commands{
-		"create": createKeyCmd,
-		"import": importKeyCmd,
-		"info":   describeKeyCmd,
-		"ls":     lsKeyCmd,
-		"rm":     rmKeyCmd,
+		"create":   createKeyCmd,
+		"import":   importKeyCmd,
+		"export":   exportKeyCmd,
+		"restore":  restoreKeyCmd,
+		"rotate":   rotateKeyCmd,
+		"info":     describeKeyCmd,
+		"ls":       lsKeyCmd,
+		"search":   searchKeyCmd,
+		"rm":       rmKeyCmd,
+		"undelete": undeleteKeyCmd,
 
 		"encrypt": encryptKeyCmd,
 		"decrypt": decryptKeyCmd,
 		"dek":     dekCmd,
+		"hmac":    hmacKeyCmd,
+		"sign":    signKeyCmd,
+		"verify":  verifyKeyCmd,
 	}
 
 	if len(args) < 2 {
@@ -80,16 +105,49 @@ func keyCmd(args []string) {
 
 const createKeyCmdUsage = `Usage:
     kes key create [options] <name>...
+    kes key create [options] -f <path>
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+    -f, --file <path>        Read key names from the file, one per line.
+                             Empty lines and lines starting with '#' are
+                             ignored. Use '-' to read from standard input.
+    -t, --tag <key:value>    Attach a tag to the key. May be specified
+                             multiple times.
+        --usage <ops>        Restrict the key to the comma-separated
+                             operations. Possible values: encrypt, decrypt,
+                             ssh, cert, hmac, sign, verify, ecdh. Without
+                             --usage, all operations except ssh and cert
+                             are permitted. The usages 'ssh' and 'cert' make the
+                             key an SSH or X.509 certificate authority. See
+                             'kes ssh --help' and 'kes cert --help'.
+        --expires <time>     RFC 3339 time, date or duration after which
+                             the key can only be used to decrypt.
+        --rotate-every <d>   Rotate the key automatically at the given
+                             interval, e.g. 90d or 720h.
+        --algorithm <alg>    Encryption algorithm of the key. By default, the
+                             server chooses AES256 if the CPU supports AES-GCM
+                             in hardware. Possible values: AES256, ChaCha20.
+        --derived            Derive data keys deterministically from the
+                             context, e.g. for convergent encryption, such
+                             that the same context yields the same data key.
 
     -h, --help               Print command line options.
 
+When creating more than one key, a failure does not stop the
+remaining keys from being created. Instead, the command prints
+a summary and exits with a non-zero status if any key failed.
+
 Examples:
     $ kes key create my-key
     $ kes key create my-key1 my-key2
+    $ kes key create -f keys.txt
+    $ kes key create --tag team:payments --tag env:prod my-key
+    $ kes key create --usage encrypt,decrypt --expires 2025-12-31 my-key
+    $ kes key create --rotate-every 90d my-key
+    $ kes key create --algorithm AES256 my-key
+    $ kes key create --derived my-key
 `
 
 func createKeyCmd(args []string) {
@@ -99,9 +157,23 @@ func createKeyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		tagFlags           []string
+		usageFlag          []string
+		expiresFlag        string
+		rotateFlag         string
+		algorithmFlag      string
+		derivedFlag        bool
+		fileFlag           string
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read key names from the file")
+	cmd.StringArrayVarP(&tagFlags, "tag", "t", nil, "Attach a tag to the key")
+	cmd.StringSliceVar(&usageFlag, "usage", nil, "Restrict the key to the operations")
+	cmd.StringVar(&expiresFlag, "expires", "", "Time after which the key can no longer be used to encrypt")
+	cmd.StringVar(&rotateFlag, "rotate-every", "", "Rotate the key automatically at the given interval")
+	cmd.StringVar(&algorithmFlag, "algorithm", "", "Encryption algorithm of the key")
+	cmd.BoolVar(&derivedFlag, "derived", false, "Derive data keys deterministically from the context")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -109,41 +181,167 @@ func createKeyCmd(args []string) {
 		cli.Fatalf("%v. See 'kes key create --help'", err)
 	}
 
-	if cmd.NArg() == 0 {
+	names := cmd.Args()
+	if fileFlag != "" {
+		fileNames, err := readKeyNames(fileFlag)
+		if err != nil {
+			cli.Fatalf("failed to read key names: %v", err)
+		}
+		names = append(names, fileNames...)
+	}
+	if len(names) == 0 {
+		if fileFlag != "" {
+			cli.Fatalf("no key name found in '%s'", fileFlag)
+		}
 		cli.Fatal("no key name specified. See 'kes key create --help'")
 	}
+	tags, err := parseTags(tagFlags)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes key create --help'", err)
+	}
+	if _, err = crypto.ParseKeyUsage(usageFlag); err != nil {
+		cli.Fatalf("invalid key usage '%s'. See 'kes key create --help'", strings.Join(usageFlag, ","))
+	}
+	var expiresAt time.Time
+	if expiresFlag != "" {
+		if expiresAt, err = parseLogTime(expiresFlag, true); err != nil {
+			cli.Fatalf("invalid expiration time '%s'. See 'kes key create --help'", expiresFlag)
+		}
+	}
+	var rotationInterval time.Duration
+	if rotateFlag != "" {
+		if rotationInterval, err = parseInterval(rotateFlag); err != nil {
+			cli.Fatalf("invalid rotation interval '%s'. See 'kes key create --help'", rotateFlag)
+		}
+	}
+	if algorithmFlag != "" {
+		if _, err = crypto.ParseSecretKeyType(algorithmFlag); err != nil {
+			cli.Fatalf("invalid algorithm '%s'. See 'kes key create --help'", algorithmFlag)
+		}
+	}
+	request := api.CreateKeyRequest{
+		Tags:             tags,
+		Usage:            usageFlag,
+		ExpiresAt:        expiresAt,
+		RotationInterval: int64(rotationInterval.Seconds()),
+		Algorithm:        algorithmFlag,
+		Derived:          derivedFlag,
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
 	client := newClient(insecureSkipVerify)
-	for _, name := range cmd.Args() {
-		if err := client.CreateKey(ctx, name); err != nil {
+	createKey := func(name string) error {
+		if len(tags) > 0 || len(usageFlag) > 0 || !expiresAt.IsZero() || rotationInterval > 0 || algorithmFlag != "" || derivedFlag {
+			return sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+name, request, nil)
+		}
+		return client.CreateKey(ctx, name)
+	}
+	if len(names) > 1 {
+		bulkKeyOp(names, "create", "Created", createKey)
+		return
+	}
+	if err := createKey(names[0]); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to create key %q: %v", names[0], err)
+	}
+}
+
+// readKeyNames reads key names, one per line, from the file or,
+// if filename is '-', from STDIN. It skips empty lines and lines
+// starting with '#'.
+func readKeyNames(filename string) ([]string, error) {
+	f := os.Stdin
+	if filename != "-" {
+		var err error
+		if f, err = os.Open(filename); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		names = append(names, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// bulkKeyOp applies op to each key. Unlike single key operations,
+// it continues when op fails for a key and prints each failure
+// followed by a summary. It exits with a non-zero status if op
+// failed for at least one key.
+func bulkKeyOp(names []string, verb, pastVerb string, op func(name string) error) {
+	var failed int
+	for _, name := range names {
+		if err := op(name); err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
-			cli.Fatalf("failed to create key %q: %v", name, err)
+			fmt.Fprintf(os.Stderr, "failed to %s key %q: %v\n", verb, name, err)
+			failed++
 		}
 	}
+
+	noun := "keys"
+	if len(names) == 1 {
+		noun = "key"
+	}
+	if failed == 0 {
+		fmt.Printf("%s %d %s.\n", pastVerb, len(names), noun)
+		return
+	}
+	fmt.Printf("%s %d of %d %s. %d failed.\n", pastVerb, len(names)-failed, len(names), noun, failed)
+	os.Exit(1)
 }
 
 const importKeyCmdUsage = `Usage:
     kes key import [options] <name> [<key>]
 
 Options:
+    -f, --file <path>        Read the key from the file. Use '-' to read
+                             the key from standard input.
+        --cipher <name>      The key's cipher: AES256 (default) or ChaCha20.
+    -t, --tag <key:value>    Attach a tag to the key. May be specified
+                             multiple times.
     -k, --insecure           Skip TLS certificate validation.
 
     -h, --help               Print command line options.
 
+The key must be 256 bits long. It is either base64-encoded or a
+PEM block. If neither <key> nor --file is specified, the key is
+read from standard input.
+
 Examples:
     $ kes key import my-key-2 Xlnr/nOgAWE5cA7GAsl3L2goCvmfs6KE0gNgB1T93wE=
+    $ kes key import --file ./hsm-export.pem my-key-3
+    $ openssl rand -base64 32 | kes key import --cipher ChaCha20 my-key-4
 `
 
 func importKeyCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, importKeyCmdUsage) }
 
-	var insecureSkipVerify bool
+	var (
+		fileFlag           string
+		cipherFlag         string
+		tagFlags           []string
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the key from the file")
+	cmd.StringVar(&cipherFlag, "cipher", "AES256", "The key's cipher")
+	cmd.StringArrayVarP(&tagFlags, "tag", "t", nil, "Attach a tag to the key")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -155,22 +353,58 @@ func importKeyCmd(args []string) {
 	switch {
 	case cmd.NArg() == 0:
 		cli.Fatal("no key name specified. See 'kes key import --help'")
-	case cmd.NArg() == 1:
-		cli.Fatal("no crypto key specified. See 'kes key import --help'")
 	case cmd.NArg() > 2:
 		cli.Fatal("too many arguments. See 'kes key import --help'")
+	case cmd.NArg() == 2 && fileFlag != "":
+		cli.Fatal("cannot use --file when a key is specified. See 'kes key import --help'")
 	}
-	name := cmd.Arg(0)
-	key, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
+
+	var cipher string
+	switch strings.ToUpper(cipherFlag) {
+	case "AES256", "AES256-GCM_SHA256":
+		cipher = "AES256"
+	case "CHACHA20", "XCHACHA20-POLY1305":
+		cipher = "ChaCha20"
+	default:
+		cli.Fatalf("invalid cipher '%s'. See 'kes key import --help'", cipherFlag)
+	}
+	tags, err := parseTags(tagFlags)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes key import --help'", err)
+	}
+
+	var data []byte
+	switch {
+	case cmd.NArg() == 2:
+		data = []byte(cmd.Arg(1))
+	case fileFlag != "" && fileFlag != "-":
+		if data, err = os.ReadFile(fileFlag); err != nil {
+			cli.Fatalf("failed to read key: %v", err)
+		}
+	default:
+		if isTerm(os.Stdin) {
+			cli.Fatal("no crypto key specified. See 'kes key import --help'")
+		}
+		if data, err = io.ReadAll(io.LimitReader(os.Stdin, 64*1024)); err != nil {
+			cli.Fatalf("failed to read key: %v", err)
+		}
+	}
+	key, err := parseImportKey(data)
 	if err != nil {
 		cli.Fatalf("invalid key: %v. See 'kes key import --help'", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
-	enclave := newClient(insecureSkipVerify)
-	if err = enclave.ImportKey(ctx, name, &kes.ImportKeyRequest{Key: key}); err != nil {
+	name := cmd.Arg(0)
+	client := newClient(insecureSkipVerify)
+	err = sendRequest(ctx, client, http.MethodPut, api.PathKeyImport+name, api.ImportKeyRequest{
+		Bytes:  key,
+		Cipher: cipher,
+		Tags:   tags,
+	}, nil)
+	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
@@ -178,12 +412,251 @@ func importKeyCmd(args []string) {
 	}
 }
 
+// parseImportKey parses data as either a PEM block or a
+// base64-encoded key and returns the raw key. It returns
+// an error if the key is not 256 bits long.
+func parseImportKey(data []byte) ([]byte, error) {
+	const KeySize = 32
+
+	var (
+		key []byte
+		err error
+	)
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("-----BEGIN")) {
+		block, rest := pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("invalid PEM block")
+		}
+		if len(bytes.TrimSpace(rest)) > 0 {
+			return nil, errors.New("more than one PEM block")
+		}
+		key = block.Bytes
+	} else {
+		if key, err = base64.StdEncoding.DecodeString(string(data)); err != nil {
+			return nil, err
+		}
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("key is %d bits long but must be %d bits", 8*len(key), 8*KeySize)
+	}
+	return key, nil
+}
+
+const exportKeyCmdUsage = `Usage:
+    kes key export [options] --wrap-with <kek> <name>
+
+Options:
+    -w, --wrap-with <kek>    Name of the key that wraps the exported key.
+    -o, --output <path>      Write the exported key to the file instead
+                             of standard output.
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Exports the key, including all previous versions, wrapped by the
+key encryption key (KEK) for offline backups. The exported key can
+only be restored under the same name with 'kes key restore' on a
+server that has the same KEK.
+
+Any identity allowed to decrypt with the KEK can unwrap exported
+keys. Hence, a KEK should not be used for anything else.
+
+Examples:
+    $ kes key export --wrap-with backup-kek my-key > my-key.json
+    $ kes key export -w backup-kek -o my-key.json my-key
+`
+
+func exportKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, exportKeyCmdUsage) }
+
+	var (
+		kekFlag            string
+		outputFlag         string
+		insecureSkipVerify bool
+	)
+	cmd.StringVarP(&kekFlag, "wrap-with", "w", "", "Name of the key that wraps the exported key")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the exported key to the file")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key export --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key export --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes key export --help'")
+	case kekFlag == "":
+		cli.Fatal("no key encryption key specified. Set the '--wrap-with' flag")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	name := cmd.Arg(0)
+	var export api.ExportKeyResponse
+	err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathKeyExport+name, api.ExportKeyRequest{
+		KEK: kekFlag,
+	}, &export)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to export %q: %v", name, err)
+	}
+
+	b, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		cli.Fatalf("failed to export %q: %v", name, err)
+	}
+	b = append(b, '\n')
+	if outputFlag == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err = os.WriteFile(outputFlag, b, 0o600); err != nil {
+		cli.Fatalf("failed to export %q: %v", name, err)
+	}
+}
+
+const restoreKeyCmdUsage = `Usage:
+    kes key restore [options] [<file>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Restores a key exported by 'kes key export', including all previous
+versions, under its original name. The server must have the key
+encryption key (KEK) that wrapped the exported key. If no <file> is
+specified or <file> is '-', the exported key is read from standard
+input.
+
+Examples:
+    $ kes key restore my-key.json
+    $ kes key export -w backup-kek my-key | kes key restore
+`
+
+func restoreKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, restoreKeyCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key restore --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes key restore --help'")
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if filename := cmd.Arg(0); filename != "" && filename != "-" {
+		if data, err = os.ReadFile(filename); err != nil {
+			cli.Fatalf("failed to read exported key: %v", err)
+		}
+	} else {
+		if isTerm(os.Stdin) {
+			cli.Fatal("no exported key specified. See 'kes key restore --help'")
+		}
+		if data, err = io.ReadAll(io.LimitReader(os.Stdin, 1<<20)); err != nil {
+			cli.Fatalf("failed to read exported key: %v", err)
+		}
+	}
+
+	var export api.ExportKeyResponse
+	if err = json.Unmarshal(data, &export); err != nil {
+		cli.Fatalf("invalid exported key: %v", err)
+	}
+	if export.Name == "" || export.KEK == "" || len(export.Key) == 0 {
+		cli.Fatal("invalid exported key: name, KEK or key is missing")
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	err = sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathKeyRestore+export.Name, api.RestoreKeyRequest{
+		KEK: export.KEK,
+		Key: export.Key,
+	}, nil)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to restore %q: %v", export.Name, err)
+	}
+}
+
+const rotateKeyCmdUsage = `Usage:
+    kes key rotate [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Rotating a key creates a new key version that is used to encrypt
+from now on. Previous key versions are kept to decrypt existing
+ciphertexts. The new version also has new signing, key agreement
+and certificate authority keys. Signatures of previous versions
+remain valid. HMACs do not change.
+
+Examples:
+    $ kes key rotate my-key
+    $ kes key rotate my-key1 my-key2
+`
+
+func rotateKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rotateKeyCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key rotate --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key rotate --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		var rotated api.RotateKeyResponse
+		if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyRotate+name, nil, &rotated); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to rotate key %q: %v", name, err)
+		}
+		fmt.Printf("Rotated key %q to version %d\n", name, rotated.Version)
+	}
+}
+
 const describeKeyCmdUsage = `Usage:
     kes key info [options] <name>
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print keys in JSON format. 
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -195,6 +668,7 @@ Options:
 
 Examples:
     $ kes key info my-key
+    $ kes key info --format '{{.Name}} {{.CreatedAt}}' my-key
 `
 
 func describeKeyCmd(args []string) {
@@ -203,11 +677,13 @@ func describeKeyCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print identities in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -218,6 +694,10 @@ func describeKeyCmd(args []string) {
 		cli.Fatalf("%v. See 'kes key info --help'", err)
 	}
 
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes key info --help'")
+	}
+
 	switch {
 	case cmd.NArg() == 0:
 		cli.Fatal("no key name specified. See 'kes key info --help'")
@@ -225,18 +705,25 @@ func describeKeyCmd(args []string) {
 		cli.Fatal("too many arguments. See 'kes key info --help'")
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	name := cmd.Arg(0)
 	client := newClient(insecureSkipVerify)
-	info, err := client.DescribeKey(ctx, name)
+	var info api.DescribeKeyResponse
+	err := sendRequest(ctx, client, http.MethodGet, api.PathKeyDescribe+name, nil, &info)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
 		}
 		cli.Fatalf("failed to describe keys: %v", err)
 	}
+	if formatFlag.IsSet() {
+		if err = formatFlag.Print(info); err != nil {
+			cli.Fatalf("failed to describe keys: %v", err)
+		}
+		return
+	}
 	if jsonFlag {
 		if err = json.NewEncoder(os.Stdout).Encode(info); err != nil {
 			cli.Fatalf("failed to describe keys: %v", err)
@@ -252,6 +739,43 @@ func describeKeyCmd(args []string) {
 	fmt.Fprintf(buf, "%-11s %s\n", "Algorithm", info.Algorithm)
 	fmt.Fprintf(buf, "%-11s %04d-%02d-%02d %02d:%02d:%02d\n", "Date", year, month, day, hour, min, sec)
 	fmt.Fprintf(buf, "%-11s %s", "Owner", info.CreatedBy)
+	for i, v := range info.Versions {
+		label := ""
+		if i == 0 {
+			label = "Versions"
+		}
+		fmt.Fprintf(buf, "\n%-11s v%-3d %s  %s", label, v.Version, v.CreatedAt.Local().Format(time.DateTime), v.CreatedBy)
+		if v.Version == info.Version {
+			fmt.Fprint(buf, "  (current)")
+		}
+	}
+	if len(info.Operations) > 0 {
+		fmt.Fprintf(buf, "\n%-11s %s", "Operations", strings.Join(info.Operations, ", "))
+	}
+	if !info.ExpiresAt.IsZero() {
+		fmt.Fprintf(buf, "\n%-11s %s", "Expires", info.ExpiresAt.Local().Format(time.DateTime))
+		if !time.Now().Before(info.ExpiresAt) {
+			fmt.Fprint(buf, "  (expired)")
+		}
+	}
+	if info.RotationInterval > 0 {
+		interval := (time.Duration(info.RotationInterval) * time.Second).String()
+		if info.RotationInterval%(24*60*60) == 0 {
+			interval = strconv.FormatInt(info.RotationInterval/(24*60*60), 10) + "d"
+		}
+		fmt.Fprintf(buf, "\n%-11s every %s, next at %s", "Rotation", interval, info.NextRotation.Local().Format(time.DateTime))
+	}
+	if info.Derived {
+		fmt.Fprintf(buf, "\n%-11s derived from context", "Data Keys")
+	}
+	if info.Usage != nil {
+		fmt.Fprintf(buf, "\n%-11s %d encrypt, %d decrypt, %d generate", "Usage", info.Usage.Encrypt, info.Usage.Decrypt, info.Usage.Generate)
+		if info.Usage.LastUsed.IsZero() {
+			fmt.Fprintf(buf, "\n%-11s never", "Last Used")
+		} else {
+			fmt.Fprintf(buf, "\n%-11s %s", "Last Used", info.Usage.LastUsed.Local().Format(time.DateTime))
+		}
+	}
 	fmt.Print(buf)
 }
 
@@ -261,18 +785,32 @@ const lsKeyCmdUsage = `Usage:
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print keys in JSON format. 
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
                              the output goes to a pipe.
                              Possible values: *auto*, never, always.
     -e, --enclave <name>     Operate within the specified enclave.
+    -l, --long               List keys with their algorithm, creation
+                             date and tags.
+        --page-size <n>      Fetch at most <n> key names per request.
+                             Default: 1000.
+        --limit <n>          Print at most <n> keys.
+    -r, --reverse            List keys in descending order.
 
     -h, --help               Print command line options.
 
+The pattern is either a prefix, optionally ending with '*', or
+a glob pattern, like 'my-*-key' or 'key-[0-9]*'. Keys are printed
+while the listing is in progress.
+
 Examples:
     $ kes key ls
     $ kes key ls 'my-key*'
+    $ kes key ls 'my-*-key' --limit 100
+    $ kes key ls --reverse --format '{{.Name}}'
+    $ kes key ls --long --format '{{.Name}} {{.Tags}}'
 `
 
 func lsKeyCmd(args []string) {
@@ -281,14 +819,24 @@ func lsKeyCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
+		longFlag           bool
+		pageSize           int
+		limit              int
+		reverse            bool
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print identities in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVarP(&longFlag, "long", "l", false, "List keys with their metadata")
+	cmd.IntVar(&pageSize, "page-size", 1000, "Fetch at most n key names per request")
+	cmd.IntVar(&limit, "limit", 0, "Print at most n keys")
+	cmd.BoolVarP(&reverse, "reverse", "r", false, "List keys in descending order")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -296,65 +844,365 @@ func lsKeyCmd(args []string) {
 		cli.Fatalf("%v. See 'kes key ls --help'", err)
 	}
 
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes key ls --help'")
+	}
+	if longFlag && (reverse || cmd.Changed("page-size")) {
+		cli.Fatal("'--long' cannot be used together with '--reverse' or '--page-size'. See 'kes key ls --help'")
+	}
+	if pageSize <= 0 {
+		cli.Fatal("invalid page size: must be greater than 0. See 'kes key ls --help'")
+	}
+	if limit < 0 {
+		cli.Fatal("invalid limit: must not be negative. See 'kes key ls --help'")
+	}
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes key ls --help'")
 	}
 
-	prefix := "*"
+	pattern := "*"
 	if cmd.NArg() == 1 {
-		prefix = cmd.Arg(0)
+		pattern = cmd.Arg(0)
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
-	enclave := newClient(insecureSkipVerify)
-	iter := &kes.ListIter[string]{
-		NextFunc: enclave.ListKeys,
-	}
-
-	var names []string
-	for id, err := iter.SeekTo(ctx, prefix); err != io.EOF; id, err = iter.Next(ctx) {
+	if longFlag {
+		keys, err := fetchKeyInfos(ctx, newClient(insecureSkipVerify), api.PathKeyListInfo+pattern, url.Values{})
 		if err != nil {
 			cli.Fatalf("failed to list keys: %v", err)
 		}
-		names = append(names, id)
+		if limit > 0 && len(keys) > limit {
+			keys = keys[:limit]
+		}
+		printKeyInfos(keys, jsonFlag, formatFlag, colorFlag)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(pageSize))
+	if reverse {
+		query.Set("order", "desc")
+	}
+	prefix, match := splitKeyPattern(pattern)
+	if match != "" {
+		query.Set("match", match)
 	}
-	slices.Sort(names)
 
+	var (
+		client  = newClient(insecureSkipVerify)
+		style   = tui.NewStyle().Underline(colorFlag.Colorize())
+		w       = bufio.NewWriter(os.Stdout)
+		printed int
+	)
 	if jsonFlag {
-		if err := json.NewEncoder(os.Stdout).Encode(names); err != nil {
+		w.WriteByte('[')
+	}
+	for {
+		var resp api.ListKeysResponse
+		if err := sendRequest(ctx, client, http.MethodGet, api.PathKeyList+prefix+"?"+query.Encode(), nil, &resp); err != nil {
+			cli.Fatalf("failed to list keys: %v", err)
+		}
+		names := resp.Names
+		if limit > 0 && printed+len(names) > limit {
+			names, resp.ContinueAt = names[:limit-printed], ""
+		}
+
+		switch {
+		case formatFlag.IsSet():
+			items := make([]any, 0, len(names))
+			for _, v := range names {
+				items = append(items, struct{ Name string }{v})
+			}
+			if err := formatFlag.Print(items...); err != nil {
+				cli.Fatalf("failed to list keys: %v", err)
+			}
+		case jsonFlag:
+			for i, name := range names {
+				if printed+i > 0 {
+					w.WriteByte(',')
+				}
+				b, _ := json.Marshal(name)
+				w.Write(b)
+			}
+		default:
+			if printed == 0 && len(names) > 0 {
+				fmt.Fprintln(w, style.Render("Key"))
+			}
+			for _, name := range names {
+				w.WriteString(name)
+				w.WriteByte('\n')
+			}
+		}
+		printed += len(names)
+		if err := w.Flush(); err != nil {
 			cli.Fatalf("failed to list keys: %v", err)
 		}
+
+		if resp.ContinueAt == "" {
+			break
+		}
+		query.Set("continue", resp.ContinueAt)
 	}
-	if len(names) == 0 {
-		return
+	if jsonFlag {
+		w.WriteString("]\n")
+		if err := w.Flush(); err != nil {
+			cli.Fatalf("failed to list keys: %v", err)
+		}
 	}
+}
 
-	var (
-		style = tui.NewStyle().Underline(colorFlag.Colorize())
-		buf   = &strings.Builder{}
-	)
-	fmt.Fprintln(buf, style.Render("Key"))
-	for _, name := range names {
-		buf.WriteString(name)
-		buf.WriteByte('\n')
+// splitKeyPattern splits the glob pattern into a prefix pattern
+// and a match pattern. The server only filters by prefix. Any
+// other glob pattern is sent as 'match' query parameter while
+// the prefix is the part before the first meta character.
+func splitKeyPattern(pattern string) (prefix, match string) {
+	if i := strings.IndexAny(pattern, "*?[\\"); i >= 0 && i < len(pattern)-1 {
+		return pattern[:i] + "*", pattern
 	}
-	fmt.Print(buf)
+	return pattern, ""
 }
 
-const rmKeyCmdUsage = `Usage:
-    kes key rm [options] <name>...
+// listKeyNames returns the names of all keys matching the
+// glob pattern.
+func listKeyNames(ctx context.Context, client *kes.Client, pattern string) ([]string, error) {
+	query := url.Values{}
+	prefix, match := splitKeyPattern(pattern)
+	if match != "" {
+		query.Set("match", match)
+	}
 
-Options:
-    -k, --insecure           Skip X.509 certificate validation during TLS handshake.
+	var names []string
+	for {
+		var resp api.ListKeysResponse
+		if err := sendRequest(ctx, client, http.MethodGet, api.PathKeyList+prefix+"?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		names = append(names, resp.Names...)
+		if resp.ContinueAt == "" {
+			return names, nil
+		}
+		query.Set("continue", resp.ContinueAt)
+	}
+}
+
+const searchKeyCmdUsage = `Usage:
+    kes key search [options] <filter>...
+
+Searches for keys whose metadata matches all filters. The
+search is evaluated by the server. Supported filters:
+
+    name=<pattern>           Key name pattern, e.g. 'my-key*'.
+    tag=<key>[:<value>]      Key has the tag. May be specified
+                             multiple times.
+    algorithm=<algorithm>    Key algorithm, e.g. AES256.
+    created_by=<identity>    Identity that created the key.
+    created_after=<time>     RFC 3339 time or date, e.g. 2024-01-31.
+    created_before=<time>    RFC 3339 time or date, e.g. 2024-01-31.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print keys in JSON format.
+        --format <template>  Print output using a Go template.
+        --color <when>       Specify when to use colored output. The automatic
+                             mode only enables colors if an interactive terminal
+                             is detected - colors are automatically disabled if
+                             the output goes to a pipe.
+                             Possible values: *auto*, never, always.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes key search tag=team:payments
+    $ kes key search name='my-key*' algorithm=AES256 created_after=2024-01-01
+`
+
+func searchKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, searchKeyCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		formatFlag         formatOption
+		colorFlag          colorOption
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print keys in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
+	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key search --help'", err)
+	}
+
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes key search --help'")
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no search filter specified. See 'kes key search --help'")
+	}
+
+	pattern, query := "*", url.Values{}
+	for _, arg := range cmd.Args() {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			cli.Fatalf("invalid filter '%s': must be of the form '<name>=<value>'. See 'kes key search --help'", arg)
+		}
+		switch name {
+		case "name":
+			pattern = value
+		case "tag", "algorithm", "created_by", "created_after", "created_before":
+			query.Add(name, value)
+		default:
+			cli.Fatalf("unknown filter '%s'. See 'kes key search --help'", name)
+		}
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	keys, err := fetchKeyInfos(ctx, newClient(insecureSkipVerify), api.PathKeySearch+pattern, query)
+	if err != nil {
+		cli.Fatalf("failed to search keys: %v", err)
+	}
+	printKeyInfos(keys, jsonFlag, formatFlag, colorFlag)
+}
+
+// fetchKeyInfos fetches all pages of a ListKeyInfos or SearchKeys
+// API response. The path must include the listing pattern.
+func fetchKeyInfos(ctx context.Context, client *kes.Client, path string, query url.Values) ([]api.DescribeKeyResponse, error) {
+	var keys []api.DescribeKeyResponse
+	for {
+		var resp api.ListKeyInfosResponse
+		if err := sendRequest(ctx, client, http.MethodGet, path+"?"+query.Encode(), nil, &resp); err != nil {
+			return nil, err
+		}
+		keys = append(keys, resp.Keys...)
+		if resp.ContinueAt == "" {
+			return keys, nil
+		}
+		query.Set("continue", resp.ContinueAt)
+	}
+}
+
+// printKeyInfos prints the keys either as table, as JSON or
+// using the format template.
+func printKeyInfos(keys []api.DescribeKeyResponse, jsonFlag bool, formatFlag formatOption, colorFlag colorOption) {
+	if formatFlag.IsSet() {
+		items := make([]any, 0, len(keys))
+		for _, key := range keys {
+			items = append(items, key)
+		}
+		if err := formatFlag.Print(items...); err != nil {
+			cli.Fatalf("failed to print keys: %v", err)
+		}
+		return
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(keys); err != nil {
+			cli.Fatalf("failed to print keys: %v", err)
+		}
+		return
+	}
+	if len(keys) == 0 {
+		return
+	}
+
+	var (
+		style = tui.NewStyle().Underline(colorFlag.Colorize())
+		buf   = &strings.Builder{}
+		width = len("Key")
+	)
+	for _, key := range keys {
+		width = max(width, len(key.Name))
+	}
+	fmt.Fprintf(buf, "%s%s  %s  %s%s  %s\n",
+		style.Render("Key"), strings.Repeat(" ", width-len("Key")),
+		style.Render("Algorithm"),
+		style.Render("Date"), strings.Repeat(" ", len(time.DateTime)-len("Date")),
+		style.Render("Tags"),
+	)
+	for _, key := range keys {
+		tags := make([]string, 0, len(key.Tags))
+		for k, v := range key.Tags {
+			tags = append(tags, k+":"+v)
+		}
+		slices.Sort(tags)
+
+		fmt.Fprintf(buf, "%-*s  %-9s  %s  %s\n",
+			width, key.Name, key.Algorithm,
+			key.CreatedAt.Local().Format(time.DateTime),
+			strings.Join(tags, ","),
+		)
+	}
+	fmt.Print(buf)
+}
+
+// parseInterval parses s as duration, e.g. '720h'. In addition,
+// it accepts a number of days, e.g. '90d'.
+func parseInterval(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseUint(days, 10, 16)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("interval must not be negative")
+	}
+	return d, nil
+}
+
+// parseTags parses tags of the form '<key>:<value>'.
+func parseTags(tags []string) (map[string]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		k, v, ok := strings.Cut(tag, ":")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid tag '%s': must be of the form '<key>:<value>'", tag)
+		}
+		m[k] = v
+	}
+	return m, nil
+}
+
+const rmKeyCmdUsage = `Usage:
+    kes key rm [options] <name>...
+    kes key rm [options] --pattern <pattern>
+
+Options:
+    -k, --insecure           Skip X.509 certificate validation during TLS handshake.
     -e, --enclave <name>     Operate within the specified enclave.
+        --pattern <pattern>  Remove all keys matching the glob pattern.
+    -y, --yes                Don't ask for confirmation before removing
+                             keys matching --pattern.
 
     -h, --help               Show list of command-line options.
 
+If soft-delete is enabled on the server, keys are only marked for
+deletion and can be recovered with 'kes key undelete' until their
+recovery window has passed.
+
+When removing more than one key, a failure does not stop the
+remaining keys from being removed. Instead, the command prints
+a summary and exits with a non-zero status if any key failed.
+
 Examples:
     $ kes key rm my-key
     $ kes key rm my-key1 my-key2
+    $ kes key rm --pattern 'tmp-*' --yes
 `
 
 func rmKeyCmd(args []string) {
@@ -364,44 +1212,148 @@ func rmKeyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		patternFlag        string
+		yesFlag            bool
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.StringVar(&patternFlag, "pattern", "", "Remove all keys matching the glob pattern")
+	cmd.BoolVarP(&yesFlag, "yes", "y", false, "Don't ask for confirmation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
 		}
 		cli.Fatalf("%v. See 'kes key rm --help'", err)
 	}
-	if cmd.NArg() == 0 {
+	if cmd.NArg() == 0 && patternFlag == "" {
 		cli.Fatal("no key name specified. See 'kes key rm --help'")
 	}
+	if cmd.NArg() > 0 && patternFlag != "" {
+		cli.Fatal("'--pattern' cannot be used together with key names. See 'kes key rm --help'")
+	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	names := cmd.Args()
+	if patternFlag != "" {
+		var err error
+		if names, err = listKeyNames(ctx, client, patternFlag); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to list keys: %v", err)
+		}
+		if len(names) == 0 {
+			fmt.Printf("No keys match '%s'.\n", patternFlag)
+			return
+		}
+		if !yesFlag {
+			if !isTerm(os.Stdin) {
+				cli.Fatalf("refusing to remove %d keys matching '%s' without confirmation. Use --yes", len(names), patternFlag)
+			}
+			p := &prompter{r: bufio.NewReader(os.Stdin)}
+			if !p.askBool(fmt.Sprintf("Remove %d keys matching '%s'?", len(names), patternFlag), false) {
+				os.Exit(1)
+			}
+		}
+	}
+
+	removeKey := func(name string) error {
+		return sendRequest(ctx, client, http.MethodDelete, api.PathKeyDelete+name, nil, nil)
+	}
+	if len(names) > 1 || patternFlag != "" {
+		bulkKeyOp(names, "remove", "Removed", removeKey)
+		return
+	}
+	if err := removeKey(names[0]); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to remove key %q: %v", names[0], err)
+	}
+}
+
+const undeleteKeyCmdUsage = `Usage:
+    kes key undelete [options] <name>...
+
+Options:
+    -k, --insecure           Skip X.509 certificate validation during TLS handshake.
+
+    -h, --help               Show list of command-line options.
+
+Recovers keys that have been marked for deletion. Requires soft-delete
+to be enabled on the server. Keys can only be recovered until their
+recovery window has passed.
+
+Examples:
+    $ kes key undelete my-key
+    $ kes key undelete my-key1 my-key2
+`
+
+func undeleteKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, undeleteKeyCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key undelete --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no key name specified. See 'kes key undelete --help'")
+	}
+
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	client := newClient(insecureSkipVerify)
 	for _, name := range cmd.Args() {
-		if err := client.DeleteKey(ctx, name); err != nil {
+		if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyUndelete+name, nil, nil); err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
-			cli.Fatalf("failed to remove key %q: %v", name, err)
+			cli.Fatalf("failed to recover key %q: %v", name, err)
 		}
 	}
 }
 
 const encryptKeyCmdUsage = `Usage:
-    kes key encrypt [options] <name> <message>
+    kes key encrypt [options] <name> [<message>]
 
 Options:
+    -f, --file <path>        Read the message from the file. Use '-' to
+                             read the message from standard input.
+    -c, --context <value>    Base64-encoded associated data. It is not
+                             encrypted but must be provided again to
+                             decrypt the ciphertext.
+    -s, --stream             Encrypt the input of arbitrary size with a
+                             new data encryption key (DEK).
+    -o, --output <path>      Write the encrypted stream to the file
+                             instead of standard output.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print ciphertext in JSON format.
 
     -h, --help               Print command line options.
 
+If neither <message> nor --file is specified, the message is
+read from standard input. The ciphertext is printed base64-encoded.
+
+Messages are limited to 1 MiB. Use --stream to encrypt larger
+inputs, like files. The server generates a DEK that encrypts the
+input in chunks, and the binary output contains the DEK encrypted
+with the key. Decrypt it with 'kes key decrypt --stream'.
+
 Examples:
     $ kes key encrypt my-key "Hello World"
+    $ kes key encrypt --context "$(echo -n bucket/object | base64)" my-key "Hello World"
+    $ cat secret.txt | kes key encrypt my-key
+    $ kes key encrypt --stream --file backup.tar --output backup.tar.enc my-key
 `
 
 func encryptKeyCmd(args []string) {
@@ -411,9 +1363,19 @@ func encryptKeyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		jsonFlag           bool
+		fileFlag           string
+		contextFlag        string
+		streamFlag         bool
+		outputFlag         string
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print ciphertext in JSON format")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the message from the file")
+	cmd.StringVarP(&contextFlag, "context", "c", "", "Base64-encoded associated data")
+	cmd.BoolVarP(&streamFlag, "stream", "s", false, "Encrypt the input of arbitrary size")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the encrypted stream to the file")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -424,20 +1386,41 @@ func encryptKeyCmd(args []string) {
 	switch {
 	case cmd.NArg() == 0:
 		cli.Fatal("no key name specified. See 'kes key encrypt --help'")
-	case cmd.NArg() == 1:
-		cli.Fatal("no message specified. See 'kes key encrypt --help'")
 	case cmd.NArg() > 2:
 		cli.Fatal("too many arguments. See 'kes key encrypt --help'")
+	case cmd.NArg() == 2 && fileFlag != "":
+		cli.Fatal("cannot use --file when a message is specified. See 'kes key encrypt --help'")
+	case cmd.NArg() == 2 && streamFlag:
+		cli.Fatal("cannot use --stream when a message is specified. See 'kes key encrypt --help'")
+	case outputFlag != "" && !streamFlag:
+		cli.Fatal("cannot use --output without --stream. See 'kes key encrypt --help'")
 	}
 
 	name := cmd.Arg(0)
-	message := cmd.Arg(1)
+	associatedData, err := base64.StdEncoding.DecodeString(contextFlag)
+	if err != nil {
+		cli.Fatalf("invalid context: %v. See 'kes key encrypt --help'", err)
+	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
 	client := newClient(insecureSkipVerify)
-	ciphertext, err := client.Encrypt(ctx, name, []byte(message), nil)
+	if streamFlag {
+		if err = encryptStream(ctx, client, name, associatedData, fileFlag, outputFlag); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to encrypt stream: %v", err)
+		}
+		return
+	}
+
+	message, err := readInput(cmd.Args()[1:], fileFlag)
+	if err != nil {
+		cli.Fatalf("failed to read message: %v. See 'kes key encrypt --help'", err)
+	}
+	ciphertext, err := client.Encrypt(ctx, name, message, associatedData)
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			os.Exit(1)
@@ -445,7 +1428,7 @@ func encryptKeyCmd(args []string) {
 		cli.Fatalf("failed to encrypt message: %v", err)
 	}
 
-	if isTerm(os.Stdout) {
+	if isTerm(os.Stdout) && !jsonFlag {
 		fmt.Printf("\nciphertext: %s\n", base64.StdEncoding.EncodeToString(ciphertext))
 	} else {
 		fmt.Printf(`{"ciphertext":"%s"}`, base64.StdEncoding.EncodeToString(ciphertext))
@@ -453,17 +1436,35 @@ func encryptKeyCmd(args []string) {
 }
 
 const decryptKeyCmdUsage = `Usage:
-    kes key decrypt [options] <name> <ciphertext> [<context>]
+    kes key decrypt [options] <name> [<ciphertext> [<context>]]
 
 Options:
+    -f, --file <path>        Read the base64-encoded ciphertext from the
+                             file. Use '-' to read it from standard input.
+    -c, --context <value>    Base64-encoded associated data used when
+                             encrypting the message.
+    -s, --stream             Decrypt a stream encrypted with
+                             'kes key encrypt --stream'.
+    -o, --output <path>      Write the decrypted stream to the file
+                             instead of standard output.
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print plaintext in JSON format.
 
     -h, --help               Print command line options.
 
+If neither <ciphertext> nor --file is specified, the ciphertext is
+read from standard input. The plaintext is printed base64-encoded.
+
+With --stream, the plaintext is written as is. If decryption fails,
+the output file is removed. Output written to standard output before
+an error occurred must not be used.
+
 Examples:
     $ CIPHERTEXT=$(kes key dek my-key | jq -r .ciphertext)
     $ kes key decrypt my-key "$CIPHERTEXT"
+    $ kes key encrypt --json my-key "Hello World" | jq -r .ciphertext | kes key decrypt my-key
+    $ kes key decrypt --stream --file backup.tar.enc --output backup.tar my-key
 `
 
 func decryptKeyCmd(args []string) {
@@ -473,9 +1474,19 @@ func decryptKeyCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		jsonFlag           bool
+		fileFlag           string
+		contextFlag        string
+		streamFlag         bool
+		outputFlag         string
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print plaintext in JSON format")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the ciphertext from the file")
+	cmd.StringVarP(&contextFlag, "context", "c", "", "Base64-encoded associated data")
+	cmd.BoolVarP(&streamFlag, "stream", "s", false, "Decrypt an encrypted stream")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the decrypted stream to the file")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -486,27 +1497,56 @@ func decryptKeyCmd(args []string) {
 	switch {
 	case cmd.NArg() == 0:
 		cli.Fatal("no key name specified. See 'kes key decrypt --help'")
-	case cmd.NArg() == 1:
-		cli.Fatal("no ciphertext specified. See 'kes key decrypt --help'")
 	case cmd.NArg() > 3:
 		cli.Fatal("too many arguments. See 'kes key decrypt --help'")
+	case cmd.NArg() > 1 && fileFlag != "":
+		cli.Fatal("cannot use --file when a ciphertext is specified. See 'kes key decrypt --help'")
+	case cmd.NArg() == 3 && contextFlag != "":
+		cli.Fatal("cannot use --context when a context is specified. See 'kes key decrypt --help'")
+	case cmd.NArg() > 1 && streamFlag:
+		cli.Fatal("cannot use --stream when a ciphertext is specified. See 'kes key decrypt --help'")
+	case outputFlag != "" && !streamFlag:
+		cli.Fatal("cannot use --output without --stream. See 'kes key decrypt --help'")
 	}
 
 	name := cmd.Arg(0)
-	ciphertext, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
+	if streamFlag {
+		associatedData, err := base64.StdEncoding.DecodeString(contextFlag)
+		if err != nil {
+			cli.Fatalf("invalid context: %v. See 'kes key decrypt --help'", err)
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		client := newClient(insecureSkipVerify)
+		if err = decryptStream(ctx, client, name, associatedData, fileFlag, outputFlag); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to decrypt stream: %v", err)
+		}
+		return
+	}
+
+	input, err := readInput(cmd.Args()[1:min(cmd.NArg(), 2)], fileFlag)
+	if err != nil {
+		cli.Fatalf("failed to read ciphertext: %v. See 'kes key decrypt --help'", err)
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(input)))
 	if err != nil {
 		cli.Fatalf("invalid ciphertext: %v. See 'kes key decrypt --help'", err)
 	}
 
-	var associatedData []byte
 	if cmd.NArg() == 3 {
-		associatedData, err = base64.StdEncoding.DecodeString(cmd.Arg(2))
-		if err != nil {
-			cli.Fatalf("invalid context: %v. See 'kes key decrypt --help'", err)
-		}
+		contextFlag = cmd.Arg(2)
+	}
+	associatedData, err := base64.StdEncoding.DecodeString(contextFlag)
+	if err != nil {
+		cli.Fatalf("invalid context: %v. See 'kes key decrypt --help'", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
 	client := newClient(insecureSkipVerify)
@@ -518,19 +1558,425 @@ func decryptKeyCmd(args []string) {
 		cli.Fatalf("failed to decrypt ciphertext: %v", err)
 	}
 
-	if isTerm(os.Stdout) {
+	if isTerm(os.Stdout) && !jsonFlag {
 		fmt.Printf("\nplaintext: %s\n", base64.StdEncoding.EncodeToString(plaintext))
 	} else {
 		fmt.Printf(`{"plaintext":"%s"}`, base64.StdEncoding.EncodeToString(plaintext))
 	}
 }
 
+// readInput returns the first argument, if present, or the
+// content of the file. If the file is empty or '-', it reads
+// from standard input instead. The input is limited to 1 MiB,
+// the max. request body size of the encrypt and decrypt APIs.
+func readInput(args []string, file string) ([]byte, error) {
+	const MaxSize = 1 << 20
+
+	if len(args) > 0 {
+		return []byte(args[0]), nil
+	}
+
+	var r io.Reader = os.Stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	} else if isTerm(os.Stdin) {
+		return nil, errors.New("no input specified")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxSize {
+		return nil, errors.New("input exceeds 1 MiB")
+	}
+	return data, nil
+}
+
+const hmacKeyCmdUsage = `Usage:
+    kes key hmac [options] <name> [<message>]
+
+Options:
+    -f, --file <path>        Read the message from the file. Use '-' to
+                             read the message from standard input.
+        --hex                Print HMAC hex-encoded instead of base64.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print HMAC in JSON format.
+
+    -h, --help               Print command line options.
+
+Computes the HMAC-SHA256 of the message with the key. The HMAC key
+never leaves the server and does not change when the key is rotated.
+
+If neither <message> nor --file is specified, the message is
+read from standard input.
+
+Examples:
+    $ kes key hmac my-key "Hello World"
+    $ echo -n "my-token" | kes key hmac --hex my-key
+`
+
+func hmacKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, hmacKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		jsonFlag           bool
+		fileFlag           string
+		hexFlag            bool
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print HMAC in JSON format")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the message from the file")
+	cmd.BoolVar(&hexFlag, "hex", false, "Print HMAC hex-encoded")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key hmac --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key hmac --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key hmac --help'")
+	case cmd.NArg() == 2 && fileFlag != "":
+		cli.Fatal("cannot use --file when a message is specified. See 'kes key hmac --help'")
+	}
+
+	name := cmd.Arg(0)
+	message, err := readInput(cmd.Args()[1:], fileFlag)
+	if err != nil {
+		cli.Fatalf("failed to read message: %v. See 'kes key hmac --help'", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	client := newClient(insecureSkipVerify)
+	sum, err := client.HMAC(ctx, name, message)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to compute HMAC: %v", err)
+	}
+
+	mac := base64.StdEncoding.EncodeToString(sum)
+	if hexFlag {
+		mac = hex.EncodeToString(sum)
+	}
+	if isTerm(os.Stdout) && !jsonFlag {
+		fmt.Printf("\nhmac: %s\n", mac)
+	} else {
+		fmt.Printf(`{"hmac":"%s"}`, mac)
+	}
+}
+
+const signKeyCmdUsage = `Usage:
+    kes key sign [options] <name> [<message>]
+
+Options:
+    -f, --file <path>        Read the message from the file. Use '-' to
+                             read the message from standard input.
+    -a, --algorithm <alg>    Signature algorithm: 'ECDSA-P256' (default)
+                             or 'Ed25519'.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print signature in JSON format.
+
+    -h, --help               Print command line options.
+
+Signs the message with a signing key derived from the key. The
+signing key never leaves the server. The signature and the PKIX,
+ASN.1 DER encoded public key are printed base64-encoded. ECDSA
+signatures are ASN.1 DER encoded.
+
+If neither <message> nor --file is specified, the message is
+read from standard input.
+
+Examples:
+    $ kes key sign my-key "Hello World"
+    $ kes key sign --algorithm Ed25519 --file release.tar.gz my-key
+`
+
+func signKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, signKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		jsonFlag           bool
+		fileFlag           string
+		algorithmFlag      string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print signature in JSON format")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the message from the file")
+	cmd.StringVarP(&algorithmFlag, "algorithm", "a", "", "Signature algorithm")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key sign --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key sign --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes key sign --help'")
+	case cmd.NArg() == 2 && fileFlag != "":
+		cli.Fatal("cannot use --file when a message is specified. See 'kes key sign --help'")
+	}
+
+	name := cmd.Arg(0)
+	message, err := readInput(cmd.Args()[1:], fileFlag)
+	if err != nil {
+		cli.Fatalf("failed to read message: %v. See 'kes key sign --help'", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var resp api.SignKeyResponse
+	client := newClient(insecureSkipVerify)
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeySign+name, api.SignKeyRequest{
+		Message:   message,
+		Algorithm: algorithmFlag,
+	}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to sign message: %v", err)
+	}
+
+	var (
+		signature = base64.StdEncoding.EncodeToString(resp.Signature)
+		publicKey = base64.StdEncoding.EncodeToString(resp.PublicKey)
+	)
+	if isTerm(os.Stdout) && !jsonFlag {
+		const format = "\nsignature:  %s\npublic key: %s\n"
+		fmt.Printf(format, signature, publicKey)
+	} else {
+		const format = `{"signature":"%s","public_key":"%s"}`
+		fmt.Printf(format, signature, publicKey)
+	}
+}
+
+const verifyKeyCmdUsage = `Usage:
+    kes key verify [options] <name> <signature> [<message>]
+
+Options:
+    -f, --file <path>        Read the message from the file. Use '-' to
+                             read the message from standard input.
+    -a, --algorithm <alg>    Signature algorithm: 'ECDSA-P256' (default)
+                             or 'Ed25519'.
+    -k, --insecure           Skip TLS certificate validation.
+    -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print result in JSON format.
+
+    -h, --help               Print command line options.
+
+Verifies the base64-encoded signature produced by 'kes key sign'.
+It exits with a non-zero exit code if the signature is not valid.
+
+If neither <message> nor --file is specified, the message is
+read from standard input.
+
+Examples:
+    $ SIGNATURE=$(kes key sign --json my-key "Hello World" | jq -r .signature)
+    $ kes key verify my-key "$SIGNATURE" "Hello World"
+`
+
+func verifyKeyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, verifyKeyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		enclaveName        string
+		jsonFlag           bool
+		fileFlag           string
+		algorithmFlag      string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print result in JSON format")
+	cmd.StringVarP(&fileFlag, "file", "f", "", "Read the message from the file")
+	cmd.StringVarP(&algorithmFlag, "algorithm", "a", "", "Signature algorithm")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes key verify --help'", err)
+	}
+
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes key verify --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no signature specified. See 'kes key verify --help'")
+	case cmd.NArg() > 3:
+		cli.Fatal("too many arguments. See 'kes key verify --help'")
+	case cmd.NArg() == 3 && fileFlag != "":
+		cli.Fatal("cannot use --file when a message is specified. See 'kes key verify --help'")
+	}
+
+	name := cmd.Arg(0)
+	signature, err := base64.StdEncoding.DecodeString(cmd.Arg(1))
+	if err != nil {
+		cli.Fatalf("invalid signature: %v. See 'kes key verify --help'", err)
+	}
+	message, err := readInput(cmd.Args()[2:], fileFlag)
+	if err != nil {
+		cli.Fatalf("failed to read message: %v. See 'kes key verify --help'", err)
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var resp api.VerifyKeyResponse
+	client := newClient(insecureSkipVerify)
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyVerify+name, api.VerifyKeyRequest{
+		Message:   message,
+		Signature: signature,
+		Algorithm: algorithmFlag,
+	}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to verify signature: %v", err)
+	}
+
+	if isTerm(os.Stdout) && !jsonFlag {
+		if !resp.Valid {
+			cli.Fatal("signature is not valid")
+		}
+		fmt.Println("Signature is valid")
+	} else {
+		fmt.Printf(`{"valid":%t}`, resp.Valid)
+		if !resp.Valid {
+			os.Exit(1)
+		}
+	}
+}
+
+// encryptStream encrypts the content of the file, or standard
+// input, with a new data encryption key generated by the server
+// and writes the encrypted stream to the output file, or standard
+// output.
+func encryptStream(ctx context.Context, client *kes.Client, name string, associatedData []byte, file, output string) error {
+	src, dst, err := openStream(file, output)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	key, err := client.GenerateKey(ctx, name, associatedData)
+	if err != nil {
+		closeStream(dst, output)
+		return err
+	}
+	defer clear(key.Plaintext)
+
+	if _, err = crypto.EncryptStream(dst, src, key.Plaintext, key.Ciphertext); err != nil {
+		closeStream(dst, output)
+		return err
+	}
+	return dst.Close()
+}
+
+// decryptStream decrypts the encrypted stream read from the file,
+// or standard input, and writes the plaintext to the output file,
+// or standard output. The data encryption key is decrypted by the
+// server.
+func decryptStream(ctx context.Context, client *kes.Client, name string, associatedData []byte, file, output string) error {
+	src, dst, err := openStream(file, output)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	header, err := crypto.ReadStreamHeader(src)
+	if err != nil {
+		closeStream(dst, output)
+		return err
+	}
+	key, err := client.Decrypt(ctx, name, header.SealedKey, associatedData)
+	if err != nil {
+		closeStream(dst, output)
+		return err
+	}
+	defer clear(key)
+
+	if _, err = crypto.DecryptStream(dst, src, key, header); err != nil {
+		closeStream(dst, output)
+		return err
+	}
+	return dst.Close()
+}
+
+// openStream opens the file for reading and creates the output
+// file. If the file or output is empty or '-', standard input or
+// standard output is used instead.
+func openStream(file, output string) (io.ReadCloser, io.WriteCloser, error) {
+	var (
+		src io.ReadCloser  = os.Stdin
+		dst io.WriteCloser = os.Stdout
+	)
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		src = f
+	} else if isTerm(os.Stdin) {
+		return nil, nil, errors.New("no input specified")
+	}
+
+	if output != "" && output != "-" {
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			src.Close()
+			return nil, nil, err
+		}
+		dst = f
+	} else if isTerm(os.Stdout) {
+		src.Close()
+		return nil, nil, errors.New("refusing to write binary data to the terminal. Use --output")
+	}
+	return src, dst, nil
+}
+
+// closeStream closes dst and removes the output file, if any,
+// such that no partial output remains after an error.
+func closeStream(dst io.Closer, output string) {
+	dst.Close()
+	if output != "" && output != "-" {
+		os.Remove(output)
+	}
+}
+
 const dekCmdUsage = `Usage:
     kes key dek <name> [<context>]
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
     -e, --enclave <name>     Operate within the specified enclave.
+        --json               Print data encryption key in JSON format.
 
     -h, --help               Print command line options.
 
@@ -545,9 +1991,11 @@ func dekCmd(args []string) {
 	var (
 		insecureSkipVerify bool
 		enclaveName        string
+		jsonFlag           bool
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print data encryption key in JSON format")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -572,7 +2020,7 @@ func dekCmd(args []string) {
 		associatedData = b
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	client := newClient(insecureSkipVerify)
@@ -588,7 +2036,7 @@ func dekCmd(args []string) {
 		plaintext  = base64.StdEncoding.EncodeToString(key.Plaintext)
 		ciphertext = base64.StdEncoding.EncodeToString(key.Ciphertext)
 	)
-	if isTerm(os.Stdout) {
+	if isTerm(os.Stdout) && !jsonFlag {
 		const format = "\nplaintext:  %s\nciphertext: %s\n"
 		fmt.Printf(format, plaintext, ciphertext)
 	} else {
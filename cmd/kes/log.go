@@ -6,28 +6,54 @@ package main
 
 import (
 	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
 	"strconv"
+	"strings"
 	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
-	"github.com/minio/kms-go/kes"
+	kesdk "github.com/minio/kms-go/kes"
 
 	flag "github.com/spf13/pflag"
 )
 
 const logCmdUsage = `Usage:
-    kes log <command>
+    kes log [options]
+    kes log verify [options] [<file>...]
+
+Commands:
+    verify                   Verify the integrity of an exported audit log.
 
 Options:
     --audit                  Print audit logs. (default)
     --error                  Print error logs.
     --json                   Print log events as JSON.
+    --query                  Print stored audit events instead of streaming
+                             new ones. Requires an audit store.
+    --limit <n>              Print at most n stored audit events. Requires
+                             '--query'. (default: 1000)
+
+    --identity <identity>    Only print audit events of the identity.
+    --path <pattern>         Only print audit events whose API path matches
+                             the pattern - e.g. '/v1/key/delete/*'.
+    --status <code>          Only print audit events with the status code.
+    --since <time>           Only print events at or after the RFC 3339
+                             timestamp or date.
+    --until <time>           Only print events before the RFC 3339 timestamp
+                             or date, or before now plus a duration - e.g.
+                             '1h'. Streaming stops once the time is reached.
 
     -k, --insecure           Skip TLS certificate validation.
     -h, --help               Print command line options.
@@ -35,9 +61,18 @@ Options:
 Examples:
     $ kes log
     $ kes log --error
+    $ kes log --path '/v1/key/delete/*' --status 200
+    $ kes log --query --path '/v1/key/delete/my-key' --since 2024-01-30 --until 2024-01-31
+    $ kes log --identity 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22 --until 1h
+    $ kes log --query --json > audit.log && kes log verify --key server.cert audit.log
 `
 
 func logCmd(args []string) {
+	if len(args) > 1 && args[1] == "verify" {
+		verifyLogCmd(args[1:])
+		return
+	}
+
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, logCmdUsage) }
 
@@ -45,11 +80,25 @@ func logCmd(args []string) {
 		auditFlag          bool
 		errorFlag          bool
 		jsonFlag           bool
+		queryFlag          bool
+		limitFlag          int
+		identityFlag       string
+		pathFlag           string
+		statusFlag         int
+		sinceFlag          string
+		untilFlag          string
 		insecureSkipVerify bool
 	)
 	cmd.BoolVar(&auditFlag, "audit", true, "Print audit logs")
 	cmd.BoolVar(&errorFlag, "error", false, "Print error logs")
-	cmd.BoolVar(&jsonFlag, "json", false, "Print log events as JSON")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print log events as JSON")
+	cmd.BoolVar(&queryFlag, "query", false, "Print stored audit events")
+	cmd.IntVar(&limitFlag, "limit", 0, "Print at most n stored audit events")
+	cmd.StringVar(&identityFlag, "identity", "", "Only print audit events of the identity")
+	cmd.StringVar(&pathFlag, "path", "", "Only print audit events whose API path matches the pattern")
+	cmd.IntVar(&statusFlag, "status", 0, "Only print audit events with the status code")
+	cmd.StringVar(&sinceFlag, "since", "", "Only print events at or after the time")
+	cmd.StringVar(&untilFlag, "until", "", "Stop at the time or after the duration")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -58,7 +107,7 @@ func logCmd(args []string) {
 		cli.Fatalf("%v. See 'kes log --help'", err)
 	}
 	if cmd.NArg() > 0 {
-		cli.Fatal("too many arguments. See 'kes key import --help'")
+		cli.Fatal("too many arguments. See 'kes log --help'")
 	}
 	if auditFlag && errorFlag && cmd.Changed("audit") {
 		cli.Fatal("cannot display audit and error logs at the same time")
@@ -66,37 +115,85 @@ func logCmd(args []string) {
 	if auditFlag && errorFlag { // Unset (default) audit flag if error flag has been set
 		auditFlag = !auditFlag
 	}
+	if errorFlag && (identityFlag != "" || pathFlag != "" || statusFlag != 0) {
+		cli.Fatal("error logs can only be filtered by time. See 'kes log --help'")
+	}
+	if errorFlag && queryFlag {
+		cli.Fatal("cannot query error logs. See 'kes log --help'")
+	}
+	if cmd.Changed("limit") && (!queryFlag || limitFlag <= 0) {
+		cli.Fatal("'--limit' requires '--query' and a positive number. See 'kes log --help'")
+	}
+
+	query := url.Values{}
+	if identityFlag != "" {
+		query.Set("identity", identityFlag)
+	}
+	if pathFlag != "" {
+		query.Set("path", pathFlag)
+	}
+	if statusFlag != 0 {
+		query.Set("status", strconv.Itoa(statusFlag))
+	}
+	if limitFlag > 0 {
+		query.Set("limit", strconv.Itoa(limitFlag))
+	}
+	if sinceFlag != "" {
+		since, err := parseLogTime(sinceFlag, false)
+		if err != nil {
+			cli.Fatalf("invalid '--since' time '%s'. See 'kes log --help'", sinceFlag)
+		}
+		query.Set("since", since.Format(time.RFC3339))
+	}
+	if untilFlag != "" {
+		until, err := parseLogTime(untilFlag, true)
+		if err != nil {
+			cli.Fatalf("invalid '--until' time '%s'. See 'kes log --help'", untilFlag)
+		}
+		query.Set("until", until.Format(time.RFC3339))
+	}
 
 	client := newClient(insecureSkipVerify)
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	switch {
 	case auditFlag:
-		stream, err := client.AuditLog(ctx)
+		path := api.PathLogAudit
+		if queryFlag {
+			path = api.PathLogAuditQuery
+		}
+		body, err := openLog(ctx, client, path, query)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
-			cli.Fatalf("failed to connect to error log: %v", err)
+			cli.Fatalf("failed to connect to audit log: %v", err)
 		}
-		defer stream.Close()
-
 		if jsonFlag {
-			if _, err = stream.WriteTo(os.Stdout); err != nil {
+			// Print the events as sent by the server, including
+			// checkpoints, such that they can be verified.
+			defer body.Close()
+			if _, err = io.Copy(os.Stdout, body); err != nil {
+				if errors.Is(err, context.Canceled) {
+					os.Exit(1)
+				}
 				cli.Fatal(err)
 			}
 		} else {
+			stream := kesdk.NewAuditStream(body)
+			defer stream.Close()
 			printAuditLog(stream)
 		}
 	case errorFlag:
-		stream, err := client.ErrorLog(ctx)
+		body, err := openLog(ctx, client, api.PathLogError, query)
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				os.Exit(1)
 			}
 			cli.Fatalf("failed to connect to error log: %v", err)
 		}
+		stream := kesdk.NewErrorStream(body)
 		defer stream.Close()
 
 		if jsonFlag {
@@ -112,7 +209,180 @@ func logCmd(args []string) {
 	}
 }
 
-func printAuditLog(stream *kes.AuditStream) {
+const verifyLogCmdUsage = `Usage:
+    kes log verify [options] [<file>...]
+
+Verifies the integrity of an audit log exported as JSON, e.g. by
+'kes log --json' or from an audit store, using the server's signed
+audit checkpoints. It detects audit events that have been modified,
+removed or inserted. Multiple files, like the daily files of an
+audit store, are verified in the given order. If no file or '-' is
+specified, the log is read from standard input.
+
+The audit log must not be filtered. Audit events before the first
+and after the last checkpoint cannot be verified and are reported
+as unverified.
+
+Options:
+        --key <path>         Path to the public key, certificate or private
+                             key matching the key that signs checkpoints.
+                             By default, this is the server's TLS key.
+        --json               Print the verification summary in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes log verify --key server.cert audit.log
+    $ kes log --query --json | kes log verify --key audit.pub
+`
+
+func verifyLogCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, verifyLogCmdUsage) }
+
+	var (
+		keyFlag  string
+		jsonFlag bool
+	)
+	cmd.StringVar(&keyFlag, "key", "", "Path to the public key, certificate or private key")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the verification summary in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes log verify --help'", err)
+	}
+	if keyFlag == "" {
+		cli.Fatal("no public key specified. See 'kes log verify --help'")
+	}
+
+	key, err := readPublicKey(keyFlag)
+	if err != nil {
+		cli.Fatalf("failed to read public key: %v", err)
+	}
+
+	var readers []io.Reader
+	for _, filename := range cmd.Args() {
+		if filename == "-" {
+			readers = append(readers, os.Stdin, strings.NewReader("\n"))
+			continue
+		}
+		file, err := os.Open(filename)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		defer file.Close()
+		readers = append(readers, file, strings.NewReader("\n"))
+	}
+	if len(readers) == 0 {
+		readers = append(readers, os.Stdin)
+	}
+
+	summary, err := kes.VerifyAuditLog(io.MultiReader(readers...), key)
+	if err != nil {
+		cli.Fatalf("failed to verify audit log: %v", err)
+	}
+
+	if jsonFlag {
+		type Output struct {
+			Events      uint64 `json:"events"`
+			Checkpoints uint64 `json:"checkpoints"`
+			Unverified  uint64 `json:"unverified"`
+		}
+		output := Output{
+			Events:      summary.Events,
+			Checkpoints: summary.Checkpoints,
+			Unverified:  summary.Unverified,
+		}
+		if err = json.NewEncoder(os.Stdout).Encode(output); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Printf("Verified %d audit events and %d checkpoints\n", summary.Events-summary.Unverified, summary.Checkpoints)
+	if summary.Unverified > 0 {
+		fmt.Printf("Could not verify %d audit events not covered by a checkpoint\n", summary.Unverified)
+	}
+}
+
+// readPublicKey reads a PEM-encoded public key, X.509 certificate
+// or private key from the file and returns its public key.
+func readPublicKey(filename string) (crypto.PublicKey, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM-encoded key found")
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM type '%s'", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type '%T'", key)
+	}
+	return signer.Public(), nil
+}
+
+// openLog subscribes to the audit or error log API, or queries
+// the audit store, depending on the path, and returns the stream
+// of log events. The query contains the server-side log filters.
+func openLog(ctx context.Context, client *kesdk.Client, path string, query url.Values) (io.ReadCloser, error) {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	resp, err := sendGet(ctx, client, path)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		return nil, api.ReadError(resp)
+	}
+	return resp.Body, nil
+}
+
+// parseLogTime parses s as RFC 3339 timestamp or date, e.g.
+// '2024-01-31'. If duration is true, s may also be a duration
+// relative to now, e.g. '1h'.
+func parseLogTime(s string, duration bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if duration {
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return time.Now().Add(d), nil
+		}
+	}
+	return time.ParseInLocation(time.DateOnly, s, time.Local)
+}
+
+func printAuditLog(stream *kesdk.AuditStream) {
 	var (
 		statStyleFail    = tui.NewStyle().Foreground(tui.Color("#ff0000")).Width(5)
 		statStyleSuccess = tui.NewStyle().Foreground(tui.Color("#00ff00")).Width(5)
@@ -175,7 +445,7 @@ func printAuditLog(stream *kes.AuditStream) {
 	}
 }
 
-func printErrorLog(stream *kes.ErrorStream) {
+func printErrorLog(stream *kesdk.ErrorStream) {
 	for stream.Next() {
 		fmt.Println(stream.Event().Message)
 	}
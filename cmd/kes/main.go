@@ -5,22 +5,34 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"aead.dev/mem"
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/headers"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kms-go/kes"
+	"github.com/muesli/termenv"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
@@ -32,24 +44,195 @@ const usage = `Usage:
 
 Commands:
     server                   Start a KES server.
+    proxy                    Start a caching KES proxy.
+    init                     Create a KES server config file.
 
     key                      Manage cryptographic keys.
     policy                   Manage KES policies.
     identity                 Manage KES identities.
+    approval                 Approve or deny pending requests.
+    ssh                      Issue SSH certificates.
+    cert                     Issue X.509 certificates.
+    secret                   Manage secrets.
+    context                  Manage server connection contexts.
 
     log                      Print error and audit log events.
+    watch                    Print key, policy and identity events.
     status                   Print server status.
     metric                   Print server metrics.
-
+    doctor                   Diagnose client and server setup.
+    compat                   Check compatibility with other services.
+    support-bundle           Collect diagnostics for support cases.
+    inspect                  Create a signed report for support and compliance.
+    debug                    Capture runtime profiles of the server.
+    benchmark                Measure server throughput and latency.
+    admin                    Perform server administration tasks.
+    unseal                   Unseal a sealed KES server.
+    cluster                  Manage KES cluster members.
+    tpm                      Seal master keys to a TPM.
+
+    backup                   Create and restore backups.
     migrate                  Migrate KMS data.
+    apply                    Reconcile the server with a state file.
     update                   Update KES binary.
 
 Options:
     -v, --version            Print version information.
         --auto-completion    Install auto-completion for this shell.
+        --timeout <duration> Abort commands that take longer than the
+                             timeout - e.g. due to an unreachable server.
+                             It applies to all commands but 'kes server'.
+                             For example: --timeout 30s
+        --no-color           Disable colored output for all commands.
+                             Colors are also disabled if the NO_COLOR
+                             env. variable is set or the output goes
+                             to a pipe.
+        --json               Print the output of all commands in JSON
+                             format instead of human-readable text.
+        --context <name>     Use the server and credentials of the named
+                             context instead of the KES_SERVER, KES_API_KEY,
+                             KES_CLIENT_CERT and KES_CLIENT_KEY env. variables.
+                             Must be specified before the command.
+                             See 'kes context --help'.
     -h, --help               Print command line options.
+
+The KES_SERVER env. variable may contain a comma-separated list of
+endpoints. Commands send requests to these endpoints round-robin and
+fail over to the next endpoint if a server is unreachable.
 `
 
+// globalTimeout is the timeout of a command set via the
+// global --timeout option. If <= 0, commands have no
+// timeout.
+var globalTimeout time.Duration
+
+// newContext returns a new context that is canceled when the
+// process receives an interrupt signal or the global --timeout,
+// if set, expires.
+func newContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	if globalTimeout <= 0 {
+		return ctx, cancel
+	}
+	ctx, cancelTimeout := context.WithTimeout(ctx, globalTimeout)
+	return ctx, func() {
+		cancelTimeout()
+		cancel()
+	}
+}
+
+// parseGlobalTimeout removes the global --timeout option from
+// the arguments, such that it can be specified before or after
+// any command, and returns the remaining arguments and timeout.
+func parseGlobalTimeout(args []string) ([]string, time.Duration, error) {
+	const Flag = "--timeout"
+
+	var (
+		rest    = make([]string, 0, len(args))
+		timeout time.Duration
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		var value string
+		switch {
+		case arg == Flag:
+			if i+1 >= len(args) {
+				return nil, 0, errors.New("flag needs an argument: --timeout")
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, Flag+"="):
+			value = strings.TrimPrefix(arg, Flag+"=")
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid argument %q for \"--timeout\" flag: %v", value, err)
+		}
+		if d < 0 {
+			return nil, 0, fmt.Errorf("invalid argument %q for \"--timeout\" flag: timeout must not be negative", value)
+		}
+		timeout = d
+	}
+	return rest, timeout, nil
+}
+
+// globalNoColor is set if colored output has been disabled
+// via the global --no-color option or the NO_COLOR env.
+// variable.
+var globalNoColor bool
+
+// globalContext is the name of the context selected via the
+// global --context option or the KES_CONTEXT env. variable.
+var globalContext string
+
+// globalJSON is set if JSON output has been requested via
+// the global --json option. Commands use it as default for
+// their --json option.
+var globalJSON bool
+
+// parseGlobalString removes the global option, like --context,
+// and its value from the arguments and returns its value. Unlike
+// boolean options, it must be specified before the command name
+// since commands may define an option with the same name, like
+// 'kes key encrypt --context'.
+func parseGlobalString(args []string, flag string) ([]string, string, error) {
+	var (
+		rest  = make([]string, 0, len(args))
+		value string
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || (i > 0 && !strings.HasPrefix(arg, "-")) {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		switch {
+		case arg == flag:
+			if i+1 >= len(args) {
+				return nil, "", fmt.Errorf("flag needs an argument: %s", flag)
+			}
+			i++
+			value = args[i]
+		case strings.HasPrefix(arg, flag+"="):
+			value = strings.TrimPrefix(arg, flag+"=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, value, nil
+}
+
+// parseGlobalBool removes the global boolean option, like
+// --no-color, from the arguments, such that it can be specified
+// before or after any command, and reports whether it was present.
+func parseGlobalBool(args []string, flag string) ([]string, bool) {
+	var (
+		rest  = make([]string, 0, len(args))
+		found bool
+	)
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == flag {
+			found = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, found
+}
+
 func main() {
 	if complete(filepath.Base(os.Args[0])) {
 		return
@@ -60,19 +243,62 @@ func main() {
 
 	subCmds := commands{
 		"server": serverCmd,
+		"proxy":  proxyCmd,
+		"init":   initCmd,
 
 		"key":      keyCmd,
 		"policy":   policyCmd,
 		"identity": identityCmd,
+		"approval": approvalCmd,
+		"ssh":      sshCmd,
+		"cert":     certCmd,
+		"secret":   secretCmd,
+		"context":  contextCmd,
 
 		"log":    logCmd,
+		"watch":  watchCmd,
 		"status": statusCmd,
 		"metric": metricCmd,
-
+		"doctor": doctorCmd,
+		"compat": compatCmd,
+
+		"support-bundle": supportBundleCmd,
+		"inspect":        inspectCmd,
+		"debug":          debugCmd,
+		"benchmark":      benchmarkCmd,
+		"admin":          adminCmd,
+		"unseal":         unsealCmd,
+		"cluster":        clusterCmd,
+		"tpm":            tpmCmd,
+
+		"backup":  backupCmd,
 		"migrate": migrateCmd,
+		"apply":   applyCmd,
 		"update":  updateCmd,
 	}
 
+	args, timeout, err := parseGlobalTimeout(os.Args)
+	if err != nil {
+		cli.Fatalf("%v. See 'kes --help'", err)
+	}
+	os.Args, globalTimeout = args, timeout
+
+	args, globalContext, err = parseGlobalString(os.Args, "--context")
+	if err != nil {
+		cli.Fatalf("%v. See 'kes --help'", err)
+	}
+	os.Args = args
+	if globalContext == "" {
+		globalContext = os.Getenv(EnvContext)
+	}
+
+	os.Args, globalNoColor = parseGlobalBool(os.Args, "--no-color")
+	os.Args, globalJSON = parseGlobalBool(os.Args, "--json")
+	if globalNoColor || termenv.EnvNoColor() {
+		globalNoColor = true
+		cli.DisableColors()
+	}
+
 	if len(os.Args) < 2 {
 		cmd.Usage()
 		os.Exit(2)
@@ -125,106 +351,308 @@ func main() {
 }
 
 func newClient(insecureSkipVerify bool) *kes.Client {
-	const DefaultServer = "https://127.0.0.1:7373"
-	const (
-		EnvServer     = "KES_SERVER"
-		EnvAPIKey     = "KES_API_KEY"
-		EnvClientKey  = "KES_CLIENT_KEY"
-		EnvClientCert = "KES_CLIENT_CERT"
-	)
+	addr, cert, err := loadClientConfig()
+	if err != nil {
+		cli.Fatal(err)
+	}
+	endpoints := splitEndpoints(addr)
+	client := kes.NewClientWithConfig(endpoints[0], &tls.Config{
+		Certificates:       []tls.Certificate{cert},
+		InsecureSkipVerify: insecureSkipVerify,
+	})
+	client.Endpoints = endpoints
+	return client
+}
 
-	if apiKey, ok := os.LookupEnv(EnvAPIKey); ok {
-		if _, ok = os.LookupEnv(EnvClientCert); ok {
-			cli.Fatalf("two conflicting environment variables set: unset either '%s' or '%s'", EnvAPIKey, EnvClientCert)
+// splitEndpoints splits a comma-separated list of KES server
+// endpoints, like the value of KES_SERVER. It returns at least
+// one, possibly empty, endpoint.
+func splitEndpoints(addr string) []string {
+	var endpoints []string
+	for _, endpoint := range strings.Split(addr, ",") {
+		if endpoint = strings.TrimSuffix(strings.TrimSpace(endpoint), "/"); endpoint != "" {
+			endpoints = append(endpoints, endpoint)
 		}
-		if _, ok = os.LookupEnv(EnvClientKey); ok {
-			cli.Fatalf("two conflicting environment variables set: unset either '%s' or '%s'", EnvAPIKey, EnvClientKey)
+	}
+	if len(endpoints) == 0 {
+		return []string{""}
+	}
+	return endpoints
+}
+
+// endpointCooldown is the time an unreachable endpoint is
+// tried last, after all other endpoints.
+const endpointCooldown = 30 * time.Second
+
+// endpointState tracks the endpoint requests are sent to next
+// and the endpoints that have been unreachable recently.
+var endpointState = struct {
+	sync.Mutex
+	next int
+	down map[string]time.Time
+}{down: map[string]time.Time{}}
+
+// sendTo sends the request created for an endpoint by newRequest to
+// one of the client's endpoints, in round-robin order. Endpoints that
+// have been unreachable within the endpointCooldown are tried last.
+//
+// If an endpoint is unreachable, sendTo retries the request with the
+// next endpoint. Requests are only retried if no connection could be
+// established. Hence, a request is never processed twice.
+func sendTo(client *kes.Client, newRequest func(endpoint string) (*http.Request, error)) (*http.Response, error) {
+	endpointState.Lock()
+	n := len(client.Endpoints)
+	start := endpointState.next % n
+	endpointState.next++
+
+	endpoints := make([]string, 0, n)
+	var down []string
+	for i := range client.Endpoints {
+		endpoint := client.Endpoints[(start+i)%n]
+		if t, ok := endpointState.down[endpoint]; ok && time.Since(t) < endpointCooldown {
+			down = append(down, endpoint)
+		} else {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	endpoints = append(endpoints, down...)
+	endpointState.Unlock()
+
+	var err error
+	for _, endpoint := range endpoints {
+		var req *http.Request
+		if req, err = newRequest(endpoint); err != nil {
+			return nil, err
+		}
+
+		var resp *http.Response
+		if resp, err = client.HTTPClient.Do(req); err == nil {
+			endpointState.Lock()
+			delete(endpointState.down, endpoint)
+			endpointState.Unlock()
+			return resp, nil
 		}
-		key, err := kes.ParseAPIKey(apiKey)
+
+		var opErr *net.OpError
+		if !errors.As(err, &opErr) || opErr.Op != "dial" {
+			return nil, err
+		}
+		endpointState.Lock()
+		endpointState.down[endpoint] = time.Now()
+		endpointState.Unlock()
+	}
+	return nil, err
+}
+
+// pinEndpoint returns a client that sends all requests to the
+// endpoint. It returns the client itself if the endpoint is empty.
+func pinEndpoint(client *kes.Client, endpoint string) *kes.Client {
+	if endpoint == "" || len(client.Endpoints) == 1 {
+		return client
+	}
+	return &kes.Client{
+		Endpoints:  []string{endpoint},
+		HTTPClient: client.HTTPClient,
+	}
+}
+
+// sendGet sends a GET request for the path to one of the
+// client's endpoints.
+func sendGet(ctx context.Context, client *kes.Client, path string) (*http.Response, error) {
+	return sendTo(client, func(endpoint string) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, endpoint+path, nil)
+	})
+}
+
+// sendRequest sends a request with the JSON-encoded body, if
+// not nil, to one of the client's endpoints and decodes the
+// JSON response into v, if not nil.
+//
+// It is used for server APIs not supported by the client SDK.
+func sendRequest(ctx context.Context, client *kes.Client, method, path string, body, v any) error {
+	var b []byte
+	if body != nil {
+		var err error
+		if b, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	resp, err := sendTo(client, func(endpoint string) (*http.Request, error) {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(b)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, r)
 		if err != nil {
-			cli.Fatalf("invalid API key: %v", err)
+			return nil, err
+		}
+		if body != nil {
+			req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+		}
+		return req, nil
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusAccepted {
+		var pending api.ApprovalRequiredResponse
+		if err = json.NewDecoder(io.LimitReader(resp.Body, 10*int64(mem.KiB))).Decode(&pending); err != nil {
+			return err
+		}
+		return &approvalRequiredError{pending}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return api.ReadError(resp)
+	}
+	if v == nil {
+		return nil
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 10*int64(mem.MiB))).Decode(v)
+}
+
+// approvalRequiredError is returned by sendRequest if the server
+// only executes the request once another identity has approved it.
+type approvalRequiredError struct {
+	api.ApprovalRequiredResponse
+}
+
+func (e *approvalRequiredError) Error() string { return e.Message }
+
+// Environment variables used to configure the KES client.
+const (
+	EnvServer     = "KES_SERVER"
+	EnvAPIKey     = "KES_API_KEY"
+	EnvClientKey  = "KES_CLIENT_KEY"
+	EnvClientCert = "KES_CLIENT_CERT"
+	EnvContext    = "KES_CONTEXT"
+)
+
+// loadClientConfig returns the KES server address and the
+// client certificate of the active context, if any, or specified
+// by the environment variables.
+func loadClientConfig() (string, tls.Certificate, error) {
+	const DefaultServer = "https://127.0.0.1:7373"
+
+	name, c, err := activeContext()
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	if c != nil {
+		var cert tls.Certificate
+		if c.APIKey != "" {
+			cert, err = apiKeyCertificate(c.APIKey)
+		} else {
+			cert, err = loadClientCertificate(c.Cert, c.Key)
 		}
-		cert, err := kes.GenerateCertificate(key)
 		if err != nil {
-			cli.Fatalf("failed to generate client certificate from API key: %v", err)
+			return "", tls.Certificate{}, fmt.Errorf("context '%s': %v", name, err)
 		}
+		return c.Server, cert, nil
+	}
+
+	addr := DefaultServer
+	if env, ok := os.LookupEnv(EnvServer); ok {
+		addr = env
+	}
 
-		addr := DefaultServer
-		if env, ok := os.LookupEnv(EnvServer); ok {
-			addr = env
+	if apiKey, ok := os.LookupEnv(EnvAPIKey); ok {
+		if _, ok = os.LookupEnv(EnvClientCert); ok {
+			return "", tls.Certificate{}, fmt.Errorf("two conflicting environment variables set: unset either '%s' or '%s'", EnvAPIKey, EnvClientCert)
+		}
+		if _, ok = os.LookupEnv(EnvClientKey); ok {
+			return "", tls.Certificate{}, fmt.Errorf("two conflicting environment variables set: unset either '%s' or '%s'", EnvAPIKey, EnvClientKey)
 		}
-		return kes.NewClientWithConfig(addr, &tls.Config{
-			Certificates:       []tls.Certificate{cert},
-			InsecureSkipVerify: insecureSkipVerify,
-		})
+		cert, err := apiKeyCertificate(apiKey)
+		if err != nil {
+			return "", tls.Certificate{}, err
+		}
+		return addr, cert, nil
 	}
 
 	certPath, ok := os.LookupEnv(EnvClientCert)
 	if !ok {
-		cli.Fatalf("no TLS client certificate. Environment variable '%s' is not set", EnvClientCert)
+		return "", tls.Certificate{}, fmt.Errorf("no TLS client certificate. Environment variable '%s' is not set", EnvClientCert)
 	}
 	if strings.TrimSpace(certPath) == "" {
-		cli.Fatalf("no TLS client certificate. Environment variable '%s' is empty", EnvClientCert)
+		return "", tls.Certificate{}, fmt.Errorf("no TLS client certificate. Environment variable '%s' is empty", EnvClientCert)
 	}
 
 	keyPath, ok := os.LookupEnv(EnvClientKey)
 	if !ok {
-		cli.Fatalf("no TLS private key. Environment variable '%s' is not set", EnvClientKey)
+		return "", tls.Certificate{}, fmt.Errorf("no TLS private key. Environment variable '%s' is not set", EnvClientKey)
 	}
 	if strings.TrimSpace(keyPath) == "" {
-		cli.Fatalf("no TLS private key. Environment variable '%s' is empty", EnvClientKey)
+		return "", tls.Certificate{}, fmt.Errorf("no TLS private key. Environment variable '%s' is empty", EnvClientKey)
 	}
 
+	cert, err := loadClientCertificate(certPath, keyPath)
+	if err != nil {
+		return "", tls.Certificate{}, err
+	}
+	return addr, cert, nil
+}
+
+// apiKeyCertificate returns the client certificate of the API key.
+func apiKeyCertificate(apiKey string) (tls.Certificate, error) {
+	key, err := kes.ParseAPIKey(apiKey)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("invalid API key: %v", err)
+	}
+	cert, err := kes.GenerateCertificate(key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate client certificate from API key: %v", err)
+	}
+	return cert, nil
+}
+
+// loadClientCertificate loads the client certificate and private key
+// from the files. If the private key is encrypted, it asks the user
+// for its password.
+func loadClientCertificate(certPath, keyPath string) (tls.Certificate, error) {
 	certPem, err := os.ReadFile(certPath)
 	if err != nil {
-		cli.Fatalf("failed to load TLS certificate: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %v", err)
 	}
 	certPem, err = https.FilterPEM(certPem, func(b *pem.Block) bool { return b.Type == "CERTIFICATE" })
 	if err != nil {
-		cli.Fatalf("failed to load TLS certificate: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS certificate: %v", err)
 	}
 	keyPem, err := os.ReadFile(keyPath)
 	if err != nil {
-		cli.Fatalf("failed to load TLS private key: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS private key: %v", err)
 	}
 
 	// Check whether the private key is encrypted. If so, ask the user
 	// to enter the password on the CLI.
 	privateKey, err := decodePrivateKey(keyPem)
 	if err != nil {
-		cli.Fatalf("failed to read TLS private key: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to read TLS private key: %v", err)
 	}
 	if len(privateKey.Headers) > 0 && x509.IsEncryptedPEMBlock(privateKey) {
 		fmt.Fprint(os.Stderr, "Enter password for private key: ")
 		password, err := term.ReadPassword(int(os.Stderr.Fd()))
 		if err != nil {
-			cli.Fatalf("failed to read private key password: %v", err)
+			return tls.Certificate{}, fmt.Errorf("failed to read private key password: %v", err)
 		}
 		fmt.Fprintln(os.Stderr) // Add the newline again
 
 		decPrivateKey, err := x509.DecryptPEMBlock(privateKey, password)
 		if err != nil {
 			if errors.Is(err, x509.IncorrectPasswordError) {
-				cli.Fatalf("incorrect password")
+				return tls.Certificate{}, errors.New("incorrect password")
 			}
-			cli.Fatalf("failed to decrypt private key: %v", err)
+			return tls.Certificate{}, fmt.Errorf("failed to decrypt private key: %v", err)
 		}
 		keyPem = pem.EncodeToMemory(&pem.Block{Type: privateKey.Type, Bytes: decPrivateKey})
 	}
 
 	cert, err := tls.X509KeyPair(certPem, keyPem)
 	if err != nil {
-		cli.Fatalf("failed to load TLS private key or certificate: %v", err)
+		return tls.Certificate{}, fmt.Errorf("failed to load TLS private key or certificate: %v", err)
 	}
-
-	addr := DefaultServer
-	if env, ok := os.LookupEnv(EnvServer); ok {
-		addr = env
-	}
-	return kes.NewClientWithConfig(addr, &tls.Config{
-		Certificates:       []tls.Certificate{cert},
-		InsecureSkipVerify: insecureSkipVerify,
-	})
+	return cert, nil
 }
 
 func isTerm(f *os.File) bool { return term.IsTerminal(int(f.Fd())) }
@@ -0,0 +1,56 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+var parseGlobalStringTests = []struct {
+	Args  []string
+	Rest  []string
+	Value string
+}{
+	{ // 0
+		Args:  []string{"kes", "--context", "prod", "key", "ls"},
+		Rest:  []string{"kes", "key", "ls"},
+		Value: "prod",
+	},
+	{ // 1
+		Args:  []string{"kes", "--context=prod", "key", "ls"},
+		Rest:  []string{"kes", "key", "ls"},
+		Value: "prod",
+	},
+	{ // 2
+		Args: []string{"kes", "key", "encrypt", "--context", "aGVsbG8=", "my-key", "Hello World"},
+		Rest: []string{"kes", "key", "encrypt", "--context", "aGVsbG8=", "my-key", "Hello World"},
+	},
+	{ // 3
+		Args:  []string{"kes", "--context", "prod", "key", "encrypt", "--context", "aGVsbG8=", "my-key", "Hello World"},
+		Rest:  []string{"kes", "key", "encrypt", "--context", "aGVsbG8=", "my-key", "Hello World"},
+		Value: "prod",
+	},
+	{ // 4
+		Args:  []string{"kes", "--json", "--context=prod", "key", "decrypt", "--context=aGVsbG8=", "my-key"},
+		Rest:  []string{"kes", "--json", "key", "decrypt", "--context=aGVsbG8=", "my-key"},
+		Value: "prod",
+	},
+}
+
+func TestParseGlobalString(t *testing.T) {
+	for i, test := range parseGlobalStringTests {
+		rest, value, err := parseGlobalString(test.Args, "--context")
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse arguments: %v", i, err)
+		}
+		if value != test.Value {
+			t.Fatalf("Test %d: got value '%s' - want '%s'", i, value, test.Value)
+		}
+		if !slices.Equal(rest, test.Rest) {
+			t.Fatalf("Test %d: got args %q - want %q", i, rest, test.Rest)
+		}
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"sort"
 	"sync"
 	"time"
@@ -27,6 +26,7 @@ const metricCmdUsage = `Usage:
 
 Options:
     --rate                   Scrap rate when monitoring metrics. (default: 5s)
+    --json                   Print metrics as JSON.
 
     -k, --insecure           Skip TLS certificate validation
     -h, --help               Print command line options.
@@ -39,8 +39,10 @@ func metricCmd(args []string) {
 	var (
 		rate               time.Duration
 		insecureSkipVerify bool
+		jsonFlag           bool
 	)
 	cmd.DurationVar(&rate, "rate", 5*time.Second, "Scrap rate when monitoring metrics")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print metrics as JSON")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -53,10 +55,10 @@ func metricCmd(args []string) {
 	}
 
 	client := newClient(insecureSkipVerify)
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
-	if isTerm(os.Stdout) {
+	if isTerm(os.Stdout) && !jsonFlag {
 		traceMetricsWithUI(ctx, client, rate)
 		return
 	}
@@ -5,42 +5,73 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/signal"
 	"path/filepath"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/minio/kes"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/kesconf"
-	"github.com/minio/kms-go/kes"
+	kesdk "github.com/minio/kms-go/kes"
 	flag "github.com/spf13/pflag"
 	"golang.org/x/term"
 )
 
 const migrateCmdUsage = `Usage:
-    kes migrate [options] [<pattern>]
+    kes migrate [options] [<pattern>...]
+
+Migrates keys from one key store to another. The source and target
+can be any key store supported by the KES server. Only keys matching
+one of the patterns are migrated. By default, all keys are migrated.
 
 Options:
     --from <PATH>            Path to the KES config file of the migration source.
     --to   <PATH>            Path to the KES config file of the migration target.
 
+    -p, --pattern <PATTERN>  Only migrate keys matching the pattern. May be
+                             specified multiple times.
+
     -f, --force              Migrate keys even if a key with the same name exists
                              at the target. The existing keys will be deleted.
 
     --merge                  Merge the source into the target by only migrating
                              those keys that do not exist at the target.
 
+    --dry-run                Only print which keys would be migrated without
+                             modifying the target.
+
+    --checkpoint <PATH>      Record migrated keys in the file. A failed or
+                             interrupted migration continues where it stopped
+                             when started again with the same checkpoint file.
+                             The file is removed once all keys are migrated.
+
+    -w, --workers <N>        Number of keys migrated in parallel. (default: 4)
+
+    --watch                  Keep replicating new and changed keys from the
+                             source to the target after the migration until
+                             interrupted. Keys deleted at the source are not
+                             deleted at the target.
+    --interval <DURATION>    Time between checking the source for new and
+                             changed keys in '--watch' mode. (default: 5s)
+
     -q, --quiet              Do not print progress information.
     -h, --help               Print command line options.
 
 Examples:
     $ kes migrate --from vault-config.yml --to aws-config.yml
+    $ kes migrate --from vault-config.yml --to aws-config.yml --pattern 'my-app-*' --dry-run
+    $ kes migrate --from vault-config.yml --to aws-config.yml --checkpoint migrate.log -w 16
+    $ kes migrate --from vault-config.yml --to aws-config.yml --watch --interval 10s
 `
 
 func migrateCmd(args []string) {
@@ -48,16 +79,28 @@ func migrateCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, migrateCmdUsage) }
 
 	var (
-		fromPath  string
-		toPath    string
-		force     bool
-		merge     bool
-		quietFlag bool
+		fromPath       string
+		toPath         string
+		patterns       []string
+		force          bool
+		merge          bool
+		dryRun         bool
+		checkpointPath string
+		workers        int
+		watch          bool
+		interval       time.Duration
+		quietFlag      bool
 	)
 	cmd.StringVar(&fromPath, "from", "", "Path to the config file of the migration source")
 	cmd.StringVar(&toPath, "to", "", "Path to the config file of the migration target")
+	cmd.StringArrayVarP(&patterns, "pattern", "p", nil, "Only migrate keys matching the pattern")
 	cmd.BoolVarP(&force, "force", "f", false, "Overwrite existing keys at the migration target")
 	cmd.BoolVar(&merge, "merge", false, "Only migrate keys that don't exist at the migration target")
+	cmd.BoolVar(&dryRun, "dry-run", false, "Only print which keys would be migrated")
+	cmd.StringVar(&checkpointPath, "checkpoint", "", "Path to the checkpoint file of the migration")
+	cmd.IntVarP(&workers, "workers", "w", 4, "Number of keys migrated in parallel")
+	cmd.BoolVar(&watch, "watch", false, "Keep replicating new and changed keys until interrupted")
+	cmd.DurationVar(&interval, "interval", 5*time.Second, "Time between checking the source for changes")
 	cmd.BoolVarP(&quietFlag, "quiet", "q", false, "Do not print progress information")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
@@ -65,9 +108,6 @@ func migrateCmd(args []string) {
 		}
 		cli.Fatalf("%v. See 'kes migrate --help'", err)
 	}
-	if cmd.NArg() > 1 {
-		cli.Fatal("too many arguments. See 'kes migrate --help'")
-	}
 	if fromPath == "" {
 		cli.Fatal("no migration source specified. Use '--from' to specify a config file")
 	}
@@ -77,107 +117,442 @@ func migrateCmd(args []string) {
 	if force && merge {
 		cli.Fatal("mutually exclusive options '--force' and '--merge' specified")
 	}
+	if workers < 1 {
+		cli.Fatalf("invalid number of workers '%d'. See 'kes migrate --help'", workers)
+	}
+	if dryRun && checkpointPath != "" {
+		cli.Fatal("mutually exclusive options '--dry-run' and '--checkpoint' specified")
+	}
+	if dryRun && watch {
+		cli.Fatal("mutually exclusive options '--dry-run' and '--watch' specified")
+	}
+	if interval <= 0 {
+		cli.Fatalf("invalid interval '%v'. See 'kes migrate --help'", interval)
+	}
 
-	quiet := quiet(quietFlag)
-	pattern := cmd.Arg(0)
-	if pattern == "" {
-		pattern = "*"
+	patterns = append(patterns, cmd.Args()...)
+	if len(patterns) == 0 {
+		patterns = []string{"*"}
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			cli.Fatalf("invalid pattern '%s': %v", pattern, err)
+		}
 	}
+	quiet := quiet(quietFlag)
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Kill, os.Interrupt)
+	ctx, cancel := newContext()
 	defer cancel()
 
 	sourceConfig, err := kesconf.ReadFile(fromPath)
 	if err != nil {
 		cli.Fatalf("failed to read '--from' config file: %v", err)
 	}
-
+	if sourceConfig.KeyStore == nil {
+		cli.Fatal("'--from' config file contains no key store")
+	}
 	targetConfig, err := kesconf.ReadFile(toPath)
 	if err != nil {
 		cli.Fatalf("failed to read '--to' config file: %v", err)
 	}
+	if targetConfig.KeyStore == nil {
+		cli.Fatal("'--to' config file contains no key store")
+	}
 
 	src, err := sourceConfig.KeyStore.Connect(ctx)
 	if err != nil {
 		cli.Fatal(err)
 	}
+	defer src.Close()
 	dst, err := targetConfig.KeyStore.Connect(ctx)
 	if err != nil {
 		cli.Fatal(err)
 	}
+	defer dst.Close()
 
+	var checkpoint *migrateCheckpoint
+	if checkpointPath != "" {
+		if checkpoint, err = openMigrateCheckpoint(checkpointPath); err != nil {
+			cli.Fatalf("failed to open checkpoint file: %v", err)
+		}
+		defer checkpoint.Close()
+	}
+
+	// First, we list all keys at the source that match one of the
+	// patterns and have not been migrated before. Knowing the total
+	// number of keys allows us to show the migration progress.
 	var (
-		n        uint64
-		uiTicker = time.NewTicker(100 * time.Millisecond)
+		names    []string
+		skipped  int
+		iterator = &kesdk.ListIter[string]{NextFunc: src.List}
 	)
-	defer uiTicker.Stop()
+	for {
+		name, err := iterator.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			quiet.ClearLine()
+			cli.Fatalf("failed to list keys: %v", err)
+		}
+		if !matchAny(patterns, name) {
+			continue
+		}
+		if checkpoint.Contains(name) {
+			skipped++
+			continue
+		}
+		names = append(names, name)
+	}
+	if skipped > 0 {
+		quiet.Printf("Skipping %d keys migrated before according to '%s'\n", skipped, checkpointPath)
+	}
 
-	// Now, we start listing the keys at the source.
-	iterator := &kes.ListIter[string]{
-		NextFunc: src.List,
+	if dryRun {
+		dryRunMigration(ctx, dst, names, force, merge)
+		return
 	}
 
+	var (
+		n, ignored atomic.Uint64
+		uiTicker   = time.NewTicker(100 * time.Millisecond)
+		start      = time.Now()
+	)
+	defer uiTicker.Stop()
+
 	// Then, we start the UI which prints how many keys have
 	// been migrated in fixed time intervals.
+	uiCtx, uiCancel := context.WithCancel(ctx)
 	go func() {
 		for {
 			select {
 			case <-uiTicker.C:
-				msg := fmt.Sprintf("Migrated keys: %d", atomic.LoadUint64(&n))
+				msg := fmt.Sprintf("Migrated keys: %d/%d", n.Load(), len(names)-int(ignored.Load()))
 				quiet.ClearMessage(msg)
 				quiet.Print(msg)
-			case <-ctx.Done():
+			case <-uiCtx.Done():
 				return
 			}
 		}
 	}()
 
-	// Finally, we start the actual migration.
-	for {
-		name, err := iterator.Next(ctx)
-		if err == io.EOF {
+	// Finally, we start the actual migration. The workers stop on
+	// the first error. All keys migrated up to this point remain
+	// at the target and are recorded in the checkpoint, if any.
+	var (
+		wg      sync.WaitGroup
+		errOnce sync.Once
+		failure error
+		queue   = make(chan string)
+	)
+	migrateCtx, migrateCancel := context.WithCancel(ctx)
+	defer migrateCancel()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				migrated, err := migrateKey(migrateCtx, src, dst, name, force, merge)
+				if err == nil && checkpoint != nil {
+					err = checkpoint.Add(name)
+				}
+				if err != nil {
+					errOnce.Do(func() {
+						failure = fmt.Errorf("failed to migrate '%s': %v", name, err)
+						migrateCancel()
+					})
+					continue
+				}
+				if migrated {
+					n.Add(1)
+				} else {
+					ignored.Add(1)
+				}
+			}
+		}()
+	}
+	for _, name := range names {
+		if migrateCtx.Err() != nil {
 			break
 		}
-		if err != nil {
-			quiet.ClearLine()
-			cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+	uiCancel()
+
+	if ctx.Err() != nil {
+		failure = errors.New("migration interrupted")
+	}
+	if failure != nil {
+		quiet.ClearLine()
+		if checkpoint != nil {
+			cli.Fatalf("%v\nMigrated keys: %d. Use '--checkpoint %s' to continue the migration", failure, n.Load(), checkpointPath)
+		}
+		cli.Fatalf("%v\nMigrated keys: %d", failure, n.Load())
+	}
+	if checkpoint != nil {
+		if err = checkpoint.Remove(); err != nil {
+			cli.Fatalf("failed to remove checkpoint file: %v", err)
 		}
+	}
 
-		if ok, _ := filepath.Match(pattern, name); !ok {
-			continue
+	// At the end we show how many keys we have migrated successfully.
+	msg := fmt.Sprintf("Migrated keys: %d in %v ", n.Load(), time.Since(start).Round(time.Millisecond))
+	quiet.ClearMessage(msg)
+	quiet.Println(msg)
+
+	if watch {
+		watchMigration(ctx, src, dst, patterns, interval, merge, quiet)
+	}
+}
+
+// watchMigration replicates new and changed keys from src to dst
+// until ctx is canceled. It checks the source for changes once per
+// interval.
+//
+// A key is considered new if it has not been seen by watchMigration
+// before. It is replicated unless it exists at the target with the
+// same value. If it exists with a different value, it is replaced
+// unless merge is true. Keys that have been seen before are replaced
+// whenever their value at the source changes.
+func watchMigration(ctx context.Context, src, dst kes.KeyStore, patterns []string, interval time.Duration, merge bool, quiet quiet) {
+	var (
+		n    int
+		seen = map[string][sha256.Size]byte{}
+		msg  string
+	)
+	printStatus := func(format string, v ...any) {
+		quiet.ClearMessage(msg)
+		msg = fmt.Sprintf(format, v...)
+		quiet.Print(msg)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		iterator := &kesdk.ListIter[string]{NextFunc: src.List}
+		for {
+			name, err := iterator.Next(ctx)
+			if err == io.EOF {
+				break
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				printStatus("Replicated keys: %d. Failed to list keys: %v", n, err)
+				break
+			}
+			if !matchAny(patterns, name) {
+				continue
+			}
+
+			replicated, err := replicateKey(ctx, src, dst, name, seen, merge)
+			if ctx.Err() != nil {
+				break
+			}
+			if err != nil {
+				printStatus("Replicated keys: %d. Failed to replicate '%s': %v", n, name, err)
+				continue
+			}
+			if replicated {
+				n++
+			}
+		}
+		if ctx.Err() == nil {
+			printStatus("Replicated keys: %d. Last checked at %s", n, time.Now().Format(time.TimeOnly))
+		}
+
+		select {
+		case <-ctx.Done():
+			msg := fmt.Sprintf("Replicated keys: %d ", n)
+			quiet.ClearMessage(msg)
+			quiet.Println(msg)
+			return
+		case <-ticker.C:
 		}
+	}
+}
+
+// replicateKey replicates the key with the given name from src
+// to dst if it is new or has changed since it has been seen last.
+// It records the checksum of the source value in seen and reports
+// whether the key has been replicated.
+func replicateKey(ctx context.Context, src, dst kes.KeyStore, name string, seen map[string][sha256.Size]byte, merge bool) (bool, error) {
+	key, err := src.Get(ctx, name)
+	if errors.Is(err, kesdk.ErrKeyNotFound) {
+		delete(seen, name) // Deleted after listing
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
 
-		key, err := src.Get(ctx, name)
+	sum := sha256.Sum256(key)
+	if prev, ok := seen[name]; ok && prev == sum {
+		return false, nil
+	}
+	_, changed := seen[name]
+
+	err = dst.Create(ctx, name, key)
+	if errors.Is(err, kesdk.ErrKeyExists) && !changed {
+		// The key has not been seen before. Usually, it has been
+		// migrated already. Otherwise, it only gets replaced if it
+		// differs and merge is false.
+		existing, err := dst.Get(ctx, name)
 		if err != nil {
-			quiet.ClearLine()
-			cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
+			return false, err
+		}
+		if merge || sha256.Sum256(existing) == sum {
+			seen[name] = sum
+			return false, nil
 		}
+	}
+	if errors.Is(err, kesdk.ErrKeyExists) {
+		if err = dst.Delete(ctx, name); err != nil {
+			return false, err
+		}
+		err = dst.Create(ctx, name, key)
+	}
+	if err != nil {
+		return false, err
+	}
+	seen[name] = sum
+	return true, nil
+}
+
+// migrateKey migrates the key with the given name from src to
+// dst. It reports whether the key has been migrated or ignored
+// since it exists at the target and merge is true.
+func migrateKey(ctx context.Context, src, dst kes.KeyStore, name string, force, merge bool) (bool, error) {
+	key, err := src.Get(ctx, name)
+	if err != nil {
+		return false, err
+	}
 
+	err = dst.Create(ctx, name, key)
+	if merge && errors.Is(err, kesdk.ErrKeyExists) {
+		return false, nil
+	}
+	if force && errors.Is(err, kesdk.ErrKeyExists) { // Try to overwrite the key
+		if err = dst.Delete(ctx, name); err != nil {
+			return false, err
+		}
 		err = dst.Create(ctx, name, key)
-		if merge && errors.Is(err, kes.ErrKeyExists) {
-			continue // Do not increment the counter since we skip this key
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// dryRunMigration prints, for each key, whether it would be
+// created, overwritten or skipped at the migration target, or
+// whether the migration would fail since the key exists.
+func dryRunMigration(ctx context.Context, dst kes.KeyStore, names []string, force, merge bool) {
+	var create, overwrite, skip, conflict int
+	for _, name := range names {
+		_, err := dst.Get(ctx, name)
+		switch {
+		case errors.Is(err, kesdk.ErrKeyNotFound):
+			create++
+			fmt.Println("create   ", name)
+		case err != nil:
+			cli.Fatalf("failed to check '%s' at the migration target: %v", name, err)
+		case force:
+			overwrite++
+			fmt.Println("overwrite", name)
+		case merge:
+			skip++
+			fmt.Println("skip     ", name)
+		default:
+			conflict++
+			fmt.Println("conflict ", name)
 		}
-		if force && errors.Is(err, kes.ErrKeyExists) { // Try to overwrite the key
-			if err = dst.Delete(ctx, name); err != nil {
-				quiet.ClearLine()
-				cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
-			}
-			err = dst.Create(ctx, name, key)
+	}
+	fmt.Printf("\nDry run: %d to create, %d to overwrite, %d to skip, %d conflicts\n", create, overwrite, skip, conflict)
+	if conflict > 0 {
+		cli.Fatalf("%d keys exist at the migration target. Use '--force' or '--merge'", conflict)
+	}
+}
+
+// matchAny reports whether name matches any of the patterns.
+// Entries holding revisions of a key match if the key matches.
+func matchAny(patterns []string, name string) bool {
+	if key, ok := keystore.RevisionOf(name); ok {
+		name = key
+	}
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
 		}
-		if err != nil {
-			quiet.ClearLine()
-			cli.Fatalf("failed to migrate %q: %v\nMigrated keys: %d", name, err, atomic.LoadUint64(&n))
+	}
+	return false
+}
+
+// migrateCheckpoint records the names of migrated keys in
+// a file, one per line, such that a migration can continue
+// where it stopped. A nil migrateCheckpoint contains no keys.
+type migrateCheckpoint struct {
+	file *os.File
+
+	lock     sync.Mutex
+	migrated map[string]struct{}
+}
+
+// openMigrateCheckpoint opens or creates the checkpoint file
+// and reads the names of all keys migrated before.
+func openMigrateCheckpoint(filename string) (*migrateCheckpoint, error) {
+	file, err := os.OpenFile(filename, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	migrated := map[string]struct{}{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := scanner.Text(); name != "" {
+			migrated[name] = struct{}{}
 		}
-		atomic.AddUint64(&n, 1)
 	}
-	cancel()
+	if err = scanner.Err(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &migrateCheckpoint{
+		file:     file,
+		migrated: migrated,
+	}, nil
+}
 
-	// At the end we show how many keys we have migrated successfully.
-	msg := fmt.Sprintf("Migrated keys: %d ", atomic.LoadUint64(&n))
-	quiet.ClearMessage(msg)
-	quiet.Println(msg)
+// Contains reports whether the key has been migrated before.
+func (c *migrateCheckpoint) Contains(name string) bool {
+	if c == nil {
+		return false
+	}
+	_, ok := c.migrated[name]
+	return ok
+}
+
+// Add records the key as migrated.
+func (c *migrateCheckpoint) Add(name string) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_, err := c.file.WriteString(name + "\n")
+	return err
+}
+
+// Remove closes and removes the checkpoint file.
+func (c *migrateCheckpoint) Remove() error {
+	c.file.Close()
+	return os.Remove(c.file.Name())
 }
 
+// Close closes the checkpoint file.
+func (c *migrateCheckpoint) Close() error { return c.file.Close() }
+
 // quiet is a boolean flag.Value that can print
 // to STDOUT.
 //
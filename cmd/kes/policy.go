@@ -10,12 +10,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
-	"os/signal"
+	"slices"
 	"strings"
 	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kms-go/kes"
 	flag "github.com/spf13/pflag"
@@ -25,9 +28,13 @@ const policyCmdUsage = `Usage:
     kes policy <command>
 
 Commands:
+    create                   Create a new policy.
+    assign                   Assign a policy to identities.
     info                     Get information about a policy.
     ls                       List policies.
+    rm                       Delete a policy.
     show                     Display a policy.
+    test                     Test whether an identity may call an API.
 
 Options:
     -h, --help               Print command line options.
@@ -38,9 +45,13 @@ func policyCmd(args []string) {
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, policyCmdUsage) }
 
 	subCmds := commands{
-		"info": infoPolicyCmd,
-		"ls":   lsPolicyCmd,
-		"show": showPolicyCmd,
+		"create": createPolicyCmd,
+		"assign": assignPolicyCmd,
+		"info":   infoPolicyCmd,
+		"ls":     lsPolicyCmd,
+		"rm":     rmPolicyCmd,
+		"show":   showPolicyCmd,
+		"test":   testPolicyCmd,
 	}
 	if len(args) < 2 {
 		cmd.Usage()
@@ -63,23 +74,236 @@ func policyCmd(args []string) {
 	os.Exit(2)
 }
 
+const createPolicyCmdUsage = `Usage:
+    kes policy create [options] <policy>
+
+Creates a new policy with the given allow and deny rules. Each
+rule is an API path pattern, e.g. '/v1/key/create/my-app-*'.
+
+The policy is kept when the server reloads its configuration.
+It is only persisted across restarts if the server has a policy
+store. Policies of the server config cannot be replaced.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --allow <pattern>    Allow requests to matching API paths.
+                             May be specified multiple times.
+        --deny <pattern>     Deny requests to matching API paths.
+                             May be specified multiple times.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy create my-app --allow '/v1/key/create/my-app-*' --allow '/v1/key/generate/my-app-*'
+`
+
+func createPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, createPolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		allowFlag          []string
+		denyFlag           []string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringArrayVar(&allowFlag, "allow", nil, "Allow requests to matching API paths")
+	cmd.StringArrayVar(&denyFlag, "deny", nil, "Deny requests to matching API paths")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy create --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no policy name specified. See 'kes policy create --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes policy create --help'")
+	case len(allowFlag) == 0 && len(denyFlag) == 0:
+		cli.Fatal("no allow or deny rule specified. See 'kes policy create --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	create := api.CreatePolicyRequest{
+		Allow: allowFlag,
+		Deny:  denyFlag,
+	}
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathPolicyCreate+name, create, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to create policy '%s': %v", name, err)
+	}
+}
+
+const assignPolicyCmdUsage = `Usage:
+    kes policy assign [options] <policy> [<identity>...]
+
+Assigns the policy to all identities at once. Identities that
+are already assigned to another policy get reassigned.
+
+The assignment is kept when the server reloads its configuration.
+It is only persisted across restarts if the server has a policy
+store.
+
+With --expiry, the assignment expires after the given duration.
+Afterwards, the server rejects all requests of the identities and
+revokes them. This is useful for short-lived credentials, like
+the ones of contractors or CI pipelines.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --from <policy>      Assign the policy to all identities of
+                             the given policy.
+        --expiry <duration>  Expire the assignment after the duration,
+                             e.g. 720h.
+        --json               Print assigned identities in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy assign my-policy 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+    $ kes policy assign --from my-old-policy my-policy
+    $ kes policy assign --expiry 720h my-policy 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22
+`
+
+func assignPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, assignPolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		jsonFlag           bool
+		fromFlag           string
+		expiryFlag         time.Duration
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print assigned identities in JSON format")
+	cmd.StringVar(&fromFlag, "from", "", "Assign the policy to all identities of the given policy")
+	cmd.DurationVar(&expiryFlag, "expiry", 0, "Expire the assignment after the duration")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy assign --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no policy name specified. See 'kes policy assign --help'")
+	case cmd.NArg() == 1 && fromFlag == "":
+		cli.Fatal("no identity specified. See 'kes policy assign --help'")
+	case expiryFlag < 0:
+		cli.Fatal("invalid expiry: must not be negative. See 'kes policy assign --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	assign := api.AssignPolicyRequest{
+		Identities: cmd.Args()[1:],
+		FromPolicy: fromFlag,
+	}
+	if expiryFlag > 0 {
+		assign.TTL = expiryFlag.String()
+	}
+	var resp api.AssignPolicyResponse
+	err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathPolicyAssign+name, assign, &resp)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to assign policy '%s': %v", name, err)
+	}
+
+	if jsonFlag {
+		if err = json.NewEncoder(os.Stdout).Encode(resp.Identities); err != nil {
+			cli.Fatalf("failed to assign policy '%s': %v", name, err)
+		}
+		return
+	}
+	if expiryFlag > 0 {
+		fmt.Printf("Assigned policy '%s' to %d identities for %v\n", name, len(resp.Identities), expiryFlag)
+		return
+	}
+	fmt.Printf("Assigned policy '%s' to %d identities\n", name, len(resp.Identities))
+}
+
+const rmPolicyCmdUsage = `Usage:
+    kes policy rm [options] <policy>...
+
+Deletes policies created via 'kes policy create' and revokes
+all identities assigned to them. Policies of the server config
+cannot be deleted.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy rm my-app
+`
+
+func rmPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmPolicyCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy rm --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no policy name specified. See 'kes policy rm --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		if err := sendRequest(ctx, client, http.MethodDelete, api.PathPolicyDelete+name, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to delete policy '%s': %v", name, err)
+		}
+	}
+}
+
 const lsPolicyCmdUsage = `Usage:
     kes policy ls [options] [<pattern>]
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print policies in JSON format.
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
                              the output goes to a pipe.
                              Possible values: *auto*, never, always.
+    -l, --long               List policies with their creation date
+                             and creator.
+        --created-after <t>  Only list policies created after the RFC 3339
+                             timestamp or date, e.g. '2024-01-31'.
+        --sort <field>       Sort policies by the field.
+                             Possible values: *name*, created.
 
     -h, --help               Print command line options.
 
 Examples:
     $ kes policy ls
     $ kes policy ls 'my-policy*'
+    $ kes policy ls --long --sort created
 `
 
 func lsPolicyCmd(args []string) {
@@ -88,12 +312,20 @@ func lsPolicyCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
+		longFlag           bool
+		createdAfterFlag   string
+		sortFlag           string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print identities in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print identities in JSON format")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVarP(&longFlag, "long", "l", false, "List policies with their metadata")
+	cmd.StringVar(&createdAfterFlag, "created-after", "", "Only list policies created after the time")
+	cmd.StringVar(&sortFlag, "sort", "name", "Sort policies by the field")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -101,6 +333,9 @@ func lsPolicyCmd(args []string) {
 		cli.Fatalf("%v. See 'kes policy ls --help'", err)
 	}
 
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes policy ls --help'")
+	}
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes policy ls --help'")
 	}
@@ -110,9 +345,33 @@ func lsPolicyCmd(args []string) {
 		prefix = cmd.Arg(0)
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
+	if longFlag || createdAfterFlag != "" || cmd.Changed("sort") {
+		var compare func(a, b api.DescribePolicyResponse) int
+		switch sortFlag {
+		case "name":
+			compare = func(a, b api.DescribePolicyResponse) int { return strings.Compare(a.Name, b.Name) }
+		case "created":
+			compare = func(a, b api.DescribePolicyResponse) int { return a.CreatedAt.Compare(b.CreatedAt) }
+		default:
+			cli.Fatalf("invalid sort field '%s'. See 'kes policy ls --help'", sortFlag)
+		}
+		query := url.Values{}
+		if createdAfterFlag != "" {
+			query.Set("created_after", createdAfterFlag)
+		}
+
+		var resp api.ListPolicyInfosResponse
+		if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathPolicyListInfo+prefix+"?"+query.Encode(), nil, &resp); err != nil {
+			cli.Fatalf("failed to list policies: %v", err)
+		}
+		slices.SortStableFunc(resp.Policies, compare)
+		printPolicyInfos(resp.Policies, longFlag, jsonFlag, formatFlag, colorFlag)
+		return
+	}
+
 	enclave := newClient(insecureSkipVerify)
 	iter := &kes.ListIter[string]{
 		NextFunc: enclave.ListPolicies,
@@ -126,10 +385,24 @@ func lsPolicyCmd(args []string) {
 		names = append(names, id)
 	}
 
+	if formatFlag.IsSet() {
+		items := make([]any, 0, len(names))
+		for _, v := range names {
+			items = append(items, struct{ Name string }{v})
+		}
+		if err := formatFlag.Print(items...); err != nil {
+			cli.Fatalf("failed to list policies: %v", err)
+		}
+		return
+	}
 	if jsonFlag {
+		if names == nil {
+			names = []string{}
+		}
 		if err := json.NewEncoder(os.Stdout).Encode(names); err != nil {
 			cli.Fatalf("failed to list keys: %v", err)
 		}
+		return
 	}
 	if len(names) == 0 {
 		return
@@ -139,7 +412,7 @@ func lsPolicyCmd(args []string) {
 		style = tui.NewStyle().Underline(colorFlag.Colorize())
 		buf   = &strings.Builder{}
 	)
-	fmt.Fprintln(buf, style.Render("Key"))
+	fmt.Fprintln(buf, style.Render("Policy"))
 	for _, name := range names {
 		buf.WriteString(name)
 		buf.WriteByte('\n')
@@ -147,12 +420,73 @@ func lsPolicyCmd(args []string) {
 	fmt.Print(buf)
 }
 
+// printPolicyInfos prints the policies either as table, as JSON
+// or using the format template. The table only contains the
+// policies' metadata if long is true.
+func printPolicyInfos(policies []api.DescribePolicyResponse, long, jsonFlag bool, formatFlag formatOption, colorFlag colorOption) {
+	if formatFlag.IsSet() {
+		items := make([]any, 0, len(policies))
+		for _, policy := range policies {
+			items = append(items, policy)
+		}
+		if err := formatFlag.Print(items...); err != nil {
+			cli.Fatalf("failed to print policies: %v", err)
+		}
+		return
+	}
+	if jsonFlag {
+		if policies == nil {
+			policies = []api.DescribePolicyResponse{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(policies); err != nil {
+			cli.Fatalf("failed to print policies: %v", err)
+		}
+		return
+	}
+	if len(policies) == 0 {
+		return
+	}
+
+	var (
+		style = tui.NewStyle().Underline(colorFlag.Colorize())
+		buf   = &strings.Builder{}
+		width = len("Policy")
+	)
+	if !long {
+		fmt.Fprintln(buf, style.Render("Policy"))
+		for _, policy := range policies {
+			buf.WriteString(policy.Name)
+			buf.WriteByte('\n')
+		}
+		fmt.Print(buf)
+		return
+	}
+
+	for _, policy := range policies {
+		width = max(width, len(policy.Name))
+	}
+	fmt.Fprintf(buf, "%s%s  %s%s  %s\n",
+		style.Render("Policy"), strings.Repeat(" ", width-len("Policy")),
+		style.Render("Date"), strings.Repeat(" ", len(time.DateTime)-len("Date")),
+		style.Render("Created By"),
+	)
+	for _, policy := range policies {
+		fmt.Fprintf(buf, "%-*s  %s  %s\n",
+			width, policy.Name,
+			policy.CreatedAt.Local().Format(time.DateTime),
+			policy.CreatedBy,
+		)
+	}
+	fmt.Print(buf)
+}
+
 const infoPolicyCmdUsage = `Usage:
     kes policy info [options] <name>
 
 Options:
     -k, --insecure           Skip TLS certificate validation.
         --json               Print policy in JSON format.
+        --format <template>  Print output using a Go template.
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -172,11 +506,13 @@ func infoPolicyCmd(args []string) {
 
 	var (
 		jsonFlag           bool
+		formatFlag         formatOption
 		colorFlag          colorOption
 		insecureSkipVerify bool
 		enclaveName        string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print policy in JSON format.")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print policy in JSON format.")
+	cmd.Var(&formatFlag, "format", "Print output using a Go template")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
@@ -186,11 +522,14 @@ func infoPolicyCmd(args []string) {
 		}
 		cli.Fatalf("%v. See 'kes policy show --help'", err)
 	}
+	if jsonFlag && formatFlag.IsSet() {
+		cli.Fatal("'--json' and '--format' cannot be used together. See 'kes policy info --help'")
+	}
 	if cmd.NArg() == 0 {
 		cli.Fatal("no policy name specified. See 'kes policy show --help'")
 	}
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	name := cmd.Arg(0)
@@ -202,6 +541,12 @@ func infoPolicyCmd(args []string) {
 		}
 		cli.Fatal(err)
 	}
+	if formatFlag.IsSet() {
+		if err = formatFlag.Print(info); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
 	if jsonFlag {
 		encoder := json.NewEncoder(os.Stdout)
 		if isTerm(os.Stdout) {
@@ -255,7 +600,7 @@ func showPolicyCmd(args []string) {
 		jsonFlag           bool
 		enclaveName        string
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print policy in JSON format.")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print policy in JSON format.")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.StringVarP(&enclaveName, "enclave", "e", "", "Operate within the specified enclave")
 	if err := cmd.Parse(args[1:]); err != nil {
@@ -271,7 +616,7 @@ func showPolicyCmd(args []string) {
 	name := cmd.Arg(0)
 	client := newClient(insecureSkipVerify)
 
-	ctx, cancelCtx := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancelCtx := newContext()
 	defer cancelCtx()
 
 	policy, err := client.GetPolicy(ctx, name)
@@ -339,3 +684,103 @@ func showPolicyCmd(args []string) {
 		}
 	}
 }
+
+const testPolicyCmdUsage = `Usage:
+    kes policy test [options] <identity> <api-path>
+
+Tests whether the identity would be allowed to call the API path
+under the policies currently loaded by the server. It prints the
+identity's policy and the allow and deny patterns that match the
+path. Deny patterns take precedence over allow patterns.
+
+The API is not called.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print result in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes policy test 3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22 /v1/key/create/my-key
+`
+
+func testPolicyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, testPolicyCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print result in JSON format.")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes policy test --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no identity specified. See 'kes policy test --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no API path specified. See 'kes policy test --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes policy test --help'")
+	}
+
+	identity, path := cmd.Arg(0), cmd.Arg(1)
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.TestPolicyResponse
+	client := newClient(insecureSkipVerify)
+	query := url.Values{"path": []string{path}}
+	if err := sendRequest(ctx, client, http.MethodGet, api.PathPolicyTest+identity+"?"+query.Encode(), nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to test policy: %v", err)
+	}
+
+	if !isTerm(os.Stdout) || jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	result := "denied"
+	if resp.Allowed {
+		result = "allowed"
+	}
+	fmt.Printf("%-8s %s\n", "Result", result)
+	switch {
+	case resp.IsAdmin:
+		fmt.Printf("%-8s %s\n", "Reason", "identity is the admin")
+	case resp.Policy == "":
+		fmt.Printf("%-8s %s\n", "Reason", "identity has no policy")
+	default:
+		fmt.Printf("%-8s %s\n", "Policy", resp.Policy)
+		if resp.Allow != "" {
+			fmt.Printf("%-8s %s\n", "Allow", resp.Allow)
+		}
+		if resp.KeyRule != "" {
+			fmt.Printf("%-8s %s\n", "Key rule", resp.KeyRule)
+		}
+		if resp.Deny != "" {
+			fmt.Printf("%-8s %s\n", "Deny", resp.Deny)
+		}
+		if resp.Allow == "" && resp.KeyRule == "" && resp.Deny == "" {
+			fmt.Printf("%-8s %s\n", "Reason", "no allow pattern or key rule matches")
+		}
+		if resp.Allowed && resp.Conditional {
+			fmt.Printf("%-8s %s\n", "Note", "policy conditions, like source IPs, are checked per request")
+		}
+	}
+}
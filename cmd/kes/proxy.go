@@ -0,0 +1,220 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/proxy"
+	flag "github.com/spf13/pflag"
+)
+
+const proxyCmdUsage = `Usage:
+    kes proxy [options] <endpoint>...
+
+Options:
+    --addr <[ip]:port>       The network interface the proxy will listen on.
+                             (default: 0.0.0.0:7373)
+
+    --cert <file>            Path to the TLS certificate the proxy serves to clients.
+    --key <file>             Path to the TLS private key of the proxy.
+
+    --client-cert <file>     Path to the TLS client certificate the proxy uses to
+                             connect to the KES servers. Its identity must be a TLS
+                             proxy identity of the KES servers. Defaults to --cert.
+    --client-key <file>      Path to the TLS client private key. Defaults to --key.
+    --ca <file>              Path to the CA certificate(s) used to verify the KES
+                             server certificates.
+    -k, --insecure           Skip TLS certificate validation of the KES servers.
+
+    --cache <op>:<pattern>   Cache the responses of the 'generate' or 'decrypt'
+                             operation for all keys matching the glob pattern.
+                             May be specified multiple times.
+    --cache-ttl <duration>   Duration cached responses are served for. Policy
+                             changes apply after this duration. (default: 5m)
+    --cache-size <n>         Max. number of cached responses. (default: 10000)
+
+    -h, --help               Print command line options.
+
+The proxy terminates client mTLS connections and forwards requests to
+the KES servers, round-robin. It forwards the client certificate, such
+that the KES servers authenticate and authorize the actual client.
+Hence, the KES servers must list the proxy identity as TLS proxy.
+
+Cached responses are only served to the client identity that sent the
+original request with the same request body. Caching 'generate' hands
+out the same data key for identical requests within the TTL. Deleting
+or rotating a key through the proxy invalidates its cached responses.
+
+Examples:
+  1. Start a proxy in front of two KES servers that caches decrypt responses.
+     $ kes proxy --cert proxy.crt --key proxy.key --cache 'decrypt:*' \
+           https://kes-1.eu-west-1.example.com:7373 https://kes-2.eu-west-1.example.com:7373
+
+  2. Start a proxy that caches generate and decrypt responses of MinIO keys for 1 minute.
+     $ kes proxy --cert proxy.crt --key proxy.key --cache 'generate:minio-*' --cache 'decrypt:minio-*' \
+           --cache-ttl 1m https://kes.example.com:7373
+`
+
+func proxyCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, proxyCmdUsage) }
+
+	var (
+		addrFlag       string
+		certFlag       string
+		keyFlag        string
+		clientCertFlag string
+		clientKeyFlag  string
+		caFlag         string
+		insecureFlag   bool
+		cacheFlags     []string
+		cacheTTLFlag   time.Duration
+		cacheSizeFlag  int
+	)
+	cmd.StringVar(&addrFlag, "addr", "0.0.0.0:7373", "The address of the proxy")
+	cmd.StringVar(&certFlag, "cert", "", "Path to the TLS certificate")
+	cmd.StringVar(&keyFlag, "key", "", "Path to the TLS private key")
+	cmd.StringVar(&clientCertFlag, "client-cert", "", "Path to the TLS client certificate")
+	cmd.StringVar(&clientKeyFlag, "client-key", "", "Path to the TLS client private key")
+	cmd.StringVar(&caFlag, "ca", "", "Path to the CA certificate(s) of the KES servers")
+	cmd.BoolVarP(&insecureFlag, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringArrayVar(&cacheFlags, "cache", nil, "Cache responses of an operation for matching keys")
+	cmd.DurationVar(&cacheTTLFlag, "cache-ttl", 5*time.Minute, "Duration cached responses are served for")
+	cmd.IntVar(&cacheSizeFlag, "cache-size", 10000, "Max. number of cached responses")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes proxy --help'", err)
+	}
+
+	if cmd.NArg() == 0 {
+		cli.Fatal("no KES server endpoint specified. See 'kes proxy --help'")
+	}
+	if certFlag == "" || keyFlag == "" {
+		cli.Fatal("'--cert' and '--key' are required. See 'kes proxy --help'")
+	}
+	if (clientCertFlag == "") != (clientKeyFlag == "") {
+		cli.Fatal("'--client-cert' and '--client-key' must be specified together")
+	}
+	if clientCertFlag == "" {
+		clientCertFlag, clientKeyFlag = certFlag, keyFlag
+	}
+	if cacheTTLFlag <= 0 {
+		cli.Fatal("'--cache-ttl' must be positive")
+	}
+	if cacheSizeFlag <= 0 {
+		cli.Fatal("'--cache-size' must be positive")
+	}
+
+	rules := make([]proxy.Rule, 0, len(cacheFlags))
+	for _, s := range cacheFlags {
+		rule, err := proxy.ParseRule(s)
+		if err != nil {
+			cli.Fatal(strings.TrimPrefix(err.Error(), "proxy: "))
+		}
+		rules = append(rules, rule)
+	}
+
+	cert, err := https.CertificateFromFile(certFlag, keyFlag, "")
+	if err != nil {
+		cli.Fatalf("failed to load TLS certificate: %v", err)
+	}
+	clientCert, err := https.CertificateFromFile(clientCertFlag, clientKeyFlag, "")
+	if err != nil {
+		cli.Fatalf("failed to load TLS client certificate: %v", err)
+	}
+	clientConf := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		Certificates:       []tls.Certificate{clientCert},
+		InsecureSkipVerify: insecureFlag,
+	}
+	if caFlag != "" {
+		if clientConf.RootCAs, err = https.CertPoolFromFile(caFlag); err != nil {
+			cli.Fatalf("failed to load CA certificates: %v", err)
+		}
+	}
+
+	p, err := proxy.New(&proxy.Config{
+		Endpoints:  cmd.Args(),
+		TLS:        clientConf,
+		Rules:      rules,
+		TTL:        cacheTTLFlag,
+		MaxEntries: cacheSizeFlag,
+	})
+	if err != nil {
+		cli.Fatal(strings.TrimPrefix(err.Error(), "proxy: "))
+	}
+
+	srv := &http.Server{
+		Addr:    addrFlag,
+		Handler: p,
+		TLSConfig: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequireAnyClientCert, // The KES servers verify the client certificates
+		},
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	defer cancel()
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- srv.ListenAndServeTLS("", "") }()
+
+	blue := tui.NewStyle().Foreground(tui.Color("#268BD2"))
+	h := sha256.Sum256(clientCert.Leaf.RawSubjectPublicKeyInfo)
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%-33s · https://%s\n", blue.Render("API"), addrFlag)
+	fmt.Fprintf(buf, "%-33s %s\n", blue.Render("KES"), cmd.Arg(0))
+	for _, endpoint := range cmd.Args()[1:] {
+		fmt.Fprintf(buf, "%-11s %s\n", " ", endpoint)
+	}
+	fmt.Fprintf(buf, "%-33s %s\n", blue.Render("Identity"), hex.EncodeToString(h[:]))
+	if len(rules) == 0 {
+		fmt.Fprintf(buf, "%-33s <disabled>\n", blue.Render("Cache"))
+	}
+	for i, rule := range rules {
+		if i == 0 {
+			fmt.Fprintf(buf, "%-33s %s %s ttl=%v\n", blue.Render("Cache"), rule.Op, rule.Pattern, cacheTTLFlag)
+		} else {
+			fmt.Fprintf(buf, "%-11s %s %s ttl=%v\n", " ", rule.Op, rule.Pattern, cacheTTLFlag)
+		}
+	}
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "=> Proxy is up and running...")
+	fmt.Println(buf.String())
+
+	select {
+	case err := <-errCh:
+		cli.Fatal(err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelShutdown()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+	}
+
+	stats := p.Stats()
+	fmt.Printf("\n=> Stopping proxy... Served %d of %d cacheable requests from the cache. Goodbye.\n", stats.Hits, stats.Hits+stats.Misses)
+}
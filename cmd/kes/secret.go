@@ -0,0 +1,323 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+const secretCmdUsage = `Usage:
+    kes secret <command>
+
+Commands:
+    set                      Store a secret.
+    get                      Print a secret.
+    ls                       List secrets.
+    rm                       Remove secrets.
+
+Options:
+    -h, --help               Print command line options.
+
+Secrets are small opaque values, like passwords or tokens, that are
+stored encrypted by a KES key. Access is controlled by policies, e.g.
+'/v1/secret/get/db-*' allows reading all secrets starting with 'db-'.
+`
+
+func secretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, secretCmdUsage) }
+
+	subCmds := commands{
+		"set": setSecretCmd,
+		"get": getSecretCmd,
+		"ls":  lsSecretCmd,
+		"rm":  rmSecretCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a secret command. See 'kes secret --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const setSecretCmdUsage = `Usage:
+    kes secret set [options] <name> [<value>]
+
+Options:
+        --key <name>         Name of the key the secret is encrypted with.
+        --file <path>        Read the secret value from the file.
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+The value is read from stdin if it is '-'. Without a value or file, it
+is read from the terminal without echoing it. An existing secret with
+the same name is replaced.
+
+Examples:
+    $ kes secret set --key my-key db-password
+    $ kes secret set --key my-key api-token "$API_TOKEN"
+    $ kes secret set --key my-key tls-key --file ./private.key
+`
+
+func setSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, setSecretCmdUsage) }
+
+	var (
+		keyFlag            string
+		fileFlag           string
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&keyFlag, "key", "", "Name of the key the secret is encrypted with")
+	cmd.StringVar(&fileFlag, "file", "", "Read the secret value from the file")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret set --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no secret name specified. See 'kes secret set --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes secret set --help'")
+	case cmd.NArg() == 2 && fileFlag != "":
+		cli.Fatal("'--file' cannot be used with a value. See 'kes secret set --help'")
+	}
+	if keyFlag == "" {
+		cli.Fatal("no key specified. Set the '--key' flag")
+	}
+
+	var (
+		value []byte
+		err   error
+	)
+	switch {
+	case fileFlag != "":
+		value, err = os.ReadFile(fileFlag)
+	case cmd.NArg() == 2 && cmd.Arg(1) == "-":
+		value, err = io.ReadAll(io.LimitReader(os.Stdin, 1<<20))
+	case cmd.NArg() == 2:
+		value = []byte(cmd.Arg(1))
+	default:
+		if !isTerm(os.Stdin) {
+			cli.Fatal("no secret value specified. See 'kes secret set --help'")
+		}
+		fmt.Fprint(os.Stderr, "Enter secret:")
+		value, err = term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+	}
+	if err != nil {
+		cli.Fatalf("failed to read secret: %v", err)
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	if err = sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathSecretSet+name, api.SetSecretRequest{
+		Key:   keyFlag,
+		Value: value,
+	}, nil); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to set secret %q: %v", name, err)
+	}
+}
+
+const getSecretCmdUsage = `Usage:
+    kes secret get [options] <name>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the secret and its metadata in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes secret get db-password
+    $ PGPASSWORD=$(kes secret get db-password) psql ...
+`
+
+func getSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, getSecretCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the secret in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret get --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no secret name specified. See 'kes secret get --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes secret get --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	name := cmd.Arg(0)
+	var resp api.GetSecretResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathSecretGet+name, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to get secret %q: %v", name, err)
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	os.Stdout.Write(resp.Value)
+	if isTerm(os.Stdout) {
+		fmt.Println()
+	}
+}
+
+const lsSecretCmdUsage = `Usage:
+    kes secret ls [options] [<prefix>]
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print secret names in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes secret ls
+    $ kes secret ls 'db-*'
+`
+
+func lsSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, lsSecretCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print secret names in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret ls --help'", err)
+	}
+	if cmd.NArg() > 1 {
+		cli.Fatal("too many arguments. See 'kes secret ls --help'")
+	}
+
+	prefix := "*"
+	if cmd.NArg() == 1 {
+		prefix = cmd.Arg(0)
+	}
+	if !strings.HasSuffix(prefix, "*") {
+		prefix += "*"
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.ListSecretsResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathSecretList+prefix, nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to list secrets: %v", err)
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp.Names); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	for _, name := range resp.Names {
+		fmt.Println(name)
+	}
+}
+
+const rmSecretCmdUsage = `Usage:
+    kes secret rm [options] <name>...
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes secret rm db-password
+    $ kes secret rm db-password api-token
+`
+
+func rmSecretCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, rmSecretCmdUsage) }
+
+	var insecureSkipVerify bool
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes secret rm --help'", err)
+	}
+	if cmd.NArg() == 0 {
+		cli.Fatal("no secret name specified. See 'kes secret rm --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	client := newClient(insecureSkipVerify)
+	for _, name := range cmd.Args() {
+		if err := sendRequest(ctx, client, http.MethodDelete, api.PathSecretDelete+name, nil, nil); err != nil {
+			if errors.Is(err, context.Canceled) {
+				os.Exit(1)
+			}
+			cli.Fatalf("failed to remove secret %q: %v", name, err)
+		}
+	}
+}
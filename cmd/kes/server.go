@@ -12,10 +12,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/base64"
 	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"math/big"
 	"net"
@@ -24,12 +26,18 @@ import (
 	"runtime"
 	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	tui "github.com/charmbracelet/lipgloss"
 	"github.com/minio/kes"
 	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/selftest"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kes/kesconf"
 	kesdk "github.com/minio/kms-go/kes"
@@ -38,6 +46,11 @@ import (
 
 const serverCmdUsage = `Usage:
     kes server [options]
+    kes server <command>
+
+Commands:
+    install                  Install the KES server as Windows service.
+    uninstall                Uninstall the KES server Windows service.
 
 Options:
     --addr <[ip]:port>       The network interface the KES server will listen on.
@@ -49,6 +62,31 @@ Options:
     --dev                    Start the KES server in development mode. The server
                              uses a volatile in-memory key store.
 
+    --snapshot <file>        Path to a file the in-memory key store is persisted to
+                             in development mode. If the file exists, the keys are
+                             restored on startup. The snapshot is encrypted with the
+                             base64-encoded 256 bit key in the env. variable
+                             KES_SNAPSHOT_KEY.
+
+    --snapshot-interval      Duration between two snapshots. (default: 1m)
+
+    --selftest <duration>    Benchmark the cipher of new keys and send synthetic
+                             traffic to the key store for the given duration
+                             before accepting requests. Print the data key
+                             throughput and the sustained key store throughput
+                             and latency. Used to validate the server sizing
+                             before going live. The data key throughput is
+                             reported by 'kes status'.
+
+    --join <endpoint>        Join the KES cluster of the given member, e.g.
+                             'https://10.1.2.1:7373', if the server is not a
+                             cluster member yet. Requires a cluster config.
+
+    --validate               Validate the config file without starting the server.
+                             Checks the TLS certificate, server settings, policies
+                             and identities and probes the key store connection.
+                             Exits with a non-zero status if any check fails.
+
     -h, --help               Show list of command-line options
 
 
@@ -59,6 +97,11 @@ any point in time to scale out infinitely.
 
    Quick Start: https://github.com/minio/kes#quick-start
    Docs:        https://min.io/docs/kes/
+
+When started by systemd as service with 'Type=notify', the server
+signals systemd once it accepts requests and sends watchdog pings
+if 'WatchdogSec' is set. On Windows, the server can be installed
+and run as native service via 'kes server install'.
 	
 Examples:
   1. Start a new KES server on '127.0.0.1:7373' in development mode.
@@ -66,12 +109,39 @@ Examples:
 
   2. Start a new KES server with a confg file on '127.0.0.1:7000'.
      $ kes server --addr :7000 --config ./kes/config.yml
+
+  3. Start a new KES server in development mode that persists its keys.
+     $ export KES_SNAPSHOT_KEY=$(head -c 32 /dev/urandom | base64)
+     $ kes server --dev --snapshot ./kes.snapshot
+
+  4. Start a new KES server after a 1 minute soak test of its key store.
+     $ kes server --config ./kes/config.yml --selftest 1m
+
+  5. Start a new KES server that joins an existing KES cluster.
+     $ kes server --config ./kes/config.yml --join https://10.1.2.1:7373
+
+  6. Validate a KES server config file, e.g. as part of a CI pipeline.
+     $ kes server --config ./kes/config.yml --validate
+
+  7. Install the KES server as Windows service.
+     $ kes server install --config C:\kes\config.yml
 `
 
 func serverCmd(args []string) {
 	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
 	cmd.Usage = func() { fmt.Fprint(os.Stderr, serverCmdUsage) }
 
+	subCmds := commands{
+		"install":   installServiceCmd,
+		"uninstall": uninstallServiceCmd,
+	}
+	if len(args) > 1 {
+		if cmd, ok := subCmds[args[1]]; ok {
+			cmd(args[1:])
+			return
+		}
+	}
+
 	var (
 		addrFlag     string
 		configFlag   string
@@ -79,6 +149,12 @@ func serverCmd(args []string) {
 		tlsCertFlag  string
 		mtlsAuthFlag string
 		devFlag      bool
+
+		snapshotFlag         string
+		snapshotIntervalFlag time.Duration
+		selftestFlag         time.Duration
+		joinFlag             string
+		validateFlag         bool
 	)
 	cmd.StringVar(&addrFlag, "addr", "", "The address of the server")
 	cmd.StringVar(&configFlag, "config", "", "Path to the server configuration file")
@@ -86,6 +162,11 @@ func serverCmd(args []string) {
 	cmd.StringVar(&tlsCertFlag, "cert", "", "Path to the TLS certificate")
 	cmd.StringVar(&mtlsAuthFlag, "auth", "", "Controls how the server handles mTLS authentication")
 	cmd.BoolVar(&devFlag, "dev", false, "Start the KES server in development mode")
+	cmd.StringVar(&snapshotFlag, "snapshot", "", "Path to the in-memory key store snapshot in development mode")
+	cmd.DurationVar(&snapshotIntervalFlag, "snapshot-interval", 1*time.Minute, "Duration between two snapshots")
+	cmd.DurationVar(&selftestFlag, "selftest", 0, "Soak test the key store for the given duration on startup")
+	cmd.StringVar(&joinFlag, "join", "", "Join the KES cluster of the given member")
+	cmd.BoolVar(&validateFlag, "validate", false, "Validate the config file without starting the server")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -93,7 +174,7 @@ func serverCmd(args []string) {
 		cli.Fatalf("%v. See 'kes server --help'", err)
 	}
 
-	warnPrefix := tui.NewStyle().Foreground(tui.Color("#ac0000")).Render("WARNING:")
+	warnPrefix := cli.Stderr().Foreground(tui.Color("#ac0000")).Render("WARNING:")
 	if tlsKeyFlag != "" {
 		fmt.Fprintln(os.Stderr, warnPrefix, "'--key' flag is deprecated and no longer honored. Specify the private key in the config file")
 	}
@@ -107,6 +188,39 @@ func serverCmd(args []string) {
 	if cmd.NArg() > 0 {
 		cli.Fatal("too many arguments. See 'kes server --help'")
 	}
+	if snapshotFlag != "" && !devFlag {
+		cli.Fatal("'--snapshot' flag is only supported in development mode")
+	}
+	if selftestFlag < 0 {
+		cli.Fatal("'--selftest' must not be negative")
+	}
+	if selftestFlag > 0 && devFlag {
+		cli.Fatal("'--selftest' flag is not supported in development mode")
+	}
+	if joinFlag != "" && devFlag {
+		cli.Fatal("'--join' flag is not supported in development mode")
+	}
+
+	if validateFlag {
+		if devFlag {
+			cli.Fatal("'--validate' flag is not supported in development mode")
+		}
+		if configFlag == "" {
+			cli.Fatal("'--validate' requires a config file. See 'kes server --help'")
+		}
+
+		ctx, cancel := newContext()
+		defer cancel()
+
+		checks := validateServerConfig(ctx, configFlag)
+		printChecks(checks, globalJSON, (&colorOption{}).Colorize())
+		for _, check := range checks {
+			if check.Status == checkFail {
+				os.Exit(1)
+			}
+		}
+		return
+	}
 
 	if devFlag {
 		if addrFlag == "" {
@@ -115,25 +229,25 @@ func serverCmd(args []string) {
 		if configFlag != "" {
 			cli.Fatal("'--config' flag is not supported in development mode")
 		}
+		if snapshotIntervalFlag <= 0 {
+			cli.Fatal("'--snapshot-interval' must be positive")
+		}
 
-		if err := startDevServer(addrFlag); err != nil {
+		if err := startDevServer(addrFlag, snapshotFlag, snapshotIntervalFlag); err != nil {
 			cli.Fatal(err)
 		}
 		return
 	}
 
-	if err := startServer(addrFlag, configFlag); err != nil {
+	err := runService(func(ctx context.Context) error {
+		return startServer(ctx, addrFlag, configFlag, joinFlag, selftestFlag)
+	})
+	if err != nil {
 		cli.Fatal(err)
 	}
 }
 
-func startServer(addrFlag, configFlag string) error {
-	var memLocked bool
-	if runtime.GOOS == "linux" {
-		memLocked = mlockall() == nil
-		defer munlockall()
-	}
-
+func startServer(ctx context.Context, addrFlag, configFlag, joinFlag string, selftestDuration time.Duration) error {
 	info, err := sys.ReadBinaryInfo()
 	if err != nil {
 		return err
@@ -147,6 +261,27 @@ func startServer(addrFlag, configFlag string) error {
 	if err != nil {
 		return err
 	}
+
+	// Lock the memory before the keystore is connected
+	// such that no keys are loaded into unlocked memory.
+	var memLocked bool
+	if rawConfig.Memory == nil || rawConfig.Memory.Lock != kesconf.MemoryLockOff {
+		lockErr := fmt.Errorf("not supported on %s", runtime.GOOS)
+		if runtime.GOOS == "linux" {
+			lockErr = mlockall()
+			memLocked = lockErr == nil
+			defer munlockall()
+		}
+		if lockErr != nil && rawConfig.Memory != nil && rawConfig.Memory.Lock == kesconf.MemoryLockRequired {
+			return fmt.Errorf("failed to lock memory: %v", lockErr)
+		}
+	}
+	if joinFlag != "" {
+		if rawConfig.Cluster == nil {
+			return errors.New("'--join' requires a cluster config")
+		}
+		rawConfig.Cluster.Join = joinFlag
+	}
 	switch {
 	case addrFlag != "":
 		// Nothing to do, addrFlag is set
@@ -171,21 +306,47 @@ func startServer(addrFlag, configFlag string) error {
 		return err
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	defer cancel()
-
 	conf, err := rawConfig.Config(ctx)
 	if err != nil {
 		return err
 	}
-	defer conf.Keys.Close()
+	if conf.Keys != nil { // Read replicas have no keystore
+		defer conf.Keys.Close()
+	}
 
-	srv := &kes.Server{}
+	// The tracer provider and SPIFFE source change when the config
+	// is reloaded. The current ones are closed once the server stops.
+	var current atomic.Pointer[kes.Config]
+	current.Store(conf)
+	defer func() {
+		closeLogHandlers(current.Load()) // Flush buffered log and audit events
+		shutdownTracing(current.Load())
+		closeSPIFFESource(current.Load())
+	}()
+
+	var cipherThroughput float64
+	if selftestDuration > 0 {
+		if conf.Keys == nil {
+			return errors.New("'--selftest' requires a keystore but the server is a read replica")
+		}
+		if cipherThroughput, err = runCipherSelftest(ctx); err != nil {
+			return err
+		}
+		if err = runSelftest(ctx, conf.Keys, selftestDuration); err != nil {
+			return err
+		}
+	}
+
+	srv := &kes.Server{CipherThroughput: cipherThroughput}
+	if rawConfig.HTTP != nil && rawConfig.HTTP.ShutdownTimeout > 0 {
+		srv.ShutdownTimeout = rawConfig.HTTP.ShutdownTimeout
+	}
 	conf.Cache = configureCache(conf.Cache)
 	if rawConfig.Log != nil {
 		srv.ErrLevel.Set(rawConfig.Log.ErrLevel)
 		srv.AuditLevel.Set(rawConfig.Log.AuditLevel)
 	}
+	errorOut, auditOut := logOutputs(rawConfig.Log)
 	sighup := make(chan os.Signal, 10)
 	signal.Notify(sighup, syscall.SIGHUP)
 	defer signal.Stop(sighup)
@@ -200,7 +361,11 @@ func startServer(addrFlag, configFlag string) error {
 		fmt.Fprintf(buf, "%-33s %-23s %s\n", blue.Render("License"), "AGPLv3", faint.Render("https://www.gnu.org/licenses/agpl-3.0.html"))
 		fmt.Fprintf(buf, "%-33s %-12s 2015-%d  %s\n", blue.Render("Copyright"), "MinIO, Inc.", time.Now().Year(), faint.Render("https://min.io"))
 		fmt.Fprintln(buf)
-		fmt.Fprintf(buf, "%-33s %v\n", blue.Render("KMS"), conf.Keys)
+		if conf.Replica != nil {
+			fmt.Fprintf(buf, "%-33s Replica: %s\n", blue.Render("KMS"), conf.Replica.Endpoint)
+		} else {
+			fmt.Fprintf(buf, "%-33s %v\n", blue.Render("KMS"), conf.Keys)
+		}
 		fmt.Fprintf(buf, "%-33s · https://%s\n", blue.Render("API"), net.JoinHostPort(ifaceIPs[0].String(), port))
 		for _, ifaceIP := range ifaceIPs[1:] {
 			fmt.Fprintf(buf, "%-11s · https://%s\n", " ", net.JoinHostPort(ifaceIP.String(), port))
@@ -215,16 +380,66 @@ func startServer(addrFlag, configFlag string) error {
 		} else {
 			fmt.Fprintf(buf, "%-33s <disabled>\n", blue.Render("Admin"))
 		}
-		fmt.Fprintf(buf, "%-33s error=stderr level=%s\n", blue.Render("Logs"), srv.ErrLevel.Level())
+		fmt.Fprintf(buf, "%-33s error=%s level=%s\n", blue.Render("Logs"), errorOut, srv.ErrLevel.Level())
 		if srv.AuditLevel.Level() <= slog.LevelInfo {
-			fmt.Fprintf(buf, "%-11s audit=stdout level=%s\n", " ", srv.AuditLevel.Level())
+			fmt.Fprintf(buf, "%-11s audit=%s level=%s\n", " ", auditOut, srv.AuditLevel.Level())
 		}
 		if memLocked {
 			fmt.Fprintf(buf, "%-33s %s\n", blue.Render("MLock"), "enabled")
 		}
+		if conf.Cache != nil && conf.Cache.Zeroize {
+			fmt.Fprintf(buf, "%-33s %s\n", blue.Render("Zeroize"), "enabled")
+		}
 		return buf
 	}
 
+	// reload reads the config file again and applies it to the
+	// server. It is invoked on SIGHUP or by the reload API.
+	// In-flight requests complete using the previous config.
+	var reloadLock sync.Mutex
+	reload := func(ctx context.Context) error {
+		reloadLock.Lock()
+		defer reloadLock.Unlock()
+
+		file, err := kesconf.ReadFile(configFlag)
+		if err != nil {
+			return err
+		}
+		config, err := file.Config(ctx)
+		if err != nil {
+			return err
+		}
+		config.Cache = configureCache(config.Cache)
+
+		closer, err := srv.Update(config)
+		if err != nil {
+			if config.Keys != nil {
+				config.Keys.Close()
+			}
+			closeLogHandlers(config)
+			closeSPIFFESource(config)
+			return err
+		}
+		errorOut, auditOut = logOutputs(file.Log)
+		if file.Log != nil {
+			srv.ErrLevel.Set(file.Log.ErrLevel)
+			srv.AuditLevel.Set(file.Log.AuditLevel)
+		}
+
+		if err = closer.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to close previous keystore connections: %v\n", err)
+		}
+		prev := current.Swap(config)
+		shutdownTracing(prev)
+		closeSPIFFESource(prev)
+		buf := startupMessage(config)
+		fmt.Fprintln(buf)
+		fmt.Fprintln(buf, "=> Reloading configuration completed.")
+		fmt.Println(buf.String())
+		return nil
+	}
+	srv.Reload = reload
+
 	go func(ctx context.Context) {
 		for {
 			select {
@@ -232,40 +447,27 @@ func startServer(addrFlag, configFlag string) error {
 				return
 			case <-sighup:
 				fmt.Fprintln(os.Stderr, "SIGHUP signal received. Reloading configuration...")
-
-				file, err := kesconf.ReadFile(configFlag)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to reload server config: %v\n", err)
-					continue
-				}
-				config, err := file.Config(ctx)
-				if err != nil {
+				if err := reload(ctx); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to reload server config: %v\n", err)
-					continue
 				}
-				config.Cache = configureCache(config.Cache)
-
-				closer, err := srv.Update(config)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to update server configuration: %v\n", err)
-					continue
-				}
-				if file.Log != nil {
-					srv.ErrLevel.Set(file.Log.ErrLevel)
-					srv.AuditLevel.Set(file.Log.AuditLevel)
-				}
-
-				if err = closer.Close(); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to close previous keystore connections: %v\n", err)
-				}
-				buf := startupMessage(config)
-				fmt.Fprintln(buf)
-				fmt.Fprintln(buf, "=> Reloading configuration after SIGHUP signal completed.")
-				fmt.Println(buf.String())
 			}
 		}
 	}(ctx)
 
+	// reloadTLS reads the config file again and only applies
+	// its TLS configuration to the server.
+	reloadTLS := func() error {
+		file, err := kesconf.ReadFile(configFlag)
+		if err != nil {
+			return err
+		}
+		conf, err := file.TLSConfig()
+		if err != nil {
+			return err
+		}
+		return srv.UpdateTLS(conf)
+	}
+
 	go func(ctx context.Context) {
 		ticker := time.NewTicker(15 * time.Minute)
 		defer ticker.Stop()
@@ -275,28 +477,51 @@ func startServer(addrFlag, configFlag string) error {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				file, err := kesconf.ReadFile(configFlag)
-				if err != nil {
+				if err := reloadTLS(); err != nil {
 					fmt.Fprintf(os.Stderr, "Failed to reload TLS configuration: %v\n", err)
-					continue
-				}
-				conf, err := file.TLSConfig()
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to reload TLS configuration: %v\n", err)
-					continue
-				}
-				if err = srv.UpdateTLS(conf); err != nil {
-					fmt.Fprintf(os.Stderr, "Failed to update TLS configuration: %v\n", err)
 				}
 			}
 		}
 	}(ctx)
 
+	// Reload the TLS configuration as soon as the certificate,
+	// private key or CA certificates change, e.g. when rotated
+	// by cert-manager. If the files are replaced one after
+	// another, reloading may fail until all have been replaced.
+	if tlsConf := rawConfig.TLS; tlsConf != nil && tlsConf.ACME == nil {
+		go func(ctx context.Context) {
+			interval := tlsConf.Reload
+			if interval == 0 {
+				interval = 5 * time.Second
+			}
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			watcher := https.NewFileWatcher(tlsConf.Certificate, tlsConf.PrivateKey, tlsConf.CAPath)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if !watcher.Changed() {
+						continue
+					}
+					if err := reloadTLS(); err != nil {
+						fmt.Fprintf(os.Stderr, "Failed to reload TLS configuration: %v\n", err)
+						continue
+					}
+					fmt.Println("=> TLS certificate has changed. Reloading TLS configuration completed.")
+				}
+			}
+		}(ctx)
+	}
+
 	buf := startupMessage(conf)
 	fmt.Fprintln(buf)
 	fmt.Fprintln(buf, "=> Server is up and running...")
 	fmt.Println(buf.String())
 
+	go notifySystemd(ctx, srv)
 	if err = srv.ListenAndStart(ctx, addrFlag, conf); err != nil {
 		return err
 	}
@@ -304,7 +529,7 @@ func startServer(addrFlag, configFlag string) error {
 	return nil
 }
 
-func startDevServer(addr string) error {
+func startDevServer(addr, snapshot string, snapshotInterval time.Duration) error {
 	info, err := sys.ReadBinaryInfo()
 	if err != nil {
 		return err
@@ -356,6 +581,40 @@ func startDevServer(addr string) error {
 	}
 	srv := &kes.Server{}
 
+	if snapshot != "" {
+		keys := conf.Keys.(*kes.MemKeyStore)
+		snapshotKey, err := base64.StdEncoding.DecodeString(os.Getenv("KES_SNAPSHOT_KEY"))
+		if err != nil {
+			return fmt.Errorf("invalid snapshot key: %v", err)
+		}
+		if err = keys.ReadSnapshot(snapshot, snapshotKey); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to restore snapshot: %v", err)
+		}
+		if err = keys.WriteSnapshot(snapshot, snapshotKey); err != nil {
+			return fmt.Errorf("failed to write snapshot: %v", err)
+		}
+
+		go func() {
+			ticker := time.NewTicker(snapshotInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := keys.WriteSnapshot(snapshot, snapshotKey); err != nil {
+						fmt.Fprintf(os.Stderr, "Error: failed to write snapshot: %v\n", err)
+					}
+				}
+			}
+		}()
+		defer func() {
+			if err := keys.WriteSnapshot(snapshot, snapshotKey); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: failed to write snapshot: %v\n", err)
+			}
+		}()
+	}
+
 	blue := tui.NewStyle().Foreground(tui.Color("#268BD2"))
 	faint := tui.NewStyle().Faint(true)
 
@@ -366,6 +625,9 @@ func startDevServer(addr string) error {
 	fmt.Fprintf(buf, "%-33s %-12s 2015-%d  %s\n", blue.Render("Copyright"), "MinIO, Inc.", time.Now().Year(), faint.Render("https://min.io"))
 	fmt.Fprintln(buf)
 	fmt.Fprintf(buf, "%-33s %v\n", blue.Render("KMS"), conf.Keys)
+	if snapshot != "" {
+		fmt.Fprintf(buf, "%-11s %s\n", " ", faint.Render("snapshot="+snapshot+" interval="+snapshotInterval.String()))
+	}
 	fmt.Fprintf(buf, "%-33s · https://%s\n", blue.Render("API"), net.JoinHostPort(ifaceIPs[0].String(), port))
 	for _, ifaceIP := range ifaceIPs[1:] {
 		fmt.Fprintf(buf, "%-11s · https://%s\n", " ", net.JoinHostPort(ifaceIP.String(), port))
@@ -388,6 +650,137 @@ func startDevServer(addr string) error {
 	return nil
 }
 
+// logOutputs returns a description of where the server writes
+// error and audit events to, e.g. "stderr" or "file+webhook".
+func logOutputs(conf *kesconf.LogConfig) (errorOut, auditOut string) {
+	var errs, audits []string
+	if conf != nil && conf.File != nil {
+		if conf.File.ErrorPath != "" {
+			errs = append(errs, "file")
+		}
+		if conf.File.AuditPath != "" {
+			audits = append(audits, "file")
+		}
+	}
+	if conf != nil && conf.Syslog != nil {
+		if conf.Syslog.Error {
+			errs = append(errs, "syslog")
+		}
+		if conf.Syslog.Audit {
+			audits = append(audits, "syslog")
+		}
+	}
+	if conf != nil && conf.Webhook != nil {
+		if conf.Webhook.Error {
+			errs = append(errs, "webhook")
+		}
+		if conf.Webhook.Audit {
+			audits = append(audits, "webhook")
+		}
+	}
+	if len(errs) == 0 {
+		errs = append(errs, "stderr")
+	}
+	if len(audits) == 0 {
+		audits = append(audits, "stdout")
+	}
+	return strings.Join(errs, "+"), strings.Join(audits, "+")
+}
+
+// closeLogHandlers closes all log handlers and audit targets
+// of the config that implement io.Closer.
+func closeLogHandlers(conf *kes.Config) {
+	handlers := []any{conf.ErrorLog, conf.AuditLog}
+	for _, t := range conf.AuditTargets {
+		handlers = append(handlers, t)
+	}
+	for _, h := range handlers {
+		if c, ok := h.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// shutdownTracing flushes all pending spans and stops
+// the config's tracer provider, if any.
+func shutdownTracing(conf *kes.Config) {
+	provider, ok := conf.TracerProvider.(interface{ Shutdown(context.Context) error })
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to flush traces: %v\n", err)
+	}
+}
+
+// closeSPIFFESource closes the config's SPIFFE source, if any.
+func closeSPIFFESource(conf *kes.Config) {
+	if conf.SPIFFE == nil {
+		return
+	}
+	if c, ok := conf.SPIFFE.Source.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// runCipherSelftest benchmarks generating data keys with the
+// cipher of new keys and returns the throughput.
+func runCipherSelftest(ctx context.Context) (float64, error) {
+	const Duration = 1 * time.Second
+	blue := tui.NewStyle().Foreground(tui.Color("#268BD2"))
+
+	cipher := crypto.DefaultSecretKeyType()
+	fmt.Printf("=> Running %v cipher selftest for %v...\n", cipher, Duration)
+	result, err := selftest.RunCipher(ctx, cipher, Duration)
+	if err != nil {
+		return 0, err
+	}
+
+	hardware := "no"
+	if cpu.HasAESGCM() {
+		hardware = "yes"
+	}
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%-33s %v (AES-GCM hardware support: %s)\n", blue.Render("Cipher"), cipher, hardware)
+	fmt.Fprintf(buf, "%-33s %.0f data keys/s on %d CPUs %s\n", blue.Render("Throughput"), result.Throughput(), runtime.GOMAXPROCS(0), runtime.GOARCH)
+	fmt.Println(buf.String())
+	return result.Throughput(), nil
+}
+
+// runSelftest soak tests the key store for the given duration
+// and prints the sustained throughput and latency.
+func runSelftest(ctx context.Context, store kes.KeyStore, duration time.Duration) error {
+	blue := tui.NewStyle().Foreground(tui.Color("#268BD2"))
+
+	fmt.Printf("=> Running key store selftest for %v...\n", duration)
+	result, err := selftest.Run(ctx, store, &selftest.Config{Duration: duration})
+	if err != nil {
+		return err
+	}
+
+	buf := &strings.Builder{}
+	fmt.Fprintf(buf, "%-33s %.1f req/s\n", blue.Render("Throughput"), result.Throughput())
+	for _, op := range []struct {
+		Name  string
+		Stats selftest.Stats
+	}{
+		{Name: "Reads", Stats: result.Reads},
+		{Name: "Writes", Stats: result.Writes},
+	} {
+		fmt.Fprintf(buf, "%-33s %d requests, %d errors, latency p50=%v p99=%v max=%v\n",
+			blue.Render(op.Name), op.Stats.Ops, op.Stats.Errors,
+			op.Stats.LatencyP50.Round(time.Microsecond),
+			op.Stats.LatencyP99.Round(time.Microsecond),
+			op.Stats.LatencyMax.Round(time.Microsecond),
+		)
+	}
+	fmt.Println(buf.String())
+	return nil
+}
+
 // configureCache sets default values for each cache config option
 // as documented in: https://github.com/minio/kes/blob/master/server-config.yaml
 func configureCache(c *kes.CacheConfig) *kes.CacheConfig {
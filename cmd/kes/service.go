@@ -0,0 +1,124 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+// defaultServiceName is the default name of the KES server
+// Windows service.
+const defaultServiceName = "kes"
+
+const installServiceCmdUsage = `Usage:
+    kes server install [options]
+
+Installs the KES server as Windows service that starts automatically
+when the system boots. The service runs 'kes server' with the given
+config file and address.
+
+Options:
+    --config <file>          Path to the KES server config file.
+    --addr <[ip]:port>       The network interface the KES server will listen on.
+    --name <name>            The name of the Windows service. (default: kes)
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes server install --config C:\kes\config.yml
+`
+
+func installServiceCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, installServiceCmdUsage) }
+
+	var (
+		configFlag string
+		addrFlag   string
+		nameFlag   string
+	)
+	cmd.StringVar(&configFlag, "config", "", "Path to the server configuration file")
+	cmd.StringVar(&addrFlag, "addr", "", "The address of the server")
+	cmd.StringVar(&nameFlag, "name", defaultServiceName, "The name of the Windows service")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes server install --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes server install --help'")
+	}
+	if configFlag == "" {
+		cli.Fatal("no config file specified. See 'kes server install --help'")
+	}
+	if nameFlag == "" {
+		cli.Fatal("invalid service name: name must not be empty")
+	}
+
+	configFile, err := filepath.Abs(configFlag)
+	if err != nil {
+		cli.Fatalf("failed to install service: %v", err)
+	}
+	if _, err = os.Stat(configFile); err != nil {
+		cli.Fatalf("failed to install service: %v", err)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		cli.Fatalf("failed to install service: %v", err)
+	}
+
+	serviceArgs := []string{"server", "--config", configFile}
+	if addrFlag != "" {
+		serviceArgs = append(serviceArgs, "--addr", addrFlag)
+	}
+	if err = installService(nameFlag, exe, serviceArgs...); err != nil {
+		cli.Fatalf("failed to install service '%s': %v", nameFlag, err)
+	}
+	fmt.Printf("Installed Windows service '%s'\n", nameFlag)
+}
+
+const uninstallServiceCmdUsage = `Usage:
+    kes server uninstall [options]
+
+Uninstalls the KES server Windows service. A running service is
+stopped once it is uninstalled.
+
+Options:
+    --name <name>            The name of the Windows service. (default: kes)
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes server uninstall
+`
+
+func uninstallServiceCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, uninstallServiceCmdUsage) }
+
+	var nameFlag string
+	cmd.StringVar(&nameFlag, "name", defaultServiceName, "The name of the Windows service")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes server uninstall --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes server uninstall --help'")
+	}
+
+	if err := uninstallService(nameFlag); err != nil {
+		cli.Fatalf("failed to uninstall service '%s': %v", nameFlag, err)
+	}
+	fmt.Printf("Uninstalled Windows service '%s'\n", nameFlag)
+}
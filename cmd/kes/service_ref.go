@@ -0,0 +1,33 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !windows
+// +build !windows
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os/signal"
+	"runtime"
+	"syscall"
+)
+
+// runService runs f until it returns or the process receives
+// a SIGINT or SIGTERM signal.
+func runService(f func(context.Context) error) error {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	return f(ctx)
+}
+
+func installService(string, string, ...string) error {
+	return errors.New("Windows services are not supported on " + runtime.GOOS)
+}
+
+func uninstallService(string) error {
+	return errors.New("Windows services are not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,129 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// runService runs f as Windows service if the process has been
+// started by the service control manager. Then, f runs until it
+// returns or the service is stopped. Otherwise, f runs until it
+// returns or the process receives an interrupt signal.
+func runService(f func(context.Context) error) error {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return err
+	}
+	if !isService {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+
+		return f(ctx)
+	}
+
+	// The service name is ignored for services
+	// running in their own process.
+	service := &windowsService{run: f}
+	if err = svc.Run(defaultServiceName, service); err != nil {
+		return err
+	}
+	return service.err
+}
+
+// windowsService implements svc.Handler. It runs the
+// server and stops it when the service control manager
+// requests the service to stop.
+type windowsService struct {
+	run func(context.Context) error
+	err error
+}
+
+func (s *windowsService) Execute(_ []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	const Accepts = svc.AcceptStop | svc.AcceptShutdown
+	status <- svc.Status{State: svc.StartPending}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- s.run(ctx) }()
+
+	status <- svc.Status{State: svc.Running, Accepts: Accepts}
+	for {
+		select {
+		case s.err = <-done:
+			status <- svc.Status{State: svc.StopPending}
+			if s.err != nil {
+				return true, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				status <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				status <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
+
+// installService installs a Windows service with the given name
+// that runs the executable exe with the given arguments. The
+// service starts automatically when the system boots.
+func installService(name, exe string, args ...string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return errors.New("service already exists")
+	}
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "MinIO KES",
+		Description: "MinIO Key Encryption Service",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return err
+	}
+	return s.Close()
+}
+
+// uninstallService stops and removes the Windows service
+// with the given name.
+func uninstallService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+			return errors.New("service does not exist")
+		}
+		return err
+	}
+	defer s.Close()
+
+	if _, err = s.Control(svc.Stop); err != nil && !errors.Is(err, windows.ERROR_SERVICE_NOT_ACTIVE) {
+		return err
+	}
+	return s.Delete()
+}
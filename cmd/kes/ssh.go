@@ -0,0 +1,240 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const sshCmdUsage = `Usage:
+    kes ssh <command>
+
+Commands:
+    sign                     Issue an SSH certificate for a public key.
+    ca                       Print the public key of an SSH certificate authority.
+
+Options:
+    -h, --help               Print command line options.
+
+A key acts as SSH certificate authority (CA) if created with the
+usage 'ssh', e.g. 'kes key create --usage ssh ssh-ca'. SSH servers
+trust the certificates issued by it once its public key, printed
+by 'kes ssh ca', is added as TrustedUserCAKeys.
+`
+
+func sshCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, sshCmdUsage) }
+
+	subCmds := commands{
+		"sign": signSSHCmd,
+		"ca":   caSSHCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not an ssh command. See 'kes ssh --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const signSSHCmdUsage = `Usage:
+    kes ssh sign [options] <key> <public-key-file>
+
+Options:
+    -n, --principal <name>   User or host name the certificate is valid for.
+                             May be specified multiple times.
+        --ttl <duration>     Validity of the certificate. Limited by the policy.
+                             (default: 1h)
+        --host               Issue a host instead of a user certificate.
+        --id <name>          Key ID of the certificate, shown in the SSH server
+                             logs. Defaults to the identity of the client.
+    -o, --output <file>      Write the certificate to the file instead of stdout.
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the certificate details in JSON format.
+
+    -h, --help               Print command line options.
+
+The public key is read from stdin if <public-key-file> is '-'.
+
+Examples:
+    $ kes ssh sign -n deploy --ttl 8h ssh-ca ~/.ssh/id_ed25519.pub -o ~/.ssh/id_ed25519-cert.pub
+    $ kes ssh sign --host -n web-1.example.com ssh-ca /etc/ssh/ssh_host_ed25519_key.pub
+`
+
+func signSSHCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, signSSHCmdUsage) }
+
+	var (
+		principalFlags     []string
+		ttlFlag            time.Duration
+		hostFlag           bool
+		idFlag             string
+		outputFlag         string
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.StringArrayVarP(&principalFlags, "principal", "n", nil, "User or host name the certificate is valid for")
+	cmd.DurationVar(&ttlFlag, "ttl", 0, "Validity of the certificate")
+	cmd.BoolVar(&hostFlag, "host", false, "Issue a host certificate")
+	cmd.StringVar(&idFlag, "id", "", "Key ID of the certificate")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the certificate to the file")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the certificate details in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh sign --help'", err)
+	}
+	switch {
+	case cmd.NArg() < 2:
+		cli.Fatal("too few arguments. See 'kes ssh sign --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes ssh sign --help'")
+	}
+	if len(principalFlags) == 0 {
+		cli.Fatal("no principal specified. See 'kes ssh sign --help'")
+	}
+	if ttlFlag < 0 {
+		cli.Fatal("'--ttl' must not be negative")
+	}
+
+	name, filename := cmd.Arg(0), cmd.Arg(1)
+	var (
+		publicKey []byte
+		err       error
+	)
+	if filename == "-" {
+		publicKey, err = io.ReadAll(io.LimitReader(os.Stdin, 64*1024))
+	} else {
+		publicKey, err = os.ReadFile(filename)
+	}
+	if err != nil {
+		cli.Fatalf("failed to read public key: %v", err)
+	}
+
+	certType := "user"
+	if hostFlag {
+		certType = "host"
+	}
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.SSHSignResponse
+	if err = sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathSSHSign+name, api.SSHSignRequest{
+		PublicKey:  string(publicKey),
+		Type:       certType,
+		Principals: principalFlags,
+		TTL:        int64(ttlFlag / time.Second),
+		KeyID:      idFlag,
+	}, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to sign SSH certificate: %v", err)
+	}
+
+	if outputFlag != "" {
+		if err = os.WriteFile(outputFlag, []byte(resp.Certificate+"\n"), 0o644); err != nil {
+			cli.Fatalf("failed to write SSH certificate: %v", err)
+		}
+	}
+	if jsonFlag {
+		encoder := json.NewEncoder(os.Stdout)
+		if isTerm(os.Stdout) {
+			encoder.SetIndent("", "  ")
+		}
+		if err = encoder.Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	if outputFlag == "" {
+		fmt.Println(resp.Certificate)
+		return
+	}
+	fmt.Printf("Serial %d valid from %s to %s\n", resp.Serial, resp.ValidAfter.Local().Format(time.DateTime), resp.ValidBefore.Local().Format(time.DateTime))
+}
+
+const caSSHCmdUsage = `Usage:
+    kes ssh ca [options] <key>
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the public key in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes ssh ca ssh-ca > /etc/ssh/kes_ca.pub
+`
+
+func caSSHCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, caSSHCmdUsage) }
+
+	var (
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the public key in JSON format")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes ssh ca --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no key name specified. See 'kes ssh ca --help'")
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes ssh ca --help'")
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.SSHCAResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodGet, api.PathSSHCA+cmd.Arg(0), nil, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to fetch SSH CA public key: %v", err)
+	}
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	fmt.Println(resp.PublicKey)
+}
@@ -5,19 +5,22 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"net/http"
 	"os"
-	"os/signal"
 	"strconv"
 	"strings"
 	"time"
 
 	"aead.dev/mem"
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cli"
 	"github.com/minio/kms-go/kes"
 	flag "github.com/spf13/pflag"
@@ -31,6 +34,8 @@ Options:
     -s, --short              Print status information in a short summary format.
         --api                List all server APIs.
         --json               Print status information in JSON format.
+    -w, --watch              Refresh the status information periodically.
+        --rate <duration>    Refresh rate when watching the status. (default: 2s)
         --color <when>       Specify when to use colored output. The automatic
                              mode only enables colors if an interactive terminal
                              is detected - colors are automatically disabled if
@@ -48,11 +53,15 @@ func statusCmd(args []string) {
 		jsonFlag           bool
 		shortFlag          bool
 		apiFlag            bool
+		watchFlag          bool
+		rate               time.Duration
 		colorFlag          colorOption
 		insecureSkipVerify bool
 	)
-	cmd.BoolVar(&jsonFlag, "json", false, "Print status information in JSON format")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print status information in JSON format")
 	cmd.BoolVar(&apiFlag, "api", false, "List all server APIs")
+	cmd.BoolVarP(&watchFlag, "watch", "w", false, "Refresh the status information periodically")
+	cmd.DurationVar(&rate, "rate", 2*time.Second, "Refresh rate when watching the status")
 	cmd.Var(&colorFlag, "color", "Specify when to use colored output")
 	cmd.BoolVarP(&shortFlag, "short", "s", false, "Print status information in a short summary format")
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
@@ -65,11 +74,30 @@ func statusCmd(args []string) {
 	if cmd.NArg() > 0 {
 		cli.Fatal("too many arguments. See 'kes status --help'")
 	}
+	if watchFlag && apiFlag {
+		cli.Fatal("'--watch' and '--api' cannot be used together. See 'kes status --help'")
+	}
+	if rate <= 0 {
+		cli.Fatal("invalid refresh rate: must be greater than 0. See 'kes status --help'")
+	}
 
 	client := newClient(insecureSkipVerify)
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
+	if watchFlag {
+		watchStatus(ctx, client, rate, jsonFlag, shortFlag, colorFlag)
+		return
+	}
+
+	// The keystore, cache and object details are not part of the
+	// SDK's status response. Servers that do not report them are
+	// skipped. All further requests are sent to the server that
+	// has responded such that the status of a single server is
+	// printed even if the client has multiple endpoints.
+	endpoint, details, detailsErr := keyStoreStatus(ctx, client)
+	client = pinEndpoint(client, endpoint)
+
 	start := time.Now()
 	status, err := client.Status(ctx)
 	if err != nil {
@@ -93,83 +121,22 @@ func statusCmd(args []string) {
 		if isTerm(os.Stdout) && !shortFlag {
 			encoder.SetIndent("", "  ")
 		}
-		if apiFlag {
-			if err = encoder.Encode(APIs); err != nil {
-				cli.Fatal(err)
-			}
-		} else {
-			if err = encoder.Encode(status); err != nil {
-				cli.Fatal(err)
-			}
-		}
-		return
-	}
-
-	faint := tui.NewStyle()
-	dotStyle := tui.NewStyle()
-	endpointStyle := tui.NewStyle()
-	if colorFlag.Colorize() {
-		const (
-			ColorDot      = tui.Color("#00f700")
-			ColorEndpoint = tui.Color("#00afaf")
-		)
-		faint = faint.Faint(true)
-		dotStyle = dotStyle.Foreground(ColorDot).Bold(true)
-		endpointStyle = endpointStyle.Foreground(ColorEndpoint).Bold(true)
-	}
-
-	fmt.Println(dotStyle.Render("●"), endpointStyle.Render(strings.TrimPrefix(client.Endpoints[0], "https://")))
-	if !shortFlag {
-		fmt.Println(
-			faint.Render(fmt.Sprintf("  %-8s", "Version")),
-			status.Version,
-		)
 		switch {
-		case status.UpTime > 24*time.Hour:
-			fmt.Println(
-				faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
-				fmt.Sprintf("%.f days %.f hours", status.UpTime.Hours()/24, math.Mod(status.UpTime.Hours(), 24)),
-			)
-		case status.UpTime > 1*time.Hour:
-			fmt.Println(
-				faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
-				fmt.Sprintf("%.f hours", status.UpTime.Hours()),
-			)
-		case status.UpTime > 1*time.Minute:
-			fmt.Println(
-				faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
-				fmt.Sprintf("%.f minutes", status.UpTime.Minutes()),
-			)
+		case apiFlag:
+			err = encoder.Encode(APIs)
+		case detailsErr == nil:
+			err = encoder.Encode(details)
 		default:
-			fmt.Println(
-				faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
-				fmt.Sprintf("%.f seconds", status.UpTime.Seconds()),
-			)
+			err = encoder.Encode(status)
 		}
-		fmt.Println(
-			faint.Render(fmt.Sprintf("  %-8s", "Latency")),
-			latency.Round(time.Millisecond),
-		)
-		fmt.Println(
-			faint.Render(fmt.Sprintf("  %-8s", "OS")),
-			status.OS,
-		)
-		fmt.Println(
-			faint.Render(fmt.Sprintf("  %-8s", "CPUs")),
-			strconv.Itoa(status.UsableCPUs),
-			status.Arch,
-		)
-		fmt.Println(faint.Render(fmt.Sprintf("  %-8s", "Memory")))
-		fmt.Println(
-			faint.Render(fmt.Sprintf("%3s %-6s", "·", "Heap")),
-			mem.FormatSize(mem.Size(status.HeapAlloc), 'D', 1),
-		)
-		fmt.Println(
-			faint.Render(fmt.Sprintf("%3s %-6s", "·", "Stack")),
-			mem.FormatSize(mem.Size(status.StackAlloc), 'D', 1),
-		)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		return
 	}
 
+	printStatus(os.Stdout, client, status, latency, details, detailsErr == nil, shortFlag, colorFlag)
+
 	if apiFlag {
 		header := tui.NewStyle()
 		pathStyle := tui.NewStyle()
@@ -199,3 +166,267 @@ func statusCmd(args []string) {
 		}
 	}
 }
+
+// watchStatus fetches and prints the server status at the given
+// rate until ctx is canceled. Like top, it redraws the status on
+// a terminal. Otherwise, it prints the status repeatedly, as one
+// JSON object per line if jsonFlag is set.
+func watchStatus(ctx context.Context, client *kes.Client, rate time.Duration, jsonFlag, shortFlag bool, colorFlag colorOption) {
+	const (
+		ClearScreen = "\033[H\033[2J"
+		ShowCursor  = "\x1b[?25h"
+		HideCursor  = "\x1b[?25l"
+	)
+	redraw := isTerm(os.Stdout) && !jsonFlag
+	if redraw {
+		fmt.Print(HideCursor)
+		defer fmt.Print(ShowCursor)
+	}
+
+	ticker := time.NewTicker(rate)
+	defer ticker.Stop()
+
+	// The status of the same server is printed repeatedly until
+	// it becomes unreachable.
+	node := client
+	encoder := json.NewEncoder(os.Stdout)
+	for {
+		endpoint, details, detailsErr := keyStoreStatus(ctx, node)
+		if endpoint == "" {
+			node = client
+		} else {
+			node = pinEndpoint(client, endpoint)
+		}
+
+		start := time.Now()
+		status, err := node.Status(ctx)
+		latency := time.Since(start)
+		if err != nil && ctx.Err() != nil {
+			return
+		}
+
+		switch {
+		case err != nil && jsonFlag:
+			cli.Fatal(err)
+		case jsonFlag && detailsErr == nil:
+			encoder.Encode(details)
+		case jsonFlag:
+			encoder.Encode(status)
+		default:
+			var buf bytes.Buffer
+			if redraw {
+				buf.WriteString(ClearScreen)
+			}
+			if err != nil {
+				fmt.Fprintf(&buf, "%s %v\n", time.Now().Format(time.TimeOnly), err)
+			} else {
+				printStatus(&buf, node, status, latency, details, detailsErr == nil, shortFlag, colorFlag)
+			}
+			if !redraw {
+				buf.WriteByte('\n')
+			}
+			os.Stdout.Write(buf.Bytes())
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// printStatus writes the human-readable server status to w. The
+// details are only printed if hasDetails is true.
+func printStatus(w io.Writer, client *kes.Client, status kes.State, latency time.Duration, details api.StatusResponse, hasDetails, shortFlag bool, colorFlag colorOption) {
+	faint := tui.NewStyle()
+	dotStyle := tui.NewStyle()
+	endpointStyle := tui.NewStyle()
+	if colorFlag.Colorize() {
+		const (
+			ColorDot      = tui.Color("#00f700")
+			ColorEndpoint = tui.Color("#00afaf")
+		)
+		faint = faint.Faint(true)
+		dotStyle = dotStyle.Foreground(ColorDot).Bold(true)
+		endpointStyle = endpointStyle.Foreground(ColorEndpoint).Bold(true)
+	}
+
+	fmt.Fprintln(w, dotStyle.Render("●"), endpointStyle.Render(strings.TrimPrefix(client.Endpoints[0], "https://")))
+	if shortFlag {
+		return
+	}
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("  %-8s", "Version")),
+		status.Version,
+	)
+	switch {
+	case status.UpTime > 24*time.Hour:
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
+			fmt.Sprintf("%.f days %.f hours", status.UpTime.Hours()/24, math.Mod(status.UpTime.Hours(), 24)),
+		)
+	case status.UpTime > 1*time.Hour:
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
+			fmt.Sprintf("%.f hours", status.UpTime.Hours()),
+		)
+	case status.UpTime > 1*time.Minute:
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
+			fmt.Sprintf("%.f minutes", status.UpTime.Minutes()),
+		)
+	default:
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Uptime")),
+			fmt.Sprintf("%.f seconds", status.UpTime.Seconds()),
+		)
+	}
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("  %-8s", "Latency")),
+		latency.Round(time.Millisecond),
+	)
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("  %-8s", "OS")),
+		status.OS,
+	)
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("  %-8s", "CPUs")),
+		strconv.Itoa(status.UsableCPUs),
+		status.Arch,
+	)
+
+	if hasDetails && details.FIPS {
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "FIPS")),
+			"enabled",
+		)
+	}
+	if hasDetails && details.Cipher != "" {
+		cipher := details.Cipher
+		if details.CipherThroughput > 0 {
+			cipher += fmt.Sprintf(" %.0f keys/s", details.CipherThroughput)
+		}
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Cipher")),
+			cipher,
+		)
+	}
+	if hasDetails && details.Frozen {
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("  %-8s", "Frozen")),
+			"read-only, see 'kes admin freeze --status'",
+		)
+	}
+	fmt.Fprintln(w, faint.Render(fmt.Sprintf("  %-8s", "Memory")))
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("%3s %-6s", "·", "Heap")),
+		mem.FormatSize(mem.Size(status.HeapAlloc), 'D', 1),
+	)
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("%3s %-6s", "·", "Stack")),
+		mem.FormatSize(mem.Size(status.StackAlloc), 'D', 1),
+	)
+
+	if keystore := details; hasDetails && keystore.KeyStoreType != "" {
+		state := "reachable"
+		if keystore.KeyStoreUnreachable {
+			state = "unreachable"
+		}
+		if keystore.KeyStoreOffline {
+			state += ", offline"
+		}
+		switch {
+		case keystore.KeyStoreFailover && keystore.KeyStoreFailoverPending > 0:
+			state += fmt.Sprintf(", failed over to secondary (%d writes pending)", keystore.KeyStoreFailoverPending)
+		case keystore.KeyStoreFailover:
+			state += ", failed over to secondary"
+		case keystore.KeyStoreFailoverFailures > 0:
+			state += fmt.Sprintf(", primary failed %d times", keystore.KeyStoreFailoverFailures)
+		}
+		fmt.Fprintln(w, faint.Render(fmt.Sprintf("  %-8s", "KeyStore")))
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("%3s %-6s", "·", "Type")),
+			keystore.KeyStoreType,
+		)
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("%3s %-6s", "·", "State")),
+			state,
+		)
+		if keystore.KeyStoreOfflinePolicy != "" {
+			fmt.Fprintln(w,
+				faint.Render(fmt.Sprintf("%3s %-6s", "·", "Policy")),
+				"offline="+keystore.KeyStoreOfflinePolicy,
+			)
+		}
+		if !keystore.KeyStoreLastSuccess.IsZero() {
+			fmt.Fprintln(w,
+				faint.Render(fmt.Sprintf("%3s %-6s", "·", "Last")),
+				fmt.Sprintf("%s ago", time.Since(keystore.KeyStoreLastSuccess).Round(time.Second)),
+			)
+		}
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("%3s %-6s", "·", "P50")),
+			time.Duration(keystore.KeyStoreLatencyP50)*time.Millisecond,
+		)
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("%3s %-6s", "·", "P90")),
+			time.Duration(keystore.KeyStoreLatencyP90)*time.Millisecond,
+		)
+		fmt.Fprintln(w,
+			faint.Render(fmt.Sprintf("%3s %-6s", "·", "P99")),
+			time.Duration(keystore.KeyStoreLatencyP99)*time.Millisecond,
+		)
+	}
+	if !hasDetails {
+		return
+	}
+
+	keys := strconv.Itoa(details.NumKeys)
+	if details.NumKeys < 0 {
+		keys = "unknown"
+	}
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("  %-8s", "Objects")),
+		fmt.Sprintf("%s keys, %d policies, %d identities", keys, details.NumPolicies, details.NumIdentities),
+	)
+
+	hitRate := 0.0
+	if lookups := details.CacheHits + details.CacheMisses; lookups > 0 {
+		hitRate = 100 * float64(details.CacheHits) / float64(lookups)
+	}
+	fmt.Fprintln(w, faint.Render(fmt.Sprintf("  %-8s", "Cache")))
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("%3s %-6s", "·", "Size")),
+		fmt.Sprintf("%d keys", details.CacheEntries),
+	)
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("%3s %-6s", "·", "Hits")),
+		fmt.Sprintf("%.1f%% (%d hits, %d misses, %d coalesced)", hitRate, details.CacheHits, details.CacheMisses, details.CacheCoalesced),
+	)
+	fmt.Fprintln(w,
+		faint.Render(fmt.Sprintf("%3s %-6s", "·", "Evict")),
+		details.CacheEvictions,
+	)
+}
+
+// keyStoreStatus fetches the server status, including the
+// keystore details, from one of the client endpoints. It
+// returns the endpoint that has responded, if any.
+func keyStoreStatus(ctx context.Context, client *kes.Client) (string, api.StatusResponse, error) {
+	resp, err := sendGet(ctx, client, api.PathStatus)
+	if err != nil {
+		return "", api.StatusResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	endpoint := strings.TrimSuffix(resp.Request.URL.String(), api.PathStatus)
+	if resp.StatusCode != http.StatusOK {
+		return endpoint, api.StatusResponse{}, fmt.Errorf("%s (%d)", resp.Status, resp.StatusCode)
+	}
+	var status api.StatusResponse
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&status); err != nil {
+		return endpoint, api.StatusResponse{}, err
+	}
+	return endpoint, status, nil
+}
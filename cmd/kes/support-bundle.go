@@ -0,0 +1,218 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/sys"
+	"github.com/minio/kms-go/kes"
+	flag "github.com/spf13/pflag"
+)
+
+const supportBundleCmdUsage = `Usage:
+    kes support-bundle [options]
+
+Collects diagnostic information about the client and the KES
+server into a gzip-compressed tar archive that can be attached
+to issues and support cases. The archive contains no private
+keys or API keys.
+
+The server part requires access to the '/v1/support/bundle' API.
+If not accessible, the archive only contains client information.
+
+Options:
+    -k, --insecure           Skip TLS certificate validation.
+    -o, --output <file>      Write the archive to the given file. Use '-'
+                             to write to standard output.
+                             Defaults to: kes-support-bundle-<time>.tar.gz
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes support-bundle
+    $ kes support-bundle -o bundle.tar.gz
+`
+
+func supportBundleCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, supportBundleCmdUsage) }
+
+	var (
+		insecureSkipVerify bool
+		outputFlag         string
+	)
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.StringVarP(&outputFlag, "output", "o", "", "Write the archive to the given file")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes support-bundle --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes support-bundle --help'")
+	}
+	if outputFlag == "" {
+		outputFlag = "kes-support-bundle-" + time.Now().UTC().Format("20060102T150405Z") + ".tar.gz"
+	}
+
+	ctx, cancel := newContext()
+	defer cancel()
+
+	var out io.Writer = os.Stdout
+	if outputFlag != "-" {
+		file, err := os.OpenFile(outputFlag, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			cli.Fatalf("failed to create support bundle: %v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	gz := gzip.NewWriter(out)
+	archive := tar.NewWriter(gz)
+	writeFile := func(name string, data []byte) {
+		err := archive.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0o644,
+			Size:    int64(len(data)),
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = archive.Write(data)
+		}
+		if err != nil {
+			cli.Fatalf("failed to write support bundle: %v", err)
+		}
+	}
+	writeJSON := func(name string, v any) {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			cli.Fatalf("failed to write support bundle: %v", err)
+		}
+		writeFile(name, append(data, '\n'))
+	}
+
+	info, _ := sys.ReadBinaryInfo()
+	writeJSON("client/version.json", struct {
+		sys.BinaryInfo
+		OS   string
+		Arch string
+	}{BinaryInfo: info, OS: runtime.GOOS, Arch: runtime.GOARCH})
+
+	// Only include the names of env. variables - except for the
+	// server address and context - since they may contain API keys.
+	var env strings.Builder
+	for _, name := range []string{EnvServer, EnvContext, EnvAPIKey, EnvClientCert, EnvClientKey} {
+		switch value, ok := os.LookupEnv(name); {
+		case !ok:
+			fmt.Fprintf(&env, "%s is not set\n", name)
+		case name == EnvServer || name == EnvContext:
+			fmt.Fprintf(&env, "%s=%s\n", name, value)
+		default:
+			fmt.Fprintf(&env, "%s is set\n", name)
+		}
+	}
+	writeFile("client/env.txt", []byte(env.String()))
+
+	checks := runDoctor(ctx, insecureSkipVerify)
+	writeJSON("client/doctor.json", checks)
+
+	var serverErr error
+	if addr, cert, err := loadClientConfig(); err != nil {
+		serverErr = err
+	} else {
+		client := kes.NewClientWithConfig(addr, &tls.Config{
+			Certificates:       []tls.Certificate{cert},
+			InsecureSkipVerify: insecureSkipVerify,
+		})
+		serverErr = copyServerBundle(ctx, client, archive)
+	}
+	if serverErr != nil {
+		writeFile("server/error.txt", []byte(serverErr.Error()+"\n"))
+	}
+
+	if err := archive.Close(); err != nil {
+		cli.Fatalf("failed to write support bundle: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		cli.Fatalf("failed to write support bundle: %v", err)
+	}
+
+	if serverErr != nil {
+		fmt.Fprintf(os.Stderr, "Failed to fetch server diagnostics: %v\n", serverErr)
+	}
+	if outputFlag != "-" {
+		fmt.Fprintf(os.Stderr, "Support bundle written to '%s'\n", outputFlag)
+	}
+}
+
+// copyServerBundle fetches the server's support bundle and copies
+// all files into the server directory of the given archive.
+func copyServerBundle(ctx context.Context, client *kes.Client, archive *tar.Writer) error {
+	resp, err := sendGet(ctx, client, api.PathSupportBundle)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return api.ReadError(resp)
+	}
+
+	// Read the server's bundle completely before adding any file
+	// such that the archive does not contain a partial server bundle.
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		return fmt.Errorf("invalid server support bundle: %v", err)
+	}
+	type File struct {
+		Header *tar.Header
+		Data   []byte
+	}
+	var files []File
+	serverArchive := tar.NewReader(gz)
+	for {
+		hdr, err := serverArchive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid server support bundle: %v", err)
+		}
+		var data bytes.Buffer
+		if _, err = io.Copy(&data, serverArchive); err != nil {
+			return fmt.Errorf("invalid server support bundle: %v", err)
+		}
+		files = append(files, File{Header: hdr, Data: data.Bytes()})
+	}
+
+	for _, file := range files {
+		file.Header.Name = "server/" + file.Header.Name
+		if err = archive.WriteHeader(file.Header); err != nil {
+			return err
+		}
+		if _, err = archive.Write(file.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
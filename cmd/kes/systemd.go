@@ -0,0 +1,97 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes"
+)
+
+// sdNotify sends the state to the systemd service manager. It is
+// a no-op if the server has not been started by systemd as service
+// with 'Type=notify', i.e. if the NOTIFY_SOCKET env. variable is
+// not set.
+func sdNotify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns the interval at which systemd expects
+// watchdog pings from the server. It returns 0 if the watchdog is
+// not enabled for the server.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseUint(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec == 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// notifySystemd signals systemd that the server is ready once it
+// accepts connections and, if enabled, sends watchdog pings until
+// ctx is canceled. Then, it signals that the server is stopping.
+func notifySystemd(ctx context.Context, srv *kes.Server) {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	for srv.Addr() == "" {
+		select {
+		case <-ctx.Done():
+			ticker.Stop()
+			return
+		case <-ticker.C:
+		}
+	}
+	ticker.Stop()
+
+	if err := sdNotify("READY=1\nSTATUS=Listening on " + srv.Addr()); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to notify systemd: %v\n", err)
+		return
+	}
+	defer sdNotify("STOPPING=1")
+
+	interval := sdWatchdogInterval()
+	if interval == 0 {
+		<-ctx.Done()
+		return
+	}
+
+	// Ping the watchdog twice per interval such that a delayed
+	// ping does not cause systemd to restart the server.
+	ticker = time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := sdNotify("WATCHDOG=1"); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to send systemd watchdog ping: %v\n", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/tpm"
+	flag "github.com/spf13/pflag"
+)
+
+const tpmCmdUsage = `Usage:
+    kes tpm <command>
+
+Commands:
+    seal                     Seal a master key to the TPM.
+
+Options:
+    -h, --help               Print command line options.
+`
+
+func tpmCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, tpmCmdUsage) }
+
+	subCmds := commands{
+		"seal": sealTPMCmd,
+	}
+	if len(args) < 2 {
+		cmd.Usage()
+		os.Exit(2)
+	}
+	if cmd, ok := subCmds[args[1]]; ok {
+		cmd(args[1:])
+		return
+	}
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes tpm --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatalf("%q is not a tpm command. See 'kes tpm --help'", cmd.Arg(0))
+	}
+	cmd.Usage()
+	os.Exit(2)
+}
+
+const sealTPMCmdUsage = `Usage:
+    kes tpm seal [options] <master-key-file> <sealed-key-file>
+
+Seals the base64-encoded 256 bit master key of a fs keystore to
+the TPM 2.0 of this machine and writes the sealed master key to
+the sealed key file. If the master key file is '-', the master key
+is read from standard input.
+
+The TPM unseals the master key only while the selected PCRs have
+the values they have now. Once the master key has been sealed,
+remove the master key file or store it offline as backup.
+
+Options:
+        --pcr <n,...>            PCRs the master key is bound to. (default: 7)
+        --device <path>          Path to the TPM device. (default: /dev/tpmrm0)
+    -f, --force                  Overwrite an existing sealed key file.
+
+    -h, --help                   Print command line options.
+
+Examples:
+    $ kes tpm seal ./master.key ./master.key.sealed
+    $ echo $KES_FS_MASTER_KEY | kes tpm seal --pcr 0,7 - ./master.key.sealed
+`
+
+func sealTPMCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, sealTPMCmdUsage) }
+
+	var (
+		pcrFlag    []int
+		deviceFlag string
+		forceFlag  bool
+	)
+	cmd.IntSliceVar(&pcrFlag, "pcr", []int{7}, "PCRs the master key is bound to")
+	cmd.StringVar(&deviceFlag, "device", tpm.DefaultDevice, "Path to the TPM device")
+	cmd.BoolVarP(&forceFlag, "force", "f", false, "Overwrite an existing sealed key file")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes tpm seal --help'", err)
+	}
+	switch {
+	case cmd.NArg() == 0:
+		cli.Fatal("no master key file specified. See 'kes tpm seal --help'")
+	case cmd.NArg() == 1:
+		cli.Fatal("no sealed key file specified. See 'kes tpm seal --help'")
+	case cmd.NArg() > 2:
+		cli.Fatal("too many arguments. See 'kes tpm seal --help'")
+	}
+	keyFile, sealedFile := cmd.Arg(0), cmd.Arg(1)
+
+	var (
+		b   []byte
+		err error
+	)
+	if keyFile == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(keyFile)
+	}
+	if err != nil {
+		cli.Fatalf("failed to read master key: %v", err)
+	}
+	masterKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(b)))
+	if err != nil {
+		cli.Fatalf("invalid master key: %v", err)
+	}
+	if len(masterKey) != 32 {
+		cli.Fatalf("invalid master key: length is %d bytes but must be 32 bytes", len(masterKey))
+	}
+	if !forceFlag {
+		if _, err = os.Stat(sealedFile); err == nil {
+			cli.Fatalf("sealed key file '%s' already exists. Use --force to overwrite it", sealedFile)
+		}
+	}
+
+	sealedKey, err := tpm.Seal(deviceFlag, masterKey, pcrFlag)
+	if err != nil {
+		cli.Fatal(err)
+	}
+
+	// Ensure that the TPM can unseal the master key before
+	// the user deletes the plaintext master key.
+	unsealed, err := sealedKey.Unseal(deviceFlag)
+	if err != nil {
+		cli.Fatal(err)
+	}
+	if !bytes.Equal(unsealed, masterKey) {
+		cli.Fatal("unsealed master key does not match the master key")
+	}
+
+	sealed, err := json.MarshalIndent(sealedKey, "", "  ")
+	if err != nil {
+		cli.Fatal(err)
+	}
+	if err = os.WriteFile(sealedFile, append(sealed, '\n'), 0o600); err != nil {
+		cli.Fatalf("failed to write sealed key file: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	"github.com/minio/kes/internal/shamir"
+	flag "github.com/spf13/pflag"
+	"golang.org/x/term"
+)
+
+// sharePrefix is the prefix of text-encoded root key shares.
+const sharePrefix = "kes:v1:share:"
+
+const unsealCmdUsage = `Usage:
+    kes unseal [options] [<share>]
+    kes unseal init [options]
+
+Provides a root key share to a sealed KES server. Once enough
+shares have been provided, the server reconstructs its root key
+and starts serving key operations. If no share is specified, it
+is read from the terminal or standard input.
+
+Commands:
+    init                     Generate a root key and split it into shares.
+
+Options:
+        --status             Only print the seal status of the server.
+    -k, --insecure           Skip TLS certificate validation.
+        --json               Print the seal status in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes unseal
+    $ kes unseal --status
+`
+
+func unsealCmd(args []string) {
+	if len(args) > 1 && args[1] == "init" {
+		initUnsealCmd(args[1:])
+		return
+	}
+
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, unsealCmdUsage) }
+
+	var (
+		statusFlag         bool
+		insecureSkipVerify bool
+		jsonFlag           bool
+	)
+	cmd.BoolVar(&statusFlag, "status", false, "Only print the seal status of the server")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print the seal status in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes unseal --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 1:
+		cli.Fatal("too many arguments. See 'kes unseal --help'")
+	case statusFlag && cmd.NArg() > 0:
+		cli.Fatal("'--status' and a share are mutually exclusive. See 'kes unseal --help'")
+	}
+
+	var req api.UnsealRequest
+	if !statusFlag {
+		var text string
+		if cmd.NArg() == 1 {
+			text = cmd.Arg(0)
+		} else {
+			var err error
+			if text, err = readShare(); err != nil {
+				cli.Fatalf("failed to read share: %v", err)
+			}
+		}
+		share, err := parseShare(text)
+		if err != nil {
+			cli.Fatal(err)
+		}
+		req.Share = share
+	}
+
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	var resp api.UnsealResponse
+	if err := sendRequest(ctx, newClient(insecureSkipVerify), http.MethodPut, api.PathAdminUnseal, req, &resp); err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to unseal server: %v", err)
+	}
+
+	if jsonFlag {
+		if err := json.NewEncoder(os.Stdout).Encode(resp); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+	switch {
+	case !resp.Sealed && resp.Threshold == 0:
+		fmt.Println("Server is not sealed")
+	case !resp.Sealed:
+		fmt.Println("Server is unsealed")
+	default:
+		fmt.Printf("Server is sealed: %d of %d shares provided\n", resp.Progress, resp.Threshold)
+	}
+}
+
+const initUnsealCmdUsage = `Usage:
+    kes unseal init [options]
+
+Generates a new root key and splits it into shares. Hand out each
+share to a different operator. Any threshold shares can unseal the
+server while fewer shares reveal nothing about the root key.
+
+The root key itself is never shown. Add the printed seal config to
+the server config file to enable sealing.
+
+Options:
+    -n, --shares <n>         Number of shares. Defaults to 5.
+    -t, --threshold <t>      Number of shares required to unseal
+                             the server. Defaults to 3.
+        --json               Print shares and seal config in JSON format.
+
+    -h, --help               Print command line options.
+
+Examples:
+    $ kes unseal init
+    $ kes unseal init --shares 3 --threshold 2
+`
+
+func initUnsealCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, initUnsealCmdUsage) }
+
+	var (
+		sharesFlag    int
+		thresholdFlag int
+		jsonFlag      bool
+	)
+	cmd.IntVarP(&sharesFlag, "shares", "n", 5, "Number of shares")
+	cmd.IntVarP(&thresholdFlag, "threshold", "t", 3, "Number of shares required to unseal the server")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print shares and seal config in JSON format")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes unseal init --help'", err)
+	}
+	switch {
+	case cmd.NArg() > 0:
+		cli.Fatal("too many arguments. See 'kes unseal init --help'")
+	case thresholdFlag < 2:
+		cli.Fatal("invalid threshold: must be at least 2. See 'kes unseal init --help'")
+	case thresholdFlag > sharesFlag:
+		cli.Fatal("invalid threshold: must not exceed the number of shares. See 'kes unseal init --help'")
+	case sharesFlag > shamir.MaxShares:
+		cli.Fatalf("invalid number of shares: must not exceed %d. See 'kes unseal init --help'", shamir.MaxShares)
+	}
+
+	rootKey := make([]byte, 32)
+	if _, err := rand.Read(rootKey); err != nil {
+		cli.Fatalf("failed to generate root key: %v", err)
+	}
+	hash := sha256.Sum256(rootKey)
+	shares, err := shamir.Split(rootKey, sharesFlag, thresholdFlag)
+	if err != nil {
+		cli.Fatalf("failed to split root key: %v", err)
+	}
+	clear(rootKey)
+
+	encoded := make([]string, 0, len(shares))
+	for _, share := range shares {
+		encoded = append(encoded, sharePrefix+base64.StdEncoding.EncodeToString(share))
+	}
+
+	if jsonFlag {
+		type Output struct {
+			Shares      []string `json:"shares"`
+			Threshold   int      `json:"threshold"`
+			RootKeyHash string   `json:"root_key_hash"`
+		}
+		output := Output{
+			Shares:      encoded,
+			Threshold:   thresholdFlag,
+			RootKeyHash: hex.EncodeToString(hash[:]),
+		}
+		if err = json.NewEncoder(os.Stdout).Encode(output); err != nil {
+			cli.Fatal(err)
+		}
+		return
+	}
+
+	bold := tui.NewStyle()
+	if isTerm(os.Stdout) {
+		bold = bold.Bold(true)
+	}
+	var buffer strings.Builder
+	fmt.Fprintln(&buffer, "Your root key shares:")
+	fmt.Fprintln(&buffer)
+	for i, share := range encoded {
+		fmt.Fprintf(&buffer, "   %d: %s\n", i+1, bold.Render(share))
+	}
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "This is the only time they are shown. Hand out each share to a")
+	fmt.Fprintf(&buffer, "different operator. Any %d shares unseal the server.\n", thresholdFlag)
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "Your seal config:")
+	fmt.Fprintln(&buffer)
+	fmt.Fprintln(&buffer, "   seal:")
+	fmt.Fprintf(&buffer, "     threshold: %d\n", thresholdFlag)
+	fmt.Fprintf(&buffer, "     root_key_hash: %s", hex.EncodeToString(hash[:]))
+	cli.Println(buffer.String())
+}
+
+// readShare reads a share from the terminal without echoing
+// it or, if standard input is not a terminal, reads the first
+// line from standard input.
+func readShare() (string, error) {
+	if isTerm(os.Stdin) {
+		fmt.Fprint(os.Stderr, "Enter unseal share: ")
+		share, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr) // Add the newline again
+		return string(share), err
+	}
+	share, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && share == "" {
+		return "", err
+	}
+	return share, nil
+}
+
+// parseShare parses a text-encoded root key share.
+func parseShare(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, sharePrefix) {
+		return nil, errors.New("invalid share: missing prefix '" + sharePrefix + "'")
+	}
+	share, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, sharePrefix))
+	if err != nil || len(share) < 2 {
+		return nil, errors.New("invalid share: invalid encoding")
+	}
+	return share, nil
+}
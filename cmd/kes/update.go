@@ -6,17 +6,22 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
-	"os/signal"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"aead.dev/mem"
@@ -39,14 +44,32 @@ Options:
         --os <OS>            Download a binary for the specified OS.
         --arch <arch>        Download a binary for the specified CPU
                              architecture.
+        --channel <name>     Release channel: 'stable' or 'rc'. The 'rc'
+                             channel includes pre-releases.
+                             (default: stable)
+        --mirror <url>       Download the binary from a release mirror
+                             instead of GitHub. The mirror must serve
+                             '<url>/<version>/kes-<OS>-<arch>'.
+        --file <path>        Update from a local binary. Its signature is
+                             read from '<path>.minisig'.
         --minisign-key <key> Use the specified minisign public key to
                              verify the binary signature.
+        --dry-run            Show the current and new version without
+                             updating.
     -h, --help               Print command line options.
 
+The binary is verified using its minisign signature and must have
+been built for the requested version. If the release or local file
+provides a SHA-256 checksum file ('.sha256sum'), the binary is
+verified against the checksum as well.
+
 Examples:
     $ kes update
-    $ kes update v0.21.0
+    $ kes update 2024-01-11T13-09-29Z
+    $ kes update --channel rc --dry-run
     $ kes update -o ./kes-darwin-arm64 --os darwin --arch arm64
+    $ kes update --mirror https://mirror.example.com/kes 2024-01-11T13-09-29Z
+    $ kes update --file ./kes-linux-amd64
 `
 
 const defaultMinisignKey = "RWTx5Zr1tiHQLwG9keckT0c45M3AGeHD6IvimQHpyRywVWGbP1aVSGav"
@@ -61,14 +84,22 @@ func updateCmd(args []string) {
 		outputFile         string
 		osFlag             string
 		archFlag           string
+		channel            string
+		mirrorURL          string
+		localFile          string
 		minisignKey        string
+		dryRun             bool
 	)
 	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
 	cmd.BoolVarP(&downgrade, "downgrade", "d", false, "Allow downgrading to a previous version")
 	cmd.StringVarP(&outputFile, "output", "o", "", "Save new binary to a file instead of replacing the current binary")
 	cmd.StringVar(&osFlag, "os", runtime.GOOS, "Download a binary for the specified OS")
 	cmd.StringVar(&archFlag, "arch", runtime.GOARCH, "Download a binary for the specified CPU architecture")
+	cmd.StringVar(&channel, "channel", "stable", "Release channel: 'stable' or 'rc'")
+	cmd.StringVar(&mirrorURL, "mirror", "", "Download the binary from a release mirror instead of GitHub")
+	cmd.StringVar(&localFile, "file", "", "Update from a local binary")
 	cmd.StringVar(&minisignKey, "minisign-key", defaultMinisignKey, "Use the specified minisign public key to verify the binary signature")
+	cmd.BoolVar(&dryRun, "dry-run", false, "Show the current and new version without updating")
 	if err := cmd.Parse(args[1:]); err != nil {
 		if errors.Is(err, flag.ErrHelp) {
 			os.Exit(2)
@@ -78,6 +109,17 @@ func updateCmd(args []string) {
 	if cmd.NArg() > 1 {
 		cli.Fatal("too many arguments. See 'kes update --help'")
 	}
+	if channel != "stable" && channel != "rc" {
+		cli.Fatalf("invalid release channel '%s'. Use 'stable' or 'rc'", channel)
+	}
+	if localFile != "" {
+		if mirrorURL != "" {
+			cli.Fatal("cannot use '--file' and '--mirror' together. See 'kes update --help'")
+		}
+		if cmd.NArg() > 0 {
+			cli.Fatal("cannot specify a version when updating from a local file. See 'kes update --help'")
+		}
+	}
 	if osFlag != runtime.GOOS && outputFile == "" {
 		cli.Fatalf("cannot update to a '%s' binary on %s-%s. Use '--output'", osFlag, runtime.GOOS, runtime.GOARCH)
 	}
@@ -86,18 +128,18 @@ func updateCmd(args []string) {
 	}
 
 	const (
-		Latest      = "latest"
-		DownloadURL = "https://github.com/minio/kes/releases/download/%s/kes-%s-%s"
+		Latest     = "latest"
+		ReleaseURL = "https://github.com/minio/kes/releases/download/"
 	)
 	var publicKey minisign.PublicKey
 	if err := publicKey.UnmarshalText([]byte(minisignKey)); err != nil {
 		cli.Fatalf("failed to parse public key: %v", err)
 	}
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, os.Kill)
+	ctx, cancel := newContext()
 	defer cancel()
 
-	client := xhttp.Retry{
+	client := &xhttp.Retry{
 		N: 2,
 		Client: http.Client{
 			Transport: &http.Transport{
@@ -119,106 +161,111 @@ func updateCmd(args []string) {
 		},
 	}
 
-	releaseTagFormat := "2006-01-02T15-04-05Z"
-	// First, we check what's the latest version and do some
+	// First, we check what's the new version and do some
 	// version comparison - i.e. are we already running the
 	// latest version, are we downgrading, etc.
-	var version time.Time
-	if n := cmd.NArg(); n == 0 || n == 1 && cmd.Arg(0) == Latest {
-		const (
-			MaxBody   = 5 * mem.MiB
-			LatestURL = "https://api.github.com/repos/minio/kes/releases/latest"
-			Tag       = "tag_name"
-		)
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, LatestURL, nil)
+	var (
+		tag    string // The release tag or version of the local file
+		source string // The URL or path of the new binary
+	)
+	switch {
+	case localFile != "":
+		info, err := sys.ReadFileBinaryInfo(localFile)
 		if err != nil {
-			cli.Fatal(err)
+			cli.Fatalf("failed to read '%s': %v", localFile, err)
 		}
-		resp, err := client.Do(req)
-		if err != nil {
-			cli.Fatalf("failed to download KES release information: %v", err)
+		tag, source = info.Version, localFile
+	case cmd.NArg() == 0 || cmd.Arg(0) == Latest:
+		if mirrorURL != "" {
+			cli.Fatal("cannot determine the latest release of a mirror. Specify a version")
 		}
-		defer resp.Body.Close()
-
-		var response map[string]any
-		if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&response); err != nil {
+		var err error
+		if tag, err = latestRelease(ctx, client, channel == "rc"); err != nil {
 			cli.Fatalf("failed to download KES release information: %v", err)
 		}
-		tag, ok := response[Tag].(string)
-		if !ok {
-			cli.Fatalf("failed to download KES release information: invalid release tag '%v", response[Tag])
+	default:
+		tag = cmd.Arg(0)
+		if _, err := parseReleaseTag(tag); err != nil {
+			cli.Fatalf("invalid release version '%s': %v", tag, err)
 		}
-		version, err = time.Parse(releaseTagFormat, tag)
-		if err != nil {
-			cli.Fatalf("failed to parse KES release information: invalid release tag '%s': %v", tag, err)
+	}
+	if source == "" {
+		baseURL := ReleaseURL
+		if mirrorURL != "" {
+			baseURL = mirrorURL
 		}
-	} else {
-		v, err := time.Parse(releaseTagFormat, cmd.Arg(0))
+		var err error
+		source, err = url.JoinPath(baseURL, tag, fmt.Sprintf("kes-%s-%s", osFlag, archFlag))
 		if err != nil {
-			cli.Fatalf("invalid release version '%s': %v", cmd.Arg(0), err)
+			cli.Fatalf("invalid release URL: %v", err)
 		}
-		version = v
 	}
 
 	info, _ := sys.ReadBinaryInfo()
-	if cv, err := time.Parse(releaseTagFormat, info.Version); err == nil {
-		switch version.After(cv) {
-		case true:
-			cli.Println(fmt.Sprintf("Upgrading from '%v' to '%v'", cv, version))
-		case false:
-			if !downgrade {
-				cli.Println(fmt.Sprintf("Already on latest version %v", cv.Format(releaseTagFormat)))
+	cli.Println(fmt.Sprintf("Current version  %s", info.Version))
+	cli.Println(fmt.Sprintf("New version      %s", tag))
+	cli.Println(fmt.Sprintf("Source           %s", source))
+	cli.Println()
+
+	if cv, err := parseReleaseTag(info.Version); err == nil {
+		if version, err := parseReleaseTag(tag); err == nil {
+			switch {
+			case version.After(cv):
+				cli.Println(fmt.Sprintf("Upgrading from '%s' to '%s'", info.Version, tag))
+			case version.Equal(cv) && !downgrade:
+				cli.Println(fmt.Sprintf("Already on version %s", info.Version))
+				return
+			case !downgrade:
+				cli.Println(fmt.Sprintf("Already on a newer version than %s. Use '--downgrade'", tag))
 				return
+			default:
+				cli.Println(fmt.Sprintf("Downgrading from '%s' to '%s'", info.Version, tag))
 			}
-			cli.Println(fmt.Sprintf("Downgrading from '%v' to '%v'", cv, version))
 		}
 	}
+	if dryRun {
+		return
+	}
 
 	// We have to download the KES binary and the corresponding minisign signature
 	// file. We start with the signature.
-	binaryURL, err := url.JoinPath(
-		"https://github.com/minio/kes/releases/download/",
-		fmt.Sprintf("v%v", version),
-		fmt.Sprintf("kes-%s-%s", osFlag, archFlag),
-	)
-	if err != nil {
-		cli.Fatalf("failed to download minisign signature: %v", err)
-	}
-
 	cli.Print("Downloading KES minisign signature...")
 	startTime := time.Now()
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, binaryURL+".minisig", nil)
-	if err != nil {
-		cli.Fatal(err)
-	}
-	resp, err := client.Do(req)
+	signature, err := readUpdateFile(ctx, client, source+".minisig")
 	if err != nil {
 		cli.Fatalf("failed to download minisign signature: %v", err)
 	}
-	defer resp.Body.Close()
-
-	bytes, err := io.ReadAll(io.LimitReader(resp.Body, int64(1*mem.MB)))
-	if err != nil {
-		cli.Fatalf("failed to download minisign signature: %v", err)
-	}
-	var signature minisign.Signature
-	if err = signature.UnmarshalText(bytes); err != nil {
+	var sig minisign.Signature
+	if err = sig.UnmarshalText(signature); err != nil {
 		cli.Fatal(err)
 	}
 	cli.Println(fmt.Sprintf("\033[2K\rDownloaded KES minisign signature in %0.1f seconds", time.Since(startTime).Seconds()))
 
+	// The SHA-256 checksum is optional since not every
+	// release or mirror provides one.
+	var checksum []byte
+	switch sum, err := readUpdateFile(ctx, client, source+".sha256sum"); {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		cli.Fatalf("failed to download SHA-256 checksum: %v", err)
+	default:
+		fields := strings.Fields(string(sum))
+		if len(fields) == 0 {
+			cli.Fatal("failed to download SHA-256 checksum: checksum file is empty")
+		}
+		if checksum, err = hex.DecodeString(fields[0]); err != nil || len(checksum) != sha256.Size {
+			cli.Fatalf("failed to download SHA-256 checksum: invalid checksum '%s'", fields[0])
+		}
+	}
+
 	// Now download the actual KES binary.
 	cli.Print("Downloading KES binary ...")
 	startTime = time.Now()
-	req, err = http.NewRequestWithContext(ctx, http.MethodGet, binaryURL, nil)
-	if err != nil {
-		cli.Fatalf("failed to download binary: %v", err)
-	}
-	resp, err = client.Do(req)
+	binary, totalSize, err := openUpdateFile(ctx, client, source)
 	if err != nil {
 		cli.Fatalf("failed to download binary: %v", err)
 	}
-	defer resp.Body.Close()
+	defer binary.Close()
 
 	// If the outputFile does not exist we create an empty
 	// one such that selfupdate can do a successful rename
@@ -237,11 +284,12 @@ func updateCmd(args []string) {
 		}
 	}
 
-	totalSize := mem.Size(resp.ContentLength)
 	verifier := &minisignVerifier{
-		src:       minisign.NewReader(resp.Body),
+		src:       minisign.NewReader(binary),
 		key:       publicKey,
-		signature: bytes,
+		signature: signature,
+		hash:      sha256.New(),
+		checksum:  checksum,
 	}
 	progress := mem.NewProgressReader(verifier, 500*time.Millisecond, func(p mem.Progress) {
 		fmt.Print("\033[2K\r")
@@ -255,29 +303,199 @@ func updateCmd(args []string) {
 		}
 	})
 
-	if err = selfupdate.Apply(progress, selfupdate.Options{TargetPath: outputFile}); err != nil {
-		if err = selfupdate.RollbackError(err); err != nil {
-			cli.Fatalf("failed to download binary: %v", err)
+	// The signature only proves that the binary is a KES release
+	// but not which one. Hence, we check that the verified binary
+	// is the requested version before replacing the current one.
+	// Otherwise, a mirror could serve an older release instead.
+	// The version of a local file has been read from the file
+	// itself.
+	targetPath := outputFile
+	if targetPath == "" {
+		if targetPath, err = os.Executable(); err != nil {
+			cli.Fatal(err)
+		}
+	}
+	opts := selfupdate.Options{TargetPath: targetPath}
+	if err = selfupdate.PrepareAndCheckBinary(progress, opts); err != nil {
+		cli.Fatalf("failed to download binary: %v", err)
+	}
+	if localFile == "" {
+		if err = verifyUpdateVersion(targetPath, tag); err != nil {
+			cli.Fatalf("failed to verify binary: %v", err)
+		}
+	}
+	if err = selfupdate.CommitBinary(opts); err != nil {
+		if rerr := selfupdate.RollbackError(err); rerr != nil {
+			cli.Fatalf("failed to download binary: %v. Rollback failed: %v", err, rerr)
 		}
 		cli.Fatalf("failed to download binary: %v", err)
 	}
 	cli.Println(fmt.Sprintf("Downloaded KES binary in %0.1f seconds", time.Since(startTime).Seconds()))
+	if checksum != nil {
+		cli.Println(fmt.Sprintf("Verified SHA-256 checksum %x", checksum))
+	}
 	cli.Println()
-	cli.Println(fmt.Sprintf("Updated to KES v%v", version))
+	cli.Println(fmt.Sprintf("Updated to KES %s", tag))
+}
+
+// releaseTagFormat is the time format of KES release tags.
+const releaseTagFormat = "2006-01-02T15-04-05Z"
+
+// parseReleaseTag parses the release time of a KES release
+// tag. Release candidate tags carry a suffix after the
+// release time, like '2024-01-11T13-09-29Z-rc1'.
+func parseReleaseTag(tag string) (time.Time, error) {
+	if len(tag) > len(releaseTagFormat) {
+		tag = tag[:len(releaseTagFormat)]
+	}
+	return time.Parse(releaseTagFormat, tag)
+}
+
+// latestRelease returns the tag of the latest KES release.
+// If prerelease is true, it considers pre-releases as well.
+func latestRelease(ctx context.Context, client *xhttp.Retry, prerelease bool) (string, error) {
+	const (
+		MaxBody     = 5 * mem.MiB
+		LatestURL   = "https://api.github.com/repos/minio/kes/releases/latest"
+		ReleasesURL = "https://api.github.com/repos/minio/kes/releases?per_page=25"
+	)
+	type Release struct {
+		Tag        string `json:"tag_name"`
+		Draft      bool   `json:"draft"`
+		Prerelease bool   `json:"prerelease"`
+	}
+
+	reqURL := LatestURL
+	if prerelease {
+		reqURL = ReleasesURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", reqURL, resp.Status)
+	}
+
+	var releases []Release
+	if prerelease {
+		err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&releases)
+	} else {
+		releases = make([]Release, 1)
+		err = json.NewDecoder(mem.LimitReader(resp.Body, MaxBody)).Decode(&releases[0])
+	}
+	if err != nil {
+		return "", err
+	}
+	for _, r := range releases {
+		if r.Draft {
+			continue
+		}
+		if _, err := parseReleaseTag(r.Tag); err != nil {
+			return "", fmt.Errorf("invalid release tag '%s': %v", r.Tag, err)
+		}
+		return r.Tag, nil
+	}
+	return "", errors.New("no release found")
+}
+
+// openUpdateFile opens the file at src for reading. The src
+// is either an HTTP(S) URL or a local file path. It returns
+// the file size, or -1 if the size is unknown, and an error
+// wrapping os.ErrNotExist if the file does not exist.
+func openUpdateFile(ctx context.Context, client *xhttp.Retry, src string) (io.ReadCloser, mem.Size, error) {
+	if !strings.HasPrefix(src, "https://") && !strings.HasPrefix(src, "http://") {
+		f, err := os.Open(src)
+		if err != nil {
+			return nil, 0, err
+		}
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, mem.Size(stat.Size()), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, mem.Size(resp.ContentLength), nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("%s: %w", src, os.ErrNotExist)
+	default:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("%s: %s", src, resp.Status)
+	}
+}
+
+// readUpdateFile reads the small file, like a signature or
+// checksum file, at src. See openUpdateFile.
+func readUpdateFile(ctx context.Context, client *xhttp.Retry, src string) ([]byte, error) {
+	f, _, err := openUpdateFile(ctx, client, src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(mem.LimitReader(f, 1*mem.MB))
+}
+
+// verifyUpdateVersion checks that the new binary prepared
+// for replacing targetPath has been built for the given
+// release tag. It removes the new binary if its version
+// does not match.
+func verifyUpdateVersion(targetPath, tag string) error {
+	newPath := filepath.Join(filepath.Dir(targetPath), "."+filepath.Base(targetPath)+".new")
+
+	info, err := sys.ReadFileBinaryInfo(newPath)
+	if err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	want, err := parseReleaseTag(tag)
+	if err != nil {
+		os.Remove(newPath)
+		return err
+	}
+	if got, err := parseReleaseTag(info.Version); err != nil || !got.Equal(want) {
+		os.Remove(newPath)
+		return fmt.Errorf("binary version '%s' does not match release '%s'", info.Version, tag)
+	}
+	return nil
 }
 
 type minisignVerifier struct {
 	src       *minisign.Reader
 	key       minisign.PublicKey
 	signature []byte
+
+	hash     hash.Hash
+	checksum []byte // Optional SHA-256 checksum
 }
 
 func (r *minisignVerifier) Read(b []byte) (int, error) {
 	n, err := r.src.Read(b)
+	r.hash.Write(b[:n])
 	if errors.Is(err, io.EOF) {
 		if !r.src.Verify(r.key, r.signature) {
 			return 0, errors.New("kes: minisign signature verification failed")
 		}
+		if r.checksum != nil && subtle.ConstantTimeCompare(r.hash.Sum(nil), r.checksum) != 1 {
+			return 0, errors.New("kes: SHA-256 checksum verification failed")
+		}
 	}
 	return n, err
 }
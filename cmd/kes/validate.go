@@ -0,0 +1,151 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/kesconf"
+)
+
+// validateServerConfig runs all server config checks in order.
+// It parses the config file, loads the TLS certificate, verifies
+// the server settings, policies and identities and probes the
+// key store without starting a server. Checks that depend on a
+// previous, failed check are skipped.
+func validateServerConfig(ctx context.Context, filename string) []doctorCheck {
+	const (
+		CheckFile       = "Config file"
+		CheckTLS        = "TLS certificate"
+		CheckSettings   = "Server settings"
+		CheckIdentities = "Identities"
+		CheckKeyStore   = "Key store"
+
+		// ExpiryWarning is the time period before a certificate
+		// expires in which a warning is reported.
+		ExpiryWarning = 30 * 24 * time.Hour
+	)
+	checks := make([]doctorCheck, 0, 5)
+	pass := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkPass, Message: fmt.Sprintf(format, v...)})
+	}
+	warn := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkWarn, Message: fmt.Sprintf(format, v...)})
+	}
+	fail := func(name, format string, v ...any) {
+		checks = append(checks, doctorCheck{Name: name, Status: checkFail, Message: fmt.Sprintf(format, v...)})
+	}
+	skip := func(names ...string) []doctorCheck {
+		for _, name := range names {
+			checks = append(checks, doctorCheck{Name: name, Status: checkSkip, Message: "skipped due to previous failure"})
+		}
+		return checks
+	}
+
+	file, err := kesconf.ReadFile(filename)
+	if err != nil {
+		fail(CheckFile, "%v", err)
+		return skip(CheckTLS, CheckSettings, CheckIdentities, CheckKeyStore)
+	}
+	pass(CheckFile, "parsed '%s'", filename)
+
+	if file.TLS == nil {
+		fail(CheckTLS, "config contains no TLS configuration")
+		skip(CheckSettings, CheckIdentities)
+	} else if tlsConf, err := file.TLSConfig(); err != nil {
+		// The server settings contain the TLS config as well.
+		// Hence, verifying them would fail with the same error.
+		fail(CheckTLS, "%v", err)
+		skip(CheckSettings, CheckIdentities)
+	} else {
+		var leaf *x509.Certificate
+		if len(tlsConf.Certificates) > 0 {
+			leaf = tlsConf.Certificates[0].Leaf
+		}
+		switch now := time.Now(); {
+		case file.TLS.ACME != nil:
+			pass(CheckTLS, "certificate for %s is obtained via ACME", strings.Join(file.TLS.ACME.Domains, ", "))
+		case file.TLS.SPIFFE != nil && file.TLS.SPIFFE.WorkloadAPI != "":
+			pass(CheckTLS, "certificate is obtained from SPIFFE workload API '%s'", file.TLS.SPIFFE.WorkloadAPI)
+		case leaf == nil:
+			pass(CheckTLS, "loaded '%s'", file.TLS.Certificate)
+		case now.Before(leaf.NotBefore):
+			fail(CheckTLS, "'%s' not valid before %s", file.TLS.Certificate, leaf.NotBefore.Local().Format(time.RFC3339))
+		case now.After(leaf.NotAfter):
+			fail(CheckTLS, "'%s' expired at %s", file.TLS.Certificate, leaf.NotAfter.Local().Format(time.RFC3339))
+		case leaf.NotAfter.Sub(now) < ExpiryWarning:
+			warn(CheckTLS, "'%s' expires soon at %s", file.TLS.Certificate, leaf.NotAfter.Local().Format(time.RFC3339))
+		default:
+			pass(CheckTLS, "'%s' valid until %s", file.TLS.Certificate, leaf.NotAfter.Local().Format(time.RFC3339))
+		}
+
+		// The key store is probed separately. Hence, the settings
+		// are verified with a placeholder key store such that an
+		// unreachable key store does not hide config errors.
+		offline := *file
+		offline.KeyStore = nil
+		conf, err := offline.Config(ctx)
+		if err == nil {
+			if file.KeyStore != nil {
+				conf.Keys = &kes.MemKeyStore{}
+			}
+			err = kes.VerifyConfig(conf)
+			closeSPIFFESource(conf)
+		}
+		if err != nil {
+			fail(CheckSettings, "%v", err)
+			skip(CheckIdentities)
+		} else {
+			pass(CheckSettings, "%d policies, admin identity '%s'", len(file.Policies), file.Admin)
+
+			var ids int
+			var adminPolicy string
+			for name, policy := range file.Policies {
+				for _, id := range policy.Identities {
+					ids++
+					if id == file.Admin {
+						adminPolicy = name
+					}
+				}
+			}
+			if adminPolicy != "" {
+				warn(CheckIdentities, "policy '%s' is assigned to the admin identity and has no effect", adminPolicy)
+			} else {
+				pass(CheckIdentities, "%d identities assigned to policies", ids)
+			}
+		}
+	}
+
+	switch {
+	case file.KeyStore == nil && file.Replica != nil:
+		pass(CheckKeyStore, "read replica of '%s' uses no key store", file.Replica.Endpoint)
+	case file.KeyStore == nil:
+		fail(CheckKeyStore, "config contains no key store")
+	default:
+		store, err := file.KeyStore.Connect(ctx)
+		if err != nil {
+			fail(CheckKeyStore, "%v", err)
+			break
+		}
+		defer store.Close()
+
+		state, err := store.Status(ctx)
+		if err != nil {
+			fail(CheckKeyStore, "%v", err)
+			break
+		}
+		name := "key store"
+		if s, ok := store.(fmt.Stringer); ok {
+			name = s.String()
+		}
+		pass(CheckKeyStore, "%s reachable with latency %v", name, state.Latency.Round(time.Millisecond))
+	}
+	return checks
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	tui "github.com/charmbracelet/lipgloss"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cli"
+	flag "github.com/spf13/pflag"
+)
+
+const watchCmdUsage = `Usage:
+    kes watch [options]
+
+Options:
+        --type <prefix>      Only print events whose type starts with the
+                             prefix - e.g. 'key' or 'identity.created'.
+        --json               Print events as JSON.
+
+    -k, --insecure           Skip TLS certificate validation.
+    -h, --help               Print command line options.
+
+Watch prints key, policy and identity lifecycle events, like a key
+being created or deleted, until interrupted.
+
+Examples:
+    $ kes watch
+    $ kes watch --type key
+`
+
+func watchCmd(args []string) {
+	cmd := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	cmd.Usage = func() { fmt.Fprint(os.Stderr, watchCmdUsage) }
+
+	var (
+		typeFlag           string
+		jsonFlag           bool
+		insecureSkipVerify bool
+	)
+	cmd.StringVar(&typeFlag, "type", "", "Only print events whose type starts with the prefix")
+	cmd.BoolVar(&jsonFlag, "json", globalJSON, "Print events as JSON")
+	cmd.BoolVarP(&insecureSkipVerify, "insecure", "k", false, "Skip TLS certificate validation")
+	if err := cmd.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		cli.Fatalf("%v. See 'kes watch --help'", err)
+	}
+	if cmd.NArg() > 0 {
+		cli.Fatal("too many arguments. See 'kes watch --help'")
+	}
+
+	client := newClient(insecureSkipVerify)
+	ctx, cancelCtx := newContext()
+	defer cancelCtx()
+
+	resp, err := sendGet(ctx, client, api.PathWatch)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			os.Exit(1)
+		}
+		cli.Fatalf("failed to watch events: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		cli.Fatalf("failed to watch events: %v", api.ReadError(resp))
+	}
+
+	var (
+		typeStyle = tui.NewStyle().Foreground(tui.AdaptiveColor{Light: "#2E42D1", Dark: "#2e8bc0"}).Width(17).Inline(true)
+		encoder   = json.NewEncoder(os.Stdout)
+		decoder   = json.NewDecoder(resp.Body)
+	)
+	for {
+		var event api.LifecycleEvent
+		if err = decoder.Decode(&event); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, context.Canceled) {
+				return
+			}
+			cli.Fatalf("failed to watch events: %v", err)
+		}
+		if !strings.HasPrefix(event.Type, typeFlag) {
+			continue
+		}
+
+		if jsonFlag {
+			encoder.Encode(event)
+			continue
+		}
+		line := fmt.Sprintf("%s  %s  %s", event.Time.Local().Format("15:04:05"), typeStyle.Render(event.Type), event.Name)
+		if event.Policy != "" {
+			line += "  => " + event.Policy
+		}
+		fmt.Println(line)
+	}
+}
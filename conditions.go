@@ -0,0 +1,141 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// PolicyConditions are conditions a request must satisfy in
+// addition to the allow and deny patterns of a policy. They
+// apply to all requests of the identities assigned to the
+// policy.
+//
+// Empty conditions are ignored. Non-empty conditions must
+// all be satisfied.
+type PolicyConditions struct {
+	// SourceIPs is a list of IP ranges. If not empty, requests
+	// must originate from an IP address within one of them.
+	SourceIPs []netip.Prefix
+
+	// TimeWindows is a list of time windows. If not empty,
+	// requests must be received within one of them.
+	TimeWindows []TimeWindow
+
+	// TLSSANs is a list of subject alternative names. If not
+	// empty, the client certificate must contain at least one
+	// of them as DNS name, IP address, email address or URI.
+	TLSSANs []string
+}
+
+// TimeWindow is a recurring period of time within a day.
+type TimeWindow struct {
+	// Days are the days of the week on which the time window
+	// starts. If empty, the time window starts on every day.
+	Days []time.Weekday
+
+	// Start and End are the offsets since midnight at which the
+	// time window starts and ends. If End is not after Start,
+	// the time window ends on the next day.
+	Start, End time.Duration
+
+	// Location is the time zone of the time window. If nil,
+	// defaults to UTC.
+	Location *time.Location
+}
+
+// Contains reports whether t is within the time window.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+
+	hour, min, sec := t.Clock()
+	offset := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second
+	weekday := t.Weekday()
+
+	switch {
+	case w.Start < w.End:
+		if offset < w.Start || offset >= w.End {
+			return false
+		}
+	case offset >= w.Start:
+	case offset < w.End:
+		// The time window started on the previous day.
+		weekday = (weekday + 6) % 7
+	default:
+		return false
+	}
+	return len(w.Days) == 0 || slices.Contains(w.Days, weekday)
+}
+
+// verify checks whether the request, received at the given
+// time, satisfies the conditions. It returns an error if not.
+// A nil PolicyConditions is always satisfied.
+func (c *PolicyConditions) verify(req *http.Request, now time.Time) error {
+	if c == nil {
+		return nil
+	}
+
+	if len(c.SourceIPs) > 0 {
+		host, _, err := net.SplitHostPort(req.RemoteAddr)
+		if err != nil {
+			host = req.RemoteAddr
+		}
+		addr, err := netip.ParseAddr(host)
+		if err != nil {
+			return errors.New("source IP condition not satisfied: invalid remote address")
+		}
+		addr = addr.Unmap()
+		if !slices.ContainsFunc(c.SourceIPs, func(p netip.Prefix) bool { return p.Contains(addr) }) {
+			return errors.New("source IP condition not satisfied")
+		}
+	}
+
+	if len(c.TimeWindows) > 0 {
+		if !slices.ContainsFunc(c.TimeWindows, func(w TimeWindow) bool { return w.Contains(now) }) {
+			return errors.New("time condition not satisfied")
+		}
+	}
+
+	if len(c.TLSSANs) > 0 {
+		if req.TLS == nil || !hasSAN(req.TLS.PeerCertificates, c.TLSSANs) {
+			return errors.New("TLS SAN condition not satisfied")
+		}
+	}
+	return nil
+}
+
+// hasSAN reports whether the client leaf certificate contains
+// at least one of the given subject alternative names.
+func hasSAN(certs []*x509.Certificate, sans []string) bool {
+	for _, cert := range certs {
+		if cert.IsCA {
+			continue
+		}
+		for _, san := range sans {
+			if slices.Contains(cert.DNSNames, san) || slices.Contains(cert.EmailAddresses, san) {
+				return true
+			}
+			if ip := net.ParseIP(san); ip != nil && slices.ContainsFunc(cert.IPAddresses, ip.Equal) {
+				return true
+			}
+			if slices.ContainsFunc(cert.URIs, func(u *url.URL) bool { return u.String() == san }) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
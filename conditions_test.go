@@ -0,0 +1,160 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestTimeWindowContains(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range timeWindowContainsTests {
+		if got := test.Window.Contains(test.Time); got != test.Contains {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, got, test.Contains)
+		}
+	}
+}
+
+var timeWindowContainsTests = []struct {
+	Window   TimeWindow
+	Time     time.Time
+	Contains bool
+}{
+	{ // 0
+		Window:   TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour},
+		Time:     time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC),
+		Contains: true,
+	},
+	{ // 1
+		Window:   TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour},
+		Time:     time.Date(2024, 3, 4, 17, 0, 0, 0, time.UTC),
+		Contains: false,
+	},
+	{ // 2
+		Window:   TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour},
+		Time:     time.Date(2024, 3, 4, 8, 59, 59, 0, time.UTC),
+		Contains: false,
+	},
+	{ // 3 - Sunday
+		Window:   TimeWindow{Days: []time.Weekday{time.Monday, time.Friday}, Start: 9 * time.Hour, End: 17 * time.Hour},
+		Time:     time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC),
+		Contains: false,
+	},
+	{ // 4 - Friday
+		Window:   TimeWindow{Days: []time.Weekday{time.Monday, time.Friday}, Start: 9 * time.Hour, End: 17 * time.Hour},
+		Time:     time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC),
+		Contains: true,
+	},
+	{ // 5 - Saturday morning, window started on Friday
+		Window:   TimeWindow{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 2 * time.Hour},
+		Time:     time.Date(2024, 3, 9, 1, 0, 0, 0, time.UTC),
+		Contains: true,
+	},
+	{ // 6 - Friday morning, window started on Thursday
+		Window:   TimeWindow{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 2 * time.Hour},
+		Time:     time.Date(2024, 3, 8, 1, 0, 0, 0, time.UTC),
+		Contains: false,
+	},
+	{ // 7
+		Window:   TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour},
+		Time:     time.Date(2024, 3, 8, 12, 0, 0, 0, time.UTC),
+		Contains: false,
+	},
+	{ // 8 - 08:30 UTC is 09:30 CET
+		Window:   TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: time.FixedZone("CET", 3600)},
+		Time:     time.Date(2024, 3, 4, 8, 30, 0, 0, time.UTC),
+		Contains: true,
+	},
+	{ // 9 - 16:30 UTC is 17:30 CET
+		Window:   TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: time.FixedZone("CET", 3600)},
+		Time:     time.Date(2024, 3, 4, 16, 30, 0, 0, time.UTC),
+		Contains: false,
+	},
+}
+
+func TestPolicyConditionsVerify(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	for i, test := range policyConditionsVerifyTests {
+		req := &http.Request{RemoteAddr: test.RemoteAddr}
+		if len(test.DNSNames) > 0 {
+			req.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{DNSNames: test.DNSNames}},
+			}
+		}
+
+		err := test.Conditions.verify(req, now)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: verify should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify conditions: %v", i, err)
+		}
+	}
+}
+
+var policyConditionsVerifyTests = []struct {
+	Conditions *PolicyConditions
+	RemoteAddr string
+	DNSNames   []string
+	ShouldFail bool
+}{
+	{Conditions: nil, RemoteAddr: "127.0.0.1:7373"},                 // 0
+	{Conditions: &PolicyConditions{}, RemoteAddr: "127.0.0.1:7373"}, // 1
+	{ // 2
+		Conditions: &PolicyConditions{SourceIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+		RemoteAddr: "10.1.2.3:7373",
+	},
+	{ // 3
+		Conditions: &PolicyConditions{SourceIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+		RemoteAddr: "[::ffff:10.1.2.3]:7373",
+	},
+	{ // 4
+		Conditions: &PolicyConditions{SourceIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}},
+		RemoteAddr: "192.168.1.7:7373",
+		ShouldFail: true,
+	},
+	{ // 5
+		Conditions: &PolicyConditions{TimeWindows: []TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour}}},
+		RemoteAddr: "127.0.0.1:7373",
+	},
+	{ // 6
+		Conditions: &PolicyConditions{TimeWindows: []TimeWindow{{Start: 18 * time.Hour, End: 6 * time.Hour}}},
+		RemoteAddr: "127.0.0.1:7373",
+		ShouldFail: true,
+	},
+	{ // 7
+		Conditions: &PolicyConditions{TLSSANs: []string{"bastion.example.com"}},
+		RemoteAddr: "127.0.0.1:7373",
+		DNSNames:   []string{"bastion.example.com"},
+	},
+	{ // 8
+		Conditions: &PolicyConditions{TLSSANs: []string{"bastion.example.com"}},
+		RemoteAddr: "127.0.0.1:7373",
+		DNSNames:   []string{"app.example.com"},
+		ShouldFail: true,
+	},
+	{ // 9
+		Conditions: &PolicyConditions{TLSSANs: []string{"bastion.example.com"}},
+		RemoteAddr: "127.0.0.1:7373",
+		ShouldFail: true,
+	},
+	{ // 10
+		Conditions: &PolicyConditions{
+			SourceIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+			TLSSANs:   []string{"bastion.example.com"},
+		},
+		RemoteAddr: "10.1.2.3:7373",
+		DNSNames:   []string{"app.example.com"},
+		ShouldFail: true,
+	},
+}
@@ -5,12 +5,33 @@
 package kes
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/minio/kes/internal/shamir"
 	"github.com/minio/kms-go/kes"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Config is a structure that holds configuration for a KES server.
@@ -24,13 +45,80 @@ type Config struct {
 	//
 	// A KES server requires a TLS certificate. Therefore, either
 	// Config.Certificates, Config.GetCertificate or
-	// Config.GetConfigForClient must be set.
+	// Config.GetConfigForClient must be set, unless the server
+	// obtains its certificate via ACME or from a SPIFFE source.
 	//
 	// Further, the KES server has to request client certificates
 	// for mTLS authentication. Hence, Config.ClientAuth must be
 	// at least tls.RequestClientCert.
 	TLS *tls.Config
 
+	// FIPS restricts the server to FIPS 140 approved algorithms.
+	// For example, new keys are always AES-256 keys and existing
+	// ChaCha20-Poly1305 keys can neither be imported nor used.
+	// Further, the server only offers FIPS 140 approved TLS 1.2
+	// cipher suites and curves. TLS 1.3 cipher suites can only be
+	// restricted by building KES with the "fips" tag, which uses
+	// BoringCrypto as FIPS 140 validated crypto module.
+	//
+	// FIPS mode is always enabled if KES has been built with the
+	// "fips" tag. It can only be enabled when starting the server
+	// and cannot be disabled afterwards.
+	FIPS bool
+
+	// ACME controls whether the server obtains and renews its
+	// TLS certificate from an ACME certificate authority, like
+	// Let's Encrypt. The certificate is stored in the KeyStore.
+	// If nil, the server uses the certificate in the TLS config.
+	ACME *ACMEConfig
+
+	// SPIFFE controls whether the server accepts SPIFFE X.509
+	// SVIDs as client identities. If nil, the identity of a
+	// client is always its certificate public key hash.
+	//
+	// SPIFFE identities require verified client certificates.
+	// Hence, TLS.ClientAuth must be tls.VerifyClientCertIfGiven
+	// or tls.RequireAndVerifyClientCert.
+	SPIFFE *SPIFFEConfig
+
+	// OIDC controls whether clients may authenticate with a JSON
+	// Web Token (JWT), issued by an OpenID Connect provider, sent
+	// as bearer token instead of a client certificate. If nil,
+	// clients have to authenticate with a client certificate.
+	//
+	// Clients without a certificate can only connect if TLS
+	// client certificates are optional. Hence, TLS.ClientAuth
+	// should be tls.RequestClientCert or tls.VerifyClientCertIfGiven.
+	OIDC *OIDCConfig
+
+	// Revocation controls whether and how the server checks
+	// that client certificates have not been revoked. If nil,
+	// revoked client certificates are not detected.
+	//
+	// Revocation checks require verified client certificates.
+	// Hence, TLS.ClientAuth must be tls.VerifyClientCertIfGiven
+	// or tls.RequireAndVerifyClientCert.
+	Revocation *RevocationConfig
+
+	// TLSProxy controls whether TLS proxies, like nginx, may
+	// forward the certificates of the clients they proxy. If
+	// nil, requests sent by a proxy are authenticated as the
+	// proxy itself.
+	TLSProxy *TLSProxyConfig
+
+	// ProxyProtocol controls whether the server accepts PROXY
+	// protocol headers sent by L4 proxies and load balancers.
+	// If nil, the PROXY protocol is disabled.
+	ProxyProtocol *ProxyProtocolConfig
+
+	// HTTP controls the timeouts and HTTP/2 settings of client
+	// connections. If nil, the server uses reasonable defaults.
+	HTTP *HTTPConfig
+
+	// GRPC controls whether the server serves the gRPC API in
+	// addition to the HTTP API. If nil, the gRPC API is disabled.
+	GRPC *GRPCConfig
+
 	// Cache specifies how long the KES server caches keys from the
 	// KeyStore. If nil, caching is disabled.
 	Cache *CacheConfig
@@ -39,7 +127,19 @@ type Config struct {
 	// must be assigned to a policy only once.
 	Policies map[string]Policy
 
+	// PolicyStore controls whether policies created and assigned
+	// via the API are persisted. If nil, they are kept in memory
+	// only and get lost when the server restarts.
+	PolicyStore *PolicyStoreConfig
+
+	// Names controls which key, policy and identity names are
+	// valid. If nil, names must not be longer than 80 characters
+	// and only contain the characters [0-9A-Za-z-_].
+	Names *NameConfig
+
 	// Keys is the KeyStore the KES server fetches keys from.
+	// It must be nil if the server is a read replica and must
+	// not be nil otherwise.
 	Keys KeyStore
 
 	// Routes allows customization of the KES server API routes. It
@@ -49,6 +149,53 @@ type Config struct {
 	// The KES server uses sane defaults for all its API routes.
 	Routes map[string]RouteConfig
 
+	// Telemetry controls whether and where the KES server sends
+	// anonymous usage reports to. If nil, telemetry is disabled.
+	Telemetry *TelemetryConfig
+
+	// KeyUsage controls whether the server tracks how often and
+	// when keys are used. If nil, key usage is not tracked.
+	KeyUsage *KeyUsageConfig
+
+	// SoftDelete controls whether deleted keys can be recovered
+	// for some time. If nil, keys are deleted immediately.
+	SoftDelete *SoftDeleteConfig
+
+	// Approval controls whether deleting keys and changing
+	// policies requires the approval of a second identity.
+	// If nil, such requests are executed immediately.
+	Approval *ApprovalConfig
+
+	// Notifications are the targets the server publishes key,
+	// policy and identity lifecycle events to. If empty, no
+	// events are published.
+	Notifications []NotificationTarget
+
+	// Replication controls whether the server replicates its keys
+	// and identities to a secondary KES cluster. If nil, nothing
+	// is replicated.
+	Replication *ReplicationConfig
+
+	// MetricsPush controls whether and where the server pushes
+	// its metrics to. If nil, metrics are only exposed via the
+	// metrics API.
+	MetricsPush *MetricsPushConfig
+
+	// MetricsLabel controls whether the per-API request metrics
+	// are also labeled by the identity or policy that sent the
+	// request. If empty, they are only labeled by API.
+	MetricsLabel MetricsLabel
+
+	// MetricsListener controls whether the server serves the
+	// metrics and health APIs on a separate listener as well.
+	// If nil, they are only served by the main listener.
+	MetricsListener *MetricsListenerConfig
+
+	// TracerProvider is used to create OpenTelemetry spans for
+	// API requests and key store operations. If nil, tracing is
+	// disabled.
+	TracerProvider trace.TracerProvider
+
 	// ErrorLog is an optional handler for handling the server's
 	// error log events. If nil, defaults to a slog.TextHandler
 	// writing to os.Stderr. The server's error log level is
@@ -60,6 +207,48 @@ type Config struct {
 	// writing to os.Stdout. The server's audit log level is
 	// controlled by Server.AuditLevel.
 	AuditLog AuditHandler
+
+	// AuditTargets are optional handlers, like external log
+	// sinks, that receive all audit events in addition to
+	// AuditLog. In contrast to AuditLog, they are not subject
+	// to Server.AuditLevel. Each target decides itself whether
+	// it handles an event. Targets that implement io.Closer
+	// are closed once they are replaced or the server is closed.
+	AuditTargets []AuditHandler
+
+	// AuditCheckpoint controls whether the server periodically
+	// emits signed audit checkpoints to the audit log. If nil,
+	// no checkpoints are emitted.
+	AuditCheckpoint *AuditCheckpointConfig
+
+	// AuditStore controls whether the server persists audit
+	// events on disk such that they can be queried via the
+	// AuditQuery API. If nil, audit events are not persisted.
+	AuditStore *AuditStoreConfig
+
+	// Cluster controls whether the server is a member of a KES
+	// cluster that replicates keys and policy assignments among
+	// its members. If nil, the server runs standalone.
+	Cluster *ClusterConfig
+
+	// Replica makes the server a read replica of another KES
+	// server, the primary, that it fetches keys from. If nil,
+	// the server fetches keys from its own KeyStore.
+	Replica *ReplicaConfig
+
+	// ReplicaIdentities are the identities of the read replicas
+	// that may fetch keys from this server using the replica key
+	// API. Since the API returns keys in plaintext, a policy that
+	// allows the API is not sufficient. The API is disabled if
+	// empty.
+	ReplicaIdentities []kes.Identity
+
+	// Seal controls whether the KeyStore entries are encrypted
+	// with a root key that is split into shares. If not nil, the
+	// server starts sealed and rejects all key store operations
+	// until enough shares have been provided via the unseal API.
+	// If nil, entries are stored as provided.
+	Seal *SealConfig
 }
 
 // Policy is a KES policy with associated identities.
@@ -70,6 +259,35 @@ type Policy struct {
 
 	Deny map[string]kes.Rule // Set of deny rules
 
+	// Keys allow key operations on keys whose names match a
+	// pattern, in addition to the allow rules. Deny rules
+	// take precedence over key rules.
+	Keys []KeyRule
+
+	// Conditions restrict the requests of the policy's
+	// identities further. If nil, no conditions apply.
+	Conditions *PolicyConditions
+
+	// RateLimit limits the number of requests of the
+	// policy's identities. If nil, requests are not
+	// rate limited.
+	RateLimit *RateLimit
+
+	// Context requires encrypt, generate and decrypt requests
+	// of the policy's identities to carry an encryption context
+	// with certain fields. If nil, any context is accepted.
+	Context *ContextPolicy
+
+	// SSH restricts the SSH certificates the policy's identities
+	// may obtain via the SSH sign API. If nil, they cannot obtain
+	// any SSH certificates.
+	SSH *SSHPolicy
+
+	// Cert restricts the X.509 certificates the policy's
+	// identities may obtain via the cert issue API. If nil,
+	// they cannot obtain any certificates.
+	Cert *CertPolicy
+
 	Identities []kes.Identity
 }
 
@@ -104,6 +322,898 @@ type CacheConfig struct {
 	//
 	// Offline caching is disabled if ExpiryOffline <= 0.
 	ExpiryOffline time.Duration
+
+	// Prewarm controls whether the cache fetches all keys
+	// from the key store once it is created. Hence, the
+	// first requests after a (re)start are served from the
+	// cache instead of all hitting the key store at once.
+	//
+	// Prewarming happens in the background and is limited
+	// to keys that start with PrewarmPrefix, if not empty.
+	Prewarm       bool
+	PrewarmPrefix string
+
+	// Preload is a list of glob patterns, as defined by path.Match.
+	// Keys with a matching name are fetched into the cache once it
+	// is created. Until all keys have been fetched, the server
+	// reports that it is not ready to handle requests. Hence, a
+	// load balancer can hold back traffic until the cache is warm.
+	Preload []string
+
+	// PreloadFile is a file to which the names of all cached keys,
+	// most recently used first, are written periodically and on
+	// shutdown. Keys listed in the file are preloaded, like keys
+	// matching Preload. Hence, a restarted server warms its cache
+	// with the keys used before the restart.
+	PreloadFile string
+
+	// OfflinePolicy controls which requests are served while
+	// the key store is unreachable. By default, cached keys
+	// are used for any request (OfflineStale).
+	OfflinePolicy OfflinePolicy
+
+	// MaxEntries limits the number of keys in the cache. Once
+	// the cache is full, the EvictionPolicy controls whether
+	// keys get evicted to make room for new ones. If zero or
+	// negative, the cache is unbounded.
+	MaxEntries int
+
+	// EvictionPolicy controls how the cache behaves once it
+	// holds MaxEntries keys. By default, the least recently
+	// used key is evicted (EvictLRU).
+	EvictionPolicy EvictionPolicy
+
+	// Pin is a list of glob patterns, as defined by path.Match.
+	// Keys with a matching name are pinned. Pinned keys don't
+	// count towards MaxEntries and are never evicted because
+	// the cache is full or they haven't been used recently.
+	// The general cache expiry still applies.
+	Pin []string
+
+	// Zeroize controls whether the key material of cached keys
+	// is overwritten with zeros once keys are evicted from the
+	// cache or the cache is closed. Otherwise, evicted keys stay
+	// in memory until the garbage collector reuses it.
+	Zeroize bool
+}
+
+// EvictionPolicy controls which keys the KES server evicts
+// from its cache once the cache is full.
+type EvictionPolicy uint
+
+// Supported eviction policies.
+const (
+	// EvictLRU evicts the least recently used key to make
+	// room for a key that is not in the cache.
+	EvictLRU EvictionPolicy = iota
+
+	// EvictNone evicts no keys. Keys that are not in the
+	// cache are fetched from the key store on every request
+	// until there is room in the cache again.
+	//
+	// EvictNone may be preferable over EvictLRU when the
+	// keys are accessed in cycles larger than the cache.
+	EvictNone
+)
+
+// String returns the EvictionPolicy's string representation.
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictLRU:
+		return "lru"
+	case EvictNone:
+		return "none"
+	default:
+		return "invalid eviction policy " + strconv.Itoa(int(p))
+	}
+}
+
+// OfflinePolicy controls how the KES server behaves while
+// its key store is unreachable.
+type OfflinePolicy uint
+
+// Supported offline policies.
+const (
+	// OfflineStale serves all requests with cached keys.
+	// Cached keys remain usable for ExpiryOffline, if set.
+	OfflineStale OfflinePolicy = iota
+
+	// OfflineDecryptOnly serves only requests that do not
+	// produce new ciphertexts, like decrypt, with cached
+	// keys. Encrypt and generate requests are rejected.
+	OfflineDecryptOnly
+
+	// OfflineFailClosed rejects all requests that require
+	// a key and evicts all keys from the cache.
+	OfflineFailClosed
+)
+
+// String returns the OfflinePolicy's string representation.
+func (p OfflinePolicy) String() string {
+	switch p {
+	case OfflineStale:
+		return "stale"
+	case OfflineDecryptOnly:
+		return "decrypt-only"
+	case OfflineFailClosed:
+		return "fail-closed"
+	default:
+		return "invalid offline policy " + strconv.Itoa(int(p))
+	}
+}
+
+// NameConfig is a structure containing the rules for valid
+// key, policy and identity names.
+//
+// Some key stores support longer names or more characters than
+// the KES server allows by default. However, names should only
+// be relaxed if all key stores used by the KES server support
+// them.
+type NameConfig struct {
+	// MaxLength is the max. length of a name in bytes. If 0,
+	// defaults to 80. Otherwise, it must be between 64 and
+	// 1024.
+	MaxLength int
+
+	// Charset contains characters that are valid within names
+	// in addition to 0-9, A-Z, a-z, '-' and '_'. It may only
+	// contain the characters: . : @ = + , ~
+	//
+	// Names must not start with '.', even if Charset contains it.
+	Charset string
+}
+
+// TelemetryConfig is a structure containing the KES server
+// telemetry configuration.
+//
+// The server periodically sends an anonymous usage report to
+// the telemetry endpoint. A report contains the server version,
+// OS and CPU architecture, the type of the key store, the number
+// of policies and identities and how many requests have been
+// served. It never contains key names, identities, addresses or
+// any other data that identifies the deployment.
+type TelemetryConfig struct {
+	// Endpoint is the HTTP(S) URL usage reports are sent to
+	// using POST requests. It must not be empty.
+	Endpoint string
+
+	// Interval is the time between two usage reports. If 0,
+	// defaults to 24 hours. Otherwise, it must be at least
+	// one minute.
+	Interval time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *TelemetryConfig) clone() *TelemetryConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// KeyUsageConfig is a structure containing the KES server
+// key usage tracking configuration.
+//
+// The server counts the encrypt, decrypt and generate operations
+// per key and remembers when a key has been used last. Admins can
+// use this information to find and retire keys that are no longer
+// used.
+type KeyUsageConfig struct {
+	// Filename is the path of the file the key usage statistics
+	// are persisted to. The server reads the file when starting
+	// and writes it periodically. If empty, key usage statistics
+	// are kept in memory only and get lost when the server stops.
+	Filename string
+
+	// Interval is the time between two writes of the key usage
+	// file. If 0, defaults to 1 minute. Otherwise, it must be
+	// at least one second.
+	Interval time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *KeyUsageConfig) clone() *KeyUsageConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// PolicyStoreConfig is a structure containing the KES server
+// policy store configuration.
+//
+// The server persists the policies created and the policy
+// assignments made via the API and applies them on top of the
+// config policies. Hence, they survive restarts and config
+// reloads. Config policies take precedence over created
+// policies with the same name.
+type PolicyStoreConfig struct {
+	// Filename is the path of the file the policies and policy
+	// assignments are persisted to. The server reads the file
+	// when starting or reloading its config and writes it on
+	// every change. It must not be empty.
+	Filename string
+}
+
+// SoftDeleteConfig is a structure containing the KES server
+// soft-delete configuration.
+//
+// With soft-delete, deleting a key marks it for deletion. A key
+// marked for deletion cannot be used but can be recovered with
+// the undelete API until its recovery window has passed. Then,
+// the server deletes the key permanently.
+type SoftDeleteConfig struct {
+	// RecoveryWindow is the time after which a key, that has
+	// been marked for deletion, gets deleted permanently. If
+	// 0, defaults to DefaultRecoveryWindow. Otherwise, it must
+	// be at least one minute.
+	RecoveryWindow time.Duration
+}
+
+// ApprovalConfig is a structure containing the KES server
+// dual-control configuration.
+//
+// With dual-control, requests that delete keys or change
+// policies are not executed immediately. Instead, they wait
+// until another identity approves them via the approval API.
+// Identities issued via the IssueIdentity API act on behalf
+// of their issuer and cannot approve its requests, and vice
+// versa. Requests that are not approved within the Window
+// expire.
+//
+// Pending requests are kept in memory. They are lost when the
+// server restarts and are not shared with other cluster members.
+type ApprovalConfig struct {
+	// Window is the time period within which a request has to
+	// be approved. If 0, defaults to one hour. Otherwise, it
+	// must be at least one minute.
+	Window time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *ApprovalConfig) clone() *ApprovalConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// DefaultRecoveryWindow is the time after which a key, that has
+// been marked for deletion, gets deleted permanently if
+// SoftDeleteConfig.RecoveryWindow is not set.
+const DefaultRecoveryWindow = 7 * 24 * time.Hour
+
+// clone returns a copy of c or nil if c is nil.
+func (c *SoftDeleteConfig) clone() *SoftDeleteConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// recoveryWindow returns the recovery window or the
+// DefaultRecoveryWindow if it is not set.
+func (c *SoftDeleteConfig) recoveryWindow() time.Duration {
+	if c.RecoveryWindow <= 0 {
+		return DefaultRecoveryWindow
+	}
+	return c.RecoveryWindow
+}
+
+// ReplicationConfig is a structure containing the KES server
+// replication configuration.
+//
+// The server periodically pushes all keys that don't exist on the
+// target cluster to the target and assigns identities to the same
+// policies as on this server. The target is a separate KES cluster,
+// usually in a different region, that takes over when this cluster
+// becomes unavailable. Hence, replication is active-passive. Clients
+// should not create keys on the target while replication is enabled.
+//
+// Keys are replicated with all their versions and wrapped by a key
+// encryption key (KEK) that exists on both clusters. A key that gets
+// rotated on this server is replicated again. Keys that exist on both
+// clusters with the same version but differ are reported as conflicts
+// and never overwritten. Policies created via the API are replicated
+// as well. Deleting a key or policy or unassigning an identity is not
+// replicated such that an accidental deletion cannot spread to the
+// target cluster.
+type ReplicationConfig struct {
+	// Endpoint is the HTTPS URL of the target KES cluster. It
+	// must not be empty.
+	Endpoint string
+
+	// TLS is the client TLS configuration used to connect to
+	// the target cluster. It must contain a client certificate
+	// whose identity is allowed to list, describe, restore and
+	// decrypt keys, to describe, create and assign policies and
+	// to describe identities on the target. Usually, it is the
+	// target's admin identity.
+	TLS *tls.Config
+
+	// KEK is the name of the key that wraps all keys pushed to
+	// the target. It must exist, with the same key material, on
+	// both clusters, for example by importing the same key on
+	// each of them. The KEK itself is not replicated. It must
+	// not be empty.
+	KEK string
+
+	// Interval is the time between two replication runs. If 0,
+	// defaults to 5 minutes. Otherwise, it must be at least
+	// one second. Keys created on this server are replicated
+	// immediately.
+	Interval time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *ReplicationConfig) clone() *ReplicationConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.TLS = c.TLS.Clone()
+	return &clone
+}
+
+// DefaultReplicaInterval is the time between two cache warm-ups
+// of a read replica if ReplicaConfig.Interval is not set.
+const DefaultReplicaInterval = 1 * time.Minute
+
+// ReplicaConfig is a structure containing the KES server read
+// replica configuration.
+//
+// A read replica mirrors the keys of its primary KES server. It
+// fetches all keys from the primary into its cache when started
+// and then periodically, such that it serves encrypt, decrypt and
+// generate requests locally. Requests that would modify keys, like
+// creating, importing, rotating or deleting a key, are rejected.
+//
+// Policies and identities are not mirrored. They are configured
+// on the replica itself.
+type ReplicaConfig struct {
+	// Endpoint is the HTTPS URL of the primary KES server.
+	// It must not be empty.
+	Endpoint string
+
+	// TLS is the client TLS configuration used to connect to
+	// the primary. It must contain a client certificate whose
+	// identity is allowed to list keys on the primary and is
+	// one of the primary's ReplicaIdentities.
+	TLS *tls.Config
+
+	// Interval is the time between two cache warm-ups. If 0,
+	// defaults to 1 minute. Otherwise, it must be at least one
+	// second. Keys not yet cached are fetched from the primary
+	// on first use.
+	Interval time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *ReplicaConfig) clone() *ReplicaConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.TLS = c.TLS.Clone()
+	return &clone
+}
+
+// MetricsPushProtocol is the protocol used to push metrics.
+type MetricsPushProtocol string
+
+// Supported metrics push protocols.
+const (
+	// PushGateway pushes metrics to a Prometheus Pushgateway.
+	// Metrics are grouped by the job and the server's hostname
+	// as instance.
+	PushGateway MetricsPushProtocol = "pushgateway"
+
+	// RemoteWrite sends metrics to a Prometheus remote write
+	// endpoint, like Prometheus itself, Mimir or Thanos.
+	RemoteWrite MetricsPushProtocol = "remote_write"
+)
+
+// MetricsLabel is an additional label of the per-API request
+// metrics.
+type MetricsLabel string
+
+// Supported metrics labels.
+const (
+	// MetricsByIdentity labels requests by the identity that
+	// sent the request.
+	MetricsByIdentity MetricsLabel = "identity"
+
+	// MetricsByPolicy labels requests by the policy assigned
+	// to the identity that sent the request.
+	MetricsByPolicy MetricsLabel = "policy"
+)
+
+// MetricsPushConfig is a structure containing the KES server
+// metrics push configuration.
+//
+// The server periodically pushes the same metrics exposed by
+// the metrics API. It is meant for deployments that cannot
+// be scraped by Prometheus, like air-gapped ones.
+type MetricsPushConfig struct {
+	// Endpoint is the HTTP(S) URL metrics are pushed to.
+	// For a Pushgateway, it is the base URL - e.g.
+	// "https://pushgateway:9091". For remote write, it
+	// is the full URL - e.g. "https://mimir/api/v1/push".
+	Endpoint string
+
+	// Protocol is the protocol used to push metrics. If
+	// empty, defaults to PushGateway.
+	Protocol MetricsPushProtocol
+
+	// Job is the value of the 'job' label. If empty,
+	// defaults to "kes".
+	Job string
+
+	// Interval is the time between two pushes. If 0,
+	// defaults to 1 minute. Otherwise, it must be at
+	// least one second.
+	Interval time.Duration
+
+	// Username and Password are optional credentials for
+	// HTTP basic authentication.
+	Username string
+	Password string
+
+	// TLS is an optional client TLS configuration used to
+	// connect to HTTPS endpoints.
+	TLS *tls.Config
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *MetricsPushConfig) clone() *MetricsPushConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.TLS = c.TLS.Clone()
+	return &clone
+}
+
+// RevocationConfig is a structure containing the KES server
+// client certificate revocation configuration.
+type RevocationConfig struct {
+	// CRLFile is an optional path to a PEM or DER encoded
+	// certificate revocation list (CRL). A PEM file may
+	// contain multiple CRLs. Client certificates listed in
+	// it are rejected.
+	CRLFile string
+
+	// CRLReload is the time between two checks whether the
+	// CRL file has changed. If so, the server reloads it.
+	// If 0, defaults to 1 minute. Otherwise, it must be at
+	// least one second.
+	CRLReload time.Duration
+
+	// OCSP enables querying the OCSP responder listed in a
+	// client certificate for its revocation status. Responses
+	// are cached until their next update.
+	OCSP bool
+
+	// OCSPStrict rejects client certificates whose revocation
+	// status cannot be determined, e.g. because their OCSP
+	// responder is not reachable. By default, such client
+	// certificates are accepted.
+	OCSPStrict bool
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *RevocationConfig) clone() *RevocationConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// ACMEChallenge is the type of challenge a KES server solves to
+// prove control over its domains to an ACME certificate authority.
+type ACMEChallenge string
+
+// Supported ACME challenges.
+const (
+	// ACMEHTTP01 proves control over a domain by serving a token
+	// via HTTP on port 80.
+	ACMEHTTP01 ACMEChallenge = "http-01"
+
+	// ACMEDNS01 proves control over a domain by creating a DNS TXT
+	// record via a DNSProvider.
+	ACMEDNS01 ACMEChallenge = "dns-01"
+)
+
+// DNSProvider creates and removes the DNS TXT records required for
+// solving ACME DNS-01 challenges.
+type DNSProvider interface {
+	// Present creates a TXT record for the fully-qualified domain
+	// name fqdn with the given value. It should return once the
+	// record has propagated to the authoritative name servers.
+	Present(ctx context.Context, fqdn, value string) error
+
+	// CleanUp removes the TXT record created by Present.
+	CleanUp(ctx context.Context, fqdn, value string) error
+}
+
+// ACMEConfig is a structure containing the configuration for
+// obtaining and renewing the KES server certificate via ACME.
+type ACMEConfig struct {
+	// Directory is the ACME directory URL of the certificate
+	// authority. If empty, defaults to Let's Encrypt.
+	Directory string
+
+	// Email is an optional contact email address for the ACME
+	// account. The certificate authority may use it to notify
+	// about problems with issued certificates.
+	Email string
+
+	// Domains are the DNS names the certificate is issued for.
+	// It must not be empty.
+	Domains []string
+
+	// Challenge is the type of challenge the server solves for
+	// each domain. If empty, defaults to ACMEHTTP01.
+	Challenge ACMEChallenge
+
+	// HTTPAddr is the address the server listens on for HTTP-01
+	// challenges while obtaining a certificate. If empty, defaults
+	// to ":80".
+	HTTPAddr string
+
+	// DNS creates the TXT records for DNS-01 challenges. It must
+	// not be nil if the challenge is ACMEDNS01.
+	DNS DNSProvider
+
+	// RenewBefore is the time period before the certificate
+	// expires at which the server renews it. If 0, defaults to
+	// 30 days.
+	RenewBefore time.Duration
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *ACMEConfig) clone() *ACMEConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Domains = slices.Clone(c.Domains)
+	return &clone
+}
+
+// SPIFFESource provides the KES server's X.509 SVID and the X.509
+// bundles of SPIFFE trust domains, e.g. a SPIFFE Workload API
+// client. Updated returns a channel that is sent on whenever the
+// SVID or bundles change.
+type SPIFFESource interface {
+	x509svid.Source
+	x509bundle.Source
+
+	Updated() <-chan struct{}
+}
+
+// SPIFFEConfig is a structure containing the SPIFFE configuration
+// of a KES server.
+//
+// The identity of a client that presents an X.509 SVID of one of
+// the trust domains is its SPIFFE ID, e.g. "spiffe://example.org/app",
+// instead of its certificate public key hash. Policies are assigned
+// to SPIFFE IDs like to any other identity. Clients that present
+// other certificates keep their public key hash as identity.
+type SPIFFEConfig struct {
+	// TrustDomains are the SPIFFE trust domains, e.g. "example.org",
+	// whose X.509 SVIDs are accepted as client identities. It must
+	// not be empty.
+	//
+	// Without a Source, the TLS ClientCAs must only contain the
+	// authorities of these trust domains. Otherwise, an authority
+	// of one trust domain could issue SVIDs for another.
+	TrustDomains []string
+
+	// Source is an optional source of the server's X.509 SVID and
+	// the trust bundles. If set, the server uses its SVID as TLS
+	// certificate and trusts client certificates issued by the
+	// authorities of the trust domains' bundles. Further, an SVID
+	// is only accepted if it has been issued by an authority of
+	// its own trust domain.
+	Source SPIFFESource
+}
+
+// OIDCConfig is a structure containing the configuration for
+// authenticating clients with JSON Web Tokens (JWT) issued by
+// an OpenID Connect (OIDC) provider.
+//
+// A token is accepted if it has been signed by one of the
+// provider's keys, has been issued by the Issuer for the
+// Audience and has not expired. Then, the client gets the
+// policy whose claims are all contained in the token.
+type OIDCConfig struct {
+	// Issuer is the issuer URL of the OIDC provider, e.g.
+	// "https://accounts.example.com". The server fetches the
+	// provider's signing keys as specified by its discovery
+	// document at "<Issuer>/.well-known/openid-configuration".
+	Issuer string
+
+	// Audience is the audience tokens must be issued for. Tokens
+	// issued for other audiences are rejected. It must not be
+	// empty.
+	Audience string
+
+	// IdentityClaim is the name of the claim whose value is the
+	// client identity, e.g. within audit logs. The identity is
+	// namespaced by the issuer: "oidc:<issuer>#<value>". If empty,
+	// defaults to "sub".
+	IdentityClaim string
+
+	// Policies maps policy names to the claims a token must
+	// contain to get the policy assigned. A token contains a
+	// claim if the claim's value is equal to the given value or,
+	// for list claims like "groups", contains it.
+	//
+	// A token that contains the claims of no or more than one
+	// policy is rejected. A policy without any claims matches
+	// any token.
+	Policies map[string]map[string]string
+
+	// Client is the HTTP client used to fetch the provider's
+	// discovery document and signing keys. If nil, a client
+	// with a default timeout is used.
+	Client *http.Client
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *OIDCConfig) clone() *OIDCConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Policies = make(map[string]map[string]string, len(c.Policies))
+	for name, claims := range c.Policies {
+		clone.Policies[name] = maps.Clone(claims)
+	}
+	return &clone
+}
+
+// SealConfig is a structure containing the configuration
+// for sealing the server's KeyStore.
+//
+// The entries of a sealed KeyStore are encrypted with a
+// 256-bit root key that is never stored. Instead, the root
+// key is split into shares using Shamir's Secret Sharing
+// and distributed among multiple operators. Once Threshold
+// operators have provided their shares, the server has
+// reconstructed the root key and is unsealed.
+type SealConfig struct {
+	// Threshold is the number of shares required to
+	// reconstruct the root key. It must be at least 2.
+	Threshold int
+
+	// RootKeyHash is the SHA-256 hash of the root key. It is
+	// used to verify that the shares provided to unseal the
+	// server reconstruct the correct root key.
+	RootKeyHash []byte
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *SealConfig) clone() *SealConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.RootKeyHash = slices.Clone(c.RootKeyHash)
+	return &clone
+}
+
+// TLSProxyConfig is a structure containing the KES server
+// TLS proxy configuration.
+type TLSProxyConfig struct {
+	// Identities are the identities of the TLS proxies directly
+	// connected to the server. Requests sent by one of them must
+	// contain the certificate of the actual client within the
+	// CertHeader. Such requests are authenticated and audited as
+	// sent by the client. The client IP is taken from the
+	// X-Forwarded-For header, if present.
+	//
+	// A TLS proxy can act as any identity it has seen before,
+	// including the admin. Hence, proxies must be trusted.
+	Identities []kes.Identity
+
+	// CertHeader is the HTTP header containing the URL-escaped
+	// and PEM-encoded client certificate forwarded by a TLS
+	// proxy. If empty, defaults to "X-Tls-Client-Cert".
+	CertHeader string
+}
+
+// ProxyProtocolConfig is a structure containing the KES server
+// PROXY protocol configuration.
+type ProxyProtocolConfig struct {
+	// TrustedProxies are the networks of the L4 proxies and load
+	// balancers in front of the server. Connections from these
+	// networks must start with a PROXY protocol header, v1 or v2.
+	// The client address within the header is used as remote
+	// address of all requests sent over the connection, e.g. for
+	// audit events and policy conditions. Connections from other
+	// networks are used as they are.
+	TrustedProxies []netip.Prefix
+}
+
+// GRPCConfig is a structure containing the KES server gRPC
+// API configuration.
+type GRPCConfig struct {
+	// Addr is the network address the gRPC API listens on,
+	// like "0.0.0.0:7374". The gRPC API uses the TLS config
+	// of the server. Clients have to send a certificate.
+	Addr string
+}
+
+// MetricsListenerConfig is a structure containing the configuration
+// of a separate listener that only serves the metrics, health and
+// version APIs. It allows monitoring systems and liveness probes to
+// access these APIs without a client certificate.
+type MetricsListenerConfig struct {
+	// Addr is the network address the listener accepts
+	// connections on, like "127.0.0.1:7374".
+	Addr string
+
+	// TLS controls whether the listener uses the TLS config of
+	// the server. Clients are not required to send a certificate.
+	// If false, the listener accepts plaintext HTTP connections.
+	TLS bool
+}
+
+// HTTPConfig is a structure containing the KES server HTTP
+// connection configuration. In contrast to most other options,
+// changes require a server restart.
+type HTTPConfig struct {
+	// ReadHeaderTimeout is the max. time the server waits for
+	// the headers of a request. If 0, defaults to 5 seconds.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the max. time the server waits for an
+	// entire request, including its body. If 0, there is no
+	// timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the max. time the server takes to write
+	// a response. API routes with a timeout override it. If 0,
+	// there is no timeout.
+	//
+	// Streaming APIs without a timeout, like the audit log API,
+	// are interrupted once the timeout has been reached.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the max. time an idle keep-alive connection
+	// is kept open. If 0, defaults to 90 seconds.
+	IdleTimeout time.Duration
+
+	// MaxConcurrentStreams is the max. number of concurrent
+	// requests per HTTP/2 connection. If 0, defaults to 250.
+	MaxConcurrentStreams uint32
+
+	// DisableHTTP2 disables HTTP/2 such that all clients have
+	// to use HTTP/1.1.
+	DisableHTTP2 bool
+}
+
+// AuditCheckpointConfig is a structure containing the KES
+// server audit checkpoint configuration.
+type AuditCheckpointConfig struct {
+	// Interval is the time between two checkpoints. If 0,
+	// defaults to 5 minutes. Otherwise, it must be at least
+	// one second.
+	Interval time.Duration
+
+	// Signer signs the checkpoints. It must not be nil. The
+	// signer's public key must be an Ed25519, ECDSA or RSA
+	// public key.
+	Signer crypto.Signer
+
+	// MerkleTree enables the audit Merkle tree. If set, the
+	// server maintains a Merkle tree over all audit records,
+	// checkpoints contain its root and clients can fetch
+	// inclusion proofs for individual audit records.
+	//
+	// The tree is kept in memory and requires about 100 bytes
+	// per audit record. It can only be enabled when starting
+	// the server. Updating the server config does not enable
+	// or disable it.
+	MerkleTree bool
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *AuditCheckpointConfig) clone() *AuditCheckpointConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	return &clone
+}
+
+// AuditStoreConfig is a structure containing the KES server
+// audit store configuration.
+//
+// The audit store is a directory containing one file of audit
+// events per day. It can only be enabled when starting the
+// server. Updating the server config does not enable, disable
+// or change it.
+type AuditStoreConfig struct {
+	// Dir is the directory the audit events are stored in.
+	// It is created if it does not exist.
+	Dir string
+
+	// Retention is the time audit events are retained. Older
+	// events are removed, one day at a time. If 0, defaults
+	// to 30 days. Otherwise, it must be at least one day.
+	Retention time.Duration
+}
+
+// ClusterConfig is a structure containing the KES server
+// cluster configuration.
+//
+// Cluster members replicate key creations and deletions as well
+// as policy assignments using the Raft consensus protocol. Each
+// member stores the keys in its own key store. Hence, a cluster
+// provides high availability without requiring a highly available
+// key store. However, the key stores of different members must
+// not be shared. A cluster with N members tolerates the failure
+// of (N-1)/2 members.
+//
+// Clustering can only be enabled when starting the server.
+// Updating the server config does not enable, disable or
+// change it.
+type ClusterConfig struct {
+	// Addr is the HTTPS URL other members reach this server
+	// at, e.g. "https://10.1.2.1:7373". It must not be empty.
+	Addr string
+
+	// Dir is the directory the server persists its cluster
+	// state in. It is created if it does not exist.
+	Dir string
+
+	// Peers are the addresses of all initial members, including
+	// Addr, when bootstrapping a new cluster. They are ignored
+	// once the server has joined or bootstrapped a cluster.
+	Peers []string
+
+	// Join is the HTTPS URL of an existing member. If set and
+	// Dir contains no cluster state, the server asks this member
+	// to add it to the cluster before it starts.
+	Join string
+
+	// TLS is the client TLS configuration used to connect to
+	// other members. It must contain a client certificate whose
+	// identity is allowed to call the cluster APIs on all other
+	// members and is one of their Identities. Usually, it is
+	// the admin identity.
+	TLS *tls.Config
+
+	// Identities are the identities of all members, i.e. of
+	// their client certificates. Only these identities can
+	// send cluster messages, regardless of their policy.
+	Identities []kes.Identity
+
+	// Key is the 256-bit key shared by all members. It encrypts
+	// the keys replicated among the members and persisted in
+	// the cluster state.
+	Key []byte
+}
+
+// clone returns a copy of c or nil if c is nil.
+func (c *ClusterConfig) clone() *ClusterConfig {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	clone.Peers = slices.Clone(c.Peers)
+	clone.TLS = c.TLS.Clone()
+	clone.Identities = slices.Clone(c.Identities)
+	clone.Key = slices.Clone(c.Key)
+	return &clone
 }
 
 // RouteConfig is a structure holding API route configuration.
@@ -138,18 +1248,310 @@ type RouteConfig struct {
 	InsecureSkipAuth bool
 }
 
+// VerifyConfig reports whether conf is a valid server
+// configuration, including its policy definitions and
+// identity assignments. It performs the same checks as
+// [Server.ListenAndStart] and [Server.Update] but does
+// not start a server.
+func VerifyConfig(conf *Config) error {
+	if err := verifyConfig(conf); err != nil {
+		return err
+	}
+	names, err := newNameRules(conf.Names)
+	if err != nil {
+		return err
+	}
+	if _, _, _, err = initPolicies(conf.Policies, names); err != nil {
+		return err
+	}
+	_, err = newRevocationChecker(conf.Revocation)
+	return err
+}
+
 // verifyConfig reports whether the c is a valid Config
 // and contains at least a TLS certificate for the server
 // and a key store.
 func verifyConfig(c *Config) error {
-	if c == nil || c.TLS == nil || (len(c.TLS.Certificates) == 0 && c.TLS.GetCertificate == nil && c.TLS.GetConfigForClient == nil) {
+	if c == nil || c.TLS == nil {
 		return errors.New("kes: tls config contains no server certificate")
 	}
+	if c.ACME == nil && (c.SPIFFE == nil || c.SPIFFE.Source == nil) && len(c.TLS.Certificates) == 0 && c.TLS.GetCertificate == nil && c.TLS.GetConfigForClient == nil {
+		return errors.New("kes: tls config contains no server certificate")
+	}
+	if c.ACME != nil {
+		if len(c.ACME.Domains) == 0 {
+			return errors.New("kes: ACME config contains no domains")
+		}
+		for _, domain := range c.ACME.Domains {
+			if domain == "" || strings.ContainsAny(domain, "/: ") {
+				return errors.New("kes: invalid ACME domain '" + domain + "'")
+			}
+		}
+		if c.ACME.Directory != "" {
+			directory, err := url.Parse(c.ACME.Directory)
+			if err != nil || directory.Scheme != "https" || directory.Host == "" {
+				return errors.New("kes: invalid ACME directory '" + c.ACME.Directory + "'")
+			}
+		}
+		switch c.ACME.Challenge {
+		case "", ACMEHTTP01:
+		case ACMEDNS01:
+			if c.ACME.DNS == nil {
+				return errors.New("kes: ACME DNS-01 challenge requires a DNS provider")
+			}
+		default:
+			return errors.New("kes: invalid ACME challenge '" + string(c.ACME.Challenge) + "'")
+		}
+		if c.ACME.RenewBefore < 0 {
+			return errors.New("kes: ACME renewal period must not be negative")
+		}
+		if c.Keys == nil {
+			return errors.New("kes: ACME requires a key store to store the certificate")
+		}
+	}
 	if c.TLS.ClientAuth == tls.NoClientCert {
 		return errors.New("kes: tls client auth must request client certificate")
 	}
-	if c.Keys == nil {
+	if c.SPIFFE != nil {
+		if c.TLS.ClientAuth != tls.VerifyClientCertIfGiven && c.TLS.ClientAuth != tls.RequireAndVerifyClientCert {
+			return errors.New("kes: SPIFFE identities require verified client certificates")
+		}
+		if len(c.SPIFFE.TrustDomains) == 0 {
+			return errors.New("kes: SPIFFE config contains no trust domains")
+		}
+		for _, td := range c.SPIFFE.TrustDomains {
+			if _, err := spiffeid.TrustDomainFromString(td); err != nil {
+				return fmt.Errorf("kes: invalid SPIFFE trust domain '%s': %v", td, err)
+			}
+		}
+		if c.SPIFFE.Source != nil && c.ACME != nil {
+			return errors.New("kes: server certificate cannot be obtained via ACME and from a SPIFFE source")
+		}
+	}
+	if c.OIDC != nil {
+		issuer, err := url.Parse(c.OIDC.Issuer)
+		if err != nil || issuer.Scheme != "https" || issuer.Host == "" {
+			return errors.New("kes: invalid OIDC issuer '" + c.OIDC.Issuer + "'")
+		}
+		if c.OIDC.Audience == "" {
+			return errors.New("kes: OIDC config contains no audience")
+		}
+		if len(c.OIDC.Policies) == 0 {
+			return errors.New("kes: OIDC config contains no policies")
+		}
+		for name, claims := range c.OIDC.Policies {
+			for claim := range claims {
+				if claim == "" {
+					return fmt.Errorf("kes: OIDC policy '%s' contains an empty claim", name)
+				}
+			}
+		}
+	}
+	if c.Revocation != nil {
+		if c.TLS.ClientAuth != tls.VerifyClientCertIfGiven && c.TLS.ClientAuth != tls.RequireAndVerifyClientCert {
+			return errors.New("kes: certificate revocation checks require verified client certificates")
+		}
+		if c.Revocation.CRLFile == "" && !c.Revocation.OCSP {
+			return errors.New("kes: revocation config contains neither a CRL file nor enables OCSP")
+		}
+		if c.Revocation.CRLReload != 0 && c.Revocation.CRLReload < time.Second {
+			return errors.New("kes: CRL reload interval must be at least 1s")
+		}
+	}
+	if c.TLSProxy != nil {
+		if len(c.TLSProxy.Identities) == 0 {
+			return errors.New("kes: TLS proxy config contains no proxy identities")
+		}
+		for _, id := range c.TLSProxy.Identities {
+			if id.IsUnknown() {
+				return errors.New("kes: TLS proxy config contains an empty proxy identity")
+			}
+			if id == c.Admin {
+				return fmt.Errorf("kes: TLS proxy identity '%s' is already admin", id)
+			}
+		}
+	}
+	if c.HTTP != nil {
+		if c.HTTP.ReadHeaderTimeout < 0 || c.HTTP.ReadTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+			return errors.New("kes: HTTP timeouts must not be negative")
+		}
+	}
+	if c.GRPC != nil {
+		if _, _, err := net.SplitHostPort(c.GRPC.Addr); err != nil {
+			return fmt.Errorf("kes: invalid gRPC address '%s': %v", c.GRPC.Addr, err)
+		}
+	}
+	if c.MetricsListener != nil {
+		if _, _, err := net.SplitHostPort(c.MetricsListener.Addr); err != nil {
+			return fmt.Errorf("kes: invalid metrics listener address '%s': %v", c.MetricsListener.Addr, err)
+		}
+	}
+	if c.ProxyProtocol != nil {
+		if len(c.ProxyProtocol.TrustedProxies) == 0 {
+			return errors.New("kes: PROXY protocol config contains no trusted proxies")
+		}
+		for _, prefix := range c.ProxyProtocol.TrustedProxies {
+			if !prefix.IsValid() {
+				return errors.New("kes: PROXY protocol config contains an invalid trusted proxy network")
+			}
+		}
+	}
+	if c.Keys == nil && c.Replica == nil {
 		return errors.New("kes: config contains no key store")
 	}
+	if c.Cache != nil {
+		for _, pattern := range c.Cache.Pin {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("kes: invalid cache pin pattern '%s'", pattern)
+			}
+		}
+		for _, pattern := range c.Cache.Preload {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("kes: invalid cache preload pattern '%s'", pattern)
+			}
+		}
+	}
+	if _, err := newNameRules(c.Names); err != nil {
+		return err
+	}
+	if c.Telemetry != nil {
+		endpoint, err := url.Parse(c.Telemetry.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return errors.New("kes: invalid telemetry endpoint '" + c.Telemetry.Endpoint + "'")
+		}
+		if c.Telemetry.Interval != 0 && c.Telemetry.Interval < time.Minute {
+			return errors.New("kes: telemetry interval must be at least 1m")
+		}
+	}
+	if c.PolicyStore != nil {
+		if c.PolicyStore.Filename == "" {
+			return errors.New("kes: policy store config contains no filename")
+		}
+		if c.Cluster != nil {
+			return errors.New("kes: policy store and cluster config are mutually exclusive")
+		}
+	}
+	if c.KeyUsage != nil && c.KeyUsage.Interval != 0 && c.KeyUsage.Interval < time.Second {
+		return errors.New("kes: key usage interval must be at least 1s")
+	}
+	if c.SoftDelete != nil && c.SoftDelete.RecoveryWindow != 0 && c.SoftDelete.RecoveryWindow < time.Minute {
+		return errors.New("kes: soft-delete recovery window must be at least 1m")
+	}
+	if c.Approval != nil && c.Approval.Window != 0 && c.Approval.Window < time.Minute {
+		return errors.New("kes: approval window must be at least 1m")
+	}
+	if c.Replication != nil {
+		endpoint, err := url.Parse(c.Replication.Endpoint)
+		if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+			return errors.New("kes: invalid replication endpoint '" + c.Replication.Endpoint + "'")
+		}
+		if c.Replication.TLS == nil || (len(c.Replication.TLS.Certificates) == 0 && c.Replication.TLS.GetClientCertificate == nil) {
+			return errors.New("kes: replication tls config contains no client certificate")
+		}
+		if c.Replication.KEK == "" {
+			return errors.New("kes: replication KEK is empty")
+		}
+		if c.Replication.Interval != 0 && c.Replication.Interval < time.Second {
+			return errors.New("kes: replication interval must be at least 1s")
+		}
+	}
+	if c.MetricsPush != nil {
+		endpoint, err := url.Parse(c.MetricsPush.Endpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return errors.New("kes: invalid metrics push endpoint '" + c.MetricsPush.Endpoint + "'")
+		}
+		if p := c.MetricsPush.Protocol; p != "" && p != PushGateway && p != RemoteWrite {
+			return errors.New("kes: invalid metrics push protocol '" + string(p) + "'")
+		}
+		if c.MetricsPush.Interval != 0 && c.MetricsPush.Interval < time.Second {
+			return errors.New("kes: metrics push interval must be at least 1s")
+		}
+	}
+	if l := c.MetricsLabel; l != "" && l != MetricsByIdentity && l != MetricsByPolicy {
+		return errors.New("kes: invalid metrics label '" + string(l) + "'")
+	}
+	if c.AuditCheckpoint != nil {
+		if c.AuditCheckpoint.Signer == nil {
+			return errors.New("kes: audit checkpoint config contains no signer")
+		}
+		switch c.AuditCheckpoint.Signer.Public().(type) {
+		case ed25519.PublicKey, *ecdsa.PublicKey, *rsa.PublicKey:
+		default:
+			return fmt.Errorf("kes: unsupported audit checkpoint public key type '%T'", c.AuditCheckpoint.Signer.Public())
+		}
+		if c.AuditCheckpoint.Interval != 0 && c.AuditCheckpoint.Interval < time.Second {
+			return errors.New("kes: audit checkpoint interval must be at least 1s")
+		}
+	}
+	if c.Cluster != nil {
+		addr, err := url.Parse(c.Cluster.Addr)
+		if err != nil || addr.Scheme != "https" || addr.Host == "" {
+			return errors.New("kes: invalid cluster address '" + c.Cluster.Addr + "'")
+		}
+		if c.Cluster.Dir == "" {
+			return errors.New("kes: cluster config contains no state directory")
+		}
+		for _, peer := range c.Cluster.Peers {
+			if addr, err := url.Parse(peer); err != nil || addr.Scheme != "https" || addr.Host == "" {
+				return errors.New("kes: invalid cluster peer '" + peer + "'")
+			}
+		}
+		if c.Cluster.Join != "" {
+			if addr, err := url.Parse(c.Cluster.Join); err != nil || addr.Scheme != "https" || addr.Host == "" {
+				return errors.New("kes: invalid cluster join address '" + c.Cluster.Join + "'")
+			}
+		}
+		if c.Cluster.TLS == nil || (len(c.Cluster.TLS.Certificates) == 0 && c.Cluster.TLS.GetClientCertificate == nil) {
+			return errors.New("kes: cluster tls config contains no client certificate")
+		}
+		if len(c.Cluster.Identities) == 0 {
+			return errors.New("kes: cluster config contains no member identities")
+		}
+		for _, id := range c.Cluster.Identities {
+			if id.IsUnknown() {
+				return errors.New("kes: cluster member identity is empty")
+			}
+		}
+		if len(c.Cluster.Key) != clusterKeySize {
+			return fmt.Errorf("kes: cluster key must be %d bytes", clusterKeySize)
+		}
+	}
+	if c.Replica != nil {
+		if c.Keys != nil {
+			return errors.New("kes: key store and replica config are mutually exclusive")
+		}
+		if c.Cluster != nil {
+			return errors.New("kes: a read replica cannot be a cluster member")
+		}
+		endpoint, err := url.Parse(c.Replica.Endpoint)
+		if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+			return errors.New("kes: invalid replica endpoint '" + c.Replica.Endpoint + "'")
+		}
+		if c.Replica.TLS == nil || (len(c.Replica.TLS.Certificates) == 0 && c.Replica.TLS.GetClientCertificate == nil) {
+			return errors.New("kes: replica tls config contains no client certificate")
+		}
+		if c.Replica.Interval != 0 && c.Replica.Interval < time.Second {
+			return errors.New("kes: replica interval must be at least 1s")
+		}
+	}
+	for _, id := range c.ReplicaIdentities {
+		if id.IsUnknown() {
+			return errors.New("kes: replica identity is empty")
+		}
+	}
+	if c.Seal != nil {
+		if c.Seal.Threshold < 2 || c.Seal.Threshold > shamir.MaxShares {
+			return fmt.Errorf("kes: seal threshold must be between 2 and %d", shamir.MaxShares)
+		}
+		if len(c.Seal.RootKeyHash) != sha256.Size {
+			return errors.New("kes: seal config contains no valid root key hash")
+		}
+		if c.Replica != nil {
+			return errors.New("kes: a read replica cannot be sealed")
+		}
+		if c.Cluster != nil {
+			return errors.New("kes: seal and cluster config are mutually exclusive")
+		}
+	}
 	return nil
 }
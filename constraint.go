@@ -0,0 +1,63 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+)
+
+var (
+	errKeyEncryptNotPermitted = api.NewError(http.StatusForbidden, "key usage does not permit encryption")
+	errKeyDecryptNotPermitted = api.NewError(http.StatusForbidden, "key usage does not permit decryption")
+	errKeySSHNotPermitted     = api.NewError(http.StatusForbidden, "key usage does not permit signing SSH certificates")
+	errKeyCertNotPermitted    = api.NewError(http.StatusForbidden, "key usage does not permit issuing certificates")
+	errKeyHMACNotPermitted    = api.NewError(http.StatusForbidden, "key usage does not permit computing HMACs")
+	errKeySignNotPermitted    = api.NewError(http.StatusForbidden, "key usage does not permit signing")
+	errKeyVerifyNotPermitted  = api.NewError(http.StatusForbidden, "key usage does not permit verifying signatures")
+	errKeyECDHNotPermitted    = api.NewError(http.StatusForbidden, "key usage does not permit ECDH key agreements")
+	errKeyExpired             = api.NewError(http.StatusForbidden, "key has expired: only decryption is permitted")
+)
+
+// checkKeyConstraints returns an error if the key must not be used
+// for the operation op at time now.
+//
+// A key, once expired, can only be used to decrypt ciphertexts
+// produced before the key has expired.
+func checkKeyConstraints(key *crypto.KeyVersion, op crypto.KeyUsage, now time.Time) api.Error {
+	switch op {
+	case crypto.UsageSSH:
+		if key.Usage&crypto.UsageSSH == 0 {
+			return errKeySSHNotPermitted
+		}
+	case crypto.UsageCert:
+		if key.Usage&crypto.UsageCert == 0 {
+			return errKeyCertNotPermitted
+		}
+	default:
+		if !key.Usage.Permits(op) {
+			switch op {
+			case crypto.UsageDecrypt:
+				return errKeyDecryptNotPermitted
+			case crypto.UsageHMAC:
+				return errKeyHMACNotPermitted
+			case crypto.UsageSign:
+				return errKeySignNotPermitted
+			case crypto.UsageVerify:
+				return errKeyVerifyNotPermitted
+			case crypto.UsageECDH:
+				return errKeyECDHNotPermitted
+			}
+			return errKeyEncryptNotPermitted
+		}
+	}
+	if op != crypto.UsageDecrypt && !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt) {
+		return errKeyExpired
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestCheckKeyConstraints(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	for i, test := range checkKeyConstraintsTests {
+		err := checkKeyConstraints(&crypto.KeyVersion{Usage: test.Usage, ExpiresAt: test.ExpiresAt}, test.Op, now)
+		if err != test.Err {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, err, test.Err)
+		}
+	}
+}
+
+func TestKeyConstraints(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{
+		Usage:     []string{"encrypt"},
+		ExpiresAt: time.Now().Add(time.Hour),
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key-2", api.CreateKeyRequest{
+		ExpiresAt: time.Now().Add(-time.Hour),
+	}); err == nil {
+		t.Fatal("Created key with expiration date in the past")
+	}
+
+	ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if _, err = client.Decrypt(ctx, "my-key", ciphertext, nil); err == nil {
+		t.Fatal("Decrypted with encrypt-only key")
+	}
+}
+
+func TestKeyConstraintsHMAC(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"decrypt-key", api.CreateKeyRequest{
+		Usage: []string{"decrypt"},
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = srv.state.Load().Keys.Create(ctx, "expired-key", crypto.KeyVersion{
+		Key:       key,
+		HMACKey:   hmac,
+		CreatedAt: time.Now().Add(-2 * time.Hour).UTC(),
+		ExpiresAt: time.Now().Add(-time.Hour).UTC(),
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	message := []byte("Hello World")
+	for _, test := range []struct {
+		Path string
+		Body any
+	}{
+		{Path: api.PathKeyHMAC, Body: api.HMACRequest{Message: message}},
+		{Path: api.PathKeyECDH, Body: api.ECDHKeyRequest{}},
+		{Path: api.PathKeySign, Body: api.SignKeyRequest{Message: message}},
+		{Path: api.PathKeyVerify, Body: api.VerifyKeyRequest{Message: message, Signature: message}},
+	} {
+		for _, name := range []string{"decrypt-key", "expired-key"} {
+			var kesErr kes.Error
+			err := sendRequest(ctx, client, http.MethodPut, test.Path+name, test.Body)
+			if !errors.As(err, &kesErr) || kesErr.Status() != http.StatusForbidden {
+				t.Fatalf("Path '%s': key '%s': got '%v' - want status '%d'", test.Path, name, err, http.StatusForbidden)
+			}
+		}
+	}
+
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"hmac-key", api.CreateKeyRequest{
+		Usage: []string{"hmac"},
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyHMAC+"hmac-key", api.HMACRequest{Message: message}); err != nil {
+		t.Fatalf("Failed to compute HMAC: %v", err)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeySign+"hmac-key", api.SignKeyRequest{Message: message}); err == nil {
+		t.Fatal("Signed message with HMAC-only key")
+	}
+}
+
+func TestCreateKeyAlgorithm(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for _, alg := range []string{"AES256", "ChaCha20"} {
+		name := "my-key-" + alg
+		if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+name, api.CreateKeyRequest{Algorithm: alg}); err != nil {
+			t.Fatalf("Failed to create '%s' key: %v", alg, err)
+		}
+		info, err := client.DescribeKey(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to describe key '%s': %v", name, err)
+		}
+		if info.Algorithm.String() != alg {
+			t.Fatalf("Invalid key algorithm: got '%v' - want '%s'", info.Algorithm, alg)
+		}
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{Algorithm: "DES"}); err == nil {
+		t.Fatal("Created key with unsupported algorithm")
+	}
+}
+
+func TestDerivedKey(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{Derived: true}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := client.GenerateKey(ctx, "my-key", nil); err == nil {
+		t.Fatal("Generated data key without context")
+	}
+
+	dek, err := client.GenerateKey(ctx, "my-key", []byte("my-context"))
+	if err != nil {
+		t.Fatalf("Failed to generate data key: %v", err)
+	}
+	other, err := client.GenerateKey(ctx, "my-key", []byte("my-context"))
+	if err != nil {
+		t.Fatalf("Failed to generate data key: %v", err)
+	}
+	if !bytes.Equal(dek.Plaintext, other.Plaintext) {
+		t.Fatal("Generated different data keys for the same context")
+	}
+	if other, _ = client.GenerateKey(ctx, "my-key", []byte("my-context-2")); bytes.Equal(dek.Plaintext, other.Plaintext) {
+		t.Fatal("Generated the same data key for different contexts")
+	}
+	plaintext, err := client.Decrypt(ctx, "my-key", dek.Ciphertext, []byte("my-context"))
+	if err != nil {
+		t.Fatalf("Failed to decrypt data key: %v", err)
+	}
+	if !bytes.Equal(plaintext, dek.Plaintext) {
+		t.Fatal("Decrypted data key does not match generated data key")
+	}
+}
+
+var checkKeyConstraintsTests = []struct {
+	Usage     crypto.KeyUsage
+	ExpiresAt time.Time
+	Op        crypto.KeyUsage
+	Err       error
+}{
+	{Usage: 0, Op: crypto.UsageEncrypt, Err: nil},                                                                               // 0
+	{Usage: 0, Op: crypto.UsageDecrypt, Err: nil},                                                                               // 1
+	{Usage: crypto.UsageEncrypt, Op: crypto.UsageEncrypt, Err: nil},                                                             // 2
+	{Usage: crypto.UsageEncrypt, Op: crypto.UsageDecrypt, Err: errKeyDecryptNotPermitted},                                       // 3
+	{Usage: crypto.UsageDecrypt, Op: crypto.UsageEncrypt, Err: errKeyEncryptNotPermitted},                                       // 4
+	{Usage: crypto.UsageEncrypt | crypto.UsageDecrypt, Op: crypto.UsageDecrypt, Err: nil},                                       // 5
+	{ExpiresAt: time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC), Op: crypto.UsageEncrypt, Err: nil},                                 // 6
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageEncrypt, Err: errKeyExpired},                       // 7
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageDecrypt, Err: nil},                                 // 8
+	{Usage: 0, Op: crypto.UsageSSH, Err: errKeySSHNotPermitted},                                                                 // 9
+	{Usage: crypto.UsageSSH, Op: crypto.UsageSSH, Err: nil},                                                                     // 10
+	{Usage: crypto.UsageSSH, Op: crypto.UsageEncrypt, Err: errKeyEncryptNotPermitted},                                           // 11
+	{Usage: crypto.UsageSSH, ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageSSH, Err: errKeyExpired},   // 12
+	{Usage: 0, Op: crypto.UsageCert, Err: errKeyCertNotPermitted},                                                               // 13
+	{Usage: crypto.UsageSSH, Op: crypto.UsageCert, Err: errKeyCertNotPermitted},                                                 // 14
+	{Usage: crypto.UsageCert, Op: crypto.UsageCert, Err: nil},                                                                   // 15
+	{Usage: crypto.UsageCert, ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageCert, Err: errKeyExpired}, // 16
+	{Usage: 0, Op: crypto.UsageHMAC, Err: nil},                                                                                  // 17
+	{Usage: crypto.UsageDecrypt, Op: crypto.UsageHMAC, Err: errKeyHMACNotPermitted},                                             // 18
+	{Usage: crypto.UsageDecrypt, Op: crypto.UsageSign, Err: errKeySignNotPermitted},                                             // 19
+	{Usage: crypto.UsageSign, Op: crypto.UsageVerify, Err: errKeyVerifyNotPermitted},                                            // 20
+	{Usage: crypto.UsageDecrypt, Op: crypto.UsageECDH, Err: errKeyECDHNotPermitted},                                             // 21
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageHMAC, Err: errKeyExpired},                          // 22
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageSign, Err: errKeyExpired},                          // 23
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageVerify, Err: errKeyExpired},                        // 24
+	{ExpiresAt: time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), Op: crypto.UsageECDH, Err: errKeyExpired},                          // 25
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minio/kes/internal/api"
+)
+
+// ContextPolicy requires encrypt, generate and decrypt requests
+// to carry an encryption context with certain fields, like the
+// bucket or tenant a data key belongs to. The context is bound
+// cryptographically to the ciphertext. Hence, a data key that
+// has been generated for one bucket cannot be decrypted for
+// another one.
+//
+// The encryption context must be a JSON object with string
+// values, like {"bucket":"photos","tenant":"team-a"}. It may
+// contain fields that are not required.
+type ContextPolicy struct {
+	// Fields maps the names of the required context fields to
+	// glob patterns, as defined by path.Match, that the field
+	// values must match. The pattern "*" matches any value.
+	// Empty values are rejected.
+	Fields map[string]string
+}
+
+// validate returns an error if the policy requires no fields
+// or contains a malformed pattern.
+func (p *ContextPolicy) validate() error {
+	if len(p.Fields) == 0 {
+		return errors.New("context policy requires no fields")
+	}
+	for name, pattern := range p.Fields {
+		if name == "" {
+			return errors.New("context policy contains an empty field name")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern '%s' for context field '%s': %v", pattern, name, err)
+		}
+	}
+	return nil
+}
+
+// verify returns an error if an encrypt, generate or decrypt
+// request does not carry an encryption context with all required
+// fields. Otherwise, it returns the required fields of each
+// context sent with the request. Other requests are not verified.
+//
+// The request body is read and replaced such that it can be read
+// again by the API handler.
+func (p *ContextPolicy) verify(req *http.Request) ([]map[string]string, error) {
+	bulk := strings.HasPrefix(req.URL.Path, api.PathKeyBulkEncrypt) || strings.HasPrefix(req.URL.Path, api.PathKeyBulkDecrypt)
+	if !bulk && !strings.HasPrefix(req.URL.Path, api.PathKeyEncrypt) &&
+		!strings.HasPrefix(req.URL.Path, api.PathKeyGenerate) && !strings.HasPrefix(req.URL.Path, api.PathKeyDecrypt) {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var data struct {
+		Context []byte `json:"context"`
+		Items   []struct {
+			Context []byte `json:"context"`
+		} `json:"items"`
+	}
+	if len(body) > 0 {
+		if err = json.Unmarshal(body, &data); err != nil {
+			return nil, errors.New("invalid request body")
+		}
+	}
+	if !bulk {
+		fields, err := p.match(data.Context)
+		if err != nil {
+			return nil, err
+		}
+		return []map[string]string{fields}, nil
+	}
+
+	contexts := make([]map[string]string, 0, len(data.Items))
+	for i, item := range data.Items {
+		fields, err := p.match(item.Context)
+		if err != nil {
+			return nil, fmt.Errorf("item %d: %v", i, err)
+		}
+		contexts = append(contexts, fields)
+	}
+	return contexts, nil
+}
+
+// match returns the required fields of the encryption context
+// or an error if the context does not contain all of them.
+func (p *ContextPolicy) match(context []byte) (map[string]string, error) {
+	if len(context) == 0 {
+		return nil, errors.New("encryption context is missing")
+	}
+	var values map[string]any
+	if err := json.Unmarshal(context, &values); err != nil {
+		return nil, errors.New("encryption context is not a JSON object")
+	}
+
+	fields := make(map[string]string, len(p.Fields))
+	for name, pattern := range p.Fields {
+		value, ok := values[name].(string)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("encryption context field '%s' is missing", name)
+		}
+		if ok, _ := path.Match(pattern, value); !ok {
+			return nil, fmt.Errorf("encryption context field '%s' does not match '%s'", name, pattern)
+		}
+		fields[name] = value
+	}
+	return fields, nil
+}
+
+// contextFieldsKey is the request context key of the encryption
+// context fields verified by a ContextPolicy.
+type contextFieldsKey struct{}
+
+// withContextFields returns a copy of ctx carrying the verified
+// encryption context fields.
+func withContextFields(ctx context.Context, contexts []map[string]string) context.Context {
+	return context.WithValue(ctx, contextFieldsKey{}, contexts)
+}
+
+// auditContext logs an audit event with the verified encryption
+// context fields of the request, if any. Requests of identities
+// whose policy does not require an encryption context are not
+// audited.
+func (s *Server) auditContext(req *api.Request, op string) {
+	contexts, ok := req.Context().Value(contextFieldsKey{}).([]map[string]string)
+	if !ok {
+		return
+	}
+
+	formatted := make([]string, 0, len(contexts))
+	for _, fields := range contexts {
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+
+		var sb strings.Builder
+		sb.WriteByte('{')
+		for i, name := range names {
+			if i > 0 {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(name + "=" + fields[name])
+		}
+		sb.WriteByte('}')
+		if s := sb.String(); !slices.Contains(formatted, s) {
+			formatted = append(formatted, s)
+		}
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("key '%s' used to %s with context %s", req.Resource, op, strings.Join(formatted, " ")),
+		StatusOK,
+		req,
+	)
+}
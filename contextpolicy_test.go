@@ -0,0 +1,145 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestContextPolicyVerify(t *testing.T) {
+	t.Parallel()
+
+	policy := &ContextPolicy{Fields: map[string]string{"bucket": "*", "tenant": "team-a*"}}
+	for i, test := range contextPolicyVerifyTests {
+		req, err := http.NewRequest(http.MethodPut, test.Path, strings.NewReader(test.Body))
+		if err != nil {
+			t.Fatalf("Test %d: failed to create request: %v", i, err)
+		}
+
+		contexts, err := policy.verify(req)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: verify should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify context: %v", i, err)
+		}
+		if err == nil && len(contexts) != test.Contexts {
+			t.Fatalf("Test %d: got %d contexts - want %d", i, len(contexts), test.Contexts)
+		}
+	}
+}
+
+var contextPolicyVerifyTests = []struct {
+	Path       string
+	Body       string
+	Contexts   int
+	ShouldFail bool
+}{
+	{Path: api.PathKeyDescribe + "my-key", Body: ""}, // 0
+	{ // 1
+		Path:     api.PathKeyEncrypt + "my-key",
+		Body:     `{"plaintext":"","context":"eyJidWNrZXQiOiJwaG90b3MiLCJ0ZW5hbnQiOiJ0ZWFtLWEifQ=="}`, // {"bucket":"photos","tenant":"team-a"}
+		Contexts: 1,
+	},
+	{ // 2
+		Path:       api.PathKeyEncrypt + "my-key",
+		Body:       `{"plaintext":""}`,
+		ShouldFail: true,
+	},
+	{ // 3
+		Path:       api.PathKeyGenerate + "my-key",
+		Body:       "",
+		ShouldFail: true,
+	},
+	{ // 4
+		Path:       api.PathKeyDecrypt + "my-key",
+		Body:       `{"ciphertext":"","context":"eyJidWNrZXQiOiJwaG90b3MiLCJ0ZW5hbnQiOiJ0ZWFtLWIifQ=="}`, // {"bucket":"photos","tenant":"team-b"}
+		ShouldFail: true,
+	},
+	{ // 5
+		Path:       api.PathKeyDecrypt + "my-key",
+		Body:       `{"ciphertext":"","context":"eyJidWNrZXQiOiIiLCJ0ZW5hbnQiOiJ0ZWFtLWEifQ=="}`, // {"bucket":"","tenant":"team-a"}
+		ShouldFail: true,
+	},
+	{ // 6
+		Path:       api.PathKeyEncrypt + "my-key",
+		Body:       `{"plaintext":"","context":"bXktY29udGV4dA=="}`, // my-context
+		ShouldFail: true,
+	},
+	{ // 7
+		Path:     api.PathKeyBulkEncrypt + "my-key",
+		Body:     `{"items":[{"context":"eyJidWNrZXQiOiJwaG90b3MiLCJ0ZW5hbnQiOiJ0ZWFtLWEifQ=="},{"context":"eyJidWNrZXQiOiJ2aWRlb3MiLCJ0ZW5hbnQiOiJ0ZWFtLWEtMiJ9"}]}`, // {"bucket":"videos","tenant":"team-a-2"}
+		Contexts: 2,
+	},
+	{ // 8
+		Path:       api.PathKeyBulkDecrypt + "my-key",
+		Body:       `{"items":[{"context":"eyJidWNrZXQiOiJwaG90b3MiLCJ0ZW5hbnQiOiJ0ZWFtLWEifQ=="},{}]}`,
+		ShouldFail: true,
+	},
+}
+
+func TestContextPolicy(t *testing.T) {
+	ctx := testContext(t)
+
+	audit := &auditRecorder{}
+	srv, url := startServer(ctx, &Config{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow: map[string]kes.Rule{
+					"/v1/key/create/*":  {},
+					"/v1/key/encrypt/*": {},
+					"/v1/key/decrypt/*": {},
+				},
+				Context:    &ContextPolicy{Fields: map[string]string{"bucket": "*"}},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+		AuditLog: audit,
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := client.Encrypt(ctx, "my-key", []byte("Hello"), nil); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Encrypting without context: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+
+	ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello"), []byte(`{"bucket":"photos"}`))
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	if _, err = client.Decrypt(ctx, "my-key", ciphertext, []byte(`{"bucket":"videos"}`)); !errors.Is(err, kes.ErrDecrypt) {
+		t.Fatalf("Decrypting with another context: got '%v' - want '%v'", err, kes.ErrDecrypt)
+	}
+	if _, err = client.Decrypt(ctx, "my-key", ciphertext, []byte(`{"bucket":"photos"}`)); err != nil {
+		t.Fatalf("Failed to decrypt: %v", err)
+	}
+
+	audit.mu.Lock()
+	defer audit.mu.Unlock()
+
+	var messages []string
+	for _, r := range audit.Records {
+		if strings.Contains(r.Message, "with context") {
+			messages = append(messages, r.Message)
+		}
+	}
+	want := []string{
+		"key 'my-key' used to encrypt with context {bucket=photos}",
+		"key 'my-key' used to decrypt with context {bucket=photos}",
+	}
+	if len(messages) != len(want) || messages[0] != want[0] || messages[1] != want[1] {
+		t.Fatalf("Invalid audit events: got '%v' - want '%v'", messages, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/headers"
+)
+
+const (
+	// defaultProfileDuration is the default time period a CPU,
+	// trace, block or mutex profile is captured.
+	defaultProfileDuration = 30 * time.Second
+
+	// maxProfileDuration is the max. time period a profile can be
+	// captured. The debug profile route timeout must be larger.
+	maxProfileDuration = 5 * time.Minute
+)
+
+// profileLock ensures that at most one profile is captured over
+// a time period at any time. The Go runtime supports only one
+// CPU profile or execution trace at a time, and the block and
+// mutex profile rates are process-wide settings.
+var profileLock sync.Mutex
+
+// debugProfile captures the runtime profile named by the request
+// resource and sends it to the client in the pprof format, or the
+// execution trace format for 'trace'.
+//
+// The 'cpu', 'trace', 'block' and 'mutex' profiles are captured
+// over the time period specified by the 'seconds' query parameter.
+// All other profiles, like 'heap' or 'goroutine', are a snapshot
+// of the current state.
+func (s *Server) debugProfile(resp *api.Response, req *api.Request) {
+	name := req.Resource
+	duration := defaultProfileDuration
+	if v := req.URL.Query().Get("seconds"); v != "" {
+		seconds, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || seconds == 0 {
+			resp.Failf(http.StatusBadRequest, "invalid profile duration '%s'", v)
+			return
+		}
+		if duration = time.Duration(seconds) * time.Second; duration > maxProfileDuration {
+			resp.Failf(http.StatusBadRequest, "profile duration '%v' exceeds limit of %v", duration, maxProfileDuration)
+			return
+		}
+	}
+
+	var (
+		profile bytes.Buffer
+		err     error
+	)
+	switch name {
+	case "cpu", "trace", "block", "mutex":
+		if !profileLock.TryLock() {
+			resp.Fail(http.StatusConflict, "another profile is being captured")
+			return
+		}
+		defer profileLock.Unlock()
+
+		err = captureProfile(req, name, duration, &profile)
+	default:
+		p := pprof.Lookup(name)
+		if p == nil {
+			resp.Failf(http.StatusNotFound, "profile '%s' not found", name)
+			return
+		}
+		if name == "heap" || name == "allocs" {
+			runtime.GC() // Report up-to-date statistics
+		}
+		err = p.WriteTo(&profile, 0)
+	}
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to capture profile")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("profile '%s' captured", name),
+		StatusOK,
+		req,
+	)
+	resp.Header().Set(headers.ContentType, headers.ContentTypeBinary)
+	resp.WriteHeader(StatusOK)
+	profile.WriteTo(resp)
+}
+
+// captureProfile captures the named profile over the given time
+// period, or until the request is canceled, and writes it to buf.
+func captureProfile(req *api.Request, name string, duration time.Duration, buf *bytes.Buffer) error {
+	switch name {
+	case "cpu":
+		if err := pprof.StartCPUProfile(buf); err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	case "trace":
+		if err := trace.Start(buf); err != nil {
+			return err
+		}
+		defer trace.Stop()
+	case "block":
+		runtime.SetBlockProfileRate(1)
+		defer runtime.SetBlockProfileRate(0)
+	case "mutex":
+		runtime.SetMutexProfileFraction(1)
+		defer runtime.SetMutexProfileFraction(0)
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+
+	switch name {
+	case "block", "mutex":
+		return pprof.Lookup(name).WriteTo(buf, 0)
+	default:
+		return nil // CPU profile and trace are written once stopped.
+	}
+}
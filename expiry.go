@@ -0,0 +1,71 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+)
+
+// revokeExpiredIdentities removes identities from the server
+// state once their policy assignment has expired until ctx is
+// canceled.
+//
+// Expired identities are rejected by the server even before
+// they have been removed.
+func (s *Server) revokeExpiredIdentities(ctx context.Context) {
+	const Delay = 1 * time.Minute // Max. delay between checks for expired identities
+
+	for {
+		wait := Delay
+		now := time.Now()
+		for _, entry := range s.state.Load().Identities {
+			if !entry.ExpiresAt.IsZero() {
+				wait = min(wait, max(entry.ExpiresAt.Sub(now), 0))
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.revokeExpired(ctx, time.Now())
+	}
+}
+
+// revokeExpired removes all identities that have expired at
+// time t and returns them.
+func (s *Server) revokeExpired(ctx context.Context, t time.Time) []kes.Identity {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.state.Load()
+	var expired []kes.Identity
+	for id, entry := range old.Identities {
+		if entry.expired(t) {
+			expired = append(expired, id)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	identities := maps.Clone(old.Identities)
+	for _, id := range expired {
+		old.Log.InfoContext(ctx, "identity expired and revoked", "identity", id, "policy", identities[id].Name)
+		delete(identities, id)
+	}
+	state := *old
+	state.Identities = identities
+	s.state.Store(&state)
+	s.notify(identityEvents(old.Identities, identities, "")...)
+	return expired
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// freezeInfo describes when, why and by whom the server
+// has been frozen.
+type freezeInfo struct {
+	Reason   string
+	FrozenAt time.Time
+	FrozenBy kes.Identity
+}
+
+// Err returns the error sent to clients when they try to
+// modify keys, secrets, policies or identities while the
+// server is frozen.
+func (f *freezeInfo) Err() api.Error {
+	msg := "server is frozen"
+	if f.Reason != "" {
+		msg += ": " + f.Reason
+	}
+	return api.NewCodeError(http.StatusMethodNotAllowed, api.CodeFrozen, msg)
+}
+
+// rejectFrozen returns a Handler that rejects requests while
+// the server is frozen and passes them to h otherwise.
+//
+// Rejected requests are not passed to h at all. Hence, the
+// idempotency cache does not record them such that clients can
+// retry them once the server has been unfrozen.
+func (s *Server) rejectFrozen(h api.Handler) api.Handler {
+	return api.HandlerFunc(func(resp *api.Response, req *api.Request) {
+		if f := s.frozen.Load(); f != nil {
+			resp.Failr(f.Err())
+			return
+		}
+		h.ServeAPI(resp, req)
+	})
+}
+
+// freeze freezes or unfreezes the server. While frozen, the
+// server keeps serving cryptographic operations, like decrypt
+// or generate, but rejects any request that modifies keys,
+// secrets, policies or identities. Requests that neither freeze
+// nor unfreeze the server only return the current freeze status.
+func (s *Server) freeze(resp *api.Response, req *api.Request) {
+	var freeze api.FreezeRequest
+	if err := api.ReadBody(req, &freeze); err != nil && err != io.EOF {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid freeze request body")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	state := s.state.Load()
+	switch {
+	case freeze.Frozen == nil:
+	case *freeze.Frozen:
+		f := &freezeInfo{
+			Reason:   freeze.Reason,
+			FrozenAt: time.Now().UTC(),
+			FrozenBy: req.Identity,
+		}
+		if s.frozen.CompareAndSwap(nil, f) {
+			msg := "server frozen"
+			if f.Reason != "" {
+				msg += ": " + f.Reason
+			}
+			state.Audit.Log(msg, StatusOK, req)
+		}
+	default:
+		if s.frozen.Swap(nil) != nil {
+			state.Audit.Log("server unfrozen", StatusOK, req)
+		}
+	}
+
+	f := s.frozen.Load()
+	if f == nil {
+		api.ReplyWith(resp, StatusOK, api.FreezeResponse{Frozen: false})
+		return
+	}
+	api.ReplyWith(resp, StatusOK, api.FreezeResponse{
+		Frozen:   true,
+		Reason:   f.Reason,
+		FrozenAt: f.FrozenAt,
+		FrozenBy: f.FrozenBy.String(),
+	})
+}
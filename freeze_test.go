@@ -0,0 +1,112 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+)
+
+func TestServerFreeze(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	freeze := func(frozen *bool, reason string) *api.FreezeResponse {
+		body, err := json.Marshal(api.FreezeRequest{Frozen: frozen, Reason: reason})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathAdminFreeze, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to freeze server: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Failed to freeze server: %v", api.ReadError(resp))
+		}
+		var freeze api.FreezeResponse
+		if err := json.NewDecoder(resp.Body).Decode(&freeze); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return &freeze
+	}
+	yes, no := true, false
+
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if resp := freeze(nil, ""); resp.Frozen {
+		t.Fatalf("Freeze status: got '%+v' - want not frozen", resp)
+	}
+	if resp := freeze(&yes, "migration"); !resp.Frozen || resp.Reason != "migration" || resp.FrozenBy != srv.state.Load().Admin.String() {
+		t.Fatalf("Freezing server: got '%+v'", resp)
+	}
+	if resp := freeze(&yes, "other reason"); resp.Reason != "migration" {
+		t.Fatalf("Freezing frozen server changed reason: got '%s' - want 'migration'", resp.Reason)
+	}
+
+	for i, test := range []struct {
+		Method string
+		Path   string
+	}{
+		{Method: http.MethodPut, Path: api.PathKeyCreate + "other-key"},
+		{Method: http.MethodPut, Path: api.PathKeyRotate + "my-key"},
+		{Method: http.MethodDelete, Path: api.PathKeyDelete + "my-key"},
+		{Method: http.MethodPut, Path: api.PathSecretSet + "my-secret"},
+		{Method: http.MethodDelete, Path: api.PathPolicyDelete + "my-policy"},
+	} {
+		req, err := http.NewRequestWithContext(ctx, test.Method, url+test.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to send request: %v", i, err)
+		}
+		err = api.ReadError(resp)
+		resp.Body.Close()
+
+		if code := api.CodeOf(err); code != api.CodeFrozen {
+			t.Fatalf("Test %d: Invalid error code: got '%s' - want '%s'", i, code, api.CodeFrozen)
+		}
+		if !strings.Contains(err.Error(), "migration") {
+			t.Fatalf("Test %d: Error '%v' does not contain the reason", i, err)
+		}
+	}
+
+	dek, err := client.GenerateKey(ctx, "my-key", nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key while frozen: %v", err)
+	}
+	plaintext, err := client.Decrypt(ctx, "my-key", dek.Ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt while frozen: %v", err)
+	}
+	if !bytes.Equal(plaintext, dek.Plaintext) {
+		t.Fatal("Decrypted plaintext does not match")
+	}
+	if status, err := srv.readStatus(ctx); err != nil || !status.Frozen {
+		t.Fatalf("Server status: got frozen '%v' with error '%v' - want frozen", status.Frozen, err)
+	}
+
+	if resp := freeze(&no, ""); resp.Frozen {
+		t.Fatalf("Unfreezing server: got '%+v'", resp)
+	}
+	if err = client.CreateKey(ctx, "other-key"); err != nil {
+		t.Fatalf("Failed to create key after unfreezing: %v", err)
+	}
+}
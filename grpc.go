@@ -0,0 +1,280 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/headers"
+	pb "github.com/minio/kes/internal/protobuf"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCServer returns a gRPC server serving the KES gRPC API
+// of s. It uses the TLS config of s, including any updates.
+func newGRPCServer(s *Server) *grpc.Server {
+	creds := credentials.NewTLS(&tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			conf := s.tls.Load().Clone()
+			conf.NextProtos = []string{"h2"}
+			return conf, nil
+		},
+	})
+
+	srv := grpc.NewServer(grpc.Creds(creds))
+	pb.RegisterKESServer(srv, &grpcServer{srv: s})
+	return srv
+}
+
+// grpcServer implements the KES gRPC API.
+//
+// Each RPC is served by the HTTP handler of the corresponding
+// API route. Hence, RPCs are authenticated, authorized, audited
+// and measured exactly like HTTP requests. Only the transport
+// and the message encoding differ.
+type grpcServer struct {
+	pb.UnimplementedKESServer
+
+	srv *Server
+}
+
+func (g *grpcServer) CreateKey(ctx context.Context, req *pb.CreateKeyRequest) (*pb.CreateKeyResponse, error) {
+	create := api.CreateKeyRequest{
+		Tags:      req.Tags,
+		Usage:     req.Usage,
+		Algorithm: req.Algorithm,
+		Derived:   req.Derived,
+	}
+	if req.ExpiresAt != nil {
+		create.ExpiresAt = req.ExpiresAt.AsTime()
+	}
+	if req.RotationInterval != nil {
+		create.RotationInterval = int64(req.RotationInterval.AsDuration() / time.Second)
+	}
+	if err := g.call(ctx, api.PathKeyCreate, req.Name, nil, create, nil); err != nil {
+		return nil, err
+	}
+	return &pb.CreateKeyResponse{}, nil
+}
+
+func (g *grpcServer) GenerateKey(ctx context.Context, req *pb.GenerateKeyRequest) (*pb.GenerateKeyResponse, error) {
+	var resp api.GenerateKeyResponse
+	if err := g.call(ctx, api.PathKeyGenerate, req.Name, nil, api.GenerateKeyRequest{Context: req.Context}, &resp); err != nil {
+		return nil, err
+	}
+	return &pb.GenerateKeyResponse{
+		Plaintext:  resp.Plaintext,
+		Ciphertext: resp.Ciphertext,
+	}, nil
+}
+
+func (g *grpcServer) GenerateKeys(stream pb.KES_GenerateKeysServer) error {
+	for {
+		req, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		resp, err := g.GenerateKey(stream.Context(), req)
+		if err != nil {
+			return err
+		}
+		if err = stream.Send(resp); err != nil {
+			return err
+		}
+	}
+}
+
+func (g *grpcServer) Encrypt(ctx context.Context, req *pb.EncryptRequest) (*pb.EncryptResponse, error) {
+	var resp api.EncryptKeyResponse
+	if err := g.call(ctx, api.PathKeyEncrypt, req.Name, nil, api.EncryptKeyRequest{
+		Plaintext: req.Plaintext,
+		Context:   req.Context,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &pb.EncryptResponse{Ciphertext: resp.Ciphertext}, nil
+}
+
+func (g *grpcServer) Decrypt(ctx context.Context, req *pb.DecryptRequest) (*pb.DecryptResponse, error) {
+	var resp api.DecryptKeyResponse
+	if err := g.call(ctx, api.PathKeyDecrypt, req.Name, nil, api.DecryptKeyRequest{
+		Ciphertext: req.Ciphertext,
+		Context:    req.Context,
+	}, &resp); err != nil {
+		return nil, err
+	}
+	return &pb.DecryptResponse{Plaintext: resp.Plaintext}, nil
+}
+
+func (g *grpcServer) ListKeys(req *pb.ListKeysRequest, stream pb.KES_ListKeysServer) error {
+	query := url.Values{}
+	if req.PageSize > 0 {
+		query.Set("limit", strconv.FormatUint(uint64(req.PageSize), 10))
+	}
+	if req.Match != "" {
+		query.Set("match", req.Match)
+	}
+	if req.Reverse {
+		query.Set("order", "desc")
+	} else {
+		query.Set("order", "asc")
+	}
+
+	for {
+		var resp api.ListKeysResponse
+		if err := g.call(stream.Context(), api.PathKeyList, req.Prefix+"*", query, nil, &resp); err != nil {
+			return err
+		}
+		if len(resp.Names) > 0 {
+			if err := stream.Send(&pb.ListKeysResponse{Names: resp.Names}); err != nil {
+				return err
+			}
+		}
+		if resp.ContinueAt == "" {
+			return nil
+		}
+		query.Set("continue", resp.ContinueAt)
+	}
+}
+
+// call serves an RPC by the HTTP handler of the API route at
+// path. It sends body, if not nil, as JSON request body and
+// decodes the JSON response body into v, if not nil.
+//
+// The request carries the TLS state and address of the gRPC
+// client such that it is authenticated like an HTTP request.
+func (g *grpcServer) call(ctx context.Context, path, resource string, query url.Values, body, v any) error {
+	route, ok := g.srv.state.Load().Routes[path]
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "API '%s' is not available", path)
+	}
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	u := url.URL{Path: path + resource, RawQuery: query.Encode()}
+	req, err := http.NewRequestWithContext(ctx, route.Method, u.String(), &buf)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+	if p, ok := peer.FromContext(ctx); ok {
+		req.RemoteAddr = p.Addr.String()
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			req.TLS = &info.State
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, h := range []string{headers.Authorization, headers.IdempotencyKey} {
+			if values := md.Get(h); len(values) > 0 {
+				req.Header.Set(h, values[0])
+			}
+		}
+	}
+
+	resp := &grpcResponse{header: make(http.Header)}
+	g.srv.handler.Load().ServeHTTP(resp, req)
+
+	if resp.code != http.StatusOK {
+		var e struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		}
+		if json.Unmarshal(resp.body.Bytes(), &e) != nil || e.Message == "" {
+			e.Message = http.StatusText(resp.code)
+		}
+		code := grpcCode(resp.code)
+		switch e.Code { // The HTTP API replies with 400 Bad Request
+		case api.CodeKeyExists, api.CodePolicyExists, api.CodeIdentityExists, api.CodeSecretExists:
+			code = codes.AlreadyExists
+		}
+		return status.Error(code, e.Message)
+	}
+	if v != nil {
+		if err := json.Unmarshal(resp.body.Bytes(), v); err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+	}
+	return nil
+}
+
+// grpcResponse is an http.ResponseWriter that buffers the
+// response of an HTTP handler serving an RPC.
+type grpcResponse struct {
+	header http.Header
+	code   int
+	body   bytes.Buffer
+}
+
+func (r *grpcResponse) Header() http.Header { return r.header }
+
+func (r *grpcResponse) WriteHeader(code int) {
+	if r.code == 0 {
+		r.code = code
+	}
+}
+
+func (r *grpcResponse) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// SetWriteDeadline allows API routes to set their timeouts.
+// It does nothing since RPCs are bound by the gRPC deadline
+// sent by the client.
+func (r *grpcResponse) SetWriteDeadline(time.Time) error { return nil }
+
+// grpcCode returns the gRPC status code corresponding
+// to the given HTTP status code.
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusAccepted: // The request waits for approval
+		return codes.FailedPrecondition
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusNotAcceptable, http.StatusPreconditionFailed, http.StatusUnprocessableEntity:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"slices"
+	"testing"
+
+	pb "github.com/minio/kes/internal/protobuf"
+	"github.com/minio/kms-go/kes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPC(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, _ := startServer(ctx, &Config{
+		GRPC: &GRPCConfig{Addr: "127.0.0.1:0"},
+	})
+	defer srv.Close()
+
+	srv.mu.Lock()
+	addr := srv.grpcLn.Addr().String()
+	srv.mu.Unlock()
+
+	client := newGRPCClient(t, addr, defaultAPIKey)
+	if _, err := client.CreateKey(ctx, &pb.CreateKeyRequest{Name: "my-key"}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	_, err := client.CreateKey(ctx, &pb.CreateKeyRequest{Name: "my-key"})
+	if code := status.Code(err); code != codes.AlreadyExists {
+		t.Fatalf("Invalid error code: got '%v' - want '%v': %v", code, codes.AlreadyExists, err)
+	}
+
+	plaintext := []byte("Hello World")
+	enc, err := client.Encrypt(ctx, &pb.EncryptRequest{Name: "my-key", Plaintext: plaintext})
+	if err != nil {
+		t.Fatalf("Failed to encrypt plaintext: %v", err)
+	}
+	dec, err := client.Decrypt(ctx, &pb.DecryptRequest{Name: "my-key", Ciphertext: enc.Ciphertext})
+	if err != nil {
+		t.Fatalf("Failed to decrypt ciphertext: %v", err)
+	}
+	if !bytes.Equal(dec.Plaintext, plaintext) {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", dec.Plaintext, plaintext)
+	}
+
+	stream, err := client.GenerateKeys(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open stream: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err = stream.Send(&pb.GenerateKeyRequest{Name: "my-key"}); err != nil {
+			t.Fatalf("Failed to send request %d: %v", i, err)
+		}
+		dek, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Failed to generate key %d: %v", i, err)
+		}
+		dec, err := client.Decrypt(ctx, &pb.DecryptRequest{Name: "my-key", Ciphertext: dek.Ciphertext})
+		if err != nil {
+			t.Fatalf("Failed to decrypt key %d: %v", i, err)
+		}
+		if !bytes.Equal(dec.Plaintext, dek.Plaintext) {
+			t.Fatalf("Invalid plaintext of key %d", i)
+		}
+	}
+	if err = stream.Send(&pb.GenerateKeyRequest{Name: "unknown-key"}); err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	if _, err = stream.Recv(); status.Code(err) != codes.NotFound {
+		t.Fatalf("Invalid error code: got '%v' - want '%v': %v", status.Code(err), codes.NotFound, err)
+	}
+
+	for _, name := range []string{"my-key-1", "my-key-2", "other-key"} {
+		if _, err := client.CreateKey(ctx, &pb.CreateKeyRequest{Name: name}); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	list, err := client.ListKeys(ctx, &pb.ListKeysRequest{Prefix: "my-key", PageSize: 1, Reverse: true})
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	var names []string
+	for {
+		resp, err := list.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Failed to list keys: %v", err)
+		}
+		names = append(names, resp.Names...)
+	}
+	if want := []string{"my-key-2", "my-key-1", "my-key"}; !slices.Equal(names, want) {
+		t.Fatalf("Invalid key names: got '%v' - want '%v'", names, want)
+	}
+
+	apiKey, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	_, err = newGRPCClient(t, addr, apiKey.String()).Encrypt(ctx, &pb.EncryptRequest{Name: "my-key", Plaintext: plaintext})
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Fatalf("Invalid error code: got '%v' - want '%v': %v", code, codes.PermissionDenied, err)
+	}
+}
+
+func newGRPCClient(t *testing.T, addr, apiKey string) pb.KESClient {
+	key, err := kes.ParseAPIKey(apiKey)
+	if err != nil {
+		t.Fatalf("Failed to parse API key: %v", err)
+	}
+	cert, err := kes.GenerateCertificate(key)
+	if err != nil {
+		t.Fatalf("Failed to generate client certificate: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+
+	conn, err := grpc.Dial(addr, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{cert},
+	})))
+	if err != nil {
+		t.Fatalf("Failed to connect to '%s': %v", addr, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pb.NewKESClient(conn)
+}
@@ -0,0 +1,201 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cache"
+	"github.com/minio/kes/internal/headers"
+)
+
+// idempotencyCache remembers the responses of mutating requests
+// that carry an idempotency key. A client that retries a request,
+// e.g. after a network error, with the same idempotency key gets
+// the original response instead of an error like "key already
+// exists".
+//
+// Responses are scoped to the identity that sent the request.
+// They are only kept in memory since some, like the response of
+// the IssueIdentity API, contain credentials.
+type idempotencyCache struct {
+	barrier cache.Barrier[string] // Serializes requests with the same idempotency key
+
+	lock    sync.Mutex
+	entries map[string]*idempotencyEntry
+}
+
+// idempotencyEntry is a recorded response.
+type idempotencyEntry struct {
+	Fingerprint [sha256.Size]byte // Hash of request method, path and body
+	Expiry      time.Time
+
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+const (
+	// idempotencyExpiry is the time period after which
+	// recorded responses are discarded.
+	idempotencyExpiry = 1 * time.Hour
+
+	// idempotencyMaxEntries is the max. number of recorded
+	// responses. Once reached, no further responses are
+	// recorded until existing ones expire.
+	idempotencyMaxEntries = 100_000
+
+	// idempotencyMaxKeyLen is the max. length of an
+	// idempotency key.
+	idempotencyMaxKeyLen = 256
+)
+
+// Handle returns an api.Handler that de-duplicates requests with
+// the same idempotency key before calling h.
+//
+// Requests without an idempotency key are passed to h unchanged.
+// A request that reuses an idempotency key for a different request
+// is rejected.
+func (c *idempotencyCache) Handle(h api.Handler) api.Handler {
+	return api.HandlerFunc(func(resp *api.Response, req *api.Request) {
+		key := req.Header.Get(headers.IdempotencyKey)
+		if key == "" {
+			h.ServeAPI(resp, req)
+			return
+		}
+		if len(key) > idempotencyMaxKeyLen {
+			resp.Failf(http.StatusBadRequest, "idempotency key is too long: exceeds %d bytes", idempotencyMaxKeyLen)
+			return
+		}
+
+		var body []byte
+		if req.Body != nil {
+			var err error
+			if body, err = io.ReadAll(req.Body); err != nil {
+				if err, ok := api.IsError(err); ok {
+					resp.Failr(err)
+					return
+				}
+				resp.Fail(http.StatusBadRequest, "invalid request body")
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+		}
+		fingerprint := sha256.New()
+		io.WriteString(fingerprint, req.Method)
+		io.WriteString(fingerprint, req.URL.Path)
+		fingerprint.Write(body)
+
+		cacheKey := req.Identity.String() + "\x00" + key
+		c.barrier.Lock(cacheKey)
+		defer c.barrier.Unlock(cacheKey)
+
+		if entry, ok := c.get(cacheKey); ok {
+			if !bytes.Equal(entry.Fingerprint[:], fingerprint.Sum(nil)) {
+				resp.Fail(http.StatusUnprocessableEntity, "idempotency key has already been used for a different request")
+				return
+			}
+			if entry.ContentType != "" {
+				resp.Header().Set(headers.ContentType, entry.ContentType)
+			}
+			resp.Header().Set(headers.IdempotentReplayed, "true")
+			resp.WriteHeader(entry.Status)
+			resp.Write(entry.Body)
+			return
+		}
+
+		rw := &recordResponseWriter{ResponseWriter: resp.ResponseWriter}
+		resp.ResponseWriter = rw
+		h.ServeAPI(resp, req)
+
+		// Server errors are not recorded such that clients
+		// can retry requests that failed due to e.g. an
+		// unreachable keystore.
+		if rw.status == 0 || rw.status >= 500 {
+			return
+		}
+		entry := &idempotencyEntry{
+			Expiry:      time.Now().Add(idempotencyExpiry),
+			Status:      rw.status,
+			ContentType: rw.Header().Get(headers.ContentType),
+			Body:        rw.body.Bytes(),
+		}
+		copy(entry.Fingerprint[:], fingerprint.Sum(nil))
+		c.add(cacheKey, entry)
+	})
+}
+
+func (c *idempotencyCache) get(key string) (*idempotencyEntry, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.Expiry) {
+		return nil, false
+	}
+	return entry, true
+}
+
+func (c *idempotencyCache) add(key string, entry *idempotencyEntry) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*idempotencyEntry)
+	}
+	if len(c.entries) >= idempotencyMaxEntries {
+		now := time.Now()
+		for k, e := range c.entries {
+			if now.After(e.Expiry) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= idempotencyMaxEntries {
+			return
+		}
+	}
+	c.entries[key] = entry
+}
+
+// recordResponseWriter is an http.ResponseWriter that
+// records the status code and body of a response.
+type recordResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+}
+
+var (
+	_ http.ResponseWriter = (*recordResponseWriter)(nil)
+	_ http.Flusher        = (*recordResponseWriter)(nil)
+)
+
+func (w *recordResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
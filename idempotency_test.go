@@ -0,0 +1,112 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+)
+
+var idempotencyTests = []struct {
+	Identity kes.Identity
+	Path     string
+	Key      string
+	Body     string
+
+	Status   int
+	Replayed bool
+}{
+	{Identity: "a", Path: "/v1/key/create/my-key", Status: http.StatusOK},                                            // 0
+	{Identity: "a", Path: "/v1/key/create/my-key", Status: http.StatusConflict},                                      // 1
+	{Identity: "a", Path: "/v1/key/create/key-1", Key: "1", Status: http.StatusOK},                                   // 2
+	{Identity: "a", Path: "/v1/key/create/key-1", Key: "1", Status: http.StatusOK, Replayed: true},                   // 3
+	{Identity: "b", Path: "/v1/key/create/key-1", Key: "1", Status: http.StatusConflict},                             // 4
+	{Identity: "a", Path: "/v1/key/create/key-2", Key: "1", Status: http.StatusUnprocessableEntity},                  // 5
+	{Identity: "a", Path: "/v1/key/import/key-3", Key: "2", Body: `{}`, Status: http.StatusOK},                       // 6
+	{Identity: "a", Path: "/v1/key/import/key-3", Key: "2", Body: `{"a":1}`, Status: http.StatusUnprocessableEntity}, // 7
+	{Identity: "a", Path: "/v1/key/create/key-4", Key: strings.Repeat("x", 257), Status: http.StatusBadRequest},      // 8
+}
+
+func TestIdempotency(t *testing.T) {
+	created := map[string]bool{}
+	handler := api.HandlerFunc(func(resp *api.Response, req *api.Request) {
+		if created[req.URL.Path] {
+			resp.Fail(http.StatusConflict, "key already exists")
+			return
+		}
+		created[req.URL.Path] = true
+		resp.Reply(http.StatusOK)
+	})
+
+	var cache idempotencyCache
+	h := cache.Handle(handler)
+	for i, test := range idempotencyTests {
+		r := httptest.NewRequest(http.MethodPut, test.Path, strings.NewReader(test.Body))
+		if test.Key != "" {
+			r.Header.Set(headers.IdempotencyKey, test.Key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeAPI(&api.Response{ResponseWriter: w}, &api.Request{Request: r, Identity: test.Identity})
+
+		if w.Code != test.Status {
+			t.Fatalf("Test %d: invalid status code: got '%d' - want '%d'", i, w.Code, test.Status)
+		}
+		if replayed := w.Header().Get(headers.IdempotentReplayed) == "true"; replayed != test.Replayed {
+			t.Fatalf("Test %d: invalid replay: got '%v' - want '%v'", i, replayed, test.Replayed)
+		}
+	}
+}
+
+func TestIdempotentPolicyAndIdentity(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	send := func(method, path, key, body string) (int, string, bool) {
+		req, err := http.NewRequestWithContext(ctx, method, url+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set(headers.IdempotencyKey, key)
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("Failed to read response: %v", err)
+		}
+		return resp.StatusCode, string(b), resp.Header.Get(headers.IdempotentReplayed) == "true"
+	}
+
+	for i := 0; i < 2; i++ {
+		code, body, replayed := send(http.MethodPut, api.PathPolicyCreate+"my-policy", "policy-1", `{"allow":["/v1/key/create/*"]}`)
+		if code != http.StatusOK {
+			t.Fatalf("Attempt %d: Failed to create policy: %d: %s", i, code, body)
+		}
+		if replayed != (i > 0) {
+			t.Fatalf("Attempt %d: invalid replay: got '%v' - want '%v'", i, replayed, i > 0)
+		}
+	}
+
+	_, first, _ := send(http.MethodPut, api.PathIdentityIssue+"my-policy", "identity-1", `{"ttl":"1h"}`)
+	code, second, replayed := send(http.MethodPut, api.PathIdentityIssue+"my-policy", "identity-1", `{"ttl":"1h"}`)
+	if code != http.StatusOK || !replayed {
+		t.Fatalf("Failed to replay identity issuance: %d: %s", code, second)
+	}
+	if first != second {
+		t.Fatalf("Retried identity issuance returned a different identity: got '%s' - want '%s'", second, first)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package acmedns implements DNS providers that create and
+// remove the DNS TXT records required for solving ACME DNS-01
+// challenges.
+package acmedns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	"github.com/minio/kes/internal/headers"
+)
+
+// Exec is a DNS provider that runs an executable to create
+// and remove TXT records. The executable is invoked as:
+//
+//	<command> present <fqdn> <value>
+//	<command> cleanup <fqdn> <value>
+//
+// It should only exit once the record has been created or
+// removed and exit with a non-zero status on failure.
+type Exec struct {
+	// Command is the path of the executable.
+	Command string
+}
+
+// Present runs the executable to create a TXT record for
+// the fully-qualified domain name with the given value.
+func (e *Exec) Present(ctx context.Context, fqdn, value string) error {
+	return e.run(ctx, "present", fqdn, value)
+}
+
+// CleanUp runs the executable to remove the TXT record for
+// the fully-qualified domain name with the given value.
+func (e *Exec) CleanUp(ctx context.Context, fqdn, value string) error {
+	return e.run(ctx, "cleanup", fqdn, value)
+}
+
+func (e *Exec) run(ctx context.Context, action, fqdn, value string) error {
+	output, err := exec.CommandContext(ctx, e.Command, action, fqdn, value).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("acmedns: '%s %s' failed: %v: %s", e.Command, action, err, msg)
+		}
+		return fmt.Errorf("acmedns: '%s %s' failed: %v", e.Command, action, err)
+	}
+	return nil
+}
+
+// Webhook is a DNS provider that sends HTTP POST requests to
+// create and remove TXT records. The request body is a JSON
+// object of the form:
+//
+//	{"fqdn": "<fqdn>", "value": "<value>"}
+//
+// Records are created via the '<endpoint>/present' and removed
+// via the '<endpoint>/cleanup' path. The webhook should only
+// respond once the record has been created or removed and reply
+// with a 2xx status code on success.
+type Webhook struct {
+	// Endpoint is the HTTP(S) URL of the webhook.
+	Endpoint string
+
+	// Client is the HTTP client used to send requests. If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// Present sends a request to create a TXT record for the
+// fully-qualified domain name with the given value.
+func (w *Webhook) Present(ctx context.Context, fqdn, value string) error {
+	return w.send(ctx, "present", fqdn, value)
+}
+
+// CleanUp sends a request to remove the TXT record for the
+// fully-qualified domain name with the given value.
+func (w *Webhook) CleanUp(ctx context.Context, fqdn, value string) error {
+	return w.send(ctx, "cleanup", fqdn, value)
+}
+
+func (w *Webhook) send(ctx context.Context, action, fqdn, value string) error {
+	type Request struct {
+		FQDN  string `json:"fqdn"`
+		Value string `json:"value"`
+	}
+	body, err := json.Marshal(Request{FQDN: fqdn, Value: value})
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(w.Endpoint, "/") + "/" + action
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("acmedns: webhook %s request failed: %v", action, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("acmedns: webhook %s request failed: %s: %s", action, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package acmedns
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Exec test requires a POSIX shell")
+	}
+
+	dir := t.TempDir()
+	output := filepath.Join(dir, "records")
+	command := filepath.Join(dir, "dns.sh")
+	script := "#!/bin/sh\necho \"$1 $2 $3\" >> " + output + "\n"
+	if err := os.WriteFile(command, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	provider := &Exec{Command: command}
+	if err := provider.Present(ctx, "_acme-challenge.example.com.", "token"); err != nil {
+		t.Fatalf("Failed to present record: %v", err)
+	}
+	if err := provider.CleanUp(ctx, "_acme-challenge.example.com.", "token"); err != nil {
+		t.Fatalf("Failed to clean up record: %v", err)
+	}
+
+	records, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const Records = "present _acme-challenge.example.com. token\ncleanup _acme-challenge.example.com. token\n"
+	if string(records) != Records {
+		t.Fatalf("Invalid records: got '%s' - want '%s'", records, Records)
+	}
+
+	if err = (&Exec{Command: filepath.Join(dir, "missing")}).Present(ctx, "_acme-challenge.example.com.", "token"); err == nil {
+		t.Fatal("Running a missing executable should fail")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	records := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			FQDN  string `json:"fqdn"`
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Invalid JSON body: %v", err)
+		}
+		switch r.URL.Path {
+		case "/dns/present":
+			records[req.FQDN] = req.Value
+		case "/dns/cleanup":
+			delete(records, req.FQDN)
+		default:
+			http.Error(w, "unknown action", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	provider := &Webhook{Endpoint: server.URL + "/dns/"}
+	if err := provider.Present(ctx, "_acme-challenge.example.com.", "token"); err != nil {
+		t.Fatalf("Failed to present record: %v", err)
+	}
+	if v := records["_acme-challenge.example.com."]; v != "token" {
+		t.Fatalf("Invalid record: got '%s' - want '%s'", v, "token")
+	}
+	if err := provider.CleanUp(ctx, "_acme-challenge.example.com.", "token"); err != nil {
+		t.Fatalf("Failed to clean up record: %v", err)
+	}
+	if len(records) != 0 {
+		t.Fatalf("Record has not been removed: %v", records)
+	}
+
+	provider = &Webhook{Endpoint: server.URL}
+	if err := provider.Present(ctx, "_acme-challenge.example.com.", "token"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("Request to unknown path should fail with 404: got '%v'", err)
+	}
+}
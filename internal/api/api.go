@@ -27,26 +27,84 @@ const (
 	PathMetrics  = "/v1/metrics"
 	PathListAPIs = "/v1/api"
 
+	PathHealthLive  = "/v1/health/live"
+	PathHealthReady = "/v1/health/ready"
+
 	PathKeyCreate   = "/v1/key/create/"
 	PathKeyImport   = "/v1/key/import/"
+	PathKeyExport   = "/v1/key/export/"
+	PathKeyRestore  = "/v1/key/restore/"
+	PathKeyRotate   = "/v1/key/rotate/"
 	PathKeyDescribe = "/v1/key/describe/"
 	PathKeyDelete   = "/v1/key/delete/"
+	PathKeyUndelete = "/v1/key/undelete/"
 	PathKeyList     = "/v1/key/list/"
+	PathKeyListInfo = "/v1/key/list-info/"
+	PathKeySearch   = "/v1/key/search/"
 	PathKeyGenerate = "/v1/key/generate/"
 	PathKeyEncrypt  = "/v1/key/encrypt/"
 	PathKeyDecrypt  = "/v1/key/decrypt/"
 	PathKeyHMAC     = "/v1/key/hmac/"
+	PathKeyECDH     = "/v1/key/ecdh/"
+	PathKeySign     = "/v1/key/sign/"
+	PathKeyVerify   = "/v1/key/verify/"
+
+	PathKeyBulkEncrypt = "/v1/key/bulk/encrypt/"
+	PathKeyBulkDecrypt = "/v1/key/bulk/decrypt/"
+
+	PathSSHSign = "/v1/ssh/sign/"
+	PathSSHCA   = "/v1/ssh/ca/"
+
+	PathCertIssue = "/v1/cert/issue/"
+	PathCertCA    = "/v1/cert/ca/"
+
+	PathSecretSet    = "/v1/secret/set/"
+	PathSecretGet    = "/v1/secret/get/"
+	PathSecretList   = "/v1/secret/list/"
+	PathSecretDelete = "/v1/secret/delete/"
 
 	PathPolicyDescribe = "/v1/policy/describe/"
 	PathPolicyRead     = "/v1/policy/read/"
 	PathPolicyList     = "/v1/policy/list/"
+	PathPolicyListInfo = "/v1/policy/list-info/"
+	PathPolicyCreate   = "/v1/policy/create/"
+	PathPolicyDelete   = "/v1/policy/delete/"
+	PathPolicyAssign   = "/v1/policy/assign/"
+	PathPolicyTest     = "/v1/policy/test/"
 
 	PathIdentityDescribe     = "/v1/identity/describe/"
 	PathIdentityList         = "/v1/identity/list/"
+	PathIdentityListInfo     = "/v1/identity/list-info/"
+	PathIdentityIssue        = "/v1/identity/issue/"
 	PathIdentitySelfDescribe = "/v1/identity/self/describe"
 
 	PathLogError = "/v1/log/error"
 	PathLogAudit = "/v1/log/audit"
+
+	PathLogAuditProof = "/v1/log/audit/proof/"
+	PathLogAuditQuery = "/v1/log/audit/query"
+
+	PathWatch = "/v1/watch"
+
+	PathSupportBundle = "/v1/support/bundle"
+
+	PathDebugProfile = "/v1/debug/pprof/"
+
+	PathAdminReload   = "/v1/admin/reload"
+	PathAdminUnseal   = "/v1/admin/unseal"
+	PathAdminFreeze   = "/v1/admin/freeze"
+	PathAdminLogLevel = "/v1/admin/log-level"
+
+	PathApprovalList    = "/v1/approval/list"
+	PathApprovalApprove = "/v1/approval/approve/"
+	PathApprovalDeny    = "/v1/approval/deny/"
+
+	PathClusterRaft   = "/v1/cluster/raft"
+	PathClusterList   = "/v1/cluster/list"
+	PathClusterAdd    = "/v1/cluster/add"
+	PathClusterRemove = "/v1/cluster/remove/"
+
+	PathReplicaKey = "/v1/replica/key/"
 )
 
 // Route represents an API route handling a client request.
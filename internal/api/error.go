@@ -9,19 +9,101 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
 )
 
+// Well-known error codes. In contrast to error messages, error
+// codes are stable across releases such that clients can branch
+// on the kind of error. Errors without a well-known code have
+// a code derived from the HTTP status, like "ErrBadRequest".
+const (
+	CodeNotAllowed            = "ErrNotAllowed"
+	CodePartialWrite          = "ErrPartialWrite"
+	CodeKeyNotFound           = "ErrKeyNotFound"
+	CodeKeyExists             = "ErrKeyExists"
+	CodeSecretNotFound        = "ErrSecretNotFound"
+	CodeSecretVersionNotFound = "ErrSecretVersionNotFound"
+	CodeSecretExists          = "ErrSecretExists"
+	CodePolicyNotFound        = "ErrPolicyNotFound"
+	CodePolicyExists          = "ErrPolicyExists"
+	CodeIdentityNotFound      = "ErrIdentityNotFound"
+	CodeIdentityExists        = "ErrIdentityExists"
+	CodeDecrypt               = "ErrDecrypt"
+	CodeFrozen                = "ErrFrozen"
+)
+
+// wellKnownErrors maps the well-known errors of the
+// client SDK to their error codes.
+var wellKnownErrors = map[kes.Error]string{
+	kes.ErrNotAllowed:            CodeNotAllowed,
+	kes.ErrPartialWrite:          CodePartialWrite,
+	kes.ErrKeyNotFound:           CodeKeyNotFound,
+	kes.ErrKeyExists:             CodeKeyExists,
+	kes.ErrSecretNotFound:        CodeSecretNotFound,
+	kes.ErrSecretVersionNotFound: CodeSecretVersionNotFound,
+	kes.ErrSecretExists:          CodeSecretExists,
+	kes.ErrPolicyNotFound:        CodePolicyNotFound,
+	kes.ErrPolicyExists:          CodePolicyExists,
+	kes.ErrIdentityNotFound:      CodeIdentityNotFound,
+	kes.ErrIdentityExists:        CodeIdentityExists,
+	kes.ErrDecrypt:               CodeDecrypt,
+}
+
+// ErrorCode returns the error code of an error with the given
+// HTTP status code and error message.
+func ErrorCode(status int, msg string) string {
+	if code, ok := wellKnownErrors[kes.NewError(status, msg)]; ok {
+		return code
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return "Err" + strconv.Itoa(status)
+	}
+
+	// Turn the status text into a code, e.g.
+	// "Bad Request" into "ErrBadRequest".
+	code := []byte("Err")
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+			code = append(code, c)
+		}
+	}
+	return string(code)
+}
+
+// CodeOf returns the error code of err if err is an Error,
+// or the empty string otherwise.
+func CodeOf(err error) string {
+	e, ok := IsError(err)
+	if !ok {
+		return ""
+	}
+	if c, ok := e.(interface{ Code() string }); ok {
+		return c.Code()
+	}
+	return ErrorCode(e.Status(), e.Error())
+}
+
 // Failr responds to the client with err. The response
 // status code is set to err.Status. The error encoding
 // format is selected automatically based on the response
 // content type. Handlers should return after calling Failr.
 func Failr(r *Response, err Error) error {
+	if e, ok := err.(*retryError); ok {
+		seconds := int64(math.Ceil(e.after.Seconds()))
+		r.Header().Set(headers.RetryAfter, strconv.FormatInt(max(seconds, 1), 10))
+	}
+	if c, ok := err.(interface{ Code() string }); ok {
+		return fail(r, err.Status(), c.Code(), err.Error())
+	}
 	return Fail(r, err.Status(), err.Error())
 }
 
@@ -37,17 +119,26 @@ func Failf(r *Response, code int, format string, a ...any) error {
 // and error message. The message encoding format is selected
 // automatically based on the response content type. Handlers
 // should return after calling Fail.
+//
+// The response contains the error code of the status code and
+// message. See ErrorCode.
 func Fail(r *Response, code int, msg string) error {
+	return fail(r, code, ErrorCode(code, msg), msg)
+}
+
+func fail(r *Response, status int, code, msg string) error {
+	type ErrResponse struct {
+		Message string `json:"message"`
+		Code    string `json:"code"`
+	}
 	var buf bytes.Buffer
-	buf.WriteString(`{"message":`)
-	if err := json.NewEncoder(&buf).Encode(msg); err != nil {
+	if err := json.NewEncoder(&buf).Encode(ErrResponse{Message: msg, Code: code}); err != nil {
 		return err
 	}
-	buf.WriteByte('}')
 
 	r.Header().Set(headers.ContentType, headers.ContentTypeJSON)
 	r.Header().Set(headers.ContentLength, strconv.Itoa(buf.Len()))
-	r.WriteHeader(code)
+	r.WriteHeader(status)
 	_, err := r.Write(buf.Bytes())
 	return err
 }
@@ -75,6 +166,31 @@ func NewError(code int, msg string) Error {
 	}
 }
 
+// NewCodeError returns a new Error from the given status code,
+// error code and error message. Failr sends the error code
+// instead of the one derived from the status code and message.
+func NewCodeError(status int, code, msg string) Error {
+	return &codeError{
+		code:    status,
+		msg:     msg,
+		errCode: code,
+	}
+}
+
+// NewRetryError returns a new Error from the given status
+// code and error message that tells clients to retry the
+// request after the given duration. When sent by Failr,
+// the duration is sent as Retry-After header.
+func NewRetryError(code int, msg string, after time.Duration) Error {
+	return &retryError{
+		codeError: codeError{
+			code: code,
+			msg:  msg,
+		},
+		after: after,
+	}
+}
+
 // IsError reports whether any error in err's tree is an
 // Error. It returns the first error that implements Error,
 // if any.
@@ -112,17 +228,24 @@ func IsError(err error) (Error, bool) {
 // ReadError reads the response body into an Error using
 // the response content encoding. It limits the response
 // body to a reasonable size for typical error messages.
+//
+// The returned Error has the error code sent by the server.
+// For older servers, that don't send error codes, it is
+// derived from the status code and message. See CodeOf.
 func ReadError(resp *http.Response) Error {
 	const MaxSize = 5 * mem.KB // An error message should not exceed 5 KB.
 
-	msg, err := readErrorMessage(resp, MaxSize)
+	msg, code, err := readErrorMessage(resp, MaxSize)
 	if err != nil {
 		return NewError(resp.StatusCode, err.Error())
 	}
-	return NewError(resp.StatusCode, msg)
+	if code == "" {
+		return NewError(resp.StatusCode, msg)
+	}
+	return NewCodeError(resp.StatusCode, code, msg)
 }
 
-func readErrorMessage(resp *http.Response, maxSize mem.Size) (string, error) {
+func readErrorMessage(resp *http.Response, maxSize mem.Size) (string, string, error) {
 	size := mem.Size(resp.ContentLength)
 	if size <= 0 || size > maxSize {
 		size = maxSize
@@ -133,26 +256,44 @@ func readErrorMessage(resp *http.Response, maxSize mem.Size) (string, error) {
 	case headers.ContentTypeHTML, headers.ContentTypeText:
 		var sb strings.Builder
 		if _, err := io.Copy(&sb, body); err != nil {
-			return "", err
+			return "", "", err
 		}
-		return sb.String(), nil
+		return sb.String(), "", nil
 	default:
 		type ErrResponse struct {
-			Message string `json:"error"`
+			Message string `json:"message"`
+			Error   string `json:"error"` // Used by older servers
+			Code    string `json:"code"`
 		}
 		var response ErrResponse
 		if err := json.NewDecoder(body).Decode(&response); err != nil {
-			return "", err
+			return "", "", err
 		}
-		return response.Message, nil
+		if response.Message == "" {
+			response.Message = response.Error
+		}
+		return response.Message, response.Code, nil
 	}
 }
 
 type codeError struct {
-	code int
-	msg  string
+	code    int
+	msg     string
+	errCode string // Optional, see NewCodeError
 }
 
 func (e *codeError) Error() string { return e.msg }
 
 func (e *codeError) Status() int { return e.code }
+
+func (e *codeError) Code() string {
+	if e.errCode != "" {
+		return e.errCode
+	}
+	return ErrorCode(e.code, e.msg)
+}
+
+type retryError struct {
+	codeError
+	after time.Duration
+}
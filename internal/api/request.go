@@ -4,10 +4,67 @@
 
 package api
 
+import "time"
+
 // ImportKeyRequest is the request sent by clients when calling the ImportKey API.
 type ImportKeyRequest struct {
-	Bytes  []byte `json:"key"`
-	Cipher string `json:"cipher"`
+	Bytes  []byte            `json:"key"`
+	Cipher string            `json:"cipher"`
+	Tags   map[string]string `json:"tags,omitempty"` // optional
+
+	// Version is the version number of the imported key, if the
+	// key has been rotated before. Optional.
+	Version uint32 `json:"version,omitempty"`
+
+	Usage     []string  `json:"usage,omitempty"`      // optional
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // optional
+
+	RotationInterval int64 `json:"rotation_interval,omitempty"` // optional, in seconds
+
+	Derived bool `json:"derived,omitempty"` // optional
+}
+
+// ExportKeyRequest is the request sent by clients when calling the ExportKey API.
+type ExportKeyRequest struct {
+	KEK string `json:"kek"` // Name of the key that wraps the exported key
+}
+
+// RestoreKeyRequest is the request sent by clients when calling the RestoreKey API.
+type RestoreKeyRequest struct {
+	KEK string `json:"kek"` // Name of the key that wrapped the exported key
+	Key []byte `json:"key"` // The wrapped key, as returned by the ExportKey API
+}
+
+// CreateKeyRequest is the request sent by clients when calling the CreateKey API.
+// The request body is optional.
+type CreateKeyRequest struct {
+	Tags map[string]string `json:"tags,omitempty"` // optional
+
+	// Usage restricts the operations the key may be used for,
+	// like "encrypt" or "decrypt". Optional. By default, a key
+	// may be used for all operations.
+	Usage []string `json:"usage,omitempty"`
+
+	// ExpiresAt is the point in time after which the key must
+	// no longer be used to encrypt. Ciphertexts can still be
+	// decrypted. Optional.
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+
+	// RotationInterval is the interval, in seconds, at which the
+	// key is rotated automatically. Optional. By default, a key
+	// is only rotated on request.
+	RotationInterval int64 `json:"rotation_interval,omitempty"`
+
+	// Algorithm is the encryption algorithm of the key, either
+	// "AES256" or "ChaCha20". Optional. By default, the server
+	// chooses AES256 if the CPU supports AES-GCM in hardware.
+	Algorithm string `json:"algorithm,omitempty"`
+
+	// Derived controls whether the key derives data keys
+	// deterministically from the context, such that the same
+	// context yields the same data key, instead of generating
+	// random data keys. Optional.
+	Derived bool `json:"derived,omitempty"`
 }
 
 // EncryptKeyRequest is the request sent by clients when calling the EncryptKey API.
@@ -27,7 +84,102 @@ type DecryptKeyRequest struct {
 	Context    []byte `json:"context"` // optional
 }
 
+// BulkEncryptKeyRequest is the request sent by clients when calling the BulkEncryptKey API.
+type BulkEncryptKeyRequest struct {
+	Items []EncryptKeyRequest `json:"items"`
+}
+
+// BulkDecryptKeyRequest is the request sent by clients when calling the BulkDecryptKey API.
+type BulkDecryptKeyRequest struct {
+	Items []DecryptKeyRequest `json:"items"`
+}
+
+// ECDHKeyRequest is the request sent by clients when calling the ECDH API.
+type ECDHKeyRequest struct {
+	PublicKey []byte `json:"public_key"` // optional
+	Info      []byte `json:"info"`       // optional
+}
+
+// SignKeyRequest is the request sent by clients when calling the Sign API.
+type SignKeyRequest struct {
+	Message   []byte `json:"message"`
+	Algorithm string `json:"algorithm"` // optional
+}
+
+// VerifyKeyRequest is the request sent by clients when calling the Verify API.
+type VerifyKeyRequest struct {
+	Message   []byte `json:"message"`
+	Signature []byte `json:"signature"`
+	Algorithm string `json:"algorithm"` // optional
+}
+
+// SSHSignRequest is the request sent by clients when calling the SSH Sign API.
+type SSHSignRequest struct {
+	PublicKey  string   `json:"public_key"`       // In authorized_keys format
+	Type       string   `json:"type,omitempty"`   // Either "user" (default) or "host"
+	Principals []string `json:"principals"`       // User or host names
+	TTL        int64    `json:"ttl,omitempty"`    // Validity in seconds. Optional
+	KeyID      string   `json:"key_id,omitempty"` // Optional
+}
+
+// IssueCertRequest is the request sent by clients when calling the Cert Issue API.
+type IssueCertRequest struct {
+	PublicKey   string   `json:"public_key"`             // PEM-encoded PKIX public key
+	Type        string   `json:"type,omitempty"`         // Either "client" (default) or "server"
+	CommonName  string   `json:"common_name"`            // Subject common name
+	DNSNames    []string `json:"dns_names,omitempty"`    // Optional
+	IPAddresses []string `json:"ip_addresses,omitempty"` // Optional
+	TTL         int64    `json:"ttl,omitempty"`          // Validity in seconds. Optional
+}
+
+// SetSecretRequest is the request sent by clients when calling the Secret Set API.
+type SetSecretRequest struct {
+	Key   string `json:"key"` // Name of the key the secret is encrypted with
+	Value []byte `json:"value"`
+}
+
 // HMACRequest is the request sent by clients when calling the HMAC API.
 type HMACRequest struct {
 	Message []byte `json:"message"`
 }
+
+// CreatePolicyRequest is the request sent by clients when calling the CreatePolicy API.
+type CreatePolicyRequest struct {
+	Allow []string `json:"allow,omitempty"` // optional
+	Deny  []string `json:"deny,omitempty"`  // optional
+}
+
+// AssignPolicyRequest is the request sent by clients when calling the AssignPolicy API.
+type AssignPolicyRequest struct {
+	Identities []string `json:"identities,omitempty"`  // optional
+	FromPolicy string   `json:"from_policy,omitempty"` // optional
+	TTL        string   `json:"ttl,omitempty"`         // optional, e.g. "720h"
+}
+
+// IssueIdentityRequest is the request sent by clients when calling the IssueIdentity API.
+type IssueIdentityRequest struct {
+	TTL string `json:"ttl"` // e.g. "1h"
+}
+
+// UnsealRequest is the request sent by clients when calling the Unseal API.
+type UnsealRequest struct {
+	Share []byte `json:"share,omitempty"` // If empty, only the seal status is returned
+}
+
+// FreezeRequest is the request sent by clients when calling the Freeze API.
+type FreezeRequest struct {
+	Frozen *bool  `json:"frozen,omitempty"` // If nil, only the freeze status is returned
+	Reason string `json:"reason,omitempty"`
+}
+
+// LogLevelRequest is the request sent by clients when calling the LogLevel API.
+type LogLevelRequest struct {
+	ErrorLevel    *string        `json:"error_level,omitempty"` // e.g. "DEBUG". If nil, the error log level is not changed
+	AuditLevel    *string        `json:"audit_level,omitempty"` // e.g. "INFO". If nil, the audit log level is not changed
+	AuditSampling *AuditSampling `json:"audit_sampling,omitempty"`
+}
+
+// AddClusterMemberRequest is the request sent by clients when calling the AddClusterMember API.
+type AddClusterMemberRequest struct {
+	Address string `json:"address"`
+}
@@ -24,9 +24,37 @@ type StatusResponse struct {
 	UsableCPUs int    `json:"num_cpu_used"`
 	HeapAlloc  uint64 `json:"mem_heap_used"`
 	StackAlloc uint64 `json:"mem_stack_used"`
+	FIPS       bool   `json:"fips,omitempty"` // Whether only FIPS 140 approved algorithms are used
+
+	Cipher           string  `json:"cipher,omitempty"`            // The cipher of new keys, e.g. AES256
+	CipherThroughput float64 `json:"cipher_throughput,omitempty"` // Data keys generated per second during a benchmark
 
 	KeyStoreLatency     int64 `json:"keystore_latency,omitempty"` // In microseconds
 	KeyStoreUnreachable bool  `json:"keystore_unreachable,omitempty"`
+	KeyStoreFailover    bool  `json:"keystore_failover,omitempty"` // Whether KES uses the secondary keystore
+
+	KeyStoreFailoverPending  int `json:"keystore_failover_pending,omitempty"`  // Writes not replayed to the primary keystore yet
+	KeyStoreFailoverFailures int `json:"keystore_failover_failures,omitempty"` // Consecutive requests the primary keystore was unreachable
+
+	KeyStoreType          string    `json:"keystore_type,omitempty"`
+	KeyStoreOfflinePolicy string    `json:"keystore_offline_policy,omitempty"`
+	KeyStoreLastSuccess   time.Time `json:"keystore_last_success,omitempty"`
+	KeyStoreLatencyP50    int64     `json:"keystore_latency_p50,omitempty"` // In milliseconds
+	KeyStoreLatencyP90    int64     `json:"keystore_latency_p90,omitempty"` // In milliseconds
+	KeyStoreLatencyP99    int64     `json:"keystore_latency_p99,omitempty"` // In milliseconds
+	KeyStoreOffline       bool      `json:"keystore_offline,omitempty"`     // Whether keys are only served from the cache
+
+	CacheEntries   int    `json:"cache_entries"`
+	CacheHits      uint64 `json:"cache_hits"`
+	CacheMisses    uint64 `json:"cache_misses"`
+	CacheCoalesced uint64 `json:"cache_coalesced"`
+	CacheEvictions uint64 `json:"cache_evictions"`
+
+	NumKeys       int `json:"num_keys"` // -1 if the keys haven't been counted yet
+	NumPolicies   int `json:"num_policies"`
+	NumIdentities int `json:"num_identities"`
+
+	Frozen bool `json:"frozen,omitempty"` // Whether the server rejects mutating requests
 }
 
 // DescribeRouteResponse describes a single API route. It is part of
@@ -43,18 +71,83 @@ type ListAPIsResponse []DescribeRouteResponse
 
 // DescribeKeyResponse is the response sent to clients by the DescribeKey API.
 type DescribeKeyResponse struct {
-	Name      string    `json:"name"`
+	Name      string            `json:"name"`
+	Algorithm string            `json:"algorithm,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitempty"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Usage     *KeyUsage         `json:"usage,omitempty"`
+	Version   uint32            `json:"version,omitempty"`
+	Versions  []KeyVersion      `json:"versions,omitempty"`
+
+	Operations []string  `json:"operations,omitempty"` // Operations the key may be used for. Empty if not restricted
+	ExpiresAt  time.Time `json:"expires_at,omitempty"`
+
+	RotationInterval int64     `json:"rotation_interval,omitempty"` // in seconds
+	NextRotation     time.Time `json:"next_rotation,omitempty"`
+
+	Derived bool `json:"derived,omitempty"` // Whether data keys are derived from the context
+}
+
+// KeyVersion describes a single version of a key. Only the
+// latest version of a key is used to encrypt. Older versions
+// are only used to decrypt.
+type KeyVersion struct {
+	Version   uint32    `json:"version"`
 	Algorithm string    `json:"algorithm,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	CreatedBy string    `json:"created_by,omitempty"`
 }
 
+// ExportKeyResponse is the response sent to clients by the ExportKey API.
+//
+// The key, including all previous versions, is wrapped by the KEK.
+// The remaining fields are not encrypted and only describe the key.
+type ExportKeyResponse struct {
+	Name      string            `json:"name"`
+	KEK       string            `json:"kek"`
+	Key       []byte            `json:"key"`
+	Algorithm string            `json:"algorithm,omitempty"`
+	CreatedAt time.Time         `json:"created_at,omitempty"`
+	CreatedBy string            `json:"created_by,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	Version   uint32            `json:"version,omitempty"`
+}
+
+// ReplicaKeyResponse is the response sent to read replicas by the
+// ReplicaKey API. It contains the key, including all previous
+// versions, in plaintext.
+type ReplicaKeyResponse struct {
+	Key []byte `json:"key"`
+}
+
+// RotateKeyResponse is the response sent to clients by the RotateKey API.
+type RotateKeyResponse struct {
+	Version uint32 `json:"version"`
+}
+
+// KeyUsage contains how often a key has been used for encrypt,
+// decrypt and generate operations and when it has been used last.
+type KeyUsage struct {
+	Encrypt  uint64    `json:"encrypt"`
+	Decrypt  uint64    `json:"decrypt"`
+	Generate uint64    `json:"generate"`
+	LastUsed time.Time `json:"last_used,omitempty"`
+}
+
 // ListKeysResponse is the response sent to clients by the ListKeys API.
 type ListKeysResponse struct {
 	Names      []string `json:"names"`
 	ContinueAt string   `json:"continue_at,omitempty"`
 }
 
+// ListKeyInfosResponse is the response sent to clients by the ListKeyInfos
+// and SearchKeys API.
+type ListKeyInfosResponse struct {
+	Keys       []DescribeKeyResponse `json:"keys"`
+	ContinueAt string                `json:"continue_at,omitempty"`
+}
+
 // EncryptKeyResponse is the response sent to clients by the EncryptKey API.
 type EncryptKeyResponse struct {
 	Ciphertext []byte `json:"ciphertext"`
@@ -71,6 +164,90 @@ type DecryptKeyResponse struct {
 	Plaintext []byte `json:"plaintext"`
 }
 
+// BulkEncryptKeyResponse is the response sent to clients by the BulkEncryptKey API.
+// The i-th item is the result of encrypting the i-th item of the request.
+type BulkEncryptKeyResponse struct {
+	Items []BulkEncryptKeyResult `json:"items"`
+}
+
+// BulkEncryptKeyResult is the result of encrypting a single
+// plaintext. Either Ciphertext or Error is set.
+type BulkEncryptKeyResult struct {
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// BulkDecryptKeyResponse is the response sent to clients by the BulkDecryptKey API.
+// The i-th item is the result of decrypting the i-th item of the request.
+type BulkDecryptKeyResponse struct {
+	Items []BulkDecryptKeyResult `json:"items"`
+}
+
+// BulkDecryptKeyResult is the result of decrypting a single
+// ciphertext. Either Plaintext or Error is set.
+type BulkDecryptKeyResult struct {
+	Plaintext []byte `json:"plaintext,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ECDHKeyResponse is the response sent to clients by the ECDH API.
+type ECDHKeyResponse struct {
+	PublicKey []byte `json:"public_key"`
+	Key       []byte `json:"key,omitempty"`
+}
+
+// SignKeyResponse is the response sent to clients by the Sign API.
+type SignKeyResponse struct {
+	Signature []byte `json:"signature"`
+	PublicKey []byte `json:"public_key"`
+}
+
+// SSHSignResponse is the response sent to clients by the SSH Sign API.
+type SSHSignResponse struct {
+	Certificate string    `json:"certificate"` // In authorized_keys format
+	Serial      uint64    `json:"serial"`
+	ValidAfter  time.Time `json:"valid_after"`
+	ValidBefore time.Time `json:"valid_before"`
+	CAPublicKey string    `json:"ca_public_key"` // In authorized_keys format
+}
+
+// SSHCAResponse is the response sent to clients by the SSH CA API.
+type SSHCAResponse struct {
+	PublicKey string `json:"public_key"` // In authorized_keys format
+}
+
+// IssueCertResponse is the response sent to clients by the Cert Issue API.
+type IssueCertResponse struct {
+	Certificate   string    `json:"certificate"` // PEM-encoded
+	Serial        string    `json:"serial"`      // Hex-encoded
+	NotBefore     time.Time `json:"not_before"`
+	NotAfter      time.Time `json:"not_after"`
+	CACertificate string    `json:"ca_certificate"` // PEM-encoded
+}
+
+// CertCAResponse is the response sent to clients by the Cert CA API.
+type CertCAResponse struct {
+	Certificate string `json:"certificate"` // PEM-encoded
+}
+
+// GetSecretResponse is the response sent to clients by the Secret Get API.
+type GetSecretResponse struct {
+	Value     []byte    `json:"value"`
+	Key       string    `json:"key"` // Name of the key the secret is encrypted with
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+}
+
+// ListSecretsResponse is the response sent to clients by the Secret List API.
+type ListSecretsResponse struct {
+	Names []string `json:"names"`
+}
+
+// VerifyKeyResponse is the response sent to clients by the Verify API.
+type VerifyKeyResponse struct {
+	Valid bool `json:"valid"`
+}
+
 // HMACResponse is the response sent to clients by the HMAC API.
 type HMACResponse struct {
 	Sum []byte `json:"hmac"`
@@ -98,12 +275,38 @@ type ListPoliciesResponse struct {
 	ContinueAt string   `json:"continue_at"`
 }
 
+// ListPolicyInfosResponse is the response sent to clients by the ListPolicyInfos API.
+type ListPolicyInfosResponse struct {
+	Policies []DescribePolicyResponse `json:"policies"`
+}
+
+// AssignPolicyResponse is the response sent to clients by the AssignPolicy API.
+type AssignPolicyResponse struct {
+	Identities []string `json:"identities"`
+}
+
+// TestPolicyResponse is the response sent to clients by the TestPolicy API.
+type TestPolicyResponse struct {
+	Allowed bool   `json:"allowed"`
+	IsAdmin bool   `json:"admin,omitempty"`
+	Policy  string `json:"policy,omitempty"`
+	Allow   string `json:"allow,omitempty"`    // Matching allow pattern, if any
+	Deny    string `json:"deny,omitempty"`     // Matching deny pattern, if any
+	KeyRule string `json:"key_rule,omitempty"` // Matching key rule pattern, if any
+
+	// Conditional indicates that the policy has conditions,
+	// like source IP ranges, that are evaluated per request.
+	// An allowed request may still be rejected by them.
+	Conditional bool `json:"conditional,omitempty"`
+}
+
 // DescribeIdentityResponse is the response sent to clients by the DescribeIdentity API.
 type DescribeIdentityResponse struct {
 	IsAdmin   bool      `json:"admin,omitempty"`
 	Policy    string    `json:"policy,omitempty"`
 	CreatedAt time.Time `json:"created_at"`
 	CreatedBy string    `json:"created_by,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
 }
 
 // ListIdentitiesResponse is the response sent to clients by the ListIdentities API.
@@ -112,6 +315,54 @@ type ListIdentitiesResponse struct {
 	ContinueAt string   `json:"continue_at"`
 }
 
+// ListIdentityInfosResponse is the response sent to clients by the ListIdentityInfos API.
+type ListIdentityInfosResponse struct {
+	Identities []IdentityInfo `json:"identities"`
+}
+
+// IdentityInfo describes an identity listed by the ListIdentityInfos API.
+type IdentityInfo struct {
+	Identity string `json:"identity"`
+	DescribeIdentityResponse
+}
+
+// IssueIdentityResponse is the response sent to clients by the IssueIdentity API.
+type IssueIdentityResponse struct {
+	Identity  string    `json:"identity"`
+	APIKey    string    `json:"api_key"`
+	Policy    string    `json:"policy"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UnsealResponse is the response sent to clients by the Unseal API.
+type UnsealResponse struct {
+	Sealed    bool `json:"sealed"`
+	Threshold int  `json:"threshold,omitempty"`
+	Progress  int  `json:"progress,omitempty"`
+}
+
+// FreezeResponse is the response sent to clients by the Freeze API.
+type FreezeResponse struct {
+	Frozen   bool      `json:"frozen"`
+	Reason   string    `json:"reason,omitempty"`
+	FrozenAt time.Time `json:"frozen_at,omitempty"`
+	FrozenBy string    `json:"frozen_by,omitempty"`
+}
+
+// LogLevelResponse is the response sent to clients by the LogLevel API.
+type LogLevelResponse struct {
+	ErrorLevel    string        `json:"error_level"`
+	AuditLevel    string        `json:"audit_level"`
+	AuditSampling AuditSampling `json:"audit_sampling"`
+}
+
+// AuditSampling controls how many audit events of successful requests
+// are logged. Events of failed requests are always logged.
+type AuditSampling struct {
+	Rate  uint64   `json:"rate"`            // Log 1 in Rate events. 0 and 1 disable sampling
+	Paths []string `json:"paths,omitempty"` // API path patterns, e.g. "/v1/key/decrypt/*". If empty, all paths are sampled
+}
+
 // SelfDescribeIdentityResponse is the response sent to clients by the SelfDescribeIdentity API.
 type SelfDescribeIdentityResponse struct {
 	Identity  string    `json:"identity"`
@@ -122,16 +373,50 @@ type SelfDescribeIdentityResponse struct {
 	Policy *ReadPolicyResponse `json:"policy,omitempty"`
 }
 
+// LifecycleEvent is sent to clients (as stream of events) when they
+// subscribe to the Watch API. Notification targets publish the same
+// JSON objects.
+type LifecycleEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Name     string    `json:"name"`
+	Policy   string    `json:"policy,omitempty"`
+	Identity string    `json:"identity,omitempty"`
+}
+
 // AuditLogEvent is sent to clients (as stream of events) when they subscribe to the AuditLog API.
 type AuditLogEvent struct {
 	Time     time.Time        `json:"time"`
 	Request  AuditLogRequest  `json:"request"`
 	Response AuditLogResponse `json:"response"`
+
+	Checkpoint *AuditLogCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// AuditLogCheckpoint is a signed summary of all audit events
+// before it. An AuditLogEvent containing a checkpoint does not
+// describe a request/response pair.
+type AuditLogCheckpoint struct {
+	Count     uint64 `json:"count"`
+	Hash      []byte `json:"hash"`
+	Root      []byte `json:"root,omitempty"`
+	Signature []byte `json:"signature"`
+}
+
+// AuditProofResponse is the response sent to clients by the AuditProof API.
+// It proves that an audit event is part of the audit Merkle tree with the
+// given size and root.
+type AuditProofResponse struct {
+	Index uint64   `json:"index"`
+	Size  uint64   `json:"size"`
+	Root  []byte   `json:"root"`
+	Proof [][]byte `json:"proof"`
 }
 
 // AuditLogRequest describes a client request in an AuditLogEvent.
 type AuditLogRequest struct {
 	IP       string `json:"ip,omitempty"`
+	Method   string `json:"method,omitempty"`
 	APIPath  string `json:"path"`
 	Identity string `json:"identity,omitempty"`
 }
@@ -139,7 +424,50 @@ type AuditLogRequest struct {
 // AuditLogResponse describes a server response in an AuditLogEvent.
 type AuditLogResponse struct {
 	StatusCode int   `json:"code"`
-	Time       int64 `json:"time"` // In microseconds
+	Time       int64 `json:"time"`               // In microseconds
+	Duration   int64 `json:"duration,omitempty"` // In nanoseconds
+}
+
+// ClusterMember describes a member of a KES cluster.
+type ClusterMember struct {
+	ID      string `json:"id"`
+	Address string `json:"address"`
+	Leader  bool   `json:"leader,omitempty"`
+}
+
+// ListClusterMembersResponse is the response sent to clients by the ListClusterMembers API.
+type ListClusterMembersResponse struct {
+	Members []ClusterMember `json:"members"`
+}
+
+// AddClusterMemberResponse is the response sent to clients by the AddClusterMember API.
+// It contains the new member's ID and all cluster members, including the new one.
+type AddClusterMemberResponse struct {
+	ID      string          `json:"id"`
+	Members []ClusterMember `json:"members"`
+}
+
+// ApprovalRequiredResponse is the response sent to clients when
+// their request has to be approved by another identity.
+type ApprovalRequiredResponse struct {
+	Message   string    `json:"message"`
+	ID        string    `json:"id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PendingRequest describes a request waiting for approval.
+type PendingRequest struct {
+	ID        string    `json:"id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Identity  string    `json:"identity"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ListApprovalsResponse is the response sent to clients by the ListApprovals API.
+type ListApprovalsResponse struct {
+	Requests []PendingRequest `json:"requests"`
 }
 
 // ErrorLogEvent is sent to clients (as stream of events) when they subscribe to the ErrorLog API.
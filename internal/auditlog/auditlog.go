@@ -0,0 +1,67 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package auditlog implements audit log targets that ship KES
+// audit records to external systems, like Kafka clusters.
+//
+// All targets publish audit records as JSON objects using the
+// same format as the AuditLog API:
+//
+//	{
+//	  "time":    "2024-03-04T08:05:10Z",
+//	  "request": {
+//	    "ip":       "10.1.2.3",
+//	    "method":   "POST",
+//	    "path":     "/v1/key/create/my-key",
+//	    "identity": "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+//	  },
+//	  "response": {
+//	    "code":     200,
+//	    "time":     1,
+//	    "duration": 1532917
+//	  }
+//	}
+package auditlog
+
+import (
+	"encoding/json"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/api"
+)
+
+// marshalRecord returns the JSON representation of the audit
+// record.
+func marshalRecord(r kes.AuditRecord) ([]byte, error) {
+	if r.Checkpoint != nil {
+		return json.Marshal(api.AuditLogEvent{
+			Time: r.Checkpoint.Time,
+			Checkpoint: &api.AuditLogCheckpoint{
+				Count:     r.Checkpoint.Count,
+				Hash:      r.Checkpoint.Hash,
+				Root:      r.Checkpoint.Root,
+				Signature: r.Checkpoint.Signature,
+			},
+		})
+	}
+
+	var ip string
+	if r.RemoteIP.IsValid() {
+		ip = r.RemoteIP.String()
+	}
+	return json.Marshal(api.AuditLogEvent{
+		Time: r.Time,
+		Request: api.AuditLogRequest{
+			IP:       ip,
+			Method:   r.Method,
+			APIPath:  r.Path,
+			Identity: r.Identity.String(),
+		},
+		Response: api.AuditLogResponse{
+			StatusCode: r.StatusCode,
+			Time:       r.ResponseTime.Milliseconds(),
+			Duration:   int64(r.ResponseTime),
+		},
+	})
+}
@@ -0,0 +1,373 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auditlog
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+// Supported SASL mechanisms.
+const (
+	SASLPlain       = "PLAIN"
+	SASLScramSHA256 = "SCRAM-SHA-256"
+	SASLScramSHA512 = "SCRAM-SHA-512"
+)
+
+// SASLConfig is a structure containing the SASL credentials
+// used to authenticate to Kafka brokers.
+type SASLConfig struct {
+	// Mechanism is the SASL mechanism. Either PLAIN,
+	// SCRAM-SHA-256 or SCRAM-SHA-512. If empty, no SASL
+	// authentication is performed.
+	Mechanism string
+
+	Username string
+	Password string
+}
+
+// KafkaConfig is a structure containing the Kafka audit log
+// target configuration.
+type KafkaConfig struct {
+	// Brokers are the addresses of the Kafka bootstrap
+	// brokers, e.g. "kafka.example.com:9092".
+	Brokers []string
+
+	// Topic is the topic audit records are published to.
+	Topic string
+
+	// TLS is an optional TLS configuration. If set, the
+	// connections to the Kafka brokers are secured by TLS.
+	TLS *tls.Config
+
+	// SASL contains optional SASL credentials.
+	SASL SASLConfig
+
+	// BufferSize is the max. number of audit records that
+	// are buffered while they cannot be published, for
+	// example because no broker is reachable. If <= 0,
+	// defaults to 10000.
+	BufferSize int
+
+	// BatchSize is the max. number of audit records that
+	// are published at once. If <= 0, defaults to 100.
+	BatchSize int
+
+	// FlushInterval is the max. time audit records are
+	// buffered before they are published. If <= 0,
+	// defaults to 1s.
+	FlushInterval time.Duration
+
+	// BlockTimeout is the max. time audit logging waits
+	// for buffer space once the buffer is full. Audit
+	// records are dropped if no space becomes available
+	// in time. Waiting slows down request processing and
+	// lets the KES server apply backpressure. If <= 0,
+	// records are dropped immediately.
+	BlockTimeout time.Duration
+
+	// ErrorLog is an optional logger for publishing errors.
+	// If nil, slog.Default is used.
+	ErrorLog *slog.Logger
+}
+
+// Kafka is an audit log target that publishes audit records
+// to a Kafka topic.
+//
+// Audit records are buffered in memory and published in
+// batches by a background goroutine. All records are written
+// to partition 0 of the topic to preserve their order. Hence,
+// consumers can verify audit checkpoints.
+//
+// Kafka uses an idempotent Kafka producer. It waits for all
+// in-sync replicas to acknowledge a batch before publishing
+// the next one and retries failed batches until the target
+// is closed.
+type Kafka struct {
+	config KafkaConfig
+	client *kgo.Client
+
+	queue   chan []byte
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	dropped atomic.Uint64
+}
+
+var _ kes.AuditHandler = (*Kafka)(nil) // compiler check
+
+// NewKafka returns a new Kafka target for the given config.
+// It connects to the Kafka brokers lazily when publishing the
+// first batch of audit records.
+func NewKafka(config *KafkaConfig) (*Kafka, error) {
+	const (
+		DefaultBufferSize    = 10000
+		DefaultBatchSize     = 100
+		DefaultFlushInterval = 1 * time.Second
+	)
+
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("auditlog: no Kafka brokers specified")
+	}
+	for _, broker := range config.Brokers {
+		if broker == "" {
+			return nil, errors.New("auditlog: Kafka broker address is empty")
+		}
+	}
+	if config.Topic == "" || len(config.Topic) > 249 || strings.ContainsFunc(config.Topic, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '_' || r == '-')
+	}) {
+		return nil, fmt.Errorf("auditlog: invalid Kafka topic '%s'", config.Topic)
+	}
+	opts := []kgo.Opt{
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.DefaultProduceTopic(config.Topic),
+		kgo.RecordPartitioner(kgo.ManualPartitioner()),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordRetries(3), // Failed batches are retried by publishRetry
+	}
+	if config.TLS != nil {
+		opts = append(opts, kgo.DialTLSConfig(config.TLS.Clone()))
+	}
+
+	var mechanism sasl.Mechanism
+	switch config.SASL.Mechanism {
+	case "":
+		if config.SASL.Username != "" || config.SASL.Password != "" {
+			return nil, errors.New("auditlog: Kafka SASL credentials require a SASL mechanism")
+		}
+	case SASLPlain:
+		mechanism = plain.Auth{User: config.SASL.Username, Pass: config.SASL.Password}.AsMechanism()
+	case SASLScramSHA256:
+		mechanism = scram.Auth{User: config.SASL.Username, Pass: config.SASL.Password}.AsSha256Mechanism()
+	case SASLScramSHA512:
+		mechanism = scram.Auth{User: config.SASL.Username, Pass: config.SASL.Password}.AsSha512Mechanism()
+	default:
+		return nil, fmt.Errorf("auditlog: unsupported SASL mechanism '%s'", config.SASL.Mechanism)
+	}
+	if mechanism != nil {
+		if config.SASL.Username == "" {
+			return nil, errors.New("auditlog: Kafka SASL username is empty")
+		}
+		opts = append(opts, kgo.SASL(mechanism))
+	}
+
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("auditlog: invalid Kafka config: %v", err)
+	}
+
+	k := &Kafka{
+		config:  *config,
+		client:  client,
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	k.config.Brokers = append([]string(nil), config.Brokers...)
+	if config.TLS != nil {
+		k.config.TLS = config.TLS.Clone()
+	}
+	if k.config.BufferSize <= 0 {
+		k.config.BufferSize = DefaultBufferSize
+	}
+	if k.config.BatchSize <= 0 {
+		k.config.BatchSize = DefaultBatchSize
+	}
+	if k.config.FlushInterval <= 0 {
+		k.config.FlushInterval = DefaultFlushInterval
+	}
+	if k.config.ErrorLog == nil {
+		k.config.ErrorLog = slog.Default()
+	}
+	k.queue = make(chan []byte, k.config.BufferSize)
+
+	go k.run()
+	return k, nil
+}
+
+// Enabled reports whether the Kafka target handles audit
+// records. It returns false once the target is closed.
+func (k *Kafka) Enabled(context.Context, slog.Level) bool {
+	select {
+	case <-k.closing:
+		return false
+	default:
+		return true
+	}
+}
+
+// Handle adds the audit record to the buffer of records that
+// are published to Kafka. If the buffer is full, it waits up
+// to the configured block timeout for buffer space. It returns
+// an error if the record has been dropped.
+func (k *Kafka) Handle(ctx context.Context, r kes.AuditRecord) error {
+	msg, err := marshalRecord(r)
+	if err != nil {
+		return err
+	}
+
+	if !k.Enabled(ctx, r.Level) {
+		return errors.New("auditlog: Kafka target is closed")
+	}
+	select {
+	case k.queue <- msg:
+		return nil
+	default:
+	}
+
+	if k.config.BlockTimeout > 0 {
+		timer := time.NewTimer(k.config.BlockTimeout)
+		defer timer.Stop()
+
+		select {
+		case k.queue <- msg:
+			return nil
+		case <-k.closing:
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	k.dropped.Add(1)
+	return errors.New("auditlog: Kafka buffer is full: audit record dropped")
+}
+
+// Dropped returns the number of audit records that have been
+// dropped because the buffer was full or they could not be
+// published before the target was closed.
+func (k *Kafka) Dropped() uint64 { return k.dropped.Load() }
+
+// Close stops accepting new audit records and tries once to
+// publish all buffered records before closing the connections
+// to the Kafka brokers. Buffered records that cannot be
+// published are dropped.
+func (k *Kafka) Close() error {
+	k.once.Do(func() { close(k.closing) })
+	<-k.done
+
+	if n := k.Dropped(); n > 0 {
+		return fmt.Errorf("auditlog: %d audit records have not been published to Kafka", n)
+	}
+	return nil
+}
+
+// run publishes buffered audit records in batches until the
+// target is closed.
+func (k *Kafka) run() {
+	defer close(k.done)
+
+	ticker := time.NewTicker(k.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, k.config.BatchSize)
+	for {
+		select {
+		case msg := <-k.queue:
+			if batch = append(batch, msg); len(batch) < k.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-k.closing:
+			k.drain(batch)
+			return
+		}
+
+		if !k.publishRetry(batch) {
+			k.drain(batch)
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+// publishRetry publishes the batch and retries with an
+// exponential backoff until it succeeds. It returns false
+// if the target has been closed before the batch could be
+// published.
+func (k *Kafka) publishRetry(batch [][]byte) bool {
+	const (
+		MinDelay = 1 * time.Second
+		MaxDelay = 30 * time.Second
+	)
+
+	var failed bool
+	for delay := MinDelay; ; delay = min(2*delay, MaxDelay) {
+		err := k.publish(batch)
+		if err == nil {
+			if failed {
+				k.config.ErrorLog.Info("auditlog: publishing audit records to Kafka succeeded again", "topic", k.config.Topic)
+			}
+			return true
+		}
+		if !failed {
+			k.config.ErrorLog.Error(fmt.Sprintf("auditlog: failed to publish audit records to Kafka: %v", err), "topic", k.config.Topic)
+			failed = true
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-k.closing:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// drain publishes the batch and all buffered audit records
+// once. Records that cannot be published are dropped.
+func (k *Kafka) drain(batch [][]byte) {
+	for {
+		select {
+		case msg := <-k.queue:
+			batch = append(batch, msg)
+			if len(batch) < k.config.BatchSize {
+				continue
+			}
+		default:
+		}
+		if len(batch) == 0 {
+			break
+		}
+		if err := k.publish(batch); err != nil {
+			k.config.ErrorLog.Error(fmt.Sprintf("auditlog: failed to publish audit records to Kafka: %v", err), "topic", k.config.Topic)
+			k.dropped.Add(uint64(len(batch) + len(k.queue)))
+			break
+		}
+		batch = batch[:0]
+	}
+	k.client.Close()
+}
+
+// publish publishes the batch to partition 0 of the Kafka
+// topic.
+func (k *Kafka) publish(batch [][]byte) error {
+	const Timeout = 30 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+
+	records := make([]*kgo.Record, 0, len(batch))
+	for _, msg := range batch {
+		records = append(records, &kgo.Record{Value: msg, Partition: 0})
+	}
+	return k.client.ProduceSync(ctx, records...).FirstErr()
+}
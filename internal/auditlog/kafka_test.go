@@ -0,0 +1,199 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/api"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+)
+
+func TestKafka(t *testing.T) {
+	t.Parallel()
+
+	cluster := newFakeCluster(t, "kes", "secret")
+	kafka, err := NewKafka(&KafkaConfig{
+		Brokers: cluster.ListenAddrs(),
+		Topic:   "kes-audit",
+		SASL: SASLConfig{
+			Mechanism: SASLPlain,
+			Username:  "kes",
+			Password:  "secret",
+		},
+		FlushInterval: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Kafka target: %v", err)
+	}
+
+	ctx := context.Background()
+	paths := []string{"/v1/key/create/my-key", "/v1/key/encrypt/my-key", "/v1/key/delete/my-key"}
+	for _, path := range paths {
+		err = kafka.Handle(ctx, kes.AuditRecord{
+			Time:       time.Now(),
+			Method:     "POST",
+			Path:       path,
+			RemoteIP:   netip.MustParseAddr("10.1.2.3"),
+			StatusCode: 200,
+		})
+		if err != nil {
+			t.Fatalf("Failed to handle audit record: %v", err)
+		}
+	}
+	if err = kafka.Close(); err != nil {
+		t.Fatalf("Failed to close Kafka target: %v", err)
+	}
+	if err = kafka.Handle(ctx, kes.AuditRecord{}); err == nil {
+		t.Fatal("Closed Kafka target accepted audit record")
+	}
+
+	records := consumeRecords(t, cluster, "kes", "secret", len(paths))
+	if len(records) != len(paths) {
+		t.Fatalf("Invalid number of records: got '%d' - want '%d'", len(records), len(paths))
+	}
+	for i, record := range records {
+		var event api.AuditLogEvent
+		if err := json.Unmarshal(record, &event); err != nil {
+			t.Fatalf("Record %d: invalid JSON: %v", i, err)
+		}
+		if event.Request.APIPath != paths[i] {
+			t.Fatalf("Record %d: invalid path: got '%s' - want '%s'", i, event.Request.APIPath, paths[i])
+		}
+		if event.Request.IP != "10.1.2.3" {
+			t.Fatalf("Record %d: invalid IP: got '%s' - want '%s'", i, event.Request.IP, "10.1.2.3")
+		}
+	}
+}
+
+func TestKafkaAuthenticationFailure(t *testing.T) {
+	t.Parallel()
+
+	cluster := newFakeCluster(t, "kes", "secret")
+	kafka, err := NewKafka(&KafkaConfig{
+		Brokers: cluster.ListenAddrs(),
+		Topic:   "kes-audit",
+		SASL: SASLConfig{
+			Mechanism: SASLPlain,
+			Username:  "kes",
+			Password:  "wrong",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Kafka target: %v", err)
+	}
+	if err = kafka.Handle(context.Background(), kes.AuditRecord{Path: "/v1/status"}); err != nil {
+		t.Fatalf("Failed to handle audit record: %v", err)
+	}
+	if err = kafka.Close(); err == nil {
+		t.Fatal("Unpublished audit records have not been reported")
+	}
+	if n := kafka.Dropped(); n != 1 {
+		t.Fatalf("Invalid number of dropped records: got '%d' - want '%d'", n, 1)
+	}
+	if records := consumeRecords(t, cluster, "kes", "secret", 1); len(records) != 0 {
+		t.Fatalf("Unauthenticated client published %d records", len(records))
+	}
+}
+
+func TestNewKafka(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range newKafkaTests {
+		kafka, err := NewKafka(&test.Config)
+		if err == nil {
+			kafka.Close()
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: creating Kafka target should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to create Kafka target: %v", i, err)
+		}
+	}
+}
+
+var newKafkaTests = []struct {
+	Config     KafkaConfig
+	ShouldFail bool
+}{
+	{Config: KafkaConfig{Brokers: []string{"127.0.0.1:9092"}, Topic: "kes-audit"}},                   // 0
+	{Config: KafkaConfig{Topic: "kes-audit"}, ShouldFail: true},                                      // 1
+	{Config: KafkaConfig{Brokers: []string{"127.0.0.1:9092"}}, ShouldFail: true},                     // 2
+	{Config: KafkaConfig{Brokers: []string{"127.0.0.1:9092"}, Topic: "kes audit"}, ShouldFail: true}, // 3
+	{ // 4
+		Config: KafkaConfig{
+			Brokers: []string{"127.0.0.1:9092"},
+			Topic:   "kes-audit",
+			SASL:    SASLConfig{Mechanism: SASLScramSHA256, Username: "kes", Password: "secret"},
+		},
+	},
+	{ // 5
+		Config: KafkaConfig{
+			Brokers: []string{"127.0.0.1:9092"},
+			Topic:   "kes-audit",
+			SASL:    SASLConfig{Mechanism: "GSSAPI", Username: "kes"},
+		},
+		ShouldFail: true,
+	},
+	{ // 6
+		Config: KafkaConfig{
+			Brokers: []string{"127.0.0.1:9092"},
+			Topic:   "kes-audit",
+			SASL:    SASLConfig{Username: "kes", Password: "secret"},
+		},
+		ShouldFail: true,
+	},
+}
+
+// newFakeCluster returns a Kafka cluster with a single broker
+// that authenticates clients using SASL PLAIN and contains the
+// topic "kes-audit".
+func newFakeCluster(t *testing.T, username, password string) *kfake.Cluster {
+	cluster, err := kfake.NewCluster(
+		kfake.NumBrokers(1),
+		kfake.SeedTopics(1, "kes-audit"),
+		kfake.EnableSASL(),
+		kfake.Superuser(SASLPlain, username, password),
+	)
+	if err != nil {
+		t.Fatalf("Failed to start Kafka cluster: %v", err)
+	}
+	t.Cleanup(cluster.Close)
+	return cluster
+}
+
+// consumeRecords returns the values of the first n records
+// of the topic "kes-audit". It returns fewer values if the
+// topic does not contain n records within a few seconds.
+func consumeRecords(t *testing.T, cluster *kfake.Cluster, username, password string, n int) [][]byte {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(cluster.ListenAddrs()...),
+		kgo.ConsumeTopics("kes-audit"),
+		kgo.SASL(plain.Auth{User: username, Pass: password}.AsMechanism()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create Kafka client: %v", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var records [][]byte
+	for len(records) < n && ctx.Err() == nil {
+		client.PollFetches(ctx).EachRecord(func(r *kgo.Record) {
+			records = append(records, r.Value)
+		})
+	}
+	return records
+}
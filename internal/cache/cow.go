@@ -33,6 +33,13 @@ func NewCow[K comparable, V any](capacity int) *Cow[K, V] {
 // The zero Cow is empty and ready for use.
 // A Cow must not be copied after first use.
 type Cow[K comparable, V any] struct {
+	// OnRemove, if set, is called for each value that is
+	// removed from the Cow or replaced by another value.
+	// It is called while holding the Cow's write lock and
+	// must not modify the Cow. OnRemove must not be changed
+	// after first use.
+	OnRemove func(K, V)
+
 	mu       sync.Mutex
 	ptr      atomic.Pointer[map[K]V]
 	capacity int
@@ -86,6 +93,9 @@ func (c *Cow[K, V]) Set(key K, value V) bool {
 	w[key] = value
 
 	c.ptr.Store(&w)
+	if v, ok := r[key]; ok {
+		c.removed(key, v)
+	}
 	return true
 }
 
@@ -150,6 +160,7 @@ func (c *Cow[K, V]) Delete(key K) bool {
 	delete(w, key)
 
 	c.ptr.Store(&w)
+	c.removed(key, r[key])
 	return true
 }
 
@@ -158,8 +169,11 @@ func (c *Cow[K, V]) DeleteAll() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	if c.ptr.Load() != nil {
+	if p := c.ptr.Load(); p != nil {
 		c.ptr.Store(new(map[K]V))
+		for k, v := range *p {
+			c.removed(k, v)
+		}
 	}
 }
 
@@ -176,13 +190,29 @@ func (c *Cow[K, V]) DeleteFunc(f func(K, V) bool) {
 
 	r := *p
 	w := make(map[K]V, len(r)/2)
+	var removed map[K]V
 	for k, v := range r {
 		if !f(k, v) {
 			w[k] = v
+		} else if c.OnRemove != nil {
+			if removed == nil {
+				removed = map[K]V{}
+			}
+			removed[k] = v
 		}
 	}
 
 	c.ptr.Store(&w)
+	for k, v := range removed {
+		c.removed(k, v)
+	}
+}
+
+// removed calls OnRemove, if set.
+func (c *Cow[K, V]) removed(key K, value V) {
+	if c.OnRemove != nil {
+		c.OnRemove(key, value)
+	}
 }
 
 // Clone returns a copy of the Cow.
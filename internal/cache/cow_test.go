@@ -4,7 +4,10 @@
 
 package cache
 
-import "testing"
+import (
+	"slices"
+	"testing"
+)
 
 func TestCowZeroValue(t *testing.T) {
 	t.Run("Get", func(t *testing.T) {
@@ -74,3 +77,24 @@ func TestCowCapacity(t *testing.T) {
 		t.Fatalf("Failed to add '%d'", 3)
 	}
 }
+
+func TestCowOnRemove(t *testing.T) {
+	var removed []int
+	var cow Cow[int, string]
+	cow.OnRemove = func(k int, _ string) { removed = append(removed, k) }
+
+	cow.Set(0, "Hello")
+	cow.Set(1, "World")
+	cow.Set(2, "!")
+	cow.Add(0, "World") // does not replace 0
+	cow.Set(0, "World") // replaces 0
+	cow.Delete(1)
+	cow.DeleteFunc(func(k int, _ string) bool { return k == 2 })
+	cow.Set(3, "!")
+	cow.DeleteAll() // removes 0 and 3
+
+	slices.Sort(removed[3:])
+	if want := []int{0, 1, 2, 0, 3}; !slices.Equal(removed, want) {
+		t.Fatalf("Invalid removed entries: got '%v' - want '%v'", removed, want)
+	}
+}
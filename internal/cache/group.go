@@ -0,0 +1,77 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// A Group coalesces concurrent calls for the same key K
+// into one call. Only the first caller executes the call.
+// All others wait and receive its result.
+//
+// Unlike a Barrier, a Group shares errors as well. Hence,
+// it is well suited for fetching values from a slow or
+// overloaded backend.
+//
+// The zero value for a Group is ready for use.
+//
+// A Group must not be copied after first use.
+type Group[K comparable, V any] struct {
+	mu    sync.Mutex
+	calls map[K]*call[V]
+}
+
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// errPanic is returned to waiting callers
+// when the executing call panics.
+var errPanic = errors.New("cache: call panicked")
+
+// Do executes f and returns its result, unless a call
+// for the same key is already in progress. Then, it
+// waits for this call to complete and returns its
+// result. It reports whether the result is shared
+// with another caller.
+//
+// If ctx is canceled while waiting, Do returns the
+// context error without waiting any longer. However,
+// the call in progress is not canceled.
+func (g *Group[K, V]) Do(ctx context.Context, key K, f func() (V, error)) (v V, shared bool, err error) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-c.done:
+			return c.value, true, c.err
+		case <-ctx.Done():
+			return v, false, ctx.Err()
+		}
+	}
+
+	c := &call[V]{done: make(chan struct{}), err: errPanic}
+	if g.calls == nil {
+		g.calls = make(map[K]*call[V])
+	}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+
+		close(c.done)
+	}()
+	c.value, c.err = f()
+	return c.value, false, c.err
+}
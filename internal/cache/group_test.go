@@ -0,0 +1,84 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGroupDo(t *testing.T) {
+	const N = 100
+	var (
+		g      Group[int, int]
+		calls  atomic.Uint32
+		shared atomic.Uint32
+		wg     sync.WaitGroup
+	)
+	errFetch := errors.New("fetch failed")
+
+	release := make(chan struct{})
+	ready := make(chan struct{})
+	go g.Do(context.Background(), 0, func() (int, error) {
+		close(ready)
+		<-release
+		calls.Add(1)
+		return 42, errFetch
+	})
+	<-ready // The first call is in progress
+
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			v, ok, err := g.Do(context.Background(), 0, func() (int, error) {
+				calls.Add(1)
+				return 0, nil
+			})
+			if !ok {
+				return
+			}
+			shared.Add(1)
+			if v != 42 || err != errFetch {
+				t.Errorf("Invalid shared result: got '%d' and '%v' - want '42' and '%v'", v, err, errFetch)
+			}
+		}()
+	}
+	time.Sleep(100 * time.Millisecond) // Let all goroutines wait for the first call
+	close(release)
+	wg.Wait()
+
+	if n := calls.Load(); n != 1 {
+		t.Fatalf("Calls have not been coalesced: got '%d' calls - want '1'", n)
+	}
+	if n := shared.Load(); n != N {
+		t.Fatalf("Invalid number of shared results: got '%d' - want '%d'", n, N)
+	}
+}
+
+func TestGroupDoCanceled(t *testing.T) {
+	var g Group[int, int]
+
+	release := make(chan struct{})
+	ready := make(chan struct{})
+	go g.Do(context.Background(), 0, func() (int, error) {
+		close(ready)
+		<-release
+		return 0, nil
+	})
+	<-ready
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, shared, err := g.Do(ctx, 0, func() (int, error) { return 0, nil }); shared || !errors.Is(err, context.Canceled) {
+		t.Fatalf("Waiting call has not been canceled: got shared '%v' and error '%v'", shared, err)
+	}
+}
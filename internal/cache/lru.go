@@ -0,0 +1,211 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// NewLRU returns a new LRU cache with the given capacity
+// that can hold at most N entries at the same time; N
+// being the capacity. If the capacity is zero or negative,
+// the LRU is unbounded.
+func NewLRU[K comparable, V any](capacity int) *LRU[K, V] {
+	return &LRU[K, V]{capacity: capacity}
+}
+
+// LRU is a cache that evicts the least recently
+// used entry once it has reached its capacity
+// limit.
+//
+// Unlike a Cow, an LRU is well suited for many
+// entries and frequent updates. However, any read
+// operation requires a lock since it marks the
+// entry as recently used.
+//
+// The zero LRU is empty, unbounded and ready for use.
+// An LRU must not be copied after first use.
+type LRU[K comparable, V any] struct {
+	// OnRemove, if set, is called for each value that is
+	// removed from the LRU, either explicitly, evicted or
+	// replaced by another value. It is called while holding
+	// the LRU's lock and must not access the LRU. OnRemove
+	// must not be changed after first use.
+	OnRemove func(K, V)
+
+	mu       sync.Mutex
+	entries  map[K]*list.Element
+	order    list.List // Most recently used entry first
+	capacity int
+}
+
+type lruEntry[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Get returns the value associated with the given
+// key, if any, and reports whether a value has
+// been found. It marks the entry as most recently
+// used.
+func (c *LRU[K, V]) Get(key K) (v V, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return v, false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*lruEntry[K, V]).Value, true
+}
+
+// Set adds the key value pair, or replaces an
+// existing value, and marks it as most recently
+// used.
+//
+// If the LRU has reached its capacity limit, if
+// set, Set evicts the least recently used entry
+// and reports whether an entry has been evicted.
+func (c *LRU[K, V]) Set(key K, value V) (evicted bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.set(key, value) {
+		return false
+	}
+	if c.capacity > 0 && c.order.Len() > c.capacity {
+		e := c.order.Back()
+		c.order.Remove(e)
+
+		entry := e.Value.(*lruEntry[K, V])
+		delete(c.entries, entry.Key)
+		c.removed(entry.Key, entry.Value)
+		return true
+	}
+	return false
+}
+
+// TrySet adds the key value pair, or replaces
+// an existing value, and reports whether the
+// given value has been stored.
+//
+// If the LRU has reached its capacity limit,
+// if set, TrySet does not add the value and
+// returns false. However, it still replaces
+// existing values, since this does not increase
+// the size of the LRU.
+func (c *LRU[K, V]) TrySet(key K, value V) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; !ok && c.capacity > 0 && c.order.Len() >= c.capacity {
+		return false
+	}
+	c.set(key, value)
+	return true
+}
+
+// set adds or replaces the value and reports
+// whether an existing value has been replaced.
+func (c *LRU[K, V]) set(key K, value V) (replaced bool) {
+	if e, ok := c.entries[key]; ok {
+		entry := e.Value.(*lruEntry[K, V])
+		c.removed(key, entry.Value)
+		entry.Value = value
+		c.order.MoveToFront(e)
+		return true
+	}
+
+	if c.entries == nil {
+		c.entries = map[K]*list.Element{}
+	}
+	c.entries[key] = c.order.PushFront(&lruEntry[K, V]{Key: key, Value: value})
+	return false
+}
+
+// Delete removes the given entry and reports
+// whether it was present.
+func (c *LRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return false
+	}
+	c.order.Remove(e)
+	delete(c.entries, key)
+	c.removed(key, e.Value.(*lruEntry[K, V]).Value)
+	return true
+}
+
+// DeleteAll removes all entries and returns
+// the number of removed entries.
+func (c *LRU[K, V]) DeleteAll() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.order.Len()
+	if c.OnRemove != nil {
+		for e := c.order.Front(); e != nil; e = e.Next() {
+			entry := e.Value.(*lruEntry[K, V])
+			c.OnRemove(entry.Key, entry.Value)
+		}
+	}
+	c.entries = nil
+	c.order.Init()
+	return n
+}
+
+// DeleteFunc calls f for each entry and removes any
+// entry for which f returns true. It returns the
+// number of removed entries.
+func (c *LRU[K, V]) DeleteFunc(f func(K, V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n int
+	for e := c.order.Front(); e != nil; {
+		next := e.Next()
+		if entry := e.Value.(*lruEntry[K, V]); f(entry.Key, entry.Value) {
+			c.order.Remove(e)
+			delete(c.entries, entry.Key)
+			c.removed(entry.Key, entry.Value)
+			n++
+		}
+		e = next
+	}
+	return n
+}
+
+// removed calls OnRemove, if set.
+func (c *LRU[K, V]) removed(key K, value V) {
+	if c.OnRemove != nil {
+		c.OnRemove(key, value)
+	}
+}
+
+// Len returns the number of entries.
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// Keys returns a slice of all keys of the LRU, most
+// recently used first. It never returns nil.
+func (c *LRU[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, c.order.Len())
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*lruEntry[K, V]).Key)
+	}
+	return keys
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cache
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLRUZeroValue(t *testing.T) {
+	var lru LRU[int, string]
+	if v, ok := lru.Get(0); ok {
+		t.Fatalf("Empty LRU contains value: %v", v)
+	}
+	if lru.Delete(0) {
+		t.Fatal("Empty LRU contains value")
+	}
+	if n := lru.DeleteAll(); n != 0 {
+		t.Fatalf("Removed '%d' entries from empty LRU", n)
+	}
+	for i := 0; i < 100; i++ {
+		if lru.Set(i, "Hello") {
+			t.Fatal("Unbounded LRU evicted an entry")
+		}
+	}
+	if n := lru.Len(); n != 100 {
+		t.Fatalf("Invalid number of entries: got '%d' - want '100'", n)
+	}
+}
+
+func TestLRUCapacity(t *testing.T) {
+	const Cap = 3
+
+	lru := NewLRU[int, string](Cap)
+	for i := 0; i < Cap; i++ {
+		if lru.Set(i, "Hello") {
+			t.Fatalf("Evicted entry when adding '%d'", i)
+		}
+	}
+	if _, ok := lru.Get(0); !ok { // 1 is now the least recently used entry
+		t.Fatal("Failed to get '0'")
+	}
+	if !lru.Set(3, "World") {
+		t.Fatal("No entry evicted when adding '3' to full LRU")
+	}
+	if _, ok := lru.Get(1); ok {
+		t.Fatal("Least recently used entry '1' has not been evicted")
+	}
+	if _, ok := lru.Get(0); !ok {
+		t.Fatal("Recently used entry '0' has been evicted")
+	}
+	if lru.Set(0, "World") {
+		t.Fatal("Evicted entry when replacing '0'")
+	}
+
+	if lru.TrySet(4, "!") {
+		t.Fatal("Added '4' to full LRU")
+	}
+	if !lru.TrySet(3, "!") {
+		t.Fatal("Failed to replace '3' in full LRU")
+	}
+	if n := lru.DeleteFunc(func(k int, _ string) bool { return k == 3 }); n != 1 {
+		t.Fatalf("Invalid number of deleted entries: got '%d' - want '1'", n)
+	}
+	if !lru.TrySet(4, "!") {
+		t.Fatal("Failed to add '4' to LRU that is not full")
+	}
+	if n := lru.Len(); n != Cap {
+		t.Fatalf("Invalid number of entries: got '%d' - want '%d'", n, Cap)
+	}
+}
+
+func TestLRUOnRemove(t *testing.T) {
+	var removed []int
+	lru := NewLRU[int, string](2)
+	lru.OnRemove = func(k int, _ string) { removed = append(removed, k) }
+
+	lru.Set(0, "Hello")
+	lru.Set(1, "World")
+	lru.Set(2, "!")     // evicts 0
+	lru.Set(1, "Hello") // replaces 1
+	lru.Delete(2)       // removes 2
+	lru.Set(3, "World") // no eviction
+	lru.DeleteFunc(func(k int, _ string) bool { return k == 3 })
+	lru.DeleteAll() // removes 1
+
+	if want := []int{0, 1, 2, 3, 1}; !slices.Equal(removed, want) {
+		t.Fatalf("Invalid removed entries: got '%v' - want '%v'", removed, want)
+	}
+}
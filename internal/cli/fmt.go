@@ -9,15 +9,33 @@ import (
 	"os"
 
 	tui "github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
-var errPrefix = tui.NewStyle().Foreground(tui.Color("#ac0000")).Render("Error: ")
+// stderr renders styled output written to OS stderr. Its color
+// profile is detected independently from OS stdout such that
+// errors written to a pipe or log file contain no escape codes.
+var stderr = tui.NewRenderer(os.Stderr)
+
+// Stderr returns a new style for output written to OS stderr.
+func Stderr() tui.Style { return stderr.NewStyle() }
+
+// DisableColors disables colored and styled output written
+// to OS stdout and OS stderr.
+func DisableColors() {
+	tui.SetColorProfile(termenv.Ascii)
+	stderr.SetColorProfile(termenv.Ascii)
+}
+
+func errPrefix() string {
+	return stderr.NewStyle().Foreground(tui.Color("#ac0000")).Render("Error: ")
+}
 
 // Fatal writes an error prefix and the operands
 // to OS stderr. Then, Fatal terminates the program by
 // calling os.Exit(1).
 func Fatal(v ...any) {
-	fmt.Fprint(os.Stderr, errPrefix)
+	fmt.Fprint(os.Stderr, errPrefix())
 	fmt.Fprint(os.Stderr, v...)
 	fmt.Fprintln(os.Stderr)
 	os.Exit(1)
@@ -27,7 +45,7 @@ func Fatal(v ...any) {
 // formatted according to the format specifier, to OS stderr.
 // Then, Fatalf terminates the program by calling os.Exit(1).
 func Fatalf(format string, v ...any) {
-	fmt.Fprintf(os.Stderr, errPrefix+format+"\n", v...)
+	fmt.Fprintf(os.Stderr, errPrefix()+format+"\n", v...)
 	os.Exit(1)
 }
 
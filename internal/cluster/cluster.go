@@ -0,0 +1,709 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package cluster implements a replicated state machine shared
+// by multiple KES servers using the Raft consensus protocol.
+//
+// Each cluster member runs a Node that persists the Raft log in a
+// local directory. Commands proposed on any member are replicated
+// to all members and applied in the same order by each member's
+// StateMachine. Members exchange Raft messages via HTTPS.
+package cluster
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.etcd.io/etcd/raft/v3"
+	pb "go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+// A StateMachine is replicated across all cluster members.
+//
+// Commands are applied in the same order on all members.
+// Hence, Apply must be deterministic. Since commands may be
+// applied again when a member restarts, applying a command
+// more than once must result in the same state.
+type StateMachine interface {
+	// Apply applies the command. The returned error is passed
+	// to the member that proposed the command, if it is waiting
+	// for the command to be applied. An error does not stop
+	// the replication.
+	Apply(cmd []byte) error
+
+	// Snapshot returns a snapshot of the current state.
+	Snapshot() ([]byte, error)
+
+	// Restore replaces the current state with the snapshot.
+	Restore(snapshot []byte) error
+}
+
+// Config is a structure containing the configuration of a
+// cluster member.
+type Config struct {
+	// Addr is the HTTPS URL of this member, e.g.
+	// "https://10.1.2.1:7373". Other members send Raft
+	// messages to this address.
+	Addr string
+
+	// Dir is the directory containing the persisted Raft
+	// state of this member.
+	Dir string
+
+	// Peers are the addresses of all initial members when
+	// bootstrapping a new cluster, including Addr. When joining
+	// an existing cluster, Peers are the addresses of the current
+	// members. Peers are ignored if Dir contains Raft state.
+	Peers []string
+
+	// Join indicates that this member has been added to an
+	// existing cluster and receives the cluster state from
+	// the current leader.
+	Join bool
+
+	// Path is the HTTP path, on all members, Raft messages
+	// are sent to.
+	Path string
+
+	// Client is the HTTP client used to send Raft messages
+	// to other members.
+	Client *http.Client
+
+	// StateMachine is the replicated state machine.
+	StateMachine StateMachine
+
+	// ErrorLog is an optional logger for errors. If nil,
+	// slog.Default is used.
+	ErrorLog *slog.Logger
+
+	// TickInterval is the duration of one Raft tick. Leaders
+	// send heartbeats once per tick and followers start an
+	// election after 10 ticks without heartbeat. If <= 0,
+	// defaults to 100ms.
+	TickInterval time.Duration
+
+	// SnapshotCount is the number of applied log entries after
+	// which a new snapshot is taken and the log is compacted.
+	// If 0, defaults to 1000.
+	SnapshotCount uint64
+}
+
+// Member is a cluster member.
+type Member struct {
+	ID     uint64
+	Addr   string
+	Leader bool
+}
+
+// MemberID returns the ID of the member with the given address.
+func MemberID(addr string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(strings.TrimSuffix(strings.ToLower(addr), "/")))
+	if id := h.Sum64(); id != raft.None {
+		return id
+	}
+	return 1
+}
+
+// Errors returned by a Node.
+var (
+	// ErrRemoved is returned by a Node that has been removed
+	// from the cluster.
+	ErrRemoved = errors.New("cluster: member has been removed from the cluster")
+
+	// ErrMemberNotFound is returned when removing a member
+	// that does not exist.
+	ErrMemberNotFound = errors.New("cluster: member does not exist")
+
+	// ErrLastMember is returned when removing the last member.
+	ErrLastMember = errors.New("cluster: cannot remove the last member")
+)
+
+// A Node is a running cluster member.
+type Node struct {
+	id     uint64
+	addr   string
+	config Config
+	log    *slog.Logger
+
+	raft    raft.Node
+	memory  *raft.MemoryStorage
+	storage *diskStorage
+
+	mu        sync.Mutex
+	members   map[uint64]string
+	confState pb.ConfState
+	applied   uint64
+	snapshot  uint64 // Index of the latest snapshot
+	waiters   map[uint64]chan error
+	peers     map[uint64]*peer
+	removed   bool
+
+	appliedCh chan struct{} // Closed and replaced whenever entries have been applied
+	stop      chan struct{}
+	done      chan struct{}
+	once      sync.Once
+}
+
+// Start starts a cluster member. If the member restarts, it
+// replays all persisted log entries before it returns.
+func Start(config *Config) (*Node, error) {
+	const (
+		DefaultTickInterval  = 100 * time.Millisecond
+		DefaultSnapshotCount = 1000
+	)
+
+	addr, err := url.Parse(config.Addr)
+	if err != nil || addr.Scheme != "https" || addr.Host == "" {
+		return nil, fmt.Errorf("cluster: invalid member address '%s'", config.Addr)
+	}
+	if config.Dir == "" {
+		return nil, errors.New("cluster: state directory is empty")
+	}
+	if config.StateMachine == nil {
+		return nil, errors.New("cluster: no state machine")
+	}
+
+	storage, state, err := openDiskStorage(config.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	n := &Node{
+		id:        MemberID(config.Addr),
+		addr:      config.Addr,
+		config:    *config,
+		log:       config.ErrorLog,
+		memory:    raft.NewMemoryStorage(),
+		storage:   storage,
+		members:   map[uint64]string{},
+		waiters:   map[uint64]chan error{},
+		peers:     map[uint64]*peer{},
+		appliedCh: make(chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	if n.log == nil {
+		n.log = slog.Default()
+	}
+	if n.config.Client == nil {
+		n.config.Client = http.DefaultClient
+	}
+	if n.config.TickInterval <= 0 {
+		n.config.TickInterval = DefaultTickInterval
+	}
+	if n.config.SnapshotCount == 0 {
+		n.config.SnapshotCount = DefaultSnapshotCount
+	}
+
+	if !raft.IsEmptySnap(state.Snapshot) {
+		if err = n.restore(state.Snapshot); err != nil {
+			storage.Close()
+			return nil, err
+		}
+		n.memory.ApplySnapshot(state.Snapshot)
+	}
+	if !raft.IsEmptyHardState(state.HardState) {
+		n.memory.SetHardState(state.HardState)
+	}
+	for _, e := range state.Entries { // Later entries may replace earlier ones
+		if err = n.memory.Append([]pb.Entry{e}); err != nil {
+			storage.Close()
+			return nil, fmt.Errorf("cluster: invalid log entries: %v", err)
+		}
+	}
+
+	c := &raft.Config{
+		ID:              n.id,
+		ElectionTick:    10,
+		HeartbeatTick:   1,
+		Storage:         n.memory,
+		Applied:         n.applied,
+		MaxSizePerMsg:   1 << 20,
+		MaxInflightMsgs: 256,
+		CheckQuorum:     true,
+		PreVote:         true,
+		Logger:          &raftLogger{log: n.log},
+	}
+	switch {
+	case !state.Empty():
+		n.raft = raft.RestartNode(c)
+	case config.Join:
+		// The new member learns about the other members once it
+		// has received the cluster state. Until then, it has to
+		// know where to send its responses to.
+		for _, p := range config.Peers {
+			n.members[MemberID(p)] = p
+		}
+		n.raft = raft.RestartNode(c)
+	default:
+		peers := make([]raft.Peer, 0, len(config.Peers)+1)
+		for _, p := range append(slices.Clone(config.Peers), config.Addr) {
+			id := MemberID(p)
+			if !slices.ContainsFunc(peers, func(peer raft.Peer) bool { return peer.ID == id }) {
+				peers = append(peers, raft.Peer{ID: id, Context: []byte(p)})
+			}
+		}
+		n.raft = raft.StartNode(c, peers)
+	}
+	go n.run()
+
+	// Replay all entries that have been committed before the
+	// restart such that the state machine is up-to-date.
+	if commit := state.HardState.Commit; commit > n.appliedIndex() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err = n.waitApplied(ctx, commit); err != nil {
+			n.Close()
+			return nil, fmt.Errorf("cluster: failed to replay log entries: %v", err)
+		}
+	}
+	return n, nil
+}
+
+// ID returns the member ID of the node.
+func (n *Node) ID() uint64 { return n.id }
+
+// IsLeader reports whether the node is the cluster leader.
+func (n *Node) IsLeader() bool { return n.raft.Status().Lead == n.id }
+
+// Members returns all cluster members, as known to this node.
+func (n *Node) Members() []Member {
+	leader := n.raft.Status().Lead
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	members := make([]Member, 0, len(n.members))
+	for id, addr := range n.members {
+		members = append(members, Member{
+			ID:     id,
+			Addr:   addr,
+			Leader: id == leader,
+		})
+	}
+	slices.SortFunc(members, func(a, b Member) int { return strings.Compare(a.Addr, b.Addr) })
+	return members
+}
+
+// Propose proposes the command and waits until this node has
+// applied it. It returns the error returned by the state
+// machine when applying the command.
+func (n *Node) Propose(ctx context.Context, cmd []byte) error {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return err
+	}
+	waiter := make(chan error, 1)
+	key := binary.BigEndian.Uint64(id[:])
+
+	n.mu.Lock()
+	if n.removed {
+		n.mu.Unlock()
+		return ErrRemoved
+	}
+	n.waiters[key] = waiter
+	n.mu.Unlock()
+
+	defer func() {
+		n.mu.Lock()
+		delete(n.waiters, key)
+		n.mu.Unlock()
+	}()
+
+	if err := n.raft.Propose(ctx, append(id[:], cmd...)); err != nil {
+		return fmt.Errorf("cluster: failed to propose command: %v", err)
+	}
+	select {
+	case err := <-waiter:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("cluster: command has not been applied: %w", context.Cause(ctx))
+	case <-n.done:
+		return errors.New("cluster: node stopped")
+	}
+}
+
+// AddMember adds a new member with the given address to the
+// cluster and waits until this node has applied the change.
+func (n *Node) AddMember(ctx context.Context, addr string) (Member, error) {
+	if u, err := url.Parse(addr); err != nil || u.Scheme != "https" || u.Host == "" {
+		return Member{}, fmt.Errorf("cluster: invalid member address '%s'", addr)
+	}
+	id := MemberID(addr)
+	if n.hasMember(id) {
+		return Member{ID: id, Addr: addr}, nil
+	}
+
+	err := n.raft.ProposeConfChange(ctx, pb.ConfChange{
+		Type:    pb.ConfChangeAddNode,
+		NodeID:  id,
+		Context: []byte(addr),
+	})
+	if err != nil {
+		return Member{}, fmt.Errorf("cluster: failed to add member: %v", err)
+	}
+	if err = n.waitMember(ctx, id, true); err != nil {
+		return Member{}, err
+	}
+	return Member{ID: id, Addr: addr}, nil
+}
+
+// RemoveMember removes the member from the cluster and waits
+// until this node has applied the change.
+func (n *Node) RemoveMember(ctx context.Context, id uint64) error {
+	if !n.hasMember(id) {
+		return ErrMemberNotFound
+	}
+	if len(n.Members()) == 1 {
+		return ErrLastMember
+	}
+
+	err := n.raft.ProposeConfChange(ctx, pb.ConfChange{
+		Type:   pb.ConfChangeRemoveNode,
+		NodeID: id,
+	})
+	if err != nil {
+		return fmt.Errorf("cluster: failed to remove member: %v", err)
+	}
+	if id == n.id { // This node stops applying changes once removed
+		return nil
+	}
+	return n.waitMember(ctx, id, false)
+}
+
+// Step passes a Raft message, sent by another member, to the node.
+func (n *Node) Step(ctx context.Context, msg []byte) error {
+	var m pb.Message
+	if err := m.Unmarshal(msg); err != nil {
+		return fmt.Errorf("cluster: invalid message: %v", err)
+	}
+	if m.To != n.id {
+		return fmt.Errorf("cluster: message for member '%x' sent to member '%x'", m.To, n.id)
+	}
+	return n.raft.Step(ctx, m)
+}
+
+// Close stops the node.
+func (n *Node) Close() error {
+	n.once.Do(func() { close(n.stop) })
+	<-n.done
+
+	n.mu.Lock()
+	for _, p := range n.peers {
+		p.Close()
+	}
+	n.mu.Unlock()
+	return n.storage.Close()
+}
+
+// run processes Raft ticks and ready states until the node
+// is stopped.
+func (n *Node) run() {
+	defer close(n.done)
+
+	ticker := time.NewTicker(n.config.TickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.stop:
+			n.raft.Stop()
+			return
+		case <-ticker.C:
+			n.raft.Tick()
+		case rd := <-n.raft.Ready():
+			if err := n.ready(rd); err != nil {
+				// The node must not continue if it cannot persist
+				// its state. Otherwise, it may violate promises,
+				// like votes, made to other members.
+				n.log.Error(err.Error())
+				n.raft.Stop()
+				return
+			}
+			n.raft.Advance()
+		}
+	}
+}
+
+// ready persists, sends and applies the ready state.
+func (n *Node) ready(rd raft.Ready) error {
+	if !raft.IsEmptySnap(rd.Snapshot) {
+		if err := n.storage.SaveSnapshot(rd.Snapshot, nil); err != nil {
+			return err
+		}
+		if err := n.memory.ApplySnapshot(rd.Snapshot); err != nil {
+			return fmt.Errorf("cluster: failed to apply snapshot: %v", err)
+		}
+		if err := n.restore(rd.Snapshot); err != nil {
+			return err
+		}
+	}
+	if err := n.storage.Save(rd.HardState, rd.Entries, rd.MustSync); err != nil {
+		return err
+	}
+	if !raft.IsEmptyHardState(rd.HardState) {
+		n.memory.SetHardState(rd.HardState)
+	}
+	if err := n.memory.Append(rd.Entries); err != nil {
+		return fmt.Errorf("cluster: failed to append log entries: %v", err)
+	}
+
+	n.send(rd.Messages)
+	for i := range rd.CommittedEntries {
+		n.apply(&rd.CommittedEntries[i])
+	}
+	if len(rd.CommittedEntries) > 0 {
+		n.mu.Lock()
+		close(n.appliedCh)
+		n.appliedCh = make(chan struct{})
+		n.mu.Unlock()
+	}
+	return n.maybeSnapshot()
+}
+
+// apply applies a committed log entry.
+func (n *Node) apply(e *pb.Entry) {
+	defer func() {
+		n.mu.Lock()
+		n.applied = e.Index
+		n.mu.Unlock()
+	}()
+
+	switch e.Type {
+	case pb.EntryNormal:
+		if len(e.Data) < 8 { // Empty entries are appended by new leaders
+			return
+		}
+		err := n.config.StateMachine.Apply(e.Data[8:])
+
+		n.mu.Lock()
+		waiter, ok := n.waiters[binary.BigEndian.Uint64(e.Data)]
+		n.mu.Unlock()
+		if ok {
+			waiter <- err
+		}
+	case pb.EntryConfChange:
+		var cc pb.ConfChange
+		if err := cc.Unmarshal(e.Data); err != nil {
+			n.log.Error(fmt.Sprintf("cluster: invalid membership change: %v", err))
+			return
+		}
+		confState := n.raft.ApplyConfChange(cc)
+
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		n.confState = *confState
+		switch cc.Type {
+		case pb.ConfChangeAddNode:
+			n.members[cc.NodeID] = string(cc.Context)
+		case pb.ConfChangeRemoveNode:
+			delete(n.members, cc.NodeID)
+			if p, ok := n.peers[cc.NodeID]; ok {
+				p.Close()
+				delete(n.peers, cc.NodeID)
+			}
+			if cc.NodeID == n.id {
+				n.removed = true
+				n.log.Warn("cluster: this server has been removed from the cluster")
+			}
+		}
+	}
+}
+
+// snapshotData is the content of a snapshot. It contains
+// the cluster members and the state machine snapshot.
+type snapshotData struct {
+	Members map[uint64]string `json:"members"`
+	State   []byte            `json:"state"`
+}
+
+// maybeSnapshot takes a snapshot and compacts the log if
+// enough entries have been applied since the last snapshot.
+func (n *Node) maybeSnapshot() error {
+	n.mu.Lock()
+	applied, last := n.applied, n.snapshot
+	members, confState := maps.Clone(n.members), n.confState
+	n.mu.Unlock()
+
+	if applied-last < n.config.SnapshotCount {
+		return nil
+	}
+
+	state, err := n.config.StateMachine.Snapshot()
+	if err != nil {
+		return fmt.Errorf("cluster: failed to create snapshot: %v", err)
+	}
+	data, err := json.Marshal(snapshotData{Members: members, State: state})
+	if err != nil {
+		return fmt.Errorf("cluster: failed to create snapshot: %v", err)
+	}
+	snap, err := n.memory.CreateSnapshot(applied, &confState, data)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to create snapshot: %v", err)
+	}
+	if err = n.memory.Compact(applied); err != nil {
+		return fmt.Errorf("cluster: failed to compact log: %v", err)
+	}
+
+	first, _ := n.memory.FirstIndex()
+	lastIndex, _ := n.memory.LastIndex()
+	var entries []pb.Entry
+	if lastIndex >= first {
+		if entries, err = n.memory.Entries(first, lastIndex+1, 1<<62); err != nil {
+			return fmt.Errorf("cluster: failed to compact log: %v", err)
+		}
+	}
+	if err = n.storage.SaveSnapshot(snap, entries); err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	n.snapshot = applied
+	n.mu.Unlock()
+	return nil
+}
+
+// restore restores the members and state machine from the
+// snapshot.
+func (n *Node) restore(snap pb.Snapshot) error {
+	var data snapshotData
+	if err := json.Unmarshal(snap.Data, &data); err != nil {
+		return fmt.Errorf("cluster: invalid snapshot: %v", err)
+	}
+	if err := n.config.StateMachine.Restore(data.State); err != nil {
+		return fmt.Errorf("cluster: failed to restore snapshot: %v", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.members = data.Members
+	if n.members == nil {
+		n.members = map[uint64]string{}
+	}
+	n.confState = snap.Metadata.ConfState
+	n.applied = snap.Metadata.Index
+	n.snapshot = snap.Metadata.Index
+	return nil
+}
+
+// send sends the messages to the respective members.
+func (n *Node) send(msgs []pb.Message) {
+	for _, msg := range msgs {
+		n.mu.Lock()
+		p, ok := n.peers[msg.To]
+		if !ok {
+			if addr, ok := n.members[msg.To]; ok {
+				p = newPeer(n, msg.To, addr)
+				n.peers[msg.To] = p
+			}
+		}
+		n.mu.Unlock()
+
+		if p == nil {
+			n.raft.ReportUnreachable(msg.To)
+			continue
+		}
+		p.Send(msg)
+	}
+}
+
+func (n *Node) appliedIndex() uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.applied
+}
+
+func (n *Node) hasMember(id uint64) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, ok := n.members[id]
+	return ok
+}
+
+// waitApplied waits until the node has applied all log entries
+// up to the index.
+func (n *Node) waitApplied(ctx context.Context, index uint64) error {
+	for {
+		n.mu.Lock()
+		applied, ch := n.applied, n.appliedCh
+		n.mu.Unlock()
+		if applied >= index {
+			return nil
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return context.Cause(ctx)
+		case <-n.done:
+			return errors.New("cluster: node stopped")
+		}
+	}
+}
+
+// waitMember waits until the member has been added or removed.
+func (n *Node) waitMember(ctx context.Context, id uint64, exists bool) error {
+	for {
+		n.mu.Lock()
+		_, ok := n.members[id]
+		ch := n.appliedCh
+		n.mu.Unlock()
+		if ok == exists {
+			return nil
+		}
+
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return fmt.Errorf("cluster: membership change has not been applied: %w", context.Cause(ctx))
+		case <-n.done:
+			return errors.New("cluster: node stopped")
+		}
+	}
+}
+
+// raftLogger is a raft.Logger that logs warnings and errors
+// to an slog.Logger and discards other messages.
+type raftLogger struct {
+	log *slog.Logger
+}
+
+func (*raftLogger) Debug(...any)          {}
+func (*raftLogger) Debugf(string, ...any) {}
+func (*raftLogger) Info(...any)           {}
+func (*raftLogger) Infof(string, ...any)  {}
+
+func (l *raftLogger) Warning(v ...any) { l.log.Warn("cluster: " + fmt.Sprint(v...)) }
+func (l *raftLogger) Warningf(format string, v ...any) {
+	l.log.Warn("cluster: " + fmt.Sprintf(format, v...))
+}
+func (l *raftLogger) Error(v ...any) { l.log.Error("cluster: " + fmt.Sprint(v...)) }
+func (l *raftLogger) Errorf(format string, v ...any) {
+	l.log.Error("cluster: " + fmt.Sprintf(format, v...))
+}
+func (l *raftLogger) Fatal(v ...any) { l.Panic(v...) }
+func (l *raftLogger) Fatalf(format string, v ...any) {
+	l.Panicf(format, v...)
+}
+func (l *raftLogger) Panic(v ...any) { panic("cluster: " + fmt.Sprint(v...)) }
+func (l *raftLogger) Panicf(format string, v ...any) {
+	panic("cluster: " + fmt.Sprintf(format, v...))
+}
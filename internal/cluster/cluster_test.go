@@ -0,0 +1,280 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+func TestDiskStorage(t *testing.T) {
+	dir := t.TempDir()
+	storage, state, err := openDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to open storage: %v", err)
+	}
+	if !state.Empty() {
+		t.Fatal("New storage is not empty")
+	}
+
+	entries := []pb.Entry{
+		{Term: 1, Index: 1, Data: []byte("a")},
+		{Term: 1, Index: 2, Data: []byte("b")},
+		{Term: 1, Index: 3, Data: []byte("c")},
+	}
+	if err = storage.Save(pb.HardState{Term: 1, Vote: 1, Commit: 3}, entries, true); err != nil {
+		t.Fatalf("Failed to save entries: %v", err)
+	}
+	snap := pb.Snapshot{Metadata: pb.SnapshotMetadata{Index: 2, Term: 1}, Data: []byte("{}")}
+	if err = storage.SaveSnapshot(snap, entries[2:]); err != nil {
+		t.Fatalf("Failed to save snapshot: %v", err)
+	}
+	if err = storage.Save(pb.HardState{}, []pb.Entry{{Term: 2, Index: 4, Data: []byte("d")}}, true); err != nil {
+		t.Fatalf("Failed to save entries: %v", err)
+	}
+	storage.Close()
+
+	// Simulate a partially written entry
+	file, err := os.OpenFile(filepath.Join(dir, entriesFile), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte{0, 0, 1})
+	file.Close()
+
+	storage, state, err = openDiskStorage(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen storage: %v", err)
+	}
+	defer storage.Close()
+
+	if state.Snapshot.Metadata.Index != 2 {
+		t.Fatalf("Invalid snapshot index: got '%d' - want '%d'", state.Snapshot.Metadata.Index, 2)
+	}
+	if state.HardState.Commit != 3 {
+		t.Fatalf("Invalid commit index: got '%d' - want '%d'", state.HardState.Commit, 3)
+	}
+	if len(state.Entries) != 2 || state.Entries[0].Index != 3 || state.Entries[1].Index != 4 {
+		t.Fatalf("Invalid entries: %v", state.Entries)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping cluster test in short mode")
+	}
+	const (
+		Path    = "/v1/cluster/raft"
+		Members = 3
+	)
+
+	var (
+		nodes   [Members + 1]atomic.Pointer[Node]
+		servers [Members + 1]*httptest.Server
+		addrs   []string
+	)
+	for i := range servers {
+		i := i
+		servers[i] = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			node := nodes[i].Load()
+			if node == nil {
+				http.Error(w, "not started", http.StatusServiceUnavailable)
+				return
+			}
+			b, err := io.ReadAll(r.Body)
+			if err == nil {
+				err = node.Step(r.Context(), b)
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			}
+		}))
+		defer servers[i].Close()
+		addrs = append(addrs, servers[i].URL)
+	}
+
+	start := func(i int, join bool) *testStateMachine {
+		sm := &testStateMachine{}
+		node, err := Start(&Config{
+			Addr:         addrs[i],
+			Dir:          t.TempDir(),
+			Peers:        addrs[:Members],
+			Join:         join,
+			Path:         Path,
+			Client:       servers[i].Client(),
+			StateMachine: sm,
+			TickInterval: 10 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Failed to start member %d: %v", i, err)
+		}
+		nodes[i].Store(node)
+		t.Cleanup(func() { node.Close() })
+		return sm
+	}
+
+	var machines []*testStateMachine
+	for i := 0; i < Members; i++ {
+		machines = append(machines, start(i, false))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Proposals may get lost, e.g. while a leader is elected.
+	// Hence, proposing a command is retried after a timeout.
+	propose := func(node *Node, cmd string) {
+		for {
+			pctx, pcancel := context.WithTimeout(ctx, time.Second)
+			err := node.Propose(pctx, []byte(cmd))
+			pcancel()
+			if err == nil {
+				return
+			}
+			if ctx.Err() != nil {
+				t.Fatalf("Failed to propose '%s': %v", cmd, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	propose(nodes[0].Load(), "a")
+	propose(nodes[1].Load(), "b")
+	if err := nodes[2].Load().Propose(ctx, []byte("fail")); err == nil {
+		t.Fatal("Applying command should have failed")
+	}
+
+	if _, err := nodes[0].Load().AddMember(ctx, addrs[Members]); err != nil {
+		t.Fatalf("Failed to add member: %v", err)
+	}
+	machines = append(machines, start(Members, true))
+	propose(nodes[Members].Load(), "c")
+
+	want := []string{"a", "b", "c"}
+	for i, sm := range machines {
+		for !slices.Equal(sm.Commands(), want) {
+			if ctx.Err() != nil {
+				t.Fatalf("Member %d: got commands '%v' - want '%v'", i, sm.Commands(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if members := nodes[0].Load().Members(); len(members) != Members+1 {
+		t.Fatalf("Invalid number of members: got '%d' - want '%d'", len(members), Members+1)
+	}
+
+	if err := nodes[0].Load().RemoveMember(ctx, MemberID(addrs[Members])); err != nil {
+		t.Fatalf("Failed to remove member: %v", err)
+	}
+	if members := nodes[0].Load().Members(); len(members) != Members {
+		t.Fatalf("Invalid number of members: got '%d' - want '%d'", len(members), Members)
+	}
+}
+
+func TestClusterRestart(t *testing.T) {
+	const Addr = "https://127.0.0.1:7373"
+
+	dir := t.TempDir()
+	start := func() (*Node, *testStateMachine) {
+		sm := &testStateMachine{}
+		node, err := Start(&Config{
+			Addr:          Addr,
+			Dir:           dir,
+			Peers:         []string{Addr},
+			StateMachine:  sm,
+			TickInterval:  10 * time.Millisecond,
+			SnapshotCount: 2,
+		})
+		if err != nil {
+			t.Fatalf("Failed to start member: %v", err)
+		}
+		return node, sm
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	node, sm := start()
+	want := []string{"a", "b", "c", "d", "e"}
+	for _, cmd := range want {
+		for {
+			err := node.Propose(ctx, []byte(cmd))
+			if err == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				t.Fatalf("Failed to propose '%s': %v", cmd, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if !slices.Equal(sm.Commands(), want) {
+		t.Fatalf("Got commands '%v' - want '%v'", sm.Commands(), want)
+	}
+	if err := node.Close(); err != nil {
+		t.Fatalf("Failed to stop member: %v", err)
+	}
+
+	node, sm = start()
+	defer node.Close()
+	if !slices.Equal(sm.Commands(), want) {
+		t.Fatalf("Got commands '%v' after restart - want '%v'", sm.Commands(), want)
+	}
+	if members := node.Members(); len(members) != 1 || members[0].Addr != Addr {
+		t.Fatalf("Invalid members after restart: %v", members)
+	}
+}
+
+// testStateMachine is a StateMachine that records all commands.
+// The command "fail" is rejected.
+type testStateMachine struct {
+	mu       sync.Mutex
+	commands []string
+}
+
+func (s *testStateMachine) Apply(cmd []byte) error {
+	if string(cmd) == "fail" {
+		return errors.New("invalid command")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = append(s.commands, string(cmd))
+	return nil
+}
+
+func (s *testStateMachine) Snapshot() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return json.Marshal(s.commands)
+}
+
+func (s *testStateMachine) Restore(snapshot []byte) error {
+	var commands []string
+	if err := json.Unmarshal(snapshot, &commands); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands = commands
+	return nil
+}
+
+func (s *testStateMachine) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.commands)
+}
@@ -0,0 +1,273 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cluster
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.etcd.io/etcd/raft/v3"
+	pb "go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+// Names of the files within the raft state directory.
+const (
+	snapshotFile  = "snapshot"
+	hardStateFile = "hardstate"
+	entriesFile   = "entries"
+)
+
+// diskStorage persists the raft state within a directory.
+//
+// The latest snapshot and hard state are stored in separate
+// files that are replaced atomically. Log entries are appended
+// to an entries file as length-prefixed and checksummed frames.
+// An entry may be followed by entries with the same or a lower
+// index that replace the previous entry and all entries after
+// it, just like raft.MemoryStorage.Append.
+type diskStorage struct {
+	dir     string
+	entries *os.File
+}
+
+// diskState is the raft state loaded from disk.
+type diskState struct {
+	Snapshot  pb.Snapshot
+	HardState pb.HardState
+	Entries   []pb.Entry
+}
+
+// Empty reports whether no raft state has been persisted.
+func (s *diskState) Empty() bool {
+	return raft.IsEmptySnap(s.Snapshot) && raft.IsEmptyHardState(s.HardState) && len(s.Entries) == 0
+}
+
+// Initialized reports whether the directory contains the
+// persisted Raft state of a cluster member.
+func Initialized(dir string) bool {
+	for _, name := range []string{snapshotFile, hardStateFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// openDiskStorage opens the raft state directory and loads the
+// persisted state. It creates the directory if it doesn't exist.
+func openDiskStorage(dir string) (*diskStorage, *diskState, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("cluster: failed to create state directory: %v", err)
+	}
+
+	var state diskState
+	if b, err := os.ReadFile(filepath.Join(dir, snapshotFile)); err == nil {
+		if err = state.Snapshot.Unmarshal(b); err != nil {
+			return nil, nil, fmt.Errorf("cluster: invalid snapshot: %v", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("cluster: failed to read snapshot: %v", err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, hardStateFile)); err == nil {
+		if err = state.HardState.Unmarshal(b); err != nil {
+			return nil, nil, fmt.Errorf("cluster: invalid hard state: %v", err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, nil, fmt.Errorf("cluster: failed to read hard state: %v", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, entriesFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cluster: failed to open log entries: %v", err)
+	}
+	entries, size, err := readEntries(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	// Discard a partially written frame, e.g. due to a crash,
+	// such that new entries are appended after the last valid one.
+	if err = file.Truncate(size); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("cluster: failed to open log entries: %v", err)
+	}
+	if _, err = file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("cluster: failed to open log entries: %v", err)
+	}
+	for _, e := range entries {
+		if e.Index > state.Snapshot.Metadata.Index {
+			state.Entries = append(state.Entries, e)
+		}
+	}
+	return &diskStorage{dir: dir, entries: file}, &state, nil
+}
+
+// Save persists the hard state, if not empty, and appends the
+// entries. If sync is true, it flushes all changes to disk.
+func (s *diskStorage) Save(hs pb.HardState, entries []pb.Entry, sync bool) error {
+	if len(entries) > 0 {
+		w := bufio.NewWriter(s.entries)
+		for i := range entries {
+			if err := writeEntry(w, &entries[i]); err != nil {
+				return err
+			}
+		}
+		if err := w.Flush(); err != nil {
+			return fmt.Errorf("cluster: failed to write log entries: %v", err)
+		}
+		if sync {
+			if err := s.entries.Sync(); err != nil {
+				return fmt.Errorf("cluster: failed to write log entries: %v", err)
+			}
+		}
+	}
+	if !raft.IsEmptyHardState(hs) {
+		b, err := hs.Marshal()
+		if err != nil {
+			return err
+		}
+		if err = writeFile(filepath.Join(s.dir, hardStateFile), b, sync); err != nil {
+			return fmt.Errorf("cluster: failed to write hard state: %v", err)
+		}
+	}
+	return nil
+}
+
+// SaveSnapshot persists the snapshot and replaces the log
+// entries by the given entries following the snapshot.
+func (s *diskStorage) SaveSnapshot(snap pb.Snapshot, entries []pb.Entry) error {
+	b, err := snap.Marshal()
+	if err != nil {
+		return err
+	}
+	if err = writeFile(filepath.Join(s.dir, snapshotFile), b, true); err != nil {
+		return fmt.Errorf("cluster: failed to write snapshot: %v", err)
+	}
+
+	filename := filepath.Join(s.dir, entriesFile)
+	tmp, err := os.OpenFile(filename+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to compact log entries: %v", err)
+	}
+	w := bufio.NewWriter(tmp)
+	for i := range entries {
+		if err = writeEntry(w, &entries[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(filename+".tmp", filename)
+	}
+	if err != nil {
+		os.Remove(filename + ".tmp")
+		return fmt.Errorf("cluster: failed to compact log entries: %v", err)
+	}
+
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("cluster: failed to open log entries: %v", err)
+	}
+	s.entries.Close()
+	s.entries = file
+	return nil
+}
+
+// Close closes the entries file.
+func (s *diskStorage) Close() error { return s.entries.Close() }
+
+// writeEntry writes the entry as frame consisting of the
+// entry's length, its CRC32-C checksum and the entry.
+func writeEntry(w io.Writer, e *pb.Entry) error {
+	b, err := e.Marshal()
+	if err != nil {
+		return err
+	}
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(b)))
+	binary.BigEndian.PutUint32(header[4:], crc32.Checksum(b, crcTable))
+	if _, err = w.Write(header[:]); err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// readEntries reads all valid entry frames from r. It returns
+// the entries and the size of all valid frames. Reading stops
+// at the first incomplete or corrupted frame.
+func readEntries(r io.Reader) ([]pb.Entry, int64, error) {
+	var (
+		entries []pb.Entry
+		size    int64
+	)
+	br := bufio.NewReader(r)
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(br, header[:]); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, size, nil
+			}
+			return nil, 0, fmt.Errorf("cluster: failed to read log entries: %v", err)
+		}
+		b := make([]byte, binary.BigEndian.Uint32(header[:]))
+		if _, err := io.ReadFull(br, b); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return entries, size, nil
+			}
+			return nil, 0, fmt.Errorf("cluster: failed to read log entries: %v", err)
+		}
+		if crc32.Checksum(b, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+			return entries, size, nil
+		}
+
+		var e pb.Entry
+		if err := e.Unmarshal(b); err != nil {
+			return entries, size, nil
+		}
+		entries = append(entries, e)
+		size += int64(len(header) + len(b))
+	}
+}
+
+// writeFile replaces the file atomically by writing the data
+// to a temporary file and renaming it.
+func writeFile(filename string, data []byte, sync bool) error {
+	tmp, err := os.OpenFile(filename+".tmp", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if err == nil && sync {
+		err = tmp.Sync()
+	}
+	if cErr := tmp.Close(); err == nil {
+		err = cErr
+	}
+	if err == nil {
+		err = os.Rename(filename+".tmp", filename)
+	}
+	if err != nil {
+		os.Remove(filename + ".tmp")
+	}
+	return err
+}
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
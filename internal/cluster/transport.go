@@ -0,0 +1,120 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.etcd.io/etcd/raft/v3"
+	pb "go.etcd.io/etcd/raft/v3/raftpb"
+)
+
+// peer sends Raft messages to another member.
+//
+// Messages are queued and sent in order by a separate goroutine
+// such that a slow or unreachable member does not block the node.
+// Messages are dropped if the queue is full. Raft retransmits
+// lost messages.
+type peer struct {
+	node *Node
+	id   uint64
+	url  string
+
+	queue chan pb.Message
+	stop  chan struct{}
+}
+
+func newPeer(n *Node, id uint64, addr string) *peer {
+	const QueueSize = 4096
+
+	p := &peer{
+		node:  n,
+		id:    id,
+		url:   strings.TrimSuffix(addr, "/") + n.config.Path,
+		queue: make(chan pb.Message, QueueSize),
+		stop:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Send queues the message.
+func (p *peer) Send(msg pb.Message) {
+	select {
+	case p.queue <- msg:
+	default:
+		p.report(msg, false)
+	}
+}
+
+// Close stops sending messages.
+func (p *peer) Close() { close(p.stop) }
+
+func (p *peer) run() {
+	for {
+		select {
+		case <-p.stop:
+			return
+		case msg := <-p.queue:
+			p.report(msg, p.send(msg) == nil)
+		}
+	}
+}
+
+func (p *peer) send(msg pb.Message) error {
+	const Timeout = 10 * time.Second
+
+	b, err := msg.Marshal()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-p.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	resp, err := p.node.config.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("cluster: member '%x' responded with %s", p.id, resp.Status)
+	}
+	return nil
+}
+
+// report informs the node about undelivered messages
+// and the status of snapshots sent to the member.
+func (p *peer) report(msg pb.Message, ok bool) {
+	if !ok {
+		p.node.raft.ReportUnreachable(p.id)
+	}
+	if msg.Type == pb.MsgSnap {
+		status := raft.SnapshotFinish
+		if !ok {
+			status = raft.SnapshotFailure
+		}
+		p.node.raft.ReportSnapshot(p.id, status)
+	}
+}
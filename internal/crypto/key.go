@@ -7,11 +7,13 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/ecdh"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,11 +22,13 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/minio/kes/internal/cpu"
 	"github.com/minio/kes/internal/fips"
 	pb "github.com/minio/kes/internal/protobuf"
 	"github.com/minio/kms-go/kes"
 	"golang.org/x/crypto/chacha20"
 	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
 )
 
 // SecretKeySize is the size of a secret key in bytes.
@@ -88,6 +92,105 @@ func (h Hash) String() string {
 	}
 }
 
+// KeyUsage is a set of cryptographic operations a key may be
+// used for. The zero KeyUsage permits all operations.
+type KeyUsage uint
+
+// Supported key usages.
+const (
+	// UsageEncrypt permits encrypting plaintexts and generating
+	// data encryption keys.
+	UsageEncrypt KeyUsage = 1 << iota
+
+	// UsageDecrypt permits decrypting ciphertexts.
+	UsageDecrypt
+
+	// UsageSSH permits signing SSH certificates. Unlike other
+	// usages, it must be set explicitly. A key with zero usage
+	// cannot act as SSH certificate authority.
+	UsageSSH
+
+	// UsageCert permits issuing X.509 certificates. Like UsageSSH,
+	// it must be set explicitly.
+	UsageCert
+
+	// UsageHMAC permits computing HMACs of messages.
+	UsageHMAC
+
+	// UsageSign permits signing messages.
+	UsageSign
+
+	// UsageVerify permits verifying message signatures.
+	UsageVerify
+
+	// UsageECDH permits ECDH key agreements.
+	UsageECDH
+)
+
+// ParseKeyUsage parses s as list of KeyUsage string representations
+// and returns an error if any element of s is not a valid
+// representation.
+func ParseKeyUsage(s []string) (KeyUsage, error) {
+	var usage KeyUsage
+	for _, v := range s {
+		switch v {
+		case "encrypt":
+			usage |= UsageEncrypt
+		case "decrypt":
+			usage |= UsageDecrypt
+		case "ssh":
+			usage |= UsageSSH
+		case "cert":
+			usage |= UsageCert
+		case "hmac":
+			usage |= UsageHMAC
+		case "sign":
+			usage |= UsageSign
+		case "verify":
+			usage |= UsageVerify
+		case "ecdh":
+			usage |= UsageECDH
+		default:
+			return 0, fmt.Errorf("crypto: key usage '%s' is not supported", v)
+		}
+	}
+	return usage, nil
+}
+
+// Permits reports whether u permits all operations of op.
+func (u KeyUsage) Permits(op KeyUsage) bool { return u == 0 || u&op == op }
+
+// Strings returns the string representations of all operations
+// of u. It returns nil if u is zero.
+func (u KeyUsage) Strings() []string {
+	var s []string
+	if u&UsageEncrypt != 0 {
+		s = append(s, "encrypt")
+	}
+	if u&UsageDecrypt != 0 {
+		s = append(s, "decrypt")
+	}
+	if u&UsageSSH != 0 {
+		s = append(s, "ssh")
+	}
+	if u&UsageCert != 0 {
+		s = append(s, "cert")
+	}
+	if u&UsageHMAC != 0 {
+		s = append(s, "hmac")
+	}
+	if u&UsageSign != 0 {
+		s = append(s, "sign")
+	}
+	if u&UsageVerify != 0 {
+		s = append(s, "verify")
+	}
+	if u&UsageECDH != 0 {
+		s = append(s, "ecdh")
+	}
+	return s
+}
+
 // EncodeKeyVersion base64-encoded binary representation of a key.
 //
 // It encodes the key's binary data as base64 since some KMS keystore
@@ -152,11 +255,166 @@ func ParseKeyVersion(b []byte) (KeyVersion, error) {
 }
 
 // KeyVersion represents a version of a secret key.
+//
+// A key may have been rotated. Then, the KeyVersion is the
+// latest version of the key and Previous contains all older
+// versions. Older versions are only used to decrypt ciphertexts
+// produced before the key has been rotated.
 type KeyVersion struct {
 	Key       SecretKey    // The secret key
 	HMACKey   HMACKey      // The HMAC key
 	CreatedAt time.Time    // The creation timestamp of the key version
 	CreatedBy kes.Identity // The identity of the entity that created the key version
+
+	Tags map[string]string // Optional tags attached to the key version
+
+	Usage     KeyUsage  // The operations the key may be used for. Zero if not restricted
+	ExpiresAt time.Time // The point in time after which the key must only be used to decrypt. Zero if it never expires
+
+	RotationInterval time.Duration // The interval at which the key is rotated automatically. Zero if not rotated automatically
+
+	Derived bool // Whether data keys are derived deterministically from the context. See DeriveKey
+
+	ID string // Random, non-secret ID of the key. The same for all versions. Empty for keys created before keys had IDs
+
+	Version  uint32       // The version number. Keys that have never been rotated may have version 0
+	Previous []KeyVersion // Previous versions of the key, oldest first
+
+	DeletedAt time.Time    // The point in time the key has been marked for deletion. Zero if not deleted
+	DeletedBy kes.Identity // The identity of the entity that marked the key for deletion
+}
+
+// versionMagic marks ciphertexts produced by a key version greater
+// than 1. Such ciphertexts end with the version number, as 4 byte
+// big endian integer, followed by versionMagic. Ciphertexts produced
+// by the first key version have no version suffix to remain compatible
+// with ciphertexts produced before keys could be rotated.
+const versionMagic = "KESv"
+
+// Number returns the version number of the key. Keys that
+// have never been rotated have the version number 1.
+func (s *KeyVersion) Number() uint32 { return max(s.Version, 1) }
+
+// Versions returns all versions of the key, oldest first.
+// The last version is s without any previous versions.
+func (s *KeyVersion) Versions() []KeyVersion {
+	latest := *s
+	latest.Previous = nil
+	return append(slices.Clone(s.Previous), latest)
+}
+
+// Rotate returns a new version of the key with the given secret key.
+// s becomes the previous version of the new version. The new version
+// keeps the ID, HMAC key, tags, usage constraints, rotation interval and
+// whether data keys are derived of s such that HMACs remain stable.
+// Its asymmetric keys differ from the ones of s. See AsymmetricKey.
+func (s *KeyVersion) Rotate(key SecretKey, createdAt time.Time, createdBy kes.Identity) KeyVersion {
+	return KeyVersion{
+		Key:       key,
+		HMACKey:   s.HMACKey,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy,
+		Tags:      s.Tags,
+		Usage:     s.Usage,
+		ExpiresAt: s.ExpiresAt,
+		Version:   s.Number() + 1,
+		Previous:  s.Versions(),
+
+		RotationInterval: s.RotationInterval,
+		Derived:          s.Derived,
+		ID:               s.ID,
+	}
+}
+
+// NextRotation returns the point in time the key is due to
+// be rotated automatically. It returns the zero time if the
+// key is not rotated automatically.
+func (s *KeyVersion) NextRotation() time.Time {
+	if s.RotationInterval <= 0 {
+		return time.Time{}
+	}
+	return s.CreatedAt.Add(s.RotationInterval)
+}
+
+// Encrypt encrypts and authenticates the plaintext with the
+// latest key version and authenticates the associatedData.
+// The ciphertext contains the key version number, if greater
+// than 1, such that Decrypt can select the right key version.
+func (s *KeyVersion) Encrypt(plaintext, associatedData []byte) ([]byte, error) {
+	ciphertext, err := s.Key.Encrypt(plaintext, associatedData)
+	if err != nil {
+		return nil, err
+	}
+	if s.Number() > 1 {
+		ciphertext = binary.BigEndian.AppendUint32(ciphertext, s.Number())
+		ciphertext = append(ciphertext, versionMagic...)
+	}
+	return ciphertext, nil
+}
+
+// Decrypt decrypts and authenticates the ciphertext with the
+// key version that produced it and authenticates the
+// associatedData.
+func (s *KeyVersion) Decrypt(ciphertext, associatedData []byte) ([]byte, error) {
+	const SuffixSize = 4 + len(versionMagic)
+
+	if number, ok := CiphertextVersion(ciphertext); ok {
+		n := len(ciphertext)
+		for _, v := range s.Versions() {
+			if v.Number() != number {
+				continue
+			}
+			// Decrypt a copy since the ciphertext may be a first version
+			// ciphertext that happens to end with the version magic.
+			if plaintext, err := v.Key.Decrypt(slices.Clone(ciphertext[:n-SuffixSize]), associatedData); err == nil {
+				return plaintext, nil
+			}
+			break
+		}
+	}
+
+	first := s
+	if len(s.Previous) > 0 {
+		first = &s.Previous[0]
+	}
+	return first.Key.Decrypt(ciphertext, associatedData)
+}
+
+// CiphertextVersion returns the number of the key version that
+// produced the ciphertext, if the ciphertext contains it. Ciphertexts
+// produced by the first key version contain no version number.
+//
+// A first version ciphertext may end with the version suffix by
+// chance. Hence, the version number is only a hint.
+func CiphertextVersion(ciphertext []byte) (uint32, bool) {
+	const SuffixSize = 4 + len(versionMagic)
+
+	n := len(ciphertext)
+	if n <= SuffixSize || string(ciphertext[n-len(versionMagic):]) != versionMagic {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(ciphertext[n-SuffixSize:]), true
+}
+
+// DeriveKey derives a 256 bit data key from the latest key version
+// and the context using HKDF-SHA256. The same context yields the same
+// data key until the key gets rotated. Hence, data keys derived from
+// the same context encrypt the same plaintext to the same ciphertext.
+func (s *KeyVersion) DeriveKey(context []byte) ([]byte, error) {
+	if !s.Key.initialized {
+		panic("crypto: usage of empty or uninitialized secret key")
+	}
+
+	info := make([]byte, 0, len("kes derived data key")+1+len(context))
+	info = append(info, "kes derived data key"...)
+	info = append(info, 0)
+	info = append(info, context...)
+
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, s.Key.key[:], nil, info), key); err != nil {
+		return nil, err
+	}
+	return key, nil
 }
 
 // HasHMACKey reports whether the KeyVersion has an HMAC key.
@@ -166,6 +424,46 @@ func (s *KeyVersion) HasHMACKey() bool {
 	return s.HMACKey.initialized
 }
 
+// AsymmetricKey returns the key from which the signing, key agreement
+// and certificate authority keys of the KeyVersion are derived. The
+// KeyVersion must have an HMAC key.
+//
+// The first version of a key uses its HMAC key. Later versions derive
+// the key from the HMAC key and their secret key. Hence, rotating a
+// key also rotates its asymmetric keys while its HMACs remain stable.
+// The returned key should be destroyed once it is no longer used.
+func (s *KeyVersion) AsymmetricKey() HMACKey {
+	if !s.HMACKey.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+	if s.Number() == 1 {
+		return s.HMACKey
+	}
+
+	var key [32]byte
+	info := binary.BigEndian.AppendUint32([]byte("kes asymmetric key version "), s.Number())
+	if _, err := io.ReadFull(hkdf.New(sha256.New, s.Key.key[:], s.HMACKey.key[:], info), key[:]); err != nil {
+		panic("crypto: failed to derive asymmetric key: " + err.Error())
+	}
+	return HMACKey{
+		hash:        s.HMACKey.hash,
+		key:         key,
+		initialized: true,
+	}
+}
+
+// Destroy overwrites the key material of the KeyVersion and all
+// previous versions with zeros. Using a destroyed key causes a
+// panic. Copies of the KeyVersion are not affected, except for
+// the previous versions they share with the KeyVersion.
+func (s *KeyVersion) Destroy() {
+	s.Key.Destroy()
+	s.HMACKey.Destroy()
+	for i := range s.Previous {
+		s.Previous[i].Destroy()
+	}
+}
+
 // MarshalPB converts the KeyVersion into its protobuf representation.
 func (s *KeyVersion) MarshalPB(v *pb.KeyVersion) error {
 	v.Key, v.HMACKey = &pb.SecretKey{}, &pb.HMACKey{}
@@ -178,6 +476,27 @@ func (s *KeyVersion) MarshalPB(v *pb.KeyVersion) error {
 
 	v.CreatedAt = pb.Time(s.CreatedAt)
 	v.CreatedBy = s.CreatedBy.String()
+	v.Tags = s.Tags
+	v.Usage = uint32(s.Usage)
+	if !s.ExpiresAt.IsZero() {
+		v.ExpiresAt = pb.Time(s.ExpiresAt)
+	}
+	v.RotationInterval = int64(s.RotationInterval)
+	v.Derived = s.Derived
+	v.ID = s.ID
+	v.Version = s.Version
+	v.Previous = make([]*pb.KeyVersion, 0, len(s.Previous))
+	for i := range s.Previous {
+		prev := &pb.KeyVersion{}
+		if err := s.Previous[i].MarshalPB(prev); err != nil {
+			return err
+		}
+		v.Previous = append(v.Previous, prev)
+	}
+	if !s.DeletedAt.IsZero() {
+		v.DeletedAt = pb.Time(s.DeletedAt)
+	}
+	v.DeletedBy = s.DeletedBy.String()
 	return nil
 }
 
@@ -194,10 +513,38 @@ func (s *KeyVersion) UnmarshalPB(v *pb.KeyVersion) error {
 		return err
 	}
 
+	var expiresAt, deletedAt time.Time
+	if v.ExpiresAt != nil {
+		expiresAt = v.ExpiresAt.AsTime()
+	}
+	if v.DeletedAt != nil {
+		deletedAt = v.DeletedAt.AsTime()
+	}
+
+	var previous []KeyVersion
+	if len(v.Previous) > 0 {
+		previous = make([]KeyVersion, len(v.Previous))
+		for i, prev := range v.Previous {
+			if err := previous[i].UnmarshalPB(prev); err != nil {
+				return err
+			}
+		}
+	}
+
 	s.Key = key
 	s.HMACKey = hmacKey
 	s.CreatedAt = v.CreatedAt.AsTime()
 	s.CreatedBy = kes.Identity(v.CreatedBy)
+	s.Tags = v.Tags
+	s.Usage = KeyUsage(v.Usage)
+	s.ExpiresAt = expiresAt
+	s.RotationInterval = time.Duration(v.RotationInterval)
+	s.Derived = v.Derived
+	s.ID = v.ID
+	s.Version = v.Version
+	s.Previous = previous
+	s.DeletedAt = deletedAt
+	s.DeletedBy = kes.Identity(v.DeletedBy)
 	return nil
 }
 
@@ -216,6 +563,17 @@ func NewSecretKey(cipher SecretKeyType, key []byte) (SecretKey, error) {
 	}, nil
 }
 
+// DefaultSecretKeyType returns the SecretKeyType of new keys. It is
+// AES256 in FIPS mode or if the CPU provides hardware instructions
+// for AES-GCM, like x86-64 with AES-NI or ARM64 with the ARMv8 crypto
+// extensions. Otherwise, ChaCha20 is faster and used instead.
+func DefaultSecretKeyType() SecretKeyType {
+	if fips.ApprovedOnly() || cpu.HasAESGCM() {
+		return AES256
+	}
+	return ChaCha20
+}
+
 // GenerateSecretKey generates a new random SecretKey with the specified
 // cipher.
 //
@@ -257,7 +615,7 @@ func (s SecretKey) Encrypt(plaintext, associatedData []byte) ([]byte, error) {
 	if !s.initialized {
 		panic("crypto: usage of empty or uninitialized secret key")
 	}
-	if fips.Enabled {
+	if fips.ApprovedOnly() {
 		if s.cipher != AES256 {
 			return nil, errors.New("crypto: cipher not available in FIPS mode")
 		}
@@ -313,7 +671,7 @@ func (s SecretKey) Decrypt(ciphertext, associatedData []byte) ([]byte, error) {
 	if !s.initialized {
 		panic("crypto: usage of empty or uninitialized secret key detected")
 	}
-	if fips.Enabled {
+	if fips.ApprovedOnly() {
 		if s.cipher != AES256 {
 			return nil, errors.New("crypto: cipher not available in FIPS mode")
 		}
@@ -362,6 +720,12 @@ func (s SecretKey) Decrypt(ciphertext, associatedData []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
+// Destroy overwrites the SecretKey with zeros.
+func (s *SecretKey) Destroy() {
+	clear(s.key[:])
+	s.initialized = false
+}
+
 // MarshalPB converts the SecretKey into its protobuf representation.
 func (s *SecretKey) MarshalPB(v *pb.SecretKey) error {
 	if !s.initialized {
@@ -454,6 +818,68 @@ func (k *HMACKey) Equal(mac1, mac2 []byte) bool {
 	return subtle.ConstantTimeCompare(mac1, mac2) == 1
 }
 
+// ECDH returns the P-256 public key of the key agreement key
+// derived from k. If peerPublicKey is not empty, ECDH also
+// performs an ECDH key agreement with the peer's uncompressed
+// P-256 public key and derives a 256-bit key from the shared
+// secret and info using HKDF-SHA256.
+//
+// The key agreement key is derived deterministically from k.
+// Hence, its public key remains the same as long as k does.
+func (k *HMACKey) ECDH(peerPublicKey, info []byte) (publicKey, key []byte, err error) {
+	if !k.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+
+	privateKey, err := k.deriveP256Key("kes ECDH P-256 private key")
+	if err != nil {
+		return nil, nil, err
+	}
+	publicKey = privateKey.PublicKey().Bytes()
+	if len(peerPublicKey) == 0 {
+		return publicKey, nil, nil
+	}
+
+	peerKey, err := ecdh.P256().NewPublicKey(peerPublicKey)
+	if err != nil {
+		return nil, nil, errors.New("crypto: invalid P-256 public key")
+	}
+	secret, err := privateKey.ECDH(peerKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer clear(secret)
+
+	key = make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, nil, info), key); err != nil {
+		return nil, nil, err
+	}
+	return publicKey, key, nil
+}
+
+// deriveP256Key derives a P-256 private key from k. Keys
+// derived with different labels are independent.
+func (k *HMACKey) deriveP256Key(label string) (*ecdh.PrivateKey, error) {
+	// A random 32 byte string is a valid P-256 private key with
+	// overwhelming probability. Otherwise, read the next 32 bytes.
+	kdf := hkdf.New(sha256.New, k.key[:], nil, []byte(label))
+	for {
+		var scalar [32]byte
+		if _, err := io.ReadFull(kdf, scalar[:]); err != nil {
+			return nil, err
+		}
+		if privateKey, err := ecdh.P256().NewPrivateKey(scalar[:]); err == nil {
+			return privateKey, nil
+		}
+	}
+}
+
+// Destroy overwrites the HMACKey with zeros.
+func (k *HMACKey) Destroy() {
+	clear(k.key[:])
+	k.initialized = false
+}
+
 // MarshalPB converts the HMACKey into its protobuf representation.
 func (k *HMACKey) MarshalPB(v *pb.HMACKey) error {
 	if !k.initialized {
@@ -5,9 +5,18 @@
 package crypto
 
 import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"io"
+	"reflect"
+	"slices"
 	"testing"
 	"time"
+
+	"golang.org/x/crypto/hkdf"
 )
 
 func TestEncodeKeyVersion(t *testing.T) {
@@ -29,7 +38,7 @@ func TestEncodeKeyVersion(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Test %d: failed to decode encoded key: %v", i, err)
 		}
-		if key != test.Key {
+		if !reflect.DeepEqual(key, test.Key) {
 			t.Fatalf("Test %d: got '%+v' - want '%+v'", i, key, test.Key)
 		}
 	}
@@ -77,6 +86,158 @@ func TestSecretKeyDecrypt(t *testing.T) {
 	}
 }
 
+func TestKeyVersionRotate(t *testing.T) {
+	t.Parallel()
+
+	const Plaintext = "Hello World"
+	associatedData := []byte("my-context")
+
+	first, err := GenerateSecretKey(AES256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	hmacKey, err := GenerateHMACKey(SHA256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate HMAC key: %v", err)
+	}
+	key := KeyVersion{Key: first, HMACKey: hmacKey, CreatedAt: time.Now().UTC()}
+
+	ciphertexts := make([][]byte, 0, 3)
+	for i := 0; i < 3; i++ {
+		if i > 0 {
+			secret, err := GenerateSecretKey(AES256, rand.Reader)
+			if err != nil {
+				t.Fatalf("Failed to generate key: %v", err)
+			}
+			key = key.Rotate(secret, time.Now().UTC(), "")
+
+			// Verify that the rotated key survives encoding.
+			b, err := EncodeKeyVersion(key)
+			if err != nil {
+				t.Fatalf("Failed to encode key: %v", err)
+			}
+			if key, err = ParseKeyVersion(b); err != nil {
+				t.Fatalf("Failed to decode key: %v", err)
+			}
+		}
+		if n := key.Number(); n != uint32(i+1) {
+			t.Fatalf("Invalid key version: got '%d' - want '%d'", n, i+1)
+		}
+		if n := len(key.Versions()); n != i+1 {
+			t.Fatalf("Invalid number of key versions: got '%d' - want '%d'", n, i+1)
+		}
+
+		ciphertext, err := key.Encrypt([]byte(Plaintext), associatedData)
+		if err != nil {
+			t.Fatalf("Failed to encrypt with key version %d: %v", key.Number(), err)
+		}
+		ciphertexts = append(ciphertexts, ciphertext)
+		if n, ok := CiphertextVersion(ciphertext); ok != (i > 0) || (ok && n != key.Number()) {
+			t.Fatalf("Invalid ciphertext version: got '%d' - want '%d'", n, key.Number())
+		}
+
+		for j, ciphertext := range ciphertexts {
+			plaintext, err := key.Decrypt(slices.Clone(ciphertext), associatedData)
+			if err != nil {
+				t.Fatalf("Failed to decrypt ciphertext of version %d with version %d: %v", j+1, key.Number(), err)
+			}
+			if string(plaintext) != Plaintext {
+				t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, Plaintext)
+			}
+		}
+	}
+
+	// The first version must not be able to decrypt
+	// ciphertexts produced by newer versions.
+	if _, err = first.Decrypt(ciphertexts[2], associatedData); err == nil {
+		t.Fatal("Decrypted ciphertext of key version 3 with key version 1")
+	}
+}
+
+func TestKeyVersionDeriveKey(t *testing.T) {
+	t.Parallel()
+
+	secret, err := GenerateSecretKey(AES256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	key := KeyVersion{Key: secret, CreatedAt: time.Now().UTC(), Derived: true}
+
+	dataKey, err := key.DeriveKey([]byte("my-context"))
+	if err != nil {
+		t.Fatalf("Failed to derive data key: %v", err)
+	}
+	if other, _ := key.DeriveKey([]byte("my-context")); !bytes.Equal(dataKey, other) {
+		t.Fatal("Derived different data keys from the same context")
+	}
+	if other, _ := key.DeriveKey([]byte("my-context-2")); bytes.Equal(dataKey, other) {
+		t.Fatal("Derived the same data key from different contexts")
+	}
+
+	secret, err = GenerateSecretKey(AES256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	rotated := key.Rotate(secret, time.Now().UTC(), "")
+	if !rotated.Derived {
+		t.Fatal("Rotated key does not derive data keys")
+	}
+	if other, _ := rotated.DeriveKey([]byte("my-context")); bytes.Equal(dataKey, other) {
+		t.Fatal("Derived the same data key after key rotation")
+	}
+}
+
+func TestHMACKeyECDH(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateHMACKey(SHA256, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate HMAC key: %v", err)
+	}
+	peer, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate P-256 key: %v", err)
+	}
+
+	publicKey, derived, err := key.ECDH(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to derive public key: %v", err)
+	}
+	if derived != nil {
+		t.Fatal("Derived key without peer public key")
+	}
+
+	info := []byte("my-app")
+	pub, derived, err := key.ECDH(peer.PublicKey().Bytes(), info)
+	if err != nil {
+		t.Fatalf("Failed to perform key agreement: %v", err)
+	}
+	if !slices.Equal(pub, publicKey) {
+		t.Fatalf("Public key is not stable: got '%x' - want '%x'", pub, publicKey)
+	}
+
+	// The peer derives the same key from the public key.
+	kesKey, err := ecdh.P256().NewPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Invalid public key: %v", err)
+	}
+	secret, err := peer.ECDH(kesKey)
+	if err != nil {
+		t.Fatalf("Failed to perform key agreement: %v", err)
+	}
+	want := make([]byte, 32)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, secret, nil, info), want); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(derived, want) {
+		t.Fatalf("Derived keys do not match: got '%x' - want '%x'", derived, want)
+	}
+
+	if _, _, err = key.ECDH([]byte("invalid"), info); err == nil {
+		t.Fatal("Key agreement with invalid public key succeeded")
+	}
+}
+
 func TestParseKeyVersion(t *testing.T) {
 	for i, test := range parseKeyVersionTests {
 		key, err := ParseKeyVersion([]byte(test.Raw))
@@ -133,6 +294,53 @@ var encodeSecretKeyVersionTests = []struct {
 		},
 		ShouldFail: true,
 	},
+	{ // 3
+		Key: KeyVersion{
+			Key:       mustSecretKey(AES256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			HMACKey:   mustHMACKey(SHA256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			CreatedAt: mustTime("2024-01-12T11:39:20.886816+01:00"),
+			CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+			Tags:      map[string]string{"team": "payments", "env": "prod"},
+		},
+	},
+	{ // 4
+		Key: KeyVersion{
+			Key:       mustSecretKey(AES256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			HMACKey:   mustHMACKey(SHA256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			CreatedAt: mustTime("2024-01-12T11:39:20.886816+01:00"),
+			CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+			DeletedAt: mustTime("2024-02-01T08:00:00Z"),
+			DeletedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+		},
+	},
+	{ // 5
+		Key: KeyVersion{
+			Key:       mustSecretKey(AES256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			HMACKey:   mustHMACKey(SHA256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			CreatedAt: mustTime("2024-01-12T11:39:20.886816+01:00"),
+			CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+			Usage:     UsageEncrypt,
+			ExpiresAt: mustTime("2025-12-31T00:00:00Z"),
+		},
+	},
+	{ // 6
+		Key: KeyVersion{
+			Key:              mustSecretKey(AES256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			HMACKey:          mustHMACKey(SHA256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			CreatedAt:        mustTime("2024-01-12T11:39:20.886816+01:00"),
+			CreatedBy:        "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+			RotationInterval: 90 * 24 * time.Hour,
+		},
+	},
+	{ // 7
+		Key: KeyVersion{
+			Key:       mustSecretKey(AES256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			HMACKey:   mustHMACKey(SHA256, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="),
+			CreatedAt: mustTime("2024-01-12T11:39:20.886816+01:00"),
+			CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+			Derived:   true,
+		},
+	},
 }
 
 var secretKeyEncryptTests = []struct {
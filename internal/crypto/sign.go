@@ -0,0 +1,191 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/minio/kes/internal/fips"
+	"golang.org/x/crypto/hkdf"
+)
+
+// SignatureAlgorithm defines an asymmetric signature algorithm.
+type SignatureAlgorithm uint
+
+// Supported signature algorithms.
+const (
+	// ECDSA_P256 represents ECDSA with curve P-256 and SHA-256.
+	// Signatures are ASN.1 DER encoded.
+	ECDSA_P256 SignatureAlgorithm = iota + 1
+
+	// Ed25519 represents the Ed25519 signature algorithm.
+	Ed25519
+)
+
+// ParseSignatureAlgorithm parses s as SignatureAlgorithm string
+// representation and returns an error if s is not a valid
+// representation or the algorithm is not available.
+func ParseSignatureAlgorithm(s string) (SignatureAlgorithm, error) {
+	switch s {
+	case "ECDSA-P256", "ES256":
+		return ECDSA_P256, nil
+	case "Ed25519", "EdDSA":
+		if fips.ApprovedOnly() {
+			return 0, fmt.Errorf("crypto: signature algorithm '%s' is not supported in FIPS mode", s)
+		}
+		return Ed25519, nil
+	default:
+		return 0, fmt.Errorf("crypto: signature algorithm '%s' is not supported", s)
+	}
+}
+
+// String returns the string representation of the SignatureAlgorithm.
+func (a SignatureAlgorithm) String() string {
+	switch a {
+	case ECDSA_P256:
+		return "ECDSA-P256"
+	case Ed25519:
+		return "Ed25519"
+	default:
+		return "!INVALID:" + strconv.Itoa(int(a))
+	}
+}
+
+// Sign signs the message with the private key of the given
+// algorithm derived from k. It returns the signature and the
+// PKIX, ASN.1 DER encoded public key that verifies it.
+//
+// The signing key is derived deterministically from k. Hence,
+// its public key remains the same as long as k does.
+func (k *HMACKey) Sign(alg SignatureAlgorithm, message []byte) (signature, publicKey []byte, err error) {
+	if !k.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+
+	switch alg {
+	case ECDSA_P256:
+		privateKey, err := k.ecdsaKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		digest := sha256.Sum256(message)
+		if signature, err = ecdsa.SignASN1(rand.Reader, privateKey, digest[:]); err != nil {
+			return nil, nil, err
+		}
+		if publicKey, err = x509.MarshalPKIXPublicKey(&privateKey.PublicKey); err != nil {
+			return nil, nil, err
+		}
+		return signature, publicKey, nil
+	case Ed25519:
+		privateKey, err := k.ed25519Key()
+		if err != nil {
+			return nil, nil, err
+		}
+		if publicKey, err = x509.MarshalPKIXPublicKey(privateKey.Public()); err != nil {
+			return nil, nil, err
+		}
+		return ed25519.Sign(privateKey, message), publicKey, nil
+	default:
+		return nil, nil, errors.New("crypto: invalid signature algorithm")
+	}
+}
+
+// Verify reports whether signature is a valid signature of
+// message produced by Sign with the same algorithm.
+func (k *HMACKey) Verify(alg SignatureAlgorithm, message, signature []byte) (bool, error) {
+	if !k.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+
+	switch alg {
+	case ECDSA_P256:
+		privateKey, err := k.ecdsaKey()
+		if err != nil {
+			return false, err
+		}
+		digest := sha256.Sum256(message)
+		return ecdsa.VerifyASN1(&privateKey.PublicKey, digest[:], signature), nil
+	case Ed25519:
+		privateKey, err := k.ed25519Key()
+		if err != nil {
+			return false, err
+		}
+		return ed25519.Verify(privateKey.Public().(ed25519.PublicKey), message, signature), nil
+	default:
+		return false, errors.New("crypto: invalid signature algorithm")
+	}
+}
+
+// SSHKey returns the Ed25519 key of the SSH certificate
+// authority derived from k. It is distinct from the Ed25519
+// key used by Sign such that SSH certificates and arbitrary
+// messages are never signed with the same key.
+func (k *HMACKey) SSHKey() (ed25519.PrivateKey, error) {
+	if !k.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+	if fips.ApprovedOnly() {
+		return nil, errors.New("crypto: Ed25519 SSH keys are not supported in FIPS mode")
+	}
+
+	var seed [ed25519.SeedSize]byte
+	if _, err := io.ReadFull(hkdf.New(sha256.New, k.key[:], nil, []byte("kes SSH CA key")), seed[:]); err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed[:]), nil
+}
+
+// CertKey returns the ECDSA P-256 key of the X.509 certificate
+// authority derived from k. Like SSHKey, it is distinct from the
+// key used by Sign.
+func (k *HMACKey) CertKey() (*ecdsa.PrivateKey, error) {
+	if !k.initialized {
+		panic("crypto: usage of empty or uninitialized HMAC key detected")
+	}
+	return k.deriveECDSAKey("kes X.509 CA key")
+}
+
+// ecdsaKey derives the ECDSA P-256 signing key from k.
+func (k *HMACKey) ecdsaKey() (*ecdsa.PrivateKey, error) {
+	return k.deriveECDSAKey("kes ECDSA P-256 signing key")
+}
+
+// deriveECDSAKey derives an ECDSA P-256 key from k and the label.
+func (k *HMACKey) deriveECDSAKey(label string) (*ecdsa.PrivateKey, error) {
+	key, err := k.deriveP256Key(label)
+	if err != nil {
+		return nil, err
+	}
+
+	// The public key is the uncompressed point: 0x04 || X || Y
+	point := key.PublicKey().Bytes()
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(point[1:33]),
+			Y:     new(big.Int).SetBytes(point[33:]),
+		},
+		D: new(big.Int).SetBytes(key.Bytes()),
+	}, nil
+}
+
+// ed25519Key derives the Ed25519 signing key from k.
+func (k *HMACKey) ed25519Key() (ed25519.PrivateKey, error) {
+	var seed [ed25519.SeedSize]byte
+	if _, err := io.ReadFull(hkdf.New(sha256.New, k.key[:], nil, []byte("kes Ed25519 signing key")), seed[:]); err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed[:]), nil
+}
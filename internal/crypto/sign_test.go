@@ -0,0 +1,143 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHMACKeySign(t *testing.T) {
+	t.Parallel()
+
+	key, err := GenerateHMACKey(SHA256, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate HMAC key: %v", err)
+	}
+	message := []byte("Hello World")
+
+	for _, alg := range []SignatureAlgorithm{ECDSA_P256, Ed25519} {
+		signature, publicKey, err := key.Sign(alg, message)
+		if err != nil {
+			t.Fatalf("%v: failed to sign message: %v", alg, err)
+		}
+		if ok, err := key.Verify(alg, message, signature); err != nil || !ok {
+			t.Fatalf("%v: failed to verify signature: %v", alg, err)
+		}
+		if ok, _ := key.Verify(alg, []byte("Hello World!"), signature); ok {
+			t.Fatalf("%v: verified signature of different message", alg)
+		}
+
+		_, publicKey2, err := key.Sign(alg, message)
+		if err != nil {
+			t.Fatalf("%v: failed to sign message: %v", alg, err)
+		}
+		if !slices.Equal(publicKey, publicKey2) {
+			t.Fatalf("%v: public key is not stable", alg)
+		}
+
+		// The signature must be verifiable with the public key only.
+		pub, err := x509.ParsePKIXPublicKey(publicKey)
+		if err != nil {
+			t.Fatalf("%v: invalid public key: %v", alg, err)
+		}
+		switch pub := pub.(type) {
+		case *ecdsa.PublicKey:
+			digest := sha256.Sum256(message)
+			if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+				t.Fatalf("%v: failed to verify signature with public key", alg)
+			}
+		case ed25519.PublicKey:
+			if !ed25519.Verify(pub, message, signature) {
+				t.Fatalf("%v: failed to verify signature with public key", alg)
+			}
+		default:
+			t.Fatalf("%v: invalid public key type '%T'", alg, pub)
+		}
+	}
+}
+
+func TestKeyVersionRotateAsymmetricKey(t *testing.T) {
+	t.Parallel()
+
+	newSecretKey := func() SecretKey {
+		key, err := GenerateSecretKey(AES256, nil)
+		if err != nil {
+			t.Fatalf("Failed to generate secret key: %v", err)
+		}
+		return key
+	}
+	hmacKey, err := GenerateHMACKey(SHA256, nil)
+	if err != nil {
+		t.Fatalf("Failed to generate HMAC key: %v", err)
+	}
+	key := KeyVersion{Key: newSecretKey(), HMACKey: hmacKey}
+	rotated := key.Rotate(newSecretKey(), time.Now(), "")
+	message := []byte("Hello World")
+
+	// The first version uses its HMAC key such that keys created
+	// before keys could be rotated keep their public keys.
+	first, latest := key.AsymmetricKey(), rotated.AsymmetricKey()
+	for _, alg := range []SignatureAlgorithm{ECDSA_P256, Ed25519} {
+		_, publicKey, err := key.HMACKey.Sign(alg, message)
+		if err != nil {
+			t.Fatalf("%v: failed to sign message: %v", alg, err)
+		}
+		signature, publicKey1, err := first.Sign(alg, message)
+		if err != nil {
+			t.Fatalf("%v: failed to sign message: %v", alg, err)
+		}
+		if !slices.Equal(publicKey, publicKey1) {
+			t.Fatalf("%v: public key of the first version has changed", alg)
+		}
+
+		_, publicKey2, err := latest.Sign(alg, message)
+		if err != nil {
+			t.Fatalf("%v: failed to sign message: %v", alg, err)
+		}
+		if slices.Equal(publicKey1, publicKey2) {
+			t.Fatalf("%v: public key has not been rotated", alg)
+		}
+		if ok, _ := latest.Verify(alg, message, signature); ok {
+			t.Fatalf("%v: rotated key verified signature of previous version", alg)
+		}
+	}
+
+	publicKey1, _, err := first.ECDH(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to compute ECDH public key: %v", err)
+	}
+	publicKey2, _, err := latest.ECDH(nil, nil)
+	if err != nil {
+		t.Fatalf("Failed to compute ECDH public key: %v", err)
+	}
+	if slices.Equal(publicKey1, publicKey2) {
+		t.Fatal("ECDH public key has not been rotated")
+	}
+
+	ca1, err := first.CertKey()
+	if err != nil {
+		t.Fatalf("Failed to derive CA key: %v", err)
+	}
+	ca2, err := latest.CertKey()
+	if err != nil {
+		t.Fatalf("Failed to derive CA key: %v", err)
+	}
+	if ca1.PublicKey.Equal(&ca2.PublicKey) {
+		t.Fatal("CA key has not been rotated")
+	}
+
+	if !slices.Equal(key.HMACKey.Sum(message), rotated.HMACKey.Sum(message)) {
+		t.Fatal("HMAC has changed after rotation")
+	}
+	if next := rotated.AsymmetricKey(); next.key != latest.key {
+		t.Fatal("Asymmetric key is not deterministic")
+	}
+}
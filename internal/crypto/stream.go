@@ -0,0 +1,228 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// Stream encryption format:
+//
+//	header: magic (4) | sealed key length (2) | sealed key | nonce prefix (7)
+//	chunks: AES-256-GCM(chunk) | ... | AES-256-GCM(final chunk)
+//
+// Each chunk, except the final one, contains exactly streamChunkSize
+// bytes of plaintext. The nonce of the i-th chunk is the nonce prefix
+// followed by the 32-bit big-endian chunk counter and a final flag,
+// which is 1 for the final chunk and 0 otherwise. The header is used
+// as associated data of every chunk. Hence, chunks cannot be reordered,
+// removed or moved between streams, and a truncated stream is detected.
+const (
+	streamMagic       = "KES\x01"
+	streamChunkSize   = 64 * 1024
+	streamNoncePrefix = 7
+)
+
+// errStreamDecrypt is returned when a stream cannot be decrypted
+// because it is malformed or not authentic.
+var errStreamDecrypt = errors.New("crypto: stream is not authentic")
+
+// StreamHeader is the header of an encrypted stream.
+type StreamHeader struct {
+	// SealedKey is the encrypted data encryption key used to
+	// encrypt the stream. It has to be decrypted, e.g. by the
+	// KES server, before the stream can be decrypted.
+	SealedKey []byte
+
+	nonce [streamNoncePrefix]byte
+}
+
+// MarshalBinary returns the binary representation of the header.
+func (h *StreamHeader) MarshalBinary() ([]byte, error) {
+	if len(h.SealedKey) > math.MaxUint16 {
+		return nil, errors.New("crypto: sealed key is too large")
+	}
+
+	b := make([]byte, 0, len(streamMagic)+2+len(h.SealedKey)+len(h.nonce))
+	b = append(b, streamMagic...)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(h.SealedKey)))
+	b = append(b, h.SealedKey...)
+	b = append(b, h.nonce[:]...)
+	return b, nil
+}
+
+// ReadStreamHeader reads the header of an encrypted stream from r.
+func ReadStreamHeader(r io.Reader) (*StreamHeader, error) {
+	var prefix [len(streamMagic) + 2]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errStreamDecrypt
+		}
+		return nil, err
+	}
+	if string(prefix[:len(streamMagic)]) != streamMagic {
+		return nil, errors.New("crypto: invalid stream header")
+	}
+
+	h := &StreamHeader{
+		SealedKey: make([]byte, binary.BigEndian.Uint16(prefix[len(streamMagic):])),
+	}
+	if _, err := io.ReadFull(r, h.SealedKey); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errStreamDecrypt
+		}
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, h.nonce[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, errStreamDecrypt
+		}
+		return nil, err
+	}
+	return h, nil
+}
+
+// EncryptStream encrypts everything read from src with the 256-bit
+// data encryption key and writes the header, containing the sealed
+// key, and the encrypted chunks to dst. It returns the number of
+// bytes written.
+func EncryptStream(dst io.Writer, src io.Reader, key, sealedKey []byte) (int64, error) {
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return 0, err
+	}
+
+	h := &StreamHeader{SealedKey: sealedKey}
+	if _, err = rand.Read(h.nonce[:]); err != nil {
+		return 0, err
+	}
+	header, err := h.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+	n, err := dst.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	written := int64(n)
+
+	// Read one byte more than a chunk to detect whether
+	// the current chunk is the final one.
+	buf := make([]byte, streamChunkSize+1, streamChunkSize+aead.Overhead())
+	nr, err := io.ReadFull(src, buf)
+	for seq := uint32(0); ; seq++ {
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return written, err
+		}
+		if !final && seq == math.MaxUint32 {
+			return written, errors.New("crypto: stream is too large")
+		}
+
+		var next byte
+		if !final {
+			next, nr = buf[streamChunkSize], streamChunkSize
+		}
+		ciphertext := aead.Seal(buf[:0], streamNonce(h.nonce, seq, final), buf[:nr], header)
+		n, wErr := dst.Write(ciphertext)
+		written += int64(n)
+		if wErr != nil {
+			return written, wErr
+		}
+		if final {
+			return written, nil
+		}
+
+		buf = buf[:streamChunkSize+1]
+		buf[0] = next
+		nr, err = io.ReadFull(src, buf[1:])
+		nr++
+	}
+}
+
+// DecryptStream decrypts the encrypted chunks read from src with the
+// 256-bit data encryption key and writes the plaintext to dst. The
+// header must have been read from src before, e.g. via ReadStreamHeader.
+// It returns the number of bytes written.
+//
+// Each chunk is verified before it is written to dst. However, if
+// DecryptStream returns an error, dst may contain a prefix of the
+// plaintext. Such a partial plaintext must not be used.
+func DecryptStream(dst io.Writer, src io.Reader, key []byte, h *StreamHeader) (int64, error) {
+	aead, err := newStreamAEAD(key)
+	if err != nil {
+		return 0, err
+	}
+	header, err := h.MarshalBinary()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		chunkSize = streamChunkSize + aead.Overhead()
+		written   int64
+	)
+	buf := make([]byte, chunkSize+1)
+	nr, err := io.ReadFull(src, buf)
+	for seq := uint32(0); ; seq++ {
+		final := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !final {
+			return written, err
+		}
+
+		var next byte
+		if !final {
+			next, nr = buf[chunkSize], chunkSize
+		}
+		plaintext, oErr := aead.Open(buf[:0], streamNonce(h.nonce, seq, final), buf[:nr], header)
+		if oErr != nil {
+			return written, errStreamDecrypt
+		}
+		n, wErr := dst.Write(plaintext)
+		written += int64(n)
+		if wErr != nil {
+			return written, wErr
+		}
+		if final {
+			return written, nil
+		}
+		if seq == math.MaxUint32 {
+			return written, errStreamDecrypt
+		}
+
+		buf[0] = next
+		nr, err = io.ReadFull(src, buf[1:])
+		nr++
+	}
+}
+
+// newStreamAEAD returns a new AES-256-GCM AEAD for the given key.
+func newStreamAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("crypto: invalid stream key length")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// streamNonce returns the nonce of the seq-th chunk.
+func streamNonce(prefix [streamNoncePrefix]byte, seq uint32, final bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix[:]...)
+	nonce = binary.BigEndian.AppendUint32(nonce, seq)
+	if final {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncryptStream(t *testing.T) {
+	t.Parallel()
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	sealedKey := []byte("sealed-key")
+
+	for i, size := range encryptStreamTests {
+		plaintext := make([]byte, size)
+		if _, err := rand.Read(plaintext); err != nil {
+			t.Fatal(err)
+		}
+
+		var ciphertext bytes.Buffer
+		n, err := EncryptStream(&ciphertext, bytes.NewReader(plaintext), key, sealedKey)
+		if err != nil {
+			t.Fatalf("Test %d: failed to encrypt stream: %v", i, err)
+		}
+		if n != int64(ciphertext.Len()) {
+			t.Fatalf("Test %d: invalid number of bytes written: got '%d' - want '%d'", i, n, ciphertext.Len())
+		}
+
+		src := bytes.NewReader(ciphertext.Bytes())
+		header, err := ReadStreamHeader(src)
+		if err != nil {
+			t.Fatalf("Test %d: failed to read stream header: %v", i, err)
+		}
+		if !bytes.Equal(header.SealedKey, sealedKey) {
+			t.Fatalf("Test %d: invalid sealed key: got '%s' - want '%s'", i, header.SealedKey, sealedKey)
+		}
+
+		var decrypted bytes.Buffer
+		if _, err = DecryptStream(&decrypted, src, key, header); err != nil {
+			t.Fatalf("Test %d: failed to decrypt stream: %v", i, err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plaintext) {
+			t.Fatalf("Test %d: decrypted plaintext does not match original plaintext", i)
+		}
+
+		// A truncated stream must not decrypt successfully
+		// unless the truncation happens within the header.
+		if size > 0 {
+			truncated := ciphertext.Bytes()[:ciphertext.Len()-1]
+			if _, err = decryptStream(truncated, key); err == nil {
+				t.Fatalf("Test %d: decrypted truncated stream successfully", i)
+			}
+		}
+		if size > streamChunkSize {
+			truncated := ciphertext.Bytes()[:ciphertext.Len()-(size%streamChunkSize)-16]
+			if _, err = decryptStream(truncated, key); err == nil {
+				t.Fatalf("Test %d: decrypted stream without final chunk successfully", i)
+			}
+		}
+	}
+}
+
+var encryptStreamTests = []int{
+	0,
+	1,
+	streamChunkSize - 1,
+	streamChunkSize,
+	streamChunkSize + 1,
+	3*streamChunkSize + 1024,
+}
+
+func decryptStream(ciphertext, key []byte) ([]byte, error) {
+	src := bytes.NewReader(ciphertext)
+	header, err := ReadStreamHeader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var plaintext bytes.Buffer
+	if _, err = DecryptStream(&plaintext, src, key, header); err != nil {
+		return nil, err
+	}
+	return plaintext.Bytes(), nil
+}
@@ -4,7 +4,10 @@
 
 package fips
 
-import "crypto/tls"
+import (
+	"crypto/tls"
+	"sync/atomic"
+)
 
 // Enabled indicates whether cryptographic primitives,
 // like AES or SHA-256, are implemented using a FIPS 140
@@ -14,10 +17,22 @@ import "crypto/tls"
 // primitives must be used.
 const Enabled = enabled
 
+var restricted atomic.Bool
+
+// Restrict restricts KES to FIPS 140 approved primitives,
+// even if they are not implemented by a FIPS 140 certified
+// module. It cannot be undone.
+func Restrict() { restricted.Store(true) }
+
+// ApprovedOnly reports whether only FIPS 140 approved
+// primitives must be used. It is true if Enabled is true
+// or Restrict has been called.
+func ApprovedOnly() bool { return Enabled || restricted.Load() }
+
 // TLSCiphers returns a list of supported TLS transport
 // cipher suite IDs.
 func TLSCiphers() []uint16 {
-	if Enabled {
+	if ApprovedOnly() {
 		return []uint16{
 			tls.TLS_AES_128_GCM_SHA256, // TLS 1.3
 			tls.TLS_AES_256_GCM_SHA384,
@@ -45,7 +60,7 @@ func TLSCiphers() []uint16 {
 // TLSCurveIDs returns a list of supported elliptic curve IDs
 // in preference order.
 func TLSCurveIDs() []tls.CurveID {
-	if Enabled {
+	if ApprovedOnly() {
 		return []tls.CurveID{
 			tls.CurveP256,
 			tls.CurveP384, // Contant time since Go 1.18
@@ -0,0 +1,28 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fips
+
+import (
+	"crypto/tls"
+	"slices"
+	"testing"
+)
+
+func TestRestrict(t *testing.T) {
+	if !Enabled && ApprovedOnly() {
+		t.Fatal("Only approved primitives allowed before FIPS mode has been enabled")
+	}
+
+	Restrict()
+	if !ApprovedOnly() {
+		t.Fatal("Non-approved primitives allowed after FIPS mode has been enabled")
+	}
+	if slices.Contains(TLSCiphers(), tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256) {
+		t.Fatal("ChaCha20-Poly1305 cipher suite offered in FIPS mode")
+	}
+	if slices.Contains(TLSCurveIDs(), tls.X25519) {
+		t.Fatal("X25519 curve offered in FIPS mode")
+	}
+}
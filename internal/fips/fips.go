@@ -7,4 +7,9 @@
 
 package fips
 
+// Building with the fips tag requires GOEXPERIMENT=boringcrypto.
+// Then, crypto/tls only negotiates FIPS 140 approved TLS versions,
+// cipher suites and curves.
+import _ "crypto/tls/fipsonly"
+
 const enabled = 0 == 0
@@ -18,6 +18,7 @@ const (
 	ContentType      = "Content-Type"      // RFC 2616
 	ContentLength    = "Content-Length"    // RFC 2616
 	ETag             = "ETag"              // RFC 2616
+	RetryAfter       = "Retry-After"       // RFC 2616
 	TransferEncoding = "Transfer-Encoding" // RFC 2616
 )
 
@@ -30,9 +31,17 @@ const (
 	XFrameOptions = "X-Frame-Options" // Non-standard
 )
 
+// Commonly used HTTP headers for de-duplicating retried
+// requests.
+const (
+	IdempotencyKey     = "Idempotency-Key"     // IETF draft-ietf-httpapi-idempotency-key-header
+	IdempotentReplayed = "Idempotent-Replayed" // Non-standard
+)
+
 // Commonly used HTTP content type values.
 const (
 	ContentTypeBinary    = "application/octet-stream"
+	ContentTypeGzip      = "application/gzip"
 	ContentTypeJSON      = "application/json"
 	ContentTypeJSONLines = "application/x-ndjson"
 	ContentTypeText      = "text/plain"
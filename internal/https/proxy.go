@@ -7,6 +7,7 @@ package https
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/hex"
 	"encoding/pem"
@@ -48,6 +49,16 @@ type TLSProxy struct {
 	// If it is nil the client certificate won't be verified.
 	VerifyOptions *x509.VerifyOptions
 
+	// Identify computes the identity of the peer from the
+	// TLS connection state of a request. A request is made
+	// by a TLS proxy if its identity has been added to the
+	// TLSProxy. Identify must be the same function that is
+	// used to compute the identity passed to Is.
+	//
+	// If it is nil the identity is the hash of the public
+	// key of the peer certificate.
+	Identify func(*tls.ConnectionState) kes.Identity
+
 	lock       sync.RWMutex
 	identities map[kes.Identity]bool
 }
@@ -111,6 +122,11 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 		return kes.NewError(http.StatusBadRequest, "insecure connection: TLS required")
 	}
 
+	// The TLS connection state is shared by all requests sent
+	// over the same connection. Hence, we modify a copy.
+	state := *req.TLS
+	req.TLS = &state
+
 	// A TLS proxy may send none, one or multiple peer certificates
 	// as part of the TLS handshake. However, we expect exactly
 	// one client certificate to check whether it is an authentic
@@ -138,6 +154,9 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 	req.TLS.PeerCertificates = peerCertificates
 
 	identity := identify(req)
+	if p.Identify != nil {
+		identity = p.Identify(req.TLS)
+	}
 	if identity.IsUnknown() {
 		return kes.ErrNotAllowed
 	}
@@ -182,9 +201,9 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 			// IP with an optional port number. So we first try
 			// to split the 'address:port' and then try to parse
 			// the address as IP.
-			addr, _, err := net.SplitHostPort(fwd)
+			addr, port, err := net.SplitHostPort(fwd)
 			if err != nil {
-				addr = fwd // There may be no port causing SplitHostPort to fail.
+				addr, port = fwd, "0" // There may be no port causing SplitHostPort to fail.
 			}
 
 			// Since cloning the request is relatively expensive,
@@ -193,6 +212,7 @@ func (p *TLSProxy) Verify(req *http.Request) error {
 			if ip := net.ParseIP(addr); ip != nil {
 				ctx := context.WithValue(req.Context(), forwardedIPContextKey{}, ip)
 				*req = *req.Clone(ctx)
+				req.RemoteAddr = net.JoinHostPort(ip.String(), port)
 			}
 		}
 	}
@@ -0,0 +1,211 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// proxyProtoV2Signature is the signature of a PROXY protocol
+// v2 header.
+var proxyProtoV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyProtoTimeout is the max. time a proxy may take to
+// send its PROXY protocol header.
+const proxyProtoTimeout = 5 * time.Second
+
+// NewProxyProtocolListener returns a net.Listener that accepts
+// connections from ln and reads the PROXY protocol header, v1
+// or v2, sent by L4 proxies and load balancers. The RemoteAddr
+// of an accepted connection is the client address within the
+// header.
+//
+// Only connections from one of the trusted networks have to
+// send a PROXY protocol header. Other connections are returned
+// as they are. Connections from trusted networks without a
+// valid header fail when reading from them.
+func NewProxyProtocolListener(ln net.Listener, trusted []netip.Prefix) net.Listener {
+	return &proxyProtoListener{
+		Listener: ln,
+		trusted:  trusted,
+	}
+}
+
+type proxyProtoListener struct {
+	net.Listener
+
+	trusted []netip.Prefix
+}
+
+// Accept waits for and returns the next connection to the
+// listener. It does not wait for the PROXY protocol header.
+// The header is read on the first Read or RemoteAddr call.
+func (l *proxyProtoListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := netip.ParseAddrPort(conn.RemoteAddr().String())
+	if err != nil {
+		return conn, nil
+	}
+	for _, prefix := range l.trusted {
+		if prefix.Contains(addr.Addr().Unmap()) {
+			return &proxyProtoConn{Conn: conn}, nil
+		}
+	}
+	return conn, nil
+}
+
+type proxyProtoConn struct {
+	net.Conn
+
+	once   sync.Once
+	r      *bufio.Reader
+	remote net.Addr
+	err    error
+}
+
+func (c *proxyProtoConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *proxyProtoConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.remote == nil {
+		return c.Conn.RemoteAddr()
+	}
+	return c.remote
+}
+
+func (c *proxyProtoConn) readHeader() {
+	c.once.Do(func() {
+		c.r = bufio.NewReader(c.Conn)
+		if c.err = c.Conn.SetReadDeadline(time.Now().Add(proxyProtoTimeout)); c.err != nil {
+			return
+		}
+		c.remote, c.err = readProxyHeader(c.r)
+		if err := c.Conn.SetReadDeadline(time.Time{}); err != nil && c.err == nil {
+			c.err = err
+		}
+	})
+}
+
+// readProxyHeader reads a PROXY protocol v1 or v2 header from r
+// and returns the client address. It returns a nil address if the
+// header does not contain a client address, e.g. for health checks
+// of the proxy itself.
+func readProxyHeader(r *bufio.Reader) (net.Addr, error) {
+	if sig, err := r.Peek(len(proxyProtoV2Signature)); err == nil && bytes.Equal(sig, proxyProtoV2Signature) {
+		return readProxyHeaderV2(r)
+	}
+	if prefix, err := r.Peek(6); err == nil && string(prefix) == "PROXY " {
+		return readProxyHeaderV1(r)
+	}
+	return nil, errors.New("https: missing or invalid PROXY protocol header")
+}
+
+// readProxyHeaderV1 reads a human-readable PROXY protocol v1 header.
+func readProxyHeaderV1(r *bufio.Reader) (net.Addr, error) {
+	const MaxSize = 107 // Max. size of a v1 header, including CRLF
+
+	var line []byte
+	for len(line) < MaxSize {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+	}
+	header, ok := strings.CutSuffix(string(line), "\r\n")
+	if !ok {
+		return nil, errors.New("https: invalid PROXY protocol v1 header")
+	}
+
+	fields := strings.Split(header, " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errors.New("https: invalid PROXY protocol v1 header")
+	}
+	ip, err := netip.ParseAddr(fields[2])
+	if err != nil || ip.Is4() != (fields[1] == "TCP4") {
+		return nil, errors.New("https: invalid PROXY protocol v1 header: invalid source address")
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, errors.New("https: invalid PROXY protocol v1 header: invalid source port")
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, uint16(port))), nil
+}
+
+// readProxyHeaderV2 reads a binary PROXY protocol v2 header.
+func readProxyHeaderV2(r *bufio.Reader) (net.Addr, error) {
+	const (
+		CmdLocal = 0x0
+		CmdProxy = 0x1
+
+		FamilyInet  = 0x1
+		FamilyInet6 = 0x2
+	)
+
+	var header [16]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, err
+	}
+	if version := header[12] >> 4; version != 2 {
+		return nil, errors.New("https: invalid PROXY protocol v2 header: unsupported version " + strconv.Itoa(int(version)))
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	switch cmd := header[12] & 0x0f; cmd {
+	case CmdLocal:
+		return nil, nil
+	case CmdProxy:
+	default:
+		return nil, errors.New("https: invalid PROXY protocol v2 header: unsupported command " + strconv.Itoa(int(cmd)))
+	}
+
+	switch header[13] >> 4 {
+	case FamilyInet:
+		if len(payload) < 12 {
+			return nil, errors.New("https: invalid PROXY protocol v2 header: address too short")
+		}
+		ip := netip.AddrFrom4([4]byte(payload[:4]))
+		port := binary.BigEndian.Uint16(payload[8:])
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, port)), nil
+	case FamilyInet6:
+		if len(payload) < 36 {
+			return nil, errors.New("https: invalid PROXY protocol v2 header: address too short")
+		}
+		ip := netip.AddrFrom16([16]byte(payload[:16]))
+		port := binary.BigEndian.Uint16(payload[32:])
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(ip, port)), nil
+	default:
+		return nil, nil // Unix sockets and unspecified address families
+	}
+}
@@ -0,0 +1,121 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+func TestReadProxyHeader(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range readProxyHeaderTests {
+		r := bufio.NewReader(strings.NewReader(test.Header + "GET / HTTP/1.1\r\n"))
+		addr, err := readProxyHeader(r)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to read header: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: reading invalid header succeeded", i)
+		}
+		if test.ShouldFail {
+			continue
+		}
+
+		var got string
+		if addr != nil {
+			got = addr.String()
+		}
+		if got != test.Addr {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, got, test.Addr)
+		}
+		if rest, _ := io.ReadAll(r); string(rest) != "GET / HTTP/1.1\r\n" {
+			t.Fatalf("Test %d: header has not been consumed: got '%q'", i, rest)
+		}
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	ln = NewProxyProtocolListener(ln, []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	defer ln.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "PROXY TCP4 192.0.2.1 198.51.100.1 56324 7373\r\nHello World")
+	}()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept connection: %v", err)
+	}
+	defer conn.Close()
+
+	if addr := conn.RemoteAddr().String(); addr != "192.0.2.1:56324" {
+		t.Fatalf("Invalid remote address: got '%s' - want '%s'", addr, "192.0.2.1:56324")
+	}
+	b, err := io.ReadAll(conn)
+	if err != nil {
+		t.Fatalf("Failed to read from connection: %v", err)
+	}
+	if string(b) != "Hello World" {
+		t.Fatalf("Invalid data: got '%s' - want '%s'", b, "Hello World")
+	}
+}
+
+var readProxyHeaderTests = []struct {
+	Header     string
+	Addr       string
+	ShouldFail bool
+}{
+	{ // 0
+		Header: "PROXY TCP4 192.0.2.1 198.51.100.1 56324 7373\r\n",
+		Addr:   "192.0.2.1:56324",
+	},
+	{ // 1
+		Header: "PROXY TCP6 2001:db8::1 2001:db8::2 56324 7373\r\n",
+		Addr:   "[2001:db8::1]:56324",
+	},
+	{ // 2
+		Header: "PROXY UNKNOWN\r\n",
+	},
+	{ // 3
+		Header: "\r\n\r\n\x00\r\nQUIT\n" + "\x21\x11\x00\x0c" + "\xc0\x00\x02\x01" + "\xc6\x33\x64\x01" + "\xdc\x04" + "\x1c\xcd",
+		Addr:   "192.0.2.1:56324",
+	},
+	{ // 4
+		Header: "\r\n\r\n\x00\r\nQUIT\n" + "\x20\x00\x00\x00", // LOCAL command
+	},
+	{ // 5
+		Header:     "PROXY TCP4 2001:db8::1 198.51.100.1 56324 7373\r\n",
+		ShouldFail: true,
+	},
+	{ // 6
+		Header:     "PROXY TCP4 192.0.2.1 198.51.100.1 56324\r\n",
+		ShouldFail: true,
+	},
+	{ // 7
+		Header:     "\r\n\r\n\x00\r\nQUIT\n" + "\x11\x11\x00\x00", // Version 1
+		ShouldFail: true,
+	},
+	{ // 8
+		Header:     "",
+		ShouldFail: true,
+	},
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import "os"
+
+// FileWatcher detects whether a set of files, like a TLS
+// certificate and private key, have changed. A file has
+// changed if it has been created, removed or replaced, or
+// if its modification time or size is different.
+//
+// Since files are compared by their os.Stat information,
+// symlinks are followed. Hence, FileWatcher also detects
+// when a symlink is updated to point to a new file, as done
+// by Kubernetes when updating mounted secrets.
+type FileWatcher struct {
+	files []string
+	stats []os.FileInfo
+}
+
+// NewFileWatcher returns a new FileWatcher that watches
+// the given files. Empty filenames are ignored.
+func NewFileWatcher(files ...string) *FileWatcher {
+	w := &FileWatcher{}
+	for _, file := range files {
+		if file == "" {
+			continue
+		}
+		w.files = append(w.files, file)
+	}
+	w.stats = make([]os.FileInfo, len(w.files))
+	for i, file := range w.files {
+		w.stats[i], _ = os.Stat(file)
+	}
+	return w
+}
+
+// Changed reports whether any of the watched files has
+// changed since the FileWatcher has been created or since
+// the last call of Changed.
+func (w *FileWatcher) Changed() bool {
+	var changed bool
+	for i, file := range w.files {
+		stat, _ := os.Stat(file)
+		if !sameFile(w.stats[i], stat) {
+			w.stats[i] = stat
+			changed = true
+		}
+	}
+	return changed
+}
+
+func sameFile(a, b os.FileInfo) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return os.SameFile(a, b) && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size()
+}
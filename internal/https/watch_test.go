@@ -0,0 +1,67 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package https
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "public.crt")
+	keyFile := filepath.Join(dir, "private.key")
+	if err := os.WriteFile(certFile, []byte("certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	w := NewFileWatcher(certFile, keyFile, "")
+	if w.Changed() {
+		t.Fatal("Files have not changed")
+	}
+
+	if err := os.WriteFile(keyFile, []byte("private key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Fatal("Creating a file should be detected")
+	}
+	if w.Changed() {
+		t.Fatal("Change has already been reported")
+	}
+
+	modTime := time.Now().Add(time.Minute)
+	if err := os.Chtimes(certFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Fatal("Modifying a file should be detected")
+	}
+
+	// Replace the certificate by a new file with the same size
+	// and modification time, similar to a symlink swap.
+	tmpFile := filepath.Join(dir, "public.crt.tmp")
+	if err := os.WriteFile(tmpFile, []byte("CERTIFICATE"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(tmpFile, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmpFile, certFile); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Fatal("Replacing a file should be detected")
+	}
+
+	if err := os.Remove(keyFile); err != nil {
+		t.Fatal(err)
+	}
+	if !w.Changed() {
+		t.Fatal("Removing a file should be detected")
+	}
+}
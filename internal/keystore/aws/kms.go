@@ -0,0 +1,506 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// KMSConfig is a structure containing configuration options
+// for storing keys envelope-encrypted with an AWS KMS key in
+// either DynamoDB or S3.
+type KMSConfig struct {
+	// Region is the AWS region.
+	Region string
+
+	// KeyARN is the ARN (or ID or alias) of the AWS KMS key
+	// used to generate and decrypt data encryption keys.
+	KeyARN string
+
+	// Endpoint is an optional AWS KMS endpoint. If empty,
+	// the AWS KMS endpoint of the region is used.
+	Endpoint string
+
+	// DynamoDB, if set, specifies the DynamoDB table
+	// storing the encrypted keys.
+	DynamoDB *DynamoDBConfig
+
+	// S3, if set, specifies the S3 bucket storing
+	// the encrypted keys.
+	S3 *S3Config
+
+	// Login contains the AWS credentials.
+	Login Credentials
+
+	// HTTP contains optional HTTP client settings used
+	// for requests to AWS KMS, DynamoDB and S3.
+	HTTP keystore.HTTPConfig
+
+	// MaxRetries is the max. number of times the AWS SDK
+	// retries a failed request. If 0, the SDK default is
+	// used. If negative, the SDK does not retry requests.
+	MaxRetries int
+}
+
+// DynamoDBConfig specifies a DynamoDB table for storing
+// encrypted keys.
+//
+// The table must have a partition key named "name" of
+// type string.
+type DynamoDBConfig struct {
+	// Table is the name of the DynamoDB table.
+	Table string
+
+	// Endpoint is an optional DynamoDB endpoint.
+	Endpoint string
+}
+
+// S3Config specifies an S3 bucket for storing encrypted
+// keys.
+type S3Config struct {
+	// Bucket is the name of the S3 bucket.
+	Bucket string
+
+	// Prefix is an optional prefix for all object names.
+	Prefix string
+
+	// Endpoint is an optional S3 endpoint. For example,
+	// the endpoint of a S3-compatible object storage.
+	Endpoint string
+}
+
+// ConnectKMS returns a KMSStore that encrypts keys with data keys
+// generated by AWS KMS and stores them in DynamoDB or S3.
+func ConnectKMS(ctx context.Context, config *KMSConfig) (*KMSStore, error) {
+	if config.KeyARN == "" {
+		return nil, errors.New("aws: no KMS key specified")
+	}
+	if (config.DynamoDB == nil) == (config.S3 == nil) {
+		return nil, errors.New("aws: exactly one of DynamoDB or S3 must be specified")
+	}
+
+	kmsSession, err := newSession(config.Endpoint, config.Region, config.Login, &config.HTTP, config.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	var blobs blobStore
+	switch {
+	case config.DynamoDB != nil:
+		if config.DynamoDB.Table == "" {
+			return nil, errors.New("aws: no DynamoDB table specified")
+		}
+		session, err := newSession(config.DynamoDB.Endpoint, config.Region, config.Login, &config.HTTP, config.MaxRetries)
+		if err != nil {
+			return nil, err
+		}
+		blobs = &dynamoDBStore{
+			client: dynamodb.New(session),
+			table:  config.DynamoDB.Table,
+		}
+	case config.S3 != nil:
+		if config.S3.Bucket == "" {
+			return nil, errors.New("aws: no S3 bucket specified")
+		}
+		session, err := newSession(config.S3.Endpoint, config.Region, config.Login, &config.HTTP, config.MaxRetries)
+		if err != nil {
+			return nil, err
+		}
+		blobs = &s3Store{
+			client: s3.New(session, aws.NewConfig().WithS3ForcePathStyle(config.S3.Endpoint != "")),
+			bucket: config.S3.Bucket,
+			prefix: config.S3.Prefix,
+		}
+	}
+
+	s := &KMSStore{
+		keyARN: config.KeyARN,
+		kms:    kms.New(kmsSession),
+		blobs:  blobs,
+	}
+	if _, err = s.Status(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// KMSStore is a key store that encrypts keys with data keys
+// generated by AWS KMS, and stores them in DynamoDB or S3.
+// The AWS KMS key, and therefore the root of trust, never
+// leaves AWS KMS.
+type KMSStore struct {
+	keyARN string
+	kms    *kms.KMS
+	blobs  blobStore
+}
+
+var _ kes.KeyStore = (*KMSStore)(nil) // compiler check
+
+// String returns a string representation of the KMSStore.
+func (s *KMSStore) String() string { return "AWS KMS: " + s.keyARN + " (" + s.blobs.String() + ")" }
+
+// Status returns the current state of the AWS KMS key.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *KMSStore) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	start := time.Now()
+	resp, err := s.kms.DescribeKeyWithContext(ctx, &kms.DescribeKeyInput{
+		KeyId: aws.String(s.keyARN),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return kes.KeyStoreState{}, err
+		}
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: fmt.Errorf("aws: %v", err)}
+	}
+	latency := time.Since(start)
+
+	if state := aws.StringValue(resp.KeyMetadata.KeyState); state != kms.KeyStateEnabled {
+		return kes.KeyStoreState{}, fmt.Errorf("aws: KMS key '%s' is not enabled: %s", s.keyARN, state)
+	}
+	return kes.KeyStoreState{
+		Latency: latency,
+	}, nil
+}
+
+// envelope is the representation of a key encrypted
+// with a data key generated by AWS KMS.
+type envelope struct {
+	KeyID      string `json:"key_id"`
+	DataKey    []byte `json:"data_key"`   // Encrypted by AWS KMS
+	Ciphertext []byte `json:"ciphertext"` // Nonce || AES-256-GCM ciphertext
+}
+
+// Create encrypts the value with a new AWS KMS data key and stores it
+// if and only if no key with the given name exists. Otherwise, it
+// returns kes.ErrKeyExists.
+func (s *KMSStore) Create(ctx context.Context, name string, value []byte) error {
+	resp, err := s.kms.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:             aws.String(s.keyARN),
+		KeySpec:           aws.String(kms.DataKeySpecAes256),
+		EncryptionContext: encryptionContext(name),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return err
+		}
+		return temporaryError(err, fmt.Errorf("aws: failed to create '%s': failed to generate data key: %v", name, err))
+	}
+	aead, err := newAEAD(resp.Plaintext)
+	if err != nil {
+		return fmt.Errorf("aws: failed to create '%s': %v", name, err)
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(value)+aead.Overhead())
+	if _, err = rand.Read(nonce); err != nil {
+		return fmt.Errorf("aws: failed to create '%s': %v", name, err)
+	}
+
+	data, err := json.Marshal(envelope{
+		KeyID:      aws.StringValue(resp.KeyId),
+		DataKey:    resp.CiphertextBlob,
+		Ciphertext: aead.Seal(nonce, nonce, value, []byte(name)),
+	})
+	if err != nil {
+		return fmt.Errorf("aws: failed to create '%s': %v", name, err)
+	}
+	if err = s.blobs.Create(ctx, name, data); err != nil && !errors.Is(err, kesdk.ErrKeyExists) {
+		return temporaryError(err, fmt.Errorf("aws: failed to create '%s': %v", name, err))
+	}
+	return err
+}
+
+// Get returns the value associated with the given key. It
+// decrypts the value's data key using AWS KMS.
+// If no entry for key exists, it returns kes.ErrKeyNotFound.
+func (s *KMSStore) Get(ctx context.Context, name string) ([]byte, error) {
+	data, err := s.blobs.Get(ctx, name)
+	if err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return nil, err
+		}
+		return nil, temporaryError(err, fmt.Errorf("aws: failed to read '%s': %v", name, err))
+	}
+
+	var env envelope
+	if err = json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("aws: failed to read '%s': invalid envelope: %v", name, err)
+	}
+	resp, err := s.kms.DecryptWithContext(ctx, &kms.DecryptInput{
+		KeyId:             aws.String(env.KeyID),
+		CiphertextBlob:    env.DataKey,
+		EncryptionContext: encryptionContext(name),
+	})
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		return nil, temporaryError(err, fmt.Errorf("aws: failed to read '%s': failed to decrypt data key: %v", name, err))
+	}
+	aead, err := newAEAD(resp.Plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to read '%s': %v", name, err)
+	}
+	if len(env.Ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("aws: failed to read '%s': ciphertext is truncated", name)
+	}
+	nonce, ciphertext := env.Ciphertext[:aead.NonceSize()], env.Ciphertext[aead.NonceSize():]
+	value, err := aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, fmt.Errorf("aws: failed to read '%s': failed to decrypt value", name)
+	}
+	return value, nil
+}
+
+// Delete removes the key-value pair, if it exists. Otherwise,
+// it returns kes.ErrKeyNotFound.
+func (s *KMSStore) Delete(ctx context.Context, name string) error {
+	if err := s.blobs.Delete(ctx, name); err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return err
+		}
+		return temporaryError(err, fmt.Errorf("aws: failed to delete '%s': %v", name, err))
+	}
+	return nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *KMSStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	names, err := s.blobs.List(ctx, prefix)
+	if err != nil {
+		return nil, "", temporaryError(err, fmt.Errorf("aws: failed to list keys: %v", err))
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the KMSStore.
+func (s *KMSStore) Close() error { return nil }
+
+// encryptionContext returns the AWS KMS encryption context
+// binding a data key to the given key name.
+func encryptionContext(name string) map[string]*string {
+	return map[string]*string{"kes:key": aws.String(name)}
+}
+
+// newAEAD returns an AES-256-GCM AEAD for the given data key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid data key length '%d'", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// blobStore stores envelope-encrypted keys.
+type blobStore interface {
+	String() string
+
+	// Create stores data under the given name if no
+	// entry with this name exists. Otherwise, it returns
+	// kes.ErrKeyExists.
+	Create(ctx context.Context, name string, data []byte) error
+
+	// Get returns the data stored under the given name
+	// or kes.ErrKeyNotFound.
+	Get(ctx context.Context, name string) ([]byte, error)
+
+	// Delete removes the entry with the given name
+	// or returns kes.ErrKeyNotFound.
+	Delete(ctx context.Context, name string) error
+
+	// List returns all names that start with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// dynamoDBStore stores envelopes in a DynamoDB table with
+// a partition key "name" and a binary attribute "envelope".
+type dynamoDBStore struct {
+	client *dynamodb.DynamoDB
+	table  string
+}
+
+func (s *dynamoDBStore) String() string { return "DynamoDB: " + s.table }
+
+func (s *dynamoDBStore) Create(ctx context.Context, name string, data []byte) error {
+	_, err := s.client.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(s.table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"name":     {S: aws.String(name)},
+			"envelope": {B: data},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#n)"),
+		ExpressionAttributeNames: map[string]*string{"#n": aws.String("name")},
+	})
+	if err, ok := err.(awserr.Error); ok && err.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return kesdk.ErrKeyExists
+	}
+	return err
+}
+
+func (s *dynamoDBStore) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.table),
+		Key:            map[string]*dynamodb.AttributeValue{"name": {S: aws.String(name)}},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	item, ok := resp.Item["envelope"]
+	if !ok {
+		return nil, kesdk.ErrKeyNotFound
+	}
+	return item.B, nil
+}
+
+func (s *dynamoDBStore) Delete(ctx context.Context, name string) error {
+	resp, err := s.client.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{
+		TableName:    aws.String(s.table),
+		Key:          map[string]*dynamodb.AttributeValue{"name": {S: aws.String(name)}},
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return err
+	}
+	if len(resp.Attributes) == 0 {
+		return kesdk.ErrKeyNotFound
+	}
+	return nil
+}
+
+func (s *dynamoDBStore) List(ctx context.Context, prefix string) ([]string, error) {
+	input := &dynamodb.ScanInput{
+		TableName:                aws.String(s.table),
+		ProjectionExpression:     aws.String("#n"),
+		ExpressionAttributeNames: map[string]*string{"#n": aws.String("name")},
+	}
+	if prefix != "" {
+		input.FilterExpression = aws.String("begins_with(#n, :prefix)")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{":prefix": {S: aws.String(prefix)}}
+	}
+
+	var names []string
+	err := s.client.ScanPagesWithContext(ctx, input, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, item := range page.Items {
+			if name, ok := item["name"]; ok && name.S != nil {
+				names = append(names, *name.S)
+			}
+		}
+		return !lastPage
+	})
+	return names, err
+}
+
+// s3Store stores envelopes as objects in an S3 bucket.
+type s3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (s *s3Store) String() string { return "S3: " + s.bucket }
+
+func (s *s3Store) Create(ctx context.Context, name string, data []byte) error {
+	req, _ := s.client.PutObjectRequest(&s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.prefix + name),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	})
+	req.SetContext(ctx)
+
+	// Conditional writes are not part of this SDK version's
+	// PutObject API. The header is set explicitly such that
+	// S3 rejects the write if the object already exists.
+	req.HTTPRequest.Header.Set("If-None-Match", "*")
+
+	err := req.Send()
+	if err, ok := err.(awserr.RequestFailure); ok && (err.StatusCode() == http.StatusPreconditionFailed || err.StatusCode() == http.StatusConflict) {
+		return kesdk.ErrKeyExists
+	}
+	return err
+}
+
+func (s *s3Store) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	if err != nil {
+		if err, ok := err.(awserr.Error); ok && err.Code() == s3.ErrCodeNoSuchKey {
+			return nil, kesdk.ErrKeyNotFound
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(mem.LimitReader(resp.Body, 1*mem.MB))
+}
+
+func (s *s3Store) Delete(ctx context.Context, name string) error {
+	// S3 DeleteObject succeeds even if the object does
+	// not exist. Hence, check whether it exists first.
+	_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	if err != nil {
+		if err, ok := err.(awserr.RequestFailure); ok && err.StatusCode() == http.StatusNotFound {
+			return kesdk.ErrKeyNotFound
+		}
+		return err
+	}
+	_, err = s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.prefix + name),
+	})
+	return err
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.prefix + prefix),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, object := range page.Contents {
+			names = append(names, strings.TrimPrefix(aws.StringValue(object.Key), s.prefix))
+		}
+		return !lastPage
+	})
+	return names, err
+}
@@ -14,19 +14,74 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/minio/kes"
 	"github.com/minio/kes/internal/keystore"
 	kesdk "github.com/minio/kms-go/kes"
 )
 
-// Credentials represents static AWS credentials:
-// access key, secret key and a session token
+// Credentials represents AWS credentials.
+//
+// Either static credentials - access key, secret key
+// and a session token - or a web identity, like an EKS
+// service account (IRSA), can be used. If neither is
+// present, the AWS SDK default credential chain is
+// used - e.g. EC2 instance or ECS task roles.
 type Credentials struct {
 	AccessKey    string // The AWS access key
 	SecretKey    string // The AWS secret key
 	SessionToken string // The AWS session token
+
+	// WebIdentity, if set, is used to obtain temp.
+	// credentials via AssumeRoleWithWebIdentity.
+	WebIdentity *WebIdentity
+
+	// AssumeRole, if set, is used to assume an IAM
+	// role using the credentials above.
+	AssumeRole *AssumeRole
+
+	// IMDSv2Only disables the fallback to IMDSv1 when
+	// fetching credentials from the EC2 instance metadata
+	// service. Credentials are only fetched using IMDSv2
+	// session tokens.
+	IMDSv2Only bool
+}
+
+// AssumeRole contains the configuration for assuming an
+// IAM role via STS AssumeRole - e.g. a role in another
+// AWS account.
+type AssumeRole struct {
+	// RoleARN is the ARN of the IAM role to assume.
+	RoleARN string
+
+	// ExternalID is an optional external ID required
+	// by the role's trust policy.
+	ExternalID string
+
+	// SessionName is an optional name for the role session.
+	// If empty, a unique session name is generated.
+	SessionName string
+}
+
+// WebIdentity contains the configuration for authenticating
+// to AWS via STS AssumeRoleWithWebIdentity. For example,
+// when running on EKS with IAM roles for service accounts.
+type WebIdentity struct {
+	// RoleARN is the ARN of the IAM role to assume.
+	RoleARN string
+
+	// TokenFile is the path to the file containing the
+	// OIDC/JWT web identity token. The file is re-read
+	// whenever the temp. credentials expire.
+	TokenFile string
+
+	// SessionName is an optional name for the role session.
+	// If empty, a unique session name is generated.
+	SessionName string
 }
 
 // Config is a structure containing configuration
@@ -50,22 +105,64 @@ type Config struct {
 
 	// Login contains the AWS credentials (access/secret key).
 	Login Credentials
+
+	// HTTP contains optional HTTP client settings, like the
+	// number of idle connections and the request timeout.
+	HTTP keystore.HTTPConfig
+
+	// MaxRetries is the max. number of times the AWS SDK
+	// retries a failed request. If 0, the SDK default is
+	// used. If negative, the SDK does not retry requests.
+	MaxRetries int
 }
 
 // Connect establishes and returns a Conn to a AWS SecretManager
 // using the given config.
 func Connect(ctx context.Context, config *Config) (*Store, error) {
+	session, err := newSession(config.Addr, config.Region, config.Login, &config.HTTP, config.MaxRetries)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Store{
+		config: *config,
+		client: secretsmanager.New(session),
+	}
+	if _, err = c.Status(ctx); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newSession returns a new AWS session for the given endpoint
+// and region using the given credentials. If endpoint is empty,
+// the AWS SDK default endpoint for the region and service is used.
+//
+// The session uses the HTTP client settings and retries failed
+// requests up to maxRetries times, or the SDK default if 0.
+func newSession(endpoint, region string, login Credentials, httpConf *keystore.HTTPConfig, maxRetries int) (*session.Session, error) {
+	if login.WebIdentity != nil && (login.AccessKey != "" || login.SecretKey != "" || login.SessionToken != "") {
+		return nil, errors.New("aws: static credentials and web identity are mutually exclusive")
+	}
+
 	credentials := credentials.NewStaticCredentials(
-		config.Login.AccessKey,
-		config.Login.SecretKey,
-		config.Login.SessionToken,
+		login.AccessKey,
+		login.SecretKey,
+		login.SessionToken,
 	)
-	if config.Login.AccessKey == "" && config.Login.SecretKey == "" && config.Login.SessionToken == "" {
+	if login.WebIdentity != nil {
+		var err error
+		if credentials, err = webIdentityCredentials(region, login.WebIdentity); err != nil {
+			return nil, err
+		}
+	} else if login.AccessKey == "" && login.SecretKey == "" && login.SessionToken == "" {
 		// If all login credentials (access key, secret key and session token) are empty
 		// we pass no (not empty) credentials to the AWS SDK. The SDK will try to fetch
 		// the credentials from:
 		//  - Environment Variables
 		//  - Shared Credentials file
+		//  - Web identity token file (AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN)
+		//  - ECS container credentials
 		//  - EC2 Instance Metadata
 		// In particular, when running a kes server on an EC2 instance, the SDK will
 		// automatically fetch the temp. credentials from the EC2 metadata service.
@@ -73,26 +170,89 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		credentials = nil
 	}
 
+	config := aws.Config{
+		Region:      aws.String(region),
+		Credentials: credentials,
+	}
+	if login.IMDSv2Only {
+		config.EC2MetadataEnableFallback = aws.Bool(false)
+	}
+	if endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	if !httpConf.IsZero() {
+		client := &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+		httpConf.Configure(client)
+		config.HTTPClient = client
+	}
+	if maxRetries != 0 {
+		config.MaxRetries = aws.Int(max(maxRetries, 0))
+	}
+	if login.AssumeRole != nil {
+		if login.AssumeRole.RoleARN == "" {
+			return nil, errors.New("aws: invalid assume role: no role ARN specified")
+		}
+
+		// The role is assumed using the credentials from above.
+		// The temp. role credentials are refreshed automatically
+		// before they expire.
+		base, err := session.NewSessionWithOptions(session.Options{
+			Config: aws.Config{
+				Region:                    aws.String(region),
+				Credentials:               credentials,
+				EC2MetadataEnableFallback: config.EC2MetadataEnableFallback,
+			},
+			SharedConfigState: session.SharedConfigDisable,
+		})
+		if err != nil {
+			return nil, err
+		}
+		role := login.AssumeRole
+		config.Credentials = stscreds.NewCredentials(base, role.RoleARN, func(p *stscreds.AssumeRoleProvider) {
+			if role.ExternalID != "" {
+				p.ExternalID = aws.String(role.ExternalID)
+			}
+			if role.SessionName != "" {
+				p.RoleSessionName = role.SessionName
+			}
+		})
+	}
+	return session.NewSessionWithOptions(session.Options{
+		Config:            config,
+		SharedConfigState: session.SharedConfigDisable,
+	})
+}
+
+// webIdentityCredentials returns AWS credentials that are obtained
+// via STS AssumeRoleWithWebIdentity and refreshed automatically
+// before they expire.
+func webIdentityCredentials(region string, identity *WebIdentity) (*credentials.Credentials, error) {
+	if identity.RoleARN == "" {
+		return nil, errors.New("aws: invalid web identity: no role ARN specified")
+	}
+	if identity.TokenFile == "" {
+		return nil, errors.New("aws: invalid web identity: no token file specified")
+	}
+
+	// AssumeRoleWithWebIdentity requests are not signed. Hence,
+	// the STS client does not need any credentials itself.
 	session, err := session.NewSessionWithOptions(session.Options{
 		Config: aws.Config{
-			Endpoint:    aws.String(config.Addr),
-			Region:      aws.String(config.Region),
-			Credentials: credentials,
+			Region:      aws.String(region),
+			Credentials: credentials.AnonymousCredentials,
 		},
 		SharedConfigState: session.SharedConfigDisable,
 	})
 	if err != nil {
 		return nil, err
 	}
-
-	c := &Store{
-		config: *config,
-		client: secretsmanager.New(session),
-	}
-	if _, err = c.Status(ctx); err != nil {
-		return nil, err
-	}
-	return c, nil
+	provider := stscreds.NewWebIdentityRoleProviderWithOptions(
+		sts.New(session),
+		identity.RoleARN,
+		identity.SessionName,
+		stscreds.FetchTokenPath(identity.TokenFile),
+	)
+	return credentials.NewCredentials(provider), nil
 }
 
 // Store is an AWS SecretsManager secret store.
@@ -147,7 +307,7 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 				return kesdk.ErrKeyExists
 			}
 		}
-		return fmt.Errorf("aws: failed to create '%s': %v", name, err)
+		return temporaryError(err, fmt.Errorf("aws: failed to create '%s': %v", name, err))
 	}
 	return nil
 }
@@ -181,7 +341,7 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 				return nil, kesdk.ErrKeyNotFound
 			}
 		}
-		return nil, fmt.Errorf("aws: failed to read '%s': %v", name, err)
+		return nil, temporaryError(err, fmt.Errorf("aws: failed to read '%s': %v", name, err))
 	}
 
 	// AWS has two different ways to store a secret. Either as
@@ -216,7 +376,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 				return kesdk.ErrKeyNotFound
 			}
 		}
-		return fmt.Errorf("aws: failed to delete '%s': %v", name, err)
+		return temporaryError(err, fmt.Errorf("aws: failed to delete '%s': %v", name, err))
 	}
 	return nil
 }
@@ -246,10 +406,27 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 		return !lastPage
 	})
 	if err != nil {
-		return nil, "", err
+		return nil, "", temporaryError(err, err)
 	}
 	return keystore.List(names, prefix, n)
 }
 
 // Close closes the Store.
 func (s *Store) Close() error { return nil }
+
+// temporaryError returns err as keystore.ErrUnreachable if
+// the AWS request failed with cause because AWS throttled
+// it or failed temporarily - e.g. with a 5xx status code.
+// Hence, such requests are retried and count as failures
+// of the keystore. Otherwise, it returns err unmodified.
+func temporaryError(cause, err error) error {
+	if request.IsErrorThrottle(cause) || request.IsErrorRetryable(cause) {
+		return &keystore.ErrUnreachable{Err: err}
+	}
+	if failure, ok := cause.(awserr.RequestFailure); ok {
+		if code := failure.StatusCode(); code == http.StatusTooManyRequests || code >= http.StatusInternalServerError {
+			return &keystore.ErrUnreachable{Err: err}
+		}
+	}
+	return err
+}
@@ -27,11 +27,31 @@ type Credentials struct {
 	Secret   string // The secret value of the Azure client
 }
 
+// CertificateCredentials are Azure client credentials that use a
+// client certificate, instead of a client secret, to authenticate
+// an application accessing Azure services.
+type CertificateCredentials struct {
+	TenantID string // The ID of the Azure tenant
+	ClientID string // The ID of the Azure client accessing KeyVault
+
+	// CertificatePath is the path to the PKCS#12 (PFX) file
+	// containing the client certificate and private key.
+	CertificatePath string
+
+	// CertificatePassword is an optional password to decrypt
+	// the PKCS#12 file.
+	CertificatePassword string
+}
+
 // ManagedIdentity is an Azure managed identity.
 //
 // It allows applications running inside Azure to authenticate
 // to Azure services via a managed identity object containing
 // the access credentials.
+//
+// If the ClientID is empty, the system-assigned managed identity
+// is used. Otherwise, the user-assigned managed identity with
+// the given client ID.
 type ManagedIdentity struct {
 	ClientID string // The Azure managed identity client ID
 }
@@ -358,6 +378,26 @@ func ConnectWithCredentials(_ context.Context, endpoint string, creds Credential
 	}, nil
 }
 
+// ConnectWithCertificate tries to establish a connection to a Azure KeyVault
+// instance using Azure client certificate credentials.
+func ConnectWithCertificate(_ context.Context, endpoint string, creds CertificateCredentials) (*Store, error) {
+	const Scope = "https://vault.azure.net"
+
+	c := auth.NewClientCertificateConfig(creds.CertificatePath, creds.CertificatePassword, creds.ClientID, creds.TenantID)
+	c.Resource = Scope
+	token, err := c.ServicePrincipalToken()
+	if err != nil {
+		return nil, fmt.Errorf("azure: failed to obtain ServicePrincipalToken from client certificate: %v", err)
+	}
+	return &Store{
+		endpoint: endpoint,
+		client: client{
+			Endpoint:   endpoint,
+			Authorizer: autorest.NewBearerAuthorizer(token),
+		},
+	}, nil
+}
+
 // ConnectWithIdentity tries to establish a connection to a Azure KeyVault
 // instance using an Azure managed identity.
 func ConnectWithIdentity(_ context.Context, endpoint string, msi ManagedIdentity) (*Store, error) {
@@ -0,0 +1,200 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package breaker implements a keystore that stops sending
+// requests to another keystore after repeated failures.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+)
+
+// ErrOpen is returned by a Store while its circuit breaker
+// is open. It is a keystore.ErrUnreachable such that callers
+// treat it like any other temporary error.
+var ErrOpen = &keystore.ErrUnreachable{Err: errors.New("breaker: circuit breaker is open")}
+
+// Config is a structure containing the circuit breaker
+// policy for requests to a keystore.
+type Config struct {
+	// Threshold is the number of consecutive requests that
+	// have to fail with a temporary error before the circuit
+	// breaker opens. If <= 0, defaults to 5.
+	Threshold int
+
+	// Interval is the time the circuit breaker remains open
+	// before it lets a single request through to probe whether
+	// the keystore has recovered. If <= 0, defaults to 30s.
+	Interval time.Duration
+}
+
+// NewStore returns a new Store that stops sending requests
+// to the given keystore after repeated failures based on the
+// config.
+func NewStore(store kes.KeyStore, config *Config) *Store {
+	c := *config
+	if c.Threshold <= 0 {
+		c.Threshold = 5
+	}
+	if c.Interval <= 0 {
+		c.Interval = 30 * time.Second
+	}
+	return &Store{
+		store:  store,
+		config: c,
+	}
+}
+
+// Store is a keystore with a circuit breaker. Once a number of
+// consecutive requests have failed with a temporary error, e.g.
+// because the keystore is unreachable or throttles requests,
+// the circuit breaker opens and requests fail with ErrOpen
+// immediately. Hence, an overloaded keystore is not flooded
+// with requests that are likely to fail.
+//
+// After some time, the circuit breaker lets a single request
+// through. If it succeeds, the circuit breaker closes again.
+// Otherwise, it remains open.
+type Store struct {
+	store  kes.KeyStore
+	config Config
+
+	lock      sync.Mutex
+	failures  int       // Number of consecutive temporary failures
+	openUntil time.Time // Zero while the circuit breaker is closed
+	probing   bool      // Whether a probe request is in progress
+}
+
+func (s *Store) String() string {
+	if str, ok := s.store.(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprintf("%T", s.store)
+}
+
+// Status returns the current state of the underlying
+// keystore or ErrOpen while the circuit breaker is open.
+//
+// Status requests are not counted as failures or successes,
+// since the keystore may be reachable but still throttle or
+// reject requests.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	if s.Open() {
+		return kes.KeyStoreState{}, ErrOpen
+	}
+	return s.store.Status(ctx)
+}
+
+// Open reports whether the circuit breaker is open.
+func (s *Store) Open() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return !s.openUntil.IsZero()
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	return s.call(func() error { return s.store.Create(ctx, name, value) })
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.call(func() error { return s.store.Delete(ctx, name) })
+}
+
+// Get returns the value associated with the given name. If
+// no such entry exists, Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	var value []byte
+	err := s.call(func() (err error) {
+		value, err = s.store.Get(ctx, name)
+		return err
+	})
+	return value, err
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	var (
+		names []string
+		next  string
+	)
+	err := s.call(func() (err error) {
+		names, next, err = s.store.List(ctx, prefix, n)
+		return err
+	})
+	return names, next, err
+}
+
+// Close closes the underlying keystore.
+func (s *Store) Close() error { return s.store.Close() }
+
+// call calls f unless the circuit breaker is open and
+// records whether f failed with a temporary error.
+func (s *Store) call(f func() error) error {
+	probe, ok := s.allow()
+	if !ok {
+		return ErrOpen
+	}
+	err := f()
+	s.record(probe, err)
+	return err
+}
+
+// allow reports whether a request may be sent to the
+// keystore and whether this request is a probe request.
+func (s *Store) allow() (probe, ok bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.openUntil.IsZero() {
+		return false, true
+	}
+	if s.probing || time.Now().Before(s.openUntil) {
+		return false, false
+	}
+	s.probing = true
+	return true, true
+}
+
+// record updates the state of the circuit breaker based
+// on the error returned by a request.
+func (s *Store) record(probe bool, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if probe {
+		s.probing = false
+	}
+	if err == nil || !keystore.IsTemporary(err) {
+		s.failures = 0
+		s.openUntil = time.Time{}
+		return
+	}
+
+	s.failures++
+	if probe || s.failures >= s.config.Threshold {
+		s.openUntil = time.Now().Add(s.config.Interval)
+	}
+}
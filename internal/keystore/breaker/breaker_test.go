@@ -0,0 +1,76 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package breaker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStore(t *testing.T) {
+	const (
+		Threshold = 3
+		Interval  = 50 * time.Millisecond
+	)
+	ctx := context.Background()
+
+	flaky := &flakyStore{KeyStore: &kes.MemKeyStore{}, Failing: true}
+	store := NewStore(flaky, &Config{Threshold: Threshold, Interval: Interval})
+	for i := 0; i < Threshold; i++ {
+		if _, err := store.Get(ctx, "key"); errors.Is(err, ErrOpen) || !keystore.IsTemporary(err) {
+			t.Fatalf("Request %d: invalid error: got '%v' - want temporary error", i, err)
+		}
+	}
+	if !store.Open() {
+		t.Fatalf("Circuit breaker is not open after '%d' failures", Threshold)
+	}
+	if _, err := store.Get(ctx, "key"); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrOpen)
+	}
+	if flaky.Calls != Threshold {
+		t.Fatalf("Request sent while circuit breaker is open: got '%d' calls - want '%d'", flaky.Calls, Threshold)
+	}
+
+	// Once the interval has passed, a failing probe request
+	// keeps the circuit breaker open while a successful one
+	// closes it.
+	time.Sleep(Interval)
+	if _, err := store.Get(ctx, "key"); errors.Is(err, ErrOpen) {
+		t.Fatal("Probe request has not been sent")
+	}
+	if !store.Open() {
+		t.Fatal("Circuit breaker closed after failed probe request")
+	}
+
+	time.Sleep(Interval)
+	flaky.Failing = false
+	if _, err := store.Get(ctx, "key"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if store.Open() {
+		t.Fatal("Circuit breaker is still open after successful probe request")
+	}
+}
+
+// flakyStore is a KeyStore that fails all requests
+// with an unreachable error while Failing is set.
+type flakyStore struct {
+	kes.KeyStore
+	Failing bool
+	Calls   int
+}
+
+func (s *flakyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.Calls++; s.Failing {
+		return nil, &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Get(ctx, name)
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package compress implements a keystore that compresses
+// values before writing them to another keystore.
+package compress
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/minio/kes"
+)
+
+// prefix is the prefix of compressed values.
+//
+// Compressed values are base64-encoded since some keystores,
+// like AWS SecretsManager, only store text. The prefix is
+// neither valid base64 nor JSON and, therefore, cannot be
+// confused with an uncompressed value.
+const prefix = "kes:deflate:"
+
+// MaxSize is the max. size of a decompressed value.
+const MaxSize = 1 << 20
+
+// NewStore returns a new Store that compresses values
+// before writing them to the given keystore.
+func NewStore(store kes.KeyStore) *Store {
+	return &Store{store: store}
+}
+
+// Store is a keystore that compresses values before writing
+// them to another keystore and decompresses them on read.
+// It helps to fit larger values into keystores that limit
+// the size of an entry.
+//
+// Values are only stored compressed if the compressed
+// representation is smaller. Values that are not compressed,
+// e.g. because they have been written before compression
+// has been enabled, are returned as they are.
+type Store struct {
+	store kes.KeyStore
+}
+
+func (s *Store) String() string {
+	if str, ok := s.store.(fmt.Stringer); ok {
+		return str.String() + " (compressed)"
+	}
+	return fmt.Sprintf("%T (compressed)", s.store)
+}
+
+// Unwrap returns the underlying keystore.
+func (s *Store) Unwrap() kes.KeyStore { return s.store }
+
+// Status returns the current state of the underlying
+// keystore.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	return s.store.Status(ctx)
+}
+
+// Create compresses the value and creates a new entry with
+// the given name if and only if no such entry exists.
+// Otherwise, Create returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	compressed, err := compress(value)
+	if err != nil {
+		return err
+	}
+	return s.store.Create(ctx, name, compressed)
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.store.Delete(ctx, name)
+}
+
+// Get returns the, possibly decompressed, value associated
+// with the given name. If no such entry exists, Get returns
+// kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	value, err := s.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return decompress(value)
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	return s.store.List(ctx, prefix, n)
+}
+
+// Close closes the underlying keystore.
+func (s *Store) Close() error { return s.store.Close() }
+
+// compress returns the compressed representation of value
+// if it is smaller than value. Otherwise, it returns value.
+func compress(value []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(value); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	n := len(prefix) + base64.StdEncoding.EncodedLen(buf.Len())
+	if n >= len(value) {
+		return value, nil
+	}
+	compressed := make([]byte, n)
+	copy(compressed, prefix)
+	base64.StdEncoding.Encode(compressed[len(prefix):], buf.Bytes())
+	return compressed, nil
+}
+
+// decompress returns the decompressed value if value has been
+// compressed. Otherwise, it returns value.
+func decompress(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, []byte(prefix)) {
+		return value, nil
+	}
+
+	value = value[len(prefix):]
+	raw := make([]byte, base64.StdEncoding.DecodedLen(len(value)))
+	n, err := base64.StdEncoding.Decode(raw, value)
+	if err != nil {
+		return nil, fmt.Errorf("compress: invalid compressed value: %v", err)
+	}
+
+	r := flate.NewReader(bytes.NewReader(raw[:n]))
+	defer r.Close()
+
+	var buf bytes.Buffer
+	if _, err = io.Copy(&buf, io.LimitReader(r, MaxSize+1)); err != nil {
+		return nil, fmt.Errorf("compress: invalid compressed value: %v", err)
+	}
+	if buf.Len() > MaxSize {
+		return nil, fmt.Errorf("compress: decompressed value exceeds %d bytes", MaxSize)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package compress
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes"
+)
+
+var storeTests = []struct {
+	Value      []byte
+	Compressed bool
+}{
+	{Value: []byte("")},            // 0
+	{Value: []byte("short value")}, // 1
+	{Value: []byte(strings.Repeat(`{"key":"value"}`, 100)), Compressed: true}, // 2
+	{Value: []byte(strings.Repeat("A", 4096)), Compressed: true},              // 3
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	mem := &kes.MemKeyStore{}
+	store := NewStore(mem)
+
+	for i, test := range storeTests {
+		name := "key-" + string(rune('a'+i))
+		if err := store.Create(ctx, name, test.Value); err != nil {
+			t.Fatalf("Test %d: failed to create key: %v", i, err)
+		}
+
+		raw, err := mem.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Test %d: failed to get key: %v", i, err)
+		}
+		if compressed := bytes.HasPrefix(raw, []byte(prefix)); compressed != test.Compressed {
+			t.Fatalf("Test %d: invalid compression: got '%v' - want '%v'", i, compressed, test.Compressed)
+		}
+		if test.Compressed && len(raw) >= len(test.Value) {
+			t.Fatalf("Test %d: compressed value is not smaller: got '%d' - want < '%d'", i, len(raw), len(test.Value))
+		}
+
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Test %d: failed to get key: %v", i, err)
+		}
+		if !bytes.Equal(value, test.Value) {
+			t.Fatalf("Test %d: invalid value: got '%s' - want '%s'", i, value, test.Value)
+		}
+	}
+}
+
+func TestStoreUncompressed(t *testing.T) {
+	ctx := context.Background()
+	mem := &kes.MemKeyStore{}
+	store := NewStore(mem)
+
+	value := []byte(strings.Repeat("A", 4096))
+	if err := mem.Create(ctx, "key", value); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	got, err := store.Get(ctx, "key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !bytes.Equal(got, value) {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", got, value)
+	}
+
+	if err = mem.Create(ctx, "invalid", []byte(prefix+"not base64!")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = store.Get(ctx, "invalid"); err == nil {
+		t.Fatal("Get succeeded for invalid compressed value")
+	}
+}
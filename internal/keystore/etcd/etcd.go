@@ -0,0 +1,250 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package etcd implements a key store that stores keys
+// as key-value pairs on an etcd v3 cluster.
+package etcd
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// DefaultLeaseTTL is the TTL of the health check lease
+// if Config.LeaseTTL is not set.
+const DefaultLeaseTTL = 10 * time.Second
+
+// Config is a structure containing configuration
+// options for connecting to an etcd cluster.
+type Config struct {
+	// Endpoints is a list of etcd cluster endpoints.
+	Endpoints []string
+
+	// Username and Password are used to authenticate
+	// to etcd, if Username is not empty.
+	Username string
+	Password string
+
+	// TLS is the TLS configuration used to connect
+	// to etcd. If nil, plain TCP connections are
+	// used.
+	TLS *tls.Config
+
+	// Prefix is an optional prefix added to the name
+	// of each key - e.g. "kes/".
+	Prefix string
+
+	// LeaseTTL is the TTL of the lease the Store keeps
+	// alive to check the health of the etcd cluster.
+	// If the lease expires, the Store considers etcd
+	// unreachable until a new lease has been granted.
+	// If <= 0, DefaultLeaseTTL is used.
+	LeaseTTL time.Duration
+}
+
+// Connect connects to the etcd cluster and returns a Store
+// that stores keys as key-value pairs on etcd.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("etcd: no endpoints specified")
+	}
+
+	ttl := config.LeaseTTL
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   config.Endpoints,
+		Username:    config.Username,
+		Password:    config.Password,
+		TLS:         config.TLS,
+		DialTimeout: 10 * time.Second,
+		Logger:      zap.NewNop(),
+		Context:     context.Background(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("etcd: failed to connect to '%s': %v", strings.Join(config.Endpoints, ","), err)
+	}
+
+	lease, err := client.Grant(ctx, int64(max(ttl/time.Second, 1)))
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("etcd: failed to grant lease: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Store{
+		client:    client,
+		endpoints: config.Endpoints,
+		prefix:    config.Prefix,
+		ttl:       ttl,
+		stop:      cancel,
+	}
+	s.lease.Store(int64(lease.ID))
+	go s.keepAlive(ctx, lease.ID)
+	return s, nil
+}
+
+// Store is a key store that stores keys as key-value
+// pairs on an etcd cluster.
+type Store struct {
+	client    *clientv3.Client
+	endpoints []string
+	prefix    string
+	ttl       time.Duration
+
+	lease atomic.Int64 // clientv3.LeaseID, zero if no lease is alive
+	stop  context.CancelFunc
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// String returns a string representation of the Store.
+func (s *Store) String() string { return "etcd: " + strings.Join(s.endpoints, ",") }
+
+// Status returns the current state of the etcd cluster.
+// In particular, whether it is reachable and the network
+// latency.
+//
+// The etcd cluster is considered unreachable if the lease
+// kept alive by the Store has expired. This happens if the
+// Store could not reach a quorum of the cluster within the
+// lease TTL.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	lease := clientv3.LeaseID(s.lease.Load())
+	if lease == clientv3.NoLease {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: errors.New("etcd: health check lease expired")}
+	}
+
+	start := time.Now()
+	resp, err := s.client.TimeToLive(ctx, lease)
+	if err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: fmt.Errorf("etcd: %v", err)}
+	}
+	if resp.TTL <= 0 {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: errors.New("etcd: health check lease expired")}
+	}
+	return kes.KeyStoreState{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create stores the key under the given name if and only if
+// no key with this name exists. Otherwise, it returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	key := s.prefix + name
+	resp, err := s.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.CreateRevision(key), "=", 0)).
+		Then(clientv3.OpPut(key, string(value))).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("etcd: failed to create key '%s': %v", name, err)
+	}
+	if !resp.Succeeded {
+		return kesdk.ErrKeyExists
+	}
+	return nil
+}
+
+// Get returns the key with the given name. If no such
+// key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.Get(ctx, s.prefix+name)
+	if err != nil {
+		return nil, fmt.Errorf("etcd: failed to fetch key '%s': %v", name, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, kesdk.ErrKeyNotFound
+	}
+	return resp.Kvs[0].Value, nil
+}
+
+// Delete deletes the key with the given name. If no such
+// key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	resp, err := s.client.Delete(ctx, s.prefix+name)
+	if err != nil {
+		return fmt.Errorf("etcd: failed to delete key '%s': %v", name, err)
+	}
+	if resp.Deleted == 0 {
+		return kesdk.ErrKeyNotFound
+	}
+	return nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	resp, err := s.client.Get(ctx, s.prefix+prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly())
+	if err != nil {
+		return nil, "", fmt.Errorf("etcd: failed to list keys: %v", err)
+	}
+
+	names := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		names = append(names, strings.TrimPrefix(string(kv.Key), s.prefix))
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close revokes the health check lease and closes the
+// connection to the etcd cluster.
+func (s *Store) Close() error {
+	s.stop()
+	if lease := clientv3.LeaseID(s.lease.Swap(0)); lease != clientv3.NoLease {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.client.Revoke(ctx, lease)
+		cancel()
+	}
+	return s.client.Close()
+}
+
+// keepAlive keeps the health check lease alive until ctx
+// is canceled. Once the lease expires, it keeps trying to
+// grant a new one.
+func (s *Store) keepAlive(ctx context.Context, lease clientv3.LeaseID) {
+	for {
+		if lease != clientv3.NoLease {
+			if ch, err := s.client.KeepAlive(ctx, lease); err == nil {
+				for range ch {
+				}
+			}
+			s.lease.CompareAndSwap(int64(lease), 0)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.ttl / 2):
+		}
+
+		resp, err := s.client.Grant(ctx, int64(max(s.ttl/time.Second, 1)))
+		if err != nil {
+			lease = clientv3.NoLease
+			continue
+		}
+		lease = resp.ID
+		s.lease.Store(int64(lease))
+	}
+}
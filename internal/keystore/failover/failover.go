@@ -0,0 +1,357 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package failover implements a keystore that fails over
+// to a secondary keystore when the primary keystore is
+// not reachable.
+package failover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Config is a structure containing the failover configuration.
+type Config struct {
+	// Threshold is the number of consecutive requests to the
+	// primary keystore that have to fail because it is not
+	// reachable before the Store fails over. If <= 1, the
+	// Store fails over on the first such request.
+	Threshold int
+
+	// Interval is the time between two health checks of the
+	// primary keystore. Once it is reachable again, pending
+	// writes are replayed to it in the background. If <= 0,
+	// defaults to 5s.
+	Interval time.Duration
+
+	// ErrorLog is an optional logger for failed mirror writes
+	// and conflicts between the primary and secondary keystore.
+	// If nil, slog.Default is used.
+	ErrorLog *slog.Logger
+}
+
+// ErrConflict is returned when a key created while failed over
+// exists at the primary keystore with a different value.
+var ErrConflict = errors.New("failover: primary keystore contains a different key with the same name")
+
+// NewStore returns a new Store that uses primary as long
+// as it is reachable and fails over to secondary otherwise.
+//
+// Close the Store to stop its background health checks.
+func NewStore(primary, secondary kes.KeyStore, config *Config) *Store {
+	threshold, interval := config.Threshold, config.Interval
+	if threshold < 1 {
+		threshold = 1
+	}
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Store{
+		primary:   primary,
+		secondary: secondary,
+		threshold: int64(threshold),
+		stop:      cancel,
+	}
+	if config.ErrorLog != nil {
+		s.log.Store(config.ErrorLog)
+	} else {
+		s.log.Store(slog.Default())
+	}
+	go s.run(ctx, interval)
+	return s
+}
+
+// Store is a keystore that consists of a primary and a
+// secondary keystore.
+//
+// As long as the primary keystore is reachable, Store
+// forwards all requests to it. Further, it mirrors all
+// writes to the secondary keystore on a best-effort basis.
+// Hence, the secondary keystore should either be a replica
+// of the primary or contain the same keys.
+//
+// Once the primary keystore becomes unreachable for several
+// consecutive requests, Store fails over to the secondary
+// keystore. It records all writes that
+// happen while failed over and replays them to the primary
+// keystore once it is reachable again. Store switches back to
+// the primary keystore once all writes have been replayed.
+// If a key created while failed over exists at the primary
+// keystore with a different value, Store stays failed over
+// and logs the conflict since it cannot be resolved without
+// losing one of the keys.
+type Store struct {
+	primary   kes.KeyStore
+	secondary kes.KeyStore
+
+	threshold  int64        // Consecutive failures before failing over
+	failures   atomic.Int64 // Consecutive requests the primary keystore was unreachable
+	failedOver atomic.Bool
+	stop       func() // Stops the health checks
+	log        atomic.Pointer[slog.Logger]
+
+	lock     sync.Mutex // Protects the journal and serializes replays
+	journal  []entry    // Writes to replay to the primary keystore
+	conflict string     // Name of the last conflicting key that has been logged
+}
+
+// entry is a write operation recorded while failed over.
+type entry struct {
+	Name   string
+	Value  []byte
+	Delete bool
+}
+
+func (s *Store) String() string {
+	return "Failover: " + describe(s.primary) + " | " + describe(s.secondary)
+}
+
+// FailedOver reports whether the Store currently uses
+// the secondary keystore.
+func (s *Store) FailedOver() bool { return s.failedOver.Load() }
+
+// Failures returns the number of consecutive requests for
+// which the primary keystore has not been reachable.
+func (s *Store) Failures() int { return int(s.failures.Load()) }
+
+// SetLog sets the logger used to report failed mirror writes
+// and conflicts between the primary and secondary keystore.
+func (s *Store) SetLog(log *slog.Logger) { s.log.Store(log) }
+
+// Pending returns the number of writes that have not been
+// replayed to the primary keystore yet.
+func (s *Store) Pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.journal)
+}
+
+// Status returns the current state of the Store.
+//
+// If the primary keystore is reachable, Status replays all
+// pending writes to it and switches back from the secondary
+// keystore, if the Store has failed over. Otherwise, it returns
+// the state of the secondary keystore once the Store has failed
+// over and the primary keystore's error before.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	state, err := s.primary.Status(ctx)
+	if s.observe(err) {
+		return s.secondary.Status(ctx)
+	}
+	if err == nil && s.failedOver.Load() {
+		if err = s.replay(ctx); err != nil {
+			return s.secondary.Status(ctx)
+		}
+	}
+	return state, err
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if !s.failedOver.Load() {
+		err := s.primary.Create(ctx, name, value)
+		if !s.observe(err) {
+			if err == nil {
+				if err = s.secondary.Create(ctx, name, value); err != nil { // Best-effort mirroring
+					s.log.Load().Warn(fmt.Sprintf("failover: failed to create key '%s' at secondary keystore: %v", name, err))
+				}
+				return nil
+			}
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.secondary.Create(ctx, name, value); err != nil {
+		return err
+	}
+	s.journal = append(s.journal, entry{Name: name, Value: value})
+	s.failedOver.Store(true) // The Store may have switched back while waiting for the lock
+	return nil
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if !s.failedOver.Load() {
+		err := s.primary.Delete(ctx, name)
+		if !s.observe(err) {
+			if err == nil {
+				if err = s.secondary.Delete(ctx, name); err != nil { // Best-effort mirroring
+					s.log.Load().Warn(fmt.Sprintf("failover: failed to delete key '%s' at secondary keystore: %v", name, err))
+				}
+				return nil
+			}
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.secondary.Delete(ctx, name); err != nil {
+		return err
+	}
+	s.journal = append(s.journal, entry{Name: name, Delete: true})
+	s.failedOver.Store(true) // The Store may have switched back while waiting for the lock
+	return nil
+}
+
+// Get returns the value associated with the given name. If
+// no such entry exists, Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if !s.failedOver.Load() {
+		value, err := s.primary.Get(ctx, name)
+		if !s.observe(err) {
+			return value, err
+		}
+	}
+	return s.secondary.Get(ctx, name)
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if !s.failedOver.Load() {
+		names, next, err := s.primary.List(ctx, prefix, n)
+		if !s.observe(err) {
+			return names, next, err
+		}
+	}
+	return s.secondary.List(ctx, prefix, n)
+}
+
+// Close stops the health checks and closes the primary
+// and secondary keystore.
+func (s *Store) Close() error {
+	s.stop()
+	return errors.Join(s.primary.Close(), s.secondary.Close())
+}
+
+// observe records the result of a request to the primary
+// keystore and reports whether the request has to be served
+// by the secondary keystore. The Store fails over once the
+// primary keystore has not been reachable for threshold
+// consecutive requests. Any other result resets the count.
+func (s *Store) observe(err error) bool {
+	if err == nil || !keystore.IsTemporary(err) {
+		s.failures.Store(0)
+		return false
+	}
+	if s.failures.Add(1) < s.threshold && !s.failedOver.Load() {
+		return false
+	}
+	s.failedOver.Store(true)
+	return true
+}
+
+// run checks the health of the primary keystore periodically
+// until ctx is canceled. Checking the health fails over or
+// replays pending writes and switches back, if necessary.
+func (s *Store) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.Status(ctx)
+		}
+	}
+}
+
+// replay writes all recorded writes to the primary keystore.
+// Once all writes have been replayed, the Store switches
+// back to the primary keystore.
+//
+// Deleting a non-existing key on the primary keystore is
+// not considered an error since the primary keystore may
+// have seen the write before becoming unreachable. The same
+// applies to creating an existing key with the same value.
+// If the values differ, replay returns ErrConflict and the
+// Store remains failed over.
+func (s *Store) replay(ctx context.Context) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for len(s.journal) > 0 {
+		var (
+			e   = s.journal[0]
+			err error
+		)
+		if e.Delete {
+			err = s.primary.Delete(ctx, e.Name)
+			if errors.Is(err, kesdk.ErrKeyNotFound) {
+				err = nil
+			}
+		} else {
+			err = s.primary.Create(ctx, e.Name, e.Value)
+			if errors.Is(err, kesdk.ErrKeyExists) {
+				err = s.compare(ctx, e)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		s.journal[0] = entry{}
+		s.journal = s.journal[1:]
+	}
+	s.journal = nil
+	s.conflict = ""
+	s.failedOver.Store(false)
+	return nil
+}
+
+// compare returns ErrConflict if the value of the primary
+// keystore's key differs from the value of the recorded write.
+// It logs each conflicting key once.
+func (s *Store) compare(ctx context.Context, e entry) error {
+	value, err := s.primary.Get(ctx, e.Name)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(value, e.Value) {
+		return nil
+	}
+
+	if s.conflict != e.Name {
+		s.conflict = e.Name
+		s.log.Load().Error(fmt.Sprintf("failover: key '%s' exists at the primary keystore with a different value: remaining failed over until resolved", e.Name))
+	}
+	return fmt.Errorf("%w: '%s'", ErrConflict, e.Name)
+}
+
+func describe(store kes.KeyStore) string {
+	if s, ok := store.(interface{ String() string }); ok {
+		return s.String()
+	}
+	return "unknown"
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package failover
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStoreFailover(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	secondary := &kes.MemKeyStore{}
+	store := NewStore(primary, secondary, &Config{})
+	defer store.Close()
+
+	if err := store.Create(ctx, "key-1", []byte("value-1")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := secondary.Get(ctx, "key-1"); err != nil {
+		t.Fatalf("Key has not been mirrored to secondary keystore: %v", err)
+	}
+
+	primary.Offline.Store(true)
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Failed to fail over: %v", err)
+	}
+	if !store.FailedOver() {
+		t.Fatal("Store has not failed over")
+	}
+	if _, err := store.Get(ctx, "key-1"); err != nil {
+		t.Fatalf("Failed to read key from secondary keystore: %v", err)
+	}
+	if err := store.Create(ctx, "key-2", []byte("value-2")); err != nil {
+		t.Fatalf("Failed to create key while failed over: %v", err)
+	}
+	if err := store.Delete(ctx, "key-1"); err != nil {
+		t.Fatalf("Failed to delete key while failed over: %v", err)
+	}
+	if n := store.Pending(); n != 2 {
+		t.Fatalf("Invalid number of pending writes: got '%d' - want '%d'", n, 2)
+	}
+
+	primary.Offline.Store(false)
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Failed to switch back to primary keystore: %v", err)
+	}
+	if store.FailedOver() {
+		t.Fatal("Store has not switched back to primary keystore")
+	}
+	if n := store.Pending(); n != 0 {
+		t.Fatalf("Invalid number of pending writes: got '%d' - want '%d'", n, 0)
+	}
+	if _, err := primary.Get(ctx, "key-1"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Delete has not been replayed: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if value, err := primary.Get(ctx, "key-2"); err != nil || string(value) != "value-2" {
+		t.Fatalf("Create has not been replayed: got '%s' - want '%s'", value, "value-2")
+	}
+}
+
+func TestStoreFailoverConflict(t *testing.T) {
+	ctx := context.Background()
+
+	var log bytes.Buffer
+	primary := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	store := NewStore(primary, &kes.MemKeyStore{}, &Config{
+		ErrorLog: slog.New(slog.NewTextHandler(&log, nil)),
+	})
+	defer store.Close()
+
+	primary.Offline.Store(true)
+	if err := store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key while failed over: %v", err)
+	}
+
+	// Another KES server creates a different key with the same
+	// name at the primary keystore while this one is failed over.
+	if err := primary.KeyStore.Create(ctx, "key", []byte("other-value")); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.Offline.Store(false)
+	if err := store.replay(ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrConflict)
+	}
+	if !store.FailedOver() {
+		t.Fatal("Store has switched back despite a conflict")
+	}
+	if n := store.Pending(); n != 1 {
+		t.Fatalf("Invalid number of pending writes: got '%d' - want '%d'", n, 1)
+	}
+	if !strings.Contains(log.String(), "key 'key' exists at the primary keystore with a different value") {
+		t.Fatalf("Conflict has not been logged: %s", log.String())
+	}
+
+	if err := primary.KeyStore.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Failed to switch back to primary keystore: %v", err)
+	}
+	if store.FailedOver() {
+		t.Fatal("Store has not switched back to primary keystore")
+	}
+	if value, err := primary.Get(ctx, "key"); err != nil || string(value) != "value" {
+		t.Fatalf("Create has not been replayed: got '%s' - want '%s'", value, "value")
+	}
+}
+
+func TestStoreFailoverError(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	store := NewStore(primary, &kes.MemKeyStore{}, &Config{})
+	defer store.Close()
+
+	if err := store.Create(ctx, "key", nil); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := store.Create(ctx, "key", nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	if store.FailedOver() {
+		t.Fatal("Store has failed over although primary keystore is reachable")
+	}
+}
+
+func TestStoreFailoverThreshold(t *testing.T) {
+	const Threshold = 3
+	ctx := context.Background()
+
+	primary := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	store := NewStore(primary, &kes.MemKeyStore{}, &Config{
+		Threshold: Threshold,
+		Interval:  time.Hour,
+	})
+	defer store.Close()
+
+	primary.Offline.Store(true)
+	for i := 1; i < Threshold; i++ {
+		if _, err := store.Get(ctx, "key"); !keystore.IsTemporary(err) {
+			t.Fatalf("Request %d: got '%v' - want unreachable error", i, err)
+		}
+		if store.FailedOver() {
+			t.Fatalf("Store has failed over after %d of %d failures", i, Threshold)
+		}
+	}
+	if n := store.Failures(); n != Threshold-1 {
+		t.Fatalf("Invalid number of failures: got '%d' - want '%d'", n, Threshold-1)
+	}
+
+	primary.Offline.Store(false)
+	if _, err := store.Status(ctx); err != nil {
+		t.Fatalf("Failed to fetch status: %v", err)
+	}
+	if n := store.Failures(); n != 0 {
+		t.Fatalf("Failures have not been reset: got '%d' - want '%d'", n, 0)
+	}
+
+	primary.Offline.Store(true)
+	for i := 0; i < Threshold; i++ {
+		store.Get(ctx, "key")
+	}
+	if !store.FailedOver() {
+		t.Fatalf("Store has not failed over after %d failures", Threshold)
+	}
+}
+
+func TestStoreFailoverHealthCheck(t *testing.T) {
+	ctx := context.Background()
+
+	primary := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	store := NewStore(primary, &kes.MemKeyStore{}, &Config{
+		Interval: 10 * time.Millisecond,
+	})
+	defer store.Close()
+
+	primary.Offline.Store(true)
+	if err := store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key while failed over: %v", err)
+	}
+	primary.Offline.Store(false)
+
+	// Pending writes are replayed by the background
+	// health checks without any further requests.
+	deadline := time.Now().Add(10 * time.Second)
+	for store.FailedOver() {
+		if time.Now().After(deadline) {
+			t.Fatal("Store has not switched back to primary keystore")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if value, err := primary.Get(ctx, "key"); err != nil || string(value) != "value" {
+		t.Fatalf("Create has not been replayed: got '%s' - want '%s'", value, "value")
+	}
+}
+
+// offlineStore is a KeyStore that returns an unreachable
+// error on every request while offline.
+type offlineStore struct {
+	kes.KeyStore
+	Offline atomic.Bool
+}
+
+func (s *offlineStore) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	if s.Offline.Load() {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Status(ctx)
+}
+
+func (s *offlineStore) Create(ctx context.Context, name string, value []byte) error {
+	if s.Offline.Load() {
+		return &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Create(ctx, name, value)
+}
+
+func (s *offlineStore) Delete(ctx context.Context, name string) error {
+	if s.Offline.Load() {
+		return &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Delete(ctx, name)
+}
+
+func (s *offlineStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.Offline.Load() {
+		return nil, &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Get(ctx, name)
+}
+
+func (s *offlineStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if s.Offline.Load() {
+		return nil, "", &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.List(ctx, prefix, n)
+}
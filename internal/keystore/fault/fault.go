@@ -0,0 +1,156 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package fault implements a keystore that injects latency
+// and errors into requests to another keystore.
+//
+// It is meant for testing how a KES server behaves when
+// its keystore is slow or unreliable, e.g. whether retries
+// and failover work as expected. It must not be used in
+// production.
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+)
+
+// ErrInjected is the error returned by a Store for
+// requests that fail due to an injected fault. It is
+// always wrapped in a keystore.ErrUnreachable.
+var ErrInjected = errors.New("fault: injected failure")
+
+// Config is a structure containing the faults injected
+// into requests to a keystore.
+type Config struct {
+	// Latency is the delay added to every request.
+	Latency time.Duration
+
+	// Jitter is the max. random delay added to every
+	// request in addition to Latency.
+	Jitter time.Duration
+
+	// ErrorRate is the probability that a request fails.
+	// It must be between 0 and 1. If 0, no errors are
+	// injected. If 1, all requests fail.
+	ErrorRate float64
+}
+
+// NewStore returns a new Store that injects faults into
+// requests to the given keystore based on the config.
+func NewStore(store kes.KeyStore, config *Config) (*Store, error) {
+	if config.Latency < 0 || config.Jitter < 0 {
+		return nil, errors.New("fault: latency and jitter must not be negative")
+	}
+	if config.ErrorRate < 0 || config.ErrorRate > 1 {
+		return nil, fmt.Errorf("fault: invalid error rate '%v'", config.ErrorRate)
+	}
+	return &Store{
+		store:  store,
+		config: *config,
+	}, nil
+}
+
+// Store is a keystore that injects latency and errors
+// into requests to another keystore.
+//
+// Requests that fail due to an injected fault are not
+// sent to the underlying keystore and fail with an
+// unreachable error wrapping ErrInjected.
+type Store struct {
+	store  kes.KeyStore
+	config Config
+}
+
+func (s *Store) String() string {
+	if str, ok := s.store.(fmt.Stringer); ok {
+		return str.String() + " (fault injection)"
+	}
+	return fmt.Sprintf("%T (fault injection)", s.store)
+}
+
+// Status returns the current state of the underlying
+// keystore or an injected fault.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	if err := s.inject(ctx); err != nil {
+		return kes.KeyStoreState{}, err
+	}
+	return s.store.Status(ctx)
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if err := s.inject(ctx); err != nil {
+		return err
+	}
+	return s.store.Create(ctx, name, value)
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := s.inject(ctx); err != nil {
+		return err
+	}
+	return s.store.Delete(ctx, name)
+}
+
+// Get returns the value associated with the given name. If
+// no such entry exists, Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := s.inject(ctx); err != nil {
+		return nil, err
+	}
+	return s.store.Get(ctx, name)
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if err := s.inject(ctx); err != nil {
+		return nil, "", err
+	}
+	return s.store.List(ctx, prefix, n)
+}
+
+// Close closes the underlying keystore.
+func (s *Store) Close() error { return s.store.Close() }
+
+// inject waits for the configured latency and returns an
+// injected error with the configured probability.
+func (s *Store) inject(ctx context.Context) error {
+	delay := s.config.Latency
+	if s.config.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.config.Jitter)))
+	}
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return &keystore.ErrUnreachable{Err: context.Cause(ctx)}
+		case <-timer.C:
+		}
+	}
+	if s.config.ErrorRate > 0 && rand.Float64() < s.config.ErrorRate {
+		return &keystore.ErrUnreachable{Err: ErrInjected}
+	}
+	return nil
+}
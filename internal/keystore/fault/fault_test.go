@@ -0,0 +1,82 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fault
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+)
+
+func TestStoreFault(t *testing.T) {
+	ctx := context.Background()
+
+	store, err := NewStore(&kes.MemKeyStore{}, &Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	store.config.ErrorRate = 1
+	if _, err = store.Get(ctx, "key"); !isInjected(err) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrInjected)
+	}
+	if _, err = store.Status(ctx); !isInjected(err) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, ErrInjected)
+	}
+
+	store.config.ErrorRate = 0
+	store.config.Latency = 50 * time.Millisecond
+	start := time.Now()
+	if _, err = store.Get(ctx, "key"); err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+	if d := time.Since(start); d < store.config.Latency {
+		t.Fatalf("Invalid latency: got '%v' - want at least '%v'", d, store.config.Latency)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Millisecond)
+	defer cancel()
+	if _, err = store.Get(ctx, "key"); !keystore.IsTemporary(err) {
+		t.Fatalf("Invalid error: got '%v' - want temporary error", err)
+	}
+}
+
+func isInjected(err error) bool {
+	u, ok := keystore.IsUnreachable(err)
+	return ok && errors.Is(u.Err, ErrInjected)
+}
+
+var newStoreTests = []struct {
+	Config     Config
+	ShouldFail bool
+}{
+	{Config: Config{}}, // 0
+	{Config: Config{Latency: time.Second, Jitter: time.Second}}, // 1
+	{Config: Config{ErrorRate: 0.5}},                            // 2
+	{Config: Config{ErrorRate: 1}},                              // 3
+	{Config: Config{ErrorRate: 1.5}, ShouldFail: true},          // 4
+	{Config: Config{ErrorRate: -0.1}, ShouldFail: true},         // 5
+	{Config: Config{Latency: -time.Second}, ShouldFail: true},   // 6
+	{Config: Config{Jitter: -time.Second}, ShouldFail: true},    // 7
+}
+
+func TestNewStore(t *testing.T) {
+	for i, test := range newStoreTests {
+		_, err := NewStore(&kes.MemKeyStore{}, &test.Config)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+	}
+}
@@ -52,10 +52,34 @@ type Config struct {
 	// the application is assigned to.
 	GroupID string
 
+	// GroupName is the name of the Fortanix SDKMS group newly created
+	// keys will belong to. It is resolved to a group ID when connecting.
+	// GroupName and GroupID are mutually exclusive.
+	GroupName string
+
 	// APIKey is the application's Fortanix SDKMS API key used to authenticate
 	// operations. It is sent on each request as part of the request headers.
+	//
+	// APIKey is mutually exclusive with the certificate and JWT based
+	// authentication methods.
 	APIKey APIKey
 
+	// AppID is the UUID of the Fortanix SDKMS application. It is required
+	// when authenticating via a client certificate or a JWT.
+	AppID string
+
+	// CertPath and KeyPath are paths to a TLS client certificate and
+	// private key. If set, the application authenticates via mTLS to
+	// obtain short-lived session tokens.
+	CertPath string
+	KeyPath  string
+
+	// TokenFile is a path to a file containing a JWT issued by an OAuth
+	// or OIDC provider trusted by the Fortanix SDKMS application. The file
+	// is re-read whenever a new session token is requested such that
+	// the JWT can be rotated externally.
+	TokenFile string
+
 	// CAPath is an optional path to a CA certificate or directory
 	// containing CA certificates.
 	//
@@ -66,8 +90,9 @@ type Config struct {
 
 // Store is a Fortanix SDKMS secret store.
 type Store struct {
-	config Config
-	client xhttp.Retry
+	config  Config
+	client  xhttp.Retry
+	session session
 }
 
 // Connect establishes and returns a Store to a Fortanix SDKMS server
@@ -76,16 +101,35 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 	if config.Endpoint == "" {
 		return nil, errors.New("fortanix: endpoint is empty")
 	}
+	if config.GroupID != "" && config.GroupName != "" {
+		return nil, errors.New("fortanix: group ID and group name are mutually exclusive")
+	}
+	useCert, useJWT := config.CertPath != "" || config.KeyPath != "", config.TokenFile != ""
+	switch {
+	case config.APIKey != "" && (useCert || useJWT):
+		return nil, errors.New("fortanix: more than one authentication method specified")
+	case useCert && useJWT:
+		return nil, errors.New("fortanix: more than one authentication method specified")
+	case config.APIKey == "" && !useCert && !useJWT:
+		return nil, errors.New("fortanix: no authentication method specified")
+	case (useCert || useJWT) && config.AppID == "":
+		return nil, errors.New("fortanix: no application ID specified")
+	}
 
-	var tlsConfig *tls.Config
+	tlsConfig := &tls.Config{}
 	if config.CAPath != "" {
 		rootCAs, err := loadCustomCAs(config.CAPath)
 		if err != nil {
 			return nil, err
 		}
-		tlsConfig = &tls.Config{
-			RootCAs: rootCAs,
+		tlsConfig.RootCAs = rootCAs
+	}
+	if useCert {
+		cert, err := tls.LoadX509KeyPair(config.CertPath, config.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("fortanix: failed to load client certificate: %v", err)
 		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
 	}
 
 	client := xhttp.Retry{
@@ -112,7 +156,9 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", config.APIKey.String())
+	if config.APIKey != "" {
+		req.Header.Set("Authorization", config.APIKey.String())
+	}
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -125,57 +171,51 @@ func Connect(ctx context.Context, config *Config) (*Store, error) {
 		return nil, fmt.Errorf("fortanix: failed to connect to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
 	}
 
-	// Check if the authentication credentials are valid
-	url = endpoint(config.Endpoint, "/sys/v1/session/auth")
-	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
-	if err != nil {
-		return nil, err
+	s := &Store{
+		config: *config,
+		client: client,
 	}
-	req.Header.Set("Authorization", config.APIKey.String())
 
-	resp, err = client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	if resp.StatusCode != http.StatusOK {
-		if err := parseErrorResponse(resp); err != nil {
+	// Check if the authentication credentials are valid
+	if config.APIKey != "" {
+		token, _, err := s.authenticate(ctx, config.APIKey.String())
+		if err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
-	}
-	type Response struct {
-		Token string `json:"access_token"` // Raw bearer token - clients have to set 'Authorization: Bearer <token>'
-	}
-	var response Response
-	if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil {
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %v", config.Endpoint, err)
-	}
 
-	// Now we revoke the session we just created to cleanup any
-	// session credentials we just created. This is not strictly
-	// necessary but allows Fortanix SDKMS to garbage-collect
-	// unused credentials early.
-	url = endpoint(config.Endpoint, "/sys/v1/session/terminate")
-	req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Authorization", "Bearer "+response.Token)
+		// Now we revoke the session we just created to cleanup any
+		// session credentials we just created. This is not strictly
+		// necessary but allows Fortanix SDKMS to garbage-collect
+		// unused credentials early.
+		url = endpoint(config.Endpoint, "/sys/v1/session/terminate")
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
 
-	resp, err = client.Do(req)
-	if err != nil {
-		return nil, err
+		resp, err = client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusNoContent {
+			if err := parseErrorResponse(resp); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
+		}
+	} else {
+		if _, err = s.session.Token(ctx, s.newSession); err != nil {
+			return nil, err
+		}
 	}
-	if resp.StatusCode != http.StatusNoContent {
-		if err := parseErrorResponse(resp); err != nil {
+
+	if config.GroupName != "" {
+		if s.config.GroupID, err = s.lookupGroup(ctx, config.GroupName); err != nil {
 			return nil, err
 		}
-		return nil, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", config.Endpoint, resp.Status, resp.StatusCode)
 	}
-	return &Store{
-		config: *config,
-		client: client,
-	}, nil
+	return s, nil
 }
 
 func (s *Store) String() string { return "Fortanix SDKMS: " + s.config.Endpoint }
@@ -236,10 +276,9 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 	if err != nil {
 		return fmt.Errorf("fortanix: failed to create key '%s': %v", name, err)
 	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if err != nil {
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return err
@@ -289,10 +328,9 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	if err != nil {
 		return fmt.Errorf("fortanix: failed to delete '%s': %v", name, err)
 	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -324,9 +362,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
-
-	resp, err = s.client.Do(req)
+	resp, err = s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -363,10 +399,9 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("fortanix: failed to fetch '%s': %v", name, err)
 	}
-	req.Header.Set("Authorization", s.config.APIKey.String())
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
@@ -428,9 +463,7 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 		if err != nil {
 			return nil, "", fmt.Errorf("fortanix: failed to list keys: %v", err)
 		}
-		req.Header.Set("Authorization", s.config.APIKey.String())
-
-		resp, err := s.client.Do(req)
+		resp, err := s.do(req)
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return nil, "", err
 		}
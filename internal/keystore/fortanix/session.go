@@ -0,0 +1,197 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package fortanix
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+)
+
+// session is a Fortanix SDKMS session token cache.
+//
+// Applications that authenticate via a client certificate
+// or a JWT cannot send their credentials on each request.
+// Instead, they obtain short-lived session tokens.
+type session struct {
+	lock   sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Token returns the current session token. If there is no
+// token yet or the token is about to expire, Token obtains
+// a new one using the given function.
+func (s *session) Token(ctx context.Context, renew func(context.Context) (string, time.Time, error)) (string, error) {
+	// Renew tokens a bit before they actually expire
+	// to not fail requests that are still in flight.
+	const RenewWindow = 30 * time.Second
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token != "" && time.Until(s.expiry) > RenewWindow {
+		return s.token, nil
+	}
+	token, expiry, err := renew(ctx)
+	if err != nil {
+		return "", err
+	}
+	s.token, s.expiry = token, expiry
+	return s.token, nil
+}
+
+// Invalidate discards the given token if it is still the
+// current session token. The next call to Token obtains a
+// new one.
+func (s *session) Invalidate(token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token == token {
+		s.token, s.expiry = "", time.Time{}
+	}
+}
+
+// do sends the request authenticated either with the API key
+// or with a session token.
+//
+// If the Fortanix SDKMS rejects a session token, e.g. since it
+// got revoked, do obtains a new session token and sends the
+// request once more.
+func (s *Store) do(req *http.Request) (*http.Response, error) {
+	if s.config.APIKey != "" {
+		req.Header.Set("Authorization", s.config.APIKey.String())
+		return s.client.Do(req)
+	}
+
+	token, err := s.session.Token(req.Context(), s.newSession)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	s.session.Invalidate(token)
+
+	if req.Body != nil {
+		seeker, ok := req.Body.(io.Seeker)
+		if !ok {
+			return resp, nil
+		}
+		if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+			return resp, nil
+		}
+	}
+	if token, err = s.session.Token(req.Context(), s.newSession); err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	return s.client.Do(req)
+}
+
+// newSession obtains a new session token using the client
+// certificate or JWT of the Fortanix SDKMS application.
+func (s *Store) newSession(ctx context.Context) (string, time.Time, error) {
+	// Fortanix SDKMS expects the application ID as username
+	// and, if present, the JWT as password. Applications
+	// using a client certificate authenticate via mTLS and
+	// send no password.
+	var credentials string
+	if s.config.TokenFile != "" {
+		jwt, err := os.ReadFile(s.config.TokenFile)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("fortanix: failed to read JWT: %v", err)
+		}
+		credentials = strings.TrimSpace(string(jwt))
+	}
+	authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte(s.config.AppID+":"+credentials))
+	return s.authenticate(ctx, authorization)
+}
+
+// authenticate creates a new Fortanix SDKMS session using the
+// given authorization header value and returns the session
+// token and its expiry.
+func (s *Store) authenticate(ctx context.Context, authorization string) (string, time.Time, error) {
+	url := endpoint(s.config.Endpoint, "/sys/v1/session/auth")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", authorization)
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if err := parseErrorResponse(resp); err != nil {
+			return "", time.Time{}, err
+		}
+		return "", time.Time{}, fmt.Errorf("fortanix: failed to authenticate to '%s': %s (%d)", s.config.Endpoint, resp.Status, resp.StatusCode)
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		Token     string `json:"access_token"` // Raw bearer token - clients have to set 'Authorization: Bearer <token>'
+		ExpiresIn int64  `json:"expires_in"`   // Token lifetime in seconds
+	}
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, 1*mem.MiB)).Decode(&response); err != nil {
+		return "", time.Time{}, fmt.Errorf("fortanix: failed to authenticate to '%s': %v", s.config.Endpoint, err)
+	}
+	return response.Token, start.Add(time.Duration(response.ExpiresIn) * time.Second), nil
+}
+
+// lookupGroup returns the ID of the Fortanix SDKMS group
+// with the given name.
+func (s *Store) lookupGroup(ctx context.Context, name string) (string, error) {
+	url := endpoint(s.config.Endpoint, "/sys/v1/groups")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return "", fmt.Errorf("fortanix: failed to lookup group '%s': %v", name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if err := parseErrorResponse(resp); err != nil {
+			return "", fmt.Errorf("fortanix: failed to lookup group '%s': %v", name, err)
+		}
+		return "", fmt.Errorf("fortanix: failed to lookup group '%s': %s (%d)", name, resp.Status, resp.StatusCode)
+	}
+	defer resp.Body.Close()
+
+	type Response struct {
+		ID   string `json:"group_id"`
+		Name string `json:"name"`
+	}
+	var groups []Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, 10*mem.MB)).Decode(&groups); err != nil {
+		return "", fmt.Errorf("fortanix: failed to lookup group '%s': failed to parse server response: %v", name, err)
+	}
+	for _, group := range groups {
+		if group.Name == name {
+			return group.ID, nil
+		}
+	}
+	return "", fmt.Errorf("fortanix: failed to lookup group '%s': group not found", name)
+}
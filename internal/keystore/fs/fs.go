@@ -8,11 +8,19 @@
 package fs
 
 import (
+	"bytes"
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
@@ -47,6 +55,35 @@ func NewStore(dir string) (*Store, error) {
 	return &Store{dir: dir}, nil
 }
 
+// NewEncryptedStore returns a new Store that reads from and
+// writes to the given directory, like NewStore. However, it
+// encrypts all values with the given 256 bit master key using
+// AES-256-GCM before writing them to disk.
+//
+// The returned Store fails to read unencrypted key files.
+// Existing keys of an unencrypted Store can be migrated
+// to an encrypted Store via 'kes migrate'.
+func NewEncryptedStore(dir string, masterKey []byte) (*Store, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("fs: invalid master key length '%d': must be 32 bytes", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := NewStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	s.aead = aead
+	return s, nil
+}
+
 // Store is a connection to a directory on
 // the filesystem.
 //
@@ -54,10 +91,16 @@ func NewStore(dir string) (*Store, error) {
 // acts as KMS abstraction over a filesystem.
 type Store struct {
 	dir  string
+	aead cipher.AEAD // Optional - if set, values are encrypted at rest
 	lock sync.RWMutex
 }
 
-func (s *Store) String() string { return "Filesystem: " + s.dir }
+func (s *Store) String() string {
+	if s.aead != nil {
+		return "Filesystem: " + s.dir + " (encrypted)"
+	}
+	return "Filesystem: " + s.dir
+}
 
 // Status returns the current state of the Conn.
 //
@@ -77,6 +120,13 @@ func (s *Store) Status(context.Context) (kes.KeyStoreState, error) {
 // the Conn directory if and only if no such file exists.
 //
 // It returns kes.ErrKeyExists if such a file already exists.
+//
+// Create writes the value to a temporary file first and
+// links it to the key file once its content has been synced
+// to disk. Hence, a crash or power loss does not leave a
+// partially written file behind. Linking fails if the key
+// file exists, even if it has been created by another process
+// using the same directory.
 func (s *Store) Create(_ context.Context, name string, value []byte) error {
 	if err := validName(name); err != nil {
 		return err
@@ -84,20 +134,19 @@ func (s *Store) Create(_ context.Context, name string, value []byte) error {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
-	filename := filepath.Join(s.dir, name)
-	switch err := s.create(filename, value); {
-	case errors.Is(err, os.ErrExist):
-		return kesdk.ErrKeyExists
-	case err != nil:
-		os.Remove(filename)
+	record, err := s.encodeRecord(name, value)
+	if err != nil {
 		return err
 	}
-	return nil
+	return s.create(filepath.Join(s.dir, name), record)
 }
 
 // Get reads the content of the named file within the Conn
 // directory. It returns kes.ErrKeyNotFound if no such file
 // exists.
+//
+// It returns an error if the file content does not match
+// its checksum.
 func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
 	const MaxSize = 1 * mem.MiB
 
@@ -116,13 +165,17 @@ func (s *Store) Get(_ context.Context, name string) ([]byte, error) {
 	}
 	defer file.Close()
 
-	value, err := io.ReadAll(mem.LimitReader(file, MaxSize))
+	record, err := io.ReadAll(mem.LimitReader(file, MaxSize+mem.Size(recordHeaderSize)))
 	if err != nil {
 		return nil, err
 	}
 	if err = file.Close(); err != nil {
 		return nil, err
 	}
+	value, err := s.decodeRecord(name, record)
+	if err != nil {
+		return nil, fmt.Errorf("fs: failed to read '%s': %v", name, err)
+	}
 	return value, nil
 }
 
@@ -133,12 +186,16 @@ func (s *Store) Delete(_ context.Context, name string) error {
 	if err := validName(name); err != nil {
 		return err
 	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	switch err := os.Remove(filepath.Join(s.dir, name)); {
 	case errors.Is(err, os.ErrNotExist):
 		return kesdk.ErrKeyNotFound
-	default:
+	case err != nil:
 		return err
 	}
+	return syncDir(s.dir)
 }
 
 // List returns a new Iterator over the names of
@@ -164,6 +221,17 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 	if err != nil {
 		return nil, "", err
 	}
+
+	// Skip temporary files of incomplete writes. Their names
+	// start with a '.' and, therefore, are not valid key names.
+	keys := names[:0]
+	for _, name := range names {
+		if !strings.HasPrefix(name, ".") {
+			keys = append(keys, name)
+		}
+	}
+	names = keys
+
 	select {
 	case <-ctx.Done():
 		if err := ctx.Err(); err != nil {
@@ -178,13 +246,25 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 // Close closes the Store.
 func (s *Store) Close() error { return nil }
 
+// create writes value to a temporary file inside the Store
+// directory and links filename to it once the temporary file
+// has been synced. Unlike a rename, the link does not replace
+// an existing file. Finally, it syncs the directory to persist
+// the link.
+//
+// It returns kes.ErrKeyExists if filename exists.
 func (s *Store) create(filename string, value []byte) error {
-	file, err := os.OpenFile(filename, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	file, err := os.CreateTemp(s.dir, "."+filepath.Base(filename)+".*.tmp")
 	if err != nil {
 		return err
 	}
+	tmpName := file.Name()
+	defer os.Remove(tmpName) // The key file remains linked to the content
 	defer file.Close()
 
+	if err = file.Chmod(0o600); err != nil && runtime.GOOS != "windows" {
+		return err
+	}
 	n, err := file.Write(value)
 	if err != nil {
 		return err
@@ -195,7 +275,120 @@ func (s *Store) create(filename string, value []byte) error {
 	if err = file.Sync(); err != nil {
 		return err
 	}
-	return file.Close()
+	if err = file.Close(); err != nil {
+		return err
+	}
+	if err = os.Link(tmpName, filename); err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return kesdk.ErrKeyExists
+		}
+		return err
+	}
+	return syncDir(s.dir)
+}
+
+// syncDir commits the directory entries of dir, e.g.
+// created, renamed or removed files, to stable storage.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil // Windows does not support syncing directories
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// The record format of key files.
+//
+// A record consists of a header and the value. The header
+// contains a magic prefix, a format version, the record
+// flags and the SHA-256 checksum of the value.
+//
+// Files written by previous versions do not start with the
+// magic prefix. They contain just the raw value.
+const (
+	recordMagic      = "KESFS\x00"
+	recordVersion    = 1
+	recordHeaderSize = len(recordMagic) + 2 + sha256.Size
+)
+
+// Record flags
+const (
+	recordEncrypted = 1 << 0 // The record value is encrypted with the master key
+)
+
+// encodeRecord returns the file content for the given value.
+//
+// If the Store has a master key, the value is encrypted
+// and the key name is bound to the ciphertext such that
+// key files cannot be swapped.
+func (s *Store) encodeRecord(name string, value []byte) ([]byte, error) {
+	var flags byte
+	if s.aead != nil {
+		nonce := make([]byte, s.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, err
+		}
+		value = s.aead.Seal(nonce, nonce, value, []byte(name))
+		flags |= recordEncrypted
+	}
+	checksum := sha256.Sum256(value)
+
+	record := make([]byte, 0, recordHeaderSize+len(value))
+	record = append(record, recordMagic...)
+	record = append(record, recordVersion, flags)
+	record = append(record, checksum[:]...)
+	return append(record, value...), nil
+}
+
+// decodeRecord returns the value stored in the given
+// file content. It returns an error if the value does
+// not match the checksum stored in the record or if
+// it cannot be decrypted.
+func (s *Store) decodeRecord(name string, record []byte) ([]byte, error) {
+	if !bytes.HasPrefix(record, []byte(recordMagic)) {
+		if s.aead != nil {
+			return nil, errors.New("record is not encrypted")
+		}
+		return record, nil // Legacy file without header
+	}
+	if len(record) < recordHeaderSize {
+		return nil, errors.New("record is truncated")
+	}
+	if v := record[len(recordMagic)]; v != recordVersion {
+		return nil, fmt.Errorf("unsupported record version '%d'", v)
+	}
+	flags := record[len(recordMagic)+1]
+
+	checksum, value := record[len(recordMagic)+2:recordHeaderSize], record[recordHeaderSize:]
+	if sum := sha256.Sum256(value); subtle.ConstantTimeCompare(sum[:], checksum) != 1 {
+		return nil, errors.New("record checksum mismatch")
+	}
+
+	switch encrypted := flags&recordEncrypted != 0; {
+	case encrypted && s.aead == nil:
+		return nil, errors.New("record is encrypted but no master key is configured")
+	case !encrypted && s.aead != nil:
+		return nil, errors.New("record is not encrypted")
+	case !encrypted:
+		return value, nil
+	}
+	if len(value) < s.aead.NonceSize() {
+		return nil, errors.New("record is truncated")
+	}
+	nonce, ciphertext := value[:s.aead.NonceSize()], value[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, errors.New("failed to decrypt record: invalid master key or corrupted record")
+	}
+	return plaintext, nil
 }
 
 func validName(name string) error {
@@ -4,7 +4,16 @@
 
 package fs
 
-import "testing"
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	kesdk "github.com/minio/kms-go/kes"
+)
 
 var validNameTests = []struct {
 	Name  string
@@ -31,3 +40,187 @@ func TestValidName(t *testing.T) {
 		}
 	}
 }
+
+func TestStoreCreateGet(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err = store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("my-value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating an existing key should fail with '%v': got '%v'", kesdk.ErrKeyExists, err)
+	}
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+}
+
+func TestStoreGetLegacy(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	// Files written by previous versions contain just the raw value.
+	if err = os.WriteFile(filepath.Join(dir, "my-key"), []byte("my-value"), 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+}
+
+func TestStoreGetCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	filename := filepath.Join(dir, "my-key")
+	record, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatalf("Failed to read key file: %v", err)
+	}
+	record[len(record)-1] ^= 1
+	if err = os.WriteFile(filename, record, 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if _, err = store.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Reading a corrupted key file should have failed")
+	}
+}
+
+func TestStoreListSkipsTempFiles(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, ".my-key-2.123.tmp"), []byte("my-val"), 0o600); err != nil {
+		t.Fatalf("Failed to write temp. file: %v", err)
+	}
+
+	names, _, err := store.List(ctx, "", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != 1 || names[0] != "my-key" {
+		t.Fatalf("Invalid listing: got '%v' - want '%v'", names, []string{"my-key"})
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	masterKey := make([]byte, 32)
+
+	store, err := NewEncryptedStore(dir, masterKey)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+
+	record, err := os.ReadFile(filepath.Join(dir, "my-key"))
+	if err != nil {
+		t.Fatalf("Failed to read key file: %v", err)
+	}
+	if bytes.Contains(record, []byte("my-value")) {
+		t.Fatal("Key file contains the plaintext value")
+	}
+
+	// Key files must not be readable with a different master key
+	// or under a different name.
+	otherKey := make([]byte, 32)
+	otherKey[0] = 1
+	other, err := NewEncryptedStore(dir, otherKey)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if _, err = other.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Decrypting with a different master key should have failed")
+	}
+	if err = os.WriteFile(filepath.Join(dir, "my-key-2"), record, 0o600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	if _, err = store.Get(ctx, "my-key-2"); err == nil {
+		t.Fatal("Decrypting a renamed key file should have failed")
+	}
+
+	// An unencrypted Store must not return encrypted values.
+	plain, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	if _, err = plain.Get(ctx, "my-key"); err == nil {
+		t.Fatal("Reading an encrypted key without master key should have failed")
+	}
+}
+
+func TestStoreCreateConcurrent(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Two stores, like two processes, using the same directory.
+	store1, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	store2, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+
+	if err = store1.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = store2.Create(ctx, "my-key", []byte("other-value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating an existing key should fail with '%v': got '%v'", kesdk.ErrKeyExists, err)
+	}
+	value, err := store1.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Existing key has been replaced: got '%s' - want '%s'", value, "my-value")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Temporary files have not been removed: got '%d' directory entries - want '1'", len(entries))
+	}
+}
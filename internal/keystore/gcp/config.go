@@ -83,6 +83,17 @@ type Config struct {
 	// access the SecretManager.
 	Credentials Credentials
 
+	// CredentialsFile is an optional path to a GCP
+	// credentials JSON file. It may contain service
+	// account credentials or an external account
+	// configuration for Workload Identity Federation.
+	//
+	// CredentialsFile and Credentials are mutually
+	// exclusive. If neither is set, the application
+	// default credentials are used - e.g. the GKE
+	// workload identity or the GCE metadata server.
+	CredentialsFile string
+
 	// Scopes are GCP OAuth2 scopes for accessing GCP APIs.
 	// If not set, defaults to the GCP default scopes.
 	//
@@ -108,10 +119,11 @@ func (c *Config) Clone() *Config {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
 	clone := &Config{
-		Endpoint:    c.Endpoint,
-		ProjectID:   c.ProjectID,
-		Credentials: c.Credentials,
-		ErrorLog:    c.ErrorLog,
+		Endpoint:        c.Endpoint,
+		ProjectID:       c.ProjectID,
+		Credentials:     c.Credentials,
+		CredentialsFile: c.CredentialsFile,
+		ErrorLog:        c.ErrorLog,
 	}
 	if len(c.Scopes) > 0 {
 		clone.Scopes = make([]string, 0, len(c.Scopes))
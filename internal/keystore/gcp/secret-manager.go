@@ -51,6 +51,15 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	// the GCP SDK will pick them up automatically. In this case
 	// the user does not have to provide login credentials at all.
 	empty := Credentials{}
+	if c.Credentials != empty && c.CredentialsFile != "" {
+		return nil, errors.New("gcp: credentials and credentials file are mutually exclusive")
+	}
+	if c.CredentialsFile != "" {
+		// The credentials file may contain service account credentials
+		// or an external account config for Workload Identity Federation.
+		// The GCP SDK detects the credential type automatically.
+		options = append(options, option.WithCredentialsFile(c.CredentialsFile))
+	}
 	if c.Credentials != empty {
 		// We do a sanity check here to ensure that the user
 		// actually provided some login credentials. However,
@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"net/http"
 	"sync"
 	"time"
@@ -21,9 +22,22 @@ import (
 // authToken is a KeySecure authentication token.
 // It can be used to authenticate API requests.
 type authToken struct {
-	Type   string
-	Value  string
-	Expiry time.Duration
+	Type     string
+	Value    string
+	Expiry   time.Duration
+	IssuedAt time.Time
+}
+
+// renewAfter returns the duration after which the
+// token should be renewed. A token should be renewed
+// once half of its lifetime has passed such that
+// there is enough time to retry a failed renewal
+// before the token actually expires.
+func (t *authToken) renewAfter() time.Duration {
+	if d := time.Until(t.IssuedAt.Add(t.Expiry / 2)); d > 0 {
+		return d
+	}
+	return 0
 }
 
 // String returns the string representation of
@@ -38,6 +52,8 @@ type client struct {
 
 	lock  sync.Mutex
 	token authToken
+
+	authLock sync.Mutex // Ensures that only one re-authentication happens at a time
 }
 
 // Authenticate tries to obtain a new authentication token
@@ -74,6 +90,7 @@ func (c *client) Authenticate(ctx context.Context, endpoint string, login Creden
 	}
 	req.Header.Set("Content-Type", "application/json")
 
+	issuedAt := time.Now() // Measure before sending the request to not overestimate the token lifetime
 	resp, err := c.Do(req)
 	if err != nil {
 		return err
@@ -105,14 +122,32 @@ func (c *client) Authenticate(ctx context.Context, endpoint string, login Creden
 
 	c.lock.Lock()
 	c.token = authToken{
-		Type:   response.Type,
-		Value:  response.Token,
-		Expiry: time.Duration(response.Expiry) * time.Second,
+		Type:     response.Type,
+		Value:    response.Token,
+		Expiry:   time.Duration(response.Expiry) * time.Second,
+		IssuedAt: issuedAt,
 	}
 	c.lock.Unlock()
 	return nil
 }
 
+// Reauthenticate obtains a new authentication token if the
+// client's current token is still the given stale token.
+//
+// It should be called when KeySecure rejects a token, for
+// example because it expired before it got renewed. If
+// multiple requests fail concurrently, only the first one
+// fetches a new token while the others wait for and use it.
+func (c *client) Reauthenticate(ctx context.Context, endpoint string, login Credentials, stale string) error {
+	c.authLock.Lock()
+	defer c.authLock.Unlock()
+
+	if c.AuthToken() != stale {
+		return nil // Another request has already obtained a new token
+	}
+	return c.Authenticate(ctx, endpoint, login)
+}
+
 // RenewAuthToken tries to renew the client's authentication
 // token before it expires. It blocks until <-ctx.Done() completes.
 //
@@ -122,11 +157,14 @@ func (c *client) Authenticate(ctx context.Context, endpoint string, login Creden
 //
 // RenewAuthToken tries get a new authentication token from the given
 // KeySecure endpoint by presenting the given refresh token.
-// It continuously tries to renew the authentication before it expires.
+// It renews the authentication token once half of its lifetime has
+// passed.
 //
 // If RenewAuthToken fails to request or renew the client's authentication
-// token then it keeps retrying and waits for the given login.Retry delay
-// between each retry attempt.
+// token then it keeps retrying. Between each retry attempt, it waits for
+// the given login.Retry delay plus or minus a random jitter of up to 50%
+// such that multiple KES servers do not retry in lockstep. However, it
+// never waits longer than the remaining lifetime of the current token.
 //
 // If login.Retry is 0 then RenewAuthToken uses a reasonable default retry delay.
 func (c *client) RenewAuthToken(ctx context.Context, endpoint string, login Credentials) {
@@ -138,20 +176,27 @@ func (c *client) RenewAuthToken(ctx context.Context, endpoint string, login Cred
 		err   error
 	)
 	for {
+		c.lock.Lock()
+		delay := c.token.renewAfter()
+		expiresIn := time.Until(c.token.IssuedAt.Add(c.token.Expiry))
+		c.lock.Unlock()
+
 		if err != nil {
-			timer = time.NewTimer(login.Retry)
-		} else {
-			c.lock.Lock()
-			timer = time.NewTimer(c.token.Expiry / 2)
-			c.lock.Unlock()
+			delay = login.Retry/2 + time.Duration(rand.Int63n(int64(login.Retry)))
+			if expiresIn > 0 && delay > expiresIn {
+				delay = expiresIn
+			}
 		}
+		timer = time.NewTimer(delay)
 
 		select {
 		case <-ctx.Done():
 			timer.Stop()
 			return
 		case <-timer.C:
+			c.authLock.Lock()
 			err = c.Authenticate(ctx, endpoint, login)
+			c.authLock.Unlock()
 			timer.Stop()
 		}
 	}
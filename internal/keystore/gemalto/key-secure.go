@@ -158,9 +158,7 @@ func (s *Store) Create(ctx context.Context, name string, value []byte) error {
 		return fmt.Errorf("gemalto: failed to create key '%s': %v", name, err)
 	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", s.client.AuthToken())
-
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -201,9 +199,7 @@ func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf("gemalto: failed to access key '%s': %v", name, err)
 	}
-	req.Header.Set("Authorization", s.client.AuthToken())
-
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
@@ -241,9 +237,7 @@ func (s *Store) Delete(ctx context.Context, name string) error {
 	if err != nil {
 		return fmt.Errorf("gemalto: failed to delete key  '%s': %v", name, err)
 	}
-	req.Header.Set("Authorization", s.client.AuthToken())
-
-	resp, err := s.client.Do(req)
+	resp, err := s.do(req)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return err
 	}
@@ -311,9 +305,7 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 		if err != nil {
 			return nil, "", fmt.Errorf("gemalto: failed to list keys: %v", err)
 		}
-		req.Header.Set("Authorization", s.client.AuthToken())
-
-		resp, err := s.client.Do(req)
+		resp, err := s.do(req)
 		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 			return nil, "", err
 		}
@@ -358,6 +350,38 @@ func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, strin
 	return keystore.List(names, prefix, n)
 }
 
+// do sends the request authenticated with the client's current
+// auth token.
+//
+// If KeySecure rejects the token - e.g. because it has expired
+// before the background renewal succeeded - do obtains a new
+// token and sends the request once more.
+func (s *Store) do(req *http.Request) (*http.Response, error) {
+	token := s.client.AuthToken()
+	req.Header.Set("Authorization", token)
+
+	resp, err := s.client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if req.Body != nil {
+		seeker, ok := req.Body.(io.Seeker)
+		if !ok {
+			return resp, nil
+		}
+		if _, err = seeker.Seek(0, io.SeekStart); err != nil {
+			return resp, nil
+		}
+	}
+	if err = s.client.Reauthenticate(req.Context(), s.config.Endpoint, s.config.Login, token); err != nil {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	req.Header.Set("Authorization", s.client.AuthToken())
+	return s.client.Do(req)
+}
+
 // Close closes the Store. It stops any authentication renewal in the background.
 func (s *Store) Close() error {
 	s.stop()
@@ -0,0 +1,61 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package gemalto
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestStoreReauthenticate(t *testing.T) {
+	var tokens atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v1/auth/tokens":
+			n := tokens.Add(1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprintf(w, `{"token_type":"Bearer","jwt":"token-%d","duration":300}`, n)
+		case "/api/v1/vault/secrets/my-key/export":
+			// Only the second token is accepted. The first
+			// one is treated as if it has already expired.
+			if r.Header.Get("Authorization") != "Bearer token-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"material":"my-value"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := Connect(ctx, &Config{
+		Endpoint: srv.URL,
+		Login:    Credentials{Token: "refresh-token"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+	if n := tokens.Load(); n != 2 {
+		t.Fatalf("Invalid number of authentications: got '%d' - want '%d'", n, 2)
+	}
+}
@@ -0,0 +1,468 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package ibm implements a key store that stores keys
+// at IBM Key Protect or IBM Hyper Protect Crypto Services.
+package ibm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// DefaultIAMEndpoint is the IBM Cloud IAM endpoint used
+// to exchange API keys for access tokens if Config.IAMEndpoint
+// is not set.
+const DefaultIAMEndpoint = "https://iam.cloud.ibm.com"
+
+// Config is a structure containing configuration
+// options for connecting to IBM Key Protect.
+type Config struct {
+	// Endpoint is the Key Protect endpoint. If empty,
+	// the public endpoint of the region is used - e.g.
+	// https://us-south.kms.cloud.ibm.com.
+	//
+	// Hyper Protect Crypto Services instances provide
+	// a Key Protect compatible API at an instance
+	// specific endpoint that must be set explicitly.
+	Endpoint string
+
+	// Region is the IBM Cloud region of the instance -
+	// e.g. "us-south".
+	Region string
+
+	// InstanceID is the ID of the Key Protect or Hyper
+	// Protect Crypto Services instance.
+	InstanceID string
+
+	// KeyRing is an optional key ring ID. If set, keys
+	// are created within this key ring. Otherwise, the
+	// default key ring is used.
+	KeyRing string
+
+	// Prefix is an optional prefix added to the alias of
+	// each key - e.g. "kes-".
+	Prefix string
+
+	// APIKey is the IBM Cloud API key used to obtain
+	// IAM access tokens.
+	APIKey string
+
+	// IAMEndpoint is the IBM Cloud IAM endpoint. If
+	// empty, DefaultIAMEndpoint is used.
+	IAMEndpoint string
+}
+
+// Connect connects to IBM Key Protect and returns a Store
+// that stores keys as Key Protect standard keys.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	c := *config
+	if c.Endpoint == "" {
+		if c.Region == "" {
+			return nil, errors.New("ibm: no endpoint or region specified")
+		}
+		c.Endpoint = "https://" + c.Region + ".kms.cloud.ibm.com"
+	}
+	if c.IAMEndpoint == "" {
+		c.IAMEndpoint = DefaultIAMEndpoint
+	}
+	if c.InstanceID == "" {
+		return nil, errors.New("ibm: no instance ID specified")
+	}
+	if c.APIKey == "" {
+		return nil, errors.New("ibm: no API key specified")
+	}
+	c.Endpoint = strings.TrimSuffix(c.Endpoint, "/")
+	c.IAMEndpoint = strings.TrimSuffix(c.IAMEndpoint, "/")
+
+	s := &Store{
+		config: c,
+	}
+	if _, err := s.Status(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store is a key store that stores keys as IBM Key Protect
+// standard keys.
+//
+// Key Protect does not enforce unique key names. Therefore,
+// the Store identifies keys by their alias, which is unique
+// within an instance. Aliases must consist of alphanumeric
+// characters, '-' and '_' and must not be a UUID.
+type Store struct {
+	config Config
+	client xhttp.Retry
+
+	lock        sync.Mutex
+	accessToken string
+	expiry      time.Time
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// contentType is the media type of Key Protect key resources.
+const contentType = "application/vnd.ibm.kms.key+json"
+
+// String returns a string representation of the Store.
+func (s *Store) String() string { return "IBM Key Protect: " + s.config.Endpoint }
+
+// Status returns the current state of the Key Protect instance.
+// In particular, whether it is reachable and the network latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	query := url.Values{}
+	query.Set("limit", "1")
+
+	start := time.Now()
+	resp, err := s.do(ctx, http.MethodGet, "/api/v2/keys", query, nil)
+	if err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{
+			Err: fmt.Errorf("ibm: failed to fetch status: %v", err),
+		}
+	}
+	latency := time.Since(start)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return kes.KeyStoreState{}, fmt.Errorf("ibm: failed to fetch status: %v", parseErrorResponse(resp))
+	}
+	return kes.KeyStoreState{
+		Latency: latency,
+	}, nil
+}
+
+// Create creates a new standard key with the given name as
+// alias and the given value as payload if and only if no key
+// with this alias exists. Otherwise, it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type Resource struct {
+		Type        string   `json:"type"`
+		Name        string   `json:"name"`
+		Aliases     []string `json:"aliases"`
+		Description string   `json:"description"`
+		Extractable bool     `json:"extractable"`
+		Payload     string   `json:"payload"`
+	}
+	type Metadata struct {
+		CollectionType  string `json:"collectionType"`
+		CollectionTotal int    `json:"collectionTotal"`
+	}
+	type Request struct {
+		Metadata  Metadata   `json:"metadata"`
+		Resources []Resource `json:"resources"`
+	}
+	body, err := json.Marshal(Request{
+		Metadata: Metadata{
+			CollectionType:  contentType,
+			CollectionTotal: 1,
+		},
+		Resources: []Resource{{
+			Type:        contentType,
+			Name:        s.config.Prefix + name,
+			Aliases:     []string{s.config.Prefix + name},
+			Description: "KES key " + name,
+			Extractable: true,
+			Payload:     base64.StdEncoding.EncodeToString(value),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "/api/v2/keys", nil, body)
+	if err != nil {
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return nil
+	case http.StatusConflict:
+		return kesdk.ErrKeyExists
+	default:
+		return fmt.Errorf("ibm: failed to create key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// Get returns the payload of the key with the given name as
+// alias. If no such key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	key, err := s.getKey(ctx, name)
+	if err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("ibm: failed to fetch key '%s': %v", name, err)
+	}
+	value, err := base64.StdEncoding.DecodeString(key.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("ibm: failed to fetch key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// Delete deletes the key with the given name as alias. If no
+// such key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	key, err := s.getKey(ctx, name)
+	if err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return err
+		}
+		return fmt.Errorf("ibm: failed to delete key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodDelete, "/api/v2/keys/"+url.PathEscape(key.ID), nil, nil)
+	if err != nil {
+		return fmt.Errorf("ibm: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound, http.StatusGone:
+		return kesdk.ErrKeyNotFound
+	default:
+		return fmt.Errorf("ibm: failed to delete key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	type Response struct {
+		Metadata struct {
+			CollectionTotal int `json:"collectionTotal"`
+		} `json:"metadata"`
+		Resources []struct {
+			Aliases []string `json:"aliases"`
+		} `json:"resources"`
+	}
+
+	const Limit = 200
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(Limit))
+	query.Set("state", "0,1,2,3") // All states except destroyed
+
+	var names []string
+	for offset := 0; ; offset += Limit {
+		query.Set("offset", strconv.Itoa(offset))
+
+		resp, err := s.do(ctx, http.MethodGet, "/api/v2/keys", query, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("ibm: failed to list keys: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = parseErrorResponse(resp)
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("ibm: failed to list keys: %v", err)
+		}
+
+		var response Response
+		err = json.NewDecoder(mem.LimitReader(resp.Body, 10*mem.MB)).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("ibm: failed to list keys: %v", err)
+		}
+		for _, key := range response.Resources {
+			for _, alias := range key.Aliases {
+				if name, ok := strings.CutPrefix(alias, s.config.Prefix); ok && name != "" {
+					names = append(names, name)
+				}
+			}
+		}
+		if len(response.Resources) < Limit {
+			break
+		}
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the Store.
+func (s *Store) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// key is the subset of a Key Protect key resource
+// used by the Store.
+type key struct {
+	ID      string `json:"id"`
+	State   int    `json:"state"`
+	Payload string `json:"payload"`
+}
+
+// getKey fetches the key with the given name as alias.
+// If no such key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) getKey(ctx context.Context, name string) (*key, error) {
+	type Response struct {
+		Resources []key `json:"resources"`
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, "/api/v2/keys/"+url.PathEscape(s.config.Prefix+name), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusGone:
+		return nil, kesdk.ErrKeyNotFound
+	default:
+		return nil, parseErrorResponse(resp)
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil {
+		return nil, err
+	}
+	if len(response.Resources) == 0 {
+		return nil, kesdk.ErrKeyNotFound
+	}
+
+	const Destroyed = 5
+	if k := &response.Resources[0]; k.State != Destroyed {
+		return k, nil
+	}
+	return nil, kesdk.ErrKeyNotFound
+}
+
+// do sends an authenticated request to the Key Protect API.
+func (s *Store) do(ctx context.Context, method, path string, query url.Values, body []byte) (*http.Response, error) {
+	token, err := s.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u := s.config.Endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Accept", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Bluemix-Instance", s.config.InstanceID)
+	if s.config.KeyRing != "" {
+		req.Header.Set("X-Kms-Key-Ring", s.config.KeyRing)
+	}
+	return s.client.Do(req)
+}
+
+// token returns an IAM access token. It exchanges the API
+// key for a new access token if no token has been obtained
+// yet or the current one is about to expire.
+func (s *Store) token(ctx context.Context) (string, error) {
+	type Response struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.accessToken != "" && time.Until(s.expiry) > time.Minute {
+		return s.accessToken, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", s.config.APIKey)
+	body := form.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.IAMEndpoint+"/identity/token", xhttp.RetryReader(strings.NewReader(body)))
+	if err != nil {
+		return "", err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain IAM access token: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to obtain IAM access token: %v", parseErrorResponse(resp))
+	}
+
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to obtain IAM access token: %v", err)
+	}
+	if response.AccessToken == "" {
+		return "", errors.New("failed to obtain IAM access token: empty access token")
+	}
+	s.accessToken = response.AccessToken
+	s.expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+// parseErrorResponse parses an IBM Cloud HTTP error response.
+// Key Protect and IAM use different error formats.
+func parseErrorResponse(resp *http.Response) error {
+	type Response struct {
+		Resources []struct {
+			ErrorMsg string `json:"errorMsg"`
+			Reasons  []struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"reasons"`
+		} `json:"resources"`
+		ErrorMessage string `json:"errorMessage"` // IAM
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil {
+		return errors.New(resp.Status)
+	}
+	if response.ErrorMessage != "" {
+		return errors.New(resp.Status + ": " + response.ErrorMessage)
+	}
+	if len(response.Resources) == 0 {
+		return errors.New(resp.Status)
+	}
+	msg := response.Resources[0].ErrorMsg
+	if reasons := response.Resources[0].Reasons; len(reasons) > 0 {
+		msg = reasons[0].Message + " (" + reasons[0].Code + ")"
+	}
+	if msg == "" {
+		return errors.New(resp.Status)
+	}
+	return errors.New(resp.Status + ": " + msg)
+}
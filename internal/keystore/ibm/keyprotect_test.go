@@ -0,0 +1,177 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package ibm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStore(t *testing.T) {
+	const (
+		InstanceID = "1b2c3d4e-instance"
+		KeyRing    = "kes-ring"
+		APIKey     = "ibm-cloud-api-key"
+	)
+	srv := newFakeKeyProtect(t, InstanceID, KeyRing, APIKey)
+	defer srv.Close()
+
+	ctx := context.Background()
+	store, err := Connect(ctx, &Config{
+		Endpoint:    srv.URL,
+		IAMEndpoint: srv.URL,
+		InstanceID:  InstanceID,
+		KeyRing:     KeyRing,
+		Prefix:      "kes-",
+		APIKey:      APIKey,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	names := []string{"my-key", "my-key-2", "other-key"}
+	for _, name := range names {
+		if err = store.Create(ctx, name, []byte("value-"+name)); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = store.Create(ctx, names[0], nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range names {
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch key '%s': %v", name, err)
+		}
+		if string(value) != "value-"+name {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, value, "value-"+name)
+		}
+	}
+
+	list, _, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if want := []string{"my-key", "my-key-2"}; !slices.Equal(list, want) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", list, want)
+	}
+
+	if err = store.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", names[0], err)
+	}
+	if _, err = store.Get(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Fetching deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err = store.Delete(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+}
+
+// newFakeKeyProtect returns a server that implements the
+// IAM token API and the subset of the Key Protect API used
+// by the Store.
+func newFakeKeyProtect(t *testing.T, instanceID, keyRing, apiKey string) *httptest.Server {
+	const AccessToken = "iam-access-token"
+
+	type Key struct {
+		ID      string   `json:"id"`
+		Aliases []string `json:"aliases"`
+		State   int      `json:"state"`
+		Payload string   `json:"payload,omitempty"`
+	}
+	var (
+		lock sync.Mutex
+		keys []*Key
+	)
+	lookup := func(alias string) *Key {
+		for _, key := range keys {
+			if key.State != 5 && slices.Contains(key.Aliases, alias) {
+				return key
+			}
+		}
+		return nil
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/identity/token" {
+			if r.PostFormValue("apikey") != apiKey {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"errorMessage": "invalid API key"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"access_token": AccessToken, "expires_in": 3600})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+AccessToken || r.Header.Get("Bluemix-Instance") != instanceID {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch id := strings.TrimPrefix(r.URL.Path, "/api/v2/keys"); {
+		case r.Method == http.MethodGet && id == "":
+			resources := []*Key{}
+			for _, key := range keys {
+				if key.State != 5 {
+					resources = append(resources, &Key{ID: key.ID, Aliases: key.Aliases, State: key.State})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{"resources": resources})
+		case r.Method == http.MethodPost && id == "":
+			if r.Header.Get("X-Kms-Key-Ring") != keyRing {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			var req struct {
+				Resources []Key `json:"resources"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Resources) != 1 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			key := req.Resources[0]
+			for _, alias := range key.Aliases {
+				if lookup(alias) != nil {
+					w.WriteHeader(http.StatusConflict)
+					return
+				}
+			}
+			key.ID = strconv.Itoa(len(keys))
+			key.State = 1
+			keys = append(keys, &key)
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet:
+			key := lookup(strings.TrimPrefix(id, "/"))
+			if key == nil {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{"resources": []*Key{key}})
+		case r.Method == http.MethodDelete:
+			for _, key := range keys {
+				if key.ID == strings.TrimPrefix(id, "/") && key.State != 5 {
+					key.State = 5
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
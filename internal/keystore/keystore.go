@@ -5,16 +5,23 @@
 package keystore
 
 import (
+	"context"
+	"encoding/hex"
 	"errors"
+	"net"
+	"net/http"
 	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // List sorts the names lexicographically and returns the
-// first n, if n > 0, names that match the given prefix.
-// If n <= 0, List limits the returned slice to a reasonable
-// default. If len(names) is greater than n then List returns
-// the next name from which to continue.
+// first n names that match the given prefix. If n < 0, List
+// returns all matching names. If n == 0, List limits the
+// returned slice to a reasonable default. If more than n
+// names match the prefix then List returns the next name
+// from which to continue.
 func List(names []string, prefix string, n int) ([]string, string, error) {
 	const N = 1024
 
@@ -28,27 +35,79 @@ func List(names []string, prefix string, n int) ([]string, string, error) {
 		}
 		names = names[i:]
 
-		for i, name := range names {
-			if !strings.HasPrefix(name, prefix) {
-				return names[:i], "", nil
-			}
-			if (n > 0 && i > n) || i == N {
-				if i == len(names)-1 {
-					return names, "", nil
-				}
-				return names[:i], names[i], nil
-			}
+		if j := slices.IndexFunc(names, func(name string) bool { return !strings.HasPrefix(name, prefix) }); j >= 0 {
+			names = names[:j]
 		}
 	}
 
-	switch {
-	case (n <= 0 && len(names) <= N) || len(names) <= n:
+	if n == 0 {
+		n = N
+	}
+	if n < 0 || len(names) <= n {
 		return names, "", nil
-	case n <= 0:
-		return names[:N], names[N], nil
-	default:
-		return names[:n], names[n], nil
 	}
+	return names[:n], names[n], nil
+}
+
+// RevisionPrefix is the name prefix of the entries holding
+// revisions of a key.
+//
+// A Store can neither update entries nor replace them atomically.
+// Hence, a key is changed, e.g. rotated, by creating a new revision
+// entry next to the original one. The latest revision is the current
+// state of the key. Since creating an entry fails if it exists, only
+// one of several concurrent changes, e.g. made by different servers
+// sharing the Store, succeeds.
+const RevisionPrefix = "kes-rev-"
+
+// OriginSize is the length of a hex-encoded key origin.
+const OriginSize = 16
+
+// MaxNameLength is the max. length of a key name such that the
+// names of all its revision entries are at most 255 bytes long.
+// Most Stores, e.g. file systems, accept names of that length.
+const MaxNameLength = 255 - len(RevisionPrefix) - 1 - OriginSize - 1 - 10 // 10 = max. length of a uint32
+
+// RevisionName returns the name of the entry holding the n-th
+// revision of the key with the given name and origin.
+//
+// The origin identifies the key and consists of OriginSize hex
+// characters. Revision entries that have not been deleted with
+// their key are never mistaken for revisions of another key,
+// created later on with the same name.
+func RevisionName(name, origin string, n uint32) string {
+	return RevisionNamePrefix(name, origin) + strconv.FormatUint(uint64(n), 10)
+}
+
+// RevisionNamePrefix returns the name prefix of all entries holding
+// revisions of the key with the given name and origin.
+func RevisionNamePrefix(name, origin string) string {
+	return RevisionPrefix + name + "-" + origin + "-"
+}
+
+// RevisionOf returns the name of the key whose revision is held by
+// the entry with the given name. It reports whether the entry holds
+// a key revision.
+func RevisionOf(entry string) (string, bool) {
+	name, ok := strings.CutPrefix(entry, RevisionPrefix)
+	if !ok {
+		return "", false
+	}
+	i := strings.LastIndexByte(name, '-')
+	if i < 0 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(name[i+1:], 10, 32); err != nil {
+		return "", false
+	}
+	name = name[:i]
+	if i = len(name) - OriginSize - 1; i <= 0 || name[i] != '-' {
+		return "", false
+	}
+	if _, err := hex.DecodeString(name[i+1:]); err != nil {
+		return "", false
+	}
+	return name[:i], true
 }
 
 // ErrUnreachable is an error that indicates that the
@@ -75,3 +134,77 @@ func IsUnreachable(err error) (*ErrUnreachable, bool) {
 	}
 	return nil, false
 }
+
+// IsTemporary reports whether err indicates that a Store
+// could not be reached temporarily - for example due to
+// a network error or a timeout. Requests that fail with
+// a temporary error may succeed when sent again.
+func IsTemporary(err error) bool {
+	if _, ok := IsUnreachable(err); ok {
+		return true
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// HTTPConfig is a structure containing the HTTP client
+// settings of keystores that are accessed via HTTP. Zero
+// values keep the keystore's defaults.
+type HTTPConfig struct {
+	// MaxIdleConns limits the number of idle (keep-alive)
+	// connections across all hosts.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits the number of idle
+	// (keep-alive) connections per host. Go's default
+	// of 2 causes many new connections under load.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is the max. amount of time an
+	// idle connection remains open.
+	IdleConnTimeout time.Duration
+
+	// TLSHandshakeTimeout limits the time spent on
+	// the TLS handshake of a new connection.
+	TLSHandshakeTimeout time.Duration
+
+	// Timeout limits the time of a single HTTP request,
+	// including connecting and reading the response.
+	Timeout time.Duration
+}
+
+// IsZero reports whether the HTTPConfig keeps all
+// defaults.
+func (c *HTTPConfig) IsZero() bool { return c == nil || *c == HTTPConfig{} }
+
+// Configure applies the HTTPConfig to the client. The
+// connection settings are only applied if the client's
+// transport is a *http.Transport.
+func (c *HTTPConfig) Configure(client *http.Client) {
+	if c.IsZero() {
+		return
+	}
+	if c.Timeout > 0 {
+		client.Timeout = c.Timeout
+	}
+
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return
+	}
+	if c.MaxIdleConns > 0 {
+		transport.MaxIdleConns = c.MaxIdleConns
+	}
+	if c.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = c.MaxIdleConnsPerHost
+	}
+	if c.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = c.IdleConnTimeout
+	}
+	if c.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = c.TLSHandshakeTimeout
+	}
+}
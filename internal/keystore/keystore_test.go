@@ -5,10 +5,36 @@
 package keystore
 
 import (
+	"fmt"
+	"net/http"
 	"slices"
 	"testing"
+	"time"
 )
 
+func TestListAll(t *testing.T) {
+	names := make([]string, 0, 2000)
+	for i := 0; i < cap(names); i++ {
+		names = append(names, fmt.Sprintf("my-key-%04d", i))
+	}
+
+	list, continueAt, err := List(slices.Clone(names), "my-key", -1)
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(list) != len(names) || continueAt != "" {
+		t.Fatalf("Listing is not complete: got '%d' names and continue at '%s' - want '%d' names", len(list), continueAt, len(names))
+	}
+
+	list, continueAt, err = List(slices.Clone(names), "my-key", 0)
+	if err != nil {
+		t.Fatalf("Failed to list: %v", err)
+	}
+	if len(list) != 1024 || continueAt != names[1024] {
+		t.Fatalf("Listing is not limited: got '%d' names and continue at '%s' - want '1024' names and continue at '%s'", len(list), continueAt, names[1024])
+	}
+}
+
 func TestList(t *testing.T) {
 	for i, test := range listTests {
 		list, continueAt, err := List(test.Names, test.Prefix, test.N)
@@ -54,3 +80,39 @@ var listTests = []struct {
 		ContinueAt: "my-key2",
 	},
 }
+
+func TestRevisionOf(t *testing.T) {
+	for i, name := range []string{"my-key", "my-key-1", "a"} {
+		entry := RevisionName(name, "0a1b2c3d4e5f6a7b", uint32(i+1))
+		if key, ok := RevisionOf(entry); !ok || key != name {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, key, name)
+		}
+	}
+	for i, entry := range []string{"my-key", "kes-rev-my-key", "kes-rev-my-key-1"} {
+		if _, ok := RevisionOf(entry); ok {
+			t.Fatalf("Test %d: '%s' is not a key revision", i, entry)
+		}
+	}
+}
+
+func TestHTTPConfigConfigure(t *testing.T) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	client := &http.Client{Transport: transport}
+
+	(&HTTPConfig{}).Configure(client)
+	if client.Timeout != 0 || transport.MaxIdleConnsPerHost != 0 {
+		t.Fatal("Empty HTTP config modified the client")
+	}
+
+	config := &HTTPConfig{MaxIdleConnsPerHost: 64, Timeout: 10 * time.Second}
+	config.Configure(client)
+	if client.Timeout != config.Timeout {
+		t.Fatalf("Invalid timeout: got '%v' - want '%v'", client.Timeout, config.Timeout)
+	}
+	if transport.MaxIdleConnsPerHost != config.MaxIdleConnsPerHost {
+		t.Fatalf("Invalid idle connections per host: got '%d' - want '%d'", transport.MaxIdleConnsPerHost, config.MaxIdleConnsPerHost)
+	}
+	if want := http.DefaultTransport.(*http.Transport).MaxIdleConns; transport.MaxIdleConns != want {
+		t.Fatalf("Default idle connections modified: got '%d' - want '%d'", transport.MaxIdleConns, want)
+	}
+}
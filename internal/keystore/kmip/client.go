@@ -0,0 +1,221 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kmip
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// operation is a KMIP operation.
+type operation uint32
+
+// KMIP operations used by the Store.
+const (
+	opRegister         operation = 0x03
+	opLocate           operation = 0x08
+	opGet              operation = 0x0A
+	opGetAttributes    operation = 0x0B
+	opDestroy          operation = 0x14
+	opDiscoverVersions operation = 0x1E
+)
+
+// KMIP result status and reason codes.
+const (
+	statusSuccess      = 0x00
+	reasonItemNotFound = 0x01
+)
+
+// The client speaks KMIP 1.2. Later versions are backward
+// compatible for all operations used by the Store.
+const (
+	protocolMajor = 1
+	protocolMinor = 2
+)
+
+// maxMessageSize is the max. size of a KMIP response message.
+const maxMessageSize = 16 << 20
+
+// kmipError is an error returned by the KMIP server when
+// an operation fails.
+type kmipError struct {
+	Reason  uint32
+	Message string
+}
+
+func (e *kmipError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("operation failed: reason %#x", e.Reason)
+	}
+	return fmt.Sprintf("operation failed: %s (reason %#x)", e.Message, e.Reason)
+}
+
+// client sends KMIP requests to a KMIP server over a single
+// TLS connection. It establishes a new connection when the
+// previous one fails.
+type client struct {
+	Endpoint string
+	TLS      *tls.Config
+
+	lock sync.Mutex
+	conn net.Conn
+}
+
+// Send sends a request for the given operation and payload
+// to the KMIP server and returns the response payload.
+func (c *client) Send(ctx context.Context, op operation, payload ...item) (item, error) {
+	request, err := structure(tagRequestMessage,
+		structure(tagRequestHeader,
+			structure(tagProtocolVersion,
+				integer(tagProtocolVersionMajor, protocolMajor),
+				integer(tagProtocolVersionMinor, protocolMinor),
+			),
+			integer(tagBatchCount, 1),
+		),
+		structure(tagBatchItem,
+			enumeration(tagOperation, uint32(op)),
+			structure(tagRequestPayload, payload...),
+		),
+	).MarshalBinary()
+	if err != nil {
+		return item{}, err
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	// A KMIP server may close idle connections at any time.
+	// Hence, a request sent on a reused connection is retried
+	// once on a new connection if the server closed it.
+	reused := c.conn != nil
+	response, err := c.roundTrip(ctx, request)
+	if err != nil && reused && errors.Is(err, io.EOF) {
+		response, err = c.roundTrip(ctx, request)
+	}
+	if err != nil {
+		return item{}, err
+	}
+
+	if response.Tag != tagResponseMessage {
+		return item{}, errors.New("kmip: invalid response: not a response message")
+	}
+	batch, ok := response.Find(tagBatchItem)
+	if !ok {
+		return item{}, errors.New("kmip: invalid response: no batch item")
+	}
+	status, ok := batch.Find(tagResultStatus)
+	if !ok {
+		return item{}, errors.New("kmip: invalid response: no result status")
+	}
+	if s, _ := status.Uint32(); s != statusSuccess {
+		var e kmipError
+		if reason, ok := batch.Find(tagResultReason); ok {
+			e.Reason, _ = reason.Uint32()
+		}
+		if message, ok := batch.Find(tagResultMessage); ok {
+			e.Message, _ = message.Text()
+		}
+		return item{}, &e
+	}
+	payloadItem, ok := batch.Find(tagResponsePayload)
+	if !ok {
+		payloadItem = structure(tagResponsePayload)
+	}
+	return payloadItem, nil
+}
+
+// Close closes the connection to the KMIP server, if any.
+func (c *client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// roundTrip writes the request to the current connection,
+// or a new one, and reads the response. It closes the
+// connection on any error.
+func (c *client) roundTrip(ctx context.Context, request []byte) (item, error) {
+	if c.conn == nil {
+		dialer := tls.Dialer{
+			NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+			Config:    c.TLS,
+		}
+		conn, err := dialer.DialContext(ctx, "tcp", c.Endpoint)
+		if err != nil {
+			return item{}, err
+		}
+		c.conn = conn
+	}
+
+	response, err := c.exchange(ctx, request)
+	if err != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return response, err
+}
+
+func (c *client) exchange(ctx context.Context, request []byte) (item, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(30 * time.Second)
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return item{}, err
+	}
+
+	// Abort the exchange if the context gets canceled before
+	// its deadline by moving the connection deadline.
+	stop := context.AfterFunc(ctx, func() { c.conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	if _, err := c.conn.Write(request); err != nil {
+		return item{}, err
+	}
+	return readMessage(c.conn)
+}
+
+// readMessage reads a single TTLV encoded message from r.
+func readMessage(r io.Reader) (item, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return item{}, err
+	}
+	length := binary.BigEndian.Uint32(header[4:])
+	if length > maxMessageSize {
+		return item{}, fmt.Errorf("kmip: message size %d exceeds %d bytes", length, maxMessageSize)
+	}
+	if itemType(header[3]) != typeStructure || length%8 != 0 {
+		return item{}, errors.New("kmip: invalid message: not a structure")
+	}
+
+	message := make([]byte, 8+int(length))
+	copy(message, header[:])
+	if _, err := io.ReadFull(r, message[8:]); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return item{}, err
+	}
+
+	var msg item
+	if err := msg.UnmarshalBinary(message); err != nil {
+		return item{}, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,317 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package kmip implements a key store that stores keys
+// as secret data objects on a KMIP server, like an
+// enterprise key manager or HSM appliance.
+package kmip
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// DefaultPort is the IANA registered KMIP port. It is used
+// if Config.Endpoint does not contain a port.
+const DefaultPort = "5696"
+
+// DefaultGroup is the object group of the secret data objects
+// created by the Store if Config.Group is empty.
+const DefaultGroup = "kes"
+
+// Config is a structure containing configuration
+// options for connecting to a KMIP server.
+type Config struct {
+	// Endpoint is the KMIP server endpoint - e.g.
+	// "kmip.example.com:5696".
+	Endpoint string
+
+	// Prefix is an optional prefix added to the
+	// name of each key - e.g. "kes/".
+	Prefix string
+
+	// Group is the object group of the secret data
+	// objects created by the Store. The Store only
+	// lists objects of this group. If empty, defaults
+	// to DefaultGroup.
+	Group string
+
+	// TLS is the TLS configuration used to connect
+	// to the KMIP server. KMIP servers authenticate
+	// clients via mTLS. Hence, it should contain a
+	// client certificate.
+	TLS *tls.Config
+}
+
+// Connect connects to the KMIP server and returns a Store
+// that stores keys as secret data objects.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Endpoint == "" {
+		return nil, errors.New("kmip: no endpoint specified")
+	}
+	if config.TLS == nil {
+		return nil, errors.New("kmip: no TLS config specified")
+	}
+
+	c := *config
+	if _, _, err := net.SplitHostPort(c.Endpoint); err != nil {
+		c.Endpoint = net.JoinHostPort(c.Endpoint, DefaultPort)
+	}
+	if c.Group == "" {
+		c.Group = DefaultGroup
+	}
+	s := &Store{
+		config: c,
+		client: &client{
+			Endpoint: c.Endpoint,
+			TLS:      c.TLS.Clone(),
+		},
+	}
+	if _, err := s.Status(ctx); err != nil {
+		s.client.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store is a key store that stores keys as KMIP
+// secret data objects.
+type Store struct {
+	config Config
+	client *client
+
+	// createLock serializes Create calls since KMIP does
+	// not require servers to reject duplicate names.
+	createLock sync.Mutex
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// KMIP enumeration values used by the Store.
+const (
+	objectTypeSecretData = 0x07
+	secretDataTypeSeed   = 0x02
+	keyFormatTypeOpaque  = 0x02
+	nameTypeText         = 0x01
+)
+
+// String returns a string representation of the Store.
+func (s *Store) String() string { return "KMIP: " + s.config.Endpoint }
+
+// Status returns the current state of the KMIP server.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	start := time.Now()
+	if _, err := s.client.Send(ctx, opDiscoverVersions); err != nil {
+		var kmipErr *kmipError
+		if errors.As(err, &kmipErr) {
+			return kes.KeyStoreState{}, fmt.Errorf("kmip: failed to fetch status: %v", err)
+		}
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{
+			Err: fmt.Errorf("kmip: failed to fetch status: %v", err),
+		}
+	}
+	return kes.KeyStoreState{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create registers a new secret data object with the given
+// name and value if and only if no object with the name
+// exists. Otherwise, it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	s.createLock.Lock()
+	defer s.createLock.Unlock()
+
+	if _, err := s.locate(ctx, name); err == nil {
+		return kesdk.ErrKeyExists
+	} else if !errors.Is(err, kesdk.ErrKeyNotFound) {
+		return fmt.Errorf("kmip: failed to create key '%s': %v", name, err)
+	}
+
+	_, err := s.client.Send(ctx, opRegister,
+		enumeration(tagObjectType, objectTypeSecretData),
+		structure(tagTemplateAttribute,
+			attribute("Name", structure(tagAttributeValue,
+				textString(tagNameValue, s.config.Prefix+name),
+				enumeration(tagNameType, nameTypeText),
+			)),
+			attribute("Object Group", textString(tagAttributeValue, s.config.Group)),
+		),
+		structure(tagSecretData,
+			enumeration(tagSecretDataType, secretDataTypeSeed),
+			structure(tagKeyBlock,
+				enumeration(tagKeyFormatType, keyFormatTypeOpaque),
+				structure(tagKeyValue,
+					byteString(tagKeyMaterial, value),
+				),
+			),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("kmip: failed to create key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// Get returns the value of the secret data object with the
+// given name. If no such object exists, it returns
+// kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	id, err := s.locate(ctx, name)
+	if errors.Is(err, kesdk.ErrKeyNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kmip: failed to fetch key '%s': %v", name, err)
+	}
+
+	resp, err := s.client.Send(ctx, opGet, textString(tagUniqueIdentifier, id))
+	if isNotFound(err) {
+		return nil, kesdk.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kmip: failed to fetch key '%s': %v", name, err)
+	}
+
+	secret, ok := resp.Find(tagSecretData)
+	if !ok {
+		return nil, fmt.Errorf("kmip: failed to fetch key '%s': object is not secret data", name)
+	}
+	block, _ := secret.Find(tagKeyBlock)
+	keyValue, _ := block.Find(tagKeyValue)
+	material, _ := keyValue.Find(tagKeyMaterial)
+	value, ok := material.Bytes()
+	if !ok {
+		return nil, fmt.Errorf("kmip: failed to fetch key '%s': object contains no key material", name)
+	}
+	return value, nil
+}
+
+// Delete destroys the secret data object with the given name.
+// If no such object exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	id, err := s.locate(ctx, name)
+	if errors.Is(err, kesdk.ErrKeyNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("kmip: failed to delete key '%s': %v", name, err)
+	}
+
+	_, err = s.client.Send(ctx, opDestroy, textString(tagUniqueIdentifier, id))
+	if isNotFound(err) {
+		return kesdk.ErrKeyNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("kmip: failed to delete key '%s': %v", name, err)
+	}
+	return nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	resp, err := s.client.Send(ctx, opLocate,
+		attribute("Object Type", enumeration(tagAttributeValue, objectTypeSecretData)),
+		attribute("Object Group", textString(tagAttributeValue, s.config.Group)),
+	)
+	if err != nil {
+		return nil, "", fmt.Errorf("kmip: failed to list keys: %v", err)
+	}
+
+	var names []string
+	for _, id := range resp.FindAll(tagUniqueIdentifier) {
+		resp, err := s.client.Send(ctx, opGetAttributes,
+			id,
+			textString(tagAttributeName, "Name"),
+		)
+		if isNotFound(err) {
+			continue // Object has been destroyed concurrently
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("kmip: failed to list keys: %v", err)
+		}
+		for _, attr := range resp.FindAll(tagAttribute) {
+			if attrName, _ := attr.Find(tagAttributeName); attrName.Value != "Name" {
+				continue
+			}
+			value, _ := attr.Find(tagAttributeValue)
+			nameValue, _ := value.Find(tagNameValue)
+			text, _ := nameValue.Text()
+			if name, ok := strings.CutPrefix(text, s.config.Prefix); ok && name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the connection to the KMIP server.
+func (s *Store) Close() error { return s.client.Close() }
+
+// locate returns the unique identifier of the secret data object
+// with the given name. If no such object exists, it returns
+// kes.ErrKeyNotFound.
+func (s *Store) locate(ctx context.Context, name string) (string, error) {
+	resp, err := s.client.Send(ctx, opLocate,
+		attribute("Object Type", enumeration(tagAttributeValue, objectTypeSecretData)),
+		attribute("Name", structure(tagAttributeValue,
+			textString(tagNameValue, s.config.Prefix+name),
+			enumeration(tagNameType, nameTypeText),
+		)),
+	)
+	if err != nil {
+		return "", err
+	}
+
+	ids := resp.FindAll(tagUniqueIdentifier)
+	switch len(ids) {
+	case 0:
+		return "", kesdk.ErrKeyNotFound
+	case 1:
+		id, ok := ids[0].Text()
+		if !ok {
+			return "", errors.New("invalid unique identifier")
+		}
+		return id, nil
+	default:
+		return "", fmt.Errorf("%d objects with name '%s' exist", len(ids), s.config.Prefix+name)
+	}
+}
+
+// attribute returns a KMIP attribute with the given name and value.
+// The value's tag must be tagAttributeValue.
+func attribute(name string, value item) item {
+	return structure(tagAttribute,
+		textString(tagAttributeName, name),
+		value,
+	)
+}
+
+// isNotFound reports whether err is a KMIP error indicating
+// that the object does not exist.
+func isNotFound(err error) bool {
+	var kmipErr *kmipError
+	return errors.As(err, &kmipErr) && kmipErr.Reason == reasonItemNotFound
+}
@@ -0,0 +1,313 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kmip
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/https"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStore(t *testing.T) {
+	srv, rootCAs := newFakeServer(t)
+	defer srv.Close()
+
+	ctx := context.Background()
+	store, err := Connect(ctx, &Config{
+		Endpoint: srv.Addr().String(),
+		Prefix:   "kes/",
+		TLS: &tls.Config{
+			ServerName: "localhost",
+			RootCAs:    rootCAs,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	testStore(ctx, t, store)
+}
+
+// TestStoreInterop runs the Store tests against a real KMIP
+// server, like an enterprise key manager or HSM appliance.
+// It is skipped unless KES_KMIP_ENDPOINT is set. The client
+// certificate and private key are read from the files at
+// KES_KMIP_CERT and KES_KMIP_KEY. The server certificate is
+// verified using the CA certificates at KES_KMIP_CA, if set.
+func TestStoreInterop(t *testing.T) {
+	endpoint := os.Getenv("KES_KMIP_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("Skipping KMIP interoperability test: KES_KMIP_ENDPOINT is not set")
+	}
+	certificate, err := https.CertificateFromFile(os.Getenv("KES_KMIP_CERT"), os.Getenv("KES_KMIP_KEY"), "")
+	if err != nil {
+		t.Fatalf("Failed to read client certificate: %v", err)
+	}
+	var rootCAs *x509.CertPool
+	if path := os.Getenv("KES_KMIP_CA"); path != "" {
+		if rootCAs, err = https.CertPoolFromFile(path); err != nil {
+			t.Fatalf("Failed to read CA certificates: %v", err)
+		}
+	}
+
+	var random [8]byte
+	if _, err = rand.Read(random[:]); err != nil {
+		t.Fatalf("Failed to generate key prefix: %v", err)
+	}
+	ctx := context.Background()
+	store, err := Connect(ctx, &Config{
+		Endpoint: endpoint,
+		Prefix:   "kes-test-" + hex.EncodeToString(random[:]) + "/",
+		TLS: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{certificate},
+			RootCAs:      rootCAs,
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	testStore(ctx, t, store)
+}
+
+// testStore creates, fetches, lists and deletes keys and
+// removes all remaining keys once the test finishes.
+func testStore(ctx context.Context, t *testing.T, store *Store) {
+	names := []string{"my-key", "My_Key", "my-key-2"}
+	defer func() {
+		for _, name := range names {
+			store.Delete(ctx, name)
+		}
+	}()
+
+	for _, name := range names {
+		if err := store.Create(ctx, name, []byte("value-"+name)); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err := store.Create(ctx, names[0], nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range names {
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch key '%s': %v", name, err)
+		}
+		if string(value) != "value-"+name {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, value, "value-"+name)
+		}
+	}
+
+	list, _, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if want := []string{"my-key", "my-key-2"}; !slices.Equal(list, want) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", list, want)
+	}
+
+	if err := store.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", names[0], err)
+	}
+	if _, err := store.Get(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Fetching deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err := store.Delete(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+}
+
+// newFakeServer returns a TLS listener that serves the subset
+// of KMIP operations used by the Store, and a certificate pool
+// containing its certificate.
+func newFakeServer(t *testing.T) (net.Listener, *x509.CertPool) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, priv.Public(), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(cert)
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{raw}, PrivateKey: priv}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type Object struct {
+		Name, Group string
+		Value       []byte
+	}
+	var (
+		lock    sync.Mutex
+		nextID  int
+		objects = map[string]Object{}
+	)
+	handle := func(op operation, payload item) (item, uint32) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		switch op {
+		case opDiscoverVersions:
+			return structure(tagResponsePayload), 0
+		case opRegister:
+			var obj Object
+			template, _ := payload.Find(tagTemplateAttribute)
+			for _, attr := range template.FindAll(tagAttribute) {
+				name, _ := attr.Find(tagAttributeName)
+				value, _ := attr.Find(tagAttributeValue)
+				switch name.Value {
+				case "Name":
+					nameValue, _ := value.Find(tagNameValue)
+					obj.Name, _ = nameValue.Text()
+				case "Object Group":
+					obj.Group, _ = value.Text()
+				}
+			}
+			secret, _ := payload.Find(tagSecretData)
+			block, _ := secret.Find(tagKeyBlock)
+			keyValue, _ := block.Find(tagKeyValue)
+			material, _ := keyValue.Find(tagKeyMaterial)
+			obj.Value, _ = material.Bytes()
+
+			nextID++
+			id := strconv.Itoa(nextID)
+			objects[id] = obj
+			return structure(tagResponsePayload, textString(tagUniqueIdentifier, id)), 0
+		case opLocate:
+			var name, group string
+			for _, attr := range payload.FindAll(tagAttribute) {
+				attrName, _ := attr.Find(tagAttributeName)
+				value, _ := attr.Find(tagAttributeValue)
+				switch attrName.Value {
+				case "Name":
+					nameValue, _ := value.Find(tagNameValue)
+					name, _ = nameValue.Text()
+				case "Object Group":
+					group, _ = value.Text()
+				}
+			}
+			var ids []item
+			for id, obj := range objects {
+				if (name == "" || obj.Name == name) && (group == "" || obj.Group == group) {
+					ids = append(ids, textString(tagUniqueIdentifier, id))
+				}
+			}
+			return structure(tagResponsePayload, ids...), 0
+		case opGet, opGetAttributes, opDestroy:
+			idItem, _ := payload.Find(tagUniqueIdentifier)
+			id, _ := idItem.Text()
+			obj, ok := objects[id]
+			if !ok {
+				return item{}, reasonItemNotFound
+			}
+			switch op {
+			case opGet:
+				return structure(tagResponsePayload,
+					enumeration(tagObjectType, objectTypeSecretData),
+					idItem,
+					structure(tagSecretData,
+						enumeration(tagSecretDataType, secretDataTypeSeed),
+						structure(tagKeyBlock,
+							enumeration(tagKeyFormatType, keyFormatTypeOpaque),
+							structure(tagKeyValue, byteString(tagKeyMaterial, obj.Value)),
+						),
+					),
+				), 0
+			case opGetAttributes:
+				return structure(tagResponsePayload,
+					idItem,
+					attribute("Name", structure(tagAttributeValue,
+						textString(tagNameValue, obj.Name),
+						enumeration(tagNameType, nameTypeText),
+					)),
+				), 0
+			default:
+				delete(objects, id)
+				return structure(tagResponsePayload, idItem), 0
+			}
+		default:
+			return item{}, 0x04 // Operation not supported
+		}
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				for {
+					request, err := readMessage(conn)
+					if err != nil {
+						return
+					}
+					batch, _ := request.Find(tagBatchItem)
+					opItem, _ := batch.Find(tagOperation)
+					op, _ := opItem.Uint32()
+					payload, _ := batch.Find(tagRequestPayload)
+
+					result := []item{opItem}
+					if resp, reason := handle(operation(op), payload); reason == 0 {
+						result = append(result, enumeration(tagResultStatus, statusSuccess), resp)
+					} else {
+						result = append(result, enumeration(tagResultStatus, 0x01), enumeration(tagResultReason, reason))
+					}
+					response, err := structure(tagResponseMessage,
+						structure(tagResponseHeader, integer(tagBatchCount, 1)),
+						structure(tagBatchItem, result...),
+					).MarshalBinary()
+					if err != nil {
+						return
+					}
+					if _, err = conn.Write(response); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return ln, rootCAs
+}
@@ -0,0 +1,271 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kmip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// tag is a KMIP tag that identifies a TTLV item.
+type tag uint32
+
+// KMIP tags used by the Store.
+const (
+	tagAttribute            tag = 0x420008
+	tagAttributeName        tag = 0x42000A
+	tagAttributeValue       tag = 0x42000B
+	tagBatchCount           tag = 0x42000D
+	tagBatchItem            tag = 0x42000F
+	tagKeyBlock             tag = 0x420040
+	tagKeyFormatType        tag = 0x420042
+	tagKeyMaterial          tag = 0x420043
+	tagKeyValue             tag = 0x420045
+	tagName                 tag = 0x420053
+	tagNameType             tag = 0x420054
+	tagNameValue            tag = 0x420055
+	tagObjectGroup          tag = 0x420056
+	tagObjectType           tag = 0x420057
+	tagOperation            tag = 0x42005C
+	tagProtocolVersion      tag = 0x420069
+	tagProtocolVersionMajor tag = 0x42006A
+	tagProtocolVersionMinor tag = 0x42006B
+	tagRequestHeader        tag = 0x420077
+	tagRequestMessage       tag = 0x420078
+	tagRequestPayload       tag = 0x420079
+	tagResponseHeader       tag = 0x42007A
+	tagResponseMessage      tag = 0x42007B
+	tagResponsePayload      tag = 0x42007C
+	tagResultMessage        tag = 0x42007D
+	tagResultReason         tag = 0x42007E
+	tagResultStatus         tag = 0x42007F
+	tagSecretData           tag = 0x420085
+	tagSecretDataType       tag = 0x420086
+	tagTemplateAttribute    tag = 0x420091
+	tagUniqueIdentifier     tag = 0x420094
+)
+
+// itemType is the type of a TTLV item.
+type itemType byte
+
+// KMIP item types.
+const (
+	typeStructure   itemType = 0x01
+	typeInteger     itemType = 0x02
+	typeLongInteger itemType = 0x03
+	typeBigInteger  itemType = 0x04
+	typeEnumeration itemType = 0x05
+	typeBoolean     itemType = 0x06
+	typeTextString  itemType = 0x07
+	typeByteString  itemType = 0x08
+	typeDateTime    itemType = 0x09
+	typeInterval    itemType = 0x0A
+)
+
+// item is a TTLV encoded KMIP item.
+//
+// The Go type of its value depends on the item type:
+//   - Structure:                          []item
+//   - Integer, Enumeration, Interval:     uint32
+//   - LongInteger, DateTime:              int64
+//   - Boolean:                            bool
+//   - TextString:                         string
+//   - ByteString, BigInteger:             []byte
+type item struct {
+	Tag   tag
+	Type  itemType
+	Value any
+}
+
+func structure(t tag, items ...item) item { return item{Tag: t, Type: typeStructure, Value: items} }
+
+func enumeration(t tag, v uint32) item { return item{Tag: t, Type: typeEnumeration, Value: v} }
+
+func integer(t tag, v uint32) item { return item{Tag: t, Type: typeInteger, Value: v} }
+
+func textString(t tag, v string) item { return item{Tag: t, Type: typeTextString, Value: v} }
+
+func byteString(t tag, v []byte) item { return item{Tag: t, Type: typeByteString, Value: v} }
+
+// Find returns the first item with the given tag
+// within the structure i.
+func (i item) Find(t tag) (item, bool) {
+	items, _ := i.Value.([]item)
+	for _, v := range items {
+		if v.Tag == t {
+			return v, true
+		}
+	}
+	return item{}, false
+}
+
+// FindAll returns all items with the given tag
+// within the structure i.
+func (i item) FindAll(t tag) []item {
+	var found []item
+	items, _ := i.Value.([]item)
+	for _, v := range items {
+		if v.Tag == t {
+			found = append(found, v)
+		}
+	}
+	return found
+}
+
+// Uint32 returns the value of an Integer, Enumeration
+// or Interval item.
+func (i item) Uint32() (uint32, bool) {
+	v, ok := i.Value.(uint32)
+	return v, ok
+}
+
+// Text returns the value of a TextString item.
+func (i item) Text() (string, bool) {
+	v, ok := i.Value.(string)
+	return v, ok
+}
+
+// Bytes returns the value of a ByteString item.
+func (i item) Bytes() ([]byte, bool) {
+	v, ok := i.Value.([]byte)
+	return v, ok
+}
+
+// MarshalBinary returns the TTLV encoding of i.
+func (i item) MarshalBinary() ([]byte, error) {
+	return i.append(nil)
+}
+
+func (i item) append(b []byte) ([]byte, error) {
+	start := len(b)
+	b = binary.BigEndian.AppendUint32(b, uint32(i.Tag)<<8|uint32(i.Type))
+	b = append(b, 0, 0, 0, 0) // Length - set below
+
+	var err error
+	switch v := i.Value.(type) {
+	case []item:
+		if i.Type != typeStructure {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a structure", i.Tag, i.Type)
+		}
+		for _, item := range v {
+			if b, err = item.append(b); err != nil {
+				return nil, err
+			}
+		}
+	case uint32:
+		if i.Type != typeInteger && i.Type != typeEnumeration && i.Type != typeInterval {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a 32 bit integer", i.Tag, i.Type)
+		}
+		b = binary.BigEndian.AppendUint32(b, v)
+	case int64:
+		if i.Type != typeLongInteger && i.Type != typeDateTime {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a 64 bit integer", i.Tag, i.Type)
+		}
+		b = binary.BigEndian.AppendUint64(b, uint64(v))
+	case bool:
+		if i.Type != typeBoolean {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a boolean", i.Tag, i.Type)
+		}
+		if v {
+			b = binary.BigEndian.AppendUint64(b, 1)
+		} else {
+			b = binary.BigEndian.AppendUint64(b, 0)
+		}
+	case string:
+		if i.Type != typeTextString {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a text string", i.Tag, i.Type)
+		}
+		b = append(b, v...)
+	case []byte:
+		if i.Type != typeByteString && i.Type != typeBigInteger {
+			return nil, fmt.Errorf("kmip: invalid item %06x: type %02x is not a byte string", i.Tag, i.Type)
+		}
+		b = append(b, v...)
+	default:
+		return nil, fmt.Errorf("kmip: invalid item %06x: unsupported value type %T", i.Tag, i.Value)
+	}
+
+	// The length does not include the padding while the value
+	// of a structure contains the padding of its items.
+	length := len(b) - start - 8
+	binary.BigEndian.PutUint32(b[start+4:], uint32(length))
+	if r := length % 8; r != 0 {
+		b = append(b, make([]byte, 8-r)...)
+	}
+	return b, nil
+}
+
+// UnmarshalBinary parses a TTLV encoded item.
+func (i *item) UnmarshalBinary(b []byte) error {
+	v, n, err := parseItem(b)
+	if err != nil {
+		return err
+	}
+	if n != len(b) {
+		return errors.New("kmip: invalid item: trailing data")
+	}
+	*i = v
+	return nil
+}
+
+// parseItem parses the first TTLV item within b and returns
+// it along with the number of bytes, including padding, it
+// occupies.
+func parseItem(b []byte) (item, int, error) {
+	if len(b) < 8 {
+		return item{}, 0, errors.New("kmip: invalid item: too short")
+	}
+	var (
+		t      = tag(binary.BigEndian.Uint32(b) >> 8)
+		typ    = itemType(b[3])
+		length = int(binary.BigEndian.Uint32(b[4:]))
+		size   = 8 + length
+	)
+	if r := length % 8; r != 0 {
+		size += 8 - r
+	}
+	if length > len(b)-8 || size > len(b) {
+		return item{}, 0, fmt.Errorf("kmip: invalid item %06x: length exceeds message", t)
+	}
+	value := b[8 : 8+length]
+
+	i := item{Tag: t, Type: typ}
+	switch typ {
+	case typeStructure:
+		items := []item{}
+		for len(value) > 0 {
+			v, n, err := parseItem(value)
+			if err != nil {
+				return item{}, 0, err
+			}
+			items = append(items, v)
+			value = value[n:]
+		}
+		i.Value = items
+	case typeInteger, typeEnumeration, typeInterval:
+		if length != 4 {
+			return item{}, 0, fmt.Errorf("kmip: invalid item %06x: invalid length %d", t, length)
+		}
+		i.Value = binary.BigEndian.Uint32(value)
+	case typeLongInteger, typeDateTime:
+		if length != 8 {
+			return item{}, 0, fmt.Errorf("kmip: invalid item %06x: invalid length %d", t, length)
+		}
+		i.Value = int64(binary.BigEndian.Uint64(value))
+	case typeBoolean:
+		if length != 8 {
+			return item{}, 0, fmt.Errorf("kmip: invalid item %06x: invalid length %d", t, length)
+		}
+		i.Value = binary.BigEndian.Uint64(value) != 0
+	case typeTextString:
+		i.Value = string(value)
+	case typeByteString, typeBigInteger:
+		i.Value = append([]byte{}, value...)
+	default:
+		return item{}, 0, fmt.Errorf("kmip: invalid item %06x: unknown type %02x", t, typ)
+	}
+	return i, size, nil
+}
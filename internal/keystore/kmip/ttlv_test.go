@@ -0,0 +1,93 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kmip
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+func TestItemMarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range marshalBinaryTests {
+		b, err := test.Item.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Test %d: failed to marshal item: %v", i, err)
+		}
+		want, _ := hex.DecodeString(test.Encoding)
+		if !bytes.Equal(b, want) {
+			t.Fatalf("Test %d: got '%x' - want '%x'", i, b, want)
+		}
+
+		var item item
+		if err = item.UnmarshalBinary(b); err != nil {
+			t.Fatalf("Test %d: failed to unmarshal item: %v", i, err)
+		}
+		if !reflect.DeepEqual(item, test.Item) {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, item, test.Item)
+		}
+	}
+}
+
+func TestItemUnmarshalBinary(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range unmarshalBinaryTests {
+		b, _ := hex.DecodeString(test)
+
+		var item item
+		if err := item.UnmarshalBinary(b); err == nil {
+			t.Fatalf("Test %d: unmarshaling invalid item succeeded", i)
+		}
+	}
+}
+
+// The encodings are taken from the KMIP 1.2 specification,
+// section 9.1.2.
+var marshalBinaryTests = []struct {
+	Item     item
+	Encoding string
+}{
+	{ // 0
+		Item:     integer(0x420020, 8),
+		Encoding: "42002002000000040000000800000000",
+	},
+	{ // 1
+		Item:     item{Tag: 0x420020, Type: typeLongInteger, Value: int64(123456789000000000)},
+		Encoding: "420020030000000801b69b4ba5749200",
+	},
+	{ // 2
+		Item:     enumeration(0x420020, 255),
+		Encoding: "4200200500000004000000ff00000000",
+	},
+	{ // 3
+		Item:     item{Tag: 0x420020, Type: typeBoolean, Value: true},
+		Encoding: "42002006000000080000000000000001",
+	},
+	{ // 4
+		Item:     textString(0x420020, "Hello World"),
+		Encoding: "420020070000000b48656c6c6f20576f726c640000000000",
+	},
+	{ // 5
+		Item:     byteString(0x420020, []byte{0x01, 0x02, 0x03}),
+		Encoding: "42002008000000030102030000000000",
+	},
+	{ // 6
+		Item:     structure(0x420020, enumeration(0x420004, 254), integer(0x420005, 255)),
+		Encoding: "42002001000000204200040500000004000000fe000000004200050200000004000000ff00000000",
+	},
+}
+
+var unmarshalBinaryTests = []string{
+	"420020020000000400000008",                                 // 0: missing padding
+	"42002002000000080000000800000000",                         // 1: invalid integer length
+	"42002011000000040000000800000000",                         // 2: unknown type
+	"420020070000000b48656c6c6f",                               // 3: length exceeds message
+	"4200200200000004000000080000000000",                       // 4: trailing data
+	"42002001000000104200040500000004000000fe00000000420005ff", // 5: invalid structure length
+}
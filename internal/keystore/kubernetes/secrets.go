@@ -0,0 +1,409 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package kubernetes implements a key store that stores
+// keys as Kubernetes Secrets within a namespace.
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base32"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Default paths of the service account credentials
+// mounted into each Kubernetes pod.
+const (
+	DefaultTokenFile     = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	DefaultCAFile        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	DefaultNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+)
+
+// Config is a structure containing configuration
+// options for connecting to the Kubernetes API server.
+type Config struct {
+	// Endpoint is the Kubernetes API server endpoint.
+	// If empty, the in-cluster endpoint specified by
+	// the KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT
+	// env. variables is used.
+	Endpoint string
+
+	// Namespace is the namespace in which the Secrets are
+	// stored. If empty, the namespace of the service account
+	// is used.
+	Namespace string
+
+	// Prefix is an optional prefix added to the name of each
+	// Secret. It must be a valid Kubernetes resource name
+	// prefix - e.g. "kes-".
+	Prefix string
+
+	// TokenFile is the path to the service account token.
+	// If empty, defaults to DefaultTokenFile. The file is
+	// read before each request since Kubernetes rotates
+	// projected service account tokens.
+	TokenFile string
+
+	// CAPath is the path to the CA certificate(s) for
+	// verifying the API server TLS certificate. If
+	// empty, defaults to DefaultCAFile.
+	CAPath string
+}
+
+// Connect connects to the Kubernetes API server and returns
+// a Store that stores keys as Secrets within the configured
+// namespace.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	c := *config
+	if c.Endpoint == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kubernetes: no endpoint specified and not running inside a Kubernetes cluster")
+		}
+		c.Endpoint = "https://" + net.JoinHostPort(host, port)
+	}
+	if c.TokenFile == "" {
+		c.TokenFile = DefaultTokenFile
+	}
+	if c.CAPath == "" {
+		c.CAPath = DefaultCAFile
+	}
+	if c.Namespace == "" {
+		namespace, err := os.ReadFile(DefaultNamespaceFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: no namespace specified: %v", err)
+		}
+		c.Namespace = strings.TrimSpace(string(namespace))
+	}
+
+	pem, err := os.ReadFile(c.CAPath)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: failed to read CA certificate: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("kubernetes: no CA certificate found in '%s'", c.CAPath)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs,
+	}
+	s := &Store{
+		config: c,
+		client: xhttp.Retry{Client: http.Client{Transport: transport}},
+	}
+	if _, err = s.Status(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store is a key store that stores keys as Kubernetes Secrets.
+type Store struct {
+	config Config
+	client xhttp.Retry
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// Secrets created by the Store carry the following label
+// such that they can be listed without listing unrelated
+// Secrets within the same namespace.
+const (
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedBy      = "kes"
+)
+
+// Each Secret stores the key under the dataKey and the
+// original KES key name as nameAnnotation.
+const (
+	dataKey        = "key"
+	nameAnnotation = "kes.min.io/key-name"
+)
+
+// String returns a string representation of the Store.
+func (s *Store) String() string {
+	return "Kubernetes: " + s.config.Endpoint + " (namespace " + s.config.Namespace + ")"
+}
+
+// Status returns the current state of the Kubernetes API server.
+// In particular, whether it is reachable and the network latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	query := url.Values{}
+	query.Set("limit", "1")
+	query.Set("labelSelector", managedByLabel+"="+managedBy)
+
+	start := time.Now()
+	resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+	if err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{
+			Err: fmt.Errorf("kubernetes: failed to fetch status: %v", err),
+		}
+	}
+	latency := time.Since(start)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return kes.KeyStoreState{}, fmt.Errorf("kubernetes: failed to fetch status: %v", parseErrorResponse(resp))
+	}
+	return kes.KeyStoreState{
+		Latency: latency,
+	}, nil
+}
+
+// Create creates a new Secret with the given name and value
+// if and only if no such Secret exists. Otherwise, it returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	}
+	type Request struct {
+		APIVersion string            `json:"apiVersion"`
+		Kind       string            `json:"kind"`
+		Metadata   Metadata          `json:"metadata"`
+		Type       string            `json:"type"`
+		Immutable  bool              `json:"immutable"`
+		Data       map[string][]byte `json:"data"`
+	}
+	body, err := json.Marshal(Request{
+		APIVersion: "v1",
+		Kind:       "Secret",
+		Metadata: Metadata{
+			Name:        s.secretName(name),
+			Labels:      map[string]string{managedByLabel: managedBy},
+			Annotations: map[string]string{nameAnnotation: name},
+		},
+		Type:      "Opaque",
+		Immutable: true,
+		Data:      map[string][]byte{dataKey: value},
+	})
+	if err != nil {
+		return fmt.Errorf("kubernetes: failed to create key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, "", nil, body)
+	if err != nil {
+		return fmt.Errorf("kubernetes: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusAccepted:
+		return nil
+	case http.StatusConflict:
+		return kesdk.ErrKeyExists
+	default:
+		return fmt.Errorf("kubernetes: failed to create key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// Get returns the value of the Secret with the given name.
+// If no such Secret exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Response struct {
+		Data map[string][]byte `json:"data"`
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.secretName(name), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: failed to fetch key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, kesdk.ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("kubernetes: failed to fetch key '%s': %v", name, parseErrorResponse(resp))
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, 2*mem.MB)).Decode(&response); err != nil {
+		return nil, fmt.Errorf("kubernetes: failed to fetch key '%s': %v", name, err)
+	}
+	value, ok := response.Data[dataKey]
+	if !ok {
+		return nil, fmt.Errorf("kubernetes: failed to fetch key '%s': secret contains no '%s' entry", name, dataKey)
+	}
+	return value, nil
+}
+
+// Delete deletes the Secret with the given name. If no such
+// Secret exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	resp, err := s.do(ctx, http.MethodDelete, s.secretName(name), nil, nil)
+	if err != nil {
+		return fmt.Errorf("kubernetes: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return nil
+	case http.StatusNotFound:
+		return kesdk.ErrKeyNotFound
+	default:
+		return fmt.Errorf("kubernetes: failed to delete key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	type Response struct {
+		Metadata struct {
+			Continue string `json:"continue"`
+		} `json:"metadata"`
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+
+	query := url.Values{}
+	query.Set("limit", "500")
+	query.Set("labelSelector", managedByLabel+"="+managedBy)
+
+	var names []string
+	for {
+		resp, err := s.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, "", fmt.Errorf("kubernetes: failed to list keys: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = parseErrorResponse(resp)
+			resp.Body.Close()
+			return nil, "", fmt.Errorf("kubernetes: failed to list keys: %v", err)
+		}
+
+		var response Response
+		err = json.NewDecoder(mem.LimitReader(resp.Body, 10*mem.MB)).Decode(&response)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("kubernetes: failed to list keys: %v", err)
+		}
+		for _, item := range response.Items {
+			if name, ok := s.keyName(item.Metadata.Name); ok {
+				names = append(names, name)
+			}
+		}
+		if response.Metadata.Continue == "" {
+			break
+		}
+		query.Set("continue", response.Metadata.Continue)
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the Store.
+func (s *Store) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// do sends a request to the secrets resource of the configured
+// namespace, or to the Secret with the given name, if not empty.
+func (s *Store) do(ctx context.Context, method, name string, query url.Values, body []byte) (*http.Response, error) {
+	token, err := os.ReadFile(s.config.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %v", err)
+	}
+
+	elems := []string{"api", "v1", "namespaces", url.PathEscape(s.config.Namespace), "secrets"}
+	if name != "" {
+		elems = append(elems, url.PathEscape(name))
+	}
+	u, err := url.JoinPath(s.config.Endpoint, elems...)
+	if err != nil {
+		return nil, err
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Accept", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	return s.client.Do(req)
+}
+
+// nameEncoding encodes key names as valid Kubernetes resource
+// names. Key names may contain characters, like upper case
+// letters or '_', that are not allowed in resource names. The
+// base32 hex alphabet only contains lower case letters and
+// digits and preserves the sort order of the key names.
+var nameEncoding = base32.NewEncoding("0123456789abcdefghijklmnopqrstuv").WithPadding(base32.NoPadding)
+
+// secretName returns the name of the Secret storing the key
+// with the given name.
+func (s *Store) secretName(name string) string {
+	return s.config.Prefix + nameEncoding.EncodeToString([]byte(name))
+}
+
+// keyName returns the name of the key stored in the Secret
+// with the given name. It reports whether secret is the name
+// of a Secret created by the Store.
+func (s *Store) keyName(secret string) (string, bool) {
+	encName, ok := strings.CutPrefix(secret, s.config.Prefix)
+	if !ok || encName == "" {
+		return "", false
+	}
+	name, err := nameEncoding.DecodeString(encName)
+	if err != nil {
+		return "", false
+	}
+	return string(name), true
+}
+
+// parseErrorResponse parses a Kubernetes HTTP error response.
+func parseErrorResponse(resp *http.Response) error {
+	type Response struct {
+		Message string `json:"message"`
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil || response.Message == "" {
+		return errors.New(resp.Status)
+	}
+	return errors.New(resp.Status + ": " + response.Message)
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStore(t *testing.T) {
+	const (
+		Namespace = "kes"
+		Token     = "service-account-token"
+	)
+	srv := newFakeAPIServer(t, Namespace, Token)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	tokenFile, caFile := filepath.Join(dir, "token"), filepath.Join(dir, "ca.crt")
+	if err := os.WriteFile(tokenFile, []byte(Token+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, ca, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	store, err := Connect(ctx, &Config{
+		Endpoint:  srv.URL,
+		Namespace: Namespace,
+		Prefix:    "kes-",
+		TokenFile: tokenFile,
+		CAPath:    caFile,
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	names := []string{"my-key", "My_Key", "my-key-2"}
+	for _, name := range names {
+		if err = store.Create(ctx, name, []byte("value-"+name)); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = store.Create(ctx, names[0], nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range names {
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch key '%s': %v", name, err)
+		}
+		if string(value) != "value-"+name {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, value, "value-"+name)
+		}
+	}
+
+	list, _, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if want := []string{"my-key", "my-key-2"}; !slices.Equal(list, want) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", list, want)
+	}
+
+	if err = store.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", names[0], err)
+	}
+	if _, err = store.Get(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Fetching deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err = store.Delete(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	s := &Store{config: Config{Prefix: "kes-"}}
+	for _, name := range []string{"a", "my-key", "My_Key", "key.with.dots", strings.Repeat("x", 100)} {
+		secret := s.secretName(name)
+		if secret != strings.ToLower(secret) {
+			t.Fatalf("Secret name '%s' of key '%s' contains upper case letters", secret, name)
+		}
+		if got, ok := s.keyName(secret); !ok || got != name {
+			t.Fatalf("Invalid key name of secret '%s': got '%s' - want '%s'", secret, got, name)
+		}
+	}
+	if _, ok := s.keyName("default-token"); ok {
+		t.Fatal("Secret 'default-token' must not be a key")
+	}
+}
+
+// newFakeAPIServer returns a TLS server that implements
+// the subset of the Kubernetes secrets API used by the
+// Store.
+func newFakeAPIServer(t *testing.T, namespace, token string) *httptest.Server {
+	type Secret struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Data map[string][]byte `json:"data"`
+	}
+	var (
+		lock    sync.Mutex
+		secrets = map[string][]byte{}
+	)
+
+	prefix := "/api/v1/namespaces/" + namespace + "/secrets"
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		name, ok := strings.CutPrefix(r.URL.Path, prefix)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		name = strings.TrimPrefix(name, "/")
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch {
+		case r.Method == http.MethodPost && name == "":
+			var body bytes.Buffer
+			body.ReadFrom(r.Body)
+
+			var secret Secret
+			if err := json.Unmarshal(body.Bytes(), &secret); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if secret.Metadata.Labels[managedByLabel] != managedBy {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if _, ok := secrets[secret.Metadata.Name]; ok {
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]string{"message": "already exists"})
+				return
+			}
+			secrets[secret.Metadata.Name] = body.Bytes()
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body.Bytes())
+		case r.Method == http.MethodGet && name == "":
+			var items []json.RawMessage
+			for _, secret := range secrets {
+				items = append(items, secret)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"items": items})
+		case r.Method == http.MethodGet:
+			secret, ok := secrets[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(secret)
+		case r.Method == http.MethodDelete:
+			if _, ok := secrets[name]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			delete(secrets, name)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
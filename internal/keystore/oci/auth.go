@@ -0,0 +1,352 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"aead.dev/mem"
+	xhttp "github.com/minio/kes/internal/http"
+)
+
+// signer signs requests to the OCI API.
+type signer interface {
+	Sign(ctx context.Context, req *http.Request, body []byte) error
+}
+
+// apiKeySigner signs requests with the API signing key
+// of an OCI user.
+type apiKeySigner struct {
+	keyID string // <tenancy OCID>/<user OCID>/<key fingerprint>
+	key   *rsa.PrivateKey
+}
+
+func newAPIKeySigner(apiKey *APIKey) (*apiKeySigner, error) {
+	pemKey, err := os.ReadFile(apiKey.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API signing key: %v", err)
+	}
+	key, err := parsePrivateKey(pemKey)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKeySigner{
+		keyID: apiKey.TenancyID + "/" + apiKey.UserID + "/" + apiKey.Fingerprint,
+		key:   key,
+	}, nil
+}
+
+func (s *apiKeySigner) Sign(_ context.Context, req *http.Request, body []byte) error {
+	return signRequest(req, body, s.keyID, s.key)
+}
+
+// instancePrincipalSigner signs requests as the compute
+// instance it is running on.
+//
+// It fetches the instance's X.509 certificate and private
+// key from the instance metadata service and exchanges
+// them for a short-lived security token at the OCI auth
+// service. Requests are signed with an ephemeral session
+// key bound to the security token.
+type instancePrincipalSigner struct {
+	metadataEndpoint   string
+	federationEndpoint string
+	client             xhttp.Retry
+
+	lock       sync.Mutex
+	token      string
+	expiry     time.Time
+	sessionKey *rsa.PrivateKey
+}
+
+func newInstancePrincipalSigner(region string) *instancePrincipalSigner {
+	return &instancePrincipalSigner{
+		metadataEndpoint:   "http://169.254.169.254/opc/v2",
+		federationEndpoint: "https://auth." + region + ".oraclecloud.com/v1/x509",
+	}
+}
+
+func (s *instancePrincipalSigner) Sign(ctx context.Context, req *http.Request, body []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	// Refresh the security token some time before it expires
+	// such that in-flight requests don't fail.
+	if s.token == "" || time.Until(s.expiry) < 5*time.Minute {
+		if err := s.refresh(ctx); err != nil {
+			return fmt.Errorf("failed to obtain instance principal security token: %v", err)
+		}
+	}
+	return signRequest(req, body, "ST$"+s.token, s.sessionKey)
+}
+
+// refresh obtains a new security token from the OCI auth service.
+func (s *instancePrincipalSigner) refresh(ctx context.Context) error {
+	certPEM, err := s.fetchMetadata(ctx, "identity/cert.pem")
+	if err != nil {
+		return err
+	}
+	keyPEM, err := s.fetchMetadata(ctx, "identity/key.pem")
+	if err != nil {
+		return err
+	}
+	intermediatePEM, err := s.fetchMetadata(ctx, "identity/intermediate.pem")
+	if err != nil {
+		return err
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return errors.New("instance certificate is not PEM-encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse instance certificate: %v", err)
+	}
+	tenancy, err := tenancyID(cert)
+	if err != nil {
+		return err
+	}
+	key, err := parsePrivateKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	var intermediates []string
+	for rest := intermediatePEM; ; {
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		intermediates = append(intermediates, base64.StdEncoding.EncodeToString(block.Bytes))
+	}
+
+	sessionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return err
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(&sessionKey.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	type Request struct {
+		Certificate              string   `json:"certificate"`
+		PublicKey                string   `json:"publicKey"`
+		IntermediateCertificates []string `json:"intermediateCertificates"`
+		Purpose                  string   `json:"purpose"`
+		FingerprintAlgorithm     string   `json:"fingerprintAlgorithm"`
+	}
+	body, err := json.Marshal(Request{
+		Certificate:              base64.StdEncoding.EncodeToString(cert.Raw),
+		PublicKey:                base64.StdEncoding.EncodeToString(publicKey),
+		IntermediateCertificates: intermediates,
+		Purpose:                  "DEFAULT",
+		FingerprintAlgorithm:     "SHA256",
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.federationEndpoint, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Content-Type", "application/json")
+	if err = signRequest(req, body, tenancy+"/fed-x509/"+fingerprint(cert), key); err != nil {
+		return err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return parseErrorResponse(resp)
+	}
+
+	type Response struct {
+		Token string `json:"token"`
+	}
+	var response Response
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil {
+		return err
+	}
+	expiry, err := tokenExpiry(response.Token)
+	if err != nil {
+		return err
+	}
+	s.token, s.expiry, s.sessionKey = response.Token, expiry, sessionKey
+	return nil
+}
+
+// fetchMetadata fetches the resource at the given path from
+// the instance metadata service.
+func (s *instancePrincipalSigner) fetchMetadata(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.metadataEndpoint+"/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer Oracle")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch instance metadata '%s': %s", path, resp.Status)
+	}
+	return io.ReadAll(mem.LimitReader(resp.Body, mem.MB))
+}
+
+// signRequest signs the request, with the given body, as
+// specified by the OCI request signature scheme - a variant
+// of the HTTP signatures draft:
+// https://docs.oracle.com/iaas/Content/API/Concepts/signingrequests.htm
+func signRequest(req *http.Request, body []byte, keyID string, key *rsa.PrivateKey) error {
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+
+	headers := []string{"date", "(request-target)", "host"}
+	switch req.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		sum := sha256.Sum256(body)
+		req.Header.Set("X-Content-Sha256", base64.StdEncoding.EncodeToString(sum[:]))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		if req.Header.Get("Content-Type") == "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		headers = append(headers, "content-length", "content-type", "x-content-sha256")
+	}
+
+	var signingString strings.Builder
+	for i, h := range headers {
+		if i > 0 {
+			signingString.WriteByte('\n')
+		}
+		switch h {
+		case "(request-target)":
+			signingString.WriteString(h + ": " + strings.ToLower(req.Method) + " " + req.URL.RequestURI())
+		case "host":
+			signingString.WriteString(h + ": " + host)
+		default:
+			signingString.WriteString(h + ": " + req.Header.Get(h))
+		}
+	}
+
+	digest := sha256.Sum256([]byte(signingString.String()))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf(
+		`Signature version="1",keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(signature),
+	))
+	return nil
+}
+
+// parsePrivateKey parses a PEM-encoded PKCS#1 or PKCS#8
+// RSA private key.
+func parsePrivateKey(pemKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("private key is not PEM-encoded")
+	}
+	if _, ok := block.Headers["DEK-Info"]; ok {
+		return nil, errors.New("encrypted private keys are not supported")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is of type '%T' - want RSA private key", key)
+	}
+	return rsaKey, nil
+}
+
+// tenancyID returns the tenancy OCID embedded into the
+// subject of an instance certificate.
+func tenancyID(cert *x509.Certificate) (string, error) {
+	names := make([]string, 0, len(cert.Subject.OrganizationalUnit)+len(cert.Subject.Organization))
+	names = append(names, cert.Subject.OrganizationalUnit...)
+	names = append(names, cert.Subject.Organization...)
+	for _, name := range names {
+		if id, ok := strings.CutPrefix(name, "opc-tenant:"); ok {
+			return id, nil
+		}
+		if id, ok := strings.CutPrefix(name, "opc-identity:"); ok {
+			return id, nil
+		}
+	}
+	return "", errors.New("instance certificate contains no tenancy ID")
+}
+
+// fingerprint returns the SHA-1 fingerprint of the certificate
+// as colon-separated hex string - e.g. "AB:CD:...".
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	parts := make([]string, 0, len(sum))
+	for _, b := range sum {
+		parts = append(parts, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(parts, ":")
+}
+
+// tokenExpiry returns the expiry time of the given
+// security token. Security tokens are JWTs.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("security token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid security token: %v", err)
+	}
+
+	type Claims struct {
+		Expiry int64 `json:"exp"`
+	}
+	var claims Claims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid security token: %v", err)
+	}
+	if claims.Expiry == 0 {
+		return time.Time{}, errors.New("security token has no expiry")
+	}
+	return time.Unix(claims.Expiry, 0), nil
+}
@@ -0,0 +1,450 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package oci implements a key store that stores keys
+// as secrets in an Oracle Cloud Infrastructure (OCI) Vault.
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes"
+	xhttp "github.com/minio/kes/internal/http"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// APIKey is an OCI API signing key of an OCI user.
+type APIKey struct {
+	TenancyID   string // The OCID of the tenancy
+	UserID      string // The OCID of the user
+	Fingerprint string // The fingerprint of the API signing key
+
+	// PrivateKeyFile is the path to the PEM-encoded
+	// RSA private key of the API signing key.
+	PrivateKeyFile string
+}
+
+// Config is a structure containing configuration
+// options for connecting to an OCI Vault.
+type Config struct {
+	// Region is the OCI region of the Vault - e.g.
+	// "us-ashburn-1".
+	Region string
+
+	// VaultID is the OCID of the Vault.
+	VaultID string
+
+	// CompartmentID is the OCID of the compartment
+	// in which secrets are created.
+	CompartmentID string
+
+	// KeyID is the OCID of the Vault master encryption
+	// key used to encrypt secrets.
+	KeyID string
+
+	// Prefix is an optional prefix added to the name of
+	// each secret - e.g. "kes-".
+	Prefix string
+
+	// APIKey authenticates requests with the API signing
+	// key of an OCI user. Mutually exclusive with
+	// InstancePrincipal.
+	APIKey *APIKey
+
+	// InstancePrincipal authenticates requests as the
+	// compute instance KES is running on. Mutually
+	// exclusive with APIKey.
+	InstancePrincipal bool
+}
+
+// Connect connects to the OCI Vault and returns a Store
+// that stores keys as Vault secrets.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Region == "" {
+		return nil, errors.New("oci: no region specified")
+	}
+	if config.VaultID == "" {
+		return nil, errors.New("oci: no vault specified")
+	}
+	if config.CompartmentID == "" {
+		return nil, errors.New("oci: no compartment specified")
+	}
+	if config.KeyID == "" {
+		return nil, errors.New("oci: no master encryption key specified")
+	}
+	if config.APIKey != nil && config.InstancePrincipal {
+		return nil, errors.New("oci: API key and instance principal authentication are mutually exclusive")
+	}
+
+	var auth signer
+	switch {
+	case config.APIKey != nil:
+		s, err := newAPIKeySigner(config.APIKey)
+		if err != nil {
+			return nil, fmt.Errorf("oci: %v", err)
+		}
+		auth = s
+	case config.InstancePrincipal:
+		auth = newInstancePrincipalSigner(config.Region)
+	default:
+		return nil, errors.New("oci: no credentials specified")
+	}
+
+	s := &Store{
+		config:          *config,
+		vaultEndpoint:   "https://vaults." + config.Region + ".oci.oraclecloud.com",
+		secretsEndpoint: "https://secrets.vaults." + config.Region + ".oci.oraclecloud.com",
+		signer:          auth,
+	}
+	if _, err := s.Status(ctx); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Store is a key store that stores keys as OCI Vault secrets.
+//
+// OCI does not delete secrets immediately. Instead, secrets
+// are scheduled for deletion and deleted after a waiting
+// period of at least one day. The name of a secret pending
+// deletion cannot be reused until the secret has been deleted.
+type Store struct {
+	config          Config
+	vaultEndpoint   string // Vault management API
+	secretsEndpoint string // Secret retrieval API
+
+	signer signer
+	client xhttp.Retry
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// deletionDelay is the time after which a deleted secret
+// is removed permanently. OCI requires at least one day.
+const deletionDelay = 24*time.Hour + 10*time.Minute
+
+// String returns a string representation of the Store.
+func (s *Store) String() string { return "OCI Vault: " + s.config.VaultID }
+
+// Status returns the current state of the OCI Vault.
+// In particular, whether it is reachable and the network
+// latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	query := url.Values{}
+	query.Set("compartmentId", s.config.CompartmentID)
+	query.Set("vaultId", s.config.VaultID)
+	query.Set("limit", "1")
+
+	start := time.Now()
+	resp, err := s.do(ctx, http.MethodGet, s.vaultEndpoint, "/20180608/secrets", query, nil)
+	if err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{
+			Err: fmt.Errorf("oci: failed to fetch status: %v", err),
+		}
+	}
+	latency := time.Since(start)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return kes.KeyStoreState{}, fmt.Errorf("oci: failed to fetch status: %v", parseErrorResponse(resp))
+	}
+	return kes.KeyStoreState{
+		Latency: latency,
+	}, nil
+}
+
+// Create creates a new secret with the given name and value
+// if and only if no such secret exists. Otherwise, it returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	type Content struct {
+		ContentType string `json:"contentType"`
+		Content     string `json:"content"`
+	}
+	type Request struct {
+		CompartmentID string            `json:"compartmentId"`
+		VaultID       string            `json:"vaultId"`
+		KeyID         string            `json:"keyId"`
+		SecretName    string            `json:"secretName"`
+		Description   string            `json:"description"`
+		Content       Content           `json:"secretContent"`
+		Tags          map[string]string `json:"freeformTags"`
+	}
+	body, err := json.Marshal(Request{
+		CompartmentID: s.config.CompartmentID,
+		VaultID:       s.config.VaultID,
+		KeyID:         s.config.KeyID,
+		SecretName:    s.config.Prefix + name,
+		Description:   "KES key " + name,
+		Content: Content{
+			ContentType: "BASE64",
+			Content:     base64.StdEncoding.EncodeToString(value),
+		},
+		Tags: map[string]string{"managed-by": "kes"},
+	})
+	if err != nil {
+		return fmt.Errorf("oci: failed to create key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, s.vaultEndpoint, "/20180608/secrets", nil, body)
+	if err != nil {
+		return fmt.Errorf("oci: failed to create key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusConflict:
+		// Secret names are reserved until the secret has been
+		// deleted permanently. Hence, a conflict may be caused
+		// by a secret that has been scheduled for deletion.
+		if _, err := s.secretID(ctx, name); errors.Is(err, kesdk.ErrKeyNotFound) {
+			return fmt.Errorf("oci: failed to create key '%s': key is scheduled for deletion", name)
+		}
+		return kesdk.ErrKeyExists
+	default:
+		return fmt.Errorf("oci: failed to create key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// Get returns the value of the secret with the given name.
+// If no such secret exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	type Response struct {
+		Content struct {
+			ContentType string `json:"contentType"`
+			Content     string `json:"content"`
+		} `json:"secretBundleContent"`
+	}
+
+	id, err := s.secretID(ctx, name)
+	if err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodGet, s.secretsEndpoint, "/20190301/secretbundles/"+url.PathEscape(id), nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, kesdk.ErrKeyNotFound
+	default:
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': %v", name, parseErrorResponse(resp))
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil {
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': %v", name, err)
+	}
+	if response.Content.ContentType != "BASE64" {
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': unsupported content type '%s'", name, response.Content.ContentType)
+	}
+	value, err := base64.StdEncoding.DecodeString(response.Content.Content)
+	if err != nil {
+		return nil, fmt.Errorf("oci: failed to fetch key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// Delete schedules the secret with the given name for deletion.
+// If no such secret exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	type Request struct {
+		TimeOfDeletion time.Time `json:"timeOfDeletion"`
+	}
+
+	id, err := s.secretID(ctx, name)
+	if err != nil {
+		if errors.Is(err, kesdk.ErrKeyNotFound) {
+			return err
+		}
+		return fmt.Errorf("oci: failed to delete key '%s': %v", name, err)
+	}
+	body, err := json.Marshal(Request{
+		TimeOfDeletion: time.Now().UTC().Add(deletionDelay),
+	})
+	if err != nil {
+		return fmt.Errorf("oci: failed to delete key '%s': %v", name, err)
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, s.vaultEndpoint, "/20180608/secrets/"+url.PathEscape(id)+"/actions/scheduleDeletion", nil, body)
+	if err != nil {
+		return fmt.Errorf("oci: failed to delete key '%s': %v", name, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return nil
+	case http.StatusNotFound:
+		return kesdk.ErrKeyNotFound
+	default:
+		return fmt.Errorf("oci: failed to delete key '%s': %v", name, parseErrorResponse(resp))
+	}
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	secrets, err := s.listSecrets(ctx, "")
+	if err != nil {
+		return nil, "", fmt.Errorf("oci: failed to list keys: %v", err)
+	}
+
+	names := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		if name, ok := strings.CutPrefix(secret.Name, s.config.Prefix); ok && name != "" {
+			names = append(names, name)
+		}
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the Store.
+func (s *Store) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// secretSummary is the subset of an OCI secret summary
+// used by the Store.
+type secretSummary struct {
+	ID    string `json:"id"`
+	Name  string `json:"secretName"`
+	State string `json:"lifecycleState"`
+}
+
+// isActive reports whether the secret is neither deleted
+// nor scheduled for deletion.
+func (s secretSummary) isActive() bool {
+	switch s.State {
+	case "ACTIVE", "CREATING", "UPDATING":
+		return true
+	default:
+		return false
+	}
+}
+
+// secretID returns the OCID of the active secret storing the
+// key with the given name. If no such secret exists, it returns
+// kes.ErrKeyNotFound.
+func (s *Store) secretID(ctx context.Context, name string) (string, error) {
+	secrets, err := s.listSecrets(ctx, s.config.Prefix+name)
+	if err != nil {
+		return "", err
+	}
+	for _, secret := range secrets {
+		if secret.Name == s.config.Prefix+name {
+			return secret.ID, nil
+		}
+	}
+	return "", kesdk.ErrKeyNotFound
+}
+
+// listSecrets returns all active secrets within the Vault.
+// If name is not empty, it only returns secrets with this
+// name.
+func (s *Store) listSecrets(ctx context.Context, name string) ([]secretSummary, error) {
+	query := url.Values{}
+	query.Set("compartmentId", s.config.CompartmentID)
+	query.Set("vaultId", s.config.VaultID)
+	query.Set("limit", "100")
+	if name != "" {
+		query.Set("name", name)
+	}
+
+	var secrets []secretSummary
+	for {
+		resp, err := s.do(ctx, http.MethodGet, s.vaultEndpoint, "/20180608/secrets", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = parseErrorResponse(resp)
+			resp.Body.Close()
+			return nil, err
+		}
+
+		var page []secretSummary
+		err = json.NewDecoder(mem.LimitReader(resp.Body, 10*mem.MB)).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, secret := range page {
+			if secret.isActive() {
+				secrets = append(secrets, secret)
+			}
+		}
+
+		next := resp.Header.Get("Opc-Next-Page")
+		if next == "" {
+			return secrets, nil
+		}
+		query.Set("page", next)
+	}
+}
+
+// do sends a signed request to the given OCI API endpoint.
+func (s *Store) do(ctx context.Context, method, endpoint, path string, query url.Values, body []byte) (*http.Response, error) {
+	u := endpoint + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, xhttp.RetryReader(bytes.NewReader(body)))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	req.Header.Set("Accept", "application/json")
+	if len(body) > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if err = s.signer.Sign(ctx, req, body); err != nil {
+		return nil, err
+	}
+	return s.client.Do(req)
+}
+
+// parseErrorResponse parses an OCI HTTP error response.
+func parseErrorResponse(resp *http.Response) error {
+	type Response struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	}
+
+	var response Response
+	if err := json.NewDecoder(mem.LimitReader(resp.Body, mem.MB)).Decode(&response); err != nil || response.Message == "" {
+		return errors.New(resp.Status)
+	}
+	return fmt.Errorf("%s: %s (%s)", resp.Status, response.Message, response.Code)
+}
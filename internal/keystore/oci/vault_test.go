@@ -0,0 +1,252 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package oci
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStore(t *testing.T) {
+	const (
+		VaultID       = "ocid1.vault.oc1.iad.example"
+		CompartmentID = "ocid1.compartment.oc1..example"
+		KeyID         = "ocid1.key.oc1.iad.example"
+	)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := newFakeVault(t, &key.PublicKey, VaultID)
+	defer srv.Close()
+
+	store := &Store{
+		config: Config{
+			VaultID:       VaultID,
+			CompartmentID: CompartmentID,
+			KeyID:         KeyID,
+			Prefix:        "kes-",
+		},
+		vaultEndpoint:   srv.URL,
+		secretsEndpoint: srv.URL,
+		signer:          &apiKeySigner{keyID: "tenancy/user/fingerprint", key: key},
+	}
+	defer store.Close()
+
+	ctx := context.Background()
+	if _, err = store.Status(ctx); err != nil {
+		t.Fatalf("Failed to fetch status: %v", err)
+	}
+
+	names := []string{"my-key", "my-key-2", "other-key"}
+	for _, name := range names {
+		if err = store.Create(ctx, name, []byte("value-"+name)); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = store.Create(ctx, names[0], nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range names {
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch key '%s': %v", name, err)
+		}
+		if string(value) != "value-"+name {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, value, "value-"+name)
+		}
+	}
+
+	list, _, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if want := []string{"my-key", "my-key-2"}; !slices.Equal(list, want) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", list, want)
+	}
+
+	if err = store.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", names[0], err)
+	}
+	if _, err = store.Get(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Fetching deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err = store.Delete(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err = store.Create(ctx, names[0], nil); err == nil || errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating key scheduled for deletion: got '%v' - want scheduled for deletion error", err)
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	const Expiry = 1700000000
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"ocid1.instance.oc1..example","exp":` + strconv.Itoa(Expiry) + `}`))
+	expiry, err := tokenExpiry("eyJhbGciOiJSUzI1NiJ9." + payload + ".c2lnbmF0dXJl")
+	if err != nil {
+		t.Fatalf("Failed to parse token expiry: %v", err)
+	}
+	if !expiry.Equal(time.Unix(Expiry, 0)) {
+		t.Fatalf("Invalid token expiry: got '%v' - want '%v'", expiry, time.Unix(Expiry, 0))
+	}
+	if _, err = tokenExpiry("not-a-jwt"); err == nil {
+		t.Fatal("Parsing invalid token succeeded")
+	}
+}
+
+// newFakeVault returns a server that implements the subset
+// of the OCI Vault and secret retrieval APIs used by the
+// Store. It rejects requests not signed by the given key.
+func newFakeVault(t *testing.T, key *rsa.PublicKey, vaultID string) *httptest.Server {
+	type Secret struct {
+		ID      string `json:"id"`
+		Name    string `json:"secretName"`
+		State   string `json:"lifecycleState"`
+		Content string `json:"-"`
+	}
+	var (
+		lock    sync.Mutex
+		secrets []*Secret
+	)
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := verifySignature(r, body, key); err != nil {
+			t.Logf("Invalid request signature: %v", err)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch path := r.URL.Path; {
+		case r.Method == http.MethodGet && path == "/20180608/secrets":
+			if r.URL.Query().Get("vaultId") != vaultID {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			list := []*Secret{}
+			for _, secret := range secrets {
+				if name := r.URL.Query().Get("name"); name == "" || name == secret.Name {
+					list = append(list, secret)
+				}
+			}
+			json.NewEncoder(w).Encode(list)
+		case r.Method == http.MethodPost && path == "/20180608/secrets":
+			var req struct {
+				Name    string `json:"secretName"`
+				Content struct {
+					Content string `json:"content"`
+				} `json:"secretContent"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			for _, secret := range secrets {
+				if secret.Name == req.Name {
+					w.WriteHeader(http.StatusConflict)
+					json.NewEncoder(w).Encode(map[string]string{"code": "Conflict", "message": "secret already exists"})
+					return
+				}
+			}
+			secret := &Secret{
+				ID:      "ocid1.vaultsecret.oc1.iad." + strconv.Itoa(len(secrets)),
+				Name:    req.Name,
+				State:   "ACTIVE",
+				Content: req.Content.Content,
+			}
+			secrets = append(secrets, secret)
+			json.NewEncoder(w).Encode(secret)
+		case r.Method == http.MethodGet && strings.HasPrefix(path, "/20190301/secretbundles/"):
+			id := strings.TrimPrefix(path, "/20190301/secretbundles/")
+			for _, secret := range secrets {
+				if secret.ID == id {
+					json.NewEncoder(w).Encode(map[string]any{
+						"secretBundleContent": map[string]string{"contentType": "BASE64", "content": secret.Content},
+					})
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && strings.HasSuffix(path, "/actions/scheduleDeletion"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "/20180608/secrets/"), "/actions/scheduleDeletion")
+			for _, secret := range secrets {
+				if secret.ID == id && secret.State == "ACTIVE" {
+					secret.State = "PENDING_DELETION"
+					w.WriteHeader(http.StatusOK)
+					return
+				}
+			}
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+}
+
+// verifySignature verifies the OCI request signature of r.
+func verifySignature(r *http.Request, body []byte, key *rsa.PublicKey) error {
+	params := map[string]string{}
+	auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Signature ")
+	if !ok {
+		return errors.New("no signature")
+	}
+	for _, param := range strings.Split(auth, ",") {
+		k, v, _ := strings.Cut(param, "=")
+		params[k] = strings.Trim(v, `"`)
+	}
+	if params["algorithm"] != "rsa-sha256" {
+		return errors.New("invalid signature algorithm")
+	}
+
+	headers := strings.Fields(params["headers"])
+	if r.Method == http.MethodPost {
+		if !slices.Contains(headers, "x-content-sha256") {
+			return errors.New("body is not signed")
+		}
+		sum := sha256.Sum256(body)
+		if r.Header.Get("X-Content-Sha256") != base64.StdEncoding.EncodeToString(sum[:]) {
+			return errors.New("body checksum mismatch")
+		}
+	}
+	lines := make([]string, 0, len(headers))
+	for _, h := range headers {
+		switch h {
+		case "(request-target)":
+			lines = append(lines, h+": "+strings.ToLower(r.Method)+" "+r.URL.RequestURI())
+		case "host":
+			lines = append(lines, h+": "+r.Host)
+		case "content-length":
+			lines = append(lines, h+": "+strconv.Itoa(len(body)))
+		default:
+			lines = append(lines, h+": "+r.Header.Get(h))
+		}
+	}
+	signature, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+}
@@ -0,0 +1,380 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+// Package pkcs11 implements a key store that stores keys
+// as data objects on a PKCS#11 token - e.g. an HSM like
+// Thales Luna, Entrust nShield or SoftHSM.
+package pkcs11
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/pkcs11"
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Config is a structure containing configuration
+// options for connecting to a PKCS#11 token.
+type Config struct {
+	// Module is the path to the PKCS#11 library
+	// of the HSM vendor - e.g. /usr/lib/softhsm/libsofthsm2.so
+	Module string
+
+	// Slot is the ID of the slot containing the token.
+	// It is ignored if TokenLabel is not empty.
+	Slot uint
+
+	// TokenLabel is the label of the token. If not empty,
+	// the key store uses the first slot containing a token
+	// with this label. Some HSMs, like SoftHSM, assign new
+	// slot IDs when tokens are created.
+	TokenLabel string
+
+	// PIN is the user PIN used to login to the token.
+	PIN string
+
+	// Prefix is an optional prefix added to the label of
+	// each data object. Objects with labels that don't
+	// start with the prefix are ignored.
+	Prefix string
+}
+
+// application is the CKA_APPLICATION attribute of
+// all data objects created by the Store.
+const application = "kes"
+
+// Store is a key store that stores keys as data objects
+// on a PKCS#11 token.
+type Store struct {
+	config Config
+	ctx    *pkcs11.Ctx
+	slot   uint
+
+	// PKCS#11 sessions must not be used concurrently.
+	// The Store uses a single session. Calls to the
+	// token are rare since the KES server caches keys.
+	lock    sync.Mutex
+	session pkcs11.SessionHandle
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// Connect loads the PKCS#11 module, opens a session to
+// the token and logs in using the config PIN.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Module == "" {
+		return nil, errors.New("pkcs11: no module specified")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p11 := pkcs11.New(config.Module)
+	if p11 == nil {
+		return nil, fmt.Errorf("pkcs11: failed to load module '%s'", config.Module)
+	}
+	if err := p11.Initialize(); err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED)) {
+		p11.Destroy()
+		return nil, fmt.Errorf("pkcs11: failed to initialize module '%s': %v", config.Module, err)
+	}
+
+	slot := config.Slot
+	if config.TokenLabel != "" {
+		var err error
+		if slot, err = findSlot(p11, config.TokenLabel); err != nil {
+			p11.Finalize()
+			p11.Destroy()
+			return nil, err
+		}
+	}
+
+	s := &Store{
+		config: *config,
+		ctx:    p11,
+		slot:   slot,
+	}
+	if err := s.login(); err != nil {
+		p11.Finalize()
+		p11.Destroy()
+		return nil, err
+	}
+	return s, nil
+}
+
+// findSlot returns the ID of the first slot containing a
+// token with the given label.
+func findSlot(p11 *pkcs11.Ctx, label string) (uint, error) {
+	slots, err := p11.GetSlotList(true)
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: failed to list slots: %v", err)
+	}
+	for _, slot := range slots {
+		info, err := p11.GetTokenInfo(slot)
+		if err != nil {
+			return 0, fmt.Errorf("pkcs11: failed to read token info of slot '%d': %v", slot, err)
+		}
+		if strings.TrimRight(info.Label, " \x00") == label {
+			return slot, nil
+		}
+	}
+	return 0, fmt.Errorf("pkcs11: no token with label '%s' found", label)
+}
+
+// login opens a new session and logs in. It must
+// be called while holding the Store's lock.
+func (s *Store) login() error {
+	session, err := s.ctx.OpenSession(s.slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		return fmt.Errorf("pkcs11: failed to open session to slot '%d': %v", s.slot, err)
+	}
+	err = s.ctx.Login(session, pkcs11.CKU_USER, s.config.PIN)
+	if err != nil && !errors.Is(err, pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN)) {
+		s.ctx.CloseSession(session)
+		return fmt.Errorf("pkcs11: failed to login to slot '%d': %v", s.slot, err)
+	}
+	s.session = session
+	return nil
+}
+
+// do calls f with the Store's session. If f fails because
+// the session is no longer valid - e.g. the HSM has been
+// restarted - do opens a new session and calls f once more.
+func (s *Store) do(f func(pkcs11.SessionHandle) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err := f(s.session)
+	if !isSessionError(err) {
+		return err
+	}
+
+	s.ctx.CloseSession(s.session)
+	if err := s.login(); err != nil {
+		return &keystore.ErrUnreachable{Err: err}
+	}
+	return f(s.session)
+}
+
+// isSessionError reports whether err indicates that
+// the session has been closed or logged out.
+func isSessionError(err error) bool {
+	var p11Err pkcs11.Error
+	if !errors.As(err, &p11Err) {
+		return false
+	}
+	switch p11Err {
+	case pkcs11.CKR_SESSION_HANDLE_INVALID, pkcs11.CKR_SESSION_CLOSED, pkcs11.CKR_USER_NOT_LOGGED_IN,
+		pkcs11.CKR_DEVICE_REMOVED, pkcs11.CKR_TOKEN_NOT_PRESENT, pkcs11.CKR_DEVICE_ERROR:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns a string representation of the Store.
+func (s *Store) String() string { return fmt.Sprintf("PKCS#11: %s (slot %d)", s.config.Module, s.slot) }
+
+// Status returns the current state of the PKCS#11 token.
+// In particular, whether it is reachable and its latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	if err := ctx.Err(); err != nil {
+		return kes.KeyStoreState{}, err
+	}
+
+	start := time.Now()
+	if _, err := s.ctx.GetTokenInfo(s.slot); err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: err}
+	}
+	return kes.KeyStoreState{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create stores the key as data object on the token if
+// and only if no data object with the same label exists.
+// Otherwise, it returns kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.do(func(session pkcs11.SessionHandle) error {
+		objects, err := s.find(session, s.config.Prefix+name, 1)
+		if err != nil {
+			return err
+		}
+		if len(objects) > 0 {
+			return kesdk.ErrKeyExists
+		}
+		_, err = s.ctx.CreateObject(session, []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+			pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+			pkcs11.NewAttribute(pkcs11.CKA_PRIVATE, true),
+			pkcs11.NewAttribute(pkcs11.CKA_MODIFIABLE, false),
+			pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, application),
+			pkcs11.NewAttribute(pkcs11.CKA_LABEL, s.config.Prefix+name),
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, value),
+		})
+		return err
+	})
+	if err != nil && !errors.Is(err, kesdk.ErrKeyExists) {
+		return fmt.Errorf("pkcs11: failed to create key '%s': %v", name, err)
+	}
+	return err
+}
+
+// Get returns the value of the data object with the given
+// name. If no such object exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	err := s.do(func(session pkcs11.SessionHandle) error {
+		objects, err := s.find(session, s.config.Prefix+name, 1)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return kesdk.ErrKeyNotFound
+		}
+		attrs, err := s.ctx.GetAttributeValue(session, objects[0], []*pkcs11.Attribute{
+			pkcs11.NewAttribute(pkcs11.CKA_VALUE, nil),
+		})
+		if err != nil {
+			return err
+		}
+		value = attrs[0].Value
+		return nil
+	})
+	if err != nil && !errors.Is(err, kesdk.ErrKeyNotFound) {
+		return nil, fmt.Errorf("pkcs11: failed to read key '%s': %v", name, err)
+	}
+	return value, err
+}
+
+// Delete removes the data object with the given name
+// from the token. If no such object exists, it returns
+// kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := s.do(func(session pkcs11.SessionHandle) error {
+		objects, err := s.find(session, s.config.Prefix+name, 1)
+		if err != nil {
+			return err
+		}
+		if len(objects) == 0 {
+			return kesdk.ErrKeyNotFound
+		}
+		return s.ctx.DestroyObject(session, objects[0])
+	})
+	if err != nil && !errors.Is(err, kesdk.ErrKeyNotFound) {
+		return fmt.Errorf("pkcs11: failed to delete key '%s': %v", name, err)
+	}
+	return err
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var names []string
+	err := s.do(func(session pkcs11.SessionHandle) error {
+		names = names[:0]
+
+		objects, err := s.find(session, "", -1)
+		if err != nil {
+			return err
+		}
+		for _, object := range objects {
+			attrs, err := s.ctx.GetAttributeValue(session, object, []*pkcs11.Attribute{
+				pkcs11.NewAttribute(pkcs11.CKA_LABEL, nil),
+			})
+			if err != nil {
+				return err
+			}
+			if name, ok := strings.CutPrefix(string(attrs[0].Value), s.config.Prefix); ok {
+				names = append(names, name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("pkcs11: failed to list keys: %v", err)
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the session and unloads the PKCS#11 module.
+func (s *Store) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.ctx.Logout(s.session)
+	s.ctx.CloseSession(s.session)
+	err := s.ctx.Finalize()
+	s.ctx.Destroy()
+	return err
+}
+
+// find returns up to n data objects created by the Store
+// with the given label, or all of them if label is empty.
+// If n < 0, find returns all matching objects.
+func (s *Store) find(session pkcs11.SessionHandle, label string, n int) ([]pkcs11.ObjectHandle, error) {
+	const BatchSize = 100
+
+	template := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, pkcs11.CKO_DATA),
+		pkcs11.NewAttribute(pkcs11.CKA_TOKEN, true),
+		pkcs11.NewAttribute(pkcs11.CKA_APPLICATION, application),
+	}
+	if label != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, label))
+	}
+	if err := s.ctx.FindObjectsInit(session, template); err != nil {
+		return nil, err
+	}
+	defer s.ctx.FindObjectsFinal(session)
+
+	var objects []pkcs11.ObjectHandle
+	for n < 0 || len(objects) < n {
+		batch := BatchSize
+		if n > 0 {
+			batch = min(batch, n-len(objects))
+		}
+		handles, _, err := s.ctx.FindObjects(session, batch)
+		if err != nil {
+			return nil, err
+		}
+		if len(handles) == 0 {
+			break
+		}
+		objects = append(objects, handles...)
+	}
+	return objects, nil
+}
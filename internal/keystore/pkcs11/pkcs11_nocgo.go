@@ -0,0 +1,50 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build !cgo
+
+// Package pkcs11 implements a key store that stores keys
+// as data objects on a PKCS#11 token - e.g. an HSM like
+// Thales Luna, Entrust nShield or SoftHSM.
+//
+// Loading a PKCS#11 module requires cgo. Without cgo,
+// Connect always returns an error.
+package pkcs11
+
+import (
+	"context"
+	"errors"
+
+	"github.com/minio/kes"
+)
+
+// Config is a structure containing configuration
+// options for connecting to a PKCS#11 token.
+type Config struct {
+	// Module is the path to the PKCS#11 library
+	// of the HSM vendor - e.g. /usr/lib/softhsm/libsofthsm2.so
+	Module string
+
+	// Slot is the ID of the slot containing the token.
+	// It is ignored if TokenLabel is not empty.
+	Slot uint
+
+	// TokenLabel is the label of the token. If not empty,
+	// the key store uses the first slot containing a token
+	// with this label.
+	TokenLabel string
+
+	// PIN is the user PIN used to login to the token.
+	PIN string
+
+	// Prefix is an optional prefix added to the label of
+	// each data object.
+	Prefix string
+}
+
+// Connect returns an error since KES has been built
+// without cgo.
+func Connect(context.Context, *Config) (kes.KeyStore, error) {
+	return nil, errors.New("pkcs11: not supported: KES has been built without cgo (CGO_ENABLED=0): rebuild KES with CGO_ENABLED=1 to use a PKCS#11 token")
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+//go:build cgo
+
+package pkcs11
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestConnectInvalidModule(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Connect(ctx, &Config{}); err == nil {
+		t.Fatal("Connecting without a module should have failed")
+	}
+	if _, err := Connect(ctx, &Config{Module: filepath.Join(t.TempDir(), "libnotfound.so")}); err == nil {
+		t.Fatal("Connecting to a non-existing module should have failed")
+	}
+}
+
+// TestStoreSoftHSM runs against a SoftHSM, or any other PKCS#11,
+// token. It is skipped unless the module path is set via the env.
+// variable KES_PKCS11_MODULE. The token is selected by its label,
+// KES_PKCS11_TOKEN, and the user PIN is read from KES_PKCS11_PIN.
+// For example:
+//
+//	softhsm2-util --init-token --free --label kes --pin 1234 --so-pin 1234
+//	KES_PKCS11_MODULE=/usr/lib/softhsm/libsofthsm2.so KES_PKCS11_TOKEN=kes KES_PKCS11_PIN=1234 go test ./internal/keystore/pkcs11
+func TestStoreSoftHSM(t *testing.T) {
+	module := os.Getenv("KES_PKCS11_MODULE")
+	if module == "" {
+		t.Skip("Skipping PKCS#11 test: KES_PKCS11_MODULE is not set")
+	}
+
+	var random [8]byte
+	if _, err := rand.Read(random[:]); err != nil {
+		t.Fatalf("Failed to generate key prefix: %v", err)
+	}
+	ctx := context.Background()
+	store, err := Connect(ctx, &Config{
+		Module:     module,
+		TokenLabel: os.Getenv("KES_PKCS11_TOKEN"),
+		PIN:        os.Getenv("KES_PKCS11_PIN"),
+		Prefix:     "kes-test-" + hex.EncodeToString(random[:]) + "/",
+	})
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer store.Close()
+
+	if _, err = store.Status(ctx); err != nil {
+		t.Fatalf("Failed to fetch token status: %v", err)
+	}
+
+	names := []string{"my-key", "My_Key", "my-key-2"}
+	defer func() {
+		for _, name := range names {
+			store.Delete(ctx, name)
+		}
+	}()
+
+	for _, name := range names {
+		if err := store.Create(ctx, name, []byte("value-"+name)); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err := store.Create(ctx, names[0], nil); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range names {
+		value, err := store.Get(ctx, name)
+		if err != nil {
+			t.Fatalf("Failed to fetch key '%s': %v", name, err)
+		}
+		if string(value) != "value-"+name {
+			t.Fatalf("Invalid value of key '%s': got '%s' - want '%s'", name, value, "value-"+name)
+		}
+	}
+
+	list, _, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if want := []string{"my-key", "my-key-2"}; !slices.Equal(list, want) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", list, want)
+	}
+
+	if err := store.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Failed to delete key '%s': %v", names[0], err)
+	}
+	if _, err := store.Get(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Fetching deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if err := store.Delete(ctx, names[0]); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+}
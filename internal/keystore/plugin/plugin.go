@@ -0,0 +1,316 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package plugin implements a key-value store backed by
+// a keystore plugin. See the kesplugin package for the
+// plugin protocol.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	pb "github.com/minio/kes/internal/protobuf"
+	"github.com/minio/kes/kesplugin"
+	kesdk "github.com/minio/kms-go/kes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// DefaultStartTimeout is the default time a plugin
+// has to complete the handshake after being started.
+const DefaultStartTimeout = 10 * time.Second
+
+// stopTimeout is the time a plugin has to exit once
+// its standard input has been closed before it gets
+// killed.
+const stopTimeout = 5 * time.Second
+
+// Config is a structure containing the configuration
+// of a keystore plugin.
+type Config struct {
+	// Path is the path of the plugin executable.
+	Path string
+
+	// Args are the command line arguments passed
+	// to the plugin.
+	Args []string
+
+	// Config is the plugin-specific configuration
+	// sent to the plugin once it has been started.
+	Config map[string]string
+
+	// StartTimeout is the time the plugin has to
+	// complete the handshake. If <= 0, defaults to
+	// DefaultStartTimeout.
+	StartTimeout time.Duration
+}
+
+// Connect starts the plugin, connects to it and sends
+// the plugin config.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	if config.Path == "" {
+		return nil, errors.New("plugin: no plugin path specified")
+	}
+	var token [32]byte
+	if _, err := rand.Read(token[:]); err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command(config.Path, config.Args...)
+	cmd.Env = append(os.Environ(),
+		kesplugin.MagicCookieKey+"="+kesplugin.MagicCookieValue,
+		kesplugin.ProtocolVersionKey+"="+strconv.Itoa(kesplugin.ProtocolVersion),
+		kesplugin.TokenKey+"="+hex.EncodeToString(token[:]),
+	)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err = cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin: failed to start '%s': %v", config.Path, err)
+	}
+
+	s := &Store{
+		path:  config.Path,
+		cmd:   cmd,
+		stdin: stdin,
+		exit:  make(chan struct{}),
+	}
+	go func() {
+		s.exitErr = cmd.Wait()
+		close(s.exit)
+	}()
+
+	network, addr, err := s.handshake(ctx, stdout, config.StartTimeout)
+	if err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	conn, err := grpc.DialContext(ctx, "passthrough:///plugin",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(tokenCredentials(hex.EncodeToString(token[:]))),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, network, addr)
+		}),
+	)
+	if err != nil {
+		s.Close()
+		return nil, fmt.Errorf("plugin: failed to connect to '%s': %v", config.Path, err)
+	}
+	s.conn = conn
+	s.client = pb.NewKeyStorePluginClient(conn)
+
+	if _, err = s.client.Configure(ctx, &pb.PluginConfigureRequest{Config: config.Config}); err != nil {
+		s.Close()
+		return nil, fmt.Errorf("plugin: failed to configure '%s': %v", config.Path, fromStatus(err))
+	}
+	return s, nil
+}
+
+// Store is a key-value store backed by a keystore plugin.
+type Store struct {
+	path   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	conn   *grpc.ClientConn
+	client pb.KeyStorePluginClient
+
+	exit    chan struct{} // Closed once the plugin has exited
+	exitErr error
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+func (s *Store) String() string { return "Plugin: " + s.path }
+
+// Status returns the current state of the plugin.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	resp, err := s.client.Status(ctx, &pb.PluginStatusRequest{})
+	if err != nil {
+		return kes.KeyStoreState{}, fromStatus(err)
+	}
+	return kes.KeyStoreState{
+		Latency: resp.Latency.AsDuration(),
+	}, nil
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	_, err := s.client.Create(ctx, &pb.PluginCreateRequest{Name: name, Value: value})
+	return fromStatus(err)
+}
+
+// Delete removes the entry with the given name.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	_, err := s.client.Delete(ctx, &pb.PluginDeleteRequest{Name: name})
+	return fromStatus(err)
+}
+
+// Get returns the value for the given name. It returns
+// kes.ErrKeyNotFound if no such entry exits.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	resp, err := s.client.Get(ctx, &pb.PluginGetRequest{Name: name})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	return resp.Value, nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	resp, err := s.client.List(ctx, &pb.PluginListRequest{Prefix: prefix, N: int32(max(n, -1))})
+	if err != nil {
+		return nil, "", fromStatus(err)
+	}
+	return resp.Names, resp.ContinueAt, nil
+}
+
+// Close closes the connection to the plugin and stops it.
+// The plugin is killed if it does not exit in time.
+func (s *Store) Close() error {
+	s.closeOnce.Do(func() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		s.stdin.Close()
+
+		timer := time.NewTimer(stopTimeout)
+		defer timer.Stop()
+		select {
+		case <-s.exit:
+		case <-timer.C:
+			s.closeErr = s.cmd.Process.Kill()
+			<-s.exit
+		}
+	})
+	return s.closeErr
+}
+
+// handshake reads the handshake line from the plugin's
+// standard output and returns the plugin's network
+// address. Any further output is written to standard
+// error.
+func (s *Store) handshake(ctx context.Context, stdout io.Reader, timeout time.Duration) (network, addr string, err error) {
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	type Result struct {
+		Line string
+		Err  error
+	}
+	result := make(chan Result, 1)
+	go func() {
+		r := bufio.NewReader(stdout)
+		line, err := r.ReadString('\n')
+		result <- Result{Line: line, Err: err}
+		if err == nil {
+			io.Copy(os.Stderr, r)
+		}
+	}()
+
+	var line string
+	select {
+	case <-ctx.Done():
+		return "", "", ctx.Err()
+	case <-timer.C:
+		return "", "", fmt.Errorf("plugin: '%s' did not complete handshake within %v", s.path, timeout)
+	case <-s.exit:
+		return "", "", fmt.Errorf("plugin: '%s' exited before handshake: %v", s.path, s.exitErr)
+	case r := <-result:
+		if r.Err != nil {
+			return "", "", fmt.Errorf("plugin: failed to read handshake of '%s': %v", s.path, r.Err)
+		}
+		line = strings.TrimSpace(r.Line)
+	}
+
+	parts := strings.SplitN(line, "|", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("plugin: invalid handshake of '%s': '%s'", s.path, line)
+	}
+	if parts[0] != strconv.Itoa(kesplugin.ProtocolVersion) {
+		return "", "", fmt.Errorf("plugin: '%s' uses unsupported protocol version '%s'", s.path, parts[0])
+	}
+	if parts[1] != "unix" && parts[1] != "tcp" {
+		return "", "", fmt.Errorf("plugin: '%s' uses unsupported network '%s'", s.path, parts[1])
+	}
+	return parts[1], parts[2], nil
+}
+
+// fromStatus converts a gRPC status error returned by
+// a plugin into a KeyStore error.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	s, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+	switch s.Code() {
+	case codes.NotFound:
+		return kesdk.ErrKeyNotFound
+	case codes.AlreadyExists:
+		return kesdk.ErrKeyExists
+	case codes.Unavailable:
+		return &keystore.ErrUnreachable{Err: errors.New("plugin: " + s.Message())}
+	case codes.DeadlineExceeded:
+		return context.DeadlineExceeded
+	case codes.Canceled:
+		return context.Canceled
+	default:
+		return errors.New("plugin: " + s.Message())
+	}
+}
+
+// tokenCredentials sends the plugin token with every RPC.
+type tokenCredentials string
+
+func (t tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{kesplugin.TokenHeader: string(t)}, nil
+}
+
+// RequireTransportSecurity returns false since plugins
+// listen on local addresses only.
+func (tokenCredentials) RequireTransportSecurity() bool { return false }
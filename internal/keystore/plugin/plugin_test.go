@@ -0,0 +1,122 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/kesplugin"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// TestMain runs the test binary as keystore plugin
+// when started by Connect.
+func TestMain(m *testing.M) {
+	if os.Getenv(kesplugin.MagicCookieKey) == "" {
+		os.Exit(m.Run())
+	}
+
+	err := kesplugin.Serve(func(_ context.Context, config map[string]string) (kes.KeyStore, error) {
+		if config["fail"] != "" {
+			return nil, errors.New(config["fail"])
+		}
+		return &kes.MemKeyStore{}, nil
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func TestStore(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store, err := Connect(ctx, &Config{Path: os.Args[0]})
+	if err != nil {
+		t.Fatalf("Failed to connect to plugin: %v", err)
+	}
+	defer store.Close()
+
+	if _, err = store.Status(ctx); err != nil {
+		t.Fatalf("Failed to fetch plugin status: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = store.Create(ctx, "my-key", []byte("value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	if err = store.Create(ctx, "my-key-2", []byte("value-2")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "value" {
+		t.Fatalf("Invalid value: got '%s' - want 'value'", value)
+	}
+	if _, err = store.Get(ctx, "unknown-key"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Getting unknown key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+
+	names, continueAt, err := store.List(ctx, "my-", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.Equal(names, []string{"my-key", "my-key-2"}) || continueAt != "" {
+		t.Fatalf("Invalid listing: got '%v' and '%s'", names, continueAt)
+	}
+
+	if err = store.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = store.Get(ctx, "my-key"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Getting deleted key: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+
+	if err = store.Close(); err != nil {
+		t.Fatalf("Failed to close plugin: %v", err)
+	}
+	select {
+	case <-store.exit:
+	default:
+		t.Fatal("Plugin is still running after closing the store")
+	}
+}
+
+func TestConnectConfigError(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	store, err := Connect(ctx, &Config{
+		Path:   os.Args[0],
+		Config: map[string]string{"fail": "invalid plugin config"},
+	})
+	if err == nil {
+		store.Close()
+		t.Fatal("Connecting with invalid plugin config should have failed")
+	}
+}
+
+func TestConnectNoPlugin(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, err := Connect(ctx, &Config{Path: filepath.Join(t.TempDir(), "no-plugin")}); err == nil {
+		t.Fatal("Connecting to a plugin that does not exist should have failed")
+	}
+}
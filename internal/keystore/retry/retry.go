@@ -0,0 +1,225 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package retry implements a keystore that retries
+// failed requests to another keystore.
+package retry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Backoff strategies
+const (
+	// Constant waits the same delay before each retry.
+	Constant = "constant"
+
+	// Linear increases the delay linearly with each retry.
+	Linear = "linear"
+
+	// Exponential doubles the delay with each retry.
+	Exponential = "exponential"
+)
+
+// Config is a structure containing the retry policy
+// for requests to a keystore.
+type Config struct {
+	// Attempts is the max. number of attempts per request,
+	// including the first one. If <= 1, requests are not
+	// retried.
+	Attempts int
+
+	// Backoff is the backoff strategy. Either Constant,
+	// Linear or Exponential. If empty, defaults to
+	// Exponential.
+	Backoff string
+
+	// Delay is the delay before the first retry. If <= 0,
+	// defaults to 100ms.
+	Delay time.Duration
+
+	// MaxDelay is the max. delay between two attempts.
+	// If <= 0, the delay is not limited.
+	MaxDelay time.Duration
+
+	// Timeout is the timeout of a single attempt. If <= 0,
+	// attempts are only limited by the request context.
+	Timeout time.Duration
+}
+
+// NewStore returns a new Store that retries failed
+// requests to the given keystore based on the config.
+func NewStore(store kes.KeyStore, config *Config) (*Store, error) {
+	c := *config
+	switch c.Backoff {
+	case "":
+		c.Backoff = Exponential
+	case Constant, Linear, Exponential:
+	default:
+		return nil, fmt.Errorf("retry: invalid backoff strategy '%s'", c.Backoff)
+	}
+	if c.Delay <= 0 {
+		c.Delay = 100 * time.Millisecond
+	}
+	return &Store{
+		store:  store,
+		config: c,
+	}, nil
+}
+
+// Store is a keystore that retries requests to another
+// keystore when they fail with a temporary error, e.g.
+// a network error or a timeout.
+//
+// Requests that fail with a non-temporary error, like
+// kes.ErrKeyNotFound, are not retried.
+type Store struct {
+	store  kes.KeyStore
+	config Config
+}
+
+func (s *Store) String() string {
+	if str, ok := s.store.(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprintf("%T", s.store)
+}
+
+// Status returns the current state of the underlying
+// keystore.
+//
+// Status does not retry requests since it is used to
+// detect whether the keystore is reachable.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	return s.store.Status(ctx)
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+//
+// If an attempt times out after the keystore has created
+// the entry, the next attempt fails with kes.ErrKeyExists.
+// Hence, Create fetches the entry if a retried attempt fails
+// with kes.ErrKeyExists and succeeds if the entry has the
+// same value.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	var attempts int
+	err := s.retry(ctx, func(ctx context.Context) error {
+		attempts++
+		return s.store.Create(ctx, name, value)
+	})
+	if attempts > 1 && errors.Is(err, kesdk.ErrKeyExists) {
+		if stored, gErr := s.Get(ctx, name); gErr == nil && bytes.Equal(stored, value) {
+			return nil
+		}
+	}
+	return err
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	return s.retry(ctx, func(ctx context.Context) error {
+		return s.store.Delete(ctx, name)
+	})
+}
+
+// Get returns the value associated with the given name. If
+// no such entry exists, Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	var value []byte
+	err := s.retry(ctx, func(ctx context.Context) (err error) {
+		value, err = s.store.Get(ctx, name)
+		return err
+	})
+	return value, err
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	var (
+		names []string
+		next  string
+	)
+	err := s.retry(ctx, func(ctx context.Context) (err error) {
+		names, next, err = s.store.List(ctx, prefix, n)
+		return err
+	})
+	return names, next, err
+}
+
+// Close closes the underlying keystore.
+func (s *Store) Close() error { return s.store.Close() }
+
+// retry calls f until it succeeds, fails with a non-temporary
+// error or the max. number of attempts has been reached.
+func (s *Store) retry(ctx context.Context, f func(context.Context) error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = s.attempt(ctx, f); err == nil || !keystore.IsTemporary(err) {
+			return err
+		}
+		if attempt >= s.config.Attempts || ctx.Err() != nil {
+			return err
+		}
+
+		timer := time.NewTimer(s.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt calls f once, limited by the per-attempt timeout.
+func (s *Store) attempt(ctx context.Context, f func(context.Context) error) error {
+	if s.config.Timeout <= 0 {
+		return f(ctx)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	return f(ctx)
+}
+
+// delay returns the delay before the next attempt after the
+// given number of failed attempts. It adds up to 10% jitter
+// such that multiple KES servers don't retry in lockstep.
+func (s *Store) delay(attempt int) time.Duration {
+	delay := s.config.Delay
+	switch s.config.Backoff {
+	case Linear:
+		delay *= time.Duration(attempt)
+	case Exponential:
+		delay <<= min(attempt-1, 30)
+	}
+	if delay <= 0 || (s.config.MaxDelay > 0 && delay > s.config.MaxDelay) {
+		delay = s.config.MaxDelay
+	}
+	if jitter := int64(delay / 10); jitter > 0 {
+		delay += time.Duration(rand.Int63n(jitter))
+	}
+	return delay
+}
@@ -0,0 +1,143 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStoreRetry(t *testing.T) {
+	ctx := context.Background()
+
+	flaky := &flakyStore{KeyStore: &kes.MemKeyStore{}, Failures: 2}
+	store, err := NewStore(flaky, &Config{Attempts: 3, Backoff: Constant, Delay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if flaky.Calls != 3 {
+		t.Fatalf("Invalid number of attempts: got '%d' - want '%d'", flaky.Calls, 3)
+	}
+
+	flaky.Calls, flaky.Failures = 0, 3
+	if _, err = store.Get(ctx, "key"); !keystore.IsTemporary(err) {
+		t.Fatalf("Invalid error: got '%v' - want temporary error", err)
+	}
+	if flaky.Calls != 3 {
+		t.Fatalf("Invalid number of attempts: got '%d' - want '%d'", flaky.Calls, 3)
+	}
+
+	flaky.Calls, flaky.Failures = 0, 0
+	if _, err = store.Get(ctx, "unknown"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kesdk.ErrKeyNotFound)
+	}
+	if flaky.Calls != 1 {
+		t.Fatalf("Invalid number of attempts: got '%d' - want '%d'", flaky.Calls, 1)
+	}
+}
+
+func TestStoreRetryCreate(t *testing.T) {
+	ctx := context.Background()
+
+	lost := &lostStore{KeyStore: &kes.MemKeyStore{}}
+	store, err := NewStore(lost, &Config{Attempts: 3, Backoff: Constant, Delay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = store.Create(ctx, "key", []byte("value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+
+	// A retried attempt must not succeed if another entry
+	// with the same name but a different value exists.
+	flaky := &flakyStore{KeyStore: &kes.MemKeyStore{}, Failures: 1}
+	if err = flaky.KeyStore.Create(ctx, "key", []byte("other")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if store, err = NewStore(flaky, &Config{Attempts: 3, Backoff: Constant, Delay: time.Millisecond}); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Create(ctx, "key", []byte("value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Creating existing key with different value: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+}
+
+func TestStoreDelay(t *testing.T) {
+	for i, test := range storeDelayTests {
+		store, err := NewStore(&kes.MemKeyStore{}, &test.Config)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		delay := store.delay(test.Attempt)
+		if delay < test.Delay || delay > test.Delay+test.Delay/10 {
+			t.Fatalf("Test %d: invalid delay: got '%v' - want '%v' + 10%% jitter", i, delay, test.Delay)
+		}
+	}
+}
+
+var storeDelayTests = []struct {
+	Config  Config
+	Attempt int
+	Delay   time.Duration
+}{
+	{Config: Config{Backoff: Constant, Delay: time.Second}, Attempt: 3, Delay: time.Second},
+	{Config: Config{Backoff: Linear, Delay: time.Second}, Attempt: 3, Delay: 3 * time.Second},
+	{Config: Config{Backoff: Exponential, Delay: time.Second}, Attempt: 3, Delay: 4 * time.Second},
+	{Config: Config{Backoff: Exponential, Delay: time.Second, MaxDelay: 2 * time.Second}, Attempt: 3, Delay: 2 * time.Second},
+	{Config: Config{Delay: time.Second}, Attempt: 1, Delay: time.Second},
+}
+
+// flakyStore is a KeyStore that fails the first requests
+// with an unreachable error.
+type flakyStore struct {
+	kes.KeyStore
+	Failures int
+	Calls    int
+}
+
+func (s *flakyStore) Create(ctx context.Context, name string, value []byte) error {
+	if s.Calls++; s.Calls <= s.Failures {
+		return &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Create(ctx, name, value)
+}
+
+func (s *flakyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.Calls++; s.Calls <= s.Failures {
+		return nil, &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Get(ctx, name)
+}
+
+// lostStore is a KeyStore that creates an entry but fails
+// the first Create request with an unreachable error, like
+// a request that times out after the entry has been created.
+type lostStore struct {
+	kes.KeyStore
+	Lost bool
+}
+
+func (s *lostStore) Create(ctx context.Context, name string, value []byte) error {
+	if err := s.KeyStore.Create(ctx, name, value); err != nil {
+		return err
+	}
+	if !s.Lost {
+		s.Lost = true
+		return &keystore.ErrUnreachable{}
+	}
+	return nil
+}
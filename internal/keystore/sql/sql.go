@@ -0,0 +1,439 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package sql implements a key store that stores keys
+// in a PostgreSQL or MySQL database table.
+package sql
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/tls"
+	dbsql "database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Supported database drivers.
+const (
+	Postgres = "postgres"
+	MySQL    = "mysql"
+)
+
+// DefaultTable is the name of the database table
+// if Config.Table is not set.
+const DefaultTable = "kes_keys"
+
+// Config is a structure containing configuration
+// options for connecting to a SQL database.
+type Config struct {
+	// Driver is the database driver. Either
+	// Postgres or MySQL.
+	Driver string
+
+	// DSN is the data source name - e.g.
+	// "postgres://kes@db.example.com/kes" or
+	// "kes:password@tcp(db.example.com)/kes".
+	DSN string
+
+	// Table is the name of the table storing the keys.
+	// The table is created if it does not exist. If
+	// empty, defaults to DefaultTable.
+	Table string
+
+	// CAPath is an optional path to the root CA
+	// certificate(s) for verifying the database
+	// TLS certificate.
+	CAPath string
+
+	// Certificate and PrivateKey are optional paths
+	// to a TLS client certificate and private key
+	// for mTLS authentication.
+	Certificate string
+	PrivateKey  string
+
+	// MasterKey is an optional 256 bit key used to encrypt
+	// all keys with AES-256-GCM before storing them in the
+	// database.
+	MasterKey []byte
+
+	// MaxOpenConns is the max. number of open database
+	// connections. If <= 0, a small default is used since
+	// KES caches keys and, therefore, sends few queries.
+	MaxOpenConns int
+}
+
+// Connect connects to the database and creates the key table
+// if it does not exist.
+func Connect(ctx context.Context, config *Config) (*Store, error) {
+	table := config.Table
+	if table == "" {
+		table = DefaultTable
+	}
+	if !isIdentifier(table) {
+		return nil, fmt.Errorf("sql: invalid table name '%s'", table)
+	}
+
+	var aead cipher.AEAD
+	if len(config.MasterKey) > 0 {
+		var err error
+		if aead, err = newAEAD(config.MasterKey); err != nil {
+			return nil, err
+		}
+	}
+
+	var (
+		db       *dbsql.DB
+		endpoint string
+		schema   string
+	)
+	switch config.Driver {
+	case Postgres:
+		dsn, err := postgresDSN(config.DSN, config.CAPath, config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("sql: invalid postgres DSN: %v", err)
+		}
+		connector, err := pq.NewConnector(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("sql: invalid postgres DSN: %v", err)
+		}
+		db = dbsql.OpenDB(connector)
+		endpoint = "PostgreSQL"
+		schema = `CREATE TABLE IF NOT EXISTS ` + table + ` (
+			name       VARCHAR(255) PRIMARY KEY,
+			value      BYTEA NOT NULL,
+			created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`
+	case MySQL:
+		cfg, err := mysql.ParseDSN(config.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("sql: invalid mysql DSN: %v", err)
+		}
+		if config.CAPath != "" || config.Certificate != "" || config.PrivateKey != "" {
+			if cfg.TLS, err = mysqlTLS(cfg.Addr, config.CAPath, config.Certificate, config.PrivateKey); err != nil {
+				return nil, fmt.Errorf("sql: invalid mysql TLS config: %v", err)
+			}
+		}
+		connector, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("sql: invalid mysql DSN: %v", err)
+		}
+		db = dbsql.OpenDB(connector)
+		endpoint = "MySQL"
+		// Key names are case-sensitive. Hence, the name
+		// column must use a binary collation.
+		schema = `CREATE TABLE IF NOT EXISTS ` + table + ` (
+			name       VARCHAR(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL PRIMARY KEY,
+			value      BLOB NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`
+	default:
+		return nil, fmt.Errorf("sql: unsupported driver '%s'", config.Driver)
+	}
+
+	// KES caches keys. Hence, only cache misses, key creation
+	// and deletion reach the database. A small pool of long-lived
+	// connections is sufficient and avoids exhausting database
+	// connection limits when running many KES servers.
+	maxConns := config.MaxOpenConns
+	if maxConns <= 0 {
+		maxConns = 8
+	}
+	db.SetMaxOpenConns(maxConns)
+	db.SetMaxIdleConns(min(maxConns, 2))
+	db.SetConnMaxIdleTime(5 * time.Minute)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sql: failed to create table '%s': %v", table, err)
+	}
+	return &Store{
+		db:     db,
+		driver: config.Driver,
+		name:   endpoint + ": " + table,
+		table:  table,
+		aead:   aead,
+	}, nil
+}
+
+// Store is a key store that stores keys in a SQL database table.
+type Store struct {
+	db     *dbsql.DB
+	driver string
+	name   string
+	table  string
+	aead   cipher.AEAD // Optional - if set, values are encrypted at rest
+}
+
+var _ kes.KeyStore = (*Store)(nil) // compiler check
+
+// String returns a string representation of the Store.
+func (s *Store) String() string {
+	if s.aead != nil {
+		return s.name + " (encrypted)"
+	}
+	return s.name
+}
+
+// Status returns the current state of the database.
+// In particular, whether it is reachable and the
+// network latency.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	start := time.Now()
+	if err := s.db.PingContext(ctx); err != nil {
+		return kes.KeyStoreState{}, &keystore.ErrUnreachable{Err: fmt.Errorf("sql: %v", err)}
+	}
+	return kes.KeyStoreState{
+		Latency: time.Since(start),
+	}, nil
+}
+
+// Create stores the key under the given name if and only if
+// no key with this name exists. Otherwise, it returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	value, err := s.seal(name, value)
+	if err != nil {
+		return fmt.Errorf("sql: failed to create key '%s': %v", name, err)
+	}
+
+	var query string
+	if s.driver == Postgres {
+		query = `INSERT INTO ` + s.table + ` (name, value) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING`
+	} else {
+		query = `INSERT INTO ` + s.table + ` (name, value) VALUES (?, ?) ON DUPLICATE KEY UPDATE name = name`
+	}
+	result, err := s.db.ExecContext(ctx, query, name, value)
+	if err != nil {
+		return fmt.Errorf("sql: failed to create key '%s': %v", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sql: failed to create key '%s': %v", name, err)
+	}
+	if n == 0 {
+		return kesdk.ErrKeyExists
+	}
+	return nil
+}
+
+// Get returns the key with the given name. If no such
+// key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM `+s.table+` WHERE name = `+s.param(1), name).Scan(&value)
+	if errors.Is(err, dbsql.ErrNoRows) {
+		return nil, kesdk.ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sql: failed to fetch key '%s': %v", name, err)
+	}
+	if value, err = s.open(name, value); err != nil {
+		return nil, fmt.Errorf("sql: failed to fetch key '%s': %v", name, err)
+	}
+	return value, nil
+}
+
+// Delete deletes the key with the given name. If no such
+// key exists, it returns kes.ErrKeyNotFound.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM `+s.table+` WHERE name = `+s.param(1), name)
+	if err != nil {
+		return fmt.Errorf("sql: failed to delete key '%s': %v", name, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("sql: failed to delete key '%s': %v", name, err)
+	}
+	if n == 0 {
+		return kesdk.ErrKeyNotFound
+	}
+	return nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// It returns all keys with the prefix if n < 0 and less than n
+// names if n is greater than the number of keys with the prefix.
+//
+// An empty prefix matches any key name. At the end of the listing
+// or when there are no (more) keys starting with the prefix, the
+// returned prefix is empty.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	// The sort order of the database depends on its collation.
+	// Hence, the names are filtered and sorted by keystore.List.
+	rows, err := s.db.QueryContext(ctx, `SELECT name FROM `+s.table)
+	if err != nil {
+		return nil, "", fmt.Errorf("sql: failed to list keys: %v", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			return nil, "", fmt.Errorf("sql: failed to list keys: %v", err)
+		}
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("sql: failed to list keys: %v", err)
+	}
+	return keystore.List(names, prefix, n)
+}
+
+// Close closes the database connection pool.
+func (s *Store) Close() error { return s.db.Close() }
+
+// param returns the i-th query placeholder
+// of the database driver.
+func (s *Store) param(i int) string {
+	if s.driver == Postgres {
+		return fmt.Sprintf("$%d", i)
+	}
+	return "?"
+}
+
+// newAEAD returns a AES-256-GCM AEAD for the given master key.
+func newAEAD(masterKey []byte) (cipher.AEAD, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("sql: invalid master key length '%d': must be 32 bytes", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the value if the Store has a master key.
+// The key name is bound to the ciphertext such that rows
+// cannot be swapped.
+func (s *Store) seal(name string, value []byte) ([]byte, error) {
+	if s.aead == nil {
+		return value, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize(), s.aead.NonceSize()+len(value)+s.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, value, []byte(name)), nil
+}
+
+// open decrypts the value if the Store has a master key.
+func (s *Store) open(name string, value []byte) ([]byte, error) {
+	if s.aead == nil {
+		return value, nil
+	}
+	if len(value) < s.aead.NonceSize() {
+		return nil, errors.New("value is truncated")
+	}
+	nonce, ciphertext := value[:s.aead.NonceSize()], value[s.aead.NonceSize():]
+	plaintext, err := s.aead.Open(nil, nonce, ciphertext, []byte(name))
+	if err != nil {
+		return nil, errors.New("failed to decrypt value: invalid master key or corrupted value")
+	}
+	return plaintext, nil
+}
+
+// postgresDSN adds the TLS options, if any, to the
+// PostgreSQL DSN. The DSN may either be a URL or a
+// list of key=value pairs.
+func postgresDSN(dsn, caPath, certFile, keyFile string) (string, error) {
+	params := [][2]string{}
+	if caPath != "" {
+		params = append(params, [2]string{"sslmode", "verify-full"}, [2]string{"sslrootcert", caPath})
+	}
+	if certFile != "" {
+		params = append(params, [2]string{"sslcert", certFile})
+	}
+	if keyFile != "" {
+		params = append(params, [2]string{"sslkey", keyFile})
+	}
+	if len(params) == 0 {
+		return dsn, nil
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			return "", err
+		}
+		query := u.Query()
+		for _, p := range params {
+			query.Set(p[0], p[1])
+		}
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(dsn)
+	for _, p := range params {
+		value := strings.ReplaceAll(strings.ReplaceAll(p[1], `\`, `\\`), `'`, `\'`)
+		fmt.Fprintf(&sb, " %s='%s'", p[0], value)
+	}
+	return sb.String(), nil
+}
+
+// mysqlTLS returns the TLS configuration for connecting
+// to the MySQL server at the given address.
+func mysqlTLS(addr, caPath, certFile, keyFile string) (*tls.Config, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: host,
+	}
+	if caPath != "" {
+		if config.RootCAs, err = https.CertPoolFromFile(caPath); err != nil {
+			return nil, err
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		certificate, err := https.CertificateFromFile(certFile, keyFile, "")
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = append(config.Certificates, certificate)
+	}
+	return config, nil
+}
+
+// isIdentifier reports whether s is a valid, unquoted
+// SQL identifier.
+func isIdentifier(s string) bool {
+	if s == "" || len(s) > 63 {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package sql
+
+import (
+	"bytes"
+	"testing"
+)
+
+var postgresDSNTests = []struct {
+	DSN      string
+	CAPath   string
+	CertFile string
+	KeyFile  string
+	Want     string
+}{
+	{ // 0
+		DSN:  "postgres://kes@localhost/kes",
+		Want: "postgres://kes@localhost/kes",
+	},
+	{ // 1
+		DSN:    "postgres://kes@localhost/kes?connect_timeout=5",
+		CAPath: "/etc/kes/ca.pem",
+		Want:   "postgres://kes@localhost/kes?connect_timeout=5&sslmode=verify-full&sslrootcert=%2Fetc%2Fkes%2Fca.pem",
+	},
+	{ // 2
+		DSN:      "host=localhost user=kes dbname=kes",
+		CAPath:   "/etc/kes/ca.pem",
+		CertFile: "/etc/kes/client.crt",
+		KeyFile:  "/etc/kes/client's.key",
+		Want:     `host=localhost user=kes dbname=kes sslmode='verify-full' sslrootcert='/etc/kes/ca.pem' sslcert='/etc/kes/client.crt' sslkey='/etc/kes/client\'s.key'`,
+	},
+}
+
+func TestPostgresDSN(t *testing.T) {
+	for i, test := range postgresDSNTests {
+		dsn, err := postgresDSN(test.DSN, test.CAPath, test.CertFile, test.KeyFile)
+		if err != nil {
+			t.Fatalf("Test %d: failed to build DSN: %v", i, err)
+		}
+		if dsn != test.Want {
+			t.Fatalf("Test %d: DSN mismatch: got '%s' - want '%s'", i, dsn, test.Want)
+		}
+	}
+}
+
+var isIdentifierTests = []struct {
+	Name  string
+	Valid bool
+}{
+	{Name: "kes_keys", Valid: true},
+	{Name: "KES_Keys2", Valid: true},
+	{Name: "_keys", Valid: true},
+	{Name: ""},
+	{Name: "2keys"},
+	{Name: "kes-keys"},
+	{Name: "keys; DROP TABLE users"},
+	{Name: "kes.keys"},
+}
+
+func TestIsIdentifier(t *testing.T) {
+	for i, test := range isIdentifierTests {
+		if valid := isIdentifier(test.Name); valid != test.Valid {
+			t.Fatalf("Test %d: got '%v' - want '%v' for '%s'", i, valid, test.Valid, test.Name)
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	aead, err := newAEAD(make([]byte, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Store{aead: aead}
+
+	value := []byte("my-secret-key")
+	ciphertext, err := s.seal("my-key", value)
+	if err != nil {
+		t.Fatalf("Failed to seal value: %v", err)
+	}
+	if bytes.Contains(ciphertext, value) {
+		t.Fatal("Sealed value contains plaintext")
+	}
+	plaintext, err := s.open("my-key", ciphertext)
+	if err != nil {
+		t.Fatalf("Failed to open value: %v", err)
+	}
+	if !bytes.Equal(plaintext, value) {
+		t.Fatalf("Value mismatch: got '%s' - want '%s'", plaintext, value)
+	}
+	if _, err = s.open("other-key", ciphertext); err == nil {
+		t.Fatal("Opening value of a different key succeeded")
+	}
+}
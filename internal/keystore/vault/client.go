@@ -7,7 +7,11 @@ package vault
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"path"
+	"path/filepath"
+	"strings"
 	"sync/atomic"
 	"time"
 
@@ -79,9 +83,13 @@ func (c *client) AuthenticateWithAppRole(login *AppRole) authFunc {
 			client = client.WithNamespace(login.Namespace)
 		}
 
+		secretID, err := readCredential(login.Secret, login.SecretFile)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to read approle secret: %v", err)
+		}
 		secret, err := client.Logical().Write(path.Join("auth", login.Engine, "login"), map[string]interface{}{
 			"role_id":   login.ID,
-			"secret_id": login.Secret,
+			"secret_id": secretID,
 		})
 		if secret == nil && err == nil {
 			// The Vault SDK eventually returns no error but also no
@@ -104,9 +112,13 @@ func (c *client) AuthenticateWithK8S(login *Kubernetes) authFunc {
 			client = client.WithNamespace(login.Namespace)
 		}
 
+		jwt, err := readCredential(login.JWT, login.JWTFile)
+		if err != nil {
+			return nil, fmt.Errorf("vault: failed to read kubernetes JWT: %v", err)
+		}
 		secret, err := client.Logical().Write(path.Join("auth", login.Engine, "login"), map[string]interface{}{
 			"role": login.Role,
-			"jwt":  login.JWT,
+			"jwt":  jwt,
 		})
 		if secret == nil && err == nil {
 			// The Vault SDK eventually returns no error but also no
@@ -208,3 +220,154 @@ func (c *client) RenewToken(ctx context.Context, authenticate authFunc, secret *
 		}
 	}
 }
+
+// RotateSecretID keeps rotating the AppRole secret stored at
+// login.SecretFile until <-ctx.Done() returns. The role is the
+// name of the AppRole.
+//
+// Once two thirds of the lifetime of the current secret have
+// passed, RotateSecretID generates a new secret, replaces the
+// content of login.SecretFile and destroys the previous secret.
+// It returns early if the secret does not expire.
+//
+// Since RotateSecretID starts an endless for-loop users should
+// usually invoke it in a separate go routine:
+//
+//	go client.RotateSecretID(ctx, login, role)
+func (c *client) RotateSecretID(ctx context.Context, login *AppRole, role string) {
+	const RetryDelay = 30 * time.Second // Retry after 30s if rotation fails.
+	for {
+		rotateAt, err := c.rotateSecretID(ctx, login, role)
+		if err != nil {
+			rotateAt = time.Now().Add(RetryDelay)
+		}
+		if rotateAt.IsZero() {
+			return // Secret does not expire. Hence, we do not need to rotate it.
+		}
+
+		timer := time.NewTimer(time.Until(rotateAt))
+		select {
+		case <-ctx.Done():
+			if !timer.Stop() {
+				<-timer.C
+			}
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// rotateSecretID rotates the AppRole secret stored at login.SecretFile
+// if two thirds of its lifetime have passed. It returns when the secret
+// should be rotated next or the zero time if it does not expire.
+func (c *client) rotateSecretID(ctx context.Context, login *AppRole, role string) (time.Time, error) {
+	if c.Sealed() {
+		return time.Time{}, errSealed
+	}
+
+	client := c.Client
+	switch {
+	case login.Namespace == "/": // Treat '/' as the root namespace
+		client = client.WithNamespace("") // Clear namespace
+	case login.Namespace != "":
+		client = client.WithNamespace(login.Namespace)
+	}
+	rolePath := path.Join("auth", login.Engine, "role", role)
+
+	secretID, err := readCredential("", login.SecretFile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	rotateAt, err := lookupSecretIDRotation(ctx, client, rolePath, secretID)
+	if err != nil || rotateAt.IsZero() || time.Now().Before(rotateAt) {
+		return rotateAt, err
+	}
+
+	secret, err := client.Logical().WriteWithContext(ctx, path.Join(rolePath, "secret-id"), nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if secret == nil {
+		return time.Time{}, errors.New("vault: failed to generate approle secret: SDK returned no error but also no secret")
+	}
+	newSecretID, ok := secret.Data["secret_id"].(string)
+	if !ok || newSecretID == "" {
+		return time.Time{}, errors.New("vault: failed to generate approle secret: response contains no secret")
+	}
+	if err = writeCredential(login.SecretFile, newSecretID); err != nil {
+		return time.Time{}, err
+	}
+
+	// The previous secret expires anyway. Hence, we ignore
+	// any error when destroying it.
+	client.Logical().WriteWithContext(ctx, path.Join(rolePath, "secret-id", "destroy"), map[string]interface{}{
+		"secret_id": secretID,
+	})
+	return lookupSecretIDRotation(ctx, client, rolePath, newSecretID)
+}
+
+// lookupSecretIDRotation looks up the AppRole secret and returns
+// the point in time when two thirds of its lifetime have passed
+// or the zero time if the secret does not expire.
+func lookupSecretIDRotation(ctx context.Context, client *vaultapi.Client, rolePath, secretID string) (time.Time, error) {
+	secret, err := client.Logical().WriteWithContext(ctx, path.Join(rolePath, "secret-id", "lookup"), map[string]interface{}{
+		"secret_id": secretID,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if secret == nil {
+		return time.Time{}, errors.New("vault: failed to lookup approle secret: secret not found")
+	}
+
+	creation, _ := secret.Data["creation_time"].(string)
+	expiration, _ := secret.Data["expiration_time"].(string)
+	createdAt, err := time.Parse(time.RFC3339Nano, creation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("vault: failed to lookup approle secret: invalid creation time: %v", err)
+	}
+	expiresAt, err := time.Parse(time.RFC3339Nano, expiration)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("vault: failed to lookup approle secret: invalid expiration time: %v", err)
+	}
+	if !expiresAt.After(createdAt) {
+		return time.Time{}, nil // Vault reports the zero time for secrets without expiration.
+	}
+	return createdAt.Add(2 * expiresAt.Sub(createdAt) / 3), nil
+}
+
+// readCredential returns the credential if filename is empty.
+// Otherwise, it returns the content of the file without leading
+// or trailing whitespaces.
+func readCredential(credential, filename string) (string, error) {
+	if filename == "" {
+		return credential, nil
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// writeCredential replaces the content of the file with the
+// credential atomically.
+func writeCredential(filename, credential string) error {
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = file.WriteString(credential + "\n"); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
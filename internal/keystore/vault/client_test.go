@@ -0,0 +1,112 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	vaultapi "github.com/hashicorp/vault/api"
+)
+
+func TestRotateSecretID(t *testing.T) {
+	const (
+		Role      = "kes"
+		OldSecret = "ba8d68af-23c4-4199-a516-e37cebdaab48"
+		NewSecret = "5a4bd5a1-4c3e-4bc4-9a52-1b8f3f1dd3b0"
+	)
+	var (
+		lock    sync.Mutex
+		secrets = map[string]time.Time{ // Secret ID -> creation time
+			OldSecret: time.Now().Add(-50 * time.Minute),
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			SecretID string `json:"secret_id"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		lock.Lock()
+		defer lock.Unlock()
+		switch r.URL.Path {
+		case "/v1/auth/approle/role/" + Role + "/secret-id/lookup":
+			created, ok := secrets[body.SecretID]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{
+					"creation_time":   created.Format(time.RFC3339Nano),
+					"expiration_time": created.Add(time.Hour).Format(time.RFC3339Nano),
+				},
+			})
+		case "/v1/auth/approle/role/" + Role + "/secret-id":
+			secrets[NewSecret] = time.Now()
+			json.NewEncoder(w).Encode(map[string]any{
+				"data": map[string]any{"secret_id": NewSecret},
+			})
+		case "/v1/auth/approle/role/" + Role + "/secret-id/destroy":
+			delete(secrets, body.SecretID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	secretFile := filepath.Join(t.TempDir(), "secret-id")
+	if err := os.WriteFile(secretFile, []byte(OldSecret+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := vaultapi.DefaultConfig()
+	config.Address = srv.URL
+	vaultClient, err := vaultapi.NewClient(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &client{Client: vaultClient}
+	login := &AppRole{
+		Engine:     EngineAppRole,
+		SecretFile: secretFile,
+		Rotate:     true,
+	}
+
+	rotateAt, err := c.rotateSecretID(context.Background(), login, Role)
+	if err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+	if d := time.Until(rotateAt); d < 39*time.Minute || d > 40*time.Minute {
+		t.Fatalf("Invalid rotation time: got %v - want ~40m", d)
+	}
+
+	secret, err := readCredential("", secretFile)
+	if err != nil {
+		t.Fatalf("Failed to read secret file: %v", err)
+	}
+	if secret != NewSecret {
+		t.Fatalf("Invalid secret: got '%s' - want '%s'", secret, NewSecret)
+	}
+	if _, ok := secrets[OldSecret]; ok {
+		t.Fatal("Previous secret has not been destroyed")
+	}
+
+	// The new secret is not due for rotation yet.
+	if _, err = c.rotateSecretID(context.Background(), login, Role); err != nil {
+		t.Fatalf("Failed to rotate secret: %v", err)
+	}
+	if secret, _ = readCredential("", secretFile); secret != NewSecret {
+		t.Fatalf("Invalid secret: got '%s' - want '%s'", secret, NewSecret)
+	}
+}
@@ -7,6 +7,8 @@ package vault
 import (
 	"sync"
 	"time"
+
+	"github.com/minio/kes/internal/keystore"
 )
 
 const (
@@ -67,6 +69,21 @@ type AppRole struct {
 
 	// Secret is the AppRole authentication secret.
 	Secret string
+
+	// SecretFile is a path to a file containing the AppRole
+	// authentication secret. If set, the secret is read from
+	// the file on every login. It is mutually exclusive with
+	// Secret.
+	SecretFile string
+
+	// Rotate controls whether the secret stored at SecretFile
+	// is rotated before it expires. Once two thirds of its
+	// lifetime have passed, a new secret is generated, written
+	// to SecretFile and the previous secret is destroyed.
+	//
+	// The AppRole has to be allowed to generate, lookup and
+	// destroy its own secret IDs.
+	Rotate bool
 }
 
 // Clone returns a copy of the AppRole auth.
@@ -75,10 +92,12 @@ func (a *AppRole) Clone() *AppRole {
 		return nil
 	}
 	return &AppRole{
-		Engine:    a.Engine,
-		Namespace: a.Namespace,
-		ID:        a.ID,
-		Secret:    a.Secret,
+		Engine:     a.Engine,
+		Namespace:  a.Namespace,
+		ID:         a.ID,
+		Secret:     a.Secret,
+		SecretFile: a.SecretFile,
+		Rotate:     a.Rotate,
 	}
 }
 
@@ -113,6 +132,13 @@ type Kubernetes struct {
 
 	// JWT is the issued authentication token.
 	JWT string
+
+	// JWTFile is a path to a file containing the issued
+	// authentication token. If set, the token is read from
+	// the file on every login such that rotated service
+	// account tokens are picked up. It is mutually exclusive
+	// with JWT.
+	JWTFile string
 }
 
 // Clone returns a copy of the Kubernetes auth.
@@ -125,6 +151,7 @@ func (k *Kubernetes) Clone() *Kubernetes {
 		Namespace: k.Namespace,
 		Role:      k.Role,
 		JWT:       k.JWT,
+		JWTFile:   k.JWTFile,
 	}
 }
 
@@ -220,6 +247,10 @@ type Config struct {
 	// host's root CA set is used.
 	CAPath string
 
+	// HTTP contains optional HTTP client settings, like the
+	// number of idle connections and the request timeout.
+	HTTP keystore.HTTPConfig
+
 	lock sync.RWMutex
 }
 
@@ -246,5 +277,6 @@ func (c *Config) Clone() *Config {
 		PrivateKey:      c.PrivateKey,
 		Certificate:     c.Certificate,
 		CAPath:          c.CAPath,
+		HTTP:            c.HTTP,
 	}
 }
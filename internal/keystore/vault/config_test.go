@@ -8,6 +8,8 @@ import (
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/minio/kes/internal/keystore"
 )
 
 func TestCloneConfig(t *testing.T) {
@@ -43,5 +45,21 @@ var cloneConfigTests = []*Config{
 		PrivateKey:      "/tmp/kes/vault.key",
 		Certificate:     "/tmp/kes/vault.crt",
 		CAPath:          "/tmp/kes/vautl.ca",
+		HTTP: keystore.HTTPConfig{
+			MaxIdleConnsPerHost: 32,
+			Timeout:             10 * time.Second,
+		},
+	},
+	{
+		Endpoint: "https://vault.cluster.local:8200",
+		AppRole: &AppRole{
+			ID:         "be7f3c83-9733-4d65-adaa-7eeb6e14e922",
+			SecretFile: "/var/run/secrets/vault/secret-id",
+			Rotate:     true,
+		},
+		K8S: &Kubernetes{
+			Role:    "kes",
+			JWTFile: "/var/run/secrets/kubernetes.io/serviceaccount/token",
+		},
 	},
 }
@@ -74,13 +74,24 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 		return nil, fmt.Errorf("vault: invalid engine API version '%s'", c.APIVersion)
 	}
 	if c.AppRole != nil && c.K8S != nil {
-		if (c.AppRole.ID == "" || c.AppRole.Secret == "") && (c.K8S.JWT == "" || c.K8S.Role == "") {
+		if (c.AppRole.ID == "" || (c.AppRole.Secret == "" && c.AppRole.SecretFile == "")) && ((c.K8S.JWT == "" && c.K8S.JWTFile == "") || c.K8S.Role == "") {
 			return nil, errors.New("vault: no authentication method specified")
 		}
-		if (c.AppRole.ID != "" || c.AppRole.Secret != "") && (c.K8S.JWT != "" || c.K8S.Role != "") {
+		if (c.AppRole.ID != "" || c.AppRole.Secret != "" || c.AppRole.SecretFile != "") && (c.K8S.JWT != "" || c.K8S.JWTFile != "" || c.K8S.Role != "") {
 			return nil, errors.New("vault: more than one authentication method specified: approle and kubernetes configuration is present")
 		}
 	}
+	if c.AppRole != nil {
+		if c.AppRole.Secret != "" && c.AppRole.SecretFile != "" {
+			return nil, errors.New("vault: approle secret and secret file are mutually exclusive")
+		}
+		if c.AppRole.Rotate && c.AppRole.SecretFile == "" {
+			return nil, errors.New("vault: approle secret rotation requires a secret file")
+		}
+	}
+	if c.K8S != nil && c.K8S.JWT != "" && c.K8S.JWTFile != "" {
+		return nil, errors.New("vault: kubernetes JWT and JWT file are mutually exclusive")
+	}
 	if c.Transit != nil {
 		if c.Transit.KeyName == "" {
 			return nil, errors.New("vault: transit key name is empty")
@@ -106,6 +117,10 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	config := vaultapi.DefaultConfig()
 	config.Address = c.Endpoint
 	config.ConfigureTLS(tlsConfig)
+	c.HTTP.Configure(config.HttpClient)
+	if c.HTTP.Timeout > 0 {
+		config.Timeout = c.HTTP.Timeout
+	}
 	vaultClient, err := vaultapi.NewClient(config)
 	if err != nil {
 		return nil, err
@@ -123,9 +138,9 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 
 	var authenticate authFunc
 	switch {
-	case c.AppRole != nil && (c.AppRole.ID != "" || c.AppRole.Secret != ""):
+	case c.AppRole != nil && (c.AppRole.ID != "" || c.AppRole.Secret != "" || c.AppRole.SecretFile != ""):
 		authenticate = client.AuthenticateWithAppRole(c.AppRole)
-	case c.K8S != nil && (c.K8S.Role != "" || c.K8S.JWT != ""):
+	case c.K8S != nil && (c.K8S.Role != "" || c.K8S.JWT != "" || c.K8S.JWTFile != ""):
 		authenticate = client.AuthenticateWithK8S(c.K8S)
 	}
 
@@ -139,9 +154,24 @@ func Connect(ctx context.Context, c *Config) (*Store, error) {
 	}
 	client.SetToken(token)
 
+	// The AppRole login attaches the role name to the token's
+	// metadata. It is required for rotating the AppRole secret.
+	var role string
+	if c.AppRole != nil && c.AppRole.Rotate {
+		if auth.Auth != nil {
+			role = auth.Auth.Metadata["role_name"]
+		}
+		if role == "" {
+			return nil, errors.New("vault: failed to rotate approle secret: login response contains no role name")
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	go client.CheckStatus(ctx, c.StatusPingAfter)
 	go client.RenewToken(ctx, authenticate, auth)
+	if role != "" {
+		go client.RotateSecretID(ctx, c.AppRole, role)
+	}
 	return &Store{
 		config: c,
 		client: client,
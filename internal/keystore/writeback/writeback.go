@@ -0,0 +1,478 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package writeback implements a keystore that acknowledges
+// writes once they are persisted in a local journal and writes
+// them to another, potentially slow, keystore in the background.
+package writeback
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Config is a structure containing the write-back configuration.
+type Config struct {
+	// Dir is the directory containing the journal files.
+	// If it does not exist, NewStore creates it.
+	//
+	// The journal contains the values of all keys that have
+	// not been written to the keystore yet. Hence, it must be
+	// protected like the keystore itself.
+	Dir string
+
+	// Interval is the interval in which pending writes are
+	// written to the keystore. If <= 0, defaults to 1s.
+	Interval time.Duration
+}
+
+// NewStore returns a new Store that writes to the given keystore
+// in the background. Before returning, it recovers all writes
+// from journals of previous Stores within the same directory.
+func NewStore(store kes.KeyStore, config *Config) (*Store, error) {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = 1 * time.Second
+	}
+	if err := os.MkdirAll(config.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("writeback: failed to create journal directory: %v", err)
+	}
+
+	s := &Store{
+		store:    store,
+		dir:      config.Dir,
+		pending:  map[string]entry{},
+		inflight: map[string]*write{},
+	}
+
+	// Recover pending writes of previous journals, e.g. from
+	// before a crash or a configuration reload, into a new
+	// journal owned by this Store.
+	recovered, err := filepath.Glob(filepath.Join(config.Dir, "*"+journalExt))
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(recovered) // Journal names start with the creation time
+	for _, filename := range recovered {
+		if err = s.recover(filename); err != nil {
+			return nil, err
+		}
+	}
+	s.filename = filepath.Join(config.Dir, strconv.FormatInt(time.Now().UnixNano(), 10)+journalExt)
+	if err = s.compact(); err != nil {
+		return nil, err
+	}
+	for _, filename := range recovered {
+		os.Remove(filename)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stop = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx, interval)
+	return s, nil
+}
+
+// Store is a keystore that acknowledges creating a key once
+// it has been persisted in a local journal. It writes pending
+// keys to the underlying keystore in the background.
+//
+// Create still checks whether a key exists at the underlying
+// keystore. Hence, it reduces the latency of writes to slow
+// keystores but not the number of requests.
+//
+// Reads of keys that have not been written to the underlying
+// keystore are served from the journal. A pending key that
+// gets deleted is never written to the underlying keystore.
+// If it is being written while getting deleted, Delete waits
+// for the write and deletes it from the underlying keystore.
+type Store struct {
+	store kes.KeyStore
+	dir   string
+
+	lock     sync.Mutex
+	filename string
+	journal  *os.File
+	pending  map[string]entry
+	inflight map[string]*write
+	seq      uint64
+
+	stop func()
+	done chan struct{}
+}
+
+// entry is a pending write. Its sequence number distinguishes
+// a key from another one with the same name that has been
+// created after deleting the first one.
+type entry struct {
+	Value []byte
+	Seq   uint64
+}
+
+// write is a pending key that is being written to the
+// underlying keystore. Done is closed once the write has
+// completed and Err is set.
+type write struct {
+	Done chan struct{}
+	Err  error
+}
+
+// record is a single journal record.
+type record struct {
+	Name   string `json:"name"`
+	Value  []byte `json:"value,omitempty"`
+	Delete bool   `json:"delete,omitempty"`
+}
+
+const journalExt = ".journal"
+
+func (s *Store) String() string {
+	if str, ok := s.store.(fmt.Stringer); ok {
+		return str.String() + " (write-back)"
+	}
+	return fmt.Sprintf("%T (write-back)", s.store)
+}
+
+// Unwrap returns the underlying keystore.
+func (s *Store) Unwrap() kes.KeyStore { return s.store }
+
+// Pending returns the number of keys that have not been
+// written to the underlying keystore yet.
+func (s *Store) Pending() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.pending)
+}
+
+// Status returns the current state of the underlying keystore.
+func (s *Store) Status(ctx context.Context) (kes.KeyStoreState, error) {
+	return s.store.Status(ctx)
+}
+
+// Create creates a new entry with the given name if and only
+// if no such entry exists. Otherwise, Create returns
+// kes.ErrKeyExists.
+func (s *Store) Create(ctx context.Context, name string, value []byte) error {
+	if s.isPending(name) {
+		return kesdk.ErrKeyExists
+	}
+	if err := s.await(ctx, name); err != nil {
+		return err
+	}
+	switch _, err := s.store.Get(ctx, name); {
+	case err == nil:
+		return kesdk.ErrKeyExists
+	case !errors.Is(err, kesdk.ErrKeyNotFound):
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if _, ok := s.pending[name]; ok {
+		return kesdk.ErrKeyExists
+	}
+	if err := s.append(record{Name: name, Value: value}); err != nil {
+		return err
+	}
+	s.seq++
+	s.pending[name] = entry{Value: slices.Clone(value), Seq: s.seq}
+	return nil
+}
+
+// Delete removes the entry with the given name if it exists.
+// Otherwise, Delete returns kes.ErrKeyNotFound.
+//
+// If the entry is being written to the underlying keystore,
+// Delete waits until the write completes and removes it from
+// the underlying keystore, too.
+func (s *Store) Delete(ctx context.Context, name string) error {
+	s.lock.Lock()
+	_, pending := s.pending[name]
+	if pending {
+		if err := s.append(record{Name: name, Delete: true}); err != nil {
+			s.lock.Unlock()
+			return err
+		}
+		delete(s.pending, name)
+	}
+	w := s.inflight[name]
+	s.lock.Unlock()
+
+	if w != nil {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-w.Done:
+		}
+	}
+	if pending && (w == nil || w.Err != nil) {
+		return nil // Never written to the underlying keystore
+	}
+
+	err := s.store.Delete(ctx, name)
+	if pending && errors.Is(err, kesdk.ErrKeyNotFound) {
+		err = nil
+	}
+	return err
+}
+
+// Get returns the value associated with the given name. If
+// no such entry exists, Get returns kes.ErrKeyNotFound.
+func (s *Store) Get(ctx context.Context, name string) ([]byte, error) {
+	s.lock.Lock()
+	e, ok := s.pending[name]
+	s.lock.Unlock()
+
+	if ok {
+		return slices.Clone(e.Value), nil
+	}
+	return s.store.Get(ctx, name)
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue.
+//
+// The listing includes pending keys that have not been written
+// to the underlying keystore yet.
+func (s *Store) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	names, next, err := s.store.List(ctx, prefix, n)
+	if err != nil {
+		return nil, "", err
+	}
+
+	s.lock.Lock()
+	for name := range s.pending {
+		if strings.HasPrefix(name, prefix) && (next == "" || name < next) {
+			names = append(names, name)
+		}
+	}
+	s.lock.Unlock()
+
+	slices.Sort(names)
+	return slices.Compact(names), next, nil
+}
+
+// Close writes all pending keys to the underlying keystore,
+// if possible, and closes it. Keys that cannot be written
+// remain in the journal and are recovered by the next Store.
+func (s *Store) Close() error {
+	s.stop()
+	<-s.done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s.flush(ctx)
+
+	s.lock.Lock()
+	err := s.journal.Close()
+	if len(s.pending) == 0 {
+		os.Remove(s.filename)
+	}
+	s.lock.Unlock()
+
+	return errors.Join(err, s.store.Close())
+}
+
+// run writes pending keys to the underlying keystore periodically
+// until ctx is canceled.
+func (s *Store) run(ctx context.Context, interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.flush(ctx)
+		}
+	}
+}
+
+// flush writes all pending keys to the underlying keystore and
+// compacts the journal afterwards. It stops once the keystore
+// is not reachable.
+func (s *Store) flush(ctx context.Context) {
+	s.lock.Lock()
+	batch := make(map[string]entry, len(s.pending))
+	for name, e := range s.pending {
+		batch[name] = e
+	}
+	s.lock.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+
+	var written int
+	for name, e := range batch {
+		// Mark the key as in-flight such that a concurrent
+		// Delete waits for the write instead of leaving the
+		// key behind at the underlying keystore.
+		s.lock.Lock()
+		if p, ok := s.pending[name]; !ok || p.Seq != e.Seq {
+			s.lock.Unlock()
+			continue // Deleted in the meantime
+		}
+		w := &write{Done: make(chan struct{})}
+		s.inflight[name] = w
+		s.lock.Unlock()
+
+		err := s.store.Create(ctx, name, e.Value)
+		if errors.Is(err, kesdk.ErrKeyExists) {
+			err = nil // Written before, e.g. by a previous Store that did not remove its journal
+		}
+
+		s.lock.Lock()
+		delete(s.inflight, name)
+		w.Err = err
+		close(w.Done)
+		if p, ok := s.pending[name]; err == nil && ok && p.Seq == e.Seq {
+			delete(s.pending, name)
+			written++
+		}
+		s.lock.Unlock()
+
+		if keystore.IsTemporary(err) || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if written > 0 {
+		s.lock.Lock()
+		s.compact()
+		s.lock.Unlock()
+	}
+}
+
+// isPending reports whether a key with the given name has not
+// been written to the underlying keystore yet.
+func (s *Store) isPending(name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_, ok := s.pending[name]
+	return ok
+}
+
+// await waits until the key with the given name is no
+// longer being written to the underlying keystore.
+func (s *Store) await(ctx context.Context, name string) error {
+	s.lock.Lock()
+	w := s.inflight[name]
+	s.lock.Unlock()
+
+	if w == nil {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.Done:
+		return nil
+	}
+}
+
+// append appends the record to the journal and syncs it to
+// stable storage.
+func (s *Store) append(r record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if _, err = s.journal.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("writeback: failed to write journal: %v", err)
+	}
+	if err = s.journal.Sync(); err != nil {
+		return fmt.Errorf("writeback: failed to sync journal: %v", err)
+	}
+	return nil
+}
+
+// compact replaces the journal with a new one that only
+// contains the pending keys.
+func (s *Store) compact() error {
+	file, err := os.CreateTemp(s.dir, ".*.tmp")
+	if err != nil {
+		return fmt.Errorf("writeback: failed to create journal: %v", err)
+	}
+	defer os.Remove(file.Name()) // Cleanup if we fail before the rename - no-op afterwards
+
+	w := bufio.NewWriter(file)
+	for name, e := range s.pending {
+		b, err := json.Marshal(record{Name: name, Value: e.Value})
+		if err != nil {
+			file.Close()
+			return err
+		}
+		w.Write(append(b, '\n'))
+	}
+	if err = w.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("writeback: failed to write journal: %v", err)
+	}
+	if err = file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("writeback: failed to sync journal: %v", err)
+	}
+	if err = os.Rename(file.Name(), s.filename); err != nil {
+		file.Close()
+		return fmt.Errorf("writeback: failed to replace journal: %v", err)
+	}
+	if s.journal != nil {
+		s.journal.Close()
+	}
+	s.journal = file
+	return nil
+}
+
+// recover reads all records of the given journal and applies
+// them to the pending keys. An incomplete last record, e.g.
+// due to a crash while writing it, is ignored.
+func (s *Store) recover(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("writeback: failed to read journal '%s': %v", filename, err)
+	}
+
+	lines := bytes.Split(data, []byte{'\n'})
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var r record
+		if err = json.Unmarshal(line, &r); err != nil {
+			if i == len(lines)-1 {
+				break
+			}
+			return fmt.Errorf("writeback: invalid journal '%s': %v", filename, err)
+		}
+		if r.Delete {
+			delete(s.pending, r.Name)
+			continue
+		}
+		s.seq++
+		s.pending[r.Name] = entry{Value: r.Value, Seq: s.seq}
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package writeback
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+func TestStoreWriteBack(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	backend := &kes.MemKeyStore{}
+	if err := backend.Create(ctx, "key-0", []byte("value-0")); err != nil {
+		t.Fatal(err)
+	}
+	store, err := NewStore(backend, &Config{Dir: dir, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Create(ctx, "key-0", []byte("value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	for _, name := range []string{"key-1", "key-2"} {
+		if err = store.Create(ctx, name, []byte("value")); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	if err = store.Create(ctx, "key-1", []byte("value")); !errors.Is(err, kesdk.ErrKeyExists) {
+		t.Fatalf("Invalid error: got '%v' - want '%v'", err, kesdk.ErrKeyExists)
+	}
+	if _, err = backend.Get(ctx, "key-1"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Key has been written to keystore before flush: %v", err)
+	}
+	if _, err = store.Get(ctx, "key-1"); err != nil {
+		t.Fatalf("Failed to read pending key: %v", err)
+	}
+	if err = store.Delete(ctx, "key-2"); err != nil {
+		t.Fatalf("Failed to delete pending key: %v", err)
+	}
+	if names, _, err := store.List(ctx, "", -1); err != nil || !slices.Equal(names, []string{"key-0", "key-1"}) {
+		t.Fatalf("Invalid listing: got '%v' - want '%v'", names, []string{"key-0", "key-1"})
+	}
+
+	// Simulate a crash by recovering the journal with
+	// a new Store while the first one is still open.
+	recovered, err := NewStore(backend, &Config{Dir: dir, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := recovered.Pending(); n != 1 {
+		t.Fatalf("Invalid number of pending keys: got '%d' - want '%d'", n, 1)
+	}
+	if err = recovered.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = backend.Get(ctx, "key-1"); err != nil {
+		t.Fatalf("Pending key has not been written to keystore: %v", err)
+	}
+	if _, err = backend.Get(ctx, "key-2"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleted key has been written to keystore: %v", err)
+	}
+}
+
+func TestStoreWriteBackOffline(t *testing.T) {
+	ctx := context.Background()
+
+	backend := &offlineStore{KeyStore: &kes.MemKeyStore{}}
+	store, err := NewStore(backend, &Config{Dir: t.TempDir(), Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	backend.Offline = true
+	store.flush(ctx)
+	if n := store.Pending(); n != 1 {
+		t.Fatalf("Invalid number of pending keys: got '%d' - want '%d'", n, 1)
+	}
+
+	backend.Offline = false
+	store.flush(ctx)
+	if n := store.Pending(); n != 0 {
+		t.Fatalf("Invalid number of pending keys: got '%d' - want '%d'", n, 0)
+	}
+}
+
+func TestStoreWriteBackDeleteInFlight(t *testing.T) {
+	ctx := context.Background()
+
+	backend := &blockingStore{
+		KeyStore: &kes.MemKeyStore{},
+		Started:  make(chan struct{}),
+		Release:  make(chan struct{}),
+	}
+	store, err := NewStore(backend, &Config{Dir: t.TempDir(), Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	if err = store.Create(ctx, "key", []byte("value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	flushed := make(chan struct{})
+	go func() {
+		defer close(flushed)
+		store.flush(ctx)
+	}()
+	<-backend.Started // The key is being written to the backend
+
+	deleted := make(chan error, 1)
+	go func() { deleted <- store.Delete(ctx, "key") }()
+	select {
+	case err = <-deleted:
+		t.Fatalf("Delete returned before the in-flight write completed: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(backend.Release)
+	if err = <-deleted; err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	<-flushed
+
+	if _, err = backend.Get(ctx, "key"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleted key has been resurrected at the keystore: %v", err)
+	}
+	if _, err = store.Get(ctx, "key"); !errors.Is(err, kesdk.ErrKeyNotFound) {
+		t.Fatalf("Deleted key is still present: %v", err)
+	}
+	if n := store.Pending(); n != 0 {
+		t.Fatalf("Invalid number of pending keys: got '%d' - want '%d'", n, 0)
+	}
+}
+
+// blockingStore is a KeyStore that signals Started when
+// creating a key and waits for Release before doing so.
+type blockingStore struct {
+	kes.KeyStore
+	Started chan struct{}
+	Release chan struct{}
+}
+
+func (s *blockingStore) Create(ctx context.Context, name string, value []byte) error {
+	close(s.Started)
+	<-s.Release
+	return s.KeyStore.Create(ctx, name, value)
+}
+
+// offlineStore is a KeyStore that fails to create
+// keys with an unreachable error while offline.
+type offlineStore struct {
+	kes.KeyStore
+	Offline bool
+}
+
+func (s *offlineStore) Create(ctx context.Context, name string, value []byte) error {
+	if s.Offline {
+		return &keystore.ErrUnreachable{}
+	}
+	return s.KeyStore.Create(ctx, name, value)
+}
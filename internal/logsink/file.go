@@ -0,0 +1,185 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileConfig is a structure containing the configuration of
+// a log file.
+type FileConfig struct {
+	// Path is the path of the log file. It is created if it
+	// does not exist.
+	Path string
+
+	// MaxSize is the max. size of the log file in bytes. Once
+	// a write would exceed MaxSize, the file is rotated. If <= 0,
+	// the file is not rotated based on its size.
+	MaxSize int64
+
+	// MaxAge is the max. age of the log file. Once the file is
+	// older than MaxAge, it is rotated. If <= 0, the file is
+	// not rotated based on its age.
+	MaxAge time.Duration
+
+	// MaxBackups is the max. number of rotated log files that
+	// are retained. Older files are removed. If <= 0, all
+	// rotated files are retained.
+	MaxBackups int
+}
+
+// backupLayout is the time layout of rotated log file suffixes.
+const backupLayout = "2006-01-02T15-04-05.000"
+
+// File is a log file that is rotated once it exceeds its max.
+// size or age.
+//
+// A rotated file is renamed to <path>.<timestamp>, where the
+// timestamp is the time of the rotation in UTC, and a new file
+// is created at the original path.
+type File struct {
+	config FileConfig
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	created time.Time
+}
+
+// OpenFile opens the log file for appending. It creates the
+// file and its parent directory if they do not exist.
+func OpenFile(config *FileConfig) (*File, error) {
+	if config.Path == "" {
+		return nil, errors.New("logsink: log file path is empty")
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("logsink: failed to create log directory: %v", err)
+	}
+
+	f := &File{config: *config}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Write appends p to the log file. It rotates the file first
+// if writing p would exceed the max. file size or the file is
+// older than the max. age.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.size > 0 && f.needsRotation(int64(len(p)), time.Now()) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// needsRotation reports whether the file has to be rotated
+// before writing n bytes at time now.
+func (f *File) needsRotation(n int64, now time.Time) bool {
+	if f.config.MaxSize > 0 && f.size+n > f.config.MaxSize {
+		return true
+	}
+	return f.config.MaxAge > 0 && now.Sub(f.created) >= f.config.MaxAge
+}
+
+// open opens or creates the log file at the configured path.
+func (f *File) open() error {
+	file, err := os.OpenFile(f.config.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("logsink: failed to open log file: %v", err)
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("logsink: failed to open log file: %v", err)
+	}
+
+	f.file, f.size, f.created = file, stat.Size(), time.Now()
+	if f.size > 0 {
+		f.created = stat.ModTime() // Approximation of when an existing file has been created
+	}
+	return nil
+}
+
+// rotate renames the current log file, opens a new one and
+// removes rotated files exceeding the max. number of backups.
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("logsink: failed to rotate log file: %v", err)
+	}
+	f.file = nil
+
+	backup := f.config.Path + "." + time.Now().UTC().Format(backupLayout)
+	if err := os.Rename(f.config.Path, backup); err != nil {
+		if oErr := f.open(); oErr != nil { // Keep writing to the current file
+			return oErr
+		}
+		return fmt.Errorf("logsink: failed to rotate log file: %v", err)
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.created = time.Now()
+	return f.removeBackups()
+}
+
+// removeBackups removes the oldest rotated log files if there
+// are more than the max. number of backups.
+func (f *File) removeBackups() error {
+	if f.config.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups, err := filepath.Glob(f.config.Path + ".*")
+	if err != nil {
+		return err
+	}
+	backups = slices.DeleteFunc(backups, func(name string) bool {
+		_, err := time.Parse(backupLayout, strings.TrimPrefix(name, f.config.Path+"."))
+		return err != nil
+	})
+	if len(backups) <= f.config.MaxBackups {
+		return nil
+	}
+
+	slices.Sort(backups) // Timestamps sort chronologically
+	for _, name := range backups[:len(backups)-f.config.MaxBackups] {
+		if err := os.Remove(name); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("logsink: failed to remove rotated log file: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRotateBySize(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "logs", "audit.log")
+	file, err := OpenFile(&FileConfig{
+		Path:       path,
+		MaxSize:    16,
+		MaxBackups: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer file.Close()
+
+	for i := 0; i < 5; i++ {
+		if _, err = file.Write([]byte("0123456789\n")); err != nil {
+			t.Fatalf("Failed to write to log file: %v", err)
+		}
+		time.Sleep(2 * time.Millisecond) // Rotated files have millisecond timestamps
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if string(content) != "0123456789\n" {
+		t.Fatalf("Invalid log file content: got '%s' - want '%s'", content, "0123456789\n")
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("Invalid number of rotated log files: got '%d' - want '%d'", len(backups), 2)
+	}
+}
+
+func TestFileRotateByAge(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "error.log")
+	file, err := OpenFile(&FileConfig{
+		Path:   path,
+		MaxAge: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Failed to open log file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err = file.Write([]byte("first\n")); err != nil {
+		t.Fatalf("Failed to write to log file: %v", err)
+	}
+	file.created = file.created.Add(-2 * time.Hour)
+	if _, err = file.Write([]byte("second\n")); err != nil {
+		t.Fatalf("Failed to write to log file: %v", err)
+	}
+
+	backups, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("Invalid number of rotated log files: got '%d' - want '%d'", len(backups), 1)
+	}
+	content, err := os.ReadFile(backups[0])
+	if err != nil {
+		t.Fatalf("Failed to read rotated log file: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "first") {
+		t.Fatalf("Invalid rotated log file content: '%s'", content)
+	}
+}
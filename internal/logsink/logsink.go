@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package logsink implements log destinations besides STDOUT
+// and STDERR, like syslog servers, HTTP webhooks and local files
+// that are rotated once they exceed a size or age.
+package logsink
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Handler is an slog.Handler that passes log records to a set
+// of handlers, e.g. one for a file and one for a syslog server.
+//
+// Closing a Handler closes all underlying log sinks.
+type Handler struct {
+	handlers []slog.Handler
+	closers  []io.Closer
+}
+
+var _ slog.Handler = (*Handler)(nil) // compiler check
+
+// NewHandler returns a new Handler that passes log records to
+// all handlers and closes all closers once it gets closed.
+func NewHandler(handlers []slog.Handler, closers []io.Closer) *Handler {
+	return &Handler{
+		handlers: handlers,
+		closers:  closers,
+	}
+}
+
+// Enabled reports whether any underlying handler handles
+// records at the given level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes the record to all underlying handlers that
+// are enabled for the record's level.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a new Handler whose attributes consist of
+// both the receiver's attributes and the arguments.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+	return &Handler{handlers: handlers, closers: h.closers}
+}
+
+// WithGroup returns a new Handler with the given group appended
+// to the receiver's existing groups.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(h.handlers))
+	for _, handler := range h.handlers {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+	return &Handler{handlers: handlers, closers: h.closers}
+}
+
+// Close closes all underlying log sinks.
+func (h *Handler) Close() error {
+	var errs []error
+	for _, c := range h.closers {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog facilities as defined by RFC 5424.
+var facilities = map[string]int{
+	"kern":     0,
+	"user":     1,
+	"mail":     2,
+	"daemon":   3,
+	"auth":     4,
+	"syslog":   5,
+	"lpr":      6,
+	"news":     7,
+	"uucp":     8,
+	"cron":     9,
+	"authpriv": 10,
+	"ftp":      11,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// Syslog severities as defined by RFC 5424.
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// ParseFacility parses a syslog facility name, like "daemon"
+// or "local0", and returns its numerical code.
+func ParseFacility(name string) (int, error) {
+	if f, ok := facilities[strings.ToLower(name)]; ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("logsink: invalid syslog facility '%s'", name)
+}
+
+// SyslogConfig is a structure containing the configuration of
+// a syslog server connection.
+type SyslogConfig struct {
+	// Network is the transport used to connect to the syslog
+	// server. Either "udp", "tcp" or "tls".
+	Network string
+
+	// Addr is the address of the syslog server, e.g.
+	// "syslog.example.com:6514".
+	Addr string
+
+	// TLS is the TLS configuration used if the network
+	// is "tls". If nil, the default TLS configuration
+	// is used.
+	TLS *tls.Config
+
+	// Facility is the syslog facility code of all messages.
+	Facility int
+
+	// AppName is the RFC 5424 APP-NAME of all messages. If
+	// empty, defaults to "kes".
+	AppName string
+
+	// Hostname is the RFC 5424 HOSTNAME of all messages. If
+	// empty, defaults to the hostname reported by the OS.
+	Hostname string
+}
+
+// Syslog is a connection to a syslog server. It sends log
+// messages formatted as specified by RFC 5424. Messages sent
+// via TCP or TLS are framed using octet counting as specified
+// by RFC 6587 and RFC 5425.
+//
+// It connects to the syslog server when sending the first
+// message and reconnects if sending a message fails.
+type Syslog struct {
+	config SyslogConfig
+	pid    string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslog returns a new Syslog for the given config.
+func NewSyslog(config *SyslogConfig) (*Syslog, error) {
+	switch config.Network {
+	case "udp", "tcp", "tls":
+	default:
+		return nil, fmt.Errorf("logsink: invalid syslog network '%s'", config.Network)
+	}
+	if config.Addr == "" {
+		return nil, errors.New("logsink: syslog server address is empty")
+	}
+	if config.Facility < 0 || config.Facility > 23 {
+		return nil, fmt.Errorf("logsink: invalid syslog facility '%d'", config.Facility)
+	}
+
+	s := &Syslog{
+		config: *config,
+		pid:    strconv.Itoa(os.Getpid()),
+	}
+	if config.TLS != nil {
+		s.config.TLS = config.TLS.Clone()
+	}
+	if s.config.AppName == "" {
+		s.config.AppName = "kes"
+	}
+	if s.config.Hostname == "" {
+		if s.config.Hostname, _ = os.Hostname(); s.config.Hostname == "" {
+			s.config.Hostname = "-"
+		}
+	}
+	return s, nil
+}
+
+// Handler returns an slog.Handler that sends log records as
+// text to the syslog server. The syslog severity of a message
+// corresponds to the level of its record.
+func (s *Syslog) Handler(opts *slog.HandlerOptions) slog.Handler {
+	var o slog.HandlerOptions
+	if opts != nil {
+		o = *opts
+	}
+	replace := o.ReplaceAttr
+	o.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey { // Syslog messages have a timestamp
+			return slog.Attr{}
+		}
+		if replace != nil {
+			return replace(groups, a)
+		}
+		return a
+	}
+
+	return &syslogHandler{handlers: [4]slog.Handler{
+		slog.NewTextHandler(severityWriter{s, severityDebug}, &o),
+		slog.NewTextHandler(severityWriter{s, severityInfo}, &o),
+		slog.NewTextHandler(severityWriter{s, severityWarning}, &o),
+		slog.NewTextHandler(severityWriter{s, severityError}, &o),
+	}}
+}
+
+// Close closes the connection to the syslog server, if any.
+func (s *Syslog) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// send sends the message with the given severity to the syslog
+// server. It retries once with a new connection if sending the
+// message fails.
+func (s *Syslog) send(severity int, msg []byte) error {
+	const (
+		Layout  = "2006-01-02T15:04:05.000000Z07:00"
+		Timeout = 5 * time.Second
+	)
+
+	msg = []byte(strings.TrimRight(string(msg), "\n"))
+	frame := fmt.Appendf(nil, "<%d>1 %s %s %s %s - - ",
+		s.config.Facility*8+severity,
+		time.Now().Format(Layout),
+		s.config.Hostname,
+		s.config.AppName,
+		s.pid,
+	)
+	frame = append(frame, msg...)
+	if s.config.Network != "udp" {
+		frame = append(fmt.Appendf(nil, "%d ", len(frame)), frame...)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if err = s.connect(Timeout); err != nil {
+				continue
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(Timeout))
+		if _, err = s.conn.Write(frame); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("logsink: failed to send syslog message: %v", err)
+}
+
+// connect connects to the syslog server.
+func (s *Syslog) connect(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		conn   net.Conn
+		err    error
+		dialer net.Dialer
+	)
+	switch s.config.Network {
+	case "tls":
+		config := s.config.TLS
+		if config == nil {
+			config = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+		tlsDialer := tls.Dialer{NetDialer: &dialer, Config: config}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", s.config.Addr)
+	default:
+		conn, err = dialer.DialContext(ctx, s.config.Network, s.config.Addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// severityWriter is an io.Writer that sends each write as one
+// syslog message with a fixed severity.
+type severityWriter struct {
+	s        *Syslog
+	severity int
+}
+
+func (w severityWriter) Write(p []byte) (int, error) {
+	if err := w.s.send(w.severity, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// syslogHandler passes log records to one of four handlers
+// depending on the record's level. Each handler sends messages
+// with the syslog severity corresponding to its level.
+type syslogHandler struct {
+	handlers [4]slog.Handler // Debug, Info, Warn, Error
+}
+
+func (h *syslogHandler) handler(level slog.Level) slog.Handler {
+	switch {
+	case level >= slog.LevelError:
+		return h.handlers[3]
+	case level >= slog.LevelWarn:
+		return h.handlers[2]
+	case level >= slog.LevelInfo:
+		return h.handlers[1]
+	default:
+		return h.handlers[0]
+	}
+}
+
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler(level).Enabled(ctx, level)
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler(r.Level).Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var handlers [4]slog.Handler
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &syslogHandler{handlers: handlers}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	var handlers [4]slog.Handler
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &syslogHandler{handlers: handlers}
+}
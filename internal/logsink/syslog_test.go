@@ -0,0 +1,119 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"bufio"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSyslogUDP(t *testing.T) {
+	t.Parallel()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	syslog, err := NewSyslog(&SyslogConfig{
+		Network:  "udp",
+		Addr:     conn.LocalAddr().String(),
+		Facility: 16, // local0
+		Hostname: "kes-1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create syslog: %v", err)
+	}
+	defer syslog.Close()
+
+	log := slog.New(syslog.Handler(nil))
+	log.Warn("key store is slow", "latency", "5s")
+
+	buf := make([]byte, 4096)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Failed to read syslog message: %v", err)
+	}
+	msg := string(buf[:n])
+	if !strings.HasPrefix(msg, "<132>1 ") { // local0 (16) * 8 + warning (4)
+		t.Fatalf("Invalid syslog priority: '%s'", msg)
+	}
+	if fields := strings.Fields(msg); len(fields) < 7 || fields[2] != "kes-1" || fields[3] != "kes" {
+		t.Fatalf("Invalid syslog header: '%s'", msg)
+	}
+	if !strings.Contains(msg, `msg="key store is slow" latency=5s`) || strings.Contains(msg, "time=") {
+		t.Fatalf("Invalid syslog message: '%s'", msg)
+	}
+}
+
+func TestSyslogTCP(t *testing.T) {
+	t.Parallel()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	syslog, err := NewSyslog(&SyslogConfig{
+		Network:  "tcp",
+		Addr:     ln.Addr().String(),
+		Facility: 3, // daemon
+	})
+	if err != nil {
+		t.Fatalf("Failed to create syslog: %v", err)
+	}
+	defer syslog.Close()
+
+	log := slog.New(syslog.Handler(nil))
+	log.Error("first")
+	log.Info("second")
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("Failed to accept connection: %v", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for _, want := range []struct{ Priority, Message string }{
+		{"<27>1 ", "first"}, // daemon (3) * 8 + error (3)
+		{"<30>1 ", "second"},
+	} {
+		length, err := r.ReadString(' ')
+		if err != nil {
+			t.Fatalf("Failed to read message length: %v", err)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(length))
+		if err != nil {
+			t.Fatalf("Invalid message length '%s': %v", length, err)
+		}
+		buf := make([]byte, n)
+		if _, err = io.ReadFull(r, buf); err != nil {
+			t.Fatalf("Failed to read message: %v", err)
+		}
+		msg := string(buf)
+		if !strings.HasPrefix(msg, want.Priority) || !strings.Contains(msg, "msg="+want.Message) {
+			t.Fatalf("Invalid syslog message: got '%s' - want priority '%s' and message '%s'", msg, want.Priority, want.Message)
+		}
+	}
+}
+
+func TestParseFacility(t *testing.T) {
+	t.Parallel()
+
+	if f, err := ParseFacility("LOCAL7"); err != nil || f != 23 {
+		t.Fatalf("Failed to parse facility: got '%d': %v", f, err)
+	}
+	if _, err := ParseFacility("local8"); err == nil {
+		t.Fatal("Parsed invalid facility")
+	}
+}
@@ -0,0 +1,327 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/headers"
+)
+
+// Webhook body formats.
+const (
+	// FormatJSON sends a batch of events as JSON array.
+	FormatJSON = "json"
+
+	// FormatSplunk sends a batch of events as concatenated
+	// JSON objects of the form {"event": <event>}, as
+	// expected by the Splunk HTTP Event Collector (HEC).
+	FormatSplunk = "splunk"
+)
+
+// WebhookConfig is a structure containing the configuration
+// of a webhook log sink.
+type WebhookConfig struct {
+	// Endpoint is the HTTP(S) URL events are sent to using
+	// POST requests.
+	Endpoint string
+
+	// AuthHeader is the name of the HTTP header containing
+	// AuthValue. If empty, defaults to "Authorization".
+	AuthHeader string
+
+	// AuthValue is an optional value sent in the AuthHeader
+	// of each request, e.g. "Splunk <token>" or "Bearer <token>".
+	AuthValue string
+
+	// Format is the body format. Either FormatJSON or
+	// FormatSplunk. If empty, defaults to FormatJSON.
+	Format string
+
+	// TLS is an optional TLS configuration used for HTTPS
+	// endpoints.
+	TLS *tls.Config
+
+	// BufferSize is the max. number of events that are
+	// buffered before new events get dropped. If <= 0,
+	// defaults to 10000.
+	BufferSize int
+
+	// BatchSize is the max. number of events sent in one
+	// request. If <= 0, defaults to 100.
+	BatchSize int
+
+	// FlushInterval is the max. time events are buffered
+	// before they are sent. If <= 0, defaults to 1s.
+	FlushInterval time.Duration
+
+	// MaxRetries is the max. number of times sending a batch
+	// is retried, with exponential backoff, before the batch
+	// gets dropped. If 0, defaults to 5. If < 0, a batch is
+	// never retried.
+	MaxRetries int
+
+	// ErrorLog is an optional logger for errors that occur
+	// while sending events. If nil, slog.Default is used.
+	ErrorLog *slog.Logger
+}
+
+// Webhook is a log sink that sends batches of JSON events to
+// an HTTP(S) endpoint.
+//
+// Events are buffered in memory and sent by a background
+// goroutine once a batch is full or the flush interval has
+// passed. Failed requests are retried with an exponential
+// backoff unless the endpoint rejected the batch with a 4xx
+// status code other than 408 or 429.
+type Webhook struct {
+	config WebhookConfig
+	client http.Client
+
+	queue   chan []byte
+	closing chan struct{}
+	done    chan struct{}
+	once    sync.Once
+
+	dropped atomic.Uint64
+}
+
+// NewWebhook returns a new Webhook for the given config.
+func NewWebhook(config *WebhookConfig) (*Webhook, error) {
+	const (
+		DefaultBufferSize    = 10000
+		DefaultBatchSize     = 100
+		DefaultFlushInterval = 1 * time.Second
+		DefaultMaxRetries    = 5
+	)
+
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("logsink: invalid webhook endpoint '%s'", config.Endpoint)
+	}
+	switch config.Format {
+	case "", FormatJSON, FormatSplunk:
+	default:
+		return nil, fmt.Errorf("logsink: invalid webhook format '%s'", config.Format)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLS != nil {
+		transport.TLSClientConfig = config.TLS.Clone()
+	}
+	w := &Webhook{
+		config:  *config,
+		client:  http.Client{Transport: transport, Timeout: 30 * time.Second},
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if w.config.AuthHeader == "" {
+		w.config.AuthHeader = headers.Authorization
+	}
+	if w.config.Format == "" {
+		w.config.Format = FormatJSON
+	}
+	if w.config.BufferSize <= 0 {
+		w.config.BufferSize = DefaultBufferSize
+	}
+	if w.config.BatchSize <= 0 {
+		w.config.BatchSize = DefaultBatchSize
+	}
+	if w.config.FlushInterval <= 0 {
+		w.config.FlushInterval = DefaultFlushInterval
+	}
+	if w.config.MaxRetries == 0 {
+		w.config.MaxRetries = DefaultMaxRetries
+	}
+	if w.config.ErrorLog == nil {
+		w.config.ErrorLog = slog.Default()
+	}
+	w.queue = make(chan []byte, w.config.BufferSize)
+
+	go w.run()
+	return w, nil
+}
+
+// Handler returns an slog.Handler that sends log records as
+// JSON events to the webhook endpoint.
+func (w *Webhook) Handler(opts *slog.HandlerOptions) slog.Handler {
+	return slog.NewJSONHandler(w, opts)
+}
+
+// Write adds p, which must be a single JSON event, to the
+// buffer of events that are sent to the webhook endpoint.
+// It returns an error if the buffer is full and the event
+// has been dropped.
+func (w *Webhook) Write(p []byte) (int, error) {
+	event := bytes.TrimSpace(bytes.Clone(p))
+
+	select {
+	case <-w.closing:
+		return 0, errors.New("logsink: webhook is closed")
+	default:
+	}
+	select {
+	case w.queue <- event:
+		return len(p), nil
+	default:
+		w.dropped.Add(1)
+		return 0, errors.New("logsink: webhook buffer is full: event dropped")
+	}
+}
+
+// Dropped returns the number of events that have been dropped
+// because the buffer was full or they could not be sent.
+func (w *Webhook) Dropped() uint64 { return w.dropped.Load() }
+
+// Close stops accepting new events and sends all buffered
+// events once.
+func (w *Webhook) Close() error {
+	w.once.Do(func() { close(w.closing) })
+	<-w.done
+	return nil
+}
+
+// run sends buffered events in batches until the webhook is
+// closed.
+func (w *Webhook) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, w.config.BatchSize)
+	for {
+		select {
+		case event := <-w.queue:
+			if batch = append(batch, event); len(batch) < w.config.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-w.closing:
+			w.drain(batch)
+			return
+		}
+
+		w.sendRetry(batch)
+		batch = batch[:0]
+	}
+}
+
+// sendRetry sends the batch and retries with an exponential
+// backoff until it succeeds, the max. number of retries has
+// been reached or the webhook has been closed.
+func (w *Webhook) sendRetry(batch [][]byte) {
+	const (
+		MinDelay = 1 * time.Second
+		MaxDelay = 30 * time.Second
+	)
+
+	var closed bool // Once closed, don't wait before the last retry
+	delay := MinDelay
+	for retry := 0; ; retry++ {
+		retryable, err := w.send(batch)
+		if err == nil {
+			return
+		}
+		if !retryable || retry >= w.config.MaxRetries || closed {
+			w.dropped.Add(uint64(len(batch)))
+			w.config.ErrorLog.Error(fmt.Sprintf("logsink: failed to send %d events to webhook: %v", len(batch), err), "endpoint", w.config.Endpoint)
+			return
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-w.closing:
+			timer.Stop()
+			closed = true
+		case <-timer.C:
+		}
+		delay = min(2*delay, MaxDelay)
+	}
+}
+
+// drain sends the batch and all buffered events once.
+func (w *Webhook) drain(batch [][]byte) {
+	for {
+		select {
+		case event := <-w.queue:
+			if batch = append(batch, event); len(batch) < w.config.BatchSize {
+				continue
+			}
+		default:
+		}
+		if len(batch) == 0 {
+			return
+		}
+		if _, err := w.send(batch); err != nil {
+			w.dropped.Add(uint64(len(batch) + len(w.queue)))
+			w.config.ErrorLog.Error(fmt.Sprintf("logsink: failed to send events to webhook: %v", err), "endpoint", w.config.Endpoint)
+			return
+		}
+		batch = batch[:0]
+	}
+}
+
+// send sends the batch to the webhook endpoint. It reports
+// whether a failed request should be retried.
+func (w *Webhook) send(batch [][]byte) (bool, error) {
+	var body bytes.Buffer
+	switch w.config.Format {
+	case FormatSplunk:
+		for _, event := range batch {
+			body.WriteString(`{"event":`)
+			body.Write(event)
+			body.WriteString("}\n")
+		}
+	default:
+		body.WriteByte('[')
+		for i, event := range batch {
+			if i > 0 {
+				body.WriteByte(',')
+			}
+			body.Write(event)
+		}
+		body.WriteByte(']')
+	}
+
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, w.config.Endpoint, &body)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+	if w.config.AuthValue != "" {
+		req.Header.Set(w.config.AuthHeader, w.config.AuthValue)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)) // Drain body such that the connection can be reused
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, errors.New("logsink: webhook responded with '" + resp.Status + "'")
+	default:
+		return false, errors.New("logsink: webhook responded with '" + resp.Status + "'")
+	}
+}
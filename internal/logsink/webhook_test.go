@@ -0,0 +1,133 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package logsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu     sync.Mutex
+		events []map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "Bearer secret" {
+			t.Errorf("Invalid Authorization header: got '%s' - want '%s'", auth, "Bearer secret")
+		}
+		var batch []map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("Invalid JSON body: %v", err)
+		}
+		mu.Lock()
+		events = append(events, batch...)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(&WebhookConfig{
+		Endpoint:  server.URL,
+		AuthValue: "Bearer secret",
+		BatchSize: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+
+	log := slog.New(webhook.Handler(nil))
+	log.Info("first")
+	log.Warn("second")
+	log.Error("third")
+	if err = webhook.Close(); err != nil {
+		t.Fatalf("Failed to close webhook: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 3 {
+		t.Fatalf("Invalid number of events: got '%d' - want '%d'", len(events), 3)
+	}
+	for i, msg := range []string{"first", "second", "third"} {
+		if events[i]["msg"] != msg {
+			t.Fatalf("Event %d: invalid message: got '%v' - want '%s'", i, events[i]["msg"], msg)
+		}
+	}
+}
+
+func TestWebhookSplunk(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(&WebhookConfig{
+		Endpoint: server.URL,
+		Format:   FormatSplunk,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	webhook.Write([]byte(`{"msg":"first"}` + "\n"))
+	webhook.Write([]byte(`{"msg":"second"}` + "\n"))
+	webhook.Close()
+
+	const Body = `{"event":{"msg":"first"}}` + "\n" + `{"event":{"msg":"second"}}` + "\n"
+	if body := <-bodies; !bytes.Equal(body, []byte(Body)) {
+		t.Fatalf("Invalid body: got '%s' - want '%s'", body, Body)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	t.Parallel()
+
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch requests.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 2:
+			w.WriteHeader(http.StatusBadRequest) // Not retryable
+		}
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook(&WebhookConfig{
+		Endpoint:      server.URL,
+		FlushInterval: 10 * time.Millisecond,
+		ErrorLog:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	webhook.Write([]byte(`{"msg":"first"}`))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for webhook.Dropped() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	webhook.Close()
+
+	if n := requests.Load(); n != 2 {
+		t.Fatalf("Invalid number of requests: got '%d' - want '%d'", n, 2)
+	}
+	if n := webhook.Dropped(); n != 1 {
+		t.Fatalf("Invalid number of dropped events: got '%d' - want '%d'", n, 1)
+	}
+}
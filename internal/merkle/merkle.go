@@ -0,0 +1,169 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package merkle implements an append-only Merkle tree
+// with inclusion proofs as specified by RFC 9162.
+package merkle
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/bits"
+)
+
+// Hash is a leaf or node hash.
+type Hash = [sha256.Size]byte
+
+// LeafHash returns the hash of a leaf with the given data:
+// SHA-256(0x00 || data).
+func LeafHash(data []byte) Hash {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+
+	var sum Hash
+	h.Sum(sum[:0])
+	return sum
+}
+
+// nodeHash returns the hash of an inner node with the given
+// children: SHA-256(0x01 || left || right).
+func nodeHash(left, right Hash) Hash {
+	var b [1 + 2*sha256.Size]byte
+	b[0] = 0x01
+	copy(b[1:], left[:])
+	copy(b[1+sha256.Size:], right[:])
+	return sha256.Sum256(b[:])
+}
+
+// Tree is an append-only Merkle tree.
+//
+// It keeps the hashes of all leaves and all complete
+// subtrees in memory, which requires about two hashes
+// per leaf. Hence, computing roots and proofs takes
+// O(log n) time.
+//
+// A Tree is not safe for concurrent use.
+type Tree struct {
+	// levels[0] contains the leaf hashes. levels[i][j]
+	// contains the hash of the complete subtree with
+	// the leaves [j*2^i, (j+1)*2^i).
+	levels [][]Hash
+}
+
+// Size returns the number of leaves.
+func (t *Tree) Size() uint64 {
+	if len(t.levels) == 0 {
+		return 0
+	}
+	return uint64(len(t.levels[0]))
+}
+
+// Append appends a leaf with the given hash and returns
+// its index.
+func (t *Tree) Append(leaf Hash) uint64 {
+	if len(t.levels) == 0 {
+		t.levels = append(t.levels, nil)
+	}
+	t.levels[0] = append(t.levels[0], leaf)
+	index := uint64(len(t.levels[0]) - 1)
+
+	// Complete all subtrees that end with the new leaf.
+	for i := 0; len(t.levels[i])%2 == 0; i++ {
+		n := len(t.levels[i])
+		if len(t.levels) == i+1 {
+			t.levels = append(t.levels, nil)
+		}
+		t.levels[i+1] = append(t.levels[i+1], nodeHash(t.levels[i][n-2], t.levels[i][n-1]))
+	}
+	return index
+}
+
+// Root returns the root hash of the tree containing the
+// first size leaves. It returns an error if the tree
+// contains less leaves.
+//
+// The root of an empty tree is the hash of the empty
+// string.
+func (t *Tree) Root(size uint64) (Hash, error) {
+	if size > t.Size() {
+		return Hash{}, errors.New("merkle: tree size exceeds number of leaves")
+	}
+	if size == 0 {
+		return sha256.Sum256(nil), nil
+	}
+	return t.hash(0, size), nil
+}
+
+// InclusionProof returns the inclusion proof of the leaf
+// with the given index in the tree containing the first
+// size leaves.
+func (t *Tree) InclusionProof(index, size uint64) ([]Hash, error) {
+	if size > t.Size() {
+		return nil, errors.New("merkle: tree size exceeds number of leaves")
+	}
+	if index >= size {
+		return nil, errors.New("merkle: leaf index exceeds tree size")
+	}
+	return t.path(index, 0, size), nil
+}
+
+// path returns the audit path of the leaf at index within
+// the subtree [lo, hi) as specified by RFC 9162 2.1.3.1.
+func (t *Tree) path(index, lo, hi uint64) []Hash {
+	if hi-lo == 1 {
+		return nil
+	}
+	k := split(hi - lo)
+	if index < lo+k {
+		return append(t.path(index, lo, lo+k), t.hash(lo+k, hi))
+	}
+	return append(t.path(index, lo+k, hi), t.hash(lo, lo+k))
+}
+
+// hash returns the hash of the subtree with the leaves [lo, hi).
+func (t *Tree) hash(lo, hi uint64) Hash {
+	n := hi - lo
+	if n&(n-1) == 0 && lo%n == 0 {
+		level := bits.TrailingZeros64(n)
+		return t.levels[level][lo>>level]
+	}
+	k := split(n)
+	return nodeHash(t.hash(lo, lo+k), t.hash(lo+k, hi))
+}
+
+// split returns the largest power of two smaller than n.
+// n must be greater than one.
+func split(n uint64) uint64 {
+	return 1 << (bits.Len64(n-1) - 1)
+}
+
+// VerifyInclusion reports whether proof proves that the leaf
+// with the given index is part of the tree with the given size
+// and root as specified by RFC 9162 2.1.3.2.
+func VerifyInclusion(leaf Hash, index, size uint64, proof []Hash, root Hash) bool {
+	if index >= size {
+		return false
+	}
+
+	fn, sn := index, size-1
+	r := leaf
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+		if fn&1 == 1 || fn == sn {
+			r = nodeHash(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = nodeHash(r, p)
+		}
+		fn >>= 1
+		sn >>= 1
+	}
+	return sn == 0 && r == root
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"crypto/sha256"
+	"strconv"
+	"testing"
+)
+
+func TestTreeRoot(t *testing.T) {
+	const N = 70
+
+	var (
+		tree   Tree
+		leaves []Hash
+	)
+	for i := 0; i < N; i++ {
+		leaves = append(leaves, LeafHash([]byte(strconv.Itoa(i))))
+		if index := tree.Append(leaves[i]); index != uint64(i) {
+			t.Fatalf("Invalid leaf index: got '%d' - want '%d'", index, i)
+		}
+	}
+
+	for size := 0; size <= N; size++ {
+		root, err := tree.Root(uint64(size))
+		if err != nil {
+			t.Fatalf("Size %d: failed to compute root: %v", size, err)
+		}
+		if want := referenceRoot(leaves[:size]); root != want {
+			t.Fatalf("Size %d: invalid root: got '%x' - want '%x'", size, root, want)
+		}
+	}
+	if _, err := tree.Root(N + 1); err == nil {
+		t.Fatal("Computed root of tree larger than number of leaves")
+	}
+}
+
+func TestTreeInclusionProof(t *testing.T) {
+	const N = 70
+
+	var (
+		tree   Tree
+		leaves []Hash
+	)
+	for i := 0; i < N; i++ {
+		leaves = append(leaves, LeafHash([]byte(strconv.Itoa(i))))
+		tree.Append(leaves[i])
+	}
+
+	for size := uint64(1); size <= N; size++ {
+		root, _ := tree.Root(size)
+		for index := uint64(0); index < size; index++ {
+			proof, err := tree.InclusionProof(index, size)
+			if err != nil {
+				t.Fatalf("Size %d: index %d: failed to compute proof: %v", size, index, err)
+			}
+			if !VerifyInclusion(leaves[index], index, size, proof, root) {
+				t.Fatalf("Size %d: index %d: failed to verify proof", size, index)
+			}
+			if VerifyInclusion(leaves[(index+1)%N], index, size, proof, root) && size > 1 {
+				t.Fatalf("Size %d: index %d: verified proof for wrong leaf", size, index)
+			}
+		}
+	}
+	if _, err := tree.InclusionProof(5, 5); err == nil {
+		t.Fatal("Computed proof for index outside of tree")
+	}
+}
+
+// referenceRoot computes the Merkle tree hash as defined
+// by RFC 9162 2.1.1.
+func referenceRoot(leaves []Hash) Hash {
+	switch n := len(leaves); n {
+	case 0:
+		return sha256.Sum256(nil)
+	case 1:
+		return leaves[0]
+	default:
+		k := 1
+		for k*2 < n {
+			k *= 2
+		}
+		return nodeHash(referenceRoot(leaves[:k]), referenceRoot(leaves[k:]))
+	}
+}
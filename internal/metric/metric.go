@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"runtime"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes/internal/api"
@@ -20,6 +22,7 @@ import (
 // metrics about the application.
 func New() *Metrics {
 	requestStatusLabels := []string{"code"}
+	apiLabels := []string{"api", "identity", "policy"}
 
 	registry := prometheus.NewRegistry()
 	factory := promauto.With(registry)
@@ -57,6 +60,25 @@ func New() *Metrics {
 			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1.0, 1.5, 3.0, 5.0, 10.0}, // from 10ms to 10s
 			Help:      "Histogram of request response times spawning from 10ms to 10s.",
 		}),
+		apiRequests: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "api_requests",
+			Help:      "Number of requests per API and status code. Optionally, also per identity or policy.",
+		}, append(apiLabels, "code")),
+		apiLatency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "api_response_time",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1.0, 1.5, 3.0, 5.0, 10.0}, // from 10ms to 10s
+			Help:      "Histogram of request response times spawning from 10ms to 10s per API. Optionally, also per identity or policy.",
+		}, apiLabels),
+		requestRateLimited: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "http",
+			Name:      "request_rate_limited",
+			Help:      "Number of requests that have been rejected since they exceeded the rate limit of the policy. (HTTP 429 status code)",
+		}, []string{"policy"}),
 
 		errorLogEvents: factory.NewCounter(prometheus.CounterOpts{
 			Namespace: "kes",
@@ -71,6 +93,82 @@ func New() *Metrics {
 			Help:      "Number of audit log events written to the audit log targets.",
 		}),
 
+		keystoreOffline: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "keystore",
+			Name:      "offline",
+			Help:      "Indicates whether the keystore is unreachable and requests are served from the cache. (1 = offline)",
+		}),
+		keystoreFailover: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "keystore",
+			Name:      "failover",
+			Help:      "Indicates whether the server has failed over to the secondary keystore. (1 = failed over)",
+		}),
+		keystoreFailoverPending: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "keystore",
+			Name:      "failover_pending",
+			Help:      "Number of writes to the secondary keystore that have not been replayed to the primary keystore, yet.",
+		}),
+		keystoreLatency: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "kes",
+			Subsystem: "keystore",
+			Name:      "response_time",
+			Buckets:   []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0}, // from 1ms to 10s
+			Help:      "Histogram of keystore response times spawning from 1ms to 10s per backend and operation. Cached keys are not included.",
+		}, []string{"backend", "operation"}),
+		keystoreErrors: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "keystore",
+			Name:      "errors",
+			Help:      "Number of keystore operations that failed per backend and operation. Keys that do not exist or exist already are not counted.",
+		}, []string{"backend", "operation"}),
+
+		cacheHits: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "cache",
+			Name:      "hits",
+			Help:      "Number of key lookups served from the key cache.",
+		}),
+		cacheMisses: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "cache",
+			Name:      "misses",
+			Help:      "Number of key lookups not served from the key cache that had to fetch the key from the keystore.",
+		}),
+		cacheCoalesced: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "cache",
+			Name:      "coalesced",
+			Help:      "Number of key lookups not served from the key cache that waited for a concurrent fetch of the same key instead of fetching it again.",
+		}),
+		cacheEvictions: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "kes",
+			Subsystem: "cache",
+			Name:      "evictions",
+			Help:      "Number of keys evicted from the key cache per reason: capacity, unused, expired or offline.",
+		}, []string{"reason"}),
+		cacheEntries: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "cache",
+			Name:      "entries",
+			Help:      "Number of keys in the key cache, including pinned keys.",
+		}),
+
+		keyOperations: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "key",
+			Name:      "operations",
+			Help:      "Number of encrypt, decrypt and generate operations per key. Only present if key usage tracking is enabled.",
+		}, []string{"key", "operation"}),
+		keyLastUsed: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "kes",
+			Subsystem: "key",
+			Name:      "last_used",
+			Help:      "The time a key has been used last as Unix timestamp in seconds. Only present if key usage tracking is enabled.",
+		}, []string{"key"}),
+
 		startTime: time.Now(),
 		upTimeInSeconds: factory.NewGauge(prometheus.GaugeOpts{
 			Namespace: "kes",
@@ -132,9 +230,30 @@ type Metrics struct {
 	requestActive    prometheus.Gauge
 	requestLatency   prometheus.Histogram
 
+	apiRequests *prometheus.CounterVec
+	apiLatency  *prometheus.HistogramVec
+	labeler     atomic.Pointer[RequestLabeler]
+
+	requestRateLimited *prometheus.CounterVec
+
 	errorLogEvents prometheus.Counter
 	auditLogEvents prometheus.Counter
 
+	keystoreOffline         prometheus.Gauge
+	keystoreFailover        prometheus.Gauge
+	keystoreFailoverPending prometheus.Gauge
+	keystoreLatency         *prometheus.HistogramVec
+	keystoreErrors          *prometheus.CounterVec
+
+	cacheHits      prometheus.Counter
+	cacheMisses    prometheus.Counter
+	cacheCoalesced prometheus.Counter
+	cacheEvictions *prometheus.CounterVec
+	cacheEntries   prometheus.Gauge
+
+	keyOperations *prometheus.GaugeVec
+	keyLastUsed   *prometheus.GaugeVec
+
 	startTime       time.Time // Used to compute the up time as upTime = now - startTime
 	upTimeInSeconds prometheus.Gauge
 	numCPUs         prometheus.Gauge
@@ -149,6 +268,23 @@ type Metrics struct {
 // EncodeTo collects all outstanding metrics information
 // about the application and writes it to encoder.
 func (m *Metrics) EncodeTo(encoder expfmt.Encoder) error {
+	m.update()
+
+	metrics, err := m.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	for _, metric := range metrics {
+		if err := encoder.Encode(metric); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// update updates the system metrics, like the up time
+// and memory usage.
+func (m *Metrics) update() {
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
@@ -159,17 +295,139 @@ func (m *Metrics) EncodeTo(encoder expfmt.Encoder) error {
 	m.memHeapUsed.Set(float64(memStats.HeapAlloc))
 	m.memHeapObjects.Set(float64(memStats.HeapObjects))
 	m.memStackUsed.Set(float64(memStats.StackSys))
+}
 
-	metrics, err := m.gatherer.Gather()
+// RateLimited increments the number of requests rejected
+// by the rate limit of the given policy.
+func (m *Metrics) RateLimited(policy string) {
+	m.requestRateLimited.WithLabelValues(policy).Inc()
+}
+
+// SetKeyStoreOffline updates the keystore offline metric.
+func (m *Metrics) SetKeyStoreOffline(offline bool) {
+	if offline {
+		m.keystoreOffline.Set(1)
+	} else {
+		m.keystoreOffline.Set(0)
+	}
+}
+
+// SetKeyStoreFailover updates the keystore failover metrics.
+func (m *Metrics) SetKeyStoreFailover(failedOver bool, pending int) {
+	if failedOver {
+		m.keystoreFailover.Set(1)
+	} else {
+		m.keystoreFailover.Set(0)
+	}
+	m.keystoreFailoverPending.Set(float64(pending))
+}
+
+// ObserveKeyStore records the response time of a keystore
+// operation, like "Get" or "Create", of the given backend and
+// whether the operation failed.
+func (m *Metrics) ObserveKeyStore(backend, operation string, latency time.Duration, failed bool) {
+	m.keystoreLatency.WithLabelValues(backend, operation).Observe(latency.Seconds())
+	if failed {
+		m.keystoreErrors.WithLabelValues(backend, operation).Inc()
+	}
+}
+
+// CacheHit increments the number of key lookups
+// served from the key cache.
+func (m *Metrics) CacheHit() { m.cacheHits.Inc() }
+
+// CacheMiss increments the number of key lookups
+// that had to fetch the key from the keystore.
+func (m *Metrics) CacheMiss() { m.cacheMisses.Inc() }
+
+// CacheCoalesced increments the number of key lookups
+// that waited for a concurrent fetch of the same key.
+func (m *Metrics) CacheCoalesced() { m.cacheCoalesced.Inc() }
+
+// CacheEvicted adds n to the number of keys evicted
+// from the key cache for the given reason.
+func (m *Metrics) CacheEvicted(reason string, n int) {
+	if n > 0 {
+		m.cacheEvictions.WithLabelValues(reason).Add(float64(n))
+	}
+}
+
+// SetCacheEntries updates the number of keys in the key cache.
+func (m *Metrics) SetCacheEntries(n int) { m.cacheEntries.Set(float64(n)) }
+
+// SetKeyUsage replaces the per-key usage metrics with the
+// given usage statistics. Keys not present in usage are
+// removed from the metrics.
+func (m *Metrics) SetKeyUsage(usage map[string]api.KeyUsage) {
+	m.keyOperations.Reset()
+	m.keyLastUsed.Reset()
+	for name, u := range usage {
+		m.keyOperations.WithLabelValues(name, "encrypt").Set(float64(u.Encrypt))
+		m.keyOperations.WithLabelValues(name, "decrypt").Set(float64(u.Decrypt))
+		m.keyOperations.WithLabelValues(name, "generate").Set(float64(u.Generate))
+		if !u.LastUsed.IsZero() {
+			m.keyLastUsed.WithLabelValues(name).Set(float64(u.LastUsed.Unix()))
+		}
+	}
+}
+
+// RequestCounts returns the total number of requests that
+// succeeded, failed with an error (HTTP 4xx) and failed due
+// to an internal failure (HTTP 5xx).
+func (m *Metrics) RequestCounts() (succeeded, errored, failed uint64) {
+	families, err := m.gatherer.Gather()
 	if err != nil {
-		return err
+		return 0, 0, 0
 	}
-	for _, metric := range metrics {
-		if err := encoder.Encode(metric); err != nil {
-			return err
+	for _, family := range families {
+		var total float64
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+		switch family.GetName() {
+		case "kes_http_request_success":
+			succeeded = uint64(total)
+		case "kes_http_request_error":
+			errored = uint64(total)
+		case "kes_http_request_failure":
+			failed = uint64(total)
 		}
 	}
-	return nil
+	return succeeded, errored, failed
+}
+
+// RequestLabeler returns the identity and policy label values
+// of the per-API request metrics for a request. Empty values
+// are omitted.
+type RequestLabeler func(*api.Request) (identity, policy string)
+
+// SetRequestLabeler sets the RequestLabeler of the per-API
+// request metrics. If f is nil, requests are only labeled by
+// API and status code.
+//
+// Labeling requests by identity or policy increases the number
+// of time series. Hence, it should only be enabled if the number
+// of identities or policies is bounded.
+func (m *Metrics) SetRequestLabeler(f RequestLabeler) {
+	if f == nil {
+		m.labeler.Store(nil)
+	} else {
+		m.labeler.Store(&f)
+	}
+}
+
+// apiLabels returns the API, identity and policy label
+// values of the request.
+func (m *Metrics) apiLabels(req *api.Request) [3]string {
+	// The API is the route path, i.e. the request
+	// path without the resource, like a key name.
+	route := strings.TrimSuffix(req.URL.Path, req.Resource)
+
+	var identity, policy string
+	if f := m.labeler.Load(); f != nil {
+		identity, policy = (*f)(req)
+	}
+	return [3]string{route, identity, policy}
 }
 
 // Count returns a HandlerFunc that wraps h and counts the
@@ -189,6 +447,8 @@ func (m *Metrics) Count(h api.Handler) api.Handler {
 			succeeded:      m.requestSucceeded,
 			errored:        m.requestErrored,
 			failed:         m.requestFailed,
+			api:            m.apiRequests,
+			labels:         m.apiLabels(req),
 		}
 		defer rw.updateMetrics()
 		resp.ResponseWriter = rw
@@ -205,10 +465,12 @@ func (m *Metrics) Count(h api.Handler) api.Handler {
 // the application can handle.
 func (m *Metrics) Latency(h api.Handler) api.Handler {
 	return api.HandlerFunc(func(resp *api.Response, req *api.Request) {
+		labels := m.apiLabels(req)
 		rw := &latencyResponseWriter{
 			ResponseWriter: resp.ResponseWriter,
 			start:          time.Now(),
 			histogram:      m.requestLatency,
+			api:            m.apiLatency.WithLabelValues(labels[:]...),
 		}
 		defer rw.updateMetrics()
 		resp.ResponseWriter = rw
@@ -275,6 +537,7 @@ type latencyResponseWriter struct {
 
 	start     time.Time            // The point in time when the request was received
 	histogram prometheus.Histogram // The latency histogram
+	api       prometheus.Observer  // The per-API latency histogram
 }
 
 var (
@@ -284,7 +547,9 @@ var (
 
 // Updates metric request-response latency.
 func (w *latencyResponseWriter) updateMetrics() {
-	w.histogram.Observe(time.Since(w.start).Seconds())
+	latency := time.Since(w.start).Seconds()
+	w.histogram.Observe(latency)
+	w.api.Observe(latency)
 }
 
 // Flush sends any buffered data to the client.
@@ -309,6 +574,9 @@ type countResponseWriter struct {
 	errored   *prometheus.CounterVec
 	failed    *prometheus.CounterVec
 
+	api    *prometheus.CounterVec // Per-API request counter
+	labels [3]string              // API, identity and policy label values
+
 	// HTTP status code set by WriteHeader
 	status int
 }
@@ -342,6 +610,7 @@ func (w *countResponseWriter) updateMetrics() {
 		// metrics would be incomplete.
 		panic("metrics: unexpected response status code " + strconv.Itoa(w.status))
 	}
+	w.api.WithLabelValues(w.labels[0], w.labels[1], w.labels[2], strconv.Itoa(w.status)).Inc()
 }
 
 // Flush sends any buffered data to the client.
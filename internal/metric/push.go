@@ -0,0 +1,160 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/client_golang/prometheus/push"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// PushOptions specifies where and how metrics are pushed.
+type PushOptions struct {
+	// Client is the HTTP client used to push metrics.
+	Client *http.Client
+
+	// Endpoint is the Pushgateway base URL or the Prometheus
+	// remote write URL.
+	Endpoint string
+
+	// Job is the value of the 'job' label.
+	Job string
+
+	// Instance is the value of the 'instance' label.
+	// If empty, no 'instance' label is added.
+	Instance string
+
+	// Username and Password are used for HTTP basic
+	// authentication, if not empty.
+	Username string
+	Password string
+}
+
+// Push pushes all metrics to the Prometheus Pushgateway.
+// It replaces all metrics previously pushed with the same
+// job and instance.
+func (m *Metrics) Push(ctx context.Context, opts *PushOptions) error {
+	m.update()
+
+	pusher := push.New(opts.Endpoint, opts.Job).Gatherer(m.gatherer).Client(opts.Client)
+	if opts.Instance != "" {
+		pusher = pusher.Grouping("instance", opts.Instance)
+	}
+	if opts.Username != "" || opts.Password != "" {
+		pusher = pusher.BasicAuth(opts.Username, opts.Password)
+	}
+	return pusher.PushContext(ctx)
+}
+
+// RemoteWrite sends all metrics to the endpoint using the
+// Prometheus remote write protocol (version 1.0). Histograms
+// and summaries are sent as their classic series - e.g.
+// '<name>_bucket', '<name>_sum' and '<name>_count'.
+func (m *Metrics) RemoteWrite(ctx context.Context, opts *PushOptions) error {
+	m.update()
+
+	families, err := m.gatherer.Gather()
+	if err != nil {
+		return err
+	}
+	b, err := writeRequest(families, opts.Job, opts.Instance, time.Now()).Marshal()
+	if err != nil {
+		return err
+	}
+	body := snappy.Encode(nil, b)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if opts.Username != "" || opts.Password != "" {
+		req.SetBasicAuth(opts.Username, opts.Password)
+	}
+
+	resp, err := opts.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		if len(msg) > 0 {
+			return fmt.Errorf("remote write failed: %s: %s", resp.Status, bytes.TrimSpace(msg))
+		}
+		return fmt.Errorf("remote write failed: %s", resp.Status)
+	}
+	return nil
+}
+
+// writeRequest returns the remote write request containing
+// one time series per metric sample.
+func writeRequest(families []*dto.MetricFamily, job, instance string, now time.Time) *prompb.WriteRequest {
+	timestamp := now.UnixMilli()
+	req := &prompb.WriteRequest{}
+	appendSeries := func(name string, labels []*dto.LabelPair, value float64, extra ...prompb.Label) {
+		series := make([]prompb.Label, 0, len(labels)+len(extra)+3)
+		series = append(series, prompb.Label{Name: "__name__", Value: name}, prompb.Label{Name: "job", Value: job})
+		if instance != "" {
+			series = append(series, prompb.Label{Name: "instance", Value: instance})
+		}
+		for _, l := range labels {
+			series = append(series, prompb.Label{Name: l.GetName(), Value: l.GetValue()})
+		}
+		series = append(series, extra...)
+		slices.SortFunc(series, func(a, b prompb.Label) int { return strings.Compare(a.Name, b.Name) })
+
+		req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+			Labels:  series,
+			Samples: []prompb.Sample{{Value: value, Timestamp: timestamp}},
+		})
+	}
+
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+	for _, family := range families {
+		name := family.GetName()
+		for _, metric := range family.GetMetric() {
+			labels := metric.GetLabel()
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				appendSeries(name, labels, metric.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				appendSeries(name, labels, metric.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				appendSeries(name, labels, metric.GetUntyped().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				h := metric.GetHistogram()
+				for _, b := range h.GetBucket() {
+					appendSeries(name+"_bucket", labels, float64(b.GetCumulativeCount()), prompb.Label{Name: "le", Value: formatFloat(b.GetUpperBound())})
+				}
+				appendSeries(name+"_bucket", labels, float64(h.GetSampleCount()), prompb.Label{Name: "le", Value: "+Inf"})
+				appendSeries(name+"_sum", labels, h.GetSampleSum())
+				appendSeries(name+"_count", labels, float64(h.GetSampleCount()))
+			case dto.MetricType_SUMMARY:
+				s := metric.GetSummary()
+				for _, q := range s.GetQuantile() {
+					appendSeries(name, labels, q.GetValue(), prompb.Label{Name: "quantile", Value: formatFloat(q.GetQuantile())})
+				}
+				appendSeries(name+"_sum", labels, s.GetSampleSum())
+				appendSeries(name+"_count", labels, float64(s.GetSampleCount()))
+			}
+		}
+	}
+	return req
+}
@@ -0,0 +1,176 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package metric
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var remoteWriteTests = []struct {
+	Labels []prompb.Label
+	Value  float64
+}{
+	{ // 0
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_cache_hits"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+		},
+		Value: 3,
+	},
+	{ // 1
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_keystore_response_time_bucket"},
+			{Name: "backend", Value: "fs"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+			{Name: "le", Value: "0.01"},
+			{Name: "operation", Value: "Get"},
+		},
+		Value: 0,
+	},
+	{ // 2
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_keystore_response_time_bucket"},
+			{Name: "backend", Value: "fs"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+			{Name: "le", Value: "0.025"},
+			{Name: "operation", Value: "Get"},
+		},
+		Value: 1,
+	},
+	{ // 3
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_keystore_response_time_bucket"},
+			{Name: "backend", Value: "fs"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+			{Name: "le", Value: "+Inf"},
+			{Name: "operation", Value: "Get"},
+		},
+		Value: 1,
+	},
+	{ // 4
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_keystore_response_time_sum"},
+			{Name: "backend", Value: "fs"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+			{Name: "operation", Value: "Get"},
+		},
+		Value: 0.02,
+	},
+	{ // 5
+		Labels: []prompb.Label{
+			{Name: "__name__", Value: "kes_keystore_response_time_count"},
+			{Name: "backend", Value: "fs"},
+			{Name: "instance", Value: "kes-1"},
+			{Name: "job", Value: "kes"},
+			{Name: "operation", Value: "Get"},
+		},
+		Value: 1,
+	},
+}
+
+func TestRemoteWrite(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies <- body
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+
+	m := New()
+	for i := 0; i < 3; i++ {
+		m.CacheHit()
+	}
+	m.ObserveKeyStore("fs", "Get", 20*time.Millisecond, false)
+
+	start := time.Now()
+	err := m.RemoteWrite(context.Background(), &PushOptions{
+		Client:   target.Client(),
+		Endpoint: target.URL,
+		Job:      "kes",
+		Instance: "kes-1",
+	})
+	if err != nil {
+		t.Fatalf("Failed to send metrics: %v", err)
+	}
+	end := time.Now()
+
+	b, err := snappy.Decode(nil, <-bodies)
+	if err != nil {
+		t.Fatalf("Failed to decode snappy payload: %v", err)
+	}
+	var req prompb.WriteRequest
+	if err = req.Unmarshal(b); err != nil {
+		t.Fatalf("Failed to decode write request: %v", err)
+	}
+
+	series := map[string]prompb.TimeSeries{}
+	for _, ts := range req.Timeseries {
+		if !slices.IsSortedFunc(ts.Labels, func(a, b prompb.Label) int { return strings.Compare(a.Name, b.Name) }) {
+			t.Fatalf("Labels are not sorted: %v", ts.Labels)
+		}
+		if len(ts.Samples) != 1 {
+			t.Fatalf("Invalid number of samples: got '%d' - want '%d'", len(ts.Samples), 1)
+		}
+		if sample := ts.Samples[0]; sample.Timestamp < start.UnixMilli() || sample.Timestamp > end.UnixMilli() {
+			t.Fatalf("Invalid sample timestamp: got '%d' - want between '%d' and '%d'", sample.Timestamp, start.UnixMilli(), end.UnixMilli())
+		}
+		series[labelString(ts.Labels)] = ts
+	}
+	for i, test := range remoteWriteTests {
+		ts, ok := series[labelString(test.Labels)]
+		if !ok {
+			t.Fatalf("Test %d: time series '%s' not found", i, labelString(test.Labels))
+		}
+		if v := ts.Samples[0].Value; v != test.Value {
+			t.Fatalf("Test %d: got value '%v' - want '%v'", i, v, test.Value)
+		}
+	}
+}
+
+func TestRemoteWriteWithoutInstance(t *testing.T) {
+	families, err := New().gatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := writeRequest(families, "kes", "", time.Now())
+	if len(req.Timeseries) == 0 {
+		t.Fatal("Write request contains no time series")
+	}
+	for _, ts := range req.Timeseries {
+		for _, l := range ts.Labels {
+			if l.Name == "instance" {
+				t.Fatalf("Time series '%s' has an instance label", labelString(ts.Labels))
+			}
+		}
+	}
+}
+
+func labelString(labels []prompb.Label) string {
+	var s strings.Builder
+	for _, l := range labels {
+		s.WriteString(l.Name + "=" + l.Value + ",")
+	}
+	return s.String()
+}
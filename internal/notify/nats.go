@@ -0,0 +1,241 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/sys"
+)
+
+// NATSConfig is a structure containing the NATS
+// notification target configuration.
+type NATSConfig struct {
+	// Addr is the address of the NATS server,
+	// e.g. "nats.example.com:4222".
+	Addr string
+
+	// Subject is the subject events are published to.
+	Subject string
+
+	// Username and Password are optional credentials
+	// used to authenticate to the NATS server.
+	Username string
+	Password string
+
+	// Token is an optional token used to authenticate
+	// to the NATS server.
+	Token string
+
+	// TLS is an optional TLS configuration. If set, the
+	// connection to the NATS server is secured by TLS.
+	TLS *tls.Config
+}
+
+// NATS is a notification target that publishes events
+// to a NATS subject.
+//
+// It implements the subset of the NATS client protocol
+// required for publishing messages. Each published message
+// is followed by a PING such that Publish returns once the
+// NATS server has processed the message.
+type NATS struct {
+	config NATSConfig
+
+	lock sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+var _ kes.NotificationTarget = (*NATS)(nil) // compiler check
+
+// NewNATS returns a new NATS target for the given config.
+// It connects to the NATS server lazily when publishing
+// the first event.
+func NewNATS(config *NATSConfig) (*NATS, error) {
+	if config.Addr == "" {
+		return nil, errors.New("notify: NATS server address is empty")
+	}
+	if config.Subject == "" || strings.ContainsAny(config.Subject, " \t\r\n") {
+		return nil, fmt.Errorf("notify: invalid NATS subject '%s'", config.Subject)
+	}
+	if config.Token != "" && (config.Username != "" || config.Password != "") {
+		return nil, errors.New("notify: NATS token and username/password are mutually exclusive")
+	}
+
+	n := &NATS{config: *config}
+	if config.TLS != nil {
+		n.config.TLS = config.TLS.Clone()
+	}
+	return n, nil
+}
+
+// Publish publishes the event as JSON to the NATS subject.
+// It (re-)connects to the NATS server if necessary.
+func (n *NATS) Publish(ctx context.Context, event kes.Event) error {
+	msg, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.conn == nil {
+		if err = n.connect(ctx); err != nil {
+			return err
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		n.conn.SetDeadline(deadline)
+	} else {
+		n.conn.SetDeadline(time.Time{})
+	}
+
+	if err = n.publish(msg); err != nil {
+		n.conn.Close()
+		n.conn, n.r = nil, nil
+		return err
+	}
+	return nil
+}
+
+// Close closes the connection to the NATS server, if any.
+func (n *NATS) Close() error {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.conn == nil {
+		return nil
+	}
+	err := n.conn.Close()
+	n.conn, n.r = nil, nil
+	return err
+}
+
+// connect connects to the NATS server and sends the
+// CONNECT message.
+func (n *NATS) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", n.config.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// The NATS server sends an INFO message first. The
+	// TLS handshake, if any, happens afterwards.
+	r := bufio.NewReader(conn)
+	line, err := readLine(r)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return fmt.Errorf("notify: unexpected NATS message '%s'", line)
+	}
+	if n.config.TLS != nil {
+		config := n.config.TLS.Clone()
+		if config.ServerName == "" {
+			config.ServerName, _, _ = net.SplitHostPort(n.config.Addr)
+		}
+		tlsConn := tls.Client(conn, config)
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn, r = tlsConn, bufio.NewReader(tlsConn)
+	}
+
+	info, _ := sys.ReadBinaryInfo()
+	connect, err := json.Marshal(struct {
+		Verbose   bool   `json:"verbose"`
+		Pedantic  bool   `json:"pedantic"`
+		TLS       bool   `json:"tls_required"`
+		Name      string `json:"name"`
+		Lang      string `json:"lang"`
+		Version   string `json:"version"`
+		User      string `json:"user,omitempty"`
+		Pass      string `json:"pass,omitempty"`
+		AuthToken string `json:"auth_token,omitempty"`
+	}{
+		TLS:       n.config.TLS != nil,
+		Name:      "kes",
+		Lang:      "go",
+		Version:   info.Version,
+		User:      n.config.Username,
+		Pass:      n.config.Password,
+		AuthToken: n.config.Token,
+	})
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if _, err = fmt.Fprintf(conn, "CONNECT %s\r\n", connect); err != nil {
+		conn.Close()
+		return err
+	}
+
+	n.conn, n.r = conn, r
+	return nil
+}
+
+// publish sends a PUB message followed by a PING and waits
+// for the corresponding PONG.
+func (n *NATS) publish(msg []byte) error {
+	if _, err := fmt.Fprintf(n.conn, "PUB %s %d\r\n%s\r\nPING\r\n", n.config.Subject, len(msg), msg); err != nil {
+		return err
+	}
+	for {
+		line, err := readLine(n.r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err = n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("notify: NATS error: %s", strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+		}
+	}
+}
+
+// readLine reads a single NATS protocol line without
+// the trailing CRLF.
+func readLine(r *bufio.Reader) (string, error) {
+	const MaxLen = 1 << 20
+
+	var line []byte
+	for {
+		b, isPrefix, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if len(line)+len(b) > MaxLen {
+			return "", errors.New("notify: NATS message is too large")
+		}
+		line = append(line, b...)
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package notify implements notification targets that publish
+// KES lifecycle events to webhooks, NATS servers and AWS SQS
+// queues.
+//
+// All targets publish events as JSON objects of the form:
+//
+//	{
+//	  "type":     "key.created",
+//	  "time":     "2023-10-24T08:05:10Z",
+//	  "name":     "my-key",
+//	  "identity": "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
+//	}
+package notify
+
+import (
+	"encoding/json"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/api"
+)
+
+// marshalEvent returns the JSON representation of the event.
+func marshalEvent(event kes.Event) ([]byte, error) {
+	return json.Marshal(api.LifecycleEvent{
+		Type:     string(event.Type),
+		Time:     event.Time,
+		Name:     event.Name,
+		Policy:   event.Policy,
+		Identity: event.Identity.String(),
+	})
+}
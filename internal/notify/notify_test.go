@@ -0,0 +1,175 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/api"
+)
+
+func TestWebhook(t *testing.T) {
+	const Token = "my-token"
+	event := kes.Event{
+		Type:     kes.EventKeyCreated,
+		Time:     time.Date(2023, 10, 24, 8, 5, 10, 0, time.UTC),
+		Name:     "my-key",
+		Identity: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+	}
+
+	received := make(chan api.LifecycleEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+Token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var e api.LifecycleEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- e
+	}))
+	defer srv.Close()
+
+	webhook, err := NewWebhook(&WebhookConfig{Endpoint: srv.URL, AuthToken: Token})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if err = webhook.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish event: %v", err)
+	}
+	if e := <-received; e.Type != string(event.Type) || e.Name != event.Name || e.Identity != event.Identity.String() || !e.Time.Equal(event.Time) {
+		t.Fatalf("Invalid event: got %+v - want %+v", e, event)
+	}
+
+	webhook, err = NewWebhook(&WebhookConfig{Endpoint: srv.URL})
+	if err != nil {
+		t.Fatalf("Failed to create webhook: %v", err)
+	}
+	if err = webhook.Publish(context.Background(), event); err == nil {
+		t.Fatal("Published event without authentication")
+	}
+
+	if _, err = NewWebhook(&WebhookConfig{Endpoint: "ftp://example.com"}); err == nil {
+		t.Fatal("Created webhook with invalid endpoint")
+	}
+}
+
+func TestNATS(t *testing.T) {
+	const (
+		Subject = "kes.events"
+		Token   = "my-token"
+	)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go serveNATS(ln, Token, received)
+
+	target, err := NewNATS(&NATSConfig{Addr: ln.Addr().String(), Subject: Subject, Token: Token})
+	if err != nil {
+		t.Fatalf("Failed to create NATS target: %v", err)
+	}
+	defer target.Close()
+
+	for _, name := range []string{"my-key", "my-key2"} {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = target.Publish(ctx, kes.Event{Type: kes.EventKeyDeleted, Time: time.Now(), Name: name})
+		cancel()
+		if err != nil {
+			t.Fatalf("Failed to publish event: %v", err)
+		}
+
+		msg := <-received
+		var e api.LifecycleEvent
+		if err = json.Unmarshal([]byte(msg), &e); err != nil {
+			t.Fatalf("Invalid message '%s': %v", msg, err)
+		}
+		if e.Type != string(kes.EventKeyDeleted) || e.Name != name {
+			t.Fatalf("Invalid event: got %+v", e)
+		}
+	}
+
+	target, err = NewNATS(&NATSConfig{Addr: ln.Addr().String(), Subject: Subject, Token: "invalid"})
+	if err != nil {
+		t.Fatalf("Failed to create NATS target: %v", err)
+	}
+	defer target.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = target.Publish(ctx, kes.Event{Type: kes.EventKeyDeleted, Name: "my-key"}); err == nil {
+		t.Fatal("Published event with invalid token")
+	}
+}
+
+// serveNATS is a minimal NATS server that accepts clients
+// sending the given token and forwards published messages
+// to received.
+func serveNATS(ln net.Listener, token string, received chan<- string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+			r := bufio.NewReader(conn)
+			authenticated := false
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				line = strings.TrimSuffix(line, "\r\n")
+				switch {
+				case strings.HasPrefix(line, "CONNECT "):
+					var connect struct {
+						AuthToken string `json:"auth_token"`
+					}
+					json.Unmarshal([]byte(strings.TrimPrefix(line, "CONNECT ")), &connect)
+					if connect.AuthToken != token {
+						fmt.Fprint(conn, "-ERR 'Authorization Violation'\r\n")
+						return
+					}
+					authenticated = true
+				case strings.HasPrefix(line, "PUB "):
+					var (
+						subject string
+						size    int
+					)
+					fmt.Sscanf(line, "PUB %s %d", &subject, &size)
+					msg := make([]byte, size+2)
+					if _, err = io.ReadFull(r, msg); err != nil {
+						return
+					}
+					if authenticated {
+						received <- string(msg[:size])
+					}
+				case line == "PING":
+					fmt.Fprint(conn, "PONG\r\n")
+				}
+			}
+		}(conn)
+	}
+}
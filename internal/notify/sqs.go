@@ -0,0 +1,83 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/minio/kes"
+)
+
+// SQSConfig is a structure containing the AWS SQS
+// notification target configuration.
+type SQSConfig struct {
+	// QueueURL is the URL of the SQS queue events
+	// are sent to.
+	QueueURL string
+
+	// Region is the AWS region of the queue.
+	Region string
+
+	// AccessKey, SecretKey and SessionToken are optional
+	// static AWS credentials. If all are empty, the AWS
+	// SDK fetches credentials from the environment, e.g.
+	// the EC2 instance metadata service.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// SQS is a notification target that sends events to
+// an AWS SQS queue.
+type SQS struct {
+	queueURL string
+	client   *sqs.SQS
+}
+
+var _ kes.NotificationTarget = (*SQS)(nil) // compiler check
+
+// NewSQS returns a new SQS target for the given config.
+func NewSQS(config *SQSConfig) (*SQS, error) {
+	if config.QueueURL == "" {
+		return nil, errors.New("notify: SQS queue URL is empty")
+	}
+
+	var creds *credentials.Credentials
+	if config.AccessKey != "" || config.SecretKey != "" || config.SessionToken != "" {
+		creds = credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, config.SessionToken)
+	}
+	session, err := session.NewSessionWithOptions(session.Options{
+		Config: aws.Config{
+			Region:      aws.String(config.Region),
+			Credentials: creds,
+		},
+		SharedConfigState: session.SharedConfigDisable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &SQS{
+		queueURL: config.QueueURL,
+		client:   sqs.New(session),
+	}, nil
+}
+
+// Publish sends the event as JSON message to the SQS queue.
+func (s *SQS) Publish(ctx context.Context, event kes.Event) error {
+	body, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.SendMessageWithContext(ctx, &sqs.SendMessageInput{
+		QueueUrl:    aws.String(s.queueURL),
+		MessageBody: aws.String(string(body)),
+	})
+	return err
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/headers"
+)
+
+// WebhookConfig is a structure containing the webhook
+// notification target configuration.
+type WebhookConfig struct {
+	// Endpoint is the HTTP(S) URL events are sent to
+	// using POST requests.
+	Endpoint string
+
+	// AuthToken is an optional bearer token sent in
+	// the Authorization header of each request.
+	AuthToken string
+
+	// TLS is an optional TLS configuration used for
+	// HTTPS endpoints.
+	TLS *tls.Config
+}
+
+// Webhook is a notification target that sends events
+// to an HTTP(S) endpoint.
+type Webhook struct {
+	config WebhookConfig
+	client http.Client
+}
+
+var _ kes.NotificationTarget = (*Webhook)(nil) // compiler check
+
+// NewWebhook returns a new Webhook for the given config.
+func NewWebhook(config *WebhookConfig) (*Webhook, error) {
+	endpoint, err := url.Parse(config.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, fmt.Errorf("notify: invalid webhook endpoint '%s'", config.Endpoint)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLS != nil {
+		transport.TLSClientConfig = config.TLS.Clone()
+	}
+	return &Webhook{
+		config: *config,
+		client: http.Client{Transport: transport},
+	}, nil
+}
+
+// Publish sends the event as JSON to the webhook endpoint.
+// It returns an error if the endpoint does not respond
+// with a 2xx status code.
+func (w *Webhook) Publish(ctx context.Context, event kes.Event) error {
+	body, err := marshalEvent(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+	if w.config.AuthToken != "" {
+		req.Header.Set(headers.Authorization, "Bearer "+w.config.AuthToken)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<20)) // Drain body such that the connection can be reused
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("notify: webhook responded with '" + resp.Status + "'")
+	}
+	return nil
+}
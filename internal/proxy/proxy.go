@@ -0,0 +1,396 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package proxy implements a caching reverse proxy for KES
+// servers.
+//
+// A Proxy terminates client mTLS connections and forwards
+// requests to a remote KES cluster. It attaches the client
+// certificate to each request such that the KES servers can
+// authenticate and authorize the actual client. Hence, the
+// proxy must be a TLS proxy of the KES servers.
+//
+// Responses to generate and decrypt requests may be cached,
+// such that repeated requests, e.g. by an edge MinIO site,
+// don't need a round trip to a remote region.
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/cache"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+)
+
+// Cacheable operations.
+const (
+	OpGenerate = "generate"
+	OpDecrypt  = "decrypt"
+)
+
+// maxBody is the max. size of request bodies that are cached.
+// Larger requests are forwarded without caching.
+const maxBody = 1 << 20
+
+// Rule enables caching of an operation for all keys
+// whose names match a pattern.
+type Rule struct {
+	// Op is the cached operation. Either OpGenerate
+	// or OpDecrypt.
+	Op string
+
+	// Pattern is a glob pattern, as defined by path.Match,
+	// that key names must match. The pattern "*" matches
+	// all keys.
+	Pattern string
+}
+
+// ParseRule parses a rule of the form <op>:<pattern>,
+// like "decrypt:minio-*".
+func ParseRule(s string) (Rule, error) {
+	op, pattern, ok := strings.Cut(s, ":")
+	if !ok {
+		return Rule{}, fmt.Errorf("proxy: invalid cache rule '%s': missing ':'", s)
+	}
+	rule := Rule{Op: op, Pattern: pattern}
+	if err := rule.validate(); err != nil {
+		return Rule{}, err
+	}
+	return rule, nil
+}
+
+func (r *Rule) validate() error {
+	if r.Op != OpGenerate && r.Op != OpDecrypt {
+		return fmt.Errorf("proxy: invalid cache rule: operation '%s' cannot be cached", r.Op)
+	}
+	if r.Pattern == "" {
+		return errors.New("proxy: invalid cache rule: pattern is empty")
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("proxy: invalid cache rule: invalid pattern '%s': %v", r.Pattern, err)
+	}
+	return nil
+}
+
+// Config is a structure containing the proxy configuration.
+type Config struct {
+	// Endpoints are the KES servers requests are forwarded to,
+	// like "https://kes.eu-west-1.example.com:7373". Requests
+	// are distributed round-robin.
+	Endpoints []string
+
+	// TLS is the client TLS configuration used to connect to
+	// the KES servers. It must contain the TLS certificate of
+	// the proxy.
+	TLS *tls.Config
+
+	// CertHeader is the HTTP header used to forward client
+	// certificates. If empty, defaults to "X-Tls-Client-Cert".
+	CertHeader string
+
+	// Rules controls which responses are cached. If empty,
+	// no responses are cached.
+	Rules []Rule
+
+	// TTL is the duration cached responses are served for.
+	// Once a cached response expires, the next request is
+	// forwarded to the KES servers again. Hence, any policy
+	// change becomes effective after TTL at the latest.
+	//
+	// If <= 0, defaults to 5 minutes.
+	TTL time.Duration
+
+	// MaxEntries is the max. number of cached responses.
+	// Once reached, the least recently used response is
+	// evicted. If <= 0, defaults to 10000.
+	MaxEntries int
+}
+
+// Stats contains cache statistics of a Proxy.
+type Stats struct {
+	Entries int    // Number of cached responses
+	Hits    uint64 // Requests served from the cache
+	Misses  uint64 // Cacheable requests forwarded to a KES server
+}
+
+// Proxy is an http.Handler that forwards requests to
+// KES servers and caches responses.
+type Proxy struct {
+	endpoints  []*url.URL
+	certHeader string
+	rules      []Rule
+	ttl        time.Duration
+
+	proxy  *httputil.ReverseProxy
+	cache  *cache.LRU[string, *entry]
+	next   atomic.Uint64
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// entry is a cached response.
+type entry struct {
+	Name      string // Name of the key
+	Body      []byte
+	ExpiresAt time.Time
+}
+
+// requestKey is the request context key of the cache
+// key of a forwarded request.
+type requestKey struct{}
+
+// cacheable is the request context value of forwarded
+// requests whose response should be cached.
+type cacheable struct {
+	Key  string
+	Name string
+}
+
+// New returns a new Proxy from the given config.
+func New(conf *Config) (*Proxy, error) {
+	if len(conf.Endpoints) == 0 {
+		return nil, errors.New("proxy: no KES server endpoint specified")
+	}
+	endpoints := make([]*url.URL, 0, len(conf.Endpoints))
+	for _, endpoint := range conf.Endpoints {
+		if !strings.HasPrefix(endpoint, "http://") && !strings.HasPrefix(endpoint, "https://") {
+			endpoint = "https://" + endpoint
+		}
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("proxy: invalid endpoint '%s': %v", endpoint, err)
+		}
+		endpoints = append(endpoints, u)
+	}
+	for i := range conf.Rules {
+		if err := conf.Rules[i].validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	p := &Proxy{
+		endpoints:  endpoints,
+		certHeader: conf.CertHeader,
+		rules:      append([]Rule(nil), conf.Rules...),
+		ttl:        conf.TTL,
+	}
+	if p.certHeader == "" {
+		p.certHeader = "X-Tls-Client-Cert"
+	}
+	if p.ttl <= 0 {
+		p.ttl = 5 * time.Minute
+	}
+	maxEntries := conf.MaxEntries
+	if maxEntries <= 0 {
+		maxEntries = 10000
+	}
+	p.cache = cache.NewLRU[string, *entry](maxEntries)
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = conf.TLS.Clone()
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(p.endpoints[p.next.Add(1)%uint64(len(p.endpoints))])
+			r.SetXForwarded()
+			r.Out.Host = r.Out.URL.Host
+		},
+		Transport:      transport,
+		FlushInterval:  -1, // Stream responses, like logs, immediately
+		ModifyResponse: p.modifyResponse,
+		ErrorHandler: func(w http.ResponseWriter, _ *http.Request, err error) {
+			api.Fail(&api.Response{ResponseWriter: w}, http.StatusBadGateway, err.Error())
+		},
+	}
+	return p, nil
+}
+
+// Stats returns the current cache statistics.
+func (p *Proxy) Stats() Stats {
+	return Stats{
+		Entries: p.cache.Len(),
+		Hits:    p.hits.Load(),
+		Misses:  p.misses.Load(),
+	}
+}
+
+// ServeHTTP forwards the request to a KES server or replies
+// with a cached response.
+//
+// The request must carry a TLS client certificate. Otherwise,
+// it is rejected since the KES servers could not authenticate
+// the client.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resp := &api.Response{ResponseWriter: w}
+
+	cert, identity, err := clientCertificate(r)
+	if err != nil {
+		api.Fail(resp, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Replace any forwarded certificate sent by the client itself.
+	r.Header.Set(p.certHeader, url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: cert.Raw,
+	}))))
+
+	// Deleting or rotating a key invalidates any cached response
+	// for it, such that the proxy doesn't hand out data keys of
+	// a deleted key. Other changes, like policy updates, become
+	// effective once cached responses expire.
+	for _, prefix := range []string{api.PathKeyDelete, api.PathKeyRotate} {
+		if name, ok := strings.CutPrefix(r.URL.Path, prefix); ok {
+			p.cache.DeleteFunc(func(_ string, e *entry) bool { return e.Name == name })
+		}
+	}
+
+	op, name, ok := p.match(r)
+	if !ok {
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil {
+		api.Fail(resp, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(body) > maxBody {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		p.proxy.ServeHTTP(w, r)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+
+	// Cached responses are bound to the client identity. Another
+	// client may not be allowed to use the key at all.
+	h := sha256.New()
+	for _, s := range []string{identity.String(), r.Header.Get(headers.Authorization), op, name} {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	key := hex.EncodeToString(h.Sum(nil))
+
+	if e, ok := p.cache.Get(key); ok {
+		if time.Now().Before(e.ExpiresAt) {
+			p.hits.Add(1)
+			w.Header().Set(headers.ContentType, headers.ContentTypeJSON)
+			w.Header().Set(headers.ContentLength, strconv.Itoa(len(e.Body)))
+			w.WriteHeader(http.StatusOK)
+			w.Write(e.Body)
+			return
+		}
+		p.cache.Delete(key)
+	}
+	p.misses.Add(1)
+
+	ctx := context.WithValue(r.Context(), requestKey{}, cacheable{Key: key, Name: name})
+	p.proxy.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// match returns the operation and key name of the request
+// and reports whether its response may be cached.
+func (p *Proxy) match(r *http.Request) (op, name string, ok bool) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		return "", "", false
+	}
+	switch {
+	case strings.HasPrefix(r.URL.Path, api.PathKeyGenerate):
+		op, name = OpGenerate, strings.TrimPrefix(r.URL.Path, api.PathKeyGenerate)
+	case strings.HasPrefix(r.URL.Path, api.PathKeyDecrypt):
+		op, name = OpDecrypt, strings.TrimPrefix(r.URL.Path, api.PathKeyDecrypt)
+	default:
+		return "", "", false
+	}
+	if name == "" {
+		return "", "", false
+	}
+	for _, rule := range p.rules {
+		if rule.Op != op {
+			continue
+		}
+		if ok, _ := path.Match(rule.Pattern, name); ok {
+			return op, name, true
+		}
+	}
+	return "", "", false
+}
+
+// modifyResponse caches successful responses of cacheable
+// requests.
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	c, ok := resp.Request.Context().Value(requestKey{}).(cacheable)
+	if !ok || resp.StatusCode != http.StatusOK {
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBody+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > maxBody {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	p.cache.Set(c.Key, &entry{
+		Name:      c.Name,
+		Body:      body,
+		ExpiresAt: time.Now().Add(p.ttl),
+	})
+	return nil
+}
+
+// clientCertificate returns the TLS client certificate of the
+// request and its KES identity. CA certificates are ignored.
+func clientCertificate(r *http.Request) (*x509.Certificate, kes.Identity, error) {
+	if r.TLS == nil {
+		return nil, "", errors.New("no client certificate is present")
+	}
+
+	var cert *x509.Certificate
+	for _, c := range r.TLS.PeerCertificates {
+		if c.IsCA {
+			continue
+		}
+		if cert != nil {
+			return nil, "", errors.New("too many client certificates are present")
+		}
+		cert = c
+	}
+	if cert == nil {
+		return nil, "", errors.New("no client certificate is present")
+	}
+
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return cert, kes.Identity(hex.EncodeToString(h[:])), nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minio/kms-go/kes"
+)
+
+func TestParseRule(t *testing.T) {
+	for i, test := range parseRuleTests {
+		rule, err := ParseRule(test.Rule)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing '%s' should have failed", i, test.Rule)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse '%s': %v", i, test.Rule, err)
+		}
+		if err == nil && rule != test.Want {
+			t.Fatalf("Test %d: got '%v' - want '%v'", i, rule, test.Want)
+		}
+	}
+}
+
+var parseRuleTests = []struct {
+	Rule       string
+	Want       Rule
+	ShouldFail bool
+}{
+	{Rule: "generate:*", Want: Rule{Op: OpGenerate, Pattern: "*"}},           // 0
+	{Rule: "decrypt:minio-*", Want: Rule{Op: OpDecrypt, Pattern: "minio-*"}}, // 1
+	{Rule: "decrypt", ShouldFail: true},                                      // 2
+	{Rule: "encrypt:*", ShouldFail: true},                                    // 3
+	{Rule: "decrypt:", ShouldFail: true},                                     // 4
+	{Rule: "decrypt:[", ShouldFail: true},                                    // 5
+}
+
+func TestProxy(t *testing.T) {
+	var requests atomic.Int64
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		cert, err := url.QueryUnescape(r.Header.Get("X-Tls-Client-Cert"))
+		if err != nil || !strings.HasPrefix(cert, "-----BEGIN CERTIFICATE-----") {
+			http.Error(w, "no client certificate is present", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Path == "/v1/key/generate/unknown-key" {
+			http.Error(w, "key does not exist", http.StatusNotFound)
+			return
+		}
+
+		var plaintext [32]byte
+		rand.Read(plaintext[:])
+		json.NewEncoder(w).Encode(map[string]string{"plaintext": hex.EncodeToString(plaintext[:])})
+	}))
+	defer upstream.Close()
+
+	p, err := New(&Config{
+		Endpoints: []string{upstream.URL},
+		TLS:       upstream.Client().Transport.(*http.Transport).TLSClientConfig,
+		Rules: []Rule{
+			{Op: OpGenerate, Pattern: "my-key"},
+			{Op: OpDecrypt, Pattern: "*"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create proxy: %v", err)
+	}
+
+	srv := httptest.NewUnstartedServer(p)
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	client := newClient(t, srv)
+	for i, test := range proxyTests {
+		resp := send(t, client, srv.URL+test.Path, test.Body)
+		if resp.StatusCode != test.Status {
+			t.Fatalf("Test %d: got status %d - want %d", i, resp.StatusCode, test.Status)
+		}
+		resp2 := send(t, client, srv.URL+test.Path, test.Body)
+		if cached := resp.Body == resp2.Body; cached != test.Cached {
+			t.Fatalf("Test %d: response cached: got '%v' - want '%v'", i, cached, test.Cached)
+		}
+	}
+
+	// Cached responses must not be served to other clients.
+	n := requests.Load()
+	send(t, newClient(t, srv), srv.URL+"/v1/key/decrypt/my-key", `{"ciphertext":"AA=="}`)
+	if requests.Load() != n+1 {
+		t.Fatal("Cached response has been served to another client")
+	}
+
+	// Deleting a key invalidates its cached responses.
+	if stats := p.Stats(); stats.Entries != 5 {
+		t.Fatalf("Invalid number of cache entries: got %d - want %d", stats.Entries, 5)
+	}
+	send(t, client, srv.URL+"/v1/key/delete/my-key", "")
+	if stats := p.Stats(); stats.Entries != 1 {
+		t.Fatalf("Invalid number of cache entries: got %d - want %d", stats.Entries, 1)
+	}
+
+	// Requests without client certificate are rejected.
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/status", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Request without client certificate: got status %d - want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+var proxyTests = []struct {
+	Path   string
+	Body   string
+	Status int
+	Cached bool
+}{
+	{Path: "/v1/status", Status: http.StatusOK},                                                             // 0
+	{Path: "/v1/key/generate/my-key", Body: `{}`, Status: http.StatusOK, Cached: true},                      // 1
+	{Path: "/v1/key/generate/my-key", Body: `{"context":"AA=="}`, Status: http.StatusOK, Cached: true},      // 2
+	{Path: "/v1/key/generate/my-key-2", Body: `{}`, Status: http.StatusOK},                                  // 3
+	{Path: "/v1/key/generate/unknown-key", Body: `{}`, Status: http.StatusNotFound, Cached: true},           // 4: Errors are not cached but identical
+	{Path: "/v1/key/decrypt/my-key", Body: `{"ciphertext":"AA=="}`, Status: http.StatusOK, Cached: true},    // 5
+	{Path: "/v1/key/decrypt/other-key", Body: `{"ciphertext":"AA=="}`, Status: http.StatusOK, Cached: true}, // 6
+	{Path: "/v1/key/encrypt/my-key", Body: `{"plaintext":"AA=="}`, Status: http.StatusOK},                   // 7
+}
+
+type response struct {
+	StatusCode int
+	Body       string
+}
+
+func send(t *testing.T, client *http.Client, url, body string) response {
+	req, err := http.NewRequest(http.MethodPut, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	return response{StatusCode: resp.StatusCode, Body: string(b)}
+}
+
+func newClient(t *testing.T, srv *httptest.Server) *http.Client {
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate API key: %v", err)
+	}
+	cert, err := kes.GenerateCertificate(key)
+	if err != nil {
+		t.Fatalf("Failed to generate client certificate: %v", err)
+	}
+
+	client := srv.Client()
+	transport := client.Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.Certificates = []tls.Certificate{cert}
+	client.Transport = transport
+	return client
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/crypto"
+)
+
+// CipherResult is the result of a cipher benchmark.
+type CipherResult struct {
+	Cipher   crypto.SecretKeyType // The benchmarked cipher
+	Ops      uint64               // Number of generated data keys
+	Duration time.Duration        // The actual benchmark duration
+}
+
+// Throughput returns the number of data keys generated
+// per second.
+func (r *CipherResult) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Ops) / r.Duration.Seconds()
+}
+
+// RunCipher benchmarks the given cipher for the given duration.
+// Like the generate API, it generates random data keys and encrypts
+// them with a key of the cipher. It uses one worker per usable CPU
+// such that the result reflects the max. throughput of the server,
+// excluding TLS and HTTP overhead.
+func RunCipher(ctx context.Context, cipher crypto.SecretKeyType, duration time.Duration) (*CipherResult, error) {
+	if duration <= 0 {
+		return nil, errors.New("selftest: duration must be positive")
+	}
+	key, err := crypto.GenerateSecretKey(cipher, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, duration)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		ops  atomic.Uint64
+		errs = make(chan error, 1)
+	)
+	start := time.Now()
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var (
+				dek [32]byte
+				n   uint64
+			)
+			defer func() { ops.Add(n) }()
+			for ctx.Err() == nil {
+				if _, err := rand.Read(dek[:]); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+				if _, err := key.Encrypt(dek[:], nil); err != nil {
+					select {
+					case errs <- err:
+					default:
+					}
+					cancel()
+					return
+				}
+				n++
+			}
+		}()
+	}
+	wg.Wait()
+
+	select {
+	case err = <-errs:
+		return nil, err
+	default:
+	}
+	return &CipherResult{
+		Cipher:   cipher,
+		Ops:      ops.Load(),
+		Duration: time.Since(start),
+	}, nil
+}
@@ -0,0 +1,252 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package selftest implements a soak test that drives
+// synthetic traffic against a keystore and measures the
+// sustained throughput and latency.
+//
+// It helps validating that a keystore can handle the
+// expected load before a KES server goes live. It also
+// benchmarks the ciphers used to generate data keys.
+package selftest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mrand "math/rand"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/crypto"
+	kesdk "github.com/minio/kms-go/kes"
+)
+
+// Config is a structure containing the soak test
+// configuration.
+type Config struct {
+	// Duration is how long synthetic traffic is sent
+	// to the keystore. It must be positive.
+	Duration time.Duration
+
+	// Concurrency is the number of concurrent clients.
+	// If <= 0, defaults to 8.
+	Concurrency int
+
+	// Keys is the number of keys created for the soak
+	// test and read by the clients. If <= 0, defaults
+	// to 16.
+	Keys int
+
+	// WriteRatio is the fraction of requests, between 0
+	// and 1, that create and delete a key instead of
+	// reading one. If 0, defaults to 0.1.
+	WriteRatio float64
+}
+
+// Result is the result of a soak test.
+type Result struct {
+	Duration time.Duration // The actual test duration
+	Reads    Stats         // Stats of the reads
+	Writes   Stats         // Stats of the writes, i.e. a create followed by a delete
+}
+
+// Throughput returns the number of successful reads and
+// writes per second.
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Reads.Ops-r.Reads.Errors+r.Writes.Ops-r.Writes.Errors) / r.Duration.Seconds()
+}
+
+// Stats contains the number of requests of one type and
+// their latency distribution.
+type Stats struct {
+	Ops    uint64 // Number of requests
+	Errors uint64 // Number of requests that failed
+
+	LatencyP50 time.Duration // Median latency
+	LatencyP99 time.Duration // 99th percentile latency
+	LatencyMax time.Duration // Max. latency
+}
+
+// Prefix is the name prefix of all keys created by a soak test.
+// Keys are deleted once the test completes. However, a crashed
+// test may leave keys with this prefix behind.
+const Prefix = "kes-selftest-"
+
+// Run runs a soak test against the keystore. First, it creates
+// a set of keys. Then it sends synthetic traffic, mostly reads,
+// for the configured duration and deletes all keys created by
+// the test.
+//
+// Run returns an error if it fails to create the set of keys.
+// Failed requests during the test itself are counted but do
+// not abort the test.
+func Run(ctx context.Context, store kes.KeyStore, config *Config) (*Result, error) {
+	if config.Duration <= 0 {
+		return nil, errors.New("selftest: duration must be positive")
+	}
+	if config.WriteRatio < 0 || config.WriteRatio > 1 {
+		return nil, fmt.Errorf("selftest: invalid write ratio '%v'", config.WriteRatio)
+	}
+	c := *config
+	if c.Concurrency <= 0 {
+		c.Concurrency = 8
+	}
+	if c.Keys <= 0 {
+		c.Keys = 16
+	}
+	if c.WriteRatio == 0 {
+		c.WriteRatio = 0.1
+	}
+
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	prefix := Prefix + hex.EncodeToString(id[:]) + "-"
+
+	value, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+	// names contains all keys that have to be deleted once the
+	// test completes. Deletes are retried a few times since the
+	// keystore may be unreliable.
+	names := make([]string, 0, c.Keys)
+	defer func() {
+		const Attempts = 3
+		ctx := context.WithoutCancel(ctx)
+		for _, name := range names {
+			for i := 0; i < Attempts; i++ {
+				if err := store.Delete(ctx, name); err == nil || errors.Is(err, kesdk.ErrKeyNotFound) {
+					break
+				}
+			}
+		}
+	}()
+	for i := 0; i < c.Keys; i++ {
+		name := prefix + strconv.Itoa(i)
+		names = append(names, name)
+		if err = store.Create(ctx, name, value); err != nil {
+			return nil, fmt.Errorf("selftest: failed to create key '%s': %v", name, err)
+		}
+	}
+	keys := names[:c.Keys:c.Keys]
+
+	ctx, cancel := context.WithTimeout(ctx, c.Duration)
+	defer cancel()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		reads   []time.Duration
+		writes  []time.Duration
+		rErrors uint64
+		wErrors uint64
+	)
+	start := time.Now()
+	for i := 0; i < c.Concurrency; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+
+			var (
+				readLatency, writeLatency []time.Duration
+				readErrors, writeErrors   uint64
+				leftover                  []string
+			)
+			random := mrand.New(mrand.NewSource(time.Now().UnixNano() + int64(worker)))
+			for n := 0; ctx.Err() == nil; n++ {
+				if random.Float64() < c.WriteRatio {
+					name := prefix + "w" + strconv.Itoa(worker) + "-" + strconv.Itoa(n)
+					t := time.Now()
+					err := store.Create(ctx, name, value)
+					if err == nil {
+						if err = store.Delete(ctx, name); err != nil {
+							leftover = append(leftover, name)
+						}
+					} else {
+						leftover = append(leftover, name) // The create may have succeeded
+					}
+					if ctx.Err() != nil {
+						break
+					}
+					writeLatency = append(writeLatency, time.Since(t))
+					if err != nil {
+						writeErrors++
+					}
+				} else {
+					t := time.Now()
+					_, err := store.Get(ctx, keys[random.Intn(len(keys))])
+					if ctx.Err() != nil {
+						break
+					}
+					readLatency = append(readLatency, time.Since(t))
+					if err != nil {
+						readErrors++
+					}
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			names = append(names, leftover...)
+			reads = append(reads, readLatency...)
+			writes = append(writes, writeLatency...)
+			rErrors += readErrors
+			wErrors += writeErrors
+		}(i)
+	}
+	wg.Wait()
+
+	return &Result{
+		Duration: time.Since(start),
+		Reads:    newStats(reads, rErrors),
+		Writes:   newStats(writes, wErrors),
+	}, nil
+}
+
+// newKey returns a new encoded key version such that keys
+// created by a soak test are valid KES keys.
+func newKey() ([]byte, error) {
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return crypto.EncodeKeyVersion(crypto.KeyVersion{
+		Key:       key,
+		HMACKey:   hmac,
+		CreatedAt: time.Now().UTC(),
+		Tags:      map[string]string{"kes-selftest": "true"},
+	})
+}
+
+// newStats computes the latency distribution of the given
+// latencies.
+func newStats(latencies []time.Duration, failed uint64) Stats {
+	stats := Stats{
+		Ops:    uint64(len(latencies)),
+		Errors: failed,
+	}
+	if len(latencies) == 0 {
+		return stats
+	}
+	slices.Sort(latencies)
+	stats.LatencyP50 = latencies[len(latencies)/2]
+	stats.LatencyP99 = latencies[len(latencies)*99/100]
+	stats.LatencyMax = latencies[len(latencies)-1]
+	return stats
+}
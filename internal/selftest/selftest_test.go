@@ -0,0 +1,76 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package selftest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/crypto"
+)
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	store := &kes.MemKeyStore{}
+
+	result, err := Run(ctx, store, &Config{
+		Duration:    100 * time.Millisecond,
+		Concurrency: 4,
+		WriteRatio:  0.5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to run selftest: %v", err)
+	}
+	if result.Reads.Ops == 0 || result.Writes.Ops == 0 {
+		t.Fatalf("No requests sent: %d reads, %d writes", result.Reads.Ops, result.Writes.Ops)
+	}
+	if result.Reads.Errors != 0 || result.Writes.Errors != 0 {
+		t.Fatalf("Requests failed: %d reads, %d writes", result.Reads.Errors, result.Writes.Errors)
+	}
+	if result.Reads.LatencyP50 > result.Reads.LatencyP99 || result.Reads.LatencyP99 > result.Reads.LatencyMax {
+		t.Fatalf("Invalid latency distribution: %+v", result.Reads)
+	}
+	if result.Throughput() <= 0 {
+		t.Fatalf("Invalid throughput: %v", result.Throughput())
+	}
+
+	names, _, err := store.List(ctx, Prefix, -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if len(names) != 0 {
+		t.Fatalf("Selftest did not delete all keys: %v", names)
+	}
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	ctx := context.Background()
+	for i, config := range []Config{
+		{Duration: 0},
+		{Duration: time.Second, WriteRatio: -0.5},
+		{Duration: time.Second, WriteRatio: 2},
+	} {
+		if _, err := Run(ctx, &kes.MemKeyStore{}, &config); err == nil {
+			t.Fatalf("Test %d: should have failed", i)
+		}
+	}
+}
+
+func TestRunCipher(t *testing.T) {
+	for _, cipher := range []crypto.SecretKeyType{crypto.AES256, crypto.ChaCha20} {
+		result, err := RunCipher(context.Background(), cipher, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Cipher '%v': failed to run benchmark: %v", cipher, err)
+		}
+		if result.Cipher != cipher || result.Ops == 0 || result.Throughput() <= 0 {
+			t.Fatalf("Cipher '%v': invalid result: %+v", cipher, result)
+		}
+	}
+	if _, err := RunCipher(context.Background(), crypto.AES256, 0); err == nil {
+		t.Fatal("Benchmark with zero duration should have failed")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package shamir implements Shamir's Secret Sharing over GF(2^8).
+//
+// A secret is split into n shares such that any k of them
+// reconstruct the secret while fewer than k shares reveal
+// nothing about it. Each share consists of one byte per secret
+// byte followed by the share's x-coordinate.
+package shamir
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// MaxShares is the max. number of shares a secret can be split into.
+const MaxShares = 255
+
+// Split splits the secret into n shares such that any k of
+// them reconstruct the secret. It returns an error if k is
+// less than 2 or greater than n, or if n is greater than
+// MaxShares.
+func Split(secret []byte, n, k int) ([][]byte, error) {
+	return split(rand.Reader, secret, n, k)
+}
+
+func split(random io.Reader, secret []byte, n, k int) ([][]byte, error) {
+	if len(secret) == 0 {
+		return nil, errors.New("shamir: secret is empty")
+	}
+	if k < 2 {
+		return nil, errors.New("shamir: threshold must be at least 2")
+	}
+	if k > n {
+		return nil, errors.New("shamir: threshold must not exceed the number of shares")
+	}
+	if n > MaxShares {
+		return nil, errors.New("shamir: too many shares")
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	// For every secret byte, a random polynomial of degree k-1
+	// with the secret byte as intercept is evaluated at the
+	// x-coordinates of all shares.
+	coefficients := make([]byte, k-1)
+	for i, b := range secret {
+		if _, err := io.ReadFull(random, coefficients); err != nil {
+			return nil, err
+		}
+		for _, share := range shares {
+			share[i] = evaluate(b, coefficients, share[len(secret)])
+		}
+	}
+	clear(coefficients)
+	return shares, nil
+}
+
+// Combine reconstructs the secret from the given shares. It
+// returns an error if there are less than two shares, the
+// shares differ in length or two shares have the same
+// x-coordinate.
+//
+// Combine cannot detect whether there are less shares than
+// required to reconstruct the secret or whether a share is
+// invalid. In such cases, it returns a wrong secret.
+func Combine(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("shamir: at least two shares are required")
+	}
+	size := len(shares[0])
+	if size < 2 {
+		return nil, errors.New("shamir: invalid share")
+	}
+
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, errors.New("shamir: shares differ in length")
+		}
+		x := share[size-1]
+		if x == 0 {
+			return nil, errors.New("shamir: invalid share")
+		}
+		for _, other := range xs[:i] {
+			if x == other {
+				return nil, errors.New("shamir: duplicate share")
+			}
+		}
+		xs[i] = x
+	}
+
+	// Lagrange interpolation at x = 0. Addition and
+	// subtraction are both XOR in GF(2^8).
+	secret := make([]byte, size-1)
+	for i := range secret {
+		var b byte
+		for j, share := range shares {
+			num, den := byte(1), byte(1)
+			for m, x := range xs {
+				if m == j {
+					continue
+				}
+				num = mul(num, x)
+				den = mul(den, xs[j]^x)
+			}
+			b ^= mul(share[i], div(num, den))
+		}
+		secret[i] = b
+	}
+	return secret, nil
+}
+
+// evaluate returns the value of the polynomial with the given
+// intercept and coefficients, of degree 1 to len(coefficients),
+// at x.
+func evaluate(intercept byte, coefficients []byte, x byte) byte {
+	var y byte
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		y = mul(y, x) ^ coefficients[i]
+	}
+	return mul(y, x) ^ intercept
+}
+
+// mul returns a * b in GF(2^8) with the AES reduction
+// polynomial x^8 + x^4 + x^3 + x + 1. It does not branch
+// on its inputs.
+func mul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		a = (a << 1) ^ (-(a >> 7) & 0x1b)
+		b >>= 1
+	}
+	return p
+}
+
+// div returns a / b in GF(2^8). b must not be 0.
+func div(a, b byte) byte {
+	// b^-1 = b^254 since b^255 = 1 for all b != 0.
+	inv := byte(1)
+	for i := 0; i < 254; i++ {
+		inv = mul(inv, b)
+	}
+	return mul(a, inv)
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package shamir
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	for i, test := range []struct {
+		N, K int
+	}{
+		{N: 2, K: 2},
+		{N: 3, K: 2},
+		{N: 5, K: 3},
+		{N: 10, K: 10},
+		{N: 255, K: 7},
+	} {
+		shares, err := Split(secret, test.N, test.K)
+		if err != nil {
+			t.Fatalf("Test %d: failed to split secret: %v", i, err)
+		}
+		if len(shares) != test.N {
+			t.Fatalf("Test %d: got %d shares - want %d", i, len(shares), test.N)
+		}
+
+		// Any k shares reconstruct the secret.
+		for j := 0; j+test.K <= test.N; j++ {
+			combined, err := Combine(shares[j : j+test.K])
+			if err != nil {
+				t.Fatalf("Test %d: failed to combine shares: %v", i, err)
+			}
+			if !bytes.Equal(combined, secret) {
+				t.Fatalf("Test %d: combined secret does not match", i)
+			}
+		}
+
+		// Fewer than k shares do not.
+		if test.K > 2 {
+			combined, err := Combine(shares[:test.K-1])
+			if err != nil {
+				t.Fatalf("Test %d: failed to combine shares: %v", i, err)
+			}
+			if bytes.Equal(combined, secret) {
+				t.Fatalf("Test %d: secret reconstructed from %d shares", i, test.K-1)
+			}
+		}
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	secret := []byte("secret")
+	for i, test := range []struct {
+		Secret []byte
+		N, K   int
+	}{
+		{Secret: nil, N: 3, K: 2},
+		{Secret: secret, N: 3, K: 1},
+		{Secret: secret, N: 2, K: 3},
+		{Secret: secret, N: 256, K: 2},
+	} {
+		if _, err := Split(test.Secret, test.N, test.K); err == nil {
+			t.Fatalf("Test %d: splitting should have failed", i)
+		}
+	}
+}
+
+func TestCombineInvalid(t *testing.T) {
+	shares, err := Split([]byte("secret"), 3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, test := range [][][]byte{
+		nil,
+		{shares[0]},
+		{shares[0], shares[0]},
+		{shares[0], shares[1][1:]},
+		{shares[0], append(bytes.Clone(shares[1][:len(shares[1])-1]), 0)},
+	} {
+		if _, err := Combine(test); err == nil {
+			t.Fatalf("Test %d: combining should have failed", i)
+		}
+	}
+}
+
+func TestMulDiv(t *testing.T) {
+	for a := 0; a < 256; a++ {
+		for b := 1; b < 256; b++ {
+			if got := div(mul(byte(a), byte(b)), byte(b)); got != byte(a) {
+				t.Fatalf("(%d * %d) / %d: got %d", a, b, b, got)
+			}
+		}
+	}
+	if mul(0x53, 0xca) != 0x01 { // Inverses in the AES field
+		t.Fatal("0x53 * 0xca != 0x01")
+	}
+}
@@ -5,6 +5,7 @@
 package sys
 
 import (
+	"debug/buildinfo"
 	"errors"
 	"runtime"
 	"runtime/debug"
@@ -23,7 +24,26 @@ type BinaryInfo struct {
 // ReadBinaryInfo returns the ReadBinaryInfo about this program.
 func ReadBinaryInfo() (BinaryInfo, error) { return readBinaryInfo() }
 
+// ReadFileBinaryInfo returns the BinaryInfo about the Go
+// binary at the given path. The binary may have been built
+// for a different OS or CPU architecture.
+func ReadFileBinaryInfo(filename string) (BinaryInfo, error) {
+	info, err := buildinfo.ReadFile(filename)
+	if err != nil {
+		return BinaryInfo{}, err
+	}
+	return parseBuildInfo(info), nil
+}
+
 var readBinaryInfo = sync.OnceValues[BinaryInfo, error](func() (BinaryInfo, error) {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return parseBuildInfo(&debug.BuildInfo{GoVersion: runtime.Version()}), errors.New("sys: binary does not contain build info")
+	}
+	return parseBuildInfo(info), nil
+})
+
+func parseBuildInfo(info *debug.BuildInfo) BinaryInfo {
 	const (
 		DefaultVersion  = "<unknown>"
 		DefaultCommitID = "<unknown>"
@@ -32,15 +52,10 @@ var readBinaryInfo = sync.OnceValues[BinaryInfo, error](func() (BinaryInfo, erro
 	binaryInfo := BinaryInfo{
 		Version:  DefaultVersion,
 		CommitID: DefaultCommitID,
-		Runtime:  runtime.Version(),
+		Runtime:  info.GoVersion,
 		Compiler: DefaultCompiler,
 	}
 
-	info, ok := debug.ReadBuildInfo()
-	if !ok {
-		return binaryInfo, errors.New("sys: binary does not contain build info")
-	}
-
 	const (
 		GitTimeKey     = "vcs.time"
 		GitRevisionKey = "vcs.revision"
@@ -56,5 +71,5 @@ var readBinaryInfo = sync.OnceValues[BinaryInfo, error](func() (BinaryInfo, erro
 			binaryInfo.Compiler = setting.Value
 		}
 	}
-	return binaryInfo, nil
-})
+	return binaryInfo
+}
@@ -0,0 +1,261 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package tpm seals secrets, like keystore master keys, to a
+// TPM 2.0 device. A sealed secret can only be unsealed by the
+// same TPM and only while the selected PCRs contain the values
+// they had when the secret was sealed. Hence, a copy of a sealed
+// secret is useless on any other machine or after the boot chain
+// of the machine has been modified.
+package tpm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// DefaultDevice is the default path of the TPM 2.0 device. It
+// refers to the Linux kernel TPM resource manager.
+const DefaultDevice = "/dev/tpmrm0"
+
+// srkTemplate is the template of the storage root key (SRK)
+// under which secrets are sealed. The TPM derives the same SRK
+// from its owner hierarchy seed whenever it gets created. Hence,
+// the SRK does not need to be persisted. It follows the TCG
+// provisioning guidance.
+var srkTemplate = tpm2.Public{
+	Type:       tpm2.AlgRSA,
+	NameAlg:    tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin | tpm2.FlagUserWithAuth | tpm2.FlagRestricted | tpm2.FlagDecrypt | tpm2.FlagNoDA,
+	RSAParameters: &tpm2.RSAParams{
+		Symmetric: &tpm2.SymScheme{
+			Alg:     tpm2.AlgAES,
+			KeyBits: 128,
+			Mode:    tpm2.AlgCFB,
+		},
+		KeyBits:    2048,
+		ModulusRaw: make([]byte, 256),
+	},
+}
+
+// SealedKey is a secret sealed to a TPM 2.0 device.
+type SealedKey struct {
+	// PCRs are the PCRs of the SHA-256 bank the
+	// secret is bound to.
+	PCRs []int
+
+	// Public is the public area of the sealed object.
+	Public []byte
+
+	// Private is the private area of the sealed object.
+	// It is encrypted by the TPM.
+	Private []byte
+}
+
+// Seal seals the secret to the TPM device at the given path.
+// The sealed secret can only be unsealed while the given PCRs
+// of the SHA-256 bank contain their current values.
+func Seal(device string, secret []byte, pcrs []int) (*SealedKey, error) {
+	if err := verifyPCRs(pcrs); err != nil {
+		return nil, err
+	}
+
+	rw, err := open(device)
+	if err != nil {
+		return nil, err
+	}
+	defer rw.Close()
+
+	return seal(rw, secret, pcrs)
+}
+
+func seal(rw io.ReadWriter, secret []byte, pcrs []int) (*SealedKey, error) {
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to create storage root key: %v", err)
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	policy, err := policyDigest(rw, pcrs)
+	if err != nil {
+		return nil, err
+	}
+	private, public, err := tpm2.Seal(rw, srk, "", "", policy, secret)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to seal secret: %v", err)
+	}
+	return &SealedKey{
+		PCRs:    slices.Clone(pcrs),
+		Public:  public,
+		Private: private,
+	}, nil
+}
+
+// Unseal unseals the sealed secret using the TPM device at
+// the given path. It returns an error if the secret has been
+// sealed by another TPM or if the values of the PCRs have
+// changed since the secret has been sealed.
+func (k *SealedKey) Unseal(device string) ([]byte, error) {
+	if err := verifyPCRs(k.PCRs); err != nil {
+		return nil, err
+	}
+
+	rw, err := open(device)
+	if err != nil {
+		return nil, err
+	}
+	defer rw.Close()
+
+	return k.unseal(rw)
+}
+
+func (k *SealedKey) unseal(rw io.ReadWriter) ([]byte, error) {
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to create storage root key: %v", err)
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	object, _, err := tpm2.Load(rw, srk, "", k.Public, k.Private)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to load sealed key: %v", err)
+	}
+	defer tpm2.FlushContext(rw, object)
+
+	session, err := startPolicySession(rw, tpm2.SessionPolicy, k.PCRs)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, session)
+
+	secret, err := tpm2.UnsealWithSession(rw, session, object, "")
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to unseal key: %v", err)
+	}
+	return secret, nil
+}
+
+// sealedKeyFile is the on-disk representation of a SealedKey.
+type sealedKeyFile struct {
+	Version string `json:"version"`
+	PCRs    []int  `json:"pcrs"`
+	Public  []byte `json:"public"`
+	Private []byte `json:"private"`
+}
+
+// MarshalJSON returns the JSON representation of k.
+func (k *SealedKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(sealedKeyFile{
+		Version: "v1",
+		PCRs:    k.PCRs,
+		Public:  k.Public,
+		Private: k.Private,
+	})
+}
+
+// UnmarshalJSON parses the JSON representation of a SealedKey.
+func (k *SealedKey) UnmarshalJSON(b []byte) error {
+	var v sealedKeyFile
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Version != "v1" {
+		return fmt.Errorf("tpm: invalid sealed key: unsupported version '%s'", v.Version)
+	}
+	if err := verifyPCRs(v.PCRs); err != nil {
+		return err
+	}
+	if len(v.Public) == 0 || len(v.Private) == 0 {
+		return errors.New("tpm: invalid sealed key: missing public or private area")
+	}
+
+	k.PCRs = v.PCRs
+	k.Public = v.Public
+	k.Private = v.Private
+	return nil
+}
+
+// ReadSealedKey reads and parses the sealed key file.
+func ReadSealedKey(filename string) (*SealedKey, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var key SealedKey
+	if err = json.Unmarshal(b, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// open opens the TPM device at the given path. If path
+// is empty, it opens the DefaultDevice.
+func open(path string) (io.ReadWriteCloser, error) {
+	if path == "" {
+		path = DefaultDevice
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to open device: %v", err)
+	}
+	return f, nil
+}
+
+// policyDigest returns the digest of a policy that binds
+// a sealed object to the current values of the given PCRs.
+func policyDigest(rw io.ReadWriter, pcrs []int) ([]byte, error) {
+	session, err := startPolicySession(rw, tpm2.SessionTrial, pcrs)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, session)
+
+	digest, err := tpm2.PolicyGetDigest(rw, session)
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to compute policy digest: %v", err)
+	}
+	return digest, nil
+}
+
+// startPolicySession starts a new policy session of the given
+// type that has been restricted to the current values of the
+// given PCRs.
+func startPolicySession(rw io.ReadWriter, typ tpm2.SessionType, pcrs []int) (tpmutil.Handle, error) {
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull, make([]byte, 16), nil, typ, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return tpm2.HandleNull, fmt.Errorf("tpm: failed to start session: %v", err)
+	}
+
+	// An empty PCR digest refers to the current PCR values.
+	if err = tpm2.PolicyPCR(rw, session, nil, tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs}); err != nil {
+		tpm2.FlushContext(rw, session)
+		return tpm2.HandleNull, fmt.Errorf("tpm: failed to bind session to PCRs: %v", err)
+	}
+	return session, nil
+}
+
+// verifyPCRs returns an error if pcrs contains no PCR, an
+// invalid PCR or the same PCR more than once.
+func verifyPCRs(pcrs []int) error {
+	if len(pcrs) == 0 {
+		return errors.New("tpm: no PCRs selected")
+	}
+	for i, pcr := range pcrs {
+		if pcr < 0 || pcr > 23 {
+			return fmt.Errorf("tpm: invalid PCR '%d': must be between 0 and 23", pcr)
+		}
+		if slices.Contains(pcrs[:i], pcr) {
+			return fmt.Errorf("tpm: PCR '%d' selected more than once", pcr)
+		}
+	}
+	return nil
+}
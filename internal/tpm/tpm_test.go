@@ -0,0 +1,81 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package tpm
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestSealedKeyJSON(t *testing.T) {
+	t.Parallel()
+
+	key := &SealedKey{
+		PCRs:    []int{0, 7},
+		Public:  []byte("public area"),
+		Private: []byte("private area"),
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal sealed key: %v", err)
+	}
+
+	var key2 SealedKey
+	if err = json.Unmarshal(b, &key2); err != nil {
+		t.Fatalf("Failed to unmarshal sealed key: %v", err)
+	}
+	if !slices.Equal(key.PCRs, key2.PCRs) {
+		t.Fatalf("PCRs don't match: got '%v' - want '%v'", key2.PCRs, key.PCRs)
+	}
+	if !bytes.Equal(key.Public, key2.Public) || !bytes.Equal(key.Private, key2.Private) {
+		t.Fatal("Public or private area doesn't match")
+	}
+}
+
+func TestSealedKeyUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range sealedKeyUnmarshalJSONTests {
+		var key SealedKey
+		err := json.Unmarshal([]byte(test.JSON), &key)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing invalid sealed key succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse sealed key: %v", i, err)
+		}
+	}
+}
+
+var sealedKeyUnmarshalJSONTests = []struct {
+	JSON       string
+	ShouldFail bool
+}{
+	{ // 0
+		JSON: `{"version":"v1","pcrs":[0,7],"public":"cHVibGlj","private":"cHJpdmF0ZQ=="}`,
+	},
+	{ // 1
+		JSON:       `{"version":"v2","pcrs":[0,7],"public":"cHVibGlj","private":"cHJpdmF0ZQ=="}`,
+		ShouldFail: true,
+	},
+	{ // 2
+		JSON:       `{"version":"v1","pcrs":[],"public":"cHVibGlj","private":"cHJpdmF0ZQ=="}`,
+		ShouldFail: true,
+	},
+	{ // 3
+		JSON:       `{"version":"v1","pcrs":[0,24],"public":"cHVibGlj","private":"cHJpdmF0ZQ=="}`,
+		ShouldFail: true,
+	},
+	{ // 4
+		JSON:       `{"version":"v1","pcrs":[7,7],"public":"cHVibGlj","private":"cHJpdmF0ZQ=="}`,
+		ShouldFail: true,
+	},
+	{ // 5
+		JSON:       `{"version":"v1","pcrs":[7],"public":"cHVibGlj"}`,
+		ShouldFail: true,
+	},
+}
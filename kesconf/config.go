@@ -5,15 +5,23 @@
 package kesconf
 
 import (
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
 	"strconv"
 	"strings"
 	"time"
 
+	"aead.dev/mem"
+	"github.com/minio/kes/internal/logsink"
 	"github.com/minio/kms-go/kes"
 	"gopkg.in/yaml.v3"
 )
@@ -27,12 +35,47 @@ type ymlFile struct {
 		Identity env[kes.Identity] `yaml:"identity"`
 	} `yaml:"admin"`
 
+	FIPS env[bool] `yaml:"fips"`
+
+	Memory struct {
+		Lock    env[string] `yaml:"lock"`
+		Zeroize env[bool]   `yaml:"zeroize"`
+	} `yaml:"memory"`
+
 	TLS struct {
-		PrivateKey  env[string] `yaml:"key"`
-		Certificate env[string] `yaml:"cert"`
-		CAPath      env[string] `yaml:"ca"`
-		Password    env[string] `yaml:"password"`
-		ClientAuth  env[string] `yaml:"auth"`
+		PrivateKey  env[string]        `yaml:"key"`
+		Certificate env[string]        `yaml:"cert"`
+		CAPath      env[string]        `yaml:"ca"`
+		Password    env[string]        `yaml:"password"`
+		ClientAuth  env[string]        `yaml:"auth"`
+		Reload      env[time.Duration] `yaml:"reload"`
+
+		Revocation *struct {
+			CRL        env[string]        `yaml:"crl"`
+			Reload     env[time.Duration] `yaml:"reload"`
+			OCSP       env[bool]          `yaml:"ocsp"`
+			OCSPStrict env[bool]          `yaml:"ocsp_strict"`
+		} `yaml:"revocation"`
+
+		ACME *struct {
+			Directory env[string]   `yaml:"directory"`
+			Email     env[string]   `yaml:"email"`
+			Domains   []env[string] `yaml:"domains"`
+			Challenge env[string]   `yaml:"challenge"`
+			HTTP      struct {
+				Addr env[string] `yaml:"addr"`
+			} `yaml:"http"`
+			DNS struct {
+				Exec    env[string] `yaml:"exec"`
+				Webhook env[string] `yaml:"webhook"`
+			} `yaml:"dns"`
+			RenewBefore env[time.Duration] `yaml:"renew_before"`
+		} `yaml:"acme"`
+
+		SPIFFE *struct {
+			TrustDomains []env[string] `yaml:"trust_domains"`
+			WorkloadAPI  env[string]   `yaml:"workload_api"`
+		} `yaml:"spiffe"`
 
 		Proxy struct {
 			Identities []env[kes.Identity] `yaml:"identities"`
@@ -42,20 +85,193 @@ type ymlFile struct {
 		} `yaml:"proxy"`
 	} `yaml:"tls"`
 
+	ProxyProtocol struct {
+		Enabled env[bool]     `yaml:"enabled"`
+		Trusted []env[string] `yaml:"trusted"`
+	} `yaml:"proxy_protocol"`
+
+	HTTP struct {
+		ReadHeaderTimeout    env[time.Duration] `yaml:"read_header_timeout"`
+		ReadTimeout          env[time.Duration] `yaml:"read_timeout"`
+		WriteTimeout         env[time.Duration] `yaml:"write_timeout"`
+		IdleTimeout          env[time.Duration] `yaml:"idle_timeout"`
+		ShutdownTimeout      env[time.Duration] `yaml:"shutdown_timeout"`
+		MaxConcurrentStreams env[uint32]        `yaml:"max_concurrent_streams"`
+		HTTP2                *env[bool]         `yaml:"http2"`
+	} `yaml:"http"`
+
+	GRPC struct {
+		Addr env[string] `yaml:"address"`
+	} `yaml:"grpc"`
+
 	Policies map[string]struct {
-		Allow      []string            `yaml:"allow"`
-		Deny       []string            `yaml:"deny"`
-		Identities []env[kes.Identity] `yaml:"identities"`
+		Allow      []string               `yaml:"allow"`
+		Deny       []string               `yaml:"deny"`
+		Keys       []ymlKeyRule           `yaml:"keys"`
+		Conditions *ymlPolicyConditions   `yaml:"conditions"`
+		RateLimit  *ymlRateLimit          `yaml:"rate_limit"`
+		Context    map[string]env[string] `yaml:"context"`
+		SSH        *ymlSSHPolicy          `yaml:"ssh"`
+		Cert       *ymlCertPolicy         `yaml:"cert"`
+		Identities []env[kes.Identity]    `yaml:"identities"`
 	} `yaml:"policy"`
 
+	PolicyStore struct {
+		File env[string] `yaml:"file"`
+	} `yaml:"policy_store"`
+
+	OIDC *struct {
+		Issuer        env[string]                  `yaml:"issuer"`
+		Audience      env[string]                  `yaml:"audience"`
+		IdentityClaim env[string]                  `yaml:"identity_claim"`
+		Policies      map[string]map[string]string `yaml:"policy"`
+	} `yaml:"oidc"`
+
+	Seal *struct {
+		Threshold   env[int]    `yaml:"threshold"`
+		RootKeyHash env[string] `yaml:"root_key_hash"`
+	} `yaml:"seal"`
+
 	Cache struct {
 		Expiry struct {
 			Any     env[time.Duration] `yaml:"any"`
 			Unused  env[time.Duration] `yaml:"unused"`
 			Offline env[time.Duration] `yaml:"offline"`
 		} `yaml:"expiry"`
+		Prewarm struct {
+			Enabled env[bool]   `yaml:"enabled"`
+			Prefix  env[string] `yaml:"prefix"`
+		} `yaml:"prewarm"`
+		Preload struct {
+			Keys []env[string] `yaml:"keys"`
+			File env[string]   `yaml:"file"`
+		} `yaml:"preload"`
+		OfflinePolicy env[string]   `yaml:"offline_policy"`
+		Policy        env[string]   `yaml:"policy"`
+		MaxEntries    env[int]      `yaml:"max_entries"`
+		Eviction      env[string]   `yaml:"eviction"`
+		Pin           []env[string] `yaml:"pin"`
+		WriteBack     struct {
+			Journal  env[string]        `yaml:"journal"`
+			Interval env[time.Duration] `yaml:"interval"`
+		} `yaml:"write_back"`
 	} `yaml:"cache"`
 
+	Names struct {
+		MaxLength env[int]    `yaml:"max_length"`
+		Charset   env[string] `yaml:"charset"`
+	} `yaml:"names"`
+
+	Telemetry struct {
+		Endpoint env[string]        `yaml:"endpoint"`
+		Interval env[time.Duration] `yaml:"interval"`
+	} `yaml:"telemetry"`
+
+	KeyUsage struct {
+		Enabled  env[bool]          `yaml:"enabled"`
+		File     env[string]        `yaml:"file"`
+		Interval env[time.Duration] `yaml:"interval"`
+	} `yaml:"key_usage"`
+
+	SoftDelete struct {
+		Enabled        env[bool]          `yaml:"enabled"`
+		RecoveryWindow env[time.Duration] `yaml:"recovery_window"`
+	} `yaml:"soft_delete"`
+
+	Approval struct {
+		Enabled env[bool]          `yaml:"enabled"`
+		Window  env[time.Duration] `yaml:"window"`
+	} `yaml:"approval"`
+
+	Notify struct {
+		Webhook struct {
+			Endpoint  env[string] `yaml:"endpoint"`
+			AuthToken env[string] `yaml:"auth_token"`
+			CAPath    env[string] `yaml:"ca"`
+		} `yaml:"webhook"`
+		NATS struct {
+			Addr     env[string] `yaml:"address"`
+			Subject  env[string] `yaml:"subject"`
+			Username env[string] `yaml:"username"`
+			Password env[string] `yaml:"password"`
+			Token    env[string] `yaml:"token"`
+			TLS      env[bool]   `yaml:"tls"`
+			CAPath   env[string] `yaml:"ca"`
+		} `yaml:"nats"`
+		SQS struct {
+			QueueURL    env[string] `yaml:"queue_url"`
+			Region      env[string] `yaml:"region"`
+			Credentials struct {
+				AccessKey    env[string] `yaml:"access_key"`
+				SecretKey    env[string] `yaml:"secret_key"`
+				SessionToken env[string] `yaml:"session_token"`
+			} `yaml:"credentials"`
+		} `yaml:"sqs"`
+	} `yaml:"notify"`
+
+	Replication struct {
+		Endpoint env[string]        `yaml:"endpoint"`
+		KEK      env[string]        `yaml:"kek"`
+		Interval env[time.Duration] `yaml:"interval"`
+		TLS      struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"replication"`
+
+	Cluster struct {
+		Addr       env[string]         `yaml:"address"`
+		Path       env[string]         `yaml:"path"`
+		Peers      []env[string]       `yaml:"peers"`
+		Join       env[string]         `yaml:"join"`
+		Identities []env[kes.Identity] `yaml:"identities"`
+		Key        env[string]         `yaml:"key"`
+		TLS        struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"cluster"`
+
+	Replica struct {
+		Endpoint   env[string]         `yaml:"endpoint"`
+		Interval   env[time.Duration]  `yaml:"interval"`
+		Identities []env[kes.Identity] `yaml:"identities"`
+		TLS        struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"replica"`
+
+	Otel struct {
+		Endpoint      env[string]  `yaml:"endpoint"`
+		ServiceName   env[string]  `yaml:"service_name"`
+		SamplingRatio env[float64] `yaml:"sampling_ratio"`
+	} `yaml:"otel"`
+
+	Metrics struct {
+		Label    env[string] `yaml:"label"`
+		Listener struct {
+			Addr env[string] `yaml:"address"`
+			TLS  env[bool]   `yaml:"tls"`
+		} `yaml:"listener"`
+		Push struct {
+			Endpoint env[string]        `yaml:"endpoint"`
+			Protocol env[string]        `yaml:"protocol"`
+			Job      env[string]        `yaml:"job"`
+			Interval env[time.Duration] `yaml:"interval"`
+			Username env[string]        `yaml:"username"`
+			Password env[string]        `yaml:"password"`
+			TLS      struct {
+				PrivateKey  env[string] `yaml:"key"`
+				Certificate env[string] `yaml:"cert"`
+				CAPath      env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"push"`
+	} `yaml:"metrics"`
+
 	API struct {
 		Paths map[string]struct {
 			InsecureSkipAuth env[bool]          `yaml:"skip_auth"`
@@ -64,151 +280,491 @@ type ymlFile struct {
 	} `yaml:"api"`
 
 	Log struct {
-		Error env[string] `yaml:"error"`
-		Audit env[string] `yaml:"audit"`
+		Error         env[string]        `yaml:"error"`
+		Audit         env[string]        `yaml:"audit"`
+		Checkpoint    env[time.Duration] `yaml:"checkpoint"`
+		CheckpointKey env[string]        `yaml:"checkpoint_key"`
+		MerkleTree    env[bool]          `yaml:"merkle_tree"`
+		Store         struct {
+			Path      env[string]        `yaml:"path"`
+			Retention env[time.Duration] `yaml:"retention"`
+		} `yaml:"store"`
+		File struct {
+			Error      env[string]        `yaml:"error"`
+			Audit      env[string]        `yaml:"audit"`
+			MaxSize    env[string]        `yaml:"max_size"`
+			MaxAge     env[time.Duration] `yaml:"max_age"`
+			MaxBackups env[int]           `yaml:"max_backups"`
+		} `yaml:"file"`
+		Syslog struct {
+			Network  env[string] `yaml:"network"`
+			Addr     env[string] `yaml:"address"`
+			CAPath   env[string] `yaml:"ca"`
+			Facility env[string] `yaml:"facility"`
+			AppName  env[string] `yaml:"app_name"`
+			Error    env[bool]   `yaml:"error"`
+			Audit    env[bool]   `yaml:"audit"`
+		} `yaml:"syslog"`
+		Webhook struct {
+			Endpoint   env[string]        `yaml:"endpoint"`
+			AuthHeader env[string]        `yaml:"auth_header"`
+			Auth       env[string]        `yaml:"auth"`
+			Format     env[string]        `yaml:"format"`
+			CAPath     env[string]        `yaml:"ca"`
+			Batch      env[int]           `yaml:"batch"`
+			Interval   env[time.Duration] `yaml:"interval"`
+			Retries    env[int]           `yaml:"retries"`
+			Error      env[bool]          `yaml:"error"`
+			Audit      env[bool]          `yaml:"audit"`
+		} `yaml:"webhook"`
+		Kafka struct {
+			Brokers []env[string] `yaml:"brokers"`
+			Topic   env[string]   `yaml:"topic"`
+			TLS     env[bool]     `yaml:"tls"`
+			CAPath  env[string]   `yaml:"ca"`
+			SASL    struct {
+				Mechanism env[string] `yaml:"mechanism"`
+				Username  env[string] `yaml:"username"`
+				Password  env[string] `yaml:"password"`
+			} `yaml:"sasl"`
+			Buffer env[int]           `yaml:"buffer"`
+			Block  env[time.Duration] `yaml:"block"`
+		} `yaml:"kafka"`
 	} `yaml:"log"`
 
 	Keys []struct {
 		Name env[string] `yaml:"name"`
 	} `yaml:"keys"`
 
-	KeyStore struct {
-		FS *struct {
-			Path env[string] `yaml:"path"`
-		}
-		KES *struct {
-			Endpoint []env[string] `yaml:"endpoint"`
-			Enclave  env[string]   `yaml:"enclave"`
+	KeyStore ymlKeyStore `yaml:"keystore"`
+}
+
+// ymlKeyRule is a key rule of a policy within a YAML
+// config file.
+type ymlKeyRule struct {
+	Pattern    env[string]   `yaml:"pattern"`
+	Operations []env[string] `yaml:"operations"`
+}
+
+// ymlPolicyConditions is the conditions section of a policy
+// within a YAML config file.
+type ymlPolicyConditions struct {
+	SourceIP []env[string] `yaml:"source_ip"`
+	Time     []struct {
+		Days     []env[string] `yaml:"days"`
+		Start    env[string]   `yaml:"start"`
+		End      env[string]   `yaml:"end"`
+		Timezone env[string]   `yaml:"timezone"`
+	} `yaml:"time"`
+	TLSSAN []env[string] `yaml:"tls_san"`
+}
+
+// ymlRateLimit is the rate limit section of a policy
+// within a YAML config file.
+type ymlRateLimit struct {
+	Rate  env[float64] `yaml:"rate"`
+	Burst env[int]     `yaml:"burst"`
+	Per   env[string]  `yaml:"per"`
+}
+
+// ymlSSHPolicy is the SSH section of a policy within
+// a YAML config file.
+type ymlSSHPolicy struct {
+	Principals []env[string]      `yaml:"principals"`
+	MaxTTL     env[time.Duration] `yaml:"max_ttl"`
+	Host       env[bool]          `yaml:"host"`
+}
+
+// ymlCertPolicy is the cert section of a policy within
+// a YAML config file.
+type ymlCertPolicy struct {
+	Names  []env[string]      `yaml:"names"`
+	IPs    []env[string]      `yaml:"ips"`
+	MaxTTL env[time.Duration] `yaml:"max_ttl"`
+	Server env[bool]          `yaml:"server"`
+}
+
+// ymlHTTPClient is the HTTP client section of a keystore
+// within a YAML config file.
+type ymlHTTPClient struct {
+	MaxIdleConns        env[int]           `yaml:"max_idle_conns"`
+	MaxIdleConnsPerHost env[int]           `yaml:"max_idle_conns_per_host"`
+	IdleConnTimeout     env[time.Duration] `yaml:"idle_conn_timeout"`
+	TLSHandshakeTimeout env[time.Duration] `yaml:"tls_handshake_timeout"`
+	Timeout             env[time.Duration] `yaml:"timeout"`
+}
+
+// config returns the HTTPClientConfig of the keystore with
+// the given name or an error if any setting is negative.
+func (y *ymlHTTPClient) config(name string) (HTTPClientConfig, error) {
+	if y.MaxIdleConns.Value < 0 || y.MaxIdleConnsPerHost.Value < 0 {
+		return HTTPClientConfig{}, fmt.Errorf("kesconf: invalid %s keystore: invalid http config: number of idle connections must not be negative", name)
+	}
+	if y.IdleConnTimeout.Value < 0 || y.TLSHandshakeTimeout.Value < 0 || y.Timeout.Value < 0 {
+		return HTTPClientConfig{}, fmt.Errorf("kesconf: invalid %s keystore: invalid http config: timeouts must not be negative", name)
+	}
+	return HTTPClientConfig{
+		MaxIdleConns:        y.MaxIdleConns.Value,
+		MaxIdleConnsPerHost: y.MaxIdleConnsPerHost.Value,
+		IdleConnTimeout:     y.IdleConnTimeout.Value,
+		TLSHandshakeTimeout: y.TLSHandshakeTimeout.Value,
+		Timeout:             y.Timeout.Value,
+	}, nil
+}
+
+// ymlKeyStore is the keystore section of a YAML config file.
+//
+// It may contain a nested secondary keystore that KES fails
+// over to when the primary keystore is not reachable.
+type ymlKeyStore struct {
+	FS *struct {
+		Path          env[string] `yaml:"path"`
+		MasterKey     env[string] `yaml:"master_key"`
+		MasterKeyFile env[string] `yaml:"master_key_file"`
+		TPM           struct {
+			SealedKey env[string] `yaml:"sealed_key"`
+			Device    env[string] `yaml:"device"`
+		} `yaml:"tpm"`
+		KMIP *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Name     env[string] `yaml:"name"`
 			TLS      struct {
-				Certificate env[string] `yaml:"cert"`
 				PrivateKey  env[string] `yaml:"key"`
+				Certificate env[string] `yaml:"cert"`
 				CAPath      env[string] `yaml:"ca"`
 			} `yaml:"tls"`
-		} `yaml:"kes"`
+		} `yaml:"kmip"`
+	}
+	KES *struct {
+		Endpoint []env[string] `yaml:"endpoint"`
+		Enclave  env[string]   `yaml:"enclave"`
+		TLS      struct {
+			Certificate env[string] `yaml:"cert"`
+			PrivateKey  env[string] `yaml:"key"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kes"`
 
-		Vault *struct {
-			Endpoint   env[string] `yaml:"endpoint"`
+	Vault *struct {
+		Endpoint   env[string] `yaml:"endpoint"`
+		Engine     env[string] `yaml:"engine"`
+		APIVersion env[string] `yaml:"version"`
+		Namespace  env[string] `yaml:"namespace"`
+		Prefix     env[string] `yaml:"prefix"`
+
+		Transit *struct {
+			Engine  env[string] `yaml:"engine"`
+			KeyName env[string] `yaml:"key"`
+		}
+
+		AppRole *struct {
 			Engine     env[string] `yaml:"engine"`
-			APIVersion env[string] `yaml:"version"`
 			Namespace  env[string] `yaml:"namespace"`
-			Prefix     env[string] `yaml:"prefix"`
+			ID         env[string] `yaml:"id"`
+			Secret     env[string] `yaml:"secret"`
+			SecretFile env[string] `yaml:"secret_file"`
+			Rotate     env[bool]   `yaml:"rotate"`
+		} `yaml:"approle"`
 
-			Transit *struct {
-				Engine  env[string] `yaml:"engine"`
-				KeyName env[string] `yaml:"key"`
-			}
+		Kubernetes *struct {
+			Engine    env[string] `yaml:"engine"`
+			Namespace env[string] `yaml:"namespace"`
+			Role      env[string] `yaml:"role"`
+			JWT       env[string] `yaml:"jwt"` // Can be either a JWT or a path to a file containing a JWT
+		} `yaml:"kubernetes"`
+
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
 
-			AppRole *struct {
-				Engine    env[string] `yaml:"engine"`
-				Namespace env[string] `yaml:"namespace"`
-				ID        env[string] `yaml:"id"`
-				Secret    env[string] `yaml:"secret"`
-			} `yaml:"approle"`
+		Status struct {
+			Ping env[time.Duration] `yaml:"ping"`
+		} `yaml:"status"`
 
-			Kubernetes *struct {
-				Engine    env[string] `yaml:"engine"`
-				Namespace env[string] `yaml:"namespace"`
-				Role      env[string] `yaml:"role"`
-				JWT       env[string] `yaml:"jwt"` // Can be either a JWT or a path to a file containing a JWT
-			} `yaml:"kubernetes"`
+		HTTP ymlHTTPClient `yaml:"http"`
+	} `yaml:"vault"`
+
+	Fortanix *struct {
+		SDKMS *struct {
+			Endpoint  env[string] `yaml:"endpoint"`
+			GroupID   env[string] `yaml:"group_id"`
+			GroupName env[string] `yaml:"group"`
+
+			Login struct {
+				APIKey    env[string] `yaml:"key"`
+				AppID     env[string] `yaml:"app_id"`
+				Cert      env[string] `yaml:"cert"`
+				Key       env[string] `yaml:"private_key"`
+				TokenFile env[string] `yaml:"token_file"`
+			} `yaml:"credentials"`
 
 			TLS struct {
-				PrivateKey  env[string] `yaml:"key"`
-				Certificate env[string] `yaml:"cert"`
-				CAPath      env[string] `yaml:"ca"`
+				CAPath env[string] `yaml:"ca"`
 			} `yaml:"tls"`
+		} `yaml:"sdkms"`
+	} `yaml:"fortanix"`
 
-			Status struct {
-				Ping env[time.Duration] `yaml:"ping"`
-			} `yaml:"status"`
-		} `yaml:"vault"`
+	Gemalto *struct {
+		KeySecure *struct {
+			Endpoint env[string] `yaml:"endpoint"`
 
-		Fortanix *struct {
-			SDKMS *struct {
-				Endpoint env[string] `yaml:"endpoint"`
-				GroupID  env[string] `yaml:"group_id"`
+			Login struct {
+				Token  env[string] `yaml:"token"`
+				Domain env[string] `yaml:"domain"`
+			} `yaml:"credentials"`
 
-				Login struct {
-					APIKey env[string] `yaml:"key"`
-				} `yaml:"credentials"`
+			TLS struct {
+				CAPath env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"keysecure"`
+	} `yaml:"gemalto"`
 
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"sdkms"`
-		} `yaml:"fortanix"`
+	GCP *struct {
+		SecretManager *struct {
+			ProjectID   env[string]   `yaml:"project_id"`
+			Endpoint    env[string]   `yaml:"endpoint"`
+			Scopes      []env[string] `yaml:"scopes"`
+			Credentials struct {
+				Client   env[string] `yaml:"client_email"`
+				ClientID env[string] `yaml:"client_id"`
+				KeyID    env[string] `yaml:"private_key_id"`
+				Key      env[string] `yaml:"private_key"`
+				File     env[string] `yaml:"file"`
+			} `yaml:"credentials"`
+		} `yaml:"secretmanager"`
+	} `yaml:"gcp"`
 
-		Gemalto *struct {
-			KeySecure *struct {
-				Endpoint env[string] `yaml:"endpoint"`
+	AWS *struct {
+		SecretsManager *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Region   env[string] `yaml:"region"`
+			KmsKey   env[string] `yaml:"kmskey"`
+
+			Login struct {
+				AccessKey    env[string] `yaml:"accesskey"`
+				SecretKey    env[string] `yaml:"secretkey"`
+				SessionToken env[string] `yaml:"token"`
+
+				WebIdentity *struct {
+					RoleARN     env[string] `yaml:"role_arn"`
+					TokenFile   env[string] `yaml:"token_file"`
+					SessionName env[string] `yaml:"session_name"`
+				} `yaml:"web_identity"`
+
+				AssumeRole *struct {
+					RoleARN     env[string] `yaml:"role_arn"`
+					ExternalID  env[string] `yaml:"external_id"`
+					SessionName env[string] `yaml:"session_name"`
+				} `yaml:"assume_role"`
+
+				IMDSv2Only env[bool] `yaml:"imdsv2_only"`
+			} `yaml:"credentials"`
+
+			HTTP       ymlHTTPClient `yaml:"http"`
+			MaxRetries env[int]      `yaml:"max_retries"`
+		} `yaml:"secretsmanager"`
 
-				Login struct {
-					Token  env[string] `yaml:"token"`
-					Domain env[string] `yaml:"domain"`
-				} `yaml:"credentials"`
-
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"keysecure"`
-		} `yaml:"gemalto"`
-
-		GCP *struct {
-			SecretManager *struct {
-				ProjectID   env[string]   `yaml:"project_id"`
-				Endpoint    env[string]   `yaml:"endpoint"`
-				Scopes      []env[string] `yaml:"scopes"`
-				Credentials struct {
-					Client   env[string] `yaml:"client_email"`
-					ClientID env[string] `yaml:"client_id"`
-					KeyID    env[string] `yaml:"private_key_id"`
-					Key      env[string] `yaml:"private_key"`
-				} `yaml:"credentials"`
-			} `yaml:"secretmanager"`
-		} `yaml:"gcp"`
-
-		AWS *struct {
-			SecretsManager *struct {
+		KMS *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			Region   env[string] `yaml:"region"`
+			Key      env[string] `yaml:"key"`
+
+			DynamoDB *struct {
+				Table    env[string] `yaml:"table"`
 				Endpoint env[string] `yaml:"endpoint"`
-				Region   env[string] `yaml:"region"`
-				KmsKey   env[string] ` yaml:"kmskey"`
-
-				Login struct {
-					AccessKey    env[string] `yaml:"accesskey"`
-					SecretKey    env[string] `yaml:"secretkey"`
-					SessionToken env[string] `yaml:"token"`
-				} `yaml:"credentials"`
-			} `yaml:"secretsmanager"`
-		} `yaml:"aws"`
-
-		Azure *struct {
-			KeyVault *struct {
-				Endpoint    env[string] `yaml:"endpoint"`
-				Credentials *struct {
-					TenantID env[string] `yaml:"tenant_id"`
-					ClientID env[string] `yaml:"client_id"`
-					Secret   env[string] `yaml:"client_secret"`
-				} `yaml:"credentials"`
-				ManagedIdentity *struct {
-					ClientID env[string] `yaml:"client_id"`
-				} `yaml:"managed_identity"`
-			} `yaml:"keyvault"`
-		} `yaml:"azure"`
-		Entrust *struct {
-			KeyControl *struct {
+			} `yaml:"dynamodb"`
+
+			S3 *struct {
+				Bucket   env[string] `yaml:"bucket"`
+				Prefix   env[string] `yaml:"prefix"`
 				Endpoint env[string] `yaml:"endpoint"`
-				VaultID  env[string] `yaml:"vault_id"`
-				BoxID    env[string] `yaml:"box_id"`
-				Login    *struct {
-					Username env[string] `yaml:"username"`
-					Password env[string] `yaml:"password"`
-				} `yaml:"credentials"`
-				TLS struct {
-					CAPath env[string] `yaml:"ca"`
-				} `yaml:"tls"`
-			} `yaml:"keycontrol"`
-		} `yaml:"entrust"`
-	} `yaml:"keystore"`
+			} `yaml:"s3"`
+
+			Login struct {
+				AccessKey    env[string] `yaml:"accesskey"`
+				SecretKey    env[string] `yaml:"secretkey"`
+				SessionToken env[string] `yaml:"token"`
+
+				WebIdentity *struct {
+					RoleARN     env[string] `yaml:"role_arn"`
+					TokenFile   env[string] `yaml:"token_file"`
+					SessionName env[string] `yaml:"session_name"`
+				} `yaml:"web_identity"`
+
+				AssumeRole *struct {
+					RoleARN     env[string] `yaml:"role_arn"`
+					ExternalID  env[string] `yaml:"external_id"`
+					SessionName env[string] `yaml:"session_name"`
+				} `yaml:"assume_role"`
+
+				IMDSv2Only env[bool] `yaml:"imdsv2_only"`
+			} `yaml:"credentials"`
+
+			HTTP       ymlHTTPClient `yaml:"http"`
+			MaxRetries env[int]      `yaml:"max_retries"`
+		} `yaml:"kms"`
+	} `yaml:"aws"`
+
+	Azure *struct {
+		KeyVault *struct {
+			Endpoint    env[string] `yaml:"endpoint"`
+			Credentials *struct {
+				TenantID env[string] `yaml:"tenant_id"`
+				ClientID env[string] `yaml:"client_id"`
+				Secret   env[string] `yaml:"client_secret"`
+
+				Certificate         env[string] `yaml:"client_certificate"`
+				CertificatePassword env[string] `yaml:"client_certificate_password"`
+			} `yaml:"credentials"`
+			ManagedIdentity *struct {
+				ClientID env[string] `yaml:"client_id"`
+			} `yaml:"managed_identity"`
+		} `yaml:"keyvault"`
+	} `yaml:"azure"`
+	Entrust *struct {
+		KeyControl *struct {
+			Endpoint env[string] `yaml:"endpoint"`
+			VaultID  env[string] `yaml:"vault_id"`
+			BoxID    env[string] `yaml:"box_id"`
+			Login    *struct {
+				Username env[string] `yaml:"username"`
+				Password env[string] `yaml:"password"`
+			} `yaml:"credentials"`
+			TLS struct {
+				CAPath env[string] `yaml:"ca"`
+			} `yaml:"tls"`
+		} `yaml:"keycontrol"`
+	} `yaml:"entrust"`
+
+	PKCS11 *struct {
+		Module env[string] `yaml:"module"`
+		Slot   *env[uint]  `yaml:"slot"`
+		Token  env[string] `yaml:"token"`
+		PIN    env[string] `yaml:"pin"`
+		Prefix env[string] `yaml:"prefix"`
+	} `yaml:"pkcs11"`
+
+	Kubernetes *struct {
+		Endpoint  env[string] `yaml:"endpoint"`
+		Namespace env[string] `yaml:"namespace"`
+		Prefix    env[string] `yaml:"prefix"`
+		TokenFile env[string] `yaml:"token_file"`
+		TLS       struct {
+			CAPath env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kubernetes"`
+
+	Etcd *struct {
+		Endpoints []env[string]      `yaml:"endpoints"`
+		Prefix    env[string]        `yaml:"prefix"`
+		LeaseTTL  env[time.Duration] `yaml:"lease_ttl"`
+		Login     struct {
+			Username env[string] `yaml:"username"`
+			Password env[string] `yaml:"password"`
+		} `yaml:"credentials"`
+		TLS struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"etcd"`
+
+	SQL *struct {
+		Driver        env[string] `yaml:"driver"`
+		DSN           env[string] `yaml:"dsn"`
+		Table         env[string] `yaml:"table"`
+		MaxOpenConns  env[int]    `yaml:"max_open_conns"`
+		MasterKey     env[string] `yaml:"master_key"`
+		MasterKeyFile env[string] `yaml:"master_key_file"`
+		TLS           struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"sql"`
+
+	OCI *struct {
+		Vault *struct {
+			Region      env[string] `yaml:"region"`
+			VaultID     env[string] `yaml:"vault"`
+			Compartment env[string] `yaml:"compartment"`
+			KeyID       env[string] `yaml:"key"`
+			Prefix      env[string] `yaml:"prefix"`
+			Login       struct {
+				APIKey *struct {
+					Tenancy     env[string] `yaml:"tenancy"`
+					User        env[string] `yaml:"user"`
+					Fingerprint env[string] `yaml:"fingerprint"`
+					PrivateKey  env[string] `yaml:"private_key"`
+				} `yaml:"api_key"`
+				InstancePrincipal env[bool] `yaml:"instance_principal"`
+			} `yaml:"credentials"`
+		} `yaml:"vault"`
+	} `yaml:"oci"`
+
+	IBM *struct {
+		KeyProtect *struct {
+			Endpoint   env[string] `yaml:"endpoint"`
+			Region     env[string] `yaml:"region"`
+			InstanceID env[string] `yaml:"instance_id"`
+			KeyRing    env[string] `yaml:"key_ring"`
+			Prefix     env[string] `yaml:"prefix"`
+			Login      struct {
+				APIKey      env[string] `yaml:"api_key"`
+				IAMEndpoint env[string] `yaml:"iam_endpoint"`
+			} `yaml:"credentials"`
+		} `yaml:"keyprotect"`
+	} `yaml:"ibm"`
+
+	KMIP *struct {
+		Endpoint env[string] `yaml:"endpoint"`
+		Prefix   env[string] `yaml:"prefix"`
+		Group    env[string] `yaml:"group"`
+		TLS      struct {
+			PrivateKey  env[string] `yaml:"key"`
+			Certificate env[string] `yaml:"cert"`
+			CAPath      env[string] `yaml:"ca"`
+		} `yaml:"tls"`
+	} `yaml:"kmip"`
+
+	Plugin *struct {
+		Path         env[string]            `yaml:"path"`
+		Args         []env[string]          `yaml:"args"`
+		Config       map[string]env[string] `yaml:"config"`
+		StartTimeout env[time.Duration]     `yaml:"start_timeout"`
+	} `yaml:"plugin"`
+
+	Compress env[bool] `yaml:"compress"`
+
+	// FaultInjection is intentionally not documented. It is
+	// only meant for testing HA, retry and failover setups.
+	FaultInjection *struct {
+		Latency   env[time.Duration] `yaml:"latency"`
+		Jitter    env[time.Duration] `yaml:"jitter"`
+		ErrorRate env[float64]       `yaml:"error_rate"`
+	} `yaml:"fault_injection"`
+
+	Retry *struct {
+		Attempts env[int]           `yaml:"attempts"`
+		Backoff  env[string]        `yaml:"backoff"`
+		Delay    env[time.Duration] `yaml:"delay"`
+		MaxDelay env[time.Duration] `yaml:"max_delay"`
+		Timeout  env[time.Duration] `yaml:"timeout"`
+	} `yaml:"retry"`
+
+	CircuitBreaker *struct {
+		Threshold env[int]           `yaml:"threshold"`
+		Interval  env[time.Duration] `yaml:"interval"`
+	} `yaml:"circuit_breaker"`
+
+	Secondary *ymlKeyStore `yaml:"secondary"`
+
+	Failover *struct {
+		Threshold env[int]           `yaml:"threshold"`
+		Interval  env[time.Duration] `yaml:"interval"`
+	} `yaml:"failover"`
 }
 
 func findVersion(root *yaml.Node) (string, error) {
@@ -248,11 +804,52 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 	if y.Admin.Identity.Value.IsUnknown() {
 		return nil, errors.New("kesconf: invalid admin identity: no admin identity")
 	}
-	if y.TLS.PrivateKey.Value == "" {
-		return nil, errors.New("kesconf: invalid tls config: no private key")
+	workloadAPI := y.TLS.SPIFFE != nil && y.TLS.SPIFFE.WorkloadAPI.Value != ""
+	if acme := y.TLS.ACME; acme != nil {
+		if y.TLS.PrivateKey.Value != "" || y.TLS.Certificate.Value != "" {
+			return nil, errors.New("kesconf: invalid tls config: private key and certificate must not be set when using ACME")
+		}
+		if workloadAPI {
+			return nil, errors.New("kesconf: invalid tls config: certificate cannot be obtained via ACME and from the SPIFFE workload API")
+		}
+		if len(acme.Domains) == 0 {
+			return nil, errors.New("kesconf: invalid tls acme config: no domains")
+		}
+		for _, domain := range acme.Domains {
+			if domain.Value == "" {
+				return nil, errors.New("kesconf: invalid tls acme config: empty domain")
+			}
+		}
+		switch acme.Challenge.Value {
+		case "", "http-01":
+		case "dns-01":
+			if (acme.DNS.Exec.Value == "") == (acme.DNS.Webhook.Value == "") {
+				return nil, errors.New("kesconf: invalid tls acme config: dns-01 challenge requires either a DNS exec or webhook provider")
+			}
+		default:
+			return nil, fmt.Errorf("kesconf: invalid tls acme config: invalid challenge '%s'", acme.Challenge.Value)
+		}
+		if acme.RenewBefore.Value < 0 {
+			return nil, errors.New("kesconf: invalid tls acme config: renewal period must not be negative")
+		}
+		if y.Replica.Endpoint.Value != "" {
+			return nil, errors.New("kesconf: invalid tls acme config: read replicas have no keystore to store the certificate")
+		}
+	} else if workloadAPI {
+		if y.TLS.PrivateKey.Value != "" || y.TLS.Certificate.Value != "" {
+			return nil, errors.New("kesconf: invalid tls config: private key and certificate must not be set when using the SPIFFE workload API")
+		}
+	} else {
+		if y.TLS.PrivateKey.Value == "" {
+			return nil, errors.New("kesconf: invalid tls config: no private key")
+		}
+		if y.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid tls config: no certificate")
+		}
 	}
-	if y.TLS.Certificate.Value == "" {
-		return nil, errors.New("kesconf: invalid tls config: no certificate")
+
+	if y.TLS.Reload.Value < 0 {
+		return nil, errors.New("kesconf: invalid tls config: reload interval must not be negative")
 	}
 
 	clientAuth := tls.RequireAnyClientCert
@@ -261,6 +858,41 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 	} else if v == "on" {
 		clientAuth = tls.RequireAndVerifyClientCert
 	}
+	if spiffe := y.TLS.SPIFFE; spiffe != nil {
+		if clientAuth != tls.RequireAndVerifyClientCert {
+			return nil, errors.New("kesconf: invalid tls config: SPIFFE identities require auth 'on'")
+		}
+		if len(spiffe.TrustDomains) == 0 {
+			return nil, errors.New("kesconf: invalid tls spiffe config: no trust domains")
+		}
+		for _, td := range spiffe.TrustDomains {
+			if td.Value == "" {
+				return nil, errors.New("kesconf: invalid tls spiffe config: empty trust domain")
+			}
+		}
+	}
+	if oidc := y.OIDC; oidc != nil {
+		if oidc.Issuer.Value == "" {
+			return nil, errors.New("kesconf: invalid oidc config: no issuer")
+		}
+		if oidc.Audience.Value == "" {
+			return nil, errors.New("kesconf: invalid oidc config: no audience")
+		}
+		if len(oidc.Policies) == 0 {
+			return nil, errors.New("kesconf: invalid oidc config: no policies")
+		}
+	}
+	if r := y.TLS.Revocation; r != nil {
+		if clientAuth != tls.RequireAndVerifyClientCert {
+			return nil, errors.New("kesconf: invalid tls config: revocation checks require auth 'on'")
+		}
+		if r.CRL.Value == "" && !r.OCSP.Value {
+			return nil, errors.New("kesconf: invalid tls config: revocation requires a CRL or OCSP")
+		}
+		if r.Reload.Value < 0 {
+			return nil, errors.New("kesconf: invalid tls config: CRL reload interval must not be negative")
+		}
+	}
 
 	for _, proxy := range y.TLS.Proxy.Identities {
 		if proxy.Value == y.Admin.Identity.Value {
@@ -290,6 +922,60 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 	if y.Cache.Expiry.Offline.Value < 0 {
 		return nil, fmt.Errorf("kesconf: invalid offline cache expiry '%v'", y.Cache.Expiry.Offline.Value)
 	}
+	if y.Cache.Prewarm.Prefix.Value != "" && !y.Cache.Prewarm.Enabled.Value {
+		return nil, errors.New("kesconf: invalid cache config: prewarm prefix specified but prewarming is not enabled")
+	}
+	offlinePolicy, err := parseOfflinePolicy(y.Cache.OfflinePolicy.Value)
+	if err != nil {
+		return nil, err
+	}
+	var cachePolicy CachePolicy
+	switch policy := strings.ToLower(y.Cache.Policy.Value); policy {
+	case "", "write-through":
+		cachePolicy = WriteThrough
+		if y.Cache.WriteBack.Journal.Value != "" {
+			return nil, errors.New("kesconf: invalid cache config: write-back journal requires write-back cache policy")
+		}
+	case "write-back":
+		cachePolicy = WriteBack
+		if y.Cache.WriteBack.Journal.Value == "" {
+			return nil, errors.New("kesconf: invalid cache config: no write-back journal specified")
+		}
+		if y.Cache.WriteBack.Interval.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid cache config: invalid write-back interval '%v'", y.Cache.WriteBack.Interval.Value)
+		}
+	default:
+		return nil, fmt.Errorf("kesconf: invalid cache policy '%s'", y.Cache.Policy.Value)
+	}
+	if y.Cache.MaxEntries.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid cache config: invalid max entries '%d'", y.Cache.MaxEntries.Value)
+	}
+	evictionPolicy, err := parseEvictionPolicy(y.Cache.Eviction.Value)
+	if err != nil {
+		return nil, err
+	}
+	if y.Cache.Eviction.Value != "" && y.Cache.MaxEntries.Value == 0 {
+		return nil, errors.New("kesconf: invalid cache config: eviction policy requires max entries")
+	}
+	var pin []string
+	for _, pattern := range y.Cache.Pin {
+		if _, err = path.Match(pattern.Value, ""); err != nil || pattern.Value == "" {
+			return nil, fmt.Errorf("kesconf: invalid cache config: invalid pin pattern '%s'", pattern.Value)
+		}
+		pin = append(pin, pattern.Value)
+	}
+	var preload []string
+	for _, pattern := range y.Cache.Preload.Keys {
+		if _, err = path.Match(pattern.Value, ""); err != nil || pattern.Value == "" {
+			return nil, fmt.Errorf("kesconf: invalid cache config: invalid preload pattern '%s'", pattern.Value)
+		}
+		preload = append(preload, pattern.Value)
+	}
+
+	memoryLock, err := parseMemoryLock(y.Memory.Lock.Value)
+	if err != nil {
+		return nil, err
+	}
 
 	errLevel, err := parseLogLevel(y.Log.Error.Value)
 	if err != nil {
@@ -300,6 +986,277 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 		return nil, err
 	}
 
+	if y.Log.Checkpoint.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid audit checkpoint interval '%v'", y.Log.Checkpoint.Value)
+	}
+	if y.Log.CheckpointKey.Value != "" && y.Log.Checkpoint.Value == 0 {
+		return nil, errors.New("kesconf: invalid log config: audit checkpoint key requires audit checkpoints")
+	}
+	if y.Log.MerkleTree.Value && y.Log.Checkpoint.Value == 0 {
+		return nil, errors.New("kesconf: invalid log config: audit Merkle tree requires audit checkpoints")
+	}
+	if y.Log.Store.Retention.Value != 0 && y.Log.Store.Path.Value == "" {
+		return nil, errors.New("kesconf: invalid log config: audit retention requires an audit store path")
+	}
+	if r := y.Log.Store.Retention.Value; r != 0 && r < 24*time.Hour {
+		return nil, fmt.Errorf("kesconf: invalid audit retention '%v': must be at least 24h", r)
+	}
+
+	var logFile *LogFileConfig
+	if f := y.Log.File; f.Error.Value != "" || f.Audit.Value != "" {
+		logFile = &LogFileConfig{
+			ErrorPath:  f.Error.Value,
+			AuditPath:  f.Audit.Value,
+			MaxAge:     f.MaxAge.Value,
+			MaxBackups: f.MaxBackups.Value,
+		}
+		if f.Error.Value != "" && f.Error.Value == f.Audit.Value {
+			return nil, errors.New("kesconf: invalid log file config: error and audit log file must not be the same")
+		}
+		if f.MaxSize.Value != "" {
+			size, err := mem.ParseSize(f.MaxSize.Value)
+			if err != nil || size <= 0 {
+				return nil, fmt.Errorf("kesconf: invalid log file config: invalid max. size '%s'", f.MaxSize.Value)
+			}
+			logFile.MaxSize = int64(size)
+		}
+		if f.MaxAge.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid log file config: invalid max. age '%v'", f.MaxAge.Value)
+		}
+		if f.MaxBackups.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid log file config: invalid max. backups '%d'", f.MaxBackups.Value)
+		}
+	}
+
+	var logSyslog *SyslogLogConfig
+	if sl := y.Log.Syslog; sl.Addr.Value != "" {
+		network := strings.ToLower(sl.Network.Value)
+		switch network {
+		case "":
+			network = "udp"
+		case "udp", "tcp", "tls":
+		default:
+			return nil, fmt.Errorf("kesconf: invalid syslog config: invalid network '%s'", sl.Network.Value)
+		}
+		if sl.CAPath.Value != "" && network != "tls" {
+			return nil, errors.New("kesconf: invalid syslog config: CA certificates require network 'tls'")
+		}
+		if !sl.Error.Value && !sl.Audit.Value {
+			return nil, errors.New("kesconf: invalid syslog config: neither error nor audit logging is enabled")
+		}
+		facility := "local0"
+		if sl.Facility.Value != "" {
+			facility = sl.Facility.Value
+		}
+		if _, err := logsink.ParseFacility(facility); err != nil {
+			return nil, fmt.Errorf("kesconf: invalid syslog config: invalid facility '%s'", facility)
+		}
+		logSyslog = &SyslogLogConfig{
+			Network:  network,
+			Addr:     sl.Addr.Value,
+			CAPath:   sl.CAPath.Value,
+			Facility: facility,
+			AppName:  sl.AppName.Value,
+			Error:    sl.Error.Value,
+			Audit:    sl.Audit.Value,
+		}
+	}
+
+	var logWebhook *WebhookLogConfig
+	if w := y.Log.Webhook; w.Endpoint.Value != "" {
+		format := strings.ToLower(w.Format.Value)
+		switch format {
+		case "", logsink.FormatJSON, logsink.FormatSplunk:
+		default:
+			return nil, fmt.Errorf("kesconf: invalid log webhook config: invalid format '%s'", w.Format.Value)
+		}
+		if !w.Error.Value && !w.Audit.Value {
+			return nil, errors.New("kesconf: invalid log webhook config: neither error nor audit logging is enabled")
+		}
+		if w.Batch.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid log webhook config: invalid batch size '%d'", w.Batch.Value)
+		}
+		if w.Interval.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid log webhook config: invalid interval '%v'", w.Interval.Value)
+		}
+		if w.Retries.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid log webhook config: invalid number of retries '%d'", w.Retries.Value)
+		}
+		logWebhook = &WebhookLogConfig{
+			Endpoint:   w.Endpoint.Value,
+			AuthHeader: w.AuthHeader.Value,
+			AuthValue:  w.Auth.Value,
+			Format:     format,
+			CAPath:     w.CAPath.Value,
+			BatchSize:  w.Batch.Value,
+			Interval:   w.Interval.Value,
+			MaxRetries: w.Retries.Value,
+			Error:      w.Error.Value,
+			Audit:      w.Audit.Value,
+		}
+	}
+
+	if k := y.Log.Kafka; len(k.Brokers) > 0 || k.Topic.Value != "" {
+		if len(k.Brokers) == 0 {
+			return nil, errors.New("kesconf: invalid Kafka audit log config: no brokers specified")
+		}
+		if k.Topic.Value == "" {
+			return nil, errors.New("kesconf: invalid Kafka audit log config: no topic specified")
+		}
+		if k.Buffer.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid Kafka audit log config: invalid buffer size '%d'", k.Buffer.Value)
+		}
+		if k.Block.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid Kafka audit log config: invalid block timeout '%v'", k.Block.Value)
+		}
+	}
+
+	if y.Names.MaxLength.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid names config: invalid max. name length '%d'", y.Names.MaxLength.Value)
+	}
+
+	if y.Telemetry.Interval.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid telemetry config: invalid interval '%v'", y.Telemetry.Interval.Value)
+	}
+	if y.KeyUsage.Interval.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid key usage config: invalid interval '%v'", y.KeyUsage.Interval.Value)
+	}
+	if y.SoftDelete.RecoveryWindow.Value < 0 {
+		return nil, fmt.Errorf("kesconf: invalid soft-delete config: invalid recovery window '%v'", y.SoftDelete.RecoveryWindow.Value)
+	}
+	if w := y.Approval.Window.Value; w < 0 || (w > 0 && w < time.Minute) {
+		return nil, fmt.Errorf("kesconf: invalid approval config: window '%v' is less than 1m", w)
+	}
+	if h := y.HTTP; h.ReadHeaderTimeout.Value < 0 || h.ReadTimeout.Value < 0 || h.WriteTimeout.Value < 0 || h.IdleTimeout.Value < 0 || h.ShutdownTimeout.Value < 0 {
+		return nil, errors.New("kesconf: invalid http config: timeouts must not be negative")
+	}
+	if addr := y.GRPC.Addr.Value; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("kesconf: invalid grpc config: invalid address '%s'", addr)
+		}
+	}
+	if addr := y.Metrics.Listener.Addr.Value; addr != "" {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("kesconf: invalid metrics listener config: invalid address '%s'", addr)
+		}
+	}
+	var trustedProxies []netip.Prefix
+	if y.ProxyProtocol.Enabled.Value {
+		if len(y.ProxyProtocol.Trusted) == 0 {
+			return nil, errors.New("kesconf: invalid proxy protocol config: no trusted proxies")
+		}
+		for _, v := range y.ProxyProtocol.Trusted {
+			prefix, err := parseIPPrefix(v.Value)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid proxy protocol config: invalid trusted proxy '%s'", v.Value)
+			}
+			trustedProxies = append(trustedProxies, prefix)
+		}
+	}
+	if y.Replication.Endpoint.Value != "" {
+		if y.Replication.Interval.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid replication config: invalid interval '%v'", y.Replication.Interval.Value)
+		}
+		if y.Replication.TLS.PrivateKey.Value == "" || y.Replication.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid replication config: no TLS private key or certificate specified")
+		}
+		if y.Replication.KEK.Value == "" {
+			return nil, errors.New("kesconf: invalid replication config: no KEK specified")
+		}
+	}
+	if y.Cluster.Addr.Value != "" {
+		addr, err := url.Parse(y.Cluster.Addr.Value)
+		if err != nil || addr.Scheme != "https" || addr.Host == "" {
+			return nil, fmt.Errorf("kesconf: invalid cluster config: invalid address '%s'", y.Cluster.Addr.Value)
+		}
+		if y.Cluster.Path.Value == "" {
+			return nil, errors.New("kesconf: invalid cluster config: no state directory path specified")
+		}
+		if y.Cluster.TLS.PrivateKey.Value == "" || y.Cluster.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid cluster config: no TLS private key or certificate specified")
+		}
+		if len(y.Cluster.Identities) == 0 {
+			return nil, errors.New("kesconf: invalid cluster config: no member identities specified")
+		}
+		for _, id := range y.Cluster.Identities {
+			if id.Value.IsUnknown() {
+				return nil, errors.New("kesconf: invalid cluster config: member identity is empty")
+			}
+		}
+		if k, err := hex.DecodeString(y.Cluster.Key.Value); err != nil || len(k) != 32 {
+			return nil, errors.New("kesconf: invalid cluster config: key is not a hex-encoded 256-bit key")
+		}
+	} else if y.Cluster.Path.Value != "" || len(y.Cluster.Peers) > 0 || y.Cluster.Join.Value != "" {
+		return nil, errors.New("kesconf: invalid cluster config: no cluster address specified")
+	}
+	if y.Replica.Endpoint.Value != "" {
+		endpoint, err := url.Parse(y.Replica.Endpoint.Value)
+		if err != nil || endpoint.Scheme != "https" || endpoint.Host == "" {
+			return nil, fmt.Errorf("kesconf: invalid replica config: invalid endpoint '%s'", y.Replica.Endpoint.Value)
+		}
+		if y.Replica.Interval.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid replica config: invalid interval '%v'", y.Replica.Interval.Value)
+		}
+		if y.Replica.TLS.PrivateKey.Value == "" || y.Replica.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid replica config: no TLS private key or certificate specified")
+		}
+		if y.Cluster.Addr.Value != "" {
+			return nil, errors.New("kesconf: invalid replica config: a read replica cannot be a cluster member")
+		}
+		if !y.KeyStore.empty() {
+			return nil, errors.New("kesconf: invalid replica config: keystore and replica are mutually exclusive")
+		}
+		if len(y.Keys) > 0 {
+			return nil, errors.New("kesconf: invalid replica config: keys cannot be created by a read replica")
+		}
+	}
+	for _, id := range y.Replica.Identities {
+		if id.Value.IsUnknown() {
+			return nil, errors.New("kesconf: invalid replica config: replica identity is empty")
+		}
+	}
+	if seal := y.Seal; seal != nil {
+		if seal.Threshold.Value < 2 {
+			return nil, fmt.Errorf("kesconf: invalid seal config: threshold '%d' is less than 2", seal.Threshold.Value)
+		}
+		if h, err := hex.DecodeString(seal.RootKeyHash.Value); err != nil || len(h) != sha256.Size {
+			return nil, errors.New("kesconf: invalid seal config: root key hash is not a hex-encoded SHA-256 hash")
+		}
+		if y.Replica.Endpoint.Value != "" {
+			return nil, errors.New("kesconf: invalid seal config: a read replica cannot be sealed")
+		}
+		if y.Cluster.Addr.Value != "" {
+			return nil, errors.New("kesconf: invalid seal config: seal and cluster are mutually exclusive")
+		}
+	}
+	if y.Otel.Endpoint.Value != "" {
+		endpoint, err := url.Parse(y.Otel.Endpoint.Value)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return nil, fmt.Errorf("kesconf: invalid otel config: invalid endpoint '%s'", y.Otel.Endpoint.Value)
+		}
+		if ratio := y.Otel.SamplingRatio.Value; ratio < 0 || ratio > 1 {
+			return nil, fmt.Errorf("kesconf: invalid otel config: sampling ratio '%v' is not between 0 and 1", ratio)
+		}
+	}
+	if l := y.Metrics.Label.Value; l != "" && l != "identity" && l != "policy" {
+		return nil, fmt.Errorf("kesconf: invalid metrics label '%s'", l)
+	}
+	if push := y.Metrics.Push; push.Endpoint.Value != "" {
+		endpoint, err := url.Parse(push.Endpoint.Value)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return nil, fmt.Errorf("kesconf: invalid metrics push config: invalid endpoint '%s'", push.Endpoint.Value)
+		}
+		if p := push.Protocol.Value; p != "" && p != "pushgateway" && p != "remote_write" {
+			return nil, fmt.Errorf("kesconf: invalid metrics push config: invalid protocol '%s'", p)
+		}
+		if push.Interval.Value != 0 && push.Interval.Value < time.Second {
+			return nil, fmt.Errorf("kesconf: invalid metrics push config: invalid interval '%v'", push.Interval.Value)
+		}
+		if (push.TLS.PrivateKey.Value == "") != (push.TLS.Certificate.Value == "") {
+			return nil, errors.New("kesconf: invalid metrics push config: TLS private key and certificate must be specified together")
+		}
+	}
+
 	for path, api := range y.API.Paths {
 		if api.Timeout.Value < 0 {
 			return nil, fmt.Errorf("kesconf: invalid timeout '%d' for API '%s'", api.Timeout.Value, path)
@@ -320,6 +1277,17 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 		}
 	}
 
+	// Clients that authenticate with an OIDC bearer
+	// token do not have to send a certificate.
+	if y.OIDC != nil {
+		if clientAuth == tls.RequireAnyClientCert {
+			clientAuth = tls.RequestClientCert
+		}
+		if clientAuth == tls.RequireAndVerifyClientCert {
+			clientAuth = tls.VerifyClientCertIfGiven
+		}
+	}
+
 	if len(y.Keys) > 0 {
 		names := make(map[string]struct{}, len(y.Keys))
 		for _, key := range y.Keys {
@@ -330,33 +1298,275 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 		}
 	}
 
-	keystore, err := ymlToKeyStore(y)
-	if err != nil {
-		return nil, err
+	var keystore KeyStore
+	if y.Replica.Endpoint.Value == "" { // Read replicas have no keystore
+		if keystore, err = ymlToKeyStore(&y.KeyStore); err != nil {
+			return nil, err
+		}
 	}
 
 	c := &File{
 		Addr:  y.Addr.Value,
 		Admin: y.Admin.Identity.Value,
+		FIPS:  y.FIPS.Value,
 		TLS: &TLSConfig{
 			PrivateKey:        y.TLS.PrivateKey.Value,
 			Certificate:       y.TLS.Certificate.Value,
 			Password:          y.TLS.Password.Value,
 			ClientAuth:        clientAuth,
 			CAPath:            y.TLS.CAPath.Value,
+			Reload:            y.TLS.Reload.Value,
 			ForwardCertHeader: y.TLS.Proxy.Header.ClientCert.Value,
 		},
 		Cache: &CacheConfig{
-			Expiry:        y.Cache.Expiry.Any.Value,
-			ExpiryUnused:  y.Cache.Expiry.Unused.Value,
-			ExpiryOffline: y.Cache.Expiry.Offline.Value,
+			Expiry:            y.Cache.Expiry.Any.Value,
+			ExpiryUnused:      y.Cache.Expiry.Unused.Value,
+			ExpiryOffline:     y.Cache.Expiry.Offline.Value,
+			Prewarm:           y.Cache.Prewarm.Enabled.Value,
+			PrewarmPrefix:     y.Cache.Prewarm.Prefix.Value,
+			Preload:           preload,
+			PreloadFile:       y.Cache.Preload.File.Value,
+			OfflinePolicy:     offlinePolicy,
+			Policy:            cachePolicy,
+			WriteBackJournal:  y.Cache.WriteBack.Journal.Value,
+			WriteBackInterval: y.Cache.WriteBack.Interval.Value,
+			MaxEntries:        y.Cache.MaxEntries.Value,
+			EvictionPolicy:    evictionPolicy,
+			Pin:               pin,
+		},
+		Memory: &MemoryConfig{
+			Lock:    memoryLock,
+			Zeroize: y.Memory.Zeroize.Value,
 		},
 		Log: &LogConfig{
-			ErrLevel:   errLevel,
-			AuditLevel: auditLevel,
+			ErrLevel:           errLevel,
+			AuditLevel:         auditLevel,
+			AuditCheckpoint:    y.Log.Checkpoint.Value,
+			AuditCheckpointKey: y.Log.CheckpointKey.Value,
+			AuditMerkleTree:    y.Log.MerkleTree.Value,
+			AuditStorePath:     y.Log.Store.Path.Value,
+			AuditRetention:     y.Log.Store.Retention.Value,
+			File:               logFile,
+			Syslog:             logSyslog,
+			Webhook:            logWebhook,
 		},
 		KeyStore: keystore,
 	}
+	if k := y.Log.Kafka; len(k.Brokers) > 0 {
+		brokers := make([]string, 0, len(k.Brokers))
+		for _, broker := range k.Brokers {
+			brokers = append(brokers, broker.Value)
+		}
+		c.Log.Kafka = &KafkaLogConfig{
+			Brokers:       brokers,
+			Topic:         k.Topic.Value,
+			TLS:           k.TLS.Value,
+			CAPath:        k.CAPath.Value,
+			SASLMechanism: k.SASL.Mechanism.Value,
+			Username:      k.SASL.Username.Value,
+			Password:      k.SASL.Password.Value,
+			BufferSize:    k.Buffer.Value,
+			BlockTimeout:  k.Block.Value,
+		}
+	}
+	if acme := y.TLS.ACME; acme != nil {
+		domains := make([]string, 0, len(acme.Domains))
+		for _, domain := range acme.Domains {
+			domains = append(domains, domain.Value)
+		}
+		c.TLS.ACME = &ACMEConfig{
+			Directory:   acme.Directory.Value,
+			Email:       acme.Email.Value,
+			Domains:     domains,
+			Challenge:   acme.Challenge.Value,
+			HTTPAddr:    acme.HTTP.Addr.Value,
+			DNSExec:     acme.DNS.Exec.Value,
+			DNSWebhook:  acme.DNS.Webhook.Value,
+			RenewBefore: acme.RenewBefore.Value,
+		}
+	}
+	if spiffe := y.TLS.SPIFFE; spiffe != nil {
+		trustDomains := make([]string, 0, len(spiffe.TrustDomains))
+		for _, td := range spiffe.TrustDomains {
+			trustDomains = append(trustDomains, td.Value)
+		}
+		c.TLS.SPIFFE = &SPIFFEConfig{
+			TrustDomains: trustDomains,
+			WorkloadAPI:  spiffe.WorkloadAPI.Value,
+		}
+	}
+	if oidc := y.OIDC; oidc != nil {
+		c.OIDC = &OIDCConfig{
+			Issuer:        oidc.Issuer.Value,
+			Audience:      oidc.Audience.Value,
+			IdentityClaim: oidc.IdentityClaim.Value,
+			Policies:      oidc.Policies,
+		}
+	}
+	if r := y.TLS.Revocation; r != nil {
+		c.TLS.CRLFile = r.CRL.Value
+		c.TLS.CRLReload = r.Reload.Value
+		c.TLS.OCSP = r.OCSP.Value
+		c.TLS.OCSPStrict = r.OCSPStrict.Value
+	}
+	if y.Names.MaxLength.Value > 0 || y.Names.Charset.Value != "" {
+		c.Names = &NameConfig{
+			MaxLength: y.Names.MaxLength.Value,
+			Charset:   y.Names.Charset.Value,
+		}
+	}
+	if y.Telemetry.Endpoint.Value != "" {
+		c.Telemetry = &TelemetryConfig{
+			Endpoint: y.Telemetry.Endpoint.Value,
+			Interval: y.Telemetry.Interval.Value,
+		}
+	}
+	if y.PolicyStore.File.Value != "" {
+		c.PolicyStore = &PolicyStoreConfig{
+			Filename: y.PolicyStore.File.Value,
+		}
+	}
+	if y.KeyUsage.Enabled.Value {
+		c.KeyUsage = &KeyUsageConfig{
+			Filename: y.KeyUsage.File.Value,
+			Interval: y.KeyUsage.Interval.Value,
+		}
+	}
+	if y.SoftDelete.Enabled.Value {
+		c.SoftDelete = &SoftDeleteConfig{
+			RecoveryWindow: y.SoftDelete.RecoveryWindow.Value,
+		}
+	}
+	if y.Approval.Enabled.Value {
+		c.Approval = &ApprovalConfig{
+			Window: y.Approval.Window.Value,
+		}
+	}
+	if h := y.HTTP; h.ReadHeaderTimeout.Value > 0 || h.ReadTimeout.Value > 0 || h.WriteTimeout.Value > 0 || h.IdleTimeout.Value > 0 || h.ShutdownTimeout.Value > 0 || h.MaxConcurrentStreams.Value > 0 || h.HTTP2 != nil {
+		c.HTTP = &HTTPConfig{
+			ReadHeaderTimeout:    h.ReadHeaderTimeout.Value,
+			ReadTimeout:          h.ReadTimeout.Value,
+			WriteTimeout:         h.WriteTimeout.Value,
+			IdleTimeout:          h.IdleTimeout.Value,
+			ShutdownTimeout:      h.ShutdownTimeout.Value,
+			MaxConcurrentStreams: h.MaxConcurrentStreams.Value,
+			DisableHTTP2:         h.HTTP2 != nil && !h.HTTP2.Value,
+		}
+	}
+	if y.GRPC.Addr.Value != "" {
+		c.GRPC = &GRPCConfig{
+			Addr: y.GRPC.Addr.Value,
+		}
+	}
+	if y.ProxyProtocol.Enabled.Value {
+		c.ProxyProtocol = &ProxyProtocolConfig{
+			TrustedProxies: trustedProxies,
+		}
+	}
+	if y.Notify.Webhook.Endpoint.Value != "" {
+		c.Notify.Webhook = &WebhookNotifyConfig{
+			Endpoint:  y.Notify.Webhook.Endpoint.Value,
+			AuthToken: y.Notify.Webhook.AuthToken.Value,
+			CAPath:    y.Notify.Webhook.CAPath.Value,
+		}
+	}
+	if y.Notify.NATS.Addr.Value != "" {
+		c.Notify.NATS = &NATSNotifyConfig{
+			Addr:     y.Notify.NATS.Addr.Value,
+			Subject:  y.Notify.NATS.Subject.Value,
+			Username: y.Notify.NATS.Username.Value,
+			Password: y.Notify.NATS.Password.Value,
+			Token:    y.Notify.NATS.Token.Value,
+			TLS:      y.Notify.NATS.TLS.Value,
+			CAPath:   y.Notify.NATS.CAPath.Value,
+		}
+	}
+	if y.Notify.SQS.QueueURL.Value != "" {
+		c.Notify.SQS = &SQSNotifyConfig{
+			QueueURL:     y.Notify.SQS.QueueURL.Value,
+			Region:       y.Notify.SQS.Region.Value,
+			AccessKey:    y.Notify.SQS.Credentials.AccessKey.Value,
+			SecretKey:    y.Notify.SQS.Credentials.SecretKey.Value,
+			SessionToken: y.Notify.SQS.Credentials.SessionToken.Value,
+		}
+	}
+	if y.Replication.Endpoint.Value != "" {
+		c.Replication = &ReplicationConfig{
+			Endpoint:    y.Replication.Endpoint.Value,
+			KEK:         y.Replication.KEK.Value,
+			Interval:    y.Replication.Interval.Value,
+			PrivateKey:  y.Replication.TLS.PrivateKey.Value,
+			Certificate: y.Replication.TLS.Certificate.Value,
+			CAPath:      y.Replication.TLS.CAPath.Value,
+		}
+	}
+	if y.Replica.Endpoint.Value != "" {
+		c.Replica = &ReplicaConfig{
+			Endpoint:    y.Replica.Endpoint.Value,
+			Interval:    y.Replica.Interval.Value,
+			PrivateKey:  y.Replica.TLS.PrivateKey.Value,
+			Certificate: y.Replica.TLS.Certificate.Value,
+			CAPath:      y.Replica.TLS.CAPath.Value,
+		}
+	}
+	if seal := y.Seal; seal != nil {
+		rootKeyHash, _ := hex.DecodeString(seal.RootKeyHash.Value) // Verified above
+		c.Seal = &SealConfig{
+			Threshold:   seal.Threshold.Value,
+			RootKeyHash: rootKeyHash,
+		}
+	}
+	if y.Cluster.Addr.Value != "" {
+		key, _ := hex.DecodeString(y.Cluster.Key.Value) // Verified above
+		c.Cluster = &ClusterConfig{
+			Addr:        y.Cluster.Addr.Value,
+			Dir:         y.Cluster.Path.Value,
+			Join:        y.Cluster.Join.Value,
+			PrivateKey:  y.Cluster.TLS.PrivateKey.Value,
+			Certificate: y.Cluster.TLS.Certificate.Value,
+			CAPath:      y.Cluster.TLS.CAPath.Value,
+			Key:         key,
+		}
+		for _, peer := range y.Cluster.Peers {
+			c.Cluster.Peers = append(c.Cluster.Peers, peer.Value)
+		}
+		for _, id := range y.Cluster.Identities {
+			c.Cluster.Identities = append(c.Cluster.Identities, id.Value)
+		}
+	}
+	if y.Otel.Endpoint.Value != "" {
+		c.Otel = &OtelConfig{
+			Endpoint:      y.Otel.Endpoint.Value,
+			ServiceName:   y.Otel.ServiceName.Value,
+			SamplingRatio: y.Otel.SamplingRatio.Value,
+		}
+	}
+	c.MetricsLabel = y.Metrics.Label.Value
+	if y.Metrics.Listener.Addr.Value != "" {
+		c.MetricsListener = &MetricsListenerConfig{
+			Addr: y.Metrics.Listener.Addr.Value,
+			TLS:  y.Metrics.Listener.TLS.Value,
+		}
+	}
+	if push := y.Metrics.Push; push.Endpoint.Value != "" {
+		c.MetricsPush = &MetricsPushConfig{
+			Endpoint:    push.Endpoint.Value,
+			Protocol:    push.Protocol.Value,
+			Job:         push.Job.Value,
+			Interval:    push.Interval.Value,
+			Username:    push.Username.Value,
+			Password:    push.Password.Value,
+			PrivateKey:  push.TLS.PrivateKey.Value,
+			Certificate: push.TLS.Certificate.Value,
+			CAPath:      push.TLS.CAPath.Value,
+		}
+	}
+	if len(y.Replica.Identities) > 0 {
+		c.ReplicaIdentities = make([]kes.Identity, 0, len(y.Replica.Identities))
+		for _, id := range y.Replica.Identities {
+			c.ReplicaIdentities = append(c.ReplicaIdentities, id.Value)
+		}
+	}
 	if len(y.TLS.Proxy.Identities) > 0 {
 		c.TLS.Proxies = make([]kes.Identity, 0, len(y.TLS.Proxy.Identities))
 		for _, proxy := range y.TLS.Proxy.Identities {
@@ -370,9 +1580,35 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 			for _, id := range policy.Identities {
 				identities = append(identities, id.Value)
 			}
+			conditions, err := parsePolicyConditions(policy.Conditions)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid policy '%s': %v", name, err)
+			}
+			rateLimit, err := parseRateLimit(policy.RateLimit)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid policy '%s': %v", name, err)
+			}
+			contextPolicy, err := parseContextPolicy(policy.Context)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid policy '%s': %v", name, err)
+			}
+			sshPolicy, err := parseSSHPolicy(policy.SSH)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid policy '%s': %v", name, err)
+			}
+			certPolicy, err := parseCertPolicy(policy.Cert)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: invalid policy '%s': %v", name, err)
+			}
 			c.Policies[name] = Policy{
 				Allow:      policy.Allow,
 				Deny:       policy.Deny,
+				Keys:       parseKeyRules(policy.Keys),
+				Conditions: conditions,
+				RateLimit:  rateLimit,
+				Context:    contextPolicy,
+				SSH:        sshPolicy,
+				Cert:       certPolicy,
 				Identities: identities,
 			}
 		}
@@ -403,265 +1639,789 @@ func ymlToServerConfig(y *ymlFile) (*File, error) {
 	return c, nil
 }
 
-func ymlToKeyStore(y *ymlFile) (KeyStore, error) {
+// empty reports whether the keystore section is empty.
+func (y *ymlKeyStore) empty() bool { return *y == ymlKeyStore{} }
+
+func ymlToKeyStore(y *ymlKeyStore) (KeyStore, error) {
 	var keystore KeyStore
 
 	// FS Keystore
-	if y.KeyStore.FS != nil {
-		if y.KeyStore.FS.Path.Value == "" {
+	if y.FS != nil {
+		if y.FS.Path.Value == "" {
 			return nil, errors.New("kesconf: invalid fs keystore: no path specified")
 		}
-		keystore = &FSKeyStore{
-			Path: y.KeyStore.FS.Path.Value,
+		if y.FS.MasterKey.Value != "" && y.FS.MasterKeyFile.Value != "" {
+			return nil, errors.New("kesconf: invalid fs keystore: master key and master key file are mutually exclusive")
+		}
+		if y.FS.TPM.SealedKey.Value != "" && (y.FS.MasterKey.Value != "" || y.FS.MasterKeyFile.Value != "") {
+			return nil, errors.New("kesconf: invalid fs keystore: master key and sealed master key are mutually exclusive")
+		}
+		if y.FS.TPM.SealedKey.Value == "" && y.FS.TPM.Device.Value != "" {
+			return nil, errors.New("kesconf: invalid fs keystore: no sealed master key specified for TPM device")
+		}
+		store := &FSKeyStore{
+			Path:                y.FS.Path.Value,
+			MasterKey:           y.FS.MasterKey.Value,
+			MasterKeyFile:       y.FS.MasterKeyFile.Value,
+			SealedMasterKeyFile: y.FS.TPM.SealedKey.Value,
+			TPMDevice:           y.FS.TPM.Device.Value,
+		}
+		if kmip := y.FS.KMIP; kmip != nil {
+			if y.FS.MasterKey.Value != "" || y.FS.MasterKeyFile.Value != "" || y.FS.TPM.SealedKey.Value != "" {
+				return nil, errors.New("kesconf: invalid fs keystore: master key and KMIP master key are mutually exclusive")
+			}
+			if kmip.Endpoint.Value == "" {
+				return nil, errors.New("kesconf: invalid fs keystore: invalid kmip config: no endpoint specified")
+			}
+			if kmip.Name.Value == "" {
+				return nil, errors.New("kesconf: invalid fs keystore: invalid kmip config: no master key name specified")
+			}
+			if kmip.TLS.PrivateKey.Value == "" || kmip.TLS.Certificate.Value == "" {
+				return nil, errors.New("kesconf: invalid fs keystore: invalid kmip config: no TLS private key or certificate specified")
+			}
+			store.KMIPMasterKey = &KMIPKeyStore{
+				Endpoint:    kmip.Endpoint.Value,
+				PrivateKey:  kmip.TLS.PrivateKey.Value,
+				Certificate: kmip.TLS.Certificate.Value,
+				CAPath:      kmip.TLS.CAPath.Value,
+			}
+			store.KMIPMasterKeyName = kmip.Name.Value
 		}
+		keystore = store
 	}
 
 	// Hashicorp Vault Keystore
-	if y.KeyStore.Vault != nil {
+	if y.Vault != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Vault.Endpoint.Value == "" {
+		if y.Vault.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid vault keystore: no endpoint specified")
 		}
-		if y.KeyStore.Vault.AppRole == nil && y.KeyStore.Vault.Kubernetes == nil {
+		if y.Vault.AppRole == nil && y.Vault.Kubernetes == nil {
 			return nil, errors.New("kesconf: invalid vault keystore: no authentication method specified")
 		}
-		if y.KeyStore.Vault.AppRole != nil && y.KeyStore.Vault.Kubernetes != nil {
+		if y.Vault.AppRole != nil && y.Vault.Kubernetes != nil {
 			return nil, errors.New("kesconf: invalid vault keystore: more than one authentication method specified")
 		}
-		if y.KeyStore.Vault.AppRole != nil {
-			if y.KeyStore.Vault.AppRole.ID.Value == "" {
+		if y.Vault.AppRole != nil {
+			if y.Vault.AppRole.ID.Value == "" {
 				return nil, errors.New("kesconf: invalid vault keystore: invalid approle config: no approle ID specified")
 			}
-			if y.KeyStore.Vault.AppRole.Secret.Value == "" {
+			if y.Vault.AppRole.Secret.Value == "" && y.Vault.AppRole.SecretFile.Value == "" {
 				return nil, errors.New("kesconf: invalid vault keystore: invalid approle config: no approle secret specified")
 			}
+			if y.Vault.AppRole.Secret.Value != "" && y.Vault.AppRole.SecretFile.Value != "" {
+				return nil, errors.New("kesconf: invalid vault keystore: invalid approle config: approle secret and secret file are mutually exclusive")
+			}
+			if y.Vault.AppRole.Rotate.Value && y.Vault.AppRole.SecretFile.Value == "" {
+				return nil, errors.New("kesconf: invalid vault keystore: invalid approle config: secret rotation requires a secret file")
+			}
 		}
-		if y.KeyStore.Vault.Kubernetes != nil {
-			if y.KeyStore.Vault.Kubernetes.JWT.Value == "" {
+		var jwtFile string
+		if y.Vault.Kubernetes != nil {
+			if y.Vault.Kubernetes.JWT.Value == "" {
 				return nil, errors.New("kesconf: invalid vault keystore: invalid kubernetes config: no JWT specified")
 			}
 
 			// If the passed JWT value contains a path separator we assume it's a file.
 			// We always check for '/' and the OS-specific one make cover cases where
 			// a path is specified using '/' but the underlying OS is e.g. windows.
-			if jwt := y.KeyStore.Vault.Kubernetes.JWT.Value; strings.ContainsRune(jwt, '/') || strings.ContainsRune(jwt, os.PathSeparator) {
-				b, err := os.ReadFile(y.KeyStore.Vault.Kubernetes.JWT.Value)
+			if jwt := y.Vault.Kubernetes.JWT.Value; strings.ContainsRune(jwt, '/') || strings.ContainsRune(jwt, os.PathSeparator) {
+				b, err := os.ReadFile(y.Vault.Kubernetes.JWT.Value)
 				if err != nil {
-					return nil, fmt.Errorf("kesconf: failed to read vault kubernetes JWT from '%s': %v", y.KeyStore.Vault.Kubernetes.JWT.Value, err)
+					return nil, fmt.Errorf("kesconf: failed to read vault kubernetes JWT from '%s': %v", y.Vault.Kubernetes.JWT.Value, err)
 				}
-				y.KeyStore.Vault.Kubernetes.JWT.Value = string(b)
+				jwtFile = y.Vault.Kubernetes.JWT.Value
+				y.Vault.Kubernetes.JWT.Value = string(b)
 			}
 		}
-		if y.KeyStore.Vault.Transit != nil {
-			if y.KeyStore.Vault.Transit.KeyName.Value == "" {
+		if y.Vault.Transit != nil {
+			if y.Vault.Transit.KeyName.Value == "" {
 				return nil, errors.New("kesconf: invalid vault keystore: invalid transit config: no key name specified")
 			}
 		}
 
-		if y.KeyStore.Vault.TLS.PrivateKey.Value != "" && y.KeyStore.Vault.TLS.Certificate.Value == "" {
+		if y.Vault.TLS.PrivateKey.Value != "" && y.Vault.TLS.Certificate.Value == "" {
 			return nil, errors.New("kesconf: invalid vault keystore: invalid tls config: no TLS certificate provided")
 		}
-		if y.KeyStore.Vault.TLS.PrivateKey.Value == "" && y.KeyStore.Vault.TLS.Certificate.Value != "" {
+		if y.Vault.TLS.PrivateKey.Value == "" && y.Vault.TLS.Certificate.Value != "" {
 			return nil, errors.New("kesconf: invalid vault keystore: invalid tls config: no TLS private key provided")
 		}
+		httpConfig, err := y.Vault.HTTP.config("vault")
+		if err != nil {
+			return nil, err
+		}
 		s := &VaultKeyStore{
-			Endpoint:    y.KeyStore.Vault.Endpoint.Value,
-			Namespace:   y.KeyStore.Vault.Namespace.Value,
-			APIVersion:  y.KeyStore.Vault.APIVersion.Value,
-			Engine:      y.KeyStore.Vault.Engine.Value,
-			Prefix:      y.KeyStore.Vault.Prefix.Value,
-			PrivateKey:  y.KeyStore.Vault.TLS.PrivateKey.Value,
-			Certificate: y.KeyStore.Vault.TLS.Certificate.Value,
-			CAPath:      y.KeyStore.Vault.TLS.CAPath.Value,
-			StatusPing:  y.KeyStore.Vault.Status.Ping.Value,
-		}
-		if y.KeyStore.Vault.AppRole != nil {
+			Endpoint:    y.Vault.Endpoint.Value,
+			Namespace:   y.Vault.Namespace.Value,
+			APIVersion:  y.Vault.APIVersion.Value,
+			Engine:      y.Vault.Engine.Value,
+			Prefix:      y.Vault.Prefix.Value,
+			PrivateKey:  y.Vault.TLS.PrivateKey.Value,
+			Certificate: y.Vault.TLS.Certificate.Value,
+			CAPath:      y.Vault.TLS.CAPath.Value,
+			StatusPing:  y.Vault.Status.Ping.Value,
+			HTTP:        httpConfig,
+		}
+		if y.Vault.AppRole != nil {
 			s.AppRole = &VaultAppRoleAuth{
-				Engine:    y.KeyStore.Vault.AppRole.Engine.Value,
-				Namespace: y.KeyStore.Vault.AppRole.Namespace.Value,
-				ID:        y.KeyStore.Vault.AppRole.ID.Value,
-				Secret:    y.KeyStore.Vault.AppRole.Secret.Value,
+				Engine:     y.Vault.AppRole.Engine.Value,
+				Namespace:  y.Vault.AppRole.Namespace.Value,
+				ID:         y.Vault.AppRole.ID.Value,
+				Secret:     y.Vault.AppRole.Secret.Value,
+				SecretFile: y.Vault.AppRole.SecretFile.Value,
+				Rotate:     y.Vault.AppRole.Rotate.Value,
 			}
 		}
-		if y.KeyStore.Vault.Kubernetes != nil {
+		if y.Vault.Kubernetes != nil {
 			s.Kubernetes = &VaultKubernetesAuth{
-				Engine:    y.KeyStore.Vault.Kubernetes.Engine.Value,
-				Namespace: y.KeyStore.Vault.Kubernetes.Namespace.Value,
-				JWT:       y.KeyStore.Vault.Kubernetes.JWT.Value,
-				Role:      y.KeyStore.Vault.Kubernetes.Role.Value,
+				Engine:    y.Vault.Kubernetes.Engine.Value,
+				Namespace: y.Vault.Kubernetes.Namespace.Value,
+				JWT:       y.Vault.Kubernetes.JWT.Value,
+				JWTFile:   jwtFile,
+				Role:      y.Vault.Kubernetes.Role.Value,
 			}
 		}
-		if y.KeyStore.Vault.Transit != nil {
+		if y.Vault.Transit != nil {
 			s.Transit = &VaultTransit{
-				Engine:  y.KeyStore.Vault.Transit.Engine.Value,
-				KeyName: y.KeyStore.Vault.Transit.KeyName.Value,
+				Engine:  y.Vault.Transit.Engine.Value,
+				KeyName: y.Vault.Transit.KeyName.Value,
 			}
 		}
 		keystore = s
 	}
 
 	// Fortanix SDKMS
-	if y.KeyStore.Fortanix != nil && y.KeyStore.Fortanix.SDKMS != nil {
+	if y.Fortanix != nil && y.Fortanix.SDKMS != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Fortanix.SDKMS.Endpoint.Value == "" {
+		if y.Fortanix.SDKMS.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: no endpoint specified")
 		}
-		if y.KeyStore.Fortanix.SDKMS.Login.APIKey.Value == "" {
+		if y.Fortanix.SDKMS.GroupID.Value != "" && y.Fortanix.SDKMS.GroupName.Value != "" {
+			return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: group ID and group name are mutually exclusive")
+		}
+		login := y.Fortanix.SDKMS.Login
+		var methods int
+		if login.APIKey.Value != "" {
+			methods++
+		}
+		if login.Cert.Value != "" || login.Key.Value != "" {
+			if login.Cert.Value == "" || login.Key.Value == "" {
+				return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: client certificate and private key must be specified together")
+			}
+			methods++
+		}
+		if login.TokenFile.Value != "" {
+			methods++
+		}
+		if methods == 0 {
 			return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: no API key specified")
 		}
+		if methods > 1 {
+			return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: more than one authentication method specified")
+		}
+		if login.APIKey.Value == "" && login.AppID.Value == "" {
+			return nil, errors.New("kesconf: invalid fortanix SDKMS keystore: no app ID specified")
+		}
 		keystore = &FortanixKeyStore{
-			Endpoint: y.KeyStore.Fortanix.SDKMS.Endpoint.Value,
-			GroupID:  y.KeyStore.Fortanix.SDKMS.GroupID.Value,
-			APIKey:   y.KeyStore.Fortanix.SDKMS.Login.APIKey.Value,
-			CAPath:   y.KeyStore.Fortanix.SDKMS.TLS.CAPath.Value,
+			Endpoint:  y.Fortanix.SDKMS.Endpoint.Value,
+			GroupID:   y.Fortanix.SDKMS.GroupID.Value,
+			GroupName: y.Fortanix.SDKMS.GroupName.Value,
+			APIKey:    login.APIKey.Value,
+			AppID:     login.AppID.Value,
+			CertPath:  login.Cert.Value,
+			KeyPath:   login.Key.Value,
+			TokenFile: login.TokenFile.Value,
+			CAPath:    y.Fortanix.SDKMS.TLS.CAPath.Value,
 		}
 	}
 
 	// Thales CipherTrust / Gemalto KeySecure
-	if y.KeyStore.Gemalto != nil && y.KeyStore.Gemalto.KeySecure != nil {
+	if y.Gemalto != nil && y.Gemalto.KeySecure != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Gemalto.KeySecure.Endpoint.Value == "" {
+		if y.Gemalto.KeySecure.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid gemalto keysecure keystore: no endpoint specified")
 		}
-		if y.KeyStore.Gemalto.KeySecure.Login.Token.Value == "" {
+		if y.Gemalto.KeySecure.Login.Token.Value == "" {
 			return nil, errors.New("kesconf: invalid gemalto keysecure keystore: no token specified")
 		}
 		keystore = &KeySecureKeyStore{
-			Endpoint: y.KeyStore.Gemalto.KeySecure.Endpoint.Value,
-			Token:    y.KeyStore.Gemalto.KeySecure.Login.Token.Value,
-			Domain:   y.KeyStore.Gemalto.KeySecure.Login.Domain.Value,
-			CAPath:   y.KeyStore.Gemalto.KeySecure.TLS.CAPath.Value,
+			Endpoint: y.Gemalto.KeySecure.Endpoint.Value,
+			Token:    y.Gemalto.KeySecure.Login.Token.Value,
+			Domain:   y.Gemalto.KeySecure.Login.Domain.Value,
+			CAPath:   y.Gemalto.KeySecure.TLS.CAPath.Value,
 		}
 	}
 
 	// GCP SecretManager
-	if y.KeyStore.GCP != nil && y.KeyStore.GCP.SecretManager != nil {
+	if y.GCP != nil && y.GCP.SecretManager != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.GCP.SecretManager.ProjectID.Value == "" {
+		if y.GCP.SecretManager.ProjectID.Value == "" {
 			return nil, errors.New("kesconf: invalid GCP secretmanager keystore: no project ID specified")
 		}
 		var scopes []string
-		if len(y.KeyStore.GCP.SecretManager.Scopes) > 0 {
+		if len(y.GCP.SecretManager.Scopes) > 0 {
 			scopes = make([]string, 0, len(scopes))
-			for _, scope := range y.KeyStore.GCP.SecretManager.Scopes {
+			for _, scope := range y.GCP.SecretManager.Scopes {
 				scopes = append(scopes, scope.Value)
 			}
 		}
+		credentials := y.GCP.SecretManager.Credentials
+		if credentials.File.Value != "" && (credentials.Client.Value != "" || credentials.ClientID.Value != "" || credentials.KeyID.Value != "" || credentials.Key.Value != "") {
+			return nil, errors.New("kesconf: invalid GCP secretmanager keystore: credentials file and service account credentials are mutually exclusive")
+		}
 		keystore = &GCPSecretManagerKeyStore{
-			ProjectID:   y.KeyStore.GCP.SecretManager.ProjectID.Value,
-			Endpoint:    y.KeyStore.GCP.SecretManager.Endpoint.Value,
-			ClientEmail: y.KeyStore.GCP.SecretManager.Credentials.Client.Value,
-			ClientID:    y.KeyStore.GCP.SecretManager.Credentials.ClientID.Value,
-			KeyID:       y.KeyStore.GCP.SecretManager.Credentials.KeyID.Value,
-			Key:         y.KeyStore.GCP.SecretManager.Credentials.Key.Value,
-			Scopes:      scopes,
+			ProjectID:       y.GCP.SecretManager.ProjectID.Value,
+			Endpoint:        y.GCP.SecretManager.Endpoint.Value,
+			ClientEmail:     credentials.Client.Value,
+			ClientID:        credentials.ClientID.Value,
+			KeyID:           credentials.KeyID.Value,
+			Key:             credentials.Key.Value,
+			CredentialsFile: credentials.File.Value,
+			Scopes:          scopes,
 		}
 	}
 
 	// AWS SecretsManager
-	if y.KeyStore.AWS != nil && y.KeyStore.AWS.SecretsManager != nil {
+	if y.AWS != nil && y.AWS.SecretsManager != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.AWS.SecretsManager.Endpoint.Value == "" {
+		if y.AWS.SecretsManager.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: no endpoint specified")
 		}
-		if y.KeyStore.AWS.SecretsManager.Region.Value == "" {
+		if y.AWS.SecretsManager.Region.Value == "" {
 			return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: no region specified")
 		}
-		keystore = &AWSSecretsManagerKeyStore{
-			Endpoint:     y.KeyStore.AWS.SecretsManager.Endpoint.Value,
-			Region:       y.KeyStore.AWS.SecretsManager.Region.Value,
-			KMSKey:       y.KeyStore.AWS.SecretsManager.KmsKey.Value,
-			AccessKey:    y.KeyStore.AWS.SecretsManager.Login.AccessKey.Value,
-			SecretKey:    y.KeyStore.AWS.SecretsManager.Login.SecretKey.Value,
-			SessionToken: y.KeyStore.AWS.SecretsManager.Login.SessionToken.Value,
+		httpConfig, err := y.AWS.SecretsManager.HTTP.config("AWS secretsmanager")
+		if err != nil {
+			return nil, err
+		}
+		s := &AWSSecretsManagerKeyStore{
+			Endpoint:     y.AWS.SecretsManager.Endpoint.Value,
+			Region:       y.AWS.SecretsManager.Region.Value,
+			KMSKey:       y.AWS.SecretsManager.KmsKey.Value,
+			AccessKey:    y.AWS.SecretsManager.Login.AccessKey.Value,
+			SecretKey:    y.AWS.SecretsManager.Login.SecretKey.Value,
+			SessionToken: y.AWS.SecretsManager.Login.SessionToken.Value,
+			IMDSv2Only:   y.AWS.SecretsManager.Login.IMDSv2Only.Value,
+			HTTP:         httpConfig,
+			MaxRetries:   y.AWS.SecretsManager.MaxRetries.Value,
+		}
+		if identity := y.AWS.SecretsManager.Login.WebIdentity; identity != nil {
+			if s.AccessKey != "" || s.SecretKey != "" || s.SessionToken != "" {
+				return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: static credentials and web identity are mutually exclusive")
+			}
+			if identity.RoleARN.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: no web identity role ARN specified")
+			}
+			if identity.TokenFile.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: no web identity token file specified")
+			}
+			s.WebIdentityRoleARN = identity.RoleARN.Value
+			s.WebIdentityTokenFile = identity.TokenFile.Value
+			s.WebIdentitySessionName = identity.SessionName.Value
+		}
+		if role := y.AWS.SecretsManager.Login.AssumeRole; role != nil {
+			if role.RoleARN.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS secretsmanager keystore: no assume role ARN specified")
+			}
+			s.AssumeRoleARN = role.RoleARN.Value
+			s.AssumeRoleExternalID = role.ExternalID.Value
+			s.AssumeRoleSessionName = role.SessionName.Value
 		}
+		keystore = s
+	}
+
+	// AWS KMS with DynamoDB or S3
+	if y.AWS != nil && y.AWS.KMS != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		if y.AWS.KMS.Region.Value == "" {
+			return nil, errors.New("kesconf: invalid AWS kms keystore: no region specified")
+		}
+		if y.AWS.KMS.Key.Value == "" {
+			return nil, errors.New("kesconf: invalid AWS kms keystore: no KMS key specified")
+		}
+		if y.AWS.KMS.DynamoDB == nil && y.AWS.KMS.S3 == nil {
+			return nil, errors.New("kesconf: invalid AWS kms keystore: no DynamoDB table or S3 bucket specified")
+		}
+		if y.AWS.KMS.DynamoDB != nil && y.AWS.KMS.S3 != nil {
+			return nil, errors.New("kesconf: invalid AWS kms keystore: DynamoDB and S3 are mutually exclusive")
+		}
+		httpConfig, err := y.AWS.KMS.HTTP.config("AWS kms")
+		if err != nil {
+			return nil, err
+		}
+		s := &AWSKMSKeyStore{
+			Endpoint:     y.AWS.KMS.Endpoint.Value,
+			Region:       y.AWS.KMS.Region.Value,
+			KMSKey:       y.AWS.KMS.Key.Value,
+			AccessKey:    y.AWS.KMS.Login.AccessKey.Value,
+			SecretKey:    y.AWS.KMS.Login.SecretKey.Value,
+			SessionToken: y.AWS.KMS.Login.SessionToken.Value,
+			IMDSv2Only:   y.AWS.KMS.Login.IMDSv2Only.Value,
+			HTTP:         httpConfig,
+			MaxRetries:   y.AWS.KMS.MaxRetries.Value,
+		}
+		if db := y.AWS.KMS.DynamoDB; db != nil {
+			if db.Table.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: no DynamoDB table specified")
+			}
+			s.DynamoDBTable = db.Table.Value
+			s.DynamoDBEndpoint = db.Endpoint.Value
+		}
+		if s3 := y.AWS.KMS.S3; s3 != nil {
+			if s3.Bucket.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: no S3 bucket specified")
+			}
+			s.S3Bucket = s3.Bucket.Value
+			s.S3Prefix = s3.Prefix.Value
+			s.S3Endpoint = s3.Endpoint.Value
+		}
+		if identity := y.AWS.KMS.Login.WebIdentity; identity != nil {
+			if s.AccessKey != "" || s.SecretKey != "" || s.SessionToken != "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: static credentials and web identity are mutually exclusive")
+			}
+			if identity.RoleARN.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: no web identity role ARN specified")
+			}
+			if identity.TokenFile.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: no web identity token file specified")
+			}
+			s.WebIdentityRoleARN = identity.RoleARN.Value
+			s.WebIdentityTokenFile = identity.TokenFile.Value
+			s.WebIdentitySessionName = identity.SessionName.Value
+		}
+		if role := y.AWS.KMS.Login.AssumeRole; role != nil {
+			if role.RoleARN.Value == "" {
+				return nil, errors.New("kesconf: invalid AWS kms keystore: no assume role ARN specified")
+			}
+			s.AssumeRoleARN = role.RoleARN.Value
+			s.AssumeRoleExternalID = role.ExternalID.Value
+			s.AssumeRoleSessionName = role.SessionName.Value
+		}
+		keystore = s
 	}
 
 	// Azure KeyVault
-	if y.KeyStore.Azure != nil && y.KeyStore.Azure.KeyVault != nil {
+	if y.Azure != nil && y.Azure.KeyVault != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Endpoint.Value == "" {
+		if y.Azure.KeyVault.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid Azure keyvault keystore: no endpoint specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials == nil && y.KeyStore.Azure.KeyVault.ManagedIdentity == nil {
+		if y.Azure.KeyVault.Credentials == nil && y.Azure.KeyVault.ManagedIdentity == nil {
 			return nil, errors.New("kesconf: invalid Azure keyvault keystore: no authentication method specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil && y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
+		if y.Azure.KeyVault.Credentials != nil && y.Azure.KeyVault.ManagedIdentity != nil {
 			return nil, errors.New("kesconf: invalid Azure keyvault keystore: more than one authentication method specified")
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil {
-			if y.KeyStore.Azure.KeyVault.Credentials.TenantID.Value == "" {
+		if y.Azure.KeyVault.Credentials != nil {
+			if y.Azure.KeyVault.Credentials.TenantID.Value == "" {
 				return nil, errors.New("kesconf: invalid Azure keyvault keystore: no tenant ID specified")
 			}
-			if y.KeyStore.Azure.KeyVault.Credentials.ClientID.Value == "" {
+			if y.Azure.KeyVault.Credentials.ClientID.Value == "" {
 				return nil, errors.New("kesconf: invalid Azure keyvault keystore: no client ID specified")
 			}
-			if y.KeyStore.Azure.KeyVault.Credentials.Secret.Value == "" {
-				return nil, errors.New("kesconf: invalid Azure keyvault keystore: no client secret specified")
+			if y.Azure.KeyVault.Credentials.Secret.Value == "" && y.Azure.KeyVault.Credentials.Certificate.Value == "" {
+				return nil, errors.New("kesconf: invalid Azure keyvault keystore: no client secret or certificate specified")
 			}
-		}
-		if y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
-			if y.KeyStore.Azure.KeyVault.ManagedIdentity.ClientID.Value == "" {
-				return nil, errors.New("kesconf: invalid Azure keyvault keystore: no client ID specified")
+			if y.Azure.KeyVault.Credentials.Secret.Value != "" && y.Azure.KeyVault.Credentials.Certificate.Value != "" {
+				return nil, errors.New("kesconf: invalid Azure keyvault keystore: client secret and certificate are mutually exclusive")
 			}
 		}
 		s := &AzureKeyVaultKeyStore{
-			Endpoint: y.KeyStore.Azure.KeyVault.Endpoint.Value,
+			Endpoint: y.Azure.KeyVault.Endpoint.Value,
 		}
-		if y.KeyStore.Azure.KeyVault.Credentials != nil {
-			s.TenantID = y.KeyStore.Azure.KeyVault.Credentials.TenantID.Value
-			s.ClientID = y.KeyStore.Azure.KeyVault.Credentials.ClientID.Value
-			s.ClientSecret = y.KeyStore.Azure.KeyVault.Credentials.Secret.Value
+		if y.Azure.KeyVault.Credentials != nil {
+			s.TenantID = y.Azure.KeyVault.Credentials.TenantID.Value
+			s.ClientID = y.Azure.KeyVault.Credentials.ClientID.Value
+			s.ClientSecret = y.Azure.KeyVault.Credentials.Secret.Value
+			s.ClientCertificate = y.Azure.KeyVault.Credentials.Certificate.Value
+			s.ClientCertificatePassword = y.Azure.KeyVault.Credentials.CertificatePassword.Value
 		}
-		if y.KeyStore.Azure.KeyVault.ManagedIdentity != nil {
-			s.ManagedIdentityClientID = y.KeyStore.Azure.KeyVault.ManagedIdentity.ClientID.Value
+		if y.Azure.KeyVault.ManagedIdentity != nil {
+			// An empty client ID refers to the system-assigned managed identity.
+			s.ManagedIdentityClientID = y.Azure.KeyVault.ManagedIdentity.ClientID.Value
+			s.SystemManagedIdentity = s.ManagedIdentityClientID == ""
 		}
 		keystore = s
 	}
-	if y.KeyStore.Entrust != nil && y.KeyStore.Entrust.KeyControl != nil {
+	if y.Entrust != nil && y.Entrust.KeyControl != nil {
 		if keystore != nil {
 			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
 		}
-		if y.KeyStore.Entrust.KeyControl.Endpoint.Value == "" {
+		if y.Entrust.KeyControl.Endpoint.Value == "" {
 			return nil, errors.New("kesconf: invalid Entrust KeyControl keystore: no endpoint specified")
 		}
-		if y.KeyStore.Entrust.KeyControl.VaultID.Value == "" {
+		if y.Entrust.KeyControl.VaultID.Value == "" {
 			return nil, errors.New("kesconf: invalid Entrust KeyControl keystore: no vault ID specified")
 		}
-		if y.KeyStore.Entrust.KeyControl.BoxID.Value == "" {
+		if y.Entrust.KeyControl.BoxID.Value == "" {
 			return nil, errors.New("kesconf: invalid Entrust KeyControl keystore: no box ID specified")
 		}
-		if y.KeyStore.Entrust.KeyControl.Login.Username.Value == "" {
+		if y.Entrust.KeyControl.Login.Username.Value == "" {
 			return nil, errors.New("kesconf: invalid Entrust KeyControl keystore: no username specified")
 		}
-		if y.KeyStore.Entrust.KeyControl.Login.Password.Value == "" {
+		if y.Entrust.KeyControl.Login.Password.Value == "" {
 			return nil, errors.New("kesconf: invalid Entrust KeyControl keystore: no password specified")
 		}
 		keystore = &EntrustKeyControlKeyStore{
-			Endpoint: y.KeyStore.Entrust.KeyControl.Endpoint.Value,
-			VaultID:  y.KeyStore.Entrust.KeyControl.VaultID.Value,
-			BoxID:    y.KeyStore.Entrust.KeyControl.BoxID.Value,
-			Username: y.KeyStore.Entrust.KeyControl.Login.Username.Value,
-			Password: y.KeyStore.Entrust.KeyControl.Login.Password.Value,
-			CAPath:   y.KeyStore.Entrust.KeyControl.TLS.CAPath.Value,
+			Endpoint: y.Entrust.KeyControl.Endpoint.Value,
+			VaultID:  y.Entrust.KeyControl.VaultID.Value,
+			BoxID:    y.Entrust.KeyControl.BoxID.Value,
+			Username: y.Entrust.KeyControl.Login.Username.Value,
+			Password: y.Entrust.KeyControl.Login.Password.Value,
+			CAPath:   y.Entrust.KeyControl.TLS.CAPath.Value,
+		}
+	}
+
+	// PKCS#11 HSM
+	if y.PKCS11 != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		if y.PKCS11.Module.Value == "" {
+			return nil, errors.New("kesconf: invalid PKCS#11 keystore: no module specified")
+		}
+		if y.PKCS11.Slot == nil && y.PKCS11.Token.Value == "" {
+			return nil, errors.New("kesconf: invalid PKCS#11 keystore: no slot or token specified")
+		}
+		if y.PKCS11.Slot != nil && y.PKCS11.Token.Value != "" {
+			return nil, errors.New("kesconf: invalid PKCS#11 keystore: slot and token are mutually exclusive")
+		}
+		if y.PKCS11.PIN.Value == "" {
+			return nil, errors.New("kesconf: invalid PKCS#11 keystore: no PIN specified")
+		}
+		s := &PKCS11KeyStore{
+			Module: y.PKCS11.Module.Value,
+			Token:  y.PKCS11.Token.Value,
+			PIN:    y.PKCS11.PIN.Value,
+			Prefix: y.PKCS11.Prefix.Value,
+		}
+		if y.PKCS11.Slot != nil {
+			s.Slot = y.PKCS11.Slot.Value
+		}
+		keystore = s
+	}
+
+	// Kubernetes Secrets
+	if y.Kubernetes != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		if prefix := y.Kubernetes.Prefix.Value; prefix != "" && !isResourceNamePrefix(prefix) {
+			return nil, fmt.Errorf("kesconf: invalid kubernetes keystore: invalid prefix '%s': must consist of lower case alphanumeric characters, '-' or '.' and start with an alphanumeric character", prefix)
+		}
+		keystore = &KubernetesKeyStore{
+			Endpoint:  y.Kubernetes.Endpoint.Value,
+			Namespace: y.Kubernetes.Namespace.Value,
+			Prefix:    y.Kubernetes.Prefix.Value,
+			TokenFile: y.Kubernetes.TokenFile.Value,
+			CAPath:    y.Kubernetes.TLS.CAPath.Value,
+		}
+	}
+
+	// etcd
+	if y.Etcd != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		endpoints := make([]string, 0, len(y.Etcd.Endpoints))
+		for _, endpoint := range y.Etcd.Endpoints {
+			if e := strings.TrimSpace(endpoint.Value); e != "" {
+				endpoints = append(endpoints, e)
+			}
+		}
+		if len(endpoints) == 0 {
+			return nil, errors.New("kesconf: invalid etcd keystore: no endpoints specified")
+		}
+		if y.Etcd.LeaseTTL.Value < 0 {
+			return nil, errors.New("kesconf: invalid etcd keystore: lease TTL must not be negative")
+		}
+		if y.Etcd.Login.Username.Value == "" && y.Etcd.Login.Password.Value != "" {
+			return nil, errors.New("kesconf: invalid etcd keystore: no username specified")
+		}
+		if y.Etcd.TLS.PrivateKey.Value != "" && y.Etcd.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid etcd keystore: invalid tls config: no TLS certificate provided")
+		}
+		if y.Etcd.TLS.PrivateKey.Value == "" && y.Etcd.TLS.Certificate.Value != "" {
+			return nil, errors.New("kesconf: invalid etcd keystore: invalid tls config: no TLS private key provided")
+		}
+		keystore = &EtcdKeyStore{
+			Endpoints:   endpoints,
+			Prefix:      y.Etcd.Prefix.Value,
+			LeaseTTL:    y.Etcd.LeaseTTL.Value,
+			Username:    y.Etcd.Login.Username.Value,
+			Password:    y.Etcd.Login.Password.Value,
+			PrivateKey:  y.Etcd.TLS.PrivateKey.Value,
+			Certificate: y.Etcd.TLS.Certificate.Value,
+			CAPath:      y.Etcd.TLS.CAPath.Value,
+		}
+	}
+
+	// PostgreSQL / MySQL
+	if y.SQL != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		switch y.SQL.Driver.Value {
+		case "postgres", "mysql":
+		case "":
+			return nil, errors.New("kesconf: invalid sql keystore: no driver specified")
+		default:
+			return nil, fmt.Errorf("kesconf: invalid sql keystore: unsupported driver '%s': must be 'postgres' or 'mysql'", y.SQL.Driver.Value)
+		}
+		if y.SQL.DSN.Value == "" {
+			return nil, errors.New("kesconf: invalid sql keystore: no DSN specified")
+		}
+		if y.SQL.MaxOpenConns.Value < 0 {
+			return nil, errors.New("kesconf: invalid sql keystore: max open connections must not be negative")
+		}
+		if y.SQL.MasterKey.Value != "" && y.SQL.MasterKeyFile.Value != "" {
+			return nil, errors.New("kesconf: invalid sql keystore: master key and master key file are mutually exclusive")
+		}
+		if y.SQL.TLS.PrivateKey.Value != "" && y.SQL.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid sql keystore: invalid tls config: no TLS certificate provided")
+		}
+		if y.SQL.TLS.PrivateKey.Value == "" && y.SQL.TLS.Certificate.Value != "" {
+			return nil, errors.New("kesconf: invalid sql keystore: invalid tls config: no TLS private key provided")
+		}
+		keystore = &SQLKeyStore{
+			Driver:        y.SQL.Driver.Value,
+			DSN:           y.SQL.DSN.Value,
+			Table:         y.SQL.Table.Value,
+			MaxOpenConns:  y.SQL.MaxOpenConns.Value,
+			MasterKey:     y.SQL.MasterKey.Value,
+			MasterKeyFile: y.SQL.MasterKeyFile.Value,
+			PrivateKey:    y.SQL.TLS.PrivateKey.Value,
+			Certificate:   y.SQL.TLS.Certificate.Value,
+			CAPath:        y.SQL.TLS.CAPath.Value,
+		}
+	}
+
+	// OCI Vault
+	if y.OCI != nil && y.OCI.Vault != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		v := y.OCI.Vault
+		if v.Region.Value == "" {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: no region specified")
+		}
+		if v.VaultID.Value == "" {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: no vault specified")
+		}
+		if v.Compartment.Value == "" {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: no compartment specified")
+		}
+		if v.KeyID.Value == "" {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: no master encryption key specified")
+		}
+		if v.Login.APIKey == nil && !v.Login.InstancePrincipal.Value {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: no credentials specified")
+		}
+		if v.Login.APIKey != nil && v.Login.InstancePrincipal.Value {
+			return nil, errors.New("kesconf: invalid OCI vault keystore: API key and instance principal are mutually exclusive")
+		}
+		s := &OCIVaultKeyStore{
+			Region:            v.Region.Value,
+			VaultID:           v.VaultID.Value,
+			CompartmentID:     v.Compartment.Value,
+			KeyID:             v.KeyID.Value,
+			Prefix:            v.Prefix.Value,
+			InstancePrincipal: v.Login.InstancePrincipal.Value,
+		}
+		if apiKey := v.Login.APIKey; apiKey != nil {
+			if apiKey.Tenancy.Value == "" {
+				return nil, errors.New("kesconf: invalid OCI vault keystore: no API key tenancy specified")
+			}
+			if apiKey.User.Value == "" {
+				return nil, errors.New("kesconf: invalid OCI vault keystore: no API key user specified")
+			}
+			if apiKey.Fingerprint.Value == "" {
+				return nil, errors.New("kesconf: invalid OCI vault keystore: no API key fingerprint specified")
+			}
+			if apiKey.PrivateKey.Value == "" {
+				return nil, errors.New("kesconf: invalid OCI vault keystore: no API key private key specified")
+			}
+			s.TenancyID = apiKey.Tenancy.Value
+			s.UserID = apiKey.User.Value
+			s.Fingerprint = apiKey.Fingerprint.Value
+			s.PrivateKeyFile = apiKey.PrivateKey.Value
+		}
+		keystore = s
+	}
+
+	// IBM Key Protect
+	if y.IBM != nil && y.IBM.KeyProtect != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		kp := y.IBM.KeyProtect
+		if kp.Endpoint.Value == "" && kp.Region.Value == "" {
+			return nil, errors.New("kesconf: invalid IBM keyprotect keystore: no endpoint or region specified")
+		}
+		if kp.InstanceID.Value == "" {
+			return nil, errors.New("kesconf: invalid IBM keyprotect keystore: no instance ID specified")
+		}
+		if kp.Login.APIKey.Value == "" {
+			return nil, errors.New("kesconf: invalid IBM keyprotect keystore: no API key specified")
+		}
+		keystore = &IBMKeyProtectKeyStore{
+			Endpoint:    kp.Endpoint.Value,
+			Region:      kp.Region.Value,
+			InstanceID:  kp.InstanceID.Value,
+			KeyRing:     kp.KeyRing.Value,
+			Prefix:      kp.Prefix.Value,
+			APIKey:      kp.Login.APIKey.Value,
+			IAMEndpoint: kp.Login.IAMEndpoint.Value,
+		}
+	}
+
+	// KMIP
+	if y.KMIP != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		if y.KMIP.Endpoint.Value == "" {
+			return nil, errors.New("kesconf: invalid kmip keystore: no endpoint specified")
+		}
+		if y.KMIP.TLS.PrivateKey.Value == "" {
+			return nil, errors.New("kesconf: invalid kmip keystore: invalid tls config: no TLS private key provided")
+		}
+		if y.KMIP.TLS.Certificate.Value == "" {
+			return nil, errors.New("kesconf: invalid kmip keystore: invalid tls config: no TLS certificate provided")
+		}
+		keystore = &KMIPKeyStore{
+			Endpoint:    y.KMIP.Endpoint.Value,
+			Prefix:      y.KMIP.Prefix.Value,
+			Group:       y.KMIP.Group.Value,
+			PrivateKey:  y.KMIP.TLS.PrivateKey.Value,
+			Certificate: y.KMIP.TLS.Certificate.Value,
+			CAPath:      y.KMIP.TLS.CAPath.Value,
+		}
+	}
+
+	// Plugin
+	if y.Plugin != nil {
+		if keystore != nil {
+			return nil, errors.New("kesconf: invalid keystore config: more than once keystore specified")
+		}
+		if y.Plugin.Path.Value == "" {
+			return nil, errors.New("kesconf: invalid plugin keystore: no path specified")
+		}
+		if y.Plugin.StartTimeout.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid plugin keystore: invalid start timeout '%v'", y.Plugin.StartTimeout.Value)
+		}
+		args := make([]string, 0, len(y.Plugin.Args))
+		for _, arg := range y.Plugin.Args {
+			args = append(args, arg.Value)
+		}
+		config := make(map[string]string, len(y.Plugin.Config))
+		for k, v := range y.Plugin.Config {
+			config[k] = v.Value
+		}
+		keystore = &PluginKeyStore{
+			Path:         y.Plugin.Path.Value,
+			Args:         args,
+			Config:       config,
+			StartTimeout: y.Plugin.StartTimeout.Value,
 		}
 	}
 
 	if keystore == nil {
 		return nil, errors.New("kesconf: no keystore specified")
 	}
+
+	if y.FaultInjection != nil {
+		if y.FaultInjection.Latency.Value < 0 || y.FaultInjection.Jitter.Value < 0 {
+			return nil, errors.New("kesconf: invalid fault injection config: latency and jitter must not be negative")
+		}
+		if rate := y.FaultInjection.ErrorRate.Value; rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("kesconf: invalid fault injection config: invalid error rate '%v'", rate)
+		}
+		keystore = &FaultKeyStore{
+			KeyStore:  keystore,
+			Latency:   y.FaultInjection.Latency.Value,
+			Jitter:    y.FaultInjection.Jitter.Value,
+			ErrorRate: y.FaultInjection.ErrorRate.Value,
+		}
+	}
+	if y.Compress.Value {
+		keystore = &CompressKeyStore{KeyStore: keystore}
+	}
+	if y.Retry != nil {
+		if y.Retry.Attempts.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid retry config: invalid number of attempts '%d'", y.Retry.Attempts.Value)
+		}
+		switch backoff := strings.ToLower(y.Retry.Backoff.Value); backoff {
+		case "", "constant", "linear", "exponential":
+		default:
+			return nil, fmt.Errorf("kesconf: invalid retry config: invalid backoff strategy '%s'", y.Retry.Backoff.Value)
+		}
+		if y.Retry.Delay.Value < 0 || y.Retry.MaxDelay.Value < 0 || y.Retry.Timeout.Value < 0 {
+			return nil, errors.New("kesconf: invalid retry config: delays and timeout must not be negative")
+		}
+		keystore = &RetryKeyStore{
+			KeyStore: keystore,
+			Attempts: y.Retry.Attempts.Value,
+			Backoff:  strings.ToLower(y.Retry.Backoff.Value),
+			Delay:    y.Retry.Delay.Value,
+			MaxDelay: y.Retry.MaxDelay.Value,
+			Timeout:  y.Retry.Timeout.Value,
+		}
+	}
+	if y.CircuitBreaker != nil {
+		if y.CircuitBreaker.Threshold.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid circuit breaker config: invalid threshold '%d'", y.CircuitBreaker.Threshold.Value)
+		}
+		if y.CircuitBreaker.Interval.Value < 0 {
+			return nil, fmt.Errorf("kesconf: invalid circuit breaker config: invalid interval '%v'", y.CircuitBreaker.Interval.Value)
+		}
+		keystore = &CircuitBreakerKeyStore{
+			KeyStore:  keystore,
+			Threshold: y.CircuitBreaker.Threshold.Value,
+			Interval:  y.CircuitBreaker.Interval.Value,
+		}
+	}
+
+	if y.Secondary != nil {
+		if y.Secondary.Secondary != nil {
+			return nil, errors.New("kesconf: invalid secondary keystore: secondary keystore must not have a secondary keystore")
+		}
+		secondary, err := ymlToKeyStore(y.Secondary)
+		if err != nil {
+			return nil, err
+		}
+		failover := &FailoverKeyStore{
+			Primary:   keystore,
+			Secondary: secondary,
+		}
+		if y.Failover != nil {
+			if y.Failover.Threshold.Value < 0 {
+				return nil, fmt.Errorf("kesconf: invalid failover config: invalid threshold '%d'", y.Failover.Threshold.Value)
+			}
+			if y.Failover.Interval.Value < 0 {
+				return nil, fmt.Errorf("kesconf: invalid failover config: invalid interval '%v'", y.Failover.Interval.Value)
+			}
+			failover.Threshold = y.Failover.Threshold.Value
+			failover.Interval = y.Failover.Interval.Value
+		}
+		keystore = failover
+	} else if y.Failover != nil {
+		return nil, errors.New("kesconf: invalid failover config: no secondary keystore specified")
+	}
 	return keystore, nil
 }
 
@@ -704,6 +2464,20 @@ func (r *env[T]) UnmarshalYAML(node *yaml.Node) error {
 	return nil
 }
 
+// isResourceNamePrefix reports whether s is a valid prefix
+// for Kubernetes resource names.
+func isResourceNamePrefix(s string) bool {
+	for i, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+		case (r == '-' || r == '.') && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 func parseLogLevel(s string) (slog.Level, error) {
 	const (
 		LevelDebug = "DEBUG"
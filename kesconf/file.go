@@ -6,32 +6,68 @@ package kesconf
 
 import (
 	"context"
+	"crypto"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/minio/kes"
+	"github.com/minio/kes/internal/acmedns"
+	"github.com/minio/kes/internal/auditlog"
 	"github.com/minio/kes/internal/https"
+	"github.com/minio/kes/internal/keystore"
 	"github.com/minio/kes/internal/keystore/aws"
 	"github.com/minio/kes/internal/keystore/azure"
+	"github.com/minio/kes/internal/keystore/breaker"
+	"github.com/minio/kes/internal/keystore/compress"
 	"github.com/minio/kes/internal/keystore/entrust"
+	"github.com/minio/kes/internal/keystore/etcd"
+	"github.com/minio/kes/internal/keystore/failover"
+	"github.com/minio/kes/internal/keystore/fault"
 	"github.com/minio/kes/internal/keystore/fortanix"
 	"github.com/minio/kes/internal/keystore/fs"
 	"github.com/minio/kes/internal/keystore/gcp"
 	"github.com/minio/kes/internal/keystore/gemalto"
+	"github.com/minio/kes/internal/keystore/ibm"
+	"github.com/minio/kes/internal/keystore/kmip"
+	"github.com/minio/kes/internal/keystore/kubernetes"
+	"github.com/minio/kes/internal/keystore/oci"
+	"github.com/minio/kes/internal/keystore/pkcs11"
+	"github.com/minio/kes/internal/keystore/plugin"
+	"github.com/minio/kes/internal/keystore/retry"
+	"github.com/minio/kes/internal/keystore/sql"
 	"github.com/minio/kes/internal/keystore/vault"
+	"github.com/minio/kes/internal/keystore/writeback"
+	"github.com/minio/kes/internal/logsink"
+	"github.com/minio/kes/internal/notify"
+	"github.com/minio/kes/internal/tpm"
 	kesdk "github.com/minio/kms-go/kes"
+	"github.com/spiffe/go-spiffe/v2/workloadapi"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	yaml "gopkg.in/yaml.v3"
 )
 
 // ReadFile opens the given file and reads the KES configuration
-// from it by calling ReadFrom.
+// from it. In contrast to ReadFrom, relative include paths are
+// resolved relative to the file's directory.
 func ReadFile(filename string) (*File, error) {
 	f, err := os.Open(filename)
 	if err != nil {
@@ -39,7 +75,7 @@ func ReadFile(filename string) (*File, error) {
 	}
 	defer f.Close() // make sure to close file in case of panic
 
-	file, err := ReadFrom(f)
+	file, err := readFrom(f, filepath.Dir(filename))
 	if cErr := f.Close(); err == nil {
 		err = cErr
 	}
@@ -48,7 +84,13 @@ func ReadFile(filename string) (*File, error) {
 
 // ReadFrom parses and returns a new KES server configuration file
 // from r.
-func ReadFrom(r io.Reader) (*File, error) {
+//
+// It merges the files referenced in the config's include section,
+// resolving relative paths relative to the current working directory,
+// and replaces env. variable references, like '${VAR}', in all values.
+func ReadFrom(r io.Reader) (*File, error) { return readFrom(r, "") }
+
+func readFrom(r io.Reader, dir string) (*File, error) {
 	var node yaml.Node
 	if err := yaml.NewDecoder(r).Decode(&node); err != nil {
 		return nil, err
@@ -62,6 +104,12 @@ func ReadFrom(r io.Reader) (*File, error) {
 	if version != "" && version != Version {
 		return nil, fmt.Errorf("edge: invalid server config version '%s'", version)
 	}
+	if err = resolveIncludes(&node, dir); err != nil {
+		return nil, err
+	}
+	if err = expandEnvNodes(&node); err != nil {
+		return nil, err
+	}
 
 	var y ymlFile
 	if err := node.Decode(&y); err != nil {
@@ -90,18 +138,119 @@ type File struct {
 	// Admin is the KES server admin identity.
 	Admin kes.Identity
 
+	// FIPS restricts the KES server to FIPS 140
+	// approved cryptographic algorithms.
+	FIPS bool
+
 	// TLS contains the KES server TLS configuration.
 	TLS *TLSConfig
 
+	// ProxyProtocol contains the KES server PROXY protocol
+	// configuration. If nil, the PROXY protocol is disabled.
+	ProxyProtocol *ProxyProtocolConfig
+
+	// HTTP contains the KES server HTTP connection configuration.
+	// If nil, the server uses its defaults.
+	HTTP *HTTPConfig
+
+	// GRPC contains the KES server gRPC API configuration.
+	// If nil, the gRPC API is disabled.
+	GRPC *GRPCConfig
+
 	// Cache contains the KES server cache configuration.
 	Cache *CacheConfig
 
+	// Memory contains the KES server memory hardening
+	// configuration. If nil, memory is locked if possible
+	// and cached keys are not zeroized.
+	Memory *MemoryConfig
+
 	// Log contains the KES server logging configuration.
 	Log *LogConfig
 
 	// API contains the KES server API configuration.
 	API *APIConfig
 
+	// Names contains the rules for valid key, policy and
+	// identity names. If nil, the KES server defaults apply.
+	Names *NameConfig
+
+	// Telemetry contains the KES server telemetry configuration.
+	// If nil, telemetry is disabled.
+	Telemetry *TelemetryConfig
+
+	// KeyUsage contains the KES server key usage tracking
+	// configuration. If nil, key usage is not tracked.
+	KeyUsage *KeyUsageConfig
+
+	// PolicyStore contains the KES server policy store
+	// configuration. If nil, policies created and assigned
+	// via the API are lost when the server restarts.
+	PolicyStore *PolicyStoreConfig
+
+	// OIDC contains the configuration for authenticating
+	// clients with JWT bearer tokens issued by an OpenID
+	// Connect provider. If nil, clients have to authenticate
+	// with a client certificate.
+	OIDC *OIDCConfig
+
+	// SoftDelete contains the KES server soft-delete
+	// configuration. If nil, keys are deleted immediately.
+	SoftDelete *SoftDeleteConfig
+
+	// Approval contains the KES server dual-control
+	// configuration. If nil, destructive operations are
+	// executed without approval.
+	Approval *ApprovalConfig
+
+	// Notify contains the targets the KES server publishes
+	// key, policy and identity lifecycle events to.
+	Notify NotifyConfig
+
+	// Replication contains the configuration for replicating
+	// keys and identities to a secondary KES cluster. If nil,
+	// nothing is replicated.
+	Replication *ReplicationConfig
+
+	// Cluster contains the configuration for running the KES
+	// server as member of a KES cluster. If nil, the server
+	// runs standalone.
+	Cluster *ClusterConfig
+
+	// Replica contains the configuration for running the KES
+	// server as read replica of a primary KES server. If nil,
+	// the server uses its own KeyStore.
+	Replica *ReplicaConfig
+
+	// ReplicaIdentities are the identities of the read replicas
+	// that may fetch keys from this server. If empty, no replica
+	// can fetch keys.
+	ReplicaIdentities []kes.Identity
+
+	// Seal contains the configuration for encrypting the
+	// KeyStore with a root key split into shares. If nil,
+	// the server is not sealed.
+	Seal *SealConfig
+
+	// Otel contains the OpenTelemetry tracing configuration.
+	// If nil, tracing is disabled.
+	Otel *OtelConfig
+
+	// MetricsPush contains the configuration for pushing
+	// metrics to a Prometheus Pushgateway or remote write
+	// endpoint. If nil, metrics are not pushed.
+	MetricsPush *MetricsPushConfig
+
+	// MetricsLabel is either empty, "identity" or "policy". If
+	// set, the per-API request metrics are also labeled by the
+	// identity or the policy that sent the request.
+	MetricsLabel string
+
+	// MetricsListener contains the configuration of a separate
+	// listener for the metrics and health APIs. If nil, these
+	// APIs are only served by the main listener.
+	MetricsListener *MetricsListenerConfig
+
 	// Policies contains the KES server policy definitions
 	// and statical identity assignments.
 	Policies map[string]Policy
@@ -112,7 +261,7 @@ type File struct {
 
 	// KeyStore contains the KES server keystore configuration.
 	// The KeyStore manages the keys used by the KES server for
-	// encryption and decryption.
+	// encryption and decryption. It is nil for read replicas.
 	KeyStore KeyStore
 }
 
@@ -123,6 +272,26 @@ func (f *File) TLSConfig() (*tls.Config, error) {
 		return nil, nil
 	}
 
+	var rootCAs *x509.CertPool
+	if f.TLS.CAPath != "" {
+		var err error
+		rootCAs, err = https.CertPoolFromFile(f.TLS.CAPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read TLS CA certificates: %v", err)
+		}
+	}
+	// The certificate is obtained by the server via ACME
+	// or from the SPIFFE workload API.
+	if f.TLS.ACME != nil || (f.TLS.SPIFFE != nil && f.TLS.SPIFFE.WorkloadAPI != "") {
+		return &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: f.TLS.ClientAuth,
+			NextProtos: []string{"h2", "http/1.1"},
+			RootCAs:    rootCAs,
+			ClientCAs:  rootCAs,
+		}, nil
+	}
+
 	certificate, err := https.CertificateFromFile(f.TLS.Certificate, f.TLS.PrivateKey, f.TLS.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read TLS certificate: %v", err)
@@ -135,15 +304,6 @@ func (f *File) TLSConfig() (*tls.Config, error) {
 			return nil, fmt.Errorf("invalid TLS certificate: certificate does not contain any DNS or IP address as SAN")
 		}
 	}
-
-	var rootCAs *x509.CertPool
-	if f.TLS.CAPath != "" {
-		rootCAs, err = https.CertPoolFromFile(f.TLS.CAPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read TLS CA certificates: %v", err)
-		}
-	}
-
 	return &tls.Config{
 		MinVersion:   tls.VersionTLS12,
 		ClientAuth:   f.TLS.ClientAuth,
@@ -160,6 +320,7 @@ func (f *File) TLSConfig() (*tls.Config, error) {
 func (f *File) Config(ctx context.Context) (*kes.Config, error) {
 	conf := &kes.Config{
 		Admin: f.Admin,
+		FIPS:  f.FIPS,
 	}
 
 	if f.TLS != nil {
@@ -168,13 +329,304 @@ func (f *File) Config(ctx context.Context) (*kes.Config, error) {
 			return nil, err
 		}
 		conf.TLS = tlsConf
+
+		if f.TLS.CRLFile != "" || f.TLS.OCSP {
+			conf.Revocation = &kes.RevocationConfig{
+				CRLFile:    f.TLS.CRLFile,
+				CRLReload:  f.TLS.CRLReload,
+				OCSP:       f.TLS.OCSP,
+				OCSPStrict: f.TLS.OCSPStrict,
+			}
+		}
+		if len(f.TLS.Proxies) > 0 {
+			conf.TLSProxy = &kes.TLSProxyConfig{
+				Identities: slices.Clone(f.TLS.Proxies),
+				CertHeader: f.TLS.ForwardCertHeader,
+			}
+		}
+		if acme := f.TLS.ACME; acme != nil {
+			conf.ACME = &kes.ACMEConfig{
+				Directory:   acme.Directory,
+				Email:       acme.Email,
+				Domains:     slices.Clone(acme.Domains),
+				Challenge:   kes.ACMEChallenge(acme.Challenge),
+				HTTPAddr:    acme.HTTPAddr,
+				RenewBefore: acme.RenewBefore,
+			}
+			switch {
+			case acme.DNSExec != "":
+				conf.ACME.DNS = &acmedns.Exec{Command: acme.DNSExec}
+			case acme.DNSWebhook != "":
+				conf.ACME.DNS = &acmedns.Webhook{Endpoint: acme.DNSWebhook}
+			}
+		}
 	}
 
 	if f.Cache != nil {
 		conf.Cache = &kes.CacheConfig{
-			Expiry:        f.Cache.Expiry,
-			ExpiryUnused:  f.Cache.ExpiryUnused,
-			ExpiryOffline: f.Cache.ExpiryOffline,
+			Expiry:         f.Cache.Expiry,
+			ExpiryUnused:   f.Cache.ExpiryUnused,
+			ExpiryOffline:  f.Cache.ExpiryOffline,
+			Prewarm:        f.Cache.Prewarm,
+			PrewarmPrefix:  f.Cache.PrewarmPrefix,
+			Preload:        f.Cache.Preload,
+			PreloadFile:    f.Cache.PreloadFile,
+			OfflinePolicy:  f.Cache.OfflinePolicy,
+			MaxEntries:     f.Cache.MaxEntries,
+			EvictionPolicy: f.Cache.EvictionPolicy,
+			Pin:            f.Cache.Pin,
+		}
+	}
+	if f.Memory != nil && f.Memory.Zeroize {
+		if conf.Cache == nil {
+			conf.Cache = &kes.CacheConfig{}
+		}
+		conf.Cache.Zeroize = true
+	}
+
+	if f.Names != nil {
+		conf.Names = &kes.NameConfig{
+			MaxLength: f.Names.MaxLength,
+			Charset:   f.Names.Charset,
+		}
+	}
+
+	if f.Telemetry != nil {
+		conf.Telemetry = &kes.TelemetryConfig{
+			Endpoint: f.Telemetry.Endpoint,
+			Interval: f.Telemetry.Interval,
+		}
+	}
+
+	if f.KeyUsage != nil {
+		conf.KeyUsage = &kes.KeyUsageConfig{
+			Filename: f.KeyUsage.Filename,
+			Interval: f.KeyUsage.Interval,
+		}
+	}
+
+	if f.PolicyStore != nil {
+		conf.PolicyStore = &kes.PolicyStoreConfig{
+			Filename: f.PolicyStore.Filename,
+		}
+	}
+
+	if f.OIDC != nil {
+		policies := make(map[string]map[string]string, len(f.OIDC.Policies))
+		for name, claims := range f.OIDC.Policies {
+			policies[name] = maps.Clone(claims)
+		}
+		conf.OIDC = &kes.OIDCConfig{
+			Issuer:        f.OIDC.Issuer,
+			Audience:      f.OIDC.Audience,
+			IdentityClaim: f.OIDC.IdentityClaim,
+			Policies:      policies,
+		}
+	}
+
+	if f.SoftDelete != nil {
+		conf.SoftDelete = &kes.SoftDeleteConfig{
+			RecoveryWindow: f.SoftDelete.RecoveryWindow,
+		}
+	}
+
+	if f.Approval != nil {
+		conf.Approval = &kes.ApprovalConfig{
+			Window: f.Approval.Window,
+		}
+	}
+
+	if f.HTTP != nil {
+		conf.HTTP = &kes.HTTPConfig{
+			ReadHeaderTimeout:    f.HTTP.ReadHeaderTimeout,
+			ReadTimeout:          f.HTTP.ReadTimeout,
+			WriteTimeout:         f.HTTP.WriteTimeout,
+			IdleTimeout:          f.HTTP.IdleTimeout,
+			MaxConcurrentStreams: f.HTTP.MaxConcurrentStreams,
+			DisableHTTP2:         f.HTTP.DisableHTTP2,
+		}
+	}
+
+	if f.GRPC != nil {
+		conf.GRPC = &kes.GRPCConfig{
+			Addr: f.GRPC.Addr,
+		}
+	}
+
+	if f.ProxyProtocol != nil {
+		conf.ProxyProtocol = &kes.ProxyProtocolConfig{
+			TrustedProxies: slices.Clone(f.ProxyProtocol.TrustedProxies),
+		}
+	}
+
+	if f.Log != nil {
+		errorLog, auditLog, err := f.Log.handlers()
+		if err != nil {
+			return nil, err
+		}
+		if errorLog != nil {
+			conf.ErrorLog = errorLog
+		}
+		if auditLog != nil {
+			conf.AuditLog = &kes.AuditLogHandler{Handler: auditLog}
+		}
+	}
+	if f.Log != nil && f.Log.Kafka != nil {
+		kafka, err := f.Log.Kafka.target()
+		if err != nil {
+			return nil, err
+		}
+		conf.AuditTargets = append(conf.AuditTargets, kafka)
+	}
+
+	notifications, err := f.Notify.targets()
+	if err != nil {
+		return nil, err
+	}
+	conf.Notifications = notifications
+
+	if f.Replication != nil {
+		certificate, err := https.CertificateFromFile(f.Replication.Certificate, f.Replication.PrivateKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to read replication TLS certificate: %v", err)
+		}
+		var rootCAs *x509.CertPool
+		if f.Replication.CAPath != "" {
+			if rootCAs, err = https.CertPoolFromFile(f.Replication.CAPath); err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read replication CA certificates: %v", err)
+			}
+		}
+		conf.Replication = &kes.ReplicationConfig{
+			Endpoint: f.Replication.Endpoint,
+			KEK:      f.Replication.KEK,
+			Interval: f.Replication.Interval,
+			TLS: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      rootCAs,
+			},
+		}
+	}
+
+	if f.Cluster != nil {
+		certificate, err := https.CertificateFromFile(f.Cluster.Certificate, f.Cluster.PrivateKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to read cluster TLS certificate: %v", err)
+		}
+		var rootCAs *x509.CertPool
+		if f.Cluster.CAPath != "" {
+			if rootCAs, err = https.CertPoolFromFile(f.Cluster.CAPath); err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read cluster CA certificates: %v", err)
+			}
+		}
+		conf.Cluster = &kes.ClusterConfig{
+			Addr:  f.Cluster.Addr,
+			Dir:   f.Cluster.Dir,
+			Peers: slices.Clone(f.Cluster.Peers),
+			Join:  f.Cluster.Join,
+			TLS: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      rootCAs,
+			},
+			Identities: slices.Clone(f.Cluster.Identities),
+			Key:        slices.Clone(f.Cluster.Key),
+		}
+	}
+
+	conf.ReplicaIdentities = slices.Clone(f.ReplicaIdentities)
+	if f.Replica != nil {
+		certificate, err := https.CertificateFromFile(f.Replica.Certificate, f.Replica.PrivateKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to read replica TLS certificate: %v", err)
+		}
+		var rootCAs *x509.CertPool
+		if f.Replica.CAPath != "" {
+			if rootCAs, err = https.CertPoolFromFile(f.Replica.CAPath); err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read replica CA certificates: %v", err)
+			}
+		}
+		conf.Replica = &kes.ReplicaConfig{
+			Endpoint: f.Replica.Endpoint,
+			Interval: f.Replica.Interval,
+			TLS: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{certificate},
+				RootCAs:      rootCAs,
+			},
+		}
+	}
+
+	if f.Seal != nil {
+		conf.Seal = &kes.SealConfig{
+			Threshold:   f.Seal.Threshold,
+			RootKeyHash: slices.Clone(f.Seal.RootKeyHash),
+		}
+	}
+
+	conf.MetricsLabel = kes.MetricsLabel(f.MetricsLabel)
+	if f.MetricsListener != nil {
+		conf.MetricsListener = &kes.MetricsListenerConfig{
+			Addr: f.MetricsListener.Addr,
+			TLS:  f.MetricsListener.TLS,
+		}
+	}
+	if f.MetricsPush != nil {
+		var certificates []tls.Certificate
+		if f.MetricsPush.Certificate != "" || f.MetricsPush.PrivateKey != "" {
+			certificate, err := https.CertificateFromFile(f.MetricsPush.Certificate, f.MetricsPush.PrivateKey, "")
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read metrics push TLS certificate: %v", err)
+			}
+			certificates = append(certificates, certificate)
+		}
+		var rootCAs *x509.CertPool
+		if f.MetricsPush.CAPath != "" {
+			if rootCAs, err = https.CertPoolFromFile(f.MetricsPush.CAPath); err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read metrics push CA certificates: %v", err)
+			}
+		}
+		conf.MetricsPush = &kes.MetricsPushConfig{
+			Endpoint: f.MetricsPush.Endpoint,
+			Protocol: kes.MetricsPushProtocol(f.MetricsPush.Protocol),
+			Job:      f.MetricsPush.Job,
+			Interval: f.MetricsPush.Interval,
+			Username: f.MetricsPush.Username,
+			Password: f.MetricsPush.Password,
+			TLS: &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: certificates,
+				RootCAs:      rootCAs,
+			},
+		}
+	}
+
+	if f.Log != nil && f.Log.AuditCheckpoint > 0 {
+		var signer crypto.Signer
+		if f.Log.AuditCheckpointKey != "" {
+			if signer, err = readSigningKey(f.Log.AuditCheckpointKey); err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read audit checkpoint key: %v", err)
+			}
+		} else {
+			if conf.TLS == nil || len(conf.TLS.Certificates) == 0 {
+				return nil, errors.New("kesconf: audit checkpoints require a TLS private key")
+			}
+			var ok bool
+			if signer, ok = conf.TLS.Certificates[0].PrivateKey.(crypto.Signer); !ok {
+				return nil, errors.New("kesconf: audit checkpoints require a TLS private key that can sign")
+			}
+		}
+		conf.AuditCheckpoint = &kes.AuditCheckpointConfig{
+			Interval:   f.Log.AuditCheckpoint,
+			Signer:     signer,
+			MerkleTree: f.Log.AuditMerkleTree,
+		}
+	}
+
+	if f.Log != nil && f.Log.AuditStorePath != "" {
+		conf.AuditStore = &kes.AuditStoreConfig{
+			Dir:       f.Log.AuditStorePath,
+			Retention: f.Log.AuditRetention,
 		}
 	}
 
@@ -195,6 +647,12 @@ func (f *File) Config(ctx context.Context) (*kes.Config, error) {
 			p := kes.Policy{
 				Allow:      make(map[string]kesdk.Rule, len(policy.Allow)),
 				Deny:       make(map[string]kesdk.Rule, len(policy.Deny)),
+				Keys:       slices.Clone(policy.Keys),
+				Conditions: policy.Conditions,
+				RateLimit:  policy.RateLimit,
+				Context:    policy.Context,
+				SSH:        policy.SSH,
+				Cert:       policy.Cert,
 				Identities: slices.Clone(policy.Identities),
 			}
 			for _, pattern := range policy.Allow {
@@ -213,8 +671,60 @@ func (f *File) Config(ctx context.Context) (*kes.Config, error) {
 		if err != nil {
 			return nil, err
 		}
+		if f.Cache != nil && f.Cache.Policy == WriteBack {
+			wb, err := writeback.NewStore(keystore, &writeback.Config{
+				Dir:      f.Cache.WriteBackJournal,
+				Interval: f.Cache.WriteBackInterval,
+			})
+			if err != nil {
+				keystore.Close()
+				return nil, err
+			}
+			keystore = wb
+		}
 		conf.Keys = keystore
 	}
+
+	var spiffeSource *workloadapi.X509Source
+	if f.TLS != nil && f.TLS.SPIFFE != nil {
+		conf.SPIFFE = &kes.SPIFFEConfig{
+			TrustDomains: slices.Clone(f.TLS.SPIFFE.TrustDomains),
+		}
+		if f.TLS.SPIFFE.WorkloadAPI != "" {
+			// The source waits for the initial SVID and keeps retrying
+			// while the workload API is not reachable. Hence, limit
+			// how long to wait.
+			const Timeout = 30 * time.Second
+			sourceCtx, cancel := context.WithTimeout(ctx, Timeout)
+			defer cancel()
+
+			var err error
+			spiffeSource, err = workloadapi.NewX509Source(sourceCtx, workloadapi.WithClientOptions(
+				workloadapi.WithAddr(f.TLS.SPIFFE.WorkloadAPI),
+			))
+			if err != nil {
+				if conf.Keys != nil {
+					conf.Keys.Close()
+				}
+				return nil, fmt.Errorf("failed to connect to SPIFFE workload API: %v", err)
+			}
+			conf.SPIFFE.Source = spiffeSource
+		}
+	}
+
+	if f.Otel != nil {
+		provider, err := f.Otel.tracerProvider(ctx)
+		if err != nil {
+			if conf.Keys != nil {
+				conf.Keys.Close()
+			}
+			if spiffeSource != nil {
+				spiffeSource.Close()
+			}
+			return nil, err
+		}
+		conf.TracerProvider = provider
+	}
 	return conf, nil
 }
 
@@ -246,6 +756,32 @@ type TLSConfig struct {
 	// certificates.
 	CAPath string
 
+	// Reload is the time between two checks whether the
+	// PrivateKey, Certificate or CAPath has changed. Once
+	// changed, the KES server reloads its TLS configuration.
+	// If 0, defaults to 5 seconds.
+	Reload time.Duration
+
+	// CRLFile is an optional path to a certificate revocation
+	// list. Client certificates listed in it are rejected. The
+	// KES server reloads the file when it changes.
+	//
+	// Revocation checks require that ClientAuth verifies client
+	// certificates.
+	CRLFile string
+
+	// CRLReload is the time between two checks whether the
+	// CRLFile has changed. If 0, defaults to 1 minute.
+	CRLReload time.Duration
+
+	// OCSP enables checking the revocation status of client
+	// certificates via the OCSP responder listed in them.
+	OCSP bool
+
+	// OCSPStrict rejects client certificates whose revocation
+	// status cannot be determined via OCSP.
+	OCSPStrict bool
+
 	// Proxies contains a list of TLS proxy identities.
 	// The KES identity of any TLS/HTTPS proxy sitting directly
 	// in-front of KES has to be included in this list. A KES
@@ -257,6 +793,117 @@ type TLSConfig struct {
 	// TLS / HTTPS proxy to forward the actual client certificate
 	// to KES.
 	ForwardCertHeader string
+
+	// ACME is an optional configuration for obtaining the KES
+	// server's TLS certificate via ACME. If set, PrivateKey and
+	// Certificate must be empty.
+	ACME *ACMEConfig
+
+	// SPIFFE is an optional configuration for accepting SPIFFE
+	// X.509 SVIDs as client identities. If it specifies a
+	// workload API, PrivateKey and Certificate must be empty.
+	SPIFFE *SPIFFEConfig
+}
+
+// SPIFFEConfig is a structure that holds the SPIFFE configuration
+// of a KES server.
+type SPIFFEConfig struct {
+	// TrustDomains are the SPIFFE trust domains whose X.509 SVIDs
+	// are accepted as client identities. The identity of such a
+	// client is its SPIFFE ID instead of its public key hash.
+	TrustDomains []string
+
+	// WorkloadAPI is the optional address of the SPIFFE workload
+	// API, e.g. "unix:///run/spire/sockets/agent.sock". If set,
+	// the KES server fetches its X.509 SVID, used as its TLS
+	// certificate, and the trust bundles from it.
+	WorkloadAPI string
+}
+
+// ACMEConfig is a structure that holds the ACME configuration
+// for obtaining and renewing a KES server's TLS certificate,
+// e.g. from Let's Encrypt. The certificate is stored in the
+// KES server's keystore.
+type ACMEConfig struct {
+	// Directory is the ACME directory URL of the certificate
+	// authority. If empty, defaults to Let's Encrypt.
+	Directory string
+
+	// Email is an optional contact email address for the
+	// ACME account.
+	Email string
+
+	// Domains are the DNS names the certificate is issued for.
+	Domains []string
+
+	// Challenge is either "http-01" or "dns-01". If empty,
+	// defaults to "http-01".
+	Challenge string
+
+	// HTTPAddr is the address the KES server listens on for
+	// HTTP-01 challenges. If empty, defaults to ":80".
+	HTTPAddr string
+
+	// DNSExec is the path of an executable that creates and
+	// removes the DNS TXT records for DNS-01 challenges.
+	DNSExec string
+
+	// DNSWebhook is the URL of a webhook that creates and
+	// removes the DNS TXT records for DNS-01 challenges.
+	DNSWebhook string
+
+	// RenewBefore is the time period before the certificate
+	// expires at which it gets renewed. If 0, defaults to
+	// 30 days.
+	RenewBefore time.Duration
+}
+
+// ProxyProtocolConfig is a structure that holds the PROXY
+// protocol configuration of a KES server.
+type ProxyProtocolConfig struct {
+	// TrustedProxies are the networks of the L4 proxies and
+	// load balancers that send a PROXY protocol header.
+	TrustedProxies []netip.Prefix
+}
+
+// GRPCConfig is a structure that holds the gRPC API
+// configuration of a KES server.
+type GRPCConfig struct {
+	// Addr is the network address the gRPC API listens on.
+	Addr string
+}
+
+// HTTPConfig is a structure that holds the HTTP connection
+// configuration of a KES server.
+type HTTPConfig struct {
+	// ReadHeaderTimeout is the max. time the server waits for
+	// the headers of a request. If 0, defaults to 5 seconds.
+	ReadHeaderTimeout time.Duration
+
+	// ReadTimeout is the max. time the server waits for an
+	// entire request. If 0, there is no timeout.
+	ReadTimeout time.Duration
+
+	// WriteTimeout is the max. time the server takes to write
+	// a response. If 0, there is no timeout.
+	WriteTimeout time.Duration
+
+	// IdleTimeout is the max. time an idle keep-alive connection
+	// is kept open. If 0, defaults to 90 seconds.
+	IdleTimeout time.Duration
+
+	// ShutdownTimeout is the max. time the server waits for
+	// active requests to finish when shutting down. It is not
+	// part of the kes.HTTPConfig but sets the ShutdownTimeout
+	// of the kes.Server. If 0, defaults to 1 second.
+	ShutdownTimeout time.Duration
+
+	// MaxConcurrentStreams is the max. number of concurrent
+	// requests per HTTP/2 connection. If 0, defaults to 250.
+	MaxConcurrentStreams uint32
+
+	// DisableHTTP2 disables HTTP/2.
+	DisableHTTP2 bool
 }
 
 // CacheConfig is a structure that holds the Cache configuration
@@ -284,6 +931,112 @@ type CacheConfig struct {
 	// available. As long as the keystore is available, the regular
 	// cache expiry periods apply.
 	ExpiryOffline time.Duration
+
+	// Policy controls whether new keys are written to the keystore
+	// before (WriteThrough) or after (WriteBack) acknowledging the
+	// request. Defaults to WriteThrough.
+	Policy CachePolicy
+
+	// WriteBackJournal is the directory of the journal that keeps
+	// keys, which have not been written to the keystore yet, durable.
+	// It must be set when using the WriteBack policy.
+	//
+	// The journal contains key material and must be protected
+	// like the keystore itself.
+	WriteBackJournal string
+
+	// WriteBackInterval is the interval in which pending keys
+	// are written to the keystore. Defaults to 1s.
+	WriteBackInterval time.Duration
+
+	// Prewarm controls whether the KES server fetches all keys
+	// from the keystore into the cache on startup, such that the
+	// first requests after a restart do not all hit the keystore.
+	Prewarm bool
+
+	// PrewarmPrefix limits prewarming to keys that start
+	// with the prefix. If empty, all keys are fetched.
+	PrewarmPrefix string
+
+	// Preload contains glob patterns of key names. Keys matching
+	// any pattern are fetched into the cache on startup. The KES
+	// server reports that it is not ready until all of them have
+	// been fetched.
+	Preload []string
+
+	// PreloadFile is a file to which the KES server writes the
+	// names of recently used keys. These keys are preloaded on
+	// startup, like keys matching Preload.
+	PreloadFile string
+
+	// OfflinePolicy controls which requests the KES server
+	// serves while the keystore is unreachable. Either serve
+	// stale cached keys for up to ExpiryOffline, only decrypt
+	// or fail closed.
+	OfflinePolicy kes.OfflinePolicy
+
+	// MaxEntries limits the number of keys in the cache.
+	// If zero, the cache is unbounded.
+	MaxEntries int
+
+	// EvictionPolicy controls whether the least recently used
+	// key gets evicted once the cache holds MaxEntries keys.
+	EvictionPolicy kes.EvictionPolicy
+
+	// Pin contains glob patterns of key names. Keys matching
+	// any pattern are never evicted because the cache is full
+	// or the key hasn't been used recently.
+	Pin []string
+}
+
+// CachePolicy is a cache write policy.
+type CachePolicy string
+
+// Supported cache write policies.
+const (
+	// WriteThrough writes new keys to the keystore
+	// before acknowledging the request.
+	WriteThrough CachePolicy = "write-through"
+
+	// WriteBack persists new keys in a local journal
+	// and acknowledges the request. It writes keys to
+	// the keystore in the background.
+	//
+	// WriteBack reduces the latency of creating keys
+	// when the keystore is slow, e.g. for workloads
+	// that create many short-lived keys.
+	WriteBack CachePolicy = "write-back"
+)
+
+// MemoryLock controls whether the KES server locks its memory
+// such that it is not swapped to disk.
+type MemoryLock uint
+
+const (
+	// MemoryLockAuto locks the memory if the OS supports
+	// it and the KES server has sufficient privileges.
+	MemoryLockAuto MemoryLock = iota
+
+	// MemoryLockRequired locks the memory. The KES server
+	// fails to start if its memory cannot be locked.
+	MemoryLockRequired
+
+	// MemoryLockOff does not lock the memory.
+	MemoryLockOff
+)
+
+// MemoryConfig is a structure that holds the memory hardening
+// configuration for a KES server.
+type MemoryConfig struct {
+	// Lock controls whether the KES server locks its memory
+	// such that keys are not swapped to disk. Memory locking
+	// is only supported on Linux.
+	Lock MemoryLock
+
+	// Zeroize controls whether the KES server overwrites the
+	// key material of cached keys once they are evicted from
+	// the cache or the server shuts down.
+	Zeroize bool
 }
 
 // LogConfig is a structure that holds the logging configuration
@@ -296,98 +1049,1239 @@ type LogConfig struct {
 	// Audit determines whether the KES server logs audit events to STDOUT.
 	// It does not en/disable audit logging in general.
 	AuditLevel slog.Level
-}
 
-// APIConfig is a structure that holds the API configuration
-// for a KES server.
-type APIConfig struct {
-	// Paths contains a set of API paths and there
-	// API configuration.
-	Paths map[string]APIPathConfig
+	// AuditCheckpoint is the interval in which the KES server emits
+	// signed audit checkpoints. If <= 0, no checkpoints are emitted.
+	AuditCheckpoint time.Duration
+
+	// AuditCheckpointKey is the path to a PEM-encoded private key
+	// the KES server signs audit checkpoints with. If empty, the
+	// server's TLS private key is used.
+	AuditCheckpointKey string
+
+	// AuditMerkleTree enables the audit Merkle tree. Audit
+	// checkpoints contain its root and clients can fetch
+	// inclusion proofs for individual audit events.
+	AuditMerkleTree bool
+
+	// AuditStorePath is the directory the KES server persists
+	// audit events in such that they can be queried. If empty,
+	// audit events are not persisted.
+	AuditStorePath string
+
+	// AuditRetention is the time persisted audit events are
+	// retained. If 0, defaults to 30 days.
+	AuditRetention time.Duration
+
+	// File is an optional log file configuration. If set, error
+	// and/or audit events are written to local files instead of
+	// STDERR and STDOUT.
+	File *LogFileConfig
+
+	// Syslog is an optional syslog configuration. If set, error
+	// and/or audit events are sent to a syslog server instead of
+	// STDERR and STDOUT.
+	Syslog *SyslogLogConfig
+
+	// Webhook is an optional webhook configuration. If set,
+	// error and/or audit events are sent to an HTTP(S) endpoint
+	// instead of STDERR and STDOUT.
+	Webhook *WebhookLogConfig
+
+	// Kafka is an optional Kafka audit log target. If set, the
+	// KES server publishes all audit events to a Kafka topic
+	// independent of the AuditLevel.
+	Kafka *KafkaLogConfig
 }
 
-// APIPathConfig is a structure that holds the API configuration
-// for one particular KES API.
-type APIPathConfig struct {
-	// Timeout is the duration after which the API response
-	// with a HTTP timeout error response. If Timeout is
-	// zero the API default is used.
-	Timeout time.Duration
+// LogFileConfig is a structure that holds the log file
+// configuration.
+type LogFileConfig struct {
+	// ErrorPath is the path of the error log file. If empty,
+	// error events are not written to a file.
+	ErrorPath string
 
-	// InsecureSkipAuth controls whether the API verifies
-	// client identities. If InsecureSkipAuth is true,
-	// the API accepts requests from arbitrary identities.
-	// In this mode, the API can be used by anyone who can
-	// communicate to the KES server over HTTPS.
-	// This should only be set for testing or in certain
-	// cases for APIs that don't expose sensitive information,
-	// like metrics.
-	InsecureSkipAuth bool
-}
+	// AuditPath is the path of the audit log file. If empty,
+	// audit events are not written to a file.
+	AuditPath string
 
-// Policy is a structure defining a KES policy.
-//
-// Any request issued by a KES identity is validated
-// by the associated allow and deny patterns. A
-// request is accepted if and only if no deny pattern
-// and at least one allow pattern matches the request.
-type Policy struct {
-	// Allow is the list of API path patterns
-	// that are explicitly allowed.
-	Allow []string
+	// MaxSize is the size in bytes at which a log file is
+	// rotated. If 0, log files are not rotated by size.
+	MaxSize int64
 
-	// Deny is the list of API path patterns
-	// that are explicitly denied.
-	Deny []string
+	// MaxAge is the age at which a log file is rotated.
+	// If 0, log files are not rotated by age.
+	MaxAge time.Duration
 
-	// Identities is a list of KES identities
-	// that are assigned to this policy.
-	//
-	// It must not contain the admin or any
-	// TLS proxy identity.
-	Identities []kes.Identity
+	// MaxBackups is the number of rotated log files that are
+	// retained. If 0, all rotated files are retained.
+	MaxBackups int
 }
 
-// Key is a structure defining a cryptographic key
-// that the KES server will create or ensure exists
-// before startup.
-type Key struct {
-	// Name is the name of the cryptographic key.
-	Name string
-}
+// SyslogLogConfig is a structure that holds the syslog
+// configuration.
+type SyslogLogConfig struct {
+	// Network is either "udp", "tcp" or "tls".
+	Network string
 
-// KeyStore is a KES keystore configuration.
-//
-// Concrete instances implement Connect to return
-// a connection to a concrete keystore.
-type KeyStore interface {
-	// Connect establishes and returns a new connection
-	// to the keystore.
-	Connect(ctx context.Context) (kes.KeyStore, error)
-}
+	// Addr is the address of the syslog server.
+	Addr string
 
-// FSKeyStore is a structure containing the configuration
-// for a simple filesystem keystore.
-//
-// A FSKeyStore should only be used when testing a KES server.
-type FSKeyStore struct {
-	// Path is the path to the directory that
-	// contains the keys.
-	//
-	// If the directory does not exist, it
-	// will be created.
-	Path string
-}
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the syslog server's TLS certificate.
+	CAPath string
 
-// Connect returns a kv.Store that stores key-value pairs in a path on the filesystem.
-func (s *FSKeyStore) Connect(context.Context) (kes.KeyStore, error) {
-	return fs.NewStore(s.Path)
+	// Facility is the syslog facility name, e.g. "local0".
+	Facility string
+
+	// AppName is the optional application name of all syslog
+	// messages. If empty, defaults to "kes".
+	AppName string
+
+	// Error and Audit control whether error and audit events
+	// are sent to the syslog server.
+	Error bool
+	Audit bool
 }
 
-// VaultKeyStore is a structure containing the configuration
-// for Hashicorp Vault.
-type VaultKeyStore struct {
-	// Endpoint is the Hashicorp Vault endpoint.
+// WebhookLogConfig is a structure that holds the log webhook
+// configuration.
+type WebhookLogConfig struct {
+	// Endpoint is the HTTP(S) URL events are sent to.
+	Endpoint string
+
+	// AuthHeader is the name of the HTTP header containing
+	// AuthValue. If empty, defaults to "Authorization".
+	AuthHeader string
+
+	// AuthValue is an optional value sent in the AuthHeader,
+	// e.g. "Splunk <token>".
+	AuthValue string
+
+	// Format is either "json" or "splunk". If empty, defaults
+	// to "json".
+	Format string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the endpoint's TLS certificate.
+	CAPath string
+
+	// BatchSize is the max. number of events per request.
+	// If 0, the default is used.
+	BatchSize int
+
+	// Interval is the max. time events are buffered before
+	// they are sent. If 0, the default is used.
+	Interval time.Duration
+
+	// MaxRetries is the max. number of retries per request.
+	// If 0, the default is used.
+	MaxRetries int
+
+	// Error and Audit control whether error and audit events
+	// are sent to the webhook endpoint.
+	Error bool
+	Audit bool
+}
+
+// readSigningKey reads a PEM-encoded PKCS #8, PKCS #1 or SEC 1
+// private key from the file.
+func readSigningKey(filename string) (crypto.Signer, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM-encoded private key found")
+	}
+
+	var key any
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type '%T'", key)
+	}
+	return signer, nil
+}
+
+// handlers returns the handlers for error and audit events.
+// A handler is nil if neither a file nor a syslog server is
+// configured for the corresponding events.
+func (c *LogConfig) handlers() (errorLog, auditLog slog.Handler, err error) {
+	var (
+		errHandlers, auditHandlers []slog.Handler
+		errClosers, auditClosers   []io.Closer
+	)
+	defer func() {
+		if err != nil {
+			for _, c := range append(errClosers, auditClosers...) {
+				c.Close()
+			}
+		}
+	}()
+
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug} // The server filters events by level
+	if f := c.File; f != nil {
+		openFile := func(path string) (*logsink.File, error) {
+			return logsink.OpenFile(&logsink.FileConfig{
+				Path:       path,
+				MaxSize:    f.MaxSize,
+				MaxAge:     f.MaxAge,
+				MaxBackups: f.MaxBackups,
+			})
+		}
+		if f.ErrorPath != "" {
+			file, err := openFile(f.ErrorPath)
+			if err != nil {
+				return nil, nil, err
+			}
+			errHandlers, errClosers = append(errHandlers, slog.NewTextHandler(file, opts)), append(errClosers, file)
+		}
+		if f.AuditPath != "" {
+			file, err := openFile(f.AuditPath)
+			if err != nil {
+				return nil, nil, err
+			}
+			auditHandlers, auditClosers = append(auditHandlers, slog.NewTextHandler(file, opts)), append(auditClosers, file)
+		}
+	}
+	if s := c.Syslog; s != nil {
+		facility, err := logsink.ParseFacility(s.Facility)
+		if err != nil {
+			return nil, nil, err
+		}
+		config := &logsink.SyslogConfig{
+			Network:  s.Network,
+			Addr:     s.Addr,
+			Facility: facility,
+			AppName:  s.AppName,
+		}
+		if s.CAPath != "" {
+			rootCAs, err := https.CertPoolFromFile(s.CAPath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("kesconf: failed to read syslog CA certificates: %v", err)
+			}
+			config.TLS = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs}
+		}
+
+		// Error and audit events use separate connections such
+		// that closing one handler does not affect the other.
+		// The same applies to webhooks below.
+		if s.Error {
+			syslog, err := logsink.NewSyslog(config)
+			if err != nil {
+				return nil, nil, err
+			}
+			errHandlers, errClosers = append(errHandlers, syslog.Handler(opts)), append(errClosers, syslog)
+		}
+		if s.Audit {
+			syslog, err := logsink.NewSyslog(config)
+			if err != nil {
+				return nil, nil, err
+			}
+			auditHandlers, auditClosers = append(auditHandlers, syslog.Handler(opts)), append(auditClosers, syslog)
+		}
+	}
+
+	if w := c.Webhook; w != nil {
+		config := &logsink.WebhookConfig{
+			Endpoint:      w.Endpoint,
+			AuthHeader:    w.AuthHeader,
+			AuthValue:     w.AuthValue,
+			Format:        w.Format,
+			BatchSize:     w.BatchSize,
+			FlushInterval: w.Interval,
+			MaxRetries:    w.MaxRetries,
+		}
+		if w.CAPath != "" {
+			rootCAs, err := https.CertPoolFromFile(w.CAPath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("kesconf: failed to read log webhook CA certificates: %v", err)
+			}
+			config.TLS = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs}
+		}
+		if w.Error {
+			webhook, err := logsink.NewWebhook(config)
+			if err != nil {
+				return nil, nil, err
+			}
+			errHandlers, errClosers = append(errHandlers, webhook.Handler(opts)), append(errClosers, webhook)
+		}
+		if w.Audit {
+			webhook, err := logsink.NewWebhook(config)
+			if err != nil {
+				return nil, nil, err
+			}
+			auditHandlers, auditClosers = append(auditHandlers, webhook.Handler(opts)), append(auditClosers, webhook)
+		}
+	}
+
+	if len(errHandlers) > 0 {
+		errorLog = logsink.NewHandler(errHandlers, errClosers)
+	}
+	if len(auditHandlers) > 0 {
+		auditLog = logsink.NewHandler(auditHandlers, auditClosers)
+	}
+	return errorLog, auditLog, nil
+}
+
+// KafkaLogConfig is a structure that holds the Kafka audit
+// log target configuration.
+type KafkaLogConfig struct {
+	// Brokers are the addresses of the Kafka bootstrap brokers.
+	Brokers []string
+
+	// Topic is the topic audit events are published to.
+	Topic string
+
+	// TLS controls whether the connections to the Kafka
+	// brokers are secured by TLS.
+	TLS bool
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the Kafka brokers' TLS certificates.
+	// If set, TLS is enabled.
+	CAPath string
+
+	// SASLMechanism is the optional SASL mechanism used to
+	// authenticate to the Kafka brokers. Either PLAIN,
+	// SCRAM-SHA-256 or SCRAM-SHA-512.
+	SASLMechanism string
+
+	// Username and Password are the SASL credentials.
+	Username string
+	Password string
+
+	// BufferSize is the max. number of audit events buffered
+	// while they cannot be published. If 0, the default is used.
+	BufferSize int
+
+	// BlockTimeout is the max. time the KES server waits for
+	// buffer space once the buffer is full before dropping an
+	// audit event. If 0, events are dropped immediately.
+	BlockTimeout time.Duration
+}
+
+// target returns a new Kafka audit log target.
+func (c *KafkaLogConfig) target() (*auditlog.Kafka, error) {
+	config := &auditlog.KafkaConfig{
+		Brokers: c.Brokers,
+		Topic:   c.Topic,
+		SASL: auditlog.SASLConfig{
+			Mechanism: c.SASLMechanism,
+			Username:  c.Username,
+			Password:  c.Password,
+		},
+		BufferSize:   c.BufferSize,
+		BlockTimeout: c.BlockTimeout,
+	}
+	if c.TLS || c.CAPath != "" {
+		config.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+		if c.CAPath != "" {
+			rootCAs, err := https.CertPoolFromFile(c.CAPath)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read Kafka CA certificates: %v", err)
+			}
+			config.TLS.RootCAs = rootCAs
+		}
+	}
+	return auditlog.NewKafka(config)
+}
+
+// NameConfig is a structure that holds the rules for valid
+// key, policy and identity names.
+type NameConfig struct {
+	// MaxLength is the max. length of a name in bytes.
+	// If 0, the KES server default is used.
+	MaxLength int
+
+	// Charset contains characters that are valid within
+	// names in addition to the KES server default ones.
+	Charset string
+}
+
+// TelemetryConfig is a structure that holds the telemetry
+// configuration of a KES server.
+type TelemetryConfig struct {
+	// Endpoint is the HTTP(S) URL anonymous usage reports
+	// are sent to.
+	Endpoint string
+
+	// Interval is the time between two usage reports.
+	// If 0, the KES server default is used.
+	Interval time.Duration
+}
+
+// PolicyStoreConfig is a structure that holds the policy
+// store configuration of a KES server.
+type PolicyStoreConfig struct {
+	// Filename is the path of the file policies created and
+	// assigned via the API are persisted to.
+	Filename string
+}
+
+// OIDCConfig is a structure that holds the configuration for
+// authenticating clients with JSON Web Tokens (JWT) issued by
+// an OpenID Connect (OIDC) provider.
+type OIDCConfig struct {
+	// Issuer is the issuer URL of the OIDC provider. Its
+	// signing keys are fetched via the provider's discovery
+	// document.
+	Issuer string
+
+	// Audience is the audience tokens must be issued for.
+	Audience string
+
+	// IdentityClaim is the name of the claim whose value is
+	// used as client identity. If empty, defaults to "sub".
+	IdentityClaim string
+
+	// Policies maps policy names to the claims a token must
+	// contain to get the policy assigned.
+	Policies map[string]map[string]string
+}
+
+// KeyUsageConfig is a structure that holds the key usage
+// tracking configuration of a KES server.
+type KeyUsageConfig struct {
+	// Filename is the path of the file key usage statistics
+	// are persisted to. If empty, key usage statistics are
+	// kept in memory only.
+	Filename string
+
+	// Interval is the time between two writes of the key
+	// usage file. If 0, the KES server default is used.
+	Interval time.Duration
+}
+
+// SoftDeleteConfig is a structure that holds the soft-delete
+// configuration of a KES server.
+type SoftDeleteConfig struct {
+	// RecoveryWindow is the time after which a key, that has
+	// been marked for deletion, gets deleted permanently. If
+	// 0, the KES server default is used.
+	RecoveryWindow time.Duration
+}
+
+// ApprovalConfig is a structure that holds the dual-control
+// configuration of a KES server.
+type ApprovalConfig struct {
+	// Window is the time period within which another identity
+	// has to approve a pending request. If 0, the KES server
+	// default is used.
+	Window time.Duration
+}
+
+// NotifyConfig is a structure that holds the notification
+// targets of a KES server. Each target is optional.
+type NotifyConfig struct {
+	// Webhook is the webhook notification target, if any.
+	Webhook *WebhookNotifyConfig
+
+	// NATS is the NATS notification target, if any.
+	NATS *NATSNotifyConfig
+
+	// SQS is the AWS SQS notification target, if any.
+	SQS *SQSNotifyConfig
+}
+
+// targets returns the configured notification targets.
+func (c *NotifyConfig) targets() ([]kes.NotificationTarget, error) {
+	var targets []kes.NotificationTarget
+	if c.Webhook != nil {
+		config := &notify.WebhookConfig{
+			Endpoint:  c.Webhook.Endpoint,
+			AuthToken: c.Webhook.AuthToken,
+		}
+		if c.Webhook.CAPath != "" {
+			rootCAs, err := https.CertPoolFromFile(c.Webhook.CAPath)
+			if err != nil {
+				return nil, fmt.Errorf("kesconf: failed to read webhook CA certificates: %v", err)
+			}
+			config.TLS = &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs}
+		}
+		webhook, err := notify.NewWebhook(config)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, webhook)
+	}
+	if c.NATS != nil {
+		config := &notify.NATSConfig{
+			Addr:     c.NATS.Addr,
+			Subject:  c.NATS.Subject,
+			Username: c.NATS.Username,
+			Password: c.NATS.Password,
+			Token:    c.NATS.Token,
+		}
+		if c.NATS.TLS || c.NATS.CAPath != "" {
+			config.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+			if c.NATS.CAPath != "" {
+				rootCAs, err := https.CertPoolFromFile(c.NATS.CAPath)
+				if err != nil {
+					return nil, fmt.Errorf("kesconf: failed to read NATS CA certificates: %v", err)
+				}
+				config.TLS.RootCAs = rootCAs
+			}
+		}
+		nats, err := notify.NewNATS(config)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, nats)
+	}
+	if c.SQS != nil {
+		sqs, err := notify.NewSQS(&notify.SQSConfig{
+			QueueURL:     c.SQS.QueueURL,
+			Region:       c.SQS.Region,
+			AccessKey:    c.SQS.AccessKey,
+			SecretKey:    c.SQS.SecretKey,
+			SessionToken: c.SQS.SessionToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, sqs)
+	}
+	return targets, nil
+}
+
+// ReplicationConfig is a structure that holds the replication
+// configuration of a KES server.
+type ReplicationConfig struct {
+	// Endpoint is the HTTPS URL of the KES cluster keys and
+	// identities are replicated to.
+	Endpoint string
+
+	// KEK is the name of the key that wraps replicated keys.
+	// It must exist on both clusters.
+	KEK string
+
+	// Interval is the time between two replication runs.
+	// If 0, the KES server default is used.
+	Interval time.Duration
+
+	// PrivateKey is the path to the TLS private key used
+	// to authenticate to the target cluster.
+	PrivateKey string
+
+	// Certificate is the path to the TLS certificate used
+	// to authenticate to the target cluster.
+	Certificate string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the target's TLS certificate.
+	CAPath string
+}
+
+// ClusterConfig is a structure that holds the cluster
+// configuration of a KES server.
+type ClusterConfig struct {
+	// Addr is the HTTPS URL other cluster members reach
+	// the KES server at.
+	Addr string
+
+	// Dir is the directory the KES server persists its
+	// cluster state in.
+	Dir string
+
+	// Peers are the addresses of all initial members when
+	// bootstrapping a new cluster.
+	Peers []string
+
+	// Join is the address of an existing member the KES
+	// server asks to add it to the cluster, if it is not
+	// a member yet.
+	Join string
+
+	// PrivateKey is the path to the TLS private key used
+	// to authenticate to other members.
+	PrivateKey string
+
+	// Certificate is the path to the TLS certificate used
+	// to authenticate to other members.
+	Certificate string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the TLS certificates of other members.
+	CAPath string
+
+	// Identities are the identities of all members that
+	// may send cluster messages.
+	Identities []kes.Identity
+
+	// Key is the 256-bit key shared by all members that
+	// encrypts the replicated keys.
+	Key []byte
+}
+
+// ReplicaConfig is a structure that holds the read replica
+// configuration of a KES server.
+type ReplicaConfig struct {
+	// Endpoint is the HTTPS URL of the primary KES server
+	// keys are fetched from.
+	Endpoint string
+
+	// Interval is the time between two cache warm-ups.
+	// If 0, the KES server default is used.
+	Interval time.Duration
+
+	// PrivateKey is the path to the TLS private key used
+	// to authenticate to the primary.
+	PrivateKey string
+
+	// Certificate is the path to the TLS certificate used
+	// to authenticate to the primary.
+	Certificate string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the primary's TLS certificate.
+	CAPath string
+}
+
+// SealConfig is a structure that holds the seal configuration
+// of a KES server.
+type SealConfig struct {
+	// Threshold is the number of root key shares required
+	// to unseal the server.
+	Threshold int
+
+	// RootKeyHash is the SHA-256 hash of the root key.
+	RootKeyHash []byte
+}
+
+// MetricsListenerConfig is a structure that holds the configuration
+// of the separate metrics and health API listener of a KES server.
+type MetricsListenerConfig struct {
+	// Addr is the network address the listener accepts
+	// connections on, like "127.0.0.1:7374".
+	Addr string
+
+	// TLS controls whether the listener uses the server's TLS
+	// certificate. Clients are not required to send a certificate.
+	// If false, the listener accepts plaintext HTTP connections.
+	TLS bool
+}
+
+// MetricsPushConfig is a structure that holds the metrics
+// push configuration of a KES server.
+type MetricsPushConfig struct {
+	// Endpoint is the HTTP(S) URL of the Pushgateway or the
+	// remote write endpoint metrics are pushed to.
+	Endpoint string
+
+	// Protocol is either "pushgateway" or "remote_write".
+	// If empty, metrics are pushed to a Pushgateway.
+	Protocol string
+
+	// Job is the value of the 'job' label. If empty,
+	// defaults to "kes".
+	Job string
+
+	// Interval is the time between two pushes. If 0,
+	// the KES server default is used.
+	Interval time.Duration
+
+	// Username and Password are optional credentials for
+	// HTTP basic authentication.
+	Username string
+	Password string
+
+	// PrivateKey is an optional path to the TLS private key
+	// used to authenticate to the endpoint.
+	PrivateKey string
+
+	// Certificate is an optional path to the TLS certificate
+	// used to authenticate to the endpoint.
+	Certificate string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the endpoint's TLS certificate.
+	CAPath string
+}
+
+// OtelConfig is a structure that holds the OpenTelemetry
+// tracing configuration of a KES server.
+type OtelConfig struct {
+	// Endpoint is the HTTP(S) URL of the OTLP/HTTP collector
+	// spans are exported to - e.g. "http://127.0.0.1:4318".
+	// If the URL has no path, spans are sent to "/v1/traces".
+	Endpoint string
+
+	// ServiceName is the service name reported with all spans.
+	// If empty, defaults to "kes".
+	ServiceName string
+
+	// SamplingRatio is the fraction of traces that are sampled.
+	// If 0, all traces are sampled. Requests that are part of a
+	// sampled trace of the client are always sampled.
+	SamplingRatio float64
+}
+
+// tracerProvider returns a new OpenTelemetry TracerProvider
+// exporting spans to the OTLP/HTTP endpoint.
+func (c *OtelConfig) tracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	endpoint, err := url.Parse(c.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("kesconf: invalid otel endpoint: %v", err)
+	}
+	options := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(endpoint.Host),
+	}
+	if endpoint.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if endpoint.Path != "" && endpoint.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(endpoint.Path))
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("kesconf: failed to create otel exporter: %v", err)
+	}
+
+	serviceName, ratio := c.ServiceName, c.SamplingRatio
+	if serviceName == "" {
+		serviceName = "kes"
+	}
+	if ratio == 0 {
+		ratio = 1
+	}
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+	), nil
+}
+
+// WebhookNotifyConfig is a structure that holds the webhook
+// notification target configuration.
+type WebhookNotifyConfig struct {
+	// Endpoint is the HTTP(S) URL events are sent to.
+	Endpoint string
+
+	// AuthToken is an optional bearer token sent with
+	// each event.
+	AuthToken string
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the endpoint's TLS certificate.
+	CAPath string
+}
+
+// NATSNotifyConfig is a structure that holds the NATS
+// notification target configuration.
+type NATSNotifyConfig struct {
+	// Addr is the address of the NATS server.
+	Addr string
+
+	// Subject is the subject events are published to.
+	Subject string
+
+	// Username and Password are optional NATS credentials.
+	Username string
+	Password string
+
+	// Token is an optional NATS authentication token.
+	Token string
+
+	// TLS controls whether the connection to the NATS
+	// server is secured by TLS.
+	TLS bool
+
+	// CAPath is an optional path to the CA certificate(s)
+	// used to verify the NATS server's TLS certificate.
+	// If set, TLS is enabled.
+	CAPath string
+}
+
+// SQSNotifyConfig is a structure that holds the AWS SQS
+// notification target configuration.
+type SQSNotifyConfig struct {
+	// QueueURL is the URL of the SQS queue.
+	QueueURL string
+
+	// Region is the AWS region of the queue.
+	Region string
+
+	// AccessKey, SecretKey and SessionToken are optional
+	// static AWS credentials.
+	AccessKey    string
+	SecretKey    string
+	SessionToken string
+}
+
+// APIConfig is a structure that holds the API configuration
+// for a KES server.
+type APIConfig struct {
+	// Paths contains a set of API paths and there
+	// API configuration.
+	Paths map[string]APIPathConfig
+}
+
+// APIPathConfig is a structure that holds the API configuration
+// for one particular KES API.
+type APIPathConfig struct {
+	// Timeout is the duration after which the API response
+	// with a HTTP timeout error response. If Timeout is
+	// zero the API default is used.
+	Timeout time.Duration
+
+	// InsecureSkipAuth controls whether the API verifies
+	// client identities. If InsecureSkipAuth is true,
+	// the API accepts requests from arbitrary identities.
+	// In this mode, the API can be used by anyone who can
+	// communicate to the KES server over HTTPS.
+	// This should only be set for testing or in certain
+	// cases for APIs that don't expose sensitive information,
+	// like metrics.
+	InsecureSkipAuth bool
+}
+
+// Policy is a structure defining a KES policy.
+//
+// Any request issued by a KES identity is validated
+// by the associated allow and deny patterns. A
+// request is accepted if and only if no deny pattern
+// and at least one allow pattern or key rule matches
+// the request.
+type Policy struct {
+	// Allow is the list of API path patterns
+	// that are explicitly allowed.
+	Allow []string
+
+	// Deny is the list of API path patterns
+	// that are explicitly denied.
+	Deny []string
+
+	// Keys is a list of key rules that allow key
+	// operations, like encrypt or decrypt, on keys
+	// whose names match a pattern.
+	Keys []kes.KeyRule
+
+	// Conditions restrict the requests of the
+	// assigned identities further, e.g. to certain
+	// source IPs or times. If nil, no conditions
+	// apply.
+	Conditions *kes.PolicyConditions
+
+	// RateLimit limits the number of requests
+	// of the assigned identities. If nil, requests
+	// are not rate limited.
+	RateLimit *kes.RateLimit
+
+	// Context requires encrypt, generate and decrypt
+	// requests of the assigned identities to carry an
+	// encryption context with certain fields. If nil,
+	// any context is accepted.
+	Context *kes.ContextPolicy
+
+	// SSH restricts the SSH certificates the assigned
+	// identities may obtain. If nil, they cannot obtain
+	// any SSH certificates.
+	SSH *kes.SSHPolicy
+
+	// Cert restricts the X.509 certificates the assigned
+	// identities may obtain. If nil, they cannot obtain
+	// any certificates.
+	Cert *kes.CertPolicy
+
+	// Identities is a list of KES identities
+	// that are assigned to this policy.
+	//
+	// It must not contain the admin or any
+	// TLS proxy identity.
+	Identities []kes.Identity
+}
+
+// Key is a structure defining a cryptographic key
+// that the KES server will create or ensure exists
+// before startup.
+type Key struct {
+	// Name is the name of the cryptographic key.
+	Name string
+}
+
+// KeyStore is a KES keystore configuration.
+//
+// Concrete instances implement Connect to return
+// a connection to a concrete keystore.
+type KeyStore interface {
+	// Connect establishes and returns a new connection
+	// to the keystore.
+	Connect(ctx context.Context) (kes.KeyStore, error)
+}
+
+// FailoverKeyStore is a structure containing the configuration
+// of a primary and a secondary keystore.
+//
+// KES uses the primary keystore as long as it is reachable and
+// fails over to the secondary keystore otherwise. Writes that
+// happen while failed over are replayed to the primary keystore
+// once it is reachable again.
+type FailoverKeyStore struct {
+	// Primary is the keystore used as long as it is reachable.
+	Primary KeyStore
+
+	// Secondary is the keystore used while the primary
+	// keystore is not reachable. It should contain the
+	// same keys as the primary keystore, e.g. a replica.
+	Secondary KeyStore
+
+	// Threshold is the number of consecutive requests the
+	// primary keystore has to be unreachable before KES fails
+	// over. If 0, KES fails over on the first such request.
+	Threshold int
+
+	// Interval is the time between two health checks of the
+	// primary keystore. If 0, the failover default is used.
+	Interval time.Duration
+}
+
+// Connect returns a kes.KeyStore that fails over from the primary
+// to the secondary keystore.
+func (s *FailoverKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	primary, err := s.Primary.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	secondary, err := s.Secondary.Connect(ctx)
+	if err != nil {
+		primary.Close()
+		return nil, err
+	}
+	return failover.NewStore(primary, secondary, &failover.Config{
+		Threshold: s.Threshold,
+		Interval:  s.Interval,
+	}), nil
+}
+
+// CompressKeyStore is a structure containing the configuration
+// of a keystore whose entries are stored compressed.
+//
+// Compression helps to fit larger keys into keystores that
+// limit the size of an entry, like AWS SecretsManager.
+// Existing, uncompressed entries remain readable.
+type CompressKeyStore struct {
+	// KeyStore is the keystore that stores
+	// the compressed entries.
+	KeyStore KeyStore
+}
+
+// Connect returns a kes.KeyStore that compresses entries
+// before writing them to the underlying keystore.
+func (s *CompressKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	store, err := s.KeyStore.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return compress.NewStore(store), nil
+}
+
+// FaultKeyStore is a structure containing the faults injected
+// into requests to a keystore.
+//
+// Fault injection helps testing how a KES server behaves when
+// its keystore is slow or unreliable. It must not be used in
+// production.
+type FaultKeyStore struct {
+	// KeyStore is the keystore to which faults
+	// are injected.
+	KeyStore KeyStore
+
+	// Latency is the delay added to every request.
+	Latency time.Duration
+
+	// Jitter is the max. random delay added to
+	// every request in addition to Latency.
+	Jitter time.Duration
+
+	// ErrorRate is the probability, between 0 and
+	// 1, that a request fails.
+	ErrorRate float64
+}
+
+// Connect returns a kes.KeyStore that injects faults into
+// requests to the underlying keystore.
+func (s *FaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	store, err := s.KeyStore.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := fault.NewStore(store, &fault.Config{
+		Latency:   s.Latency,
+		Jitter:    s.Jitter,
+		ErrorRate: s.ErrorRate,
+	})
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// RetryKeyStore is a structure containing the retry policy
+// for requests to a keystore.
+//
+// Requests that fail with a temporary error, like a network
+// error or a timeout, are retried. All other requests are not.
+type RetryKeyStore struct {
+	// KeyStore is the keystore to which requests
+	// are retried.
+	KeyStore KeyStore
+
+	// Attempts is the max. number of attempts per
+	// request, including the first one. If <= 1,
+	// requests are not retried.
+	Attempts int
+
+	// Backoff is the backoff strategy between two
+	// attempts. Either "constant", "linear" or
+	// "exponential". Defaults to "exponential".
+	Backoff string
+
+	// Delay is the delay before the first retry.
+	// Defaults to 100ms.
+	Delay time.Duration
+
+	// MaxDelay is the max. delay between two
+	// attempts. If <= 0, the delay is not limited.
+	MaxDelay time.Duration
+
+	// Timeout is the timeout of a single attempt.
+	// If <= 0, an attempt may take as long as the
+	// request timeout permits.
+	Timeout time.Duration
+}
+
+// Connect returns a kes.KeyStore that retries failed requests
+// to the underlying keystore.
+func (s *RetryKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	store, err := s.KeyStore.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r, err := retry.NewStore(store, &retry.Config{
+		Attempts: s.Attempts,
+		Backoff:  s.Backoff,
+		Delay:    s.Delay,
+		MaxDelay: s.MaxDelay,
+		Timeout:  s.Timeout,
+	})
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// CircuitBreakerKeyStore is a structure containing the circuit
+// breaker policy for requests to a keystore.
+//
+// Once a number of consecutive requests have failed with a
+// temporary error, like a network error or throttling, requests
+// fail immediately for some time instead of being sent to the
+// keystore.
+type CircuitBreakerKeyStore struct {
+	// KeyStore is the keystore protected by the
+	// circuit breaker.
+	KeyStore KeyStore
+
+	// Threshold is the number of consecutive failed
+	// requests that open the circuit breaker.
+	// Defaults to 5.
+	Threshold int
+
+	// Interval is the time the circuit breaker remains
+	// open before it sends a single request to probe
+	// whether the keystore has recovered. Defaults to
+	// 30s.
+	Interval time.Duration
+}
+
+// Connect returns a kes.KeyStore that stops sending requests
+// to the underlying keystore after repeated failures.
+func (s *CircuitBreakerKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	store, err := s.KeyStore.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return breaker.NewStore(store, &breaker.Config{
+		Threshold: s.Threshold,
+		Interval:  s.Interval,
+	}), nil
+}
+
+// HTTPClientConfig is a structure containing the HTTP client
+// settings of a keystore that is accessed via HTTP. Zero values
+// keep the keystore's defaults.
+type HTTPClientConfig struct {
+	// MaxIdleConns limits the number of idle (keep-alive)
+	// connections across all hosts.
+	MaxIdleConns int
+
+	// MaxIdleConnsPerHost limits the number of idle
+	// (keep-alive) connections per host.
+	MaxIdleConnsPerHost int
+
+	// IdleConnTimeout is the max. amount of time an
+	// idle connection remains open.
+	IdleConnTimeout time.Duration
+
+	// TLSHandshakeTimeout limits the time spent on
+	// the TLS handshake of a new connection.
+	TLSHandshakeTimeout time.Duration
+
+	// Timeout limits the time of a single HTTP request.
+	Timeout time.Duration
+}
+
+// FSKeyStore is a structure containing the configuration
+// for a simple filesystem keystore.
+//
+// A FSKeyStore without a master key should only be used
+// when testing a KES server.
+type FSKeyStore struct {
+	// Path is the path to the directory that
+	// contains the keys.
+	//
+	// If the directory does not exist, it
+	// will be created.
+	Path string
+
+	// MasterKey is an optional base64-encoded 256 bit
+	// key used to encrypt all keys at rest. Usually, it
+	// is provided via an environment variable.
+	MasterKey string
+
+	// MasterKeyFile is an optional path to a file that
+	// contains the base64-encoded master key - e.g. a
+	// file provisioned by a secret manager or unsealed
+	// via a TPM.
+	MasterKeyFile string
+
+	// SealedMasterKeyFile is an optional path to a master
+	// key sealed to a TPM 2.0 via 'kes tpm seal'. Only the
+	// same TPM can unseal it and only while its PCRs have
+	// not changed.
+	SealedMasterKeyFile string
+
+	// TPMDevice is the path of the TPM 2.0 device used to
+	// unseal the SealedMasterKeyFile. If empty, defaults
+	// to /dev/tpmrm0.
+	TPMDevice string
+
+	// KMIPMasterKey is an optional KMIP server that stores
+	// the master key as secret data object. The master key
+	// is fetched once when connecting.
+	KMIPMasterKey *KMIPKeyStore
+
+	// KMIPMasterKeyName is the name of the secret data
+	// object that contains the master key. If no such object
+	// exists, a new master key is created.
+	KMIPMasterKeyName string
+}
+
+// Connect returns a kv.Store that stores key-value pairs in a path on the filesystem.
+func (s *FSKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	if s.MasterKey == "" && s.MasterKeyFile == "" && s.SealedMasterKeyFile == "" && s.KMIPMasterKey == nil {
+		return fs.NewStore(s.Path)
+	}
+	if s.MasterKey != "" && s.MasterKeyFile != "" {
+		return nil, errors.New("kesconf: invalid fs keystore: master key and master key file are mutually exclusive")
+	}
+	if s.SealedMasterKeyFile != "" {
+		if s.MasterKey != "" || s.MasterKeyFile != "" {
+			return nil, errors.New("kesconf: invalid fs keystore: master key and sealed master key are mutually exclusive")
+		}
+
+		sealedKey, err := tpm.ReadSealedKey(s.SealedMasterKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to read sealed fs master key: %v", err)
+		}
+		masterKey, err := sealedKey.Unseal(s.TPMDevice)
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to unseal fs master key: %v", err)
+		}
+		return fs.NewEncryptedStore(s.Path, masterKey)
+	}
+	if s.KMIPMasterKey != nil {
+		if s.MasterKey != "" || s.MasterKeyFile != "" {
+			return nil, errors.New("kesconf: invalid fs keystore: master key and KMIP master key are mutually exclusive")
+		}
+
+		masterKey, err := s.kmipMasterKey(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return fs.NewEncryptedStore(s.Path, masterKey)
+	}
+
+	masterKey, err := readMasterKey("fs", s.MasterKey, s.MasterKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return fs.NewEncryptedStore(s.Path, masterKey)
+}
+
+// kmipMasterKey fetches the master key from the KMIP server.
+// If the server does not store a master key with the given
+// name, it creates a new random master key.
+func (s *FSKeyStore) kmipMasterKey(ctx context.Context) ([]byte, error) {
+	store, err := s.KMIPMasterKey.Connect(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("kesconf: failed to connect to KMIP server: %v", err)
+	}
+	defer store.Close()
+
+	masterKey, err := store.Get(ctx, s.KMIPMasterKeyName)
+	if errors.Is(err, kesdk.ErrKeyNotFound) {
+		masterKey = make([]byte, 32)
+		if _, err = rand.Read(masterKey); err != nil {
+			return nil, err
+		}
+		if err = store.Create(ctx, s.KMIPMasterKeyName, masterKey); errors.Is(err, kesdk.ErrKeyExists) {
+			masterKey, err = store.Get(ctx, s.KMIPMasterKeyName)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("kesconf: failed to fetch fs master key from KMIP server: %v", err)
+	}
+	return masterKey, nil
+}
+
+// readMasterKey returns the base64-decoded master key of the
+// given keystore type. The key is either provided directly or
+// read from keyFile.
+func readMasterKey(typ, key, keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		b, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("kesconf: failed to read %s master key: %v", typ, err)
+		}
+		key = string(b)
+	}
+	masterKey, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key))
+	if err != nil {
+		return nil, fmt.Errorf("kesconf: invalid %s master key: %v", typ, err)
+	}
+	return masterKey, nil
+}
+
+// VaultKeyStore is a structure containing the configuration
+// for Hashicorp Vault.
+type VaultKeyStore struct {
+	// Endpoint is the Hashicorp Vault endpoint.
 	Endpoint string
 
 	// Namespace is an optional Hashicorp Vault namespace.
@@ -395,37 +2289,809 @@ type VaultKeyStore struct {
 	// is used.
 	Namespace string
 
-	// APIVersion is the API version of the Hashicorp Vault
-	// K/V engine. Valid values are: "v1" and "v2".
-	// If empty, defaults to "v1".
-	APIVersion string
+	// APIVersion is the API version of the Hashicorp Vault
+	// K/V engine. Valid values are: "v1" and "v2".
+	// If empty, defaults to "v1".
+	APIVersion string
+
+	// Engine is the Hashicorp Vault K/V engine path.
+	// If empty, defaults to "kv".
+	Engine string
+
+	// Prefix is an optional prefix / directory within the
+	// K/V engine.
+	// If empty, keys will be stored at the K/V engine top
+	// level.
+	Prefix string
+
+	// AppRole contains the Vault AppRole authentication
+	// method credentials.
+	AppRole *VaultAppRoleAuth
+
+	// Kubernetes contains the Vault Kubernetes authentication
+	// method credentials.
+	Kubernetes *VaultKubernetesAuth
+
+	// Transit contains the Vault transit encryption engine
+	// configuration for en/decrypting K/V entries with a
+	// key managed by Vault.
+	//
+	// This is an optional and additional layer of encryption.
+	// Since Vault manages and encrypts K/V values in any case,
+	// using the transit engine is usually not necessary.
+	Transit *VaultTransit
+
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	PrivateKey string
+
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	//
+	// If empty, mTLS authentication is disabled.
+	Certificate string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the Hashicorp Vault server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+
+	// StatusPing controls how often to Vault health status
+	// is checked.
+	// If not set, defaults to 10s.
+	StatusPing time.Duration
+
+	// HTTP contains optional HTTP client settings.
+	HTTP HTTPClientConfig
+}
+
+// VaultAppRoleAuth is a structure containing the configuration
+// for the Hashicorp Vault AppRole authentication method.
+type VaultAppRoleAuth struct {
+	// Engine is the AppRole authentication engine path.
+	// If empty, defaults to "approle".
+	Engine string
+
+	// Namespace is the Vault namespace in which the AppRole
+	// authentication is performed. It can be used to authenticate
+	// in a different namespace compared to the secret engine
+	// namespace. For example, authenticate within the root
+	// namespace but use a team-specific namespace for the secret
+	// engine.
+	//
+	// If empty, the VaultKeyStore namespace is used, if set.
+	// A single "/" is treated as alias for the Vault root
+	// namespace such that no namespace header is sent as part
+	// of the request.
+	Namespace string
+
+	// AppRoleID is the AppRole access ID for authenticating
+	// to Hashicorp Vault via the AppRole method.
+	ID string
+
+	// AppRoleSecret is the AppRole access secret for authenticating
+	// to Hashicorp Vault via the AppRole method.
+	Secret string
+
+	// SecretFile is a path to a file containing the AppRole
+	// access secret. The file is read on every login. It is
+	// mutually exclusive with Secret.
+	SecretFile string
+
+	// Rotate controls whether the AppRole access secret is
+	// rotated before it expires. The new secret is written
+	// to SecretFile.
+	Rotate bool
+}
+
+// VaultKubernetesAuth is a structure containing the configuration
+// for the Hashicorp Vault Kubernetes authentication method.
+type VaultKubernetesAuth struct {
+	// Engine is the Kubernetes authentication engine path.
+	// If empty, defaults to "kubernetes".
+	Engine string
+
+	// Namespace is the Vault namespace in which the Kubernetes
+	// authentication is performed. It can be used to authenticate
+	// in a different namespace compared to the secret engine
+	// namespace. For example, authenticate within the root
+	// namespace but use a team-specific namespace for the secret
+	// engine.
+	//
+	// If empty, the VaultKeyStore namespace is used, if set.
+	// A single "/" is treated as alias for the Vault root
+	// namespace such that no namespace header is sent as part
+	// of the request.
+	Namespace string
+
+	// KubernetesRole is the login role for authenticating via the
+	// kubernetes authentication method.
+	Role string
+
+	// KubernetesJWT is either the JWT or a path to a file containing
+	// the JWT for for authenticating via the kubernetes authentication
+	// method.
+	JWT string
+
+	// JWTFile is an optional path to the file containing the JWT.
+	// If set, the JWT is read from the file on every login such
+	// that rotated service account tokens are used.
+	JWTFile string
+}
+
+// VaultTransit is a structure containing the configuration
+// for the Hashicorp Vault transit encryption engine.
+type VaultTransit struct {
+	// Engine is the Transit encryption engine path.
+	// If empty, defaults to "transit".
+	Engine string
+
+	// KeyName is the name of the key used for en/decryption.
+	KeyName string
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a Hashicorp Vault server.
+func (s *VaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	if s.AppRole == nil && s.Kubernetes == nil {
+		return nil, errors.New("edge: failed to connect to hashicorp vault: no authentication method specified")
+	}
+	if s.AppRole != nil && s.Kubernetes != nil {
+		return nil, errors.New("edge: failed to connect to hashicorp vault: more than one authentication method specified")
+	}
+	c := &vault.Config{
+		Endpoint:        s.Endpoint,
+		Engine:          s.Engine,
+		APIVersion:      s.APIVersion,
+		Namespace:       s.Namespace,
+		Prefix:          s.Prefix,
+		PrivateKey:      s.PrivateKey,
+		Certificate:     s.Certificate,
+		CAPath:          s.CAPath,
+		StatusPingAfter: s.StatusPing,
+		HTTP:            keystore.HTTPConfig(s.HTTP),
+	}
+	if s.AppRole != nil {
+		c.AppRole = &vault.AppRole{
+			Engine:     s.AppRole.Engine,
+			Namespace:  s.AppRole.Namespace,
+			ID:         s.AppRole.ID,
+			Secret:     s.AppRole.Secret,
+			SecretFile: s.AppRole.SecretFile,
+			Rotate:     s.AppRole.Rotate,
+		}
+	}
+	if s.Kubernetes != nil {
+		c.K8S = &vault.Kubernetes{
+			Engine:    s.Kubernetes.Engine,
+			Namespace: s.Kubernetes.Namespace,
+			Role:      s.Kubernetes.Role,
+			JWT:       s.Kubernetes.JWT,
+		}
+		if s.Kubernetes.JWTFile != "" {
+			c.K8S.JWT, c.K8S.JWTFile = "", s.Kubernetes.JWTFile
+		}
+	}
+	if s.Transit != nil {
+		c.Transit = &vault.Transit{
+			Engine:  s.Transit.Engine,
+			KeyName: s.Transit.KeyName,
+		}
+	}
+	return vault.Connect(ctx, c)
+}
+
+// FortanixKeyStore is a structure containing the
+// configuration for Fortanix SDKMS.
+type FortanixKeyStore struct {
+	// Endpoint is the endpoint of the Fortanix KMS.
+	Endpoint string
+
+	// GroupID is the ID of the access control group.
+	GroupID string
+
+	// GroupName is the name of the access control group.
+	// It is mutually exclusive with GroupID.
+	GroupName string
+
+	// APIKey is the API key for authenticating to
+	// the Fortanix KMS.
+	APIKey string
+
+	// AppID is the ID of the Fortanix application.
+	// It is required when authenticating via a client
+	// certificate or a JWT.
+	AppID string
+
+	// CertPath and KeyPath are the paths to the TLS
+	// client certificate and private key of the
+	// Fortanix application.
+	CertPath string
+	KeyPath  string
+
+	// TokenFile is the path to a file containing a JWT
+	// issued by an OAuth/OIDC provider that the Fortanix
+	// application trusts.
+	TokenFile string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the Hashicorp Vault server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a Fortanix SDKMS server.
+func (s *FortanixKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return fortanix.Connect(ctx, &fortanix.Config{
+		Endpoint:  s.Endpoint,
+		GroupID:   s.GroupID,
+		GroupName: s.GroupName,
+		APIKey:    fortanix.APIKey(s.APIKey),
+		AppID:     s.AppID,
+		CertPath:  s.CertPath,
+		KeyPath:   s.KeyPath,
+		TokenFile: s.TokenFile,
+		CAPath:    s.CAPath,
+	})
+}
+
+// KeySecureKeyStore is a structure containing the
+// configuration for Gemalto KeySecure / Thales
+// CipherTrust Manager.
+type KeySecureKeyStore struct {
+	// Endpoint is the endpoint to the KeySecure server.
+	Endpoint string
+
+	// Token is the refresh authentication token to
+	// access the KeySecure server.
+	Token string
+
+	// Domain is the isolated namespace within the
+	// KeySecure server. If empty, defaults to the
+	// top-level / root domain.
+	Domain string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the KeySecure server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+}
+
+// Connect returns a kv.Store that stores key-value pairs on a Gemalto KeySecure instance.
+func (s *KeySecureKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return gemalto.Connect(ctx, &gemalto.Config{
+		Endpoint: s.Endpoint,
+		CAPath:   s.CAPath,
+		Login: gemalto.Credentials{
+			Token:  s.Token,
+			Domain: s.Domain,
+		},
+	})
+}
+
+// GCPSecretManagerKeyStore is a structure containing the
+// configuration for GCP SecretManager.
+type GCPSecretManagerKeyStore struct {
+	// ProjectID is the GCP project ID.
+	ProjectID string
+
+	// Endpoint is the GCP project ID. If empty,
+	// defaults to:
+	//   secretmanager.googleapis.com:443
+	Endpoint string
+
+	// Scopes are GCP OAuth2 scopes for accessing
+	// GCP APIs. If empty, defaults to the GCP
+	// default scopes.
+	Scopes []string
+
+	// ClientEmail is the Client email of the
+	// GCP service account used to access the
+	// SecretManager.
+	ClientEmail string
+
+	// ClientID is the Client ID of the GCP
+	// service account used to access the
+	// SecretManager.
+	ClientID string
+
+	// KeyID is the private key ID of the GCP
+	// service account used to access the
+	// SecretManager.
+	KeyID string
+
+	// Key is the private key of the GCP
+	// service account used to access the
+	// SecretManager.
+	Key string
+
+	// CredentialsFile is the path to a GCP credentials
+	// JSON file - e.g. an external account config for
+	// Workload Identity Federation. If it and the service
+	// account fields are empty, the application default
+	// credentials are used.
+	CredentialsFile string
+}
+
+// Connect returns a kv.Store that stores key-value pairs on GCP SecretManager.
+func (s *GCPSecretManagerKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return gcp.Connect(ctx, &gcp.Config{
+		Endpoint:  s.Endpoint,
+		ProjectID: s.ProjectID,
+		Scopes:    s.Scopes,
+		Credentials: gcp.Credentials{
+			ClientID: s.ClientID,
+			Client:   s.ClientEmail,
+			KeyID:    s.KeyID,
+			Key:      s.Key,
+		},
+		CredentialsFile: s.CredentialsFile,
+	})
+}
+
+// AWSSecretsManagerKeyStore is a structure containing the
+// configuration for AWS SecretsManager.
+type AWSSecretsManagerKeyStore struct {
+	// Endpoint is the AWS SecretsManager endpoint.
+	// AWS SecretsManager endpoints have the following
+	// schema:
+	//  secrestmanager[-fips].<region>.amanzonaws.com
+	Endpoint string
+
+	// Region is the AWS region the SecretsManager is
+	// located.
+	Region string
+
+	// KMSKey is the AWS-KMS key ID (CMK-ID) used to
+	// to en/decrypt secrets managed by the SecretsManager.
+	// It may be a key ID, key ARN, alias name or alias ARN
+	// of a customer-managed key. If empty, the default AWS
+	// KMS key (aws/secretsmanager) is used.
+	KMSKey string
+
+	// AccessKey is the access key for authenticating to AWS.
+	AccessKey string
+
+	// SecretKey is the secret key for authenticating to AWS.
+	SecretKey string
+
+	// SessionToken is an optional session token for authenticating
+	// to AWS.
+	SessionToken string
+
+	// WebIdentityRoleARN is the ARN of the IAM role to assume
+	// via STS AssumeRoleWithWebIdentity - e.g. when using IAM
+	// roles for EKS service accounts (IRSA).
+	WebIdentityRoleARN string
+
+	// WebIdentityTokenFile is the path to the file containing
+	// the web identity token. For IRSA, this is the projected
+	// service account token.
+	WebIdentityTokenFile string
+
+	// WebIdentitySessionName is an optional name for the
+	// assumed role session.
+	WebIdentitySessionName string
+
+	// AssumeRoleARN is the ARN of an IAM role to assume via
+	// STS AssumeRole using the credentials above - e.g. to
+	// exchange EC2 instance credentials for a scoped role.
+	AssumeRoleARN string
+
+	// AssumeRoleExternalID is an optional external ID
+	// required by the role's trust policy.
+	AssumeRoleExternalID string
+
+	// AssumeRoleSessionName is an optional name for the
+	// assumed role session.
+	AssumeRoleSessionName string
+
+	// IMDSv2Only disables the fallback to IMDSv1 when
+	// fetching credentials from the EC2 instance metadata
+	// service.
+	IMDSv2Only bool
+
+	// HTTP contains optional HTTP client settings.
+	HTTP HTTPClientConfig
+
+	// MaxRetries is the max. number of times the AWS SDK
+	// retries a failed request. If 0, the SDK default is
+	// used. If negative, the SDK does not retry requests.
+	MaxRetries int
+}
+
+// Connect returns a kv.Store that stores key-value pairs on AWS SecretsManager.
+func (s *AWSSecretsManagerKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	config := &aws.Config{
+		Addr:     s.Endpoint,
+		Region:   s.Region,
+		KMSKeyID: s.KMSKey,
+		Login: aws.Credentials{
+			AccessKey:    s.AccessKey,
+			SecretKey:    s.SecretKey,
+			SessionToken: s.SessionToken,
+			IMDSv2Only:   s.IMDSv2Only,
+		},
+		HTTP:       keystore.HTTPConfig(s.HTTP),
+		MaxRetries: s.MaxRetries,
+	}
+	if s.WebIdentityRoleARN != "" || s.WebIdentityTokenFile != "" {
+		config.Login.WebIdentity = &aws.WebIdentity{
+			RoleARN:     s.WebIdentityRoleARN,
+			TokenFile:   s.WebIdentityTokenFile,
+			SessionName: s.WebIdentitySessionName,
+		}
+	}
+	if s.AssumeRoleARN != "" {
+		config.Login.AssumeRole = &aws.AssumeRole{
+			RoleARN:     s.AssumeRoleARN,
+			ExternalID:  s.AssumeRoleExternalID,
+			SessionName: s.AssumeRoleSessionName,
+		}
+	}
+	return aws.Connect(ctx, config)
+}
+
+// AWSKMSKeyStore is a structure containing the configuration
+// for storing keys envelope-encrypted by AWS KMS in either
+// a DynamoDB table or an S3 bucket.
+type AWSKMSKeyStore struct {
+	// Endpoint is an optional AWS KMS endpoint. If empty,
+	// the AWS KMS endpoint of the region is used.
+	Endpoint string
+
+	// Region is the AWS region.
+	Region string
+
+	// KMSKey is the AWS KMS key used to generate and
+	// decrypt data keys. It may be a key ID, key ARN,
+	// alias name or alias ARN.
+	KMSKey string
+
+	// DynamoDBTable is the name of the DynamoDB table storing
+	// the encrypted keys. The table must have a partition key
+	// "name" of type string.
+	DynamoDBTable string
+
+	// DynamoDBEndpoint is an optional DynamoDB endpoint.
+	DynamoDBEndpoint string
+
+	// S3Bucket is the name of the S3 bucket storing the
+	// encrypted keys.
+	S3Bucket string
+
+	// S3Prefix is an optional prefix for the S3 object names.
+	S3Prefix string
+
+	// S3Endpoint is an optional S3 endpoint.
+	S3Endpoint string
+
+	// AccessKey is the access key for authenticating to AWS.
+	AccessKey string
+
+	// SecretKey is the secret key for authenticating to AWS.
+	SecretKey string
+
+	// SessionToken is an optional session token for authenticating
+	// to AWS.
+	SessionToken string
+
+	// WebIdentityRoleARN is the ARN of the IAM role to assume
+	// via STS AssumeRoleWithWebIdentity.
+	WebIdentityRoleARN string
+
+	// WebIdentityTokenFile is the path to the file containing
+	// the web identity token.
+	WebIdentityTokenFile string
+
+	// WebIdentitySessionName is an optional name for the
+	// assumed role session.
+	WebIdentitySessionName string
+
+	// AssumeRoleARN is the ARN of an IAM role to assume via
+	// STS AssumeRole using the credentials above.
+	AssumeRoleARN string
+
+	// AssumeRoleExternalID is an optional external ID
+	// required by the role's trust policy.
+	AssumeRoleExternalID string
+
+	// AssumeRoleSessionName is an optional name for the
+	// assumed role session.
+	AssumeRoleSessionName string
+
+	// IMDSv2Only disables the fallback to IMDSv1 when
+	// fetching credentials from the EC2 instance metadata
+	// service.
+	IMDSv2Only bool
+
+	// HTTP contains optional HTTP client settings.
+	HTTP HTTPClientConfig
+
+	// MaxRetries is the max. number of times the AWS SDK
+	// retries a failed request. If 0, the SDK default is
+	// used. If negative, the SDK does not retry requests.
+	MaxRetries int
+}
+
+// Connect returns a kv.Store that stores key-value pairs encrypted by AWS KMS.
+func (s *AWSKMSKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	config := &aws.KMSConfig{
+		Endpoint: s.Endpoint,
+		Region:   s.Region,
+		KeyARN:   s.KMSKey,
+		Login: aws.Credentials{
+			AccessKey:    s.AccessKey,
+			SecretKey:    s.SecretKey,
+			SessionToken: s.SessionToken,
+			IMDSv2Only:   s.IMDSv2Only,
+		},
+		HTTP:       keystore.HTTPConfig(s.HTTP),
+		MaxRetries: s.MaxRetries,
+	}
+	if s.DynamoDBTable != "" {
+		config.DynamoDB = &aws.DynamoDBConfig{
+			Table:    s.DynamoDBTable,
+			Endpoint: s.DynamoDBEndpoint,
+		}
+	}
+	if s.S3Bucket != "" {
+		config.S3 = &aws.S3Config{
+			Bucket:   s.S3Bucket,
+			Prefix:   s.S3Prefix,
+			Endpoint: s.S3Endpoint,
+		}
+	}
+	if s.WebIdentityRoleARN != "" || s.WebIdentityTokenFile != "" {
+		config.Login.WebIdentity = &aws.WebIdentity{
+			RoleARN:     s.WebIdentityRoleARN,
+			TokenFile:   s.WebIdentityTokenFile,
+			SessionName: s.WebIdentitySessionName,
+		}
+	}
+	if s.AssumeRoleARN != "" {
+		config.Login.AssumeRole = &aws.AssumeRole{
+			RoleARN:     s.AssumeRoleARN,
+			ExternalID:  s.AssumeRoleExternalID,
+			SessionName: s.AssumeRoleSessionName,
+		}
+	}
+	return aws.ConnectKMS(ctx, config)
+}
+
+// AzureKeyVaultKeyStore is a structure containing the
+// configuration for Azure KeyVault.
+type AzureKeyVaultKeyStore struct {
+	// Endpoint is the Azure KeyVault endpoint.
+	Endpoint string
+
+	// TenantID is the ID of the Azure KeyVault tenant.
+	TenantID string
+
+	// ClientID is the ID of the client accessing
+	// Azure KeyVault.
+	ClientID string
+
+	// ClientSecret is the client secret accessing the
+	// Azure KeyVault.
+	ClientSecret string
+
+	// ClientCertificate is the path to a PKCS#12 file
+	// containing the client certificate and private key
+	// used to access the Azure KeyVault. It is mutually
+	// exclusive with ClientSecret.
+	ClientCertificate string
+
+	// ClientCertificatePassword is an optional password
+	// to decrypt the ClientCertificate file.
+	ClientCertificatePassword string
+
+	// ManagedIdentityClientID is the client ID of the
+	// Azure user-assigned managed identity that access
+	// the KeyVault.
+	ManagedIdentityClientID string
+
+	// SystemManagedIdentity indicates whether the Azure
+	// system-assigned managed identity should be used to
+	// access the KeyVault.
+	SystemManagedIdentity bool
+}
+
+// Connect returns a kv.Store that stores key-value pairs on Azure KeyVault.
+func (s *AzureKeyVaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	if (s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "" || s.ClientCertificate != "") && (s.ManagedIdentityClientID != "" || s.SystemManagedIdentity) {
+		return nil, errors.New("edge: failed to connect to Azure KeyVault: more than one authentication method specified")
+	}
+	if s.ClientSecret != "" && s.ClientCertificate != "" {
+		return nil, errors.New("edge: failed to connect to Azure KeyVault: client secret and certificate are mutually exclusive")
+	}
+	switch {
+	case s.ClientCertificate != "":
+		creds := azure.CertificateCredentials{
+			TenantID:            s.TenantID,
+			ClientID:            s.ClientID,
+			CertificatePath:     s.ClientCertificate,
+			CertificatePassword: s.ClientCertificatePassword,
+		}
+		return azure.ConnectWithCertificate(ctx, s.Endpoint, creds)
+	case s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "":
+		creds := azure.Credentials{
+			TenantID: s.TenantID,
+			ClientID: s.ClientID,
+			Secret:   s.ClientSecret,
+		}
+		return azure.ConnectWithCredentials(ctx, s.Endpoint, creds)
+	case s.ManagedIdentityClientID != "" || s.SystemManagedIdentity:
+		creds := azure.ManagedIdentity{
+			ClientID: s.ManagedIdentityClientID,
+		}
+		return azure.ConnectWithIdentity(ctx, s.Endpoint, creds)
+	default:
+		return nil, errors.New("edge: failed to connect to Azure KeyVault: no authentication method specified")
+	}
+}
+
+// EntrustKeyControlKeyStore is a structure containing the
+// configuration for Entrust KeyControl.
+type EntrustKeyControlKeyStore struct {
+	// Endpoint is the Entrust KeyControl endpoint.
+	Endpoint string
+
+	// VaultID is the KeyControl Vault UUID.
+	VaultID string
+
+	// BoxID is the KeyControl box ID or name within the Vault.
+	BoxID string
+
+	// Username is the username used for authentication.
+	Username string
+
+	// Password is the password associated with the provided username.
+	Password string
 
-	// Engine is the Hashicorp Vault K/V engine path.
-	// If empty, defaults to "kv".
-	Engine string
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the KeyControl server.
+	//
+	// If empty, the OS default root CA set is
+	// used.
+	CAPath string
+}
 
-	// Prefix is an optional prefix / directory within the
-	// K/V engine.
-	// If empty, keys will be stored at the K/V engine top
-	// level.
+// Connect returns a kv.Store that stores key-value pairs on Entrust KeyControl.
+func (s *EntrustKeyControlKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	var rootCAs *x509.CertPool
+	if s.CAPath != "" {
+		ca, err := https.CertPoolFromFile(s.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		rootCAs = ca
+	}
+	return entrust.Login(ctx, &entrust.Config{
+		Endpoint: s.Endpoint,
+		VaultID:  s.VaultID,
+		BoxID:    s.BoxID,
+		Username: s.Username,
+		Password: s.Password,
+		TLS: &tls.Config{
+			RootCAs: rootCAs,
+		},
+	})
+}
+
+// PKCS11KeyStore is a structure containing the
+// configuration for a PKCS#11 token - e.g. a HSM.
+//
+// Loading a PKCS#11 module requires cgo. The official
+// release binaries and container images are built with
+// CGO_ENABLED=0 and cannot connect to a PKCS#11 token.
+// Build KES with CGO_ENABLED=1 to use this key store.
+type PKCS11KeyStore struct {
+	// Module is the path to the PKCS#11 library
+	// provided by the HSM vendor.
+	Module string
+
+	// Slot is the ID of the slot containing the
+	// token. It is ignored if Token is not empty.
+	Slot uint
+
+	// Token is the label of the token. If not
+	// empty, the first slot containing a token
+	// with this label is used.
+	Token string
+
+	// PIN is the user PIN for the token.
+	PIN string
+
+	// Prefix is an optional prefix for the labels
+	// of the data objects stored on the token.
 	Prefix string
+}
 
-	// AppRole contains the Vault AppRole authentication
-	// method credentials.
-	AppRole *VaultAppRoleAuth
+// Connect returns a kv.Store that stores key-value pairs on a PKCS#11 token.
+func (s *PKCS11KeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return pkcs11.Connect(ctx, &pkcs11.Config{
+		Module:     s.Module,
+		Slot:       s.Slot,
+		TokenLabel: s.Token,
+		PIN:        s.PIN,
+		Prefix:     s.Prefix,
+	})
+}
 
-	// Kubernetes contains the Vault Kubernetes authentication
-	// method credentials.
-	Kubernetes *VaultKubernetesAuth
+// KubernetesKeyStore is a structure containing the
+// configuration for storing keys as Kubernetes Secrets.
+type KubernetesKeyStore struct {
+	// Endpoint is the Kubernetes API server endpoint.
+	// If empty, the in-cluster endpoint is used.
+	Endpoint string
 
-	// Transit contains the Vault transit encryption engine
-	// configuration for en/decrypting K/V entries with a
-	// key managed by Vault.
+	// Namespace is the namespace of the Secrets. If
+	// empty, the namespace of the service account is
+	// used.
+	Namespace string
+
+	// Prefix is an optional prefix for the names
+	// of the Secrets.
+	Prefix string
+
+	// TokenFile is the path to the service account
+	// token. If empty, the token mounted into the
+	// pod is used.
+	TokenFile string
+
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the API server.
 	//
-	// This is an optional and additional layer of encryption.
-	// Since Vault manages and encrypts K/V values in any case,
-	// using the transit engine is usually not necessary.
-	Transit *VaultTransit
+	// If empty, the CA certificate mounted into
+	// the pod is used.
+	CAPath string
+}
+
+// Connect returns a kv.Store that stores key-value pairs as Kubernetes Secrets.
+func (s *KubernetesKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return kubernetes.Connect(ctx, &kubernetes.Config{
+		Endpoint:  s.Endpoint,
+		Namespace: s.Namespace,
+		Prefix:    s.Prefix,
+		TokenFile: s.TokenFile,
+		CAPath:    s.CAPath,
+	})
+}
+
+// EtcdKeyStore is a structure containing the
+// configuration for an etcd v3 cluster.
+type EtcdKeyStore struct {
+	// Endpoints is a list of etcd cluster endpoints.
+	Endpoints []string
+
+	// Prefix is an optional prefix for the names
+	// of the keys stored on etcd.
+	Prefix string
+
+	// LeaseTTL is the TTL of the lease used to check
+	// the health of the etcd cluster. If 0, defaults
+	// to 10s.
+	LeaseTTL time.Duration
+
+	// Username and Password are optional etcd
+	// user credentials.
+	Username string
+	Password string
 
 	// PrivateKey is an optional path to a
 	// TLS private key file containing a
@@ -443,386 +3109,582 @@ type VaultKeyStore struct {
 
 	// CAPath is an optional path to the root
 	// CA certificate(s) for verifying the TLS
-	// certificate of the Hashicorp Vault server.
+	// certificate of the etcd servers.
 	//
 	// If empty, the OS default root CA set is
 	// used.
 	CAPath string
-
-	// StatusPing controls how often to Vault health status
-	// is checked.
-	// If not set, defaults to 10s.
-	StatusPing time.Duration
 }
 
-// VaultAppRoleAuth is a structure containing the configuration
-// for the Hashicorp Vault AppRole authentication method.
-type VaultAppRoleAuth struct {
-	// Engine is the AppRole authentication engine path.
-	// If empty, defaults to "approle".
-	Engine string
+// Connect returns a kv.Store that stores key-value pairs on an etcd cluster.
+func (s *EtcdKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	// Use TLS if any endpoint requires it or
+	// any TLS option has been specified.
+	var tlsConfig *tls.Config
+	for _, endpoint := range s.Endpoints {
+		if strings.HasPrefix(endpoint, "https://") {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		}
+	}
+	if tlsConfig == nil && (s.PrivateKey != "" || s.Certificate != "" || s.CAPath != "") {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if s.PrivateKey != "" || s.Certificate != "" {
+		certificate, err := https.CertificateFromFile(s.Certificate, s.PrivateKey, "")
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = append(tlsConfig.Certificates, certificate)
+	}
+	if s.CAPath != "" {
+		rootCAs, err := https.CertPoolFromFile(s.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return etcd.Connect(ctx, &etcd.Config{
+		Endpoints: s.Endpoints,
+		Username:  s.Username,
+		Password:  s.Password,
+		TLS:       tlsConfig,
+		Prefix:    s.Prefix,
+		LeaseTTL:  s.LeaseTTL,
+	})
+}
 
-	// Namespace is the Vault namespace in which the AppRole
-	// authentication is performed. It can be used to authenticate
-	// in a different namespace compared to the secret engine
-	// namespace. For example, authenticate within the root
-	// namespace but use a team-specific namespace for the secret
-	// engine.
-	//
-	// If empty, the VaultKeyStore namespace is used, if set.
-	// A single "/" is treated as alias for the Vault root
-	// namespace such that no namespace header is sent as part
-	// of the request.
-	Namespace string
+// SQLKeyStore is a structure containing the configuration
+// for storing keys in a PostgreSQL or MySQL database.
+type SQLKeyStore struct {
+	// Driver is the database driver. Either "postgres"
+	// or "mysql".
+	Driver string
 
-	// AppRoleID is the AppRole access ID for authenticating
-	// to Hashicorp Vault via the AppRole method.
-	ID string
+	// DSN is the data source name of the database.
+	DSN string
 
-	// AppRoleSecret is the AppRole access secret for authenticating
-	// to Hashicorp Vault via the AppRole method.
-	Secret string
-}
+	// Table is the name of the database table. If
+	// empty, defaults to "kes_keys".
+	Table string
 
-// VaultKubernetesAuth is a structure containing the configuration
-// for the Hashicorp Vault Kubernetes authentication method.
-type VaultKubernetesAuth struct {
-	// Engine is the Kubernetes authentication engine path.
-	// If empty, defaults to "kubernetes".
-	Engine string
+	// MaxOpenConns is the max. number of open
+	// database connections. If 0, a small default
+	// is used.
+	MaxOpenConns int
 
-	// Namespace is the Vault namespace in which the Kubernetes
-	// authentication is performed. It can be used to authenticate
-	// in a different namespace compared to the secret engine
-	// namespace. For example, authenticate within the root
-	// namespace but use a team-specific namespace for the secret
-	// engine.
-	//
-	// If empty, the VaultKeyStore namespace is used, if set.
-	// A single "/" is treated as alias for the Vault root
-	// namespace such that no namespace header is sent as part
-	// of the request.
-	Namespace string
+	// MasterKey is an optional base64-encoded 256 bit
+	// key used to encrypt all keys before storing them
+	// in the database.
+	MasterKey string
 
-	// KubernetesRole is the login role for authenticating via the
-	// kubernetes authentication method.
-	Role string
+	// MasterKeyFile is an optional path to a file that
+	// contains the base64-encoded master key.
+	MasterKeyFile string
 
-	// KubernetesJWT is either the JWT or a path to a file containing
-	// the JWT for for authenticating via the kubernetes authentication
-	// method.
-	JWT string
-}
+	// PrivateKey is an optional path to a
+	// TLS private key file containing a
+	// TLS private key for mTLS authentication.
+	PrivateKey string
 
-// VaultTransit is a structure containing the configuration
-// for the Hashicorp Vault transit encryption engine.
-type VaultTransit struct {
-	// Engine is the Transit encryption engine path.
-	// If empty, defaults to "transit".
-	Engine string
+	// Certificate is an optional path to a
+	// TLS certificate file containing a
+	// TLS certificate for mTLS authentication.
+	Certificate string
 
-	// KeyName is the name of the key used for en/decryption.
-	KeyName string
+	// CAPath is an optional path to the root
+	// CA certificate(s) for verifying the TLS
+	// certificate of the database.
+	CAPath string
 }
 
-// Connect returns a kv.Store that stores key-value pairs on a Hashicorp Vault server.
-func (s *VaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	if s.AppRole == nil && s.Kubernetes == nil {
-		return nil, errors.New("edge: failed to connect to hashicorp vault: no authentication method specified")
-	}
-	if s.AppRole != nil && s.Kubernetes != nil {
-		return nil, errors.New("edge: failed to connect to hashicorp vault: more than one authentication method specified")
-	}
-	c := &vault.Config{
-		Endpoint:        s.Endpoint,
-		Engine:          s.Engine,
-		APIVersion:      s.APIVersion,
-		Namespace:       s.Namespace,
-		Prefix:          s.Prefix,
-		PrivateKey:      s.PrivateKey,
-		Certificate:     s.Certificate,
-		CAPath:          s.CAPath,
-		StatusPingAfter: s.StatusPing,
+// Connect returns a kv.Store that stores key-value pairs in a SQL database.
+func (s *SQLKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	if s.MasterKey != "" && s.MasterKeyFile != "" {
+		return nil, errors.New("kesconf: invalid sql keystore: master key and master key file are mutually exclusive")
 	}
-	if s.AppRole != nil {
-		c.AppRole = &vault.AppRole{
-			Engine:    s.AppRole.Engine,
-			Namespace: s.AppRole.Namespace,
-			ID:        s.AppRole.ID,
-			Secret:    s.AppRole.Secret,
+
+	var masterKey []byte
+	if s.MasterKey != "" || s.MasterKeyFile != "" {
+		var err error
+		if masterKey, err = readMasterKey("sql", s.MasterKey, s.MasterKeyFile); err != nil {
+			return nil, err
 		}
 	}
-	if s.Kubernetes != nil {
-		c.K8S = &vault.Kubernetes{
-			Engine:    s.Kubernetes.Engine,
-			Namespace: s.Kubernetes.Namespace,
-			Role:      s.Kubernetes.Role,
-			JWT:       s.Kubernetes.JWT,
-		}
+	return sql.Connect(ctx, &sql.Config{
+		Driver:       s.Driver,
+		DSN:          s.DSN,
+		Table:        s.Table,
+		CAPath:       s.CAPath,
+		Certificate:  s.Certificate,
+		PrivateKey:   s.PrivateKey,
+		MasterKey:    masterKey,
+		MaxOpenConns: s.MaxOpenConns,
+	})
+}
+
+// OCIVaultKeyStore is a structure containing the
+// configuration for an Oracle Cloud Infrastructure
+// (OCI) Vault.
+type OCIVaultKeyStore struct {
+	// Region is the OCI region of the Vault.
+	Region string
+
+	// VaultID is the OCID of the Vault.
+	VaultID string
+
+	// CompartmentID is the OCID of the compartment
+	// in which secrets are created.
+	CompartmentID string
+
+	// KeyID is the OCID of the master encryption
+	// key used to encrypt secrets.
+	KeyID string
+
+	// Prefix is an optional prefix for the names
+	// of the secrets.
+	Prefix string
+
+	// TenancyID is the OCID of the tenancy of the
+	// user owning the API signing key.
+	TenancyID string
+
+	// UserID is the OCID of the user owning the
+	// API signing key.
+	UserID string
+
+	// Fingerprint is the fingerprint of the API
+	// signing key.
+	Fingerprint string
+
+	// PrivateKeyFile is the path to the PEM-encoded
+	// private key of the API signing key.
+	PrivateKeyFile string
+
+	// InstancePrincipal indicates whether KES should
+	// authenticate as the compute instance it is
+	// running on instead of using an API signing key.
+	InstancePrincipal bool
+}
+
+// Connect returns a kv.Store that stores key-value pairs as OCI Vault secrets.
+func (s *OCIVaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	config := &oci.Config{
+		Region:            s.Region,
+		VaultID:           s.VaultID,
+		CompartmentID:     s.CompartmentID,
+		KeyID:             s.KeyID,
+		Prefix:            s.Prefix,
+		InstancePrincipal: s.InstancePrincipal,
 	}
-	if s.Transit != nil {
-		c.Transit = &vault.Transit{
-			Engine:  s.Transit.Engine,
-			KeyName: s.Transit.KeyName,
+	if s.PrivateKeyFile != "" {
+		config.APIKey = &oci.APIKey{
+			TenancyID:      s.TenancyID,
+			UserID:         s.UserID,
+			Fingerprint:    s.Fingerprint,
+			PrivateKeyFile: s.PrivateKeyFile,
 		}
 	}
-	return vault.Connect(ctx, c)
+	return oci.Connect(ctx, config)
 }
 
-// FortanixKeyStore is a structure containing the
-// configuration for Fortanix SDKMS.
-type FortanixKeyStore struct {
-	// Endpoint is the endpoint of the Fortanix KMS.
+// IBMKeyProtectKeyStore is a structure containing the
+// configuration for IBM Key Protect or IBM Hyper Protect
+// Crypto Services.
+type IBMKeyProtectKeyStore struct {
+	// Endpoint is the Key Protect endpoint. If empty,
+	// the public endpoint of the region is used.
+	// Hyper Protect Crypto Services require an
+	// explicit endpoint.
 	Endpoint string
 
-	// GroupID is the ID of the access control group.
-	GroupID string
+	// Region is the IBM Cloud region of the instance.
+	Region string
 
-	// APIKey is the API key for authenticating to
-	// the Fortanix KMS.
-	APIKey string
+	// InstanceID is the ID of the Key Protect or
+	// Hyper Protect Crypto Services instance.
+	InstanceID string
 
-	// CAPath is an optional path to the root
-	// CA certificate(s) for verifying the TLS
-	// certificate of the Hashicorp Vault server.
-	//
-	// If empty, the OS default root CA set is
-	// used.
-	CAPath string
-}
+	// KeyRing is an optional key ring ID in which
+	// keys are created.
+	KeyRing string
 
-// Connect returns a kv.Store that stores key-value pairs on a Fortanix SDKMS server.
-func (s *FortanixKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	return fortanix.Connect(ctx, &fortanix.Config{
-		Endpoint: s.Endpoint,
-		GroupID:  s.GroupID,
-		APIKey:   fortanix.APIKey(s.APIKey),
-		CAPath:   s.CAPath,
+	// Prefix is an optional prefix for the key
+	// aliases.
+	Prefix string
+
+	// APIKey is the IBM Cloud API key.
+	APIKey string
+
+	// IAMEndpoint is an optional IBM Cloud IAM
+	// endpoint.
+	IAMEndpoint string
+}
+
+// Connect returns a kv.Store that stores key-value pairs at IBM Key Protect.
+func (s *IBMKeyProtectKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return ibm.Connect(ctx, &ibm.Config{
+		Endpoint:    s.Endpoint,
+		Region:      s.Region,
+		InstanceID:  s.InstanceID,
+		KeyRing:     s.KeyRing,
+		Prefix:      s.Prefix,
+		APIKey:      s.APIKey,
+		IAMEndpoint: s.IAMEndpoint,
 	})
 }
 
-// KeySecureKeyStore is a structure containing the
-// configuration for Gemalto KeySecure / Thales
-// CipherTrust Manager.
-type KeySecureKeyStore struct {
-	// Endpoint is the endpoint to the KeySecure server.
+// KMIPKeyStore is a structure containing the configuration
+// for a KMIP server, like an enterprise key manager.
+type KMIPKeyStore struct {
+	// Endpoint is the KMIP server endpoint. If it
+	// contains no port, the KMIP port 5696 is used.
 	Endpoint string
 
-	// Token is the refresh authentication token to
-	// access the KeySecure server.
-	Token string
+	// Prefix is an optional prefix for the names
+	// of the secret data objects.
+	Prefix string
 
-	// Domain is the isolated namespace within the
-	// KeySecure server. If empty, defaults to the
-	// top-level / root domain.
-	Domain string
+	// Group is the object group of the secret data
+	// objects. If empty, defaults to "kes".
+	Group string
+
+	// PrivateKey is a path to a TLS private key
+	// file for mTLS authentication.
+	PrivateKey string
+
+	// Certificate is a path to a TLS certificate
+	// file for mTLS authentication.
+	Certificate string
 
 	// CAPath is an optional path to the root
 	// CA certificate(s) for verifying the TLS
-	// certificate of the KeySecure server.
+	// certificate of the KMIP server.
 	//
 	// If empty, the OS default root CA set is
 	// used.
 	CAPath string
 }
 
-// Connect returns a kv.Store that stores key-value pairs on a Gemalto KeySecure instance.
-func (s *KeySecureKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	return gemalto.Connect(ctx, &gemalto.Config{
+// Connect returns a kv.Store that stores key-value pairs on a KMIP server.
+func (s *KMIPKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	certificate, err := https.CertificateFromFile(s.Certificate, s.PrivateKey, "")
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{certificate},
+	}
+	if s.CAPath != "" {
+		rootCAs, err := https.CertPoolFromFile(s.CAPath)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+	return kmip.Connect(ctx, &kmip.Config{
 		Endpoint: s.Endpoint,
-		CAPath:   s.CAPath,
-		Login: gemalto.Credentials{
-			Token:  s.Token,
-			Domain: s.Domain,
-		},
+		Prefix:   s.Prefix,
+		Group:    s.Group,
+		TLS:      tlsConfig,
 	})
 }
 
-// GCPSecretManagerKeyStore is a structure containing the
-// configuration for GCP SecretManager.
-type GCPSecretManagerKeyStore struct {
-	// ProjectID is the GCP project ID.
-	ProjectID string
-
-	// Endpoint is the GCP project ID. If empty,
-	// defaults to:
-	//   secretmanager.googleapis.com:443
-	Endpoint string
-
-	// Scopes are GCP OAuth2 scopes for accessing
-	// GCP APIs. If empty, defaults to the GCP
-	// default scopes.
-	Scopes []string
-
-	// ClientEmail is the Client email of the
-	// GCP service account used to access the
-	// SecretManager.
-	ClientEmail string
+// PluginKeyStore is a structure containing the configuration
+// for a keystore plugin. Plugins are executables that KES
+// starts and talks to via gRPC. See the kesplugin package.
+type PluginKeyStore struct {
+	// Path is the path of the plugin executable.
+	Path string
 
-	// ClientID is the Client ID of the GCP
-	// service account used to access the
-	// SecretManager.
-	ClientID string
+	// Args are optional command line arguments
+	// passed to the plugin.
+	Args []string
 
-	// KeyID is the private key ID of the GCP
-	// service account used to access the
-	// SecretManager.
-	KeyID string
+	// Config is the plugin-specific configuration.
+	// KES passes it to the plugin without further
+	// validation.
+	Config map[string]string
 
-	// Key is the private key of the GCP
-	// service account used to access the
-	// SecretManager.
-	Key string
+	// StartTimeout is the time the plugin has to
+	// start. If 0, defaults to 10s.
+	StartTimeout time.Duration
 }
 
-// Connect returns a kv.Store that stores key-value pairs on GCP SecretManager.
-func (s *GCPSecretManagerKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	return gcp.Connect(ctx, &gcp.Config{
-		Endpoint:  s.Endpoint,
-		ProjectID: s.ProjectID,
-		Scopes:    s.Scopes,
-		Credentials: gcp.Credentials{
-			ClientID: s.ClientID,
-			Client:   s.ClientEmail,
-			KeyID:    s.KeyID,
-			Key:      s.Key,
-		},
+// Connect starts the plugin and returns a kv.Store that
+// stores key-value pairs via the plugin.
+func (s *PluginKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
+	return plugin.Connect(ctx, &plugin.Config{
+		Path:         s.Path,
+		Args:         s.Args,
+		Config:       s.Config,
+		StartTimeout: s.StartTimeout,
 	})
 }
 
-// AWSSecretsManagerKeyStore is a structure containing the
-// configuration for AWS SecretsManager.
-type AWSSecretsManagerKeyStore struct {
-	// Endpoint is the AWS SecretsManager endpoint.
-	// AWS SecretsManager endpoints have the following
-	// schema:
-	//  secrestmanager[-fips].<region>.amanzonaws.com
-	Endpoint string
-
-	// Region is the AWS region the SecretsManager is
-	// located.
-	Region string
-
-	// KMSKey is the AWS-KMS key ID (CMK-ID) used to
-	// to en/decrypt secrets managed by the SecretsManager.
-	// If empty, the default AWS KMS key is used.
-	KMSKey string
-
-	// AccessKey is the access key for authenticating to AWS.
-	AccessKey string
-
-	// SecretKey is the secret key for authenticating to AWS.
-	SecretKey string
-
-	// SessionToken is an optional session token for authenticating
-	// to AWS.
-	SessionToken string
+// parseKeyRules converts the key rules of a policy into
+// kes.KeyRules. The rules are validated by the server.
+func parseKeyRules(rules []ymlKeyRule) []kes.KeyRule {
+	if len(rules) == 0 {
+		return nil
+	}
+	keys := make([]kes.KeyRule, 0, len(rules))
+	for _, rule := range rules {
+		operations := make([]string, 0, len(rule.Operations))
+		for _, op := range rule.Operations {
+			operations = append(operations, op.Value)
+		}
+		keys = append(keys, kes.KeyRule{
+			Pattern:    rule.Pattern.Value,
+			Operations: operations,
+		})
+	}
+	return keys
 }
 
-// Connect returns a kv.Store that stores key-value pairs on AWS SecretsManager.
-func (s *AWSSecretsManagerKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	return aws.Connect(ctx, &aws.Config{
-		Addr:     s.Endpoint,
-		Region:   s.Region,
-		KMSKeyID: s.KMSKey,
-		Login: aws.Credentials{
-			AccessKey:    s.AccessKey,
-			SecretKey:    s.SecretKey,
-			SessionToken: s.SessionToken,
-		},
-	})
+// parseIPPrefix parses s as CIDR network, e.g. '10.0.0.0/8',
+// or as single IP address, e.g. '192.168.1.7'.
+func parseIPPrefix(s string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		addr, aErr := netip.ParseAddr(s)
+		if aErr != nil {
+			return netip.Prefix{}, err
+		}
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+	return prefix.Masked(), nil
 }
 
-// AzureKeyVaultKeyStore is a structure containing the
-// configuration for Azure KeyVault.
-type AzureKeyVaultKeyStore struct {
-	// Endpoint is the Azure KeyVault endpoint.
-	Endpoint string
-
-	// TenantID is the ID of the Azure KeyVault tenant.
-	TenantID string
+// parsePolicyConditions parses the conditions section of a
+// policy. It returns nil if c is nil or empty.
+func parsePolicyConditions(c *ymlPolicyConditions) (*kes.PolicyConditions, error) {
+	if c == nil || (len(c.SourceIP) == 0 && len(c.Time) == 0 && len(c.TLSSAN) == 0) {
+		return nil, nil
+	}
 
-	// ClientID is the ID of the client accessing
-	// Azure KeyVault.
-	ClientID string
+	conditions := &kes.PolicyConditions{}
+	for _, v := range c.SourceIP {
+		prefix, err := parseIPPrefix(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source IP '%s'", v.Value)
+		}
+		conditions.SourceIPs = append(conditions.SourceIPs, prefix)
+	}
+	for _, t := range c.Time {
+		var (
+			window kes.TimeWindow
+			err    error
+		)
+		for _, day := range t.Days {
+			weekday, err := parseWeekday(day.Value)
+			if err != nil {
+				return nil, err
+			}
+			window.Days = append(window.Days, weekday)
+		}
+		if window.Start, err = parseTimeOfDay(t.Start.Value); err != nil {
+			return nil, fmt.Errorf("invalid time window start: %v", err)
+		}
+		if window.End, err = parseTimeOfDay(t.End.Value); err != nil {
+			return nil, fmt.Errorf("invalid time window end: %v", err)
+		}
+		if window.Start == window.End {
+			return nil, errors.New("invalid time window: start and end are equal")
+		}
+		if t.Timezone.Value != "" {
+			if window.Location, err = time.LoadLocation(t.Timezone.Value); err != nil {
+				return nil, fmt.Errorf("invalid time window timezone '%s'", t.Timezone.Value)
+			}
+		}
+		conditions.TimeWindows = append(conditions.TimeWindows, window)
+	}
+	for _, san := range c.TLSSAN {
+		if san.Value == "" {
+			return nil, errors.New("invalid TLS SAN: empty")
+		}
+		conditions.TLSSANs = append(conditions.TLSSANs, san.Value)
+	}
+	return conditions, nil
+}
 
-	// ClientSecret is the client secret accessing the
-	// Azure KeyVault.
-	ClientSecret string
+// parseRateLimit parses the rate limit section of a policy.
+// It returns nil if r is nil.
+func parseRateLimit(r *ymlRateLimit) (*kes.RateLimit, error) {
+	if r == nil {
+		return nil, nil
+	}
+	if r.Rate.Value <= 0 {
+		return nil, fmt.Errorf("invalid rate limit '%v': must be a positive number of requests per second", r.Rate.Value)
+	}
+	if r.Burst.Value < 0 {
+		return nil, fmt.Errorf("invalid rate limit burst '%d': must not be negative", r.Burst.Value)
+	}
 
-	// ManagedIdentityClientID is the client ID of the
-	// Azure managed identity that access the KeyVault.
-	ManagedIdentityClientID string
+	limit := &kes.RateLimit{
+		Rate:  r.Rate.Value,
+		Burst: r.Burst.Value,
+	}
+	switch strings.ToLower(r.Per.Value) {
+	case "", "identity":
+	case "policy":
+		limit.PerPolicy = true
+	default:
+		return nil, fmt.Errorf("invalid rate limit scope '%s': must be 'identity' or 'policy'", r.Per.Value)
+	}
+	return limit, nil
 }
 
-// Connect returns a kv.Store that stores key-value pairs on Azure KeyVault.
-func (s *AzureKeyVaultKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	if (s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "") && s.ManagedIdentityClientID != "" {
-		return nil, errors.New("edge: failed to connect to Azure KeyVault: more than one authentication method specified")
+// parseContextPolicy parses the required encryption
+// context fields of a policy.
+func parseContextPolicy(fields map[string]env[string]) (*kes.ContextPolicy, error) {
+	if len(fields) == 0 {
+		return nil, nil
 	}
-	switch {
-	case s.TenantID != "" || s.ClientID != "" || s.ClientSecret != "":
-		creds := azure.Credentials{
-			TenantID: s.TenantID,
-			ClientID: s.ClientID,
-			Secret:   s.ClientSecret,
+
+	policy := &kes.ContextPolicy{
+		Fields: make(map[string]string, len(fields)),
+	}
+	for name, pattern := range fields {
+		if name == "" {
+			return nil, errors.New("invalid context: empty field name")
 		}
-		return azure.ConnectWithCredentials(ctx, s.Endpoint, creds)
-	case s.ManagedIdentityClientID != "":
-		creds := azure.ManagedIdentity{
-			ClientID: s.ManagedIdentityClientID,
+		if pattern.Value == "" {
+			return nil, fmt.Errorf("invalid context field '%s': empty pattern", name)
 		}
-		return azure.ConnectWithIdentity(ctx, s.Endpoint, creds)
-	default:
-		return nil, errors.New("edge: failed to connect to Azure KeyVault: no authentication method specified")
+		if _, err := path.Match(pattern.Value, ""); err != nil {
+			return nil, fmt.Errorf("invalid context field '%s': invalid pattern '%s'", name, pattern.Value)
+		}
+		policy.Fields[name] = pattern.Value
 	}
+	return policy, nil
 }
 
-// EntrustKeyControlKeyStore is a structure containing the
-// configuration for Entrust KeyControl.
-type EntrustKeyControlKeyStore struct {
-	// Endpoint is the Entrust KeyControl endpoint.
-	Endpoint string
+// parseWeekday parses s as day of the week, like 'mon' or 'Monday'.
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if name := d.String(); strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day of the week '%s'", s)
+}
 
-	// VaultID is the KeyControl Vault UUID.
-	VaultID string
+// parseTimeOfDay parses s as 24-hour clock time, like '09:00' or
+// '24:00', and returns the offset since midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s'", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
 
-	// BoxID is the KeyControl box ID or name within the Vault.
-	BoxID string
+// parseOfflinePolicy parses s as offline policy. An empty
+// string is parsed as the default kes.OfflineStale policy.
+func parseEvictionPolicy(s string) (kes.EvictionPolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "lru":
+		return kes.EvictLRU, nil
+	case "none":
+		return kes.EvictNone, nil
+	default:
+		return 0, fmt.Errorf("kesconf: invalid cache eviction policy '%s'", s)
+	}
+}
 
-	// Username is the username used for authentication.
-	Username string
+func parseOfflinePolicy(s string) (kes.OfflinePolicy, error) {
+	switch strings.ToLower(s) {
+	case "", "stale":
+		return kes.OfflineStale, nil
+	case "decrypt-only":
+		return kes.OfflineDecryptOnly, nil
+	case "fail-closed":
+		return kes.OfflineFailClosed, nil
+	default:
+		return 0, fmt.Errorf("kesconf: invalid cache offline policy '%s'", s)
+	}
+}
 
-	// Password is the password associated with the provided username.
-	Password string
+// parseMemoryLock parses s as memory lock mode. An empty
+// string is parsed as MemoryLockAuto.
+func parseMemoryLock(s string) (MemoryLock, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return MemoryLockAuto, nil
+	case "required":
+		return MemoryLockRequired, nil
+	case "off":
+		return MemoryLockOff, nil
+	default:
+		return 0, fmt.Errorf("kesconf: invalid memory lock '%s'", s)
+	}
+}
 
-	// CAPath is an optional path to the root
-	// CA certificate(s) for verifying the TLS
-	// certificate of the KeyControl server.
-	//
-	// If empty, the OS default root CA set is
-	// used.
-	CAPath string
+// parseSSHPolicy parses the SSH section of a policy.
+func parseSSHPolicy(p *ymlSSHPolicy) (*kes.SSHPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if len(p.Principals) == 0 {
+		return nil, errors.New("invalid ssh: no principals specified")
+	}
+	if p.MaxTTL.Value < 0 {
+		return nil, fmt.Errorf("invalid ssh max_ttl '%v': must not be negative", p.MaxTTL.Value)
+	}
+
+	policy := &kes.SSHPolicy{
+		Principals: make([]string, 0, len(p.Principals)),
+		MaxTTL:     p.MaxTTL.Value,
+		Host:       p.Host.Value,
+	}
+	for _, pattern := range p.Principals {
+		if pattern.Value == "" {
+			return nil, errors.New("invalid ssh: empty principal pattern")
+		}
+		if _, err := path.Match(pattern.Value, ""); err != nil {
+			return nil, fmt.Errorf("invalid ssh principal pattern '%s'", pattern.Value)
+		}
+		policy.Principals = append(policy.Principals, pattern.Value)
+	}
+	return policy, nil
 }
 
-// Connect returns a kv.Store that stores key-value pairs on Entrust KeyControl.
-func (s *EntrustKeyControlKeyStore) Connect(ctx context.Context) (kes.KeyStore, error) {
-	var rootCAs *x509.CertPool
-	if s.CAPath != "" {
-		ca, err := https.CertPoolFromFile(s.CAPath)
+// parseCertPolicy parses the cert section of a policy.
+func parseCertPolicy(p *ymlCertPolicy) (*kes.CertPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	if len(p.Names) == 0 {
+		return nil, errors.New("invalid cert: no names specified")
+	}
+	if p.MaxTTL.Value < 0 {
+		return nil, fmt.Errorf("invalid cert max_ttl '%v': must not be negative", p.MaxTTL.Value)
+	}
+
+	policy := &kes.CertPolicy{
+		Names:  make([]string, 0, len(p.Names)),
+		MaxTTL: p.MaxTTL.Value,
+		Server: p.Server.Value,
+	}
+	for _, pattern := range p.Names {
+		if pattern.Value == "" {
+			return nil, errors.New("invalid cert: empty name pattern")
+		}
+		if _, err := path.Match(pattern.Value, ""); err != nil {
+			return nil, fmt.Errorf("invalid cert name pattern '%s'", pattern.Value)
+		}
+		policy.Names = append(policy.Names, pattern.Value)
+	}
+	for _, v := range p.IPs {
+		prefix, err := parseIPPrefix(v.Value)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("invalid cert IP '%s'", v.Value)
 		}
-		rootCAs = ca
+		policy.IPs = append(policy.IPs, prefix)
 	}
-	return entrust.Login(ctx, &entrust.Config{
-		Endpoint: s.Endpoint,
-		VaultID:  s.VaultID,
-		BoxID:    s.BoxID,
-		Username: s.Username,
-		Password: s.Password,
-		TLS: &tls.Config{
-			RootCAs: rootCAs,
-		},
-	})
+	return policy, nil
 }
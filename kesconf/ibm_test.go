@@ -0,0 +1,41 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesconf_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/minio/kes/kesconf"
+)
+
+var ibmConfigFile = flag.String("ibm.config", "", "Path to a KES config file with IBM Key Protect config")
+
+func TestIBMKeyProtect(t *testing.T) {
+	if *ibmConfigFile == "" {
+		t.Skip("IBM Key Protect tests disabled. Use -ibm.config=<FILE> to enable them")
+	}
+
+	config, err := kesconf.ReadFile(*ibmConfigFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := config.KeyStore.(*kesconf.IBMKeyProtectKeyStore); !ok {
+		t.Fatalf("Invalid Keystore: want %T - got %T", config.KeyStore, &kesconf.IBMKeyProtectKeyStore{})
+	}
+
+	ctx, cancel := testingContext(t)
+	defer cancel()
+
+	store, err := config.KeyStore.Connect(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Create", func(t *testing.T) { testCreate(ctx, store, t, RandString(ranStringLength)) })
+	t.Run("Get", func(t *testing.T) { testGet(ctx, store, t, RandString(ranStringLength)) })
+	t.Run("Status", func(t *testing.T) { testStatus(ctx, store, t) })
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kesconf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// resolveIncludes replaces the top-level 'include' section of
+// the config document with the content of the referenced files.
+// Relative paths and glob patterns are resolved relative to dir.
+//
+// Each included file must contain a YAML mapping. It is merged
+// into the config document recursively. Included files must not
+// redefine values of the config document or of other included
+// files, and must not include further files.
+func resolveIncludes(root *yaml.Node, dir string) error {
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	var patterns []string
+	for i := 0; i < len(doc.Content)-1; i += 2 {
+		if doc.Content[i].Value != "include" {
+			continue
+		}
+		include := doc.Content[i+1]
+		switch include.Kind {
+		case yaml.ScalarNode:
+			if include.Tag != "!!null" && include.Value != "" {
+				patterns = append(patterns, include.Value)
+			}
+		case yaml.SequenceNode:
+			for _, n := range include.Content {
+				if n.Kind != yaml.ScalarNode {
+					return fmt.Errorf("kesconf: invalid include in line '%d'", n.Line)
+				}
+				patterns = append(patterns, n.Value)
+			}
+		default:
+			return fmt.Errorf("kesconf: invalid include in line '%d'", include.Line)
+		}
+		doc.Content = append(doc.Content[:i], doc.Content[i+2:]...)
+		break
+	}
+
+	for _, pattern := range patterns {
+		pattern, err := expandEnv(strings.TrimSpace(pattern))
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+		filenames, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("kesconf: invalid include '%s': %v", pattern, err)
+		}
+		if len(filenames) == 0 {
+			return fmt.Errorf("kesconf: include '%s' does not match any file", pattern)
+		}
+		for _, filename := range filenames {
+			if err = includeFile(doc, filename); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// includeFile merges the YAML mapping in the given file into doc.
+func includeFile(doc *yaml.Node, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("kesconf: failed to include '%s': %v", filename, err)
+	}
+	defer f.Close()
+
+	var node yaml.Node
+	if err = yaml.NewDecoder(f).Decode(&node); err != nil {
+		return fmt.Errorf("kesconf: failed to include '%s': %v", filename, err)
+	}
+	if node.Kind != yaml.DocumentNode || len(node.Content) != 1 || node.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("kesconf: failed to include '%s': file does not contain a YAML mapping", filename)
+	}
+	for i := 0; i < len(node.Content[0].Content)-1; i += 2 {
+		if node.Content[0].Content[i].Value == "include" {
+			return fmt.Errorf("kesconf: failed to include '%s': nested includes are not supported", filename)
+		}
+	}
+	if err = mergeMapping(doc, node.Content[0], ""); err != nil {
+		return fmt.Errorf("kesconf: failed to include '%s': %v", filename, err)
+	}
+	return nil
+}
+
+// mergeMapping merges the YAML mapping src into dst. Nested
+// mappings are merged recursively. It returns an error if
+// both mappings contain the same key and at least one of its
+// values is not a mapping.
+func mergeMapping(dst, src *yaml.Node, path string) error {
+	for i := 0; i < len(src.Content)-1; i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+
+		var existing *yaml.Node
+		for j := 0; j < len(dst.Content)-1; j += 2 {
+			if dst.Content[j].Value == key.Value {
+				existing = dst.Content[j+1]
+				break
+			}
+		}
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			if err := mergeMapping(existing, value, path+key.Value+"."); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("'%s' in line '%d' is already defined", path+key.Value, key.Line)
+		}
+	}
+	return nil
+}
+
+// expandEnvNodes replaces env. variable references in all scalar
+// values of the YAML node tree.
+//
+// Values that consist of a single reference, e.g. '${VAR}', are
+// not modified since they get resolved when decoding the value.
+// This preserves the variable name.
+func expandEnvNodes(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode && strings.Contains(node.Value, "${") {
+		if v := strings.TrimSpace(node.Value); strings.HasPrefix(v, "${") && strings.HasSuffix(v, "}") && strings.Count(v, "${") == 1 {
+			return nil
+		}
+		value, err := expandEnv(node.Value)
+		if err != nil {
+			return fmt.Errorf("%v in line '%d'", err, node.Line)
+		}
+		node.Value = value
+		return nil
+	}
+	for _, n := range node.Content {
+		if err := expandEnvNodes(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces all '${VAR}' references in s with the
+// value of the corresponding env. variable. In contrast to
+// os.ExpandEnv, it does not expand '$VAR' references since
+// '$' may be part of secrets, and returns an error if an
+// env. variable is not set.
+func expandEnv(s string) (string, error) {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end < 0 {
+			return "", errors.New("kesconf: unterminated env. variable reference")
+		}
+		end += start
+
+		name := strings.TrimSpace(s[start+2 : end])
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("kesconf: referenced env. variable '%s' not found", name)
+		}
+		b.WriteString(s[:start])
+		b.WriteString(value)
+		s = s[end+1:]
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+// Package kesplugin implements keystore plugins for KES.
+//
+// A keystore plugin is an executable that KES starts as child
+// process when the config file contains a plugin keystore:
+//
+//	keystore:
+//	  plugin:
+//	    path: /usr/local/bin/kes-keystore-example
+//	    config:
+//	      endpoint: https://kms.example.com
+//
+// KES talks to the plugin via gRPC. The KeyStorePlugin service is
+// defined in internal/protobuf/keystore.proto. Plugins written in
+// Go implement a kes.KeyStore and call Serve from their main function.
+//
+// The plugin protocol works as following:
+//   - KES starts the plugin with the environment variables
+//     MagicCookieKey, ProtocolVersionKey and TokenKey, in addition
+//     to its own environment. Plugins exit if the magic cookie does
+//     not match MagicCookieValue, i.e. when not started by KES.
+//   - The plugin listens on a local address and prints one handshake
+//     line to standard output, like "1|unix|/tmp/plugin/plugin.sock".
+//     It consists of the protocol version, the network type, either
+//     "unix" or "tcp", and the network address.
+//   - KES connects to the address and sends the token of TokenKey as
+//     TokenHeader metadata with every RPC. Plugins reject RPCs without
+//     the token.
+//   - KES calls Configure once before any other RPC.
+//   - KES closes the plugin's standard input when closing the keystore.
+//     The plugin should exit once it reaches the end of its standard
+//     input.
+//
+// Anything the plugin writes to standard error, or to standard output
+// after the handshake line, is written to the standard error of KES.
+package kesplugin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/minio/kes"
+	"github.com/minio/kes/internal/keystore"
+	pb "github.com/minio/kes/internal/protobuf"
+	kesdk "github.com/minio/kms-go/kes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	pbd "google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ProtocolVersion is the version of the plugin protocol.
+const ProtocolVersion = 1
+
+// Environment variables set by KES when starting a plugin.
+const (
+	MagicCookieKey     = "KES_PLUGIN_MAGIC_COOKIE"
+	ProtocolVersionKey = "KES_PLUGIN_PROTOCOL_VERSION"
+	TokenKey           = "KES_PLUGIN_TOKEN"
+)
+
+// MagicCookieValue is the value of the MagicCookieKey environment
+// variable. It is not a security measure but prevents plugins from
+// being started by accident.
+const MagicCookieValue = "c4a1b7b0a6c23d9f6e1d8e2b5f3c7a90"
+
+// TokenHeader is the gRPC metadata key of the token that
+// KES sends with every RPC.
+const TokenHeader = "kes-plugin-token"
+
+// ErrNotPlugin is returned by Serve when the plugin has not
+// been started by KES.
+var ErrNotPlugin = errors.New("kesplugin: plugin must be started by KES. Add it to the keystore section of the KES config file")
+
+// OpenFunc returns the KeyStore served by a plugin. It is called
+// with the plugin config of the KES config file.
+type OpenFunc func(ctx context.Context, config map[string]string) (kes.KeyStore, error)
+
+// Serve serves the KeyStore returned by open as keystore plugin
+// until KES closes the plugin. The KeyStore is closed before
+// Serve returns.
+//
+// Serve must be called from the plugin's main function. It uses
+// the plugin's standard input and output for the plugin protocol.
+//
+// The KeyStore should return kes.ErrKeyNotFound and kes.ErrKeyExists
+// of the KES SDK as described by the kes.KeyStore interface. Other
+// errors may be gRPC status errors, like codes.Unavailable if the
+// plugin's backend is not reachable.
+func Serve(open OpenFunc) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return ErrNotPlugin
+	}
+	if v := os.Getenv(ProtocolVersionKey); v != strconv.Itoa(ProtocolVersion) {
+		return fmt.Errorf("kesplugin: unsupported protocol version '%s': plugin supports version %d", v, ProtocolVersion)
+	}
+	token := os.Getenv(TokenKey)
+	if token == "" {
+		return errors.New("kesplugin: no plugin token provided")
+	}
+
+	network, addr := "unix", ""
+	if runtime.GOOS == "windows" {
+		network, addr = "tcp", "127.0.0.1:0"
+	} else {
+		dir, err := os.MkdirTemp("", "kes-plugin-")
+		if err != nil {
+			return fmt.Errorf("kesplugin: %v", err)
+		}
+		defer os.RemoveAll(dir)
+		addr = filepath.Join(dir, "plugin.sock")
+	}
+	listener, err := net.Listen(network, addr)
+	if err != nil {
+		return fmt.Errorf("kesplugin: %v", err)
+	}
+	defer listener.Close()
+
+	srv := &server{open: open}
+	defer srv.Close()
+
+	grpcSrv := grpc.NewServer(grpc.UnaryInterceptor(verifyToken(token)))
+	pb.RegisterKeyStorePluginServer(grpcSrv, srv)
+
+	if _, err = fmt.Fprintf(os.Stdout, "%d|%s|%s\n", ProtocolVersion, listener.Addr().Network(), listener.Addr().String()); err != nil {
+		return fmt.Errorf("kesplugin: %v", err)
+	}
+
+	// KES closes the plugin's standard input when closing the
+	// keystore or when it exits. Hence, the plugin stops as well.
+	go func() {
+		io.Copy(io.Discard, os.Stdin)
+		grpcSrv.Stop()
+	}()
+	if err = grpcSrv.Serve(listener); err != nil {
+		return fmt.Errorf("kesplugin: %v", err)
+	}
+	return nil
+}
+
+// verifyToken returns a gRPC interceptor that rejects RPCs
+// without the plugin token.
+func verifyToken(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if v := md.Get(TokenHeader); len(v) != 1 || subtle.ConstantTimeCompare([]byte(v[0]), []byte(token)) != 1 {
+			return nil, status.Error(codes.Unauthenticated, "invalid plugin token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// server implements the KeyStorePlugin gRPC service
+// for the KeyStore returned by open.
+type server struct {
+	pb.UnimplementedKeyStorePluginServer
+
+	open OpenFunc
+
+	mu    sync.RWMutex
+	store kes.KeyStore
+}
+
+func (s *server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store == nil {
+		return nil
+	}
+	return s.store.Close()
+}
+
+func (s *server) keyStore() (kes.KeyStore, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.store == nil {
+		return nil, status.Error(codes.FailedPrecondition, "plugin not configured")
+	}
+	return s.store, nil
+}
+
+func (s *server) Configure(ctx context.Context, req *pb.PluginConfigureRequest) (*pb.PluginConfigureResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.store != nil {
+		return nil, status.Error(codes.FailedPrecondition, "plugin already configured")
+	}
+	store, err := s.open(ctx, req.Config)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	s.store = store
+	return &pb.PluginConfigureResponse{}, nil
+}
+
+func (s *server) Status(ctx context.Context, _ *pb.PluginStatusRequest) (*pb.PluginStatusResponse, error) {
+	store, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	state, err := store.Status(ctx)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if state.Latency == 0 {
+		state.Latency = time.Since(start)
+	}
+	return &pb.PluginStatusResponse{Latency: pbd.New(state.Latency)}, nil
+}
+
+func (s *server) Create(ctx context.Context, req *pb.PluginCreateRequest) (*pb.PluginCreateResponse, error) {
+	store, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Create(ctx, req.Name, req.Value); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.PluginCreateResponse{}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *pb.PluginDeleteRequest) (*pb.PluginDeleteResponse, error) {
+	store, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Delete(ctx, req.Name); err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.PluginDeleteResponse{}, nil
+}
+
+func (s *server) Get(ctx context.Context, req *pb.PluginGetRequest) (*pb.PluginGetResponse, error) {
+	store, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	value, err := store.Get(ctx, req.Name)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.PluginGetResponse{Value: value}, nil
+}
+
+func (s *server) List(ctx context.Context, req *pb.PluginListRequest) (*pb.PluginListResponse, error) {
+	store, err := s.keyStore()
+	if err != nil {
+		return nil, err
+	}
+	names, continueAt, err := store.List(ctx, req.Prefix, int(req.N))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &pb.PluginListResponse{Names: names, ContinueAt: continueAt}, nil
+}
+
+// toStatus converts a KeyStore error into a gRPC status error.
+// Status errors returned by the KeyStore are passed through.
+func toStatus(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case errors.Is(err, kesdk.ErrKeyNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, kesdk.ErrKeyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	}
+	if _, ok := keystore.IsUnreachable(err); ok {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
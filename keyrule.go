@@ -0,0 +1,128 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// KeyRule allows a set of key operations on all keys whose
+// names match a pattern.
+//
+// For example, a KeyRule with the pattern "team-a-*" and the
+// operations "encrypt" and "decrypt" allows encrypting and
+// decrypting data with any key whose name starts with "team-a-".
+type KeyRule struct {
+	// Pattern is a glob pattern, as defined by path.Match,
+	// that is matched against key names.
+	Pattern string
+
+	// Operations are the allowed key operations. An operation
+	// is the name of a key API, like "encrypt" for the API
+	// /v1/key/encrypt/<name>. The operation "*" allows all
+	// key operations.
+	Operations []string
+}
+
+// keyOperations are the key operations a KeyRule may allow.
+// Key APIs that do not refer to a single key by name, like
+// listing keys, cannot be allowed by a KeyRule.
+var keyOperations = []string{
+	api.PathKeyCreate,
+	api.PathKeyImport,
+	api.PathKeyExport,
+	api.PathKeyRestore,
+	api.PathKeyRotate,
+	api.PathKeyDescribe,
+	api.PathKeyDelete,
+	api.PathKeyUndelete,
+	api.PathKeyGenerate,
+	api.PathKeyEncrypt,
+	api.PathKeyDecrypt,
+	api.PathKeyHMAC,
+	api.PathKeyECDH,
+	api.PathKeySign,
+	api.PathKeyVerify,
+}
+
+// validate returns an error if the rule's pattern is malformed
+// or it contains an unknown operation.
+func (r *KeyRule) validate() error {
+	if r.Pattern == "" {
+		return errors.New("key rule pattern is empty")
+	}
+	if _, err := path.Match(r.Pattern, ""); err != nil {
+		return fmt.Errorf("invalid key rule pattern '%s': %v", r.Pattern, err)
+	}
+	if len(r.Operations) == 0 {
+		return fmt.Errorf("key rule '%s' has no operations", r.Pattern)
+	}
+	for _, op := range r.Operations {
+		if op == "*" {
+			continue
+		}
+		if !slices.Contains(keyOperations, "/v1/key/"+op+"/") {
+			return fmt.Errorf("key rule '%s' contains unknown operation '%s'", r.Pattern, op)
+		}
+	}
+	return nil
+}
+
+// matchKeyRule returns the first key rule that allows the API
+// path, or nil if no rule allows it.
+func matchKeyRule(rules []KeyRule, urlPath string) *KeyRule {
+	if len(rules) == 0 {
+		return nil
+	}
+
+	i := slices.IndexFunc(keyOperations, func(p string) bool { return strings.HasPrefix(urlPath, p) })
+	if i < 0 {
+		return nil
+	}
+	name := strings.TrimPrefix(urlPath, keyOperations[i])
+	if name == "" || strings.Contains(name, "/") {
+		return nil
+	}
+	op := strings.TrimSuffix(strings.TrimPrefix(keyOperations[i], "/v1/key/"), "/")
+
+	for i := range rules {
+		if !slices.Contains(rules[i].Operations, op) && !slices.Contains(rules[i].Operations, "*") {
+			continue
+		}
+		if ok, _ := path.Match(rules[i].Pattern, name); ok {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// verify returns an error if the policy of the identity does not
+// allow the request. A request is allowed if none of the policy's
+// deny patterns and either one of its allow patterns or one of
+// its key rules matches.
+func (e *identityEntry) verify(req *http.Request) error {
+	if len(e.Keys) == 0 {
+		return e.Policy.Verify(req)
+	}
+
+	if matchPolicyPattern(e.Deny, req.URL.Path) != "" {
+		return kes.ErrNotAllowed
+	}
+	if matchPolicyPattern(e.Allow, req.URL.Path) != "" {
+		return nil
+	}
+	if matchKeyRule(e.Keys, req.URL.Path) != nil {
+		return nil
+	}
+	return kes.ErrNotAllowed
+}
@@ -0,0 +1,88 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/minio/kms-go/kes"
+)
+
+func TestKeyRuleValidate(t *testing.T) {
+	t.Parallel()
+
+	for i, test := range keyRuleValidateTests {
+		err := test.Rule.validate()
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: validate should have failed", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to validate key rule: %v", i, err)
+		}
+	}
+}
+
+var keyRuleValidateTests = []struct {
+	Rule       KeyRule
+	ShouldFail bool
+}{
+	{Rule: KeyRule{Pattern: "team-a-*", Operations: []string{"encrypt", "decrypt"}}},         // 0
+	{Rule: KeyRule{Pattern: "*", Operations: []string{"*"}}},                                 // 1
+	{Rule: KeyRule{Pattern: "my-key", Operations: []string{"hmac", "sign"}}},                 // 2
+	{Rule: KeyRule{Pattern: "", Operations: []string{"encrypt"}}, ShouldFail: true},          // 3
+	{Rule: KeyRule{Pattern: "team-[", Operations: []string{"encrypt"}}, ShouldFail: true},    // 4
+	{Rule: KeyRule{Pattern: "team-a-*"}, ShouldFail: true},                                   // 5
+	{Rule: KeyRule{Pattern: "team-a-*", Operations: []string{"list"}}, ShouldFail: true},     // 6
+	{Rule: KeyRule{Pattern: "team-a-*", Operations: []string{"encrypt/"}}, ShouldFail: true}, // 7
+}
+
+func TestIdentityEntryVerify(t *testing.T) {
+	t.Parallel()
+
+	entry := identityEntry{
+		Name: "my-policy",
+		Policy: &kes.Policy{
+			Allow: map[string]kes.Rule{"/v1/key/generate/my-key": {}},
+			Deny:  map[string]kes.Rule{"/v1/key/decrypt/team-a-secret": {}},
+		},
+		policyRules: policyRules{
+			Keys: []KeyRule{
+				{Pattern: "team-a-*", Operations: []string{"encrypt", "decrypt"}},
+				{Pattern: "team-b-?", Operations: []string{"*"}},
+			},
+		},
+	}
+	for i, test := range identityEntryVerifyTests {
+		req := &http.Request{URL: &url.URL{Path: test.Path}}
+
+		err := entry.verify(req)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: '%s' should not be allowed", i, test.Path)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: '%s' should be allowed: %v", i, test.Path, err)
+		}
+	}
+}
+
+var identityEntryVerifyTests = []struct {
+	Path       string
+	ShouldFail bool
+}{
+	{Path: "/v1/key/generate/my-key"},                           // 0
+	{Path: "/v1/key/encrypt/team-a-key"},                        // 1
+	{Path: "/v1/key/decrypt/team-a-key"},                        // 2
+	{Path: "/v1/key/decrypt/team-a-secret", ShouldFail: true},   // 3
+	{Path: "/v1/key/generate/team-a-key", ShouldFail: true},     // 4
+	{Path: "/v1/key/encrypt/team-c-key", ShouldFail: true},      // 5
+	{Path: "/v1/key/delete/team-b-1"},                           // 6
+	{Path: "/v1/key/delete/team-b-10", ShouldFail: true},        // 7
+	{Path: "/v1/key/encrypt/", ShouldFail: true},                // 8
+	{Path: "/v1/key/encrypt/team-a-key/x", ShouldFail: true},    // 9
+	{Path: "/v1/key/list/team-a-*", ShouldFail: true},           // 10
+	{Path: "/v1/key/bulk/encrypt/team-a-key", ShouldFail: true}, // 11
+}
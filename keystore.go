@@ -6,16 +6,32 @@ package kes
 
 import (
 	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
 	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kes/internal/cache"
 	"github.com/minio/kes/internal/crypto"
 	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kms-go/kes"
 )
 
@@ -158,6 +174,106 @@ func (ks *MemKeyStore) List(_ context.Context, prefix string, n int) ([]string,
 // interface.
 func (ks *MemKeyStore) Close() error { return nil }
 
+// memSnapshotAAD is the associated data of encrypted MemKeyStore
+// snapshots. It binds the ciphertext to the snapshot format.
+const memSnapshotAAD = "kes:mem:snapshot:v1"
+
+// WriteSnapshot writes a snapshot of all entries to the given file.
+//
+// The snapshot is encrypted with the given 256 bit key using
+// AES-256-GCM. WriteSnapshot writes the snapshot to a temporary
+// file first and renames it afterwards. Hence, an existing snapshot
+// is not lost when WriteSnapshot fails.
+func (ks *MemKeyStore) WriteSnapshot(filename string, key []byte) error {
+	aead, err := newSnapshotCipher(key)
+	if err != nil {
+		return err
+	}
+
+	entries := map[string][]byte{}
+	for _, name := range ks.keys.Keys() {
+		if value, ok := ks.keys.Get(name); ok {
+			entries[name] = value
+		}
+	}
+	plaintext, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	defer clear(plaintext) // Don't keep key material around
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return err
+	}
+	snapshot := aead.Seal(nonce, nonce, plaintext, []byte(memSnapshotAAD))
+
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = file.Write(snapshot); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// ReadSnapshot restores the entries of the snapshot stored in the
+// given file, previously written by WriteSnapshot, and decrypts it
+// using the given 256 bit key.
+//
+// Entries present in the snapshot replace existing entries with
+// the same name.
+func (ks *MemKeyStore) ReadSnapshot(filename string, key []byte) error {
+	aead, err := newSnapshotCipher(key)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	if len(snapshot) < aead.NonceSize() {
+		return errors.New("kes: invalid snapshot: snapshot is truncated")
+	}
+	nonce, ciphertext := snapshot[:aead.NonceSize()], snapshot[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(memSnapshotAAD))
+	if err != nil {
+		return errors.New("kes: invalid snapshot: invalid key or corrupted snapshot")
+	}
+	defer clear(plaintext)
+
+	var entries map[string][]byte
+	if err = json.Unmarshal(plaintext, &entries); err != nil {
+		return fmt.Errorf("kes: invalid snapshot: %v", err)
+	}
+	for name, value := range entries {
+		ks.keys.Set(name, value)
+	}
+	return nil
+}
+
+func newSnapshotCipher(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("kes: invalid snapshot key length '%d': must be 32 bytes", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
 // newCache returns a new keyCache wrapping the KeyStore.
 // It caches keys in memory and evicts cache entries based
 // on the CacheConfig.
@@ -168,19 +284,29 @@ func (ks *MemKeyStore) Close() error { return nil }
 func newCache(store KeyStore, conf *CacheConfig) *keyCache {
 	ctx, stop := context.WithCancel(context.Background())
 	c := &keyCache{
-		store: store,
-		stop:  stop,
+		store:          store,
+		cache:          cache.NewLRU[string, *cacheEntry](conf.MaxEntries),
+		pin:            slices.Clone(conf.Pin),
+		stop:           stop,
+		offlinePolicy:  conf.OfflinePolicy,
+		evictionPolicy: conf.EvictionPolicy,
+		preloadFile:    conf.PreloadFile,
+		zeroize:        conf.Zeroize,
+	}
+	if c.zeroize {
+		c.cache.OnRemove = func(_ string, e *cacheEntry) { e.destroy() }
+		c.pinned.OnRemove = func(_ string, e *cacheEntry) { e.destroy() }
 	}
 
 	expiryOffline := conf.ExpiryOffline
 	go c.gc(ctx, conf.Expiry, func() {
 		if offline := c.offline.Load(); !offline || expiryOffline <= 0 {
-			c.cache.DeleteAll()
+			c.evictAll("expired")
 		}
 	})
 	go c.gc(ctx, conf.ExpiryUnused/2, func() {
 		if offline := c.offline.Load(); !offline || conf.ExpiryOffline <= 0 {
-			c.cache.DeleteFunc(func(_ string, e *cacheEntry) bool {
+			n := c.cache.DeleteFunc(func(_ string, e *cacheEntry) bool {
 				// We remove an entry if it isn't marked as used.
 				// We also change all other entries to unused such
 				// that they get evicted on the next GC run unless
@@ -193,21 +319,31 @@ func newCache(store KeyStore, conf *CacheConfig) *keyCache {
 				// as used and we should evict it.
 				return !e.Used.CompareAndSwap(true, false)
 			})
+			c.evicted("unused", n)
 		}
 	})
 	go c.gc(ctx, conf.ExpiryOffline, func() {
 		if offline := c.offline.Load(); offline && expiryOffline > 0 {
-			c.cache.DeleteAll()
-		}
-	})
-	go c.gc(ctx, 10*time.Second, func() {
-		_, err := c.store.Status(ctx)
-		if err != nil && !errors.Is(err, context.Canceled) {
-			c.offline.Store(true)
-		} else {
-			c.offline.Store(false)
+			c.evictAll("expired")
 		}
 	})
+	go c.gc(ctx, 10*time.Second, func() { c.checkStatus(ctx) })
+	if conf.Prewarm {
+		go c.prewarm(ctx, conf.PrewarmPrefix)
+	}
+	if len(conf.Preload) > 0 || conf.PreloadFile != "" {
+		c.preloading.Store(true)
+		go c.preload(ctx, conf.Preload, conf.PreloadFile)
+	}
+	if conf.PreloadFile != "" {
+		go c.gc(ctx, time.Minute, func() {
+			if err := c.writePreloadFile(); err != nil {
+				if log := c.log.Load(); log != nil {
+					log.Warn(fmt.Sprintf("failed to write cache preload file '%s': %v", c.preloadFile, err))
+				}
+			}
+		})
+	}
 	return c
 }
 
@@ -215,30 +351,122 @@ func newCache(store KeyStore, conf *CacheConfig) *keyCache {
 // A keyCache runs a background garbage collector that periodically
 // evicts cache entries based on a CacheConfig.
 //
-// It uses lock-free concurrency primitives to optimize for fast
-// concurrent reads.
+// Keys are kept in an LRU cache that holds at most a fixed number
+// of keys, if limited. Pinned keys are kept separately such that
+// they are never evicted because the cache is full.
 type keyCache struct {
-	store KeyStore
-	cache cache.Cow[string, *cacheEntry]
+	store  KeyStore
+	cache  *cache.LRU[string, *cacheEntry]
+	pinned cache.Cow[string, *cacheEntry] // Keys with a name matching a pin pattern
+	pin    []string                       // Glob patterns of pinned key names
 
-	// The barrier prevents reading the same key multiple
+	// The fetches prevent reading the same key multiple
 	// times concurrently from the kv.Store.
 	// When a particular key isn't cached, we don't want
 	// to fetch it N times given N concurrent requests.
 	// Instead, we want the first request to fetch it and
-	// all others to wait until the first is done.
+	// all others to wait for and share its result.
+	fetches cache.Group[string, crypto.KeyVersion]
+
+	// The barrier prevents fetching a key while it is
+	// being replaced, and hence is not in the kv.Store.
 	barrier cache.Barrier[string]
 
 	// Controls whether we treat the cache as offline
 	// cache (with different GC config).
-	offline atomic.Bool
-	stop    func() // Stops the GC
+	offline        atomic.Bool
+	offlinePolicy  OfflinePolicy  // Controls which requests are served while offline
+	evictionPolicy EvictionPolicy // Controls whether keys are evicted once the cache is full
+	stop           func()         // Stops the GC
+
+	preloading  atomic.Bool // Whether keys are still being preloaded
+	preloadFile string      // File containing the names of recently used keys
+
+	// If zeroize is set, the key material of entries is
+	// overwritten once they are removed from the cache.
+	// Entries exclusively own their key material. Hence,
+	// keys are copied, including their previous versions,
+	// when added to or returned from the cache.
+	zeroize bool
+
+	stats     keyStoreStats    // Latency and last success of KeyStore calls
+	counters  cacheCounters    // Cache hits, misses and evictions
+	count     keyCounter       // Number of keys reported by the status API
+	rotations rotationSchedule // When keys with a rotation interval are due for rotation
+
+	log     atomic.Pointer[slog.Logger]    // Logs when the cache goes offline or online
+	metrics atomic.Pointer[metric.Metrics] // Counts cache hits, misses and evictions
+}
+
+// cacheCounters count the key lookups and evictions of a
+// keyCache. Unlike the metrics, they are reported by the
+// status API.
+type cacheCounters struct {
+	Hits      atomic.Uint64
+	Misses    atomic.Uint64
+	Coalesced atomic.Uint64
+	Evictions atomic.Uint64
+}
+
+// keyCounter caches the number of keys of a key store. Counting
+// all keys is expensive and the status API may be polled often.
+type keyCounter struct {
+	mu    sync.Mutex
+	count int
+	at    time.Time // Zero if the keys haven't been counted yet
+}
+
+// add adds n to the number of keys, if they have been counted.
+func (c *keyCounter) add(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.at.IsZero() {
+		c.count += n
+	}
 }
 
 // A cache entry with a recently used flag.
 type cacheEntry struct {
 	Key  crypto.KeyVersion
 	Used atomic.Bool
+
+	mu        sync.RWMutex // Only used if the cache zeroizes keys
+	destroyed bool
+}
+
+// destroy overwrites the entry's key material.
+func (e *cacheEntry) destroy() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.Key.Destroy()
+	e.destroyed = true
+}
+
+// load returns a copy of the entry's key. It returns false
+// if the entry has been destroyed in the meantime.
+func (c *keyCache) load(e *cacheEntry) (crypto.KeyVersion, bool) {
+	if !c.zeroize {
+		return e.Key, true
+	}
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	if e.destroyed {
+		return crypto.KeyVersion{}, false
+	}
+	return c.copyKey(e.Key), true
+}
+
+// copyKey returns a copy of the key that does not share any
+// key material with it, if the cache zeroizes keys.
+func (c *keyCache) copyKey(key crypto.KeyVersion) crypto.KeyVersion {
+	if c.zeroize {
+		key.Previous = slices.Clone(key.Previous)
+	}
+	return key
 }
 
 // Status returns the current state of the underlying KeyStore.
@@ -249,88 +477,488 @@ func (c *keyCache) Status(ctx context.Context) (KeyStoreState, error) {
 	if c.offline.Load() {
 		return KeyStoreState{}, &keystore.ErrUnreachable{Err: errors.New("keystore is offline")}
 	}
-	return c.store.Status(ctx)
+	start := time.Now()
+	state, err := c.store.Status(ctx)
+	c.stats.Observe(time.Since(start), err)
+	return state, err
 }
 
 // Create creates a new key with the given name if and only if
 // no such entry exists. Otherwise, kes.ErrKeyExists is returned.
+//
+// Keys without an ID are assigned a random one. Names longer than
+// keystore.MaxNameLength are rejected since the names of the key's
+// revision entries may not be accepted by the key store.
 func (c *keyCache) Create(ctx context.Context, name string, key crypto.KeyVersion) error {
+	if strings.HasPrefix(name, keystore.RevisionPrefix) {
+		return kes.ErrKeyExists
+	}
+	if len(name) > keystore.MaxNameLength {
+		return errKeyNameTooLong
+	}
+	if key.ID == "" {
+		var id [keystore.OriginSize / 2]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return err
+		}
+		key.ID = hex.EncodeToString(id[:])
+	}
 	b, err := crypto.EncodeKeyVersion(key)
 	if err != nil {
 		return err
 	}
 
-	if err = c.store.Create(ctx, name, b); err != nil {
+	start := time.Now()
+	err = c.store.Create(ctx, name, b)
+	c.stats.Observe(time.Since(start), err)
+	if err != nil {
 		if errors.Is(err, kes.ErrKeyExists) {
 			return kes.ErrKeyExists
 		}
+		return err
 	}
-	return err
+	c.count.add(1)
+	c.rotations.Set(name, &key)
+	return nil
+}
+
+// keyOrigin returns the origin of the key, as part of the names of
+// its revision entries, from the entry created by Create. It is the
+// key's ID or, for keys created before keys had IDs, derived from
+// its creation time.
+func keyOrigin(key *crypto.KeyVersion) string {
+	if key.ID != "" {
+		return key.ID
+	}
+	first := key
+	if len(key.Previous) > 0 {
+		first = &key.Previous[0]
+	}
+	return fmt.Sprintf("%016x", uint64(first.CreatedAt.UnixNano()))
+}
+
+// Replace replaces the existing key old with the given name by key.
+// The key keeps the ID of old.
+//
+// It creates a new revision of the key at the key store, see
+// keystore.RevisionName, and never modifies or deletes the current
+// one. Hence, a failed or interrupted Replace leaves the key unchanged.
+// If the key at the key store is not old anymore, e.g. because another
+// server has changed it, Replace returns an error and does not change
+// the key.
+//
+// Once the new revision has been created, Replace deletes all but
+// the new and the previous revision such that the number of revision
+// entries remains bounded.
+func (c *keyCache) Replace(ctx context.Context, name string, old, key crypto.KeyVersion) error {
+	if strings.HasPrefix(name, keystore.RevisionPrefix) {
+		return kes.ErrKeyNotFound
+	}
+
+	c.barrier.Lock(name)
+	defer c.barrier.Unlock(name)
+
+	current, origin, revisions, err := c.fetchLatest(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !sameKeyVersion(&current, &old) {
+		c.invalidate(name)
+		return errKeyModified
+	}
+
+	key.ID = current.ID
+	b, err := crypto.EncodeKeyVersion(key)
+	if err != nil {
+		return err
+	}
+
+	var n uint32
+	if len(revisions) > 0 {
+		n = revisions[len(revisions)-1]
+	}
+	revision := keystore.RevisionName(name, origin, n+1)
+	start := time.Now()
+	err = c.store.Create(ctx, revision, b)
+	c.stats.Observe(time.Since(start), err)
+	if err != nil {
+		if errors.Is(err, kes.ErrKeyExists) {
+			c.invalidate(name)
+			return errKeyModified
+		}
+		return err
+	}
+
+	// The revision n+1 might have been deleted, and hence created
+	// again, if other servers have replaced the key multiple times
+	// since the latest revision has been fetched. Then, a later
+	// revision exists that does not contain the new key version.
+	if revisions, err = c.listRevisions(ctx, name, origin); err != nil {
+		return err
+	}
+	if latest := revisions[len(revisions)-1]; latest > n+1 {
+		if !c.hasKeyVersion(ctx, keystore.RevisionName(name, origin, latest), &key) {
+			c.store.Delete(ctx, revision)
+			c.invalidate(name)
+			return errKeyModified
+		}
+	}
+	for _, r := range revisions {
+		if r < n {
+			c.store.Delete(ctx, keystore.RevisionName(name, origin, r))
+		}
+	}
+
+	entry := &cacheEntry{Key: c.copyKey(key)}
+	entry.Used.Store(true)
+	c.add(name, entry)
+	c.rotations.Set(name, &key)
+	return nil
 }
 
-// Delete deletes the key from the key store and removes it from the
-// cache. It may return either no error or kes.ErrKeyNotFound if no
-// such entry exists.
+// Delete deletes the key and all its revisions from the key store
+// and removes it from the cache. It may return either no error or
+// kes.ErrKeyNotFound if no such entry exists.
+//
+// Revisions are deleted once the key itself has been deleted.
+// Revisions that cannot be deleted remain at the key store but
+// are never read again.
 func (c *keyCache) Delete(ctx context.Context, name string) error {
-	if err := c.store.Delete(ctx, name); err != nil {
+	if strings.HasPrefix(name, keystore.RevisionPrefix) {
+		return kes.ErrKeyNotFound
+	}
+
+	_, origin, revisions, err := c.fetchLatest(ctx, name)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	err = c.store.Delete(ctx, name)
+	c.stats.Observe(time.Since(start), err)
+	if err != nil {
 		if errors.Is(err, kes.ErrKeyNotFound) {
-			return err
+			return kes.ErrKeyNotFound
 		}
 		return err
 	}
-	c.cache.Delete(name)
+	c.invalidate(name)
+	c.count.add(-1)
+	c.rotations.Remove(name)
+
+	for _, r := range revisions {
+		c.store.Delete(ctx, keystore.RevisionName(name, origin, r))
+	}
 	return nil
 }
 
+// sameKeyVersion reports whether a and b are the same revision
+// of a key.
+func sameKeyVersion(a, b *crypto.KeyVersion) bool {
+	return a.Number() == b.Number() &&
+		a.Key == b.Key &&
+		a.CreatedAt.Equal(b.CreatedAt) &&
+		a.DeletedAt.Equal(b.DeletedAt) &&
+		a.ExpiresAt.Equal(b.ExpiresAt) &&
+		a.RotationInterval == b.RotationInterval &&
+		a.Usage == b.Usage &&
+		maps.Equal(a.Tags, b.Tags)
+}
+
 // Get returns the key from the cache. If it key is not in the cache,
 // Get tries to fetch it from the key store and put it into the cache.
-// If the key is also not found at the key store, it returns
-// kes.ErrKeyNotFound.
+// If the key is also not found at the key store, or has been marked
+// for deletion, it returns kes.ErrKeyNotFound.
 //
 // Get tries to make as few calls to the underlying key store. Multiple
 // concurrent Get calls for the same key, that is not in the cache, are
 // serialized.
 func (c *keyCache) Get(ctx context.Context, name string) (crypto.KeyVersion, error) {
-	if entry, ok := c.cache.Get(name); ok {
-		entry.Used.Store(true)
-		return entry.Key, nil
+	key, err := c.get(ctx, name)
+	if err != nil {
+		return crypto.KeyVersion{}, err
+	}
+	if !key.DeletedAt.IsZero() {
+		return crypto.KeyVersion{}, kes.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// GetFor returns the key with the given name, like Get, for
+// decrypting the given ciphertexts. If any ciphertext has been
+// produced by a key version newer than the cached key, e.g. since
+// another server has rotated the key, GetFor removes the key from
+// the cache and fetches it again.
+func (c *keyCache) GetFor(ctx context.Context, name string, ciphertexts ...[]byte) (crypto.KeyVersion, error) {
+	key, err := c.Get(ctx, name)
+	if err != nil {
+		return crypto.KeyVersion{}, err
+	}
+	if c.offline.Load() {
+		return key, nil
+	}
+	for _, ciphertext := range ciphertexts {
+		if n, ok := crypto.CiphertextVersion(ciphertext); ok && n > key.Number() {
+			c.invalidate(name)
+			return c.Get(ctx, name)
+		}
+	}
+	return key, nil
+}
+
+// GetDeleted returns the key with the given name if it has been
+// marked for deletion but not deleted yet. Otherwise, it returns
+// kes.ErrKeyNotFound.
+func (c *keyCache) GetDeleted(ctx context.Context, name string) (crypto.KeyVersion, error) {
+	key, err := c.get(ctx, name)
+	if err != nil {
+		return crypto.KeyVersion{}, err
+	}
+	if key.DeletedAt.IsZero() {
+		return crypto.KeyVersion{}, kes.ErrKeyNotFound
+	}
+	return key, nil
+}
+
+// get returns the key from the cache or fetches it from the key
+// store, whether or not it has been marked for deletion.
+func (c *keyCache) get(ctx context.Context, name string) (crypto.KeyVersion, error) {
+	if c.offlinePolicy == OfflineFailClosed && c.offline.Load() {
+		return crypto.KeyVersion{}, errOfflineFailClosed
+	}
+	if entry, ok := c.lookup(name); ok {
+		if key, ok := c.load(entry); ok {
+			return key, nil
+		}
 	}
 
 	// Since the key is not in the cache, we want to fetch it, once.
-	// However, we also don't want to block conccurent reads for different
-	// key names.
-	// Hence, we acquire a lock per key and release it once done.
-	c.barrier.Lock(name)
-	defer c.barrier.Unlock(name)
+	// Concurrent requests for the same key wait for the first one
+	// and share its result, even if fetching the key failed.
+	for {
+		key, shared, err := c.fetches.Do(ctx, name, func() (crypto.KeyVersion, error) {
+			return c.fetch(ctx, name)
+		})
+		if !shared {
+			return key, err
+		}
+		c.counters.Coalesced.Add(1)
+		if m := c.metrics.Load(); m != nil {
+			m.CacheCoalesced()
+		}
 
-	// Check the cache again, a previous request might have fetched the key
-	// while we were blocked by the barrier.
-	if entry, ok := c.cache.Get(name); ok {
-		entry.Used.Store(true)
-		return entry.Key, nil
+		// If the request that fetched the key has been canceled,
+		// we try again unless this request has been canceled, too.
+		if err != nil && ctx.Err() == nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)) {
+			continue
+		}
+		return key, err
 	}
+}
 
-	b, err := c.store.Get(ctx, name)
-	if err != nil {
-		if errors.Is(err, kes.ErrKeyNotFound) {
-			return crypto.KeyVersion{}, kes.ErrKeyNotFound
+// fetch fetches the key from the key store and adds it to the cache.
+func (c *keyCache) fetch(ctx context.Context, name string) (crypto.KeyVersion, error) {
+	c.barrier.Lock(name)
+	defer c.barrier.Unlock(name)
+
+	// Check the cache again, a previous request might have fetched
+	// or replaced the key just before this one started fetching it.
+	if entry, ok := c.lookup(name); ok {
+		if key, ok := c.load(entry); ok {
+			return key, nil
 		}
-		return crypto.KeyVersion{}, err
+	}
+	c.counters.Misses.Add(1)
+	if m := c.metrics.Load(); m != nil {
+		m.CacheMiss()
 	}
 
-	k, err := crypto.ParseKeyVersion(b)
+	k, _, _, err := c.fetchLatest(ctx, name)
 	if err != nil {
 		return crypto.KeyVersion{}, err
 	}
 
 	entry := &cacheEntry{
-		Key: k,
+		Key: c.copyKey(k),
 	}
 	entry.Used.Store(true)
-	c.cache.Set(name, entry)
-	return entry.Key, nil
+	c.add(name, entry)
+	c.rotations.Set(name, &k)
+	return k, nil
+}
+
+// fetchLatest fetches the latest revision of the key with the
+// given name from the key store. It returns the key, its origin
+// and the numbers of all its revisions in ascending order. The
+// latest revision is the last one. A key that has never been
+// changed has no revisions.
+//
+// It reads the entry created by Create, lists the key's revisions
+// and reads the latest one. Hence, it makes at most three calls to
+// the key store, independent of the number of revisions.
+func (c *keyCache) fetchLatest(ctx context.Context, name string) (crypto.KeyVersion, string, []uint32, error) {
+	const MaxAttempts = 3
+
+	if strings.HasPrefix(name, keystore.RevisionPrefix) {
+		return crypto.KeyVersion{}, "", nil, kes.ErrKeyNotFound
+	}
+	for i := 0; ; i++ {
+		start := time.Now()
+		b, err := c.store.Get(ctx, name)
+		c.stats.Observe(time.Since(start), err)
+		if err != nil {
+			if errors.Is(err, kes.ErrKeyNotFound) {
+				return crypto.KeyVersion{}, "", nil, kes.ErrKeyNotFound
+			}
+			return crypto.KeyVersion{}, "", nil, err
+		}
+		key, err := crypto.ParseKeyVersion(b)
+		if err != nil {
+			return crypto.KeyVersion{}, "", nil, err
+		}
+		origin := keyOrigin(&key)
+
+		revisions, err := c.listRevisions(ctx, name, origin)
+		if err != nil || len(revisions) == 0 {
+			return key, origin, nil, err
+		}
+
+		start = time.Now()
+		b, err = c.store.Get(ctx, keystore.RevisionName(name, origin, revisions[len(revisions)-1]))
+		c.stats.Observe(time.Since(start), err)
+		if errors.Is(err, kes.ErrKeyNotFound) && i+1 < MaxAttempts {
+			continue // The key has been deleted or replaced concurrently
+		}
+		if err != nil {
+			return crypto.KeyVersion{}, "", nil, err
+		}
+		if key, err = crypto.ParseKeyVersion(b); err != nil {
+			return crypto.KeyVersion{}, "", nil, err
+		}
+		return key, origin, revisions, nil
+	}
+}
+
+// listRevisions returns the numbers of all revisions of the key
+// with the given name and origin in ascending order.
+func (c *keyCache) listRevisions(ctx context.Context, name, origin string) ([]uint32, error) {
+	prefix := keystore.RevisionNamePrefix(name, origin)
+
+	start := time.Now()
+	names, _, err := c.store.List(ctx, prefix, -1)
+	c.stats.Observe(time.Since(start), err)
+	if err != nil {
+		return nil, err
+	}
+
+	revisions := make([]uint32, 0, len(names))
+	for _, entry := range names {
+		n, err := strconv.ParseUint(strings.TrimPrefix(entry, prefix), 10, 32)
+		if err != nil || !strings.HasPrefix(entry, prefix) {
+			continue // Revision of another key whose name starts with the same prefix
+		}
+		revisions = append(revisions, uint32(n))
+	}
+	slices.Sort(revisions)
+	return revisions, nil
+}
+
+// hasKeyVersion reports whether the revision entry contains the
+// given key version.
+func (c *keyCache) hasKeyVersion(ctx context.Context, revision string, key *crypto.KeyVersion) bool {
+	b, err := c.store.Get(ctx, revision)
+	if err != nil {
+		return false
+	}
+	latest, err := crypto.ParseKeyVersion(b)
+	if err != nil {
+		return false
+	}
+	for _, v := range latest.Versions() {
+		if v.Number() == key.Number() && v.Key == key.Key {
+			return true
+		}
+	}
+	return false
+}
+
+// lookup returns the cache entry for the given key name,
+// if any, and marks it as used.
+func (c *keyCache) lookup(name string) (*cacheEntry, bool) {
+	entry, ok := c.pinned.Get(name)
+	if !ok {
+		entry, ok = c.cache.Get(name)
+	}
+	if ok {
+		entry.Used.Store(true)
+		c.counters.Hits.Add(1)
+		if m := c.metrics.Load(); m != nil {
+			m.CacheHit()
+		}
+	}
+	return entry, ok
+}
+
+// add adds the entry to the cache. Once the cache is full,
+// it either evicts the least recently used entry or, if
+// eviction is disabled, doesn't add the entry.
+func (c *keyCache) add(name string, entry *cacheEntry) {
+	switch {
+	case c.isPinned(name):
+		c.pinned.Set(name, entry)
+	case c.evictionPolicy == EvictNone:
+		c.cache.TrySet(name, entry)
+	default:
+		if c.cache.Set(name, entry) {
+			c.evicted("capacity", 1)
+		}
+	}
 }
 
+// isPinned reports whether the key name matches any pin pattern.
+func (c *keyCache) isPinned(name string) bool {
+	for _, pattern := range c.pin {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidate removes the key, if present, from the cache
+// such that it gets fetched from the key store again.
+func (c *keyCache) invalidate(name string) {
+	c.cache.Delete(name)
+	c.pinned.Delete(name)
+}
+
+// invalidateAll removes all entries, including pinned
+// ones, from the cache and returns the number of removed
+// entries.
+func (c *keyCache) invalidateAll() int {
+	n := c.cache.DeleteAll() + len(c.pinned.Keys())
+	c.pinned.DeleteAll()
+	return n
+}
+
+// evictAll removes all entries, including pinned ones,
+// from the cache and records them as evicted for the
+// given reason.
+func (c *keyCache) evictAll(reason string) { c.evicted(reason, c.invalidateAll()) }
+
+// evicted records that n entries have been evicted from
+// the cache for the given reason.
+func (c *keyCache) evicted(reason string, n int) {
+	if n > 0 {
+		c.counters.Evictions.Add(uint64(n))
+	}
+	if m := c.metrics.Load(); m != nil {
+		m.CacheEvicted(reason, n)
+	}
+}
+
+// Len returns the number of keys in the cache.
+func (c *keyCache) Len() int { return c.cache.Len() + len(c.pinned.Keys()) }
+
 // List returns the first n key names, that start with the given prefix,
 // and the next prefix from which the listing should continue.
 //
@@ -341,14 +969,360 @@ func (c *keyCache) Get(ctx context.Context, name string) (crypto.KeyVersion, err
 // or when there are no (more) keys starting with the prefix, the
 // returned prefix is empty.
 func (c *keyCache) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
-	return c.store.List(ctx, prefix, n)
+	start := time.Now()
+	names, next, err := c.store.List(ctx, prefix, n)
+	c.stats.Observe(time.Since(start), err)
+	if err != nil {
+		return nil, "", err
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		return strings.HasPrefix(name, keystore.RevisionPrefix)
+	}), next, nil
+}
+
+// ScanRotations reads all keys from the key store and records when
+// keys with a rotation interval are due for rotation. Unlike Get,
+// it does not add the keys to the cache.
+func (c *keyCache) ScanRotations(ctx context.Context) error {
+	iter := kes.ListIter[string]{NextFunc: c.List}
+	for name, err := iter.Next(ctx); err != io.EOF; name, err = iter.Next(ctx) {
+		if err != nil {
+			return err
+		}
+		key, _, _, err := c.fetchLatest(ctx, name)
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			c.rotations.Remove(name)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		c.rotations.Set(name, &key)
+		key.Destroy()
+	}
+	return nil
+}
+
+// DueRotations returns the names of all keys that are due for
+// rotation at time t.
+func (c *keyCache) DueRotations(t time.Time) []string { return c.rotations.Due(t) }
+
+// Count returns the number of keys in the key store. The keys
+// are counted at most every 30 seconds. In between, keys created
+// or deleted via the keyCache are taken into account. Count
+// returns the last count if the key store cannot be listed,
+// or -1 if the keys haven't been counted yet.
+func (c *keyCache) Count(ctx context.Context) int {
+	const MaxAge = 30 * time.Second
+
+	c.count.mu.Lock()
+	defer c.count.mu.Unlock()
+
+	if !c.count.at.IsZero() && time.Since(c.count.at) < MaxAge {
+		return c.count.count
+	}
+	names, _, err := c.List(ctx, "", -1)
+	if err != nil {
+		if c.count.at.IsZero() {
+			return -1
+		}
+		return c.count.count
+	}
+	c.count.count, c.count.at = len(names), time.Now()
+	return c.count.count
+}
+
+// CheckEncrypt returns an error if the keyCache must not be
+// used to produce new ciphertexts since the key store is
+// offline and the offline policy only permits decryption.
+func (c *keyCache) CheckEncrypt() api.Error {
+	if c.offlinePolicy == OfflineDecryptOnly && c.offline.Load() {
+		return errOfflineDecryptOnly
+	}
+	return nil
+}
+
+// Offline reports whether the key store is unreachable such
+// that requests are served from the cache.
+func (c *keyCache) Offline() bool { return c.offline.Load() }
+
+// SetLog sets the logger used to report that the key store
+// has become unreachable or reachable again. It also sets the
+// logger of underlying KeyStores that log errors themselves,
+// like a failover keystore.
+func (c *keyCache) SetLog(log *slog.Logger) {
+	c.log.Store(log)
+
+	for store := c.store; store != nil; {
+		if l, ok := store.(interface{ SetLog(*slog.Logger) }); ok {
+			l.SetLog(log)
+		}
+		u, ok := store.(interface{ Unwrap() KeyStore })
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+}
+
+// SetMetrics sets the metrics used to count cache hits,
+// misses and evictions.
+func (c *keyCache) SetMetrics(metrics *metric.Metrics) { c.metrics.Store(metrics) }
+
+// checkStatus checks whether the key store is reachable and
+// switches the cache into or out of offline mode. While offline,
+// requests are served from the cache as permitted by the offline
+// policy.
+func (c *keyCache) checkStatus(ctx context.Context) {
+	start := time.Now()
+	_, err := c.store.Status(ctx)
+	c.stats.Observe(time.Since(start), err)
+
+	offline := err != nil && !errors.Is(err, context.Canceled)
+	if offline && c.offlinePolicy == OfflineFailClosed {
+		c.evictAll("offline")
+	}
+	if c.offline.Swap(offline) == offline {
+		return
+	}
+	if log := c.log.Load(); log != nil {
+		switch {
+		case offline && c.offlinePolicy == OfflineFailClosed:
+			log.Warn(fmt.Sprintf("kes: keystore is offline: rejecting requests with offline policy '%s': %v", c.offlinePolicy, err))
+		case offline:
+			log.Warn(fmt.Sprintf("kes: keystore is offline: serving cached keys with offline policy '%s': %v", c.offlinePolicy, err))
+		default:
+			log.Info("kes: keystore is online again")
+		}
+	}
+}
+
+// OfflinePolicy returns the keyCache's offline policy.
+func (c *keyCache) OfflinePolicy() OfflinePolicy { return c.offlinePolicy }
+
+// Type returns a description of the underlying KeyStore,
+// for example "Hashicorp Vault: https://127.0.0.1:8200".
+func (c *keyCache) Type() string {
+	if s, ok := c.store.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", c.store)
+}
+
+// Failover reports whether the underlying KeyStore has failed
+// over to a secondary keystore, how many writes have not been
+// replayed to the primary keystore yet and for how many
+// consecutive requests the primary keystore has not been
+// reachable. It returns false if the underlying KeyStore
+// does not support failover.
+func (c *keyCache) Failover() (failedOver bool, pending, failures int, ok bool) {
+	type FailoverKeyStore interface {
+		FailedOver() bool
+		Pending() int
+		Failures() int
+	}
+	for store := c.store; store != nil; {
+		if f, ok := store.(FailoverKeyStore); ok {
+			return f.FailedOver(), f.Pending(), f.Failures(), true
+		}
+		u, ok := store.(interface{ Unwrap() KeyStore })
+		if !ok {
+			break
+		}
+		store = u.Unwrap()
+	}
+	return false, 0, 0, false
 }
 
 // Close stops the cache's background garbage collector and
 // releases associated resources.
+//
+// If the cache has a preload file, Close writes the names of
+// all cached keys to it. If the cache zeroizes keys, Close
+// removes all keys from the cache.
 func (c *keyCache) Close() error {
 	c.stop()
-	return nil
+	err := c.writePreloadFile()
+	if c.zeroize {
+		c.invalidateAll()
+	}
+	return err
+}
+
+// Errors returned while the key store is offline.
+var (
+	errOfflineDecryptOnly = api.NewError(http.StatusServiceUnavailable, "key store is offline: only decryption is permitted")
+	errOfflineFailClosed  = api.NewError(http.StatusServiceUnavailable, "key store is offline")
+
+	errKeyModified    = api.NewError(http.StatusConflict, "key has been modified concurrently")
+	errKeyNameTooLong = api.NewError(http.StatusBadRequest, fmt.Sprintf("key name is longer than %d characters", keystore.MaxNameLength))
+)
+
+// keyStoreStats tracks the latency of the most recent calls
+// to a KeyStore and the time of the last successful call.
+type keyStoreStats struct {
+	lastSuccess atomic.Int64 // Unix time in nanoseconds
+
+	lock    sync.Mutex
+	samples [keyStoreStatsSamples]time.Duration
+	n       int // Total number of samples, may be larger than len(samples)
+}
+
+// Number of latency samples kept by keyStoreStats.
+const keyStoreStatsSamples = 1024
+
+// Observe records the latency of a KeyStore call. A call
+// is considered successful if the KeyStore responded,
+// even with an error like kes.ErrKeyNotFound.
+func (s *keyStoreStats) Observe(latency time.Duration, err error) {
+	if err != nil && !errors.Is(err, kes.ErrKeyExists) && !errors.Is(err, kes.ErrKeyNotFound) {
+		return
+	}
+	s.lastSuccess.Store(time.Now().UnixNano())
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.samples[s.n%len(s.samples)] = latency
+	s.n++
+}
+
+// LastSuccess returns the time of the last successful
+// KeyStore call or the zero time if there has been none.
+func (s *keyStoreStats) LastSuccess() time.Time {
+	if t := s.lastSuccess.Load(); t != 0 {
+		return time.Unix(0, t)
+	}
+	return time.Time{}
+}
+
+// Percentiles returns the 50th, 90th and 99th latency percentile
+// of the most recent successful KeyStore calls. It returns zero
+// durations if there have been no successful calls.
+func (s *keyStoreStats) Percentiles() (p50, p90, p99 time.Duration) {
+	s.lock.Lock()
+	samples := slices.Clone(s.samples[:min(s.n, len(s.samples))])
+	s.lock.Unlock()
+
+	if len(samples) == 0 {
+		return 0, 0, 0
+	}
+	slices.Sort(samples)
+	percentile := func(p int) time.Duration { return samples[(len(samples)-1)*p/100] }
+	return percentile(50), percentile(90), percentile(99)
+}
+
+// prewarm fetches all keys that start with the prefix from the
+// key store into the cache. It stops once ctx is canceled or
+// the key store fails to list or fetch a key.
+func (c *keyCache) prewarm(ctx context.Context, prefix string) {
+	for {
+		names, next, err := c.List(ctx, prefix, -1)
+		if err != nil {
+			return
+		}
+		for _, name := range names {
+			if _, err = c.Get(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+				return
+			}
+		}
+		if next == "" || next == prefix {
+			return
+		}
+		prefix = next
+	}
+}
+
+// preload fetches all keys whose name matches one of the glob
+// patterns and all keys listed in the preload file, if any, into
+// the cache. Once done, or once the key store fails to list or
+// fetch a key, it marks the cache as preloaded.
+//
+// Keys listed in the preload file are fetched in reverse order.
+// Hence, the most recently used keys are least likely to be
+// evicted if the cache is limited.
+func (c *keyCache) preload(ctx context.Context, patterns []string, filename string) {
+	defer c.preloading.Store(false)
+
+	var names []string
+	for _, pattern := range patterns {
+		prefix := pattern
+		if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+			prefix = pattern[:i]
+		}
+		keys, _, err := c.List(ctx, prefix, -1)
+		if err != nil {
+			c.preloadFailed(err)
+			return
+		}
+		for _, name := range keys {
+			if ok, _ := path.Match(pattern, name); ok {
+				names = append(names, name)
+			}
+		}
+	}
+	if filename != "" {
+		data, err := os.ReadFile(filename)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			c.preloadFailed(err)
+			return
+		}
+		lines := strings.Fields(string(data))
+		slices.Reverse(lines)
+		names = append(names, lines...)
+	}
+
+	for _, name := range names {
+		if _, err := c.Get(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			c.preloadFailed(err)
+			return
+		}
+	}
+}
+
+// preloadFailed logs that preloading keys failed with err.
+func (c *keyCache) preloadFailed(err error) {
+	if log := c.log.Load(); log != nil {
+		log.Warn(fmt.Sprintf("failed to preload keys into the cache: %v", err))
+	}
+}
+
+// Preloading reports whether the cache is still fetching
+// keys that should be cached before serving requests.
+func (c *keyCache) Preloading() bool { return c.preloading.Load() }
+
+// writePreloadFile writes the names of all cached keys, pinned
+// keys and then most recently used first, to the preload file.
+// It does nothing if there is no preload file or if keys are
+// still being preloaded, since the file would be incomplete.
+func (c *keyCache) writePreloadFile() error {
+	if c.preloadFile == "" || c.preloading.Load() {
+		return nil
+	}
+
+	var buf strings.Builder
+	for _, name := range append(c.pinned.Keys(), c.cache.Keys()...) {
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(c.preloadFile), "."+filepath.Base(c.preloadFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = file.WriteString(buf.String()); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), c.preloadFile)
 }
 
 // gc executes f periodically until the ctx.Done() channel returns.
@@ -0,0 +1,588 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestMemKeyStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	filename := filepath.Join(t.TempDir(), "kes.snapshot")
+	key := make([]byte, 32)
+
+	var store MemKeyStore
+	if err := store.Create(ctx, "my-key", []byte("my-value")); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := store.WriteSnapshot(filename, key); err != nil {
+		t.Fatalf("Failed to write snapshot: %v", err)
+	}
+
+	var restored MemKeyStore
+	if err := restored.ReadSnapshot(filename, key); err != nil {
+		t.Fatalf("Failed to read snapshot: %v", err)
+	}
+	value, err := restored.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if string(value) != "my-value" {
+		t.Fatalf("Invalid value: got '%s' - want '%s'", value, "my-value")
+	}
+
+	key[0] = 1
+	if err = restored.ReadSnapshot(filename, key); err == nil {
+		t.Fatal("Reading a snapshot with a different key should have failed")
+	}
+}
+
+func TestKeyStoreStats(t *testing.T) {
+	var stats keyStoreStats
+	if p50, p90, p99 := stats.Percentiles(); p50 != 0 || p90 != 0 || p99 != 0 {
+		t.Fatalf("Invalid percentiles: got '%v, %v, %v' - want '0, 0, 0'", p50, p90, p99)
+	}
+	if !stats.LastSuccess().IsZero() {
+		t.Fatalf("Invalid last success: got '%v' - want zero time", stats.LastSuccess())
+	}
+
+	for i := 1; i <= 100; i++ {
+		stats.Observe(time.Duration(i)*time.Millisecond, nil)
+	}
+	stats.Observe(time.Hour, errors.New("network error"))   // Failed calls are ignored
+	stats.Observe(100*time.Millisecond, kes.ErrKeyNotFound) // The keystore responded
+
+	if p50, p90, p99 := stats.Percentiles(); p50 != 51*time.Millisecond || p90 != 91*time.Millisecond || p99 != 100*time.Millisecond {
+		t.Fatalf("Invalid percentiles: got '%v, %v, %v' - want '51ms, 91ms, 100ms'", p50, p90, p99)
+	}
+	if stats.LastSuccess().IsZero() {
+		t.Fatal("Invalid last success: got zero time")
+	}
+}
+
+func TestKeyCachePrewarm(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := crypto.EncodeKeyVersion(crypto.KeyVersion{Key: key, HMACKey: hmac})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var store MemKeyStore
+	for _, name := range []string{"tenant-1-key", "tenant-2-key"} {
+		if err = store.Create(ctx, name, value); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	c := newCache(&store, &CacheConfig{Prewarm: true, PrewarmPrefix: "tenant-1-"})
+	defer c.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		if _, ok := c.cache.Get("tenant-1-key"); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Key has not been prewarmed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if _, ok := c.cache.Get("tenant-2-key"); ok {
+		t.Fatal("Key without prewarm prefix has been prewarmed")
+	}
+}
+
+func TestKeyCacheMaxEntries(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := crypto.EncodeKeyVersion(crypto.KeyVersion{Key: key, HMACKey: hmac})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store MemKeyStore
+	names := []string{"key-1", "key-2", "key-3", "pinned-1", "pinned-2"}
+	for _, name := range names {
+		if err = store.Create(ctx, name, value); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	for _, policy := range []EvictionPolicy{EvictLRU, EvictNone} {
+		c := newCache(&store, &CacheConfig{MaxEntries: 2, EvictionPolicy: policy, Pin: []string{"pinned-*"}})
+		for _, name := range names {
+			if _, err = c.Get(ctx, name); err != nil {
+				t.Fatalf("Policy '%v': failed to get key '%s': %v", policy, name, err)
+			}
+		}
+		c.Close()
+
+		if n := c.Len(); n != 4 {
+			t.Fatalf("Policy '%v': invalid number of cached keys: got '%d' - want '4'", policy, n)
+		}
+		if _, ok := c.pinned.Get("pinned-2"); !ok {
+			t.Fatalf("Policy '%v': pinned key is not cached", policy)
+		}
+		if _, ok := c.cache.Get("key-1"); ok != (policy == EvictNone) {
+			t.Fatalf("Policy '%v': least recently used key cached: got '%v' - want '%v'", policy, ok, policy == EvictNone)
+		}
+		if _, ok := c.cache.Get("key-3"); ok != (policy == EvictLRU) {
+			t.Fatalf("Policy '%v': most recently used key cached: got '%v' - want '%v'", policy, ok, policy == EvictLRU)
+		}
+	}
+}
+
+func TestKeyCacheZeroize(t *testing.T) {
+	ctx := context.Background()
+
+	newKey := func() crypto.KeyVersion {
+		key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crypto.KeyVersion{Key: key, HMACKey: hmac}
+	}
+	key := newKey()
+	key = key.Rotate(newKey().Key, time.Now(), "")
+
+	var store MemKeyStore
+	for _, name := range []string{"key-1", "key-2"} {
+		value, err := crypto.EncodeKeyVersion(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = store.Create(ctx, name, value); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	c := newCache(&store, &CacheConfig{MaxEntries: 1, Zeroize: true})
+	key1, err := c.Get(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	entry1, ok := c.cache.Get("key-1")
+	if !ok {
+		t.Fatal("Key 'key-1' is not cached")
+	}
+	if _, err = c.Get(ctx, "key-2"); err != nil { // Evicts key-1
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if !entry1.destroyed || entry1.Key.HasHMACKey() {
+		t.Fatal("Evicted key has not been zeroized")
+	}
+	for _, version := range key1.Versions() {
+		if _, err = version.Encrypt([]byte("Hello World"), nil); err != nil {
+			t.Fatalf("Failed to encrypt with key version '%d' returned by the cache: %v", version.Number(), err)
+		}
+	}
+
+	entry2, ok := c.cache.Get("key-2")
+	if !ok {
+		t.Fatal("Key 'key-2' is not cached")
+	}
+	if err = c.Replace(ctx, "key-2", key, key.Rotate(newKey().Key, time.Now(), "")); err != nil {
+		t.Fatalf("Failed to replace key: %v", err)
+	}
+	if !entry2.destroyed {
+		t.Fatal("Replaced key has not been zeroized")
+	}
+	entry2, ok = c.cache.Get("key-2")
+	if !ok {
+		t.Fatal("Key 'key-2' is not cached")
+	}
+	c.Close()
+	if !entry2.destroyed {
+		t.Fatal("Key has not been zeroized when closing the cache")
+	}
+	if _, err = key.Encrypt([]byte("Hello World"), nil); err != nil {
+		t.Fatalf("Key added to the cache has been zeroized: %v", err)
+	}
+}
+
+func TestKeyCacheReplace(t *testing.T) {
+	ctx := context.Background()
+
+	newKey := func() crypto.KeyVersion {
+		key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return crypto.KeyVersion{Key: key, HMACKey: hmac, CreatedAt: time.Now()}
+	}
+
+	// Two caches, like two servers, sharing the same key store.
+	var store MemKeyStore
+	c1 := newCache(&store, &CacheConfig{})
+	defer c1.Close()
+	c2 := newCache(&store, &CacheConfig{})
+	defer c2.Close()
+
+	key := newKey()
+	if err := c1.Create(ctx, "my-key", key); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := c2.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+
+	rotated := key.Rotate(newKey().Key, time.Now(), "")
+	if err := c1.Replace(ctx, "my-key", key, rotated); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if err := c2.Replace(ctx, "my-key", key, key.Rotate(newKey().Key, time.Now(), "")); !errors.Is(err, errKeyModified) {
+		t.Fatalf("Replacing a modified key should have failed: got '%v' - want '%v'", err, errKeyModified)
+	}
+	if names, _, _ := c1.List(ctx, "", -1); !slices.Equal(names, []string{"my-key"}) {
+		t.Fatalf("Key revisions are listed: got '%v'", names)
+	}
+
+	// c2 has the first key version cached until it sees a
+	// ciphertext of the second one.
+	if _, err := c2.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	ciphertext, err := rotated.Encrypt([]byte("Hello World"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest, err := c2.GetFor(ctx, "my-key", ciphertext)
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if _, err = latest.Decrypt(ciphertext, nil); err != nil {
+		t.Fatalf("Failed to decrypt with refetched key: %v", err)
+	}
+
+	// Revisions left over from a deleted key must not be
+	// mistaken for revisions of a new key with the same name.
+	if err = store.Delete(ctx, "my-key"); err != nil {
+		t.Fatal(err)
+	}
+	c1.invalidate("my-key")
+	recreated := newKey()
+	if err = c1.Create(ctx, "my-key", recreated); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if key, err = c1.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if key.Key != recreated.Key || key.Number() != 1 {
+		t.Fatal("Revision of a deleted key has been used for a new key with the same name")
+	}
+
+	if err = c1.Replace(ctx, "my-key", key, key.Rotate(newKey().Key, time.Now(), "")); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if err = c1.Delete(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if names, _, _ := store.List(ctx, "kes-rev-my-key-", -1); len(names) != 1 {
+		t.Fatalf("Key revisions have not been deleted: got '%v' - want only the left over revision", names)
+	}
+}
+
+func TestKeyCacheRevisions(t *testing.T) {
+	ctx := context.Background()
+
+	secret, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var store MemKeyStore
+	c := newCache(&store, &CacheConfig{})
+	defer c.Close()
+
+	if err = c.Create(ctx, strings.Repeat("a", keystore.MaxNameLength+1), crypto.KeyVersion{Key: secret, HMACKey: hmac}); !errors.Is(err, errKeyNameTooLong) {
+		t.Fatalf("Creating a key with a too long name should have failed: got '%v' - want '%v'", err, errKeyNameTooLong)
+	}
+	if err = c.Create(ctx, "my-key", crypto.KeyVersion{Key: secret, HMACKey: hmac, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	key, err := c.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if len(key.ID) != keystore.OriginSize {
+		t.Fatalf("Invalid key ID: got '%s' - want %d hex characters", key.ID, keystore.OriginSize)
+	}
+	id := key.ID
+
+	const Rotations = 10
+	for i := 0; i < Rotations; i++ {
+		secret, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rotated := key.Rotate(secret, time.Now(), "")
+		if err = c.Replace(ctx, "my-key", key, rotated); err != nil {
+			t.Fatalf("Failed to rotate key: %v", err)
+		}
+		if key, err = c.Get(ctx, "my-key"); err != nil {
+			t.Fatalf("Failed to get key: %v", err)
+		}
+	}
+	if key.Number() != Rotations+1 {
+		t.Fatalf("Invalid key version: got '%d' - want '%d'", key.Number(), Rotations+1)
+	}
+	if key.ID != id {
+		t.Fatalf("Key ID has changed: got '%s' - want '%s'", key.ID, id)
+	}
+
+	// Only the latest two revisions are kept and their names
+	// contain the random key ID, not data derived from the key.
+	names, _, err := store.List(ctx, keystore.RevisionPrefix, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		keystore.RevisionName("my-key", id, Rotations-1),
+		keystore.RevisionName("my-key", id, Rotations),
+	}
+	slices.Sort(names)
+	slices.Sort(want)
+	if !slices.Equal(names, want) {
+		t.Fatalf("Invalid key revisions: got '%v' - want '%v'", names, want)
+	}
+}
+
+func TestKeyCachePreload(t *testing.T) {
+	ctx := context.Background()
+
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := crypto.EncodeKeyVersion(crypto.KeyVersion{Key: key, HMACKey: hmac})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store MemKeyStore
+	for _, name := range []string{"key-1", "key-2", "other-1", "other-2"} {
+		if err = store.Create(ctx, name, value); err != nil {
+			t.Fatalf("Failed to create key: %v", err)
+		}
+	}
+
+	filename := filepath.Join(t.TempDir(), "preload")
+	waitPreloaded := func(c *keyCache) {
+		for deadline := time.Now().Add(5 * time.Second); c.Preloading(); time.Sleep(10 * time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatal("Cache has not been preloaded")
+			}
+		}
+	}
+
+	c := newCache(&store, &CacheConfig{Preload: []string{"key-*"}, PreloadFile: filename})
+	waitPreloaded(c)
+	if keys := c.cache.Keys(); len(keys) != 2 || !slices.Contains(keys, "key-1") || !slices.Contains(keys, "key-2") {
+		t.Fatalf("Invalid preloaded keys: got '%v' - want '[key-1 key-2]'", keys)
+	}
+	if _, err = c.Get(ctx, "other-2"); err != nil {
+		t.Fatalf("Failed to get key: %v", err)
+	}
+	if err = c.Close(); err != nil {
+		t.Fatalf("Failed to write preload file: %v", err)
+	}
+
+	// A restarted cache preloads the keys listed in the file,
+	// such that their order of use remains unchanged.
+	c = newCache(&store, &CacheConfig{PreloadFile: filename})
+	waitPreloaded(c)
+	defer c.Close()
+
+	if keys := c.cache.Keys(); len(keys) != 3 || keys[0] != "other-2" {
+		t.Fatalf("Invalid preloaded keys: got '%v' - want 'other-2' followed by 'key-1' and 'key-2'", keys)
+	}
+}
+
+func TestKeyCacheOfflinePolicy(t *testing.T) {
+	ctx := context.Background()
+
+	for i, test := range keyCacheOfflinePolicyTests {
+		c := newCache(&MemKeyStore{}, &CacheConfig{OfflinePolicy: test.Policy})
+		c.offline.Store(true)
+
+		if err := c.CheckEncrypt(); (err != nil) != test.EncryptErr {
+			t.Errorf("Test %d: got encrypt error '%v' - want error: %v", i, err, test.EncryptErr)
+		}
+		_, err := c.Get(ctx, "my-key")
+		if test.GetErr && !errors.Is(err, errOfflineFailClosed) {
+			t.Errorf("Test %d: got get error '%v' - want '%v'", i, err, errOfflineFailClosed)
+		}
+		if !test.GetErr && !errors.Is(err, kes.ErrKeyNotFound) {
+			t.Errorf("Test %d: got get error '%v' - want '%v'", i, err, kes.ErrKeyNotFound)
+		}
+		c.Close()
+	}
+}
+
+func TestKeyCacheOffline(t *testing.T) {
+	ctx := context.Background()
+
+	store := &unreachableKeyStore{}
+	c := newCache(store, &CacheConfig{})
+	defer c.Close()
+
+	var log bytes.Buffer
+	c.SetLog(slog.New(slog.NewTextHandler(&log, nil)))
+
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = c.Create(ctx, "my-key", crypto.KeyVersion{Key: key, HMACKey: hmac}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = c.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to fetch key: %v", err)
+	}
+
+	store.Unreachable.Store(true)
+	c.checkStatus(ctx)
+	if !c.Offline() {
+		t.Fatal("Cache is not offline although the key store is unreachable")
+	}
+	if !strings.Contains(log.String(), "keystore is offline") {
+		t.Fatalf("Going offline has not been logged: %s", log.String())
+	}
+	if _, err = c.Get(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to fetch cached key while offline: %v", err)
+	}
+
+	store.Unreachable.Store(false)
+	c.checkStatus(ctx)
+	if c.Offline() {
+		t.Fatal("Cache is still offline although the key store is reachable")
+	}
+	if !strings.Contains(log.String(), "keystore is online again") {
+		t.Fatalf("Going online has not been logged: %s", log.String())
+	}
+}
+
+// unreachableKeyStore is a MemKeyStore that returns an
+// unreachable error on every request while Unreachable
+// is set.
+type unreachableKeyStore struct {
+	MemKeyStore
+	Unreachable atomic.Bool
+}
+
+func (s *unreachableKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	if s.Unreachable.Load() {
+		return KeyStoreState{}, &keystore.ErrUnreachable{}
+	}
+	return s.MemKeyStore.Status(ctx)
+}
+
+func (s *unreachableKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if s.Unreachable.Load() {
+		return nil, &keystore.ErrUnreachable{}
+	}
+	return s.MemKeyStore.Get(ctx, name)
+}
+
+func TestKeyCacheCoalesce(t *testing.T) {
+	const N = 100
+	ctx := context.Background()
+
+	store := &slowKeyStore{Delay: 100 * time.Millisecond}
+	c := newCache(store, &CacheConfig{})
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < N; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.Get(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+				t.Errorf("Invalid error: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Keys that don't exist aren't cached. Still, concurrent
+	// requests should share the result of one fetch. Allow a
+	// few fetches in case some goroutines have been delayed.
+	if n := store.Calls.Load(); n > 3 {
+		t.Fatalf("Concurrent fetches have not been coalesced: got '%d' key store calls for '%d' requests", n, N)
+	}
+}
+
+// slowKeyStore is a MemKeyStore that delays every Get
+// request and counts the number of Get requests.
+type slowKeyStore struct {
+	MemKeyStore
+	Delay time.Duration
+	Calls atomic.Uint32
+}
+
+func (s *slowKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	s.Calls.Add(1)
+	time.Sleep(s.Delay)
+	return s.MemKeyStore.Get(ctx, name)
+}
+
+var keyCacheOfflinePolicyTests = []struct {
+	Policy     OfflinePolicy
+	EncryptErr bool
+	GetErr     bool
+}{
+	{Policy: OfflineStale, EncryptErr: false, GetErr: false},
+	{Policy: OfflineDecryptOnly, EncryptErr: true, GetErr: false},
+	{Policy: OfflineFailClosed, EncryptErr: false, GetErr: true},
+}
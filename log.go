@@ -6,7 +6,9 @@ package kes
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"sync"
 
 	"github.com/minio/kes/internal/api"
 )
@@ -21,12 +23,18 @@ import (
 // custom/default handler. For example to write to standard error.
 // Second, they are sent to clients, that have subscribed to the
 // ErrorLog API, if any.
+//
+// Further, the most recent records are kept in memory such that
+// they can be included in support bundles.
 type logHandler struct {
 	h     slog.Handler
 	level slog.Leveler
 
 	text slog.Handler
 	out  *api.Multicast // clients subscribed to the ErrorLog API
+
+	recentText slog.Handler
+	recent     *logHistory // most recent log records
 }
 
 // newLogHandler returns a new logHandler that passing records to h.
@@ -35,13 +43,17 @@ type logHandler struct {
 // its log level is >= level.
 func newLogHandler(h slog.Handler, level slog.Leveler) *logHandler {
 	handler := &logHandler{
-		h:     h,
-		level: level,
-		out:   &api.Multicast{},
+		h:      h,
+		level:  level,
+		out:    &api.Multicast{},
+		recent: &logHistory{},
 	}
 	handler.text = slog.NewTextHandler(handler.out, &slog.HandlerOptions{
 		Level: level,
 	})
+	handler.recentText = slog.NewTextHandler(handler.recent, &slog.HandlerOptions{
+		Level: level,
+	})
 	return handler
 }
 
@@ -57,6 +69,7 @@ func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
 	var err error
 	if r.Level >= h.level.Level() {
 		err = h.h.Handle(ctx, r)
+		h.recentText.Handle(ctx, r)
 	}
 	if h.out.Num() > 0 && h.text.Enabled(ctx, r.Level) {
 		if tErr := h.text.Handle(ctx, r); err == nil {
@@ -71,9 +84,12 @@ func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
 // The Handler owns the slice: it may retain, modify or discard it.
 func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	return &logHandler{
-		h:    h.h.WithAttrs(attrs),
-		text: h.text.WithAttrs(attrs),
-		out:  h.out, // Share all connections to clients
+		h:          h.h.WithAttrs(attrs),
+		level:      h.level,
+		text:       h.text.WithAttrs(attrs),
+		out:        h.out, // Share all connections to clients
+		recentText: h.recentText.WithAttrs(attrs),
+		recent:     h.recent,
 	}
 }
 
@@ -81,11 +97,63 @@ func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 // the receiver's existing groups.
 func (h *logHandler) WithGroup(name string) slog.Handler {
 	return &logHandler{
-		h:    h.h.WithGroup(name),
-		text: h.text.WithGroup(name),
-		out:  h.out, // Share all connections to clients
+		h:          h.h.WithGroup(name),
+		level:      h.level,
+		text:       h.text.WithGroup(name),
+		out:        h.out, // Share all connections to clients
+		recentText: h.recentText.WithGroup(name),
+		recent:     h.recent,
 	}
 }
 
 // Handler returns the underlying custom/default slog.Handler.
 func (h *logHandler) Handler() slog.Handler { return h.h }
+
+// logHistory is an io.Writer that keeps the most recent
+// log records in memory. Each Write call is considered a
+// single log record.
+type logHistory struct {
+	lock    sync.Mutex
+	records []string
+	next    int
+}
+
+// maxLogHistory is the max. number of log records
+// kept by a logHistory.
+const maxLogHistory = 1000
+
+func (l *logHistory) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if len(l.records) < maxLogHistory {
+		l.records = append(l.records, string(p))
+	} else {
+		l.records[l.next] = string(p)
+	}
+	l.next = (l.next + 1) % maxLogHistory
+	return len(p), nil
+}
+
+// WriteTo writes all log records, from oldest to newest, to w.
+func (l *logHistory) WriteTo(w io.Writer) (int64, error) {
+	l.lock.Lock()
+	records := make([]string, 0, len(l.records))
+	if len(l.records) == maxLogHistory {
+		records = append(records, l.records[l.next:]...)
+		records = append(records, l.records[:l.next]...)
+	} else {
+		records = append(records, l.records...)
+	}
+	l.lock.Unlock()
+
+	var n int64
+	for _, record := range records {
+		m, err := io.WriteString(w, record)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// logFilter is a set of conditions on audit and error log
+// events. An event matches the filter if it satisfies all
+// conditions. The zero logFilter matches any event.
+type logFilter struct {
+	Identity   kes.Identity
+	Path       string // Glob pattern, e.g. '/v1/key/delete/*'
+	StatusCode int
+	Since      time.Time
+	Until      time.Time
+}
+
+// parseLogFilter parses a logFilter from URL query parameters.
+// The following parameters are recognized:
+//   - since:    An RFC 3339 timestamp or date.
+//   - until:    An RFC 3339 timestamp or date.
+//
+// If audit is true, the following parameters are recognized
+// as well:
+//   - identity: The identity of the client.
+//   - path:     The API path. May be a glob pattern.
+//   - status:   The response status code, e.g. '403'.
+//
+// Any other parameter causes an error.
+func parseLogFilter(query url.Values, audit bool) (logFilter, error) {
+	var filter logFilter
+	for name, values := range query {
+		if len(values) > 1 {
+			return logFilter{}, fmt.Errorf("log parameter '%s' specified more than once", name)
+		}
+
+		var err error
+		switch value := values[0]; {
+		case name == "since":
+			filter.Since, err = parseFilterTime(value)
+		case name == "until":
+			filter.Until, err = parseFilterTime(value)
+		case name == "identity" && audit:
+			filter.Identity = kes.Identity(value)
+		case name == "path" && audit:
+			if _, err = path.Match(value, ""); err == nil {
+				filter.Path = value
+			}
+		case name == "status" && audit:
+			filter.StatusCode, err = strconv.Atoi(value)
+			if err == nil && (filter.StatusCode < 100 || filter.StatusCode > 599) {
+				err = fmt.Errorf("'%d' is not an HTTP status code", filter.StatusCode)
+			}
+		default:
+			return logFilter{}, fmt.Errorf("unknown log parameter '%s'", name)
+		}
+		if err != nil {
+			return logFilter{}, fmt.Errorf("invalid log parameter '%s': %v", name, err)
+		}
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return logFilter{}, fmt.Errorf("invalid log time range: 'since' must be before 'until'")
+	}
+	return filter, nil
+}
+
+// InRange reports whether t is within the filter's time range.
+func (f *logFilter) InRange(t time.Time) bool {
+	if !f.Since.IsZero() && t.Before(f.Since) {
+		return false
+	}
+	return f.Until.IsZero() || t.Before(f.Until)
+}
+
+// Match reports whether the audit event satisfies all
+// conditions of the filter. Checkpoints do not describe
+// a request and only have to be within the time range.
+func (f *logFilter) Match(event *api.AuditLogEvent) bool {
+	if !f.InRange(event.Time) {
+		return false
+	}
+	if event.Checkpoint != nil {
+		return true
+	}
+	if f.Identity != "" && f.Identity.String() != event.Request.Identity {
+		return false
+	}
+	if f.Path != "" {
+		if ok, _ := path.Match(f.Path, event.Request.APIPath); !ok {
+			return false
+		}
+	}
+	return f.StatusCode == 0 || f.StatusCode == event.Response.StatusCode
+}
+
+// auditFilterWriter is an io.Writer that writes JSON-encoded
+// audit events to w if they match the filter. Each write must
+// contain exactly one event.
+type auditFilterWriter struct {
+	w      io.Writer
+	filter logFilter
+}
+
+func (w *auditFilterWriter) Write(p []byte) (int, error) {
+	var event api.AuditLogEvent
+	if err := json.Unmarshal(p, &event); err != nil {
+		return 0, err
+	}
+	if !w.filter.Match(&event) {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
+
+// errorFilterWriter is an io.Writer that writes to w if the
+// current time is within the filter's time range. Error log
+// records are written when they occur and carry no timestamp
+// that could be matched otherwise.
+type errorFilterWriter struct {
+	w      io.Writer
+	filter logFilter
+}
+
+func (w *errorFilterWriter) Write(p []byte) (int, error) {
+	if !w.filter.InRange(time.Now()) {
+		return len(p), nil
+	}
+	return w.w.Write(p)
+}
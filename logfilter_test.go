@@ -0,0 +1,86 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+)
+
+func TestLogFilter(t *testing.T) {
+	event := api.AuditLogEvent{
+		Time: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		Request: api.AuditLogRequest{
+			IP:       "10.1.2.3",
+			APIPath:  "/v1/key/delete/my-key",
+			Identity: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+		},
+		Response: api.AuditLogResponse{StatusCode: 200},
+	}
+
+	for i, test := range logFilterTests {
+		query, err := url.ParseQuery(test.Query)
+		if err != nil {
+			t.Fatalf("Test %d: invalid query: %v", i, err)
+		}
+
+		filter, err := parseLogFilter(query, true)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse filter: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing should have failed but succeeded", i)
+		}
+		if test.ShouldFail {
+			continue
+		}
+
+		if match := filter.Match(&event); match != test.Match {
+			t.Fatalf("Test %d: got match '%v' - want '%v'", i, match, test.Match)
+		}
+	}
+}
+
+func TestErrorLogFilter(t *testing.T) {
+	if _, err := parseLogFilter(url.Values{"since": {"2024-01-15"}}, false); err != nil {
+		t.Fatalf("Failed to parse error log filter: %v", err)
+	}
+	for _, name := range []string{"identity", "path", "status"} {
+		if _, err := parseLogFilter(url.Values{name: {"1"}}, false); err == nil {
+			t.Fatalf("Error log filter accepted audit parameter '%s'", name)
+		}
+	}
+}
+
+var logFilterTests = []struct {
+	Query      string
+	Match      bool
+	ShouldFail bool
+}{
+	{Query: "", Match: true}, // 0
+	{Query: "identity=3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", Match: true}, // 1
+	{Query: "identity=unknown", Match: false},                                                         // 2
+	{Query: "path=/v1/key/delete/my-key", Match: true},                                                // 3
+	{Query: "path=/v1/key/delete/*", Match: true},                                                     // 4
+	{Query: "path=/v1/key/create/*", Match: false},                                                    // 5
+	{Query: "status=200", Match: true},                                                                // 6
+	{Query: "status=403", Match: false},                                                               // 7
+	{Query: "since=2024-01-15&until=2024-01-16", Match: true},                                         // 8
+	{Query: "since=2024-01-15T12:00:00Z", Match: true},                                                // 9
+	{Query: "until=2024-01-15T12:00:00Z", Match: false},                                               // 10
+	{Query: "since=2024-01-16", Match: false},                                                         // 11
+	{Query: "path=/v1/key/delete/*&status=200&since=2024-01-15", Match: true},                         // 12
+
+	{Query: "unknown=1", ShouldFail: true},                         // 13
+	{Query: "status=OK", ShouldFail: true},                         // 14
+	{Query: "status=42", ShouldFail: true},                         // 15
+	{Query: "path=/v1/key/[", ShouldFail: true},                    // 16
+	{Query: "since=yesterday", ShouldFail: true},                   // 17
+	{Query: "since=2024-01-16&until=2024-01-15", ShouldFail: true}, // 18
+	{Query: "status=200&status=403", ShouldFail: true},             // 19
+}
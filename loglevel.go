@@ -0,0 +1,119 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/minio/kes/internal/api"
+)
+
+// auditSampling drops the audit events of successful requests
+// except for every Rate-th one. Events of failed requests are
+// never dropped.
+type auditSampling struct {
+	Rate  uint64
+	Paths []string // API path patterns. If empty, all paths are sampled.
+
+	count atomic.Uint64
+}
+
+// Drop reports whether the audit event of a request to the
+// given API path, that has been answered with the given status
+// code, should be dropped.
+func (s *auditSampling) Drop(apiPath string, statusCode int) bool {
+	if s == nil || s.Rate <= 1 || statusCode < 200 || statusCode > 299 {
+		return false
+	}
+	if len(s.Paths) > 0 && !slices.ContainsFunc(s.Paths, func(pattern string) bool {
+		ok, _ := path.Match(pattern, apiPath)
+		return ok
+	}) {
+		return false
+	}
+	return (s.count.Add(1)-1)%s.Rate != 0
+}
+
+// setLogLevel changes the error and audit log levels and the
+// audit sampling of the server. Like the Server.ErrLevel and
+// Server.AuditLevel, these settings are kept across config
+// updates. Requests that change nothing only return the current
+// settings.
+func (s *Server) setLogLevel(resp *api.Response, req *api.Request) {
+	var body api.LogLevelRequest
+	if err := api.ReadBody(req, &body); err != nil && err != io.EOF {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid log level request body")
+		return
+	}
+
+	var errLevel, auditLevel slog.Level
+	if body.ErrorLevel != nil {
+		if err := errLevel.UnmarshalText([]byte(*body.ErrorLevel)); err != nil {
+			resp.Failf(http.StatusBadRequest, "invalid error log level '%s'", *body.ErrorLevel)
+			return
+		}
+	}
+	if body.AuditLevel != nil {
+		if err := auditLevel.UnmarshalText([]byte(*body.AuditLevel)); err != nil {
+			resp.Failf(http.StatusBadRequest, "invalid audit log level '%s'", *body.AuditLevel)
+			return
+		}
+	}
+	if body.AuditSampling != nil {
+		for _, pattern := range body.AuditSampling.Paths {
+			if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+				resp.Failf(http.StatusBadRequest, "invalid audit sampling path '%s'", pattern)
+				return
+			}
+		}
+	}
+
+	// The change is logged before it is applied such that it is
+	// recorded even when it disables the audit log.
+	const StatusOK = http.StatusOK
+	if body.ErrorLevel != nil || body.AuditLevel != nil || body.AuditSampling != nil {
+		s.state.Load().Audit.Log("log level changed", StatusOK, req)
+	}
+	if body.ErrorLevel != nil {
+		s.ErrLevel.Set(errLevel)
+	}
+	if body.AuditLevel != nil {
+		s.AuditLevel.Set(auditLevel)
+	}
+	if body.AuditSampling != nil {
+		if body.AuditSampling.Rate > 1 {
+			s.auditSampling.Store(&auditSampling{
+				Rate:  body.AuditSampling.Rate,
+				Paths: slices.Clone(body.AuditSampling.Paths),
+			})
+		} else {
+			s.auditSampling.Store(nil)
+		}
+	}
+
+	reply := api.LogLevelResponse{
+		ErrorLevel: s.ErrLevel.Level().String(),
+		AuditLevel: s.AuditLevel.Level().String(),
+	}
+	if sampling := s.auditSampling.Load(); sampling != nil {
+		reply.AuditSampling = api.AuditSampling{
+			Rate:  sampling.Rate,
+			Paths: slices.Clone(sampling.Paths),
+		}
+	}
+	api.ReplyWith(resp, StatusOK, reply)
+}
@@ -0,0 +1,120 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+)
+
+func TestAuditSampling(t *testing.T) {
+	var sampling atomic.Pointer[auditSampling]
+	sampling.Store(&auditSampling{Rate: 3, Paths: []string{"/v1/key/describe/*"}})
+
+	handler := &auditRecorder{}
+	logger := newAuditLogger(handler, slog.LevelInfo)
+	logger.sampling = &sampling
+
+	for i := 0; i < 9; i++ {
+		logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	}
+	if n := len(handler.Records); n != 3 {
+		t.Fatalf("Invalid number of sampled records: got '%d' - want '3'", n)
+	}
+	if logger.count != 3 {
+		t.Fatalf("Dropped records have been hashed: got count '%d' - want '3'", logger.count)
+	}
+
+	logger.Log("audit", http.StatusForbidden, newAuditTestRequest())
+	logger.Log("audit", http.StatusForbidden, newAuditTestRequest())
+	if n := len(handler.Records); n != 5 {
+		t.Fatalf("Records of failed requests have been dropped: got '%d' records - want '5'", n)
+	}
+
+	sampling.Store(&auditSampling{Rate: 3, Paths: []string{"/v1/key/decrypt/*"}})
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	logger.Log("audit", http.StatusOK, newAuditTestRequest())
+	if n := len(handler.Records); n != 7 {
+		t.Fatalf("Records of not sampled paths have been dropped: got '%d' records - want '7'", n)
+	}
+}
+
+func TestServerLogLevel(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	setLogLevel := func(body api.LogLevelRequest) (*api.LogLevelResponse, error) {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathAdminLogLevel, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return nil, api.ReadError(resp)
+		}
+		var level api.LogLevelResponse
+		if err := json.NewDecoder(resp.Body).Decode(&level); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		return &level, nil
+	}
+	str := func(s string) *string { return &s }
+
+	resp, err := setLogLevel(api.LogLevelRequest{
+		ErrorLevel:    str("debug"),
+		AuditLevel:    str("WARN"),
+		AuditSampling: &api.AuditSampling{Rate: 100, Paths: []string{"/v1/key/decrypt/*"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to change log level: %v", err)
+	}
+	if resp.ErrorLevel != "DEBUG" || resp.AuditLevel != "WARN" || resp.AuditSampling.Rate != 100 {
+		t.Fatalf("Invalid log level: got '%+v'", resp)
+	}
+	if srv.ErrLevel.Level() != slog.LevelDebug || srv.AuditLevel.Level() != slog.LevelWarn {
+		t.Fatalf("Log level not applied: got error level '%v' and audit level '%v'", srv.ErrLevel.Level(), srv.AuditLevel.Level())
+	}
+
+	if resp, err = setLogLevel(api.LogLevelRequest{}); err != nil {
+		t.Fatalf("Failed to fetch log level: %v", err)
+	}
+	if resp.ErrorLevel != "DEBUG" || resp.AuditSampling.Rate != 100 {
+		t.Fatalf("Fetching log level changed it: got '%+v'", resp)
+	}
+	if resp, err = setLogLevel(api.LogLevelRequest{AuditSampling: &api.AuditSampling{Rate: 1}}); err != nil {
+		t.Fatalf("Failed to disable audit sampling: %v", err)
+	}
+	if resp.AuditSampling.Rate != 0 || srv.auditSampling.Load() != nil {
+		t.Fatalf("Audit sampling not disabled: got '%+v'", resp.AuditSampling)
+	}
+
+	for i, body := range []api.LogLevelRequest{
+		{ErrorLevel: str("verbose")},
+		{AuditLevel: str("")},
+		{AuditSampling: &api.AuditSampling{Rate: 10, Paths: []string{"/v1/key/["}}},
+		{AuditSampling: &api.AuditSampling{Rate: 10, Paths: []string{"v1/key/*"}}},
+	} {
+		if _, err = setLogLevel(body); err == nil {
+			t.Fatalf("Test %d: invalid request should have failed", i)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/minio/kes/internal/metric"
+	"github.com/minio/kms-go/kes"
+)
+
+// meterKeyStore returns a KeyStore that records the response
+// time and errors of each operation on the KeyStore, labeled
+// by the KeyStore backend.
+func meterKeyStore(store KeyStore, metrics *metric.Metrics) KeyStore {
+	backend := fmt.Sprintf("%T", store)
+	if s, ok := store.(fmt.Stringer); ok {
+		backend = s.String()
+	}
+	return &meteringKeyStore{
+		store:   store,
+		backend: backend,
+		metrics: metrics,
+	}
+}
+
+// meteringKeyStore is a KeyStore that records the response
+// time and errors of each operation on the wrapped KeyStore.
+type meteringKeyStore struct {
+	store   KeyStore
+	backend string
+	metrics *metric.Metrics
+}
+
+var _ KeyStore = (*meteringKeyStore)(nil) // compiler check
+
+func (ks *meteringKeyStore) String() string { return ks.backend }
+
+// Unwrap returns the underlying KeyStore.
+func (ks *meteringKeyStore) Unwrap() KeyStore { return ks.store }
+
+func (ks *meteringKeyStore) Close() error { return ks.store.Close() }
+
+func (ks *meteringKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	start := time.Now()
+	state, err := ks.store.Status(ctx)
+	ks.observe("Status", start, err)
+	return state, err
+}
+
+func (ks *meteringKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	start := time.Now()
+	err := ks.store.Create(ctx, name, value)
+	ks.observe("Create", start, err)
+	return err
+}
+
+func (ks *meteringKeyStore) Delete(ctx context.Context, name string) error {
+	start := time.Now()
+	err := ks.store.Delete(ctx, name)
+	ks.observe("Delete", start, err)
+	return err
+}
+
+func (ks *meteringKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	start := time.Now()
+	value, err := ks.store.Get(ctx, name)
+	ks.observe("Get", start, err)
+	return value, err
+}
+
+func (ks *meteringKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	start := time.Now()
+	names, next, err := ks.store.List(ctx, prefix, n)
+	ks.observe("List", start, err)
+	return names, next, err
+}
+
+// observe records the response time of the operation started
+// at start. Keys that do not exist or already exist and canceled
+// requests are not recorded as error.
+func (ks *meteringKeyStore) observe(op string, start time.Time, err error) {
+	failed := err != nil &&
+		!errors.Is(err, kes.ErrKeyNotFound) &&
+		!errors.Is(err, kes.ErrKeyExists) &&
+		!errors.Is(err, context.Canceled)
+	ks.metrics.ObserveKeyStore(ks.backend, op, time.Since(start), failed)
+}
@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/minio/kes/internal/metric"
+)
+
+// DefaultMetricsPushInterval is the time between two metric
+// pushes if MetricsPushConfig.Interval is not set.
+const DefaultMetricsPushInterval = 1 * time.Minute
+
+// pushMetrics pushes the server metrics to the metrics push
+// endpoint, if configured, until ctx is canceled.
+func (s *Server) pushMetrics(ctx context.Context) {
+	const (
+		Delay   = 1 * time.Minute
+		Timeout = 30 * time.Second
+	)
+
+	instance, _ := os.Hostname()
+
+	var (
+		conf   *MetricsPushConfig
+		client *http.Client
+	)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.MetricsPush == nil {
+			timer.Reset(Delay)
+			continue
+		}
+		if state.MetricsPush != conf {
+			if client != nil {
+				client.CloseIdleConnections()
+			}
+			conf = state.MetricsPush
+
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = conf.TLS
+			client = &http.Client{Transport: transport}
+		}
+
+		job := conf.Job
+		if job == "" {
+			job = "kes"
+		}
+		opts := &metric.PushOptions{
+			Client:   client,
+			Endpoint: conf.Endpoint,
+			Job:      job,
+			Instance: instance,
+			Username: conf.Username,
+			Password: conf.Password,
+		}
+
+		s.updateMetrics(state)
+		pushCtx, cancel := context.WithTimeout(ctx, Timeout)
+		var err error
+		if conf.Protocol == RemoteWrite {
+			err = state.Metrics.RemoteWrite(pushCtx, opts)
+		} else {
+			err = state.Metrics.Push(pushCtx, opts)
+		}
+		cancel()
+		if err != nil && ctx.Err() == nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to push metrics to '%s': %v", conf.Endpoint, err))
+		}
+
+		interval := conf.Interval
+		if interval <= 0 {
+			interval = DefaultMetricsPushInterval
+		}
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+var pushMetricsTests = []struct {
+	Protocol MetricsPushProtocol
+	Endpoint string
+	Method   string
+	Path     string
+	Header   http.Header
+}{
+	{ // 0
+		Protocol: PushGateway,
+		Method:   http.MethodPut,
+		Path:     "/metrics/job/kes-test/instance/",
+	},
+	{ // 1
+		Protocol: RemoteWrite,
+		Endpoint: "/api/v1/push",
+		Method:   http.MethodPost,
+		Path:     "/api/v1/push",
+		Header: http.Header{
+			"Content-Type":     []string{"application/x-protobuf"},
+			"Content-Encoding": []string{"snappy"},
+		},
+	},
+}
+
+func TestPushMetrics(t *testing.T) {
+	type Request struct {
+		Method, Path string
+		Header       http.Header
+		Body         []byte
+		User, Pass   string
+	}
+
+	for i, test := range pushMetricsTests {
+		requests := make(chan Request, 1)
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			user, pass, _ := r.BasicAuth()
+			select {
+			case requests <- Request{Method: r.Method, Path: r.URL.Path, Header: r.Header, Body: body, User: user, Pass: pass}:
+			default:
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		ctx := testContext(t)
+		srv, _ := startServer(ctx, &Config{
+			MetricsPush: &MetricsPushConfig{
+				Endpoint: target.URL + test.Endpoint,
+				Protocol: test.Protocol,
+				Job:      "kes-test",
+				Interval: time.Hour,
+				Username: "minio",
+				Password: "minio123",
+			},
+		})
+
+		var req Request
+		select {
+		case req = <-requests:
+		case <-time.After(10 * time.Second):
+			t.Fatalf("Test %d: no metrics have been pushed", i)
+		}
+		srv.Close()
+		target.Close()
+
+		if req.Method != test.Method {
+			t.Fatalf("Test %d: method mismatch: got '%s' - want '%s'", i, req.Method, test.Method)
+		}
+		if !strings.HasPrefix(req.Path, test.Path) {
+			t.Fatalf("Test %d: path mismatch: got '%s' - want prefix '%s'", i, req.Path, test.Path)
+		}
+		for name := range test.Header {
+			if got, want := req.Header.Get(name), test.Header.Get(name); got != want {
+				t.Fatalf("Test %d: header '%s' mismatch: got '%s' - want '%s'", i, name, got, want)
+			}
+		}
+		if req.User != "minio" || req.Pass != "minio123" {
+			t.Fatalf("Test %d: basic auth mismatch: got '%s:%s' - want 'minio:minio123'", i, req.User, req.Pass)
+		}
+		body := req.Body
+		if req.Header.Get("Content-Encoding") == "snappy" {
+			var err error
+			if body, err = snappy.Decode(nil, body); err != nil {
+				t.Fatalf("Test %d: failed to decode snappy body: %v", i, err)
+			}
+		}
+		if !bytes.Contains(body, []byte("kes_system_up_time")) {
+			t.Fatalf("Test %d: pushed metrics do not contain 'kes_system_up_time'", i)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestKeyStoreMetrics(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := client.CreateKey(ctx, "my-key"); err == nil {
+		t.Fatal("Creating an existing key should fail")
+	}
+
+	metrics := fetchMetrics(ctx, t, client, url)
+	const Latency = `kes_keystore_response_time_count{backend="In Memory",operation="Create"} 2`
+	if !strings.Contains(metrics, Latency) {
+		t.Fatalf("Metrics do not contain '%s'", Latency)
+	}
+	if strings.Contains(metrics, "kes_keystore_errors{") {
+		t.Fatal("Metrics contain keystore errors for an existing key")
+	}
+}
+
+func TestAPIMetrics(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, &Config{MetricsLabel: MetricsByIdentity})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	metrics := fetchMetrics(ctx, t, client, url)
+	requests := `kes_http_api_requests{api="` + api.PathKeyCreate + `",code="200",identity="` + defaultIdentity + `",policy=""} 1`
+	if !strings.Contains(metrics, requests) {
+		t.Fatalf("Metrics do not contain '%s'", requests)
+	}
+	latency := `kes_http_api_response_time_count{api="` + api.PathKeyCreate + `",identity="` + defaultIdentity + `",policy=""} 1`
+	if !strings.Contains(metrics, latency) {
+		t.Fatalf("Metrics do not contain '%s'", latency)
+	}
+}
+
+func TestMetricsListener(t *testing.T) {
+	for _, useTLS := range []bool{false, true} {
+		ctx := testContext(t)
+		srv, _ := startServer(ctx, &Config{
+			MetricsListener: &MetricsListenerConfig{Addr: "127.0.0.1:0", TLS: useTLS},
+		})
+		defer srv.Close()
+
+		srv.mu.Lock()
+		addr := srv.metricsLn.Addr().String()
+		srv.mu.Unlock()
+
+		// The client sends no certificate.
+		client := &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			},
+		}
+		url := "http://" + addr
+		if useTLS {
+			url = "https://" + addr
+		}
+		for path, code := range map[string]int{
+			api.PathMetrics:     http.StatusOK,
+			api.PathHealthLive:  http.StatusOK,
+			api.PathHealthReady: http.StatusOK,
+			api.PathVersion:     http.StatusOK,
+			api.PathKeyList:     http.StatusNotFound,
+			api.PathStatus:      http.StatusNotFound,
+		} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatalf("TLS=%v: Failed to send request to '%s': %v", useTLS, path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != code {
+				t.Fatalf("TLS=%v: Invalid status code for '%s': got '%d' - want '%d'", useTLS, path, resp.StatusCode, code)
+			}
+		}
+	}
+}
+
+func fetchMetrics(ctx context.Context, t *testing.T, client *kes.Client, url string) string {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathMetrics, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to fetch metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("Failed to read metrics: %v", err)
+	}
+	return string(body)
+}
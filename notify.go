@@ -0,0 +1,196 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// EventType is the type of a lifecycle Event.
+type EventType string
+
+// Lifecycle event types.
+const (
+	EventKeyCreated EventType = "key.created"
+	EventKeyRotated EventType = "key.rotated"
+	EventKeyDeleted EventType = "key.deleted"
+
+	EventPolicyCreated EventType = "policy.created"
+	EventPolicyUpdated EventType = "policy.updated"
+	EventPolicyDeleted EventType = "policy.deleted"
+
+	EventIdentityCreated EventType = "identity.created"
+	EventIdentityUpdated EventType = "identity.updated"
+	EventIdentityDeleted EventType = "identity.deleted"
+)
+
+// An Event describes a change of a key, policy or identity.
+//
+// Keys are created, rotated and deleted via the API. Policies change
+// when the server configuration is updated. Identities change
+// when they are assigned to a policy, either via the API or
+// by updating the server configuration.
+type Event struct {
+	// Type is the type of the event.
+	Type EventType
+
+	// Point in time when the event happened.
+	Time time.Time
+
+	// Name is the name of the key or policy or, for identity
+	// events, the identity.
+	Name string
+
+	// Policy is the name of the policy an identity is assigned
+	// to. It is empty for key and policy events and when an
+	// identity has been deleted.
+	Policy string
+
+	// Identity is the identity of the client that caused the
+	// event. It is empty for events caused by updating the
+	// server configuration.
+	Identity kes.Identity
+}
+
+// A NotificationTarget publishes lifecycle events to some
+// external system, like a webhook or a message queue, such
+// that dependent systems can react to them.
+type NotificationTarget interface {
+	// Publish publishes the event. It may be called
+	// concurrently and should return once the event
+	// has been delivered or ctx is canceled.
+	Publish(ctx context.Context, event Event) error
+}
+
+const (
+	// eventQueueSize is the max. number of events waiting
+	// to be published. Once reached, further events are
+	// dropped until queued events have been published.
+	eventQueueSize = 1000
+
+	// eventPublishTimeout is the max. time publishing
+	// an event to a single target may take.
+	eventPublishTimeout = 10 * time.Second
+)
+
+// notify sends the events to all clients subscribed to the Watch
+// API and queues them for publishing to the notification targets,
+// if any. It does not wait for events to be published. If the
+// queue is full, the events are dropped. If replication is enabled,
+// new keys and identity assignments trigger a replication run.
+func (s *Server) notify(events ...Event) {
+	if s.watchers.Num() > 0 {
+		s.watchLock.Lock()
+		for _, event := range events {
+			json.NewEncoder(&s.watchers).Encode(api.LifecycleEvent{
+				Type:     string(event.Type),
+				Time:     event.Time,
+				Name:     event.Name,
+				Policy:   event.Policy,
+				Identity: event.Identity.String(),
+			})
+		}
+		s.watchLock.Unlock()
+	}
+
+	state := s.state.Load()
+	if state.Replication != nil {
+		for _, event := range events {
+			if event.Type == EventKeyCreated || event.Type == EventPolicyCreated || event.Type == EventIdentityCreated || event.Type == EventIdentityUpdated {
+				select {
+				case s.replicateNow <- struct{}{}:
+				default:
+				}
+				break
+			}
+		}
+	}
+	if len(state.Notifications) == 0 {
+		return
+	}
+	for _, event := range events {
+		select {
+		case s.events <- event:
+		default:
+			state.Log.Error(fmt.Sprintf("kes: dropped %s event for '%s': event queue is full", event.Type, event.Name))
+		}
+	}
+}
+
+// publishEvents publishes queued events to all notification
+// targets until ctx is canceled.
+func (s *Server) publishEvents(ctx context.Context) {
+	for {
+		var event Event
+		select {
+		case <-ctx.Done():
+			return
+		case event = <-s.events:
+		}
+
+		state := s.state.Load()
+		for _, target := range state.Notifications {
+			tctx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+			if err := target.Publish(tctx, event); err != nil {
+				state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to publish %s event for '%s': %v", event.Type, event.Name, err))
+			}
+			cancel()
+		}
+	}
+}
+
+// policyEvents returns the events describing how the policies
+// have changed from old to new.
+func policyEvents(old, new map[string]*kes.Policy) []Event {
+	now := time.Now().UTC()
+
+	var events []Event
+	for name, policy := range new {
+		oldPolicy, ok := old[name]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventPolicyCreated, Time: now, Name: name})
+		case !maps.Equal(oldPolicy.Allow, policy.Allow) || !maps.Equal(oldPolicy.Deny, policy.Deny):
+			events = append(events, Event{Type: EventPolicyUpdated, Time: now, Name: name})
+		}
+	}
+	for name := range old {
+		if _, ok := new[name]; !ok {
+			events = append(events, Event{Type: EventPolicyDeleted, Time: now, Name: name})
+		}
+	}
+	return events
+}
+
+// identityEvents returns the events describing how the identities
+// have changed from old to new. The identity causing the changes
+// may be empty.
+func identityEvents(old, new map[kes.Identity]identityEntry, by kes.Identity) []Event {
+	now := time.Now().UTC()
+
+	var events []Event
+	for id, entry := range new {
+		oldEntry, ok := old[id]
+		switch {
+		case !ok:
+			events = append(events, Event{Type: EventIdentityCreated, Time: now, Name: id.String(), Policy: entry.Name, Identity: by})
+		case oldEntry.Name != entry.Name:
+			events = append(events, Event{Type: EventIdentityUpdated, Time: now, Name: id.String(), Policy: entry.Name, Identity: by})
+		}
+	}
+	for id := range old {
+		if _, ok := new[id]; !ok {
+			events = append(events, Event{Type: EventIdentityDeleted, Time: now, Name: id.String(), Identity: by})
+		}
+	}
+	return events
+}
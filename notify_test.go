@@ -0,0 +1,152 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestNotifications(t *testing.T) {
+	ctx := testContext(t)
+	target := make(eventRecorder, 2)
+	srv, url := startServer(ctx, &Config{
+		Notifications: []NotificationTarget{target},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := client.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+
+	for _, want := range []EventType{EventKeyCreated, EventKeyDeleted} {
+		select {
+		case event := <-target:
+			if event.Type != want || event.Name != "my-key" || event.Identity != defaultIdentity {
+				t.Fatalf("Invalid event: got %+v - want type '%s'", event, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("No %s event published: %v", want, ctx.Err())
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathWatch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to watch: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to watch: got status '%s'", resp.Status)
+	}
+	for srv.watchers.Num() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err = client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err = client.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for _, want := range []EventType{EventKeyCreated, EventKeyDeleted} {
+		var event api.LifecycleEvent
+		if err = decoder.Decode(&event); err != nil {
+			t.Fatalf("Failed to read event: %v", err)
+		}
+		if event.Type != string(want) || event.Name != "my-key" || event.Identity != defaultIdentity {
+			t.Fatalf("Invalid event: got %+v - want type '%s'", event, want)
+		}
+	}
+}
+
+func TestPolicyEvents(t *testing.T) {
+	old := map[string]*kes.Policy{
+		"unchanged": {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}},
+		"updated":   {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}},
+		"deleted":   {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}},
+	}
+	new := map[string]*kes.Policy{
+		"unchanged": {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}},
+		"updated":   {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}, Deny: map[string]kes.Rule{"/v1/key/delete/*": {}}},
+		"created":   {Allow: map[string]kes.Rule{"/v1/key/create/*": {}}},
+	}
+
+	events := policyEvents(old, new)
+	got := make([]string, 0, len(events))
+	for _, e := range events {
+		got = append(got, string(e.Type)+" "+e.Name)
+	}
+	slices.Sort(got)
+
+	want := []string{"policy.created created", "policy.deleted deleted", "policy.updated updated"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Invalid policy events: got %v - want %v", got, want)
+	}
+}
+
+func TestIdentityEvents(t *testing.T) {
+	old := map[kes.Identity]identityEntry{
+		"unchanged": {Name: "policy-1"},
+		"updated":   {Name: "policy-1"},
+		"deleted":   {Name: "policy-1"},
+	}
+	new := map[kes.Identity]identityEntry{
+		"unchanged": {Name: "policy-1"},
+		"updated":   {Name: "policy-2"},
+		"created":   {Name: "policy-1"},
+	}
+
+	events := identityEvents(old, new, "admin")
+	got := make([]string, 0, len(events))
+	for _, e := range events {
+		if e.Identity != "admin" {
+			t.Fatalf("Invalid event identity: got '%s' - want '%s'", e.Identity, "admin")
+		}
+		got = append(got, string(e.Type)+" "+e.Name+" "+e.Policy)
+	}
+	slices.Sort(got)
+
+	want := []string{"identity.created created policy-1", "identity.deleted deleted ", "identity.updated updated policy-2"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("Invalid identity events: got %v - want %v", got, want)
+	}
+}
+
+// eventRecorder is a NotificationTarget that sends
+// all published events to the channel.
+type eventRecorder chan Event
+
+func (r eventRecorder) Publish(ctx context.Context, event Event) error {
+	select {
+	case r <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,289 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+)
+
+// oidcSignatureAlgorithms are the JWT signature algorithms
+// accepted by an oidcVerifier. Symmetric algorithms, like
+// HS256, are not supported since the signing keys are public.
+var oidcSignatureAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// oidcVerifier verifies JSON Web Tokens issued by an OIDC
+// provider and determines the identity and policy of the
+// client that has sent the token.
+type oidcVerifier struct {
+	conf   *OIDCConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetchedAt time.Time
+}
+
+// newOIDCVerifier returns a new oidcVerifier for the given config.
+// It returns nil if conf is nil.
+func newOIDCVerifier(conf *OIDCConfig) *oidcVerifier {
+	if conf == nil {
+		return nil
+	}
+
+	conf = conf.clone()
+	if conf.IdentityClaim == "" {
+		conf.IdentityClaim = "sub"
+	}
+	client := conf.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &oidcVerifier{
+		conf:   conf,
+		client: client,
+	}
+}
+
+// bearerToken returns the bearer token of the request's
+// Authorization header, if any.
+func bearerToken(req *http.Request) (string, bool) {
+	const Prefix = "Bearer "
+
+	auth := req.Header.Get(headers.Authorization)
+	if len(auth) <= len(Prefix) || !strings.EqualFold(auth[:len(Prefix)], Prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(auth[len(Prefix):]), true
+}
+
+// oidcPolicyKey is the request context key of the name of
+// the policy assigned to a client authenticated by a token.
+type oidcPolicyKey struct{}
+
+// withOIDCPolicy returns a copy of ctx carrying the policy name.
+func withOIDCPolicy(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, oidcPolicyKey{}, name)
+}
+
+// Verify verifies the token and returns the client identity
+// and the name of the policy assigned to the client. The
+// identity is namespaced by the issuer, "oidc:<issuer>#<claim>",
+// such that it never collides with a certificate identity or
+// an identity of another provider. It
+// returns an error if the token is invalid or has expired, or
+// if the token does not contain the claims of exactly one
+// policy.
+func (v *oidcVerifier) Verify(ctx context.Context, token string, now time.Time) (kes.Identity, string, error) {
+	const Leeway = 1 * time.Minute // Max. tolerated clock skew
+
+	tok, err := jwt.ParseSigned(token)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid token: %v", err)
+	}
+	if len(tok.Headers) != 1 {
+		return "", "", errors.New("invalid token: token must have exactly one signature")
+	}
+	header := tok.Headers[0]
+	if !slices.Contains(oidcSignatureAlgorithms, jose.SignatureAlgorithm(header.Algorithm)) {
+		return "", "", fmt.Errorf("invalid token: unsupported signature algorithm '%s'", header.Algorithm)
+	}
+
+	keys, err := v.keySet(ctx, header.KeyID, now)
+	if err != nil {
+		return "", "", err
+	}
+	var (
+		claims jwt.Claims
+		custom map[string]any
+	)
+	verified := false
+	for _, key := range keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if err = tok.Claims(key, &claims, &custom); err == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return "", "", errors.New("invalid token: signature verification failed")
+	}
+
+	if claims.Expiry == nil {
+		return "", "", errors.New("invalid token: token does not expire")
+	}
+	err = claims.ValidateWithLeeway(jwt.Expected{
+		Issuer:   v.conf.Issuer,
+		Audience: jwt.Audience{v.conf.Audience},
+		Time:     now,
+	}, Leeway)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid token: %v", err)
+	}
+
+	identity, ok := custom[v.conf.IdentityClaim].(string)
+	if !ok || identity == "" {
+		return "", "", fmt.Errorf("invalid token: no '%s' claim", v.conf.IdentityClaim)
+	}
+
+	var policy string
+	for name, required := range v.conf.Policies {
+		if !containsClaims(custom, required) {
+			continue
+		}
+		if policy != "" {
+			return "", "", fmt.Errorf("token matches multiple policies: '%s' and '%s'", policy, name)
+		}
+		policy = name
+	}
+	if policy == "" {
+		return "", "", errors.New("token matches no policy")
+	}
+	return kes.Identity("oidc:" + v.conf.Issuer + "#" + identity), policy, nil
+}
+
+// keySet returns the provider's signing keys with the given key ID,
+// or all keys if kid is empty. It fetches the keys if they have not
+// been fetched yet, are older than one hour or if no key with the
+// given ID is known. The keys are fetched at most once per minute.
+func (v *oidcVerifier) keySet(ctx context.Context, kid string, now time.Time) ([]jose.JSONWebKey, error) {
+	const (
+		MaxAge       = 1 * time.Hour   // Max. time after which the keys are fetched again
+		RefreshDelay = 1 * time.Minute // Min. time between two fetches
+	)
+	lookup := func(set *jose.JSONWebKeySet) []jose.JSONWebKey {
+		if set == nil {
+			return nil
+		}
+		if kid == "" {
+			return set.Keys
+		}
+		return set.Key(kid)
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	set := v.keys
+	keys := lookup(set)
+	if len(keys) > 0 && now.Sub(v.fetchedAt) < MaxAge {
+		return keys, nil
+	}
+	if set != nil && now.Sub(v.fetchedAt) < RefreshDelay {
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("invalid token: unknown signing key '%s'", kid)
+		}
+		return keys, nil
+	}
+
+	fetched, err := v.fetchKeys(ctx)
+	if err != nil {
+		if len(keys) > 0 { // Keep using the cached keys if the provider is not reachable
+			return keys, nil
+		}
+		return nil, err
+	}
+	v.keys = fetched
+	v.fetchedAt = now
+
+	if keys = lookup(fetched); len(keys) == 0 {
+		return nil, fmt.Errorf("invalid token: unknown signing key '%s'", kid)
+	}
+	return keys, nil
+}
+
+// fetchKeys fetches the provider's signing keys via the
+// provider's discovery document.
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (*jose.JSONWebKeySet, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.get(ctx, strings.TrimSuffix(v.conf.Issuer, "/")+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != v.conf.Issuer {
+		return nil, fmt.Errorf("kes: OIDC issuer mismatch: got '%s' - want '%s'", discovery.Issuer, v.conf.Issuer)
+	}
+	if !strings.HasPrefix(discovery.JWKSURI, "https://") {
+		return nil, fmt.Errorf("kes: invalid OIDC JWKS URI '%s'", discovery.JWKSURI)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := v.get(ctx, discovery.JWKSURI, &keys); err != nil {
+		return nil, err
+	}
+	return &keys, nil
+}
+
+// get sends a GET request to url and decodes the JSON
+// response body into v.
+func (v *oidcVerifier) get(ctx context.Context, url string, dst any) error {
+	const MaxBody = 1 << 20
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("kes: failed to fetch OIDC provider config: %v", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("kes: failed to fetch OIDC provider config: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kes: failed to fetch OIDC provider config from '%s': %s", url, resp.Status)
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, MaxBody)).Decode(dst); err != nil {
+		return fmt.Errorf("kes: failed to decode OIDC provider config from '%s': %v", url, err)
+	}
+	return nil
+}
+
+// containsClaims reports whether the claims contain all
+// required claims. A claim is contained if its value is
+// equal to the required value or, for list claims, if the
+// list contains the required value.
+func containsClaims(claims map[string]any, required map[string]string) bool {
+	for name, want := range required {
+		switch v := claims[name].(type) {
+		case string:
+			if v != want {
+				return false
+			}
+		case []any:
+			if !slices.ContainsFunc(v, func(e any) bool { return fmt.Sprint(e) == want }) {
+				return false
+			}
+		case nil:
+			return false
+		default:
+			if fmt.Sprint(v) != want {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,272 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-jose/go-jose/v3"
+	"github.com/go-jose/go-jose/v3/jwt"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestOIDCVerify(t *testing.T) {
+	ctx := testContext(t)
+	provider := newTestOIDCProvider(t)
+
+	verifier := newOIDCVerifier(&OIDCConfig{
+		Issuer:   provider.Issuer,
+		Audience: "kes",
+		Policies: map[string]map[string]string{
+			"admins":  {"groups": "admins"},
+			"readers": {"groups": "readers", "env": "prod"},
+		},
+		Client: provider.Client,
+	})
+
+	now := time.Now()
+	for i, test := range []struct {
+		Token    string
+		Identity kes.Identity
+		Policy   string
+		Fail     bool
+	}{
+		{
+			Token:    provider.Sign(t, provider.Claims("alice", now, map[string]any{"groups": []string{"admins"}})),
+			Identity: kes.Identity("oidc:" + provider.Issuer + "#alice"),
+			Policy:   "admins",
+		},
+		{
+			Token:    provider.Sign(t, provider.Claims("bob", now, map[string]any{"groups": "readers", "env": "prod"})),
+			Identity: kes.Identity("oidc:" + provider.Issuer + "#bob"),
+			Policy:   "readers",
+		},
+		{ // No policy matches
+			Token: provider.Sign(t, provider.Claims("bob", now, map[string]any{"groups": "readers", "env": "dev"})),
+			Fail:  true,
+		},
+		{ // More than one policy matches
+			Token: provider.Sign(t, provider.Claims("carol", now, map[string]any{"groups": []string{"admins", "readers"}, "env": "prod"})),
+			Fail:  true,
+		},
+		{ // Expired
+			Token: provider.Sign(t, provider.Claims("alice", now.Add(-2*time.Hour), map[string]any{"groups": "admins"})),
+			Fail:  true,
+		},
+		{ // Wrong audience
+			Token: provider.Sign(t, provider.Claims("alice", now, map[string]any{"groups": "admins", "aud": "other"})),
+			Fail:  true,
+		},
+		{ // Wrong issuer
+			Token: provider.Sign(t, provider.Claims("alice", now, map[string]any{"groups": "admins", "iss": "https://example.com"})),
+			Fail:  true,
+		},
+		{ // No expiry
+			Token: provider.Sign(t, provider.Claims("alice", now, map[string]any{"groups": "admins", "exp": nil})),
+			Fail:  true,
+		},
+		{ // Signed by an unknown key
+			Token: newTestOIDCProvider(t).Sign(t, provider.Claims("alice", now, map[string]any{"groups": "admins"})),
+			Fail:  true,
+		},
+		{
+			Token: "not-a-jwt",
+			Fail:  true,
+		},
+	} {
+		identity, policy, err := verifier.Verify(ctx, test.Token, now)
+		if err == nil && test.Fail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.Fail {
+			t.Fatalf("Test %d: failed to verify token: %v", i, err)
+		}
+		if identity != test.Identity {
+			t.Fatalf("Test %d: invalid identity: got '%s' - want '%s'", i, identity, test.Identity)
+		}
+		if policy != test.Policy {
+			t.Fatalf("Test %d: invalid policy: got '%s' - want '%s'", i, policy, test.Policy)
+		}
+	}
+}
+
+func TestServerOIDC(t *testing.T) {
+	ctx := testContext(t)
+	provider := newTestOIDCProvider(t)
+
+	srv, endpoint := startServer(ctx, &Config{
+		TLS: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			ClientAuth:   tls.RequestClientCert,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		OIDC: &OIDCConfig{
+			Issuer:   provider.Issuer,
+			Audience: "kes",
+			Policies: map[string]map[string]string{
+				"my-policy": {"groups": "kes-users"},
+			},
+			Client: provider.Client,
+		},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow: map[string]kes.Rule{api.PathKeyCreate + "my-key*": {}},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12, RootCAs: rootCAs},
+		},
+	}
+	createKey := func(name, token string) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint+api.PathKeyCreate+name, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	token := provider.Sign(t, provider.Claims("alice", time.Now(), map[string]any{"groups": "kes-users"}))
+	if code := createKey("my-key", token); code != http.StatusOK {
+		t.Fatalf("Creating key: got status '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := createKey("other-key", token); code != http.StatusForbidden {
+		t.Fatalf("Creating key: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+
+	token = provider.Sign(t, provider.Claims("bob", time.Now(), map[string]any{"groups": "others"}))
+	if code := createKey("my-key-2", token); code != http.StatusForbidden {
+		t.Fatalf("Creating key: got status '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	if code := createKey("my-key-3", ""); code == http.StatusOK {
+		t.Fatal("Creating key without token or client certificate should fail")
+	}
+}
+
+func TestVerifyConfigOIDC(t *testing.T) {
+	for i, test := range []struct {
+		OIDC       *OIDCConfig
+		ShouldFail bool
+	}{
+		{OIDC: &OIDCConfig{Issuer: "https://example.com", Audience: "kes", Policies: map[string]map[string]string{"p": {"groups": "g"}}}},
+		{OIDC: &OIDCConfig{Issuer: "https://example.com", Audience: "kes", Policies: map[string]map[string]string{"p": nil}}},
+		{OIDC: &OIDCConfig{Issuer: "http://example.com", Audience: "kes", Policies: map[string]map[string]string{"p": nil}}, ShouldFail: true},
+		{OIDC: &OIDCConfig{Issuer: "https://example.com", Policies: map[string]map[string]string{"p": nil}}, ShouldFail: true},
+		{OIDC: &OIDCConfig{Issuer: "https://example.com", Audience: "kes"}, ShouldFail: true},
+		{OIDC: &OIDCConfig{Issuer: "https://example.com", Audience: "kes", Policies: map[string]map[string]string{"p": {"": "g"}}}, ShouldFail: true},
+	} {
+		conf := &Config{
+			TLS: &tls.Config{
+				ClientAuth:   tls.RequestClientCert,
+				Certificates: []tls.Certificate{defaultServerCertificate()},
+			},
+			OIDC: test.OIDC,
+			Keys: &MemKeyStore{},
+		}
+		err := verifyConfig(conf)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify config: %v", i, err)
+		}
+	}
+}
+
+// testOIDCProvider is an OIDC provider that serves its
+// discovery document and signing keys via HTTPS.
+type testOIDCProvider struct {
+	Issuer string
+	Client *http.Client
+
+	key *rsa.PrivateKey
+}
+
+func newTestOIDCProvider(t *testing.T) *testOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewTLSServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   srv.URL,
+			"jwks_uri": srv.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, _ *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{{Key: key.Public(), KeyID: "test", Algorithm: string(jose.RS256), Use: "sig"}},
+		})
+	})
+	return &testOIDCProvider{
+		Issuer: srv.URL,
+		Client: srv.Client(),
+		key:    key,
+	}
+}
+
+// Claims returns the claims of a token for the subject that
+// is valid for one hour, starting at now. The custom claims
+// are added to, or replace, the default claims.
+func (p *testOIDCProvider) Claims(subject string, now time.Time, custom map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss": p.Issuer,
+		"sub": subject,
+		"aud": "kes",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+	for k, v := range custom {
+		if v == nil {
+			delete(claims, k)
+			continue
+		}
+		claims[k] = v
+	}
+	return claims
+}
+
+// Sign returns the signed, compact-serialized JWT.
+func (p *testOIDCProvider) Sign(t *testing.T, claims map[string]any) string {
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.RS256,
+		Key:       jose.JSONWebKey{Key: p.key, KeyID: "test"},
+	}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
@@ -0,0 +1,209 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+)
+
+// policyStore contains the policies created and the policy
+// assignments made via the API. They are applied on top of
+// the config policies whenever the server policies change.
+//
+// A policyStore is immutable once it has been loaded. Changes
+// are made to a copy which replaces the store once it has been
+// written successfully.
+type policyStore struct {
+	// filename is the path of the file the store is persisted
+	// to. If empty, the store is kept in memory only.
+	filename string
+
+	Policies   map[string]storedPolicy           `json:"policies,omitempty"`
+	Identities map[kes.Identity]storedAssignment `json:"identities,omitempty"`
+}
+
+// storedPolicy is a policy created via the API.
+type storedPolicy struct {
+	Allow     []string     `json:"allow,omitempty"`
+	Deny      []string     `json:"deny,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	CreatedBy kes.Identity `json:"created_by,omitempty"`
+}
+
+// storedAssignment is a policy assignment made via the API.
+type storedAssignment struct {
+	Policy     string       `json:"policy"`
+	ExpiresAt  time.Time    `json:"expires_at"`
+	AssignedAt time.Time    `json:"assigned_at"`
+	AssignedBy kes.Identity `json:"assigned_by,omitempty"`
+	IssuedBy   kes.Identity `json:"issued_by,omitempty"`
+}
+
+// readPolicyStore reads the policy store persisted to the file
+// specified by conf. It returns an empty store if conf is nil or
+// the file does not exist yet.
+func readPolicyStore(conf *PolicyStoreConfig) (*policyStore, error) {
+	store := &policyStore{
+		Policies:   map[string]storedPolicy{},
+		Identities: map[kes.Identity]storedAssignment{},
+	}
+	if conf == nil {
+		return store, nil
+	}
+	store.filename = conf.Filename
+
+	data, err := os.ReadFile(conf.Filename)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("kes: invalid policy store file '%s': %v", conf.Filename, err)
+	}
+	if store.Policies == nil {
+		store.Policies = map[string]storedPolicy{}
+	}
+	if store.Identities == nil {
+		store.Identities = map[kes.Identity]storedAssignment{}
+	}
+	return store, nil
+}
+
+// clone returns a copy of the store that can be modified.
+func (p *policyStore) clone() *policyStore {
+	return &policyStore{
+		filename:   p.filename,
+		Policies:   maps.Clone(p.Policies),
+		Identities: maps.Clone(p.Identities),
+	}
+}
+
+// write persists the store to its file, if any. It writes to a
+// temporary file first and renames it afterwards. Hence, an
+// existing file is not lost when write fails.
+func (p *policyStore) write() error {
+	if p.filename == "" {
+		return nil
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(p.filename), "."+filepath.Base(p.filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), p.filename)
+}
+
+// apply returns the given config policies combined with the
+// policies and assignments of the store.
+//
+// Config policies take precedence over stored policies with the
+// same name. Stored assignments replace the config assignments
+// of an identity unless the policy does not exist anymore. An
+// identity whose stored assignment has expired remains revoked
+// until a policy gets assigned to it via the API again.
+func (p *policyStore) apply(policies map[string]Policy, now time.Time) map[string]Policy {
+	combined := make(map[string]Policy, len(policies)+len(p.Policies))
+	for name, policy := range policies {
+		combined[name] = policy
+	}
+	for name, stored := range p.Policies {
+		if _, ok := combined[name]; ok {
+			continue
+		}
+		policy := Policy{
+			Allow: make(map[string]kes.Rule, len(stored.Allow)),
+			Deny:  make(map[string]kes.Rule, len(stored.Deny)),
+		}
+		for _, pattern := range stored.Allow {
+			policy.Allow[pattern] = kes.Rule{}
+		}
+		for _, pattern := range stored.Deny {
+			policy.Deny[pattern] = kes.Rule{}
+		}
+		combined[name] = policy
+	}
+
+	assigned := make(map[kes.Identity]string, len(p.Identities))
+	for id, a := range p.Identities {
+		if _, ok := combined[a.Policy]; !ok {
+			continue
+		}
+		if !a.ExpiresAt.IsZero() && !now.Before(a.ExpiresAt) {
+			assigned[id] = "" // Revoked
+			continue
+		}
+		assigned[id] = a.Policy
+	}
+	if len(assigned) == 0 {
+		return combined
+	}
+
+	for name, policy := range combined {
+		policy.Identities = slices.DeleteFunc(slices.Clone(policy.Identities), func(id kes.Identity) bool {
+			_, ok := assigned[id]
+			return ok
+		})
+		combined[name] = policy
+	}
+	for id, name := range assigned {
+		if name == "" {
+			continue
+		}
+		policy := combined[name]
+		policy.Identities = append(policy.Identities, id)
+		combined[name] = policy
+	}
+	return combined
+}
+
+// annotate sets the creation time and creator of all stored
+// policies that are not overridden by a config policy, and the
+// expiry and assignment time of all identities with a stored
+// assignment.
+func (p *policyStore) annotate(conf map[string]Policy, policies map[string]*kes.Policy, identities map[kes.Identity]identityEntry) {
+	for name, stored := range p.Policies {
+		if _, ok := conf[name]; ok {
+			continue
+		}
+		if policy, ok := policies[name]; ok {
+			policy.CreatedAt, policy.CreatedBy = stored.CreatedAt, stored.CreatedBy
+		}
+	}
+	for id, a := range p.Identities {
+		if entry, ok := identities[id]; ok && entry.Name == a.Policy {
+			entry.ExpiresAt = a.ExpiresAt
+			entry.AssignedAt, entry.AssignedBy = a.AssignedAt, a.AssignedBy
+			entry.IssuedBy = a.IssuedBy
+			identities[id] = entry
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestPolicyStore(t *testing.T) {
+	const Identity = "a4b5e4c4e8c3bd5a9c5d1a4a0a3a6dca62e2b8b5a0c1d9e5f8a3c2b1d0e9f8a7"
+
+	ctx := testContext(t)
+	conf := &Config{
+		PolicyStore: &PolicyStoreConfig{
+			Filename: filepath.Join(t.TempDir(), "policies.json"),
+		},
+		Policies: map[string]Policy{
+			"config-policy": {
+				Allow:      map[string]kes.Rule{"/v1/status": {}},
+				Identities: []kes.Identity{Identity},
+			},
+		},
+	}
+
+	srv, url := startServer(ctx, conf)
+	client := defaultClient(url)
+	err := sendRequest(ctx, client, http.MethodPut, api.PathPolicyCreate+"api-policy", api.CreatePolicyRequest{
+		Allow: []string{"/v1/key/create/my-app-*"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	err = sendRequest(ctx, client, http.MethodPut, api.PathPolicyCreate+"config-policy", api.CreatePolicyRequest{
+		Allow: []string{"/v1/status"},
+	})
+	if !errors.Is(err, kes.ErrPolicyExists) {
+		t.Fatalf("Creating existing policy: got '%v' - want '%v'", err, kes.ErrPolicyExists)
+	}
+	err = sendRequest(ctx, client, http.MethodPut, api.PathPolicyAssign+"api-policy", api.AssignPolicyRequest{
+		Identities: []string{Identity},
+	})
+	if err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	srv.Close()
+
+	// The created policy and the assignment must survive a restart.
+	srv, url = startServer(ctx, conf)
+	client = defaultClient(url)
+	if _, err = client.GetPolicy(ctx, "api-policy"); err != nil {
+		t.Fatalf("Failed to fetch created policy after restart: %v", err)
+	}
+	info, err := client.DescribeIdentity(ctx, Identity)
+	if err != nil {
+		t.Fatalf("Failed to describe identity after restart: %v", err)
+	}
+	if info.Policy != "api-policy" {
+		t.Fatalf("Invalid policy assignment: got '%s' - want '%s'", info.Policy, "api-policy")
+	}
+
+	// The stored assignment must survive a config reload as well.
+	if err = srv.UpdatePolicies(conf.Policies); err != nil {
+		t.Fatalf("Failed to update policies: %v", err)
+	}
+	if info, err = client.DescribeIdentity(ctx, Identity); err != nil || info.Policy != "api-policy" {
+		t.Fatalf("Invalid policy assignment after reload: got '%v' - want '%s'", info, "api-policy")
+	}
+
+	if err = sendRequest(ctx, client, http.MethodDelete, api.PathPolicyDelete+"config-policy", nil); err == nil {
+		t.Fatal("Deleting a config policy should fail")
+	}
+	if err = sendRequest(ctx, client, http.MethodDelete, api.PathPolicyDelete+"api-policy", nil); err != nil {
+		t.Fatalf("Failed to delete policy: %v", err)
+	}
+	srv.Close()
+
+	srv, url = startServer(ctx, conf)
+	defer srv.Close()
+	client = defaultClient(url)
+	if _, err = client.GetPolicy(ctx, "api-policy"); !errors.Is(err, kes.ErrPolicyNotFound) {
+		t.Fatalf("Fetching deleted policy: got '%v' - want '%v'", err, kes.ErrPolicyNotFound)
+	}
+	if info, err = client.DescribeIdentity(ctx, Identity); err != nil || info.Policy != "config-policy" {
+		t.Fatalf("Invalid policy assignment after deletion: got '%v' - want '%s'", info, "config-policy")
+	}
+}
+
+func TestPolicyStoreApply(t *testing.T) {
+	const (
+		IdentityA = "aa"
+		IdentityB = "bb"
+	)
+	now := time.Now()
+	store := &policyStore{
+		Policies: map[string]storedPolicy{
+			"config": {Allow: []string{"/v1/key/create/*"}},
+			"stored": {Allow: []string{"/v1/key/create/*"}},
+		},
+		Identities: map[kes.Identity]storedAssignment{
+			IdentityA: {Policy: "stored"},
+			IdentityB: {Policy: "stored", ExpiresAt: now.Add(-time.Minute)},
+		},
+	}
+	policies := store.apply(map[string]Policy{
+		"config": {
+			Allow:      map[string]kes.Rule{"/v1/status": {}},
+			Identities: []kes.Identity{IdentityA, IdentityB},
+		},
+	}, now)
+
+	if _, ok := policies["config"].Allow["/v1/status"]; !ok {
+		t.Fatal("Config policy has been replaced by stored policy")
+	}
+	if ids := policies["config"].Identities; len(ids) != 0 {
+		t.Fatalf("Config policy contains reassigned identities: %v", ids)
+	}
+	if ids := policies["stored"].Identities; len(ids) != 1 || ids[0] != IdentityA {
+		t.Fatalf("Invalid identities of stored policy: got '%v' - want '[%s]'", ids, IdentityA)
+	}
+}
+
+func TestPolicyStoreAnnotate(t *testing.T) {
+	const (
+		Admin    = "aa"
+		Identity = "bb"
+	)
+	now := time.Now().UTC()
+	store := &policyStore{
+		Policies: map[string]storedPolicy{
+			"config": {CreatedAt: now, CreatedBy: Admin},
+			"stored": {CreatedAt: now, CreatedBy: Admin},
+		},
+		Identities: map[kes.Identity]storedAssignment{
+			Identity: {Policy: "stored", AssignedAt: now, AssignedBy: Admin},
+		},
+	}
+	conf := map[string]Policy{"config": {}}
+	policies, _, identities, err := initPolicies(store.apply(conf, now), defaultNameRules)
+	if err != nil {
+		t.Fatalf("Failed to initialize policies: %v", err)
+	}
+	store.annotate(conf, policies, identities)
+
+	if p := policies["config"]; !p.CreatedAt.IsZero() || p.CreatedBy != "" {
+		t.Fatalf("Config policy has stored creation metadata: '%v' by '%s'", p.CreatedAt, p.CreatedBy)
+	}
+	if p := policies["stored"]; !p.CreatedAt.Equal(now) || p.CreatedBy != Admin {
+		t.Fatalf("Invalid creation metadata of stored policy: got '%v' by '%s'", p.CreatedAt, p.CreatedBy)
+	}
+	if e := identities[Identity]; !e.AssignedAt.Equal(now) || e.AssignedBy != Admin {
+		t.Fatalf("Invalid assignment metadata: got '%v' by '%s'", e.AssignedAt, e.AssignedBy)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+	"golang.org/x/time/rate"
+)
+
+// RateLimit limits how many requests the identities assigned
+// to a policy may send. It is a token bucket that gets refilled
+// with Rate tokens per second and holds at most Burst tokens.
+// Each request consumes one token. Requests that exceed the
+// limit are rejected with HTTP 429 (Too Many Requests).
+type RateLimit struct {
+	// Rate is the number of requests per second that are
+	// allowed on average. It must be greater than zero.
+	Rate float64
+
+	// Burst is the max. number of requests that are allowed
+	// at once. If <= 0, defaults to Rate rounded up.
+	Burst int
+
+	// PerPolicy controls whether the limit applies to all
+	// identities of the policy together. By default, each
+	// identity is limited individually.
+	PerPolicy bool
+}
+
+// validate reports whether the rate limit is valid.
+func (l *RateLimit) validate() error {
+	if l.Rate <= 0 || math.IsInf(l.Rate, 0) || math.IsNaN(l.Rate) {
+		return fmt.Errorf("invalid rate limit '%v': must be a positive number of requests per second", l.Rate)
+	}
+	return nil
+}
+
+// burst returns the bucket size of the rate limit.
+func (l *RateLimit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return max(1, int(math.Ceil(l.Rate)))
+}
+
+// rateLimiter keeps the token buckets of all rate limited
+// identities and policies. Its buckets are shared between
+// server states such that a configuration reload does not
+// reset the limits.
+type rateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*rate.Limiter
+}
+
+// Allow reports whether a request of the given identity, with
+// the given policy, is within its rate limit at time now. If not,
+// it returns how long the client should wait before retrying.
+func (r *rateLimiter) Allow(identity kes.Identity, policy string, limit *RateLimit, now time.Time) (time.Duration, bool) {
+	key := "identity:" + identity.String()
+	if limit.PerPolicy {
+		key = "policy:" + policy
+	}
+
+	r.mu.Lock()
+	if r.buckets == nil {
+		r.buckets = map[string]*rate.Limiter{}
+	}
+	bucket, ok := r.buckets[key]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(limit.Rate), limit.burst())
+		r.buckets[key] = bucket
+	} else if bucket.Limit() != rate.Limit(limit.Rate) || bucket.Burst() != limit.burst() {
+		bucket.SetLimitAt(now, rate.Limit(limit.Rate))
+		bucket.SetBurstAt(now, limit.burst())
+	}
+	r.mu.Unlock()
+
+	reservation := bucket.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
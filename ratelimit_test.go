@@ -0,0 +1,92 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		Alice kes.Identity = "alice"
+		Bob   kes.Identity = "bob"
+	)
+	var (
+		limiter rateLimiter
+		limit   = &RateLimit{Rate: 1, Burst: 2}
+		now     = time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	)
+
+	for i := 0; i < 2; i++ {
+		if _, ok := limiter.Allow(Alice, "my-policy", limit, now); !ok {
+			t.Fatalf("Request %d within burst has been rejected", i)
+		}
+	}
+	delay, ok := limiter.Allow(Alice, "my-policy", limit, now)
+	if ok {
+		t.Fatal("Request exceeding the burst has been accepted")
+	}
+	if delay <= 0 || delay > time.Second {
+		t.Fatalf("Invalid retry delay: got '%v' - want '(0s, 1s]'", delay)
+	}
+	if _, ok = limiter.Allow(Bob, "my-policy", limit, now); !ok {
+		t.Fatal("Request of another identity has been rejected")
+	}
+	if _, ok = limiter.Allow(Alice, "my-policy", limit, now.Add(time.Second)); !ok {
+		t.Fatal("Request after the bucket has been refilled has been rejected")
+	}
+
+	perPolicy := &RateLimit{Rate: 1, Burst: 1, PerPolicy: true}
+	if _, ok = limiter.Allow(Alice, "shared-policy", perPolicy, now); !ok {
+		t.Fatal("First request of a policy has been rejected")
+	}
+	if _, ok = limiter.Allow(Bob, "shared-policy", perPolicy, now); ok {
+		t.Fatal("Request exceeding the policy limit has been accepted")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, &Config{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{"/v1/key/describe/*": {}},
+				RateLimit:  &RateLimit{Rate: 0.01, Burst: 1},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if _, err := client.DescribeKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Describing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+"/v1/key/describe/my-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Invalid status code: got '%d' - want '%d'", resp.StatusCode, http.StatusTooManyRequests)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Fatal("Response does not contain a Retry-After header")
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kms-go/kes"
+)
+
+// errReplicaReadOnly is returned when a read replica is asked to
+// modify a key. Keys can only be modified on the primary.
+var errReplicaReadOnly = api.NewError(http.StatusMethodNotAllowed, "server is a read-only replica")
+
+// newReplicaKeyStore returns a KeyStore that fetches keys from the
+// replica's primary KES server.
+func newReplicaKeyStore(conf *ReplicaConfig) *replicaKeyStore {
+	return &replicaKeyStore{
+		endpoint: conf.Endpoint,
+		client:   kes.NewClientWithConfig(conf.Endpoint, conf.TLS),
+	}
+}
+
+// replicaKeyStore is a read-only KeyStore that fetches keys from
+// a primary KES server using its replica key API.
+type replicaKeyStore struct {
+	endpoint string
+	client   *kes.Client
+}
+
+func (ks *replicaKeyStore) String() string { return "Replica: " + ks.endpoint }
+
+// Status returns the current state of the primary. It returns a
+// keystore.ErrUnreachable if the primary is not reachable.
+func (ks *replicaKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	start := time.Now()
+	if _, err := ks.client.Version(ctx); err != nil {
+		return KeyStoreState{}, ks.primaryError(err)
+	}
+	return KeyStoreState{Latency: time.Since(start)}, nil
+}
+
+// Create returns errReplicaReadOnly.
+func (ks *replicaKeyStore) Create(context.Context, string, []byte) error {
+	return errReplicaReadOnly
+}
+
+// Delete returns errReplicaReadOnly.
+func (ks *replicaKeyStore) Delete(context.Context, string) error {
+	return errReplicaReadOnly
+}
+
+// Get fetches the key with the given name from the primary. It
+// returns kes.ErrKeyNotFound if no such key exists.
+func (ks *replicaKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	const MaxResponseSize = 1 * mem.MiB
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.endpoint+api.PathReplicaKey+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := ks.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, &keystore.ErrUnreachable{Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, kes.ErrKeyNotFound
+		}
+		return nil, ks.primaryError(api.ReadError(resp))
+	}
+
+	var key api.ReplicaKeyResponse
+	if err = json.NewDecoder(mem.LimitReader(resp.Body, MaxResponseSize)).Decode(&key); err != nil {
+		return nil, err
+	}
+	return key.Key, nil
+}
+
+// List returns the first n key names, that start with the given
+// prefix, and the next prefix from which the listing should
+// continue. The names are listed by the primary.
+//
+// The primary sends the latest revision of a key and never lists
+// revisions. Hence, List returns no names for revision prefixes.
+func (ks *replicaKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if strings.HasPrefix(prefix, keystore.RevisionPrefix) {
+		return nil, "", nil
+	}
+	names, next, err := ks.client.ListKeys(ctx, prefix, n)
+	if err != nil {
+		return nil, "", ks.primaryError(err)
+	}
+	return names, next, nil
+}
+
+// Close closes idle connections to the primary.
+func (ks *replicaKeyStore) Close() error {
+	ks.client.HTTPClient.CloseIdleConnections()
+	return nil
+}
+
+// primaryError converts an error returned by the primary into
+// an error that is not sent to clients of the replica as is.
+// Errors not sent by the primary itself indicate that it is
+// not reachable.
+func (ks *replicaKeyStore) primaryError(err error) error {
+	var (
+		kErr kes.Error
+		aErr api.Error
+	)
+	if !errors.As(err, &kErr) && !errors.As(err, &aErr) {
+		return &keystore.ErrUnreachable{Err: err}
+	}
+	return fmt.Errorf("kes: primary '%s' responded with: %v", ks.endpoint, err)
+}
+
+// warmReplicaCache fetches all keys from the primary into the
+// cache, if the server is a read replica, until ctx is canceled.
+// Keys are fetched immediately and then periodically such that
+// keys created on the primary are cached before they are used.
+func (s *Server) warmReplicaCache(ctx context.Context) {
+	const Delay = 1 * time.Minute
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.Replica == nil {
+			timer.Reset(Delay)
+			continue
+		}
+		if err := warmCache(ctx, state.Keys); err != nil && ctx.Err() == nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to fetch keys from primary '%s': %v", state.Replica.Endpoint, err))
+		}
+
+		interval := state.Replica.Interval
+		if interval <= 0 {
+			interval = DefaultReplicaInterval
+		}
+		timer.Reset(interval)
+	}
+}
+
+// warmCache fetches all keys that are not cached yet into the cache.
+func warmCache(ctx context.Context, keys *keyCache) error {
+	iter := kes.ListIter[string]{NextFunc: keys.List}
+	for name, err := iter.Next(ctx); err != io.EOF; name, err = iter.Next(ctx) {
+		if err != nil {
+			return err
+		}
+		if _, err = keys.Get(ctx, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// replicaKey sends a key, including all previous versions, to a
+// read replica. The key is sent in plaintext. Hence, only identities
+// configured as replica identities may use this API, regardless of
+// their policy.
+func (s *Server) replicaKey(resp *api.Response, req *api.Request) {
+	if !slices.Contains(s.state.Load().Replicas, req.Identity) {
+		s.state.Load().Log.DebugContext(req.Context(), "access denied: identity is not a replica identity", "req", req)
+		resp.Failr(kes.ErrNotAllowed)
+		return
+	}
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	b, err := crypto.EncodeKeyVersion(key)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to read key")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' sent to replica", req.Resource),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.ReplicaKeyResponse{
+		Key: b,
+	})
+}
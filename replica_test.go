@@ -0,0 +1,136 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestReplica(t *testing.T) {
+	ctx := testContext(t)
+
+	primary, primaryURL := startServer(ctx, &Config{
+		ReplicaIdentities: []kes.Identity{defaultIdentity},
+	})
+	defer primary.Close()
+
+	primaryClient := defaultClient(primaryURL)
+	for _, name := range []string{"my-key", "my-key-2"} {
+		if err := primaryClient.CreateKey(ctx, name); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+
+	replica, replicaURL := startServer(ctx, &Config{
+		Replica: &ReplicaConfig{
+			Endpoint: primaryURL,
+			TLS:      primaryClient.HTTPClient.Transport.(*http.Transport).TLSClientConfig.Clone(),
+		},
+	})
+	defer replica.Close()
+
+	// The replica fetches all keys from the primary when started.
+	for _, name := range []string{"my-key", "my-key-2"} {
+		for {
+			if _, ok := replica.state.Load().Keys.cache.Get(name); ok {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Key '%s' has not been cached: %v", name, ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+
+	replicaClient := defaultClient(replicaURL)
+	ciphertext, err := replicaClient.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt on replica: %v", err)
+	}
+	plaintext, err := primaryClient.Decrypt(ctx, "my-key", ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt on primary: %v", err)
+	}
+	if string(plaintext) != "Hello World" {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+	}
+
+	// Keys created after the cache warm-up are fetched on first use.
+	if err = primaryClient.CreateKey(ctx, "my-key-3"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err = replicaClient.GenerateKey(ctx, "my-key-3", nil); err != nil {
+		t.Fatalf("Failed to generate key on replica: %v", err)
+	}
+	if _, err = replicaClient.GenerateKey(ctx, "my-key-4", nil); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Generating key for non-existing key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+
+	if err = replicaClient.CreateKey(ctx, "my-key-4"); err == nil {
+		t.Fatal("Creating a key on a replica should have failed")
+	}
+	if err = replicaClient.DeleteKey(ctx, "my-key"); err == nil {
+		t.Fatal("Deleting a key on a replica should have failed")
+	}
+	if _, err = primaryClient.DescribeKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to describe key on primary: %v", err)
+	}
+
+	// Identities not configured as replica identities must not
+	// fetch keys - not even the admin.
+	other, otherURL := startServer(ctx, nil)
+	defer other.Close()
+
+	otherClient := defaultClient(otherURL)
+	if err = otherClient.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, otherURL+api.PathReplicaKey+"my-key", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	resp, err := otherClient.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to send request: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("Fetching key as non-replica: got status '%d' - want '%d'", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestReplicaConfig(t *testing.T) {
+	replica := &ReplicaConfig{
+		Endpoint: "https://127.0.0.1:7373",
+		TLS:      defaultClient("https://127.0.0.1:7373").HTTPClient.Transport.(*http.Transport).TLSClientConfig.Clone(),
+	}
+	conf := &Config{
+		TLS: &tls.Config{
+			ClientAuth:   tls.RequestClientCert,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		Replica: replica,
+		Keys:    &MemKeyStore{},
+	}
+	if err := verifyConfig(conf); err == nil {
+		t.Fatal("Replica with key store should be rejected")
+	}
+	conf.Keys = nil
+	if err := verifyConfig(conf); err != nil {
+		t.Fatalf("Failed to verify replica config: %v", err)
+	}
+	replica.Endpoint = "http://127.0.0.1:7373"
+	if err := verifyConfig(conf); err == nil {
+		t.Fatal("Replica with HTTP endpoint should be rejected")
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+)
+
+// DefaultReplicationInterval is the time between two replication
+// runs if ReplicationConfig.Interval is not set.
+const DefaultReplicationInterval = 5 * time.Minute
+
+// replicate replicates keys, policies and identities to the
+// replication target, if configured, until ctx is canceled.
+// Besides running periodically, a replication run is triggered
+// whenever a key or policy is created or an identity is assigned
+// to a policy.
+func (s *Server) replicate(ctx context.Context) {
+	const Delay = 1 * time.Minute
+
+	var (
+		conf   *ReplicationConfig
+		client *kes.Client
+
+		// verified contains the version numbers of all keys
+		// that are known to exist on the target and to be
+		// equal to the keys on this server. They are not
+		// checked again unless they get rotated.
+		verified = map[string]uint32{}
+	)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		case <-s.replicateNow:
+			if !timer.Stop() {
+				<-timer.C
+			}
+		}
+
+		state := s.state.Load()
+		if state.Replication == nil {
+			timer.Reset(Delay)
+			continue
+		}
+		if state.Replication != conf {
+			if client != nil {
+				client.HTTPClient.CloseIdleConnections()
+			}
+			conf = state.Replication
+			client = kes.NewClientWithConfig(conf.Endpoint, conf.TLS)
+			clear(verified)
+		}
+
+		if err := replicateKeys(ctx, state, client, conf.KEK, verified); err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate keys to '%s': %v", conf.Endpoint, err))
+		}
+		s.mu.Lock()
+		policies := s.policies
+		s.mu.Unlock()
+		if err := replicatePolicies(ctx, state, client, policies); err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate policies to '%s': %v", conf.Endpoint, err))
+		}
+		if err := replicateIdentities(ctx, state, client); err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate identities to '%s': %v", conf.Endpoint, err))
+		}
+
+		interval := conf.Interval
+		if interval <= 0 {
+			interval = DefaultReplicationInterval
+		}
+		timer.Reset(interval)
+	}
+}
+
+// replicateKeys restores all keys that don't exist on the target,
+// or only with fewer versions, at the target. Keys, including all
+// previous versions, are wrapped by the KEK, that must exist on
+// both, this server and the target.
+//
+// Keys that exist on both with the same version number are compared
+// and reported as conflict if they differ. Conflicting keys are never
+// overwritten.
+func replicateKeys(ctx context.Context, state *serverState, client *kes.Client, kekName string, verified map[string]uint32) error {
+	kek, err := state.Keys.Get(ctx, kekName)
+	if err != nil {
+		return fmt.Errorf("failed to read KEK '%s': %v", kekName, err)
+	}
+	if equal, err := equalKey(ctx, client, kekName, kek); err != nil || !equal {
+		if err == nil {
+			err = errors.New("key differs on replication target")
+		}
+		return fmt.Errorf("invalid KEK '%s': %v", kekName, err)
+	}
+
+	targetKeys := map[string]bool{}
+	target := kes.ListIter[string]{NextFunc: client.ListKeys}
+	for name, err := target.Next(ctx); err != io.EOF; name, err = target.Next(ctx) {
+		if err != nil {
+			return err
+		}
+		targetKeys[name] = true
+	}
+
+	source := kes.ListIter[string]{NextFunc: state.Keys.List}
+	for name, err := source.Next(ctx); err != io.EOF; name, err = source.Next(ctx) {
+		if err != nil {
+			return err
+		}
+		if name == kekName {
+			continue
+		}
+
+		key, err := state.Keys.Get(ctx, name)
+		if errors.Is(err, kes.ErrKeyNotFound) { // Key has been deleted in the meantime
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if verified[name] == key.Number() {
+			continue
+		}
+
+		var version uint32
+		if targetKeys[name] {
+			if version, err = keyVersion(ctx, client, name); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+				state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to describe key '%s' on replication target: %v", name, err))
+				continue
+			}
+		}
+		if version > key.Number() {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: replication conflict: key '%s' has version %d on replication target but %d on this server", name, version, key.Number()))
+			continue
+		}
+		if version < key.Number() {
+			err = pushKey(ctx, client, name, key, kekName, kek)
+			if err == nil {
+				verified[name] = key.Number()
+				continue
+			}
+			if !errors.Is(err, kes.ErrKeyExists) {
+				state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate key '%s': %v", name, err))
+				continue
+			}
+			// The key has been created or rotated on the target in the
+			// meantime. Hence, we have to check whether it's the same key.
+		}
+
+		equal, err := equalKey(ctx, client, name, key)
+		if err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to compare key '%s' with replication target: %v", name, err))
+			continue
+		}
+		if !equal {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: replication conflict: key '%s' exists on replication target but differs", name))
+			continue
+		}
+		verified[name] = key.Number()
+	}
+	return nil
+}
+
+// replicateIdentities assigns all identities that are not assigned
+// to any policy on the target to the same policy as on this server.
+// Identities assigned to a different policy on the target and policies
+// that differ from the ones on this server are reported as conflict.
+//
+// Config policies are not replicated. They have to be defined on the
+// target, for example by using the same policy configuration.
+func replicateIdentities(ctx context.Context, state *serverState, client *kes.Client) error {
+	missing := map[string][]string{}
+	for id, entry := range state.Identities {
+		info, err := client.DescribeIdentity(ctx, id)
+		switch {
+		case err == nil && info.Policy == entry.Name:
+			continue
+		case err == nil:
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: replication conflict: identity '%s' is assigned to policy '%s' on replication target", id, info.Policy))
+			continue
+		case !errors.Is(err, kes.ErrIdentityNotFound):
+			return err
+		}
+		missing[entry.Name] = append(missing[entry.Name], id.String())
+	}
+
+	for name, ids := range missing {
+		policy, err := client.GetPolicy(ctx, name)
+		if errors.Is(err, kes.ErrPolicyNotFound) {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate identities of policy '%s': policy does not exist on replication target", name))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if p := state.Policies[name]; !maps.Equal(p.Allow, policy.Allow) || !maps.Equal(p.Deny, policy.Deny) {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: replication conflict: policy '%s' differs on replication target", name))
+			continue
+		}
+
+		slices.Sort(ids)
+		err = sendRequest(ctx, client, http.MethodPost, api.PathPolicyAssign+name, api.AssignPolicyRequest{
+			Identities: ids,
+		})
+		if err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate identities of policy '%s': %v", name, err))
+		}
+	}
+	return nil
+}
+
+// replicatePolicies creates all policies, that have been created via
+// the API and don't exist on the target, at the target. Policies that
+// exist on the target but differ from the ones on this server are
+// reported as conflict and never overwritten.
+func replicatePolicies(ctx context.Context, state *serverState, client *kes.Client, store *policyStore) error {
+	if store == nil {
+		return nil
+	}
+	for name, p := range store.Policies {
+		policy, err := client.GetPolicy(ctx, name)
+		if err == nil {
+			if q := state.Policies[name]; q == nil || !maps.Equal(q.Allow, policy.Allow) || !maps.Equal(q.Deny, policy.Deny) {
+				state.Log.ErrorContext(ctx, fmt.Sprintf("kes: replication conflict: policy '%s' differs on replication target", name))
+			}
+			continue
+		}
+		if !errors.Is(err, kes.ErrPolicyNotFound) {
+			return err
+		}
+
+		err = sendRequest(ctx, client, http.MethodPost, api.PathPolicyCreate+name, api.CreatePolicyRequest{
+			Allow: p.Allow,
+			Deny:  p.Deny,
+		})
+		if err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to replicate policy '%s': %v", name, err))
+		}
+	}
+	return nil
+}
+
+// pushKey restores the key, including all previous versions, at
+// the target. Like an exported key, it is wrapped by the KEK and
+// bound to its name. If the key exists at the target with fewer
+// versions, the target updates it to the pushed key.
+func pushKey(ctx context.Context, client *kes.Client, name string, key crypto.KeyVersion, kekName string, kek crypto.KeyVersion) error {
+	plaintext, err := crypto.EncodeKeyVersion(key)
+	if err != nil {
+		return err
+	}
+	wrapped, err := kek.Encrypt(plaintext, []byte(name))
+	if err != nil {
+		return err
+	}
+	return sendRequest(ctx, client, http.MethodPut, api.PathKeyRestore+name, api.RestoreKeyRequest{
+		KEK: kekName,
+		Key: wrapped,
+	})
+}
+
+// keyVersion returns the version number of the target's key
+// with the given name.
+func keyVersion(ctx context.Context, client *kes.Client, name string) (uint32, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, client.Endpoints[0]+api.PathKeyDescribe+name, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := api.ReadError(resp)
+		return 0, kes.NewError(err.Status(), err.Error())
+	}
+	var info api.DescribeKeyResponse
+	if err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&info); err != nil {
+		return 0, err
+	}
+	return max(info.Version, 1), nil
+}
+
+// equalKey reports whether the target's key with the given name
+// is equal to key. It encrypts a random message with the latest
+// version of key and asks the target to decrypt it. The key
+// material never leaves this server.
+func equalKey(ctx context.Context, client *kes.Client, name string, key crypto.KeyVersion) (bool, error) {
+	var msg [32]byte
+	if _, err := rand.Read(msg[:]); err != nil {
+		return false, err
+	}
+	ciphertext, err := key.Encrypt(msg[:], nil)
+	if err != nil {
+		return false, err
+	}
+
+	plaintext, err := client.Decrypt(ctx, name, ciphertext, nil)
+	if errors.Is(err, kes.ErrDecrypt) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(plaintext, msg[:]), nil
+}
+
+// sendRequest sends a request with the JSON-encoded body to the target's
+// API path and returns a kes.Error if the request does not succeed.
+func sendRequest(ctx context.Context, client *kes.Client, method, path string, body any) error {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, client.Endpoints[0]+path, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := api.ReadError(resp)
+		return kes.NewError(err.Status(), err.Error())
+	}
+	return nil
+}
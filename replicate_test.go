@@ -0,0 +1,230 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestReplication(t *testing.T) {
+	const Identity = "a4d5f1a8e7c3b2d6f9e0a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2"
+	policy := Policy{
+		Allow: map[string]kes.Rule{"/v1/key/encrypt/*": {}},
+	}
+
+	var kek [32]byte
+	if _, err := rand.Read(kek[:]); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := testContext(t)
+	target, targetURL := startServer(ctx, &Config{
+		Policies: map[string]Policy{"my-policy": policy},
+	})
+	defer target.Close()
+
+	targetClient := defaultClient(targetURL)
+	if err := targetClient.ImportKey(ctx, "my-kek", &kes.ImportKeyRequest{Key: kek[:], Cipher: kes.AES256}); err != nil {
+		t.Fatalf("Failed to import KEK: %v", err)
+	}
+	policy.Identities = []kes.Identity{Identity}
+	source, sourceURL := startServer(ctx, &Config{
+		Policies: map[string]Policy{"my-policy": policy},
+		Replication: &ReplicationConfig{
+			Endpoint: targetURL,
+			TLS:      targetClient.HTTPClient.Transport.(*http.Transport).TLSClientConfig,
+			KEK:      "my-kek",
+			Interval: time.Second,
+		},
+	})
+	defer source.Close()
+
+	sourceClient := defaultClient(sourceURL)
+	if err := sourceClient.ImportKey(ctx, "my-kek", &kes.ImportKeyRequest{Key: kek[:], Cipher: kes.AES256}); err != nil {
+		t.Fatalf("Failed to import KEK: %v", err)
+	}
+	if err := sourceClient.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	ciphertext, err := sourceClient.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	awaitDecrypt := func(ciphertext []byte) {
+		for {
+			plaintext, err := targetClient.Decrypt(ctx, "my-key", ciphertext, nil)
+			if err == nil {
+				if string(plaintext) != "Hello World" {
+					t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+				}
+				return
+			}
+			if !errors.Is(err, kes.ErrKeyNotFound) && !errors.Is(err, kes.ErrDecrypt) {
+				t.Fatalf("Failed to decrypt: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				t.Fatalf("Key has not been replicated: %v", ctx.Err())
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+	}
+	awaitDecrypt(ciphertext)
+
+	// Rotated keys are replicated again with all versions.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, sourceURL+api.PathKeyRotate+"my-key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := sourceClient.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Failed to rotate key: got status '%d' - want '%d'", resp.StatusCode, http.StatusOK)
+	}
+	rotated, err := sourceClient.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+	awaitDecrypt(rotated)
+	awaitDecrypt(ciphertext)
+
+	for {
+		info, err := targetClient.DescribeIdentity(ctx, Identity)
+		if err == nil {
+			if info.Policy != "my-policy" {
+				t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "my-policy")
+			}
+			break
+		}
+		if !errors.Is(err, kes.ErrIdentityNotFound) {
+			t.Fatalf("Failed to describe identity: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Identity has not been replicated: %v", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// Policies created via the API are replicated before the
+	// identities assigned to them.
+	const APIIdentity = "b5e6f2b9f8d4c3e7a0f1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6e7f8a9b0c1d2e3"
+	err = sendRequest(ctx, sourceClient, http.MethodPut, api.PathPolicyCreate+"api-policy", api.CreatePolicyRequest{
+		Allow: []string{"/v1/key/decrypt/*"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create policy: %v", err)
+	}
+	err = sendRequest(ctx, sourceClient, http.MethodPut, api.PathPolicyAssign+"api-policy", api.AssignPolicyRequest{
+		Identities: []string{APIIdentity},
+	})
+	if err != nil {
+		t.Fatalf("Failed to assign policy: %v", err)
+	}
+	for {
+		info, err := targetClient.DescribeIdentity(ctx, APIIdentity)
+		if err == nil {
+			if info.Policy != "api-policy" {
+				t.Fatalf("Invalid policy: got '%s' - want '%s'", info.Policy, "api-policy")
+			}
+			break
+		}
+		if !errors.Is(err, kes.ErrIdentityNotFound) {
+			t.Fatalf("Failed to describe identity: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("Policy has not been replicated: %v", ctx.Err())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	replicated, err := targetClient.GetPolicy(ctx, "api-policy")
+	if err != nil {
+		t.Fatalf("Failed to get policy: %v", err)
+	}
+	if _, ok := replicated.Allow["/v1/key/decrypt/*"]; !ok || len(replicated.Allow) != 1 || len(replicated.Deny) != 0 {
+		t.Fatalf("Invalid policy: got allow '%v' and deny '%v'", replicated.Allow, replicated.Deny)
+	}
+}
+
+func TestReplicationConflict(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	var kekBytes [32]byte
+	if _, err := rand.Read(kekBytes[:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.ImportKey(ctx, "my-kek", &kes.ImportKeyRequest{Key: kekBytes[:], Cipher: kes.AES256}); err != nil {
+		t.Fatalf("Failed to import KEK: %v", err)
+	}
+	kekKey, err := crypto.NewSecretKey(crypto.AES256, kekBytes[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	kek := crypto.KeyVersion{Key: kekKey}
+
+	key, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	hmacKey, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate HMAC key: %v", err)
+	}
+	if equal, err := equalKey(ctx, client, "my-key", crypto.KeyVersion{Key: key}); err != nil || equal {
+		t.Fatalf("Conflicting key not detected: equal=%v err=%v", equal, err)
+	}
+
+	if err = pushKey(ctx, client, "my-key", crypto.KeyVersion{Key: key, HMACKey: hmacKey}, "my-kek", kek); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Existing key has been overwritten: got %v - want %v", err, kes.ErrKeyExists)
+	}
+	if err = pushKey(ctx, client, "my-key2", crypto.KeyVersion{Key: key, HMACKey: hmacKey}, "my-kek", kek); err != nil {
+		t.Fatalf("Failed to push key: %v", err)
+	}
+	if equal, err := equalKey(ctx, client, "my-key2", crypto.KeyVersion{Key: key}); err != nil || !equal {
+		t.Fatalf("Pushed key is not equal: equal=%v err=%v", equal, err)
+	}
+
+	// A newer version of an existing key replaces it but
+	// a diverged version is a conflict.
+	secret, err := crypto.GenerateSecretKey(crypto.AES256, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	first := crypto.KeyVersion{Key: key, HMACKey: hmacKey}
+	rotated := first.Rotate(secret, time.Now().UTC(), "")
+	if err = pushKey(ctx, client, "my-key2", rotated, "my-kek", kek); err != nil {
+		t.Fatalf("Failed to push rotated key: %v", err)
+	}
+	if version, err := keyVersion(ctx, client, "my-key2"); err != nil || version != 2 {
+		t.Fatalf("Invalid key version: got '%d' - want '2': %v", version, err)
+	}
+	if equal, err := equalKey(ctx, client, "my-key2", rotated); err != nil || !equal {
+		t.Fatalf("Pushed key is not equal: equal=%v err=%v", equal, err)
+	}
+	diverged := crypto.KeyVersion{Key: secret, HMACKey: hmacKey}
+	diverged = diverged.Rotate(key, time.Now().UTC(), "")
+	diverged = diverged.Rotate(secret, time.Now().UTC(), "")
+	if err = pushKey(ctx, client, "my-key2", diverged, "my-kek", kek); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Diverged key has been pushed: got %v - want %v", err, kes.ErrKeyExists)
+	}
+}
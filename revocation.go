@@ -0,0 +1,289 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"aead.dev/mem"
+	"golang.org/x/crypto/ocsp"
+)
+
+// errCertificateRevoked is returned when a client certificate
+// has been revoked.
+var errCertificateRevoked = errors.New("client certificate has been revoked")
+
+// revocationChecker checks whether client certificates have been
+// revoked using a certificate revocation list (CRL), OCSP or both.
+type revocationChecker struct {
+	conf RevocationConfig
+	crl  atomic.Pointer[crlSet]
+
+	client *http.Client
+
+	mu   sync.Mutex
+	ocsp map[revocationKey]ocspEntry
+}
+
+// revocationKey identifies a certificate by its issuer and
+// serial number.
+type revocationKey struct {
+	Issuer string
+	Serial string
+}
+
+// crlSet contains the revoked certificates listed in a CRL file.
+type crlSet struct {
+	ModTime time.Time
+	Size    int64
+	Revoked map[revocationKey]struct{}
+}
+
+// ocspEntry is a cached OCSP status.
+type ocspEntry struct {
+	Err    error // Non-nil if the certificate is revoked or the status is unknown
+	Expiry time.Time
+}
+
+// newRevocationChecker returns a new revocationChecker for the
+// given config. It returns nil if conf is nil.
+func newRevocationChecker(conf *RevocationConfig) (*revocationChecker, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	c := &revocationChecker{
+		conf: *conf,
+		client: &http.Client{
+			Timeout: 5 * time.Second,
+		},
+		ocsp: map[revocationKey]ocspEntry{},
+	}
+	if conf.CRLFile != "" {
+		if _, err := c.reloadCRL(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// reloadCRL reloads the CRL file if it has changed since it was
+// loaded last. It reports whether the file has been reloaded.
+// On error, the previously loaded CRL remains in use.
+func (c *revocationChecker) reloadCRL() (bool, error) {
+	if c.conf.CRLFile == "" {
+		return false, nil
+	}
+
+	stat, err := os.Stat(c.conf.CRLFile)
+	if err != nil {
+		return false, fmt.Errorf("kes: failed to read CRL: %v", err)
+	}
+	if old := c.crl.Load(); old != nil && old.ModTime.Equal(stat.ModTime()) && old.Size == stat.Size() {
+		return false, nil
+	}
+
+	set, err := readCRLFile(c.conf.CRLFile)
+	if err != nil {
+		return false, err
+	}
+	set.ModTime, set.Size = stat.ModTime(), stat.Size()
+	c.crl.Store(set)
+	return true, nil
+}
+
+// Verify returns an error if the client certificate of the TLS
+// connection has been revoked. It expects that the certificate
+// chain has been verified during the TLS handshake. Otherwise,
+// it cannot determine the issuer of the client certificate.
+func (c *revocationChecker) Verify(ctx context.Context, state *tls.ConnectionState) error {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return errors.New("client certificate has not been verified")
+	}
+	chain := state.VerifiedChains[0]
+	leaf := chain[0]
+
+	if crl := c.crl.Load(); crl != nil {
+		key := revocationKey{Issuer: string(leaf.RawIssuer), Serial: leaf.SerialNumber.String()}
+		if _, ok := crl.Revoked[key]; ok {
+			return errCertificateRevoked
+		}
+	}
+
+	if !c.conf.OCSP || len(leaf.OCSPServer) == 0 || len(chain) < 2 {
+		return nil
+	}
+	if err := c.verifyOCSP(ctx, leaf, chain[1]); err != nil {
+		if err == errCertificateRevoked || c.conf.OCSPStrict {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyOCSP returns an error if the OCSP responder of the leaf
+// certificate reports it as revoked or its status is unknown.
+// Responses are cached until their next update.
+func (c *revocationChecker) verifyOCSP(ctx context.Context, leaf, issuer *x509.Certificate) error {
+	const (
+		DefaultExpiry = 5 * time.Minute // If the response contains no next update
+		ErrorExpiry   = 1 * time.Minute // If the status cannot be determined
+	)
+
+	key := revocationKey{Issuer: string(leaf.RawIssuer), Serial: leaf.SerialNumber.String()}
+	now := time.Now()
+
+	c.mu.Lock()
+	entry, ok := c.ocsp[key]
+	c.mu.Unlock()
+	if ok && now.Before(entry.Expiry) {
+		return entry.Err
+	}
+
+	resp, err := c.queryOCSP(ctx, leaf, issuer)
+	switch {
+	case err != nil:
+		entry = ocspEntry{Err: err, Expiry: now.Add(ErrorExpiry)}
+	case resp.Status == ocsp.Good:
+		entry = ocspEntry{Expiry: now.Add(DefaultExpiry)}
+	case resp.Status == ocsp.Revoked:
+		entry = ocspEntry{Err: errCertificateRevoked, Expiry: now.Add(DefaultExpiry)}
+	default:
+		entry = ocspEntry{Err: errors.New("OCSP status of client certificate is unknown"), Expiry: now.Add(ErrorExpiry)}
+	}
+	if err == nil && resp.NextUpdate.After(now) {
+		entry.Expiry = resp.NextUpdate
+	}
+
+	c.mu.Lock()
+	for k, e := range c.ocsp { // Remove expired entries to bound the cache size
+		if !now.Before(e.Expiry) {
+			delete(c.ocsp, k)
+		}
+	}
+	c.ocsp[key] = entry
+	c.mu.Unlock()
+	return entry.Err
+}
+
+// queryOCSP sends an OCSP request for the leaf certificate to
+// the leaf's OCSP responder.
+func (c *revocationChecker) queryOCSP(ctx context.Context, leaf, issuer *x509.Certificate) (*ocsp.Response, error) {
+	const MaxSize = 1 * mem.MiB
+
+	body, err := ocsp.CreateRequest(leaf, issuer, nil)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, leaf.OCSPServer[0], bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/ocsp-request")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query OCSP responder: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to query OCSP responder: %s", resp.Status)
+	}
+
+	raw, err := io.ReadAll(mem.LimitReader(resp.Body, MaxSize))
+	if err != nil {
+		return nil, err
+	}
+	return ocsp.ParseResponseForCert(raw, leaf, issuer)
+}
+
+// readCRLFile reads a PEM or DER encoded certificate revocation
+// list from the file. A PEM file may contain multiple CRLs.
+func readCRLFile(filename string) (*crlSet, error) {
+	const MaxSize = 64 * mem.MiB
+
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to read CRL: %v", err)
+	}
+	defer file.Close()
+
+	raw, err := io.ReadAll(mem.LimitReader(file, MaxSize))
+	if err != nil {
+		return nil, fmt.Errorf("kes: failed to read CRL: %v", err)
+	}
+
+	var ders [][]byte
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("-----BEGIN")) {
+		for block, rest := pem.Decode(raw); block != nil; block, rest = pem.Decode(rest) {
+			if block.Type == "X509 CRL" {
+				ders = append(ders, block.Bytes)
+			}
+		}
+		if len(ders) == 0 {
+			return nil, fmt.Errorf("kes: failed to read CRL '%s': no PEM-encoded CRL found", filename)
+		}
+	} else {
+		ders = append(ders, raw)
+	}
+
+	set := &crlSet{Revoked: map[revocationKey]struct{}{}}
+	for _, der := range ders {
+		crl, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, fmt.Errorf("kes: failed to parse CRL '%s': %v", filename, err)
+		}
+		for _, entry := range crl.RevokedCertificateEntries {
+			set.Revoked[revocationKey{Issuer: string(crl.RawIssuer), Serial: entry.SerialNumber.String()}] = struct{}{}
+		}
+	}
+	return set, nil
+}
+
+// reloadCRLs reloads the CRL file, if configured, whenever it
+// changes until ctx is canceled.
+func (s *Server) reloadCRLs(ctx context.Context) {
+	const Delay = 1 * time.Minute // Delay between checks whether a CRL is configured
+
+	for {
+		wait := Delay
+		if r := s.state.Load().Revocation; r != nil && r.conf.CRLReload > 0 {
+			wait = r.conf.CRLReload
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.Revocation == nil {
+			continue
+		}
+		reloaded, err := state.Revocation.reloadCRL()
+		if err != nil {
+			state.Log.ErrorContext(ctx, err.Error())
+			continue
+		}
+		if reloaded {
+			state.Log.InfoContext(ctx, "reloaded certificate revocation list", "file", state.Revocation.conf.CRLFile)
+		}
+	}
+}
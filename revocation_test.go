@@ -0,0 +1,217 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+func TestRevocationCheckerCRL(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey := newTestCA(t)
+	leaf1 := newTestLeaf(t, ca, caKey, 1, "")
+	leaf2 := newTestLeaf(t, ca, caKey, 2, "")
+
+	filename := filepath.Join(t.TempDir(), "ca.crl")
+	writeTestCRL(t, filename, ca, caKey, 1, leaf1.SerialNumber)
+
+	checker, err := newRevocationChecker(&RevocationConfig{CRLFile: filename})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	ctx := context.Background()
+	if err = checker.Verify(ctx, connectionState(leaf1, ca)); err != errCertificateRevoked {
+		t.Fatalf("Revoked certificate has been accepted: %v", err)
+	}
+	if err = checker.Verify(ctx, connectionState(leaf2, ca)); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+	if err = checker.Verify(ctx, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf2}}); err == nil {
+		t.Fatal("Unverified certificate has been accepted")
+	}
+
+	// Replace the CRL and ensure its modification time changes.
+	writeTestCRL(t, filename, ca, caKey, 2, leaf2.SerialNumber)
+	modTime := time.Now().Add(time.Minute)
+	if err = os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := checker.reloadCRL(); err != nil || !reloaded {
+		t.Fatalf("Failed to reload CRL: reloaded=%v: %v", reloaded, err)
+	}
+	if reloaded, err := checker.reloadCRL(); err != nil || reloaded {
+		t.Fatalf("Reloaded unchanged CRL: reloaded=%v: %v", reloaded, err)
+	}
+	if err = checker.Verify(ctx, connectionState(leaf1, ca)); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+	if err = checker.Verify(ctx, connectionState(leaf2, ca)); err != errCertificateRevoked {
+		t.Fatalf("Revoked certificate has been accepted: %v", err)
+	}
+}
+
+func TestRevocationCheckerOCSP(t *testing.T) {
+	t.Parallel()
+
+	ca, caKey := newTestCA(t)
+	responder := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		req, err := ocsp.ParseRequest(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		status := ocsp.Good
+		if req.SerialNumber.Int64() == 1 {
+			status = ocsp.Revoked
+		}
+		resp, err := ocsp.CreateResponse(ca, ca, ocsp.Response{
+			Status:       status,
+			SerialNumber: req.SerialNumber,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+			RevokedAt:    time.Now().Add(-time.Minute),
+		}, caKey)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Write(resp)
+	}))
+	defer responder.Close()
+
+	revoked := newTestLeaf(t, ca, caKey, 1, responder.URL)
+	good := newTestLeaf(t, ca, caKey, 2, responder.URL)
+	unreachable := newTestLeaf(t, ca, caKey, 3, "http://127.0.0.1:1")
+
+	ctx := context.Background()
+	checker, err := newRevocationChecker(&RevocationConfig{OCSP: true})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = checker.Verify(ctx, connectionState(revoked, ca)); err != errCertificateRevoked {
+		t.Fatalf("Revoked certificate has been accepted: %v", err)
+	}
+	if err = checker.Verify(ctx, connectionState(good, ca)); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+	if err = checker.Verify(ctx, connectionState(unreachable, ca)); err != nil {
+		t.Fatalf("Certificate with unknown status has been rejected: %v", err)
+	}
+
+	strict, err := newRevocationChecker(&RevocationConfig{OCSP: true, OCSPStrict: true})
+	if err != nil {
+		t.Fatalf("Failed to create revocation checker: %v", err)
+	}
+	if err = strict.Verify(ctx, connectionState(good, ca)); err != nil {
+		t.Fatalf("Failed to verify certificate: %v", err)
+	}
+	if err = strict.Verify(ctx, connectionState(unreachable, ca)); err == nil {
+		t.Fatal("Certificate with unknown status has been accepted")
+	}
+}
+
+func newTestCA(t *testing.T) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1000),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func newTestLeaf(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, serial int64, ocspServer string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if ocspServer != "" {
+		template.OCSPServer = []string{ocspServer}
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+func writeTestCRL(t *testing.T, filename string, ca *x509.Certificate, caKey crypto.Signer, number int64, revoked ...*big.Int) {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(number),
+		ThisUpdate: time.Now(),
+		NextUpdate: time.Now().Add(time.Hour),
+	}
+	for _, serial := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   serial,
+			RevocationTime: time.Now(),
+		})
+	}
+	raw, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filename, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: raw}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func connectionState(leaf, ca *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains:   [][]*x509.Certificate{{leaf, ca}},
+	}
+}
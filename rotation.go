@@ -0,0 +1,205 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+)
+
+// minRotationInterval is the min. interval at which
+// keys can be rotated automatically.
+const minRotationInterval = 1 * time.Hour
+
+// validRotationInterval returns an error if keys cannot
+// be rotated automatically at the given interval. A zero
+// interval disables automatic rotation.
+func validRotationInterval(interval time.Duration) error {
+	if interval < 0 || (interval > 0 && interval < minRotationInterval) {
+		return fmt.Errorf("invalid rotation interval '%v': must be at least %v", interval, minRotationInterval)
+	}
+	return nil
+}
+
+// rotationSchedule records when keys with a rotation interval
+// are due for rotation. Hence, finding the keys to rotate does
+// not require reading all keys from the key store.
+type rotationSchedule struct {
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+// Set records when the key with the given name is due for
+// rotation. Keys without rotation interval or marked for
+// deletion are removed from the schedule.
+func (s *rotationSchedule) Set(name string, key *crypto.KeyVersion) {
+	next := key.NextRotation()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if next.IsZero() || !key.DeletedAt.IsZero() {
+		delete(s.next, name)
+		return
+	}
+	if s.next == nil {
+		s.next = map[string]time.Time{}
+	}
+	s.next[name] = next
+}
+
+// Remove removes the key with the given name from the schedule.
+func (s *rotationSchedule) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.next, name)
+}
+
+// Due returns the names of all keys due for rotation at time t.
+func (s *rotationSchedule) Due(t time.Time) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name, next := range s.next {
+		if !t.Before(next) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// rotateScheduledKeys rotates keys, that have a rotation
+// interval, once they are due for rotation until ctx is
+// canceled. Keys are not rotated while the server is frozen.
+//
+// Keys are rotated based on the rotation schedule of the key
+// cache. Keys created, rotated or read by this server are added
+// to the schedule. Keys created by other servers sharing the key
+// store are added once all keys are scanned, which happens once a
+// day.
+//
+// All servers sharing a key store may rotate keys. A key is rotated
+// by creating a new revision, which fails if another server has
+// rotated the key already. Hence, a key due for rotation is rotated
+// once. Within a cluster, only the leader rotates keys.
+func (s *Server) rotateScheduledKeys(ctx context.Context) {
+	const (
+		Delay        = 5 * time.Minute // Max. delay between checks for keys to rotate
+		ScanInterval = 24 * time.Hour  // Max. delay between scanning all keys
+	)
+
+	var (
+		scanned  *keyCache // The key cache whose keys have been scanned
+		scanTime time.Time
+	)
+	for {
+		timer := time.NewTimer(Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.Replica != nil || s.frozen.Load() != nil {
+			continue
+		}
+		if node := s.cluster.Load(); node != nil && !node.IsLeader() {
+			continue
+		}
+		if state.Keys != scanned || time.Since(scanTime) >= ScanInterval {
+			if err := state.Keys.ScanRotations(ctx); err != nil {
+				if ctx.Err() == nil {
+					state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to scan keys for rotation: %v", err))
+				}
+				continue
+			}
+			scanned, scanTime = state.Keys, time.Now()
+		}
+		s.rotateKeys(ctx, state, time.Now())
+	}
+}
+
+// rotateKeys rotates all keys that are due for rotation at time t
+// according to the rotation schedule of the key cache.
+func (s *Server) rotateKeys(ctx context.Context, state *serverState, t time.Time) {
+	for _, name := range state.Keys.DueRotations(t) {
+		if err := s.rotateScheduledKey(ctx, state, name, t); err != nil && ctx.Err() == nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to rotate key '%s': %v", name, err))
+		}
+	}
+}
+
+// rotateScheduledKey rotates the key if it is due for rotation
+// at time t. The new key version is created on behalf of the
+// identity that created the current key version. If another
+// server has rotated the key concurrently, it does nothing.
+func (s *Server) rotateScheduledKey(ctx context.Context, state *serverState, name string, t time.Time) error {
+	s.rotateLock.Lock()
+	defer s.rotateLock.Unlock()
+
+	old, err := state.Keys.Get(ctx, name)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		state.Keys.rotations.Remove(name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if next := old.NextRotation(); next.IsZero() || t.Before(next) {
+		state.Keys.rotations.Set(name, &old) // The schedule may be outdated
+		return nil
+	}
+
+	secret, err := crypto.GenerateSecretKey(old.Key.Type(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	if !old.HasHMACKey() {
+		if old.HMACKey, err = crypto.GenerateHMACKey(crypto.SHA256, rand.Reader); err != nil {
+			return err
+		}
+	}
+	key := old.Rotate(secret, t.UTC(), old.CreatedBy)
+	if err = state.Keys.Replace(ctx, name, old, key); err != nil {
+		if errors.Is(err, errKeyModified) {
+			state.Keys.rotations.Remove(name) // Re-added once the key is read or scanned again
+			return nil
+		}
+		return err
+	}
+
+	s.notify(Event{
+		Type:     EventKeyRotated,
+		Time:     time.Now().UTC(),
+		Name:     name,
+		Identity: old.CreatedBy,
+	})
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPut, api.PathKeyRotate+name, nil)
+	if err != nil {
+		return err
+	}
+	state.Audit.Log(
+		fmt.Sprintf("secret key '%s' rotated to version %d by schedule. Next rotation at %s", name, key.Number(), key.NextRotation().Format(time.RFC3339)),
+		http.StatusOK,
+		&api.Request{Request: r, Identity: old.CreatedBy, Received: t},
+	)
+	return nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+)
+
+func TestRotateScheduledKeys(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	const RotationInterval = 24 * time.Hour
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{
+		RotationInterval: int64(RotationInterval.Seconds()),
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key-2", api.CreateKeyRequest{
+		RotationInterval: 60,
+	}); err == nil {
+		t.Fatal("Created key with rotation interval below the minimum")
+	}
+	if err := client.CreateKey(ctx, "my-key-3"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	state := srv.state.Load()
+	srv.rotateKeys(ctx, state, time.Now())
+	if key, _ := state.Keys.Get(ctx, "my-key"); key.Number() != 1 {
+		t.Fatalf("Key has been rotated before it was due: got version '%d' - want '%d'", key.Number(), 1)
+	}
+
+	now := time.Now().Add(RotationInterval)
+	srv.rotateKeys(ctx, state, now)
+	key, err := state.Keys.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to read key: %v", err)
+	}
+	if key.Number() != 2 {
+		t.Fatalf("Key has not been rotated: got version '%d' - want '%d'", key.Number(), 2)
+	}
+	if next := key.NextRotation(); !next.Equal(now.UTC().Add(RotationInterval)) {
+		t.Fatalf("Invalid next rotation: got '%v' - want '%v'", next, now.UTC().Add(RotationInterval))
+	}
+	if key, _ = state.Keys.Get(ctx, "my-key-3"); key.Number() != 1 {
+		t.Fatalf("Key without rotation interval has been rotated: got version '%d' - want '%d'", key.Number(), 1)
+	}
+
+	plaintext, err := client.Decrypt(ctx, "my-key", ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt with rotated key: %v", err)
+	}
+	if string(plaintext) != "Hello World" {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+	}
+}
+
+func TestRotateScheduledKeysShared(t *testing.T) {
+	ctx := testContext(t)
+
+	// Two servers sharing the same key store.
+	store := &MemKeyStore{}
+	srvA, url := startServer(ctx, &Config{Keys: store})
+	defer srvA.Close()
+	srvB, _ := startServer(ctx, &Config{Keys: store})
+	defer srvB.Close()
+
+	const RotationInterval = 24 * time.Hour
+	if err := sendRequest(ctx, defaultClient(url), http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{
+		RotationInterval: int64(RotationInterval.Seconds()),
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	stateA, stateB := srvA.state.Load(), srvB.state.Load()
+	if _, err := stateB.Keys.Get(ctx, "my-key"); err != nil { // Cache the first version
+		t.Fatalf("Failed to read key: %v", err)
+	}
+
+	now := time.Now().Add(RotationInterval)
+	if err := srvA.rotateScheduledKey(ctx, stateA, "my-key", now); err != nil {
+		t.Fatalf("Failed to rotate key: %v", err)
+	}
+	if err := srvB.rotateScheduledKey(ctx, stateB, "my-key", now); err != nil {
+		t.Fatalf("Failed to rotate key rotated by another server: %v", err)
+	}
+
+	key, err := stateB.Keys.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to read key: %v", err)
+	}
+	if key.Number() != 2 {
+		t.Fatalf("Key has been rotated more than once: got version '%d' - want '%d'", key.Number(), 2)
+	}
+}
+
+func TestRotateScheduledKeysScan(t *testing.T) {
+	ctx := testContext(t)
+
+	// Two servers sharing the same key store.
+	store := &MemKeyStore{}
+	srvA, url := startServer(ctx, &Config{Keys: store})
+	defer srvA.Close()
+	srvB, _ := startServer(ctx, &Config{Keys: store})
+	defer srvB.Close()
+
+	const RotationInterval = 24 * time.Hour
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"my-key", api.CreateKeyRequest{
+		RotationInterval: int64(RotationInterval.Seconds()),
+	}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := client.CreateKey(ctx, "my-key-2"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	// Server B has not seen the key yet. Hence, it does not
+	// rotate the key until it has scanned all keys.
+	stateB := srvB.state.Load()
+	now := time.Now().Add(RotationInterval)
+	srvB.rotateKeys(ctx, stateB, now)
+	if key, _, _, _ := stateB.Keys.fetchLatest(ctx, "my-key"); key.Number() != 1 {
+		t.Fatalf("Key not scheduled for rotation has been rotated: got version '%d' - want '%d'", key.Number(), 1)
+	}
+
+	if err := stateB.Keys.ScanRotations(ctx); err != nil {
+		t.Fatalf("Failed to scan keys: %v", err)
+	}
+	if n := stateB.Keys.Len(); n != 0 {
+		t.Fatalf("Scanned keys have been cached: got '%d' cache entries - want '%d'", n, 0)
+	}
+	if due := stateB.Keys.DueRotations(now); len(due) != 1 || due[0] != "my-key" {
+		t.Fatalf("Invalid keys due for rotation: got '%v' - want '%v'", due, []string{"my-key"})
+	}
+
+	srvB.rotateKeys(ctx, stateB, now)
+	if key, _, _, _ := stateB.Keys.fetchLatest(ctx, "my-key"); key.Number() != 2 {
+		t.Fatalf("Key has not been rotated: got version '%d' - want '%d'", key.Number(), 2)
+	}
+	if due := stateB.Keys.DueRotations(now); len(due) != 0 {
+		t.Fatalf("Rotated key is still due for rotation: got '%v'", due)
+	}
+}
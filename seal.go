@@ -0,0 +1,242 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/shamir"
+)
+
+// errSealed is returned by a sealedKeyStore as long as
+// the server has not been unsealed.
+var errSealed = api.NewError(http.StatusServiceUnavailable, "server is sealed")
+
+// rootKeySize is the size of the root key of a sealed server.
+const rootKeySize = 32
+
+// sealVersion is the first byte of every sealed KeyStore entry.
+const sealVersion = 1
+
+// sealer holds the root key shares provided to unseal the
+// server until the root key can be reconstructed. Once
+// unsealed, it en/decrypts KeyStore entries with the root
+// key.
+type sealer struct {
+	threshold   int
+	rootKeyHash []byte
+
+	mu     sync.Mutex
+	shares [][]byte
+
+	aead atomic.Pointer[cipher.AEAD] // nil while sealed
+}
+
+// newSealer returns a new sealer for the SealConfig, or nil
+// if conf is nil. It returns old if old seals the server
+// with the same root key such that reloading the config
+// does not seal the server again.
+func newSealer(conf *SealConfig, old *sealer) *sealer {
+	if conf == nil {
+		return nil
+	}
+	if old != nil && old.threshold == conf.Threshold && bytes.Equal(old.rootKeyHash, conf.RootKeyHash) {
+		return old
+	}
+	return &sealer{
+		threshold:   conf.Threshold,
+		rootKeyHash: bytes.Clone(conf.RootKeyHash),
+	}
+}
+
+// Sealed reports whether the root key has not been
+// reconstructed yet.
+func (s *sealer) Sealed() bool { return s.aead.Load() == nil }
+
+// Threshold returns the number of shares required to
+// unseal the server.
+func (s *sealer) Threshold() int { return s.threshold }
+
+// Progress returns the number of shares provided so far.
+// It returns the threshold once the server is unsealed.
+func (s *sealer) Progress() int {
+	if !s.Sealed() {
+		return s.threshold
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.shares)
+}
+
+// Unseal adds the share and tries to reconstruct the root
+// key once the threshold is reached. It reports whether the
+// server is unsealed.
+//
+// If the shares do not reconstruct the root key, all shares
+// provided so far are discarded and Unseal returns an error.
+// Shares provided more than once are ignored.
+func (s *sealer) Unseal(share []byte) (bool, error) {
+	if !s.Sealed() {
+		return true, nil
+	}
+	if len(share) != rootKeySize+1 || share[rootKeySize] == 0 {
+		return false, api.NewError(http.StatusBadRequest, "invalid unseal share")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.Sealed() {
+		return true, nil
+	}
+	for _, sh := range s.shares {
+		if sh[rootKeySize] == share[rootKeySize] {
+			return false, nil
+		}
+	}
+	s.shares = append(s.shares, bytes.Clone(share))
+	if len(s.shares) < s.threshold {
+		return false, nil
+	}
+
+	rootKey, err := shamir.Combine(s.shares)
+	for _, sh := range s.shares {
+		clear(sh)
+	}
+	s.shares = nil
+	if err != nil {
+		return false, api.NewError(http.StatusBadRequest, "invalid unseal shares: "+err.Error())
+	}
+	defer clear(rootKey)
+
+	if h := sha256.Sum256(rootKey); subtle.ConstantTimeCompare(h[:], s.rootKeyHash) != 1 {
+		return false, api.NewError(http.StatusBadRequest, "invalid unseal shares: root key does not match")
+	}
+	block, err := aes.NewCipher(rootKey)
+	if err != nil {
+		return false, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return false, err
+	}
+	s.aead.Store(&aead)
+	return true, nil
+}
+
+// Seal encrypts the KeyStore entry value. The entry name is
+// authenticated such that sealed values cannot be swapped.
+func (s *sealer) Seal(name string, value []byte) ([]byte, error) {
+	aead := s.aead.Load()
+	if aead == nil {
+		return nil, errSealed
+	}
+
+	n := (*aead).NonceSize()
+	sealed := make([]byte, 1+n, 1+n+len(value)+(*aead).Overhead())
+	sealed[0] = sealVersion
+	nonce := sealed[1:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return (*aead).Seal(sealed, nonce, value, []byte(name)), nil
+}
+
+// Open decrypts the sealed KeyStore entry value.
+func (s *sealer) Open(name string, sealed []byte) ([]byte, error) {
+	aead := s.aead.Load()
+	if aead == nil {
+		return nil, errSealed
+	}
+
+	n := (*aead).NonceSize()
+	if len(sealed) < 1+n || sealed[0] != sealVersion {
+		return nil, fmt.Errorf("kes: key store entry '%s' is not sealed", name)
+	}
+	value, err := (*aead).Open(nil, sealed[1:1+n], sealed[1+n:], []byte(name))
+	if err != nil {
+		return nil, errors.New("kes: failed to unseal key store entry '" + name + "'")
+	}
+	return value, nil
+}
+
+// sealKeyStore returns a KeyStore that encrypts all entries
+// of store with the sealer's root key. It returns store if
+// s is nil.
+func sealKeyStore(store KeyStore, s *sealer) KeyStore {
+	if s == nil {
+		return store
+	}
+	return &sealedKeyStore{store: store, sealer: s}
+}
+
+// sealedKeyStore is a KeyStore that encrypts entry values
+// before storing them. All operations, except Status, fail
+// while the server is sealed.
+type sealedKeyStore struct {
+	store  KeyStore
+	sealer *sealer
+}
+
+var _ KeyStore = (*sealedKeyStore)(nil) // compiler check
+
+func (ks *sealedKeyStore) String() string { return fmt.Sprint(ks.store) }
+
+// Unwrap returns the underlying KeyStore.
+func (ks *sealedKeyStore) Unwrap() KeyStore { return ks.store }
+
+func (ks *sealedKeyStore) Close() error { return ks.store.Close() }
+
+// Status returns the state of the underlying KeyStore. It
+// does not fail while the server is sealed since a sealed
+// KeyStore is still reachable.
+func (ks *sealedKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	return ks.store.Status(ctx)
+}
+
+func (ks *sealedKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	sealed, err := ks.sealer.Seal(name, value)
+	if err != nil {
+		return err
+	}
+	return ks.store.Create(ctx, name, sealed)
+}
+
+func (ks *sealedKeyStore) Delete(ctx context.Context, name string) error {
+	if ks.sealer.Sealed() {
+		return errSealed
+	}
+	return ks.store.Delete(ctx, name)
+}
+
+func (ks *sealedKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if ks.sealer.Sealed() {
+		return nil, errSealed
+	}
+	sealed, err := ks.store.Get(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return ks.sealer.Open(name, sealed)
+}
+
+func (ks *sealedKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	if ks.sealer.Sealed() {
+		return nil, "", errSealed
+	}
+	return ks.store.List(ctx, prefix, n)
+}
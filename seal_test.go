@@ -0,0 +1,224 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/shamir"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestSealer(t *testing.T) {
+	conf, shares := newTestSealConfig(t, 5, 3)
+
+	s := newSealer(conf, nil)
+	if !s.Sealed() {
+		t.Fatal("New sealer is not sealed")
+	}
+	if _, err := s.Seal("my-key", []byte("value")); err == nil {
+		t.Fatal("Sealing entry while sealed should have failed")
+	}
+	if _, err := s.Unseal(shares[0][:len(shares[0])-1]); err == nil {
+		t.Fatal("Unsealing with invalid share should have failed")
+	}
+
+	// Shares of a different root key reset the progress.
+	_, other := newTestSealConfig(t, 5, 3)
+	for _, share := range [][]byte{shares[0], shares[1], other[2]} {
+		if _, err := s.Unseal(share); err != nil && !bytes.Equal(share, other[2]) {
+			t.Fatalf("Failed to unseal: %v", err)
+		}
+	}
+	if !s.Sealed() || s.Progress() != 0 {
+		t.Fatalf("Invalid shares: got sealed '%v' and progress '%d' - want sealed and no progress", s.Sealed(), s.Progress())
+	}
+
+	for i, share := range [][]byte{shares[4], shares[4], shares[2], shares[0]} {
+		unsealed, err := s.Unseal(share)
+		if err != nil {
+			t.Fatalf("Share %d: failed to unseal: %v", i, err)
+		}
+		if unsealed != (i == 3) {
+			t.Fatalf("Share %d: got unsealed '%v' - want '%v'", i, unsealed, i == 3)
+		}
+	}
+
+	sealed, err := s.Seal("my-key", []byte("value"))
+	if err != nil {
+		t.Fatalf("Failed to seal entry: %v", err)
+	}
+	if _, err = s.Open("other-key", sealed); err == nil {
+		t.Fatal("Opening entry with different name should have failed")
+	}
+	value, err := s.Open("my-key", sealed)
+	if err != nil {
+		t.Fatalf("Failed to open entry: %v", err)
+	}
+	if !bytes.Equal(value, []byte("value")) {
+		t.Fatalf("Invalid entry value: got '%s' - want 'value'", value)
+	}
+
+	if newSealer(conf, s) != s {
+		t.Fatal("Sealer with same config has not been reused")
+	}
+	if newSealer(&SealConfig{Threshold: 2, RootKeyHash: conf.RootKeyHash}, s) == s {
+		t.Fatal("Sealer with different threshold has been reused")
+	}
+}
+
+func TestServerSeal(t *testing.T) {
+	ctx := testContext(t)
+	conf, shares := newTestSealConfig(t, 3, 2)
+
+	store := &MemKeyStore{}
+	srv, url := startServer(ctx, &Config{
+		Keys: store,
+		Seal: conf,
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	unseal := func(share []byte) (*api.UnsealResponse, int) {
+		body, err := json.Marshal(api.UnsealRequest{Share: share})
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, url+api.PathAdminUnseal, bytes.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to unseal server: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var unseal api.UnsealResponse
+		if resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(&unseal); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+		}
+		return &unseal, resp.StatusCode
+	}
+
+	var kErr kes.Error
+	if err := client.CreateKey(ctx, "my-key"); !errors.As(err, &kErr) || kErr.Status() != http.StatusServiceUnavailable {
+		t.Fatalf("Creating key while sealed: got '%v' - want status '%d'", err, http.StatusServiceUnavailable)
+	}
+	if resp, code := unseal(nil); code != http.StatusOK || !resp.Sealed || resp.Threshold != 2 || resp.Progress != 0 {
+		t.Fatalf("Seal status: got '%+v' with status '%d'", resp, code)
+	}
+	if resp, code := unseal(shares[2]); code != http.StatusOK || !resp.Sealed || resp.Progress != 1 {
+		t.Fatalf("Providing first share: got '%+v' with status '%d'", resp, code)
+	}
+	if resp, code := unseal(shares[0]); code != http.StatusOK || resp.Sealed {
+		t.Fatalf("Providing second share: got '%+v' with status '%d'", resp, code)
+	}
+
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if _, err := client.GenerateKey(ctx, "my-key", nil); err != nil {
+		t.Fatalf("Failed to generate data key: %v", err)
+	}
+	value, err := store.Get(ctx, "my-key")
+	if err != nil {
+		t.Fatalf("Failed to get key store entry: %v", err)
+	}
+	if value[0] != sealVersion || json.Valid(value) {
+		t.Fatal("Key store entry is not sealed")
+	}
+
+	// Reloading the same seal config does not seal the server again.
+	closer, err := srv.Update(&Config{
+		Admin: defaultIdentity,
+		TLS: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			ClientAuth:   tls.RequestClientCert,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		Cache:    &CacheConfig{},
+		Keys:     store,
+		Seal:     conf,
+		ErrorLog: discardLog{},
+		AuditLog: discardAudit{},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update server: %v", err)
+	}
+	defer closer.Close()
+	if _, err := client.DescribeKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to describe key after update: %v", err)
+	}
+}
+
+func TestVerifyConfigSeal(t *testing.T) {
+	hash := make([]byte, sha256.Size)
+	for i, test := range []struct {
+		Seal       *SealConfig
+		Cluster    *ClusterConfig
+		ShouldFail bool
+	}{
+		{Seal: &SealConfig{Threshold: 2, RootKeyHash: hash}},
+		{Seal: &SealConfig{Threshold: 1, RootKeyHash: hash}, ShouldFail: true},
+		{Seal: &SealConfig{Threshold: 256, RootKeyHash: hash}, ShouldFail: true},
+		{Seal: &SealConfig{Threshold: 2, RootKeyHash: hash[:16]}, ShouldFail: true},
+		{Seal: &SealConfig{Threshold: 2}, ShouldFail: true},
+		{
+			Seal: &SealConfig{Threshold: 2, RootKeyHash: hash},
+			Cluster: &ClusterConfig{
+				Addr: "https://127.0.0.1:7373",
+				Dir:  t.TempDir(),
+				TLS:  &tls.Config{Certificates: []tls.Certificate{defaultServerCertificate()}},
+
+				Identities: []kes.Identity{defaultIdentity},
+				Key:        make([]byte, clusterKeySize),
+			},
+			ShouldFail: true,
+		},
+	} {
+		conf := &Config{
+			TLS: &tls.Config{
+				ClientAuth:   tls.RequestClientCert,
+				Certificates: []tls.Certificate{defaultServerCertificate()},
+			},
+			Seal:    test.Seal,
+			Cluster: test.Cluster,
+			Keys:    &MemKeyStore{},
+		}
+		err := verifyConfig(conf)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify config: %v", i, err)
+		}
+	}
+}
+
+// newTestSealConfig returns a new SealConfig for a random
+// root key and the root key shares.
+func newTestSealConfig(t *testing.T, n, k int) (*SealConfig, [][]byte) {
+	rootKey := make([]byte, rootKeySize)
+	if _, err := rand.Read(rootKey); err != nil {
+		t.Fatal(err)
+	}
+	shares, err := shamir.Split(rootKey, n, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := sha256.Sum256(rootKey)
+	return &SealConfig{Threshold: k, RootKeyHash: hash[:]}, shares
+}
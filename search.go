@@ -0,0 +1,248 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+)
+
+// keyFilter is a set of conditions on key metadata. A key
+// matches the filter if it satisfies all conditions. The
+// zero keyFilter matches any key.
+type keyFilter struct {
+	Tags          []tagFilter
+	Algorithm     string
+	CreatedBy     kes.Identity
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// tagFilter matches keys with a tag Key. If HasValue is
+// true, the tag's value must be equal to Value.
+type tagFilter struct {
+	Key      string
+	Value    string
+	HasValue bool
+}
+
+// parseKeyFilter parses a keyFilter from URL query parameters.
+// The following parameters are recognized:
+//   - tag:            '<key>' or '<key>:<value>'. May be repeated.
+//   - algorithm:      The key algorithm, e.g. 'AES256'.
+//   - created_by:     The identity that created the key.
+//   - created_after:  An RFC 3339 timestamp or date.
+//   - created_before: An RFC 3339 timestamp or date.
+//
+// The 'continue' parameter is ignored. Any other parameter
+// causes an error.
+func parseKeyFilter(query url.Values) (keyFilter, error) {
+	var filter keyFilter
+	for name, values := range query {
+		if name == "continue" {
+			continue
+		}
+		if name != "tag" && len(values) > 1 {
+			return keyFilter{}, fmt.Errorf("search parameter '%s' specified more than once", name)
+		}
+
+		var err error
+		switch value := values[0]; name {
+		case "tag":
+			for _, value := range values {
+				k, v, ok := strings.Cut(value, ":")
+				if k == "" {
+					return keyFilter{}, fmt.Errorf("invalid tag filter '%s'", value)
+				}
+				filter.Tags = append(filter.Tags, tagFilter{Key: k, Value: v, HasValue: ok})
+			}
+		case "algorithm":
+			filter.Algorithm = value
+		case "created_by":
+			filter.CreatedBy = kes.Identity(value)
+		case "created_after":
+			filter.CreatedAfter, err = parseFilterTime(value)
+		case "created_before":
+			filter.CreatedBefore, err = parseFilterTime(value)
+		default:
+			return keyFilter{}, fmt.Errorf("unknown search parameter '%s'", name)
+		}
+		if err != nil {
+			return keyFilter{}, fmt.Errorf("invalid search parameter '%s': %v", name, err)
+		}
+	}
+	return filter, nil
+}
+
+// Match reports whether the key satisfies all conditions
+// of the filter.
+func (f *keyFilter) Match(key *crypto.KeyVersion) bool {
+	if f.Algorithm != "" && !strings.EqualFold(f.Algorithm, key.Key.Type().String()) {
+		return false
+	}
+	if f.CreatedBy != "" && f.CreatedBy != key.CreatedBy {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !key.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !key.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		v, ok := key.Tags[tag.Key]
+		if !ok || (tag.HasValue && v != tag.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseFilterTime parses s as either RFC 3339 timestamp
+// or as date, e.g. '2024-01-31'.
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// listPage describes one page of a key listing.
+type listPage struct {
+	Limit    int    // Max. number of names on the page
+	Continue string // First name on the page, if present
+	Match    string // Glob pattern names must match, if not empty
+	Reverse  bool   // Sort names in descending order
+}
+
+// parseListPage parses a listPage from URL query parameters.
+// The following parameters are recognized:
+//   - limit:    The max. number of names. Defaults to def.
+//   - continue: The name at which the page starts.
+//   - match:    A glob pattern, as recognized by path.Match.
+//   - order:    Either 'asc' or 'desc'. Defaults to 'asc'.
+//
+// Any other parameter causes an error.
+func parseListPage(query url.Values, def, maxLimit int) (listPage, error) {
+	page := listPage{Limit: def}
+	for name, values := range query {
+		if len(values) > 1 {
+			return listPage{}, fmt.Errorf("list parameter '%s' specified more than once", name)
+		}
+
+		switch value := values[0]; name {
+		case "limit":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 || n > maxLimit {
+				return listPage{}, fmt.Errorf("invalid list parameter 'limit': must be between 1 and %d", maxLimit)
+			}
+			page.Limit = n
+		case "continue":
+			page.Continue = value
+		case "match":
+			if _, err := path.Match(value, ""); err != nil {
+				return listPage{}, fmt.Errorf("invalid list parameter 'match': %v", err)
+			}
+			page.Match = value
+		case "order":
+			switch value {
+			case "asc":
+			case "desc":
+				page.Reverse = true
+			default:
+				return listPage{}, fmt.Errorf("invalid list parameter 'order': must be 'asc' or 'desc'")
+			}
+		default:
+			return listPage{}, fmt.Errorf("unknown list parameter '%s'", name)
+		}
+	}
+	return page, nil
+}
+
+// Apply sorts and filters the names and returns the names
+// on the page. If there are more names after the page, it
+// returns the name at which the next page starts.
+func (p *listPage) Apply(names []string) ([]string, string) {
+	if p.Match != "" {
+		names = slices.DeleteFunc(names, func(name string) bool {
+			ok, _ := path.Match(p.Match, name)
+			return !ok
+		})
+	}
+
+	slices.Sort(names)
+	if p.Reverse {
+		slices.Reverse(names)
+	}
+	if p.Continue != "" {
+		var i int
+		if p.Reverse {
+			i, _ = slices.BinarySearchFunc(names, p.Continue, func(name, target string) int {
+				return strings.Compare(target, name)
+			})
+		} else {
+			i, _ = slices.BinarySearch(names, p.Continue)
+		}
+		names = names[i:]
+	}
+
+	if len(names) <= p.Limit {
+		return names, ""
+	}
+	return names[:p.Limit], names[p.Limit]
+}
+
+// listFilter is a set of conditions on policy and identity
+// metadata. The zero listFilter matches everything.
+type listFilter struct {
+	Policy       string
+	CreatedAfter time.Time
+}
+
+// parseListFilter parses a listFilter from URL query parameters.
+// The following parameters are recognized:
+//   - policy:        The name of the assigned policy.
+//   - created_after: An RFC 3339 timestamp or date.
+//
+// Any other parameter causes an error.
+func parseListFilter(query url.Values) (listFilter, error) {
+	var filter listFilter
+	for name, values := range query {
+		if len(values) > 1 {
+			return listFilter{}, fmt.Errorf("list parameter '%s' specified more than once", name)
+		}
+
+		var err error
+		switch value := values[0]; name {
+		case "policy":
+			filter.Policy = value
+		case "created_after":
+			filter.CreatedAfter, err = parseFilterTime(value)
+		default:
+			return listFilter{}, fmt.Errorf("unknown list parameter '%s'", name)
+		}
+		if err != nil {
+			return listFilter{}, fmt.Errorf("invalid list parameter '%s': %v", name, err)
+		}
+	}
+	return filter, nil
+}
+
+// Match reports whether an entry with the given policy and
+// creation time satisfies all conditions of the filter.
+func (f *listFilter) Match(policy string, createdAt time.Time) bool {
+	if f.Policy != "" && f.Policy != policy {
+		return false
+	}
+	return f.CreatedAfter.IsZero() || createdAt.After(f.CreatedAfter)
+}
@@ -0,0 +1,74 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/crypto"
+)
+
+func TestKeyFilter(t *testing.T) {
+	secretKey, err := crypto.GenerateSecretKey(crypto.AES256, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := crypto.KeyVersion{
+		Key:       secretKey,
+		CreatedAt: time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC),
+		CreatedBy: "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22",
+		Tags:      map[string]string{"team": "payments", "env": "prod"},
+	}
+
+	for i, test := range keyFilterTests {
+		query, err := url.ParseQuery(test.Query)
+		if err != nil {
+			t.Fatalf("Test %d: invalid query: %v", i, err)
+		}
+
+		filter, err := parseKeyFilter(query)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to parse filter: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: parsing should have failed but succeeded", i)
+		}
+		if test.ShouldFail {
+			continue
+		}
+
+		if match := filter.Match(&key); match != test.Match {
+			t.Fatalf("Test %d: got match '%v' - want '%v'", i, match, test.Match)
+		}
+	}
+}
+
+var keyFilterTests = []struct {
+	Query      string
+	Match      bool
+	ShouldFail bool
+}{
+	{Query: "", Match: true},                                                                            // 0
+	{Query: "continue=my-key", Match: true},                                                             // 1
+	{Query: "tag=team:payments", Match: true},                                                           // 2
+	{Query: "tag=team", Match: true},                                                                    // 3
+	{Query: "tag=team:payments&tag=env:prod", Match: true},                                              // 4
+	{Query: "tag=team:payments&tag=env:dev", Match: false},                                              // 5
+	{Query: "tag=team:storage", Match: false},                                                           // 6
+	{Query: "tag=owner", Match: false},                                                                  // 7
+	{Query: "algorithm=aes256", Match: true},                                                            // 8
+	{Query: "algorithm=ChaCha20", Match: false},                                                         // 9
+	{Query: "created_after=2024-01-01&created_before=2024-02-01", Match: true},                          // 10
+	{Query: "created_after=2024-01-15T12:00:00Z", Match: false},                                         // 11
+	{Query: "created_by=3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22", Match: true}, // 12
+	{Query: "created_by=unknown", Match: false},                                                         // 13
+
+	{Query: "unknown=1", ShouldFail: true},                           // 14
+	{Query: "tag=:payments", ShouldFail: true},                       // 15
+	{Query: "algorithm=AES256&algorithm=ChaCha20", ShouldFail: true}, // 16
+	{Query: "created_after=yesterday", ShouldFail: true},             // 17
+}
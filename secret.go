@@ -0,0 +1,382 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"aead.dev/mem"
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"github.com/minio/kms-go/kes"
+)
+
+const (
+	// secretPrefix is the name prefix of the KeyStore entries
+	// containing secrets. They cannot be accessed via the key
+	// APIs.
+	secretPrefix = "kes-secret-"
+
+	// maxSecretSize is the max. size of a secret value.
+	maxSecretSize = 64 * mem.KiB
+)
+
+var errSecretNotFound = api.NewError(http.StatusNotFound, "secret does not exist")
+
+// storedSecret is a secret value encrypted by a key. It is
+// stored as JSON in the KeyStore.
+type storedSecret struct {
+	Key        string       `json:"key"`
+	Ciphertext []byte       `json:"ciphertext"`
+	CreatedAt  time.Time    `json:"created_at"`
+	CreatedBy  kes.Identity `json:"created_by,omitempty"`
+}
+
+// secretStore stores secrets in a KeyStore. Entry names are
+// the secret names prefixed with the secretPrefix.
+type secretStore struct {
+	store KeyStore
+}
+
+// Get returns the secret with the given name. It returns
+// errSecretNotFound if no such secret exists.
+func (s *secretStore) Get(ctx context.Context, name string) (storedSecret, error) {
+	b, err := s.store.Get(ctx, secretPrefix+name)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		return storedSecret{}, errSecretNotFound
+	}
+	if err != nil {
+		return storedSecret{}, err
+	}
+
+	var secret storedSecret
+	if err = json.Unmarshal(b, &secret); err != nil {
+		return storedSecret{}, fmt.Errorf("kes: invalid secret '%s': %v", name, err)
+	}
+	return secret, nil
+}
+
+// Set creates the secret with the given name or replaces an
+// existing one.
+//
+// A KeyStore cannot update entries. Hence, Set deletes and then
+// re-creates an existing entry. If re-creating the entry fails,
+// Set tries to restore the old secret.
+func (s *secretStore) Set(ctx context.Context, name string, secret storedSecret) error {
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return err
+	}
+
+	err = s.store.Create(ctx, secretPrefix+name, b)
+	if !errors.Is(err, kes.ErrKeyExists) {
+		return err
+	}
+	prev, err := s.store.Get(ctx, secretPrefix+name)
+	if err != nil {
+		return err
+	}
+	if err = s.store.Delete(ctx, secretPrefix+name); err != nil {
+		return err
+	}
+	if err = s.store.Create(ctx, secretPrefix+name, b); err != nil {
+		// Restore the old secret even if ctx has been canceled.
+		// Otherwise, the secret would be lost.
+		if rErr := s.store.Create(context.WithoutCancel(ctx), secretPrefix+name, prev); rErr != nil {
+			return fmt.Errorf("failed to restore secret '%s': %v: %w", name, rErr, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// Delete deletes the secret with the given name. It returns
+// errSecretNotFound if no such secret exists.
+func (s *secretStore) Delete(ctx context.Context, name string) error {
+	if _, err := s.store.Get(ctx, secretPrefix+name); errors.Is(err, kes.ErrKeyNotFound) {
+		return errSecretNotFound
+	}
+	err := s.store.Delete(ctx, secretPrefix+name)
+	if errors.Is(err, kes.ErrKeyNotFound) {
+		return errSecretNotFound
+	}
+	return err
+}
+
+// List returns the names of all secrets that start with the
+// prefix.
+func (s *secretStore) List(ctx context.Context, prefix string) ([]string, error) {
+	names, _, err := s.store.List(ctx, secretPrefix+prefix, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]string, 0, len(names))
+	for _, name := range names {
+		if name, ok := strings.CutPrefix(name, secretPrefix); ok && strings.HasPrefix(name, prefix) {
+			secrets = append(secrets, name)
+		}
+	}
+	return secrets, nil
+}
+
+// setSecret encrypts the secret value sent by the client with
+// a key and stores it. An existing secret is replaced.
+func (s *Server) setSecret(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "secret name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.SetSecretRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !s.state.Load().Names.ValidName(body.Key) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", body.Key)
+		return
+	}
+	if len(body.Value) == 0 {
+		resp.Fail(http.StatusBadRequest, "secret value is empty")
+		return
+	}
+	if len(body.Value) > int(maxSecretSize) {
+		resp.Failf(http.StatusBadRequest, "secret value exceeds max. size of %v", maxSecretSize)
+		return
+	}
+	if err := s.state.Load().Keys.CheckEncrypt(); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	s.secretLock.Lock()
+	defer s.secretLock.Unlock()
+
+	key, err := s.state.Load().Keys.Get(req.Context(), body.Key)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageEncrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+	ciphertext, err := key.Encrypt(body.Value, []byte(req.Resource))
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to encrypt secret")
+		return
+	}
+
+	if err = s.state.Load().Secrets.Set(req.Context(), req.Resource, storedSecret{
+		Key:        body.Key,
+		Ciphertext: ciphertext,
+		CreatedAt:  time.Now().UTC(),
+		CreatedBy:  req.Identity,
+	}); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to store secret")
+		return
+	}
+	s.recordKeyUsage(body.Key, keyOpEncrypt)
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret '%s' encrypted with key '%s' stored", req.Resource, body.Key),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// getSecret decrypts a secret and returns its value.
+func (s *Server) getSecret(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "secret name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	secret, err := s.state.Load().Secrets.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read secret")
+		return
+	}
+	key, err := s.state.Load().Keys.GetFor(req.Context(), secret.Key, secret.Ciphertext)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageDecrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+	value, err := key.Decrypt(secret.Ciphertext, []byte(req.Resource))
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to decrypt secret")
+		return
+	}
+	s.recordKeyUsage(secret.Key, keyOpDecrypt)
+
+	api.ReplyWith(resp, http.StatusOK, api.GetSecretResponse{
+		Value:     value,
+		Key:       secret.Key,
+		CreatedAt: secret.CreatedAt,
+		CreatedBy: secret.CreatedBy.String(),
+	})
+}
+
+// listSecrets lists the names of all secrets that match the
+// listing pattern. Unlike the key listing, the response contains
+// all names since there are usually only a few secrets.
+func (s *Server) listSecrets(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+
+	prefix := strings.TrimSuffix(req.Resource, "*")
+	names, err := s.state.Load().Secrets.List(req.Context(), prefix)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to list secrets")
+		return
+	}
+	slices.Sort(names)
+	api.ReplyWith(resp, http.StatusOK, api.ListSecretsResponse{
+		Names: names,
+	})
+}
+
+// deleteSecret deletes a secret permanently.
+func (s *Server) deleteSecret(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "secret name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	s.secretLock.Lock()
+	defer s.secretLock.Unlock()
+
+	if err := s.state.Load().Secrets.Delete(req.Context(), req.Resource); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to delete secret")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret '%s' deleted", req.Resource),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// hideSecrets returns a KeyStore that hides the KeyStore
+// entries containing secrets.
+func hideSecrets(store KeyStore) KeyStore { return &secretKeyStore{store: store} }
+
+// secretKeyStore is a KeyStore that hides all entries whose
+// names start with the secretPrefix.
+type secretKeyStore struct {
+	store KeyStore
+}
+
+var _ KeyStore = (*secretKeyStore)(nil) // compiler check
+
+func (ks *secretKeyStore) String() string { return fmt.Sprint(ks.store) }
+
+// Unwrap returns the underlying KeyStore.
+func (ks *secretKeyStore) Unwrap() KeyStore { return ks.store }
+
+func (ks *secretKeyStore) Close() error { return ks.store.Close() }
+
+func (ks *secretKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	return ks.store.Status(ctx)
+}
+
+func (ks *secretKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	if strings.HasPrefix(name, secretPrefix) {
+		return kes.ErrKeyExists
+	}
+	return ks.store.Create(ctx, name, value)
+}
+
+func (ks *secretKeyStore) Delete(ctx context.Context, name string) error {
+	if strings.HasPrefix(name, secretPrefix) {
+		return kes.ErrKeyNotFound
+	}
+	return ks.store.Delete(ctx, name)
+}
+
+func (ks *secretKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	if strings.HasPrefix(name, secretPrefix) {
+		return nil, kes.ErrKeyNotFound
+	}
+	return ks.store.Get(ctx, name)
+}
+
+func (ks *secretKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	names, next, err := ks.store.List(ctx, prefix, n)
+	if err != nil {
+		return nil, "", err
+	}
+	return slices.DeleteFunc(names, func(name string) bool {
+		return strings.HasPrefix(name, secretPrefix)
+	}), next, nil
+}
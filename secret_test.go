@@ -0,0 +1,110 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"net/http"
+	"slices"
+	"testing"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestSecret(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, &Config{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"secrets": {
+				Allow: map[string]kes.Rule{
+					"/v1/key/create/*":     {},
+					"/v1/key/list/*":       {},
+					"/v1/secret/set/app-*": {},
+					"/v1/secret/get/app-*": {},
+					"/v1/secret/list/*":    {},
+					"/v1/secret/delete/*":  {},
+				},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	for i, test := range secretTests {
+		err := sendRequest(ctx, client, http.MethodPut, api.PathSecretSet+test.Name, api.SetSecretRequest{Key: test.Key, Value: test.Value})
+		if test.Status != 0 {
+			if e, ok := api.IsError(err); !ok || e.Status() != test.Status {
+				t.Fatalf("Test %d: got error '%v' - want status %d", i, err, test.Status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to set secret: %v", i, err)
+		}
+
+		var secret api.GetSecretResponse
+		if err = doRequest(ctx, client, http.MethodGet, api.PathSecretGet+test.Name, nil, &secret); err != nil {
+			t.Fatalf("Test %d: failed to get secret: %v", i, err)
+		}
+		if !bytes.Equal(secret.Value, test.Value) {
+			t.Fatalf("Test %d: invalid secret value: got '%s' - want '%s'", i, secret.Value, test.Value)
+		}
+		if secret.Key != test.Key {
+			t.Fatalf("Test %d: invalid secret key: got '%s' - want '%s'", i, secret.Key, test.Key)
+		}
+	}
+
+	// Secrets are not listed as keys.
+	var keys api.ListKeysResponse
+	if err := doRequest(ctx, client, http.MethodGet, api.PathKeyList+"*", nil, &keys); err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.Equal(keys.Names, []string{"my-key"}) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", keys.Names, []string{"my-key"})
+	}
+
+	var secrets api.ListSecretsResponse
+	if err := doRequest(ctx, client, http.MethodGet, api.PathSecretList+"*", nil, &secrets); err != nil {
+		t.Fatalf("Failed to list secrets: %v", err)
+	}
+	if want := []string{"app-db-password", "app-token"}; !slices.Equal(secrets.Names, want) {
+		t.Fatalf("Invalid secret listing: got '%v' - want '%v'", secrets.Names, want)
+	}
+
+	if err := sendRequest(ctx, client, http.MethodDelete, api.PathSecretDelete+"app-token", nil); err != nil {
+		t.Fatalf("Failed to delete secret: %v", err)
+	}
+	err := doRequest(ctx, client, http.MethodGet, api.PathSecretGet+"app-token", nil, &api.GetSecretResponse{})
+	if e, ok := api.IsError(err); !ok || e.Status() != http.StatusNotFound {
+		t.Fatalf("Deleted secret: got error '%v' - want status %d", err, http.StatusNotFound)
+	}
+	err = sendRequest(ctx, client, http.MethodDelete, api.PathSecretDelete+"app-token", nil)
+	if e, ok := api.IsError(err); !ok || e.Status() != http.StatusNotFound {
+		t.Fatalf("Deleting non-existing secret: got error '%v' - want status %d", err, http.StatusNotFound)
+	}
+}
+
+var secretTests = []struct {
+	Name   string
+	Key    string
+	Value  []byte
+	Status int
+}{
+	{Name: "app-db-password", Key: "my-key", Value: []byte("password")},                                     // 0
+	{Name: "app-token", Key: "my-key", Value: []byte("token")},                                              // 1
+	{Name: "app-token", Key: "my-key", Value: []byte("new token")},                                          // 2: replaces the secret
+	{Name: "app-empty", Key: "my-key", Status: http.StatusBadRequest},                                       // 3
+	{Name: "app-unknown-key", Key: "unknown", Value: []byte("password"), Status: http.StatusNotFound},       // 4
+	{Name: "app-large", Key: "my-key", Value: make([]byte, maxSecretSize+1), Status: http.StatusBadRequest}, // 5
+	{Name: "other-secret", Key: "my-key", Value: []byte("password"), Status: http.StatusForbidden},          // 6
+}
@@ -5,34 +5,47 @@
 package kes
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/rand"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 	"os"
+	"path"
 	"runtime"
+	"runtime/pprof"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/minio/kes/internal/api"
-	"github.com/minio/kes/internal/cpu"
+	"github.com/minio/kes/internal/cluster"
 	"github.com/minio/kes/internal/crypto"
 	"github.com/minio/kes/internal/fips"
 	"github.com/minio/kes/internal/headers"
 	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/keystore"
+	"github.com/minio/kes/internal/merkle"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kes/internal/sys"
 	"github.com/minio/kms-go/kes"
 	"github.com/prometheus/common/expfmt"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
 )
 
 // An Identity should uniquely identify a client and
@@ -81,14 +94,108 @@ type Server struct {
 	// Defaults to slog.LevelInfo.
 	AuditLevel slog.LevelVar
 
+	// CipherThroughput is the number of data keys per second
+	// the server generated with its default cipher during a
+	// benchmark, e.g. on startup. If > 0, it is reported by
+	// the status API. It must not be modified once the server
+	// has been started.
+	CipherThroughput float64
+
+	// Reload reloads the server configuration, for example
+	// by reading the config file again and passing it to
+	// Server.Update. It is invoked when a client calls the
+	// reload API and must be set before the server is started.
+	//
+	// If nil, the reload API is not supported and responds
+	// with 501 Not Implemented.
+	Reload func(context.Context) error
+
 	tls     atomic.Pointer[tls.Config]
 	state   atomic.Pointer[serverState]
 	handler atomic.Pointer[http.ServeMux]
 
+	// idempotency records responses of mutating requests.
+	// It is not part of the server state such that retried
+	// requests are de-duplicated across config reloads.
+	idempotency idempotencyCache
+
+	// frozen is set while the server rejects requests that
+	// modify keys, secrets, policies or identities. It is not
+	// part of the server state such that the server stays frozen
+	// across config reloads.
+	frozen atomic.Pointer[freezeInfo]
+
+	// auditSampling is set while the audit events of successful
+	// requests are sampled. Like ErrLevel and AuditLevel, it is
+	// not part of the server state.
+	auditSampling atomic.Pointer[auditSampling]
+
+	// approvals holds requests waiting for approval. It
+	// is not part of the server state such that pending
+	// requests are kept across config reloads.
+	approvals approvalQueue
+
+	// usage tracks the usage statistics of keys. It is
+	// not part of the server state such that statistics
+	// are kept across config reloads.
+	usage keyUsageTracker
+
+	// events queues lifecycle events until they
+	// are published to the notification targets.
+	events chan Event
+
+	// replicateNow triggers a replication run, for
+	// example when a new key has been created.
+	replicateNow chan struct{}
+
+	// rotateLock serializes key rotations such that
+	// concurrent rotations don't drop key versions.
+	rotateLock sync.Mutex
+
+	// secretLock serializes secret updates since a
+	// KeyStore cannot replace entries atomically.
+	secretLock sync.Mutex
+
+	// watchers are the clients subscribed to the
+	// Watch API. Writes are serialized by watchLock.
+	watchLock sync.Mutex
+	watchers  api.Multicast
+
+	// cluster is the cluster member, if the server is part
+	// of a cluster. Key stores are replicated if clustered
+	// is set, even before the member has been started.
+	cluster   atomic.Pointer[cluster.Node]
+	clustered bool
+
+	// clusterPeers are the identities allowed to send cluster
+	// messages and clusterKey encrypts the replicated keys. Both
+	// are set before the cluster member is started.
+	clusterPeers []kes.Identity
+	clusterKey   crypto.SecretKey
+
+	// policies contains the policies created and assigned via
+	// the API. They are applied on top of the config policies
+	// confPolicies. Both are guarded by mu.
+	policies     *policyStore
+	confPolicies map[string]Policy
+
 	mu              sync.Mutex
 	srv             *http.Server
+	grpc            *grpc.Server // nil if the gRPC API is disabled
+	grpcLn          net.Listener
+	metricsSrv      *http.Server // nil if there is no metrics listener
+	metricsLn       net.Listener
+	noHTTP2         bool // Config.HTTP.DisableHTTP2
 	started, closed bool
 	cErr            error
+
+	// shutdown is canceled once Server.Close starts to shut
+	// down the server. Streaming APIs return when it is done.
+	// cancelRequests cancels the contexts of all requests
+	// once they have been drained or the shutdown timed out.
+	shutdown       context.Context
+	cancelShutdown context.CancelFunc
+	cancelRequests context.CancelFunc
 }
 
 // Addr returns the server's listener address, or the
@@ -125,17 +232,38 @@ func (s *Server) UpdateAdmin(admin kes.Identity) error {
 
 	old := s.state.Load()
 	s.state.Store(&serverState{
-		Addr:       old.Addr,
-		StartTime:  old.StartTime,
-		Admin:      admin,
-		Keys:       old.Keys,
-		Policies:   old.Policies,
-		Identities: old.Identities,
-		Metrics:    old.Metrics,
-		Routes:     old.Routes,
-		LogHandler: old.LogHandler,
-		Log:        old.Log,
-		Audit:      old.Audit,
+		Addr:        old.Addr,
+		StartTime:   old.StartTime,
+		Admin:       admin,
+		Revocation:  old.Revocation,
+		ACME:        old.ACME,
+		SPIFFE:      old.SPIFFE,
+		OIDC:        old.OIDC,
+		Seal:        old.Seal,
+		TLSProxy:    old.TLSProxy,
+		Keys:        old.Keys,
+		Policies:    old.Policies,
+		PolicyRules: old.PolicyRules,
+		Identities:  old.Identities,
+		Names:       old.Names,
+		RateLimiter: old.RateLimiter,
+		Metrics:     old.Metrics,
+		Routes:      old.Routes,
+		Telemetry:   old.Telemetry,
+		KeyUsage:    old.KeyUsage,
+		SoftDelete:  old.SoftDelete,
+		Approval:    old.Approval,
+		LogHandler:  old.LogHandler,
+		Log:         old.Log,
+		Audit:       old.Audit,
+
+		AuditCheckpoint: old.AuditCheckpoint,
+		Notifications:   old.Notifications,
+		Replication:     old.Replication,
+		Replica:         old.Replica,
+		Replicas:        old.Replicas,
+		MetricsPush:     old.MetricsPush,
+		Tracer:          old.Tracer,
 	})
 	return nil
 }
@@ -145,7 +273,7 @@ func (s *Server) UpdateAdmin(admin kes.Identity) error {
 // unchanged. It returns an error if the server
 // has not been started or has been closed.
 func (s *Server) UpdateTLS(conf *tls.Config) error {
-	if conf == nil || (len(conf.Certificates) == 0 && conf.GetCertificate == nil && conf.GetConfigForClient == nil) {
+	if conf == nil {
 		return errors.New("kes: tls config contains no server certificate")
 	}
 	if conf.ClientAuth == tls.NoClientCert {
@@ -162,7 +290,25 @@ func (s *Server) UpdateTLS(conf *tls.Config) error {
 		return errors.New("kes: server not started")
 	}
 
-	s.tls.Store(conf)
+	// Without a certificate, the server keeps using
+	// the one obtained via ACME, if enabled. With a
+	// SPIFFE source, it always uses its X.509 SVID.
+	state := s.state.Load()
+	conf = withSPIFFE(withACME(conf, state.ACME), state.SPIFFE)
+	if len(conf.Certificates) == 0 && conf.GetCertificate == nil && conf.GetConfigForClient == nil {
+		return errors.New("kes: tls config contains no server certificate")
+	}
+	if state.Revocation != nil && conf.ClientAuth != tls.VerifyClientCertIfGiven && conf.ClientAuth != tls.RequireAndVerifyClientCert {
+		return errors.New("kes: certificate revocation checks require verified client certificates")
+	}
+	if state.SPIFFE != nil && conf.ClientAuth != tls.VerifyClientCertIfGiven && conf.ClientAuth != tls.RequireAndVerifyClientCert {
+		return errors.New("kes: SPIFFE identities require verified client certificates")
+	}
+
+	if s.noHTTP2 {
+		conf = withoutHTTP2(conf)
+	}
+	s.tls.Store(withFIPS(conf))
 	return nil
 }
 
@@ -171,11 +317,6 @@ func (s *Server) UpdateTLS(conf *tls.Config) error {
 // unchanged. It returns an error if the server
 // has not been started or has been closed.
 func (s *Server) UpdatePolicies(policies map[string]Policy) error {
-	policySet, identitySet, err := initPolicies(policies)
-	if err != nil {
-		return err
-	}
-
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -187,19 +328,48 @@ func (s *Server) UpdatePolicies(policies map[string]Policy) error {
 	}
 
 	old := s.state.Load()
+	policySet, ruleSet, identitySet, err := initPolicies(s.policies.apply(policies, time.Now()), old.Names)
+	if err != nil {
+		return err
+	}
+	s.policies.annotate(policies, policySet, identitySet)
+	s.confPolicies = maps.Clone(policies)
 	s.state.Store(&serverState{
-		Addr:       old.Addr,
-		StartTime:  old.StartTime,
-		Admin:      old.Admin,
-		Keys:       old.Keys,
-		Policies:   policySet,
-		Identities: identitySet,
-		Metrics:    old.Metrics,
-		Routes:     old.Routes,
-		LogHandler: old.LogHandler,
-		Log:        old.Log,
-		Audit:      old.Audit,
+		Addr:        old.Addr,
+		StartTime:   old.StartTime,
+		Admin:       old.Admin,
+		Revocation:  old.Revocation,
+		ACME:        old.ACME,
+		SPIFFE:      old.SPIFFE,
+		OIDC:        old.OIDC,
+		Seal:        old.Seal,
+		TLSProxy:    old.TLSProxy,
+		Keys:        old.Keys,
+		Policies:    policySet,
+		PolicyRules: ruleSet,
+		Identities:  identitySet,
+		Names:       old.Names,
+		RateLimiter: old.RateLimiter,
+		Metrics:     old.Metrics,
+		Routes:      old.Routes,
+		Telemetry:   old.Telemetry,
+		KeyUsage:    old.KeyUsage,
+		SoftDelete:  old.SoftDelete,
+		Approval:    old.Approval,
+		LogHandler:  old.LogHandler,
+		Log:         old.Log,
+		Audit:       old.Audit,
+
+		AuditCheckpoint: old.AuditCheckpoint,
+		Notifications:   old.Notifications,
+		Replication:     old.Replication,
+		Replica:         old.Replica,
+		Replicas:        old.Replicas,
+		MetricsPush:     old.MetricsPush,
+		Tracer:          old.Tracer,
 	})
+	s.notify(policyEvents(old.Policies, policySet)...)
+	s.notify(identityEvents(old.Identities, identitySet, "")...)
 	return nil
 }
 
@@ -216,7 +386,15 @@ func (s *Server) Update(conf *Config) (io.Closer, error) {
 	if err := verifyConfig(conf); err != nil {
 		return nil, err
 	}
-	policySet, identitySet, err := initPolicies(conf.Policies)
+	names, err := newNameRules(conf.Names)
+	if err != nil {
+		return nil, err
+	}
+	revocation, err := newRevocationChecker(conf.Revocation)
+	if err != nil {
+		return nil, err
+	}
+	spiffe, err := newSPIFFEAuth(conf.SPIFFE)
 	if err != nil {
 		return nil, err
 	}
@@ -230,41 +408,111 @@ func (s *Server) Update(conf *Config) (io.Closer, error) {
 	if !s.started {
 		return nil, errors.New("kes: server not started")
 	}
+	if conf.FIPS && !fips.ApprovedOnly() {
+		return nil, errors.New("kes: FIPS mode can only be enabled when starting the server")
+	}
+
+	// Without a policy store config, the policies created and
+	// assigned via the API are kept in memory across reloads.
+	store := s.policies
+	if conf.PolicyStore != nil || store.filename != "" {
+		if store, err = readPolicyStore(conf.PolicyStore); err != nil {
+			return nil, err
+		}
+	}
+	policySet, ruleSet, identitySet, err := initPolicies(store.apply(conf.Policies, time.Now()), names)
+	if err != nil {
+		return nil, err
+	}
+	store.annotate(conf.Policies, policySet, identitySet)
+	s.policies = store
+	s.confPolicies = maps.Clone(conf.Policies)
 
 	old := s.state.Load()
+	acme := newACMEManager(conf.ACME, conf.Keys)
+	if acme != nil && old.ACME != nil {
+		acme.cert.Store(old.ACME.Certificate()) // Renewed by the new manager if the domains changed
+	}
+	tracer := newTracer(conf.TracerProvider)
+	seal := newSealer(conf.Seal, old.Seal)
+	keyStore := meterKeyStore(traceKeyStore(s.replicateKeyStore(sealKeyStore(configKeyStore(conf), seal)), tracer), old.Metrics)
 	state := &serverState{
-		Addr:       old.Addr,
-		StartTime:  old.StartTime,
-		Admin:      conf.Admin,
-		Keys:       newCache(conf.Keys, conf.Cache),
-		Policies:   policySet,
-		Identities: identitySet,
-		Metrics:    old.Metrics,
+		Addr:        old.Addr,
+		StartTime:   old.StartTime,
+		Admin:       conf.Admin,
+		Revocation:  revocation,
+		ACME:        acme,
+		SPIFFE:      spiffe,
+		OIDC:        newOIDCVerifier(conf.OIDC),
+		Seal:        seal,
+		TLSProxy:    newTLSProxy(conf, spiffe),
+		Keys:        newCache(hideSecrets(keyStore), conf.Cache),
+		Secrets:     &secretStore{store: keyStore},
+		Policies:    policySet,
+		PolicyRules: ruleSet,
+		Identities:  identitySet,
+		Names:       names,
+		RateLimiter: old.RateLimiter,
+		Metrics:     old.Metrics,
+		Telemetry:   conf.Telemetry.clone(),
+		KeyUsage:    conf.KeyUsage.clone(),
+		SoftDelete:  conf.SoftDelete.clone(),
+		Approval:    conf.Approval.clone(),
 
 		LogHandler: old.LogHandler,
 		Log:        old.Log,
 		Audit:      old.Audit,
+
+		AuditCheckpoint: conf.AuditCheckpoint.clone(),
+		Notifications:   slices.Clone(conf.Notifications),
+		Replication:     conf.Replication.clone(),
+		Replica:         conf.Replica.clone(),
+		Replicas:        slices.Clone(conf.ReplicaIdentities),
+		MetricsPush:     conf.MetricsPush.clone(),
+		Tracer:          tracer,
 	}
 
+	var closers []io.Closer // Replaced log handlers that have to be closed
 	if conf.ErrorLog != nil && conf.ErrorLog != state.LogHandler.Handler() {
+		if c, ok := state.LogHandler.Handler().(io.Closer); ok {
+			closers = append(closers, c)
+		}
 		state.LogHandler = &logHandler{
-			h:    conf.ErrorLog,
-			text: state.LogHandler.text,
-			out:  state.LogHandler.out,
+			h:          conf.ErrorLog,
+			level:      state.LogHandler.level,
+			text:       state.LogHandler.text,
+			out:        state.LogHandler.out,
+			recentText: state.LogHandler.recentText,
+			recent:     state.LogHandler.recent,
 		}
 		state.Log = slog.New(state.LogHandler)
 	}
-	if conf.AuditLog != nil {
+	state.Keys.SetLog(state.Log)
+	state.Keys.SetMetrics(state.Metrics)
+	if conf.AuditLog != nil && conf.AuditLog != state.Audit.h {
+		if c, ok := state.Audit.h.(io.Closer); ok {
+			closers = append(closers, c)
+		}
 		state.Audit.h = conf.AuditLog
 	}
+	oldTargets := state.Audit.setTargets(conf.AuditTargets)
 
 	mux, routes := initRoutes(s, conf.Routes, state.Metrics)
 	state.Routes = routes
+	state.Metrics.SetRequestLabeler(s.metricsLabeler(conf.MetricsLabel))
 
-	s.tls.Store(conf.TLS.Clone())
+	s.tls.Store(withFIPS(withSPIFFE(withACME(conf.TLS.Clone(), acme), spiffe)))
 	s.state.Store(state)
 	s.handler.Store(mux)
 
+	s.notify(policyEvents(old.Policies, state.Policies)...)
+	s.notify(identityEvents(old.Identities, state.Identities, "")...)
+	go closeAuditTargets(state.Log, oldTargets, conf.AuditTargets)
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			state.Log.Error(fmt.Sprintf("kes: failed to close log handler: %v", err))
+		}
+	}
 	return old.Keys, nil
 }
 
@@ -273,7 +521,8 @@ func (s *Server) Update(conf *Config) (io.Closer, error) {
 // Accepted connections are configured to enable TCP keep-alives.
 //
 // HTTP/2 support is only enabled if conf.TLS is configured
-// with "h2" in the TLS Config.NextProtos.
+// with "h2" in the TLS Config.NextProtos and conf.HTTP does
+// not disable it.
 //
 // ListenAndStart returns once the server is closed or ctx.Done
 // returns, whatever happens first. It returns the first error
@@ -305,7 +554,8 @@ func (s *Server) ListenAndStart(ctx context.Context, addr string, conf *Config)
 // new service goroutine for each.
 //
 // HTTP/2 support is only enabled if conf.TLS is configured
-// with "h2" in the TLS Config.NextProtos.
+// with "h2" in the TLS Config.NextProtos and conf.HTTP does
+// not disable it.
 //
 // Start returns once the server is closed or ctx.Done returns,
 // whatever happens first. It returns the first error encountered
@@ -323,7 +573,22 @@ func (s *Server) Start(ctx context.Context, ln net.Listener, conf *Config) error
 // It first tries to shutdown the server gracefully
 // by waiting for requests to finish before closing
 // the server forcefully.
+//
+// Once Close is called, the server stops accepting new
+// connections and ends all streaming requests, like the
+// audit log API. The contexts of all other requests are
+// canceled once they have finished or the ShutdownTimeout
+// is exceeded. Afterwards, Close flushes the audit log
+// targets and closes the key store.
 func (s *Server) Close() error {
+	// Stop the cluster member before acquiring the server lock
+	// since it may wait for the lock to apply a change.
+	if node := s.cluster.Swap(nil); node != nil {
+		if err := node.Close(); err != nil {
+			s.state.Load().Log.Error(fmt.Sprintf("kes: failed to stop cluster member: %v", err))
+		}
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -351,16 +616,79 @@ func (s *Server) Close() error {
 		defer cancel()
 	}
 
+	s.cancelShutdown()
+
 	s.cErr = s.srv.Shutdown(ctx)
 	if errors.Is(s.cErr, context.Canceled) || errors.Is(s.cErr, context.DeadlineExceeded) {
 		s.cErr = s.srv.Close()
 	}
+	if s.metricsSrv != nil {
+		if err := s.metricsSrv.Shutdown(ctx); err != nil {
+			s.metricsSrv.Close()
+		}
+	}
+	if s.grpc != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpc.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpc.Stop()
+		}
+	}
+	s.cancelRequests() // Abort requests that are still running after a forced close
+
+	// Emit a final checkpoint such that downstream systems can
+	// tell a server shutdown apart from a truncated audit log.
+	if state := s.state.Load(); state.AuditCheckpoint != nil {
+		if err := state.Audit.Checkpoint(context.Background(), state.AuditCheckpoint.Signer); err != nil {
+			state.Log.Error(err.Error())
+		}
+	}
+	if state := s.state.Load(); state.Audit != nil {
+		closeAuditTargets(state.Log, state.Audit.setTargets(nil), nil)
+		if state.Audit.store != nil {
+			if err := state.Audit.store.Close(); err != nil {
+				state.Log.Error(fmt.Sprintf("kes: failed to close audit store: %v", err))
+			}
+		}
+	}
+	if state := s.state.Load(); state.KeyUsage != nil && state.KeyUsage.Filename != "" {
+		if err := s.usage.WriteFile(state.KeyUsage.Filename); err != nil {
+			state.Log.Error(fmt.Sprintf("kes: failed to write key usage file: %v", err))
+		}
+	}
 	if err := s.state.Load().Keys.Close(); s.cErr == nil {
 		s.cErr = err
 	}
 	return s.cErr
 }
 
+// replicateKeyStore returns a KeyStore that replicates changes
+// to all cluster members if the server is clustered. Otherwise,
+// it returns store.
+func (s *Server) replicateKeyStore(store KeyStore) KeyStore {
+	if !s.clustered {
+		return store
+	}
+	return &clusterKeyStore{server: s, local: store}
+}
+
+// configKeyStore returns the KeyStore the server fetches keys from.
+// A read replica fetches keys from its primary.
+func configKeyStore(conf *Config) KeyStore {
+	if conf.Replica != nil {
+		return newReplicaKeyStore(conf.Replica)
+	}
+	if conf.ACME != nil {
+		return hideACMEKeys(conf.Keys)
+	}
+	return conf.Keys
+}
+
 func (s *Server) serve(ctx context.Context, ln net.Listener, conf *Config) error {
 	listener, err := s.listen(ctx, ln, conf)
 	if err != nil {
@@ -368,12 +696,51 @@ func (s *Server) serve(ctx context.Context, ln net.Listener, conf *Config) error
 	}
 	defer listener.Close()
 
+	// The cluster member is started once the server state exists
+	// since it applies replicated changes to it. The server lock
+	// must not be held since applying changes acquires it.
+	if conf.Cluster != nil {
+		if err = s.startCluster(ctx, conf.Cluster); err != nil {
+			s.Close()
+			return err
+		}
+	}
+
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 	go func() {
 		<-ctx.Done()
 		s.Close()
 	}()
+	go s.reportTelemetry(ctx)
+	go s.emitAuditCheckpoints(ctx)
+	go s.persistKeyUsage(ctx)
+	go s.publishEvents(ctx)
+	go s.replicate(ctx)
+	go s.warmReplicaCache(ctx)
+	go s.pushMetrics(ctx)
+	go s.revokeExpiredIdentities(ctx)
+	go s.purgeDeletedKeys(ctx)
+	go s.rotateScheduledKeys(ctx)
+	go s.reloadCRLs(ctx)
+	go s.renewACMECertificate(ctx)
+	go s.updateSPIFFEBundles(ctx)
+	go s.expireAuditEvents(ctx)
+
+	if s.grpc != nil {
+		go func() {
+			if err := s.grpc.Serve(s.grpcLn); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+				s.state.Load().Log.Error(fmt.Sprintf("kes: failed to serve gRPC API: %v", err))
+			}
+		}()
+	}
+	if s.metricsSrv != nil {
+		go func() {
+			if err := s.metricsSrv.Serve(s.metricsLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.state.Load().Log.Error(fmt.Sprintf("kes: failed to serve metrics listener: %v", err))
+			}
+		}()
+	}
 
 	err = s.srv.Serve(listener)
 	if errors.Is(err, http.ErrServerClosed) {
@@ -383,10 +750,33 @@ func (s *Server) serve(ctx context.Context, ln net.Listener, conf *Config) error
 }
 
 func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net.Listener, error) {
-	policySet, identitySet, err := initPolicies(conf.Policies)
+	names, err := newNameRules(conf.Names)
+	if err != nil {
+		return nil, err
+	}
+	store, err := readPolicyStore(conf.PolicyStore)
+	if err != nil {
+		return nil, err
+	}
+	policySet, ruleSet, identitySet, err := initPolicies(store.apply(conf.Policies, time.Now()), names)
 	if err != nil {
 		return nil, err
 	}
+	store.annotate(conf.Policies, policySet, identitySet)
+	revocation, err := newRevocationChecker(conf.Revocation)
+	if err != nil {
+		return nil, err
+	}
+	spiffe, err := newSPIFFEAuth(conf.SPIFFE)
+	if err != nil {
+		return nil, err
+	}
+	acme := newACMEManager(conf.ACME, conf.Keys)
+	if acme != nil {
+		if err = acme.Load(ctx); err != nil {
+			return nil, err
+		}
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -397,15 +787,55 @@ func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net
 	if s.started {
 		return nil, errors.New("kes: server already started")
 	}
+	if conf.FIPS {
+		fips.Restrict()
+	}
+	s.policies = store
+	s.confPolicies = maps.Clone(conf.Policies)
+	s.events = make(chan Event, eventQueueSize)
+	s.replicateNow = make(chan struct{}, 1)
+	if conf.KeyUsage != nil && conf.KeyUsage.Filename != "" {
+		if err := s.usage.ReadFile(conf.KeyUsage.Filename); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	s.clustered = conf.Cluster != nil
 
+	tracer := newTracer(conf.TracerProvider)
+	metrics := metric.New()
+	seal := newSealer(conf.Seal, nil)
+	keyStore := meterKeyStore(traceKeyStore(s.replicateKeyStore(sealKeyStore(configKeyStore(conf), seal)), tracer), metrics)
 	state := &serverState{
-		Addr:       ln.Addr(),
-		StartTime:  time.Now(),
-		Admin:      conf.Admin,
-		Keys:       newCache(conf.Keys, conf.Cache),
-		Policies:   policySet,
-		Identities: identitySet,
-		Metrics:    metric.New(),
+		Addr:        ln.Addr(),
+		StartTime:   time.Now(),
+		Admin:       conf.Admin,
+		Revocation:  revocation,
+		ACME:        acme,
+		SPIFFE:      spiffe,
+		OIDC:        newOIDCVerifier(conf.OIDC),
+		Seal:        seal,
+		TLSProxy:    newTLSProxy(conf, spiffe),
+		Keys:        newCache(hideSecrets(keyStore), conf.Cache),
+		Secrets:     &secretStore{store: keyStore},
+		Policies:    policySet,
+		PolicyRules: ruleSet,
+		Identities:  identitySet,
+		Names:       names,
+		RateLimiter: &rateLimiter{},
+		Metrics:     metrics,
+		Telemetry:   conf.Telemetry.clone(),
+		KeyUsage:    conf.KeyUsage.clone(),
+		SoftDelete:  conf.SoftDelete.clone(),
+		Approval:    conf.Approval.clone(),
+
+		AuditCheckpoint: conf.AuditCheckpoint.clone(),
+		Notifications:   slices.Clone(conf.Notifications),
+		Replication:     conf.Replication.clone(),
+		Replica:         conf.Replica.clone(),
+		Replicas:        slices.Clone(conf.ReplicaIdentities),
+		MetricsPush:     conf.MetricsPush.clone(),
+		Tracer:          tracer,
 	}
 
 	if conf.ErrorLog == nil {
@@ -419,6 +849,8 @@ func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net
 		state.LogHandler = newLogHandler(conf.ErrorLog, &s.ErrLevel)
 	}
 	state.Log = slog.New(state.LogHandler)
+	state.Keys.SetLog(state.Log)
+	state.Keys.SetMetrics(state.Metrics)
 
 	if conf.AuditLog == nil {
 		handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: &s.AuditLevel})
@@ -426,14 +858,38 @@ func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net
 	} else {
 		state.Audit = newAuditLogger(conf.AuditLog, &s.AuditLevel)
 	}
+	state.Audit.sampling = &s.auditSampling
+	if conf.AuditCheckpoint != nil && conf.AuditCheckpoint.MerkleTree {
+		state.Audit.enableMerkleTree()
+	}
+	if conf.AuditStore != nil {
+		store, err := openAuditStore(conf.AuditStore)
+		if err != nil {
+			return nil, err
+		}
+		state.Audit.enableStore(store)
+	}
+	state.Audit.setTargets(conf.AuditTargets)
 
 	mux, routes := initRoutes(s, conf.Routes, state.Metrics)
 	state.Routes = routes
+	state.Metrics.SetRequestLabeler(s.metricsLabeler(conf.MetricsLabel))
 
-	s.tls.Store(conf.TLS.Clone())
+	s.noHTTP2 = conf.HTTP != nil && conf.HTTP.DisableHTTP2
+	if s.noHTTP2 {
+		s.tls.Store(withFIPS(withSPIFFE(withACME(withoutHTTP2(conf.TLS), acme), spiffe)))
+	} else {
+		s.tls.Store(withFIPS(withSPIFFE(withACME(conf.TLS.Clone(), acme), spiffe)))
+	}
 	s.state.Store(state)
 	s.handler.Store(mux)
 
+	// Requests must not be canceled once ctx is done, e.g. on
+	// SIGTERM, but complete while the server is draining them.
+	reqCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	s.shutdown, s.cancelShutdown = context.WithCancel(reqCtx)
+	s.cancelRequests = cancelRequests
+
 	s.srv = &http.Server{
 		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			s.handler.Load().ServeHTTP(w, r)
@@ -442,11 +898,38 @@ func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net
 		ReadHeaderTimeout: 5 * time.Second,
 		WriteTimeout:      0 * time.Second, // explicitly set no write timeout - api.Route uses http.ResponseController
 		IdleTimeout:       90 * time.Second,
-		BaseContext:       func(net.Listener) context.Context { return ctx },
+		BaseContext:       func(net.Listener) context.Context { return reqCtx },
 		ErrorLog:          slog.NewLogLogger(s.state.Load().LogHandler, slog.LevelInfo), // TODO: wrap
 	}
+	if err := configureHTTP(s.srv, conf.HTTP); err != nil {
+		return nil, err
+	}
+	if conf.MetricsListener != nil {
+		if s.metricsLn, err = s.listenMetrics(conf.MetricsListener); err != nil {
+			return nil, err
+		}
+		s.metricsSrv = &http.Server{
+			Handler:           s.metricsHandler(routes),
+			ReadHeaderTimeout: 5 * time.Second,
+			IdleTimeout:       90 * time.Second,
+			BaseContext:       func(net.Listener) context.Context { return reqCtx },
+			ErrorLog:          slog.NewLogLogger(s.state.Load().LogHandler, slog.LevelInfo),
+		}
+	}
+	if conf.GRPC != nil {
+		if s.grpcLn, err = net.Listen("tcp", conf.GRPC.Addr); err != nil {
+			if s.metricsLn != nil {
+				s.metricsLn.Close()
+			}
+			return nil, err
+		}
+		s.grpc = newGRPCServer(s)
+	}
 	s.started = true
 
+	if conf.ProxyProtocol != nil {
+		ln = https.NewProxyProtocolListener(ln, slices.Clone(conf.ProxyProtocol.TrustedProxies))
+	}
 	return tls.NewListener(ln, &tls.Config{
 		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
 			return s.tls.Load(), nil
@@ -454,6 +937,96 @@ func (s *Server) listen(ctx context.Context, ln net.Listener, conf *Config) (net
 	}), nil
 }
 
+// listenMetrics returns a new listener for the metrics listener
+// config. If TLS is enabled, it uses the current TLS config of
+// the server but does not require client certificates.
+func (s *Server) listenMetrics(conf *MetricsListenerConfig) (net.Listener, error) {
+	ln, err := net.Listen("tcp", conf.Addr)
+	if err != nil {
+		return nil, err
+	}
+	if !conf.TLS {
+		return ln, nil
+	}
+	return tls.NewListener(ln, &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			tlsConf := s.tls.Load().Clone()
+			tlsConf.ClientAuth = tls.NoClientCert
+			tlsConf.VerifyPeerCertificate, tlsConf.VerifyConnection = nil, nil
+			return tlsConf, nil
+		},
+	}), nil
+}
+
+// metricsHandler returns an HTTP handler for the metrics listener.
+// It serves the metrics, health and version APIs of the given routes
+// without authenticating requests.
+func (s *Server) metricsHandler(routes map[string]api.Route) http.Handler {
+	mux := http.NewServeMux()
+	for _, path := range []string{api.PathVersion, api.PathReady, api.PathHealthLive, api.PathHealthReady, api.PathMetrics} {
+		route, ok := routes[path]
+		if !ok {
+			continue
+		}
+		route.Auth = api.InsecureSkipVerify
+		mux.Handle(path, s.traceRoute(route))
+	}
+	return mux
+}
+
+// configureHTTP applies the HTTP config, if not nil, to srv.
+func configureHTTP(srv *http.Server, conf *HTTPConfig) error {
+	if conf == nil {
+		return nil
+	}
+
+	if conf.ReadHeaderTimeout > 0 {
+		srv.ReadHeaderTimeout = conf.ReadHeaderTimeout
+	}
+	if conf.IdleTimeout > 0 {
+		srv.IdleTimeout = conf.IdleTimeout
+	}
+	srv.ReadTimeout = conf.ReadTimeout
+	srv.WriteTimeout = conf.WriteTimeout
+
+	switch {
+	case conf.DisableHTTP2:
+		// A non-nil, empty map disables HTTP/2.
+		srv.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+	case conf.MaxConcurrentStreams > 0:
+		return http2.ConfigureServer(srv, &http2.Server{
+			MaxConcurrentStreams: conf.MaxConcurrentStreams,
+		})
+	}
+	return nil
+}
+
+// withFIPS returns a copy of conf that only offers FIPS 140
+// approved cipher suites and curves if FIPS mode is enabled.
+// Otherwise, it returns conf.
+func withFIPS(conf *tls.Config) *tls.Config {
+	if !fips.ApprovedOnly() {
+		return conf
+	}
+	conf = conf.Clone()
+	conf.CipherSuites = fips.TLSCiphers()
+	conf.CurvePreferences = fips.TLSCurveIDs()
+	if conf.MinVersion < tls.VersionTLS12 {
+		conf.MinVersion = tls.VersionTLS12
+	}
+	return conf
+}
+
+// withoutHTTP2 returns a copy of conf that does not
+// offer HTTP/2 to clients during the TLS handshake.
+func withoutHTTP2(conf *tls.Config) *tls.Config {
+	conf = conf.Clone()
+	conf.NextProtos = slices.DeleteFunc(slices.Clone(conf.NextProtos), func(proto string) bool {
+		return proto == "h2"
+	})
+	return conf
+}
+
 func (s *Server) version(resp *api.Response, req *api.Request) {
 	info, err := sys.ReadBinaryInfo()
 	if err != nil {
@@ -482,19 +1055,86 @@ func (s *Server) ready(resp *api.Response, req *api.Request) {
 	resp.Reply(http.StatusOK)
 }
 
+// live reports whether the server is alive. It succeeds as long
+// as the server is able to handle requests.
+func (s *Server) live(resp *api.Response, req *api.Request) {
+	resp.Reply(http.StatusOK)
+}
+
+// healthReady reports whether the server is ready to handle
+// requests. A server is ready if its TLS certificates are
+// valid, it is not sealed and its keystore is reachable.
+func (s *Server) healthReady(resp *api.Response, req *api.Request) {
+	if seal := s.state.Load().Seal; seal != nil && seal.Sealed() {
+		resp.Fail(http.StatusServiceUnavailable, "server is sealed")
+		return
+	}
+	if acme := s.state.Load().ACME; acme != nil && acme.Certificate() == nil {
+		resp.Fail(http.StatusServiceUnavailable, "server certificate has not been obtained yet")
+		return
+	}
+	if s.state.Load().Keys.Preloading() {
+		resp.Fail(http.StatusServiceUnavailable, "key cache is being preloaded")
+		return
+	}
+	if err := verifyCertificates(s.tls.Load(), time.Now()); err != nil {
+		s.state.Load().Log.WarnContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusServiceUnavailable, "server certificate is not valid")
+		return
+	}
+	s.ready(resp, req)
+}
+
+// verifyCertificates returns an error if any of the certificates
+// in the TLS config is not valid at time t. Certificates loaded
+// dynamically, e.g. via GetCertificate, are not verified.
+func verifyCertificates(conf *tls.Config, t time.Time) error {
+	if conf == nil {
+		return nil
+	}
+	for _, cert := range conf.Certificates {
+		leaf := cert.Leaf
+		if leaf == nil {
+			if len(cert.Certificate) == 0 {
+				continue
+			}
+			var err error
+			if leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+				return fmt.Errorf("kes: failed to parse server certificate: %v", err)
+			}
+		}
+		if t.Before(leaf.NotBefore) {
+			return fmt.Errorf("kes: server certificate '%s' is not valid before %v", leaf.Subject.CommonName, leaf.NotBefore)
+		}
+		if t.After(leaf.NotAfter) {
+			return fmt.Errorf("kes: server certificate '%s' expired at %v", leaf.Subject.CommonName, leaf.NotAfter)
+		}
+	}
+	return nil
+}
+
 func (s *Server) status(resp *api.Response, req *api.Request) {
-	info, err := sys.ReadBinaryInfo()
+	status, err := s.readStatus(req.Context())
 	if err != nil {
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
 		resp.Fail(http.StatusInternalServerError, "failed to read server version")
 		return
 	}
+	api.ReplyWith(resp, http.StatusOK, status)
+}
+
+// readStatus returns the current server status.
+func (s *Server) readStatus(ctx context.Context) (api.StatusResponse, error) {
+	info, err := sys.ReadBinaryInfo()
+	if err != nil {
+		return api.StatusResponse{}, err
+	}
 
 	var (
 		latency     time.Duration
 		unreachable = true
 	)
-	state, err := s.state.Load().Keys.Status(req.Context())
+	state, err := s.state.Load().Keys.Status(ctx)
 	if err == nil {
 		unreachable = false
 		latency = state.Latency.Round(time.Millisecond)
@@ -504,10 +1144,20 @@ func (s *Server) status(resp *api.Response, req *api.Request) {
 		}
 	}
 
+	failover, pending, failures, _ := s.state.Load().Keys.Failover()
+	p50, p90, p99 := s.state.Load().Keys.stats.Percentiles()
+	counters := &s.state.Load().Keys.counters
+	numKeys, numIdentities := s.state.Load().Keys.Count(ctx), 1 // The admin is always an identity
+	for _, entry := range s.state.Load().Identities {
+		if !entry.expired(time.Now()) {
+			numIdentities++
+		}
+	}
+
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
 
-	api.ReplyWith(resp, http.StatusOK, api.StatusResponse{
+	return api.StatusResponse{
 		Version: info.Version,
 		OS:      runtime.GOOS,
 		Arch:    runtime.GOARCH,
@@ -517,141 +1167,389 @@ func (s *Server) status(resp *api.Response, req *api.Request) {
 		UsableCPUs: runtime.GOMAXPROCS(0),
 		HeapAlloc:  memStats.HeapAlloc,
 		StackAlloc: memStats.StackSys,
+		FIPS:       fips.ApprovedOnly(),
+
+		Cipher:           crypto.DefaultSecretKeyType().String(),
+		CipherThroughput: s.CipherThroughput,
 
 		KeyStoreLatency:     latency.Milliseconds(),
 		KeyStoreUnreachable: unreachable,
-	})
+		KeyStoreFailover:    failover,
+
+		KeyStoreFailoverPending:  pending,
+		KeyStoreFailoverFailures: failures,
+
+		KeyStoreType:          s.state.Load().Keys.Type(),
+		KeyStoreOfflinePolicy: s.state.Load().Keys.OfflinePolicy().String(),
+		KeyStoreLastSuccess:   s.state.Load().Keys.stats.LastSuccess(),
+		KeyStoreLatencyP50:    p50.Milliseconds(),
+		KeyStoreLatencyP90:    p90.Milliseconds(),
+		KeyStoreLatencyP99:    p99.Milliseconds(),
+		KeyStoreOffline:       s.state.Load().Keys.Offline(),
+
+		CacheEntries:   s.state.Load().Keys.Len(),
+		CacheHits:      counters.Hits.Load(),
+		CacheMisses:    counters.Misses.Load(),
+		CacheCoalesced: counters.Coalesced.Load(),
+		CacheEvictions: counters.Evictions.Load(),
+
+		NumKeys:       numKeys,
+		NumPolicies:   len(s.state.Load().Policies),
+		NumIdentities: numIdentities,
+
+		Frozen: s.frozen.Load() != nil,
+	}, nil
 }
 
 func (s *Server) metrics(resp *api.Response, req *api.Request) {
 	contentType := expfmt.Negotiate(req.Header)
 	resp.Header().Set(headers.ContentType, string(contentType))
 	resp.WriteHeader(http.StatusOK)
-	s.state.Load().Metrics.EncodeTo(expfmt.NewEncoder(resp, contentType))
+
+	state := s.state.Load()
+	s.updateMetrics(state)
+	state.Metrics.EncodeTo(expfmt.NewEncoder(resp, contentType))
 }
 
-// ListAPIs is a HandlerFunc that sends the list of server API
-// routes to the client.
-func (s *Server) listAPIs(resp *api.Response, _ *api.Request) {
-	routes := s.state.Load().Routes
-	responses := make(api.ListAPIsResponse, 0, len(routes))
-	for _, ro := range routes {
-		responses = append(responses, api.DescribeRouteResponse{
-			Method:  ro.Method,
-			Path:    ro.Path,
-			MaxBody: int64(ro.MaxBody),
-			Timeout: int64(ro.Timeout.Truncate(time.Second).Seconds()),
-		})
+// metricsLabeler returns the RequestLabeler for the given
+// MetricsLabel, or nil if label is empty.
+func (s *Server) metricsLabeler(label MetricsLabel) metric.RequestLabeler {
+	switch label {
+	case MetricsByIdentity:
+		return func(req *api.Request) (string, string) {
+			return req.Identity.String(), ""
+		}
+	case MetricsByPolicy:
+		return func(req *api.Request) (string, string) {
+			return "", s.state.Load().Identities[req.Identity].Name
+		}
+	default:
+		return nil
 	}
-	api.ReplyWith(resp, http.StatusOK, responses)
 }
 
-func (s *Server) createKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
-		return
+// updateMetrics updates the keystore offline, keystore
+// failover, key cache and key usage metrics.
+func (s *Server) updateMetrics(state *serverState) {
+	state.Metrics.SetKeyStoreOffline(state.Keys.Offline())
+	state.Metrics.SetCacheEntries(state.Keys.Len())
+	if failedOver, pending, _, ok := state.Keys.Failover(); ok {
+		state.Metrics.SetKeyStoreFailover(failedOver, pending)
 	}
-
-	var cipher crypto.SecretKeyType
-	if fips.Enabled || cpu.HasAESGCM() {
-		cipher = crypto.AES256
+	if state.KeyUsage != nil {
+		state.Metrics.SetKeyUsage(s.usage.All())
 	} else {
-		cipher = crypto.ChaCha20
+		state.Metrics.SetKeyUsage(nil)
 	}
+}
 
-	key, err := crypto.GenerateSecretKey(cipher, rand.Reader)
+// supportBundle sends a gzip-compressed tar archive containing
+// diagnostic information, like the server status, metrics, recent
+// error logs and goroutine dumps, to the client. The archive contains
+// no secrets. In particular, no key material and no keystore
+// credentials.
+func (s *Server) supportBundle(resp *api.Response, req *api.Request) {
+	type Policy struct {
+		Allow       []string            `json:"allow,omitempty"`
+		Deny        []string            `json:"deny,omitempty"`
+		Keys        map[string][]string `json:"keys,omitempty"` // Key rule patterns and their operations
+		Conditional bool                `json:"conditional,omitempty"`
+		RateLimit   float64             `json:"rate_limit,omitempty"` // Requests per second
+		Context     map[string]string   `json:"context,omitempty"`    // Required encryption context fields
+		SSH         []string            `json:"ssh,omitempty"`        // Allowed SSH principal patterns
+		Cert        []string            `json:"cert,omitempty"`       // Allowed X.509 certificate name patterns
+		Identities  []kes.Identity      `json:"identities,omitempty"`
+	}
+	type Config struct {
+		Admin         kes.Identity                         `json:"admin"`
+		KeyStore      string                               `json:"keystore"`
+		OfflinePolicy string                               `json:"offline_policy"`
+		Policies      map[string]Policy                    `json:"policies,omitempty"`
+		Routes        map[string]api.DescribeRouteResponse `json:"routes"`
+	}
+
+	state := s.state.Load()
+	info, err := sys.ReadBinaryInfo()
 	if err != nil {
-		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to read server version")
 		return
 	}
-	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	status, err := s.readStatus(req.Context())
 	if err != nil {
-		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to read server status")
 		return
 	}
 
-	if err = s.state.Load().Keys.Create(req.Context(), req.Resource, crypto.KeyVersion{
-		Key:       key,
-		HMACKey:   hmac,
-		CreatedAt: time.Now().UTC(),
-		CreatedBy: req.Identity,
-	}); err != nil {
-		if err, ok := api.IsError(err); ok {
-			resp.Failr(err)
-			return
+	config := Config{
+		Admin:         state.Admin,
+		KeyStore:      state.Keys.Type(),
+		OfflinePolicy: state.Keys.OfflinePolicy().String(),
+		Policies:      make(map[string]Policy, len(state.Policies)),
+		Routes:        make(map[string]api.DescribeRouteResponse, len(state.Routes)),
+	}
+	for name, policy := range state.Policies {
+		p := Policy{
+			Conditional: state.PolicyRules[name].Conditions != nil,
+		}
+		if limit := state.PolicyRules[name].RateLimit; limit != nil {
+			p.RateLimit = limit.Rate
+		}
+		if context := state.PolicyRules[name].Context; context != nil {
+			p.Context = context.Fields
+		}
+		if ssh := state.PolicyRules[name].SSH; ssh != nil {
+			p.SSH = ssh.Principals
+		}
+		if cert := state.PolicyRules[name].Cert; cert != nil {
+			p.Cert = cert.Names
+		}
+		for _, rule := range state.PolicyRules[name].Keys {
+			if p.Keys == nil {
+				p.Keys = make(map[string][]string)
+			}
+			p.Keys[rule.Pattern] = append(p.Keys[rule.Pattern], rule.Operations...)
+		}
+		for path := range policy.Allow {
+			p.Allow = append(p.Allow, path)
+		}
+		for path := range policy.Deny {
+			p.Deny = append(p.Deny, path)
+		}
+		slices.Sort(p.Allow)
+		slices.Sort(p.Deny)
+		config.Policies[name] = p
+	}
+	for id, entry := range state.Identities {
+		if p, ok := config.Policies[entry.Name]; ok {
+			p.Identities = append(p.Identities, id)
+			config.Policies[entry.Name] = p
+		}
+	}
+	for path, ro := range state.Routes {
+		config.Routes[path] = api.DescribeRouteResponse{
+			Method:  ro.Method,
+			Path:    ro.Path,
+			MaxBody: int64(ro.MaxBody),
+			Timeout: int64(ro.Timeout.Truncate(time.Second).Seconds()),
 		}
-
-		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadGateway, "failed to create key")
-		return
 	}
 
-	const StatusOK = http.StatusOK
-	s.state.Load().Audit.Log(
-		fmt.Sprintf("secret key '%s' created", req.Resource),
-		StatusOK,
-		req,
+	var (
+		version    bytes.Buffer
+		statusJSON bytes.Buffer
+		configJSON bytes.Buffer
+		metrics    bytes.Buffer
+		errorLog   bytes.Buffer
+		goroutines bytes.Buffer
 	)
-	resp.Reply(StatusOK)
-}
+	json.NewEncoder(&version).Encode(info)
+	encoder := json.NewEncoder(&statusJSON)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(status)
+	encoder = json.NewEncoder(&configJSON)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(config)
+	s.updateMetrics(state)
+	state.Metrics.EncodeTo(expfmt.NewEncoder(&metrics, expfmt.NewFormat(expfmt.TypeTextPlain)))
+	state.LogHandler.recent.WriteTo(&errorLog)
+	pprof.Lookup("goroutine").WriteTo(&goroutines, 2)
+
+	resp.Header().Set(headers.ContentType, headers.ContentTypeGzip)
+	resp.WriteHeader(http.StatusOK)
 
-func (s *Server) importKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+	gz := gzip.NewWriter(resp)
+	archive := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range []struct {
+		Name string
+		Data *bytes.Buffer
+	}{
+		{Name: "version.json", Data: &version},
+		{Name: "status.json", Data: &statusJSON},
+		{Name: "config.json", Data: &configJSON},
+		{Name: "metrics.txt", Data: &metrics},
+		{Name: "error.log", Data: &errorLog},
+		{Name: "goroutines.txt", Data: &goroutines},
+	} {
+		err = archive.WriteHeader(&tar.Header{
+			Name:    file.Name,
+			Mode:    0o644,
+			Size:    int64(file.Data.Len()),
+			ModTime: now,
+		})
+		if err != nil {
+			return
+		}
+		if _, err = file.Data.WriteTo(archive); err != nil {
+			return
+		}
+	}
+	if err = archive.Close(); err != nil {
 		return
 	}
+	gz.Close()
+}
 
-	var imp api.ImportKeyRequest
-	if err := api.ReadBody(req, &imp); err != nil {
+func (s *Server) reload(resp *api.Response, req *api.Request) {
+	if s.Reload == nil {
+		resp.Fail(http.StatusNotImplemented, "reloading the server configuration is not supported")
+		return
+	}
+
+	// The reload replaces the server state. Hence, log errors
+	// and audit events using the state of the request.
+	state := s.state.Load()
+	if err := s.Reload(req.Context()); err != nil {
+		state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Failf(http.StatusInternalServerError, "failed to reload server configuration: %v", err)
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	state.Audit.Log("server configuration reloaded", StatusOK, req)
+	resp.Reply(StatusOK)
+}
+
+// unseal adds the root key share sent by the client and unseals
+// the server once enough shares have been provided. Requests
+// without a share only return the current seal status.
+func (s *Server) unseal(resp *api.Response, req *api.Request) {
+	var unseal api.UnsealRequest
+	if err := api.ReadBody(req, &unseal); err != nil && err != io.EOF {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
 			return
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadRequest, "invalid import key request body")
+		resp.Fail(http.StatusBadRequest, "invalid unseal request body")
 		return
 	}
 
-	var cipher crypto.SecretKeyType
-	switch imp.Cipher {
-	case "AES256", "AES256-GCM_SHA256":
-		cipher = crypto.AES256
-	case "ChaCha20", "XCHACHA20-POLY1305":
-		if fips.Enabled {
-			resp.Failf(http.StatusNotAcceptable, "algorithm '%s' not supported by FIPS 140-2", imp.Cipher)
+	const StatusOK = http.StatusOK
+	state := s.state.Load()
+	if state.Seal == nil {
+		api.ReplyWith(resp, StatusOK, api.UnsealResponse{Sealed: false})
+		return
+	}
+	if len(unseal.Share) > 0 && state.Seal.Sealed() {
+		unsealed, err := state.Seal.Unseal(unseal.Share)
+		if err != nil {
+			state.Audit.Log("unseal share rejected: "+err.Error(), http.StatusBadRequest, req)
+			if err, ok := api.IsError(err); ok {
+				resp.Failr(err)
+				return
+			}
+			state.Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusInternalServerError, "failed to unseal server")
 			return
 		}
-		cipher = crypto.ChaCha20
-	default:
-		resp.Failf(http.StatusNotAcceptable, "algorithm '%s' is not supported", imp.Cipher)
+		if unsealed {
+			state.Audit.Log("server unsealed", StatusOK, req)
+		} else {
+			state.Audit.Log(fmt.Sprintf("unseal share provided: %d of %d", state.Seal.Progress(), state.Seal.Threshold()), StatusOK, req)
+		}
+	}
+	api.ReplyWith(resp, StatusOK, api.UnsealResponse{
+		Sealed:    state.Seal.Sealed(),
+		Threshold: state.Seal.Threshold(),
+		Progress:  state.Seal.Progress(),
+	})
+}
+
+// ListAPIs is a HandlerFunc that sends the list of server API
+// routes to the client.
+func (s *Server) listAPIs(resp *api.Response, _ *api.Request) {
+	routes := s.state.Load().Routes
+	responses := make(api.ListAPIsResponse, 0, len(routes))
+	for _, ro := range routes {
+		responses = append(responses, api.DescribeRouteResponse{
+			Method:  ro.Method,
+			Path:    ro.Path,
+			MaxBody: int64(ro.MaxBody),
+			Timeout: int64(ro.Timeout.Truncate(time.Second).Seconds()),
+		})
+	}
+	api.ReplyWith(resp, http.StatusOK, responses)
+}
+
+func (s *Server) createKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	if len(imp.Bytes) != crypto.SecretKeySize {
-		resp.Failf(http.StatusNotAcceptable, "invalid key size for '%s'", imp.Cipher)
+	var create api.CreateKeyRequest
+	if err := api.ReadBody(req, &create); err != nil && err != io.EOF {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid create key request body")
+		return
+	}
+	if err := validTags(create.Tags); err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	usage, err := crypto.ParseKeyUsage(create.Usage)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, "invalid key usage: must be 'encrypt', 'decrypt', 'ssh', 'cert', 'hmac', 'sign', 'verify' or 'ecdh'")
+		return
+	}
+	if !create.ExpiresAt.IsZero() && !create.ExpiresAt.After(time.Now()) {
+		resp.Fail(http.StatusBadRequest, "invalid key expiration: must be in the future")
+		return
+	}
+	rotationInterval := time.Duration(create.RotationInterval) * time.Second
+	if err = validRotationInterval(rotationInterval); err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	key, err := crypto.NewSecretKey(cipher, imp.Bytes)
+	var cipher crypto.SecretKeyType
+	switch {
+	case create.Algorithm != "":
+		if cipher, err = crypto.ParseSecretKeyType(create.Algorithm); err != nil {
+			resp.Failf(http.StatusBadRequest, "invalid key algorithm '%s': must be 'AES256' or 'ChaCha20'", create.Algorithm)
+			return
+		}
+		if cipher != crypto.AES256 && fips.ApprovedOnly() {
+			resp.Failf(http.StatusNotAcceptable, "algorithm '%s' not supported by FIPS 140-2", create.Algorithm)
+			return
+		}
+	default:
+		cipher = crypto.DefaultSecretKeyType()
+	}
+
+	key, err := crypto.GenerateSecretKey(cipher, rand.Reader)
 	if err != nil {
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to create key")
+		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
 		return
 	}
 	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
 	if err != nil {
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to create key")
+		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
 		return
 	}
+
 	if err = s.state.Load().Keys.Create(req.Context(), req.Resource, crypto.KeyVersion{
 		Key:       key,
 		HMACKey:   hmac,
 		CreatedAt: time.Now().UTC(),
 		CreatedBy: req.Identity,
+		Tags:      create.Tags,
+		Usage:     usage,
+		ExpiresAt: create.ExpiresAt.UTC(),
+		Derived:   create.Derived,
+
+		RotationInterval: rotationInterval,
 	}); err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
@@ -663,6 +1561,13 @@ func (s *Server) importKey(resp *api.Response, req *api.Request) {
 		return
 	}
 
+	s.notify(Event{
+		Type:     EventKeyCreated,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
 	const StatusOK = http.StatusOK
 	s.state.Load().Audit.Log(
 		fmt.Sprintf("secret key '%s' created", req.Resource),
@@ -672,101 +1577,145 @@ func (s *Server) importKey(resp *api.Response, req *api.Request) {
 	resp.Reply(StatusOK)
 }
 
-func (s *Server) describeKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+func (s *Server) importKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
-	if err != nil {
+	var imp api.ImportKeyRequest
+	if err := api.ReadBody(req, &imp); err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
 			return
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadGateway, "failed to read key")
+		resp.Fail(http.StatusBadRequest, "invalid import key request body")
 		return
 	}
 
-	api.ReplyWith(resp, http.StatusOK, api.DescribeKeyResponse{
-		Name:      req.Resource,
-		Algorithm: key.Key.Type().String(),
-		CreatedAt: key.CreatedAt,
-		CreatedBy: key.CreatedBy.String(),
-	})
-}
+	var cipher crypto.SecretKeyType
+	switch imp.Cipher {
+	case "AES256", "AES256-GCM_SHA256":
+		cipher = crypto.AES256
+	case "ChaCha20", "XCHACHA20-POLY1305":
+		if fips.ApprovedOnly() {
+			resp.Failf(http.StatusNotAcceptable, "algorithm '%s' not supported by FIPS 140-2", imp.Cipher)
+			return
+		}
+		cipher = crypto.ChaCha20
+	default:
+		resp.Failf(http.StatusNotAcceptable, "algorithm '%s' is not supported", imp.Cipher)
+		return
+	}
 
-func (s *Server) listKeys(resp *api.Response, req *api.Request) {
-	if !validPattern(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+	if err := validTags(imp.Tags); err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	usage, err := crypto.ParseKeyUsage(imp.Usage)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, "invalid key usage: must be 'encrypt', 'decrypt', 'ssh', 'cert', 'hmac', 'sign', 'verify' or 'ecdh'")
+		return
+	}
+	rotationInterval := time.Duration(imp.RotationInterval) * time.Second
+	if err = validRotationInterval(rotationInterval); err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
 		return
 	}
 
-	prefix := req.Resource
-	if prefix == "*" {
-		prefix = ""
+	if len(imp.Bytes) != crypto.SecretKeySize {
+		resp.Failf(http.StatusNotAcceptable, "invalid key size for '%s'", imp.Cipher)
+		return
 	}
 
-	names, prefix, err := s.state.Load().Keys.List(req.Context(), prefix, -1)
+	key, err := crypto.NewSecretKey(cipher, imp.Bytes)
 	if err != nil {
-		if err, ok := api.IsError(err); ok {
-			resp.Failr(err)
-			return
-		}
-
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadGateway, "failed to list keys")
+		resp.Fail(http.StatusInternalServerError, "failed to create key")
+		return
 	}
-
-	api.ReplyWith(resp, http.StatusOK, api.ListKeysResponse{
-		Names:      names,
-		ContinueAt: prefix,
-	})
-}
-
-func (s *Server) deleteKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+	hmac, err := crypto.GenerateHMACKey(crypto.SHA256, rand.Reader)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to create key")
 		return
 	}
+	if err = s.state.Load().Keys.Create(req.Context(), req.Resource, crypto.KeyVersion{
+		Key:       key,
+		HMACKey:   hmac,
+		CreatedAt: time.Now().UTC(),
+		CreatedBy: req.Identity,
+		Tags:      imp.Tags,
+		Usage:     usage,
+		ExpiresAt: imp.ExpiresAt.UTC(),
+		Version:   imp.Version,
+		Derived:   imp.Derived,
 
-	if err := s.state.Load().Keys.Delete(req.Context(), req.Resource); err != nil {
+		RotationInterval: rotationInterval,
+	}); err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
 			return
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadGateway, "failed to delete key")
+		resp.Fail(http.StatusBadGateway, "failed to create key")
 		return
 	}
 
+	s.notify(Event{
+		Type:     EventKeyCreated,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
 	const StatusOK = http.StatusOK
 	s.state.Load().Audit.Log(
-		fmt.Sprintf("secret key '%s' deleted", req.Resource),
+		fmt.Sprintf("secret key '%s' created", req.Resource),
 		StatusOK,
 		req,
 	)
-	resp.Reply(http.StatusOK)
+	resp.Reply(StatusOK)
 }
 
-func (s *Server) encryptKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+// exportKey returns the key, including all previous versions,
+// wrapped by the KEK specified in the request body. The name
+// of the exported key is bound to the wrapped key such that it
+// can only be restored under the same name.
+//
+// Any identity that can decrypt with the KEK can unwrap the
+// exported key. Hence, the KEK should only be used for exports.
+func (s *Server) exportKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	var enc api.EncryptKeyRequest
-	if err := api.ReadBody(req, &enc); err != nil {
+	var exp api.ExportKeyRequest
+	if err := api.ReadBody(req, &exp); err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
 			return
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadRequest, "invalid request body")
+		resp.Fail(http.StatusBadRequest, "invalid export key request body")
+		return
+	}
+	if !s.state.Load().Names.ValidName(exp.KEK) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", exp.KEK)
+		return
+	}
+	if exp.KEK == req.Resource {
+		resp.Fail(http.StatusBadRequest, "key cannot be wrapped by itself")
+		return
+	}
+
+	if err := s.state.Load().Keys.CheckEncrypt(); err != nil {
+		resp.Failr(err)
 		return
 	}
 
@@ -781,39 +1730,7 @@ func (s *Server) encryptKey(resp *api.Response, req *api.Request) {
 		resp.Fail(http.StatusBadGateway, "failed to read key")
 		return
 	}
-	ciphertext, err := key.Key.Encrypt(enc.Plaintext, enc.Context)
-	if err != nil {
-		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to encrypt plaintext")
-		return
-	}
-
-	api.ReplyWith(resp, http.StatusOK, api.EncryptKeyResponse{
-		Ciphertext: ciphertext,
-	})
-}
-
-func (s *Server) generateKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
-		return
-	}
-
-	var gen api.GenerateKeyRequest
-	if req.ContentLength > 0 {
-		if err := api.ReadBody(req, &gen); err != nil {
-			if err, ok := api.IsError(err); ok {
-				resp.Failr(err)
-				return
-			}
-
-			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-			resp.Fail(http.StatusBadRequest, "invalid request body")
-			return
-		}
-	}
-
-	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	kek, err := s.state.Load().Keys.Get(req.Context(), exp.KEK)
 	if err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
@@ -824,45 +1741,73 @@ func (s *Server) generateKey(resp *api.Response, req *api.Request) {
 		resp.Fail(http.StatusBadGateway, "failed to read key")
 		return
 	}
+	if err := checkKeyConstraints(&kek, crypto.UsageEncrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
 
-	dataKey := make([]byte, 32)
-	if _, err = rand.Read(dataKey); err != nil {
+	plaintext, err := crypto.EncodeKeyVersion(key)
+	if err != nil {
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		resp.Fail(http.StatusInternalServerError, "failed to export key")
 		return
 	}
-	ciphertext, err := key.Key.Encrypt(dataKey, gen.Context)
+	wrapped, err := kek.Encrypt(plaintext, []byte(req.Resource))
 	if err != nil {
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		resp.Fail(http.StatusInternalServerError, "failed to export key")
 		return
 	}
 
-	api.ReplyWith(resp, http.StatusOK, api.GenerateKeyResponse{
-		Plaintext:  dataKey,
-		Ciphertext: ciphertext,
+	s.recordKeyUsage(exp.KEK, keyOpEncrypt)
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' exported wrapped by '%s'", req.Resource, exp.KEK),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.ExportKeyResponse{
+		Name:      req.Resource,
+		KEK:       exp.KEK,
+		Key:       wrapped,
+		Algorithm: key.Key.Type().String(),
+		CreatedAt: key.CreatedAt,
+		CreatedBy: key.CreatedBy.String(),
+		Tags:      key.Tags,
+		Version:   key.Version,
 	})
 }
 
-func (s *Server) decryptKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+// restoreKey unwraps a key exported by exportKey and creates
+// it, including all previous versions, under the same name.
+//
+// If the key exists, restoreKey updates it if the restored
+// key is a newer version of it, e.g. because the key has been
+// rotated since an earlier export. Otherwise, it fails.
+func (s *Server) restoreKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	var enc api.DecryptKeyRequest
-	if err := api.ReadBody(req, &enc); err != nil {
+	var res api.RestoreKeyRequest
+	if err := api.ReadBody(req, &res); err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
 			return
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadRequest, "invalid request body")
+		resp.Fail(http.StatusBadRequest, "invalid restore key request body")
+		return
+	}
+	if !s.state.Load().Names.ValidName(res.KEK) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", res.KEK)
 		return
 	}
 
-	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	kek, err := s.state.Load().Keys.Get(req.Context(), res.KEK)
 	if err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
@@ -873,7 +1818,11 @@ func (s *Server) decryptKey(resp *api.Response, req *api.Request) {
 		resp.Fail(http.StatusBadGateway, "failed to read key")
 		return
 	}
-	plaintext, err := key.Key.Decrypt(enc.Ciphertext, enc.Context)
+	if err := checkKeyConstraints(&kek, crypto.UsageDecrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+	plaintext, err := kek.Decrypt(res.Key, []byte(req.Resource))
 	if err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
@@ -881,34 +1830,914 @@ func (s *Server) decryptKey(resp *api.Response, req *api.Request) {
 		}
 
 		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusInternalServerError, "failed to decrypt ciphertext")
+		resp.Fail(http.StatusInternalServerError, "failed to unwrap key")
 		return
 	}
+	s.recordKeyUsage(res.KEK, keyOpDecrypt)
 
-	api.ReplyWith(resp, http.StatusOK, api.DecryptKeyResponse{
-		Plaintext: plaintext,
-	})
-}
-
-func (s *Server) hmacKey(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+	key, err := crypto.ParseKeyVersion(plaintext)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, "invalid wrapped key")
 		return
 	}
 
-	var body api.HMACRequest
-	if err := api.ReadBody(req, &body); err != nil {
-		if err, ok := api.IsError(err); ok {
-			resp.Failr(err)
-			return
+	event := EventKeyCreated
+	err = s.state.Load().Keys.Create(req.Context(), req.Resource, key)
+	if errors.Is(err, kes.ErrKeyExists) {
+		var old crypto.KeyVersion
+		if old, err = s.state.Load().Keys.Get(req.Context(), req.Resource); err == nil {
+			if !isNewerVersion(&key, &old) {
+				resp.Failr(kes.ErrKeyExists)
+				return
+			}
+			event = EventKeyRotated
+			err = s.state.Load().Keys.Replace(req.Context(), req.Resource, old, key)
 		}
-
-		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
-		resp.Fail(http.StatusBadRequest, "invalid request body")
-		return
 	}
-
-	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to create key")
+		return
+	}
+
+	s.notify(Event{
+		Type:     event,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' restored at version %d", req.Resource, key.Number()),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// isNewerVersion reports whether key is a newer version of
+// old. This is the case if one of the previous versions of
+// key has the same version number and key material as old.
+func isNewerVersion(key, old *crypto.KeyVersion) bool {
+	if key.Number() <= old.Number() {
+		return false
+	}
+	for _, v := range key.Previous {
+		if v.Number() == old.Number() {
+			return v.Key == old.Key
+		}
+	}
+	return false
+}
+
+func (s *Server) rotateKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	s.rotateLock.Lock()
+	defer s.rotateLock.Unlock()
+
+	old, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+
+	secret, err := crypto.GenerateSecretKey(old.Key.Type(), rand.Reader)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to rotate key")
+		return
+	}
+	if !old.HasHMACKey() { // Keys created in the past have no HMAC key
+		if old.HMACKey, err = crypto.GenerateHMACKey(crypto.SHA256, rand.Reader); err != nil {
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusInternalServerError, "failed to rotate key")
+			return
+		}
+	}
+	key := old.Rotate(secret, time.Now().UTC(), req.Identity)
+	if err = s.state.Load().Keys.Replace(req.Context(), req.Resource, old, key); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to rotate key")
+		return
+	}
+
+	s.notify(Event{
+		Type:     EventKeyRotated,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' rotated to version %d", req.Resource, key.Number()),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.RotateKeyResponse{
+		Version: key.Number(),
+	})
+}
+
+func (s *Server) describeKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+
+	var versions []api.KeyVersion
+	if len(key.Previous) > 0 {
+		for _, v := range key.Versions() {
+			versions = append(versions, api.KeyVersion{
+				Version:   v.Number(),
+				Algorithm: v.Key.Type().String(),
+				CreatedAt: v.CreatedAt,
+				CreatedBy: v.CreatedBy.String(),
+			})
+		}
+	}
+	api.ReplyWith(resp, http.StatusOK, api.DescribeKeyResponse{
+		Name:      req.Resource,
+		Algorithm: key.Key.Type().String(),
+		CreatedAt: key.CreatedAt,
+		CreatedBy: key.CreatedBy.String(),
+		Tags:      key.Tags,
+		Usage:     s.keyUsage(req.Resource),
+		Version:   key.Number(),
+		Versions:  versions,
+
+		Operations: key.Usage.Strings(),
+		ExpiresAt:  key.ExpiresAt,
+
+		RotationInterval: int64(key.RotationInterval.Seconds()),
+		NextRotation:     key.NextRotation(),
+
+		Derived: key.Derived,
+	})
+}
+
+func (s *Server) listKeys(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+	if len(req.URL.Query()) > 0 {
+		s.listKeyPage(resp, req)
+		return
+	}
+
+	// Without any query parameters, the listing follows the
+	// legacy protocol: the response contains at most 1024 names
+	// and clients continue at the returned name.
+	prefix := strings.TrimSuffix(req.Resource, "*")
+	names, _, err := s.state.Load().Keys.List(req.Context(), prefix, -1)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to list keys")
+		return
+	}
+	names, continueAt, _ := keystore.List(names, prefix, 0)
+	if s.state.Load().SoftDelete != nil {
+		if names, err = s.withoutDeletedKeys(req.Context(), names); err != nil {
+			if err, ok := api.IsError(err); ok {
+				resp.Failr(err)
+				return
+			}
+
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusBadGateway, "failed to list keys")
+			return
+		}
+	}
+
+	api.ReplyWith(resp, http.StatusOK, api.ListKeysResponse{
+		Names:      names,
+		ContinueAt: continueAt,
+	})
+}
+
+// listKeyPage replies with one page of key names that match
+// the listing prefix and the optional 'match' glob pattern,
+// sorted in 'order'. The page starts at the 'continue' name,
+// inclusive, and contains at most 'limit' names. The response
+// contains the name of the next page, if any.
+func (s *Server) listKeyPage(resp *api.Response, req *api.Request) {
+	const (
+		DefaultLimit = 1000
+		MaxLimit     = 10000
+	)
+
+	page, err := parseListPage(req.URL.Query(), DefaultLimit, MaxLimit)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	prefix := strings.TrimSuffix(req.Resource, "*")
+	names, _, err := s.state.Load().Keys.List(req.Context(), prefix, -1)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to list keys")
+		return
+	}
+
+	names, continueAt := page.Apply(names)
+	if s.state.Load().SoftDelete != nil {
+		if names, err = s.withoutDeletedKeys(req.Context(), names); err != nil {
+			if err, ok := api.IsError(err); ok {
+				resp.Failr(err)
+				return
+			}
+
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusBadGateway, "failed to list keys")
+			return
+		}
+	}
+
+	api.ReplyWith(resp, http.StatusOK, api.ListKeysResponse{
+		Names:      names,
+		ContinueAt: continueAt,
+	})
+}
+
+func (s *Server) listKeyInfos(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+	s.replyKeyInfos(resp, req, keyFilter{})
+}
+
+func (s *Server) searchKeys(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+	filter, err := parseKeyFilter(req.URL.Query())
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	s.replyKeyInfos(resp, req, filter)
+}
+
+// replyKeyInfos sends the metadata of all keys, that match the
+// request's listing pattern and the filter, sorted by name to
+// the client.
+//
+// A response contains at most 100 keys, starting at the name
+// specified by the 'continue' query parameter, and the name
+// from which the next request should continue, if any.
+func (s *Server) replyKeyInfos(resp *api.Response, req *api.Request, filter keyFilter) {
+	const (
+		// PageSize is the max. number of keys per response. Each
+		// key has to be fetched from the keystore, unless cached,
+		// so the page size is much smaller than for the ListKeys API.
+		PageSize = 100
+
+		// MaxScan is the max. number of keys fetched per request,
+		// including keys that don't match the filter.
+		MaxScan = 1000
+	)
+
+	prefix := strings.TrimSuffix(req.Resource, "*")
+	names, _, err := s.state.Load().Keys.List(req.Context(), prefix, -1)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to list keys")
+		return
+	}
+	slices.Sort(names)
+	if continueAt := req.URL.Query().Get("continue"); continueAt != "" {
+		i, _ := slices.BinarySearch(names, continueAt)
+		names = names[i:]
+	}
+
+	var (
+		keys       = make([]api.DescribeKeyResponse, 0, min(len(names), PageSize))
+		continueAt string
+	)
+	for i, name := range names {
+		if len(keys) == PageSize || i == MaxScan {
+			continueAt = name
+			break
+		}
+
+		key, err := s.state.Load().Keys.Get(req.Context(), name)
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			continue // Key has been deleted concurrently
+		}
+		if err != nil {
+			if err, ok := api.IsError(err); ok {
+				resp.Failr(err)
+				return
+			}
+
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusBadGateway, "failed to read key")
+			return
+		}
+		if !filter.Match(&key) {
+			continue
+		}
+		keys = append(keys, api.DescribeKeyResponse{
+			Name:      name,
+			Algorithm: key.Key.Type().String(),
+			CreatedAt: key.CreatedAt,
+			CreatedBy: key.CreatedBy.String(),
+			Tags:      key.Tags,
+			Usage:     s.keyUsage(name),
+
+			Operations: key.Usage.Strings(),
+			ExpiresAt:  key.ExpiresAt,
+
+			RotationInterval: int64(key.RotationInterval.Seconds()),
+			NextRotation:     key.NextRotation(),
+
+			Derived: key.Derived,
+		})
+	}
+
+	api.ReplyWith(resp, http.StatusOK, api.ListKeyInfosResponse{
+		Keys:       keys,
+		ContinueAt: continueAt,
+	})
+}
+
+func (s *Server) deleteKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+	if conf := s.state.Load().SoftDelete; conf != nil {
+		s.softDeleteKey(resp, req, conf)
+		return
+	}
+
+	if err := s.state.Load().Keys.Delete(req.Context(), req.Resource); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to delete key")
+		return
+	}
+
+	s.usage.Delete(req.Resource)
+	s.notify(Event{
+		Type:     EventKeyDeleted,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' deleted", req.Resource),
+		StatusOK,
+		req,
+	)
+	resp.Reply(http.StatusOK)
+}
+
+func (s *Server) encryptKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var enc api.EncryptKeyRequest
+	if err := api.ReadBody(req, &enc); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if err := s.state.Load().Keys.CheckEncrypt(); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageEncrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+	ciphertext, err := key.Encrypt(enc.Plaintext, enc.Context)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to encrypt plaintext")
+		return
+	}
+
+	s.recordKeyUsage(req.Resource, keyOpEncrypt)
+	s.auditContext(req, "encrypt")
+	api.ReplyWith(resp, http.StatusOK, api.EncryptKeyResponse{
+		Ciphertext: ciphertext,
+	})
+}
+
+func (s *Server) generateKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var gen api.GenerateKeyRequest
+	if req.ContentLength > 0 {
+		if err := api.ReadBody(req, &gen); err != nil {
+			if err, ok := api.IsError(err); ok {
+				resp.Failr(err)
+				return
+			}
+
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	if err := s.state.Load().Keys.CheckEncrypt(); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageEncrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	var dataKey []byte
+	if key.Derived {
+		if len(gen.Context) == 0 {
+			resp.Failf(http.StatusBadRequest, "key '%s' derives data keys and requires a context", req.Resource)
+			return
+		}
+		dataKey, err = key.DeriveKey(gen.Context)
+	} else {
+		dataKey = make([]byte, 32)
+		_, err = rand.Read(dataKey)
+	}
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		return
+	}
+	ciphertext, err := key.Encrypt(dataKey, gen.Context)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to generate encryption key")
+		return
+	}
+
+	s.recordKeyUsage(req.Resource, keyOpGenerate)
+	s.auditContext(req, "generate")
+	api.ReplyWith(resp, http.StatusOK, api.GenerateKeyResponse{
+		Plaintext:  dataKey,
+		Ciphertext: ciphertext,
+	})
+}
+
+func (s *Server) decryptKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var enc api.DecryptKeyRequest
+	if err := api.ReadBody(req, &enc); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	key, err := s.state.Load().Keys.GetFor(req.Context(), req.Resource, enc.Ciphertext)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageDecrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+	plaintext, err := key.Decrypt(enc.Ciphertext, enc.Context)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to decrypt ciphertext")
+		return
+	}
+
+	s.recordKeyUsage(req.Resource, keyOpDecrypt)
+	s.auditContext(req, "decrypt")
+	api.ReplyWith(resp, http.StatusOK, api.DecryptKeyResponse{
+		Plaintext: plaintext,
+	})
+}
+
+// maxBulkItems is the max. number of items within a
+// single bulk encrypt or decrypt request.
+const maxBulkItems = 1000
+
+func (s *Server) bulkEncryptKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var bulk api.BulkEncryptKeyRequest
+	if err := api.ReadBody(req, &bulk); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(bulk.Items) > maxBulkItems {
+		resp.Failf(http.StatusBadRequest, "too many items: at most %d items per request", maxBulkItems)
+		return
+	}
+
+	if err := s.state.Load().Keys.CheckEncrypt(); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageEncrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	results := make([]api.BulkEncryptKeyResult, 0, len(bulk.Items))
+	for _, item := range bulk.Items {
+		ciphertext, err := key.Encrypt(item.Plaintext, item.Context)
+		if err != nil {
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			results = append(results, api.BulkEncryptKeyResult{Error: "failed to encrypt plaintext"})
+			continue
+		}
+		s.recordKeyUsage(req.Resource, keyOpEncrypt)
+		results = append(results, api.BulkEncryptKeyResult{Ciphertext: ciphertext})
+	}
+	s.auditContext(req, "encrypt")
+	api.ReplyWith(resp, http.StatusOK, api.BulkEncryptKeyResponse{
+		Items: results,
+	})
+}
+
+func (s *Server) bulkDecryptKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var bulk api.BulkDecryptKeyRequest
+	if err := api.ReadBody(req, &bulk); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(bulk.Items) > maxBulkItems {
+		resp.Failf(http.StatusBadRequest, "too many items: at most %d items per request", maxBulkItems)
+		return
+	}
+
+	ciphertexts := make([][]byte, 0, len(bulk.Items))
+	for _, item := range bulk.Items {
+		ciphertexts = append(ciphertexts, item.Ciphertext)
+	}
+	key, err := s.state.Load().Keys.GetFor(req.Context(), req.Resource, ciphertexts...)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageDecrypt, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	// A single invalid ciphertext does not fail the entire
+	// request. Instead, the error is reported for this item.
+	results := make([]api.BulkDecryptKeyResult, 0, len(bulk.Items))
+	for _, item := range bulk.Items {
+		plaintext, err := key.Decrypt(item.Ciphertext, item.Context)
+		if err != nil {
+			if err, ok := api.IsError(err); ok {
+				results = append(results, api.BulkDecryptKeyResult{Error: err.Error()})
+				continue
+			}
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			results = append(results, api.BulkDecryptKeyResult{Error: "failed to decrypt ciphertext"})
+			continue
+		}
+		s.recordKeyUsage(req.Resource, keyOpDecrypt)
+		results = append(results, api.BulkDecryptKeyResult{Plaintext: plaintext})
+	}
+	s.auditContext(req, "decrypt")
+	api.ReplyWith(resp, http.StatusOK, api.BulkDecryptKeyResponse{
+		Items: results,
+	})
+}
+
+func (s *Server) hmacKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.HMACRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support HMAC")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageHMAC, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	api.ReplyWith(resp, http.StatusOK, api.HMACResponse{
+		Sum: key.HMACKey.Sum(body.Message),
+	})
+}
+
+// ecdhKey performs an ECDH key agreement between the P-256 key
+// derived from the key's HMAC key and the public key sent by the
+// client. The private key never leaves the server.
+func (s *Server) ecdhKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.ECDHKeyRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support ECDH")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageECDH, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	asymmetricKey := key.AsymmetricKey()
+	defer asymmetricKey.Destroy()
+
+	publicKey, derivedKey, err := asymmetricKey.ECDH(body.PublicKey, body.Info)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, "invalid public key")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, api.ECDHKeyResponse{
+		PublicKey: publicKey,
+		Key:       derivedKey,
+	})
+}
+
+// signKey signs a message with the signing key derived from
+// the key's HMAC key. The signing key never leaves the server.
+func (s *Server) signKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.SignKeyRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	alg, err := parseSignatureAlgorithm(body.Algorithm)
+	if err != nil {
+		resp.Failf(http.StatusBadRequest, "invalid signature algorithm '%s'", body.Algorithm)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support signing")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageSign, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	asymmetricKey := key.AsymmetricKey()
+	defer asymmetricKey.Destroy()
+
+	signature, publicKey, err := asymmetricKey.Sign(alg, body.Message)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to sign message")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, api.SignKeyResponse{
+		Signature: signature,
+		PublicKey: publicKey,
+	})
+}
+
+func (s *Server) verifyKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.VerifyKeyRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	alg, err := parseSignatureAlgorithm(body.Algorithm)
+	if err != nil {
+		resp.Failf(http.StatusBadRequest, "invalid signature algorithm '%s'", body.Algorithm)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
 	if err != nil {
 		if err, ok := api.IsError(err); ok {
 			resp.Failr(err)
@@ -919,100 +2748,569 @@ func (s *Server) hmacKey(resp *api.Response, req *api.Request) {
 		resp.Fail(http.StatusBadGateway, "failed to read key")
 		return
 	}
-	if !key.HasHMACKey() {
-		resp.Fail(http.StatusConflict, "key does not support HMAC")
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support signing")
+		return
+	}
+	if err := checkKeyConstraints(&key, crypto.UsageVerify, time.Now()); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	// Signatures produced before the key has been rotated
+	// remain valid. Hence, try all versions, latest first.
+	var valid bool
+	versions := key.Versions()
+	for i := len(versions) - 1; i >= 0 && !valid; i-- {
+		if !versions[i].HasHMACKey() {
+			continue
+		}
+		asymmetricKey := versions[i].AsymmetricKey()
+		valid, err = asymmetricKey.Verify(alg, body.Message, body.Signature)
+		asymmetricKey.Destroy()
+		if err != nil {
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			resp.Fail(http.StatusInternalServerError, "failed to verify signature")
+			return
+		}
+	}
+	api.ReplyWith(resp, http.StatusOK, api.VerifyKeyResponse{
+		Valid: valid,
+	})
+}
+
+// parseSignatureAlgorithm parses s as signature algorithm.
+// If s is empty, it returns ECDSA with P-256.
+func parseSignatureAlgorithm(s string) (crypto.SignatureAlgorithm, error) {
+	if s == "" {
+		return crypto.ECDSA_P256, nil
+	}
+	return crypto.ParseSignatureAlgorithm(s)
+}
+
+func (s *Server) describePolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	state := s.state.Load()
+	policy, ok := state.Policies[req.Resource]
+	if !ok {
+		resp.Failr(kes.ErrPolicyNotFound)
+		return
+	}
+	createdAt, createdBy := state.policyCreated(policy)
+	api.ReplyWith(resp, http.StatusOK, api.DescribePolicyResponse{
+		Name:      req.Resource,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy.String(),
+	})
+}
+
+func (s *Server) readPolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	state := s.state.Load()
+	policy, ok := state.Policies[req.Resource]
+	if !ok {
+		resp.Failr(kes.ErrPolicyNotFound)
+		return
+	}
+
+	allow := make(map[string]struct{}, len(policy.Allow))
+	for p := range policy.Allow {
+		allow[p] = struct{}{}
+	}
+	deny := make(map[string]struct{}, len(policy.Deny))
+	for p := range policy.Deny {
+		deny[p] = struct{}{}
+	}
+
+	createdAt, createdBy := state.policyCreated(policy)
+	api.ReplyWith(resp, http.StatusOK, api.ReadPolicyResponse{
+		Name:      req.Resource,
+		Allow:     allow,
+		Deny:      deny,
+		CreatedAt: createdAt,
+		CreatedBy: createdBy.String(),
+	})
+}
+
+func (s *Server) listPolicies(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+
+	policies := s.state.Load().Policies
+	var names []string
+	if req.Resource == "" || req.Resource == "*" { // fast path
+		names = make([]string, 0, len(policies))
+		for name := range policies {
+			names = append(names, name)
+		}
+	} else {
+		prefix := req.Resource
+		if prefix[len(prefix)-1] == '*' {
+			prefix = prefix[:len(prefix)-1]
+		}
+
+		names = make([]string, 0, 1+len(policies)/10) // pre-alloc space for ~10%
+		for name := range policies {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+
+	api.ReplyWith(resp, http.StatusOK, api.ListPoliciesResponse{
+		Names: names,
+	})
+}
+
+func (s *Server) listPolicyInfos(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+	filter, err := parseListFilter(req.URL.Query())
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+	if filter.Policy != "" {
+		resp.Fail(http.StatusBadRequest, "unknown list parameter 'policy'")
+		return
+	}
+
+	state := s.state.Load()
+	prefix := strings.TrimSuffix(req.Resource, "*")
+	policies := make([]api.DescribePolicyResponse, 0, len(state.Policies))
+	for name, policy := range state.Policies {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		createdAt, createdBy := state.policyCreated(policy)
+		if !filter.Match("", createdAt) {
+			continue
+		}
+		policies = append(policies, api.DescribePolicyResponse{
+			Name:      name,
+			CreatedAt: createdAt,
+			CreatedBy: createdBy.String(),
+		})
+	}
+	slices.SortFunc(policies, func(a, b api.DescribePolicyResponse) int { return strings.Compare(a.Name, b.Name) })
+
+	api.ReplyWith(resp, http.StatusOK, api.ListPolicyInfosResponse{
+		Policies: policies,
+	})
+}
+
+// createPolicy creates a new policy with the allow and deny rules
+// of the request. The policy is kept across config reloads but only
+// persisted across restarts if the server has a policy store.
+func (s *Server) createPolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+	if s.clustered {
+		resp.Fail(http.StatusNotImplemented, "creating policies is not supported by cluster members")
+		return
+	}
+
+	var create api.CreatePolicyRequest
+	if err := api.ReadBody(req, &create); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid create policy request body")
+		return
+	}
+	if len(create.Allow) == 0 && len(create.Deny) == 0 {
+		resp.Fail(http.StatusBadRequest, "no allow or deny rules specified")
 		return
 	}
+	for _, pattern := range append(slices.Clone(create.Allow), create.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil || !strings.HasPrefix(pattern, "/") {
+			resp.Failf(http.StatusBadRequest, "invalid rule '%s': must be an API path pattern", pattern)
+			return
+		}
+	}
 
-	api.ReplyWith(resp, http.StatusOK, api.HMACResponse{
-		Sum: key.HMACKey.Sum(body.Message),
+	events, err := s.updatePolicyStore(req.Identity, func(state *serverState, store *policyStore) api.Error {
+		if _, ok := state.Policies[req.Resource]; ok {
+			return kes.ErrPolicyExists
+		}
+		store.Policies[req.Resource] = storedPolicy{
+			Allow:     create.Allow,
+			Deny:      create.Deny,
+			CreatedAt: time.Now().UTC(),
+			CreatedBy: req.Identity,
+		}
+		return nil
 	})
+	if err != nil {
+		resp.Failr(err)
+		return
+	}
+	s.notify(events...)
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(fmt.Sprintf("policy '%s' created", req.Resource), StatusOK, req)
+	resp.Reply(StatusOK)
 }
 
-func (s *Server) describePolicy(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+// deletePolicy deletes a policy created via the API and revokes
+// all identities assigned to it. Config policies cannot be deleted.
+func (s *Server) deletePolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	state := s.state.Load()
-	if _, ok := state.Policies[req.Resource]; !ok {
-		resp.Failr(kes.ErrPolicyNotFound)
+	events, err := s.updatePolicyStore(req.Identity, func(state *serverState, store *policyStore) api.Error {
+		if _, ok := store.Policies[req.Resource]; !ok {
+			if _, ok = state.Policies[req.Resource]; ok {
+				return api.NewError(http.StatusBadRequest, fmt.Sprintf("policy '%s' is defined in the config file", req.Resource))
+			}
+			return kes.ErrPolicyNotFound
+		}
+		delete(store.Policies, req.Resource)
+		for id, a := range store.Identities {
+			if a.Policy == req.Resource {
+				delete(store.Identities, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		resp.Failr(err)
 		return
 	}
-	api.ReplyWith(resp, http.StatusOK, api.DescribePolicyResponse{
-		Name:      req.Resource,
-		CreatedAt: state.StartTime,
-		CreatedBy: state.Admin.String(),
-	})
+	s.notify(events...)
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(fmt.Sprintf("policy '%s' deleted", req.Resource), StatusOK, req)
+	resp.Reply(StatusOK)
 }
 
-func (s *Server) readPolicy(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+// updatePolicyStore applies the change f to a copy of the policy
+// store, persists it and updates the server policies accordingly.
+// It returns the resulting lifecycle events.
+func (s *Server) updatePolicyStore(by kes.Identity, f func(*serverState, *policyStore) api.Error) ([]Event, api.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.state.Load()
+	store := s.policies.clone()
+	if err := f(old, store); err != nil {
+		return nil, err
+	}
+	policySet, ruleSet, identitySet, err := initPolicies(store.apply(s.confPolicies, time.Now()), old.Names)
+	if err != nil {
+		return nil, api.NewError(http.StatusBadRequest, err.Error())
+	}
+	store.annotate(s.confPolicies, policySet, identitySet)
+	if err = store.write(); err != nil {
+		old.Log.Error(fmt.Sprintf("kes: failed to persist policies: %v", err))
+		return nil, errPersistPolicies
+	}
+	s.policies = store
+
+	state := *old
+	state.Policies = policySet
+	state.PolicyRules = ruleSet
+	state.Identities = identitySet
+	s.state.Store(&state)
+	return append(policyEvents(old.Policies, policySet), identityEvents(old.Identities, identitySet, by)...), nil
+}
+
+// assignPolicy assigns the policy to a set of identities and
+// all identities of another policy, if specified, at once. The
+// assignment replaces the identities' current policies.
+//
+// Assignments are kept across config reloads but only persisted
+// across restarts if the server has a policy store.
+func (s *Server) assignPolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
 
-	state := s.state.Load()
-	policy, ok := state.Policies[req.Resource]
-	if !ok {
-		resp.Failr(kes.ErrPolicyNotFound)
+	var assign api.AssignPolicyRequest
+	if err := api.ReadBody(req, &assign); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid assign policy request body")
+		return
+	}
+	if len(assign.Identities) == 0 && assign.FromPolicy == "" {
+		resp.Fail(http.StatusBadRequest, "no identities or policy to assign from specified")
 		return
 	}
+	var expiresAt time.Time
+	if assign.TTL != "" {
+		ttl, err := time.ParseDuration(assign.TTL)
+		if err != nil || ttl <= 0 {
+			resp.Failf(http.StatusBadRequest, "invalid TTL '%s': must be a positive duration", assign.TTL)
+			return
+		}
+		expiresAt = time.Now().Add(ttl).UTC()
+	}
 
-	allow := make(map[string]struct{}, len(policy.Allow))
-	for p := range policy.Allow {
-		allow[p] = struct{}{}
+	ids, err := assignedIdentities(s.state.Load(), req.Resource, &assign, req.Identity)
+	if err != nil {
+		resp.Failr(err)
+		return
 	}
-	deny := make(map[string]struct{}, len(policy.Deny))
-	for p := range policy.Deny {
-		deny[p] = struct{}{}
+
+	if err := s.applyAssignment(req, req.Resource, ids, expiresAt, ""); err != nil {
+		resp.Failr(err)
+		return
 	}
 
-	api.ReplyWith(resp, http.StatusOK, api.ReadPolicyResponse{
-		Name:      req.Resource,
-		Allow:     allow,
-		Deny:      deny,
-		CreatedAt: state.StartTime,
-		CreatedBy: state.Admin.String(),
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		names = append(names, id.String())
+	}
+	msg := fmt.Sprintf("policy '%s' assigned to %d identities", req.Resource, len(ids))
+	if !expiresAt.IsZero() {
+		msg += fmt.Sprintf(" until %s", expiresAt.Format(time.RFC3339))
+	}
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(msg, StatusOK, req)
+	api.ReplyWith(resp, StatusOK, api.AssignPolicyResponse{
+		Identities: names,
 	})
 }
 
-func (s *Server) listPolicies(resp *api.Response, req *api.Request) {
-	if !validPattern(req.Resource) {
-		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
-		return
+// applyAssignment assigns the policy to the identities until expiresAt,
+// unless zero, and publishes the resulting lifecycle events.
+func (s *Server) applyAssignment(req *api.Request, policy string, ids []kes.Identity, expiresAt time.Time, issuedBy kes.Identity) api.Error {
+	// In a cluster, the assignment is applied once it has been
+	// replicated, on all members. The state lock must not be
+	// held while waiting since applying it acquires the lock.
+	if node := s.cluster.Load(); node != nil {
+		before := identitySubset(s.state.Load().Identities, ids)
+		err := s.propose(req.Context(), node, &clusterCommand{
+			Op:         clusterAssign,
+			Policy:     policy,
+			Identities: ids,
+			ExpiresAt:  expiresAt,
+			IssuedBy:   issuedBy,
+		})
+		if err != nil {
+			if err, ok := api.IsError(err); ok {
+				return err
+			}
+			s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+			return api.NewError(http.StatusServiceUnavailable, "failed to replicate policy assignment")
+		}
+
+		after := identitySubset(s.state.Load().Identities, ids)
+		s.notify(identityEvents(before, after, req.Identity)...)
+		return nil
 	}
 
-	policies := s.state.Load().Policies
-	var names []string
-	if req.Resource == "" || req.Resource == "*" { // fast path
-		names = make([]string, 0, len(policies))
-		for name := range policies {
-			names = append(names, name)
+	events, err := s.assignIdentities(policy, ids, expiresAt, req.Identity, issuedBy)
+	if err != nil {
+		return err
+	}
+	s.notify(events...)
+	return nil
+}
+
+// assignedIdentities returns the identities, in sorted order, the
+// policy gets assigned to by the request. These are the requested
+// identities and all identities of the policy to assign from.
+//
+// It returns an error if the identity that sent the request is
+// one of them. Otherwise, an identity that may assign policies
+// could grant itself any policy.
+func assignedIdentities(state *serverState, policy string, assign *api.AssignPolicyRequest, by kes.Identity) ([]kes.Identity, api.Error) {
+	if _, ok := state.Policies[policy]; !ok {
+		return nil, kes.ErrPolicyNotFound
+	}
+
+	ids := make([]kes.Identity, 0, len(assign.Identities))
+	for _, id := range assign.Identities {
+		if !state.Names.ValidIdentity(id) {
+			return nil, api.NewError(http.StatusBadRequest, fmt.Sprintf("identity '%s' is empty, too long or contains invalid characters", id))
 		}
-	} else {
-		prefix := req.Resource
-		if prefix[len(prefix)-1] == '*' {
-			prefix = prefix[:len(prefix)-1]
+		if kes.Identity(id) == state.Admin {
+			return nil, errAssignAdmin
 		}
-
-		names = make([]string, 0, 1+len(policies)/10) // pre-alloc space for ~10%
-		for name := range policies {
-			if strings.HasPrefix(name, prefix) {
-				names = append(names, name)
+		ids = append(ids, kes.Identity(id))
+	}
+	if assign.FromPolicy != "" {
+		if _, ok := state.Policies[assign.FromPolicy]; !ok {
+			return nil, kes.ErrPolicyNotFound
+		}
+		for id, entry := range state.Identities {
+			if entry.Name == assign.FromPolicy {
+				ids = append(ids, id)
 			}
 		}
 	}
-	slices.Sort(names)
+	if slices.Contains(ids, by) {
+		return nil, errAssignSelf
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
 
-	api.ReplyWith(resp, http.StatusOK, api.ListPoliciesResponse{
-		Names: names,
+// assignIdentities assigns the policy to the identities, replacing
+// their current policies, and returns the resulting lifecycle events.
+// An assignment expires at expiresAt, unless zero. The identity by,
+// unless empty, must not be one of the identities.
+func (s *Server) assignIdentities(policy string, ids []kes.Identity, expiresAt time.Time, by, issuedBy kes.Identity) ([]Event, api.Error) {
+	// Hold the lock while updating the state such that concurrent
+	// assignments or policy updates don't overwrite each other.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old := s.state.Load()
+	p, ok := old.Policies[policy]
+	if !ok {
+		return nil, kes.ErrPolicyNotFound
+	}
+	if slices.Contains(ids, old.Admin) {
+		return nil, errAssignAdmin
+	}
+	if by != "" && slices.Contains(ids, by) {
+		return nil, errAssignSelf
+	}
+
+	now := time.Now().UTC()
+	identities := maps.Clone(old.Identities)
+	for _, id := range ids {
+		identities[id] = identityEntry{
+			Name:        policy,
+			Policy:      p,
+			policyRules: old.PolicyRules[policy],
+			ExpiresAt:   expiresAt,
+			AssignedAt:  now,
+			AssignedBy:  by,
+			IssuedBy:    issuedBy,
+		}
+	}
+
+	store := s.policies.clone()
+	for _, id := range ids {
+		store.Identities[id] = storedAssignment{Policy: policy, ExpiresAt: expiresAt, AssignedAt: now, AssignedBy: by, IssuedBy: issuedBy}
+	}
+	if err := store.write(); err != nil {
+		old.Log.Error(fmt.Sprintf("kes: failed to persist policy assignment: %v", err))
+		return nil, errPersistPolicies
+	}
+	s.policies = store
+
+	state := *old
+	state.Identities = identities
+	s.state.Store(&state)
+	return identityEvents(old.Identities, identities, by), nil
+}
+
+// identitySubset returns the entries of the given identities.
+func identitySubset(identities map[kes.Identity]identityEntry, ids []kes.Identity) map[kes.Identity]identityEntry {
+	subset := make(map[kes.Identity]identityEntry, len(ids))
+	for _, id := range ids {
+		if entry, ok := identities[id]; ok {
+			subset[id] = entry
+		}
+	}
+	return subset
+}
+
+var (
+	errAssignAdmin     = api.NewError(http.StatusBadRequest, "cannot assign a policy to the admin identity")
+	errAssignSelf      = api.NewError(http.StatusForbidden, "identity cannot assign policy to itself")
+	errPersistPolicies = api.NewError(http.StatusInternalServerError, "failed to persist policies")
+)
+
+// testPolicy reports whether the identity would be allowed to
+// call the API path, specified by the path query parameter, under
+// the currently loaded policies. It does not call the API.
+func (s *Server) testPolicy(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "identity '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+	path := req.URL.Query().Get("path")
+	if !strings.HasPrefix(path, "/") {
+		resp.Failf(http.StatusBadRequest, "API path '%s' is empty or does not start with '/'", path)
+		return
+	}
+
+	state := s.state.Load()
+	identity := kes.Identity(req.Resource)
+	if identity == state.Admin {
+		api.ReplyWith(resp, http.StatusOK, api.TestPolicyResponse{
+			Allowed: true,
+			IsAdmin: true,
+		})
+		return
+	}
+	entry, ok := state.Identities[identity]
+	if !ok || entry.expired(time.Now()) {
+		api.ReplyWith(resp, http.StatusOK, api.TestPolicyResponse{})
+		return
+	}
+
+	// Same evaluation as identityEntry.verify: a matching deny
+	// pattern takes precedence over any allow pattern or key rule.
+	deny := matchPolicyPattern(entry.Policy.Deny, path)
+	allow := matchPolicyPattern(entry.Policy.Allow, path)
+	var keyRule string
+	if rule := matchKeyRule(entry.Keys, path); rule != nil {
+		keyRule = rule.Pattern
+	}
+	api.ReplyWith(resp, http.StatusOK, api.TestPolicyResponse{
+		Allowed:     deny == "" && (allow != "" || keyRule != ""),
+		Policy:      entry.Name,
+		Allow:       allow,
+		Deny:        deny,
+		KeyRule:     keyRule,
+		Conditional: entry.Conditions != nil,
 	})
 }
 
+// matchPolicyPattern returns the most specific pattern matching
+// the path, or the empty string if no pattern matches. A pattern
+// matches if it is equal to the path or ends with '*' and the
+// path starts with the pattern without the '*'.
+func matchPolicyPattern(patterns map[string]kes.Rule, path string) string {
+	var match string
+	for pattern := range patterns {
+		if pattern == "" {
+			continue
+		}
+		prefix, wildcard := strings.CutSuffix(pattern, "*")
+		if (wildcard && strings.HasPrefix(path, prefix)) || (!wildcard && path == pattern) {
+			if len(pattern) > len(match) || (len(pattern) == len(match) && pattern < match) {
+				match = pattern
+			}
+		}
+	}
+	return match
+}
+
 func (s *Server) describeIdentity(resp *api.Response, req *api.Request) {
-	if !validName(req.Resource) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "identity '%s' is empty, too long or contains invalid characters", req.Resource)
 		return
 	}
@@ -1028,19 +3326,21 @@ func (s *Server) describeIdentity(resp *api.Response, req *api.Request) {
 	}
 
 	info, ok := state.Identities[kes.Identity(req.Resource)]
-	if !ok {
+	if !ok || info.expired(time.Now()) {
 		resp.Failr(kes.ErrIdentityNotFound)
 		return
 	}
+	createdAt, createdBy := state.identityCreated(&info)
 	api.ReplyWith(resp, http.StatusOK, api.DescribeIdentityResponse{
 		Policy:    info.Name,
-		CreatedAt: state.StartTime,
-		CreatedBy: state.Admin.String(),
+		CreatedAt: createdAt,
+		CreatedBy: createdBy.String(),
+		ExpiresAt: info.ExpiresAt,
 	})
 }
 
 func (s *Server) listIdentities(resp *api.Response, req *api.Request) {
-	if !validPattern(req.Resource) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
 		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
 		return
 	}
@@ -1077,6 +3377,114 @@ func (s *Server) listIdentities(resp *api.Response, req *api.Request) {
 	})
 }
 
+func (s *Server) listIdentityInfos(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidPattern(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "listing pattern '%s' is empty, too long or is invalid", req.Resource)
+		return
+	}
+	filter, err := parseListFilter(req.URL.Query())
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var (
+		state      = s.state.Load()
+		prefix     = strings.TrimSuffix(req.Resource, "*")
+		now        = time.Now()
+		identities = make([]api.IdentityInfo, 0, 1+len(state.Identities))
+	)
+	if strings.HasPrefix(state.Admin.String(), prefix) && filter.Match("", state.StartTime) {
+		identities = append(identities, api.IdentityInfo{
+			Identity: state.Admin.String(),
+			DescribeIdentityResponse: api.DescribeIdentityResponse{
+				IsAdmin:   true,
+				CreatedAt: state.StartTime,
+			},
+		})
+	}
+	for id, info := range state.Identities {
+		if !strings.HasPrefix(id.String(), prefix) || info.expired(now) {
+			continue
+		}
+		createdAt, createdBy := state.identityCreated(&info)
+		if !filter.Match(info.Name, createdAt) {
+			continue
+		}
+		identities = append(identities, api.IdentityInfo{
+			Identity: id.String(),
+			DescribeIdentityResponse: api.DescribeIdentityResponse{
+				Policy:    info.Name,
+				CreatedAt: createdAt,
+				CreatedBy: createdBy.String(),
+				ExpiresAt: info.ExpiresAt,
+			},
+		})
+	}
+	slices.SortFunc(identities, func(a, b api.IdentityInfo) int { return strings.Compare(a.Identity, b.Identity) })
+
+	api.ReplyWith(resp, http.StatusOK, api.ListIdentityInfosResponse{
+		Identities: identities,
+	})
+}
+
+// issueIdentity generates a new API key and assigns the policy
+// to its identity until the requested TTL has passed. The API key
+// is sent to the client but never stored by the server.
+func (s *Server) issueIdentity(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "policy name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var issue api.IssueIdentityRequest
+	if err := api.ReadBody(req, &issue); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid issue identity request body")
+		return
+	}
+	ttl, err := time.ParseDuration(issue.TTL)
+	if err != nil || ttl <= 0 {
+		resp.Failf(http.StatusBadRequest, "invalid TTL '%s': must be a positive duration", issue.TTL)
+		return
+	}
+	if _, ok := s.state.Load().Policies[req.Resource]; !ok {
+		resp.Failr(kes.ErrPolicyNotFound)
+		return
+	}
+
+	key, err := kes.GenerateAPIKey(nil)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to generate API key")
+		return
+	}
+	identity := key.Identity()
+	expiresAt := time.Now().Add(ttl).UTC()
+	if err := s.applyAssignment(req, req.Resource, []kes.Identity{identity}, expiresAt, req.Identity); err != nil {
+		resp.Failr(err)
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("identity '%s' issued with policy '%s' until %s", identity, req.Resource, expiresAt.Format(time.RFC3339)),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.IssueIdentityResponse{
+		Identity:  identity.String(),
+		APIKey:    key.String(),
+		Policy:    req.Resource,
+		ExpiresAt: expiresAt,
+	})
+}
+
 func (s *Server) selfDescribeIdentity(resp *api.Response, req *api.Request) {
 	state := s.state.Load()
 	if req.Identity == state.Admin {
@@ -1103,40 +3511,135 @@ func (s *Server) selfDescribeIdentity(resp *api.Response, req *api.Request) {
 		deny[p] = struct{}{}
 	}
 
+	createdAt, createdBy := state.identityCreated(&info)
+	policyCreatedAt, policyCreatedBy := state.policyCreated(info.Policy)
 	api.ReplyWith(resp, http.StatusOK, api.SelfDescribeIdentityResponse{
 		Identity:  req.Identity.String(),
-		CreatedAt: state.StartTime,
-		CreatedBy: state.Admin.String(),
+		CreatedAt: createdAt,
+		CreatedBy: createdBy.String(),
 		Policy: &api.ReadPolicyResponse{
 			Name:      info.Name,
 			Allow:     allow,
 			Deny:      deny,
-			CreatedAt: state.StartTime,
-			CreatedBy: state.Admin.String(),
+			CreatedAt: policyCreatedAt,
+			CreatedBy: policyCreatedBy.String(),
 		},
 	})
 }
 
 func (s *Server) logError(resp *api.Response, req *api.Request) {
+	filter, err := parseLogFilter(req.URL.Query(), false)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+
 	resp.Header().Set(headers.ContentType, headers.ContentTypeJSONLines)
 	resp.WriteHeader(http.StatusOK)
 
-	w := api.NewLogWriter(resp.ResponseWriter)
+	w := &errorFilterWriter{w: api.NewLogWriter(resp.ResponseWriter), filter: filter}
 	errLog := s.state.Load().LogHandler
 	errLog.out.Add(w)
 	defer errLog.out.Remove(w)
 
-	<-req.Context().Done()
+	ctx, cancel := s.streamContext(req)
+	defer cancel()
+	waitUntil(ctx, filter.Until)
 }
 
-func (s *Server) logAudit(resp *api.Response, req *api.Request) {
+func (s *Server) proveAudit(resp *api.Response, req *api.Request) {
+	leaf, err := hex.DecodeString(req.Resource)
+	if err != nil || len(leaf) != len(merkle.Hash{}) {
+		resp.Fail(http.StatusBadRequest, "invalid audit event hash")
+		return
+	}
+	var size uint64
+	if v := req.URL.Query().Get("size"); v != "" {
+		if size, err = strconv.ParseUint(v, 10, 64); err != nil || size == 0 {
+			resp.Fail(http.StatusBadRequest, "invalid tree size")
+			return
+		}
+	}
+
+	proof, err := s.state.Load().Audit.Prove(merkle.Hash(leaf), size)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to compute audit proof")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, proof)
+}
+
+// watch streams key, policy and identity lifecycle events as
+// newline-delimited JSON to the client until the client closes
+// the connection.
+func (s *Server) watch(resp *api.Response, req *api.Request) {
 	resp.Header().Set(headers.ContentType, headers.ContentTypeJSONLines)
 	resp.WriteHeader(http.StatusOK)
 
+	http.NewResponseController(resp.ResponseWriter).Flush() // Send the response header before the first event
+
 	w := https.FlushOnWrite(resp.ResponseWriter)
+	s.watchers.Add(w)
+	defer s.watchers.Remove(w)
+
+	ctx, cancel := s.streamContext(req)
+	defer cancel()
+	<-ctx.Done()
+}
+
+func (s *Server) logAudit(resp *api.Response, req *api.Request) {
+	filter, err := parseLogFilter(req.URL.Query(), true)
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	resp.Header().Set(headers.ContentType, headers.ContentTypeJSONLines)
+	resp.WriteHeader(http.StatusOK)
+
+	var w io.Writer = https.FlushOnWrite(resp.ResponseWriter)
+	if filter != (logFilter{}) {
+		w = &auditFilterWriter{w: w, filter: filter}
+	}
 	auditLog := s.state.Load().Audit
 	auditLog.out.Add(w)
 	defer auditLog.out.Remove(w)
 
-	<-req.Context().Done()
+	ctx, cancel := s.streamContext(req)
+	defer cancel()
+	waitUntil(ctx, filter.Until)
+}
+
+// streamContext returns a context for streaming APIs that is
+// done once the client closes the connection or the server
+// starts to shut down. Streams never complete on their own and
+// would delay a graceful shutdown until it times out.
+func (s *Server) streamContext(req *api.Request) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(s.shutdown, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// waitUntil blocks until the ctx is done or, if until is not
+// zero, the time until has been reached.
+func waitUntil(ctx context.Context, until time.Time) {
+	if until.IsZero() {
+		<-ctx.Done()
+		return
+	}
+
+	timer := time.NewTimer(time.Until(until))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
 }
@@ -9,12 +9,16 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"io"
 	"log/slog"
 	"net"
+	"net/http"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/minio/kes/internal/api"
 	"github.com/minio/kms-go/kes"
 )
 
@@ -44,9 +48,205 @@ const (
 	defaultIdentity = "3ecfcdf38fcbe141ae26a1030f81e96b753365a46760ae6b578698a97c59fd22"
 )
 
+func TestServerDisableHTTP2(t *testing.T) {
+	ctx := testContext(t)
+
+	for _, disable := range []bool{false, true} {
+		srv, url := startServer(ctx, &Config{
+			HTTP: &HTTPConfig{DisableHTTP2: disable},
+		})
+
+		client := defaultClient(url)
+		if _, err := client.Status(ctx); err != nil {
+			t.Fatalf("Failed to fetch server status: %v", err)
+		}
+
+		conn, err := tls.Dial("tcp", strings.TrimPrefix(url, "https://"), &tls.Config{
+			InsecureSkipVerify: true,
+			NextProtos:         []string{"h2", "http/1.1"},
+		})
+		if err != nil {
+			t.Fatalf("Failed to connect to server: %v", err)
+		}
+		proto := conn.ConnectionState().NegotiatedProtocol
+		conn.Close()
+		srv.Close()
+
+		if disable && proto == "h2" {
+			t.Fatal("Negotiated HTTP/2 although it is disabled")
+		}
+		if !disable && proto != "h2" {
+			t.Fatalf("Failed to negotiate HTTP/2: got '%s'", proto)
+		}
+	}
+}
+
+func TestServerGracefulShutdown(t *testing.T) {
+	ctx := testContext(t)
+
+	store := &blockingKeyStore{
+		Started: make(chan struct{}),
+		Release: make(chan struct{}),
+	}
+	srvCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	srv, url := startServerWith(srvCtx, &Server{ShutdownTimeout: 10 * time.Second}, &Config{Keys: store})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url+api.PathWatch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := client.HTTPClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to watch events: %v", err)
+	}
+	defer stream.Body.Close()
+
+	created := make(chan error, 1)
+	go func() { created <- client.CreateKey(ctx, "my-key") }()
+	<-store.Started
+
+	stop() // Like SIGTERM, starts to shut down the server
+	time.Sleep(100 * time.Millisecond)
+	close(store.Release)
+
+	if err = <-created; err != nil {
+		t.Fatalf("In-flight request has not been drained: %v", err)
+	}
+	if _, err = io.Copy(io.Discard, stream.Body); err != nil {
+		t.Fatalf("Event stream has not been closed gracefully: %v", err)
+	}
+}
+
+// blockingKeyStore is a MemKeyStore that blocks every Create
+// request until Release is closed or the request is canceled.
+type blockingKeyStore struct {
+	MemKeyStore
+	Started chan struct{} // Closed once the first Create request arrives
+	Release chan struct{}
+
+	once sync.Once
+}
+
+func (s *blockingKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	s.once.Do(func() { close(s.Started) })
+	select {
+	case <-s.Release:
+		return s.MemKeyStore.Create(ctx, name, value)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	ctx := testContext(t)
+	srv, url := startServer(ctx, nil)
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	for i, test := range []struct {
+		Method string
+		Path   string
+		Code   string
+	}{
+		{Method: http.MethodGet, Path: api.PathKeyDescribe + "unknown-key", Code: api.CodeKeyNotFound},
+		{Method: http.MethodPut, Path: api.PathKeyCreate + "my-key", Code: api.CodeKeyExists},
+		{Method: http.MethodGet, Path: api.PathPolicyDescribe + "unknown-policy", Code: api.CodePolicyNotFound},
+		{Method: http.MethodGet, Path: api.PathKeyDescribe + "invalid%20name", Code: "ErrBadRequest"},
+	} {
+		req, err := http.NewRequestWithContext(ctx, test.Method, url+test.Path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := client.HTTPClient.Do(req)
+		if err != nil {
+			t.Fatalf("Test %d: Failed to send request: %v", i, err)
+		}
+		err = api.ReadError(resp)
+		resp.Body.Close()
+
+		if code := api.CodeOf(err); code != test.Code {
+			t.Fatalf("Test %d: Invalid error code: got '%s' - want '%s'", i, code, test.Code)
+		}
+	}
+
+	// Errors of the client SDK map to the same codes.
+	if err := client.CreateKey(ctx, "my-key"); api.CodeOf(err) != api.CodeKeyExists {
+		t.Fatalf("Invalid error code: got '%s' - want '%s'", api.CodeOf(err), api.CodeKeyExists)
+	}
+}
+
+func TestVerifyCertificates(t *testing.T) {
+	conf := &tls.Config{
+		Certificates: []tls.Certificate{defaultServerCertificate()},
+	}
+	for i, test := range []struct {
+		Time       time.Time
+		ShouldFail bool
+	}{
+		{Time: time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{Time: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), ShouldFail: true},
+		{Time: time.Date(2060, time.January, 1, 0, 0, 0, 0, time.UTC), ShouldFail: true},
+	} {
+		err := verifyCertificates(conf, test.Time)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify certificates: %v", i, err)
+		}
+	}
+}
+
+func TestVerifyConfig(t *testing.T) {
+	conf := &Config{
+		TLS: &tls.Config{
+			ClientAuth:   tls.RequestClientCert,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		Keys: &MemKeyStore{},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{"/v1/key/create/*": {}},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	}
+	if err := VerifyConfig(conf); err != nil {
+		t.Fatalf("Failed to verify config: %v", err)
+	}
+
+	conf.Policies["my-policy-2"] = Policy{
+		Identities: []kes.Identity{defaultIdentity},
+	}
+	if err := VerifyConfig(conf); err == nil {
+		t.Fatal("Identity assigned to multiple policies should be rejected")
+	}
+	delete(conf.Policies, "my-policy-2")
+
+	conf.Keys = nil
+	if err := VerifyConfig(conf); err == nil {
+		t.Fatal("Config without key store should be rejected")
+	}
+}
+
 func startServer(ctx context.Context, conf *Config) (*Server, string) {
-	ln := newLocalListener()
+	return startServerWith(ctx, &Server{
+		ShutdownTimeout: -1, // wait for all requests to finish
+	}, conf)
+}
+
+func startServerWith(ctx context.Context, srv *Server, conf *Config) (*Server, string) {
+	return startServerOn(ctx, srv, newLocalListener(), conf)
+}
 
+func startServerOn(ctx context.Context, srv *Server, ln net.Listener, conf *Config) (*Server, string) {
 	if conf == nil {
 		conf = &Config{}
 	}
@@ -68,7 +268,7 @@ func startServer(ctx context.Context, conf *Config) (*Server, string) {
 			ExpiryOffline: 0,
 		}
 	}
-	if conf.Keys == nil {
+	if conf.Keys == nil && conf.Replica == nil {
 		conf.Keys = &MemKeyStore{}
 	}
 	if conf.ErrorLog == nil {
@@ -78,10 +278,6 @@ func startServer(ctx context.Context, conf *Config) (*Server, string) {
 		conf.AuditLog = discardAudit{}
 	}
 
-	srv := &Server{
-		ShutdownTimeout: -1, // wait for all requests to finish
-	}
-
 	var wg sync.WaitGroup
 	wg.Add(1)
 	go func() {
@@ -0,0 +1,214 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+// softDeleteKey marks a key for deletion. The key cannot be used
+// anymore but can be recovered with the undelete API until the
+// recovery window has passed.
+func (s *Server) softDeleteKey(resp *api.Response, req *api.Request, conf *SoftDeleteConfig) {
+	s.rotateLock.Lock()
+	defer s.rotateLock.Unlock()
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+
+	now := time.Now().UTC()
+	deleted := key
+	deleted.DeletedAt = now
+	deleted.DeletedBy = req.Identity
+	if err = s.state.Load().Keys.Replace(req.Context(), req.Resource, key, deleted); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to delete key")
+		return
+	}
+
+	s.notify(Event{
+		Type:     EventKeyDeleted,
+		Time:     now,
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' marked for deletion. It can be recovered until %s", req.Resource, now.Add(conf.recoveryWindow()).Format(time.RFC3339)),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// undeleteKey recovers a key that has been marked for deletion
+// but has not been deleted permanently yet.
+func (s *Server) undeleteKey(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	s.rotateLock.Lock()
+	defer s.rotateLock.Unlock()
+
+	key, err := s.state.Load().Keys.GetDeleted(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+
+	recovered := key
+	recovered.DeletedAt = time.Time{}
+	recovered.DeletedBy = ""
+	if err = s.state.Load().Keys.Replace(req.Context(), req.Resource, key, recovered); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to recover key")
+		return
+	}
+
+	s.notify(Event{
+		Type:     EventKeyCreated,
+		Time:     time.Now().UTC(),
+		Name:     req.Resource,
+		Identity: req.Identity,
+	})
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("secret key '%s' recovered. It has been marked for deletion at %s", req.Resource, key.DeletedAt.Format(time.RFC3339)),
+		StatusOK,
+		req,
+	)
+	resp.Reply(StatusOK)
+}
+
+// withoutDeletedKeys returns all names of keys that have not been
+// marked for deletion.
+func (s *Server) withoutDeletedKeys(ctx context.Context, names []string) ([]string, error) {
+	keys := make([]string, 0, len(names))
+	for _, name := range names {
+		_, err := s.state.Load().Keys.Get(ctx, name)
+		if errors.Is(err, kes.ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, name)
+	}
+	return keys, nil
+}
+
+// purgeDeletedKeys deletes keys, that have been marked for deletion,
+// permanently once their recovery window has passed until ctx is
+// canceled. No keys are deleted while the server is frozen.
+func (s *Server) purgeDeletedKeys(ctx context.Context) {
+	const Delay = 1 * time.Hour // Max. delay between checks for keys to delete
+
+	for {
+		wait := Delay
+		if conf := s.state.Load().SoftDelete; conf != nil {
+			wait = min(wait, conf.recoveryWindow())
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.SoftDelete == nil || state.Replica != nil || s.frozen.Load() != nil {
+			continue
+		}
+		if err := s.purgeKeys(ctx, state, time.Now()); err != nil && ctx.Err() == nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to delete keys marked for deletion: %v", err))
+		}
+	}
+}
+
+// purgeKeys deletes all keys, whose recovery window has passed at
+// time t, permanently.
+func (s *Server) purgeKeys(ctx context.Context, state *serverState, t time.Time) error {
+	iter := kes.ListIter[string]{NextFunc: state.Keys.List}
+	for name, err := iter.Next(ctx); err != io.EOF; name, err = iter.Next(ctx) {
+		if err != nil {
+			return err
+		}
+		if err = s.purgeKey(ctx, state, name, t); err != nil && !errors.Is(err, kes.ErrKeyNotFound) {
+			return err
+		}
+	}
+	return nil
+}
+
+// purgeKey deletes the key permanently if it has been marked for
+// deletion and its recovery window has passed at time t. It emits
+// an audit event on behalf of the identity that marked the key
+// for deletion.
+func (s *Server) purgeKey(ctx context.Context, state *serverState, name string, t time.Time) error {
+	s.rotateLock.Lock()
+	defer s.rotateLock.Unlock()
+
+	key, err := state.Keys.GetDeleted(ctx, name)
+	if err != nil {
+		return err
+	}
+	if t.Before(key.DeletedAt.Add(state.SoftDelete.recoveryWindow())) {
+		return nil
+	}
+	if err = state.Keys.Delete(ctx, name); err != nil {
+		return err
+	}
+	s.usage.Delete(name)
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodDelete, api.PathKeyDelete+name, nil)
+	if err != nil {
+		return err
+	}
+	state.Audit.Log(
+		fmt.Sprintf("secret key '%s' deleted permanently. It has been marked for deletion at %s", name, key.DeletedAt.Format(time.RFC3339)),
+		http.StatusOK,
+		&api.Request{Request: r, Identity: key.DeletedBy, Received: t},
+	)
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := testContext(t)
+
+	const RecoveryWindow = 1 * time.Hour
+	srv, url := startServer(ctx, &Config{
+		SoftDelete: &SoftDeleteConfig{RecoveryWindow: RecoveryWindow},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	for _, name := range []string{"my-key", "my-key-2"} {
+		if err := client.CreateKey(ctx, name); err != nil {
+			t.Fatalf("Failed to create key '%s': %v", name, err)
+		}
+	}
+	ciphertext, err := client.Encrypt(ctx, "my-key", []byte("Hello World"), nil)
+	if err != nil {
+		t.Fatalf("Failed to encrypt: %v", err)
+	}
+
+	if err = client.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	if _, err = client.DescribeKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Describing deleted key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if _, err = client.Decrypt(ctx, "my-key", ciphertext, nil); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Decrypting with deleted key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err = client.DeleteKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Deleting deleted key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err = client.CreateKey(ctx, "my-key"); !errors.Is(err, kes.ErrKeyExists) {
+		t.Fatalf("Creating deleted key: got '%v' - want '%v'", err, kes.ErrKeyExists)
+	}
+	names, _, err := client.ListKeys(ctx, "", -1)
+	if err != nil {
+		t.Fatalf("Failed to list keys: %v", err)
+	}
+	if !slices.Equal(names, []string{"my-key-2"}) {
+		t.Fatalf("Invalid key listing: got '%v' - want '%v'", names, []string{"my-key-2"})
+	}
+
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyUndelete+"my-key", nil); err != nil {
+		t.Fatalf("Failed to undelete key: %v", err)
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyUndelete+"my-key-2", nil); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Undeleting key that has not been deleted: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	plaintext, err := client.Decrypt(ctx, "my-key", ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Failed to decrypt with recovered key: %v", err)
+	}
+	if string(plaintext) != "Hello World" {
+		t.Fatalf("Invalid plaintext: got '%s' - want '%s'", plaintext, "Hello World")
+	}
+
+	// Keys are deleted permanently once their recovery window has passed.
+	if err = client.DeleteKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to delete key: %v", err)
+	}
+	state := srv.state.Load()
+	if err = srv.purgeKeys(ctx, state, time.Now()); err != nil {
+		t.Fatalf("Failed to purge keys: %v", err)
+	}
+	if _, err = state.Keys.GetDeleted(ctx, "my-key"); err != nil {
+		t.Fatalf("Key has been deleted before its recovery window has passed: %v", err)
+	}
+	if err = srv.purgeKeys(ctx, state, time.Now().Add(RecoveryWindow)); err != nil {
+		t.Fatalf("Failed to purge keys: %v", err)
+	}
+	if err = sendRequest(ctx, client, http.MethodPut, api.PathKeyUndelete+"my-key", nil); !errors.Is(err, kes.ErrKeyNotFound) {
+		t.Fatalf("Undeleting purged key: got '%v' - want '%v'", err, kes.ErrKeyNotFound)
+	}
+	if err = client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key after it has been purged: %v", err)
+	}
+	if _, err = client.DescribeKey(ctx, "my-key-2"); err != nil {
+		t.Fatalf("Key has been purged although it has not been deleted: %v", err)
+	}
+}
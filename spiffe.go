@@ -0,0 +1,170 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/minio/kms-go/kes"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+// spiffeAuth identifies clients by the SPIFFE ID of their
+// X.509 SVIDs and, if it has a source, provides the server's
+// X.509 SVID as TLS certificate.
+type spiffeAuth struct {
+	trustDomains []spiffeid.TrustDomain
+	source       SPIFFESource
+}
+
+// newSPIFFEAuth returns a new spiffeAuth for the given config.
+// It returns nil if conf is nil.
+func newSPIFFEAuth(conf *SPIFFEConfig) (*spiffeAuth, error) {
+	if conf == nil {
+		return nil, nil
+	}
+
+	trustDomains := make([]spiffeid.TrustDomain, 0, len(conf.TrustDomains))
+	for _, name := range conf.TrustDomains {
+		td, err := spiffeid.TrustDomainFromString(name)
+		if err != nil {
+			return nil, err
+		}
+		trustDomains = append(trustDomains, td)
+	}
+	return &spiffeAuth{
+		trustDomains: trustDomains,
+		source:       conf.Source,
+	}, nil
+}
+
+// Identify returns the SPIFFE ID of the client certificate as
+// identity if it is an X.509 SVID of one of the trust domains.
+// It expects that the certificate chains have been verified
+// during the TLS handshake.
+//
+// If a has a source, the SVID must have been issued by an
+// authority of its trust domain's bundle.
+func (a *spiffeAuth) Identify(chains [][]*x509.Certificate) (kes.Identity, bool) {
+	if len(chains) == 0 || len(chains[0]) == 0 {
+		return "", false
+	}
+	chain := chains[0]
+
+	id, err := x509svid.IDFromCert(chain[0])
+	if err != nil {
+		return "", false
+	}
+	if !slices.Contains(a.trustDomains, id.TrustDomain()) {
+		return "", false
+	}
+	if a.source != nil {
+		bundle, err := a.source.GetX509BundleForTrustDomain(id.TrustDomain())
+		if err != nil || !bundle.HasX509Authority(chain[len(chain)-1]) {
+			return "", false
+		}
+	}
+	return kes.Identity(id.String()), true
+}
+
+// GetCertificate returns the server's current X.509 SVID.
+func (a *spiffeAuth) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	svid, err := a.source.GetX509SVID()
+	if err != nil {
+		return nil, err
+	}
+
+	cert := &tls.Certificate{
+		PrivateKey: svid.PrivateKey,
+		Leaf:       svid.Certificates[0],
+	}
+	for _, c := range svid.Certificates {
+		cert.Certificate = append(cert.Certificate, c.Raw)
+	}
+	return cert, nil
+}
+
+// withSPIFFE returns conf if a is nil or has no source. Otherwise,
+// it returns a copy of conf that uses the server's X.509 SVID as
+// certificate and trusts the authorities of the trust domains'
+// bundles, in addition to conf.ClientCAs, for client certificates.
+//
+// Bundle authorities are added to the ClientCAs of conf. Hence,
+// applying withSPIFFE to a config returned by withSPIFFE keeps
+// authorities that have been removed from a bundle meanwhile.
+func withSPIFFE(conf *tls.Config, a *spiffeAuth) *tls.Config {
+	if a == nil || a.source == nil {
+		return conf
+	}
+
+	clientCAs := x509.NewCertPool()
+	if conf.ClientCAs != nil {
+		clientCAs = conf.ClientCAs.Clone()
+	}
+	for _, td := range a.trustDomains {
+		bundle, err := a.source.GetX509BundleForTrustDomain(td)
+		if err != nil {
+			continue
+		}
+		for _, cert := range bundle.X509Authorities() {
+			clientCAs.AddCert(cert)
+		}
+	}
+
+	conf = conf.Clone()
+	conf.Certificates = nil
+	conf.GetCertificate = a.GetCertificate
+	conf.ClientCAs = clientCAs
+	return conf
+}
+
+// updateSPIFFEBundles updates the server's TLS config whenever
+// the SPIFFE source, if any, receives new trust bundles. The
+// server's SVID is fetched from the source for every handshake
+// and does not require updating the TLS config.
+func (s *Server) updateSPIFFEBundles(ctx context.Context) {
+	const Delay = 1 * time.Minute // Delay between checks whether a SPIFFE source is configured
+
+	for {
+		var updated <-chan struct{}
+		auth := s.state.Load().SPIFFE
+		if auth != nil && auth.source != nil {
+			updated = auth.source.Updated()
+		}
+
+		timer := time.NewTimer(Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			continue
+		case <-updated:
+			timer.Stop()
+		}
+
+		s.mu.Lock()
+		if s.state.Load().SPIFFE == auth {
+			s.tls.Store(withSPIFFE(s.tls.Load(), auth))
+		}
+		s.mu.Unlock()
+	}
+}
+
+// isSPIFFEID reports whether id is a SPIFFE ID, like
+// "spiffe://example.org/app".
+func isSPIFFEID(id string) bool {
+	if !strings.HasPrefix(id, "spiffe://") || len(id) > maxNameLength {
+		return false
+	}
+	_, err := spiffeid.FromString(id)
+	return err == nil
+}
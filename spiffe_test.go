@@ -0,0 +1,316 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+	"github.com/spiffe/go-spiffe/v2/bundle/x509bundle"
+	"github.com/spiffe/go-spiffe/v2/spiffeid"
+	"github.com/spiffe/go-spiffe/v2/svid/x509svid"
+)
+
+func TestSPIFFEIdentify(t *testing.T) {
+	ca, caKey := newTestCA(t)
+	svid, _ := newTestSVID(t, ca, caKey, "spiffe://example.org/app")
+	other, _ := newTestSVID(t, ca, caKey, "spiffe://other.org/app")
+
+	auth, err := newSPIFFEAuth(&SPIFFEConfig{TrustDomains: []string{"example.org"}})
+	if err != nil {
+		t.Fatalf("Failed to create SPIFFE auth: %v", err)
+	}
+	if id, err := identifyRequest(connectionState(svid, ca), auth); err != nil || id != "spiffe://example.org/app" {
+		t.Fatalf("Invalid identity: got '%s' - want '%s'", id, "spiffe://example.org/app")
+	}
+	if id, _ := identifyRequest(connectionState(svid, ca), nil); id == "spiffe://example.org/app" {
+		t.Fatal("SPIFFE ID should not be used as identity without a SPIFFE config")
+	}
+	if id, _ := identifyRequest(connectionState(other, ca), auth); id == "spiffe://other.org/app" {
+		t.Fatal("SPIFFE ID of an untrusted trust domain should not be used as identity")
+	}
+	if id, _ := identifyRequest(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{svid}}, auth); id == "spiffe://example.org/app" {
+		t.Fatal("SPIFFE ID of an unverified certificate should not be used as identity")
+	}
+
+	// With a source, the SVID has to be issued by an authority of its trust domain.
+	otherCA, _ := newTestCA(t)
+	auth.source = &testSPIFFESource{
+		bundles: x509bundle.NewSet(x509bundle.FromX509Authorities(spiffeid.RequireTrustDomainFromString("example.org"), []*x509.Certificate{otherCA})),
+	}
+	if id, _ := identifyRequest(connectionState(svid, ca), auth); id == "spiffe://example.org/app" {
+		t.Fatal("SPIFFE ID issued by an authority of another trust domain should not be used as identity")
+	}
+}
+
+func TestServerSPIFFE(t *testing.T) {
+	ctx := testContext(t)
+
+	ca, caKey := newTestCA(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	srv, endpoint := startServer(ctx, &Config{
+		TLS: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		SPIFFE: &SPIFFEConfig{TrustDomains: []string{"example.org"}},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{api.PathKeyCreate + "*": {}},
+				Identities: []kes.Identity{"spiffe://example.org/app"},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	newClient := func(id string) *kes.Client {
+		cert, key := newTestSVID(t, ca, caKey, id)
+		return kes.NewClientWithConfig(endpoint, &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			RootCAs:    rootCAs,
+			Certificates: []tls.Certificate{{
+				Certificate: [][]byte{cert.Raw},
+				PrivateKey:  key,
+			}},
+		})
+	}
+
+	if err := newClient("spiffe://example.org/app").CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := newClient("spiffe://example.org/other").CreateKey(ctx, "my-key-2"); !errors.Is(err, kes.ErrNotAllowed) {
+		t.Fatalf("Creating key: got '%v' - want '%v'", err, kes.ErrNotAllowed)
+	}
+}
+
+func TestServerSPIFFETLSProxy(t *testing.T) {
+	ctx := testContext(t)
+
+	ca, caKey := newTestCA(t)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca)
+
+	srv, endpoint := startServer(ctx, &Config{
+		TLS: &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			Certificates: []tls.Certificate{defaultServerCertificate()},
+		},
+		SPIFFE:   &SPIFFEConfig{TrustDomains: []string{"example.org"}},
+		TLSProxy: &TLSProxyConfig{Identities: []kes.Identity{"spiffe://example.org/proxy"}},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{api.PathKeyCreate + "*": {}},
+				Identities: []kes.Identity{"spiffe://example.org/app"},
+			},
+		},
+	})
+	defer srv.Close()
+
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(defaultServerCertificate().Leaf)
+	proxyCert, proxyKey := newTestSVID(t, ca, caKey, "spiffe://example.org/proxy")
+	proxy := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				MinVersion: tls.VersionTLS12,
+				RootCAs:    rootCAs,
+				Certificates: []tls.Certificate{{
+					Certificate: [][]byte{proxyCert.Raw},
+					PrivateKey:  proxyKey,
+				}},
+			},
+		},
+	}
+	forward := func(name, id string) int {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint+api.PathKeyCreate+name, nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if id != "" {
+			cert, _ := newTestSVID(t, ca, caKey, id)
+			req.Header.Set("X-Tls-Client-Cert", url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))))
+		}
+		resp, err := proxy.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to send request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := forward("my-key", "spiffe://example.org/app"); code != http.StatusOK {
+		t.Fatalf("Forwarded request has been rejected: got '%d' - want '%d'", code, http.StatusOK)
+	}
+	if code := forward("my-key-2", "spiffe://example.org/other"); code != http.StatusForbidden {
+		t.Fatalf("Forwarded request of unknown identity has been accepted: got '%d' - want '%d'", code, http.StatusForbidden)
+	}
+	if code := forward("my-key-3", ""); code != http.StatusBadRequest {
+		t.Fatalf("Request without client certificate has been accepted: got '%d' - want '%d'", code, http.StatusBadRequest)
+	}
+}
+
+func TestServerSPIFFESource(t *testing.T) {
+	ctx := testContext(t)
+
+	ca, caKey := newTestCA(t)
+	cert, key := newTestSVID(t, ca, caKey, "spiffe://example.org/kes")
+	source := &testSPIFFESource{
+		svid: &x509svid.SVID{
+			ID:           spiffeid.RequireFromString("spiffe://example.org/kes"),
+			Certificates: []*x509.Certificate{cert},
+			PrivateKey:   key,
+		},
+		bundles: x509bundle.NewSet(x509bundle.FromX509Authorities(spiffeid.RequireTrustDomainFromString("example.org"), []*x509.Certificate{ca})),
+	}
+
+	srv, endpoint := startServer(ctx, &Config{
+		TLS: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		},
+		SPIFFE: &SPIFFEConfig{
+			TrustDomains: []string{"example.org"},
+			Source:       source,
+		},
+		Policies: map[string]Policy{
+			"my-policy": {
+				Allow:      map[string]kes.Rule{api.PathKeyCreate + "*": {}},
+				Identities: []kes.Identity{"spiffe://example.org/app"},
+			},
+		},
+	})
+	defer srv.Close()
+
+	clientCert, clientKey := newTestSVID(t, ca, caKey, "spiffe://example.org/app")
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(ca)
+	client := kes.NewClientWithConfig(endpoint, &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		RootCAs:    rootCAs,
+		Certificates: []tls.Certificate{{
+			Certificate: [][]byte{clientCert.Raw},
+			PrivateKey:  clientKey,
+		}},
+	})
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+}
+
+func TestValidIdentity(t *testing.T) {
+	for i, test := range []struct {
+		Identity string
+		Valid    bool
+	}{
+		{Identity: defaultIdentity, Valid: true},
+		{Identity: "spiffe://example.org/app", Valid: true},
+		{Identity: "spiffe://example.org", Valid: true},
+		{Identity: "spiffe://", Valid: false},
+		{Identity: "spiffe://Example.org/app", Valid: false},
+		{Identity: "https://example.org/app", Valid: false},
+		{Identity: "", Valid: false},
+	} {
+		if valid := defaultNameRules.ValidIdentity(test.Identity); valid != test.Valid {
+			t.Fatalf("Test %d: got '%v' - want '%v' for identity '%s'", i, valid, test.Valid, test.Identity)
+		}
+	}
+}
+
+func TestVerifyConfigSPIFFE(t *testing.T) {
+	for i, test := range []struct {
+		ClientAuth tls.ClientAuthType
+		SPIFFE     *SPIFFEConfig
+		ShouldFail bool
+	}{
+		{ClientAuth: tls.RequireAndVerifyClientCert, SPIFFE: &SPIFFEConfig{TrustDomains: []string{"example.org"}}},
+		{ClientAuth: tls.VerifyClientCertIfGiven, SPIFFE: &SPIFFEConfig{TrustDomains: []string{"example.org", "other.org"}}},
+		{ClientAuth: tls.RequireAnyClientCert, SPIFFE: &SPIFFEConfig{TrustDomains: []string{"example.org"}}, ShouldFail: true},
+		{ClientAuth: tls.RequireAndVerifyClientCert, SPIFFE: &SPIFFEConfig{}, ShouldFail: true},
+		{ClientAuth: tls.RequireAndVerifyClientCert, SPIFFE: &SPIFFEConfig{TrustDomains: []string{"spiffe://"}}, ShouldFail: true},
+	} {
+		conf := &Config{
+			TLS: &tls.Config{
+				ClientAuth:   test.ClientAuth,
+				Certificates: []tls.Certificate{defaultServerCertificate()},
+			},
+			SPIFFE: test.SPIFFE,
+			Keys:   &MemKeyStore{},
+		}
+		err := verifyConfig(conf)
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: should have failed but succeeded", i)
+		}
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to verify config: %v", i, err)
+		}
+	}
+}
+
+type testSPIFFESource struct {
+	svid    *x509svid.SVID
+	bundles *x509bundle.Set
+}
+
+func (s *testSPIFFESource) GetX509SVID() (*x509svid.SVID, error) { return s.svid, nil }
+
+func (s *testSPIFFESource) GetX509BundleForTrustDomain(td spiffeid.TrustDomain) (*x509bundle.Bundle, error) {
+	return s.bundles.GetX509BundleForTrustDomain(td)
+}
+
+func (s *testSPIFFESource) Updated() <-chan struct{} { return nil }
+
+// newTestSVID returns a new X.509 SVID for the SPIFFE ID id, issued
+// by the given CA. It can be used as client and server certificate
+// for 127.0.0.1.
+func newTestSVID(t *testing.T, ca *x509.Certificate, caKey crypto.Signer, id string) (*x509.Certificate, crypto.Signer) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uri, err := url.Parse(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		URIs:         []*url.URL{uri},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
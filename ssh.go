@@ -0,0 +1,312 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/crypto"
+	"golang.org/x/crypto/ssh"
+)
+
+// Default validity of SSH certificates.
+const (
+	defaultSSHTTL    = 1 * time.Hour
+	defaultSSHMaxTTL = 24 * time.Hour
+)
+
+// SSHPolicy restricts the SSH certificates the identities of a
+// policy may obtain from a key acting as SSH certificate authority.
+// Keys act as SSH certificate authority if created with the usage
+// "ssh".
+//
+// Identities whose policy allows the SSH sign API but contains no
+// SSHPolicy cannot obtain any SSH certificates.
+type SSHPolicy struct {
+	// Principals are glob patterns, as defined by path.Match,
+	// that the requested principals - user or host names - must
+	// match. For example, "deploy-*" allows certificates for
+	// the users "deploy-web" and "deploy-db".
+	Principals []string
+
+	// MaxTTL is the max. validity of a certificate. If zero,
+	// certificates are valid for at most 24 hours.
+	MaxTTL time.Duration
+
+	// Host allows host certificates. Otherwise, only user
+	// certificates are issued.
+	Host bool
+}
+
+// validate returns an error if the policy allows no principals
+// or contains a malformed pattern.
+func (p *SSHPolicy) validate() error {
+	if len(p.Principals) == 0 {
+		return errors.New("SSH policy allows no principals")
+	}
+	for _, pattern := range p.Principals {
+		if pattern == "" {
+			return errors.New("SSH policy contains an empty principal pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid SSH principal pattern '%s': %v", pattern, err)
+		}
+	}
+	if p.MaxTTL < 0 {
+		return fmt.Errorf("invalid SSH max. TTL '%v': must not be negative", p.MaxTTL)
+	}
+	return nil
+}
+
+// allows returns an error if the policy does not allow a
+// certificate for all principals.
+func (p *SSHPolicy) allows(principals []string) error {
+	for _, principal := range principals {
+		var ok bool
+		for _, pattern := range p.Principals {
+			if ok, _ = path.Match(pattern, principal); ok {
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf("principal '%s' is not allowed", principal)
+		}
+	}
+	return nil
+}
+
+// sshPolicyKey is the request context key of the SSHPolicy
+// of the identity that sent the request.
+type sshPolicyKey struct{}
+
+// withSSHPolicy returns a copy of ctx carrying the SSHPolicy.
+func withSSHPolicy(ctx context.Context, policy *SSHPolicy) context.Context {
+	return context.WithValue(ctx, sshPolicyKey{}, policy)
+}
+
+// signSSH issues an SSH certificate for the public key sent by
+// the client. It is signed by the SSH certificate authority key
+// derived from the key's HMAC key.
+//
+// Requests of the admin are only restricted by the default max.
+// TTL. All other requests must satisfy the SSHPolicy of the
+// client's policy.
+func (s *Server) signSSH(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	var body api.SSHSignRequest
+	if err := api.ReadBody(req, &body); err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadRequest, "invalid request body")
+		return
+	}
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(body.PublicKey))
+	if err != nil {
+		resp.Fail(http.StatusBadRequest, "invalid SSH public key")
+		return
+	}
+	if _, ok := publicKey.(*ssh.Certificate); ok {
+		resp.Fail(http.StatusBadRequest, "invalid SSH public key: key is a certificate")
+		return
+	}
+
+	var certType uint32
+	switch body.Type {
+	case "", "user":
+		body.Type, certType = "user", ssh.UserCert
+	case "host":
+		certType = ssh.HostCert
+	default:
+		resp.Failf(http.StatusBadRequest, "invalid SSH certificate type '%s': must be 'user' or 'host'", body.Type)
+		return
+	}
+	if len(body.Principals) == 0 {
+		resp.Fail(http.StatusBadRequest, "no SSH principals specified")
+		return
+	}
+	for _, principal := range body.Principals {
+		if principal == "" || strings.ContainsAny(principal, ", \t\n") {
+			resp.Failf(http.StatusBadRequest, "invalid SSH principal '%s'", principal)
+			return
+		}
+	}
+	if body.TTL < 0 {
+		resp.Fail(http.StatusBadRequest, "invalid SSH certificate TTL: must not be negative")
+		return
+	}
+
+	maxTTL := defaultSSHMaxTTL
+	if req.Identity != s.state.Load().Admin {
+		policy, _ := req.Context().Value(sshPolicyKey{}).(*SSHPolicy)
+		if policy == nil {
+			resp.Fail(http.StatusForbidden, "policy does not allow SSH certificates")
+			return
+		}
+		if certType == ssh.HostCert && !policy.Host {
+			resp.Fail(http.StatusForbidden, "policy does not allow SSH host certificates")
+			return
+		}
+		if err := policy.allows(body.Principals); err != nil {
+			resp.Failf(http.StatusForbidden, "policy does not allow SSH certificate: %v", err)
+			return
+		}
+		if policy.MaxTTL > 0 {
+			maxTTL = policy.MaxTTL
+		}
+	}
+	ttl := time.Duration(body.TTL) * time.Second
+	if ttl == 0 {
+		ttl = min(defaultSSHTTL, maxTTL)
+	}
+	if ttl > maxTTL {
+		resp.Failf(http.StatusForbidden, "SSH certificate TTL '%v' exceeds max. TTL '%v'", ttl, maxTTL)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	now := time.Now()
+	if err := checkKeyConstraints(&key, crypto.UsageSSH, now); err != nil {
+		resp.Failr(err)
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support SSH certificates")
+		return
+	}
+	signer, err := sshSigner(&key)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to sign SSH certificate")
+		return
+	}
+
+	var serial [8]byte
+	if _, err = rand.Read(serial[:]); err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to sign SSH certificate")
+		return
+	}
+	keyID := body.KeyID
+	if keyID == "" {
+		keyID = req.Identity.String()
+	}
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          binary.BigEndian.Uint64(serial[:]),
+		CertType:        certType,
+		KeyId:           keyID,
+		ValidPrincipals: body.Principals,
+		ValidAfter:      uint64(now.Add(-1 * time.Minute).Unix()), // Tolerate some clock skew
+		ValidBefore:     uint64(now.Add(ttl).Unix()),
+	}
+	if certType == ssh.UserCert {
+		cert.Permissions.Extensions = map[string]string{
+			"permit-X11-forwarding":   "",
+			"permit-agent-forwarding": "",
+			"permit-port-forwarding":  "",
+			"permit-pty":              "",
+			"permit-user-rc":          "",
+		}
+	}
+	if err = cert.SignCert(rand.Reader, signer); err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to sign SSH certificate")
+		return
+	}
+
+	const StatusOK = http.StatusOK
+	s.state.Load().Audit.Log(
+		fmt.Sprintf("SSH %s certificate '%s' with serial %d issued by key '%s' for principals %s valid until %s",
+			body.Type, keyID, cert.Serial, req.Resource, strings.Join(body.Principals, ","), now.Add(ttl).UTC().Format(time.RFC3339)),
+		StatusOK,
+		req,
+	)
+	api.ReplyWith(resp, StatusOK, api.SSHSignResponse{
+		Certificate: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(cert))),
+		Serial:      cert.Serial,
+		ValidAfter:  time.Unix(int64(cert.ValidAfter), 0).UTC(),
+		ValidBefore: time.Unix(int64(cert.ValidBefore), 0).UTC(),
+		CAPublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+	})
+}
+
+// describeSSH returns the public key of the SSH certificate
+// authority of a key, e.g. to add it to the trusted CA keys
+// of SSH servers.
+func (s *Server) describeSSH(resp *api.Response, req *api.Request) {
+	if !s.state.Load().Names.ValidName(req.Resource) {
+		resp.Failf(http.StatusBadRequest, "key name '%s' is empty, too long or contains invalid characters", req.Resource)
+		return
+	}
+
+	key, err := s.state.Load().Keys.Get(req.Context(), req.Resource)
+	if err != nil {
+		if err, ok := api.IsError(err); ok {
+			resp.Failr(err)
+			return
+		}
+
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusBadGateway, "failed to read key")
+		return
+	}
+	if key.Usage&crypto.UsageSSH == 0 {
+		resp.Failr(errKeySSHNotPermitted)
+		return
+	}
+	if !key.HasHMACKey() {
+		resp.Fail(http.StatusConflict, "key does not support SSH certificates")
+		return
+	}
+	signer, err := sshSigner(&key)
+	if err != nil {
+		s.state.Load().Log.ErrorContext(req.Context(), err.Error(), "req", req)
+		resp.Fail(http.StatusInternalServerError, "failed to derive SSH CA key")
+		return
+	}
+	api.ReplyWith(resp, http.StatusOK, api.SSHCAResponse{
+		PublicKey: strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))),
+	})
+}
+
+// sshSigner returns the SSH certificate authority signer of
+// the key. The key must have an HMAC key.
+func sshSigner(key *crypto.KeyVersion) (ssh.Signer, error) {
+	asymmetricKey := key.AsymmetricKey()
+	defer asymmetricKey.Destroy()
+
+	privateKey, err := asymmetricKey.SSHKey()
+	if err != nil {
+		return nil, err
+	}
+	return ssh.NewSignerFromKey(privateKey)
+}
@@ -0,0 +1,155 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kms-go/kes"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSHSign(t *testing.T) {
+	ctx := testContext(t)
+
+	srv, url := startServer(ctx, &Config{
+		Admin: "c84cc9b91ae2399b043da7eca616048d4b4200edf2ff418d8af3835911db945d",
+		Policies: map[string]Policy{
+			"ssh": {
+				Allow: map[string]kes.Rule{
+					"/v1/key/create/*": {},
+					"/v1/ssh/sign/*":   {},
+					"/v1/ssh/ca/*":     {},
+				},
+				SSH: &SSHPolicy{
+					Principals: []string{"deploy-*"},
+					MaxTTL:     2 * time.Hour,
+				},
+				Identities: []kes.Identity{defaultIdentity},
+			},
+		},
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := sendRequest(ctx, client, http.MethodPut, api.PathKeyCreate+"ssh-ca", api.CreateKeyRequest{Usage: []string{"ssh"}}); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate SSH key: %v", err)
+	}
+	sshPub, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatalf("Failed to create SSH public key: %v", err)
+	}
+	publicKey := string(ssh.MarshalAuthorizedKey(sshPub))
+
+	for i, test := range sshSignTests {
+		test.Request.PublicKey = publicKey
+
+		var resp api.SSHSignResponse
+		err := doRequest(ctx, client, http.MethodPut, api.PathSSHSign+test.Key, test.Request, &resp)
+		if test.Status != 0 {
+			if e, ok := api.IsError(err); !ok || e.Status() != test.Status {
+				t.Fatalf("Test %d: got error '%v' - want status %d", i, err, test.Status)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Test %d: failed to sign SSH certificate: %v", i, err)
+		}
+
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(resp.Certificate))
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse SSH certificate: %v", i, err)
+		}
+		cert, ok := key.(*ssh.Certificate)
+		if !ok {
+			t.Fatalf("Test %d: response is no SSH certificate", i)
+		}
+		if !slices.Equal(cert.ValidPrincipals, test.Request.Principals) {
+			t.Fatalf("Test %d: invalid principals: got '%v' - want '%v'", i, cert.ValidPrincipals, test.Request.Principals)
+		}
+		if !bytes.Equal(cert.Key.Marshal(), sshPub.Marshal()) {
+			t.Fatalf("Test %d: certificate has been issued for another public key", i)
+		}
+
+		var ca api.SSHCAResponse
+		if err = doRequest(ctx, client, http.MethodGet, api.PathSSHCA+test.Key, nil, &ca); err != nil {
+			t.Fatalf("Test %d: failed to fetch SSH CA key: %v", i, err)
+		}
+		caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(ca.PublicKey))
+		if err != nil {
+			t.Fatalf("Test %d: failed to parse SSH CA key: %v", i, err)
+		}
+		checker := ssh.CertChecker{
+			IsUserAuthority: func(auth ssh.PublicKey) bool { return bytes.Equal(auth.Marshal(), caKey.Marshal()) },
+		}
+		if err = checker.CheckCert(test.Request.Principals[0], cert); err != nil {
+			t.Fatalf("Test %d: invalid SSH certificate: %v", i, err)
+		}
+	}
+}
+
+var sshSignTests = []struct {
+	Key     string
+	Request api.SSHSignRequest
+	Status  int
+}{
+	{Key: "ssh-ca", Request: api.SSHSignRequest{Principals: []string{"deploy-web"}}},                                              // 0
+	{Key: "ssh-ca", Request: api.SSHSignRequest{Principals: []string{"deploy-web", "deploy-db"}, TTL: 3600}},                      // 1
+	{Key: "ssh-ca", Request: api.SSHSignRequest{Principals: []string{"root"}}, Status: http.StatusForbidden},                      // 2
+	{Key: "ssh-ca", Request: api.SSHSignRequest{Principals: []string{"deploy-web"}, TTL: 3 * 3600}, Status: http.StatusForbidden}, // 3
+	{Key: "ssh-ca", Request: api.SSHSignRequest{Principals: []string{"deploy-web"}, Type: "host"}, Status: http.StatusForbidden},  // 4
+	{Key: "ssh-ca", Request: api.SSHSignRequest{}, Status: http.StatusBadRequest},                                                 // 5
+	{Key: "my-key", Request: api.SSHSignRequest{Principals: []string{"deploy-web"}}, Status: http.StatusForbidden},                // 6
+	{Key: "unknown", Request: api.SSHSignRequest{Principals: []string{"deploy-web"}}, Status: http.StatusNotFound},                // 7
+}
+
+// doRequest sends a request with the JSON-encoded body, if not
+// nil, and decodes the JSON response into v.
+func doRequest(ctx context.Context, client *kes.Client, method, path string, body, v any) error {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, client.Endpoints[0]+path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return api.ReadError(resp)
+	}
+	if err = json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("invalid response body: %v", err)
+	}
+	return nil
+}
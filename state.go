@@ -10,25 +10,52 @@ import (
 	"maps"
 	"net"
 	"net/http"
+	"slices"
 	"time"
 
 	"aead.dev/mem"
 	"github.com/minio/kes/internal/api"
+	"github.com/minio/kes/internal/https"
 	"github.com/minio/kes/internal/metric"
 	"github.com/minio/kms-go/kes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type serverState struct {
 	Addr      net.Addr
 	StartTime time.Time
 
-	Admin      kes.Identity
-	Keys       *keyCache
-	Policies   map[string]*kes.Policy
-	Identities map[kes.Identity]identityEntry
+	Admin       kes.Identity
+	Revocation  *revocationChecker
+	ACME        *acmeManager
+	SPIFFE      *spiffeAuth
+	OIDC        *oidcVerifier
+	Seal        *sealer
+	TLSProxy    *https.TLSProxy
+	Keys        *keyCache
+	Secrets     *secretStore
+	Policies    map[string]*kes.Policy
+	PolicyRules map[string]policyRules
+	Identities  map[kes.Identity]identityEntry
+	Names       nameRules
+	RateLimiter *rateLimiter
 
-	Metrics *metric.Metrics
-	Routes  map[string]api.Route
+	Metrics   *metric.Metrics
+	Routes    map[string]api.Route
+	Telemetry *TelemetryConfig
+	KeyUsage  *KeyUsageConfig
+
+	Notifications []NotificationTarget
+	Replication   *ReplicationConfig
+	Replica       *ReplicaConfig
+	Replicas      []kes.Identity // Identities of read replicas that may fetch keys
+	SoftDelete    *SoftDeleteConfig
+	Approval      *ApprovalConfig
+	MetricsPush   *MetricsPushConfig
+
+	Tracer trace.Tracer
+
+	AuditCheckpoint *AuditCheckpointConfig
 
 	LogHandler *logHandler
 	Log        *slog.Logger
@@ -38,6 +65,75 @@ type serverState struct {
 type identityEntry struct {
 	Name string
 	*kes.Policy
+	policyRules
+
+	// ExpiresAt is the point in time at which the identity
+	// expires and gets revoked. Zero if it never expires.
+	ExpiresAt time.Time
+
+	// AssignedAt and AssignedBy describe when and by whom the
+	// policy has been assigned via the API. Both are zero if
+	// the policy has been assigned by the config file.
+	AssignedAt time.Time
+	AssignedBy kes.Identity
+
+	// IssuedBy is the identity that issued this identity via
+	// the IssueIdentity API, if any. An issued identity acts
+	// on behalf of its issuer.
+	IssuedBy kes.Identity
+}
+
+// expired reports whether the identity has expired at time t.
+func (e *identityEntry) expired(t time.Time) bool {
+	return !e.ExpiresAt.IsZero() && !t.Before(e.ExpiresAt)
+}
+
+// policyCreated returns when and by whom the policy has been
+// created. Policies defined in the config file are created by
+// the admin when the server starts.
+func (s *serverState) policyCreated(p *kes.Policy) (time.Time, kes.Identity) {
+	if p.CreatedAt.IsZero() {
+		return s.StartTime, s.Admin
+	}
+	return p.CreatedAt, p.CreatedBy
+}
+
+// identityCreated returns when and by whom the identity's policy
+// has been assigned. Assignments of the config file are made by
+// the admin when the server starts.
+func (s *serverState) identityCreated(e *identityEntry) (time.Time, kes.Identity) {
+	if e.AssignedAt.IsZero() {
+		return s.StartTime, s.Admin
+	}
+	return e.AssignedAt, e.AssignedBy
+}
+
+// principal returns the identity on whose behalf the given
+// identity acts. Issued identities act on behalf of the
+// identity that issued them, possibly via other issued
+// identities. All other identities act on their own behalf.
+func (s *serverState) principal(id kes.Identity) kes.Identity {
+	for seen := map[kes.Identity]bool{}; !seen[id]; {
+		seen[id] = true
+		e, ok := s.Identities[id]
+		if !ok || e.IssuedBy == "" {
+			return id
+		}
+		id = e.IssuedBy
+	}
+	return id
+}
+
+// policyRules are the parts of a policy that are not part
+// of a kes.Policy and enforced by the server in addition to
+// the policy's allow and deny patterns.
+type policyRules struct {
+	Keys       []KeyRule
+	Conditions *PolicyConditions
+	RateLimit  *RateLimit
+	Context    *ContextPolicy
+	SSH        *SSHPolicy
+	Cert       *CertPolicy
 }
 
 func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.Metrics) (*http.ServeMux, map[string]api.Route) {
@@ -58,6 +154,22 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: api.HandlerFunc(s.ready),
 		},
+		api.PathHealthLive: {
+			Method:  http.MethodGet,
+			Path:    api.PathHealthLive,
+			MaxBody: 0,
+			Timeout: 10 * time.Second,
+			Auth:    api.InsecureSkipVerify,
+			Handler: api.HandlerFunc(s.live),
+		},
+		api.PathHealthReady: {
+			Method:  http.MethodGet,
+			Path:    api.PathHealthReady,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.healthReady),
+		},
 		api.PathStatus: {
 			Method:  http.MethodGet,
 			Path:    api.PathStatus,
@@ -86,10 +198,10 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 		api.PathKeyCreate: {
 			Method:  http.MethodPut,
 			Path:    api.PathKeyCreate,
-			MaxBody: 0,
+			MaxBody: 1 * mem.MB,
 			Timeout: 15 * time.Second,
 			Auth:    (*verifyIdentity)(&s.state),
-			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.createKey))),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.createKey))))),
 		},
 		api.PathKeyImport: {
 			Method:  http.MethodPut,
@@ -97,7 +209,31 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			MaxBody: 1 * mem.MB,
 			Timeout: 15 * time.Second,
 			Auth:    (*verifyIdentity)(&s.state),
-			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.importKey))),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.importKey))))),
+		},
+		api.PathKeyExport: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyExport,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.exportKey))),
+		},
+		api.PathKeyRestore: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyRestore,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.restoreKey))))),
+		},
+		api.PathKeyRotate: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyRotate,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.rotateKey))))),
 		},
 		api.PathKeyDescribe: {
 			Method:  http.MethodGet,
@@ -115,13 +251,37 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listKeys))),
 		},
+		api.PathKeyListInfo: {
+			Method:  http.MethodGet,
+			Path:    api.PathKeyListInfo,
+			MaxBody: 0,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listKeyInfos))),
+		},
+		api.PathKeySearch: {
+			Method:  http.MethodGet,
+			Path:    api.PathKeySearch,
+			MaxBody: 0,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.searchKeys))),
+		},
 		api.PathKeyDelete: {
 			Method:  http.MethodDelete,
 			Path:    api.PathKeyDelete,
 			MaxBody: 0,
 			Timeout: 15 * time.Second,
 			Auth:    (*verifyIdentity)(&s.state),
-			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.deleteKey))),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(s.requireApproval(api.HandlerFunc(s.deleteKey)))))),
+		},
+		api.PathKeyUndelete: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyUndelete,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.undeleteKey))))),
 		},
 		api.PathKeyEncrypt: {
 			Method:  http.MethodPut,
@@ -155,6 +315,110 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.hmacKey))),
 		},
+		api.PathKeyECDH: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyECDH,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.ecdhKey))),
+		},
+		api.PathKeySign: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeySign,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.signKey))),
+		},
+		api.PathKeyVerify: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyVerify,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.verifyKey))),
+		},
+		api.PathSSHSign: {
+			Method:  http.MethodPut,
+			Path:    api.PathSSHSign,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.signSSH))),
+		},
+		api.PathSSHCA: {
+			Method:  http.MethodGet,
+			Path:    api.PathSSHCA,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.describeSSH))),
+		},
+		api.PathCertIssue: {
+			Method:  http.MethodPut,
+			Path:    api.PathCertIssue,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.issueCert))),
+		},
+		api.PathCertCA: {
+			Method:  http.MethodGet,
+			Path:    api.PathCertCA,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.describeCertCA))),
+		},
+		api.PathSecretSet: {
+			Method:  http.MethodPut,
+			Path:    api.PathSecretSet,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.setSecret))))),
+		},
+		api.PathSecretGet: {
+			Method:  http.MethodGet,
+			Path:    api.PathSecretGet,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.getSecret))),
+		},
+		api.PathSecretList: {
+			Method:  http.MethodGet,
+			Path:    api.PathSecretList,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listSecrets))),
+		},
+		api.PathSecretDelete: {
+			Method:  http.MethodDelete,
+			Path:    api.PathSecretDelete,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.deleteSecret))))),
+		},
+		api.PathKeyBulkEncrypt: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyBulkEncrypt,
+			MaxBody: 8 * mem.MB,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.bulkEncryptKey))),
+		},
+		api.PathKeyBulkDecrypt: {
+			Method:  http.MethodPut,
+			Path:    api.PathKeyBulkDecrypt,
+			MaxBody: 8 * mem.MB,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.bulkDecryptKey))),
+		},
 
 		api.PathPolicyDescribe: {
 			Method:  http.MethodGet,
@@ -180,6 +444,47 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listPolicies))),
 		},
+		api.PathPolicyListInfo: {
+			Method:  http.MethodGet,
+			Path:    api.PathPolicyListInfo,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listPolicyInfos))),
+		},
+
+		api.PathPolicyCreate: {
+			Method:  http.MethodPut,
+			Path:    api.PathPolicyCreate,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(s.requireApproval(api.HandlerFunc(s.createPolicy)))))),
+		},
+		api.PathPolicyDelete: {
+			Method:  http.MethodDelete,
+			Path:    api.PathPolicyDelete,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(s.requireApproval(api.HandlerFunc(s.deletePolicy)))))),
+		},
+		api.PathPolicyAssign: {
+			Method:  http.MethodPut,
+			Path:    api.PathPolicyAssign,
+			MaxBody: 1 * mem.MB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(s.requireApproval(api.HandlerFunc(s.assignPolicy)))))),
+		},
+		api.PathPolicyTest: {
+			Method:  http.MethodGet,
+			Path:    api.PathPolicyTest,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.testPolicy))),
+		},
 
 		api.PathIdentityDescribe: {
 			Method:  http.MethodGet,
@@ -197,6 +502,22 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listIdentities))),
 		},
+		api.PathIdentityListInfo: {
+			Method:  http.MethodGet,
+			Path:    api.PathIdentityListInfo,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listIdentityInfos))),
+		},
+		api.PathIdentityIssue: {
+			Method:  http.MethodPut,
+			Path:    api.PathIdentityIssue,
+			MaxBody: 1 * mem.KiB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(s.rejectFrozen(s.idempotency.Handle(api.HandlerFunc(s.issueIdentity))))),
+		},
 		api.PathIdentitySelfDescribe: {
 			Method:  http.MethodGet,
 			Path:    api.PathIdentitySelfDescribe,
@@ -222,6 +543,148 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 			Auth:    (*verifyIdentity)(&s.state),
 			Handler: metrics.AuditEventCounter(api.HandlerFunc(s.logAudit)),
 		},
+		api.PathLogAuditProof: {
+			Method:  http.MethodGet,
+			Path:    api.PathLogAuditProof,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.proveAudit))),
+		},
+		api.PathLogAuditQuery: {
+			Method:  http.MethodGet,
+			Path:    api.PathLogAuditQuery,
+			MaxBody: 0,
+			Timeout: 60 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.queryAudit))),
+		},
+
+		api.PathWatch: {
+			Method:  http.MethodGet,
+			Path:    api.PathWatch,
+			MaxBody: 0,
+			Timeout: 0, // No timeout
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.watch),
+		},
+
+		api.PathSupportBundle: {
+			Method:  http.MethodGet,
+			Path:    api.PathSupportBundle,
+			MaxBody: 0,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.supportBundle))),
+		},
+		api.PathDebugProfile: {
+			Method:  http.MethodGet,
+			Path:    api.PathDebugProfile,
+			MaxBody: 0,
+			Timeout: maxProfileDuration + time.Minute,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.debugProfile),
+		},
+
+		api.PathAdminReload: {
+			Method:  http.MethodPost,
+			Path:    api.PathAdminReload,
+			MaxBody: 0,
+			Timeout: 60 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.reload),
+		},
+		api.PathAdminUnseal: {
+			Method:  http.MethodPut,
+			Path:    api.PathAdminUnseal,
+			MaxBody: 1 * mem.KiB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.unseal),
+		},
+		api.PathAdminFreeze: {
+			Method:  http.MethodPut,
+			Path:    api.PathAdminFreeze,
+			MaxBody: 1 * mem.KiB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.freeze),
+		},
+		api.PathAdminLogLevel: {
+			Method:  http.MethodPut,
+			Path:    api.PathAdminLogLevel,
+			MaxBody: 1 * mem.KiB,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.setLogLevel),
+		},
+
+		api.PathApprovalList: {
+			Method:  http.MethodGet,
+			Path:    api.PathApprovalList,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.listApprovals),
+		},
+		api.PathApprovalApprove: {
+			Method:  http.MethodPut,
+			Path:    api.PathApprovalApprove,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.approveRequest),
+		},
+		api.PathApprovalDeny: {
+			Method:  http.MethodPut,
+			Path:    api.PathApprovalDeny,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.denyRequest),
+		},
+
+		api.PathClusterRaft: {
+			Method:  http.MethodPost,
+			Path:    api.PathClusterRaft,
+			MaxBody: 256 * mem.MiB, // Snapshots contain all keys
+			Timeout: 60 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: api.HandlerFunc(s.clusterRaft),
+		},
+		api.PathClusterList: {
+			Method:  http.MethodGet,
+			Path:    api.PathClusterList,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.listClusterMembers))),
+		},
+		api.PathClusterAdd: {
+			Method:  http.MethodPost,
+			Path:    api.PathClusterAdd,
+			MaxBody: 1 * mem.KiB,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.addClusterMember))),
+		},
+		api.PathClusterRemove: {
+			Method:  http.MethodDelete,
+			Path:    api.PathClusterRemove,
+			MaxBody: 0,
+			Timeout: 30 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.removeClusterMember))),
+		},
+
+		api.PathReplicaKey: {
+			Method:  http.MethodGet,
+			Path:    api.PathReplicaKey,
+			MaxBody: 0,
+			Timeout: 15 * time.Second,
+			Auth:    (*verifyIdentity)(&s.state),
+			Handler: metrics.Latency(metrics.Count(api.HandlerFunc(s.replicaKey))),
+		},
 	}
 
 	for path, conf := range routeConfig { // apply API customization
@@ -240,36 +703,91 @@ func initRoutes(s *Server, routeConfig map[string]RouteConfig, metrics *metric.M
 
 	mux := http.NewServeMux()
 	for path, route := range routes {
-		mux.Handle(path, route)
+		mux.Handle(path, s.traceRoute(route))
 	}
 	return mux, routes
 }
 
-func initPolicies(policies map[string]Policy) (map[string]*kes.Policy, map[kes.Identity]identityEntry, error) {
+func initPolicies(policies map[string]Policy, names nameRules) (map[string]*kes.Policy, map[string]policyRules, map[kes.Identity]identityEntry, error) {
 	policySet := make(map[string]*kes.Policy, len(policies))
+	ruleSet := make(map[string]policyRules, len(policies))
 	identitySet := make(map[kes.Identity]identityEntry, len(policies))
 	for name, policy := range policies {
-		if !validName(name) {
-			return nil, nil, fmt.Errorf("kes: policy name '%s' is empty, too long or contains invalid characters", name)
+		if !names.ValidName(name) {
+			return nil, nil, nil, fmt.Errorf("kes: policy name '%s' is empty, too long or contains invalid characters", name)
 		}
 		p := &kes.Policy{
 			Allow: maps.Clone(policy.Allow),
 			Deny:  maps.Clone(policy.Deny),
 		}
 
+		for _, rule := range policy.Keys {
+			if err := rule.validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("kes: invalid policy '%s': %v", name, err)
+			}
+		}
+		var rateLimit *RateLimit
+		if policy.RateLimit != nil {
+			if err := policy.RateLimit.validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("kes: invalid policy '%s': %v", name, err)
+			}
+			limit := *policy.RateLimit
+			rateLimit = &limit
+		}
+		var contextPolicy *ContextPolicy
+		if policy.Context != nil {
+			if err := policy.Context.validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("kes: invalid policy '%s': %v", name, err)
+			}
+			contextPolicy = &ContextPolicy{Fields: maps.Clone(policy.Context.Fields)}
+		}
+		var sshPolicy *SSHPolicy
+		if policy.SSH != nil {
+			if err := policy.SSH.validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("kes: invalid policy '%s': %v", name, err)
+			}
+			sshPolicy = &SSHPolicy{
+				Principals: slices.Clone(policy.SSH.Principals),
+				MaxTTL:     policy.SSH.MaxTTL,
+				Host:       policy.SSH.Host,
+			}
+		}
+		var certPolicy *CertPolicy
+		if policy.Cert != nil {
+			if err := policy.Cert.validate(); err != nil {
+				return nil, nil, nil, fmt.Errorf("kes: invalid policy '%s': %v", name, err)
+			}
+			certPolicy = &CertPolicy{
+				Names:  slices.Clone(policy.Cert.Names),
+				IPs:    slices.Clone(policy.Cert.IPs),
+				MaxTTL: policy.Cert.MaxTTL,
+				Server: policy.Cert.Server,
+			}
+		}
+		rules := policyRules{
+			Keys:       slices.Clone(policy.Keys),
+			Conditions: policy.Conditions,
+			RateLimit:  rateLimit,
+			Context:    contextPolicy,
+			SSH:        sshPolicy,
+			Cert:       certPolicy,
+		}
+
 		policySet[name] = p
+		ruleSet[name] = rules
 		for _, id := range policy.Identities {
-			if !validName(id.String()) {
-				return nil, nil, fmt.Errorf("kes: identity '%s' is empty, too long or contains invalid characters", id)
+			if !names.ValidIdentity(id.String()) {
+				return nil, nil, nil, fmt.Errorf("kes: identity '%s' is empty, too long or contains invalid characters", id)
 			}
 			if _, ok := identitySet[id]; ok {
-				return nil, nil, fmt.Errorf("kes: cannot assign policy '%s' to '%v': identity already has a policy", name, id)
+				return nil, nil, nil, fmt.Errorf("kes: cannot assign policy '%s' to '%v': identity already has a policy", name, id)
 			}
 			identitySet[id] = identityEntry{
-				Name:   name,
-				Policy: p,
+				Name:        name,
+				Policy:      p,
+				policyRules: rules,
 			}
 		}
 	}
-	return policySet, identitySet, nil
+	return policySet, ruleSet, identitySet, nil
 }
@@ -0,0 +1,64 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits for key tags.
+const (
+	maxTags           = 32
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// validTags returns an error if tags contains too many
+// or invalid tags.
+//
+// Valid tag keys are at most 128 bytes long and only
+// contain the characters:
+//   - 0-9
+//   - A-Z
+//   - a-z
+//   - '-', '_', '.' and '/'
+//
+// Valid tag values are at most 256 bytes long and must
+// not contain control characters.
+func validTags(tags map[string]string) error {
+	if len(tags) > maxTags {
+		return fmt.Errorf("too many tags: at most %d tags are allowed", maxTags)
+	}
+	for k, v := range tags {
+		if k == "" || len(k) > maxTagKeyLength {
+			return fmt.Errorf("tag key '%s' is empty or too long", k)
+		}
+		for _, r := range k {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'A' && r <= 'Z':
+			case r >= 'a' && r <= 'z':
+			case r == '-' || r == '_' || r == '.' || r == '/':
+			default:
+				return fmt.Errorf("tag key '%s' contains invalid characters", k)
+			}
+		}
+
+		if len(v) > maxTagValueLength {
+			return fmt.Errorf("value of tag '%s' is too long", k)
+		}
+		if !utf8.ValidString(v) {
+			return fmt.Errorf("value of tag '%s' is not valid UTF-8", k)
+		}
+		for _, r := range v {
+			if unicode.IsControl(r) {
+				return fmt.Errorf("value of tag '%s' contains invalid characters", k)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidTags(t *testing.T) {
+	for i, test := range validTagsTests {
+		err := validTags(test.Tags)
+		if err != nil && !test.ShouldFail {
+			t.Fatalf("Test %d: failed to validate tags: %v", i, err)
+		}
+		if err == nil && test.ShouldFail {
+			t.Fatalf("Test %d: invalid tags validated successfully", i)
+		}
+	}
+}
+
+var validTagsTests = []struct {
+	Tags       map[string]string
+	ShouldFail bool
+}{
+	{Tags: nil}, // 0
+	{Tags: map[string]string{"team": "payments"}},                       // 1
+	{Tags: map[string]string{"app.kubernetes.io/name": "minio-tenant"}}, // 2
+	{Tags: map[string]string{"env": ""}},                                // 3
+	{Tags: map[string]string{"owner": "Jane Doe <jane@example.com>"}},   // 4
+	{Tags: manyTags(32)}, // 5
+
+	{Tags: manyTags(33), ShouldFail: true},                                            // 6
+	{Tags: map[string]string{"": "payments"}, ShouldFail: true},                       // 7
+	{Tags: map[string]string{"team:name": "payments"}, ShouldFail: true},              // 8
+	{Tags: map[string]string{strings.Repeat("a", 129): "payments"}, ShouldFail: true}, // 9
+	{Tags: map[string]string{"team": strings.Repeat("a", 257)}, ShouldFail: true},     // 10
+	{Tags: map[string]string{"team": "pay\nments"}, ShouldFail: true},                 // 11
+	{Tags: map[string]string{"team": "\xff"}, ShouldFail: true},                       // 12
+}
+
+func manyTags(n int) map[string]string {
+	tags := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		tags["tag-"+strconv.Itoa(i)] = strconv.Itoa(i)
+	}
+	return tags
+}
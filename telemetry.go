@@ -0,0 +1,146 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/minio/kes/internal/headers"
+	"github.com/minio/kes/internal/sys"
+)
+
+// DefaultTelemetryInterval is the time between two usage
+// reports if TelemetryConfig.Interval is not set.
+const DefaultTelemetryInterval = 24 * time.Hour
+
+// telemetryReport is the anonymous usage report sent to
+// the telemetry endpoint.
+type telemetryReport struct {
+	ID         string            `json:"id"` // Random ID that changes on every restart
+	Version    string            `json:"version"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	KeyStore   string            `json:"keystore"`
+	UpTime     uint64            `json:"uptime"` // in seconds
+	Policies   int               `json:"policies"`
+	Identities int               `json:"identities"`
+	Requests   telemetryRequests `json:"requests"`
+}
+
+// telemetryRequests contains the total number of requests
+// served by the server grouped by outcome.
+type telemetryRequests struct {
+	Succeeded uint64 `json:"succeeded"` // HTTP 2xx
+	Errored   uint64 `json:"errored"`   // HTTP 4xx
+	Failed    uint64 `json:"failed"`    // HTTP 5xx
+}
+
+// reportTelemetry sends usage reports to the telemetry endpoint,
+// if configured, until ctx is canceled. The first report is sent
+// one minute after telemetry has been enabled. Changes of the
+// telemetry interval take effect after the next report.
+func (s *Server) reportTelemetry(ctx context.Context) {
+	const (
+		Delay   = 1 * time.Minute
+		Timeout = 30 * time.Second
+	)
+
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return
+	}
+	client := &http.Client{Timeout: Timeout}
+
+	timer := time.NewTimer(Delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.Telemetry == nil {
+			timer.Reset(Delay)
+			continue
+		}
+
+		report := newTelemetryReport(state)
+		report.ID = hex.EncodeToString(id[:])
+		if err := sendTelemetry(ctx, client, state.Telemetry.Endpoint, report); err != nil {
+			state.Log.WarnContext(ctx, fmt.Sprintf("failed to send telemetry report: %v", err))
+		}
+
+		interval := state.Telemetry.Interval
+		if interval <= 0 {
+			interval = DefaultTelemetryInterval
+		}
+		timer.Reset(interval)
+	}
+}
+
+// newTelemetryReport returns a new usage report for the given
+// server state. The report's ID is not set.
+func newTelemetryReport(state *serverState) telemetryReport {
+	info, _ := sys.ReadBinaryInfo()
+	succeeded, errored, failed := state.Metrics.RequestCounts()
+	return telemetryReport{
+		Version:    info.Version,
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+		KeyStore:   keyStoreType(state.Keys.Type()),
+		UpTime:     uint64(time.Since(state.StartTime).Round(time.Second).Seconds()),
+		Policies:   len(state.Policies),
+		Identities: len(state.Identities),
+		Requests: telemetryRequests{
+			Succeeded: succeeded,
+			Errored:   errored,
+			Failed:    failed,
+		},
+	}
+}
+
+// keyStoreType returns the type of the key store described by
+// desc, for example "Hashicorp Vault" for "Hashicorp Vault:
+// https://127.0.0.1:8200". It drops everything that may identify
+// the deployment, like endpoints or paths.
+func keyStoreType(desc string) string {
+	typ, _, _ := strings.Cut(desc, ":")
+	return strings.TrimSpace(typ)
+}
+
+// sendTelemetry sends the report to the given endpoint.
+func sendTelemetry(ctx context.Context, client *http.Client, endpoint string, report telemetryReport) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, headers.ContentTypeJSON)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %s", endpoint, resp.Status)
+	}
+	return nil
+}
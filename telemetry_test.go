@@ -0,0 +1,66 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var keyStoreTypeTests = []struct {
+	Desc string
+	Type string
+}{
+	{Desc: "In Memory", Type: "In Memory"},                                                            // 0
+	{Desc: "Hashicorp Vault: https://127.0.0.1:8200", Type: "Hashicorp Vault"},                        // 1
+	{Desc: "Filesystem: /tmp/keys (encrypted) (compressed)", Type: "Filesystem"},                      // 2
+	{Desc: "Failover: Hashicorp Vault: https://vault:8200 | Filesystem: /tmp/keys", Type: "Failover"}, // 3
+	{Desc: "*kes.MemKeyStore", Type: "*kes.MemKeyStore"},                                              // 4
+}
+
+func TestKeyStoreType(t *testing.T) {
+	for i, test := range keyStoreTypeTests {
+		if typ := keyStoreType(test.Desc); typ != test.Type {
+			t.Fatalf("Test %d: got '%s' - want '%s'", i, typ, test.Type)
+		}
+	}
+}
+
+func TestSendTelemetry(t *testing.T) {
+	reports := make(chan telemetryReport, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		var report telemetryReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reports <- report
+	}))
+	defer srv.Close()
+
+	ctx := testContext(t)
+	report := telemetryReport{
+		ID:       "f3a9c1d2",
+		Version:  "v1.0.0",
+		KeyStore: "Hashicorp Vault",
+		Requests: telemetryRequests{Succeeded: 10, Errored: 2},
+	}
+	if err := sendTelemetry(ctx, srv.Client(), srv.URL, report); err != nil {
+		t.Fatalf("Failed to send report: %v", err)
+	}
+	if got := <-reports; got != report {
+		t.Fatalf("Report mismatch: got '%+v' - want '%+v'", got, report)
+	}
+
+	if err := sendTelemetry(ctx, srv.Client(), srv.URL+"/invalid\x00", report); err == nil {
+		t.Fatal("Sending report to invalid endpoint succeeded")
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/minio/kes/internal/api"
+	"github.com/minio/kms-go/kes"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the name of the OpenTelemetry tracer
+// creating the server's spans.
+const tracerName = "github.com/minio/kes"
+
+// newTracer returns a new tracer from the provider or a
+// no-op tracer if the provider is nil.
+func newTracer(provider trace.TracerProvider) trace.Tracer {
+	if provider == nil {
+		provider = noop.NewTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+// traceRoute returns a http.Handler that creates a span for
+// each request handled by the route. The span continues the
+// trace of the client, if the request contains a W3C trace
+// context.
+func (s *Server) traceRoute(route api.Route) http.Handler {
+	name := route.Method + " " + route.Path
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := s.state.Load().Tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route.Path),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		sw := &statusResponseWriter{ResponseWriter: w}
+		route.ServeHTTP(sw, r.WithContext(ctx))
+
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+	})
+}
+
+// statusResponseWriter is an http.ResponseWriter that
+// records the response status code.
+type statusResponseWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+var (
+	_ http.ResponseWriter = (*statusResponseWriter)(nil)
+	_ http.Flusher        = (*statusResponseWriter)(nil)
+)
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Flush sends any buffered data to the client.
+//
+// This method will be called by http.ResponseController.
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+//
+// This method will be called by http.ResponseController.
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// traceKeyStore returns a KeyStore that creates a span for
+// each operation on the KeyStore.
+func traceKeyStore(store KeyStore, tracer trace.Tracer) KeyStore {
+	return &tracingKeyStore{
+		store:  store,
+		tracer: tracer,
+	}
+}
+
+// tracingKeyStore is a KeyStore that creates a span for
+// each operation on the wrapped KeyStore.
+type tracingKeyStore struct {
+	store  KeyStore
+	tracer trace.Tracer
+}
+
+var _ KeyStore = (*tracingKeyStore)(nil) // compiler check
+
+func (ks *tracingKeyStore) String() string {
+	if s, ok := ks.store.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", ks.store)
+}
+
+// Unwrap returns the underlying KeyStore.
+func (ks *tracingKeyStore) Unwrap() KeyStore { return ks.store }
+
+func (ks *tracingKeyStore) Close() error { return ks.store.Close() }
+
+func (ks *tracingKeyStore) Status(ctx context.Context) (KeyStoreState, error) {
+	ctx, span := ks.start(ctx, "Status", "")
+	state, err := ks.store.Status(ctx)
+	ks.end(span, err)
+	return state, err
+}
+
+func (ks *tracingKeyStore) Create(ctx context.Context, name string, value []byte) error {
+	ctx, span := ks.start(ctx, "Create", name)
+	err := ks.store.Create(ctx, name, value)
+	ks.end(span, err)
+	return err
+}
+
+func (ks *tracingKeyStore) Delete(ctx context.Context, name string) error {
+	ctx, span := ks.start(ctx, "Delete", name)
+	err := ks.store.Delete(ctx, name)
+	ks.end(span, err)
+	return err
+}
+
+func (ks *tracingKeyStore) Get(ctx context.Context, name string) ([]byte, error) {
+	ctx, span := ks.start(ctx, "Get", name)
+	value, err := ks.store.Get(ctx, name)
+	ks.end(span, err)
+	return value, err
+}
+
+func (ks *tracingKeyStore) List(ctx context.Context, prefix string, n int) ([]string, string, error) {
+	ctx, span := ks.start(ctx, "List", prefix)
+	names, next, err := ks.store.List(ctx, prefix, n)
+	ks.end(span, err)
+	return names, next, err
+}
+
+func (ks *tracingKeyStore) start(ctx context.Context, op, name string) (context.Context, trace.Span) {
+	return ks.tracer.Start(ctx, "keystore."+op,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("kes.keystore", ks.String()),
+			attribute.String("kes.key", name),
+		),
+	)
+}
+
+// end ends the span and records the error, if any. Keys that
+// do not exist or already exist are not recorded as error.
+func (ks *tracingKeyStore) end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, kes.ErrKeyNotFound) && !errors.Is(err, kes.ErrKeyExists) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,48 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer provider.Shutdown(testContext(t))
+
+	ctx := testContext(t)
+	srv, url := startServer(ctx, &Config{
+		TracerProvider: provider,
+	})
+	defer srv.Close()
+
+	client := defaultClient(url)
+	if err := client.CreateKey(ctx, "my-key"); err != nil {
+		t.Fatalf("Failed to create key: %v", err)
+	}
+
+	var server, keystore sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch span.Name() {
+		case "PUT /v1/key/create/":
+			server = span
+		case "keystore.Create":
+			keystore = span
+		}
+	}
+	if server == nil {
+		t.Fatal("No span for the API request")
+	}
+	if keystore == nil {
+		t.Fatal("No span for the key store operation")
+	}
+	if keystore.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Fatalf("Key store span is not a child of the API request span: got parent '%s' - want '%s'", keystore.Parent().SpanID(), server.SpanContext().SpanID())
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/minio/kes/internal/api"
+)
+
+// DefaultKeyUsageInterval is the default time between two
+// writes of the key usage file.
+const DefaultKeyUsageInterval = 1 * time.Minute
+
+// keyOp is a cryptographic operation tracked per key.
+type keyOp uint
+
+const (
+	keyOpEncrypt keyOp = iota
+	keyOpDecrypt
+	keyOpGenerate
+)
+
+// keyUsageTracker counts how often keys are used for encrypt,
+// decrypt and generate operations and when they have been used
+// last. Its zero value is ready and safe to be used concurrently
+// from different go routines.
+//
+// It is not part of the server state such that key usage
+// statistics are kept across config reloads.
+type keyUsageTracker struct {
+	lock sync.RWMutex
+	keys map[string]*keyUsage
+}
+
+// keyUsage are the usage statistics of a single key.
+type keyUsage struct {
+	Encrypt  atomic.Uint64
+	Decrypt  atomic.Uint64
+	Generate atomic.Uint64
+	LastUsed atomic.Int64 // Unix time in nanoseconds
+}
+
+// Record records that the key with the given name has been
+// used for the given operation.
+func (t *keyUsageTracker) Record(name string, op keyOp) {
+	t.lock.RLock()
+	usage, ok := t.keys[name]
+	t.lock.RUnlock()
+
+	if !ok {
+		t.lock.Lock()
+		if usage, ok = t.keys[name]; !ok {
+			if t.keys == nil {
+				t.keys = map[string]*keyUsage{}
+			}
+			usage = &keyUsage{}
+			t.keys[name] = usage
+		}
+		t.lock.Unlock()
+	}
+
+	switch op {
+	case keyOpEncrypt:
+		usage.Encrypt.Add(1)
+	case keyOpDecrypt:
+		usage.Decrypt.Add(1)
+	case keyOpGenerate:
+		usage.Generate.Add(1)
+	}
+	usage.LastUsed.Store(time.Now().UnixNano())
+}
+
+// Get returns the usage statistics of the key with the given
+// name. Keys that have never been used have zero statistics.
+func (t *keyUsageTracker) Get(name string) api.KeyUsage {
+	t.lock.RLock()
+	usage, ok := t.keys[name]
+	t.lock.RUnlock()
+
+	if !ok {
+		return api.KeyUsage{}
+	}
+	return usage.load()
+}
+
+// All returns the usage statistics of all keys that have
+// been used.
+func (t *keyUsageTracker) All() map[string]api.KeyUsage {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	all := make(map[string]api.KeyUsage, len(t.keys))
+	for name, usage := range t.keys {
+		all[name] = usage.load()
+	}
+	return all
+}
+
+// Delete removes the usage statistics of the key with the
+// given name.
+func (t *keyUsageTracker) Delete(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	delete(t.keys, name)
+}
+
+// WriteFile writes the usage statistics of all keys as JSON
+// to the given file. It writes to a temporary file first and
+// renames it afterwards. Hence, an existing file is not lost
+// when WriteFile fails.
+func (t *keyUsageTracker) WriteFile(filename string) error {
+	data, err := json.Marshal(t.All())
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if _, err = file.Write(data); err != nil {
+		return err
+	}
+	if err = file.Sync(); err != nil {
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filename)
+}
+
+// ReadFile restores the usage statistics stored in the given
+// file, previously written by WriteFile. Statistics present in
+// the file replace existing statistics of the same key.
+func (t *keyUsageTracker) ReadFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var keys map[string]api.KeyUsage
+	if err = json.Unmarshal(data, &keys); err != nil {
+		return fmt.Errorf("kes: invalid key usage file '%s': %v", filename, err)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.keys == nil {
+		t.keys = make(map[string]*keyUsage, len(keys))
+	}
+	for name, v := range keys {
+		usage := &keyUsage{}
+		usage.Encrypt.Store(v.Encrypt)
+		usage.Decrypt.Store(v.Decrypt)
+		usage.Generate.Store(v.Generate)
+		if !v.LastUsed.IsZero() {
+			usage.LastUsed.Store(v.LastUsed.UnixNano())
+		}
+		t.keys[name] = usage
+	}
+	return nil
+}
+
+func (u *keyUsage) load() api.KeyUsage {
+	usage := api.KeyUsage{
+		Encrypt:  u.Encrypt.Load(),
+		Decrypt:  u.Decrypt.Load(),
+		Generate: u.Generate.Load(),
+	}
+	if lastUsed := u.LastUsed.Load(); lastUsed != 0 {
+		usage.LastUsed = time.Unix(0, lastUsed).UTC()
+	}
+	return usage
+}
+
+// recordKeyUsage records that the key with the given name has
+// been used for the given operation, if key usage tracking is
+// enabled.
+func (s *Server) recordKeyUsage(name string, op keyOp) {
+	if s.state.Load().KeyUsage != nil {
+		s.usage.Record(name, op)
+	}
+}
+
+// keyUsage returns the usage statistics of the key with the
+// given name or nil if key usage tracking is disabled.
+func (s *Server) keyUsage(name string) *api.KeyUsage {
+	if s.state.Load().KeyUsage == nil {
+		return nil
+	}
+	usage := s.usage.Get(name)
+	return &usage
+}
+
+// persistKeyUsage writes the key usage statistics periodically
+// to the key usage file, if configured, until ctx is canceled.
+// Changes of the interval take effect after the next write.
+func (s *Server) persistKeyUsage(ctx context.Context) {
+	const Delay = 1 * time.Minute // Delay between checks whether key usage tracking is enabled
+
+	for {
+		wait := Delay
+		if conf := s.state.Load().KeyUsage; conf != nil && conf.Filename != "" {
+			wait = conf.Interval
+			if wait <= 0 {
+				wait = DefaultKeyUsageInterval
+			}
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		state := s.state.Load()
+		if state.KeyUsage == nil || state.KeyUsage.Filename == "" {
+			continue
+		}
+		if err := s.usage.WriteFile(state.KeyUsage.Filename); err != nil {
+			state.Log.ErrorContext(ctx, fmt.Sprintf("kes: failed to write key usage file: %v", err))
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 - MinIO, Inc. All rights reserved.
+// Use of this source code is governed by the AGPLv3
+// license that can be found in the LICENSE file.
+
+package kes
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestKeyUsageTracker(t *testing.T) {
+	var tracker keyUsageTracker
+	if usage := tracker.Get("my-key"); usage.Encrypt != 0 || !usage.LastUsed.IsZero() {
+		t.Fatalf("Unused key has usage statistics: %+v", usage)
+	}
+
+	start := time.Now()
+	tracker.Record("my-key", keyOpEncrypt)
+	tracker.Record("my-key", keyOpEncrypt)
+	tracker.Record("my-key", keyOpDecrypt)
+	tracker.Record("my-key", keyOpGenerate)
+	tracker.Record("my-key2", keyOpGenerate)
+
+	usage := tracker.Get("my-key")
+	if usage.Encrypt != 2 || usage.Decrypt != 1 || usage.Generate != 1 {
+		t.Fatalf("Invalid usage statistics: got %+v", usage)
+	}
+	if usage.LastUsed.Before(start.Truncate(time.Second)) {
+		t.Fatalf("Invalid last used time: got '%v' - want after '%v'", usage.LastUsed, start)
+	}
+	if n := len(tracker.All()); n != 2 {
+		t.Fatalf("Invalid number of keys: got '%d' - want '%d'", n, 2)
+	}
+
+	tracker.Delete("my-key")
+	if usage := tracker.Get("my-key"); usage.Encrypt != 0 {
+		t.Fatalf("Deleted key has usage statistics: %+v", usage)
+	}
+	if n := len(tracker.All()); n != 1 {
+		t.Fatalf("Invalid number of keys: got '%d' - want '%d'", n, 1)
+	}
+}
+
+func TestKeyUsageTrackerFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "key-usage.json")
+
+	var tracker keyUsageTracker
+	tracker.Record("my-key", keyOpEncrypt)
+	tracker.Record("my-key", keyOpDecrypt)
+	tracker.Record("my-key2", keyOpGenerate)
+	if err := tracker.WriteFile(filename); err != nil {
+		t.Fatalf("Failed to write key usage file: %v", err)
+	}
+
+	var restored keyUsageTracker
+	if err := restored.ReadFile(filename); err != nil {
+		t.Fatalf("Failed to read key usage file: %v", err)
+	}
+	want, got := tracker.All(), restored.All()
+	if len(got) != len(want) {
+		t.Fatalf("Invalid number of keys: got '%d' - want '%d'", len(got), len(want))
+	}
+	for name, usage := range want {
+		if !got[name].LastUsed.Equal(usage.LastUsed) {
+			t.Fatalf("Key '%s': invalid last used time: got '%v' - want '%v'", name, got[name].LastUsed, usage.LastUsed)
+		}
+		if got[name].Encrypt != usage.Encrypt || got[name].Decrypt != usage.Decrypt || got[name].Generate != usage.Generate {
+			t.Fatalf("Key '%s': invalid usage statistics: got %+v - want %+v", name, got[name], usage)
+		}
+	}
+
+	restored.Record("my-key", keyOpEncrypt)
+	if usage := restored.Get("my-key"); usage.Encrypt != 2 {
+		t.Fatalf("Invalid encrypt count: got '%d' - want '%d'", usage.Encrypt, 2)
+	}
+}